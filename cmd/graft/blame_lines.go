@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+// parseBlameLineRange parses a -L argument: "<start>,<end>",
+// "<start>,+<count>", or "<start>" for start through the end of the file.
+func parseBlameLineRange(spec string) (start, end int, err error) {
+	first, second, hasEnd := strings.Cut(spec, ",")
+	if start, err = strconv.Atoi(strings.TrimSpace(first)); err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid -L %q: start must be a line number", spec)
+	}
+	second = strings.TrimSpace(second)
+	switch {
+	case !hasEnd || second == "":
+		return start, 0, nil
+	case strings.HasPrefix(second, "+"):
+		count, err := strconv.Atoi(second[1:])
+		if err != nil || count < 1 {
+			return 0, 0, fmt.Errorf("invalid -L %q: count must be positive", spec)
+		}
+		return start, start + count - 1, nil
+	default:
+		if end, err = strconv.Atoi(second); err != nil || end < start {
+			return 0, 0, fmt.Errorf("invalid -L %q: end must be a line number not before start", spec)
+		}
+		return start, end, nil
+	}
+}
+
+// printLineBlame prints one line per blamed line, as in git blame:
+//
+//	<hash> [<orig path>] (<author> <date> <line>) <content>
+//
+// The original path column appears only when some line came from another
+// file. With color set, commit hashes are shown in yellow.
+func printLineBlame(out io.Writer, path string, lines []repo.LineBlame, color bool) {
+	authorWidth, lineWidth, pathWidth := 0, len(strconv.Itoa(lastBlameLine(lines))), 0
+	showPath := false
+	for _, l := range lines {
+		authorWidth = max(authorWidth, len(l.Author))
+		pathWidth = max(pathWidth, len(l.OrigPath))
+		showPath = showPath || l.OrigPath != path || l.OrigPath != lines[0].OrigPath
+	}
+
+	for _, l := range lines {
+		origin := ""
+		if showPath {
+			origin = fmt.Sprintf(" %-*s", pathWidth, l.OrigPath)
+		}
+		date := time.Unix(l.Timestamp, 0).Format("2006-01-02")
+		fmt.Fprintf(out, "%s%s (%-*s %s %*d) %s\n", paint(color, ansiYellow, shortHash(l.CommitHash)), origin, authorWidth, l.Author, date, lineWidth, l.Line, l.Content)
+	}
+}
+
+func lastBlameLine(lines []repo.LineBlame) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	return lines[len(lines)-1].Line
+}
+
+func lineBlameJSON(path string, lines []repo.LineBlame) JSONLineBlameOutput {
+	result := JSONLineBlameOutput{Path: path, Lines: make([]JSONLineBlame, len(lines))}
+	for i, l := range lines {
+		result.Lines[i] = JSONLineBlame{
+			Line:       l.Line,
+			CommitHash: string(l.CommitHash),
+			Author:     l.Author,
+			Timestamp:  l.Timestamp,
+			OrigPath:   l.OrigPath,
+			OrigLine:   l.OrigLine,
+			Content:    l.Content,
+		}
+	}
+	return result
+}
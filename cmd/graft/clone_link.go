@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cloneLinkStats counts how object files were materialized by a local clone.
+type cloneLinkStats struct {
+	Linked    int
+	Cloned    int
+	Copied    int
+	Skipped   int
+	hardlinks bool
+}
+
+// isImmutableObjectFile reports whether rel (relative to .graft/) names a
+// loose object or pack file. These are content-addressed and only ever
+// replaced via rename, so sharing them between repositories is safe.
+func isImmutableObjectFile(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "objects/") {
+		return false
+	}
+	rest := strings.TrimPrefix(rel, "objects/")
+	if strings.HasPrefix(rest, "pack/") {
+		name := strings.TrimPrefix(rest, "pack/")
+		if strings.HasPrefix(name, ".") || strings.Contains(name, "/") {
+			return false
+		}
+		return strings.HasSuffix(name, ".pack") || strings.HasSuffix(name, ".idx")
+	}
+	dir, name, ok := strings.Cut(rest, "/")
+	return ok && len(dir) == 2 && !strings.HasPrefix(name, ".") && !strings.Contains(name, "/")
+}
+
+// materializeObjectFile places an object file at dst, preferring a hardlink,
+// then a copy-on-write reflink, and finally a byte copy.
+func (s *cloneLinkStats) materializeObjectFile(src, dst string, perm os.FileMode) error {
+	if s.hardlinks {
+		if err := os.Link(src, dst); err == nil {
+			s.Linked++
+			return nil
+		}
+	}
+	if err := reflinkFile(src, dst, perm); err == nil {
+		s.Cloned++
+		return nil
+	}
+	s.Copied++
+	return copyFile(src, dst, perm)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestIsImmutableObjectFile(t *testing.T) {
+	cases := map[string]bool{
+		"objects/ab/cdef0123":         true,
+		"objects/pack/pack-1234.pack": true,
+		"objects/pack/pack-1234.idx":  true,
+		"objects/pack/pack-1234.keep": false,
+		"objects/pack/.tmp-pack-1":    false,
+		"objects/ab/.tmp-obj":         false,
+		"objects/info/alternates":     false,
+		"refs/heads/main":             false,
+		"HEAD":                        false,
+		"objects/abc/def":             false,
+	}
+	for rel, want := range cases {
+		if got := isImmutableObjectFile(rel); got != want {
+			t.Errorf("isImmutableObjectFile(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// reflinkFile is unsupported on this platform; callers fall back to copying.
+func reflinkFile(src, dst string, perm os.FileMode) error {
+	return errors.ErrUnsupported
+}
@@ -22,12 +22,18 @@ func newAddCmd() *cobra.Command {
 	var forceCoord bool
 	var stdin bool
 	var stdin0 bool
+	var update bool
 
 	cmd := &cobra.Command{
 		Use:   "add <files...>",
 		Short: "Stage files for the next commit",
+		Long: `Stage files for the next commit.
+
+With -u/--update, only files that are already tracked are staged: modified
+files are restaged and deleted files are removed from the index, while
+untracked files are left alone. Without paths, -u covers the whole tree.`,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 && !stdin && !stdin0 {
+			if len(args) == 0 && !stdin && !stdin0 && !update {
 				return fmt.Errorf("requires at least 1 arg(s), only received 0")
 			}
 			return nil
@@ -60,6 +66,7 @@ func newAddCmd() *cobra.Command {
 			opts := repo.AddOptions{
 				SkipEntities:  skipEntities,
 				ForceEntities: forceEntities,
+				Update:        update,
 			}
 
 			if quiet {
@@ -119,6 +126,7 @@ func newAddCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&forceCoord, "force", false, "override coordination soft blocks during staging")
 	cmd.Flags().BoolVar(&stdin, "stdin", false, "read file paths from stdin (one per line)")
 	cmd.Flags().BoolVar(&stdin0, "stdin0", false, "read file paths from stdin, null-separated (for git ls-files -z)")
+	cmd.Flags().BoolVarP(&update, "update", "u", false, "stage modifications and deletions of tracked files only")
 	return cmd
 }
 
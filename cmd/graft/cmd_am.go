@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newAMCmd() *cobra.Command {
+	var threeWay bool
+	var continueFlag, abortFlag, skipFlag bool
+
+	cmd := &cobra.Command{
+		Use:   "am [--3way] [--continue | --skip | --abort] [<mbox>...]",
+		Short: "Apply mailbox patches as commits",
+		Long: `Am applies the patches in mailboxes written by format-patch, from the named
+files or stdin, committing each with the author, date and message it
+records. The current user is recorded as committer.
+
+When a patch does not apply, am stops. Apply the change by hand or resolve
+the conflicts, stage the result and run 'graft am --continue'; or run
+--skip to drop the patch, or --abort to return to where am started. With
+--3way, a patch that does not apply cleanly is merged three-way against the
+preimage blobs it records.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flagCount := 0
+			for _, set := range []bool{continueFlag, abortFlag, skipFlag} {
+				if set {
+					flagCount++
+				}
+			}
+			if flagCount > 1 {
+				return fmt.Errorf("am: only one of --continue, --abort, or --skip may be specified")
+			}
+			if flagCount == 1 && len(args) > 0 {
+				return fmt.Errorf("am: --continue, --abort and --skip take no arguments")
+			}
+
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+
+			var result *repo.AMResult
+			switch {
+			case abortFlag:
+				if err := r.AMAbort(); err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "am aborted")
+				return nil
+			case continueFlag:
+				result, err = r.AMContinue()
+			case skipFlag:
+				result, err = r.AMSkip()
+			default:
+				patches, readErr := readPatchArgs(cmd, args)
+				if readErr != nil {
+					return fmt.Errorf("am: %w", readErr)
+				}
+				result, err = r.AM(patches, repo.AMOptions{ThreeWay: threeWay})
+			}
+			if result != nil {
+				for _, p := range result.Patches {
+					fmt.Fprintf(cmd.OutOrStdout(), "Applying: %s\n", p.Subject)
+				}
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&threeWay, "3way", false, "fall back to a three-way merge when a patch does not apply")
+	cmd.Flags().BoolVar(&continueFlag, "continue", false, "commit the resolved patch and apply the rest")
+	cmd.Flags().BoolVar(&abortFlag, "abort", false, "abort the am session and restore the original branch")
+	cmd.Flags().BoolVar(&skipFlag, "skip", false, "skip the current patch")
+
+	return cmd
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatPatchAndAMIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	src := initRepo(t)
+	dst := initRepo(t)
+	for _, dir := range []string{src, dst} {
+		commitFile(t, dir, "a.txt", "one\ntwo\nthree\n", "base")
+	}
+	commitFile(t, src, "a.txt", "one\nTWO\nthree\n", "Shout two")
+	commitFile(t, src, "b.txt", "new\n", "Add b")
+
+	outDir := t.TempDir()
+	files := nonEmptyLines(mustRunGraft(t, src, "format-patch", "-o", outDir, "HEAD~2"))
+	want := []string{
+		filepath.Join(outDir, "0001-shout-two.patch"),
+		filepath.Join(outDir, "0002-add-b.patch"),
+	}
+	if strings.Join(files, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("format-patch wrote %q, want %q", files, want)
+	}
+	first, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(first), "Subject: [PATCH 1/2] Shout two\n") {
+		t.Fatalf("first patch has no numbered subject:\n%s", first)
+	}
+
+	out := mustRunGraft(t, dst, "am", files[0], files[1])
+	if !strings.Contains(out, "Applying: Shout two") || !strings.Contains(out, "Applying: Add b") {
+		t.Fatalf("am output = %q", out)
+	}
+	subjects := nonEmptyLines(mustRunGraft(t, dst, "log", "--format=%s"))
+	if strings.Join(subjects, "|") != "Add b|Shout two|base" {
+		t.Fatalf("log after am = %q", subjects)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "one\nTWO\nthree\n" {
+		t.Fatalf("a.txt after am = %q", data)
+	}
+	if status := strings.TrimSpace(mustRunGraft(t, dst, "status", "--porcelain")); status != "" {
+		t.Fatalf("status after am = %q, want clean", status)
+	}
+}
+
+func TestApplyThreeWayIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "one\ntwo\nthree\nfour\nfive\n", "base")
+	commitFile(t, dir, "a.txt", "one\ntwo\nthree\nfour\nFIVE\n", "Shout five")
+	patch := filepath.Join(t.TempDir(), "five.patch")
+	writeFile(t, filepath.Dir(patch), filepath.Base(patch), mustRunGraft(t, dir, "format-patch", "--stdout", "HEAD~1"))
+
+	mustRunGraft(t, dir, "reset", "--hard", "HEAD~1")
+	writeFile(t, dir, "a.txt", "one\ntwo\nthree\nFOUR\nfive\n")
+	if _, err := runGraft(t, dir, "apply", patch); err == nil {
+		t.Fatal("apply succeeded although a context line changed")
+	}
+	out := mustRunGraft(t, dir, "apply", "--3way", patch)
+	if !strings.Contains(out, "applied a.txt with a three-way merge") {
+		t.Fatalf("apply --3way output = %q", out)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "one\ntwo\nthree\nFOUR\nFIVE\n" {
+		t.Fatalf("a.txt after apply --3way = %q", data)
+	}
+}
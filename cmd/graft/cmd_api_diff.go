@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newAPIDiffCmd() *cobra.Command {
+	var jsonFlag, breakingOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "api-diff <rev-a> [<rev-b>]",
+		Short: "Report public API declarations removed or changed between revisions",
+		Long: `Api-diff compares the public declarations of two revisions (rev-b defaults
+to HEAD) by their extracted signatures and lists, per package, those that
+were removed, changed or added. Declarations moved between files of the
+same package are not reported.
+
+Removed and changed declarations break callers: when there are any,
+api-diff exits with status 1, so it can gate a release in CI. Additions
+are listed but never fail the check; --breaking hides them.
+
+What counts as public follows each language: capitalized names in Go
+packages other than main and internal ones, names without a leading
+underscore in Python, exported declarations in JavaScript and TypeScript,
+pub items in Rust, public members in Java, C#, Kotlin, Swift and PHP, and
+all declarations in C and C++ headers. Test files are skipped.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			newRev := "HEAD"
+			if len(args) == 2 {
+				newRev = args[1]
+			}
+			report, err := r.APIDiff(args[0], newRev)
+			if err != nil {
+				return err
+			}
+			if breakingOnly {
+				kept := report.Changes[:0]
+				for _, c := range report.Changes {
+					if c.Breaking() {
+						kept = append(kept, c)
+					}
+				}
+				report.Changes = kept
+			}
+
+			out := cmd.OutOrStdout()
+			if jsonFlag {
+				if err := writeJSON(out, apiDiffJSON(report)); err != nil {
+					return err
+				}
+			} else {
+				printAPIDiff(out, report, colorEnabled(cmd))
+			}
+			if n := report.BreakingCount(); n > 0 {
+				// A failed check is not a usage error.
+				cmd.SilenceUsage = true
+				return fmt.Errorf("api-diff: %s", pluralize(n, "breaking change"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output as JSON")
+	cmd.Flags().BoolVar(&breakingOnly, "breaking", false, "list only removed and changed declarations")
+	return cmd
+}
+
+func printAPIDiff(w io.Writer, report *repo.APIDiffReport, color bool) {
+	if len(report.Changes) == 0 {
+		fmt.Fprintln(w, "no public API changes")
+		return
+	}
+	pkg := ""
+	for i, c := range report.Changes {
+		if i == 0 || c.Package != pkg {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			pkg = c.Package
+			fmt.Fprintf(w, "package %s\n", paint(color, ansiBold, pkg))
+		}
+		switch c.Kind {
+		case repo.APIRemoved:
+			fmt.Fprintf(w, "  %s  %s  (%s)\n", paint(color, ansiRed, "removed"), c.OldSignature, c.Path)
+		case repo.APIChanged:
+			fmt.Fprintf(w, "  %s  %s  (%s)\n", paint(color, ansiYellow, "changed"), c.OldSignature, c.Path)
+			fmt.Fprintf(w, "       to  %s\n", c.NewSignature)
+		default:
+			fmt.Fprintf(w, "  %s    %s  (%s)\n", paint(color, ansiGreen, "added"), c.NewSignature, c.Path)
+		}
+	}
+}
+
+func apiDiffJSON(report *repo.APIDiffReport) JSONAPIDiffOutput {
+	out := JSONAPIDiffOutput{
+		OldCommit: string(report.OldCommit),
+		NewCommit: string(report.NewCommit),
+		Breaking:  report.BreakingCount(),
+		Changes:   make([]JSONAPIChange, 0, len(report.Changes)),
+	}
+	for _, c := range report.Changes {
+		out.Changes = append(out.Changes, JSONAPIChange{
+			Package:      c.Package,
+			Path:         c.Path,
+			Name:         c.Name,
+			Kind:         c.Kind,
+			Breaking:     c.Breaking(),
+			OldSignature: c.OldSignature,
+			NewSignature: c.NewSignature,
+		})
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAPIDiffIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	dir := initRepo(t)
+	commitFile(t, dir, "lib/lib.go", "package lib\n\nfunc Open(path string) error { return nil }\n", "v1")
+	mustRunGraft(t, dir, "tag", "v1")
+
+	commitFile(t, dir, "lib/lib.go", "package lib\n\nfunc Open(path string) error { return nil }\n\nfunc Dial() {}\n", "add dial")
+	if out := mustRunGraft(t, dir, "api-diff", "v1"); !strings.Contains(out, "added    func Dial()  (lib/lib.go)") {
+		t.Fatalf("api-diff after an addition = %q, want Dial added", out)
+	}
+	mustRunGraft(t, dir, "tag", "v2")
+
+	commitFile(t, dir, "lib/lib.go", "package lib\n\nfunc Open(path string, mode int) error { return nil }\n", "break")
+	out, err := runGraft(t, dir, "api-diff", "v2", "HEAD")
+	if err == nil {
+		t.Fatalf("api-diff with breaking changes succeeded:\n%s", out)
+	}
+	for _, want := range []string{
+		"package lib",
+		"removed  func Dial()  (lib/lib.go)",
+		"changed  func Open(path string) error  (lib/lib.go)",
+		"to  func Open(path string, mode int) error",
+		"2 breaking changes",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("api-diff output missing %q:\n%s", want, out)
+		}
+	}
+
+	jsonOut, _ := runGraft(t, dir, "api-diff", "--json", "--breaking", "v1")
+	var got JSONAPIDiffOutput
+	if err := json.Unmarshal([]byte(jsonOut[:strings.LastIndex(jsonOut, "}")+1]), &got); err != nil {
+		t.Fatalf("api-diff --json: %v\n%s", err, jsonOut)
+	}
+	if got.Breaking != 1 || len(got.Changes) != 1 || got.Changes[0].Name != "func Open" || !got.Changes[0].Breaking {
+		t.Fatalf("api-diff --json --breaking v1 = %+v, want only Open changed", got)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newApplyCmd() *cobra.Command {
+	var index, threeWay bool
+
+	cmd := &cobra.Command{
+		Use:   "apply [--index] [--3way] [<patch>...]",
+		Short: "Apply patches to the working tree",
+		Long: `Apply reads patches written by format-patch, or plain unified diffs, from
+the named files or stdin and applies them to the working tree. A patch
+applies in full or not at all.
+
+With --index the result is staged as well. With --3way, a file whose hunks
+do not apply is merged three-way against the preimage blob recorded in the
+patch, when that blob is in the object store; conflicts are left in the
+file with markers and recorded in the staging area. --3way implies --index.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			patches, err := readPatchArgs(cmd, args)
+			if err != nil {
+				return fmt.Errorf("apply: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			for _, p := range patches {
+				result, err := r.ApplyPatch(p, repo.ApplyOptions{Index: index, ThreeWay: threeWay})
+				var conflictErr *repo.ErrApplyConflict
+				if err != nil && !errors.As(err, &conflictErr) {
+					return err
+				}
+				for _, path := range result.Merged {
+					fmt.Fprintf(out, "applied %s with a three-way merge\n", path)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&index, "index", false, "stage the patched files")
+	cmd.Flags().BoolVar(&threeWay, "3way", false, "fall back to a three-way merge when a patch does not apply")
+
+	return cmd
+}
+
+// readPatchArgs parses the patches in the named files, or on stdin when
+// there are none or the name is "-".
+func readPatchArgs(cmd *cobra.Command, args []string) ([]*repo.Patch, error) {
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	var patches []*repo.Patch
+	for _, name := range args {
+		var data []byte
+		var err error
+		if name == "-" {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := repo.ParsePatches(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		patches = append(patches, parsed...)
+	}
+	return patches, nil
+}
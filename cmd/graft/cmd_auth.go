@@ -460,7 +460,7 @@ func newAuthLogoutCmd() *cobra.Command {
 				return err
 			}
 			profile := cfg.OrchardProfile(baseURL)
-			profile.Token = ""
+			profile.Token, profile.RefreshToken, profile.TokenExpiresAt = "", "", ""
 			cfg.SetOrchardProfile(baseURL, profile)
 			if strings.TrimSpace(cfg.DefaultOrchardURL()) == strings.TrimSpace(baseURL) {
 				cfg.Token = ""
@@ -566,7 +566,7 @@ func clearAllStoredAuthTokens(cfg *userconfig.Config) {
 	cfg.Token = ""
 	for _, host := range cfg.OrchardProfileHosts() {
 		profile := cfg.OrchardProfile(host)
-		profile.Token = ""
+		profile.Token, profile.RefreshToken, profile.TokenExpiresAt = "", "", ""
 		cfg.SetOrchardProfile(host, profile)
 	}
 }
@@ -732,7 +732,11 @@ func registerSSHKey(cmd *cobra.Command, baseURL, token, name, publicKey string)
 func writeAuthConfig(baseURL, token string, user authUser) error {
 	cfg := loadUserConfig()
 	profile := cfg.OrchardProfile(baseURL)
-	profile.Token = strings.TrimSpace(token)
+	if token = strings.TrimSpace(token); token != profile.Token {
+		// A token from another sign-in method cannot be refreshed.
+		profile.RefreshToken, profile.TokenExpiresAt = "", ""
+	}
+	profile.Token = token
 	if username := strings.TrimSpace(user.Username); username != "" {
 		profile.Username = username
 		if strings.TrimSpace(profile.Owner) == "" {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -182,14 +183,38 @@ func newBisectLogCmd() *cobra.Command {
 	}
 }
 
+// Exit codes of a bisect run command with a meaning of their own: 125 skips
+// the commit, and 126 and 127 (the command could not be run) or 128 and up
+// (it was killed by a signal) stop the run.
+const (
+	bisectRunSkipCode  = 125
+	bisectRunAbortCode = 126
+)
+
+// bisectVerdict is how bisect run classifies a tested commit.
+type bisectVerdict int
+
+const (
+	bisectVerdictGood bisectVerdict = iota
+	bisectVerdictBad
+	bisectVerdictSkip
+)
+
 func newBisectRunCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "run <script>",
-		Short: "Automated bisect using a script (exit 0 = good, non-zero = bad)",
-		Args:  cobra.ExactArgs(1),
+		Use:   "run <cmd> [<args>...]",
+		Short: "Automated bisect using a command (exit 0 = good, 125 = skip, other = bad)",
+		Long: `Run drives a bisect session started with bisect start to the end unattended.
+It runs the command on each commit bisect checks out and marks the commit by
+the exit code: 0 is good, 125 skips the commit, and any other code below 126
+is bad. Exit codes 126 and 127, which mean the command could not be run, and
+128 or above, which mean it was killed, stop the run and leave the session as
+it is.
+
+A single argument is run by sh -c, so it may be a shell snippet; with more
+arguments the first is run directly with the rest as its arguments.`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			script := args[0]
-
 			r, err := repo.Open(".")
 			if err != nil {
 				return err
@@ -199,27 +224,37 @@ func newBisectRunCmd() *cobra.Command {
 				return fmt.Errorf("bisect run: not bisecting (use bisect start first)")
 			}
 
+			path, cmdArgs := "sh", []string{"-c", args[0]}
+			if len(args) > 1 {
+				path, cmdArgs = args[0], args[1:]
+			}
 			out := cmd.OutOrStdout()
 
 			for {
-				// Run the user script.
+				fmt.Fprintf(out, "running %s\n", strings.Join(args, " "))
 				runErr := repo.RunExternalProcess(repo.ExternalProcessSpec{
 					Context: cmd.Context(),
 					Dir:     r.RootDir,
-					Path:    "sh",
-					Args:    []string{"-c", script},
-					Stdout:  cmd.OutOrStdout(),
+					Path:    path,
+					Args:    cmdArgs,
+					Stdout:  out,
 					Stderr:  cmd.ErrOrStderr(),
 					Label:   "bisect-run",
 				})
+				verdict, err := bisectRunVerdict(runErr)
+				if err != nil {
+					cmd.SilenceUsage = true
+					return fmt.Errorf("bisect run: %w", err)
+				}
 
 				var result *repo.BisectResult
-				if runErr == nil {
-					// Exit 0 means good.
+				switch verdict {
+				case bisectVerdictGood:
 					result, err = r.BisectGood()
-				} else {
-					// Non-zero exit means bad.
+				case bisectVerdictBad:
 					result, err = r.BisectBad()
+				case bisectVerdictSkip:
+					result, err = r.BisectSkip()
 				}
 				if err != nil {
 					return err
@@ -235,8 +270,36 @@ func newBisectRunCmd() *cobra.Command {
 	}
 }
 
+// bisectRunVerdict classifies the outcome of one bisect run command by its
+// exit code, or returns an error when the run has to stop.
+func bisectRunVerdict(runErr error) (bisectVerdict, error) {
+	if runErr == nil {
+		return bisectVerdictGood, nil
+	}
+	var exitCoder interface{ ExitCode() int }
+	if !errors.As(runErr, &exitCoder) || exitCoder.ExitCode() < 0 {
+		return 0, runErr
+	}
+	switch code := exitCoder.ExitCode(); {
+	case code == bisectRunSkipCode:
+		return bisectVerdictSkip, nil
+	case code >= bisectRunAbortCode:
+		return 0, fmt.Errorf("command exited with status %d, stopping", code)
+	default:
+		return bisectVerdictBad, nil
+	}
+}
+
 // printBisectResult prints the result of a bisect step.
 func printBisectResult(out io.Writer, result *repo.BisectResult) {
+	if result.Done && len(result.Suspects) > 0 {
+		fmt.Fprintln(out, "There are only skipped commits left to test.")
+		fmt.Fprintln(out, "The first bad commit could be any of:")
+		for _, h := range result.Suspects {
+			fmt.Fprintln(out, h)
+		}
+		return
+	}
 	if result.Done {
 		fmt.Fprintf(out, "%s is the first bad commit\n%s\n", result.FirstBad, result.Message)
 		return
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBisectRunIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	contents := []string{"ok 0", "ok 1", "ok 2", "ok 3", "skip 4", "ok 5", "bug 6", "bug 7"}
+	var hashes []string
+	for i, content := range contents {
+		commitFile(t, dir, "f.txt", content+"\n", fmt.Sprintf("commit %d", i))
+		hashes = append(hashes, strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD")))
+	}
+	mustRunGraft(t, dir, "bisect", "start", hashes[7], hashes[0])
+
+	script := "if grep -q skip f.txt; then exit 125; fi; ! grep -q bug f.txt"
+	out := mustRunGraft(t, dir, "bisect", "run", script)
+	if !strings.Contains(out, hashes[6]+" is the first bad commit") {
+		t.Fatalf("bisect run did not find commit 6 (%s):\n%s", hashes[6], out)
+	}
+	if log := mustRunGraft(t, dir, "bisect", "log"); !strings.Contains(log, "# skip: "+hashes[4]) {
+		t.Fatalf("bisect run should have skipped commit 4 on exit 125:\n%s", log)
+	}
+	mustRunGraft(t, dir, "bisect", "reset")
+
+	// Exit codes of 126 and up stop the run and leave the session alone.
+	mustRunGraft(t, dir, "bisect", "start", hashes[7], hashes[0])
+	out, err := runGraft(t, dir, "bisect", "run", "exit 127")
+	if err == nil || !strings.Contains(out, "status 127") {
+		t.Fatalf("bisect run with exit 127 = %v, want a stop:\n%s", err, out)
+	}
+	if !strings.Contains(mustRunGraft(t, dir, "bisect", "log"), "# bad:") {
+		t.Fatal("bisect session should still be in progress after an aborted run")
+	}
+}
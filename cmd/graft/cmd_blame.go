@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/odvcencio/graft/pkg/coord"
@@ -15,11 +16,27 @@ func newBlameCmd() *cobra.Command {
 	var limit int
 	var jsonFlag bool
 	var coordFlag bool
+	var lineRange string
+	var ignoreWhitespace, detectCopies bool
 
 	cmd := &cobra.Command{
-		Use:   "blame [<path>]",
+		Use:   "blame [-L <start>,<end>] [-w] [-C] [<path>]",
 		Short: "Show entity-level attribution and coordination history",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Blame attributes each declaration in a file, or the one entity named by
+--entity, to the commit that last changed it.
+
+With -L, -w or -C, blame attributes lines instead:
+
+  -L <start>,<end>   only lines start through end; <start>,+<count> and
+                     <start> (to the end of the file) also work
+  -w                 ignore whitespace-only changes, such as reindenting
+  -C                 follow lines copied or moved from another file that
+                     was changed in the same commit
+
+Authors are shown as the .mailmap file at the root of the working tree
+maps them.`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeTrackedPaths,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if limit <= 0 {
 				return fmt.Errorf("--limit must be greater than 0")
@@ -44,6 +61,42 @@ func newBlameCmd() *cobra.Command {
 				return blameCoord(cmd, r, filePath, entitySelector, jsonFlag)
 			}
 
+			mailmap, err := r.Mailmap()
+			if err != nil {
+				return err
+			}
+
+			if lineRange != "" || ignoreWhitespace || detectCopies {
+				if entitySelector != "" || coordFlag {
+					return fmt.Errorf("-L, -w and -C cannot be combined with --entity or --coord")
+				}
+				if len(args) == 0 {
+					return fmt.Errorf("line blame requires a file path argument")
+				}
+				opts := repo.BlameLinesOptions{
+					IgnoreWhitespace: ignoreWhitespace,
+					DetectCopies:     detectCopies,
+					Limit:            limit,
+				}
+				if lineRange != "" {
+					if opts.Start, opts.End, err = parseBlameLineRange(lineRange); err != nil {
+						return err
+					}
+				}
+				lines, err := r.BlameLines(args[0], opts)
+				if err != nil {
+					return err
+				}
+				for i := range lines {
+					lines[i].Author = mailmap.Map(lines[i].Author)
+				}
+				if jsonFlag {
+					return writeJSON(cmd.OutOrStdout(), lineBlameJSON(args[0], lines))
+				}
+				printLineBlame(cmd.OutOrStdout(), filepath.ToSlash(filepath.Clean(args[0])), lines, colorEnabled(cmd))
+				return nil
+			}
+
 			if entitySelector != "" && len(args) > 0 {
 				return fmt.Errorf("--entity and positional path argument are mutually exclusive")
 			}
@@ -54,6 +107,7 @@ func newBlameCmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
+				result.Author = mailmap.Map(result.Author)
 
 				if jsonFlag {
 					return writeJSON(cmd.OutOrStdout(), JSONBlameOutput{
@@ -79,6 +133,9 @@ func newBlameCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			for i := range results {
+				results[i].Author = mailmap.Map(results[i].Author)
+			}
 
 			if jsonFlag {
 				entities := make([]JSONBlameOutput, len(results))
@@ -108,6 +165,9 @@ func newBlameCmd() *cobra.Command {
 	cmd.Flags().IntVar(&limit, "limit", 200, "maximum number of commits to scan")
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
 	cmd.Flags().BoolVar(&coordFlag, "coord", false, "show coordination claims and feed history for a file")
+	cmd.Flags().StringVarP(&lineRange, "lines", "L", "", "blame only lines <start>,<end> or <start>,+<count>")
+	cmd.Flags().BoolVarP(&ignoreWhitespace, "ignore-whitespace", "w", false, "ignore whitespace-only changes when attributing lines")
+	cmd.Flags().BoolVarP(&detectCopies, "copies", "C", false, "follow lines copied or moved from other files changed in the same commit")
 
 	return cmd
 }
@@ -85,6 +85,83 @@ func TestBlameCmd_EntityNotFound(t *testing.T) {
 	}
 }
 
+func TestBlameCmd_LineRangeOutput(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	writeCmdBlameFile(t, filepath.Join(dir, "notes.txt"), []byte("one\ntwo\nthree\n"))
+	if err := r.Add([]string{"notes.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	first, err := r.Commit("initial", "alice")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	writeCmdBlameFile(t, filepath.Join(dir, "notes.txt"), []byte("one\n  two\nTHREE\n"))
+	if err := r.Add([]string{"notes.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	second, err := r.Commit("edit", "bob")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	var out bytes.Buffer
+	cmd := newBlameCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"-L", "2,+2", "-w", "notes.txt"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("output = %q, want 2 lines", out.String())
+	}
+	if !strings.HasPrefix(lines[0], shortHash(first)+" (alice ") || !strings.HasSuffix(lines[0], " 2)   two") {
+		t.Errorf("line 2 = %q, want it blamed on alice with -w", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], shortHash(second)+" (bob   ") || !strings.HasSuffix(lines[1], " 3) THREE") {
+		t.Errorf("line 3 = %q, want it blamed on bob", lines[1])
+	}
+}
+
+func TestParseBlameLineRange(t *testing.T) {
+	tests := []struct {
+		spec       string
+		start, end int
+		wantErr    bool
+	}{
+		{spec: "3,7", start: 3, end: 7},
+		{spec: "3,+2", start: 3, end: 4},
+		{spec: "5", start: 5},
+		{spec: "5,", start: 5},
+		{spec: "0,3", wantErr: true},
+		{spec: "7,3", wantErr: true},
+		{spec: "2,+0", wantErr: true},
+		{spec: "x", wantErr: true},
+	}
+	for _, tt := range tests {
+		start, end, err := parseBlameLineRange(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseBlameLineRange(%q) succeeded, want error", tt.spec)
+			}
+			continue
+		}
+		if err != nil || start != tt.start || end != tt.end {
+			t.Errorf("parseBlameLineRange(%q) = %d, %d, %v, want %d, %d", tt.spec, start, end, err, tt.start, tt.end)
+		}
+	}
+}
+
 func writeCmdBlameFile(t *testing.T, path string, content []byte) {
 	t.Helper()
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/odvcencio/graft/pkg/repo"
 	"github.com/spf13/cobra"
@@ -9,22 +11,31 @@ import (
 
 func newBranchCmd() *cobra.Command {
 	var deleteBranch string
+	var verbose bool
 
 	cmd := &cobra.Command{
-		Use:   "branch [name]",
+		Use:   "branch [-v] [name]",
 		Short: "List, create, or delete branches",
 		Args:  cobra.MaximumNArgs(1),
+		// A new branch name has nothing to complete.
+		ValidArgsFunction: cobra.NoFileCompletions,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
 				return err
 			}
 
+			jsonOut := jsonOutput(cmd)
+
 			// Delete mode.
 			if deleteBranch != "" {
+				tip, _ := r.ResolveRef("refs/heads/" + deleteBranch)
 				if err := r.DeleteBranch(deleteBranch); err != nil {
 					return err
 				}
+				if jsonOut {
+					return writeJSON(cmd.OutOrStdout(), JSONRefAction{Action: "deleted", Name: deleteBranch, Target: string(tip)})
+				}
 				fmt.Fprintf(cmd.OutOrStdout(), "deleted branch '%s'\n", deleteBranch)
 				return nil
 			}
@@ -38,6 +49,9 @@ func newBranchCmd() *cobra.Command {
 				if err := r.CreateBranch(args[0], head); err != nil {
 					return err
 				}
+				if jsonOut {
+					return writeJSON(cmd.OutOrStdout(), JSONRefAction{Action: "created", Name: args[0], Target: string(head)})
+				}
 				return nil
 			}
 
@@ -50,6 +64,12 @@ func newBranchCmd() *cobra.Command {
 			current, _ := r.CurrentBranch()
 
 			out := cmd.OutOrStdout()
+			if jsonOut {
+				return listBranchesJSON(out, r, branches, current)
+			}
+			if verbose {
+				return listBranchesVerbose(out, r, branches, current)
+			}
 			for _, b := range branches {
 				if b == current {
 					fmt.Fprintf(out, "* %s\n", b)
@@ -62,6 +82,84 @@ func newBranchCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&deleteBranch, "delete", "d", "", "delete the named branch")
+	_ = cmd.RegisterFlagCompletionFunc("delete", completeBranches(-1))
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show each branch's tip commit and ahead/behind counts against its upstream")
 
 	return cmd
 }
+
+// listBranchesVerbose prints each branch with its tip commit and, for
+// branches with an upstream tracking ref, how far ahead or behind it is,
+// e.g. "main 1a2b3c4d [origin/main: ahead 1, behind 2] subject".
+func listBranchesVerbose(out io.Writer, r *repo.Repo, branches []string, current string) error {
+	width := 0
+	for _, b := range branches {
+		width = max(width, len(b))
+	}
+	for _, b := range branches {
+		marker := "  "
+		if b == current {
+			marker = "* "
+		}
+		h, err := r.ResolveRef("refs/heads/" + b)
+		if err != nil {
+			return err
+		}
+		commit, err := r.Store.ReadCommit(h)
+		if err != nil {
+			return fmt.Errorf("read commit %s: %w", h, err)
+		}
+		subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+
+		tracking, err := r.BranchTracking(b)
+		if err != nil {
+			return err
+		}
+		upstream := ""
+		if tracking != nil {
+			var counts []string
+			if tracking.Ahead > 0 {
+				counts = append(counts, fmt.Sprintf("ahead %d", tracking.Ahead))
+			}
+			if tracking.Behind > 0 {
+				counts = append(counts, fmt.Sprintf("behind %d", tracking.Behind))
+			}
+			if len(counts) > 0 {
+				upstream = fmt.Sprintf("[%s: %s] ", tracking.Name, strings.Join(counts, ", "))
+			} else {
+				upstream = fmt.Sprintf("[%s] ", tracking.Name)
+			}
+		}
+		fmt.Fprintf(out, "%s%-*s %s %s%s\n", marker, width, b, shortHash(h), upstream, subject)
+	}
+	return nil
+}
+
+// listBranchesJSON writes the branches with their tips and upstream
+// tracking state as JSON.
+func listBranchesJSON(out io.Writer, r *repo.Repo, branches []string, current string) error {
+	result := JSONBranchOutput{Current: current, Branches: make([]JSONBranch, 0, len(branches))}
+	for _, b := range branches {
+		h, err := r.ResolveRef("refs/heads/" + b)
+		if err != nil {
+			return err
+		}
+		commit, err := r.Store.ReadCommit(h)
+		if err != nil {
+			return fmt.Errorf("read commit %s: %w", h, err)
+		}
+		subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+		entry := JSONBranch{Name: b, Current: b == current, Commit: string(h), Subject: subject}
+		tracking, err := r.BranchTracking(b)
+		if err != nil {
+			return err
+		}
+		if tracking != nil {
+			entry.Upstream = tracking.Name
+			entry.Ahead = tracking.Ahead
+			entry.Behind = tracking.Behind
+		}
+		result.Branches = append(result.Branches, entry)
+	}
+	return writeJSON(out, result)
+}
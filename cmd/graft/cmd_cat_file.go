@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newCatFileCmd() *cobra.Command {
+	var showType, showSize, pretty, batch bool
+
+	cmd := &cobra.Command{
+		Use:   "cat-file (-t | -s | -p) <object> | --batch",
+		Short: "Print the type, size or content of an object",
+		Long: `Cat-file prints information about an object in the object store.
+The object is a full hash or any revision rev-parse accepts, such as
+HEAD, main~2 or HEAD:path/to/file.
+
+  -t   print the object type: blob, tree, commit, tag, entity,
+       entitylist or chunk
+  -s   print the size of the stored content in bytes
+  -p   pretty-print the content: file data for blobs, one line per entry
+       for trees, headers and message for commits, the header and body
+       of an entity, and the entities of an entity list
+
+Tree entries print as "<mode> <type> <hash>\t<name>"; a file with extracted
+entities also shows its entity list hash after the blob hash.
+
+With --batch, object names are read from stdin, one per line, and each is
+printed as "<hash> <type> <size>" followed by its stored content and a
+newline, or as "<name> missing" if it does not resolve.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			modes := 0
+			for _, set := range []bool{showType, showSize, pretty, batch} {
+				if set {
+					modes++
+				}
+			}
+			if modes != 1 {
+				return fmt.Errorf("cat-file: exactly one of -t, -s, -p or --batch is required")
+			}
+			if batch {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if batch {
+				return catFileBatch(r, cmd.InOrStdin(), out)
+			}
+
+			h, err := resolveObject(r, args[0])
+			if err != nil {
+				return fmt.Errorf("cat-file: %w", err)
+			}
+			objType, data, err := r.Store.Read(h)
+			if err != nil {
+				return fmt.Errorf("cat-file: read %s: %w", h, err)
+			}
+			switch {
+			case showType:
+				fmt.Fprintln(out, objType)
+			case showSize:
+				fmt.Fprintln(out, len(data))
+			default:
+				if err := prettyPrintObject(out, r, objType, data); err != nil {
+					return fmt.Errorf("cat-file: %s: %w", h, err)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&showType, "type", "t", false, "print the object type")
+	cmd.Flags().BoolVarP(&showSize, "size", "s", false, "print the object size in bytes")
+	cmd.Flags().BoolVarP(&pretty, "pretty", "p", false, "pretty-print the object content")
+	cmd.Flags().BoolVar(&batch, "batch", false, "print type, size and content of objects named on stdin")
+	return cmd
+}
+
+// resolveObject resolves name to an object of any type: a full hash of a
+// stored object, or a revision.
+func resolveObject(r *repo.Repo, name string) (object.Hash, error) {
+	name = strings.TrimSpace(name)
+	if object.ValidateHash(name) == nil && r.Store.Has(object.Hash(name)) {
+		return object.Hash(name), nil
+	}
+	return r.ResolveTreeish(name)
+}
+
+// catFileBatch answers each object name read from in with its header and
+// stored content.
+func catFileBatch(r *repo.Repo, in io.Reader, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" {
+			continue
+		}
+		h, err := resolveObject(r, name)
+		if err != nil {
+			fmt.Fprintf(w, "%s missing\n", name)
+			continue
+		}
+		objType, data, err := r.Store.Read(h)
+		if err != nil {
+			fmt.Fprintf(w, "%s missing\n", name)
+			continue
+		}
+		fmt.Fprintf(w, "%s %s %d\n", h, objType, len(data))
+		w.Write(data)
+		w.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cat-file: read stdin: %w", err)
+	}
+	return w.Flush()
+}
+
+// prettyPrintObject writes a human-readable form of an object's content.
+func prettyPrintObject(out io.Writer, r *repo.Repo, objType object.ObjectType, data []byte) error {
+	switch objType {
+	case object.TypeTree:
+		tree, err := object.UnmarshalTree(data)
+		if err != nil {
+			return err
+		}
+		for _, e := range tree.Entries {
+			mode, typ, hash := treeEntryFields(e)
+			if e.EntityListHash != "" {
+				fmt.Fprintf(out, "%s %s %s %s\t%s\n", mode, typ, hash, e.EntityListHash, e.Name)
+			} else {
+				fmt.Fprintf(out, "%s %s %s\t%s\n", mode, typ, hash, e.Name)
+			}
+		}
+	case object.TypeCommit:
+		c, err := object.UnmarshalCommit(data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "tree %s\n", c.TreeHash)
+		for _, p := range c.Parents {
+			fmt.Fprintf(out, "parent %s\n", p)
+		}
+		fmt.Fprintln(out, strings.TrimSpace(fmt.Sprintf("author %s %d %s", c.Author, c.Timestamp, c.AuthorTimezone)))
+		if c.Committer != "" {
+			fmt.Fprintln(out, strings.TrimSpace(fmt.Sprintf("committer %s %d %s", c.Committer, c.CommitterTimestamp, c.CommitterTimezone)))
+		}
+		fmt.Fprintf(out, "\n%s", c.Message)
+		if !strings.HasSuffix(c.Message, "\n") {
+			fmt.Fprintln(out)
+		}
+	case object.TypeTag:
+		tag, err := object.UnmarshalTag(data)
+		if err != nil {
+			return err
+		}
+		out.Write(tag.Data)
+	case object.TypeEntity:
+		e, err := object.UnmarshalEntity(data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "kind %s\n", e.Kind)
+		if e.Name != "" {
+			fmt.Fprintf(out, "name %s\n", e.Name)
+		}
+		if e.DeclKind != "" {
+			fmt.Fprintf(out, "declkind %s\n", e.DeclKind)
+		}
+		if e.Receiver != "" {
+			fmt.Fprintf(out, "receiver %s\n", e.Receiver)
+		}
+		fmt.Fprintf(out, "\n%s", e.Body)
+		if len(e.Body) > 0 && e.Body[len(e.Body)-1] != '\n' {
+			fmt.Fprintln(out)
+		}
+	case object.TypeEntityList:
+		el, err := object.UnmarshalEntityList(data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "language %s\npath %s\n\n", el.Language, el.Path)
+		for _, ref := range el.EntityRefs {
+			e, err := r.Store.ReadEntity(ref)
+			if err != nil {
+				return fmt.Errorf("entity %s: %w", ref, err)
+			}
+			switch {
+			case e.Name == "":
+				fmt.Fprintf(out, "%s %s\n", ref, e.Kind)
+			case e.Receiver != "":
+				fmt.Fprintf(out, "%s %s %s (%s) %s\n", ref, e.Kind, entity.ShortDeclKind(e.DeclKind), e.Receiver, e.Name)
+			default:
+				fmt.Fprintf(out, "%s %s %s %s\n", ref, e.Kind, entity.ShortDeclKind(e.DeclKind), e.Name)
+			}
+		}
+	default:
+		out.Write(data)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+func TestCatFileIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "main.go", "package main\n\nfunc A() {}\n", "first")
+
+	if typ := strings.TrimSpace(mustRunGraft(t, dir, "cat-file", "-t", "HEAD")); typ != "commit" {
+		t.Fatalf("cat-file -t HEAD = %q, want commit", typ)
+	}
+	if size := strings.TrimSpace(mustRunGraft(t, dir, "cat-file", "-s", "HEAD:main.go")); size != "26" {
+		t.Fatalf("cat-file -s HEAD:main.go = %q, want 26", size)
+	}
+	if blob := mustRunGraft(t, dir, "cat-file", "-p", "HEAD:main.go"); blob != "package main\n\nfunc A() {}\n" {
+		t.Fatalf("cat-file -p HEAD:main.go = %q", blob)
+	}
+
+	commit := nonEmptyLines(mustRunGraft(t, dir, "cat-file", "-p", "HEAD"))
+	if len(commit) < 3 || !strings.HasPrefix(commit[0], "tree ") || commit[len(commit)-1] != "first" {
+		t.Fatalf("cat-file -p HEAD = %q", commit)
+	}
+	tree := nonEmptyLines(mustRunGraft(t, dir, "cat-file", "-p", strings.TrimPrefix(commit[0], "tree ")))
+	fields := strings.Fields(tree[0])
+	if len(tree) != 1 || len(fields) != 5 || fields[1] != "blob" || fields[4] != "main.go" {
+		t.Fatalf("cat-file -p <tree> = %q, want one main.go entry with an entity list", tree)
+	}
+
+	entityList := fields[3]
+	if typ := strings.TrimSpace(mustRunGraft(t, dir, "cat-file", "-t", entityList)); typ != "entitylist" {
+		t.Fatalf("cat-file -t <entity list> = %q, want entitylist", typ)
+	}
+	var declaration string
+	for _, line := range nonEmptyLines(mustRunGraft(t, dir, "cat-file", "-p", entityList)) {
+		if strings.HasSuffix(line, " declaration func A") {
+			declaration = strings.Fields(line)[0]
+		}
+	}
+	if declaration == "" {
+		t.Fatal("entity list does not list func A")
+	}
+	entity := mustRunGraft(t, dir, "cat-file", "-p", declaration)
+	if !strings.Contains(entity, "name A\n") || !strings.HasSuffix(entity, "\nfunc A() {}\n") {
+		t.Fatalf("cat-file -p <entity> = %q", entity)
+	}
+
+	if _, err := runGraft(t, dir, "cat-file", "-t", "-p", "HEAD"); err == nil {
+		t.Fatal("expected an error for -t combined with -p")
+	}
+	if _, err := runGraft(t, dir, "cat-file", "-t", "no-such-ref"); err == nil {
+		t.Fatal("expected an error for an unknown object")
+	}
+}
+
+func TestCatFileBatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "hello\n", "first")
+	blob := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD:a.txt"))
+
+	r, err := repo.Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	var out bytes.Buffer
+	if err := catFileBatch(r, strings.NewReader("HEAD:a.txt\nno-such-ref\n"), &out); err != nil {
+		t.Fatalf("catFileBatch: %v", err)
+	}
+	want := blob + " blob 6\nhello\n\nno-such-ref missing\n"
+	if out.String() != want {
+		t.Fatalf("batch output = %q, want %q", out.String(), want)
+	}
+}
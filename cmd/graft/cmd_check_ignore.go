@@ -66,7 +66,7 @@ func newCheckIgnoreCmd() *cobra.Command {
 
 				result := checkIgnoreResult{Path: rel}
 				if includeGraft {
-					explanation := graftIgnoreExplanation(checker, rel)
+					explanation := checker.Explain(rel)
 					result.Graft = &explanation
 				}
 				if includeGit {
@@ -171,30 +171,6 @@ func resolveRepoRelativePath(rootDir, input string) (string, error) {
 	return rel, nil
 }
 
-func graftIgnoreExplanation(checker *repo.IgnoreChecker, relPath string) repo.IgnoreExplanation {
-	explanation := checker.Explain(relPath)
-	if explanation.Ignored {
-		return explanation
-	}
-
-	dir := filepath.ToSlash(filepath.Dir(relPath))
-	for dir != "." && dir != "" {
-		dirExplanation := checker.Explain(dir)
-		if dirExplanation.Ignored {
-			dirExplanation.Path = relPath
-			dirExplanation.MatchedPath = dir
-			return dirExplanation
-		}
-		next := filepath.ToSlash(filepath.Dir(dir))
-		if next == dir {
-			break
-		}
-		dir = next
-	}
-
-	return explanation
-}
-
 func gitIgnoreExplanation(rootDir, relPath string) (*repo.IgnoreExplanation, error) {
 	explanation := &repo.IgnoreExplanation{Path: relPath}
 
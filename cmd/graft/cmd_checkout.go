@@ -9,19 +9,56 @@ import (
 
 func newCheckoutCmd() *cobra.Command {
 	var createBranch bool
+	var conflictStyle string
+	var newProgress func() *progressMeter
 
 	cmd := &cobra.Command{
-		Use:   "checkout <branch>",
-		Short: "Switch branches",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			target := args[0]
+		Use:   "checkout <branch> | --conflict=merge <path>...",
+		Short: "Switch branches or recreate merge conflicts",
+		Long: `Checkout switches to <branch>.
 
+With --conflict=merge it instead rewrites each <path> with the conflict
+markers of the merge that conflicted on it and marks it conflicted again.
+This works for paths that are still conflicted and for conflicts that were
+resolved and restaged since, which is how an accidental resolution is
+undone. Local edits to the paths are overwritten.`,
+		Args: cobra.MinimumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+			if cmd.Flags().Changed("conflict") {
+				return completeTrackedPaths(cmd, args, toComplete)
+			}
+			if createBranch {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeRefs(1)(cmd, args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
 				return err
 			}
 
+			if cmd.Flags().Changed("conflict") {
+				if conflictStyle != "merge" {
+					return fmt.Errorf("checkout: unsupported conflict style %q (supported: merge)", conflictStyle)
+				}
+				if createBranch {
+					return fmt.Errorf("checkout: --conflict cannot be combined with -b")
+				}
+				recreated, err := r.RecreateConflicts(args)
+				if err != nil {
+					return err
+				}
+				for _, p := range recreated {
+					fmt.Fprintf(cmd.OutOrStdout(), "recreated conflict in %s\n", p)
+				}
+				return nil
+			}
+			if len(args) != 1 {
+				return fmt.Errorf("checkout: expected a single branch, got %d arguments", len(args))
+			}
+			target := args[0]
+
 			if createBranch {
 				head, err := r.ResolveRef("HEAD")
 				if err != nil {
@@ -32,7 +69,11 @@ func newCheckoutCmd() *cobra.Command {
 				}
 			}
 
-			if err := r.Checkout(target); err != nil {
+			progress := newProgress()
+			r.SetProgress(progress.Func())
+			err = r.CheckoutWithOptions(target, repo.CheckoutOptions{Autostash: autostashEnabled(cmd, r)})
+			progress.Done()
+			if err != nil {
 				return err
 			}
 
@@ -46,6 +87,9 @@ func newCheckoutCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVarP(&createBranch, "branch", "b", false, "create and switch to a new branch")
+	cmd.Flags().StringVar(&conflictStyle, "conflict", "", "recreate the conflict markers of the given paths (style: merge)")
+	addAutostashFlags(cmd)
+	newProgress = addProgressFlag(cmd)
 
 	return cmd
 }
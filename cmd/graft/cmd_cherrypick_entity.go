@@ -15,9 +15,10 @@ func newCherryPickCmd() *cobra.Command {
 	var continueFlag, abortFlag, skipFlag bool
 
 	cmd := &cobra.Command{
-		Use:   "cherry-pick [--entity <path::entity_key>] [--continue | --abort | --skip] [<commit>]",
-		Short: "Cherry-pick a commit, optionally scoped to one entity",
-		Args:  cobra.RangeArgs(0, 1),
+		Use:               "cherry-pick [--entity <path::entity_key>] [--continue | --abort | --skip] [<commit>]",
+		Short:             "Cherry-pick a commit, optionally scoped to one entity",
+		Args:              cobra.RangeArgs(0, 1),
+		ValidArgsFunction: completeRefs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
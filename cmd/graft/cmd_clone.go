@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/odvcencio/graft/pkg/lockfile"
 	"github.com/odvcencio/graft/pkg/object"
 	"github.com/odvcencio/graft/pkg/remote"
 	"github.com/odvcencio/graft/pkg/repo"
@@ -20,11 +21,19 @@ func newCloneCmd() *cobra.Command {
 	var depth int
 	var moduleDepth int
 	var noModules bool
+	var noHardlinks bool
+	var mirror bool
+	var newProgress func() *progressMeter
+	var showStats *bool
 
 	cmd := &cobra.Command{
 		Use:   "clone <remote-url> [directory]",
 		Short: "Clone a repository from Graft/Git endpoints or local path",
-		Args:  cobra.RangeArgs(1, 2),
+		Long: "Clone a repository from Graft/Git endpoints or a local path.\n\n" +
+			"With --mirror every branch, tag and note of the source becomes a local ref of the same name " +
+			"instead of a tracking ref, and the remote is marked as a mirror: later fetches replace and " +
+			"prune the local refs, and a push without refs mirrors the repository back.",
+		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			source := strings.TrimSpace(args[0])
 			localSourceRoot, isLocalSource, err := resolveLocalCloneSource(source)
@@ -68,11 +77,22 @@ func newCloneCmd() *cobra.Command {
 			if depth > 0 && isLocalSource {
 				return fmt.Errorf("--depth is not supported for local clone sources")
 			}
+			if mirror && depth > 0 {
+				return fmt.Errorf("--mirror cannot be combined with --depth")
+			}
+			if mirror && !isLocalSource && remoteKind == remoteTransportGit {
+				return fmt.Errorf("--mirror currently supports orchard/graft remotes only")
+			}
 
 			if isLocalSource {
-				if err := cloneFromLocalSource(cmd, localSourceRoot, source, absDest, remoteName, branch); err != nil {
+				if err := cloneFromLocalSource(cmd, localSourceRoot, source, absDest, remoteName, branch, !noHardlinks); err != nil {
 					return err
 				}
+				if mirror {
+					if err := markCloneMirror(absDest, remoteName); err != nil {
+						return err
+					}
+				}
 				return syncModulesAfterClone(cmd, absDest, noModules)
 			}
 			if remoteKind == remoteTransportGit {
@@ -107,23 +127,40 @@ func newCloneCmd() *cobra.Command {
 				}
 			}
 			if len(wants) > 0 {
+				progress := newProgress()
+				client.SetProgress(progress.Func())
 				cfg := remote.FetchConfig{
-					Depth: depth,
+					Depth:    depth,
+					Progress: progress.Func(),
 				}
 				result, err := remote.FetchIntoStoreShallow(cmd.Context(), client, r.Store, wants, nil, cfg)
+				progress.Done()
 				if err != nil {
 					return err
 				}
+				printTransferStats(cmd.ErrOrStderr(), *showStats, client.Stats())
 				// Write shallow boundaries if this is a shallow clone.
 				if depth > 0 && result.ShallowState != nil && result.ShallowState.Len() > 0 {
-					if err := remote.WriteShallowFile(r.GraftDir, result.ShallowState); err != nil {
+					if err := r.WriteShallowState(result.ShallowState); err != nil {
 						return fmt.Errorf("write shallow file: %w", err)
 					}
 				}
 			}
 
 			for name, h := range remoteRefs {
-				if err := r.UpdateRef(remoteTrackingRefName(remoteName, name), h); err != nil {
+				local := remoteTrackingRefName(remoteName, name)
+				if mirror {
+					if !repo.IsMirrorRef(name) {
+						continue
+					}
+					local = "refs/" + name
+				}
+				if err := r.UpdateRef(local, h); err != nil {
+					return err
+				}
+			}
+			if mirror {
+				if err := r.SetRemoteMirror(remoteName); err != nil {
 					return err
 				}
 			}
@@ -150,9 +187,21 @@ func newCloneCmd() *cobra.Command {
 				selectedHash = h
 			}
 
+			if mirror {
+				// The mirrored refs gave HEAD's branch a history; point HEAD
+				// at an unborn branch again so the empty tree looks clean.
+				if err := r.SetHeadSymbolic("refs/heads/_graft_clone_unborn"); err != nil {
+					return fmt.Errorf("reset HEAD for clone: %w", err)
+				}
+			}
 			// First checkout by commit hash while HEAD still points to an
 			// unborn branch, so clean-tree checks do not fail on initial clone.
-			if err := r.Checkout(string(selectedHash)); err != nil {
+			progress := newProgress()
+			r.SetProgress(progress.Func())
+			err = r.Checkout(string(selectedHash))
+			progress.Done()
+			r.SetProgress(nil)
+			if err != nil {
 				return err
 			}
 			if err := r.UpdateRef("refs/heads/"+selectedBranch, selectedHash); err != nil {
@@ -182,6 +231,10 @@ func newCloneCmd() *cobra.Command {
 	cmd.Flags().IntVar(&depth, "depth", 0, "create a shallow clone with history truncated to the specified number of commits")
 	cmd.Flags().IntVar(&moduleDepth, "module-depth", 0, "depth limit for module fetches (0 = full)")
 	cmd.Flags().BoolVar(&noModules, "no-modules", false, "skip automatic module sync after clone")
+	cmd.Flags().BoolVar(&noHardlinks, "no-hardlinks", false, "copy object files from a local source instead of hardlinking them")
+	cmd.Flags().BoolVar(&mirror, "mirror", false, "replicate every branch, tag and note as local refs and mark the remote as a mirror")
+	newProgress = addProgressFlag(cmd)
+	showStats = addStatsFlag(cmd)
 	return cmd
 }
 
@@ -214,8 +267,12 @@ func syncModulesAfterClone(cmd *cobra.Command, absDest string, skip bool) error
 }
 
 func resolveLocalCloneSource(source string) (string, bool, error) {
+	// Plain local paths to a Graft repository are copied directly; file://
+	// URLs go through the local protocol transport like any other remote.
 	if looksLikeRemoteURL(source) {
-		return "", false, nil
+		if _, local := remote.LocalRepoDir(source); !local || strings.Contains(source, "://") {
+			return "", false, nil
+		}
 	}
 	absSource, err := filepath.Abs(source)
 	if err != nil {
@@ -228,10 +285,11 @@ func resolveLocalCloneSource(source string) (string, bool, error) {
 	return srcRepo.RootDir, true, nil
 }
 
-func cloneFromLocalSource(cmd *cobra.Command, sourceRoot, sourceSpec, absDest, remoteName, branch string) error {
+func cloneFromLocalSource(cmd *cobra.Command, sourceRoot, sourceSpec, absDest, remoteName, branch string, hardlinks bool) error {
 	srcGraftDir := filepath.Join(sourceRoot, ".graft")
 	dstGraftDir := filepath.Join(absDest, ".graft")
-	if err := copyDir(srcGraftDir, dstGraftDir); err != nil {
+	stats := &cloneLinkStats{hardlinks: hardlinks}
+	if err := copyDir(srcGraftDir, dstGraftDir, stats); err != nil {
 		return err
 	}
 
@@ -246,7 +304,7 @@ func cloneFromLocalSource(cmd *cobra.Command, sourceRoot, sourceSpec, absDest, r
 	if err != nil {
 		return err
 	}
-	if err := r.SetRemote(remoteName, sourceSpec); err != nil {
+	if err := r.SetRemote(remoteName, sourceRoot); err != nil {
 		return err
 	}
 
@@ -281,7 +339,21 @@ func cloneFromLocalSource(cmd *cobra.Command, sourceRoot, sourceSpec, absDest, r
 	return nil
 }
 
-func copyDir(src, dst string) error {
+// markCloneMirror marks remoteName as a mirror of the repository cloned into
+// absDest. Local clones copy the source's refs as they are, so only the
+// remote config changes.
+func markCloneMirror(absDest, remoteName string) error {
+	r, err := repo.Open(absDest)
+	if err != nil {
+		return err
+	}
+	return r.SetRemoteMirror(remoteName)
+}
+
+// copyDir copies a .graft directory tree. Loose objects and pack files are
+// hardlinked or reflinked when possible since they are immutable; lock files
+// and in-progress temp files are skipped.
+func copyDir(src, dst string, stats *cloneLinkStats) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -294,6 +366,13 @@ func copyDir(src, dst string) error {
 		if info.IsDir() {
 			return os.MkdirAll(target, info.Mode())
 		}
+		if !info.Mode().IsRegular() || strings.HasSuffix(info.Name(), lockfile.Suffix) || strings.HasPrefix(info.Name(), ".tmp-") {
+			stats.Skipped++
+			return nil
+		}
+		if isImmutableObjectFile(rel) {
+			return stats.materializeObjectFile(path, target, info.Mode())
+		}
 		return copyFile(path, target, info.Mode())
 	})
 }
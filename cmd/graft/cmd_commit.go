@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/odvcencio/graft/pkg/coord"
 	"github.com/odvcencio/graft/pkg/object"
@@ -21,20 +23,67 @@ func newCommitCmd() *cobra.Command {
 	var signKey string
 	var noSign bool
 	var amend bool
+	var allowEmpty bool
+	var noVerify bool
+	var date string
+	var cleanup string
+	var templateFile string
 
 	cmd := &cobra.Command{
-		Use:   "commit",
+		Use:   "commit [--] [<pathspec>...]",
 		Short: "Record changes to the repository",
+		Long: `Record the staged changes as a new commit on the current branch.
+
+With pathspecs, only the staged changes to matching paths are committed;
+every other path keeps its content from HEAD and stays staged for a later
+commit.
+
+Without -m, the message is written in $VISUAL or $EDITOR, starting from
+--template or the commit.template config; lines starting with '#' are
+dropped and an empty message aborts the commit. --cleanup chooses how the
+message is cleaned up: "strip" (the default for an edited message) also
+drops '#' lines, "whitespace" (the default for -m) only trims blank lines
+and trailing whitespace, "verbatim" keeps the message as given.
+
+The message is then checked against commit.maxSubjectLength and, with
+commit.conventional, the conventional commit format "type(scope): summary";
+see "graft config". The commit is refused when a rule is broken.
+
+The author and the committer are taken from user.name and user.email in
+the repository config, else in ~/.graftconfig. GRAFT_AUTHOR_NAME and
+GRAFT_AUTHOR_EMAIL override the author, GRAFT_COMMITTER_NAME and
+GRAFT_COMMITTER_EMAIL the committer, field by field. Every commit records
+its committer and commit date alongside the author.
+
+--author records someone else as the author. --date sets the author date, given as RFC 3339, "YYYY-MM-DD
+[HH:MM:SS [+ZZZZ]]", or "@<unix-seconds>". --no-verify skips the
+pre-commit and commit-msg hooks and the message checks.`,
+		PostRun: runAutoGC,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if message == "" && !amend {
-				return fmt.Errorf("commit message is required (-m)")
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
 			}
 
-			r, err := repo.Open(".")
+			filter, err := parsePathspecArgs(r, args)
 			if err != nil {
 				return err
 			}
+			if filter != nil && amend {
+				return fmt.Errorf("--amend cannot be combined with pathspecs")
+			}
 
+			opts := repo.CommitOptions{
+				Paths:      filter,
+				AllowEmpty: allowEmpty,
+				NoVerify:   noVerify,
+				Lint:       true,
+			}
+			if date != "" {
+				if opts.Date, err = parseCommitDate(date); err != nil {
+					return err
+				}
+			}
 			if author == "" {
 				author = r.ResolveAuthor()
 			}
@@ -48,7 +97,7 @@ func newCommitCmd() *cobra.Command {
 			// Load hooks config and run pre-commit hooks.
 			hooksCfg, _ := repo.LoadHooksConfig(r.RootDir, nil)
 			preCommitHooks := hooksCfg.ForPoint("pre-commit")
-			if len(preCommitHooks) > 0 {
+			if len(preCommitHooks) > 0 && !noVerify {
 				payload, _ := json.Marshal(repo.PreCommitPayload{
 					Hook:   "pre-commit",
 					Repo:   r.RootDir,
@@ -60,6 +109,26 @@ func newCommitCmd() *cobra.Command {
 				}
 			}
 
+			edit := message == "" && !amend
+			if edit {
+				if message, err = editCommitMessage(r, templateFile, branch); err != nil {
+					return err
+				}
+			}
+			mode := cleanup
+			if mode == "default" {
+				mode = repo.CleanupWhitespace
+				if edit {
+					mode = repo.CleanupStrip
+				}
+			}
+			if message, err = repo.CleanupCommitMessage(message, mode); err != nil {
+				return err
+			}
+			if message == "" && !amend {
+				return fmt.Errorf("aborting commit due to empty commit message")
+			}
+
 			// Determine whether to sign. Explicit --sign/--sign-key flags
 			// take highest priority, then --no-sign disables, otherwise
 			// fall back to user config auto-sign.
@@ -79,30 +148,8 @@ func newCommitCmd() *cobra.Command {
 				}
 			}
 
-			var (
-				h          string
-				commitErr  error
-				signedWith string
-			)
-			if amend {
-				if shouldSign {
-					signer, keyPath, signErr := newSSHCommitSigner(resolvedKey)
-					if signErr != nil {
-						return signErr
-					}
-					signedWith = keyPath
-					if autoSigned {
-						signedWith = resolvedKey
-					}
-					commitHash, cErr := r.CommitAmendWithSigner(message, author, signer)
-					h = string(commitHash)
-					commitErr = cErr
-				} else {
-					commitHash, cErr := r.CommitAmend(message, author)
-					h = string(commitHash)
-					commitErr = cErr
-				}
-			} else if shouldSign {
+			var signedWith string
+			if shouldSign {
 				signer, keyPath, signErr := newSSHCommitSigner(resolvedKey)
 				if signErr != nil {
 					return signErr
@@ -111,17 +158,22 @@ func newCommitCmd() *cobra.Command {
 				if autoSigned {
 					signedWith = resolvedKey
 				}
-				commitHash, cErr := r.CommitWithSigner(message, author, signer)
-				h = string(commitHash)
-				commitErr = cErr
+				opts.Signer = signer
+			}
+
+			var (
+				commitHash object.Hash
+				commitErr  error
+			)
+			if amend {
+				commitHash, commitErr = r.CommitAmendWithOptions(message, author, opts)
 			} else {
-				commitHash, cErr := r.Commit(message, author)
-				h = string(commitHash)
-				commitErr = cErr
+				commitHash, commitErr = r.CommitWithOptions(message, author, opts)
 			}
 			if commitErr != nil {
 				return commitErr
 			}
+			h := string(commitHash)
 
 			// For amend with empty message, read back the actual message.
 			if amend && message == "" {
@@ -153,7 +205,7 @@ func newCommitCmd() *cobra.Command {
 				short = short[:8]
 			}
 
-			fmt.Fprintf(cmd.OutOrStdout(), "[%s %s] %s\n", branch, short, message)
+			fmt.Fprintf(cmd.OutOrStdout(), "[%s %s] %s\n", branch, short, commitTitleStr(message))
 			if shouldSign {
 				fmt.Fprintf(cmd.OutOrStdout(), "signed with %s\n", signedWith)
 			}
@@ -185,6 +237,89 @@ func newCommitCmd() *cobra.Command {
 	cmd.Flags().StringVar(&signKey, "sign-key", "", "path to SSH private key (defaults to ~/.ssh/id_ed25519, id_ecdsa, id_rsa)")
 	cmd.Flags().BoolVar(&noSign, "no-sign", false, "disable auto-signing even if configured")
 	cmd.Flags().BoolVar(&amend, "amend", false, "replace the tip of the current branch by creating a new commit")
+	cmd.Flags().BoolVar(&allowEmpty, "allow-empty", false, "allow recording a commit with an empty staging area")
+	cmd.Flags().BoolVarP(&noVerify, "no-verify", "n", false, "bypass the pre-commit and commit-msg hooks")
+	cmd.Flags().StringVar(&date, "date", "", "override the author date")
+	cmd.Flags().StringVar(&cleanup, "cleanup", "default", "how to clean up the message: strip, whitespace, verbatim or default")
+	cmd.Flags().StringVarP(&templateFile, "template", "t", "", "start the edited message from this file instead of commit.template")
 
 	return cmd
 }
+
+// commitEditHelp follows the template in the message editor.
+const commitEditHelp = `
+# Please enter the commit message for your changes. Lines starting
+# with '#' will be ignored, and an empty message aborts the commit.
+#
+# On branch %s
+`
+
+// editCommitMessage has the user write the commit message in an editor,
+// starting from templateFile or else the configured commit template. A
+// message left exactly as the template aborts the commit.
+func editCommitMessage(r *repo.Repo, templateFile, branch string) (string, error) {
+	var template string
+	if templateFile != "" {
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return "", fmt.Errorf("commit template: %w", err)
+		}
+		template = string(data)
+	} else {
+		var err error
+		if template, err = r.CommitTemplate(); err != nil {
+			return "", err
+		}
+	}
+
+	message, err := r.EditCommitMessage(template + fmt.Sprintf(commitEditHelp, branch))
+	if err != nil {
+		return "", err
+	}
+	if template != "" {
+		edited, _ := repo.CleanupCommitMessage(message, repo.CleanupStrip)
+		unedited, _ := repo.CleanupCommitMessage(template, repo.CleanupStrip)
+		if edited == unedited {
+			return "", fmt.Errorf("aborting commit; you did not edit the message")
+		}
+	}
+	return message, nil
+}
+
+// commitDateLayouts are the layouts accepted by --date, tried in order.
+// Layouts without a zone are read in local time.
+var commitDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseCommitDate parses a --date value: one of commitDateLayouts, or
+// "@<unix-seconds>" optionally followed by a "+ZZZZ" zone.
+func parseCommitDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if rest, ok := strings.CutPrefix(value, "@"); ok {
+		secs, zone, _ := strings.Cut(rest, " ")
+		n, err := strconv.ParseInt(secs, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --date %q: %w", value, err)
+		}
+		t := time.Unix(n, 0)
+		if zone != "" {
+			z, err := time.Parse("-0700", zone)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid --date %q: bad zone %q", value, zone)
+			}
+			t = t.In(z.Location())
+		}
+		return t, nil
+	}
+	for _, layout := range commitDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --date %q: want RFC 3339, YYYY-MM-DD [HH:MM:SS [+ZZZZ]], or @<unix-seconds>", value)
+}
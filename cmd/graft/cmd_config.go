@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/odvcencio/graft/pkg/repo"
 	"github.com/odvcencio/graft/pkg/userconfig"
@@ -20,13 +22,39 @@ func newConfigCmd() *cobra.Command {
 Without --global, values are stored in the repository config (.graft/config.json).
 With --global, values are stored in the user config (~/.graftconfig).
 
-Supported keys: user.name, user.email
+Supported keys: user.name, user.email,
+core.pager (command for paging log, diff and blame output; "cat" disables paging),
+color.ui (auto/always/never; default for --color),
+commit.template (file the commit message editor starts from)
+User-only keys: core.excludesFile (user-wide ignore file; default ~/.config/graft/ignore)
+Repository-only keys: storage.chunkLargeBlobs (true/false; dedups the local object store only,
+push and fetch send whole blobs), storage.chunkMinSize (bytes),
+storage.encrypt (true/false), storage.encryptionKeyFile (path; see "graft encrypt"),
+core.fsmonitor (filesystem monitor hook; empty to disable),
+core.filemode (true/false; false ignores executable-bit changes on FAT/Windows filesystems),
+core.ignorecase (true/false; default detected from the filesystem),
+core.autocrlf (true/input/false; see .graftattributes text and eol for per-path control),
+core.autostash (true/false; default for --autostash on checkout, switch, merge and rebase),
+core.gitSync (true/false; export history into the colocated .git exactly; see "graft git-sync"),
+gc.auto (loose objects that trigger packing after commit and pull; default 6700, 0 disables),
+gc.autoPackLimit (packs that trigger a full repack instead; default 50, 0 disables),
+gc.aggressiveWindow (delta window of "graft gc --aggressive"; default 250),
+commit.conventional (true/false; refuse commits whose subject is not "type(scope): summary"),
+commit.conventionalTypes (space-separated types allowed; default build chore ci docs feat fix
+perf refactor revert style test),
+commit.maxSubjectLength (refuse commits with longer subjects; 0 disables),
+remote.<name>.fetch, remote.<name>.push (space-separated refspecs; empty to reset),
+filter.<name>.clean, filter.<name>.smudge (commands for paths with filter=<name> in
+.graftattributes; content on stdin, result on stdout, %f is the path; empty to remove)
 
 Examples:
   graft config user.name "Alice"
   graft config user.email "alice@example.com"
   graft config --global user.name "Alice"
   graft config user.name
+  graft config remote.origin.fetch "+refs/heads/*:refs/remotes/origin/heads/*"
+  graft config remote.origin.push "main:release"
+  graft config filter.nbstrip.clean "jq --indent 1 '.cells[].outputs = []'"
   graft config --list`,
 		Args: cobra.MaximumNArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -91,6 +119,18 @@ func applyUserConfigKey(cfg *userconfig.Config, key, value string) error {
 		cfg.Name = value
 	case "user.email":
 		cfg.Email = value
+	case "core.excludesFile":
+		cfg.ExcludesFile = value
+	case "core.pager":
+		cfg.Pager = value
+	case "color.ui":
+		mode, err := parseColorMode(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+		cfg.ColorUI = mode
+	case "commit.template":
+		cfg.CommitTemplate = value
 	default:
 		return fmt.Errorf("unknown config key: %s", key)
 	}
@@ -110,12 +150,252 @@ func applyRepoConfigKey(cfg *repo.Config, key, value string) error {
 			cfg.User = &repo.UserConfig{}
 		}
 		cfg.User.Email = value
+	case "storage.chunkLargeBlobs":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q (want true or false)", key, value)
+		}
+		if cfg.Storage == nil {
+			cfg.Storage = &repo.StorageConfig{}
+		}
+		cfg.Storage.ChunkLargeBlobs = enabled
+	case "storage.chunkMinSize":
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("invalid value for %s: %q (want a byte count)", key, value)
+		}
+		if cfg.Storage == nil {
+			cfg.Storage = &repo.StorageConfig{}
+		}
+		cfg.Storage.ChunkMinSize = size
+	case "storage.encrypt":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q (want true or false)", key, value)
+		}
+		if cfg.Storage == nil {
+			cfg.Storage = &repo.StorageConfig{}
+		}
+		cfg.Storage.Encrypt = enabled
+	case "storage.encryptionKeyFile":
+		if cfg.Storage == nil {
+			cfg.Storage = &repo.StorageConfig{}
+		}
+		cfg.Storage.EncryptionKeyFile = value
+	case "core.fsmonitor":
+		if cfg.Core == nil {
+			cfg.Core = &repo.CoreConfig{}
+		}
+		cfg.Core.FSMonitor = value
+	case "core.filemode":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q (want true or false)", key, value)
+		}
+		if cfg.Core == nil {
+			cfg.Core = &repo.CoreConfig{}
+		}
+		cfg.Core.FileMode = &enabled
+	case "core.ignorecase":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q (want true or false)", key, value)
+		}
+		if cfg.Core == nil {
+			cfg.Core = &repo.CoreConfig{}
+		}
+		cfg.Core.IgnoreCase = &enabled
+	case "core.autocrlf":
+		value = strings.ToLower(value)
+		switch value {
+		case "true", "input", "false", "":
+		default:
+			return fmt.Errorf("invalid value for %s: %q (want true, input or false)", key, value)
+		}
+		if cfg.Core == nil {
+			cfg.Core = &repo.CoreConfig{}
+		}
+		cfg.Core.AutoCRLF = value
+	case "core.autostash":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q (want true or false)", key, value)
+		}
+		if cfg.Core == nil {
+			cfg.Core = &repo.CoreConfig{}
+		}
+		cfg.Core.AutoStash = enabled
+	case "core.gitSync":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q (want true or false)", key, value)
+		}
+		if cfg.Core == nil {
+			cfg.Core = &repo.CoreConfig{}
+		}
+		cfg.Core.GitSync = enabled
+	case "core.pager":
+		if cfg.Core == nil {
+			cfg.Core = &repo.CoreConfig{}
+		}
+		cfg.Core.Pager = value
+	case "color.ui":
+		mode, err := parseColorMode(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+		if cfg.Color == nil {
+			cfg.Color = &repo.ColorConfig{}
+		}
+		cfg.Color.UI = mode
+	case "gc.auto", "gc.autoPackLimit", "gc.aggressiveWindow":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid value for %s: %q (want a non-negative number)", key, value)
+		}
+		if cfg.GC == nil {
+			cfg.GC = &repo.GCConfig{}
+		}
+		switch key {
+		case "gc.auto":
+			cfg.GC.Auto = &n
+		case "gc.autoPackLimit":
+			cfg.GC.AutoPackLimit = &n
+		default:
+			cfg.GC.AggressiveWindow = n
+		}
+	case "commit.template":
+		if cfg.Commit == nil {
+			cfg.Commit = &repo.CommitConfig{}
+		}
+		cfg.Commit.Template = value
+	case "commit.conventional":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q (want true or false)", key, value)
+		}
+		if cfg.Commit == nil {
+			cfg.Commit = &repo.CommitConfig{}
+		}
+		cfg.Commit.Conventional = enabled
+	case "commit.conventionalTypes":
+		if cfg.Commit == nil {
+			cfg.Commit = &repo.CommitConfig{}
+		}
+		cfg.Commit.ConventionalTypes = strings.Fields(value)
+	case "commit.maxSubjectLength":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid value for %s: %q (want a non-negative number)", key, value)
+		}
+		if cfg.Commit == nil {
+			cfg.Commit = &repo.CommitConfig{}
+		}
+		cfg.Commit.MaxSubjectLength = n
 	default:
+		if name, field, ok := parseRemoteRefspecKey(key); ok {
+			return applyRemoteRefspecKey(cfg, name, field, value)
+		}
+		if name, field, ok := parseFilterKey(key); ok {
+			applyFilterKey(cfg, name, field, value)
+			return nil
+		}
 		return fmt.Errorf("unknown config key: %s", key)
 	}
 	return nil
 }
 
+// parseFilterKey splits filter.<name>.clean and filter.<name>.smudge keys
+// into the filter name and field.
+func parseFilterKey(key string) (name, field string, ok bool) {
+	rest, ok := strings.CutPrefix(key, "filter.")
+	if !ok {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, ".")
+	if i <= 0 {
+		return "", "", false
+	}
+	name, field = rest[:i], rest[i+1:]
+	if field != "clean" && field != "smudge" {
+		return "", "", false
+	}
+	return name, field, true
+}
+
+// applyFilterKey stores a filter command; a filter left without commands
+// is removed.
+func applyFilterKey(cfg *repo.Config, name, field, value string) {
+	if cfg.Filters == nil {
+		cfg.Filters = make(map[string]*repo.FilterConfig)
+	}
+	f := cfg.Filters[name]
+	if f == nil {
+		f = &repo.FilterConfig{}
+	}
+	if field == "clean" {
+		f.Clean = value
+	} else {
+		f.Smudge = value
+	}
+	if f.Clean == "" && f.Smudge == "" {
+		delete(cfg.Filters, name)
+	} else {
+		cfg.Filters[name] = f
+	}
+	if len(cfg.Filters) == 0 {
+		cfg.Filters = nil
+	}
+}
+
+// parseRemoteRefspecKey splits remote.<name>.fetch and remote.<name>.push
+// keys into the remote name and field.
+func parseRemoteRefspecKey(key string) (name, field string, ok bool) {
+	rest, ok := strings.CutPrefix(key, "remote.")
+	if !ok {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, ".")
+	if i <= 0 {
+		return "", "", false
+	}
+	name, field = rest[:i], rest[i+1:]
+	if field != "fetch" && field != "push" {
+		return "", "", false
+	}
+	return name, field, true
+}
+
+// applyRemoteRefspecKey validates and stores the space-separated refspecs in
+// value for the remote; an empty value restores the default.
+func applyRemoteRefspecKey(cfg *repo.Config, name, field, value string) error {
+	if _, ok := cfg.Remotes[name]; !ok {
+		return fmt.Errorf("remote %q is not configured", name)
+	}
+	specs := strings.Fields(value)
+	if _, err := repo.ParseRefspecs(specs); err != nil {
+		return err
+	}
+	if cfg.RemoteSettings == nil {
+		cfg.RemoteSettings = make(map[string]*repo.RemoteConfig)
+	}
+	rc := cfg.RemoteSettings[name]
+	if rc == nil {
+		rc = &repo.RemoteConfig{}
+	}
+	if field == "fetch" {
+		rc.Fetch = specs
+	} else {
+		rc.Push = specs
+	}
+	if len(rc.Fetch) == 0 && len(rc.Push) == 0 && !rc.Mirror {
+		delete(cfg.RemoteSettings, name)
+	} else {
+		cfg.RemoteSettings[name] = rc
+	}
+	return nil
+}
+
 // configGet retrieves and prints a config value.
 func configGet(cmd *cobra.Command, key string, global bool) error {
 	if global {
@@ -156,6 +436,11 @@ func configGetWithFallback(cmd *cobra.Command, key string) error {
 		fmt.Fprintln(cmd.OutOrStdout(), val)
 		return nil
 	}
+	if !strings.HasPrefix(key, "user.") && key != "core.pager" && key != "color.ui" && key != "commit.template" {
+		// Only user identity, output settings and the commit template have
+		// a global fallback.
+		return nil
+	}
 	// Fall back to global config.
 	ucfg, err := userconfig.Load()
 	if err != nil {
@@ -178,6 +463,14 @@ func readUserConfigKey(cfg *userconfig.Config, key string) (string, error) {
 		return cfg.Name, nil
 	case "user.email":
 		return cfg.Email, nil
+	case "core.excludesFile":
+		return cfg.ExcludesFile, nil
+	case "core.pager":
+		return cfg.Pager, nil
+	case "color.ui":
+		return cfg.ColorUI, nil
+	case "commit.template":
+		return cfg.CommitTemplate, nil
 	default:
 		return "", fmt.Errorf("unknown config key: %s", key)
 	}
@@ -196,7 +489,122 @@ func readRepoConfigKey(cfg *repo.Config, key string) (string, error) {
 			return cfg.User.Email, nil
 		}
 		return "", nil
+	case "storage.chunkLargeBlobs":
+		if cfg.Storage != nil {
+			return strconv.FormatBool(cfg.Storage.ChunkLargeBlobs), nil
+		}
+		return "", nil
+	case "storage.chunkMinSize":
+		if cfg.Storage != nil && cfg.Storage.ChunkMinSize > 0 {
+			return strconv.FormatInt(cfg.Storage.ChunkMinSize, 10), nil
+		}
+		return "", nil
+	case "storage.encrypt":
+		if cfg.Storage != nil {
+			return strconv.FormatBool(cfg.Storage.Encrypt), nil
+		}
+		return "", nil
+	case "storage.encryptionKeyFile":
+		if cfg.Storage != nil {
+			return cfg.Storage.EncryptionKeyFile, nil
+		}
+		return "", nil
+	case "core.fsmonitor":
+		if cfg.Core != nil {
+			return cfg.Core.FSMonitor, nil
+		}
+		return "", nil
+	case "core.filemode":
+		if cfg.Core != nil && cfg.Core.FileMode != nil {
+			return strconv.FormatBool(*cfg.Core.FileMode), nil
+		}
+		return "", nil
+	case "core.ignorecase":
+		if cfg.Core != nil && cfg.Core.IgnoreCase != nil {
+			return strconv.FormatBool(*cfg.Core.IgnoreCase), nil
+		}
+		return "", nil
+	case "core.autocrlf":
+		if cfg.Core != nil {
+			return cfg.Core.AutoCRLF, nil
+		}
+		return "", nil
+	case "core.autostash":
+		if cfg.Core != nil {
+			return strconv.FormatBool(cfg.Core.AutoStash), nil
+		}
+		return "", nil
+	case "core.gitSync":
+		if cfg.Core != nil {
+			return strconv.FormatBool(cfg.Core.GitSync), nil
+		}
+		return "", nil
+	case "core.pager":
+		if cfg.Core != nil {
+			return cfg.Core.Pager, nil
+		}
+		return "", nil
+	case "color.ui":
+		if cfg.Color != nil {
+			return cfg.Color.UI, nil
+		}
+		return "", nil
+	case "gc.auto":
+		if cfg.GC != nil && cfg.GC.Auto != nil {
+			return strconv.Itoa(*cfg.GC.Auto), nil
+		}
+		return "", nil
+	case "gc.autoPackLimit":
+		if cfg.GC != nil && cfg.GC.AutoPackLimit != nil {
+			return strconv.Itoa(*cfg.GC.AutoPackLimit), nil
+		}
+		return "", nil
+	case "gc.aggressiveWindow":
+		if cfg.GC != nil && cfg.GC.AggressiveWindow > 0 {
+			return strconv.Itoa(cfg.GC.AggressiveWindow), nil
+		}
+		return "", nil
+	case "commit.template":
+		if cfg.Commit != nil {
+			return cfg.Commit.Template, nil
+		}
+		return "", nil
+	case "commit.conventional":
+		if cfg.Commit != nil {
+			return strconv.FormatBool(cfg.Commit.Conventional), nil
+		}
+		return "", nil
+	case "commit.conventionalTypes":
+		if cfg.Commit != nil {
+			return strings.Join(cfg.Commit.ConventionalTypes, " "), nil
+		}
+		return "", nil
+	case "commit.maxSubjectLength":
+		if cfg.Commit != nil && cfg.Commit.MaxSubjectLength > 0 {
+			return strconv.Itoa(cfg.Commit.MaxSubjectLength), nil
+		}
+		return "", nil
 	default:
+		if name, field, ok := parseRemoteRefspecKey(key); ok {
+			rc := cfg.RemoteSettings[name]
+			if rc == nil {
+				return "", nil
+			}
+			if field == "fetch" {
+				return strings.Join(rc.Fetch, " "), nil
+			}
+			return strings.Join(rc.Push, " "), nil
+		}
+		if name, field, ok := parseFilterKey(key); ok {
+			f := cfg.Filters[name]
+			if f == nil {
+				return "", nil
+			}
+			if field == "clean" {
+				return f.Clean, nil
+			}
+			return f.Smudge, nil
+		}
 		return "", fmt.Errorf("unknown config key: %s", key)
 	}
 }
@@ -246,6 +654,18 @@ func formatUserConfig(cfg *userconfig.Config) []string {
 	if cfg.Email != "" {
 		lines = append(lines, "user.email="+cfg.Email)
 	}
+	if cfg.ExcludesFile != "" {
+		lines = append(lines, "core.excludesFile="+cfg.ExcludesFile)
+	}
+	if cfg.Pager != "" {
+		lines = append(lines, "core.pager="+cfg.Pager)
+	}
+	if cfg.ColorUI != "" {
+		lines = append(lines, "color.ui="+cfg.ColorUI)
+	}
+	if cfg.CommitTemplate != "" {
+		lines = append(lines, "commit.template="+cfg.CommitTemplate)
+	}
 	if cfg.OrchardURL != "" {
 		lines = append(lines, "orchard.url="+cfg.OrchardURL)
 	}
@@ -309,8 +729,88 @@ func formatRepoConfig(cfg *repo.Config) []string {
 			lines = append(lines, "user.email="+cfg.User.Email)
 		}
 	}
+	if cfg.Storage != nil {
+		lines = append(lines, "storage.chunkLargeBlobs="+strconv.FormatBool(cfg.Storage.ChunkLargeBlobs))
+		if cfg.Storage.ChunkMinSize > 0 {
+			lines = append(lines, "storage.chunkMinSize="+strconv.FormatInt(cfg.Storage.ChunkMinSize, 10))
+		}
+		if cfg.Storage.Encrypt {
+			lines = append(lines, "storage.encrypt=true")
+		}
+		if cfg.Storage.EncryptionKeyFile != "" {
+			lines = append(lines, "storage.encryptionKeyFile="+cfg.Storage.EncryptionKeyFile)
+		}
+	}
+	if cfg.Core != nil && cfg.Core.FSMonitor != "" {
+		lines = append(lines, "core.fsmonitor="+cfg.Core.FSMonitor)
+	}
+	if cfg.Core != nil && cfg.Core.FileMode != nil {
+		lines = append(lines, "core.filemode="+strconv.FormatBool(*cfg.Core.FileMode))
+	}
+	if cfg.Core != nil && cfg.Core.IgnoreCase != nil {
+		lines = append(lines, "core.ignorecase="+strconv.FormatBool(*cfg.Core.IgnoreCase))
+	}
+	if cfg.Core != nil && cfg.Core.AutoCRLF != "" {
+		lines = append(lines, "core.autocrlf="+cfg.Core.AutoCRLF)
+	}
+	if cfg.Core != nil && cfg.Core.AutoStash {
+		lines = append(lines, "core.autostash=true")
+	}
+	if cfg.Core != nil && cfg.Core.GitSync {
+		lines = append(lines, "core.gitSync=true")
+	}
+	if cfg.Core != nil && cfg.Core.Pager != "" {
+		lines = append(lines, "core.pager="+cfg.Core.Pager)
+	}
+	if cfg.Color != nil && cfg.Color.UI != "" {
+		lines = append(lines, "color.ui="+cfg.Color.UI)
+	}
+	if cfg.GC != nil {
+		if cfg.GC.Auto != nil {
+			lines = append(lines, "gc.auto="+strconv.Itoa(*cfg.GC.Auto))
+		}
+		if cfg.GC.AutoPackLimit != nil {
+			lines = append(lines, "gc.autoPackLimit="+strconv.Itoa(*cfg.GC.AutoPackLimit))
+		}
+		if cfg.GC.AggressiveWindow > 0 {
+			lines = append(lines, "gc.aggressiveWindow="+strconv.Itoa(cfg.GC.AggressiveWindow))
+		}
+	}
+	if cfg.Commit != nil {
+		if cfg.Commit.Template != "" {
+			lines = append(lines, "commit.template="+cfg.Commit.Template)
+		}
+		if cfg.Commit.Conventional {
+			lines = append(lines, "commit.conventional=true")
+		}
+		if len(cfg.Commit.ConventionalTypes) > 0 {
+			lines = append(lines, "commit.conventionalTypes="+strings.Join(cfg.Commit.ConventionalTypes, " "))
+		}
+		if cfg.Commit.MaxSubjectLength > 0 {
+			lines = append(lines, "commit.maxSubjectLength="+strconv.Itoa(cfg.Commit.MaxSubjectLength))
+		}
+	}
 	for name, url := range cfg.Remotes {
 		lines = append(lines, "remote."+name+".url="+url)
+		if rc := cfg.RemoteSettings[name]; rc != nil {
+			if len(rc.Fetch) > 0 {
+				lines = append(lines, "remote."+name+".fetch="+strings.Join(rc.Fetch, " "))
+			}
+			if len(rc.Push) > 0 {
+				lines = append(lines, "remote."+name+".push="+strings.Join(rc.Push, " "))
+			}
+			if rc.Mirror {
+				lines = append(lines, "remote."+name+".mirror=true")
+			}
+		}
+	}
+	for name, f := range cfg.Filters {
+		if f.Clean != "" {
+			lines = append(lines, "filter."+name+".clean="+f.Clean)
+		}
+		if f.Smudge != "" {
+			lines = append(lines, "filter."+name+".smudge="+f.Smudge)
+		}
 	}
 	return lines
 }
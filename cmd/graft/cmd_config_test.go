@@ -176,3 +176,93 @@ func TestFormatUserConfigIncludesOrchardProfiles(t *testing.T) {
 		t.Fatalf("formatUserConfig leaked token values:\n%s", out)
 	}
 }
+
+func TestIntegration_ConfigStorageChunking(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	if out := mustRunGraft(t, dir, "config", "storage.chunkMinSize"); strings.TrimSpace(out) != "" {
+		t.Fatalf("unset storage.chunkMinSize = %q, want empty", out)
+	}
+	mustRunGraft(t, dir, "config", "storage.chunkLargeBlobs", "true")
+	mustRunGraft(t, dir, "config", "storage.chunkMinSize", "65536")
+	if _, err := runGraft(t, dir, "config", "storage.chunkLargeBlobs", "maybe"); err == nil {
+		t.Fatal("expected invalid boolean to be rejected")
+	}
+
+	var lock strings.Builder
+	for i := 0; i < 5000; i++ {
+		lock.WriteString("dependency-" + strings.Repeat("x", i%40) + "\n")
+	}
+	commitFile(t, dir, "deps.lock", lock.String(), "add lockfile")
+
+	out := mustRunGraft(t, dir, "count-objects", "-v")
+	if !strings.Contains(out, "type chunk:") {
+		t.Fatalf("expected chunk objects after committing a large lockfile, got:\n%s", out)
+	}
+	if got := mustRunGraft(t, dir, "config", "--list"); !strings.Contains(got, "storage.chunkMinSize=65536") {
+		t.Fatalf("config --list missing storage settings: %s", got)
+	}
+}
+
+func TestIntegration_ConfigCoreFileMode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	if out := mustRunGraft(t, dir, "config", "core.filemode"); strings.TrimSpace(out) != "" {
+		t.Fatalf("unset core.filemode = %q, want empty", out)
+	}
+	mustRunGraft(t, dir, "config", "core.filemode", "false")
+	if out := mustRunGraft(t, dir, "config", "core.filemode"); strings.TrimSpace(out) != "false" {
+		t.Fatalf("core.filemode = %q, want false", out)
+	}
+	if _, err := runGraft(t, dir, "config", "core.filemode", "sometimes"); err == nil {
+		t.Fatal("expected invalid boolean to be rejected")
+	}
+	if got := mustRunGraft(t, dir, "config", "--list"); !strings.Contains(got, "core.filemode=false") {
+		t.Fatalf("config --list missing core.filemode: %s", got)
+	}
+
+	mustRunGraft(t, dir, "config", "core.ignorecase", "true")
+	if out := mustRunGraft(t, dir, "config", "core.ignorecase"); strings.TrimSpace(out) != "true" {
+		t.Fatalf("core.ignorecase = %q, want true", out)
+	}
+	if got := mustRunGraft(t, dir, "config", "--list"); !strings.Contains(got, "core.ignorecase=true") {
+		t.Fatalf("config --list missing core.ignorecase: %s", got)
+	}
+
+	mustRunGraft(t, dir, "config", "core.autocrlf", "input")
+	if out := mustRunGraft(t, dir, "config", "core.autocrlf"); strings.TrimSpace(out) != "input" {
+		t.Fatalf("core.autocrlf = %q, want input", out)
+	}
+	if _, err := runGraft(t, dir, "config", "core.autocrlf", "sometimes"); err == nil {
+		t.Fatal("expected invalid core.autocrlf to be rejected")
+	}
+}
+
+func TestIntegration_ConfigFilterCommands(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	mustRunGraft(t, dir, "config", "filter.nbstrip.clean", "jq -S .")
+	if out := mustRunGraft(t, dir, "config", "filter.nbstrip.clean"); strings.TrimSpace(out) != "jq -S ." {
+		t.Fatalf("filter.nbstrip.clean = %q, want %q", out, "jq -S .")
+	}
+	if got := mustRunGraft(t, dir, "config", "--list"); !strings.Contains(got, "filter.nbstrip.clean=jq -S .") {
+		t.Fatalf("config --list missing filter.nbstrip.clean: %s", got)
+	}
+
+	mustRunGraft(t, dir, "config", "filter.nbstrip.clean", "")
+	if got := mustRunGraft(t, dir, "config", "--list"); strings.Contains(got, "filter.nbstrip") {
+		t.Fatalf("filter.nbstrip still listed after removal: %s", got)
+	}
+	if _, err := runGraft(t, dir, "config", "filter.nbstrip.process", "x"); err == nil {
+		t.Fatal("expected unknown filter field to be rejected")
+	}
+}
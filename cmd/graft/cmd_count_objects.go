@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newCountObjectsCmd() *cobra.Command {
+	var verbose bool
+	var humanReadable bool
+	var top int
+	var jsonFlag bool
+
+	cmd := &cobra.Command{
+		Use:   "count-objects",
+		Short: "Report object store storage statistics",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+
+			largest := 0
+			if verbose || jsonFlag {
+				largest = top
+			}
+			stats, err := r.Store.CountObjects(largest)
+			if err != nil {
+				return err
+			}
+
+			if jsonFlag {
+				return writeJSON(cmd.OutOrStdout(), jsonCountObjects(stats))
+			}
+			printCountObjects(cmd.OutOrStdout(), stats, verbose, humanReadable)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show pack statistics, per-type breakdown, and largest objects")
+	cmd.Flags().BoolVarP(&humanReadable, "human-readable", "H", false, "print sizes in human-readable units")
+	cmd.Flags().IntVar(&top, "top", 10, "number of largest objects to report with --verbose or --json")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
+	return cmd
+}
+
+func printCountObjects(w io.Writer, stats *object.ObjectStats, verbose, humanReadable bool) {
+	size := func(n int64) string {
+		if humanReadable {
+			return formatBinaryBytes(n)
+		}
+		return fmt.Sprintf("%d", n)
+	}
+
+	fmt.Fprintf(w, "count: %d\n", stats.LooseObjects)
+	fmt.Fprintf(w, "size: %s\n", size(stats.LooseSize))
+	if !verbose {
+		return
+	}
+	fmt.Fprintf(w, "in-pack: %d\n", stats.PackedObjects)
+	fmt.Fprintf(w, "packs: %d\n", stats.PackFiles)
+	fmt.Fprintf(w, "size-pack: %s\n", size(stats.PackSize))
+
+	for _, objType := range sortedObjectTypes(stats.ByType) {
+		ts := stats.ByType[objType]
+		fmt.Fprintf(w, "type %s: %d object(s), %s\n", objType, ts.Count, size(ts.Size))
+	}
+
+	if len(stats.Largest) > 0 {
+		fmt.Fprintln(w, "largest:")
+		for _, obj := range stats.Largest {
+			where := "loose"
+			if obj.Packed {
+				where = "packed"
+			}
+			fmt.Fprintf(w, "  %s %-10s %s (%s)\n", shortHash(obj.Hash), obj.Type, size(obj.Size), where)
+		}
+	}
+}
+
+func sortedObjectTypes(byType map[object.ObjectType]object.ObjectTypeStats) []object.ObjectType {
+	types := make([]object.ObjectType, 0, len(byType))
+	for objType := range byType {
+		types = append(types, objType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+func jsonCountObjects(stats *object.ObjectStats) JSONCountObjectsOutput {
+	out := JSONCountObjectsOutput{
+		LooseObjects:  stats.LooseObjects,
+		LooseSize:     stats.LooseSize,
+		PackFiles:     stats.PackFiles,
+		PackSize:      stats.PackSize,
+		PackedObjects: stats.PackedObjects,
+		ByType:        make(map[string]JSONCountObjectsType, len(stats.ByType)),
+	}
+	for objType, ts := range stats.ByType {
+		out.ByType[string(objType)] = JSONCountObjectsType{Count: ts.Count, SizeBytes: ts.Size}
+	}
+	for _, obj := range stats.Largest {
+		out.Largest = append(out.Largest, JSONVerifySizedObject{
+			Hash:      string(obj.Hash),
+			ShortHash: shortHash(obj.Hash),
+			Type:      string(obj.Type),
+			SizeBytes: obj.Size,
+		})
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+func TestCountObjectsCmdVerboseReportsPacksAndTypes(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+
+	writeGcCmdFile(t, filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"))
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("initial", "tester"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if _, err := r.GC(); err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	var out bytes.Buffer
+	cmd := newCountObjectsCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"-v"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("count-objects Execute: %v\noutput:\n%s", err, out.String())
+	}
+	for _, want := range []string{"count: ", "packs: 1", "type commit: 1 object(s)", "largest:"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("count-objects output = %q, want to contain %q", out.String(), want)
+		}
+	}
+}
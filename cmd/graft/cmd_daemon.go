@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/odvcencio/graft/pkg/watchd"
+	"github.com/spf13/cobra"
+)
+
+func newDaemonCmd() *cobra.Command {
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Watch the repository and publish change events over a unix socket",
+		Long: `Daemon watches the working tree and refs, keeps the status and the
+entities of changed files warm, and publishes change events on the unix
+socket .graft/daemon.sock until interrupted.
+
+Clients write JSON lines to the socket: {"method":"status"} and
+{"method":"entities","path":"<path>"} are answered with one JSON line from
+the caches; {"method":"subscribe"} streams events as JSON lines:
+head_changed, ref_changed, status_changed, file_changed and
+entities_changed, the last listing the declarations added, removed and
+modified by an edit. "graft daemon status", "graft daemon entities" and
+"graft daemon events" are such clients.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			l, err := watchd.Listen(r.GraftDir)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "graft daemon listening on %s\n", l.Addr())
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return watchd.New(r, watchd.Options{Interval: interval, Log: cmd.ErrOrStderr()}).Run(ctx, l)
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 500*time.Millisecond, "how often to poll the working tree and refs")
+	cmd.AddCommand(newDaemonStatusCmd())
+	cmd.AddCommand(newDaemonEntitiesCmd())
+	cmd.AddCommand(newDaemonEventsCmd())
+	return cmd
+}
+
+func newDaemonStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Print the daemon's cached status as JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			resp, err := queryDaemon(r, watchd.Request{Method: "status"})
+			if err != nil {
+				return err
+			}
+			return writeJSON(cmd.OutOrStdout(), resp.Status)
+		},
+	}
+}
+
+func newDaemonEntitiesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "entities <path>",
+		Short:             "Print the entities of a working tree file, from the daemon's cache, as JSON",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTrackedPaths,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			abs, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(r.RootDir, abs)
+			if err != nil {
+				return err
+			}
+			resp, err := queryDaemon(r, watchd.Request{Method: "entities", Path: filepath.ToSlash(rel)})
+			if err != nil {
+				return err
+			}
+			return writeJSON(cmd.OutOrStdout(), resp.Entities)
+		},
+	}
+}
+
+func newDaemonEventsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "events",
+		Short: "Print the daemon's change events as JSON lines until interrupted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			conn, err := watchd.Dial(r.GraftDir)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			if err := json.NewEncoder(conn).Encode(watchd.Request{Method: "subscribe"}); err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				conn.Close()
+			}()
+			if _, err := io.Copy(cmd.OutOrStdout(), conn); err != nil && ctx.Err() == nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// queryDaemon sends req to the daemon of r and returns its response.
+func queryDaemon(r *repo.Repo, req watchd.Request) (*watchd.Response, error) {
+	conn, err := watchd.Dial(r.GraftDir)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read daemon response: %w", err)
+	}
+	var resp watchd.Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("decode daemon response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("daemon: %s", resp.Error)
+	}
+	return &resp, nil
+}
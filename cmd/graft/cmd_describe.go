@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newDescribeCmd() *cobra.Command {
+	var tags, always, long bool
+	var dirtyMark string
+
+	cmd := &cobra.Command{
+		Use:   "describe [--dirty[=<mark>]] [<commit-ish>]",
+		Short: "Name a commit after the nearest tag it descends from",
+		Long: `Describe names a commit (HEAD by default) after the nearest annotated tag
+it descends from: the tag itself when the commit is tagged, otherwise
+<tag>-<n>-g<hash>, where n counts the commits made since the tag.
+
+--tags considers lightweight tags too. --always prints the abbreviated
+hash when no tag reaches the commit, and --long uses the
+<tag>-<n>-g<hash> form even for a tagged commit.
+
+--dirty appends "-dirty", or the given mark, when tracked files in the
+working tree or staging area differ from HEAD, so a build stamped with the
+description shows it came from uncommitted changes. Untracked files are
+ignored.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			dirty := cmd.Flags().Changed("dirty")
+			rev := ""
+			if len(args) == 1 {
+				rev = args[0]
+			}
+			d, err := r.Describe(rev, repo.DescribeOptions{Tags: tags, Always: always, Dirty: dirty})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), d.Format(long, dirtyMark))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&tags, "tags", false, "use lightweight tags as well as annotated ones")
+	cmd.Flags().BoolVar(&always, "always", false, "show the abbreviated hash when no tag reaches the commit")
+	cmd.Flags().BoolVar(&long, "long", false, "always use the <tag>-<n>-g<hash> form")
+	cmd.Flags().StringVar(&dirtyMark, "dirty", "", "append a mark (default \"-dirty\") when the working tree has uncommitted changes")
+	cmd.Flags().Lookup("dirty").NoOptDefVal = repo.DefaultDirtyMark
+	return cmd
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDescribeDirtyIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	dir := initRepo(t)
+	commitFile(t, dir, "notes.txt", "one\n", "first")
+	mustRunGraft(t, dir, "tag", "-m", "release", "v1.0")
+
+	if got := strings.TrimSpace(mustRunGraft(t, dir, "describe", "--dirty")); got != "v1.0" {
+		t.Fatalf("describe --dirty on a clean tree = %q, want v1.0", got)
+	}
+	writeFile(t, dir, "notes.txt", "two\n")
+	if got := strings.TrimSpace(mustRunGraft(t, dir, "describe", "--dirty")); got != "v1.0-dirty" {
+		t.Fatalf("describe --dirty on a modified tree = %q, want v1.0-dirty", got)
+	}
+	if got := strings.TrimSpace(mustRunGraft(t, dir, "describe", "--dirty=.mod")); got != "v1.0.mod" {
+		t.Fatalf("describe --dirty=.mod = %q, want v1.0.mod", got)
+	}
+	mustRunGraft(t, dir, "add", "notes.txt")
+	mustRunGraft(t, dir, "commit", "-m", "second")
+	got := strings.TrimSpace(mustRunGraft(t, dir, "describe", "--dirty"))
+	if !strings.HasPrefix(got, "v1.0-1-g") || strings.HasSuffix(got, "-dirty") {
+		t.Fatalf("describe --dirty after a commit = %q, want v1.0-1-g<hash>", got)
+	}
+}
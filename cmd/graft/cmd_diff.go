@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
@@ -13,6 +12,7 @@ import (
 	"github.com/odvcencio/graft/pkg/diff"
 	"github.com/odvcencio/graft/pkg/diff3"
 	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/pathspec"
 	"github.com/odvcencio/graft/pkg/repo"
 	"github.com/spf13/cobra"
 )
@@ -25,80 +25,223 @@ func newDiffCmd() *cobra.Command {
 	var jsonFlag bool
 	var reviewFlag bool
 	var coordFlag bool
+	var wordDiff string
+	var colorWords bool
+	var dirstatFlag string
+	var statFlag bool
+	var exitCodeFlag bool
+	var quietFlag bool
+	// changes sees the output under --exit-code and --quiet, so PostRunE
+	// can tell whether there were differences.
+	var changes *writeDetector
 
 	cmd := &cobra.Command{
-		Use:   "diff [ref1..ref2]",
-		Short: "Show changes between working tree, staging, HEAD, or two refs",
-		Args:  cobra.MaximumNArgs(1),
+		Use:   "diff [<rev1> <rev2> | <rev1>..<rev2> | <rev1>...<rev2>] [-- <pathspec>...]",
+		Short: "Show changes between working tree, staging, HEAD, or two revisions",
+		Long: `Show changes between working tree, staging, HEAD, or two revisions.
+
+Two revisions, given as "rev1 rev2" or "rev1..rev2", compare any two commits
+or trees (such as HEAD:dir). "rev1...rev2" compares the merge base of rev1
+and rev2 with rev2, showing only the changes made on rev2's side. An omitted
+side of a range means HEAD.
+
+Pathspecs after -- limit the diff to matching files. They accept globs with
+recursive "**" and the magic prefixes :(exclude) (or :!), :(icase),
+:(literal), :(glob) and :(top) (or :/).
+
+--entity reports each changed declaration as added, removed, modified,
+renamed or moved, naming it by its signature, instead of printing line hunks.
+Files whose language has no entity support fall back to a line diff.
+
+--word-diff marks the changed words within each hunk instead of whole
+lines, as [-removed-]{+added+} (plain, the default) or in red and green
+(color, also selected by --color-words).
+
+--stat prints, instead of the diff, the number of lines added and removed
+in each file with a bar of + and -, then the totals; with --json the same
+counts are written as JSON.
+
+--exit-code makes diff exit with 1 when there are differences and 0 when
+there are none; --quiet (-q) does the same without printing the diff.
+
+--dirstat prints, instead of the diff, the share of the changed lines made
+in each directory, leaving out directories under 3% of the total; their
+changes count toward the parent directory instead. It takes
+comma-separated parameters: a different limit in percent (--dirstat=10),
+"files" to count changed files instead of lines, and "cumulative" to count
+a directory's changes toward its parents even when it is listed itself.
+
+--staged --entity (or --cached --entity) lists the entities added, modified
+and removed in each file between HEAD and the staging area, from the entity
+lists recorded when the files were staged.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if n := argsBeforeDash(cmd, args); n > 2 {
+				return fmt.Errorf("accepts at most 2 revisions before --, received %d", n)
+			}
+			return nil
+		},
+		ValidArgsFunction: completeRefsThenPaths(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
 				return err
 			}
+			filter, err := parsePathspecArgs(r, args[argsBeforeDash(cmd, args):])
+			if err != nil {
+				return err
+			}
+			args = args[:argsBeforeDash(cmd, args)]
 			if reviewFlag && entity {
 				return fmt.Errorf("--review and --entity cannot be combined")
 			}
 			if reviewFlag && jsonFlag {
 				return fmt.Errorf("--review and --json cannot be combined")
 			}
+			words, err := parseWordDiffMode(wordDiff, colorWords)
+			if err != nil {
+				return err
+			}
+			if words != wordDiffNone && (entity || reviewFlag || jsonFlag) {
+				return fmt.Errorf("--word-diff cannot be combined with --entity, --review or --json")
+			}
+			var stat diffSummary
+			if cmd.Flags().Changed("dirstat") {
+				if entity || reviewFlag || jsonFlag || words != wordDiffNone || coordFlag || statFlag {
+					return fmt.Errorf("--dirstat cannot be combined with --entity, --review, --word-diff, --json, --coord or --stat")
+				}
+				if stat, err = parseDirstat(dirstatFlag); err != nil {
+					return err
+				}
+			}
+			var lineStat *diffstat
+			if statFlag {
+				if entity || reviewFlag || words != wordDiffNone || coordFlag {
+					return fmt.Errorf("--stat cannot be combined with --entity, --review, --word-diff or --coord")
+				}
+				lineStat = &diffstat{}
+				stat = lineStat
+			}
+			// writeStat prints the collected --stat or --dirstat summary.
+			writeStat := func() error {
+				if lineStat != nil && jsonFlag {
+					return writeJSON(cmd.OutOrStdout(), lineStat.summary())
+				}
+				stat.write(cmd.OutOrStdout())
+				return nil
+			}
+
+			if exitCodeFlag || quietFlag {
+				if jsonFlag || cmd.Flags().Changed("dirstat") {
+					return fmt.Errorf("--exit-code and --quiet cannot be combined with --json or --dirstat")
+				}
+				changes = &writeDetector{w: cmd.OutOrStdout()}
+				if quietFlag {
+					changes.w = io.Discard
+				}
+			}
+			if !jsonFlag && !quietFlag && colorEnabled(cmd) {
+				colored := newDiffColorWriter(cmd.OutOrStdout())
+				cmd.SetOut(colored)
+				defer colored.Flush()
+				if changes != nil {
+					changes.w = colored
+				}
+			}
+			if changes != nil {
+				cmd.SetOut(changes)
+			}
 
-			// Handle ref1..ref2 range argument.
-			if len(args) == 1 {
-				parts := strings.SplitN(args[0], "..", 2)
-				if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-					return fmt.Errorf("invalid ref range %q: expected format ref1..ref2", args[0])
+			// Handle two revisions, or a rev1..rev2 / rev1...rev2 range.
+			if len(args) > 0 {
+				if len(args) == 1 && !strings.Contains(args[0], "..") {
+					return fmt.Errorf("invalid ref range %q: expected format ref1..ref2, ref1...ref2 or two revisions", args[0])
 				}
 				if staged {
 					return fmt.Errorf("--staged cannot be used with ref range")
 				}
-				if jsonFlag {
-					if entity {
-						return fmt.Errorf("--json and --entity cannot be combined")
-					}
-					return diffRefsJSON(cmd, r, parts[0], parts[1])
+				if jsonFlag && entity {
+					return fmt.Errorf("--json and --entity cannot be combined")
+				}
+				var report *repo.CommitDiffReport
+				if len(args) == 2 {
+					report, err = r.DiffRevisions(args[0], args[1])
+				} else {
+					report, err = r.DiffRange(args[0])
+				}
+				if err != nil {
+					return err
+				}
+				if jsonFlag && stat == nil {
+					return diffRefsJSON(cmd, r, report, filter)
+				}
+				if err := diffRefs(cmd, r, report, entity, reviewFlag, words, stat, filter); err != nil {
+					return err
+				}
+				if stat != nil {
+					return writeStat()
 				}
-				return diffRefs(cmd, r, parts[0], parts[1], entity, reviewFlag)
+				return nil
 			}
 
-			if jsonFlag {
+			if jsonFlag && stat == nil {
 				if entity {
 					return fmt.Errorf("--json and --entity cannot be combined")
 				}
 				if staged {
-					return diffStagedJSON(cmd, r)
+					return diffStagedJSON(cmd, r, filter)
 				}
-				return diffUnstagedJSON(cmd, r)
+				return diffUnstagedJSON(cmd, r, filter)
 			}
 
 			var result error
-			if staged {
-				result = diffStaged(cmd, r, entity, reviewFlag)
+			if staged && entity {
+				result = diffStagedEntities(cmd, r, filter)
+			} else if staged {
+				result = diffStaged(cmd, r, entity, reviewFlag, words, stat, filter)
 			} else {
-				result = diffUnstaged(cmd, r, entity, reviewFlag)
+				result = diffUnstaged(cmd, r, entity, reviewFlag, words, stat, filter)
+			}
+			if stat != nil && result == nil {
+				result = writeStat()
 			}
 
 			// If --coord is set, annotate with claim info for changed files
 			if coordFlag && result == nil {
-				if err := printCoordAnnotations(cmd, r, staged); err != nil {
+				if err := printCoordAnnotations(cmd, r, staged, filter); err != nil {
 					fmt.Fprintf(cmd.ErrOrStderr(), "coord: %v\n", err)
 				}
 			}
 
 			return result
 		},
+		PostRunE: func(cmd *cobra.Command, args []string) error {
+			if changes != nil && changes.wrote {
+				return exitStatus(exitFailure)
+			}
+			return nil
+		},
 	}
 
 	cmd.Flags().BoolVar(&staged, "staged", false, "show staged changes (staging vs HEAD)")
+	cmd.Flags().BoolVar(&staged, "cached", false, "synonym for --staged")
 	cmd.Flags().BoolVar(&entity, "entity", false, "show entity-level structural diff")
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
 	cmd.Flags().BoolVar(&reviewFlag, "review", false, "show structural code review format")
 	cmd.Flags().BoolVar(&coordFlag, "coord", false, "annotate diff with coordination claim info")
+	cmd.Flags().StringVar(&wordDiff, "word-diff", "none", "show changed words within lines: plain, color or none")
+	cmd.Flags().Lookup("word-diff").NoOptDefVal = "plain"
+	cmd.Flags().BoolVar(&colorWords, "color-words", false, "show changed words in color; same as --word-diff=color")
+	cmd.Flags().BoolVar(&exitCodeFlag, "exit-code", false, "exit with status 1 when there are differences")
+	cmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "print nothing; exit with status 1 when there are differences")
+	cmd.Flags().BoolVar(&statFlag, "stat", false, "show the lines added and removed per file instead of the diff")
+	cmd.Flags().StringVar(&dirstatFlag, "dirstat", "", "show the share of changes per directory instead of the diff (limit%, files, cumulative)")
+	cmd.Flags().Lookup("dirstat").NoOptDefVal = "lines"
 
 	return cmd
 }
 
 // printCoordAnnotations shows active coordination claims for files that have changes.
-func printCoordAnnotations(cmd *cobra.Command, r *repo.Repo, staged bool) error {
+func printCoordAnnotations(cmd *cobra.Command, r *repo.Repo, staged bool, filter *pathspec.Set) error {
 	c := coord.New(r, coord.DefaultConfig)
 	out := cmd.OutOrStdout()
 
@@ -123,6 +266,9 @@ func printCoordAnnotations(cmd *cobra.Command, r *repo.Repo, staged bool) error
 			}
 		}
 		for p, se := range stg.Entries {
+			if !filter.Match(p) {
+				continue
+			}
 			headEntry, inHead := headMap[p]
 			if !inHead || headEntry.BlobHash != se.BlobHash {
 				changedFiles = append(changedFiles, p)
@@ -134,8 +280,10 @@ func printCoordAnnotations(cmd *cobra.Command, r *repo.Repo, staged bool) error
 			return err
 		}
 		for p, se := range stg.Entries {
-			absPath := filepath.Join(r.RootDir, filepath.FromSlash(p))
-			workData, err := os.ReadFile(absPath)
+			if !filter.Match(p) {
+				continue
+			}
+			workData, err := r.ReadWorktreeFile(p)
 			if err != nil {
 				changedFiles = append(changedFiles, p)
 				continue
@@ -173,7 +321,7 @@ func printCoordAnnotations(cmd *cobra.Command, r *repo.Repo, staged bool) error
 }
 
 // diffUnstaged compares the working tree against the staging area.
-func diffUnstaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bool) error {
+func diffUnstaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bool, words wordDiffMode, stat diffSummary, filter *pathspec.Set) error {
 	stg, err := r.ReadStaging()
 	if err != nil {
 		return err
@@ -192,7 +340,9 @@ func diffUnstaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode
 	// Sort paths for deterministic output.
 	paths := make([]string, 0, len(stg.Entries))
 	for p := range stg.Entries {
-		paths = append(paths, p)
+		if matchRenamePathspec(filter, p, workRenamedOldToNew[p]) {
+			paths = append(paths, p)
+		}
 	}
 	sort.Strings(paths)
 
@@ -201,12 +351,13 @@ func diffUnstaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode
 	for _, p := range paths {
 		se := stg.Entries[p]
 
-		absPath := filepath.Join(r.RootDir, filepath.FromSlash(p))
-		workData, err := os.ReadFile(absPath)
+		workData, err := r.ReadWorktreeFile(p)
 		if err != nil {
 			if os.IsNotExist(err) {
 				if newPath, renamed := workRenamedOldToNew[p]; renamed {
-					printRename(out, p, newPath)
+					if stat == nil {
+						printRename(out, p, newPath)
+					}
 					continue
 				}
 				// File deleted from working tree -- show full deletion.
@@ -214,7 +365,7 @@ func diffUnstaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode
 				if blobErr != nil {
 					return fmt.Errorf("diff: read staged blob %s: %w", p, blobErr)
 				}
-				if err := printDiff(out, p, stagedBlob.Data, nil, entityMode, reviewMode); err != nil {
+				if err := printDiff(out, p, stagedBlob.Data, nil, entityMode, reviewMode, words, stat); err != nil {
 					return err
 				}
 				continue
@@ -233,7 +384,7 @@ func diffUnstaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode
 			return fmt.Errorf("diff: read staged blob %s: %w", p, err)
 		}
 
-		if err := printDiff(out, p, stagedBlob.Data, workData, entityMode, reviewMode); err != nil {
+		if err := printDiff(out, p, stagedBlob.Data, workData, entityMode, reviewMode, words, stat); err != nil {
 			return err
 		}
 	}
@@ -242,7 +393,7 @@ func diffUnstaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode
 }
 
 // diffStaged compares the staging area against the HEAD commit tree.
-func diffStaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bool) error {
+func diffStaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bool, words wordDiffMode, stat diffSummary, filter *pathspec.Set) error {
 	stg, err := r.ReadStaging()
 	if err != nil {
 		return err
@@ -278,7 +429,9 @@ func diffStaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bo
 	// Sort paths for deterministic output.
 	paths := make([]string, 0, len(stg.Entries))
 	for p := range stg.Entries {
-		paths = append(paths, p)
+		if matchRenamePathspec(filter, p, indexRenamedNewToOld[p]) {
+			paths = append(paths, p)
+		}
 	}
 	sort.Strings(paths)
 
@@ -287,7 +440,9 @@ func diffStaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bo
 	for _, p := range paths {
 		se := stg.Entries[p]
 		if oldPath, renamed := indexRenamedNewToOld[p]; renamed {
-			printRename(out, oldPath, p)
+			if stat == nil {
+				printRename(out, oldPath, p)
+			}
 			continue
 		}
 
@@ -310,7 +465,7 @@ func diffStaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bo
 			return fmt.Errorf("diff: read staged blob %s: %w", p, err)
 		}
 
-		if err := printDiff(out, p, before, stagedBlob.Data, entityMode, reviewMode); err != nil {
+		if err := printDiff(out, p, before, stagedBlob.Data, entityMode, reviewMode, words, stat); err != nil {
 			return err
 		}
 	}
@@ -318,7 +473,7 @@ func diffStaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bo
 	// Check for files deleted from staging that exist in HEAD.
 	deletedPaths := make([]string, 0)
 	for p := range headMap {
-		if _, inStaging := stg.Entries[p]; !inStaging {
+		if _, inStaging := stg.Entries[p]; !inStaging && filter.Match(p) {
 			deletedPaths = append(deletedPaths, p)
 		}
 	}
@@ -333,7 +488,7 @@ func diffStaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bo
 		if err != nil {
 			return fmt.Errorf("diff: read HEAD blob %s: %w", p, err)
 		}
-		if err := printDiff(out, p, blob.Data, nil, entityMode, reviewMode); err != nil {
+		if err := printDiff(out, p, blob.Data, nil, entityMode, reviewMode, words, stat); err != nil {
 			return err
 		}
 	}
@@ -341,15 +496,67 @@ func diffStaged(cmd *cobra.Command, r *repo.Repo, entityMode bool, reviewMode bo
 	return nil
 }
 
+// diffStagedEntities prints, file by file, the entities added, modified and
+// removed between HEAD and the staging area.
+func diffStagedEntities(cmd *cobra.Command, r *repo.Repo, filter *pathspec.Set) error {
+	changes, err := r.DiffStagedEntities()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	lastPath := ""
+	for _, c := range changes {
+		if !filter.Match(c.Path) {
+			continue
+		}
+		if c.Path != lastPath {
+			fmt.Fprintf(out, "%s:\n", c.Path)
+			lastPath = c.Path
+		}
+		marker := "~"
+		switch c.ChangeType {
+		case "added":
+			marker = "+"
+		case "removed":
+			marker = "-"
+		}
+		fmt.Fprintf(out, "  %s %s     (%s)\n", marker, c.DisplayName, c.ChangeType)
+	}
+	return nil
+}
+
+// writeDetector passes writes on to w and records whether there were
+// any, which for diff means there were differences.
+type writeDetector struct {
+	w     io.Writer
+	wrote bool
+}
+
+func (d *writeDetector) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		d.wrote = true
+	}
+	return d.w.Write(p)
+}
+
 // printDiff prints a diff for a single file. before or after may be nil for
-// additions and deletions respectively.
-func printDiff(out io.Writer, path string, before, after []byte, entityMode bool, reviewMode bool) error {
+// additions and deletions respectively. With stat set, the change is
+// recorded there for --stat or --dirstat instead.
+func printDiff(out io.Writer, path string, before, after []byte, entityMode bool, reviewMode bool, words wordDiffMode, stat diffSummary) error {
+	if stat != nil {
+		stat.add(path, before, after)
+		return nil
+	}
 	if reviewMode {
 		return printReviewDiff(out, path, before, after)
 	}
 	if entityMode {
 		return printEntityDiff(out, path, before, after)
 	}
+	if words != wordDiffNone {
+		return printWordDiff(out, path, before, after, words)
+	}
 	return printLineDiff(out, path, before, after)
 }
 
@@ -362,13 +569,13 @@ func printEntityDiff(out io.Writer, path string, before, after []byte) error {
 		after = []byte{}
 	}
 
-	fd, err := diff.DiffFiles(path, before, after)
+	fd, err := diff.StructuralDiff(path, before, after)
 	if err != nil {
 		// Entity extraction not supported for this file type; fall back to line diff.
 		return printLineDiff(out, path, before, after)
 	}
 
-	s := diff.FormatEntityDiff(fd)
+	s := diff.FormatStructuralDiff(fd)
 	if s != "" {
 		fmt.Fprint(out, s)
 	}
@@ -520,7 +727,7 @@ func printRename(out io.Writer, fromPath, toPath string) {
 }
 
 // diffUnstagedJSON collects unstaged diff data and writes JSON output.
-func diffUnstagedJSON(cmd *cobra.Command, r *repo.Repo) error {
+func diffUnstagedJSON(cmd *cobra.Command, r *repo.Repo, filter *pathspec.Set) error {
 	stg, err := r.ReadStaging()
 	if err != nil {
 		return err
@@ -538,7 +745,9 @@ func diffUnstagedJSON(cmd *cobra.Command, r *repo.Repo) error {
 
 	paths := make([]string, 0, len(stg.Entries))
 	for p := range stg.Entries {
-		paths = append(paths, p)
+		if matchRenamePathspec(filter, p, workRenamedOldToNew[p]) {
+			paths = append(paths, p)
+		}
 	}
 	sort.Strings(paths)
 
@@ -547,8 +756,7 @@ func diffUnstagedJSON(cmd *cobra.Command, r *repo.Repo) error {
 	for _, p := range paths {
 		se := stg.Entries[p]
 
-		absPath := filepath.Join(r.RootDir, filepath.FromSlash(p))
-		workData, err := os.ReadFile(absPath)
+		workData, err := r.ReadWorktreeFile(p)
 		if err != nil {
 			if os.IsNotExist(err) {
 				if newPath, renamed := workRenamedOldToNew[p]; renamed {
@@ -587,7 +795,7 @@ func diffUnstagedJSON(cmd *cobra.Command, r *repo.Repo) error {
 }
 
 // diffStagedJSON collects staged diff data and writes JSON output.
-func diffStagedJSON(cmd *cobra.Command, r *repo.Repo) error {
+func diffStagedJSON(cmd *cobra.Command, r *repo.Repo, filter *pathspec.Set) error {
 	stg, err := r.ReadStaging()
 	if err != nil {
 		return err
@@ -621,7 +829,9 @@ func diffStagedJSON(cmd *cobra.Command, r *repo.Repo) error {
 
 	paths := make([]string, 0, len(stg.Entries))
 	for p := range stg.Entries {
-		paths = append(paths, p)
+		if matchRenamePathspec(filter, p, indexRenamedNewToOld[p]) {
+			paths = append(paths, p)
+		}
 	}
 	sort.Strings(paths)
 
@@ -664,7 +874,7 @@ func diffStagedJSON(cmd *cobra.Command, r *repo.Repo) error {
 	// Check for files deleted from staging that exist in HEAD.
 	deletedPaths := make([]string, 0)
 	for p := range headMap {
-		if _, inStaging := stg.Entries[p]; !inStaging {
+		if _, inStaging := stg.Entries[p]; !inStaging && filter.Match(p) {
 			deletedPaths = append(deletedPaths, p)
 		}
 	}
@@ -685,25 +895,15 @@ func diffStagedJSON(cmd *cobra.Command, r *repo.Repo) error {
 	return writeJSON(cmd.OutOrStdout(), JSONDiffOutput{Files: files})
 }
 
-// diffRefs compares two refs and prints the text diff.
-func diffRefs(cmd *cobra.Command, r *repo.Repo, ref1, ref2 string, entityMode bool, reviewMode bool) error {
-	report, err := r.DiffRefs(ref1, ref2)
-	if err != nil {
-		return err
-	}
-
+// diffRefs prints the text diff between two revisions.
+func diffRefs(cmd *cobra.Command, r *repo.Repo, report *repo.CommitDiffReport, entityMode bool, reviewMode bool, words wordDiffMode, stat diffSummary, filter *pathspec.Set) error {
 	out := cmd.OutOrStdout()
 
-	// In entity-only mode, print entity changes and return.
-	if entityMode {
-		for _, ec := range report.EntityChanges {
-			fmt.Fprintf(out, "%s  %s  %s\n", ec.ChangeType, ec.Path, ec.EntityKey)
-		}
-		return nil
-	}
-
-	// Print file-level diffs.
+	// Print file-level diffs, or entity-level ones in entity mode.
 	for _, f := range report.Files {
+		if !filter.Match(f.Path) {
+			continue
+		}
 		var before, after []byte
 		if f.OldBlobHash != "" {
 			blob, err := r.Store.ReadBlob(f.OldBlobHash)
@@ -719,7 +919,7 @@ func diffRefs(cmd *cobra.Command, r *repo.Repo, ref1, ref2 string, entityMode bo
 			}
 			after = blob.Data
 		}
-		if err := printDiff(out, f.Path, before, after, false, reviewMode); err != nil {
+		if err := printDiff(out, f.Path, before, after, entityMode, reviewMode, words, stat); err != nil {
 			return err
 		}
 	}
@@ -727,15 +927,13 @@ func diffRefs(cmd *cobra.Command, r *repo.Repo, ref1, ref2 string, entityMode bo
 	return nil
 }
 
-// diffRefsJSON compares two refs and writes JSON output.
-func diffRefsJSON(cmd *cobra.Command, r *repo.Repo, ref1, ref2 string) error {
-	report, err := r.DiffRefs(ref1, ref2)
-	if err != nil {
-		return err
-	}
-
+// diffRefsJSON writes the JSON output for a diff between two revisions.
+func diffRefsJSON(cmd *cobra.Command, r *repo.Repo, report *repo.CommitDiffReport, filter *pathspec.Set) error {
 	files := make([]JSONDiffFile, 0, len(report.Files))
 	for _, f := range report.Files {
+		if !filter.Match(f.Path) {
+			continue
+		}
 		var before, after []byte
 		if f.OldBlobHash != "" {
 			blob, err := r.Store.ReadBlob(f.OldBlobHash)
@@ -756,6 +954,9 @@ func diffRefsJSON(cmd *cobra.Command, r *repo.Repo, ref1, ref2 string) error {
 
 	var entityChanges []JSONDiffEntityChange
 	for _, ec := range report.EntityChanges {
+		if !filter.Match(ec.Path) {
+			continue
+		}
 		entityChanges = append(entityChanges, JSONDiffEntityChange{
 			Path:       ec.Path,
 			EntityKey:  ec.EntityKey,
@@ -96,3 +96,177 @@ func makeNumberedLines(n int) []string {
 	}
 	return lines
 }
+
+func TestDiffPathspecIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	writeFile(t, dir, "src/main.go", "package main\n")
+	writeFile(t, dir, "src/vendor/lib.go", "package lib\n")
+	writeFile(t, dir, "notes.txt", "notes\n")
+	mustRunGraft(t, dir, "add", ".")
+	mustRunGraft(t, dir, "commit", "-m", "initial", "--author", "Test User", "--no-sign")
+
+	writeFile(t, dir, "src/main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, dir, "src/vendor/lib.go", "package lib\n\nvar X = 1\n")
+	writeFile(t, dir, "notes.txt", "more notes\n")
+
+	out := mustRunGraft(t, dir, "diff", "--", "src", ":(exclude)src/vendor")
+	if !strings.Contains(out, "src/main.go") {
+		t.Fatalf("diff output missing src/main.go:\n%s", out)
+	}
+	for _, unwanted := range []string{"src/vendor/lib.go", "notes.txt"} {
+		if strings.Contains(out, unwanted) {
+			t.Fatalf("diff output includes excluded %s:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestDiffCachedEntityIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	writeFile(t, dir, "main.go", "package main\n\nfunc A() {}\n\nfunc B() {}\n")
+	mustRunGraft(t, dir, "add", "main.go")
+	mustRunGraft(t, dir, "commit", "-m", "initial", "--author", "Test User", "--no-sign")
+
+	writeFile(t, dir, "main.go", "package main\n\nfunc A() { println() }\n\nfunc C() {}\n")
+	mustRunGraft(t, dir, "add", "main.go")
+
+	out := mustRunGraft(t, dir, "diff", "--cached", "--entity")
+	for _, want := range []string{"main.go:", "~ func A", "+ func C", "- func B"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("diff --cached --entity output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDiffRevisionsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "base.txt", "base\n", "initial")
+	branch := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "--abbrev-ref", "HEAD"))
+	mustRunGraft(t, dir, "branch", "feature")
+	commitFile(t, dir, "main.txt", "main\n", "main work")
+	mustRunGraft(t, dir, "switch", "feature")
+	commitFile(t, dir, "src/feature.txt", "feature\n", "feature work")
+
+	both := mustRunGraft(t, dir, "diff", branch, "feature")
+	if !strings.Contains(both, "main.txt") || !strings.Contains(both, "src/feature.txt") {
+		t.Fatalf("diff %s feature missing changes:\n%s", branch, both)
+	}
+	if dots := mustRunGraft(t, dir, "diff", branch+"..feature"); dots != both {
+		t.Fatalf("diff %s..feature differs from two-revision form:\n%s", branch, dots)
+	}
+
+	sinceBase := mustRunGraft(t, dir, "diff", branch+"...feature")
+	if !strings.Contains(sinceBase, "src/feature.txt") || strings.Contains(sinceBase, "main.txt") {
+		t.Fatalf("diff %s...feature should show only feature changes:\n%s", branch, sinceBase)
+	}
+
+	limited := mustRunGraft(t, dir, "diff", branch, "feature", "--", "src")
+	if strings.Contains(limited, "main.txt") || !strings.Contains(limited, "src/feature.txt") {
+		t.Fatalf("pathspec did not limit the diff:\n%s", limited)
+	}
+
+	if _, err := runGraft(t, dir, "diff", branch, "feature", "HEAD"); err == nil {
+		t.Fatal("expected an error for three revisions")
+	}
+}
+
+func TestDiffEntityStructuralIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "main.go", `package main
+
+func Setup() {
+	println("one")
+	println("two")
+	println("three")
+}
+
+func Run() {
+	println("run")
+}
+
+func Helper() {
+	println("helper")
+}
+`, "initial")
+	writeFile(t, dir, "main.go", `package main
+
+func Helper() {
+	println("helper")
+}
+
+func Prepare() {
+	println("one")
+	println("two")
+	println("three")
+}
+
+func Run() {
+	println("run")
+}
+`)
+
+	out := mustRunGraft(t, dir, "diff", "--entity")
+	for _, want := range []string{"R func Setup() -> func Prepare()", "> func Helper()     (moved, line 13 -> 3)"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("diff --entity output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "func Run()") {
+		t.Fatalf("diff --entity reported the unchanged Run:\n%s", out)
+	}
+
+	mustRunGraft(t, dir, "add", "main.go")
+	mustRunGraft(t, dir, "commit", "-m", "rename and move", "--author", "Test User", "--no-sign")
+	if refs := mustRunGraft(t, dir, "diff", "--entity", "HEAD~1", "HEAD"); refs != out {
+		t.Fatalf("diff --entity HEAD~1 HEAD = %q, want the worktree diff %q", refs, out)
+	}
+}
+
+func TestDiffDirstatIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	dir := initRepo(t)
+	commitFile(t, dir, "docs/guide.md", "one\n", "docs")
+	commitFile(t, dir, "src/core/engine.go", "package core\n", "engine")
+
+	writeFile(t, dir, "docs/guide.md", "two\n")
+	writeFile(t, dir, "src/core/engine.go", "package core\n\nfunc A() {}\nfunc B() {}\nfunc C() {}\n")
+	out := mustRunGraft(t, dir, "diff", "--dirstat")
+	if want := "  33.3% docs/\n  66.6% src/core/\n"; out != want {
+		t.Fatalf("diff --dirstat =\n%q\nwant\n%q", out, want)
+	}
+	if out := mustRunGraft(t, dir, "diff", "--dirstat=cumulative,50"); out != "  66.6% src/\n  66.6% src/core/\n" {
+		t.Fatalf("diff --dirstat=cumulative,50 = %q", out)
+	}
+
+	mustRunGraft(t, dir, "add", "docs/guide.md", "src/core/engine.go")
+	if staged := mustRunGraft(t, dir, "diff", "--staged", "--dirstat"); staged != out {
+		t.Fatalf("diff --staged --dirstat = %q, want %q", staged, out)
+	}
+	mustRunGraft(t, dir, "commit", "-m", "both")
+	if refs := mustRunGraft(t, dir, "diff", "--dirstat", "HEAD~1..HEAD"); refs != out {
+		t.Fatalf("diff --dirstat HEAD~1..HEAD = %q, want %q", refs, out)
+	}
+	if show := mustRunGraft(t, dir, "show", "--dirstat=files"); !strings.HasSuffix(show, "\n  50.0% docs/\n  50.0% src/core/\n") {
+		t.Fatalf("show --dirstat=files =\n%s", show)
+	}
+	if out, err := runGraft(t, dir, "diff", "--dirstat", "--json"); err == nil {
+		t.Fatalf("diff --dirstat --json succeeded: %s", out)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegration_EncryptObjectStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	t.Setenv("GRAFT_ENCRYPTION_KEY", "")
+
+	dir := initRepo(t)
+	commitFile(t, dir, "notes.txt", "quarterly numbers\n", "add notes")
+	keyFile := filepath.Join(t.TempDir(), "repo.key")
+
+	out := mustRunGraft(t, dir, "encrypt", "--key-file", keyFile)
+	if !strings.Contains(out, "generated encryption key") || !strings.Contains(out, "encrypted") {
+		t.Fatalf("unexpected encrypt output:\n%s", out)
+	}
+	if got := mustRunGraft(t, dir, "config", "storage.encryptionKeyFile"); strings.TrimSpace(got) != keyFile {
+		t.Fatalf("storage.encryptionKeyFile = %q, want %q", got, keyFile)
+	}
+
+	commitFile(t, dir, "notes.txt", "quarterly numbers, revised\n", "revise notes")
+	mustRunGraft(t, dir, "gc")
+	if out := mustRunGraft(t, dir, "verify"); strings.Contains(strings.ToLower(out), "corrupt") {
+		t.Fatalf("verify reported corruption:\n%s", out)
+	}
+	if out := mustRunGraft(t, dir, "log"); !strings.Contains(out, "revise notes") {
+		t.Fatalf("log after encryption missing commit:\n%s", out)
+	}
+
+	packs, err := filepath.Glob(filepath.Join(dir, ".graft", "objects", "pack", "*.pack"))
+	if err != nil || len(packs) == 0 {
+		t.Fatalf("no packs after gc: %v", err)
+	}
+	for _, p := range packs {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "quarterly numbers") || strings.HasPrefix(string(data), "PACK") {
+			t.Fatalf("pack %s is not encrypted", filepath.Base(p))
+		}
+	}
+
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGraft(t, dir, "log"); err == nil {
+		t.Fatal("expected log to fail without the encryption key")
+	}
+}
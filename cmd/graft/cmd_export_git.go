@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newExportGitCmd() *cobra.Command {
+	var force bool
+	var newProgress func() *progressMeter
+
+	cmd := &cobra.Command{
+		Use:   "export-git [--force] <git-repo>",
+		Short: "Write the branches and tags into a git repository",
+		Long: `Export-git converts the repository's branches and tags into git objects
+and writes them into <git-repo>, without running git. It is the reverse of
+import-git. Merges, authors, committers, dates, messages, executable bits
+and symlinks carry over, and annotated tags stay annotated:
+
+  graft export-git ../published.git
+  git -C ../published.git push --mirror git@example.com:team/project.git
+
+When <git-repo> does not exist or is empty, a bare repository is created
+with HEAD naming the current branch. Exporting again only writes new
+history. Updates that are not fast-forwards, and changes to existing tags,
+are refused unless --force is given. Module entries are left out, as graft
+commits have no git hash to record as a gitlink.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+
+			progress := newProgress()
+			r.SetProgress(progress.Func())
+			res, err := r.ExportGit(cmd.Context(), args[0], repo.ExportGitOptions{Force: force})
+			progress.Done()
+			r.SetProgress(nil)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if res.Created {
+				fmt.Fprintf(out, "created bare git repository %s\n", args[0])
+			}
+			printFetchRefUpdates(cmd, res.UpdatedRefs, nil, res.RejectedRefs)
+			for _, name := range res.SkippedRefs {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: skipped %s: it does not name a commit\n", name)
+			}
+			fmt.Fprintf(out, "exported %d commit(s), %d blob(s) and %d tag(s)\n", res.Commits, res.Blobs, res.Tags)
+			if len(res.RejectedRefs) > 0 {
+				return fmt.Errorf("export-git: %d ref updates rejected; use --force to overwrite", len(res.RejectedRefs))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "allow non-fast-forward ref updates")
+	newProgress = addProgressFlag(cmd)
+	return cmd
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newFastExportCmd() *cobra.Command {
+	var importMarks, exportMarks string
+
+	cmd := &cobra.Command{
+		Use:   "fast-export [--import-marks=<file>] [--export-marks=<file>] [<ref>...]",
+		Short: "Write history as a git fast-import stream",
+		Long: `Fast-export writes the history of the named refs, or of every branch and
+tag, to stdout in git's fast-import format:
+
+  graft fast-export | (cd ../mirror && git fast-import)
+
+--export-marks saves which commit and blob each mark in the stream stands
+for. A later run given the same file through --import-marks only writes
+history added since, for incremental mirroring; the importer must load its
+own marks from the previous run the same way.
+
+Entity lists are not part of the stream, module entries are left out and
+commit signatures are dropped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			opts := repo.FastExportOptions{Refs: args}
+			if importMarks != "" {
+				if opts.Marks, err = repo.ReadFastMarks(importMarks); err != nil {
+					return fmt.Errorf("fast-export: %w", err)
+				}
+			}
+			if exportMarks != "" && opts.Marks == nil {
+				opts.Marks = make(map[int]object.Hash)
+			}
+
+			out := bufio.NewWriter(cmd.OutOrStdout())
+			if err := r.FastExport(out, opts); err != nil {
+				return err
+			}
+			if err := out.Flush(); err != nil {
+				return fmt.Errorf("fast-export: %w", err)
+			}
+			if exportMarks != "" {
+				if err := repo.WriteFastMarks(exportMarks, opts.Marks); err != nil {
+					return fmt.Errorf("fast-export: %w", err)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&importMarks, "import-marks", "", "load marks of a previous export and skip the history they cover")
+	cmd.Flags().StringVar(&exportMarks, "export-marks", "", "write the marks of exported objects to a file")
+	return cmd
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newFastImportCmd() *cobra.Command {
+	var importMarks, exportMarks string
+	var force, quiet bool
+
+	cmd := &cobra.Command{
+		Use:   "fast-import [--import-marks=<file>] [--export-marks=<file>] [--force] [--quiet]",
+		Short: "Read history from a git fast-import stream",
+		Long: `Fast-import reads a fast-import stream from stdin, such as git fast-export
+writes, and stores its commits, blobs and annotated tags:
+
+  (cd ../legacy && git fast-export --all) | graft fast-import
+
+Entity lists are extracted for the files the stream adds or changes.
+Gitlink entries are skipped.
+
+Refs are updated once the stream has been read. Updates that are not
+fast-forwards, and changes to existing tags, are refused unless --force
+is given. The working tree is not updated; run checkout afterwards if the
+current branch moved.
+
+--import-marks and --export-marks load and save the marks of the stream,
+so a stream can refer to objects an earlier one defined.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			opts := repo.FastImportOptions{Force: force, Progress: cmd.OutOrStdout()}
+			if importMarks != "" {
+				if opts.Marks, err = repo.ReadFastMarks(importMarks); err != nil {
+					return fmt.Errorf("fast-import: %w", err)
+				}
+			}
+			if exportMarks != "" && opts.Marks == nil {
+				opts.Marks = make(map[int]object.Hash)
+			}
+
+			res, err := r.FastImport(cmd.InOrStdin(), opts)
+			if err != nil {
+				return err
+			}
+			if exportMarks != "" {
+				if err := repo.WriteFastMarks(exportMarks, opts.Marks); err != nil {
+					return fmt.Errorf("fast-import: %w", err)
+				}
+			}
+
+			if !quiet {
+				printFetchRefUpdates(cmd, res.UpdatedRefs, nil, res.RejectedRefs)
+				fmt.Fprintf(cmd.OutOrStdout(), "imported %d commit(s), %d blob(s) and %d tag(s)\n", res.Commits, res.Blobs, res.Tags)
+			}
+			if len(res.RejectedRefs) > 0 {
+				return fmt.Errorf("fast-import: %d ref updates rejected; use --force to overwrite", len(res.RejectedRefs))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&importMarks, "import-marks", "", "load marks defined by an earlier stream")
+	cmd.Flags().StringVar(&exportMarks, "export-marks", "", "write the marks of imported objects to a file")
+	cmd.Flags().BoolVar(&force, "force", false, "allow non-fast-forward ref updates")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "do not print ref updates and the import summary")
+	return cmd
+}
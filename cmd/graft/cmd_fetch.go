@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/odvcencio/graft/pkg/object"
 	"github.com/odvcencio/graft/pkg/remote"
 	"github.com/odvcencio/graft/pkg/repo"
 	"github.com/spf13/cobra"
@@ -13,13 +12,19 @@ import (
 func newFetchCmd() *cobra.Command {
 	var depth int
 	var deepen int
+	var unshallow bool
 	var coordFlag bool
+	var prune bool
+	var newProgress func() *progressMeter
+	var showStats *bool
 
 	cmd := &cobra.Command{
 		Use:   "fetch [remote]",
 		Short: "Download objects and refs from a remote",
-		Long:  "Fetch downloads objects and refs from a remote without modifying the working tree or current branch. Remote refs are stored under refs/remotes/<remote>/.",
-		Args:  cobra.MaximumNArgs(1),
+		Long: "Fetch downloads objects and refs from a remote without modifying the working tree or current branch. Remote refs are stored under refs/remotes/<remote>/.\n\n" +
+			"With --prune, tracking refs whose branch or tag was deleted on the remote are removed.",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeRemotes(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
@@ -31,28 +36,47 @@ func newFetchCmd() *cobra.Command {
 				remoteName = args[0]
 			}
 
-			if depth > 0 || deepen > 0 {
-				return fetchShallow(cmd, r, remoteName, depth, deepen)
+			if unshallow && (depth > 0 || deepen > 0) {
+				return fmt.Errorf("fetch: --unshallow cannot be combined with --depth or --deepen")
+			}
+			if unshallow && !r.IsShallowRepository() {
+				return fmt.Errorf("fetch: --unshallow on a complete repository does not make sense")
+			}
+			progress := newProgress()
+			defer progress.Done()
+			r.SetProgress(progress.Func())
+
+			// Shallow repositories keep their boundaries on a plain fetch so
+			// new commits are downloaded without pulling in old history.
+			opts := repo.FetchOptions{Prune: prune, Depth: depth, Deepen: deepen, Unshallow: unshallow}
+			shallow := depth > 0 || deepen > 0 || unshallow || r.IsShallowRepository()
+
+			if remoteURL, urlErr := r.RemoteURL(remoteName); urlErr == nil && !shallow && !r.HasGitDir() {
+				if _, kind, specErr := parseAnyRemoteSpec(remoteURL); specErr == nil && kind == remoteTransportGit {
+					if prune {
+						return fmt.Errorf("fetch: --prune is not supported for plain git remotes")
+					}
+					return fetchFromGitRemote(cmd, r, remoteName, remoteURL)
+				}
 			}
 
-			result, err := r.FetchContext(cmd.Context(), remoteName)
+			result, err := r.FetchWithOptions(cmd.Context(), remoteName, opts)
+			progress.Done()
 			if err != nil {
 				return err
 			}
+			printTransferStats(cmd.ErrOrStderr(), *showStats, result.Stats)
 
-			if len(result.UpdatedRefs) == 0 {
+			if len(result.UpdatedRefs) == 0 && len(result.PrunedRefs) == 0 && len(result.RejectedRefs) == 0 {
 				fmt.Fprintf(cmd.OutOrStdout(), "already up to date\n")
 				return nil
 			}
 
-			for _, ru := range result.UpdatedRefs {
-				if ru.OldHash == "" {
-					fmt.Fprintf(cmd.OutOrStdout(), " * [new ref] %s -> %s\n", shortHash(ru.NewHash), ru.Name)
-				} else {
-					fmt.Fprintf(cmd.OutOrStdout(), "   %s..%s %s\n", shortHash(ru.OldHash), shortHash(ru.NewHash), ru.Name)
-				}
-			}
+			printFetchRefUpdates(cmd, result.UpdatedRefs, result.PrunedRefs, result.RejectedRefs)
 
+			if result.Resumed > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "resumed interrupted fetch (%d objects already received)\n", result.Resumed)
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "fetched %d objects from %s\n", result.ObjectCount, result.RemoteName)
 
 			// If --coord is set, also fetch coord refs from the remote.
@@ -96,99 +120,51 @@ func newFetchCmd() *cobra.Command {
 
 	cmd.Flags().IntVar(&depth, "depth", 0, "limit fetching to the specified number of commits from tip")
 	cmd.Flags().IntVar(&deepen, "deepen", 0, "deepen a shallow clone by the specified number of commits")
+	cmd.Flags().BoolVar(&unshallow, "unshallow", false, "fetch the complete history of a shallow clone")
 	cmd.Flags().BoolVar(&coordFlag, "coord", false, "also fetch refs/coord/ coordination refs from the remote")
+	cmd.Flags().BoolVarP(&prune, "prune", "p", false, "delete tracking refs whose upstream ref no longer exists on the remote")
+	newProgress = addProgressFlag(cmd)
+	showStats = addStatsFlag(cmd)
 
 	return cmd
 }
 
-func fetchShallow(cmd *cobra.Command, r *repo.Repo, remoteName string, depth, deepenN int) error {
-	remoteURL, err := r.RemoteURL(remoteName)
-	if err != nil {
-		return fmt.Errorf("fetch: %w", err)
-	}
-
-	client, err := remote.NewClient(remoteURL)
-	if err != nil {
-		return fmt.Errorf("fetch: create client: %w", err)
-	}
-
-	remoteRefs, err := client.ListRefs(cmd.Context())
-	if err != nil {
-		return fmt.Errorf("fetch: list remote refs: %w", err)
-	}
-
-	if len(remoteRefs) == 0 {
-		fmt.Fprintf(cmd.OutOrStdout(), "already up to date\n")
-		return nil
-	}
-
-	// Read existing shallow state.
-	shallowState, err := remote.ReadShallowFile(r.GraftDir)
-	if err != nil {
-		return fmt.Errorf("fetch: read shallow state: %w", err)
-	}
-
-	wants := make([]object.Hash, 0, len(remoteRefs))
-	for _, h := range remoteRefs {
-		if strings.TrimSpace(string(h)) != "" {
-			wants = append(wants, h)
-		}
-	}
-
-	haves, err := localRefTips(r)
+// fetchFromGitRemote imports the current branch from a plain git remote.
+func fetchFromGitRemote(cmd *cobra.Command, r *repo.Repo, remoteName, remoteURL string) error {
+	branch, err := r.CurrentBranch()
 	if err != nil {
-		return fmt.Errorf("fetch: collect local refs: %w", err)
+		return err
 	}
-
-	cfg := remote.FetchConfig{
-		Depth:        depth,
-		Deepen:       deepenN,
-		ShallowState: shallowState,
+	if branch == "" {
+		return fmt.Errorf("fetch: cannot infer branch while HEAD is detached")
 	}
-
-	result, err := remote.FetchIntoStoreShallow(cmd.Context(), client, r.Store, wants, haves, cfg)
+	result, err := r.FetchFromGit(cmd.Context(), remoteName, remoteURL, branch)
 	if err != nil {
-		return fmt.Errorf("fetch: download objects: %w", err)
+		return err
 	}
-
-	// Update shallow file.
-	if result.ShallowState != nil && result.ShallowState.Len() > 0 {
-		if err := remote.WriteShallowFile(r.GraftDir, result.ShallowState); err != nil {
-			return fmt.Errorf("fetch: write shallow state: %w", err)
-		}
-	}
-
-	// Update tracking refs.
-	var updatedRefs []repo.RefUpdate
-	for refName, h := range remoteRefs {
-		trackingRef := fmt.Sprintf("refs/remotes/%s/%s", remoteName, strings.TrimPrefix(refName, "/"))
-		oldHash, _ := r.ResolveRef(trackingRef)
-		if oldHash == h {
-			continue
-		}
-		if err := r.UpdateRef(trackingRef, h); err != nil {
-			return fmt.Errorf("fetch: update tracking ref %q: %w", trackingRef, err)
-		}
-		updatedRefs = append(updatedRefs, repo.RefUpdate{
-			Name:    trackingRef,
-			OldHash: oldHash,
-			NewHash: h,
-		})
-	}
-
-	if len(updatedRefs) == 0 {
+	if result.ImportedCommits == 0 {
 		fmt.Fprintf(cmd.OutOrStdout(), "already up to date\n")
 		return nil
 	}
+	fmt.Fprintf(cmd.OutOrStdout(), "imported %d commit(s) and %d blob(s) from %s into %s\n",
+		result.ImportedCommits, result.ImportedBlobs, remoteName, result.TrackingRef)
+	return nil
+}
 
-	for _, ru := range updatedRefs {
+// printFetchRefUpdates lists updated, pruned and rejected tracking refs.
+func printFetchRefUpdates(cmd *cobra.Command, updated, pruned, rejected []repo.RefUpdate) {
+	out := cmd.OutOrStdout()
+	for _, ru := range updated {
 		if ru.OldHash == "" {
-			fmt.Fprintf(cmd.OutOrStdout(), " * [new ref] %s -> %s\n", shortHash(ru.NewHash), ru.Name)
+			fmt.Fprintf(out, " * [new ref] %s -> %s\n", shortHash(ru.NewHash), ru.Name)
 		} else {
-			fmt.Fprintf(cmd.OutOrStdout(), "   %s..%s %s\n", shortHash(ru.OldHash), shortHash(ru.NewHash), ru.Name)
+			fmt.Fprintf(out, "   %s..%s %s\n", shortHash(ru.OldHash), shortHash(ru.NewHash), ru.Name)
 		}
 	}
-
-	fmt.Fprintf(cmd.OutOrStdout(), "fetched %d objects from %s\n", result.Written, remoteName)
-	return nil
+	for _, ru := range pruned {
+		fmt.Fprintf(out, " - [deleted] (was %s) %s\n", shortHash(ru.OldHash), ru.Name)
+	}
+	for _, ru := range rejected {
+		fmt.Fprintf(out, " ! [rejected] %s -> %s (non-fast-forward)\n", shortHash(ru.NewHash), ru.Name)
+	}
 }
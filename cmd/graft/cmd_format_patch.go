@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newFormatPatchCmd() *cobra.Command {
+	var outputDir string
+	var stdout bool
+
+	cmd := &cobra.Command{
+		Use:   "format-patch [-o <dir>] [--stdout] (<since> | <revision-range>)",
+		Short: "Write commits as mailbox patches",
+		Long: `Format-patch writes one mailbox-style patch per commit in the range,
+oldest first, with the commit's author, date and message. A single
+revision means every commit since it, as in <since>..HEAD. Merge commits
+are skipped.
+
+Each patch records the full blob hashes of the files it changes, so that
+'graft apply --3way' and 'graft am --3way' can fall back to a three-way
+merge when the patch does not apply cleanly.
+
+Patches are written to files named like 0001-subject.patch in the output
+directory (the current directory by default), or to stdout with --stdout.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if stdout && outputDir != "" {
+				return fmt.Errorf("format-patch: --stdout and -o are mutually exclusive")
+			}
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			patches, err := r.FormatPatches(args)
+			if err != nil {
+				return fmt.Errorf("format-patch: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			for i, p := range patches {
+				if stdout {
+					if err := repo.WritePatch(out, p, i+1, len(patches)); err != nil {
+						return err
+					}
+					continue
+				}
+				name := filepath.Join(outputDir, fmt.Sprintf("%04d-%s.patch", i+1, patchFileSlug(p.Subject)))
+				if outputDir != "" {
+					if err := os.MkdirAll(outputDir, 0o755); err != nil {
+						return fmt.Errorf("format-patch: %w", err)
+					}
+				}
+				f, err := os.Create(name)
+				if err != nil {
+					return fmt.Errorf("format-patch: %w", err)
+				}
+				if err := repo.WritePatch(f, p, i+1, len(patches)); err != nil {
+					f.Close()
+					return fmt.Errorf("format-patch: write %s: %w", name, err)
+				}
+				if err := f.Close(); err != nil {
+					return fmt.Errorf("format-patch: write %s: %w", name, err)
+				}
+				fmt.Fprintln(out, name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputDir, "output-directory", "o", "", "write patch files to this directory")
+	cmd.Flags().BoolVar(&stdout, "stdout", false, "write all patches to stdout as one mailbox")
+
+	return cmd
+}
+
+// patchFileSlug turns a commit subject into the file-name part of a patch
+// name: lowercase letters and digits joined by single dashes.
+func patchFileSlug(subject string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(subject) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' || c == '.' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(c)
+			dash = false
+			if b.Len() >= 52 {
+				break
+			}
+			continue
+		}
+		dash = true
+	}
+	slug := strings.Trim(b.String(), ".")
+	if slug == "" {
+		return "patch"
+	}
+	return slug
+}
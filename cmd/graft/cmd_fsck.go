@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newFsckCmd() *cobra.Command {
+	var lostFound, unreachable, noReflogs, jsonFlag bool
+	var recent int
+
+	cmd := &cobra.Command{
+		Use:   "fsck",
+		Short: "Find unreachable commits and blobs to recover lost work",
+		Long: `Find the commits and blobs that no branch, tag, HEAD, stash entry, staged
+file or reflog entry reaches, and report the dangling ones: those no other
+unreachable object refers to. A dangling commit is the tip of a line of lost
+work, for example after a bad reset or a dropped stash; bring it back with
+"graft reset --hard <commit>" or "graft cherry-pick <commit>".
+
+The most recent dangling commits are listed with their subjects. The reflog
+already keeps the commits a ref pointed at, so fsck reports what it missed;
+--no-reflogs also reports commits only the reflog remembers.
+
+--lost-found writes the dangling commits to .graft/lost-found/commit/, one
+file per commit holding its hash, and the dangling blobs to
+.graft/lost-found/other/, each holding the blob's content.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			report, err := r.FindLostObjects(repo.LostFoundOptions{NoReflogs: noReflogs})
+			if err != nil {
+				return err
+			}
+			var dir string
+			if lostFound {
+				if dir, err = r.WriteLostFound(report); err != nil {
+					return err
+				}
+				if rel, err := filepath.Rel(r.RootDir, dir); err == nil {
+					dir = filepath.ToSlash(rel)
+				}
+			}
+			if recent > 0 && len(report.DanglingCommits) > recent {
+				report.DanglingCommits = report.DanglingCommits[:recent]
+			}
+
+			out := cmd.OutOrStdout()
+			if jsonFlag {
+				return writeJSON(out, fsckJSON(report, unreachable, dir))
+			}
+			printFsck(out, report, unreachable, dir)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&lostFound, "lost-found", false, "write dangling commits and blobs into .graft/lost-found")
+	cmd.Flags().BoolVar(&unreachable, "unreachable", false, "list every unreachable object, not only dangling ones")
+	cmd.Flags().BoolVar(&noReflogs, "no-reflogs", false, "do not treat reflog entries as reachable")
+	cmd.Flags().IntVarP(&recent, "max-count", "n", 10, "list at most this many recent dangling commits (0 for all)")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output the report as JSON")
+	return cmd
+}
+
+func printFsck(w io.Writer, report *repo.LostFoundReport, unreachable bool, dir string) {
+	for _, obj := range report.Unreachable {
+		switch {
+		case unreachable:
+			fmt.Fprintf(w, "unreachable %s %s\n", obj.Type, obj.Hash)
+		case obj.Dangling:
+			fmt.Fprintf(w, "dangling %s %s\n", obj.Type, obj.Hash)
+		}
+	}
+	if len(report.DanglingCommits) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "recent dangling commits:")
+		for _, c := range report.DanglingCommits {
+			date := time.Unix(c.Timestamp, 0).Format("2006-01-02 15:04:05")
+			fmt.Fprintf(w, "  %s %s %s\n", shortHash(c.Hash), date, c.Subject)
+		}
+	}
+	if dir != "" {
+		fmt.Fprintf(w, "wrote %s and %s to %s\n",
+			pluralize(report.DanglingCount(object.TypeCommit), "dangling commit"),
+			pluralize(report.DanglingCount(object.TypeBlob), "dangling blob"),
+			dir)
+	}
+}
+
+func fsckJSON(report *repo.LostFoundReport, unreachable bool, dir string) JSONFsckOutput {
+	out := JSONFsckOutput{
+		Objects:         []JSONFsckObject{},
+		DanglingCommits: make([]JSONFsckCommit, 0, len(report.DanglingCommits)),
+		LostFound:       dir,
+	}
+	for _, obj := range report.Unreachable {
+		if unreachable || obj.Dangling {
+			out.Objects = append(out.Objects, JSONFsckObject{Hash: string(obj.Hash), Type: string(obj.Type), Dangling: obj.Dangling})
+		}
+	}
+	for _, c := range report.DanglingCommits {
+		out.DanglingCommits = append(out.DanglingCommits, JSONFsckCommit{
+			Hash:      string(c.Hash),
+			Author:    c.Author,
+			Timestamp: c.Timestamp,
+			Subject:   c.Subject,
+		})
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFsckLostFoundIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "one\n", "first")
+	commitFile(t, dir, "a.txt", "two\n", "work to lose")
+	lost := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD"))
+	mustRunGraft(t, dir, "reset", "--hard", "HEAD~1")
+
+	// The reflog still remembers the commit.
+	if out := mustRunGraft(t, dir, "fsck"); strings.Contains(out, lost) {
+		t.Fatalf("fsck reported a commit the reflog keeps:\n%s", out)
+	}
+
+	out := mustRunGraft(t, dir, "fsck", "--no-reflogs", "--lost-found")
+	if !strings.Contains(out, "dangling commit "+lost) || !strings.Contains(out, "work to lose") {
+		t.Fatalf("fsck --no-reflogs output:\n%s", out)
+	}
+	if !strings.Contains(out, "wrote 1 dangling commit and 0 dangling blobs to .graft/lost-found") {
+		t.Fatalf("fsck --lost-found summary:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".graft", "lost-found", "commit", lost)); err != nil {
+		t.Fatalf("lost-found commit not written: %v", err)
+	}
+
+	var report JSONFsckOutput
+	if err := json.Unmarshal([]byte(mustRunGraft(t, dir, "fsck", "--no-reflogs", "--unreachable", "--json")), &report); err != nil {
+		t.Fatalf("unmarshal fsck --json: %v", err)
+	}
+	if len(report.DanglingCommits) != 1 || report.DanglingCommits[0].Hash != lost || len(report.Objects) < 2 {
+		t.Fatalf("fsck --json = %+v", report)
+	}
+}
@@ -3,27 +3,62 @@ package main
 import (
 	"fmt"
 
+	"github.com/odvcencio/graft/pkg/object"
 	"github.com/odvcencio/graft/pkg/repo"
 	"github.com/spf13/cobra"
 )
 
 func newGcCmd() *cobra.Command {
-	return &cobra.Command{
+	var repack, aggressive, auto bool
+	var newProgress func() *progressMeter
+
+	cmd := &cobra.Command{
 		Use:   "gc",
 		Short: "Pack loose objects into a pack file",
-		Args:  cobra.NoArgs,
+		Long: "Pack loose objects into a pack file.\n\n" +
+			"With --repack, existing packs are consolidated into the new pack as well. " +
+			"Packs with a pack-<checksum>.keep file next to them are never rewritten or deleted.\n\n" +
+			"--aggressive repacks like --repack but searches a wide window of similar objects " +
+			"(gc.aggressiveWindow, default 250) for delta bases, trading time for a smaller pack.\n\n" +
+			"--auto packs only when there are more than gc.auto loose objects (default 6700), " +
+			"or repacks when there are more than gc.autoPackLimit packs (default 50). " +
+			"Commit and pull run it after they finish; set gc.auto to 0 to turn that off.",
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if auto && (repack || aggressive) {
+				return fmt.Errorf("--auto cannot be combined with --repack or --aggressive")
+			}
 			r, err := repo.Open(".")
 			if err != nil {
 				return err
 			}
 
-			summary, err := r.GC()
+			progress := newProgress()
+			r.SetProgress(progress.Func())
+
+			var summary *object.GCSummary
+			switch {
+			case auto:
+				summary, err = r.AutoGC()
+			case aggressive:
+				summary, err = r.AggressiveGC()
+			case repack:
+				summary, err = r.Repack()
+			default:
+				summary, err = r.GC()
+			}
+			progress.Done()
 			if err != nil {
 				return err
 			}
 
 			out := cmd.OutOrStdout()
+			if summary == nil {
+				return nil
+			}
+			if summary.KeptPacks > 0 {
+				fmt.Fprintf(out, "skipped %d kept pack(s)\n", summary.KeptPacks)
+			}
 			if summary.PackedObjects == 0 {
 				fmt.Fprintln(out, "nothing to pack")
 				return nil
@@ -36,7 +71,36 @@ func newGcCmd() *cobra.Command {
 				summary.PackFile,
 				summary.IndexFile,
 			)
+			if summary.DeltaObjects > 0 {
+				fmt.Fprintf(out, "stored %d object(s) as deltas\n", summary.DeltaObjects)
+			}
+			if len(summary.RemovedPacks) > 0 {
+				fmt.Fprintf(out, "removed %d replaced pack(s)\n", len(summary.RemovedPacks))
+			}
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&repack, "repack", false, "consolidate existing packs (except those with a .keep file) into the new pack")
+	cmd.Flags().BoolVar(&aggressive, "aggressive", false, "repack everything with a wide delta search window")
+	cmd.Flags().BoolVar(&auto, "auto", false, "pack only when loose objects or packs exceed the gc.auto thresholds")
+	newProgress = addProgressFlag(cmd)
+	return cmd
+}
+
+// runAutoGC runs the gc --auto heuristic after a command that adds objects.
+// Failures are reported as warnings: the command itself has succeeded.
+func runAutoGC(cmd *cobra.Command, args []string) {
+	r, err := repo.Open(".")
+	if err != nil {
+		return
+	}
+	summary, err := r.AutoGC()
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: auto gc failed: %v\n", err)
+		return
+	}
+	if summary != nil && summary.PackedObjects > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "auto gc: packed %d object(s) into %s\n", summary.PackedObjects, summary.PackFile)
+	}
 }
@@ -82,6 +82,72 @@ func TestGcCmdPacksReachableLooseObjectsAndIsIdempotent(t *testing.T) {
 	}
 }
 
+func TestGcCmdRepackLeavesKeptPacks(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	commitAndGC := func(name, content string) *object.GCSummary {
+		t.Helper()
+		writeGcCmdFile(t, filepath.Join(dir, name), []byte(content))
+		if err := r.Add([]string{name}); err != nil {
+			t.Fatalf("Add(%s): %v", name, err)
+		}
+		if _, err := r.Commit("add "+name, "tester"); err != nil {
+			t.Fatalf("Commit(%s): %v", name, err)
+		}
+		summary, err := r.GC()
+		if err != nil {
+			t.Fatalf("GC: %v", err)
+		}
+		return summary
+	}
+
+	kept := commitAndGC("a.go", "package a\n")
+	commitAndGC("b.go", "package b\n")
+	commitAndGC("c.go", "package c\n")
+	if err := r.Store.KeepPack(kept.PackFile, ""); err != nil {
+		t.Fatalf("KeepPack: %v", err)
+	}
+
+	var out bytes.Buffer
+	gcCmd := newGcCmd()
+	gcCmd.SetOut(&out)
+	gcCmd.SetErr(&out)
+	gcCmd.SetArgs([]string{"--repack"})
+	if err := gcCmd.Execute(); err != nil {
+		t.Fatalf("gc --repack: %v\noutput:\n%s", err, out.String())
+	}
+	for _, want := range []string{"skipped 1 kept pack(s)", "removed 2 replaced pack(s)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("gc --repack output = %q, want to contain %q", out.String(), want)
+		}
+	}
+
+	packs, err := r.Store.PackFiles()
+	if err != nil {
+		t.Fatalf("PackFiles: %v", err)
+	}
+	if len(packs) != 2 {
+		t.Fatalf("expected kept pack plus one repacked pack, got %+v", packs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".graft", "objects", "pack", kept.PackFile)); err != nil {
+		t.Fatalf("kept pack missing after repack: %v", err)
+	}
+	head, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef(HEAD): %v", err)
+	}
+	if commits, err := r.Log(head, 10); err != nil || len(commits) != 3 {
+		t.Fatalf("Log after repack = %d commits, %v; want 3", len(commits), err)
+	}
+}
+
 func writeGcCmdFile(t *testing.T, path string, content []byte) {
 	t.Helper()
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
@@ -91,3 +157,27 @@ func writeGcCmdFile(t *testing.T, path string, content []byte) {
 		t.Fatalf("WriteFile(%s): %v", path, err)
 	}
 }
+
+func TestCommitRunsAutoGCAboveThreshold(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	dir := initRepo(t)
+	mustRunGraft(t, dir, "config", "gc.auto", "1")
+	commitFile(t, dir, "main.go", "package main\n", "initial")
+
+	r, err := repo.Open(dir)
+	if err != nil {
+		t.Fatalf("repo.Open: %v", err)
+	}
+	loose, err := r.Store.LooseObjectCount()
+	if err != nil {
+		t.Fatalf("LooseObjectCount: %v", err)
+	}
+	if loose != 0 {
+		t.Fatalf("loose objects after commit with gc.auto=1 = %d, want 0", loose)
+	}
+	if got := mustRunGraft(t, dir, "config", "gc.auto"); strings.TrimSpace(got) != "1" {
+		t.Fatalf("config gc.auto = %q, want 1", got)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newGitSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git-sync",
+		Short: "Keep a colocated .git in step with graft",
+		Long: `Git-sync keeps the .git next to .graft holding the same history, so a
+team can try graft's structural merge while git stays the system of
+record.
+
+Once enabled, graft commands that move refs export graft's exact history
+into .git, and git hooks run "graft git-sync from-git" after git commits,
+merges, checkouts and rewrites, importing what git did. Without git-sync,
+graft only replays its operations as git commands, which gives git
+commits of its own.
+
+The hooks call graft from PATH. Existing hooks are never overwritten;
+enable names them so the call can be added by hand.`,
+	}
+	cmd.AddCommand(newGitSyncEnableCmd())
+	cmd.AddCommand(newGitSyncDisableCmd())
+	cmd.AddCommand(newGitSyncToGitCmd())
+	cmd.AddCommand(newGitSyncFromGitCmd())
+	return cmd
+}
+
+func newGitSyncEnableCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "enable [--force]",
+		Short: "Sync both ways, set core.gitSync and install the git hooks",
+		Long: `Enable imports what only git has, exports what only graft has, sets
+core.gitSync and installs the git hooks. Branches that have diverged
+between the two are refused unless --force is given, in which case git's
+side wins on import and graft's on export.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			if !r.HasGitDir() {
+				return fmt.Errorf("git-sync: no .git in the repository root; run \"git init\" first")
+			}
+			out := cmd.OutOrStdout()
+
+			imported, err := r.SyncFromGit(cmd.Context(), force)
+			if err != nil {
+				return err
+			}
+			printFetchRefUpdates(cmd, imported.UpdatedRefs, nil, imported.RejectedRefs)
+			if len(imported.RejectedRefs) > 0 {
+				return fmt.Errorf("git-sync: %d branches or tags differ between git and graft; use --force to take git's", len(imported.RejectedRefs))
+			}
+			exported, err := r.SyncToGit(cmd.Context(), force)
+			if err != nil {
+				return err
+			}
+			printFetchRefUpdates(cmd, exported.UpdatedRefs, nil, exported.RejectedRefs)
+			if len(exported.RejectedRefs) > 0 {
+				return fmt.Errorf("git-sync: %d branches or tags differ between graft and git; use --force to take graft's", len(exported.RejectedRefs))
+			}
+
+			excludeFromGitInfoExclude(r.RootDir, ".graft/")
+			if err := r.SetGitSync(true); err != nil {
+				return err
+			}
+			if err := r.InstallGitSyncHooks(); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "git sync enabled: imported %d commit(s), exported %d commit(s)\n", imported.Commits, exported.Commits)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite branches that have diverged")
+	return cmd
+}
+
+func newGitSyncDisableCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable",
+		Short: "Clear core.gitSync and remove the git hooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			if err := r.SetGitSync(false); err != nil {
+				return err
+			}
+			if r.HasGitDir() {
+				if err := r.RemoveGitSyncHooks(); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "git sync disabled")
+			return nil
+		},
+	}
+}
+
+func newGitSyncToGitCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "to-git [--force]",
+		Short: "Export graft's branches and tags into .git",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			res, err := r.SyncToGit(cmd.Context(), force)
+			if err != nil {
+				return err
+			}
+			printFetchRefUpdates(cmd, res.UpdatedRefs, nil, res.RejectedRefs)
+			if len(res.RejectedRefs) > 0 {
+				return fmt.Errorf("git-sync: %d ref updates rejected; use --force to overwrite", len(res.RejectedRefs))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "allow non-fast-forward ref updates")
+	return cmd
+}
+
+func newGitSyncFromGitCmd() *cobra.Command {
+	var force, hook bool
+
+	cmd := &cobra.Command{
+		Use:   "from-git [--force]",
+		Short: "Import .git's branches and tags and follow its HEAD",
+		Long: `From-git imports the branches and tags of .git, points HEAD at git's
+HEAD and, when that moved, resets the index to it.
+
+The git hooks run it with --hook, which prints nothing, lets git's side
+win as --force does, and does nothing when core.gitSync is not set, so
+hooks left behind are harmless.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			if hook && !r.GitSyncEnabled() {
+				return nil
+			}
+			res, err := r.SyncFromGit(cmd.Context(), force || hook)
+			if err != nil {
+				return err
+			}
+			if !hook {
+				printFetchRefUpdates(cmd, res.UpdatedRefs, nil, res.RejectedRefs)
+			}
+			if len(res.RejectedRefs) > 0 {
+				return fmt.Errorf("git-sync: %d ref updates rejected; use --force to overwrite", len(res.RejectedRefs))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "allow non-fast-forward ref updates")
+	cmd.Flags().BoolVar(&hook, "hook", false, "run as a git hook")
+	cmd.Flags().MarkHidden("hook")
+	return cmd
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDotQuote(t *testing.T) {
+	got := dotQuote("fix \"quotes\" in C:\\path\nmain")
+	want := `"fix \"quotes\" in C:\\path\nmain"`
+	if got != want {
+		t.Fatalf("dotQuote = %s, want %s", got, want)
+	}
+}
+
+func TestGraphExportIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "main.go", "package main\n\nfunc Load() {}\n", "add load")
+	commitFile(t, dir, "main.go", "package main\n\nfunc Load() { run() }\n", "run in load")
+	mustRunGraft(t, dir, "tag", "v1")
+
+	dot := mustRunGraft(t, dir, "graph", "export", "--entities")
+	for _, want := range []string{
+		"digraph graft {",
+		"run in load",
+		"tag: v1",
+		`[shape=ellipse, label="main.go::Load"]`,
+		`[style=dashed, label="modify"]`,
+		`[style=dashed, label="create"]`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Fatalf("graph export DOT missing %q:\n%s", want, dot)
+		}
+	}
+
+	var graph JSONGraphOutput
+	if err := json.Unmarshal([]byte(mustRunGraft(t, dir, "graph", "export", "--format", "json")), &graph); err != nil {
+		t.Fatalf("decode graph export JSON: %v", err)
+	}
+	if len(graph.Commits) != 2 {
+		t.Fatalf("graph export JSON has %d commits, want 2", len(graph.Commits))
+	}
+	head, root := graph.Commits[0], graph.Commits[1]
+	if head.Subject != "run in load" || len(head.Parents) != 1 || head.Parents[0] != root.Hash {
+		t.Fatalf("head commit = %+v, root = %+v", head, root)
+	}
+	if len(root.Parents) != 0 || head.Entities != nil {
+		t.Fatalf("unexpected parents or entities without --entities: %+v", graph.Commits)
+	}
+
+	if _, err := runGraft(t, dir, "graph", "export", "--format", "svg"); err == nil {
+		t.Fatal("expected an error for an unknown --format")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newHashObjectCmd() *cobra.Command {
+	var write, stdin, entities, jsonFlag bool
+	var path string
+
+	cmd := &cobra.Command{
+		Use:   "hash-object [-w] [--entities] [--path <path>] [--json] (--stdin | <file>...)",
+		Short: "Compute object hashes for file content",
+		Long: `Hash-object prints the blob hash graft gives each file, or the data read
+from stdin with --stdin, one per line. With -w the blob is also written to
+the object store, which requires a repository; without it nothing is
+written and no repository is needed.
+
+With --entities, the entities add would extract are hashed too. Each input
+then prints as
+
+  blob <hash>
+  entitylist <hash>
+  entity <hash> <kind> [<declaration>]
+
+with the entitylist and entity lines left out for content without entities.
+The path selects the language and is recorded in the entity list, so it
+must match where the file would be added: files inside the repository use
+their repository path, and --path sets it for stdin or overrides it.
+
+Content is hashed as given, without the line-ending or LFS conversion add
+may apply.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !stdin && len(args) == 0 {
+				return fmt.Errorf("hash-object: no input; name files or use --stdin")
+			}
+			var r *repo.Repo
+			var store *object.Store
+			if opened, err := repo.Open("."); err == nil {
+				r = opened
+				if write {
+					store = r.Store
+				}
+			} else if write {
+				return err
+			}
+
+			var results []hashObjectResult
+			if stdin {
+				data, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return fmt.Errorf("hash-object: read stdin: %w", err)
+				}
+				res, err := hashObjectInput(store, path, data, entities)
+				if err != nil {
+					return err
+				}
+				results = append(results, res)
+			}
+			for _, arg := range args {
+				data, err := os.ReadFile(arg)
+				if err != nil {
+					return fmt.Errorf("hash-object: %w", err)
+				}
+				relPath := path
+				if relPath == "" {
+					relPath = hashObjectPath(r, arg)
+				}
+				res, err := hashObjectInput(store, relPath, data, entities)
+				if err != nil {
+					return err
+				}
+				results = append(results, res)
+			}
+
+			if jsonFlag {
+				return writeJSON(cmd.OutOrStdout(), jsonHashObject(results))
+			}
+			out := cmd.OutOrStdout()
+			for _, res := range results {
+				if !entities {
+					fmt.Fprintln(out, res.Blob)
+					continue
+				}
+				fmt.Fprintf(out, "blob %s\n", res.Blob)
+				if res.EntityList == "" {
+					continue
+				}
+				fmt.Fprintf(out, "entitylist %s\n", res.EntityList)
+				for _, e := range res.Entities {
+					if label := hashedEntityLabel(e); label != "" {
+						fmt.Fprintf(out, "entity %s %s %s\n", e.Hash, e.Kind, label)
+					} else {
+						fmt.Fprintf(out, "entity %s %s\n", e.Hash, e.Kind)
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&write, "write", "w", false, "write the objects to the object store")
+	cmd.Flags().BoolVar(&stdin, "stdin", false, "hash data read from stdin")
+	cmd.Flags().BoolVar(&entities, "entities", false, "also hash the extracted entities and entity list")
+	cmd.Flags().StringVar(&path, "path", "", "repository path used to detect the language and record in the entity list")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
+	return cmd
+}
+
+// hashObjectResult is one hashed input.
+type hashObjectResult struct {
+	Path string
+	*repo.HashedObject
+}
+
+func hashObjectInput(store *object.Store, relPath string, data []byte, entities bool) (hashObjectResult, error) {
+	hashed, err := repo.HashObject(store, relPath, data, entities)
+	if err != nil {
+		return hashObjectResult{}, fmt.Errorf("hash-object: %w", err)
+	}
+	return hashObjectResult{Path: relPath, HashedObject: hashed}, nil
+}
+
+// hashObjectPath returns the path a file argument is recorded under: its
+// path in the repository when it is inside one, else the path as given.
+func hashObjectPath(r *repo.Repo, arg string) string {
+	if r != nil {
+		if abs, err := filepath.Abs(arg); err == nil {
+			if rel, err := filepath.Rel(r.RootDir, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(arg))
+}
+
+// hashedEntityLabel names a declaration entity, e.g. "func (s *S) Run";
+// other entities have no label.
+func hashedEntityLabel(e repo.HashedEntity) string {
+	if e.Name == "" {
+		return ""
+	}
+	if e.Receiver != "" {
+		return fmt.Sprintf("%s (%s) %s", entity.ShortDeclKind(e.DeclKind), e.Receiver, e.Name)
+	}
+	return entity.ShortDeclKind(e.DeclKind) + " " + e.Name
+}
+
+func jsonHashObject(results []hashObjectResult) []JSONHashObject {
+	out := make([]JSONHashObject, 0, len(results))
+	for _, res := range results {
+		obj := JSONHashObject{
+			Path:       res.Path,
+			Blob:       string(res.Blob),
+			EntityList: string(res.EntityList),
+		}
+		for _, e := range res.Entities {
+			obj.Entities = append(obj.Entities, JSONHashObjectEntity{
+				Hash:  string(e.Hash),
+				Kind:  e.Kind,
+				Label: hashedEntityLabel(e),
+			})
+		}
+		out = append(out, obj)
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashObjectIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	writeFile(t, dir, "main.go", "package main\n\nfunc A() {}\n")
+
+	blob := strings.TrimSpace(mustRunGraft(t, dir, "hash-object", "main.go"))
+	if _, err := runGraft(t, dir, "cat-file", "-t", blob); err == nil {
+		t.Fatal("hash-object without -w wrote the blob")
+	}
+
+	lines := nonEmptyLines(mustRunGraft(t, dir, "hash-object", "-w", "--entities", "main.go"))
+	if len(lines) < 3 || lines[0] != "blob "+blob || !strings.HasPrefix(lines[1], "entitylist ") {
+		t.Fatalf("hash-object --entities = %q", lines)
+	}
+	entityList := strings.TrimPrefix(lines[1], "entitylist ")
+	var declaration string
+	for _, line := range lines[2:] {
+		if strings.HasSuffix(line, " declaration func A") {
+			declaration = strings.Fields(line)[1]
+		}
+	}
+	if declaration == "" {
+		t.Fatalf("hash-object --entities lists no func A: %q", lines)
+	}
+	if typ := strings.TrimSpace(mustRunGraft(t, dir, "cat-file", "-t", declaration)); typ != "entity" {
+		t.Fatalf("cat-file -t <entity> = %q, want entity", typ)
+	}
+
+	// The hashes are the ones add records.
+	mustRunGraft(t, dir, "add", "main.go")
+	mustRunGraft(t, dir, "commit", "-m", "first")
+	tree := nonEmptyLines(mustRunGraft(t, dir, "cat-file", "-p", "HEAD:"))
+	if fields := strings.Fields(tree[0]); len(fields) != 5 || fields[2] != blob || fields[3] != entityList {
+		t.Fatalf("committed tree = %q, want blob %s and entity list %s", tree, blob, entityList)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newImportGitCmd() *cobra.Command {
+	var force bool
+	var newProgress func() *progressMeter
+
+	cmd := &cobra.Command{
+		Use:   "import-git [--force] <git-repo>",
+		Short: "Convert the branches and tags of a git repository",
+		Long: `Import-git reads a git repository's loose objects and packfiles directly,
+without running git, and converts its branches and tags into graft history.
+Authors, committers, dates, messages, merges and executable bits are kept,
+annotated tags stay annotated, and entity lists are extracted for every
+file:
+
+  graft init && graft import-git ../legacy
+
+<git-repo> may be a work tree or a git directory. Importing again only
+converts new history. Updates that are not fast-forwards, and changes to
+existing tags, are refused unless --force is given. Gitlinks are skipped,
+as are tags of trees or blobs.
+
+When the current branch has no commits yet, HEAD is switched to the branch
+the git repository had checked out and its files are checked out.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			_, headErr := r.ResolveRef("HEAD")
+			unborn := headErr != nil
+
+			progress := newProgress()
+			r.SetProgress(progress.Func())
+			res, err := r.ImportGit(cmd.Context(), args[0], repo.ImportGitOptions{Force: force})
+			progress.Done()
+			r.SetProgress(nil)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			printFetchRefUpdates(cmd, res.UpdatedRefs, nil, res.RejectedRefs)
+			for _, name := range res.SkippedRefs {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: skipped %s: it does not name a commit\n", name)
+			}
+			fmt.Fprintf(out, "imported %d commit(s), %d blob(s) and %d tag(s)\n", res.Commits, res.Blobs, res.Tags)
+
+			if unborn && importedRef(res.UpdatedRefs, res.Head) {
+				if err := checkoutImportedHead(r, res.Head, newProgress); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "checked out %s\n", strings.TrimPrefix(res.Head, "refs/heads/"))
+			}
+			if len(res.RejectedRefs) > 0 {
+				return fmt.Errorf("import-git: %d ref updates rejected; use --force to overwrite", len(res.RejectedRefs))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "allow non-fast-forward ref updates")
+	newProgress = addProgressFlag(cmd)
+	return cmd
+}
+
+func importedRef(updates []repo.RefUpdate, name string) bool {
+	for _, u := range updates {
+		if u.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkoutImportedHead checks out branch in a repository whose HEAD was
+// unborn before the import. As in clone, HEAD is first pointed at a branch
+// that does not exist so the empty index is not taken for deleted files.
+func checkoutImportedHead(r *repo.Repo, branch string, newProgress func() *progressMeter) error {
+	if err := r.SetHeadSymbolic("refs/heads/_graft_import_unborn"); err != nil {
+		return err
+	}
+	progress := newProgress()
+	r.SetProgress(progress.Func())
+	err := r.Checkout(strings.TrimPrefix(branch, "refs/heads/"))
+	progress.Done()
+	r.SetProgress(nil)
+	if err != nil {
+		if headErr := r.SetHeadSymbolic(branch); headErr != nil {
+			return fmt.Errorf("%w (restoring HEAD: %v)", err, headErr)
+		}
+		return err
+	}
+	return nil
+}
@@ -42,7 +42,7 @@ func newInitCmd() *cobra.Command {
 				}
 				bridge.Close()
 				excludeFromGitInfoExclude(abs, ".gts/")
-				fmt.Println("Initialized graft bridge alongside existing git repository")
+				fmt.Fprintln(cmd.OutOrStdout(), "Initialized graft bridge alongside existing git repository")
 				return nil
 			}
 
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,15 +19,90 @@ func newLogCmd() *cobra.Command {
 	var all bool
 	var graph bool
 	var jsonFlag bool
+	var author, grep, since, until string
+	var diffGrep, entityContains string
+	var firstParent bool
+	var merges, noMerges bool
+	var format string
 
 	cmd := &cobra.Command{
-		Use:   "log",
+		Use:   "log [--] [<pathspec>...]",
 		Short: "Show commit history",
+		Long: `Show commit history.
+
+Pathspecs limit the log to commits that changed a matching file. They accept
+globs with recursive "**" and the magic prefixes :(exclude) (or :!),
+:(icase), :(literal), :(glob) and :(top) (or :/).
+
+--author and --grep keep commits whose author or message matches a regular
+expression; --since and --until keep commits dated within a range, given as
+a date (YYYY-MM-DD [HH:MM:SS]), RFC 3339, or a relative time such as
+"2 weeks ago" or "yesterday". Filters combine, and -n counts only the
+commits that pass them.
+
+-G keeps commits whose diff adds or removes a line matching a regular
+expression. --entity-contains keeps commits that changed a declaration so
+that its body matches a regular expression where it did not before; with
+--entity, only the selected entity is considered.
+
+Log follows the first parent of each merge, showing the history of the
+current branch as it was merged into. --all walks every parent of every
+branch and tag, unless --first-parent is given too.
+
+--merges shows only merge commits and --no-merges leaves them out. These
+filters need nothing but the commits themselves, so they are checked before
+any pathspec or pickaxe filter reads a tree, and log stops walking history
+once -n commits have been found, with or without --all.
+
+--format prints each commit through a template of placeholders: %H and %h
+(hash), %an and %ae (author name and email), %ad, %as, %aI and %at (date as
+"YYYY-MM-DD HH:MM:SS", YYYY-MM-DD, RFC 3339 and Unix time), %s (subject),
+%b (body), %B (raw message), %P and %p (parent hashes), %T (tree hash),
+%d (decoration), %n (newline) and %% (a literal %).
+
+Authors are shown as the .mailmap file at the root of the working tree
+maps them.`,
+		ValidArgsFunction: completeTrackedPaths,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
 				return err
 			}
+			logOpts, err := buildLogOptions(author, grep, since, until, time.Now())
+			if err != nil {
+				return err
+			}
+			switch {
+			case merges && noMerges:
+				return fmt.Errorf("--merges and --no-merges cannot be combined")
+			case merges:
+				logOpts.Filter = repo.AllOf(logOpts.Filter, repo.MergesOnly())
+			case noMerges:
+				logOpts.Filter = repo.AllOf(logOpts.Filter, repo.NoMerges())
+			}
+			if logOpts, err = addPickaxeFilters(r, logOpts, diffGrep, entityContains, entitySelector); err != nil {
+				return err
+			}
+			logOpts.FirstParent = firstParent
+			var logFmt logFormat
+			if cmd.Flags().Changed("format") {
+				if jsonFlag || oneline {
+					return fmt.Errorf("--format cannot be combined with --json or --oneline")
+				}
+				if logFmt, err = parseLogFormat(format); err != nil {
+					return err
+				}
+			}
+			filter, err := parsePathspecArgs(r, args)
+			if err != nil {
+				return err
+			}
+			if filter != nil && all {
+				return fmt.Errorf("pathspecs cannot be combined with --all")
+			}
+			if filter != nil && strings.TrimSpace(entitySelector) != "" {
+				return fmt.Errorf("pathspecs cannot be combined with --entity")
+			}
 
 			// Determine the current branch name for decoration.
 			branchName := ""
@@ -55,10 +131,13 @@ func newLogCmd() *cobra.Command {
 					return err
 				}
 
-				entries, err := r.LogByEntity(headHash, limit, selector.Path, selector.Key)
+				entries, err := r.LogByEntityWithOptions(headHash, limit, selector.Path, selector.Key, logOpts)
 				if err != nil {
 					return err
 				}
+				if err := mailmapLogEntries(r, entries); err != nil {
+					return err
+				}
 
 				if jsonFlag {
 					return logEntriesToJSON(cmd, entries, headHash, branchName, false, nil)
@@ -69,24 +148,27 @@ func newLogCmd() *cobra.Command {
 				}
 
 				out := cmd.OutOrStdout()
+				color := colorEnabled(cmd)
 				for _, entry := range entries {
 					h := entry.Hash
 					c := entry.Commit
 					decoration := buildDecoration(h, headHash, branchName)
 
-					if oneline {
-						short := shortHash(h)
+					if logFmt != nil {
+						fmt.Fprintln(out, logFmt.render(logFormatCommit{Hash: h, Commit: c, Decoration: decoration}))
+					} else if oneline {
+						short := paint(color, ansiYellow, shortHash(h))
 						if decoration != "" {
 							fmt.Fprintf(out, "%s %s %s\n", short, decoration, c.Message)
 						} else {
 							fmt.Fprintf(out, "%s %s\n", short, c.Message)
 						}
 					} else {
+						commitLine := "commit " + string(h)
 						if decoration != "" {
-							fmt.Fprintf(out, "commit %s %s\n", h, decoration)
-						} else {
-							fmt.Fprintf(out, "commit %s\n", h)
+							commitLine += " " + decoration
 						}
+						fmt.Fprintln(out, paint(color, ansiYellow, commitLine))
 						fmt.Fprintf(out, "Author: %s\n", c.Author)
 						fmt.Fprintf(out, "Date:   %s\n", time.Unix(c.Timestamp, 0).Format("2006-01-02 15:04:05"))
 						fmt.Fprintln(out)
@@ -100,27 +182,17 @@ func newLogCmd() *cobra.Command {
 			var entries []repo.LogEntry
 
 			if all {
-				entries, err = r.LogAll(limit)
-				if err != nil {
-					return err
-				}
+				entries, err = r.LogAllWithOptions(limit, logOpts)
+			} else if filter != nil {
+				entries, err = r.LogByPathsWithOptions(headHash, limit, filter, logOpts)
 			} else {
-				commits, err := r.Log(headHash, limit)
-				if err != nil {
-					return err
-				}
-
-				// Convert to LogEntry slice with hashes.
-				if len(commits) > 0 {
-					hashes := make([]object.Hash, len(commits))
-					hashes[0] = headHash
-					for i := 1; i < len(commits); i++ {
-						hashes[i] = commits[i-1].Parents[0]
-					}
-					for i, c := range commits {
-						entries = append(entries, repo.LogEntry{Hash: hashes[i], Commit: c})
-					}
-				}
+				entries, err = r.LogWithOptions(headHash, limit, logOpts)
+			}
+			if err != nil {
+				return err
+			}
+			if err := mailmapLogEntries(r, entries); err != nil {
+				return err
 			}
 
 			if jsonFlag {
@@ -128,11 +200,15 @@ func newLogCmd() *cobra.Command {
 			}
 
 			if len(entries) == 0 {
+				if filter != nil || logOpts.Filter != nil {
+					return nil
+				}
 				fmt.Fprintln(cmd.OutOrStdout(), "no commits yet")
 				return nil
 			}
 
 			out := cmd.OutOrStdout()
+			color := colorEnabled(cmd)
 
 			var graphLines []string
 			if graph {
@@ -155,9 +231,15 @@ func newLogCmd() *cobra.Command {
 					graphPrefix = graphLines[i]
 				}
 
-				if oneline {
-					short := shortHash(h)
-					line := short
+				if logFmt != nil {
+					line := logFmt.render(logFormatCommit{Hash: h, Commit: c, Decoration: decoration})
+					if graphPrefix != "" {
+						fmt.Fprintf(out, "%s %s\n", graphPrefix, line)
+					} else {
+						fmt.Fprintln(out, line)
+					}
+				} else if oneline {
+					line := paint(color, ansiYellow, shortHash(h))
 					if decoration != "" {
 						line += " " + decoration
 					}
@@ -172,6 +254,7 @@ func newLogCmd() *cobra.Command {
 					if decoration != "" {
 						commitLine += " " + decoration
 					}
+					commitLine = paint(color, ansiYellow, commitLine)
 					if graphPrefix != "" {
 						fmt.Fprintf(out, "%s %s\n", graphPrefix, commitLine)
 					} else {
@@ -192,12 +275,135 @@ func newLogCmd() *cobra.Command {
 	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "maximum number of commits to show")
 	cmd.Flags().StringVar(&entitySelector, "entity", "", "filter commits by entity selector (path::entity_key or entity_key)")
 	cmd.Flags().BoolVar(&all, "all", false, "show commits from all branches and tags")
+	cmd.Flags().BoolVar(&firstParent, "first-parent", false, "follow only the first parent of merge commits")
+	cmd.Flags().BoolVar(&merges, "merges", false, "show only merge commits")
+	cmd.Flags().BoolVar(&noMerges, "no-merges", false, "do not show merge commits")
 	cmd.Flags().BoolVar(&graph, "graph", false, "draw an ASCII commit graph alongside the log")
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
+	cmd.Flags().StringVar(&author, "author", "", "show only commits whose author matches a regular expression")
+	cmd.Flags().StringVar(&grep, "grep", "", "show only commits whose message matches a regular expression")
+	cmd.Flags().StringVar(&since, "since", "", "show only commits dated at or after a date")
+	cmd.Flags().StringVar(&until, "until", "", "show only commits dated at or before a date")
+	cmd.Flags().StringVarP(&diffGrep, "diff-grep", "G", "", "show only commits whose diff adds or removes a line matching a regular expression")
+	cmd.Flags().StringVar(&entityContains, "entity-contains", "", "show only commits that made an entity body match a regular expression")
+	cmd.Flags().StringVar(&format, "format", "", "print each commit with a template such as \"%h %an %s\"")
 
 	return cmd
 }
 
+// mailmapLogEntries rewrites the authors of entries as the .mailmap file
+// maps them.
+func mailmapLogEntries(r *repo.Repo, entries []repo.LogEntry) error {
+	mailmap, err := r.Mailmap()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		e.Commit.Author = mailmap.Map(e.Commit.Author)
+	}
+	return nil
+}
+
+// buildLogOptions turns the log filter flags into commit predicates. Empty
+// flags add no predicate; now anchors relative dates.
+func buildLogOptions(author, grep, since, until string, now time.Time) (repo.LogOptions, error) {
+	var preds []repo.CommitPredicate
+	if author != "" {
+		p, err := repo.AuthorMatches(author)
+		if err != nil {
+			return repo.LogOptions{}, fmt.Errorf("--author: %w", err)
+		}
+		preds = append(preds, p)
+	}
+	if grep != "" {
+		p, err := repo.MessageMatches(grep)
+		if err != nil {
+			return repo.LogOptions{}, fmt.Errorf("--grep: %w", err)
+		}
+		preds = append(preds, p)
+	}
+	if since != "" {
+		t, err := parseLogDate("--since", since, now)
+		if err != nil {
+			return repo.LogOptions{}, err
+		}
+		preds = append(preds, repo.CommittedSince(t))
+	}
+	if until != "" {
+		t, err := parseLogDate("--until", until, now)
+		if err != nil {
+			return repo.LogOptions{}, err
+		}
+		preds = append(preds, repo.CommittedUntil(t))
+	}
+	return repo.LogOptions{Filter: repo.AllOf(preds...)}, nil
+}
+
+// addPickaxeFilters adds the -G and --entity-contains predicates to opts.
+// An --entity selector narrows --entity-contains to that entity.
+func addPickaxeFilters(r *repo.Repo, opts repo.LogOptions, diffGrep, entityContains, entitySelector string) (repo.LogOptions, error) {
+	preds := []repo.CommitPredicate{opts.Filter}
+	if diffGrep != "" {
+		p, err := r.DiffLinesMatch(diffGrep)
+		if err != nil {
+			return repo.LogOptions{}, fmt.Errorf("-G: %w", err)
+		}
+		preds = append(preds, p)
+	}
+	if entityContains != "" {
+		var selector logEntitySelector
+		if strings.TrimSpace(entitySelector) != "" {
+			var err error
+			if selector, err = parseLogEntitySelector(entitySelector); err != nil {
+				return repo.LogOptions{}, err
+			}
+		}
+		p, err := r.EntityBodyContains(selector.Path, selector.Key, entityContains)
+		if err != nil {
+			return repo.LogOptions{}, fmt.Errorf("--entity-contains: %w", err)
+		}
+		preds = append(preds, p)
+	}
+	opts.Filter = repo.AllOf(preds...)
+	return opts, nil
+}
+
+// logDateUnits maps the units of a relative date to their duration.
+var logDateUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+	"year":   365 * 24 * time.Hour,
+}
+
+// parseLogDate parses a --since/--until value: "now", "yesterday",
+// "<n> <unit>[s] ago" (dots may replace the spaces, as in "2.weeks.ago"),
+// or any date accepted by commit --date.
+func parseLogDate(flag, value string, now time.Time) (time.Time, error) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	switch v {
+	case "now":
+		return now, nil
+	case "yesterday":
+		return now.Add(-24 * time.Hour), nil
+	}
+	if fields := strings.Fields(strings.ReplaceAll(v, ".", " ")); len(fields) == 3 && fields[2] == "ago" {
+		n, err := strconv.Atoi(fields[0])
+		unit, ok := logDateUnits[strings.TrimSuffix(fields[1], "s")]
+		if err == nil && ok && n >= 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+	t, err := parseCommitDate(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: want a date, or a relative time like \"2 weeks ago\"", flag, value)
+	}
+	return t, nil
+}
+
 // logEntriesToJSON converts log entries to JSON output.
 func logEntriesToJSON(cmd *cobra.Command, entries []repo.LogEntry, headHash object.Hash, branchName string, useAllDecoration bool, refDecorations map[object.Hash][]string) error {
 	result := JSONLogOutput{
@@ -215,6 +421,7 @@ func logEntriesToJSON(cmd *cobra.Command, entries []repo.LogEntry, headHash obje
 			decoration = buildDecoration(h, headHash, branchName)
 		}
 
+		subject, body := splitCommitMessage(c.Message)
 		parents := make([]string, len(c.Parents))
 		for i, p := range c.Parents {
 			parents[i] = string(p)
@@ -227,6 +434,8 @@ func logEntriesToJSON(cmd *cobra.Command, entries []repo.LogEntry, headHash obje
 			Date:       time.Unix(c.Timestamp, 0).Format("2006-01-02 15:04:05"),
 			Timestamp:  c.Timestamp,
 			Message:    c.Message,
+			Subject:    subject,
+			Body:       body,
 			Parents:    parents,
 			Decoration: decoration,
 		})
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/odvcencio/graft/pkg/object"
 	"github.com/odvcencio/graft/pkg/repo"
@@ -284,6 +286,168 @@ func TestLogGraphIntegration(t *testing.T) {
 	}
 }
 
+// TestLogPathspecIntegration verifies that pathspecs limit the log to
+// commits touching matching files.
+func TestLogPathspecIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "README.md", "readme\n", "docs commit")
+	commitFile(t, dir, "pkg/a.go", "package pkg\n", "code commit")
+	commitFile(t, dir, "pkg/a_test.go", "package pkg\n", "test commit")
+
+	out := mustRunGraft(t, dir, "log", "--oneline", "--", "**/*.go", ":!*_test.go")
+	lines := nonEmptyLines(out)
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], "code commit") {
+		t.Fatalf("log -- **/*.go :!*_test.go = %q, want only the code commit", lines)
+	}
+
+	out = mustRunGraft(t, dir, "log", "--oneline", ":(icase)readme.md")
+	lines = nonEmptyLines(out)
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], "docs commit") {
+		t.Fatalf("log :(icase)readme.md = %q, want only the docs commit", lines)
+	}
+}
+
+func TestLogFilterFlagsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	for _, c := range []struct{ file, author, date, msg string }{
+		{"a.txt", "Alice", "2026-01-05", "add parser"},
+		{"b.txt", "Bob", "2026-01-10", "fix parser crash"},
+		{"c.txt", "Alice", "2026-01-20", "fix lexer"},
+	} {
+		writeFile(t, dir, c.file, c.msg+"\n")
+		mustRunGraft(t, dir, "add", c.file)
+		mustRunGraft(t, dir, "commit", "-m", c.msg, "--author", c.author, "--date", c.date, "--no-sign")
+	}
+
+	checks := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--author", "Alice"}, []string{"fix lexer", "add parser"}},
+		{[]string{"--grep", "^fix"}, []string{"fix lexer", "fix parser crash"}},
+		{[]string{"--author", "Alice", "--grep", "fix"}, []string{"fix lexer"}},
+		{[]string{"--since", "2026-01-06", "--until", "2026-01-15"}, []string{"fix parser crash"}},
+		{[]string{"--author", "Alice", "-n", "1"}, []string{"fix lexer"}},
+		{[]string{"--author", "Nobody"}, nil},
+	}
+	for _, c := range checks {
+		out := mustRunGraft(t, dir, append([]string{"log", "--oneline"}, c.args...)...)
+		lines := nonEmptyLines(out)
+		if len(lines) != len(c.want) {
+			t.Fatalf("log %v = %q, want %q", c.args, lines, c.want)
+		}
+		for i, want := range c.want {
+			if !strings.HasSuffix(lines[i], want) {
+				t.Fatalf("log %v = %q, want %q", c.args, lines, c.want)
+			}
+		}
+	}
+
+	if _, err := runGraft(t, dir, "log", "--since", "last tuesday-ish"); err == nil {
+		t.Fatal("expected an error for an unparseable --since")
+	}
+}
+
+func TestLogMergeFilterIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "a\n", "base")
+	mainBranch := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "--abbrev-ref", "HEAD"))
+	mustRunGraft(t, dir, "branch", "feature")
+	commitFile(t, dir, "b.txt", "b\n", "main work")
+	mustRunGraft(t, dir, "checkout", "feature")
+	commitFile(t, dir, "c.txt", "c\n", "feature work")
+	mustRunGraft(t, dir, "checkout", mainBranch)
+	mustRunGraft(t, dir, "merge", "feature")
+
+	mergeLines := nonEmptyLines(mustRunGraft(t, dir, "log", "--oneline", "--merges"))
+	if len(mergeLines) != 1 || strings.Contains(mergeLines[0], "work") || strings.Contains(mergeLines[0], "base") {
+		t.Fatalf("log --merges = %q, want only the merge commit", mergeLines)
+	}
+
+	noMerge := nonEmptyLines(mustRunGraft(t, dir, "log", "--oneline", "--no-merges", "--all"))
+	if len(noMerge) != 3 || !strings.Contains(strings.Join(noMerge, "\n"), "feature work") {
+		t.Fatalf("log --no-merges --all = %q, want the three non-merge commits", noMerge)
+	}
+
+	limited := nonEmptyLines(mustRunGraft(t, dir, "log", "--oneline", "--no-merges", "-n", "1"))
+	if len(limited) != 1 || !strings.HasSuffix(limited[0], "main work") {
+		t.Fatalf("log --no-merges -n 1 = %q, want main work", limited)
+	}
+
+	if _, err := runGraft(t, dir, "log", "--merges", "--no-merges"); err == nil {
+		t.Fatal("expected an error combining --merges and --no-merges")
+	}
+}
+
+func TestLogPickaxeIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "main.go", "package main\n\nfunc Load() {\n}\n\nfunc Save() {\n}\n", "add load and save")
+	commitFile(t, dir, "main.go", "package main\n\nfunc Load() {\n}\n\nfunc Save() {\n\tcache.Flush()\n}\n", "flush in save")
+	final := "package main\n\nfunc Load() {\n\tcache.Flush()\n}\n\nfunc Save() {\n}\n"
+	commitFile(t, dir, "main.go", final, "move flush to load")
+	loadKey := cmdBlameDeclarationKey(t, "main.go", []byte(final), "Load")
+
+	checks := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-G", `cache\.Flush`}, []string{"move flush to load", "flush in save"}},
+		{[]string{"--entity-contains", "Flush"}, []string{"move flush to load", "flush in save"}},
+		{[]string{"--entity", "main.go::" + loadKey, "--entity-contains", "Flush"}, []string{"move flush to load"}},
+		{[]string{"-G", "Flush", "--grep", "^flush"}, []string{"flush in save"}},
+	}
+	for _, c := range checks {
+		out := mustRunGraft(t, dir, append([]string{"log", "--oneline"}, c.args...)...)
+		lines := nonEmptyLines(out)
+		if len(lines) != len(c.want) {
+			t.Fatalf("log %v = %q, want %q", c.args, lines, c.want)
+		}
+		for i, want := range c.want {
+			if !strings.HasSuffix(lines[i], want) {
+				t.Fatalf("log %v = %q, want %q", c.args, lines, c.want)
+			}
+		}
+	}
+
+	if _, err := runGraft(t, dir, "log", "-G", "("); err == nil {
+		t.Fatal("expected an error for an invalid -G pattern")
+	}
+}
+
+func TestParseLogDate_Relative(t *testing.T) {
+	now := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Time{
+		"2 weeks ago": now.Add(-14 * 24 * time.Hour),
+		"3.days.ago":  now.Add(-3 * 24 * time.Hour),
+		"1 hour ago":  now.Add(-time.Hour),
+		"yesterday":   now.Add(-24 * time.Hour),
+	} {
+		got, err := parseLogDate("--since", value, now)
+		if err != nil {
+			t.Fatalf("parseLogDate(%q): %v", value, err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("parseLogDate(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
 // TestLogAllDeduplicatesIntegration verifies --all does not show the same
 // commit twice when branches share history.
 func TestLogAllDeduplicatesIntegration(t *testing.T) {
@@ -318,3 +482,73 @@ func nonEmptyLines(s string) []string {
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func TestParseLogFormat(t *testing.T) {
+	c := logFormatCommit{
+		Hash: object.Hash("abcdef0123456789"),
+		Commit: &object.CommitObj{
+			Author:    "Ada Lovelace <ada@example.com>",
+			Timestamp: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC).Unix(),
+			Message:   "Add parser\n\nHandles nested groups.\n",
+			Parents:   []object.Hash{"1111111111111111", "2222222222222222"},
+		},
+		Decoration: "(HEAD -> main)",
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"%H", "abcdef0123456789"},
+		{"format:%an <%ae>", "Ada Lovelace <ada@example.com>"},
+		{"%at|%s|%b", "1772366400|Add parser|Handles nested groups."},
+		{"%h%d", shortHash(c.Hash) + " (HEAD -> main)"},
+		{"%P", "1111111111111111 2222222222222222"},
+		{"100%% %s%nend", "100% Add parser\nend"},
+	}
+	for _, tt := range tests {
+		f, err := parseLogFormat(tt.tmpl)
+		if err != nil {
+			t.Fatalf("parseLogFormat(%q): %v", tt.tmpl, err)
+		}
+		if got := f.render(c); got != tt.want {
+			t.Errorf("render(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	for _, tmpl := range []string{"%x", "trailing %"} {
+		if _, err := parseLogFormat(tmpl); err == nil {
+			t.Errorf("parseLogFormat(%q): expected an error", tmpl)
+		}
+	}
+}
+
+func TestLogFormatAndJSONIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "a\n", "first change")
+	commitFile(t, dir, "b.txt", "b\n", "second change")
+
+	lines := nonEmptyLines(mustRunGraft(t, dir, "log", "--format=%h|%an|%s"))
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "|Test User|second change") || !strings.HasSuffix(lines[1], "|Test User|first change") {
+		t.Fatalf("log --format = %q", lines)
+	}
+
+	var parsed JSONLogOutput
+	if err := json.Unmarshal([]byte(mustRunGraft(t, dir, "log", "--json")), &parsed); err != nil {
+		t.Fatalf("log --json: %v", err)
+	}
+	if len(parsed.Commits) != 2 || parsed.Commits[0].Subject != "second change" {
+		t.Fatalf("log --json commits = %+v", parsed.Commits)
+	}
+
+	if _, err := runGraft(t, dir, "log", "--format=%q"); err == nil {
+		t.Fatal("expected an error for an unknown placeholder")
+	}
+	if _, err := runGraft(t, dir, "log", "--format=%h", "--json"); err == nil {
+		t.Fatal("expected an error combining --format with --json")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/remote"
+	"github.com/spf13/cobra"
+)
+
+func newLoginCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "login [host]",
+		Short: "Sign in to Orchard with the OAuth device flow",
+		Long: `Sign in to an Orchard server by approving this device in a browser.
+
+The access and refresh tokens are stored in ~/.graftconfig, and remote
+operations refresh the access token automatically when it expires.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := ""
+			if len(args) > 0 {
+				host = args[0]
+			}
+			baseURL, err := normalizeBaseURL(configuredOrchardHost(host), defaultOrchardBaseURL)
+			if err != nil {
+				return err
+			}
+
+			httpClient := &http.Client{Timeout: 20 * time.Second}
+			auth, err := remote.RequestDeviceAuthorization(cmd.Context(), httpClient, baseURL)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "To sign in, open %s and enter code %s\n", auth.VerificationURI, auth.UserCode)
+			if auth.VerificationURIComplete != "" {
+				fmt.Fprintf(out, "Or open %s\n", auth.VerificationURIComplete)
+			}
+			fmt.Fprintln(out, "Waiting for approval...")
+
+			token, err := remote.PollDeviceToken(cmd.Context(), httpClient, baseURL, auth)
+			if err != nil {
+				return err
+			}
+			if err := remote.SaveOAuthToken(baseURL, token); err != nil {
+				return err
+			}
+			// Make the host the default, as the other sign-in commands do.
+			if err := writeAuthConfig(baseURL, token.AccessToken, authUser{}); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Authenticated on %s\n", baseURL)
+			return nil
+		},
+	}
+}
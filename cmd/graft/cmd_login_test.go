@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/userconfig"
+)
+
+func TestLoginStoresRefreshableToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GRAFT_ORCHARD_URL", "")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/oauth/device/code":
+			_, _ = w.Write([]byte(`{"device_code":"dev-1","user_code":"WXYZ-1234","verification_uri":"https://orchard.example/device","expires_in":600,"interval":1}`))
+		case "/api/v1/oauth/token":
+			_, _ = w.Write([]byte(`{"access_token":"access-1","refresh_token":"refresh-1","expires_in":3600}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cmd := newLoginCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{server.URL})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if !strings.Contains(out.String(), "enter code WXYZ-1234") {
+		t.Fatalf("output = %q, want user code prompt", out.String())
+	}
+
+	cfg, err := userconfig.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.DefaultOrchardURL(); got != server.URL {
+		t.Fatalf("DefaultOrchardURL() = %q, want %q", got, server.URL)
+	}
+	profile := cfg.OrchardProfile(server.URL)
+	if profile.Token != "access-1" || profile.RefreshToken != "refresh-1" || profile.TokenExpiresAt == "" {
+		t.Fatalf("profile = %+v, want stored OAuth tokens", profile)
+	}
+
+	// Signing in another way replaces the refreshable token.
+	if err := writeAuthConfig(server.URL, "ssh-token", authUser{}); err != nil {
+		t.Fatalf("writeAuthConfig: %v", err)
+	}
+	cfg, err = userconfig.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if profile := cfg.OrchardProfile(server.URL); profile.RefreshToken != "" || profile.TokenExpiresAt != "" {
+		t.Fatalf("profile = %+v, want refresh token cleared", profile)
+	}
+}
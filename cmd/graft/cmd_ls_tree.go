@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newLsTreeCmd() *cobra.Command {
+	var recursive, nameOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "ls-tree [-r] [--name-only] <tree-ish>",
+		Short: "List the entries of a tree",
+		Long: `Ls-tree lists the entries of a tree, one per line as
+"<mode> <type> <hash>\t<name>". The tree-ish is a commit, whose root tree
+is listed, a tree hash, or a revision naming a directory such as
+HEAD:pkg/repo.
+
+With -r, subdirectories are listed recursively: every file and module of
+the tree is printed with its full path, and directories themselves are
+left out. With --name-only, only the names are printed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			treeHash, err := resolveTree(r, args[0])
+			if err != nil {
+				return fmt.Errorf("ls-tree: %w", err)
+			}
+
+			var entries []lsTreeEntry
+			if recursive {
+				entries, err = lsTreeRecursive(r, treeHash)
+			} else {
+				entries, err = lsTreeEntries(r, treeHash)
+			}
+			if err != nil {
+				return fmt.Errorf("ls-tree: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			for _, e := range entries {
+				if nameOnly {
+					fmt.Fprintln(out, e.name)
+					continue
+				}
+				fmt.Fprintf(out, "%s %s %s\t%s\n", e.mode, e.typ, e.hash, e.name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "list subdirectories recursively")
+	cmd.Flags().BoolVar(&nameOnly, "name-only", false, "print only entry names")
+	return cmd
+}
+
+// lsTreeEntry is one line of ls-tree output.
+type lsTreeEntry struct {
+	mode string
+	typ  string
+	hash object.Hash
+	name string
+}
+
+// resolveTree resolves name to a tree hash, peeling tags and commits.
+func resolveTree(r *repo.Repo, name string) (object.Hash, error) {
+	h, err := resolveObject(r, name)
+	if err != nil {
+		return "", err
+	}
+	for {
+		objType, data, err := r.Store.Read(h)
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", h, err)
+		}
+		switch objType {
+		case object.TypeTree:
+			return h, nil
+		case object.TypeCommit:
+			c, err := object.UnmarshalCommit(data)
+			if err != nil {
+				return "", fmt.Errorf("read commit %s: %w", h, err)
+			}
+			return c.TreeHash, nil
+		case object.TypeTag:
+			tag, err := object.UnmarshalTag(data)
+			if err != nil {
+				return "", fmt.Errorf("read tag %s: %w", h, err)
+			}
+			h = tag.TargetHash
+		default:
+			return "", fmt.Errorf("%s is a %s, not a tree", name, objType)
+		}
+	}
+}
+
+// lsTreeEntries lists the direct entries of a tree.
+func lsTreeEntries(r *repo.Repo, h object.Hash) ([]lsTreeEntry, error) {
+	tree, err := r.Store.ReadTree(h)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]lsTreeEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		mode, typ, hash := treeEntryFields(e)
+		entries = append(entries, lsTreeEntry{mode: mode, typ: typ, hash: hash, name: e.Name})
+	}
+	return entries, nil
+}
+
+// lsTreeRecursive lists every file and module below a tree by full path.
+func lsTreeRecursive(r *repo.Repo, h object.Hash) ([]lsTreeEntry, error) {
+	files, modules, err := r.FlattenTreeWithModules(h)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]lsTreeEntry, 0, len(files)+len(modules))
+	for _, f := range files {
+		entries = append(entries, lsTreeEntry{mode: f.Mode, typ: "blob", hash: f.BlobHash, name: f.Path})
+	}
+	for _, m := range modules {
+		entries = append(entries, lsTreeEntry{mode: object.TreeModeModule, typ: "commit", hash: m.BlobHash, name: m.Path})
+	}
+	if len(modules) > 0 {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	}
+	return entries, nil
+}
+
+// treeEntryFields returns the mode, object type and hash a tree entry is
+// listed with: trees for directories, commits for modules, else blobs.
+func treeEntryFields(e object.TreeEntry) (mode, typ string, hash object.Hash) {
+	if e.IsDir {
+		return object.TreeModeDir, "tree", e.SubtreeHash
+	}
+	mode = e.Mode
+	if mode == "" {
+		mode = object.TreeModeFile
+	}
+	if mode == object.TreeModeModule {
+		return mode, "commit", e.BlobHash
+	}
+	return mode, "blob", e.BlobHash
+}
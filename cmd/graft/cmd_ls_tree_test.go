@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLsTreeIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	writeFile(t, dir, "a.txt", "a\n")
+	writeFile(t, dir, "pkg/b.txt", "b\n")
+	writeFile(t, dir, "pkg/sub/c.txt", "c\n")
+	mustRunGraft(t, dir, "add", ".")
+	mustRunGraft(t, dir, "commit", "-m", "first")
+
+	top := nonEmptyLines(mustRunGraft(t, dir, "ls-tree", "HEAD"))
+	if len(top) != 2 || !strings.HasSuffix(top[0], "\ta.txt") || !strings.Contains(top[1], " tree ") || !strings.HasSuffix(top[1], "\tpkg") {
+		t.Fatalf("ls-tree HEAD = %q, want a.txt and the pkg tree", top)
+	}
+	blob := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD:a.txt"))
+	if fields := strings.Fields(top[0]); len(fields) != 4 || fields[0] != "100644" || fields[1] != "blob" || fields[2] != blob {
+		t.Fatalf("ls-tree a.txt entry = %q, want 100644 blob %s", top[0], blob)
+	}
+
+	names := nonEmptyLines(mustRunGraft(t, dir, "ls-tree", "-r", "--name-only", "HEAD"))
+	if strings.Join(names, ",") != "a.txt,pkg/b.txt,pkg/sub/c.txt" {
+		t.Fatalf("ls-tree -r --name-only HEAD = %q", names)
+	}
+	sub := nonEmptyLines(mustRunGraft(t, dir, "ls-tree", "--name-only", "HEAD:pkg"))
+	if strings.Join(sub, ",") != "b.txt,sub" {
+		t.Fatalf("ls-tree --name-only HEAD:pkg = %q", sub)
+	}
+
+	if _, err := runGraft(t, dir, "ls-tree", "HEAD:a.txt"); err == nil {
+		t.Fatal("expected an error listing a blob")
+	}
+}
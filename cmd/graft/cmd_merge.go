@@ -12,10 +12,16 @@ func newMergeCmd() *cobra.Command {
 	var abortFlag bool
 	var dryRunFlag bool
 	var jsonFlag bool
+	var newProgress func() *progressMeter
 	cmd := &cobra.Command{
 		Use:   "merge <branch>",
 		Short: "Merge a branch into the current branch",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Merge a branch into the current branch.
+
+A merge that stops on conflicts exits with status 2, once the conflicts
+have been reported; fix them and run graft commit.`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeRefs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
@@ -65,13 +71,22 @@ func newMergeCmd() *cobra.Command {
 				fmt.Fprintf(out, "merging %s into %s...\n", branchName, current)
 			}
 
-			report, err := r.Merge(branchName)
+			progress := newProgress()
+			r.SetProgress(progress.Func())
+			report, err := r.MergeWithOptions(branchName, repo.MergeOptions{Autostash: autostashEnabled(cmd, r)})
+			progress.Done()
 			if err != nil {
 				return err
 			}
 
 			if jsonFlag {
-				return mergeReportToJSON(cmd, report, "merge", branchName, current)
+				if err := mergeReportToJSON(cmd, report, "merge", branchName, current); err != nil {
+					return err
+				}
+				if report.HasConflicts {
+					return exitStatus(exitConflict)
+				}
+				return nil
 			}
 
 			if report.IsFastForward {
@@ -94,15 +109,18 @@ func newMergeCmd() *cobra.Command {
 				}
 				fmt.Fprintln(out)
 				fmt.Fprintln(out, "fix conflicts and run graft commit")
-			} else {
-				fmt.Fprintln(out, "merge completed cleanly")
-				short := string(report.MergeCommit)
-				if len(short) > 8 {
-					short = short[:8]
+				if report.AutostashPending {
+					fmt.Fprintln(out, "your local changes are in stash@{0}; run graft stash pop after committing the merge")
 				}
-				fmt.Fprintf(out, "[%s %s] Merge branch '%s'\n", current, short, branchName)
+				return exitStatus(exitConflict)
 			}
 
+			fmt.Fprintln(out, "merge completed cleanly")
+			short := string(report.MergeCommit)
+			if len(short) > 8 {
+				short = short[:8]
+			}
+			fmt.Fprintf(out, "[%s %s] Merge branch '%s'\n", current, short, branchName)
 			return nil
 		},
 	}
@@ -110,6 +128,8 @@ func newMergeCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&abortFlag, "abort", false, "abort the current merge and restore original state")
 	cmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "preview what a merge would do without modifying anything")
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
+	addAutostashFlags(cmd)
+	newProgress = addProgressFlag(cmd)
 	return cmd
 }
 
@@ -186,6 +206,11 @@ func humanConflictType(ct string) string {
 
 // mergeReportToJSON converts a MergeReport to JSON output.
 func mergeReportToJSON(cmd *cobra.Command, report *repo.MergeReport, action, source, target string) error {
+	return writeJSON(cmd.OutOrStdout(), mergeReportJSON(report, action, source, target))
+}
+
+// mergeReportJSON converts a MergeReport to its JSON form.
+func mergeReportJSON(report *repo.MergeReport, action, source, target string) JSONMergeOutput {
 	result := JSONMergeOutput{
 		Action:         action,
 		Source:         source,
@@ -220,8 +245,7 @@ func mergeReportToJSON(cmd *cobra.Command, report *repo.MergeReport, action, sou
 		}
 		result.Files = append(result.Files, jf)
 	}
-
-	return writeJSON(cmd.OutOrStdout(), result)
+	return result
 }
 
 // runMergePreviewJSON handles --dry-run --json: runs MergePreview and writes JSON.
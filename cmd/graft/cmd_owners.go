@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newOwnersCmd() *cobra.Command {
+	var jsonFlag bool
+	var ownerFilter string
+
+	cmd := &cobra.Command{
+		Use:   "owners [<commit> | <a>..<b> | <a>...<b>]",
+		Short: "Group the entities changed in a range by their owners",
+		Long: `Owners lists the entities changed in a revision range grouped by who owns
+them, to show whose review a change needs. A single commit (HEAD by
+default) is compared with its first parent; <a>...<b> compares the merge
+base of a and b with b, as diff does.
+
+Ownership comes from .graftowners, or else from a CODEOWNERS file in
+.github/, the repository root or docs/, read from the newest commit of the
+range. In CODEOWNERS the last matching pattern decides a file's owners;
+.graftowners rules accumulate and may also name entities, such as
+"func:*Handler @api-team". Files without entity-level changes are listed
+whole. Each change shows the authors of the commits that made it.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			spec := ""
+			if len(args) == 1 {
+				spec = args[0]
+			}
+			report, err := r.EntityOwnership(spec)
+			if err != nil {
+				return err
+			}
+			if ownerFilter != "" {
+				filterOwnership(report, ownerFilter)
+			}
+
+			out := cmd.OutOrStdout()
+			if jsonFlag {
+				return writeJSON(out, ownersJSON(report))
+			}
+			printOwnership(out, report)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output as JSON")
+	cmd.Flags().StringVar(&ownerFilter, "owner", "", "only show the changes owned by this owner")
+	return cmd
+}
+
+// filterOwnership keeps only owner's group in report.
+func filterOwnership(report *repo.OwnershipReport, owner string) {
+	var kept []repo.OwnerChanges
+	for _, oc := range report.Owners {
+		if oc.Owner == owner {
+			kept = append(kept, oc)
+		}
+	}
+	report.Owners = kept
+	report.Unowned = nil
+}
+
+func printOwnership(w io.Writer, report *repo.OwnershipReport) {
+	from := "(root)"
+	if report.OldCommit != "" {
+		from = shortHash(report.OldCommit)
+	}
+	source := report.OwnersFile
+	if source == "" {
+		source = "no owners file"
+	}
+	fmt.Fprintf(w, "%s..%s: %s, owners from %s\n", from, shortHash(report.NewCommit),
+		pluralize(len(report.Changes), "change"), source)
+
+	for _, oc := range report.Owners {
+		fmt.Fprintf(w, "\n%s (%d)\n", oc.Owner, len(oc.Changes))
+		printOwnedChanges(w, oc.Changes)
+	}
+	if len(report.Unowned) > 0 {
+		fmt.Fprintf(w, "\nunowned (%d)\n", len(report.Unowned))
+		printOwnedChanges(w, report.Unowned)
+	}
+}
+
+func printOwnedChanges(w io.Writer, changes []repo.OwnedChange) {
+	for _, c := range changes {
+		line := fmt.Sprintf("  %-6s  %s", c.ChangeType, c.Path)
+		if c.EntityKey != "" {
+			line += "::" + c.EntityKey
+		}
+		if len(c.Authors) > 0 {
+			names := make([]string, len(c.Authors))
+			for i, a := range c.Authors {
+				names[i] = authorName(a)
+			}
+			line += "  (" + strings.Join(names, ", ") + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// authorName drops the email address from an author string.
+func authorName(author string) string {
+	if i := strings.Index(author, " <"); i > 0 {
+		return author[:i]
+	}
+	return author
+}
+
+func ownersJSON(report *repo.OwnershipReport) JSONOwnersOutput {
+	out := JSONOwnersOutput{
+		OldCommit:  string(report.OldCommit),
+		NewCommit:  string(report.NewCommit),
+		OwnersFile: report.OwnersFile,
+		Owners:     []JSONOwnerChanges{},
+		Unowned:    ownedChangesJSON(report.Unowned),
+	}
+	for _, oc := range report.Owners {
+		out.Owners = append(out.Owners, JSONOwnerChanges{Owner: oc.Owner, Changes: ownedChangesJSON(oc.Changes)})
+	}
+	return out
+}
+
+func ownedChangesJSON(changes []repo.OwnedChange) []JSONOwnedChange {
+	out := make([]JSONOwnedChange, 0, len(changes))
+	for _, c := range changes {
+		owners, authors := c.Owners, c.Authors
+		if owners == nil {
+			owners = []string{}
+		}
+		if authors == nil {
+			authors = []string{}
+		}
+		out = append(out, JSONOwnedChange{
+			Path:       c.Path,
+			EntityKey:  c.EntityKey,
+			ChangeType: c.ChangeType,
+			Owners:     owners,
+			Authors:    authors,
+		})
+	}
+	return out
+}
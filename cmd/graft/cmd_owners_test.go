@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestOwnersIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	dir := initRepo(t)
+	commitFile(t, dir, "CODEOWNERS", "*.md  @docs\n/svc/  @svc-team\n", "owners")
+	commitFile(t, dir, "svc/run.go", "package svc\n\nfunc Run() {}\n", "add svc")
+	writeFile(t, dir, "notes.md", "notes\n")
+	writeFile(t, dir, "svc/run.go", "package svc\n\nfunc Run() { println() }\n")
+	mustRunGraft(t, dir, "add", "notes.md", "svc/run.go")
+	mustRunGraft(t, dir, "commit", "-m", "touch both")
+
+	out := mustRunGraft(t, dir, "owners")
+	for _, want := range []string{"owners from CODEOWNERS", "@svc-team (1)", "svc/run.go::", "@docs (1)", "notes.md"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("owners output missing %q:\n%s", want, out)
+		}
+	}
+
+	var got JSONOwnersOutput
+	if err := json.Unmarshal([]byte(mustRunGraft(t, dir, "owners", "--json", "--owner", "@svc-team")), &got); err != nil {
+		t.Fatalf("owners --json: %v", err)
+	}
+	if len(got.Owners) != 1 || got.Owners[0].Owner != "@svc-team" || len(got.Owners[0].Changes) != 1 {
+		t.Fatalf("owners --json --owner @svc-team = %+v", got)
+	}
+	if c := got.Owners[0].Changes[0]; c.Path != "svc/run.go" || c.ChangeType != "modify" || len(c.Authors) != 1 {
+		t.Fatalf("@svc-team change = %+v, want svc/run.go modified by one author", c)
+	}
+}
@@ -86,7 +86,7 @@ func newPublishCmd() *cobra.Command {
 			if transport != remoteTransportGraft {
 				return fmt.Errorf("publish currently supports orchard/graft remotes only")
 			}
-			return pushBranchGot(cmd, r, remoteName, remoteURL, pushBranchName, false)
+			return pushRefsGot(cmd, r, remoteName, remoteURL, []string{pushBranchName}, pushOptions{thin: true}, nil)
 		},
 	}
 
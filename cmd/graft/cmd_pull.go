@@ -3,10 +3,12 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/odvcencio/graft/pkg/object"
 	"github.com/odvcencio/graft/pkg/repo"
 	"github.com/spf13/cobra"
 )
@@ -14,11 +16,21 @@ import (
 func newPullCmd() *cobra.Command {
 	var allowMerge bool
 	var rebaseFlag bool
+	var newProgress func() *progressMeter
+	var showStats *bool
 
 	cmd := &cobra.Command{
 		Use:   "pull [remote] [branch]",
 		Short: "Fetch from remote and integrate (fast-forward, --merge, or --rebase)",
-		Args:  cobra.MaximumNArgs(2),
+		Long: `Fetch from remote and integrate (fast-forward, --merge, or --rebase).
+
+With the global --json flag, the outcome is written as JSON: the branch,
+its old and new commits, the objects fetched and a result of up-to-date,
+ahead, created, fast-forward, merged, rebased or conflict. A pull that
+stops on conflicts exits with status 2.`,
+		Args:              cobra.MaximumNArgs(2),
+		ValidArgsFunction: completeRemoteAndBranch(2),
+		PostRun:           runAutoGC,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if allowMerge && rebaseFlag {
 				return fmt.Errorf("--merge and --rebase are mutually exclusive")
@@ -49,7 +61,11 @@ func newPullCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if transport == remoteTransportGit {
+			jsonOut := jsonOutput(cmd)
+			if transport == remoteTransportGit && r.HasGitDir() {
+				if jsonOut {
+					return fmt.Errorf("pull --json is not supported when git runs the pull in a .git directory")
+				}
 				return pullViaGit(cmd, r, remoteURL, branch, allowMerge, rebaseFlag)
 			}
 
@@ -64,6 +80,21 @@ func newPullCmd() *cobra.Command {
 				return fmt.Errorf("cannot infer branch while HEAD is detached; specify branch")
 			}
 
+			// In JSON mode the text summary is dropped and summary written
+			// by report instead.
+			out := cmd.OutOrStdout()
+			if jsonOut {
+				out = io.Discard
+			}
+			summary := JSONPullOutput{Remote: remoteName, Branch: branch}
+			report := func(result string, oldHash, newHash object.Hash) error {
+				if !jsonOut {
+					return nil
+				}
+				summary.Result, summary.Old, summary.New = result, string(oldHash), string(newHash)
+				return writeJSON(cmd.OutOrStdout(), summary)
+			}
+
 			localRef := "refs/heads/" + branch
 			localHash, err := r.ResolveRef(localRef)
 			hasLocal := err == nil
@@ -77,14 +108,42 @@ func newPullCmd() *cobra.Command {
 				}
 			}
 
-			// Fetch all refs from the remote (objects + tracking refs).
-			result, err := r.FetchContext(cmd.Context(), remoteName)
-			if err != nil {
-				return err
+			// Fetch all refs from the remote (objects + tracking refs). Git
+			// remotes without a co-located .git are translated branch by
+			// branch into graft history.
+			var fetchedObjects int
+			if transport == remoteTransportGit {
+				result, err := r.FetchFromGit(cmd.Context(), remoteName, remoteURL, branch)
+				if err != nil {
+					return err
+				}
+				fetchedObjects = result.ObjectCount()
+			} else {
+				progress := newProgress()
+				r.SetProgress(progress.Func())
+				result, err := r.FetchContext(cmd.Context(), remoteName)
+				progress.Done()
+				if err != nil {
+					return err
+				}
+				printTransferStats(cmd.ErrOrStderr(), *showStats, result.Stats)
+				fetchedObjects = result.ObjectCount
 			}
+			summary.ObjectsFetched = fetchedObjects
 
-			// Look up the remote branch hash from the tracking ref that Fetch populated.
+			// Look up the remote branch hash from the tracking ref that Fetch
+			// populated, as placed by the remote's fetch refspecs.
 			trackingRef := remoteTrackingRefName(remoteName, "heads/"+branch)
+			if transport != remoteTransportGit {
+				mapped, ok, err := r.TrackingRefFor(remoteName, "heads/"+branch)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return fmt.Errorf("remote branch %q is not fetched by the refspecs of remote %q", branch, remoteName)
+				}
+				trackingRef = mapped
+			}
 			remoteHash, err := r.ResolveRef(trackingRef)
 			if err != nil {
 				return fmt.Errorf("remote branch %q not found", branch)
@@ -97,8 +156,8 @@ func newPullCmd() *cobra.Command {
 				}
 				// Local already contains remote commit(s).
 				if base == remoteHash {
-					fmt.Fprintf(cmd.OutOrStdout(), "already up to date (local %s is ahead of remote %s)\n", shortHash(localHash), shortHash(remoteHash))
-					return nil
+					fmt.Fprintf(out, "already up to date (local %s is ahead of remote %s)\n", shortHash(localHash), shortHash(remoteHash))
+					return report("ahead", localHash, remoteHash)
 				}
 
 				// Diverged: require explicit merge or rebase mode.
@@ -116,23 +175,27 @@ func newPullCmd() *cobra.Command {
 								details := conflictErr.Details
 								details = strings.TrimPrefix(details, "conflict in: ")
 								paths := strings.Split(details, ", ")
-								out := cmd.OutOrStdout()
 								for _, p := range paths {
 									p = strings.TrimSpace(p)
 									if p != "" {
 										fmt.Fprintf(out, "CONFLICT in %s. Fix conflicts and run: graft rebase --continue\n", p)
+										summary.Conflicts = append(summary.Conflicts, p)
 									}
 								}
-								return nil
+								if err := report("conflict", localHash, remoteHash); err != nil {
+									return err
+								}
+								return exitStatus(exitConflict)
 							}
 							return fmt.Errorf("pull --rebase: %w", err)
 						}
-						fmt.Fprintf(cmd.OutOrStdout(), "rebased onto %s (%d objects fetched)\n", shortHash(remoteHash), result.ObjectCount)
-						return nil
+						fmt.Fprintf(out, "rebased onto %s (%d objects fetched)\n", shortHash(remoteHash), fetchedObjects)
+						newHead, _ := r.ResolveRef(localRef)
+						return report("rebased", localHash, newHead)
 					}
 
 					if !allowMerge {
-						return fmt.Errorf("pull would not fast-forward %s (local %s, remote %s); retry with --merge or --rebase", branch, shortHash(localHash), shortHash(remoteHash))
+						return fmt.Errorf("pull would not fast-forward %s (local %s, remote %s): %w; retry with --merge or --rebase", branch, shortHash(localHash), shortHash(remoteHash), repo.ErrNonFastForward)
 					}
 					if currentBranch != branch {
 						return fmt.Errorf("pull --merge requires checked out branch %q (current: %q)", branch, currentBranch)
@@ -144,15 +207,16 @@ func newPullCmd() *cobra.Command {
 					}
 					defer func() { _ = r.DeleteBranch(tempBranch) }()
 
-					report, err := r.Merge(tempBranch)
+					mergeReport, err := r.Merge(tempBranch)
 					if err != nil {
 						return fmt.Errorf("pull: merge: %w", err)
 					}
-					if report.HasConflicts {
-						return fmt.Errorf("pull: merge completed with %d conflict(s); resolve conflicts and commit", report.TotalConflicts)
+					if mergeReport.HasConflicts {
+						return fmt.Errorf("pull: merge stopped with %w (%d conflict(s)); resolve them and commit", repo.ErrMergeConflicts, mergeReport.TotalConflicts)
 					}
-					fmt.Fprintf(cmd.OutOrStdout(), "merged %s into %s (%d objects fetched)\n", shortHash(remoteHash), branch, result.ObjectCount)
-					return nil
+					fmt.Fprintf(out, "merged %s into %s (%d objects fetched)\n", shortHash(remoteHash), branch, fetchedObjects)
+					newHead, _ := r.ResolveRef(localRef)
+					return report("merged", localHash, newHead)
 				}
 			}
 
@@ -176,19 +240,21 @@ func newPullCmd() *cobra.Command {
 			}
 
 			if hasLocal && localHash == remoteHash {
-				fmt.Fprintf(cmd.OutOrStdout(), "already up to date (%s)\n", shortHash(remoteHash))
-				return nil
+				fmt.Fprintf(out, "already up to date (%s)\n", shortHash(remoteHash))
+				return report("up-to-date", localHash, remoteHash)
 			}
 			if !hasLocal {
-				fmt.Fprintf(cmd.OutOrStdout(), "created local branch %s at %s (%d objects fetched)\n", branch, shortHash(remoteHash), result.ObjectCount)
-				return nil
+				fmt.Fprintf(out, "created local branch %s at %s (%d objects fetched)\n", branch, shortHash(remoteHash), fetchedObjects)
+				return report("created", "", remoteHash)
 			}
-			fmt.Fprintf(cmd.OutOrStdout(), "updated %s: %s -> %s (%d objects fetched)\n", branch, shortHash(localHash), shortHash(remoteHash), result.ObjectCount)
-			return nil
+			fmt.Fprintf(out, "updated %s: %s -> %s (%d objects fetched)\n", branch, shortHash(localHash), shortHash(remoteHash), fetchedObjects)
+			return report("fast-forward", localHash, remoteHash)
 		},
 	}
 	cmd.Flags().BoolVar(&allowMerge, "merge", false, "allow a merge commit when fast-forward is not possible")
 	cmd.Flags().BoolVar(&rebaseFlag, "rebase", false, "rebase local commits on top of remote instead of merging")
+	newProgress = addProgressFlag(cmd)
+	showStats = addStatsFlag(cmd)
 	return cmd
 }
 
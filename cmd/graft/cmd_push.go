@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/odvcencio/graft/pkg/object"
@@ -15,11 +18,27 @@ import (
 func newPushCmd() *cobra.Command {
 	var force bool
 	var checkOnly bool
+	var noThin bool
+	var followTags bool
+	var mirror bool
+	var newProgress func() *progressMeter
+	var showStats *bool
 
 	cmd := &cobra.Command{
-		Use:   "push [remote] [branch]",
-		Short: "Push a local branch or ref to a remote",
-		Args:  cobra.MaximumNArgs(2),
+		Use:   "push [remote] [ref | [+]<src>:<dst>]...",
+		Short: "Push local branches or refs to a remote",
+		Long: "Push local branches or tags to a remote.\n\n" +
+			"A refspec such as main:release pushes a local branch to a differently named remote branch; " +
+			"a leading + allows a non-fast-forward update. Without one, the remote's push refspecs " +
+			"(graft config remote.<name>.push) decide the destination.\n\n" +
+			"Several refs may be given after the remote; they are updated together, so either every ref " +
+			"moves or none does. --follow-tags also pushes the annotated tags that point at commits " +
+			"reachable from the pushed refs and are missing from the remote.\n\n" +
+			"--mirror replicates every local branch, tag and note and deletes the remote refs that no " +
+			"longer exist locally. A push without refs to a remote set up by clone --mirror does the same.\n\n" +
+			"With the global --json flag, a summary of the ref updates is written as JSON.",
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completeRemoteAndBranch(-1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
@@ -27,8 +46,9 @@ func newPushCmd() *cobra.Command {
 			}
 
 			remoteArg := ""
-			branch := ""
+			var refArgs []string
 			switch len(args) {
+			case 0:
 			case 1:
 				candidate := strings.TrimSpace(args[0])
 				if looksLikeRemoteURL(candidate) {
@@ -36,115 +56,331 @@ func newPushCmd() *cobra.Command {
 				} else if _, err := r.RemoteURL(candidate); err == nil {
 					remoteArg = candidate
 				} else {
-					branch = candidate
+					refArgs = []string{candidate}
 				}
-			case 2:
+			default:
 				remoteArg = strings.TrimSpace(args[0])
-				branch = strings.TrimSpace(args[1])
+				for _, arg := range args[1:] {
+					refArgs = append(refArgs, strings.TrimSpace(arg))
+				}
 			}
 			remoteName, remoteURL, transport, err := resolveRemoteNameAndSpec(r, remoteArg)
 			if err != nil {
 				return err
 			}
+			if mirror && len(refArgs) > 0 {
+				return fmt.Errorf("push: --mirror cannot be combined with refs")
+			}
+			jsonOut := jsonOutput(cmd)
+			if checkOnly && jsonOut {
+				return fmt.Errorf("push --check does not support --json; use graft verify push-limits --json")
+			}
 			if checkOnly {
+				if mirror {
+					return fmt.Errorf("push --check does not support --mirror")
+				}
 				if transport == remoteTransportGit {
 					return fmt.Errorf("push --check currently supports orchard/graft remotes only")
 				}
-				pushTarget, localRef, remoteRef, err := resolvePushRefNames(r, branch)
-				if err != nil {
-					return err
+				if len(refArgs) == 0 {
+					refArgs = []string{""}
 				}
-				report, err := collectPushLimitReport(cmd.Context(), r, pushTarget, localRef, remoteName, remoteURL, remoteRef)
-				if err != nil {
-					return err
+				for _, arg := range refArgs {
+					pushTarget, localRef, remoteRef, _, err := resolvePushRefspec(r, remoteName, arg)
+					if err != nil {
+						return err
+					}
+					report, err := collectPushLimitReport(cmd.Context(), r, pushTarget, localRef, remoteName, remoteURL, remoteRef)
+					if err != nil {
+						return err
+					}
+					if err := pushLimitError(report); err != nil {
+						return err
+					}
+					printPushLimitSummary(cmd.OutOrStdout(), report)
 				}
-				if err := pushLimitError(report); err != nil {
+				return nil
+			}
+			if !mirror && len(refArgs) == 0 {
+				if mirror, err = r.IsMirrorRemote(remoteName); err != nil {
 					return err
 				}
-				printPushLimitSummary(cmd.OutOrStdout(), report)
-				return nil
 			}
 			if transport == remoteTransportGit {
-				return pushViaGit(cmd, r, remoteURL, branch, force)
+				if mirror {
+					return fmt.Errorf("push --mirror currently supports orchard/graft remotes only")
+				}
+				if jsonOut {
+					return fmt.Errorf("push --json currently supports orchard/graft remotes only")
+				}
+				if r.HasGitDir() {
+					return pushViaGit(cmd, r, remoteURL, refArgs, force, followTags)
+				}
+				if len(refArgs) > 1 || followTags {
+					return fmt.Errorf("push: multiple refs and --follow-tags need a .git directory when pushing to a git remote")
+				}
+				branch := ""
+				if len(refArgs) == 1 {
+					branch = refArgs[0]
+				}
+				return pushBranchGitInterop(cmd, r, remoteName, remoteURL, branch, force)
 			}
-			return pushBranchGot(cmd, r, remoteName, remoteURL, branch, force)
+			opts := pushOptions{force: force, followTags: followTags, thin: !noThin, mirror: mirror, stats: *showStats, json: jsonOut}
+			return pushRefsGot(cmd, r, remoteName, remoteURL, refArgs, opts, newProgress())
 		},
 	}
 
 	cmd.Flags().BoolVar(&force, "force", false, "allow non-fast-forward update")
 	cmd.Flags().BoolVar(&checkOnly, "check", false, "validate push object limits without uploading anything")
+	cmd.Flags().BoolVar(&noThin, "no-thin", false, "send whole objects instead of deltas against objects the remote already has")
+	cmd.Flags().BoolVar(&followTags, "follow-tags", false, "also push annotated tags that point at pushed commits")
+	cmd.Flags().BoolVar(&mirror, "mirror", false, "push all branches, tags and notes and delete remote refs missing locally")
+	newProgress = addProgressFlag(cmd)
+	showStats = addStatsFlag(cmd)
 	return cmd
 }
 
-func pushBranchGot(cmd *cobra.Command, r *repo.Repo, remoteName, remoteURL, branch string, force bool) error {
-	pushTarget, localRef, remoteRef, err := resolvePushRefNames(r, branch)
+// pushOptions controls how pushRefsGot updates the remote.
+type pushOptions struct {
+	force      bool // allow non-fast-forward updates
+	followTags bool // add annotated tags pointing into the pushed history
+	thin       bool // send deltas against objects the remote has
+	mirror     bool // push every local ref and delete the remote's others
+	stats      bool // print transfer statistics when done
+	json       bool // write a JSON summary instead of text
+}
+
+// pushRef is one ref update of a push to a Graft remote.
+type pushRef struct {
+	display   string
+	localRef  string
+	remoteRef string
+	force     bool
+	local     object.Hash // "" deletes the remote ref
+	remote    object.Hash // "" when the remote does not have the ref
+}
+
+// mirrorPushRefs returns the updates that make the remote's branches, tags
+// and notes match the local ones, deleting the remote refs that do not
+// exist locally.
+func mirrorPushRefs(r *repo.Repo, remoteRefs map[string]object.Hash) ([]pushRef, error) {
+	local, err := r.MirrorRefs()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	refs := make([]pushRef, 0, len(local))
+	for _, name := range slices.Sorted(maps.Keys(local)) {
+		refs = append(refs, pushRef{display: mirrorRefDisplay(name), localRef: "refs/" + name, remoteRef: name, force: true, local: local[name]})
+	}
+	for _, name := range slices.Sorted(maps.Keys(remoteRefs)) {
+		if _, ok := local[name]; ok || !repo.IsMirrorRef(name) || strings.TrimSpace(string(remoteRefs[name])) == "" {
+			continue
+		}
+		refs = append(refs, pushRef{display: mirrorRefDisplay(name), remoteRef: name, force: true})
 	}
-	localHash, err := r.ResolveRef(localRef)
+	return refs, nil
+}
+
+func mirrorRefDisplay(name string) string {
+	if branch, ok := strings.CutPrefix(name, "heads/"); ok {
+		return "branch " + branch
+	}
+	if tag, ok := strings.CutPrefix(name, "tags/"); ok {
+		return "tag " + tag
+	}
+	return "ref " + name
+}
+
+// resolvePushRefs resolves the ref arguments of push, defaulting to the
+// current branch. Naming the same remote ref twice is only allowed when both
+// arguments push the same local ref.
+func resolvePushRefs(r *repo.Repo, remoteName string, args []string) ([]pushRef, error) {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	refs := make([]pushRef, 0, len(args))
+	seen := make(map[string]int, len(args))
+	for _, arg := range args {
+		display, localRef, remoteRef, force, err := resolvePushRefspec(r, remoteName, arg)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := seen[remoteRef]; ok {
+			if refs[i].localRef != localRef {
+				return nil, fmt.Errorf("push: %s and %s both update %s", refs[i].localRef, localRef, remoteRef)
+			}
+			refs[i].force = refs[i].force || force
+			continue
+		}
+		local, err := r.ResolveRef(localRef)
+		if err != nil {
+			return nil, fmt.Errorf("resolve local ref %q: %w", localRef, err)
+		}
+		seen[remoteRef] = len(refs)
+		refs = append(refs, pushRef{display: display, localRef: localRef, remoteRef: remoteRef, force: force, local: local})
+	}
+	return refs, nil
+}
+
+// followTagRefs returns the local annotated tags that point at a commit
+// reachable from one of refs and that the remote does not have yet.
+func followTagRefs(r *repo.Repo, remoteName string, refs []pushRef, remoteRefs map[string]object.Hash) ([]pushRef, error) {
+	tags, err := r.ListTagsWithHashes()
 	if err != nil {
-		return fmt.Errorf("resolve local ref %q: %w", localRef, err)
+		return nil, err
+	}
+	pushing := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		pushing[ref.remoteRef] = true
+	}
+
+	var out []pushRef
+	for _, name := range slices.Sorted(maps.Keys(tags)) {
+		target, ok := peelAnnotatedTag(r.Store, tags[name])
+		if !ok {
+			continue
+		}
+		display, localRef, remoteRef, _, err := resolvePushRefspec(r, remoteName, "refs/tags/"+name)
+		if err != nil {
+			return nil, err
+		}
+		if pushing[remoteRef] || strings.TrimSpace(string(remoteRefs[remoteRef])) != "" {
+			continue
+		}
+		for _, ref := range refs {
+			base, err := r.FindMergeBase(target, ref.local)
+			if err != nil || base != target {
+				continue
+			}
+			pushing[remoteRef] = true
+			out = append(out, pushRef{display: display, localRef: localRef, remoteRef: remoteRef, local: tags[name]})
+			break
+		}
+	}
+	return out, nil
+}
+
+// peelAnnotatedTag follows the tag object h to the commit it points at. It
+// reports false for lightweight tags and tags of anything but a commit.
+func peelAnnotatedTag(store *object.Store, h object.Hash) (object.Hash, bool) {
+	annotated := false
+	for range 16 {
+		typ, _, err := store.Read(h)
+		if err != nil {
+			return "", false
+		}
+		if typ != object.TypeTag {
+			return h, annotated && typ == object.TypeCommit
+		}
+		tag, err := store.ReadTag(h)
+		if err != nil {
+			return "", false
+		}
+		h = tag.TargetHash
+		annotated = true
+	}
+	return "", false
+}
+
+// pushRefsGot pushes the refs named by refArgs, or every ref for a mirror
+// push, to a Graft remote, updating them in a single all-or-nothing ref
+// update. progress may be nil.
+func pushRefsGot(cmd *cobra.Command, r *repo.Repo, remoteName, remoteURL string, refArgs []string, opts pushOptions, progress *progressMeter) error {
+	var refs []pushRef
+	var err error
+	// In JSON mode the text summary is dropped and result written instead.
+	out := cmd.OutOrStdout()
+	if opts.json {
+		out = io.Discard
+	}
+	result := JSONPushOutput{Remote: remoteName, Refs: []JSONPushRef{}}
+	if !opts.mirror {
+		if refs, err = resolvePushRefs(r, remoteName, refArgs); err != nil {
+			return err
+		}
 	}
 
 	client, err := remote.NewClient(remoteURL)
 	if err != nil {
 		return err
 	}
+	defer progress.Done()
+	client.SetProgress(progress.Func())
 	remoteRefs, err := client.ListRefs(cmd.Context())
 	if err != nil {
 		return err
 	}
-
-	remoteHash, hasRemote := remoteRefs[remoteRef]
-	if hasRemote && strings.TrimSpace(string(remoteHash)) == "" {
-		hasRemote = false
+	if opts.mirror {
+		if refs, err = mirrorPushRefs(r, remoteRefs); err != nil {
+			return err
+		}
+	}
+	// LFS objects are pushed for the refs named on the command line, or the
+	// branches of a mirror; tags added by --follow-tags point into their
+	// history.
+	lfsRoots := make([]object.Hash, 0, len(refs))
+	for _, ref := range refs {
+		if ref.local != "" && (!opts.mirror || strings.HasPrefix(ref.remoteRef, "heads/")) {
+			lfsRoots = append(lfsRoots, ref.local)
+		}
+	}
+	if opts.followTags && !opts.mirror {
+		tagRefs, err := followTagRefs(r, remoteName, refs, remoteRefs)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, tagRefs...)
+	}
+	for i := range refs {
+		refs[i].remote = object.Hash(strings.TrimSpace(string(remoteRefs[refs[i].remoteRef])))
 	}
 
 	// Load hooks config and run pre-push hooks.
 	hooksCfg, _ := repo.LoadHooksConfig(r.RootDir, nil)
 	prePushHooks := hooksCfg.ForPoint("pre-push")
 	if len(prePushHooks) > 0 {
+		hookRefs := make([]repo.HookRefUpdate, len(refs))
+		for i, ref := range refs {
+			hookRefs[i] = repo.HookRefUpdate{LocalRef: ref.localRef, RemoteRef: ref.remoteRef, LocalHash: string(ref.local), RemoteHash: string(ref.remote)}
+		}
 		payload, _ := json.Marshal(repo.PrePushPayload{
 			Hook:      "pre-push",
 			Repo:      r.RootDir,
 			Remote:    remoteName,
 			RemoteURL: remoteURL,
-			Refs: []repo.HookRefUpdate{
-				{LocalRef: localRef, RemoteRef: remoteRef, LocalHash: string(localHash), RemoteHash: string(remoteHash)},
-			},
+			Refs:      hookRefs,
 		})
 		if err := repo.RunHooksForPoint(cmd.Context(), r.RootDir, prePushHooks, payload, true); err != nil {
 			return err
 		}
 	}
 
-	if hasRemote && remoteHash == localHash {
-		_ = r.UpdateRef(remoteTrackingRefName(remoteName, remoteRef), remoteHash)
-		fmt.Fprintf(cmd.OutOrStdout(), "everything up-to-date (%s)\n", shortHash(localHash))
-		return nil
-	}
-
-	if hasRemote && !force {
-		if strings.HasPrefix(remoteRef, "heads/") {
-			if !r.Store.Has(remoteHash) {
-				haves, err := localRefTips(r)
-				if err != nil {
-					return err
-				}
-				if _, err := remote.FetchIntoStore(cmd.Context(), client, r.Store, []object.Hash{remoteHash}, haves); err != nil {
-					return fmt.Errorf("push safety check failed fetching remote head: %w", err)
-				}
-			}
-			base, err := r.FindMergeBase(localHash, remoteHash)
-			if err != nil {
-				return fmt.Errorf("push safety check failed: %w", err)
-			}
-			if base != remoteHash {
-				return fmt.Errorf("push rejected: non-fast-forward (local %s does not contain remote %s)", shortHash(localHash), shortHash(remoteHash))
+	// Every ref is checked before anything is uploaded, so one rejected ref
+	// fails the whole push.
+	pending := make([]pushRef, 0, len(refs))
+	for _, ref := range refs {
+		if ref.remote == ref.local {
+			_ = updatePushTrackingRef(r, remoteName, ref.remoteRef, ref.remote)
+			result.Refs = append(result.Refs, JSONPushRef{Ref: ref.display, RemoteRef: ref.remoteRef, Status: "up-to-date", Old: string(ref.remote), New: string(ref.local)})
+			continue
+		}
+		if ref.remote != "" && !opts.force && !ref.force {
+			if err := checkFastForward(cmd.Context(), client, r, ref); err != nil {
+				return err
 			}
-		} else if remoteHash != localHash {
-			return fmt.Errorf("push rejected: remote %s already exists at %s (use --force to overwrite)", remoteRef, shortHash(remoteHash))
 		}
+		pending = append(pending, ref)
+	}
+	if len(pending) == 0 {
+		if opts.json {
+			return writeJSON(cmd.OutOrStdout(), result)
+		}
+		if len(refs) == 1 {
+			fmt.Fprintf(out, "everything up-to-date (%s)\n", shortHash(refs[0].local))
+		} else {
+			fmt.Fprintln(out, "everything up-to-date")
+		}
+		return nil
 	}
 
 	stopRoots := make([]object.Hash, 0, len(remoteRefs))
@@ -157,42 +393,101 @@ func pushBranchGot(cmd *cobra.Command, r *repo.Repo, remoteName, remoteURL, bran
 		}
 	}
 
-	objectsToPush, err := remote.CollectObjectsForPush(r.Store, []object.Hash{localHash}, stopRoots)
+	roots := make([]object.Hash, 0, len(pending))
+	remoteNames := make([]string, 0, len(pending))
+	for _, ref := range pending {
+		if ref.local != "" {
+			roots = append(roots, ref.local)
+		}
+		remoteNames = append(remoteNames, ref.remoteRef)
+	}
+	// A push that only deletes refs has no objects to send.
+	var objectsToPush []remote.ObjectRecord
+	if len(roots) > 0 {
+		objectsToPush, err = remote.CollectObjectsForPushWithProgress(r.Store, roots, stopRoots, progress.Func())
+		if err != nil {
+			return err
+		}
+	}
+	var bases map[object.Hash]remote.ThinPackBase
+	if len(objectsToPush) > 0 && opts.thin && shouldUseThinPush(client) {
+		haves := stopRoots
+		for _, ref := range pending {
+			if ref.remote != "" && r.Store.Has(ref.remote) {
+				haves = append([]object.Hash{ref.remote}, haves...)
+			}
+		}
+		bases, err = remote.FindThinPackBases(r.Store, objectsToPush, haves)
+		if err != nil {
+			return err
+		}
+	}
+	// Objects accepted by the remote during an earlier, interrupted push of
+	// these refs are skipped.
+	checkpoint, err := remote.OpenTransferCheckpoint(r.GraftDir, remote.CheckpointPush, remoteURL+" "+strings.Join(remoteNames, " "))
 	if err != nil {
 		return err
 	}
-	uploaded, err := pushObjectsChunked(cmd.Context(), client, objectsToPush)
+	if checkpoint.Len() > 0 {
+		progress.Done()
+		fmt.Fprintf(out, "resuming interrupted push (%d objects already sent)\n", checkpoint.Len())
+	}
+	uploaded, err := pushObjectsChunked(cmd.Context(), client, objectsToPush, bases, checkpoint)
+	progress.Done()
 	if err != nil {
 		return err
 	}
 
-	old := object.Hash("")
-	if hasRemote {
-		old = remoteHash
+	updates := make([]remote.RefUpdate, len(pending))
+	for i, ref := range pending {
+		old, newHash := ref.remote, ref.local
+		updates[i] = remote.RefUpdate{Name: ref.remoteRef, Old: &old, New: &newHash}
 	}
-	newHash := localHash
-	updated, err := client.UpdateRefs(cmd.Context(), []remote.RefUpdate{{
-		Name: remoteRef,
-		Old:  &old,
-		New:  &newHash,
-	}})
+	updated, err := client.UpdateRefs(cmd.Context(), updates)
 	if err != nil {
-		return err
+		return reportRefRejections(cmd, pending, err)
+	}
+	if err := checkpoint.Remove(); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
 	}
 
-	finalHash := localHash
-	if h, ok := updated[remoteRef]; ok && strings.TrimSpace(string(h)) != "" {
-		finalHash = h
+	objectsNote := ""
+	if len(pending) == 1 {
+		objectsNote = fmt.Sprintf(" (%d objects)", uploaded)
 	}
-	if err := r.UpdateRef(remoteTrackingRefName(remoteName, remoteRef), finalHash); err != nil {
-		return err
+	postRefs := make([]repo.HookRefUpdate, 0, len(pending))
+	for _, ref := range pending {
+		if ref.local == "" {
+			if err := deletePushTrackingRef(r, remoteName, ref.remoteRef); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "deleted %s (was %s)\n", ref.display, shortHash(ref.remote))
+			result.Refs = append(result.Refs, JSONPushRef{Ref: ref.display, RemoteRef: ref.remoteRef, Status: "deleted", Old: string(ref.remote)})
+			postRefs = append(postRefs, repo.HookRefUpdate{Name: ref.remoteRef, Old: string(ref.remote)})
+			continue
+		}
+		finalHash := ref.local
+		if h, ok := updated[ref.remoteRef]; ok && strings.TrimSpace(string(h)) != "" {
+			finalHash = h
+		}
+		if err := updatePushTrackingRef(r, remoteName, ref.remoteRef, finalHash); err != nil {
+			return err
+		}
+		status := "updated"
+		if ref.remote != "" {
+			fmt.Fprintf(out, "pushed %s: %s -> %s%s\n", ref.display, shortHash(ref.remote), shortHash(finalHash), objectsNote)
+		} else {
+			status = "created"
+			fmt.Fprintf(out, "pushed new %s at %s%s\n", ref.display, shortHash(finalHash), objectsNote)
+		}
+		result.Refs = append(result.Refs, JSONPushRef{Ref: ref.display, RemoteRef: ref.remoteRef, Status: status, Old: string(ref.remote), New: string(finalHash)})
+		postRefs = append(postRefs, repo.HookRefUpdate{Name: ref.remoteRef, Old: string(ref.remote), New: string(finalHash)})
 	}
-
-	if hasRemote {
-		fmt.Fprintf(cmd.OutOrStdout(), "pushed %s: %s -> %s (%d objects)\n", pushTarget, shortHash(remoteHash), shortHash(finalHash), uploaded)
-	} else {
-		fmt.Fprintf(cmd.OutOrStdout(), "pushed new %s at %s (%d objects)\n", pushTarget, shortHash(finalHash), uploaded)
+	if len(pending) > 1 {
+		fmt.Fprintf(out, "uploaded %d objects\n", uploaded)
 	}
+	result.ObjectsUploaded = uploaded
+	printTransferStats(cmd.ErrOrStderr(), opts.stats, client.Stats())
 
 	// Run post-push hooks (non-blocking: errors are warnings only).
 	postPushHooks := hooksCfg.ForPoint("post-push")
@@ -201,21 +496,52 @@ func pushBranchGot(cmd *cobra.Command, r *repo.Repo, remoteName, remoteURL, bran
 			Hook:          "post-push",
 			Remote:        remoteName,
 			RemoteURL:     remoteURL,
-			Refs:          []repo.HookRefUpdate{{Name: remoteRef, Old: string(remoteHash), New: string(finalHash)}},
+			Refs:          postRefs,
 			ObjectsPushed: uploaded,
 		})
 		_ = repo.RunHooksForPoint(cmd.Context(), r.RootDir, postPushHooks, payload, false)
 	}
 
-	// Push LFS objects referenced by the commit.
+	// Push LFS objects referenced by the pushed commits.
 	lfsClient := remote.NewLFSClient(client)
-	lfsCount, err := r.PushLFSObjects(cmd.Context(), lfsClient, localHash)
-	if err != nil {
-		fmt.Fprintf(cmd.ErrOrStderr(), "warning: LFS push failed: %v\n", err)
-	} else if lfsCount > 0 {
-		fmt.Fprintf(cmd.OutOrStdout(), "pushed %d LFS objects\n", lfsCount)
+	for _, h := range lfsRoots {
+		lfsCount, err := r.PushLFSObjects(cmd.Context(), lfsClient, h)
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: LFS push failed: %v\n", err)
+		} else if lfsCount > 0 {
+			fmt.Fprintf(out, "pushed %d LFS objects\n", lfsCount)
+			result.LFSObjects += lfsCount
+		}
 	}
 
+	if opts.json {
+		return writeJSON(cmd.OutOrStdout(), result)
+	}
+	return nil
+}
+
+// checkFastForward rejects updating ref on the remote unless that keeps the
+// remote's history: branches must fast-forward and tags may not move.
+func checkFastForward(ctx context.Context, client *remote.Client, r *repo.Repo, ref pushRef) error {
+	if !strings.HasPrefix(ref.remoteRef, "heads/") {
+		return fmt.Errorf("push rejected: remote %s already exists at %s (use --force to overwrite)", ref.remoteRef, shortHash(ref.remote))
+	}
+	if !r.Store.Has(ref.remote) {
+		haves, err := localRefTips(r)
+		if err != nil {
+			return err
+		}
+		if _, err := remote.FetchIntoStore(ctx, client, r.Store, []object.Hash{ref.remote}, haves); err != nil {
+			return fmt.Errorf("push safety check failed fetching remote head: %w", err)
+		}
+	}
+	base, err := r.FindMergeBase(ref.local, ref.remote)
+	if err != nil {
+		return fmt.Errorf("push safety check failed: %w", err)
+	}
+	if base != ref.remote {
+		return fmt.Errorf("push rejected: %w (local %s does not contain remote %s)", remote.ErrNonFastForward, shortHash(ref.local), shortHash(ref.remote))
+	}
 	return nil
 }
 
@@ -257,7 +583,126 @@ func resolvePushRefNames(r *repo.Repo, branchArg string) (display string, localR
 	return "branch " + branchArg, "refs/heads/" + branchArg, "heads/" + branchArg, nil
 }
 
-func pushObjectsChunked(ctx context.Context, client *remote.Client, objects []remote.ObjectRecord) (int, error) {
+// resolvePushRefspec resolves the ref argument of push for remoteName. An
+// argument of the form "[+]<src>:<dst>" pushes src to a differently named
+// remote ref; a short dst stays in src's namespace. Otherwise the ref
+// resolves as in resolvePushRefNames and the first push refspec configured
+// for the remote that matches it may redirect it. force reports a "+"
+// refspec.
+func resolvePushRefspec(r *repo.Repo, remoteName, arg string) (display, localRef, remoteRef string, force bool, err error) {
+	arg = strings.TrimSpace(arg)
+	if src, dst, ok := strings.Cut(arg, ":"); ok {
+		force = strings.HasPrefix(src, "+")
+		src = strings.TrimPrefix(src, "+")
+		if strings.Contains(arg, "*") {
+			return "", "", "", false, fmt.Errorf("push refspec %q: wildcards are not supported", arg)
+		}
+		display, localRef, remoteRef, err = resolvePushRefNames(r, src)
+		if err != nil {
+			return "", "", "", false, err
+		}
+		dst = strings.TrimSpace(dst)
+		if !strings.HasPrefix(dst, "refs/") && !strings.HasPrefix(dst, "heads/") && !strings.HasPrefix(dst, "tags/") {
+			namespace, _, _ := strings.Cut(remoteRef, "/")
+			dst = namespace + "/" + dst
+		}
+		spec, err := repo.ParseRefspec(src + ":" + dst)
+		if err != nil {
+			return "", "", "", false, fmt.Errorf("push: %w", err)
+		}
+		remoteRef = strings.TrimPrefix(spec.Dst, "refs/")
+	} else {
+		display, localRef, remoteRef, err = resolvePushRefNames(r, arg)
+		if err != nil {
+			return "", "", "", false, err
+		}
+		specs, err := r.PushRefspecs(remoteName)
+		if err != nil {
+			return "", "", "", false, err
+		}
+		for _, spec := range specs {
+			if dst, ok := spec.Map(localRef); ok {
+				remoteRef = strings.TrimPrefix(dst, "refs/")
+				force = spec.Force
+				break
+			}
+		}
+	}
+	if !strings.HasPrefix(remoteRef, "heads/") && !strings.HasPrefix(remoteRef, "tags/") {
+		return "", "", "", false, fmt.Errorf("push: unsupported remote ref %q (only refs/heads/* and refs/tags/* are supported)", "refs/"+remoteRef)
+	}
+	if "refs/"+remoteRef != localRef {
+		display = fmt.Sprintf("%s -> %s", display, remoteRef)
+	}
+	return display, localRef, remoteRef, force, nil
+}
+
+// updatePushTrackingRef records h as the pushed value of remoteRef in the
+// tracking ref the remote's fetch refspecs assign to it, if any.
+func updatePushTrackingRef(r *repo.Repo, remoteName, remoteRef string, h object.Hash) error {
+	trackingRef, ok, err := r.TrackingRefFor(remoteName, remoteRef)
+	if err != nil || !ok {
+		return err
+	}
+	return r.UpdateRef(trackingRef, h)
+}
+
+// reportRefRejections prints the remote's reason for each ref it refused and
+// returns a summary error. Errors without per-ref reasons are returned as
+// they are.
+func reportRefRejections(cmd *cobra.Command, pending []pushRef, err error) error {
+	rejected := remote.RefRejections(err)
+	if len(rejected) == 0 {
+		return err
+	}
+	displays := make(map[string]string, len(pending))
+	for _, ref := range pending {
+		displays[ref.remoteRef] = ref.display
+	}
+	for _, rej := range rejected {
+		display, ok := displays[rej.Name]
+		if !ok {
+			display = "ref " + rej.Name
+		}
+		reason := rej.Reason
+		if reason == "" {
+			reason = "rejected"
+		}
+		if rej.Code != "" {
+			reason += " [" + rej.Code + "]"
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), " ! %s: %s\n", display, reason)
+	}
+	return fmt.Errorf("push rejected by remote: %d of %d refs refused, no refs were updated", len(rejected), len(pending))
+}
+
+// deletePushTrackingRef removes the tracking ref of a remote ref deleted by
+// a push, if it exists.
+func deletePushTrackingRef(r *repo.Repo, remoteName, remoteRef string) error {
+	trackingRef, ok, err := r.TrackingRefFor(remoteName, remoteRef)
+	if err != nil || !ok {
+		return err
+	}
+	current, err := r.ResolveRef(trackingRef)
+	if err != nil {
+		return nil
+	}
+	return r.DeleteRefCAS(trackingRef, current)
+}
+
+// pushObjectsChunked uploads objects in size-bounded chunks. When checkpoint
+// is non-nil, objects it already records are skipped and each accepted chunk
+// is recorded so an interrupted push can resume.
+func pushObjectsChunked(ctx context.Context, client *remote.Client, objects []remote.ObjectRecord, bases map[object.Hash]remote.ThinPackBase, checkpoint *remote.TransferCheckpoint) (int, error) {
+	if checkpoint.Len() > 0 {
+		pending := make([]remote.ObjectRecord, 0, len(objects))
+		for _, obj := range objects {
+			if !checkpoint.Has(obj.Hash) {
+				pending = append(pending, obj)
+			}
+		}
+		objects = pending
+	}
 	if len(objects) == 0 {
 		return 0, nil
 	}
@@ -272,12 +717,15 @@ func pushObjectsChunked(ctx context.Context, client *remote.Client, objects []re
 			return nil
 		}
 		if usePack {
-			if err := client.PushObjectsPack(ctx, chunk); err != nil {
+			if err := client.PushObjectsThinPack(ctx, chunk, bases); err != nil {
 				if !remote.IsPackUploadUnsupported(err) {
 					return err
 				}
 				usePack = false
 			} else {
+				if err := recordPushed(checkpoint, chunk); err != nil {
+					return err
+				}
 				uploaded += len(chunk)
 				chunk = chunk[:0]
 				chunkBytes = 0
@@ -287,6 +735,9 @@ func pushObjectsChunked(ctx context.Context, client *remote.Client, objects []re
 		if err := client.PushObjects(ctx, chunk); err != nil {
 			return err
 		}
+		if err := recordPushed(checkpoint, chunk); err != nil {
+			return err
+		}
 		uploaded += len(chunk)
 		chunk = chunk[:0]
 		chunkBytes = 0
@@ -312,13 +763,25 @@ func pushObjectsChunked(ctx context.Context, client *remote.Client, objects []re
 	return uploaded, nil
 }
 
+func recordPushed(checkpoint *remote.TransferCheckpoint, chunk []remote.ObjectRecord) error {
+	if checkpoint == nil {
+		return nil
+	}
+	hashes := make([]object.Hash, len(chunk))
+	for i, obj := range chunk {
+		hashes[i] = obj.Hash
+	}
+	return checkpoint.Record(hashes...)
+}
+
 func shouldUsePackPush(client *remote.Client) bool {
 	if client == nil {
 		return false
 	}
-	caps := client.ServerCapabilities()
-	if caps == nil {
-		return true
-	}
-	return caps.Has(remote.CapPack) && caps.Has(remote.CapZstd)
+	features := client.Features()
+	return features.Pack && features.Zstd
+}
+
+func shouldUseThinPush(client *remote.Client) bool {
+	return shouldUsePackPush(client) && client.Features().ThinPack
 }
@@ -3,10 +3,12 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -122,7 +124,7 @@ func TestPushObjectsChunkedPrefersPackTransport(t *testing.T) {
 	blobData := object.MarshalBlob(&object.Blob{Data: []byte("hello\n")})
 	uploaded, err := pushObjectsChunked(context.Background(), client, []remote.ObjectRecord{
 		{Hash: object.HashObject(object.TypeBlob, blobData), Type: object.TypeBlob, Data: blobData},
-	})
+	}, nil, nil)
 	if err != nil {
 		t.Fatalf("pushObjectsChunked: %v", err)
 	}
@@ -169,7 +171,7 @@ func TestPushObjectsChunkedFallsBackWhenPackUnsupported(t *testing.T) {
 	blobData := object.MarshalBlob(&object.Blob{Data: []byte("hello\n")})
 	uploaded, err := pushObjectsChunked(context.Background(), client, []remote.ObjectRecord{
 		{Hash: object.HashObject(object.TypeBlob, blobData), Type: object.TypeBlob, Data: blobData},
-	})
+	}, nil, nil)
 	if err != nil {
 		t.Fatalf("pushObjectsChunked: %v", err)
 	}
@@ -236,3 +238,152 @@ func TestPushCmdCheckRejectsOversizedObject(t *testing.T) {
 		t.Fatalf("error = %q, want formatted object limit", err.Error())
 	}
 }
+
+func TestPushAndFetchCmdPlainGitRemoteWithoutGitDir(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	head, err := r.Commit("initial", "tester <tester@example.com>")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	if out, err := exec.Command("git", "init", "--bare", "-q", "-b", "main", remoteDir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	if err := r.SetRemote("origin", "file://"+remoteDir); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	var out bytes.Buffer
+	push := newPushCmd()
+	push.SilenceUsage = true
+	push.SetOut(&out)
+	push.SetErr(io.Discard)
+	push.SetArgs([]string{"origin", "main"})
+	if err := push.Execute(); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if !strings.Contains(out.String(), "pushed branch main") {
+		t.Fatalf("push output = %q", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); !os.IsNotExist(err) {
+		t.Fatalf("push created a .git directory: %v", err)
+	}
+	msg, err := exec.Command("git", "--git-dir", remoteDir, "log", "-1", "--format=%s", "main").Output()
+	if err != nil || strings.TrimSpace(string(msg)) != "initial" {
+		t.Fatalf("remote log = %q, %v", msg, err)
+	}
+
+	out.Reset()
+	fetch := newFetchCmd()
+	fetch.SilenceUsage = true
+	fetch.SetOut(&out)
+	fetch.SetErr(io.Discard)
+	fetch.SetArgs([]string{"origin"})
+	if err := fetch.Execute(); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if !strings.Contains(out.String(), "already up to date") {
+		t.Fatalf("fetch output = %q", out.String())
+	}
+	if tracking, err := r.ResolveRef("refs/remotes/origin/heads/main"); err != nil || tracking != head {
+		t.Fatalf("tracking ref = %s, %v; want %s", tracking, err, head)
+	}
+}
+
+func TestPushObjectsChunkedResumesFromCheckpoint(t *testing.T) {
+	var requests int
+	failSecond := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		requests++
+		if failSecond && requests == 2 {
+			http.Error(w, "connection dropped", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"received":1}`))
+	}))
+	defer ts.Close()
+
+	client, err := remote.NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	objects := make([]remote.ObjectRecord, 0, pushChunkObjectLimit+1)
+	for i := 0; i <= pushChunkObjectLimit; i++ {
+		data := object.MarshalBlob(&object.Blob{Data: []byte(strings.Repeat("x", i))})
+		objects = append(objects, remote.ObjectRecord{Hash: object.HashObject(object.TypeBlob, data), Type: object.TypeBlob, Data: data})
+	}
+
+	graftDir := t.TempDir()
+	checkpoint, err := remote.OpenTransferCheckpoint(graftDir, remote.CheckpointPush, ts.URL+" heads/main")
+	if err != nil {
+		t.Fatalf("OpenTransferCheckpoint: %v", err)
+	}
+	if _, err := pushObjectsChunked(context.Background(), client, objects, nil, checkpoint); err == nil {
+		t.Fatal("expected the interrupted push to fail")
+	}
+
+	failSecond = false
+	resumed, err := remote.OpenTransferCheckpoint(graftDir, remote.CheckpointPush, ts.URL+" heads/main")
+	if err != nil {
+		t.Fatalf("OpenTransferCheckpoint: %v", err)
+	}
+	if resumed.Len() != pushChunkObjectLimit {
+		t.Fatalf("checkpoint has %d objects, want %d", resumed.Len(), pushChunkObjectLimit)
+	}
+	uploaded, err := pushObjectsChunked(context.Background(), client, objects, nil, resumed)
+	if err != nil {
+		t.Fatalf("resumed push: %v", err)
+	}
+	if uploaded != 1 {
+		t.Fatalf("resumed push uploaded %d objects, want 1", uploaded)
+	}
+}
+
+func TestReportRefRejectionsPrintsReasonPerRef(t *testing.T) {
+	cmd := newPushCmd()
+	var stderr bytes.Buffer
+	cmd.SetErr(&stderr)
+
+	pending := []pushRef{
+		{display: "branch main", remoteRef: "heads/main"},
+		{display: "tag v1", remoteRef: "tags/v1"},
+	}
+	rejection := &remote.RemoteError{
+		Code:    "ref_update_rejected",
+		Message: "ref update rejected",
+		Rejected: []remote.RefRejection{
+			{Name: "heads/main", Code: "hook_declined", Reason: "pre-receive: commits must be signed"},
+		},
+	}
+	err := reportRefRejections(cmd, pending, rejection)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 refs refused") {
+		t.Fatalf("reportRefRejections error = %v", err)
+	}
+	if got, want := stderr.String(), " ! branch main: pre-receive: commits must be signed [hook_declined]\n"; got != want {
+		t.Fatalf("stderr = %q, want %q", got, want)
+	}
+
+	plain := errors.New("connection reset")
+	if err := reportRefRejections(cmd, pending, plain); err != plain {
+		t.Fatalf("reportRefRejections(plain) = %v, want the error unchanged", err)
+	}
+}
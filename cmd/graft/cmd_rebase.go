@@ -18,7 +18,6 @@ func newRebaseCmd() *cobra.Command {
 	var skipFlag bool
 	var interactiveFlag bool
 	var autosquashFlag bool
-	var autostashFlag bool
 
 	cmd := &cobra.Command{
 		Use:   "rebase [<upstream>]",
@@ -27,9 +26,12 @@ func newRebaseCmd() *cobra.Command {
 
 Use -i/--interactive to edit the list of commits before replaying.
 Use --autosquash with -i to auto-reorder fixup!/squash! commits.
-Use --autostash to automatically stash and restore uncommitted changes.
-Use --continue after resolving conflicts, --abort to cancel, or --skip to skip a commit.`,
-		Args: cobra.ArbitraryArgs,
+Use --autostash to automatically stash and restore uncommitted changes
+(the default when core.autostash is set; --no-autostash overrides it).
+Use --continue after resolving conflicts, --abort to cancel, or --skip to skip a commit.
+A rebase that stops on conflicts exits with status 2.`,
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completeRefs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Validate mutual exclusivity of control flags.
 			flagCount := 0
@@ -85,7 +87,7 @@ Use --continue after resolving conflicts, --abort to cancel, or --skip to skip a
 			upstream := args[0]
 
 			opts := repo.RebaseOptions{
-				Autostash: autostashFlag,
+				Autostash: autostashEnabled(cmd, r),
 			}
 
 			if interactiveFlag {
@@ -107,7 +109,7 @@ Use --continue after resolving conflicts, --abort to cancel, or --skip to skip a
 	cmd.Flags().BoolVar(&skipFlag, "skip", false, "skip the conflicting commit")
 	cmd.Flags().BoolVarP(&interactiveFlag, "interactive", "i", false, "interactive rebase: edit the todo list before replaying")
 	cmd.Flags().BoolVar(&autosquashFlag, "autosquash", false, "auto-reorder fixup!/squash! commits (requires -i)")
-	cmd.Flags().BoolVar(&autostashFlag, "autostash", false, "automatically stash and restore uncommitted changes")
+	addAutostashFlags(cmd)
 
 	return cmd
 }
@@ -275,7 +277,7 @@ func handleRebaseResult(r *repo.Repo, out io.Writer, ontoHash object.Hash, err e
 				fmt.Fprintf(out, "CONFLICT in %s. Fix conflicts and run: graft rebase --continue\n", p)
 			}
 		}
-		return nil
+		return exitStatus(exitConflict)
 	}
 
 	var editErr *repo.ErrRebaseEditStop
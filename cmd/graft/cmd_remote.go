@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"sort"
+	"strings"
 
+	"github.com/odvcencio/graft/pkg/remote"
 	"github.com/odvcencio/graft/pkg/repo"
 	"github.com/spf13/cobra"
 )
@@ -56,9 +58,10 @@ func newRemoteCmd() *cobra.Command {
 	})
 
 	cmd.AddCommand(&cobra.Command{
-		Use:   "set-url <name> <url>",
-		Short: "Update a named remote URL",
-		Args:  cobra.ExactArgs(2),
+		Use:               "set-url <name> <url>",
+		ValidArgsFunction: completeRemotes(1),
+		Short:             "Update a named remote URL",
+		Args:              cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
@@ -76,5 +79,116 @@ func newRemoteCmd() *cobra.Command {
 		},
 	})
 
+	cmd.AddCommand(newRemoteCapabilitiesCmd())
+
+	return cmd
+}
+
+func newRemoteCapabilitiesCmd() *cobra.Command {
+	var jsonFlag bool
+	cmd := &cobra.Command{
+		Use:   "capabilities [remote]",
+		Short: "Show the protocol version and features negotiated with a remote",
+		Long: "Contact a Graft remote and show the protocol version it speaks, the capabilities it " +
+			"advertises, and the transport features selected for the session. Features the server " +
+			"does not support are listed as disabled; transfers fall back to the plainer transport.",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeRemotes(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			remoteArg := ""
+			if len(args) > 0 {
+				remoteArg = args[0]
+			}
+			remoteName, remoteURL, transport, err := resolveRemoteNameAndSpec(r, remoteArg)
+			if err != nil {
+				return err
+			}
+			if transport != remoteTransportGraft {
+				return fmt.Errorf("remote capabilities currently supports orchard/graft remotes only")
+			}
+			client, err := remote.NewClient(remoteURL)
+			if err != nil {
+				return err
+			}
+			features, err := client.Negotiate(cmd.Context())
+			if err != nil {
+				return err
+			}
+			var advertised []string
+			if caps := client.ServerCapabilities(); caps != nil && caps.Len() > 0 {
+				advertised = strings.Split(caps.String(), ",")
+			}
+			out := remoteCapabilitiesReport(remoteName, remoteURL, features, advertised)
+			if jsonFlag {
+				return writeJSON(cmd.OutOrStdout(), out)
+			}
+			w := cmd.OutOrStdout()
+			fmt.Fprintf(w, "remote:     %s (%s)\n", out.Remote, out.URL)
+			fmt.Fprintf(w, "protocol:   %s\n", out.Protocol)
+			switch {
+			case features.Local:
+				fmt.Fprintln(w, "advertised: (local repository)")
+			case !features.Advertised:
+				fmt.Fprintln(w, "advertised: (none; assuming pack,zstd)")
+			default:
+				fmt.Fprintf(w, "advertised: %s\n", strings.Join(out.Advertised, ","))
+			}
+			fmt.Fprintf(w, "enabled:    %s\n", strings.Join(out.Enabled, " "))
+			if len(out.Disabled) > 0 {
+				fmt.Fprintf(w, "disabled:   %s\n", strings.Join(out.Disabled, " "))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
 	return cmd
 }
+
+// JSONRemoteCapabilities is the output of "graft remote capabilities".
+type JSONRemoteCapabilities struct {
+	Remote     string   `json:"remote"`
+	URL        string   `json:"url"`
+	Protocol   string   `json:"protocol"`
+	Local      bool     `json:"local,omitempty"`
+	Advertised []string `json:"advertised"`
+	Enabled    []string `json:"enabled"`
+	Disabled   []string `json:"disabled"`
+}
+
+func remoteCapabilitiesReport(name, url string, f remote.Features, advertised []string) JSONRemoteCapabilities {
+	out := JSONRemoteCapabilities{
+		Remote:     name,
+		URL:        url,
+		Protocol:   f.Protocol,
+		Local:      f.Local,
+		Advertised: advertised,
+		Enabled:    []string{},
+		Disabled:   []string{},
+	}
+	if out.Advertised == nil {
+		out.Advertised = []string{}
+	}
+	for _, feature := range []struct {
+		name string
+		on   bool
+	}{
+		{remote.CapPack, f.Pack},
+		{remote.CapZstd, f.Zstd},
+		{remote.CapSideband, f.Sideband},
+		{remote.CapThinPack, f.ThinPack},
+		{remote.CapShallow, f.Shallow},
+		{remote.CapFilter, f.Filter},
+		{remote.CapIncludeTag, f.IncludeTag},
+	} {
+		if feature.on {
+			out.Enabled = append(out.Enabled, feature.name)
+		} else {
+			out.Disabled = append(out.Disabled, feature.name)
+		}
+	}
+	return out
+}
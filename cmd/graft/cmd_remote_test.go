@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+func TestRemoteCapabilitiesReportsNegotiatedFeatures(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GRAFT_TOKEN", "")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Graft-Protocol", "1")
+		w.Header().Set("Graft-Capabilities", "pack,zstd,shallow")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"refs":{}}`))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	if err := r.SetRemote("origin", ts.URL+"/graft/alice/repo"); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := newRemoteCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(append([]string{"capabilities"}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("remote capabilities %v: %v", args, err)
+		}
+		return out.String()
+	}
+
+	out := run()
+	for _, want := range []string{"protocol:   1\n", "advertised: pack,shallow,zstd\n", "enabled:    pack zstd shallow\n", "disabled:   sideband thin-pack filter include-tag\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	var report JSONRemoteCapabilities
+	if err := json.Unmarshal([]byte(run("--json", "origin")), &report); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if report.Remote != "origin" || report.Protocol != "1" || !slices.Equal(report.Enabled, []string{"pack", "zstd", "shallow"}) {
+		t.Fatalf("report = %+v", report)
+	}
+}
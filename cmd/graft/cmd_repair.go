@@ -22,6 +22,7 @@ func newRepairCmd() *cobra.Command {
 	}
 	cmd.AddCommand(newRepairReseedCmd())
 	cmd.AddCommand(newRepairResyncGitCmd())
+	cmd.AddCommand(newRepairObjectsCmd())
 	return cmd
 }
 
@@ -458,3 +459,86 @@ func shortHashString(hash string) string {
 	}
 	return hash[:8]
 }
+
+func newRepairObjectsCmd() *cobra.Command {
+	var remotes []string
+	var jsonFlag bool
+
+	cmd := &cobra.Command{
+		Use:   "objects",
+		Short: "Refetch corrupt objects from remotes and replace damaged store entries",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+
+			report, err := r.RepairObjects(cmd.Context(), remotes)
+			if err != nil {
+				return err
+			}
+
+			if jsonFlag {
+				out := JSONRepairObjectsOutput{
+					Corrupt:         report.Corrupt,
+					SalvagedPacks:   report.SalvagedPacks,
+					Salvaged:        report.Salvaged,
+					IntactPacks:     report.IntactPacks,
+					UnreadablePacks: report.UnreadablePacks,
+				}
+				for _, obj := range report.Refetched {
+					out.Refetched = append(out.Refetched, JSONRepairedObject{Hash: string(obj.Hash), Remote: obj.Remote})
+				}
+				for _, h := range report.Unrecovered {
+					out.Unrecovered = append(out.Unrecovered, string(h))
+				}
+				if err := writeJSON(cmd.OutOrStdout(), out); err != nil {
+					return err
+				}
+				return repairObjectsError(report)
+			}
+
+			out := cmd.OutOrStdout()
+			if report.Corrupt == 0 {
+				fmt.Fprintln(out, "ok: no corrupt objects found")
+				return nil
+			}
+			for _, name := range report.SalvagedPacks {
+				fmt.Fprintf(out, "salvaged pack %s\n", name)
+			}
+			for _, name := range report.IntactPacks {
+				fmt.Fprintf(out, "kept pack %s (damaged at the pack level; every object decodes)\n", name)
+			}
+			for _, obj := range report.Refetched {
+				fmt.Fprintf(out, "refetched %s from %s\n", shortHash(obj.Hash), obj.Remote)
+			}
+			for _, h := range report.Unrecovered {
+				fmt.Fprintf(out, "unrecovered %s\n", h)
+			}
+			for _, name := range report.UnreadablePacks {
+				fmt.Fprintf(out, "unreadable pack %s (index damaged)\n", name)
+			}
+			fmt.Fprintf(
+				out,
+				"found %d corrupt object(s): %d salvaged from packs, %d refetched, %d unrecovered\n",
+				report.Corrupt,
+				report.Salvaged,
+				len(report.Refetched),
+				len(report.Unrecovered),
+			)
+			return repairObjectsError(report)
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&remotes, "remote", nil, "remote to refetch from (repeatable; default: all configured remotes)")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
+	return cmd
+}
+
+func repairObjectsError(report *repo.RepairReport) error {
+	if len(report.Unrecovered) == 0 && len(report.UnreadablePacks) == 0 {
+		return nil
+	}
+	return fmt.Errorf("repair: %d object(s) and %d pack(s) could not be recovered", len(report.Unrecovered), len(report.UnreadablePacks))
+}
@@ -26,6 +26,12 @@ func newResetCmd() *cobra.Command {
    --soft:  Only move HEAD. Staging and working tree are unchanged.
    --mixed: Move HEAD and reset staging (default).
    --hard:  Move HEAD, reset staging, and restore working tree.`,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+			if soft || mixed || hard {
+				return completeRefs(1)(cmd, args, toComplete)
+			}
+			return completeTrackedPaths(cmd, args, toComplete)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
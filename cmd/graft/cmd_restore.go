@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newRestoreCmd() *cobra.Command {
+	var source, entitySelector string
+
+	cmd := &cobra.Command{
+		Use:   "restore [--source <rev>] --entity <path:Name | path::entity_key>",
+		Short: "Restore one entity of a working file from history",
+		Long: `Restore replaces a single entity (function, method, type, ...) in a
+working-tree file with its version from --source (HEAD by default). The
+rest of the file, including uncommitted edits elsewhere in it, is left
+alone. Nothing is staged.
+
+The entity is named as path:Name, with methods optionally qualified by
+their receiver type (main.go:Repo.Commit), or by its full identity key as
+path::entity_key. If the entity no longer exists in the working file it is
+appended to the end of it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(entitySelector) == "" {
+				return fmt.Errorf("restore: --entity is required")
+			}
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			sourceHash, err := r.ResolveTreeish(source)
+			if err != nil {
+				return fmt.Errorf("restore: %w", err)
+			}
+
+			result, err := r.RestoreEntity(entitySelector, sourceHash)
+			if err != nil {
+				return err
+			}
+			short := string(result.Source)
+			if len(short) > 8 {
+				short = short[:8]
+			}
+			verb := "restored"
+			if result.Added {
+				verb = "re-added"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s in %s from %s\n", verb, result.DisplayName, result.Path, short)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&source, "source", "s", "HEAD", "revision to restore the entity from")
+	cmd.Flags().StringVar(&entitySelector, "entity", "", "entity to restore, as <path:Name> or <path::entity_key>")
+
+	return cmd
+}
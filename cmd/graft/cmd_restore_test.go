@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegration_RestoreEntityFromSource(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "main.go", "package main\n\nfunc helper() int { return 1 }\n\nfunc target() int { return 1 }\n", "base")
+	commitFile(t, dir, "main.go", "package main\n\nfunc helper() int { return 2 }\n\nfunc target() int { return 2 }\n", "update")
+
+	out := mustRunGraft(t, dir, "restore", "--source", "HEAD~1", "--entity", "main.go:target")
+	if !strings.Contains(out, "restored func target in main.go") {
+		t.Fatalf("restore output = %q", out)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if want := "package main\n\nfunc helper() int { return 2 }\n\nfunc target() int { return 1 }\n"; string(data) != want {
+		t.Fatalf("main.go = %q, want %q", data, want)
+	}
+
+	if _, err := runGraft(t, dir, "restore", "--source", "HEAD~1"); err == nil {
+		t.Fatal("expected restore without --entity to fail")
+	}
+}
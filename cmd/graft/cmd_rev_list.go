@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newRevListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rev-list [--max-count <n>] [--topo-order] <revision>... [--not <revision>...]",
+		Short: "List commits reachable from revisions, one hash per line",
+		Long: `Rev-list prints the hashes of the commits reachable from the given
+revisions, newest first, excluding those reachable from negated revisions:
+
+  A..B     commits reachable from B but not from A ("A.." means A..HEAD)
+  A...B    commits reachable from either A or B but not both
+  ^A       exclude commits reachable from A
+  --not    negate every following revision, until the next --not
+
+Options:
+  -n, --max-count <n>  stop after n commits; also -n<n> and -<n>
+  --topo-order         list no parent before all of its children
+
+Revision arguments are order sensitive, so options and revisions are read
+exactly as given.`,
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var opts repo.RevListOptions
+			var revs []string
+			maxCount := -1
+			for i := 0; i < len(args); i++ {
+				arg := args[i]
+				switch {
+				case arg == "-h" || arg == "--help":
+					return cmd.Help()
+				case arg == "--topo-order":
+					opts.TopoOrder = true
+				case arg == "-n" || arg == "--max-count":
+					if i+1 >= len(args) {
+						return fmt.Errorf("rev-list: %s requires a value", arg)
+					}
+					i++
+					n, err := parseMaxCount(args[i])
+					if err != nil {
+						return err
+					}
+					maxCount = n
+				case strings.HasPrefix(arg, "--max-count="):
+					n, err := parseMaxCount(strings.TrimPrefix(arg, "--max-count="))
+					if err != nil {
+						return err
+					}
+					maxCount = n
+				case strings.HasPrefix(arg, "-n"):
+					n, err := parseMaxCount(strings.TrimPrefix(arg, "-n"))
+					if err != nil {
+						return err
+					}
+					maxCount = n
+				case isShortMaxCount(arg):
+					n, err := parseMaxCount(strings.TrimPrefix(arg, "-"))
+					if err != nil {
+						return err
+					}
+					maxCount = n
+				case arg == "--not":
+					revs = append(revs, arg)
+				case arg == "--":
+					return fmt.Errorf("rev-list: path limiting is not supported")
+				case strings.HasPrefix(arg, "-"):
+					return fmt.Errorf("rev-list: unknown option %q", arg)
+				default:
+					revs = append(revs, arg)
+				}
+			}
+			if len(revs) == 0 {
+				return fmt.Errorf("rev-list: no revisions given")
+			}
+			if maxCount == 0 {
+				return nil
+			}
+			if maxCount > 0 {
+				opts.MaxCount = maxCount
+			}
+
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			rr, err := r.ParseRevRange(revs)
+			if err != nil {
+				return err
+			}
+			hashes, err := r.RevList(rr, opts)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, h := range hashes {
+				fmt.Fprintln(out, h)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// parseMaxCount parses a --max-count value; negative values mean no limit.
+func parseMaxCount(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("rev-list: invalid --max-count %q", value)
+	}
+	return max(n, -1), nil
+}
+
+// isShortMaxCount reports whether arg is the "-<n>" form of --max-count.
+func isShortMaxCount(arg string) bool {
+	digits := strings.TrimPrefix(arg, "-")
+	if digits == arg || digits == "" {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRevListIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "a\n", "first")
+	commitFile(t, dir, "b.txt", "b\n", "second")
+	commitFile(t, dir, "c.txt", "c\n", "third")
+
+	all := nonEmptyLines(mustRunGraft(t, dir, "rev-list", "HEAD"))
+	if len(all) != 3 {
+		t.Fatalf("rev-list HEAD = %q, want 3 commits", all)
+	}
+	for _, limit := range [][]string{{"--max-count", "1"}, {"--max-count=1"}, {"-n", "1"}, {"-n1"}, {"-1"}} {
+		args := append(append([]string{"rev-list"}, limit...), "HEAD")
+		head := strings.TrimSpace(mustRunGraft(t, dir, args...))
+		if head != all[0] {
+			t.Fatalf("rev-list %v HEAD = %q, want %q", limit, head, all[0])
+		}
+	}
+	if got := nonEmptyLines(mustRunGraft(t, dir, "rev-list", "-2", "HEAD")); len(got) != 2 {
+		t.Fatalf("rev-list -2 HEAD = %q, want 2 commits", got)
+	}
+
+	for _, args := range [][]string{
+		{"HEAD~2..HEAD"},
+		{"HEAD", "--not", "HEAD~2"},
+		{"--topo-order", "^HEAD~2", "HEAD"},
+	} {
+		got := nonEmptyLines(mustRunGraft(t, dir, append([]string{"rev-list"}, args...)...))
+		if len(got) != 2 || got[0] != all[0] || got[1] != all[1] {
+			t.Fatalf("rev-list %v = %q, want %q", args, got, all[:2])
+		}
+	}
+
+	if out := mustRunGraft(t, dir, "rev-list", "-n", "0", "HEAD"); strings.TrimSpace(out) != "" {
+		t.Fatalf("rev-list -n 0 = %q, want no output", out)
+	}
+	if _, err := runGraft(t, dir, "rev-list", "--bogus", "HEAD"); err == nil {
+		t.Fatal("expected an error for an unknown option")
+	}
+}
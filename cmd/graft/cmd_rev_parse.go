@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newRevParseCmd() *cobra.Command {
+	var abbrevRef bool
+
+	cmd := &cobra.Command{
+		Use:   "rev-parse [--abbrev-ref] <revision>...",
+		Short: "Resolve revisions to object hashes",
+		Long: `Rev-parse prints the object hash each revision resolves to, one per line.
+It accepts the revision syntax every command understands:
+
+  HEAD~3, main^2       ancestors by first parent, or by parent number
+  @{-1}                the branch checked out before the current one
+  main@{upstream}      the tracking ref of a branch (@{u} for the current one)
+  main@{2}             the value main had two updates ago, from its reflog
+  HEAD:path/to/file    the blob or tree at a path in a commit
+  :path/to/file        the blob staged for a path
+
+With --abbrev-ref, the short ref name is printed instead of the hash.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, rev := range args {
+				if abbrevRef {
+					name, err := r.SymbolicName(rev)
+					if err != nil {
+						return fmt.Errorf("rev-parse: %w", err)
+					}
+					fmt.Fprintln(out, name)
+					continue
+				}
+				h, err := r.ResolveTreeish(rev)
+				if err != nil {
+					return fmt.Errorf("rev-parse: %w", err)
+				}
+				fmt.Fprintln(out, h)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&abbrevRef, "abbrev-ref", false, "print the short ref name instead of the hash")
+	return cmd
+}
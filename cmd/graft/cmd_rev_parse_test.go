@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRevParseIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "a\n", "first")
+	commitFile(t, dir, "a.txt", "a2\n", "second")
+	branch := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "--abbrev-ref", "HEAD"))
+
+	history := nonEmptyLines(mustRunGraft(t, dir, "rev-list", "HEAD"))
+	got := nonEmptyLines(mustRunGraft(t, dir, "rev-parse", "HEAD", "HEAD~1"))
+	if len(got) != 2 || got[0] != history[0] || got[1] != history[1] {
+		t.Fatalf("rev-parse HEAD HEAD~1 = %q, want %q", got, history)
+	}
+
+	mustRunGraft(t, dir, "branch", "feature")
+	mustRunGraft(t, dir, "switch", "feature")
+	if prev := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "--abbrev-ref", "@{-1}")); prev != branch {
+		t.Fatalf("rev-parse --abbrev-ref @{-1} = %q, want %q", prev, branch)
+	}
+	mustRunGraft(t, dir, "checkout", "@{-1}")
+	if cur := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "--abbrev-ref", "HEAD")); cur != branch {
+		t.Fatalf("after checkout @{-1}, HEAD is %q, want %q", cur, branch)
+	}
+
+	blob := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD:a.txt"))
+	if staged := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", ":a.txt")); blob == "" || staged != blob {
+		t.Fatalf("rev-parse :a.txt = %q, want HEAD:a.txt %q", staged, blob)
+	}
+	if old := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD~1:a.txt")); old == blob {
+		t.Fatal("HEAD~1:a.txt resolved to the same blob as HEAD:a.txt")
+	}
+
+	if _, err := runGraft(t, dir, "rev-parse", "@{upstream}"); err == nil {
+		t.Fatal("expected an error for a branch without an upstream")
+	}
+}
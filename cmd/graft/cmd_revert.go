@@ -11,9 +11,10 @@ func newRevertCmd() *cobra.Command {
 	var continueFlag, abortFlag bool
 
 	cmd := &cobra.Command{
-		Use:   "revert <commit>",
-		Short: "Revert a commit by creating an inverse commit",
-		Args:  cobra.RangeArgs(0, 1),
+		Use:               "revert <commit>",
+		Short:             "Revert a commit by creating an inverse commit",
+		Args:              cobra.RangeArgs(0, 1),
+		ValidArgsFunction: completeRefs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newRewriteHistoryCmd() *cobra.Command {
+	var removePaths, mapAuthors []string
+	var maxSize, authorMapFile string
+	var dryRun, jsonFlag bool
+
+	cmd := &cobra.Command{
+		Use:   "rewrite-history",
+		Short: "Rewrite every commit to remove paths, large files or old author identities",
+		Long: `Rewrite every commit reachable from HEAD, a branch or a tag, then move
+the branches and tags to the rewritten commits.
+
+--remove-path drops a file, or a directory with everything below it, from
+every commit. --strip-blobs-bigger-than drops every file larger than the
+given size (a byte count with an optional K, M or G suffix). A commit whose
+changes are all dropped is pruned.
+
+--map-author old=new replaces an author or committer identity; old is a
+full "Name <email>" identity or a bare "<email>". --author-map reads such
+pairs from a file, one per line, with "#" starting a comment.
+
+Rewritten commits get new hashes and lose their signatures, and annotated
+tags are rewritten to match. The current branch is moved like a mixed
+reset: removed files stay in the working tree, untracked. Without
+--dry-run the command refuses to run while changes are staged or a merge,
+rebase, cherry-pick, revert or am is in progress. The old to new
+commit map and the ref updates are written to .graft/rewrite-history/.
+With --dry-run the refs are left alone and nothing is written there.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := repo.RewriteHistoryOptions{RemovePaths: removePaths, DryRun: dryRun}
+			if maxSize != "" {
+				size, err := parseByteSize(maxSize)
+				if err != nil {
+					return fmt.Errorf("--strip-blobs-bigger-than: %w", err)
+				}
+				opts.MaxBlobSize = size
+			}
+			authorMap, err := loadAuthorMap(authorMapFile, mapAuthors)
+			if err != nil {
+				return err
+			}
+			opts.AuthorMap = authorMap
+			if len(opts.RemovePaths) == 0 && opts.MaxBlobSize == 0 && len(opts.AuthorMap) == 0 {
+				return fmt.Errorf("nothing to rewrite: give --remove-path, --strip-blobs-bigger-than, --map-author or --author-map")
+			}
+
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			res, err := r.RewriteHistory(opts)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if jsonFlag {
+				return writeJSON(out, rewriteHistoryJSON(res, dryRun))
+			}
+			printRewriteHistory(out, res, dryRun)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&removePaths, "remove-path", nil, "remove a file or directory from every commit (repeatable)")
+	cmd.Flags().StringVar(&maxSize, "strip-blobs-bigger-than", "", "remove files larger than this size, e.g. 10M")
+	cmd.Flags().StringArrayVar(&mapAuthors, "map-author", nil, "replace an identity, as old=new (repeatable)")
+	cmd.Flags().StringVar(&authorMapFile, "author-map", "", "read old=new identity pairs from a file")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would change without moving any ref")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output the commit map and ref updates as JSON")
+	return cmd
+}
+
+// parseByteSize parses a byte count with an optional K, M or G suffix,
+// each a power of 1024.
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	shift := 0
+	upper := strings.TrimSuffix(strings.ToUpper(s), "B")
+	if upper != "" {
+		switch upper[len(upper)-1] {
+		case 'K':
+			shift = 10
+		case 'M':
+			shift = 20
+		case 'G':
+			shift = 30
+		}
+		if shift > 0 {
+			upper = upper[:len(upper)-1]
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n << shift, nil
+}
+
+// loadAuthorMap merges the old=new pairs of file, when given, with those
+// of pairs; pairs given on the command line win.
+func loadAuthorMap(file string, pairs []string) (map[string]string, error) {
+	m := make(map[string]string)
+	add := func(line, source string) error {
+		old, repl, ok := strings.Cut(line, "=")
+		old, repl = strings.TrimSpace(old), strings.TrimSpace(repl)
+		if !ok || old == "" || repl == "" {
+			return fmt.Errorf("%s: want old=new, got %q", source, line)
+		}
+		m[old] = repl
+		return nil
+	}
+
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("--author-map: %w", err)
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for lineNo := 1; scanner.Scan(); lineNo++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if err := add(line, fmt.Sprintf("%s:%d", file, lineNo)); err != nil {
+				return nil, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("--author-map: %w", err)
+		}
+	}
+	for _, pair := range pairs {
+		if err := add(pair, "--map-author"); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func printRewriteHistory(w io.Writer, res *repo.RewriteHistoryResult, dryRun bool) {
+	verb := "rewrote"
+	if dryRun {
+		verb = "would rewrite"
+	}
+	fmt.Fprintf(w, "%s %d of %s", verb, res.Changed(), pluralize(len(res.Commits), "commit"))
+	if res.Pruned > 0 {
+		fmt.Fprintf(w, " (%d pruned as empty)", res.Pruned)
+	}
+	fmt.Fprintln(w)
+	for _, ref := range res.Refs {
+		fmt.Fprintf(w, "  %s: %s -> %s\n", ref.Name, shortHash(ref.Old), shortHash(ref.New))
+	}
+	if !dryRun {
+		fmt.Fprintln(w, "commit map written to .graft/rewrite-history/commit-map")
+	}
+}
+
+func rewriteHistoryJSON(res *repo.RewriteHistoryResult, dryRun bool) JSONRewriteHistoryOutput {
+	out := JSONRewriteHistoryOutput{
+		DryRun:    dryRun,
+		Commits:   len(res.Commits),
+		Changed:   res.Changed(),
+		Pruned:    res.Pruned,
+		CommitMap: make([]JSONRewriteCommit, 0, len(res.Commits)),
+		Refs:      make([]JSONRewriteRef, 0, len(res.Refs)),
+	}
+	for _, old := range res.Commits {
+		out.CommitMap = append(out.CommitMap, JSONRewriteCommit{Old: string(old), New: string(res.CommitMap[old])})
+	}
+	for _, ref := range res.Refs {
+		out.Refs = append(out.Refs, JSONRewriteRef{Name: ref.Name, Old: string(ref.Old), New: string(ref.New)})
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteHistoryIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "main.go", "package main\n", "initial")
+	commitFile(t, dir, "vendor/blob.bin", strings.Repeat("x", 2048), "add blob")
+	commitFile(t, dir, "main.go", "package main\n\nfunc main() {}\n", "add main")
+	oldHead := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD"))
+
+	if _, err := runGraft(t, dir, "rewrite-history"); err == nil {
+		t.Fatal("expected an error without any filter")
+	}
+	if _, err := runGraft(t, dir, "rewrite-history", "--strip-blobs-bigger-than", "lots"); err == nil {
+		t.Fatal("expected an error for an invalid size")
+	}
+
+	dry := mustRunGraft(t, dir, "rewrite-history", "--strip-blobs-bigger-than", "1K", "--dry-run")
+	if !strings.Contains(dry, "would rewrite 2 of 3 commits (1 pruned as empty)") {
+		t.Fatalf("dry run output:\n%s", dry)
+	}
+	if head := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD")); head != oldHead {
+		t.Fatalf("dry run moved HEAD from %s to %s", oldHead, head)
+	}
+
+	authorMap := filepath.Join(t.TempDir(), "authors")
+	if err := os.WriteFile(authorMap, []byte("# rename\nTest User = Renamed User <renamed@example.com>\n"), 0o644); err != nil {
+		t.Fatalf("write author map: %v", err)
+	}
+	var out JSONRewriteHistoryOutput
+	raw := mustRunGraft(t, dir, "rewrite-history", "--remove-path", "vendor", "--author-map", authorMap, "--json")
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		t.Fatalf("unmarshal rewrite-history --json: %v\n%s", err, raw)
+	}
+	if out.Commits != 3 || out.Changed != 3 || out.Pruned != 1 || len(out.CommitMap) != 3 || len(out.Refs) != 1 {
+		t.Fatalf("rewrite-history --json = %+v", out)
+	}
+	if out.Refs[0].Old != oldHead || out.Refs[0].New == oldHead {
+		t.Fatalf("ref update = %+v", out.Refs[0])
+	}
+
+	log := mustRunGraft(t, dir, "log", "--format=%an %s")
+	if log != "Renamed User add main\nRenamed User initial\n" {
+		t.Fatalf("rewritten log:\n%s", log)
+	}
+	if status := mustRunGraft(t, dir, "status", "--porcelain"); !strings.Contains(status, "?? vendor/") {
+		t.Fatalf("removed files should be left untracked:\n%s", status)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".graft", "rewrite-history", "ref-map")); err != nil {
+		t.Fatalf("ref-map not written: %v", err)
+	}
+}
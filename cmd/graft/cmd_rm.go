@@ -9,9 +9,10 @@ func newRmCmd() *cobra.Command {
 	var cached bool
 
 	cmd := &cobra.Command{
-		Use:   "rm [--cached] <files...>",
-		Short: "Remove files from working tree and stage the deletion",
-		Args:  cobra.MinimumNArgs(1),
+		Use:               "rm [--cached] <files...>",
+		Short:             "Remove files from working tree and stage the deletion",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeTrackedPaths,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
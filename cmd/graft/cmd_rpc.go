@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/diff"
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+// rpcServerError is the JSON-RPC error code for a method that was called
+// correctly but failed, e.g. on an unknown revision.
+const rpcServerError = -32000
+
+func newRPCCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rpc",
+		Short: "JSON-RPC server for editor integration",
+	}
+
+	cmd.AddCommand(newRPCServeCmd())
+	return cmd
+}
+
+func newRPCServeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Serve structural diff, entity and merge queries over stdio",
+		Long: `Start a JSON-RPC 2.0 server over stdio with Content-Length framing, the
+framing of the Language Server Protocol, so that an editor extension can
+keep one process open instead of running graft per keystroke.
+
+Methods:
+  initialize     server name, version and the methods below
+  diff/buffer    {path, content, rev?}: the structural (entity-level) and line
+                 diff from path at rev (default HEAD) to an unsaved buffer
+  entities/at    {path, rev?}: the entities of path at rev (default HEAD)
+  merge/preview  {branch}: what merging branch into HEAD would produce,
+                 conflicts included, without touching the repository
+  shutdown       acknowledged; exit (request or notification) stops the server
+
+Paths are relative to the repository root. Results use the same fields as
+the --json output of diff and merge.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			return newRPCServer(r, os.Stdin, os.Stdout).run()
+		},
+	}
+}
+
+// --- Results ---
+
+// rpcEntity describes one entity of a file.
+type rpcEntity struct {
+	Key       string `json:"key"`
+	Name      string `json:"name,omitempty"`
+	Kind      string `json:"kind"`
+	DeclKind  string `json:"declKind,omitempty"`
+	Receiver  string `json:"receiver,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+}
+
+// rpcEntityChange is one entity-level change of a structural diff.
+type rpcEntityChange struct {
+	Type   string     `json:"type"` // "added", "removed", "modified", "moved", "renamed"
+	Key    string     `json:"key"`
+	Before *rpcEntity `json:"before,omitempty"`
+	After  *rpcEntity `json:"after,omitempty"`
+}
+
+// rpcBufferDiffResult is the result of diff/buffer. Structural is false
+// when the file's language has no entity support; Hunks are always set.
+type rpcBufferDiffResult struct {
+	Path       string            `json:"path"`
+	Commit     string            `json:"commit"`
+	Structural bool              `json:"structural"`
+	Changes    []rpcEntityChange `json:"changes"`
+	Hunks      []JSONDiffHunk    `json:"hunks"`
+}
+
+// rpcEntitiesResult is the result of entities/at.
+type rpcEntitiesResult struct {
+	Path     string      `json:"path"`
+	Commit   string      `json:"commit"`
+	Language string      `json:"language"`
+	Entities []rpcEntity `json:"entities"`
+}
+
+// --- Server ---
+
+// rpcServer answers editor queries against one repository. It keeps the
+// flattened tree of the last commit it read, so that repeated queries
+// against HEAD do not walk the tree again.
+type rpcServer struct {
+	repo   *repo.Repo
+	reader *bufio.Reader
+	writer io.Writer
+
+	treeCommit object.Hash
+	treeFiles  map[string]repo.TreeFileEntry
+}
+
+func newRPCServer(r *repo.Repo, in io.Reader, out io.Writer) *rpcServer {
+	return &rpcServer{
+		repo:   r,
+		reader: bufio.NewReader(in),
+		writer: out,
+	}
+}
+
+func (s *rpcServer) run() error {
+	for {
+		payload, err := mcpReadFramedMessage(s.reader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var request mcpRPCRequest
+		if err := json.Unmarshal(payload, &request); err != nil {
+			_ = s.send(mcpRPCResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &mcpRPCError{Code: -32700, Message: "parse error"}})
+			continue
+		}
+		if request.Method == "exit" {
+			if id := bytes.TrimSpace(request.ID); len(id) > 0 && string(id) != "null" {
+				_ = s.send(mcpRPCResponse{JSONRPC: "2.0", ID: request.ID, Result: map[string]any{}})
+			}
+			return nil
+		}
+		// Notifications get no response.
+		if id := bytes.TrimSpace(request.ID); len(id) == 0 || string(id) == "null" {
+			continue
+		}
+
+		result, rpcErr := s.handle(request)
+		response := mcpRPCResponse{JSONRPC: "2.0", ID: request.ID, Result: result, Error: rpcErr}
+		if err := s.send(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *rpcServer) handle(request mcpRPCRequest) (any, *mcpRPCError) {
+	switch request.Method {
+	case "initialize":
+		return map[string]any{
+			"serverInfo": map[string]any{"name": "graft", "version": version},
+			"methods":    []string{"diff/buffer", "entities/at", "merge/preview"},
+		}, nil
+	case "shutdown":
+		return map[string]any{}, nil
+	case "diff/buffer":
+		var params struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+			Rev     string `json:"rev"`
+		}
+		if err := mcpDecodeParams(request.Params, &params); err != nil {
+			return nil, &mcpRPCError{Code: -32602, Message: err.Error()}
+		}
+		p, err := rpcPath(params.Path)
+		if err != nil {
+			return nil, &mcpRPCError{Code: -32602, Message: err.Error()}
+		}
+		return rpcResult(s.diffBuffer(p, []byte(params.Content), params.Rev))
+	case "entities/at":
+		var params struct {
+			Path string `json:"path"`
+			Rev  string `json:"rev"`
+		}
+		if err := mcpDecodeParams(request.Params, &params); err != nil {
+			return nil, &mcpRPCError{Code: -32602, Message: err.Error()}
+		}
+		p, err := rpcPath(params.Path)
+		if err != nil {
+			return nil, &mcpRPCError{Code: -32602, Message: err.Error()}
+		}
+		return rpcResult(s.entitiesAt(p, params.Rev))
+	case "merge/preview":
+		var params struct {
+			Branch string `json:"branch"`
+		}
+		if err := mcpDecodeParams(request.Params, &params); err != nil {
+			return nil, &mcpRPCError{Code: -32602, Message: err.Error()}
+		}
+		if strings.TrimSpace(params.Branch) == "" {
+			return nil, &mcpRPCError{Code: -32602, Message: "branch is required"}
+		}
+		return rpcResult(s.mergePreview(params.Branch))
+	default:
+		return nil, &mcpRPCError{Code: -32601, Message: fmt.Sprintf("method not found: %s", request.Method)}
+	}
+}
+
+// rpcResult turns the outcome of a method into a response's result or
+// error.
+func rpcResult(result any, err error) (any, *mcpRPCError) {
+	if err != nil {
+		return nil, &mcpRPCError{Code: rpcServerError, Message: err.Error()}
+	}
+	return result, nil
+}
+
+func (s *rpcServer) send(response mcpRPCResponse) error {
+	payload, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n", len(payload)); err != nil {
+		return err
+	}
+	_, err = s.writer.Write(payload)
+	return err
+}
+
+// diffBuffer diffs path at rev against content.
+func (s *rpcServer) diffBuffer(p string, content []byte, rev string) (*rpcBufferDiffResult, error) {
+	commit, before, _, err := s.fileAt(p, rev)
+	if err != nil {
+		return nil, err
+	}
+	result := &rpcBufferDiffResult{
+		Path:    p,
+		Commit:  string(commit),
+		Changes: []rpcEntityChange{},
+		Hunks:   buildJSONDiffFile(p, before, content).Hunks,
+	}
+	if result.Hunks == nil {
+		result.Hunks = []JSONDiffHunk{}
+	}
+	if before == nil {
+		before = []byte{}
+	}
+	fd, err := diff.StructuralDiff(p, before, content)
+	if err != nil {
+		// No entity support for this language; the line hunks stand alone.
+		return result, nil
+	}
+	result.Structural = true
+	for _, c := range fd.Changes {
+		result.Changes = append(result.Changes, rpcEntityChange{
+			Type:   c.Type.String(),
+			Key:    c.Key,
+			Before: newRPCEntity(c.Before),
+			After:  newRPCEntity(c.After),
+		})
+	}
+	return result, nil
+}
+
+// entitiesAt lists the entities of path at rev.
+func (s *rpcServer) entitiesAt(p, rev string) (*rpcEntitiesResult, error) {
+	commit, data, found, err := s.fileAt(p, rev)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("path %q does not exist in %s", p, shortHash(commit))
+	}
+	el, err := entity.Extract(p, data)
+	if err != nil {
+		return nil, fmt.Errorf("extract entities of %s: %w", p, err)
+	}
+	result := &rpcEntitiesResult{Path: p, Commit: string(commit), Language: el.Language, Entities: []rpcEntity{}}
+	for i := range el.Entities {
+		if el.Entities[i].Kind == entity.KindInterstitial {
+			continue
+		}
+		result.Entities = append(result.Entities, *newRPCEntity(&el.Entities[i]))
+	}
+	return result, nil
+}
+
+// mergePreview reports what merging branch into HEAD would produce.
+func (s *rpcServer) mergePreview(branch string) (JSONMergeOutput, error) {
+	current, err := s.repo.CurrentBranch()
+	if err != nil {
+		return JSONMergeOutput{}, err
+	}
+	report, err := s.repo.MergePreview(branch)
+	if err != nil {
+		return JSONMergeOutput{}, err
+	}
+	return mergeReportJSON(report, "preview", branch, current), nil
+}
+
+// fileAt returns the commit rev (default HEAD) names and the content of
+// path in it. found is false, with nil data, when the commit has no such
+// file.
+func (s *rpcServer) fileAt(p, rev string) (commit object.Hash, data []byte, found bool, err error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	commit, err = s.repo.ResolveTreeish(rev)
+	if err != nil {
+		return "", nil, false, err
+	}
+	if commit != s.treeCommit {
+		c, err := s.repo.Store.ReadCommit(commit)
+		if err != nil {
+			return "", nil, false, fmt.Errorf("read commit %s: %w", shortHash(commit), err)
+		}
+		entries, err := s.repo.FlattenTree(c.TreeHash)
+		if err != nil {
+			return "", nil, false, err
+		}
+		s.treeFiles = make(map[string]repo.TreeFileEntry, len(entries))
+		for _, e := range entries {
+			s.treeFiles[e.Path] = e
+		}
+		s.treeCommit = commit
+	}
+	entry, ok := s.treeFiles[p]
+	if !ok {
+		return commit, nil, false, nil
+	}
+	blob, err := s.repo.Store.ReadBlob(entry.BlobHash)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("read %s: %w", p, err)
+	}
+	return commit, blob.Data, true, nil
+}
+
+// rpcPath cleans a repository-relative path from a request.
+func rpcPath(p string) (string, error) {
+	if strings.TrimSpace(p) == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	cleaned := path.Clean(strings.TrimPrefix(p, "/"))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("path %q is outside the repository", p)
+	}
+	return cleaned, nil
+}
+
+func newRPCEntity(e *entity.Entity) *rpcEntity {
+	if e == nil {
+		return nil
+	}
+	return &rpcEntity{
+		Key:       e.IdentityKey(),
+		Name:      e.Name,
+		Kind:      e.Kind.String(),
+		DeclKind:  e.DeclKind,
+		Receiver:  e.Receiver,
+		Signature: e.Signature,
+		StartLine: e.StartLine,
+		EndLine:   e.EndLine,
+	}
+}
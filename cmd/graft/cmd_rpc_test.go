@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+// rpcTestResponse is a response from the rpc server with its result left
+// raw for the caller to decode.
+type rpcTestResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *mcpRPCError    `json:"error"`
+}
+
+// runRPCServer sends requests, each a method and its params, to a server
+// for r and returns the responses in order.
+func runRPCServer(t *testing.T, r *repo.Repo, requests ...any) []rpcTestResponse {
+	t.Helper()
+	var in bytes.Buffer
+	for i := 0; i < len(requests); i += 2 {
+		payload, err := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      i/2 + 1,
+			"method":  requests[i],
+			"params":  requests[i+1],
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(payload), payload)
+	}
+	fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(`{"jsonrpc":"2.0","method":"exit"}`), `{"jsonrpc":"2.0","method":"exit"}`)
+
+	var out bytes.Buffer
+	if err := newRPCServer(r, &in, &out).run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	reader := bufio.NewReader(&out)
+	var responses []rpcTestResponse
+	for {
+		payload, err := mcpReadFramedMessage(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		var resp rpcTestResponse
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			t.Fatalf("decode response %s: %v", payload, err)
+		}
+		responses = append(responses, resp)
+	}
+	if len(responses) != len(requests)/2 {
+		t.Fatalf("got %d responses, want %d", len(responses), len(requests)/2)
+	}
+	return responses
+}
+
+func TestRPCServer(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "main.go"), []byte("package main\n\nfunc a() int {\n\treturn 1\n}\n"))
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	head, err := r.Commit("initial", "tester")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	buffer := "package main\n\nfunc a() int {\n\treturn 2\n}\n\nfunc b() {}\n"
+	responses := runRPCServer(t, r,
+		"diff/buffer", map[string]any{"path": "main.go", "content": buffer},
+		"entities/at", map[string]any{"path": "main.go", "rev": "HEAD"},
+		"diff/buffer", map[string]any{"path": "new.go", "content": "package main\n"},
+		"entities/at", map[string]any{"path": "../outside.go"},
+		"merge/preview", map[string]any{"branch": "missing"},
+		"unknown/method", nil,
+	)
+
+	var bufDiff rpcBufferDiffResult
+	if err := json.Unmarshal(responses[0].Result, &bufDiff); err != nil || responses[0].Error != nil {
+		t.Fatalf("diff/buffer = %s, %+v", responses[0].Result, responses[0].Error)
+	}
+	if !bufDiff.Structural || bufDiff.Commit != string(head) || len(bufDiff.Hunks) == 0 {
+		t.Fatalf("diff/buffer = %+v", bufDiff)
+	}
+	changes := map[string]string{}
+	for _, c := range bufDiff.Changes {
+		e := c.After
+		if e == nil {
+			e = c.Before
+		}
+		changes[e.Name] = c.Type
+	}
+	if changes["a"] != "modified" || changes["b"] != "added" || len(changes) != 2 {
+		t.Fatalf("diff/buffer changes = %v, want a modified and b added", changes)
+	}
+
+	var entities rpcEntitiesResult
+	if err := json.Unmarshal(responses[1].Result, &entities); err != nil || responses[1].Error != nil {
+		t.Fatalf("entities/at = %s, %+v", responses[1].Result, responses[1].Error)
+	}
+	var names []string
+	for _, e := range entities.Entities {
+		if e.Kind == "declaration" {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) != 1 || names[0] != "a" {
+		t.Fatalf("entities/at declarations = %v, want [a]", names)
+	}
+
+	// A file missing from the revision diffs as added.
+	var added rpcBufferDiffResult
+	if err := json.Unmarshal(responses[2].Result, &added); err != nil || responses[2].Error != nil {
+		t.Fatalf("diff/buffer of a new file = %s, %+v", responses[2].Result, responses[2].Error)
+	}
+	if len(added.Hunks) != 1 {
+		t.Fatalf("diff/buffer of a new file = %+v, want one hunk", added)
+	}
+
+	for i, code := range map[int]int{3: -32602, 4: rpcServerError, 5: -32601} {
+		if responses[i].Error == nil || responses[i].Error.Code != code {
+			t.Errorf("response %d error = %+v, want code %d", i, responses[i].Error, code)
+		}
+	}
+}
@@ -14,7 +14,10 @@ func newShortlogCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "shortlog [-s] [-n]",
 		Short: "Summarise commit history by author",
-		Args:  cobra.NoArgs,
+		Long: `Shortlog groups the commits on the first-parent history of HEAD by
+author, merging the identities the .mailmap file at the root of the
+working tree maps to one person.`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
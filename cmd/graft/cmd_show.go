@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -14,12 +15,54 @@ import (
 
 func newShowCmd() *cobra.Command {
 	var jsonFlag bool
+	var separate, combined, firstParent bool
+	var dirstatFlag string
 
 	cmd := &cobra.Command{
-		Use:   "show [commit-ish]",
+		Use:   "show [commit-ish | <rev>:<path>#<entity>]",
 		Short: "Show commit metadata and changed files",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Show commit metadata and the files it changed against its first parent.
+
+<rev>:<path>#<entity> prints one function, type or other declaration as it
+was at a revision instead, without checking anything out. The entity is a
+declaration name, optionally qualified by its receiver (Repo.Commit), or an
+entity key; <path> is relative to the repository root and an empty <rev>
+means HEAD, as in ":main.go#main".
+
+-m, -c and --first-parent show the full diff instead, and choose how a merge
+commit is compared with its parents: -m diffs it against each parent in
+turn, -c shows one combined diff of the files that differ from every
+parent, with a column per parent, and --first-parent diffs it against the
+first parent only. A commit with one parent always gets its plain diff.
+
+--dirstat adds the share of the changed lines made in each directory, as
+diff --dirstat does and with the same parameters.`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeRefs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			modes := 0
+			for _, set := range []bool{separate, combined, firstParent} {
+				if set {
+					modes++
+				}
+			}
+			if modes > 1 {
+				return fmt.Errorf("-m, -c and --first-parent cannot be combined")
+			}
+			if modes > 0 && jsonFlag {
+				return fmt.Errorf("--json cannot be combined with -m, -c or --first-parent")
+			}
+			var stat *dirstat
+			if cmd.Flags().Changed("dirstat") {
+				if modes > 0 || jsonFlag {
+					return fmt.Errorf("--dirstat cannot be combined with --json, -m, -c or --first-parent")
+				}
+				var err error
+				if stat, err = parseDirstat(dirstatFlag); err != nil {
+					return err
+				}
+			}
+
 			r, err := repo.Open(".")
 			if err != nil {
 				return err
@@ -29,6 +72,12 @@ func newShowCmd() *cobra.Command {
 			if len(args) == 1 && strings.TrimSpace(args[0]) != "" {
 				target = strings.TrimSpace(args[0])
 			}
+			if rev, path, selector, ok := parseShowEntitySpec(target); ok {
+				if modes > 0 || stat != nil {
+					return fmt.Errorf("-m, -c, --first-parent and --dirstat apply to commits, not entities")
+				}
+				return showEntity(cmd.OutOrStdout(), r, rev, path, selector, jsonFlag)
+			}
 
 			h, err := resolveCommitish(r, target)
 			if err != nil {
@@ -38,6 +87,9 @@ func newShowCmd() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("show: read commit %s: %w", h, err)
 			}
+			if modes > 0 {
+				return showMergeDiff(cmd.OutOrStdout(), r, h, commit, combined, firstParent)
+			}
 
 			before := make(map[string]repo.TreeFileEntry)
 			if len(commit.Parents) > 0 {
@@ -67,12 +119,7 @@ func newShowCmd() *cobra.Command {
 			}
 
 			out := cmd.OutOrStdout()
-			fmt.Fprintf(out, "commit %s\n", h)
-			fmt.Fprintf(out, "Author: %s\n", commit.Author)
-			fmt.Fprintf(out, "Date:   %s\n", time.Unix(commit.Timestamp, 0).Format("2006-01-02 15:04:05"))
-			fmt.Fprintln(out)
-			fmt.Fprintf(out, "    %s\n", commit.Message)
-			fmt.Fprintln(out)
+			printShowHeader(out, h, commit, "")
 
 			if len(changes) == 0 {
 				return nil
@@ -82,15 +129,115 @@ func newShowCmd() *cobra.Command {
 			for _, line := range changes {
 				fmt.Fprintf(out, "  %s\n", line)
 			}
+			if stat != nil {
+				if err := addTreeChangesToDirstat(r, stat, before, after); err != nil {
+					return err
+				}
+				fmt.Fprintln(out)
+				stat.write(out)
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
+	cmd.Flags().BoolVarP(&separate, "separate", "m", false, "show the diff of a merge against each parent")
+	cmd.Flags().BoolVarP(&combined, "combined", "c", false, "show a combined diff of a merge against all parents")
+	cmd.Flags().BoolVar(&firstParent, "first-parent", false, "show the diff of a merge against its first parent")
+	cmd.Flags().StringVar(&dirstatFlag, "dirstat", "", "also show the share of changes per directory (limit%, files, cumulative)")
+	cmd.Flags().Lookup("dirstat").NoOptDefVal = "lines"
 
 	return cmd
 }
 
+// parseShowEntitySpec splits a "<rev>:<path>#<entity>" argument. The path
+// runs from the first colon to the last "#".
+func parseShowEntitySpec(arg string) (rev, path, selector string, ok bool) {
+	hash := strings.LastIndex(arg, "#")
+	if hash < 0 {
+		return "", "", "", false
+	}
+	rev, path, found := strings.Cut(arg[:hash], ":")
+	if !found || path == "" {
+		return "", "", "", false
+	}
+	return rev, path, arg[hash+1:], true
+}
+
+// showEntity prints the source of one entity at a revision.
+func showEntity(out io.Writer, r *repo.Repo, rev, path, selector string, jsonFlag bool) error {
+	ent, err := r.EntityAtRevision(rev, path, selector)
+	if err != nil {
+		return fmt.Errorf("show: %w", err)
+	}
+	if jsonFlag {
+		return writeJSON(out, JSONShowEntityOutput{
+			Commit:   string(ent.Commit),
+			Path:     ent.Path,
+			Name:     ent.Name,
+			DeclKind: ent.DeclKind,
+			Receiver: ent.Receiver,
+			Body:     string(ent.Body),
+		})
+	}
+	if _, err := out.Write(ent.Body); err != nil {
+		return err
+	}
+	if len(ent.Body) > 0 && ent.Body[len(ent.Body)-1] != '\n' {
+		_, err = io.WriteString(out, "\n")
+	}
+	return err
+}
+
+// printShowHeader writes the commit header show prints. A non-empty from
+// names the parent the following diff is against.
+func printShowHeader(out io.Writer, h object.Hash, commit *object.CommitObj, from object.Hash) {
+	if from != "" {
+		fmt.Fprintf(out, "commit %s (from %s)\n", h, from)
+	} else {
+		fmt.Fprintf(out, "commit %s\n", h)
+	}
+	fmt.Fprintf(out, "Author: %s\n", commit.Author)
+	fmt.Fprintf(out, "Date:   %s\n", time.Unix(commit.Timestamp, 0).Format("2006-01-02 15:04:05"))
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "    %s\n", commit.Message)
+	fmt.Fprintln(out)
+}
+
+// showMergeDiff writes the commit with its full diff: combined for a merge
+// with combined, against the first parent with firstParent or for a
+// commit with at most one parent, and otherwise against each parent.
+func showMergeDiff(out io.Writer, r *repo.Repo, h object.Hash, commit *object.CommitObj, combined, firstParent bool) error {
+	merge := len(commit.Parents) > 1
+	if merge && combined {
+		files, err := r.CombinedDiff(h)
+		if err != nil {
+			return fmt.Errorf("show: %w", err)
+		}
+		printShowHeader(out, h, commit, "")
+		return repo.WriteCombinedDiff(out, files)
+	}
+
+	diffs, err := r.DiffParents(h, firstParent || !merge)
+	if err != nil {
+		return fmt.Errorf("show: %w", err)
+	}
+	for i, d := range diffs {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		from := object.Hash("")
+		if merge && !firstParent {
+			from = d.Parent
+		}
+		printShowHeader(out, h, commit, from)
+		if err := repo.WriteDiff(out, d.Files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // showJSON writes JSON output for the show command.
 func showJSON(cmd *cobra.Command, h object.Hash, commit *object.CommitObj, changes []string) error {
 	parents := make([]string, len(commit.Parents))
@@ -166,3 +313,42 @@ func summarizeTreeChanges(before, after map[string]repo.TreeFileEntry) []string
 	}
 	return out
 }
+
+// addTreeChangesToDirstat records in stat every file whose content differs
+// between before and after.
+func addTreeChangesToDirstat(r *repo.Repo, stat *dirstat, before, after map[string]repo.TreeFileEntry) error {
+	read := func(entries map[string]repo.TreeFileEntry, p string) ([]byte, error) {
+		e, ok := entries[p]
+		if !ok {
+			return nil, nil
+		}
+		blob, err := r.Store.ReadBlob(e.BlobHash)
+		if err != nil {
+			return nil, fmt.Errorf("show: read blob %s: %w", p, err)
+		}
+		return blob.Data, nil
+	}
+	paths := make(map[string]struct{}, len(before)+len(after))
+	for p, b := range before {
+		if a, ok := after[p]; !ok || a.BlobHash != b.BlobHash {
+			paths[p] = struct{}{}
+		}
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			paths[p] = struct{}{}
+		}
+	}
+	for p := range paths {
+		oldData, err := read(before, p)
+		if err != nil {
+			return err
+		}
+		newData, err := read(after, p)
+		if err != nil {
+			return err
+		}
+		stat.add(p, oldData, newData)
+	}
+	return nil
+}
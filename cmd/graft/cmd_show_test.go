@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestShowMergeDiffModesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "f.txt", "a\nb\nc\n", "base")
+	mainBranch := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "--abbrev-ref", "HEAD"))
+	mustRunGraft(t, dir, "branch", "feature")
+	commitFile(t, dir, "f.txt", "a\nB\nc\n", "ours")
+	mustRunGraft(t, dir, "checkout", "feature")
+	commitFile(t, dir, "f.txt", "a\nb\nc\nd\n", "theirs")
+	commitFile(t, dir, "side.txt", "side\n", "side file")
+	mustRunGraft(t, dir, "checkout", mainBranch)
+	mustRunGraft(t, dir, "merge", "feature")
+
+	separate := mustRunGraft(t, dir, "show", "-m")
+	if strings.Count(separate, "(from ") != 2 {
+		t.Fatalf("show -m should diff against both parents:\n%s", separate)
+	}
+	if !strings.Contains(separate, "+++ b/side.txt") || !strings.Contains(separate, "-b\n+B\n") {
+		t.Fatalf("show -m missing per-parent hunks:\n%s", separate)
+	}
+
+	first := mustRunGraft(t, dir, "show", "--first-parent")
+	if strings.Contains(first, "(from ") || strings.Contains(first, "-b\n+B\n") || !strings.Contains(first, "+d\n") {
+		t.Fatalf("show --first-parent should diff against the first parent only:\n%s", first)
+	}
+
+	combined := mustRunGraft(t, dir, "show", "-c")
+	if !strings.Contains(combined, "diff --combined f.txt") || strings.Contains(combined, "side.txt") {
+		t.Fatalf("show -c should list only f.txt:\n%s", combined)
+	}
+	if !strings.Contains(combined, " -b\n +B\n") || !strings.Contains(combined, "+ d\n") {
+		t.Fatalf("show -c missing combined columns:\n%s", combined)
+	}
+
+	if _, err := runGraft(t, dir, "show", "-m", "-c"); err == nil {
+		t.Fatal("expected an error combining -m and -c")
+	}
+
+	// With the feature branch gone, only the merge's second parent
+	// reaches the feature commits.
+	mustRunGraft(t, dir, "branch", "-d", "feature")
+	all := nonEmptyLines(mustRunGraft(t, dir, "log", "--all", "--oneline"))
+	firstParent := nonEmptyLines(mustRunGraft(t, dir, "log", "--all", "--first-parent", "--oneline"))
+	if len(all) != 5 || len(firstParent) != 3 {
+		t.Fatalf("log --all = %q, --all --first-parent = %q", all, firstParent)
+	}
+}
+
+func TestShowEntityAtRevisionIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "main.go", "package main\n\nfunc Load() int {\n\treturn 1\n}\n", "add load")
+	commitFile(t, dir, "main.go", "package main\n\nfunc Load() int {\n\treturn 2\n}\n", "change load")
+
+	old := mustRunGraft(t, dir, "show", "HEAD~1:main.go#Load")
+	if old != "func Load() int {\n\treturn 1\n}\n" {
+		t.Fatalf("show HEAD~1:main.go#Load = %q", old)
+	}
+	if cur := mustRunGraft(t, dir, "show", ":main.go#Load"); !strings.Contains(cur, "return 2") {
+		t.Fatalf("show :main.go#Load = %q", cur)
+	}
+
+	var out JSONShowEntityOutput
+	if err := json.Unmarshal([]byte(mustRunGraft(t, dir, "show", "--json", "HEAD~1:main.go#Load")), &out); err != nil {
+		t.Fatalf("unmarshal show --json: %v", err)
+	}
+	if out.Path != "main.go" || out.Name != "Load" || !strings.Contains(out.Body, "return 1") {
+		t.Fatalf("show --json = %+v", out)
+	}
+
+	if _, err := runGraft(t, dir, "show", "HEAD:main.go#Missing"); err == nil {
+		t.Fatal("expected an error for a missing entity")
+	}
+	if _, err := runGraft(t, dir, "show", "-m", "HEAD:main.go#Load"); err == nil {
+		t.Fatal("expected an error combining -m with an entity spec")
+	}
+}
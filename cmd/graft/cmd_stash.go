@@ -15,6 +15,7 @@ func newStashCmd() *cobra.Command {
 		Short: "Stash changes in the working directory",
 		RunE:  stashPushRun, // bare "graft stash" behaves like "graft stash push"
 	}
+	addStashPushFlags(cmd)
 
 	cmd.AddCommand(newStashPushCmd())
 	cmd.AddCommand(newStashPopCmd())
@@ -27,12 +28,26 @@ func newStashCmd() *cobra.Command {
 }
 
 func newStashPushCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "push",
+	cmd := &cobra.Command{
+		Use:   "push [-u | -a]",
 		Short: "Save changes and revert working tree",
-		Args:  cobra.NoArgs,
-		RunE:  stashPushRun,
+		Long: `Save changes and revert the working tree and staging area to HEAD.
+
+By default new untracked files are saved together with tracked changes and
+come back staged. With -u they are saved in a separate tree instead, with
+-a ignored files too, and they come back as untracked files on apply.`,
+		Args: cobra.NoArgs,
+		RunE: stashPushRun,
 	}
+	addStashPushFlags(cmd)
+	return cmd
+}
+
+// addStashPushFlags registers the push flags on cmd; "graft stash" and
+// "graft stash push" share them.
+func addStashPushFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("include-untracked", "u", false, "save untracked files in a separate tree and remove them")
+	cmd.Flags().BoolP("all", "a", false, "like --include-untracked, but also save and remove ignored files")
 }
 
 func stashPushRun(cmd *cobra.Command, args []string) error {
@@ -43,7 +58,12 @@ func stashPushRun(cmd *cobra.Command, args []string) error {
 
 	author := r.ResolveAuthor()
 
-	entry, err := r.Stash(author)
+	includeUntracked, _ := cmd.Flags().GetBool("include-untracked")
+	all, _ := cmd.Flags().GetBool("all")
+	entry, err := r.StashWithOptions(author, repo.StashOptions{
+		IncludeUntracked: includeUntracked,
+		IncludeIgnored:   all,
+	})
 	if err != nil {
 		return err
 	}
@@ -91,6 +111,7 @@ func newStashPopCmd() *cobra.Command {
 				}
 				fmt.Fprintf(out, "Applied stash@{%d} with %d conflict(s). Stash not dropped. Resolve and commit.\n",
 					index, len(result.ConflictPaths))
+				return exitStatus(exitConflict)
 			}
 			return nil
 		},
@@ -127,6 +148,7 @@ func newStashApplyCmd() *cobra.Command {
 				}
 				fmt.Fprintf(out, "Applied stash@{%d} with %d conflict(s). Resolve and commit.\n",
 					index, len(result.ConflictPaths))
+				return exitStatus(exitConflict)
 			}
 			return nil
 		},
@@ -235,6 +257,24 @@ func newStashShowCmd() *cobra.Command {
 	return cmd
 }
 
+// addAutostashFlags registers --autostash and --no-autostash on cmd.
+func addAutostashFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("autostash", false, "stash local changes before the operation and re-apply them afterwards")
+	cmd.Flags().Bool("no-autostash", false, "do not autostash, overriding core.autostash")
+}
+
+// autostashEnabled resolves --autostash and --no-autostash against the
+// core.autostash default.
+func autostashEnabled(cmd *cobra.Command, r *repo.Repo) bool {
+	if off, _ := cmd.Flags().GetBool("no-autostash"); off {
+		return false
+	}
+	if on, _ := cmd.Flags().GetBool("autostash"); on {
+		return true
+	}
+	return r.AutostashDefault()
+}
+
 // parseStashIndex extracts the stash index from the optional positional arg,
 // defaulting to 0 when no argument is provided.
 func parseStashIndex(args []string) (int, error) {
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newStatsCmd() *cobra.Command {
+	var by string
+	var top int
+	var jsonFlag, csvFlag bool
+
+	cmd := &cobra.Command{
+		Use:   "stats [<revision-range>...]",
+		Short: "Report commit statistics and code churn per author and entity",
+		Long: `Report how much a revision range changed: commits, and lines added and
+removed, per author and per entity (function, type or other declaration).
+
+Revisions are read as rev-list reads them, so "v1.0..HEAD" reports the
+commits since v1.0; with none, stats covers the history of HEAD. Merge
+commits are not counted. Entities are listed by lines changed, most first.
+Authors are grouped as the .mailmap file at the root of the working tree
+maps them.
+
+--by limits the report to the author or the entity table. --json and --csv
+export the report for other tools; CSV holds one table, the authors unless
+--by entity is given.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonFlag && csvFlag {
+				return fmt.Errorf("--json and --csv cannot be combined")
+			}
+			switch by {
+			case "", "author", "entity":
+			default:
+				return fmt.Errorf("--by must be author or entity, got %q", by)
+			}
+
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			report, err := r.Stats(args)
+			if err != nil {
+				return err
+			}
+			if by == "entity" {
+				report.Authors = nil
+			}
+			if by == "author" {
+				report.Entities = nil
+			}
+			if top > 0 && len(report.Entities) > top {
+				report.Entities = report.Entities[:top]
+			}
+
+			out := cmd.OutOrStdout()
+			switch {
+			case jsonFlag:
+				return writeJSON(out, statsToJSON(report))
+			case csvFlag:
+				return writeStatsCSV(out, report, by == "entity")
+			}
+			return printStats(out, report)
+		},
+	}
+
+	cmd.Flags().StringVar(&by, "by", "", "report only the author or the entity table")
+	cmd.Flags().IntVar(&top, "top", 0, "show only the N most changed entities (0 = all)")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
+	cmd.Flags().BoolVar(&csvFlag, "csv", false, "output in CSV format")
+	return cmd
+}
+
+func printStats(w io.Writer, report *repo.StatsReport) error {
+	fmt.Fprintf(w, "%d commits, %d insertions(+), %d deletions(-)\n", report.Commits, report.Added, report.Removed)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(report.Authors) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "COMMITS\tENTITIES\tADDED\tREMOVED\tAUTHOR")
+		for _, a := range report.Authors {
+			fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%s\n", a.Commits, a.Entities, a.Added, a.Removed, a.Author)
+		}
+	}
+	if len(report.Entities) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "COMMITS\tAUTHORS\tADDED\tREMOVED\tENTITY")
+		for _, e := range report.Entities {
+			fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%s::%s (%s)\n", e.Commits, e.Authors, e.Added, e.Removed, e.Path, e.Name, e.DeclKind)
+		}
+	}
+	return tw.Flush()
+}
+
+func writeStatsCSV(w io.Writer, report *repo.StatsReport, entities bool) error {
+	cw := csv.NewWriter(w)
+	itoa := strconv.Itoa
+	if entities {
+		cw.Write([]string{"path", "name", "decl_kind", "key", "commits", "authors", "added", "removed"})
+		for _, e := range report.Entities {
+			cw.Write([]string{e.Path, e.Name, e.DeclKind, e.Key, itoa(e.Commits), itoa(e.Authors), itoa(e.Added), itoa(e.Removed)})
+		}
+	} else {
+		cw.Write([]string{"author", "commits", "entities", "added", "removed"})
+		for _, a := range report.Authors {
+			cw.Write([]string{a.Author, itoa(a.Commits), itoa(a.Entities), itoa(a.Added), itoa(a.Removed)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func statsToJSON(report *repo.StatsReport) JSONStatsOutput {
+	out := JSONStatsOutput{Commits: report.Commits, Added: report.Added, Removed: report.Removed}
+	for _, a := range report.Authors {
+		out.Authors = append(out.Authors, JSONAuthorStats{
+			Author:   a.Author,
+			Commits:  a.Commits,
+			Entities: a.Entities,
+			Added:    a.Added,
+			Removed:  a.Removed,
+		})
+	}
+	for _, e := range report.Entities {
+		out.Entities = append(out.Entities, JSONEntityStats{
+			Path:     e.Path,
+			Name:     e.Name,
+			DeclKind: e.DeclKind,
+			Key:      e.Key,
+			Commits:  e.Commits,
+			Authors:  e.Authors,
+			Added:    e.Added,
+			Removed:  e.Removed,
+		})
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStatsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	writeFile(t, dir, "main.go", "package main\n\nfunc Load() {\n}\n")
+	mustRunGraft(t, dir, "add", "main.go")
+	mustRunGraft(t, dir, "commit", "-m", "add load", "--author", "Alice", "--no-sign")
+	writeFile(t, dir, "main.go", "package main\n\nfunc Load() {\n\tinit()\n}\n")
+	mustRunGraft(t, dir, "add", "main.go")
+	mustRunGraft(t, dir, "commit", "-m", "init in load", "--author", "Bob", "--no-sign")
+
+	out := mustRunGraft(t, dir, "stats")
+	if !strings.Contains(out, "2 commits, 5 insertions(+), 0 deletions(-)") {
+		t.Fatalf("stats summary missing:\n%s", out)
+	}
+	if !strings.Contains(out, "main.go::Load (function_declaration)") {
+		t.Fatalf("stats entity table missing Load:\n%s", out)
+	}
+
+	var report JSONStatsOutput
+	if err := json.Unmarshal([]byte(mustRunGraft(t, dir, "stats", "--json", "HEAD~1..HEAD")), &report); err != nil {
+		t.Fatalf("decode stats --json: %v", err)
+	}
+	if report.Commits != 1 || len(report.Authors) != 1 || report.Authors[0].Author != "Bob" {
+		t.Fatalf("stats --json HEAD~1..HEAD = %+v", report)
+	}
+	if len(report.Entities) != 1 || report.Entities[0].Name != "Load" || report.Entities[0].Added != 1 {
+		t.Fatalf("stats --json entities = %+v", report.Entities)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(mustRunGraft(t, dir, "stats", "--csv", "--by", "entity"))).ReadAll()
+	if err != nil {
+		t.Fatalf("parse stats --csv: %v", err)
+	}
+	if len(records) != 2 || records[0][0] != "path" || records[1][1] != "Load" || records[1][4] != "2" {
+		t.Fatalf("stats --csv --by entity = %q", records)
+	}
+
+	if _, err := runGraft(t, dir, "stats", "--json", "--csv"); err == nil {
+		t.Fatal("expected an error combining --json and --csv")
+	}
+}
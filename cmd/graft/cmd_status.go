@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
 
@@ -13,11 +14,29 @@ import (
 func newStatusCmd() *cobra.Command {
 	var jsonFlag bool
 	var shortFlag bool
+	var porcelainFlag bool
+	var nulFlag bool
+	var exitCodeFlag bool
 
 	cmd := &cobra.Command{
-		Use:   "status [-s|--short] [--json]",
+		Use:   "status [-s|--short] [--porcelain [-z]] [--json] [--exit-code]",
 		Short: "Show working tree status",
-		Args:  cobra.NoArgs,
+		Long: `Show working tree status.
+
+--porcelain prints one line per changed path as "XY path", where X is the
+index status and Y the working tree status: ' ' unmodified, M modified,
+A added, D deleted, R renamed. Renames print "R  old -> new", untracked
+files "?? path", and conflicts the two-letter code of which sides changed
+the file: UU both modified, AA both added, DD both deleted, AU/UA added by
+us/them, UD/DU deleted by them/us. Unlike --short, this format is stable
+across versions and meant for scripts. With -z, lines end in NUL instead of
+newline, and renames print "R  new" NUL "old".
+
+--json prints the same information as a JSON document.
+
+--exit-code makes status exit with 1 when anything is staged, modified,
+conflicted or untracked, and 0 when the working tree is clean.`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
@@ -44,16 +63,43 @@ func newStatusCmd() *cobra.Command {
 				}
 			}
 
+			if nulFlag {
+				porcelainFlag = true
+			}
 			if jsonFlag && shortFlag {
 				return fmt.Errorf("--json and --short cannot be used together")
 			}
+			if porcelainFlag && (jsonFlag || shortFlag) {
+				return fmt.Errorf("--porcelain cannot be used with --json or --short")
+			}
+
+			var tracking *repo.TrackingInfo
+			if !noCommits && strings.HasPrefix(head, "refs/heads/") {
+				tracking, err = r.BranchTracking(branch)
+				if err != nil {
+					return err
+				}
+			}
+
+			// done ends the command once the status is printed, with exit
+			// status 1 under --exit-code when the tree is not clean.
+			done := func(err error) error {
+				if err == nil && exitCodeFlag && !statusClean(entries) {
+					return exitStatus(exitFailure)
+				}
+				return err
+			}
 
 			if jsonFlag {
-				return statusJSON(cmd, r, entries, branch, noCommits)
+				return done(statusJSON(cmd, r, entries, branch, noCommits, tracking))
+			}
+
+			if porcelainFlag {
+				return done(statusPorcelain(cmd, r, entries, nulFlag))
 			}
 
 			if shortFlag {
-				return statusShort(cmd, entries)
+				return done(statusShort(cmd, entries))
 			}
 
 			out := cmd.OutOrStdout()
@@ -63,6 +109,9 @@ func newStatusCmd() *cobra.Command {
 			} else {
 				fmt.Fprintf(out, "on %s\n", branch)
 			}
+			if tracking != nil {
+				fmt.Fprintln(out, trackingSummary(tracking))
+			}
 
 			// Categorize entries.
 			var conflicts, staged, unstaged, untracked []string
@@ -144,7 +193,7 @@ func newStatusCmd() *cobra.Command {
 					_, err := b.GitHEAD()
 					if err == nil {
 						// Simple check: just show bridge is active
-						fmt.Println("\ngit bridge: active")
+						fmt.Fprintln(out, "\ngit bridge: active")
 					}
 				}
 			}
@@ -153,31 +202,56 @@ func newStatusCmd() *cobra.Command {
 				fmt.Fprintln(out, "\nwarning: git shadow out of sync (run 'graft repair resync-git' to fix)")
 			}
 
-			return nil
+			return done(nil)
 		},
 	}
 
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
 	cmd.Flags().BoolVarP(&shortFlag, "short", "s", false, "output in short format")
+	cmd.Flags().BoolVar(&porcelainFlag, "porcelain", false, "output in a stable format for scripts")
+	cmd.Flags().BoolVarP(&nulFlag, "null", "z", false, "terminate porcelain entries with NUL (implies --porcelain)")
+	cmd.Flags().BoolVar(&exitCodeFlag, "exit-code", false, "exit with status 1 when the working tree is not clean")
 
 	return cmd
 }
 
+// statusClean reports whether status has nothing to show: no staged,
+// modified, conflicted or untracked files.
+func statusClean(entries []repo.StatusEntry) bool {
+	for _, e := range entries {
+		if e.IndexStatus != repo.StatusClean || e.WorkStatus != repo.StatusClean {
+			return false
+		}
+	}
+	return true
+}
+
 // statusJSON builds and writes the JSON output for the status command.
-func statusJSON(cmd *cobra.Command, r *repo.Repo, entries []repo.StatusEntry, branch string, noCommits bool) error {
+func statusJSON(cmd *cobra.Command, r *repo.Repo, entries []repo.StatusEntry, branch string, noCommits bool, tracking *repo.TrackingInfo) error {
 	result := JSONStatusOutput{
 		Branch:       branch,
 		NoCommits:    noCommits,
 		ShadowDesync: r.HasShadowFailures(),
 	}
+	if tracking != nil {
+		result.Upstream = tracking.Name
+		result.Ahead = tracking.Ahead
+		result.Behind = tracking.Behind
+	}
+
+	stg, err := statusConflictStaging(r, entries)
+	if err != nil {
+		return err
+	}
 
 	for _, e := range entries {
 		p := filepath.ToSlash(e.Path)
 
 		if e.IndexStatus == repo.StatusConflict || e.WorkStatus == repo.StatusConflict {
 			result.Conflicts = append(result.Conflicts, JSONStatusEntry{
-				Path:   p,
-				Status: "conflict",
+				Path:     p,
+				Status:   "conflict",
+				Conflict: conflictKinds[conflictCode(stg.Entries[e.Path])],
 			})
 			continue
 		}
@@ -223,6 +297,116 @@ func statusJSON(cmd *cobra.Command, r *repo.Repo, entries []repo.StatusEntry, br
 	return writeJSON(cmd.OutOrStdout(), result)
 }
 
+// trackingSummary describes how a branch relates to its upstream, e.g.
+// "ahead of origin/main by 2 commits".
+func trackingSummary(t *repo.TrackingInfo) string {
+	switch {
+	case t.Ahead > 0 && t.Behind > 0:
+		return fmt.Sprintf("diverged from %s: %d ahead, %d behind", t.Name, t.Ahead, t.Behind)
+	case t.Ahead > 0:
+		return fmt.Sprintf("ahead of %s by %s", t.Name, pluralize(t.Ahead, "commit"))
+	case t.Behind > 0:
+		return fmt.Sprintf("behind %s by %s", t.Name, pluralize(t.Behind, "commit"))
+	}
+	return fmt.Sprintf("up to date with %s", t.Name)
+}
+
+// statusPorcelain writes the --porcelain format: git's porcelain v1 codes
+// over graft's status, one entry per changed path.
+func statusPorcelain(cmd *cobra.Command, r *repo.Repo, entries []repo.StatusEntry, nul bool) error {
+	stg, err := statusConflictStaging(r, entries)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	for _, entry := range entries {
+		path := filepath.ToSlash(entry.Path)
+		var code string
+		from := ""
+		switch {
+		case entry.IndexStatus == repo.StatusConflict || entry.WorkStatus == repo.StatusConflict:
+			code = conflictCode(stg.Entries[entry.Path])
+		case entry.IndexStatus == repo.StatusUntracked && entry.WorkStatus != repo.StatusRenamed:
+			code = "??"
+		default:
+			indexCode := shortIndexStatusCode(entry.IndexStatus)
+			workCode := shortWorkStatusCode(entry.IndexStatus, entry.WorkStatus)
+			if indexCode == ' ' && workCode == ' ' {
+				continue
+			}
+			code = string([]byte{indexCode, workCode})
+			if entry.RenamedFrom != "" && (indexCode == 'R' || workCode == 'R') {
+				from = filepath.ToSlash(entry.RenamedFrom)
+			}
+		}
+
+		var line string
+		switch {
+		case nul && from != "":
+			line = code + " " + path + "\x00" + from + "\x00"
+		case nul:
+			line = code + " " + path + "\x00"
+		case from != "":
+			line = code + " " + from + " -> " + path + "\n"
+		default:
+			line = code + " " + path + "\n"
+		}
+		if _, err := io.WriteString(out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statusConflictStaging reads the staging area when entries include
+// conflicts, whose codes depend on which sides of the merge had the file.
+func statusConflictStaging(r *repo.Repo, entries []repo.StatusEntry) (*repo.Staging, error) {
+	for _, e := range entries {
+		if e.IndexStatus == repo.StatusConflict || e.WorkStatus == repo.StatusConflict {
+			return r.ReadStaging()
+		}
+	}
+	return &repo.Staging{}, nil
+}
+
+// conflictCode returns the porcelain code for a conflicted staging entry
+// from which merge sides had the file: present on both sides but not in
+// the base means both added, missing on one side means deleted by it.
+func conflictCode(se *repo.StagingEntry) string {
+	if se == nil {
+		return "UU"
+	}
+	base, ours, theirs := se.BaseBlobHash != "", se.OursBlobHash != "", se.TheirsBlobHash != ""
+	switch {
+	case !base && ours && theirs:
+		return "AA"
+	case !base && ours:
+		return "AU"
+	case !base && theirs:
+		return "UA"
+	case base && !ours && !theirs:
+		return "DD"
+	case base && !ours:
+		return "DU"
+	case base && !theirs:
+		return "UD"
+	default:
+		return "UU"
+	}
+}
+
+// conflictKinds names conflict codes for --json output.
+var conflictKinds = map[string]string{
+	"UU": "both_modified",
+	"AA": "both_added",
+	"DD": "both_deleted",
+	"AU": "added_by_us",
+	"UA": "added_by_them",
+	"DU": "deleted_by_us",
+	"UD": "deleted_by_them",
+}
+
 func statusShort(cmd *cobra.Command, entries []repo.StatusEntry) error {
 	out := cmd.OutOrStdout()
 	for _, entry := range entries {
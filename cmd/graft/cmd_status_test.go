@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/odvcencio/graft/pkg/object"
 	"github.com/odvcencio/graft/pkg/repo"
 )
 
@@ -124,3 +125,172 @@ func TestStatusCmd_ShortConflictsWithJSON(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestStatusCmd_Porcelain(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+
+	writeTestFile(t, filepath.Join(dir, "tracked.txt"), []byte("one\n"))
+	if err := r.Add([]string{"tracked.txt"}); err != nil {
+		t.Fatalf("Add tracked.txt: %v", err)
+	}
+	if _, err := r.Commit("initial", "tester"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	writeTestFile(t, filepath.Join(dir, "tracked.txt"), []byte("two\n"))
+	writeTestFile(t, filepath.Join(dir, "staged.txt"), []byte("staged\n"))
+	writeTestFile(t, filepath.Join(dir, "untracked.txt"), []byte("untracked\n"))
+	if err := r.Add([]string{"staged.txt"}); err != nil {
+		t.Fatalf("Add staged.txt: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--porcelain"}, "A  staged.txt\n M tracked.txt\n?? untracked.txt\n"},
+		{[]string{"-z"}, "A  staged.txt\x00 M tracked.txt\x00?? untracked.txt\x00"},
+	} {
+		var out bytes.Buffer
+		cmd := newStatusCmd()
+		cmd.SilenceUsage = true
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(tc.args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute(%v): %v", tc.args, err)
+		}
+		if out.String() != tc.want {
+			t.Errorf("status %v = %q, want %q", tc.args, out.String(), tc.want)
+		}
+	}
+}
+
+func TestStatusPorcelainRenamesAndConflicts(t *testing.T) {
+	entries := []repo.StatusEntry{
+		{Path: "new.go", RenamedFrom: "old.go", IndexStatus: repo.StatusRenamed, WorkStatus: repo.StatusClean},
+		{Path: "moved.txt", RenamedFrom: "orig.txt", IndexStatus: repo.StatusUntracked, WorkStatus: repo.StatusRenamed},
+		{Path: "clean.txt"},
+	}
+	for _, tc := range []struct {
+		nul  bool
+		want string
+	}{
+		{false, "R  old.go -> new.go\n R orig.txt -> moved.txt\n"},
+		{true, "R  new.go\x00old.go\x00 R moved.txt\x00orig.txt\x00"},
+	} {
+		var out bytes.Buffer
+		cmd := newStatusCmd()
+		cmd.SetOut(&out)
+		if err := statusPorcelain(cmd, nil, entries, tc.nul); err != nil {
+			t.Fatalf("statusPorcelain: %v", err)
+		}
+		if out.String() != tc.want {
+			t.Errorf("porcelain(nul=%v) = %q, want %q", tc.nul, out.String(), tc.want)
+		}
+	}
+
+	h := object.Hash("h")
+	for _, tc := range []struct {
+		entry *repo.StagingEntry
+		want  string
+	}{
+		{&repo.StagingEntry{BaseBlobHash: h, OursBlobHash: h, TheirsBlobHash: h}, "UU"},
+		{&repo.StagingEntry{OursBlobHash: h, TheirsBlobHash: h}, "AA"},
+		{&repo.StagingEntry{OursBlobHash: h}, "AU"},
+		{&repo.StagingEntry{TheirsBlobHash: h}, "UA"},
+		{&repo.StagingEntry{BaseBlobHash: h, TheirsBlobHash: h}, "DU"},
+		{&repo.StagingEntry{BaseBlobHash: h, OursBlobHash: h}, "UD"},
+		{&repo.StagingEntry{BaseBlobHash: h}, "DD"},
+		{nil, "UU"},
+	} {
+		if got := conflictCode(tc.entry); got != tc.want {
+			t.Errorf("conflictCode(%+v) = %s, want %s", tc.entry, got, tc.want)
+		}
+	}
+}
+
+func TestStatusCmd_PorcelainRejectsOtherFormats(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := repo.Init(dir); err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	cmd := newStatusCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--porcelain", "--json"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--porcelain cannot be used") {
+		t.Fatalf("Execute err = %v, want --porcelain conflict error", err)
+	}
+}
+
+func TestStatusAndBranchCmd_AheadBehindUpstream(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "a.txt"), []byte("a\n"))
+	if err := r.Add([]string{"a.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	base, err := r.Commit("base", "tester")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "b.txt"), []byte("b\n"))
+	if err := r.Add([]string{"b.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("local work", "tester"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	branch, err := r.CurrentBranch()
+	if err != nil {
+		t.Fatalf("CurrentBranch: %v", err)
+	}
+	if err := r.SetRemote("origin", "https://example.com/got/alice/repo"); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+	if err := r.UpdateRef("refs/remotes/origin/heads/"+branch, base); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	var out bytes.Buffer
+	cmd := newStatusCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(nil)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if want := "ahead of origin/" + branch + " by 1 commit\n"; !strings.Contains(out.String(), want) {
+		t.Fatalf("status output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	cmd = newBranchCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"-v"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("branch -v: %v", err)
+	}
+	if want := "[origin/" + branch + ": ahead 1] local work"; !strings.Contains(out.String(), want) {
+		t.Fatalf("branch -v output = %q, want %q", out.String(), want)
+	}
+}
@@ -17,7 +17,8 @@ func newSwitchCmd() *cobra.Command {
 
 This is the modern alternative to 'graft checkout' for branch switching.
 Use -c to create a new branch and switch to it in one step.`,
-		Args: cobra.RangeArgs(0, 1),
+		Args:              cobra.RangeArgs(0, 1),
+		ValidArgsFunction: completeBranches(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 && createBranch == "" {
 				return fmt.Errorf("branch name is required (or use -c to create a new branch)")
@@ -52,7 +53,7 @@ Use -c to create a new branch and switch to it in one step.`,
 				target = createBranch
 			}
 
-			if err := r.Checkout(target); err != nil {
+			if err := r.CheckoutWithOptions(target, repo.CheckoutOptions{Autostash: autostashEnabled(cmd, r)}); err != nil {
 				return err
 			}
 
@@ -66,6 +67,7 @@ Use -c to create a new branch and switch to it in one step.`,
 	}
 
 	cmd.Flags().StringVarP(&createBranch, "create", "c", "", "create and switch to a new branch")
+	addAutostashFlags(cmd)
 
 	return cmd
 }
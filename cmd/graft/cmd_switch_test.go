@@ -113,3 +113,29 @@ func TestIntegration_SwitchDashNotSupported(t *testing.T) {
 		t.Fatalf("expected 'not yet supported' message, got: %s", out)
 	}
 }
+
+func TestIntegration_SwitchAutostashConfig(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "base.txt", "base content\n", "initial commit")
+	mustRunGraft(t, dir, "switch", "-c", "feature")
+	commitFile(t, dir, "feature.txt", "feature content\n", "feature commit")
+	writeFile(t, dir, "base.txt", "local edit\n")
+
+	if _, err := runGraft(t, dir, "switch", "--no-autostash", "-c", "other"); err == nil {
+		t.Fatal("expected switching with local changes to be refused")
+	}
+	mustRunGraft(t, dir, "config", "core.autostash", "true")
+	mustRunGraft(t, dir, "switch", "other")
+
+	data, err := os.ReadFile(filepath.Join(dir, "base.txt"))
+	if err != nil || string(data) != "local edit\n" {
+		t.Fatalf("base.txt = %q, %v; want the local edit carried over", data, err)
+	}
+	if out := mustRunGraft(t, dir, "stash", "list"); strings.TrimSpace(out) != "" {
+		t.Fatalf("autostash left in the stash:\n%s", out)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newSymbolicRefCmd() *cobra.Command {
+	var short bool
+
+	cmd := &cobra.Command{
+		Use:   "symbolic-ref [--short] HEAD [<ref>]",
+		Short: "Read or change the branch HEAD names",
+		Long: `Symbolic-ref prints the ref HEAD points at, such as refs/heads/main, or
+with --short just main. It fails when HEAD is detached, so scripts can use
+it to test for a branch.
+
+Given <ref>, HEAD is pointed at it without touching the index or working
+tree. The ref must be a full name under refs/heads/ and need not exist
+yet, which is how an unborn branch is started.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] != "HEAD" {
+				return fmt.Errorf("symbolic-ref: only HEAD is a symbolic ref, not %q", args[0])
+			}
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 2 {
+				target := args[1]
+				if !strings.HasPrefix(target, "refs/heads/") || len(target) == len("refs/heads/") {
+					return fmt.Errorf("symbolic-ref: %q is not a branch ref (refs/heads/...)", target)
+				}
+				if err := r.SetHeadSymbolic(target); err != nil {
+					return fmt.Errorf("symbolic-ref: %w", err)
+				}
+				return nil
+			}
+
+			head, err := r.Head()
+			if err != nil {
+				return fmt.Errorf("symbolic-ref: %w", err)
+			}
+			if !strings.HasPrefix(head, "refs/") {
+				return fmt.Errorf("symbolic-ref: HEAD is not a symbolic ref")
+			}
+			if short {
+				head = strings.TrimPrefix(head, "refs/heads/")
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), head)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&short, "short", false, "print the branch name without refs/heads/")
+	return cmd
+}
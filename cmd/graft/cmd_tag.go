@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"sort"
+	"io"
 	"strings"
 
 	"github.com/odvcencio/graft/pkg/object"
@@ -17,44 +17,65 @@ func newTagCmd() *cobra.Command {
 	var annotate bool
 	var message string
 	var tagger string
+	var lines int
+	var sortKey string
+	var contains string
 
 	cmd := &cobra.Command{
 		Use:   "tag [name] [target]",
 		Short: "List, create, or delete tags",
-		Args:  cobra.MaximumNArgs(2),
+		Long: `List, create, or delete tags.
+
+Without a name, tags are listed. -n prints the first line of each tag's
+message next to its name, the annotation of an annotated tag or the commit
+message of a lightweight one; -n=<num> prints up to num lines. --sort orders
+the list by refname (the default), version:refname, which sorts v1.10 after
+v1.9, or creatordate; prefix the key with "-" to reverse it. --contains
+lists only the tags whose commit contains the given commit. With the global
+--json flag, tags are listed, and created or deleted ones reported, as JSON.`,
+		Args: cobra.MaximumNArgs(2),
+		// A tag's name is new; its target is a ref.
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+			if len(args) != 1 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeRefs(2)(cmd, args, toComplete)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			r, err := repo.Open(".")
 			if err != nil {
 				return err
 			}
+			jsonOut := jsonOutput(cmd)
 
 			if strings.TrimSpace(deleteTag) != "" {
 				if len(args) > 0 {
 					return fmt.Errorf("tag --delete does not accept positional args")
 				}
-				return r.DeleteTag(deleteTag)
+				target, _ := r.ResolveRef("refs/tags/" + deleteTag)
+				if err := r.DeleteTag(deleteTag); err != nil {
+					return err
+				}
+				if jsonOut {
+					return writeJSON(cmd.OutOrStdout(), JSONRefAction{Action: "deleted", Name: deleteTag, Target: string(target)})
+				}
+				return nil
 			}
 
 			if len(args) == 0 {
-				tags, err := r.ListTagsWithHashes()
+				tags, err := r.ListTagInfo(repo.TagListOptions{Sort: sortKey, Contains: contains})
 				if err != nil {
 					return err
 				}
-				names := make([]string, 0, len(tags))
-				for name := range tags {
-					names = append(names, name)
-				}
-				sort.Strings(names)
-
-				for _, name := range names {
-					if showHash {
-						fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", tags[name], name)
-					} else {
-						fmt.Fprintln(cmd.OutOrStdout(), name)
-					}
+				if jsonOut {
+					return writeJSON(cmd.OutOrStdout(), jsonTagList(tags))
 				}
+				printTagList(cmd.OutOrStdout(), tags, showHash, lines)
 				return nil
 			}
+			if cmd.Flags().Changed("lines") || sortKey != "" || contains != "" {
+				return fmt.Errorf("-n, --sort and --contains only apply when listing tags")
+			}
 
 			name := args[0]
 			var target object.Hash
@@ -82,12 +103,18 @@ func newTagCmd() *cobra.Command {
 				}
 				tagIdentity := strings.TrimSpace(tagger)
 				if tagIdentity == "" {
-					tagIdentity = r.ResolveAuthor()
+					tagIdentity = r.ResolveCommitter()
 				}
-				_, err := r.CreateAnnotatedTag(name, target, tagIdentity, message, force)
+				tagHash, err := r.CreateAnnotatedTag(name, target, tagIdentity, message, force)
+				if err != nil || !jsonOut {
+					return err
+				}
+				return writeJSON(cmd.OutOrStdout(), JSONRefAction{Action: "created", Name: name, Target: string(tagHash)})
+			}
+			if err := r.CreateTag(name, target, force); err != nil || !jsonOut {
 				return err
 			}
-			return r.CreateTag(name, target, force)
+			return writeJSON(cmd.OutOrStdout(), JSONRefAction{Action: "created", Name: name, Target: string(target)})
 		},
 	}
 
@@ -97,6 +124,58 @@ func newTagCmd() *cobra.Command {
 	cmd.Flags().BoolVarP(&annotate, "annotate", "a", false, "create an annotated tag object")
 	cmd.Flags().StringVarP(&message, "message", "m", "", "tag message (implies --annotate)")
 	cmd.Flags().StringVar(&tagger, "tagger", "", "override tagger identity (default: $USER)")
+	cmd.Flags().IntVarP(&lines, "lines", "n", 0, "show up to this many lines of each tag's message when listing (-n alone shows one)")
+	cmd.Flags().Lookup("lines").NoOptDefVal = "1"
+	cmd.Flags().StringVar(&sortKey, "sort", "", "sort listed tags by refname, version:refname or creatordate (prefix - to reverse)")
+	cmd.Flags().StringVar(&contains, "contains", "", "list only tags whose commit contains this commit")
+	_ = cmd.RegisterFlagCompletionFunc("delete", completeTags(-1))
+	_ = cmd.RegisterFlagCompletionFunc("contains", completeRefs(-1))
 
 	return cmd
 }
+
+// jsonTagList converts listed tags to their JSON form.
+func jsonTagList(tags []repo.TagInfo) JSONTagOutput {
+	result := JSONTagOutput{Tags: make([]JSONTag, 0, len(tags))}
+	for _, t := range tags {
+		result.Tags = append(result.Tags, JSONTag{
+			Name:      t.Name,
+			Target:    string(t.Target),
+			Commit:    string(t.Commit),
+			Annotated: t.Annotated,
+			Tagger:    t.Tagger,
+			Timestamp: t.Timestamp,
+			Message:   t.Message,
+		})
+	}
+	return result
+}
+
+// printTagList prints one tag per line, with its target hash when showHash
+// is set and up to lines lines of its message, aligned after the names.
+func printTagList(w io.Writer, tags []repo.TagInfo, showHash bool, lines int) {
+	width := 0
+	for _, t := range tags {
+		width = max(width, len(t.Name))
+	}
+	for _, t := range tags {
+		name := t.Name
+		if showHash {
+			name = string(t.Target) + " " + name
+		}
+		if lines <= 0 {
+			fmt.Fprintln(w, name)
+			continue
+		}
+		msg := strings.Split(t.Message, "\n")
+		if len(msg) > lines {
+			msg = msg[:lines]
+		}
+		pad := width - len(t.Name)
+		fmt.Fprintln(w, strings.TrimRight(name+strings.Repeat(" ", pad)+" "+msg[0], " "))
+		indent := strings.Repeat(" ", len(name)+pad+1)
+		for _, line := range msg[1:] {
+			fmt.Fprintln(w, strings.TrimRight(indent+line, " "))
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTagListMessagesSortAndContainsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	dir := initRepo(t)
+	commitFile(t, dir, "notes.txt", "one\n", "first release")
+	mustRunGraft(t, dir, "tag", "v1.9")
+	commitFile(t, dir, "notes.txt", "two\n", "second release")
+	mustRunGraft(t, dir, "tag", "-m", "Big release\n\nwith notes", "v1.10")
+
+	if got := mustRunGraft(t, dir, "tag", "--sort=version:refname"); got != "v1.9\nv1.10\n" {
+		t.Fatalf("tag --sort=version:refname = %q", got)
+	}
+	want := "v1.10 Big release\nv1.9  first release\n"
+	if got := mustRunGraft(t, dir, "tag", "-n"); got != want {
+		t.Fatalf("tag -n = %q, want %q", got, want)
+	}
+	want = "v1.10 Big release\n\n      with notes\n"
+	if got := mustRunGraft(t, dir, "tag", "-n=3", "--contains", "HEAD"); got != want {
+		t.Fatalf("tag -n=3 --contains HEAD = %q, want %q", got, want)
+	}
+
+	out, err := runGraft(t, dir, "tag", "--sort=size")
+	if err == nil || !strings.Contains(out, "unsupported sort key") {
+		t.Fatalf("tag --sort=size = %q, %v; want an unsupported sort key error", out, err)
+	}
+}
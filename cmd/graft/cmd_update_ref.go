@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func newUpdateRefCmd() *cobra.Command {
+	var deleteRef, noDeref bool
+
+	cmd := &cobra.Command{
+		Use:   "update-ref [--no-deref] (<ref> <new> [<old>] | -d <ref> [<old>])",
+		Short: "Update or delete a ref safely",
+		Long: `Update-ref points a ref at the commit <new> resolves to. The ref must be
+HEAD or a full name under refs/, such as refs/heads/main.
+
+When <old> is given the update is a compare-and-swap: it only happens if
+the ref still points at <old>, and fails without changing anything
+otherwise. An <old> of all zeros requires the ref not to exist yet.
+
+With -d the ref is deleted instead, again only if it still points at
+<old> when given. HEAD cannot be deleted.
+
+Updating HEAD while it names a branch updates that branch; --no-deref
+detaches HEAD at <new> instead.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if deleteRef {
+				return cobra.RangeArgs(1, 2)(cmd, args)
+			}
+			return cobra.RangeArgs(2, 3)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			if name != "HEAD" && !strings.HasPrefix(name, "refs/") {
+				return fmt.Errorf("update-ref: %q is not a full ref name (HEAD or refs/...)", name)
+			}
+			if name == "HEAD" && !noDeref {
+				head, err := r.Head()
+				if err != nil {
+					return fmt.Errorf("update-ref: %w", err)
+				}
+				if strings.HasPrefix(head, "refs/") {
+					name = head
+				}
+			}
+
+			if deleteRef {
+				if name == "HEAD" {
+					return fmt.Errorf("update-ref: refusing to delete HEAD")
+				}
+				var old object.Hash
+				if len(args) == 2 {
+					old, err = resolveRefValue(r, args[1])
+				} else {
+					old, err = r.ResolveRef(name)
+				}
+				if err != nil {
+					return fmt.Errorf("update-ref: %w", err)
+				}
+				if err := r.DeleteRefCAS(name, old); err != nil {
+					return fmt.Errorf("update-ref: %w", err)
+				}
+				return nil
+			}
+
+			newHash, err := r.ResolveTreeish(args[1])
+			if err != nil {
+				return fmt.Errorf("update-ref: %w", err)
+			}
+			if len(args) == 3 {
+				var old object.Hash
+				old, err = resolveRefValue(r, args[2])
+				if err != nil {
+					return fmt.Errorf("update-ref: %w", err)
+				}
+				err = r.UpdateRefCAS(name, newHash, old)
+			} else {
+				err = r.UpdateRefCAS(name, newHash)
+			}
+			if err != nil {
+				return fmt.Errorf("update-ref: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&deleteRef, "delete", "d", false, "delete the ref")
+	cmd.Flags().BoolVar(&noDeref, "no-deref", false, "update HEAD itself rather than the branch it names")
+	return cmd
+}
+
+// resolveRefValue resolves the expected old value of a ref. All zeros stand
+// for a ref that must not exist and resolve to the empty hash.
+func resolveRefValue(r *repo.Repo, value string) (object.Hash, error) {
+	if value != "" && strings.Trim(value, "0") == "" {
+		return "", nil
+	}
+	return r.ResolveTreeish(value)
+}
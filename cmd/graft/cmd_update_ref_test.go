@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpdateRefIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "a\n", "first")
+	first := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD"))
+	commitFile(t, dir, "a.txt", "b\n", "second")
+	second := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "HEAD"))
+	zero := strings.Repeat("0", 64)
+
+	mustRunGraft(t, dir, "update-ref", "refs/heads/topic", first, zero)
+	if _, err := runGraft(t, dir, "update-ref", "refs/heads/topic", second, zero); err == nil {
+		t.Fatal("expected creating an existing ref to fail")
+	}
+	if _, err := runGraft(t, dir, "update-ref", "refs/heads/topic", second, second); err == nil {
+		t.Fatal("expected a stale old value to fail")
+	}
+	if got := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "topic")); got != first {
+		t.Fatalf("topic = %s after failed updates, want %s", got, first)
+	}
+	mustRunGraft(t, dir, "update-ref", "refs/heads/topic", second, first)
+	if got := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "topic")); got != second {
+		t.Fatalf("topic = %s, want %s", got, second)
+	}
+
+	if _, err := runGraft(t, dir, "update-ref", "-d", "refs/heads/topic", first); err == nil {
+		t.Fatal("expected delete with a stale old value to fail")
+	}
+	mustRunGraft(t, dir, "update-ref", "-d", "refs/heads/topic", second)
+	if _, err := runGraft(t, dir, "rev-parse", "topic"); err == nil {
+		t.Fatal("topic still resolves after delete")
+	}
+
+	// HEAD updates go through to the branch unless --no-deref.
+	mustRunGraft(t, dir, "update-ref", "HEAD", first)
+	if got := strings.TrimSpace(mustRunGraft(t, dir, "symbolic-ref", "--short", "HEAD")); got != "main" {
+		t.Fatalf("symbolic-ref --short HEAD = %q, want main", got)
+	}
+	if got := strings.TrimSpace(mustRunGraft(t, dir, "rev-parse", "main")); got != first {
+		t.Fatalf("main = %s, want %s", got, first)
+	}
+	mustRunGraft(t, dir, "update-ref", "--no-deref", "HEAD", second)
+	if _, err := runGraft(t, dir, "symbolic-ref", "HEAD"); err == nil {
+		t.Fatal("expected symbolic-ref to fail on a detached HEAD")
+	}
+
+	mustRunGraft(t, dir, "symbolic-ref", "HEAD", "refs/heads/main")
+	if got := strings.TrimSpace(mustRunGraft(t, dir, "symbolic-ref", "HEAD")); got != "refs/heads/main" {
+		t.Fatalf("symbolic-ref HEAD = %q, want refs/heads/main", got)
+	}
+	if _, err := runGraft(t, dir, "symbolic-ref", "HEAD", "main"); err == nil {
+		t.Fatal("expected symbolic-ref to reject a short name")
+	}
+}
@@ -12,6 +12,8 @@ import (
 func newVerifyCmd() *cobra.Command {
 	var signatures bool
 	var jsonFlag bool
+	var jobs int
+	var progress bool
 
 	cmd := &cobra.Command{
 		Use:   "verify",
@@ -28,19 +30,49 @@ func newVerifyCmd() *cobra.Command {
 			}
 
 			// Default: verify object store integrity.
-			report, err := r.Store.Verify()
+			opts := object.VerifyOptions{Workers: jobs}
+			if progress {
+				errOut := cmd.ErrOrStderr()
+				opts.Progress = func(p object.VerifyProgress) {
+					if p.Done == p.Total || p.Done%1000 == 0 {
+						label := p.Phase
+						if p.Pack != "" {
+							label = p.Pack
+						}
+						fmt.Fprintf(errOut, "verifying %s: %d/%d\n", label, p.Done, p.Total)
+					}
+				}
+			}
+			report, err := r.Store.VerifyWithOptions(opts)
 			if err != nil {
 				return err
 			}
 
 			if jsonFlag {
-				return writeJSON(cmd.OutOrStdout(), JSONVerifyOutput{
+				out := JSONVerifyOutput{
 					LooseObjects: report.LooseObjects,
 					PackFiles:    report.PackFiles,
 					PackObjects:  report.PackObjects,
-				})
+				}
+				for _, c := range report.Corrupt {
+					out.Corrupt = append(out.Corrupt, JSONVerifyCorruptObject{
+						Hash:     string(c.Hash),
+						Location: c.Location,
+						Error:    c.Err.Error(),
+					})
+				}
+				if err := writeJSON(cmd.OutOrStdout(), out); err != nil {
+					return err
+				}
+				return report.Err()
 			}
 
+			if len(report.Corrupt) > 0 {
+				for _, c := range report.Corrupt {
+					fmt.Fprintf(cmd.OutOrStdout(), "corrupt: %v\n", c.Err)
+				}
+				return report.Err()
+			}
 			fmt.Fprintf(
 				cmd.OutOrStdout(),
 				"ok: verified %d loose object(s), %d pack file(s), %d packed object(s)\n",
@@ -54,6 +86,8 @@ func newVerifyCmd() *cobra.Command {
 
 	cmd.Flags().BoolVar(&signatures, "signatures", false, "Verify commit signatures on current branch (up to 100)")
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "output in JSON format")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "number of objects to verify in parallel (0 = number of CPUs)")
+	cmd.Flags().BoolVar(&progress, "progress", false, "report verification progress on stderr")
 
 	// Add the "commit" subcommand.
 	cmd.AddCommand(newVerifyCommitCmd())
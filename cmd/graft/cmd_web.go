@@ -0,0 +1,644 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/diff"
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+// webLogLimit is the number of commits a log page shows unless ?n= asks
+// for another.
+const webLogLimit = 100
+
+func newWebCmd() *cobra.Command {
+	var addr string
+
+	cmd := &cobra.Command{
+		Use:   "web",
+		Short: "Browse history, entity diffs, blame and branches in a local web UI",
+		Long: `Web serves a read-only web UI for the repository on addr until
+interrupted. It shows the commit log of any ref, each commit with the
+entities (functions, types, methods) it added, removed, modified, moved or
+renamed next to its line diff, entity and line blame of files at HEAD,
+and the branches and tags with their tips.
+
+The UI is embedded in the binary and needs no network access. It listens
+on 127.0.0.1 by default; it has no authentication, so think twice before
+binding another interface. Requests must name the server by the address it
+is bound to or by localhost, which keeps web pages that rebind their own
+host name to 127.0.0.1 from reading the repository.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := repo.Open(".")
+			if err != nil {
+				return err
+			}
+			l, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			srv := &http.Server{Handler: newWebServer(r, l.Addr()), ReadHeaderTimeout: 10 * time.Second}
+			fmt.Fprintf(cmd.ErrOrStderr(), "graft web serving %s on http://%s/\n", r.RootDir, l.Addr())
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdownCtx)
+			}()
+			if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:7070", "address to listen on")
+	return cmd
+}
+
+// --- Pages ---
+
+// webCommit is one row of a log or branch listing.
+type webCommit struct {
+	Hash    string
+	Short   string
+	Subject string
+	Author  string
+	Date    string
+}
+
+type webLogPage struct {
+	Title   string
+	Ref     string
+	Commits []webCommit
+	More    bool
+	Next    int
+}
+
+// webEntityChange is one entity-level change of a file in a commit.
+type webEntityChange struct {
+	Type      string
+	Kind      string
+	Name      string
+	Signature string
+	Lines     string
+}
+
+// webFileDiff is one file changed by a commit. Structural is false when
+// the file's language has no entity support.
+type webFileDiff struct {
+	Path       string
+	Status     string
+	Structural bool
+	Changes    []webEntityChange
+	Hunks      []JSONDiffHunk
+}
+
+type webCommitPage struct {
+	Title   string
+	Commit  webCommit
+	Message string
+	Parents []webCommit
+	Files   []webFileDiff
+}
+
+// webEntityBlame is the last change of one entity of a blamed file.
+type webEntityBlame struct {
+	Key     string
+	Author  string
+	Hash    string
+	Short   string
+	Subject string
+}
+
+// webLineBlame is one blamed line. Hash and Author are empty for a line
+// from the same commit as the line above, so runs read as blocks.
+type webLineBlame struct {
+	Line    int
+	Content string
+	Hash    string
+	Short   string
+	Author  string
+}
+
+type webBlamePage struct {
+	Title    string
+	Path     string
+	Entities []webEntityBlame
+	Lines    []webLineBlame
+}
+
+// webRef is a branch or tag with its tip.
+type webRef struct {
+	Name    string
+	Current bool
+	Tip     webCommit
+}
+
+type webRefsPage struct {
+	Title    string
+	Branches []webRef
+	Tags     []webRef
+}
+
+type webFilesPage struct {
+	Title string
+	Files []string
+}
+
+type webErrorPage struct {
+	Title   string
+	Message string
+}
+
+// --- Server ---
+
+// webServer serves the web UI for one repository. Requests are handled
+// one at a time: the repository is shared and the UI has a single user.
+// The .mailmap file is reread for each request, so edits to it show up
+// without a restart.
+type webServer struct {
+	repo    *repo.Repo
+	mux     *http.ServeMux
+	addr    *net.TCPAddr
+	mu      sync.Mutex
+	mailmap *repo.Mailmap
+}
+
+// newWebServer serves r to requests for addr, the address it listens on.
+func newWebServer(r *repo.Repo, addr net.Addr) *webServer {
+	tcpAddr, _ := addr.(*net.TCPAddr)
+	s := &webServer{repo: r, mux: http.NewServeMux(), addr: tcpAddr}
+	s.mux.HandleFunc("GET /{$}", s.handleLog)
+	s.mux.HandleFunc("GET /commit/{hash}", s.handleCommit)
+	s.mux.HandleFunc("GET /blame/{path...}", s.handleBlame)
+	s.mux.HandleFunc("GET /branches", s.handleBranches)
+	s.mux.HandleFunc("GET /files", s.handleFiles)
+	return s
+}
+
+func (s *webServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !s.allowedHost(req.Host) {
+		s.fail(w, http.StatusForbidden, fmt.Errorf("unexpected host %q", req.Host))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mailmap, err := s.repo.Mailmap()
+	if err != nil {
+		s.fail(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.mailmap = mailmap
+	s.mux.ServeHTTP(w, req)
+}
+
+// allowedHost reports whether host, a request's Host header, names the
+// server: localhost or the IP address it is bound to, any IP address when
+// bound to all interfaces, at the port it listens on. The UI has no
+// authentication, so a page whose own host name resolves to 127.0.0.1
+// must not be able to read it.
+func (s *webServer) allowedHost(host string) bool {
+	if s.addr == nil {
+		return false
+	}
+	name, port, err := net.SplitHostPort(host)
+	if err != nil || port != strconv.Itoa(s.addr.Port) {
+		return false
+	}
+	if strings.EqualFold(name, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(name)
+	return ip != nil && (s.addr.IP.IsUnspecified() || ip.Equal(s.addr.IP))
+}
+
+func (s *webServer) render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := webTemplates.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *webServer) fail(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = webTemplates.ExecuteTemplate(w, "error", webErrorPage{Title: http.StatusText(status), Message: err.Error()})
+}
+
+// handleLog lists the commits of ?ref= (default HEAD), ?n= at a time.
+func (s *webServer) handleLog(w http.ResponseWriter, req *http.Request) {
+	ref := req.URL.Query().Get("ref")
+	if ref == "" {
+		ref = "HEAD"
+	}
+	limit := webLogLimit
+	if n, err := strconv.Atoi(req.URL.Query().Get("n")); err == nil && n > 0 {
+		limit = n
+	}
+	start, err := s.repo.ResolveTreeish(ref)
+	if err != nil {
+		s.fail(w, http.StatusNotFound, err)
+		return
+	}
+	// Ask for one more than shown to know whether there is more.
+	entries, err := s.repo.LogWithOptions(start, limit+1, repo.LogOptions{})
+	if err != nil {
+		s.fail(w, http.StatusInternalServerError, err)
+		return
+	}
+	page := webLogPage{Title: "Log of " + ref, Ref: ref, Next: limit + webLogLimit}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.More = true
+	}
+	for _, e := range entries {
+		page.Commits = append(page.Commits, s.newWebCommit(e.Hash, e.Commit))
+	}
+	s.render(w, "log", page)
+}
+
+// handleCommit shows a commit and its changes against its first parent.
+func (s *webServer) handleCommit(w http.ResponseWriter, req *http.Request) {
+	h, err := s.repo.ResolveTreeish(req.PathValue("hash"))
+	if err != nil {
+		s.fail(w, http.StatusNotFound, err)
+		return
+	}
+	commit, err := s.repo.Store.ReadCommit(h)
+	if err != nil {
+		s.fail(w, http.StatusNotFound, fmt.Errorf("read commit %s: %w", shortHash(h), err))
+		return
+	}
+	page := webCommitPage{
+		Title:   commitTitleStr(commit.Message),
+		Commit:  s.newWebCommit(h, commit),
+		Message: commit.Message,
+	}
+	for _, p := range commit.Parents {
+		if parent, err := s.repo.Store.ReadCommit(p); err == nil {
+			page.Parents = append(page.Parents, s.newWebCommit(p, parent))
+		}
+	}
+	if page.Files, err = s.commitFiles(commit); err != nil {
+		s.fail(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.render(w, "commit", page)
+}
+
+// commitFiles diffs each file commit changed against its first parent,
+// or against nothing for a root commit.
+func (s *webServer) commitFiles(commit *object.CommitObj) ([]webFileDiff, error) {
+	before := map[string]repo.TreeFileEntry{}
+	if len(commit.Parents) > 0 {
+		parent, err := s.repo.Store.ReadCommit(commit.Parents[0])
+		if err != nil {
+			return nil, fmt.Errorf("read parent %s: %w", shortHash(commit.Parents[0]), err)
+		}
+		if before, err = s.flattenTree(parent.TreeHash); err != nil {
+			return nil, err
+		}
+	}
+	after, err := s.flattenTree(commit.TreeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []webFileDiff
+	for _, change := range summarizeTreeChanges(before, after) {
+		p := change[2:]
+		oldData, err := s.readEntry(before, p)
+		if err != nil {
+			return nil, err
+		}
+		newData, err := s.readEntry(after, p)
+		if err != nil {
+			return nil, err
+		}
+		jsonFile := buildJSONDiffFile(p, oldData, newData)
+		file := webFileDiff{Path: p, Status: jsonFile.Status, Hunks: jsonFile.Hunks}
+		if fd, err := diff.StructuralDiff(p, orEmpty(oldData), orEmpty(newData)); err == nil {
+			file.Structural = true
+			for _, c := range fd.Changes {
+				file.Changes = append(file.Changes, newWebEntityChange(c))
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func (s *webServer) flattenTree(treeHash object.Hash) (map[string]repo.TreeFileEntry, error) {
+	entries, err := s.repo.FlattenTree(treeHash)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]repo.TreeFileEntry, len(entries))
+	for _, e := range entries {
+		files[e.Path] = e
+	}
+	return files, nil
+}
+
+// readEntry returns the content of p in entries, nil if it has no such
+// file.
+func (s *webServer) readEntry(entries map[string]repo.TreeFileEntry, p string) ([]byte, error) {
+	e, ok := entries[p]
+	if !ok {
+		return nil, nil
+	}
+	blob, err := s.repo.Store.ReadBlob(e.BlobHash)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", p, err)
+	}
+	return blob.Data, nil
+}
+
+// handleBlame shows the last change of each entity of a file at HEAD and
+// the commit that introduced each of its lines.
+func (s *webServer) handleBlame(w http.ResponseWriter, req *http.Request) {
+	p, err := rpcPath(req.PathValue("path"))
+	if err != nil {
+		s.fail(w, http.StatusBadRequest, err)
+		return
+	}
+	// Blame resolves relative paths against the working directory, so hand
+	// it an absolute one.
+	abs := filepath.Join(s.repo.RootDir, filepath.FromSlash(p))
+	lines, err := s.repo.BlameLines(abs, repo.BlameLinesOptions{})
+	if err != nil {
+		s.fail(w, http.StatusNotFound, err)
+		return
+	}
+	page := webBlamePage{Title: "Blame of " + p, Path: p}
+	var prev object.Hash
+	for _, l := range lines {
+		line := webLineBlame{Line: l.Line, Content: l.Content}
+		if l.CommitHash != prev {
+			line.Hash, line.Short, line.Author = string(l.CommitHash), shortHash(l.CommitHash), s.mailmap.Map(l.Author)
+			prev = l.CommitHash
+		}
+		page.Lines = append(page.Lines, line)
+	}
+	// Files without entity support have only line blame.
+	if entities, err := s.repo.BlameFile(abs, 200); err == nil {
+		for _, e := range entities {
+			page.Entities = append(page.Entities, webEntityBlame{
+				Key:     e.EntityKey,
+				Author:  s.mailmap.Map(e.Author),
+				Hash:    string(e.CommitHash),
+				Short:   shortHash(e.CommitHash),
+				Subject: commitTitleStr(e.Message),
+			})
+		}
+	}
+	s.render(w, "blame", page)
+}
+
+// handleBranches lists the branches and tags with their tips.
+func (s *webServer) handleBranches(w http.ResponseWriter, req *http.Request) {
+	branches, err := s.repo.ListBranches()
+	if err != nil {
+		s.fail(w, http.StatusInternalServerError, err)
+		return
+	}
+	current, _ := s.repo.CurrentBranch()
+	page := webRefsPage{Title: "Branches"}
+	for _, b := range branches {
+		ref, err := s.refTip(b, "refs/heads/"+b)
+		if err != nil {
+			continue
+		}
+		ref.Current = b == current
+		page.Branches = append(page.Branches, ref)
+	}
+	tags, err := s.repo.ListTags()
+	if err != nil {
+		s.fail(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, t := range tags {
+		if ref, err := s.refTip(t, "refs/tags/"+t); err == nil {
+			page.Tags = append(page.Tags, ref)
+		}
+	}
+	s.render(w, "branches", page)
+}
+
+// refTip resolves refName, peeling annotated tags, to its commit.
+func (s *webServer) refTip(name, refName string) (webRef, error) {
+	h, err := s.repo.ResolveTreeish(refName)
+	if err != nil {
+		return webRef{}, err
+	}
+	commit, err := s.repo.Store.ReadCommit(h)
+	if err != nil {
+		return webRef{}, err
+	}
+	return webRef{Name: name, Tip: s.newWebCommit(h, commit)}, nil
+}
+
+// handleFiles lists the files at HEAD, each linking to its blame.
+func (s *webServer) handleFiles(w http.ResponseWriter, req *http.Request) {
+	head, err := s.repo.ResolveRef("HEAD")
+	if err != nil {
+		s.fail(w, http.StatusNotFound, err)
+		return
+	}
+	commit, err := s.repo.Store.ReadCommit(head)
+	if err != nil {
+		s.fail(w, http.StatusInternalServerError, err)
+		return
+	}
+	entries, err := s.repo.FlattenTree(commit.TreeHash)
+	if err != nil {
+		s.fail(w, http.StatusInternalServerError, err)
+		return
+	}
+	page := webFilesPage{Title: "Files"}
+	for _, e := range entries {
+		page.Files = append(page.Files, e.Path)
+	}
+	sort.Strings(page.Files)
+	s.render(w, "files", page)
+}
+
+func (s *webServer) newWebCommit(h object.Hash, commit *object.CommitObj) webCommit {
+	return webCommit{
+		Hash:    string(h),
+		Short:   shortHash(h),
+		Subject: commitTitleStr(commit.Message),
+		Author:  s.mailmap.Map(commit.Author),
+		Date:    time.Unix(commit.Timestamp, 0).Format("2006-01-02 15:04"),
+	}
+}
+
+func newWebEntityChange(c diff.EntityChange) webEntityChange {
+	e := c.After
+	if e == nil {
+		e = c.Before
+	}
+	change := webEntityChange{Type: c.Type.String(), Name: c.Key}
+	if e == nil {
+		return change
+	}
+	change.Kind = e.DeclKind
+	if change.Kind == "" {
+		change.Kind = e.Kind.String()
+	}
+	if e.Name != "" {
+		change.Name = e.Name
+		if e.Receiver != "" {
+			change.Name = e.Receiver + "." + e.Name
+		}
+	}
+	if e.Kind == entity.KindDeclaration {
+		change.Signature = e.Signature
+	}
+	change.Lines = fmt.Sprintf("L%d-%d", e.StartLine, e.EndLine)
+	return change
+}
+
+func orEmpty(data []byte) []byte {
+	if data == nil {
+		return []byte{}
+	}
+	return data
+}
+
+// --- Templates ---
+
+var webTemplates = template.Must(template.New("web").Parse(`
+{{define "header"}}<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} · graft</title>
+<style>
+body { font: 14px/1.45 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #1f2328; }
+nav { background: #24292f; padding: 10px 24px; }
+nav a { color: #fff; margin-right: 18px; text-decoration: none; font-weight: 600; }
+main { padding: 16px 24px; max-width: 1200px; }
+a { color: #0969da; text-decoration: none; }
+a:hover { text-decoration: underline; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 16px; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #d0d7de; vertical-align: top; }
+code, pre, .mono { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 12px; }
+pre { margin: 0; white-space: pre-wrap; }
+.muted { color: #656d76; }
+.file { border: 1px solid #d0d7de; border-radius: 6px; margin-bottom: 16px; }
+.file h3 { margin: 0; padding: 8px 12px; background: #f6f8fa; border-bottom: 1px solid #d0d7de; font-size: 14px; }
+.file table { margin: 0; }
+.badge { display: inline-block; padding: 0 6px; border-radius: 10px; font-size: 12px; font-weight: 600; }
+.added { background: #dafbe1; } .removed, .deleted { background: #ffebe9; }
+.modified { background: #fff8c5; } .moved, .renamed { background: #ddf4ff; }
+.hunk { background: #ddf4ff; color: #656d76; }
+.add { background: #e6ffec; } .delete { background: #ffebe9; }
+.blame td { border: none; padding: 0 8px; }
+.blame tr.start td { border-top: 1px solid #d0d7de; }
+</style>
+</head>
+<body>
+<nav><a href="/">Log</a><a href="/branches">Branches</a><a href="/files">Files</a></nav>
+<main>
+{{end}}
+
+{{define "footer"}}</main>
+</body>
+</html>
+{{end}}
+
+{{define "commits"}}<table>
+<tr><th>Commit</th><th>Subject</th><th>Author</th><th>Date</th></tr>
+{{range .}}<tr><td class="mono"><a href="/commit/{{.Hash}}">{{.Short}}</a></td><td>{{.Subject}}</td><td>{{.Author}}</td><td class="muted">{{.Date}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{define "log"}}{{template "header" .}}
+<h2>{{.Ref}}</h2>
+{{template "commits" .Commits}}
+{{if .More}}<p><a href="/?ref={{.Ref}}&amp;n={{.Next}}">More commits</a></p>{{end}}
+{{template "footer" .}}{{end}}
+
+{{define "commit"}}{{template "header" .}}
+<h2>{{.Commit.Subject}}</h2>
+<p class="mono">commit {{.Commit.Hash}}</p>
+{{range .Parents}}<p class="mono muted">parent <a href="/commit/{{.Hash}}">{{.Short}}</a> {{.Subject}}</p>
+{{end}}<p>{{.Commit.Author}} <span class="muted">{{.Commit.Date}}</span></p>
+<pre>{{.Message}}</pre>
+<h3>{{len .Files}} file(s) changed</h3>
+{{range .Files}}<div class="file">
+<h3><span class="badge {{.Status}}">{{.Status}}</span> <a href="/blame/{{.Path}}">{{.Path}}</a></h3>
+{{if .Structural}}{{if .Changes}}<table>
+<tr><th>Entity change</th><th>Kind</th><th>Name</th><th>Lines</th></tr>
+{{range .Changes}}<tr><td><span class="badge {{.Type}}">{{.Type}}</span></td><td>{{.Kind}}</td><td class="mono">{{.Name}}{{if .Signature}}<div class="muted">{{.Signature}}</div>{{end}}</td><td class="mono muted">{{.Lines}}</td></tr>
+{{end}}</table>
+{{else}}<p class="muted" style="padding: 0 12px">No entity changes.</p>
+{{end}}{{end}}<table class="mono">
+{{range .Hunks}}<tr class="hunk"><td><pre>@@ -{{.OldStart}},{{.OldCount}} +{{.NewStart}},{{.NewCount}} @@</pre></td></tr>
+{{range .Lines}}<tr class="{{.Type}}"><td><pre>{{if eq .Type "add"}}+{{else if eq .Type "delete"}}-{{else}} {{end}}{{.Content}}</pre></td></tr>
+{{end}}{{end}}</table>
+</div>
+{{end}}
+{{template "footer" .}}{{end}}
+
+{{define "blame"}}{{template "header" .}}
+<h2>{{.Path}}</h2>
+{{if .Entities}}<h3>Entities</h3>
+<table>
+<tr><th>Entity</th><th>Last changed</th><th>Author</th><th>Subject</th></tr>
+{{range .Entities}}<tr><td class="mono">{{.Key}}</td><td class="mono"><a href="/commit/{{.Hash}}">{{.Short}}</a></td><td>{{.Author}}</td><td>{{.Subject}}</td></tr>
+{{end}}</table>
+{{end}}<h3>Lines</h3>
+<table class="blame mono">
+{{range .Lines}}<tr{{if .Hash}} class="start"{{end}}><td>{{if .Hash}}<a href="/commit/{{.Hash}}">{{.Short}}</a>{{end}}</td><td class="muted">{{.Author}}</td><td class="muted">{{.Line}}</td><td><pre>{{.Content}}</pre></td></tr>
+{{end}}</table>
+{{template "footer" .}}{{end}}
+
+{{define "branches"}}{{template "header" .}}
+<h2>Branches</h2>
+<table>
+<tr><th>Branch</th><th>Tip</th><th>Subject</th><th>Date</th></tr>
+{{range .Branches}}<tr><td><a href="/?ref={{.Name}}">{{.Name}}</a>{{if .Current}} <span class="badge added">current</span>{{end}}</td><td class="mono"><a href="/commit/{{.Tip.Hash}}">{{.Tip.Short}}</a></td><td>{{.Tip.Subject}}</td><td class="muted">{{.Tip.Date}}</td></tr>
+{{end}}</table>
+{{if .Tags}}<h2>Tags</h2>
+<table>
+<tr><th>Tag</th><th>Commit</th><th>Subject</th><th>Date</th></tr>
+{{range .Tags}}<tr><td><a href="/?ref={{.Name}}">{{.Name}}</a></td><td class="mono"><a href="/commit/{{.Tip.Hash}}">{{.Tip.Short}}</a></td><td>{{.Tip.Subject}}</td><td class="muted">{{.Tip.Date}}</td></tr>
+{{end}}</table>
+{{end}}{{template "footer" .}}{{end}}
+
+{{define "files"}}{{template "header" .}}
+<h2>Files at HEAD</h2>
+<table class="mono">
+{{range .Files}}<tr><td><a href="/blame/{{.}}">{{.}}</a></td></tr>
+{{end}}</table>
+{{template "footer" .}}{{end}}
+
+{{define "error"}}{{template "header" .}}
+<h2>{{.Title}}</h2>
+<p>{{.Message}}</p>
+{{template "footer" .}}{{end}}
+`))
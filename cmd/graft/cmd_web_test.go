@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+func TestWebServer(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "main.go"), []byte("package main\n\nfunc a() int {\n\treturn 1\n}\n"))
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("initial", "tester"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "main.go"), []byte("package main\n\nfunc a() int {\n\treturn 2\n}\n\nfunc helper() {}\n"))
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	head, err := r.Commit("add helper", "tester")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config.Handler = newWebServer(r, srv.Listener.Addr())
+	srv.Start()
+	defer srv.Close()
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	for _, tc := range []struct {
+		path string
+		want []string
+	}{
+		{"/", []string{"add helper", "initial", "/commit/" + string(head)}},
+		{"/commit/" + string(head), []string{"main.go", "helper", "added", "modified", "+func helper() {}"}},
+		{"/blame/main.go", []string{"return 2", shortHash(head)}},
+		{"/branches", []string{"main", "current", "add helper"}},
+		{"/files", []string{"/blame/main.go"}},
+	} {
+		code, body := get(tc.path)
+		if code != http.StatusOK {
+			t.Fatalf("GET %s = %d\n%s", tc.path, code, body)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(body, want) {
+				t.Errorf("GET %s: body does not contain %q\n%s", tc.path, want, body)
+			}
+		}
+	}
+
+	// Only requests naming the server itself are served.
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	for host, want := range map[string]int{
+		"localhost:" + port:           http.StatusOK,
+		"rebound.example.com:" + port: http.StatusForbidden,
+		"localhost:1":                 http.StatusForbidden,
+		"10.0.0.1:" + port:            http.StatusForbidden,
+	} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/branches", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET with Host %s: %v", host, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET with Host %s = %d, want %d", host, resp.StatusCode, want)
+		}
+	}
+
+	for _, path := range []string{"/commit/nope", "/?ref=nope", "/blame/missing.go"} {
+		if code, _ := get(path); code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want %d", path, code, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+// Shell completion for arguments that name things in the repository. Each
+// function here is a cobra.CompletionFunc, or builds one, and is set as a
+// command's ValidArgsFunction. They open the repository in the current
+// directory and offer nothing when that fails. Those taking maxArgs
+// complete only the first maxArgs arguments, or every argument, and flag
+// values, when maxArgs is negative.
+
+// completeRefs completes branch, remote-tracking branch and tag names. A
+// word such as "main.." completes the ref after the dots.
+func completeRefs(maxArgs int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if completedArgs(args, maxArgs) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		r, err := repo.Open(".")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		prefix := ""
+		if i := strings.LastIndex(toComplete, ".."); i >= 0 {
+			prefix, toComplete = toComplete[:i+2], toComplete[i+2:]
+			if strings.HasPrefix(toComplete, ".") {
+				prefix, toComplete = prefix+".", toComplete[1:]
+			}
+		}
+		var names []string
+		names = append(names, completionBranches(r)...)
+		names = append(names, completionRemoteBranches(r, "")...)
+		if tags, err := r.ListTags(); err == nil {
+			names = append(names, tags...)
+		}
+		return completionMatches(names, prefix, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeBranches completes local branch names.
+func completeBranches(maxArgs int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if completedArgs(args, maxArgs) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		r, err := repo.Open(".")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completionMatches(completionBranches(r), "", toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeTags completes tag names.
+func completeTags(maxArgs int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if completedArgs(args, maxArgs) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		r, err := repo.Open(".")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		tags, _ := r.ListTags()
+		return completionMatches(tags, "", toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeRemotes completes remote names.
+func completeRemotes(maxArgs int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if completedArgs(args, maxArgs) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		r, err := repo.Open(".")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completionMatches(completionRemotes(r), "", toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeRemoteAndBranch completes a remote name, then branch names: the
+// branches the remote is known to have, followed by the local ones. It
+// suits push and pull.
+func completeRemoteAndBranch(maxArgs int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if completedArgs(args, maxArgs) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		r, err := repo.Open(".")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if len(args) == 0 {
+			return completionMatches(completionRemotes(r), "", toComplete), cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for _, name := range completionRemoteBranches(r, args[0]) {
+			names = append(names, strings.TrimPrefix(name, args[0]+"/"))
+		}
+		names = append(names, completionBranches(r)...)
+		return completionMatches(names, "", toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeTrackedPaths completes the paths of tracked files, relative to
+// the current directory, one directory level at a time.
+func completeTrackedPaths(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	r, err := repo.Open(".")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	base, err := filepath.Rel(r.RootDir, cwd)
+	if err != nil || base == ".." || strings.HasPrefix(base, ".."+string(filepath.Separator)) {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	base = filepath.ToSlash(base)
+	if base == "." {
+		base = ""
+	} else {
+		base += "/"
+	}
+
+	seen := make(map[string]bool)
+	var matches []string
+	directive := cobra.ShellCompDirectiveNoFileComp
+	for path := range stg.Entries {
+		rel, ok := strings.CutPrefix(path, base)
+		if !ok || !strings.HasPrefix(rel, toComplete) {
+			continue
+		}
+		// Stop at the next directory so that large trees complete level
+		// by level, as file completion does.
+		if i := strings.IndexByte(rel[len(toComplete):], '/'); i >= 0 {
+			rel = rel[:len(toComplete)+i+1]
+		}
+		if seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		matches = append(matches, rel)
+	}
+	sort.Strings(matches)
+	if len(matches) == 1 && strings.HasSuffix(matches[0], "/") {
+		directive |= cobra.ShellCompDirectiveNoSpace
+	}
+	return matches, directive
+}
+
+// completeRefsThenPaths completes refs before a "--" separator and tracked
+// paths after it.
+func completeRefsThenPaths(maxArgs int) cobra.CompletionFunc {
+	refs := completeRefs(maxArgs)
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if completionAfterDash(cmd) {
+			return completeTrackedPaths(cmd, args, toComplete)
+		}
+		return refs(cmd, args, toComplete)
+	}
+}
+
+type completionArgsKey struct{}
+
+// recordCompletionArgs keeps the words handed to cobra's completion command
+// on its context, which cobra passes on to the command being completed.
+// Cobra parses that command's flags with a "--" appended, so its
+// ArgsLenAtDash cannot tell whether the user typed one.
+func recordCompletionArgs(cmd *cobra.Command, args []string) {
+	if cmd.Name() == cobra.ShellCompRequestCmd {
+		cmd.SetContext(context.WithValue(cmd.Context(), completionArgsKey{}, args))
+	}
+}
+
+// completionAfterDash reports whether the word being completed follows a
+// "--" the user typed.
+func completionAfterDash(cmd *cobra.Command) bool {
+	ctx := cmd.Context()
+	if ctx == nil {
+		return false
+	}
+	words, _ := ctx.Value(completionArgsKey{}).([]string)
+	return len(words) > 0 && slices.Contains(words[:len(words)-1], "--")
+}
+
+// completedArgs reports whether args already holds the maxArgs arguments
+// a command takes, so that nothing more should be offered.
+func completedArgs(args []string, maxArgs int) bool {
+	return maxArgs >= 0 && len(args) >= maxArgs
+}
+
+// completionBranches returns the local branch names, or nil when they
+// cannot be listed.
+func completionBranches(r *repo.Repo) []string {
+	branches, err := r.ListBranches()
+	if err != nil {
+		return nil
+	}
+	return branches
+}
+
+// completionRemoteBranches returns remote-tracking branches as
+// "<remote>/<branch>", only those of remote when it is not empty.
+func completionRemoteBranches(r *repo.Repo, remote string) []string {
+	prefix := "remotes"
+	if remote != "" {
+		prefix += "/" + remote
+	}
+	refs, err := r.ListRefs(prefix)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		name = strings.TrimPrefix(name, "remotes/")
+		if strings.HasSuffix(name, "/HEAD") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completionRemotes returns the configured remote names, sorted.
+func completionRemotes(r *repo.Repo) []string {
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(cfg.Remotes))
+	for name := range cfg.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completionMatches returns the names starting with toComplete, each
+// prefixed with prefix, without duplicates.
+func completionMatches(names []string, prefix, toComplete string) []cobra.Completion {
+	seen := make(map[string]bool, len(names))
+	var out []cobra.Completion
+	for _, name := range names {
+		if seen[name] || !strings.HasPrefix(name, toComplete) {
+			continue
+		}
+		seen[name] = true
+		out = append(out, prefix+name)
+	}
+	return out
+}
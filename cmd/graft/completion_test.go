@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func TestDynamicCompletion(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "pkg", "util"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "main.go"), []byte("package main\n"))
+	writeTestFile(t, filepath.Join(dir, "pkg", "util", "util.go"), []byte("package util\n"))
+	if err := r.Add([]string{"main.go", "pkg/util/util.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	head, err := r.Commit("initial", "tester")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := r.CreateBranch("feature/login", head); err != nil {
+		t.Fatalf("CreateBranch: %v", err)
+	}
+	if err := r.CreateTag("v1.0.0", head, false); err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	if err := r.SetRemote("origin", "https://example.com/repo"); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+	if err := r.UpdateRef("refs/remotes/origin/release", head); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	complete := func(cmd *cobra.Command, args ...string) []string {
+		t.Helper()
+		var out bytes.Buffer
+		root := newJSONTestRoot(&out, cmd)
+		root.SetArgs(append([]string{cobra.ShellCompRequestCmd}, args...))
+		if err := root.Execute(); err != nil {
+			t.Fatalf("complete %q: %v", args, err)
+		}
+		// The last line holds the directive.
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		return lines[:len(lines)-1]
+	}
+
+	tests := []struct {
+		cmd  *cobra.Command
+		args []string
+		want []string
+	}{
+		{newCheckoutCmd(), []string{"checkout", ""}, []string{"feature/login", "main", "origin/release", "v1.0.0"}},
+		{newMergeCmd(), []string{"merge", "f"}, []string{"feature/login"}},
+		{newMergeCmd(), []string{"merge", "main", ""}, nil},
+		{newDiffCmd(), []string{"diff", "main..v"}, []string{"main..v1.0.0"}},
+		{newDiffCmd(), []string{"diff", "--", "p"}, []string{"pkg/"}},
+		{newRmCmd(), []string{"rm", ""}, []string{"main.go", "pkg/"}},
+		{newRmCmd(), []string{"rm", "pkg/util/"}, []string{"pkg/util/util.go"}},
+		{newPullCmd(), []string{"pull", ""}, []string{"origin"}},
+		{newPullCmd(), []string{"pull", "origin", "r"}, []string{"release"}},
+		{newBranchCmd(), []string{"branch", "-d", "fe"}, []string{"feature/login"}},
+	}
+	for _, tt := range tests {
+		got := complete(tt.cmd, tt.args...)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("complete %q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	// Paths are relative to the current directory.
+	if err := os.Chdir(filepath.Join(dir, "pkg")); err != nil {
+		t.Fatal(err)
+	}
+	if got := complete(newRmCmd(), "rm", ""); strings.Join(got, ",") != "util/" {
+		t.Errorf("complete rm in pkg = %q, want [util/]", got)
+	}
+}
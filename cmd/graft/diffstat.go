@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/diff3"
+)
+
+// diffstatBarWidth is the widest the +/- bar of --stat grows; longer bars
+// are scaled down to it.
+const diffstatBarWidth = 40
+
+// diffSummary collects each changed file in place of printing its diff,
+// and prints a summary once all are in. --stat and --dirstat implement it.
+type diffSummary interface {
+	add(path string, before, after []byte)
+	write(w io.Writer)
+}
+
+// diffstat accumulates the lines added and removed per file for --stat.
+type diffstat struct {
+	files []JSONDiffStatFile
+}
+
+// add records the change from before to after at p. Unchanged files are
+// ignored.
+func (d *diffstat) add(p string, before, after []byte) {
+	if bytes.Equal(before, after) {
+		return
+	}
+	f := JSONDiffStatFile{Path: p}
+	if bytes.IndexByte(before, 0) >= 0 || bytes.IndexByte(after, 0) >= 0 {
+		f.Binary = true
+	} else {
+		for _, l := range diff3.LineDiff(before, after) {
+			switch l.Type {
+			case diff3.Insert:
+				f.Insertions++
+			case diff3.Delete:
+				f.Deletions++
+			}
+		}
+	}
+	d.files = append(d.files, f)
+}
+
+// summary returns the recorded files with their totals.
+func (d *diffstat) summary() JSONDiffStatOutput {
+	out := JSONDiffStatOutput{Files: d.files, FilesChanged: len(d.files)}
+	if out.Files == nil {
+		out.Files = []JSONDiffStatFile{}
+	}
+	for _, f := range d.files {
+		out.Insertions += f.Insertions
+		out.Deletions += f.Deletions
+	}
+	return out
+}
+
+// write prints one line per file with its count of changed lines and a
+// bar of + and -, then the totals, as git diff --stat does.
+func (d *diffstat) write(w io.Writer) {
+	if len(d.files) == 0 {
+		return
+	}
+	nameWidth, maxChanges := 0, 0
+	for _, f := range d.files {
+		nameWidth = max(nameWidth, len(f.Path))
+		maxChanges = max(maxChanges, f.Insertions+f.Deletions)
+	}
+	countWidth := len(fmt.Sprint(maxChanges))
+	for _, f := range d.files {
+		if f.Binary {
+			fmt.Fprintf(w, " %-*s | %*s\n", nameWidth, f.Path, countWidth, "Bin")
+			continue
+		}
+		plus, minus := f.Insertions, f.Deletions
+		if maxChanges > diffstatBarWidth {
+			plus = scaleDiffstat(plus, maxChanges)
+			minus = scaleDiffstat(minus, maxChanges)
+		}
+		bar := strings.Repeat("+", plus) + strings.Repeat("-", minus)
+		fmt.Fprintf(w, " %-*s | %*d %s\n", nameWidth, f.Path, countWidth, f.Insertions+f.Deletions, bar)
+	}
+
+	s := d.summary()
+	line := " " + pluralize(s.FilesChanged, "file") + " changed"
+	if s.Insertions > 0 || s.Deletions == 0 {
+		line += ", " + pluralize(s.Insertions, "insertion") + "(+)"
+	}
+	if s.Deletions > 0 || s.Insertions == 0 {
+		line += ", " + pluralize(s.Deletions, "deletion") + "(-)"
+	}
+	fmt.Fprintln(w, line)
+}
+
+// scaleDiffstat scales n changed lines to the bar width, keeping at least
+// one mark for a non-zero count.
+func scaleDiffstat(n, maxChanges int) int {
+	if n == 0 {
+		return 0
+	}
+	return max(1, n*diffstatBarWidth/maxChanges)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/diff3"
+)
+
+// defaultDirstatLimit is the share of all changes, in percent, below which
+// --dirstat leaves a directory out.
+const defaultDirstatLimit = 3.0
+
+// binaryDirstatChunk is the number of bytes of a binary file that count as
+// one changed line.
+const binaryDirstatChunk = 64
+
+// dirstat accumulates per-file change counts for --dirstat and prints the
+// share of the changes made in each directory.
+type dirstat struct {
+	limit      float64
+	cumulative bool
+	files      bool
+	damage     map[string]int
+}
+
+// parseDirstat parses a --dirstat value: comma-separated parameters, each
+// a limit in percent, "cumulative", "lines" (the default) or "files".
+func parseDirstat(value string) (*dirstat, error) {
+	d := &dirstat{limit: defaultDirstatLimit, damage: make(map[string]int)}
+	for _, param := range strings.Split(value, ",") {
+		switch param = strings.TrimSpace(param); param {
+		case "":
+		case "cumulative":
+			d.cumulative = true
+		case "noncumulative":
+			d.cumulative = false
+		case "lines", "changes":
+			d.files = false
+		case "files":
+			d.files = true
+		default:
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil || limit < 0 || limit > 100 {
+				return nil, fmt.Errorf("--dirstat: unknown parameter %q (want a percentage, cumulative, lines or files)", param)
+			}
+			d.limit = limit
+		}
+	}
+	return d, nil
+}
+
+// add records the change from before to after at p. Unchanged files are
+// ignored.
+func (d *dirstat) add(p string, before, after []byte) {
+	if bytes.Equal(before, after) {
+		return
+	}
+	n := 1
+	switch {
+	case d.files:
+	case bytes.IndexByte(before, 0) >= 0 || bytes.IndexByte(after, 0) >= 0:
+		n = max(1, (len(before)+len(after))/binaryDirstatChunk)
+	default:
+		n = 0
+		for _, l := range diff3.LineDiff(before, after) {
+			if l.Type != diff3.Equal {
+				n++
+			}
+		}
+		n = max(n, 1)
+	}
+	d.damage[p] += n
+}
+
+// write prints one line per directory holding at least the limit's share
+// of all changes, as a percentage of the total. Unless cumulative, a
+// directory that is listed does not count again toward its parent, and
+// files directly in the repository root are not listed.
+func (d *dirstat) write(w io.Writer) {
+	total := 0
+	for _, n := range d.damage {
+		total += n
+	}
+	if total == 0 {
+		return
+	}
+
+	// Sum the changes under each directory, deepest directories first so
+	// that a directory sees its subdirectories' final counts.
+	own := make(map[string]int)
+	all := make(map[string]int)
+	var dirs []string
+	for p, n := range d.damage {
+		dir := path.Dir(p)
+		own[dir] += n
+		for ; dir != "."; dir = path.Dir(dir) {
+			if _, ok := all[dir]; !ok {
+				dirs = append(dirs, dir)
+			}
+			all[dir] += n
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		di, dj := strings.Count(dirs[i], "/"), strings.Count(dirs[j], "/")
+		if di != dj {
+			return di > dj
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	shown := make(map[string]int)
+	rolled := make(map[string]int)
+	for _, dir := range dirs {
+		damage := all[dir]
+		if !d.cumulative {
+			damage = own[dir] + rolled[dir]
+		}
+		if float64(damage)*100 >= d.limit*float64(total) {
+			shown[dir] = damage
+			if !d.cumulative {
+				damage = 0
+			}
+		}
+		if parent := path.Dir(dir); parent != "." {
+			rolled[parent] += damage
+		}
+	}
+
+	listed := make([]string, 0, len(shown))
+	for dir := range shown {
+		listed = append(listed, dir)
+	}
+	sort.Strings(listed)
+	for _, dir := range listed {
+		permille := shown[dir] * 1000 / total
+		fmt.Fprintf(w, "%4d.%01d%% %s/\n", permille/10, permille%10, dir)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDirstatRollsSmallDirectoriesIntoParents(t *testing.T) {
+	damage := map[string]int{"a/b/x.go": 10, "a/y.go": 2, "c/z.go": 85, "root.txt": 3}
+	tests := []struct {
+		params string
+		want   string
+	}{
+		{"", "  10.0% a/b/\n  85.0% c/\n"},
+		{"cumulative", "  12.0% a/\n  10.0% a/b/\n  85.0% c/\n"},
+		{"11", "  12.0% a/\n  85.0% c/\n"},
+		{"90", ""},
+	}
+	for _, tt := range tests {
+		d, err := parseDirstat(tt.params)
+		if err != nil {
+			t.Fatalf("parseDirstat(%q): %v", tt.params, err)
+		}
+		d.damage = damage
+		var buf bytes.Buffer
+		d.write(&buf)
+		if buf.String() != tt.want {
+			t.Errorf("--dirstat=%s:\n%q\nwant\n%q", tt.params, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestDirstatCountsLinesOrFiles(t *testing.T) {
+	d, err := parseDirstat("files,0")
+	if err != nil {
+		t.Fatalf("parseDirstat: %v", err)
+	}
+	d.add("a/f", []byte("1\n2\n3\n"), []byte("1\nx\ny\n"))
+	d.add("b/g", nil, []byte("new\n"))
+	d.add("b/same", []byte("s\n"), []byte("s\n"))
+	var buf bytes.Buffer
+	d.write(&buf)
+	if want := "  50.0% a/\n  50.0% b/\n"; buf.String() != want {
+		t.Fatalf("--dirstat=files =\n%q\nwant\n%q", buf.String(), want)
+	}
+
+	lines, _ := parseDirstat("lines,0")
+	lines.add("a/f", []byte("1\n2\n3\n"), []byte("1\nx\ny\n"))
+	lines.add("b/g", nil, []byte("new\n"))
+	buf.Reset()
+	lines.write(&buf)
+	if want := "  80.0% a/\n  20.0% b/\n"; buf.String() != want {
+		t.Fatalf("--dirstat=lines =\n%q\nwant\n%q", buf.String(), want)
+	}
+
+	if _, err := parseDirstat("sideways"); err == nil || !strings.Contains(err.Error(), "sideways") {
+		t.Fatalf("parseDirstat(sideways) error = %v", err)
+	}
+}
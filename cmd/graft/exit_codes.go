@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+// Exit statuses of graft commands. Scripts may rely on them.
+const (
+	// exitFailure ends a command that failed, and one whose --exit-code or
+	// --quiet found differences: a dirty status, a non-empty diff.
+	exitFailure = 1
+	// exitConflict ends a merge, pull, rebase, cherry-pick, revert, apply,
+	// am or stash apply that stopped on conflicts needing resolution.
+	exitConflict = 2
+)
+
+// exitStatus is returned by a command that has reported its outcome and
+// only needs the process to end with a particular status. main prints
+// nothing for it.
+type exitStatus int
+
+func (e exitStatus) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+
+// ExitCode returns the status the process ends with.
+func (e exitStatus) ExitCode() int { return int(e) }
+
+// exitCode returns the status the process ends with after a command
+// returned err: the status err carries, exitConflict for conflict errors,
+// and exitFailure otherwise.
+func exitCode(err error) int {
+	var exitCoder interface{ ExitCode() int }
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+	if isConflictError(err) {
+		return exitConflict
+	}
+	return exitFailure
+}
+
+// isConflictError reports whether err means an operation stopped, or
+// refused to continue, because of unresolved conflicts.
+func isConflictError(err error) bool {
+	var (
+		cherryPick *repo.ErrCherryPickConflict
+		rebase     *repo.ErrRebaseConflict
+		revert     *repo.ErrRevertConflict
+		apply      *repo.ErrApplyConflict
+	)
+	return errors.Is(err, repo.ErrMergeConflicts) ||
+		errors.As(err, &cherryPick) || errors.As(err, &rebase) ||
+		errors.As(err, &revert) || errors.As(err, &apply)
+}
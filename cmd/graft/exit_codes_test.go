@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), exitFailure},
+		{exitStatus(exitConflict), exitConflict},
+		{fmt.Errorf("pull: merge stopped with %w", repo.ErrMergeConflicts), exitConflict},
+		{&repo.ErrCherryPickConflict{}, exitConflict},
+		{&repo.ErrApplyConflict{Paths: []string{"a.go"}}, exitConflict},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestStatusAndDiffExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "file.txt"), []byte("one\n"))
+	if err := r.Add([]string{"file.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("initial", "tester"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	run := func(cmd *cobra.Command, args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd.SilenceUsage = true
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// A clean tree exits with 0.
+	if _, err := run(newStatusCmd(), "--porcelain", "--exit-code"); err != nil {
+		t.Fatalf("clean status --exit-code: %v", err)
+	}
+	if out, err := run(newDiffCmd(), "--quiet"); err != nil || out != "" {
+		t.Fatalf("clean diff --quiet = %q, %v", out, err)
+	}
+
+	// A modified file exits with 1; --quiet prints nothing.
+	writeTestFile(t, filepath.Join(dir, "file.txt"), []byte("one\ntwo\n"))
+	if out, err := run(newStatusCmd(), "--porcelain", "--exit-code"); exitCode(err) != exitFailure || out != " M file.txt\n" {
+		t.Fatalf("dirty status --exit-code = %q, %v; want the entry and status 1", out, err)
+	}
+	if out, err := run(newDiffCmd(), "--quiet"); exitCode(err) != exitFailure || out != "" {
+		t.Fatalf("dirty diff --quiet = %q, %v; want no output and status 1", out, err)
+	}
+	if out, err := run(newDiffCmd(), "--exit-code"); exitCode(err) != exitFailure || out == "" {
+		t.Fatalf("dirty diff --exit-code = %q, %v; want the diff and status 1", out, err)
+	}
+	// Staged changes are not in the unstaged diff.
+	if err := r.Add([]string{"file.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := run(newDiffCmd(), "--quiet"); err != nil {
+		t.Fatalf("diff --quiet after staging: %v", err)
+	}
+	if _, err := run(newDiffCmd(), "--staged", "--quiet"); exitCode(err) != exitFailure {
+		t.Fatalf("diff --staged --quiet = %v, want status 1", err)
+	}
+}
+
+func TestGlobalQuietFlag(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := repo.Init(dir); err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "new.txt"), []byte("new\n"))
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	var out bytes.Buffer
+	root := newJSONTestRoot(&out, newStatusCmd())
+	root.SetArgs([]string{"-q", "status", "--exit-code"})
+	err := root.Execute()
+	if exitCode(err) != exitFailure {
+		t.Fatalf("status --exit-code with an untracked file = %v, want status 1", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("-q printed %q", out.String())
+	}
+
+	root = newJSONTestRoot(&out, jsonCommand(newBranchCmd()))
+	root.SetArgs([]string{"-q", "--json", "branch"})
+	if err := root.Execute(); err == nil {
+		t.Fatal("-q --json succeeded, want an error")
+	}
+}
@@ -110,13 +110,22 @@ func bootstrapGotFromGit(ctx context.Context, dest, remoteName, remoteURL string
 	return nil
 }
 
-func pushViaGit(cmd *cobra.Command, r *repo.Repo, remoteURL, branch string, force bool) error {
+// pushViaGit pushes branches with git itself. Several branches are pushed
+// with --atomic, matching the all-or-nothing update of Graft remotes.
+func pushViaGit(cmd *cobra.Command, r *repo.Repo, remoteURL string, branches []string, force, followTags bool) error {
 	if err := ensureGitRepository(r.RootDir); err != nil {
 		return err
 	}
-	pushRef, err := resolveGitPushRef(cmd.Context(), r.RootDir, branch)
-	if err != nil {
-		return err
+	if len(branches) == 0 {
+		branches = []string{""}
+	}
+	pushRefs := make([]string, 0, len(branches))
+	for _, branch := range branches {
+		pushRef, err := resolveGitPushRef(cmd.Context(), r.RootDir, branch)
+		if err != nil {
+			return err
+		}
+		pushRefs = append(pushRefs, pushRef)
 	}
 
 	if err := syncGitSnapshotFromWorktree(cmd.Context(), r); err != nil {
@@ -127,10 +136,40 @@ func pushViaGit(cmd *cobra.Command, r *repo.Repo, remoteURL, branch string, forc
 	if force {
 		args = append(args, "--force")
 	}
-	args = append(args, remoteURL, pushRef)
+	if followTags {
+		args = append(args, "--follow-tags")
+	}
+	if len(pushRefs) > 1 {
+		args = append(args, "--atomic")
+	}
+	args = append(args, remoteURL)
+	args = append(args, pushRefs...)
 	return runGitStreaming(cmd.Context(), r.RootDir, cmd.OutOrStdout(), cmd.ErrOrStderr(), args...)
 }
 
+// pushBranchGitInterop pushes a branch to a git remote from a repository
+// without a co-located .git directory by translating its history into git
+// objects first.
+func pushBranchGitInterop(cmd *cobra.Command, r *repo.Repo, remoteName, remoteURL, branch string, force bool) error {
+	pushTarget, localRef, remoteRef, specForce, err := resolvePushRefspec(r, remoteName, branch)
+	if err != nil {
+		return err
+	}
+	localHash, err := r.ResolveRef(localRef)
+	if err != nil {
+		return fmt.Errorf("resolve local ref %q: %w", localRef, err)
+	}
+	gitHash, err := r.PushToGit(cmd.Context(), remoteURL, localHash, remoteRef, force || specForce, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+	if err := updatePushTrackingRef(r, remoteName, remoteRef, localHash); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "pushed %s at %s (git %s)\n", pushTarget, shortHash(localHash), gitHash[:min(12, len(gitHash))])
+	return nil
+}
+
 func pullViaGit(cmd *cobra.Command, r *repo.Repo, remoteURL, branch string, allowMerge bool, rebase bool) error {
 	if err := ensureGitRepository(r.RootDir); err != nil {
 		return err
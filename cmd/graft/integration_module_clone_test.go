@@ -67,3 +67,61 @@ func TestIntegration_CloneNoModules(t *testing.T) {
 		t.Fatalf("expected .graftmodules to exist in clone even with --no-modules: %v", err)
 	}
 }
+
+// TestIntegration_LocalCloneHardlinksObjects verifies that a local clone
+// shares loose object files with its source instead of copying them, and that
+// --no-hardlinks forces independent copies.
+func TestIntegration_LocalCloneHardlinksObjects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	src := initRepo(t)
+	commitFile(t, src, "main.go", "package main\n", "initial")
+
+	srcObjects := filepath.Join(src, ".graft", "objects")
+	var objectRel string
+	filepath.Walk(srcObjects, func(path string, info os.FileInfo, err error) error {
+		if err == nil && objectRel == "" && !info.IsDir() && isImmutableObjectFile(mustRel(t, filepath.Join(src, ".graft"), path)) {
+			objectRel = mustRel(t, src, path)
+		}
+		return nil
+	})
+	if objectRel == "" {
+		t.Fatal("source repository has no object files")
+	}
+	srcInfo, err := os.Stat(filepath.Join(src, objectRel))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linked := filepath.Join(t.TempDir(), "linked")
+	mustRunGraft(t, t.TempDir(), "clone", "--no-modules", src, linked)
+	linkedInfo, err := os.Stat(filepath.Join(linked, objectRel))
+	if err != nil {
+		t.Fatalf("expected object %s in clone: %v", objectRel, err)
+	}
+	if !os.SameFile(srcInfo, linkedInfo) {
+		t.Errorf("expected %s to be hardlinked into the clone", objectRel)
+	}
+
+	copied := filepath.Join(t.TempDir(), "copied")
+	mustRunGraft(t, t.TempDir(), "clone", "--no-modules", "--no-hardlinks", src, copied)
+	copiedInfo, err := os.Stat(filepath.Join(copied, objectRel))
+	if err != nil {
+		t.Fatalf("expected object %s in clone: %v", objectRel, err)
+	}
+	if os.SameFile(srcInfo, copiedInfo) {
+		t.Errorf("expected %s to be copied with --no-hardlinks", objectRel)
+	}
+	mustRunGraft(t, copied, "log")
+}
+
+func mustRel(t *testing.T, base, path string) string {
+	t.Helper()
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rel
+}
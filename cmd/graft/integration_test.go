@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // graftBin holds the path to the built graft binary for integration tests.
@@ -335,3 +336,99 @@ func TestIntegration_TagCreateAndList(t *testing.T) {
 		t.Errorf("tag list should still contain v1.1: %s", tagOut)
 	}
 }
+
+// TestIntegration_CommitPathspec verifies that commit -- <paths> commits
+// only the selected staged paths and keeps the rest staged.
+func TestIntegration_CommitPathspec(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "a1\n", "initial a")
+	commitFile(t, dir, "b.txt", "b1\n", "initial b")
+
+	writeFile(t, dir, "a.txt", "a2\n")
+	writeFile(t, dir, "b.txt", "b2\n")
+	mustRunGraft(t, dir, "add", "a.txt", "b.txt")
+	mustRunGraft(t, dir, "commit", "-m", "only a", "--author", "Test User", "--no-sign", "--", "a.txt")
+
+	if out := mustRunGraft(t, dir, "show", "HEAD"); strings.Contains(out, "b.txt") || !strings.Contains(out, "a.txt") {
+		t.Fatalf("HEAD should change only a.txt:\n%s", out)
+	}
+	stagedDiff := mustRunGraft(t, dir, "diff", "--staged")
+	if !strings.Contains(stagedDiff, "b.txt") || strings.Contains(stagedDiff, "a.txt") {
+		t.Fatalf("only b.txt should remain staged:\n%s", stagedDiff)
+	}
+
+	if _, err := runGraft(t, dir, "commit", "--amend", "-m", "x", "--no-sign", "--", "b.txt"); err == nil {
+		t.Fatal("expected --amend with pathspecs to be rejected")
+	}
+}
+
+func TestIntegration_CommitAllowEmptyAndDate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	if _, err := runGraft(t, dir, "commit", "-m", "empty", "--no-sign"); err == nil {
+		t.Fatal("expected a commit with nothing staged to fail")
+	}
+	mustRunGraft(t, dir, "commit", "-m", "empty", "--allow-empty", "--no-sign",
+		"--author", "Someone Else <else@example.com>", "--date", "@1577934245")
+
+	out := mustRunGraft(t, dir, "show", "HEAD")
+	if !strings.Contains(out, "Someone Else <else@example.com>") {
+		t.Fatalf("show should report the overridden author:\n%s", out)
+	}
+	if want := time.Unix(1577934245, 0).Format("2006-01-02 15:04:05"); !strings.Contains(out, want) {
+		t.Fatalf("show should report the author date %s:\n%s", want, out)
+	}
+
+	if _, err := parseCommitDate("yesterday"); err == nil {
+		t.Fatal("expected an unparseable --date to be rejected")
+	}
+}
+
+func TestIntegration_CommitTemplateAndLint(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "a.txt", "a1\n", "initial a")
+	writeFile(t, dir, "template.txt", "\n# Why was this change made?\n")
+	mustRunGraft(t, dir, "config", "commit.template", "template.txt")
+
+	// An editor that leaves the template alone aborts the commit.
+	writeFile(t, dir, "b.txt", "b1\n")
+	mustRunGraft(t, dir, "add", "b.txt")
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "true")
+	if out, err := runGraft(t, dir, "commit", "--no-sign"); err == nil || !strings.Contains(out, "did not edit") {
+		t.Fatalf("commit with an unedited template = %v\n%s", err, out)
+	}
+
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\nprintf 'feat: add b' | cat - \"$1\" > \"$1.tmp\" && mv \"$1.tmp\" \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+	mustRunGraft(t, dir, "commit", "--no-sign")
+	if out := mustRunGraft(t, dir, "log", "-n", "1"); !strings.Contains(out, "feat: add b") || strings.Contains(out, "Why was") {
+		t.Fatalf("log should show the edited message without comments:\n%s", out)
+	}
+
+	mustRunGraft(t, dir, "config", "commit.conventional", "true")
+	mustRunGraft(t, dir, "config", "commit.maxSubjectLength", "20")
+	writeFile(t, dir, "c.txt", "c1\n")
+	mustRunGraft(t, dir, "add", "c.txt")
+	if out, err := runGraft(t, dir, "commit", "-m", "add c", "--no-sign"); err == nil || !strings.Contains(out, "conventional") {
+		t.Fatalf("non-conventional commit = %v\n%s", err, out)
+	}
+	if out, err := runGraft(t, dir, "commit", "-m", "feat: add c, with a long subject", "--no-sign"); err == nil || !strings.Contains(out, "maxSubjectLength") {
+		t.Fatalf("commit with a long subject = %v\n%s", err, out)
+	}
+	mustRunGraft(t, dir, "commit", "-m", "add c", "--no-verify", "--no-sign")
+}
@@ -19,6 +19,9 @@ type JSONStatusOutput struct {
 	Branch       string            `json:"branch"`
 	NoCommits    bool              `json:"noCommits"`
 	ShadowDesync bool              `json:"shadow_desync,omitempty"`
+	Upstream     string            `json:"upstream,omitempty"`
+	Ahead        int               `json:"ahead,omitempty"`
+	Behind       int               `json:"behind,omitempty"`
 	Conflicts    []JSONStatusEntry `json:"conflicts,omitempty"`
 	Staged       []JSONStatusEntry `json:"staged,omitempty"`
 	Unstaged     []JSONStatusEntry `json:"unstaged,omitempty"`
@@ -30,6 +33,7 @@ type JSONStatusEntry struct {
 	Path        string `json:"path"`
 	Status      string `json:"status"` // "new", "modified", "deleted", "renamed", "conflict", "dirty"
 	RenamedFrom string `json:"renamedFrom,omitempty"`
+	Conflict    string `json:"conflict,omitempty"` // for conflicts: "both_modified", "both_added", "deleted_by_us", ...
 }
 
 // --- Diff ---
@@ -71,6 +75,22 @@ type JSONDiffLine struct {
 	Content string `json:"content"`
 }
 
+// JSONDiffStatOutput is the JSON output for "graft diff --stat --json".
+type JSONDiffStatOutput struct {
+	Files        []JSONDiffStatFile `json:"files"`
+	FilesChanged int                `json:"filesChanged"`
+	Insertions   int                `json:"insertions"`
+	Deletions    int                `json:"deletions"`
+}
+
+// JSONDiffStatFile is the number of lines added and removed in one file.
+type JSONDiffStatFile struct {
+	Path       string `json:"path"`
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+	Binary     bool   `json:"binary,omitempty"`
+}
+
 // --- Log ---
 
 // JSONLogOutput is the top-level JSON output for "graft log --json".
@@ -86,10 +106,57 @@ type JSONLogEntry struct {
 	Date       string   `json:"date"`
 	Timestamp  int64    `json:"timestamp"`
 	Message    string   `json:"message"`
+	Subject    string   `json:"subject"`
+	Body       string   `json:"body,omitempty"`
 	Parents    []string `json:"parents,omitempty"`
 	Decoration string   `json:"decoration,omitempty"`
 }
 
+// --- Branch ---
+
+// JSONBranchOutput is the JSON output for "graft branch --json".
+type JSONBranchOutput struct {
+	Current  string       `json:"current,omitempty"`
+	Branches []JSONBranch `json:"branches"`
+}
+
+// JSONBranch is one local branch with its tip and upstream tracking state.
+type JSONBranch struct {
+	Name     string `json:"name"`
+	Current  bool   `json:"current"`
+	Commit   string `json:"commit"`
+	Subject  string `json:"subject"`
+	Upstream string `json:"upstream,omitempty"`
+	Ahead    int    `json:"ahead,omitempty"`
+	Behind   int    `json:"behind,omitempty"`
+}
+
+// JSONRefAction is the JSON output for creating or deleting a branch or tag.
+type JSONRefAction struct {
+	Action string `json:"action"` // "created", "deleted"
+	Name   string `json:"name"`
+	Target string `json:"target,omitempty"`
+}
+
+// --- Tag ---
+
+// JSONTagOutput is the JSON output for "graft tag --json".
+type JSONTagOutput struct {
+	Tags []JSONTag `json:"tags"`
+}
+
+// JSONTag is one tag. Target is the tag object of an annotated tag, Commit
+// the commit it peels to.
+type JSONTag struct {
+	Name      string `json:"name"`
+	Target    string `json:"target"`
+	Commit    string `json:"commit,omitempty"`
+	Annotated bool   `json:"annotated"`
+	Tagger    string `json:"tagger,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
 // --- Merge ---
 
 // JSONMergeOutput is the top-level JSON output for "graft merge --json".
@@ -129,6 +196,36 @@ type JSONDiagnostic struct {
 	Rule     string `json:"rule"`
 }
 
+// --- Push / Pull ---
+
+// JSONPushOutput is the JSON output for "graft push --json".
+type JSONPushOutput struct {
+	Remote          string        `json:"remote"`
+	Refs            []JSONPushRef `json:"refs"`
+	ObjectsUploaded int           `json:"objectsUploaded"`
+	LFSObjects      int           `json:"lfsObjects,omitempty"`
+}
+
+// JSONPushRef is one remote ref the push considered.
+type JSONPushRef struct {
+	Ref       string `json:"ref"`
+	RemoteRef string `json:"remoteRef"`
+	Status    string `json:"status"` // "up-to-date", "created", "updated", "deleted"
+	Old       string `json:"old,omitempty"`
+	New       string `json:"new,omitempty"`
+}
+
+// JSONPullOutput is the JSON output for "graft pull --json".
+type JSONPullOutput struct {
+	Remote         string   `json:"remote"`
+	Branch         string   `json:"branch"`
+	Result         string   `json:"result"` // "up-to-date", "ahead", "created", "fast-forward", "merged", "rebased", "conflict"
+	Old            string   `json:"old,omitempty"`
+	New            string   `json:"new,omitempty"`
+	ObjectsFetched int      `json:"objectsFetched"`
+	Conflicts      []string `json:"conflicts,omitempty"`
+}
+
 // --- Show ---
 
 // JSONShowOutput is the top-level JSON output for "graft show --json".
@@ -148,6 +245,16 @@ type JSONShowChange struct {
 	Status string `json:"status"` // "A" (added), "D" (deleted), "M" (modified)
 }
 
+// JSONShowEntityOutput is the JSON output for "graft show <rev>:<path>#<entity> --json".
+type JSONShowEntityOutput struct {
+	Commit   string `json:"commit"`
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	DeclKind string `json:"declKind"`
+	Receiver string `json:"receiver,omitempty"`
+	Body     string `json:"body"`
+}
+
 // --- Blame ---
 
 // JSONBlameOutput is the JSON output for "graft blame --entity --json".
@@ -165,6 +272,23 @@ type JSONBatchBlameOutput struct {
 	Entities []JSONBlameOutput `json:"entities"`
 }
 
+// JSONLineBlame is one attributed line in "graft blame -L/-w/-C --json".
+type JSONLineBlame struct {
+	Line       int    `json:"line"`
+	CommitHash string `json:"commitHash"`
+	Author     string `json:"author"`
+	Timestamp  int64  `json:"timestamp"`
+	OrigPath   string `json:"origPath"`
+	OrigLine   int    `json:"origLine"`
+	Content    string `json:"content"`
+}
+
+// JSONLineBlameOutput is the JSON output for line blame.
+type JSONLineBlameOutput struct {
+	Path  string          `json:"path"`
+	Lines []JSONLineBlame `json:"lines"`
+}
+
 // --- Conflicts ---
 
 // JSONConflictsOutput is the top-level JSON output for "graft conflicts --json".
@@ -190,10 +314,18 @@ type JSONConflictEntity struct {
 
 // JSONVerifyOutput is the top-level JSON output for "graft verify --json".
 type JSONVerifyOutput struct {
-	Results      []JSONVerifyResult `json:"results,omitempty"`
-	LooseObjects int                `json:"looseObjects,omitempty"`
-	PackFiles    int                `json:"packFiles,omitempty"`
-	PackObjects  int                `json:"packObjects,omitempty"`
+	Results      []JSONVerifyResult        `json:"results,omitempty"`
+	LooseObjects int                       `json:"looseObjects,omitempty"`
+	PackFiles    int                       `json:"packFiles,omitempty"`
+	PackObjects  int                       `json:"packObjects,omitempty"`
+	Corrupt      []JSONVerifyCorruptObject `json:"corrupt,omitempty"`
+}
+
+// JSONVerifyCorruptObject describes one integrity failure found by "graft verify".
+type JSONVerifyCorruptObject struct {
+	Hash     string `json:"hash,omitempty"`
+	Location string `json:"location"`
+	Error    string `json:"error"`
 }
 
 // JSONVerifyResult represents the signature verification result for a single commit.
@@ -230,6 +362,44 @@ type JSONVerifySizedObject struct {
 	SizeBytes int64  `json:"sizeBytes"`
 }
 
+// --- Repair ---
+
+// JSONRepairObjectsOutput is the JSON output for "graft repair objects --json".
+type JSONRepairObjectsOutput struct {
+	Corrupt         int                  `json:"corrupt"`
+	SalvagedPacks   []string             `json:"salvagedPacks,omitempty"`
+	Salvaged        int                  `json:"salvaged"`
+	IntactPacks     []string             `json:"intactPacks,omitempty"`
+	Refetched       []JSONRepairedObject `json:"refetched,omitempty"`
+	Unrecovered     []string             `json:"unrecovered,omitempty"`
+	UnreadablePacks []string             `json:"unreadablePacks,omitempty"`
+}
+
+// JSONRepairedObject describes one object restored from a remote.
+type JSONRepairedObject struct {
+	Hash   string `json:"hash"`
+	Remote string `json:"remote"`
+}
+
+// --- Count Objects ---
+
+// JSONCountObjectsOutput is the JSON output for "graft count-objects --json".
+type JSONCountObjectsOutput struct {
+	LooseObjects  int                             `json:"looseObjects"`
+	LooseSize     int64                           `json:"looseSizeBytes"`
+	PackFiles     int                             `json:"packFiles"`
+	PackSize      int64                           `json:"packSizeBytes"`
+	PackedObjects int                             `json:"packedObjects"`
+	ByType        map[string]JSONCountObjectsType `json:"byType"`
+	Largest       []JSONVerifySizedObject         `json:"largest,omitempty"`
+}
+
+// JSONCountObjectsType aggregates object counts for a single object type.
+type JSONCountObjectsType struct {
+	Count     int   `json:"count"`
+	SizeBytes int64 `json:"sizeBytes"`
+}
+
 // --- Entity Search ---
 
 // JSONEntitySearchOutput is the top-level JSON output for "graft grep --entity --json".
@@ -245,3 +415,174 @@ type JSONEntitySearchResult struct {
 	DeclKind string `json:"declKind"`
 	Key      string `json:"key"`
 }
+
+// --- Stats ---
+
+// JSONStatsOutput is the JSON output for "graft stats --json".
+type JSONStatsOutput struct {
+	Commits  int               `json:"commits"`
+	Added    int               `json:"added"`
+	Removed  int               `json:"removed"`
+	Authors  []JSONAuthorStats `json:"authors,omitempty"`
+	Entities []JSONEntityStats `json:"entities,omitempty"`
+}
+
+// JSONAuthorStats is one author's churn.
+type JSONAuthorStats struct {
+	Author   string `json:"author"`
+	Commits  int    `json:"commits"`
+	Entities int    `json:"entities"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+}
+
+// JSONEntityStats is one entity's churn.
+type JSONEntityStats struct {
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	DeclKind string `json:"declKind"`
+	Key      string `json:"key"`
+	Commits  int    `json:"commits"`
+	Authors  int    `json:"authors"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+}
+
+// --- Graph Export ---
+
+// JSONGraphOutput is the JSON output for "graft graph export --format json".
+type JSONGraphOutput struct {
+	Commits []JSONGraphCommit `json:"commits"`
+}
+
+// JSONGraphCommit is one commit of the exported graph.
+type JSONGraphCommit struct {
+	Hash      string                  `json:"hash"`
+	Parents   []string                `json:"parents"`
+	Author    string                  `json:"author"`
+	Timestamp int64                   `json:"timestamp"`
+	Subject   string                  `json:"subject"`
+	Refs      []string                `json:"refs,omitempty"`
+	Entities  []JSONGraphEntityChange `json:"entities,omitempty"`
+}
+
+// JSONGraphEntityChange is an entity-level change edge from a commit.
+type JSONGraphEntityChange struct {
+	Path   string `json:"path"`
+	Key    string `json:"key"`
+	Change string `json:"change"`
+}
+
+// --- Rewrite History ---
+
+// JSONRewriteHistoryOutput is the JSON output for "graft rewrite-history --json".
+type JSONRewriteHistoryOutput struct {
+	DryRun    bool                `json:"dryRun"`
+	Commits   int                 `json:"commits"`
+	Changed   int                 `json:"changed"`
+	Pruned    int                 `json:"pruned"`
+	CommitMap []JSONRewriteCommit `json:"commitMap"`
+	Refs      []JSONRewriteRef    `json:"refs"`
+}
+
+// JSONRewriteCommit maps one old commit to its replacement.
+type JSONRewriteCommit struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// JSONRewriteRef is a ref moved by the rewrite.
+type JSONRewriteRef struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// --- Fsck ---
+
+// JSONFsckOutput is the JSON output for "graft fsck --json".
+type JSONFsckOutput struct {
+	Objects         []JSONFsckObject `json:"objects"`
+	DanglingCommits []JSONFsckCommit `json:"danglingCommits"`
+	LostFound       string           `json:"lostFound,omitempty"`
+}
+
+// JSONFsckObject is an unreachable object.
+type JSONFsckObject struct {
+	Hash     string `json:"hash"`
+	Type     string `json:"type"`
+	Dangling bool   `json:"dangling"`
+}
+
+// JSONFsckCommit is a dangling commit tip.
+type JSONFsckCommit struct {
+	Hash      string `json:"hash"`
+	Author    string `json:"author"`
+	Timestamp int64  `json:"timestamp"`
+	Subject   string `json:"subject"`
+}
+
+// --- Owners ---
+
+// JSONOwnersOutput is the JSON output for "graft owners --json".
+type JSONOwnersOutput struct {
+	OldCommit  string             `json:"oldCommit,omitempty"`
+	NewCommit  string             `json:"newCommit"`
+	OwnersFile string             `json:"ownersFile,omitempty"`
+	Owners     []JSONOwnerChanges `json:"owners"`
+	Unowned    []JSONOwnedChange  `json:"unowned"`
+}
+
+// JSONOwnerChanges lists the changes one owner is responsible for.
+type JSONOwnerChanges struct {
+	Owner   string            `json:"owner"`
+	Changes []JSONOwnedChange `json:"changes"`
+}
+
+// JSONOwnedChange is a changed entity, or a file without entity changes.
+type JSONOwnedChange struct {
+	Path       string   `json:"path"`
+	EntityKey  string   `json:"entityKey,omitempty"`
+	ChangeType string   `json:"changeType"`
+	Owners     []string `json:"owners"`
+	Authors    []string `json:"authors"`
+}
+
+// --- API diff ---
+
+// JSONAPIDiffOutput is the JSON output for "graft api-diff --json".
+type JSONAPIDiffOutput struct {
+	OldCommit string          `json:"oldCommit,omitempty"`
+	NewCommit string          `json:"newCommit,omitempty"`
+	Breaking  int             `json:"breaking"`
+	Changes   []JSONAPIChange `json:"changes"`
+}
+
+// JSONAPIChange is one public declaration that was removed, changed or
+// added.
+type JSONAPIChange struct {
+	Package      string `json:"package"`
+	Path         string `json:"path"`
+	Name         string `json:"name"`
+	Kind         string `json:"kind"`
+	Breaking     bool   `json:"breaking"`
+	OldSignature string `json:"oldSignature,omitempty"`
+	NewSignature string `json:"newSignature,omitempty"`
+}
+
+// --- Hash object ---
+
+// JSONHashObject is one input of "graft hash-object --json".
+type JSONHashObject struct {
+	Path       string                 `json:"path,omitempty"`
+	Blob       string                 `json:"blob"`
+	EntityList string                 `json:"entityList,omitempty"`
+	Entities   []JSONHashObjectEntity `json:"entities,omitempty"`
+}
+
+// JSONHashObjectEntity is one entity hashed by "graft hash-object --entities".
+type JSONHashObjectEntity struct {
+	Hash  string `json:"hash"`
+	Kind  string `json:"kind"`
+	Label string `json:"label,omitempty"`
+}
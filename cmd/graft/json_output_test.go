@@ -6,11 +6,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/odvcencio/graft/pkg/entity"
 	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
 )
 
 // TestWriteJSON verifies writeJSON produces pretty-printed JSON with the correct structure.
@@ -563,6 +565,154 @@ func TestStatusCmd_JSON_NoHumanOutput(t *testing.T) {
 	}
 }
 
+// newJSONTestRoot returns a root command with the global output flags and
+// cmds added, as main sets them up.
+func newJSONTestRoot(out io.Writer, cmds ...*cobra.Command) *cobra.Command {
+	root := &cobra.Command{Use: "graft", SilenceUsage: true, SilenceErrors: true}
+	addOutputFlags(root)
+	root.AddCommand(cmds...)
+	root.SetOut(out)
+	root.SetErr(io.Discard)
+	return root
+}
+
+// TestBranchAndTagCmd_GlobalJSON tests the global --json flag on branch and
+// tag, listing and creating.
+func TestBranchAndTagCmd_GlobalJSON(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "file.txt"), []byte("hello\n"))
+	if err := r.Add([]string{"file.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	head, err := r.Commit("initial commit", "tester")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	run := func(args ...string) []byte {
+		t.Helper()
+		var out bytes.Buffer
+		root := newJSONTestRoot(&out, jsonCommand(newBranchCmd()), jsonCommand(newTagCmd()))
+		root.SetArgs(args)
+		if err := root.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return out.Bytes()
+	}
+
+	var created JSONRefAction
+	if err := json.Unmarshal(run("--json", "branch", "topic"), &created); err != nil {
+		t.Fatalf("branch create output is not valid JSON: %v", err)
+	}
+	if created.Action != "created" || created.Name != "topic" || created.Target != string(head) {
+		t.Fatalf("branch create = %+v", created)
+	}
+
+	var branches JSONBranchOutput
+	if err := json.Unmarshal(run("branch", "--json"), &branches); err != nil {
+		t.Fatalf("branch output is not valid JSON: %v", err)
+	}
+	if branches.Current != "main" || len(branches.Branches) != 2 {
+		t.Fatalf("branches = %+v, want main and topic with main current", branches)
+	}
+	for _, b := range branches.Branches {
+		if b.Commit != string(head) || b.Subject != "initial commit" || b.Current != (b.Name == "main") {
+			t.Fatalf("branch entry = %+v", b)
+		}
+	}
+
+	run("tag", "-m", "first release", "v1.0")
+	var tags JSONTagOutput
+	if err := json.Unmarshal(run("--json", "tag"), &tags); err != nil {
+		t.Fatalf("tag output is not valid JSON: %v", err)
+	}
+	if len(tags.Tags) != 1 {
+		t.Fatalf("tags = %+v, want v1.0", tags)
+	}
+	if tag := tags.Tags[0]; tag.Name != "v1.0" || !tag.Annotated || tag.Commit != string(head) || tag.Message != "first release" {
+		t.Fatalf("tag = %+v", tag)
+	}
+}
+
+// TestGlobalJSON_UnsupportedCommand tests that --json is refused by a
+// command that would print text anyway.
+func TestGlobalJSON_UnsupportedCommand(t *testing.T) {
+	root := newJSONTestRoot(io.Discard, newVersionCmd())
+	root.SetArgs([]string{"--json", "version"})
+	err := root.Execute()
+	if err == nil || !strings.Contains(err.Error(), "does not support --json") {
+		t.Fatalf("Execute = %v, want --json refused", err)
+	}
+}
+
+// TestDiffCmd_StatJSON tests --stat with and without --json.
+func TestDiffCmd_StatJSON(t *testing.T) {
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "a.txt"), []byte("one\ntwo\nthree\n"))
+	if err := r.Add([]string{"a.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("initial", "tester"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, "a.txt"), []byte("one\n2\nthree\nfour\n"))
+	writeTestFile(t, filepath.Join(dir, "docs/b.txt"), []byte("new\n"))
+	if err := r.Add([]string{"a.txt", "docs/b.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	restore := chdirForTest(t, dir)
+	defer restore()
+
+	var out bytes.Buffer
+	cmd := newDiffCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--staged", "--stat", "--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	var result JSONDiffStatOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\nraw: %s", err, out.String())
+	}
+	want := JSONDiffStatOutput{
+		Files: []JSONDiffStatFile{
+			{Path: "a.txt", Insertions: 2, Deletions: 1},
+			{Path: "docs/b.txt", Insertions: 1},
+		},
+		FilesChanged: 2, Insertions: 3, Deletions: 1,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("stat = %+v, want %+v", result, want)
+	}
+
+	out.Reset()
+	cmd = newDiffCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--staged", "--stat"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	wantText := " a.txt      | 3 ++-\n docs/b.txt | 1 +\n 2 files changed, 3 insertions(+), 1 deletion(-)\n"
+	if out.String() != wantText {
+		t.Fatalf("stat =\n%s\nwant\n%s", out.String(), wantText)
+	}
+}
+
 // --- helpers ---
 
 func writeTestFile(t *testing.T, path string, content []byte) {
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/remote"
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+func TestLocalPathRemoteCloneFetchAndPush(t *testing.T) {
+	work := t.TempDir()
+	srcDir := filepath.Join(work, "src")
+	src, err := repo.Init(srcDir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := src.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := src.Commit("initial", "tester"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	restore := chdirForTest(t, work)
+	defer restore()
+
+	var out bytes.Buffer
+	clone := newCloneCmd()
+	clone.SilenceUsage = true
+	clone.SetOut(&out)
+	clone.SetErr(io.Discard)
+	clone.SetArgs([]string{"file://" + filepath.ToSlash(srcDir), "dst"})
+	if err := clone.Execute(); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	dstDir := filepath.Join(work, "dst")
+	if data, err := os.ReadFile(filepath.Join(dstDir, "main.go")); err != nil || !strings.Contains(string(data), "func main") {
+		t.Fatalf("cloned main.go = %q, %v", data, err)
+	}
+
+	// Relative local paths are stored as absolute remote URLs.
+	remoteAdd := newRemoteCmd()
+	remoteAdd.SilenceUsage = true
+	remoteAdd.SetOut(io.Discard)
+	remoteAdd.SetErr(io.Discard)
+	remoteAdd.SetArgs([]string{"add", "mirror", "../src"})
+	restoreDst := chdirForTest(t, dstDir)
+	defer restoreDst()
+	if err := remoteAdd.Execute(); err != nil {
+		t.Fatalf("remote add: %v", err)
+	}
+	dst, err := repo.Open(dstDir)
+	if err != nil {
+		t.Fatalf("repo.Open: %v", err)
+	}
+	if url, err := dst.RemoteURL("mirror"); err != nil || url != srcDir {
+		t.Fatalf("mirror URL = %q, %v; want %q", url, err, srcDir)
+	}
+
+	if err := os.WriteFile(filepath.Join(srcDir, "util.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := src.Add([]string{"util.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	upstream, err := src.Commit("add util", "tester")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	fetch := newFetchCmd()
+	fetch.SilenceUsage = true
+	var fetchErr bytes.Buffer
+	fetch.SetOut(io.Discard)
+	fetch.SetErr(&fetchErr)
+	fetch.SetArgs([]string{"--stats", "mirror"})
+	if err := fetch.Execute(); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if !strings.HasPrefix(fetchErr.String(), "transfer: received ") || strings.HasPrefix(fetchErr.String(), "transfer: received 0 ") {
+		t.Fatalf("fetch --stats stderr = %q", fetchErr.String())
+	}
+	if got, err := dst.ResolveRef("refs/remotes/mirror/heads/main"); err != nil || got != upstream {
+		t.Fatalf("mirror/main = %s, %v; want %s", got, err, upstream)
+	}
+
+	if err := dst.CreateBranch("feature", upstream); err != nil {
+		t.Fatalf("CreateBranch: %v", err)
+	}
+	out.Reset()
+	push := newPushCmd()
+	push.SilenceUsage = true
+	push.SetOut(&out)
+	push.SetErr(io.Discard)
+	push.SetArgs([]string{"mirror", "feature"})
+	if err := push.Execute(); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if got, err := src.ResolveRef("refs/heads/feature"); err != nil || got != upstream {
+		t.Fatalf("source feature = %s, %v; want %s", got, err, upstream)
+	}
+
+	push = newPushCmd()
+	push.SilenceUsage = true
+	var stderr bytes.Buffer
+	push.SetOut(io.Discard)
+	push.SetErr(&stderr)
+	push.SetArgs([]string{"--force", "mirror", "main"})
+	if err := push.Execute(); err == nil || !strings.Contains(stderr.String(), "branch main: refusing to update the branch checked out") {
+		t.Fatalf("push to checked-out branch error = %v, stderr %q", err, stderr.String())
+	}
+}
+
+func TestFetchCmdPruneReportsDeletedTrackingRefs(t *testing.T) {
+	work := t.TempDir()
+	srcDir := filepath.Join(work, "src")
+	src, err := repo.Init(srcDir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := src.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	head, err := src.Commit("initial", "tester")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := src.UpdateRef("refs/heads/topic", head); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+
+	dstDir := filepath.Join(work, "dst")
+	dst, err := repo.Init(dstDir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	if err := dst.SetRemote("origin", srcDir); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+	restore := chdirForTest(t, dstDir)
+	defer restore()
+
+	runFetch := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		fetch := newFetchCmd()
+		fetch.SilenceUsage = true
+		fetch.SetOut(&out)
+		fetch.SetErr(io.Discard)
+		fetch.SetArgs(args)
+		if err := fetch.Execute(); err != nil {
+			t.Fatalf("fetch %v: %v", args, err)
+		}
+		return out.String()
+	}
+	runFetch()
+	if err := src.DeleteRefCAS("refs/heads/topic", head); err != nil {
+		t.Fatalf("DeleteRefCAS: %v", err)
+	}
+
+	out := runFetch("--prune")
+	if !strings.Contains(out, "[deleted]") || !strings.Contains(out, "refs/remotes/origin/heads/topic") {
+		t.Fatalf("fetch --prune output = %q", out)
+	}
+	if _, err := dst.ResolveRef("refs/remotes/origin/heads/topic"); err == nil {
+		t.Fatal("stale tracking ref survived fetch --prune")
+	}
+	if out := runFetch("--prune"); !strings.Contains(out, "already up to date") {
+		t.Fatalf("second fetch --prune output = %q", out)
+	}
+}
+
+func TestPushRefspecsMapBranchesToRemoteNames(t *testing.T) {
+	work := t.TempDir()
+	srcDir := filepath.Join(work, "src")
+	if _, err := repo.Init(srcDir); err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	dstDir := filepath.Join(work, "dst")
+	dst, err := repo.Init(dstDir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dstDir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := dst.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	head, err := dst.Commit("initial", "tester")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := dst.SetRemote("origin", srcDir); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+	restore := chdirForTest(t, dstDir)
+	defer restore()
+
+	run := func(cmd interface {
+		SetOut(io.Writer)
+		SetErr(io.Writer)
+		SetArgs([]string)
+		Execute() error
+	}, args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return out.String()
+	}
+
+	out := run(newPushCmd(), "origin", "main:release")
+	if !strings.Contains(out, "branch main -> heads/release") {
+		t.Fatalf("push output = %q", out)
+	}
+	src, err := repo.Open(srcDir)
+	if err != nil {
+		t.Fatalf("repo.Open: %v", err)
+	}
+	if got, err := src.ResolveRef("refs/heads/release"); err != nil || got != head {
+		t.Fatalf("remote release = %q, %v; want %s", got, err, head)
+	}
+	if got, err := dst.ResolveRef("refs/remotes/origin/heads/release"); err != nil || got != head {
+		t.Fatalf("tracking release = %q, %v; want %s", got, err, head)
+	}
+
+	run(newConfigCmd(), "remote.origin.push", "main:staging")
+	if got := run(newConfigCmd(), "remote.origin.push"); strings.TrimSpace(got) != "main:staging" {
+		t.Fatalf("config get remote.origin.push = %q", got)
+	}
+	run(newPushCmd(), "origin", "main")
+	if got, err := src.ResolveRef("refs/heads/staging"); err != nil || got != head {
+		t.Fatalf("remote staging = %q, %v; want %s", got, err, head)
+	}
+
+	config := newConfigCmd()
+	config.SetOut(io.Discard)
+	config.SetErr(io.Discard)
+	config.SetArgs([]string{"remote.origin.fetch", "refs/heads/*:refs/remotes/origin/main"})
+	if err := config.Execute(); err == nil {
+		t.Fatal("config accepted a refspec with a one-sided wildcard")
+	}
+}
+
+func TestPushUpdatesSeveralRefsAtomicallyAndFollowsTags(t *testing.T) {
+	work := t.TempDir()
+	srcDir := filepath.Join(work, "src")
+	src, err := repo.Init(srcDir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	dstDir := filepath.Join(work, "dst")
+	dst, err := repo.Init(dstDir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	commit := func(name, msg string) object.Hash {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dstDir, name), []byte("package main\n"), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := dst.Add([]string{name}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		h, err := dst.Commit(msg, "tester")
+		if err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		return h
+	}
+	first := commit("main.go", "initial")
+	for _, branch := range []string{"feature", "release"} {
+		if err := dst.CreateBranch(branch, first); err != nil {
+			t.Fatalf("CreateBranch: %v", err)
+		}
+	}
+	annotated, err := dst.CreateAnnotatedTag("v1", first, "tester", "v1", false)
+	if err != nil {
+		t.Fatalf("CreateAnnotatedTag: %v", err)
+	}
+	if err := dst.CreateTag("light", first, false); err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	if err := dst.SetRemote("origin", srcDir); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+	restore := chdirForTest(t, dstDir)
+	defer restore()
+
+	push := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := newPushCmd()
+		cmd.SilenceUsage = true
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := push("--follow-tags", "origin", "feature", "release")
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	for _, want := range []string{"pushed new branch feature", "pushed new branch release", "pushed new tag v1"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("push output = %q, want %q", out, want)
+		}
+	}
+	for ref, want := range map[string]object.Hash{"refs/heads/feature": first, "refs/heads/release": first, "refs/tags/v1": annotated} {
+		if got, err := src.ResolveRef(ref); err != nil || got != want {
+			t.Fatalf("remote %s = %s, %v; want %s", ref, got, err, want)
+		}
+	}
+	if _, err := src.ResolveRef("refs/tags/light"); err == nil {
+		t.Fatal("--follow-tags pushed a lightweight tag")
+	}
+
+	// A rejected tag update leaves the branch pushed alongside it untouched.
+	if err := dst.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	second := commit("util.go", "add util")
+	if err := dst.CreateTag("light", second, true); err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	if err := src.UpdateRef("refs/tags/light", first); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+	if _, err := push("origin", "feature", "light"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("push error = %v, want rejected tag", err)
+	}
+	if got, err := src.ResolveRef("refs/heads/feature"); err != nil || got != first {
+		t.Fatalf("remote feature = %s, %v; want %s after rejected push", got, err, first)
+	}
+
+	if _, err := push("--force", "origin", "feature", "light"); err != nil {
+		t.Fatalf("push --force: %v", err)
+	}
+	for ref, want := range map[string]object.Hash{"refs/heads/feature": second, "refs/tags/light": second} {
+		if got, err := src.ResolveRef(ref); err != nil || got != want {
+			t.Fatalf("remote %s = %s, %v; want %s", ref, got, err, want)
+		}
+	}
+}
+
+func TestMirrorCloneFetchAndPush(t *testing.T) {
+	work := t.TempDir()
+	srcDir := filepath.Join(work, "src")
+	src, err := repo.Init(srcDir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := src.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	head, err := src.Commit("initial", "tester")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	for _, ref := range []string{"refs/heads/topic", "refs/notes/commits"} {
+		if err := src.UpdateRef(ref, head); err != nil {
+			t.Fatalf("UpdateRef: %v", err)
+		}
+	}
+	tag, err := src.CreateAnnotatedTag("v1", head, "tester", "v1", false)
+	if err != nil {
+		t.Fatalf("CreateAnnotatedTag: %v", err)
+	}
+
+	// A bare backup target: a Graft directory without a working tree.
+	scratch := filepath.Join(work, "scratch")
+	if _, err := repo.Init(scratch); err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	backupDir := filepath.Join(work, "backup.graft")
+	if err := os.Rename(filepath.Join(scratch, ".graft"), backupDir); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+
+	restore := chdirForTest(t, work)
+	defer restore()
+	run := func(cmd interface {
+		SetOut(io.Writer)
+		SetErr(io.Writer)
+		SetArgs([]string)
+		Execute() error
+	}, args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return out.String()
+	}
+
+	run(newCloneCmd(), "--mirror", "file://"+filepath.ToSlash(srcDir), "mirror")
+	mirrorDir := filepath.Join(work, "mirror")
+	mirror, err := repo.Open(mirrorDir)
+	if err != nil {
+		t.Fatalf("repo.Open: %v", err)
+	}
+	want := map[string]object.Hash{"heads/main": head, "heads/topic": head, "notes/commits": head, "tags/v1": tag}
+	if got, err := mirror.MirrorRefs(); err != nil || !maps.Equal(got, want) {
+		t.Fatalf("mirror refs = %v, %v; want %v", got, err, want)
+	}
+	if tracking, _ := mirror.ListRefs("remotes"); len(tracking) != 0 {
+		t.Fatalf("mirror clone created tracking refs %v", tracking)
+	}
+	if ok, err := mirror.IsMirrorRemote("origin"); err != nil || !ok {
+		t.Fatalf("IsMirrorRemote = %v, %v; want true", ok, err)
+	}
+
+	// Fetching a mirror replaces its refs and prunes deleted ones.
+	if err := src.DeleteRefCAS("refs/heads/topic", head); err != nil {
+		t.Fatalf("DeleteRefCAS: %v", err)
+	}
+	if err := src.UpdateRef("refs/heads/extra", head); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+	restoreMirror := chdirForTest(t, mirrorDir)
+	defer restoreMirror()
+	run(newFetchCmd())
+	if _, err := mirror.ResolveRef("refs/heads/topic"); err == nil {
+		t.Fatal("fetch kept a branch deleted on the mirrored remote")
+	}
+	if got, err := mirror.ResolveRef("refs/heads/extra"); err != nil || got != head {
+		t.Fatalf("mirror extra = %s, %v; want %s", got, err, head)
+	}
+
+	run(newRemoteCmd(), "add", "backup", backupDir)
+	run(newPushCmd(), "--mirror", "backup")
+	client, err := remote.NewClient(backupDir)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	want = map[string]object.Hash{"heads/main": head, "heads/extra": head, "notes/commits": head, "tags/v1": tag}
+	if got, err := client.ListRefs(t.Context()); err != nil || !maps.Equal(got, want) {
+		t.Fatalf("backup refs = %v, %v; want %v", got, err, want)
+	}
+
+	// Refs deleted locally are deleted from the mirror target, and a push
+	// without refs to the mirrored remote mirrors too.
+	if err := mirror.DeleteRefCAS("refs/heads/extra", head); err != nil {
+		t.Fatalf("DeleteRefCAS: %v", err)
+	}
+	if out := run(newPushCmd(), "--mirror", "backup"); !strings.Contains(out, "deleted branch extra") {
+		t.Fatalf("push --mirror output = %q", out)
+	}
+	if got, err := client.ListRefs(t.Context()); err != nil || got["heads/extra"] != "" {
+		t.Fatalf("backup refs after delete = %v, %v", got, err)
+	}
+	if err := mirror.UpdateRef("refs/heads/local-only", head); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+	run(newPushCmd())
+	if got, err := src.ResolveRef("refs/heads/local-only"); err != nil || got != head {
+		t.Fatalf("origin local-only = %s, %v; want %s", got, err, head)
+	}
+}
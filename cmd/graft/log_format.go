@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// logFormatCommit is the data a --format template is expanded against.
+type logFormatCommit struct {
+	Hash       object.Hash
+	Commit     *object.CommitObj
+	Decoration string
+}
+
+// logFormatPlaceholders maps --format placeholders, as in git log, to the
+// value they expand to.
+var logFormatPlaceholders = map[string]func(logFormatCommit) string{
+	"H":  func(c logFormatCommit) string { return string(c.Hash) },
+	"h":  func(c logFormatCommit) string { return shortHash(c.Hash) },
+	"T":  func(c logFormatCommit) string { return string(c.Commit.TreeHash) },
+	"P":  func(c logFormatCommit) string { return joinParents(c.Commit.Parents, false) },
+	"p":  func(c logFormatCommit) string { return joinParents(c.Commit.Parents, true) },
+	"an": func(c logFormatCommit) string { name, _ := splitAuthor(c.Commit.Author); return name },
+	"ae": func(c logFormatCommit) string { _, email := splitAuthor(c.Commit.Author); return email },
+	"ad": func(c logFormatCommit) string {
+		return time.Unix(c.Commit.Timestamp, 0).Format("2006-01-02 15:04:05")
+	},
+	"as": func(c logFormatCommit) string { return time.Unix(c.Commit.Timestamp, 0).Format("2006-01-02") },
+	"aI": func(c logFormatCommit) string { return time.Unix(c.Commit.Timestamp, 0).Format(time.RFC3339) },
+	"at": func(c logFormatCommit) string { return strconv.FormatInt(c.Commit.Timestamp, 10) },
+	"s":  func(c logFormatCommit) string { subject, _ := splitCommitMessage(c.Commit.Message); return subject },
+	"b":  func(c logFormatCommit) string { _, body := splitCommitMessage(c.Commit.Message); return body },
+	"B":  func(c logFormatCommit) string { return c.Commit.Message },
+	"d": func(c logFormatCommit) string {
+		if c.Decoration == "" {
+			return ""
+		}
+		return " " + c.Decoration
+	},
+	"n": func(logFormatCommit) string { return "\n" },
+	"%": func(logFormatCommit) string { return "%" },
+}
+
+// logFormat is a compiled --format template: literal text interleaved with
+// placeholder expansions.
+type logFormat []func(logFormatCommit) string
+
+// parseLogFormat compiles a --format template. A "format:" or "tformat:"
+// prefix is accepted and ignored, since every commit ends in a newline.
+func parseLogFormat(tmpl string) (logFormat, error) {
+	tmpl = strings.TrimPrefix(tmpl, "tformat:")
+	tmpl = strings.TrimPrefix(tmpl, "format:")
+
+	var parts logFormat
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() == 0 {
+			return
+		}
+		text := literal.String()
+		parts = append(parts, func(logFormatCommit) string { return text })
+		literal.Reset()
+	}
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] != '%' {
+			literal.WriteByte(tmpl[i])
+			continue
+		}
+		expand, width := matchLogPlaceholder(tmpl[i+1:])
+		if expand == nil {
+			return nil, fmt.Errorf("--format: unknown placeholder %q", "%"+tmpl[i+1:min(i+3, len(tmpl))])
+		}
+		flush()
+		parts = append(parts, expand)
+		i += width
+	}
+	flush()
+	return parts, nil
+}
+
+// matchLogPlaceholder finds the placeholder at the start of s, preferring
+// two-letter names, and returns its expansion and length.
+func matchLogPlaceholder(s string) (func(logFormatCommit) string, int) {
+	for width := 2; width >= 1; width-- {
+		if len(s) < width {
+			continue
+		}
+		if expand, ok := logFormatPlaceholders[s[:width]]; ok {
+			return expand, width
+		}
+	}
+	return nil, 0
+}
+
+// render expands the template for one commit.
+func (f logFormat) render(c logFormatCommit) string {
+	var b strings.Builder
+	for _, part := range f {
+		b.WriteString(part(c))
+	}
+	return b.String()
+}
+
+// splitAuthor splits "Name <email>" into its name and email. An author
+// without an email is all name.
+func splitAuthor(author string) (name, email string) {
+	open := strings.LastIndexByte(author, '<')
+	if open < 0 || !strings.HasSuffix(author, ">") {
+		return strings.TrimSpace(author), ""
+	}
+	return strings.TrimSpace(author[:open]), author[open+1 : len(author)-1]
+}
+
+// splitCommitMessage splits a commit message into its subject line and the
+// body that follows the blank line after it.
+func splitCommitMessage(message string) (subject, body string) {
+	subject, body, _ = strings.Cut(message, "\n")
+	return strings.TrimSpace(subject), strings.Trim(body, "\n")
+}
+
+func joinParents(parents []object.Hash, short bool) string {
+	names := make([]string, len(parents))
+	for i, p := range parents {
+		if short {
+			names[i] = shortHash(p)
+		} else {
+			names[i] = string(p)
+		}
+	}
+	return strings.Join(names, " ")
+}
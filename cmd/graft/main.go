@@ -15,7 +15,10 @@ func main() {
 	root := &cobra.Command{
 		Use:   "graft",
 		Short: "Structural version control powered by tree-sitter",
+		// main prints errors itself, once, and leaves out exit statuses.
+		SilenceErrors: true,
 	}
+	addOutputFlags(root)
 
 	root.AddCommand(newVersionCmd())
 	root.AddCommand(newInitCmd())
@@ -25,29 +28,52 @@ func main() {
 	root.AddCommand(newStatusCmd())
 	root.AddCommand(newCheckIgnoreCmd())
 	root.AddCommand(newCommitCmd())
-	root.AddCommand(newLogCmd())
+	root.AddCommand(pagedCommand(newLogCmd()))
 	root.AddCommand(newShowCmd())
-	root.AddCommand(newBlameCmd())
-	root.AddCommand(newDiffCmd())
-	root.AddCommand(newBranchCmd())
-	root.AddCommand(newTagCmd())
+	root.AddCommand(pagedCommand(newBlameCmd()))
+	root.AddCommand(newOwnersCmd())
+	root.AddCommand(pagedCommand(newDiffCmd()))
+	root.AddCommand(newAPIDiffCmd())
+	root.AddCommand(jsonCommand(newBranchCmd()))
+	root.AddCommand(jsonCommand(newTagCmd()))
 	root.AddCommand(newCheckoutCmd())
+	root.AddCommand(newRestoreCmd())
 	root.AddCommand(newSwitchCmd())
 	root.AddCommand(newMergeCmd())
 	root.AddCommand(newConflictsCmd())
 	root.AddCommand(newCherryPickCmd())
 	root.AddCommand(newRevertCmd())
+	root.AddCommand(newFormatPatchCmd())
+	root.AddCommand(newApplyCmd())
+	root.AddCommand(newAMCmd())
 	root.AddCommand(newRemoteCmd())
 	root.AddCommand(newConfigCmd())
 	root.AddCommand(newAuthCmd())
+	root.AddCommand(newLoginCmd())
 	root.AddCommand(newPublishCmd())
 	root.AddCommand(newCloneCmd())
 	root.AddCommand(newFetchCmd())
-	root.AddCommand(newPullCmd())
-	root.AddCommand(newPushCmd())
+	root.AddCommand(jsonCommand(newPullCmd()))
+	root.AddCommand(jsonCommand(newPushCmd()))
 	root.AddCommand(newReflogCmd())
+	root.AddCommand(newRevListCmd())
+	root.AddCommand(newRevParseCmd())
+	root.AddCommand(newCatFileCmd())
+	root.AddCommand(newHashObjectCmd())
+	root.AddCommand(newLsTreeCmd())
+	root.AddCommand(newUpdateRefCmd())
+	root.AddCommand(newSymbolicRefCmd())
+	root.AddCommand(newFastExportCmd())
+	root.AddCommand(newFastImportCmd())
+	root.AddCommand(newImportGitCmd())
+	root.AddCommand(newExportGitCmd())
+	root.AddCommand(newGitSyncCmd())
+	root.AddCommand(newDescribeCmd())
 	root.AddCommand(newGcCmd())
 	root.AddCommand(newVerifyCmd())
+	root.AddCommand(newFsckCmd())
+	root.AddCommand(newCountObjectsCmd())
+	root.AddCommand(newEncryptCmd())
 	root.AddCommand(newStashCmd())
 	root.AddCommand(newRebaseCmd())
 	root.AddCommand(newSparseCheckoutCmd())
@@ -57,6 +83,9 @@ func main() {
 	root.AddCommand(newCleanCmd())
 	root.AddCommand(newGrepCmd())
 	root.AddCommand(newShortlogCmd())
+	root.AddCommand(newStatsCmd())
+	root.AddCommand(newGraphCmd())
+	root.AddCommand(newRewriteHistoryCmd())
 	root.AddCommand(newArchiveCmd())
 	root.AddCommand(newModuleCmd())
 	root.AddCommand(newRepairCmd())
@@ -65,14 +94,18 @@ func main() {
 	root.AddCommand(newCoorddCmd())
 	root.AddCommand(newWorkspaceCmd())
 	root.AddCommand(newMCPCmd())
+	root.AddCommand(newRPCCmd())
+	root.AddCommand(newDaemonCmd())
+	root.AddCommand(newWebCmd())
 
-	if err := root.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		var exitCoder interface{ ExitCode() int }
-		if errors.As(err, &exitCoder) {
-			os.Exit(exitCoder.ExitCode())
+	err := root.Execute()
+	closePager()
+	if err != nil {
+		var status exitStatus
+		if !errors.As(err, &status) {
+			fmt.Fprintln(os.Stderr, err)
 		}
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/odvcencio/graft/pkg/userconfig"
+	"github.com/spf13/cobra"
+)
+
+// Values of --color and color.ui.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// ANSI escape sequences used for colored output.
+const (
+	ansiReset  = "\x1b[m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// pagedAnnotation marks a command whose output is piped through the pager
+// when it goes to a terminal.
+const pagedAnnotation = "graft.paged"
+
+// jsonAnnotation marks a command that honors the global --json flag.
+// Commands that define a --json flag of their own shadow the global one
+// and need no annotation.
+const jsonAnnotation = "graft.json"
+
+// defaultPager is used when neither $GRAFT_PAGER, core.pager nor $PAGER
+// names one.
+const defaultPager = "less"
+
+// activePager is the pager started for the running command, if any. main
+// closes it once the command has finished.
+var activePager *pagerWriter
+
+// pagedCommand marks cmd as one whose output is paged and returns it.
+func pagedCommand(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[pagedAnnotation] = "true"
+	return cmd
+}
+
+// jsonCommand marks cmd as one that honors the global --json flag and
+// returns it.
+func jsonCommand(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[jsonAnnotation] = "true"
+	return cmd
+}
+
+// jsonOutput reports whether cmd should write JSON instead of text, from
+// the global --json flag or a command's own.
+func jsonOutput(cmd *cobra.Command) bool {
+	enabled, _ := cmd.Flags().GetBool("json")
+	return enabled
+}
+
+// addOutputFlags registers the global --color, --no-pager, --json and
+// --quiet flags on root and starts the pager before a paged command runs.
+func addOutputFlags(root *cobra.Command) {
+	root.PersistentFlags().String("color", "", "color output: auto, always or never (default color.ui, else auto)")
+	root.PersistentFlags().Bool("no-pager", false, "do not pipe log, diff and blame output into a pager")
+	root.PersistentFlags().Bool("json", false, "write structured JSON instead of text, for commands that support it")
+	root.PersistentFlags().BoolP("quiet", "q", false, "print nothing but errors and warnings; the exit status tells the outcome")
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		recordCompletionArgs(cmd, args)
+		// Arguments have been validated by now; a failure from here on is
+		// an outcome, not a usage mistake.
+		cmd.SilenceUsage = true
+		if value, _ := cmd.Flags().GetString("color"); value != "" {
+			if _, err := parseColorMode(value); err != nil {
+				return fmt.Errorf("--color: %w", err)
+			}
+		}
+		// A command with a --json flag of its own has shadowed the global
+		// one; any other must be marked as producing JSON.
+		if jsonOutput(cmd) && cmd.Flags().Lookup("json") == root.PersistentFlags().Lookup("json") && cmd.Annotations[jsonAnnotation] == "" {
+			return fmt.Errorf("%s does not support --json", cmd.CommandPath())
+		}
+		// A command with a --quiet flag of its own decides what it prints.
+		if quiet, _ := cmd.Flags().GetBool("quiet"); quiet && cmd.Flags().Lookup("quiet") == root.PersistentFlags().Lookup("quiet") {
+			if jsonOutput(cmd) {
+				return fmt.Errorf("--quiet and --json cannot be used together")
+			}
+			cmd.SetOut(io.Discard)
+			return nil
+		}
+		if cmd.Annotations[pagedAnnotation] == "" {
+			return nil
+		}
+		if noPager, _ := cmd.Flags().GetBool("no-pager"); noPager {
+			return nil
+		}
+		startPager(cmd)
+		return nil
+	}
+}
+
+// parseColorMode validates a --color or color.ui value.
+func parseColorMode(value string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case colorAuto, colorAlways, colorNever:
+		return mode, nil
+	}
+	return "", fmt.Errorf("%q (want auto, always or never)", value)
+}
+
+// outputSettings returns the pager and color.ui values configured for the
+// current repository, falling back to the user config for each.
+func outputSettings() (pager, color string) {
+	if r, err := repo.Open("."); err == nil {
+		if cfg, err := r.ReadConfig(); err == nil {
+			if cfg.Core != nil {
+				pager = cfg.Core.Pager
+			}
+			if cfg.Color != nil {
+				color = cfg.Color.UI
+			}
+		}
+	}
+	if pager != "" && color != "" {
+		return pager, color
+	}
+	if ucfg, err := userconfig.Load(); err == nil {
+		if pager == "" {
+			pager = ucfg.Pager
+		}
+		if color == "" {
+			color = ucfg.ColorUI
+		}
+	}
+	return pager, color
+}
+
+// colorEnabled reports whether cmd should color its output: --color, then
+// color.ui, decide, and in auto mode output is colored when it goes to a
+// terminal or the pager, $NO_COLOR is unset and $TERM is not "dumb".
+func colorEnabled(cmd *cobra.Command) bool {
+	mode := ""
+	if f := cmd.Flag("color"); f != nil {
+		mode = f.Value.String()
+	}
+	if mode == "" {
+		_, mode = outputSettings()
+	}
+	switch mode, _ = parseColorMode(mode); mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	out := cmd.OutOrStdout()
+	if _, ok := out.(*pagerWriter); ok {
+		return true
+	}
+	return isTerminalOutput(out)
+}
+
+// paint wraps s in the escape sequence code when enabled is set.
+func paint(enabled bool, code, s string) string {
+	if !enabled || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// pagerCommand returns the pager to use: $GRAFT_PAGER, core.pager, $PAGER
+// or less, in that order. An empty result or "cat" means no pager.
+func pagerCommand() string {
+	if pager, ok := os.LookupEnv("GRAFT_PAGER"); ok {
+		return strings.TrimSpace(pager)
+	}
+	if pager, _ := outputSettings(); pager != "" {
+		return strings.TrimSpace(pager)
+	}
+	if pager, ok := os.LookupEnv("PAGER"); ok {
+		return strings.TrimSpace(pager)
+	}
+	return defaultPager
+}
+
+// startPager pipes cmd's output through the pager when it would go to a
+// terminal. A pager whose program cannot be found is reported and skipped.
+func startPager(cmd *cobra.Command) {
+	if cmd.OutOrStdout() != io.Writer(os.Stdout) || !isTerminalOutput(os.Stdout) {
+		return
+	}
+	command := pagerCommand()
+	if command == "" || command == "cat" {
+		return
+	}
+	if _, err := exec.LookPath(strings.Fields(command)[0]); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: cannot start pager %q: %v\n", command, err)
+		return
+	}
+
+	env := os.Environ()
+	// Like git: quit when the output fits on one screen, pass colors
+	// through and leave the screen as it is on exit.
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		env = append(env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		env = append(env, "LV=-c")
+	}
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := repo.RunExternalProcess(repo.ExternalProcessSpec{
+			Path:   "sh",
+			Args:   []string{"-c", command},
+			Env:    env,
+			Stdin:  pr,
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
+			Label:  "pager",
+		})
+		// Unblock writers once the pager is gone.
+		pr.CloseWithError(err)
+	}()
+	activePager = &pagerWriter{w: pw, done: done}
+	cmd.SetOut(activePager)
+}
+
+// closePager waits for the pager started by startPager, if any, to exit.
+func closePager() {
+	if activePager == nil {
+		return
+	}
+	activePager.w.Close()
+	<-activePager.done
+	activePager = nil
+}
+
+// pagerWriter feeds the pager. Once the pager has gone away, for example
+// because the user quit it, further output is discarded rather than
+// reported as an error.
+type pagerWriter struct {
+	w      io.WriteCloser
+	done   chan struct{}
+	broken bool
+}
+
+func (p *pagerWriter) Write(b []byte) (int, error) {
+	if !p.broken {
+		if _, err := p.w.Write(b); err != nil {
+			p.broken = true
+		}
+	}
+	return len(b), nil
+}
+
+// diffColorWriter colors unified diff output line by line: file headers
+// bold, hunk headers cyan, and removed and added lines red and green.
+type diffColorWriter struct {
+	w      io.Writer
+	buf    []byte
+	inHunk bool
+}
+
+func newDiffColorWriter(w io.Writer) *diffColorWriter {
+	return &diffColorWriter{w: w}
+}
+
+func (d *diffColorWriter) Write(p []byte) (int, error) {
+	d.buf = append(d.buf, p...)
+	for {
+		i := bytes.IndexByte(d.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := io.WriteString(d.w, d.colorLine(string(d.buf[:i]))+"\n"); err != nil {
+			return 0, err
+		}
+		d.buf = d.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a trailing partial line, if any.
+func (d *diffColorWriter) Flush() error {
+	if len(d.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(d.w, d.colorLine(string(d.buf)))
+	d.buf = nil
+	return err
+}
+
+func (d *diffColorWriter) colorLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "diff --"):
+		d.inHunk = false
+		return paint(true, ansiBold, line)
+	case strings.HasPrefix(line, "@@"):
+		d.inHunk = true
+		return paint(true, ansiCyan, line)
+	case !d.inHunk:
+		if strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ") ||
+			strings.HasPrefix(line, "rename from ") || strings.HasPrefix(line, "rename to ") {
+			return paint(true, ansiBold, line)
+		}
+	case strings.HasPrefix(line, "-"):
+		return paint(true, ansiRed, line)
+	case strings.HasPrefix(line, "+"):
+		return paint(true, ansiGreen, line)
+	}
+	return line
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDiffColorWriterColorsHeadersHunksAndLines(t *testing.T) {
+	var buf bytes.Buffer
+	w := newDiffColorWriter(&buf)
+	// Write in pieces that split lines to exercise the line buffering.
+	for _, chunk := range []string{
+		"diff --graft a/f b/f\n--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-",
+		"-- b\n+c\n",
+		"diff --graft a/g b/g\n--- a/g\n+++ b/g\nno newline",
+	} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	want := strings.Join([]string{
+		ansiBold + "diff --graft a/f b/f" + ansiReset,
+		ansiBold + "--- a/f" + ansiReset,
+		ansiBold + "+++ b/f" + ansiReset,
+		ansiCyan + "@@ -1,2 +1,2 @@" + ansiReset,
+		" a",
+		ansiRed + "--- b" + ansiReset,
+		ansiGreen + "+c" + ansiReset,
+		ansiBold + "diff --graft a/g b/g" + ansiReset,
+		ansiBold + "--- a/g" + ansiReset,
+		ansiBold + "+++ b/g" + ansiReset,
+		"no newline",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Fatalf("colored diff =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestParseColorMode(t *testing.T) {
+	for _, value := range []string{"auto", "ALWAYS", " never "} {
+		if _, err := parseColorMode(value); err != nil {
+			t.Errorf("parseColorMode(%q): %v", value, err)
+		}
+	}
+	if _, err := parseColorMode("sometimes"); err == nil {
+		t.Error("parseColorMode(sometimes) succeeded, want error")
+	}
+}
+
+func TestPagerCommandPrecedence(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	dir := initRepo(t)
+	restore := chdirForTest(t, dir)
+	defer restore()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GRAFT_PAGER", "")
+	os.Unsetenv("GRAFT_PAGER")
+
+	t.Setenv("PAGER", "more")
+	if got := pagerCommand(); got != "more" {
+		t.Fatalf("pagerCommand with $PAGER = %q, want more", got)
+	}
+	mustRunGraft(t, dir, "config", "core.pager", "less -S")
+	if got := pagerCommand(); got != "less -S" {
+		t.Fatalf("pagerCommand with core.pager = %q, want %q", got, "less -S")
+	}
+	t.Setenv("GRAFT_PAGER", "cat")
+	if got := pagerCommand(); got != "cat" {
+		t.Fatalf("pagerCommand with $GRAFT_PAGER = %q, want cat", got)
+	}
+}
+
+func TestColorFlagAndConfigIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	dir := initRepo(t)
+	commitFile(t, dir, "notes.txt", "one\n", "first")
+
+	if out := mustRunGraft(t, dir, "log", "--oneline"); strings.Contains(out, "\x1b[") {
+		t.Fatalf("log to a pipe is colored by default: %q", out)
+	}
+	if out := mustRunGraft(t, dir, "log", "--oneline", "--color=always"); !strings.Contains(out, ansiYellow) {
+		t.Fatalf("log --color=always is not colored: %q", out)
+	}
+
+	mustRunGraft(t, dir, "config", "color.ui", "always")
+	writeFile(t, dir, "notes.txt", "two\n")
+	out := mustRunGraft(t, dir, "diff")
+	if !strings.Contains(out, ansiRed+"-one"+ansiReset) || !strings.Contains(out, ansiGreen+"+two"+ansiReset) {
+		t.Fatalf("diff with color.ui=always is not colored: %q", out)
+	}
+	if out := mustRunGraft(t, dir, "diff", "--color=never"); strings.Contains(out, "\x1b[") {
+		t.Fatalf("diff --color=never is colored: %q", out)
+	}
+
+	if out, err := runGraft(t, dir, "log", "--color=sometimes"); err == nil {
+		t.Fatalf("log --color=sometimes succeeded: %q", out)
+	}
+}
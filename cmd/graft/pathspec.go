@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/odvcencio/graft/pkg/pathspec"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+// argsBeforeDash returns how many of args precede a "--" separator, or
+// len(args) when there is none.
+func argsBeforeDash(cmd *cobra.Command, args []string) int {
+	if n := cmd.ArgsLenAtDash(); n >= 0 && n <= len(args) {
+		return n
+	}
+	return len(args)
+}
+
+// parsePathspecArgs parses the pathspecs given after "--". It returns nil,
+// which matches every path, when there are none.
+func parsePathspecArgs(r *repo.Repo, args []string) (*pathspec.Set, error) {
+	filter, err := r.ParsePathspecs(args)
+	if err != nil || filter.Empty() {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// matchRenamePathspec reports whether filter selects a path or, for a rename,
+// the other side of it.
+func matchRenamePathspec(filter *pathspec.Set, path, other string) bool {
+	return filter.Match(path) || (other != "" && filter.Match(other))
+}
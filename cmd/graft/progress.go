@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+	"github.com/spf13/cobra"
+)
+
+// progressInterval throttles how often the progress line is redrawn.
+const progressInterval = 100 * time.Millisecond
+
+// fileProgressPhases are the progress phases whose counts are files.
+var fileProgressPhases = map[string]bool{
+	repo.ProgressStaging:     true,
+	repo.ProgressExtracting:  true,
+	repo.ProgressCheckingOut: true,
+	repo.ProgressMerging:     true,
+}
+
+// progressMeter renders object.Progress updates as a single status line that
+// is redrawn in place, e.g.
+//
+//	receiving: 1200 objects, 14.2 MiB | 3.1 MiB/s
+//
+// Throughput is measured from the first update of each phase.
+type progressMeter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	now     func() time.Time
+	started map[string]time.Time
+	last    object.Progress
+	drawn   time.Time
+	width   int
+	pending bool
+}
+
+func newProgressMeter(w io.Writer) *progressMeter {
+	return &progressMeter{w: w, now: time.Now, started: make(map[string]time.Time)}
+}
+
+// addProgressFlag registers --progress on cmd and returns a function that
+// builds the meter for a run of the command: progress is shown when stderr is
+// a terminal or --progress is set, and the meter is nil otherwise. A nil
+// meter is safe to use.
+func addProgressFlag(cmd *cobra.Command) func() *progressMeter {
+	var force bool
+	cmd.Flags().BoolVar(&force, "progress", false, "report progress on stderr even when it is not a terminal")
+	return func() *progressMeter {
+		errOut := cmd.ErrOrStderr()
+		if !force && !isTerminalOutput(errOut) {
+			return nil
+		}
+		return newProgressMeter(errOut)
+	}
+}
+
+// isTerminalOutput reports whether w is a character device such as a tty.
+func isTerminalOutput(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return (stat.Mode() & os.ModeCharDevice) != 0
+}
+
+// Func returns the callback to hand to the store and transport, or nil when
+// m is nil.
+func (m *progressMeter) Func() object.ProgressFunc {
+	if m == nil {
+		return nil
+	}
+	return m.Update
+}
+
+// Update records p and redraws the status line if the phase changed or the
+// redraw interval has elapsed.
+func (m *progressMeter) Update(p object.Progress) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if _, ok := m.started[p.Phase]; !ok {
+		m.started[p.Phase] = now
+	}
+	phaseChanged := p.Phase != m.last.Phase
+	m.last = p
+	m.pending = true
+	if !phaseChanged && now.Sub(m.drawn) < progressInterval {
+		return
+	}
+	m.draw(now)
+}
+
+// Done draws the final state and ends the status line. It must be called
+// before the command prints its own output.
+func (m *progressMeter) Done() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending {
+		m.draw(m.now())
+	}
+	if m.width > 0 {
+		fmt.Fprintln(m.w)
+		m.width = 0
+	}
+}
+
+func (m *progressMeter) draw(now time.Time) {
+	line := formatProgress(m.last, now.Sub(m.started[m.last.Phase]))
+	pad := ""
+	if n := m.width - len(line); n > 0 {
+		pad = strings.Repeat(" ", n)
+	}
+	fmt.Fprintf(m.w, "\r%s%s", line, pad)
+	m.width = len(line)
+	m.drawn = now
+	m.pending = false
+}
+
+// formatProgress renders one progress line. Object counts are omitted for
+// byte-only phases, and throughput is omitted until time has elapsed. The
+// phases of repository operations count files.
+func formatProgress(p object.Progress, elapsed time.Duration) string {
+	unit := "objects"
+	if fileProgressPhases[p.Phase] {
+		unit = "files"
+	}
+	var parts []string
+	switch {
+	case p.Total > 0:
+		parts = append(parts, fmt.Sprintf("%d/%d %s", p.Objects, p.Total, unit))
+	case p.Objects > 0:
+		parts = append(parts, fmt.Sprintf("%d %s", p.Objects, unit))
+	}
+	if p.Bytes > 0 {
+		parts = append(parts, formatBinaryBytes(p.Bytes))
+	}
+	line := p.Phase + ": " + strings.Join(parts, ", ")
+	if p.Bytes > 0 && elapsed > 0 {
+		rate := int64(float64(p.Bytes) / elapsed.Seconds())
+		line += " | " + formatBinaryBytes(rate) + "/s"
+	}
+	return line
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+func TestProgressMeterThrottlesAndRendersThroughput(t *testing.T) {
+	var out bytes.Buffer
+	m := newProgressMeter(&out)
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+
+	m.Update(object.Progress{Phase: object.ProgressReceiving, Objects: 1, Bytes: 1 << 20})
+	now = now.Add(10 * time.Millisecond)
+	m.Update(object.Progress{Phase: object.ProgressReceiving, Objects: 2, Bytes: 2 << 20})
+	if strings.Contains(out.String(), "2 objects") {
+		t.Fatalf("update within the redraw interval was drawn: %q", out.String())
+	}
+	now = now.Add(2 * time.Second)
+	m.Update(object.Progress{Phase: object.ProgressReceiving, Objects: 3, Bytes: 4 << 20})
+	m.Done()
+
+	got := out.String()
+	if !strings.HasSuffix(got, "receiving: 3 objects, 4.0 MiB | 2.0 MiB/s\n") {
+		t.Fatalf("progress output = %q", got)
+	}
+	if strings.Count(got, "\n") != 1 {
+		t.Fatalf("progress output spans several lines: %q", got)
+	}
+}
+
+func TestFormatProgress(t *testing.T) {
+	tests := []struct {
+		p       object.Progress
+		elapsed time.Duration
+		want    string
+	}{
+		{object.Progress{Phase: "packing", Objects: 5, Total: 10, Bytes: 2048}, 0, "packing: 5/10 objects, 2.0 KiB"},
+		{object.Progress{Phase: "downloading", Bytes: 3 << 20}, time.Second, "downloading: 3.0 MiB | 3.0 MiB/s"},
+		{object.Progress{Phase: "counting", Objects: 7}, time.Second, "counting: 7 objects"},
+		{object.Progress{Phase: repo.ProgressCheckingOut, Objects: 3, Total: 9}, time.Second, "checking out: 3/9 files"},
+	}
+	for _, tc := range tests {
+		if got := formatProgress(tc.p, tc.elapsed); got != tc.want {
+			t.Errorf("formatProgress(%+v) = %q, want %q", tc.p, got, tc.want)
+		}
+	}
+}
+
+func TestNilProgressMeterIsSafe(t *testing.T) {
+	var m *progressMeter
+	if m.Func() != nil {
+		t.Fatal("nil meter returned a callback")
+	}
+	m.Done()
+}
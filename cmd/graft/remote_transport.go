@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/odvcencio/graft/pkg/remote"
@@ -21,6 +22,14 @@ func parseRemoteSpec(raw string) (remoteTransportKind, string, error) {
 	if err != nil {
 		return "", "", err
 	}
+	if _, ok := remote.LocalRepoDir(canonical); ok {
+		if !strings.Contains(canonical, "://") {
+			if abs, err := filepath.Abs(canonical); err == nil {
+				canonical = abs
+			}
+		}
+		return remoteTransportGraft, canonical, nil
+	}
 	if shouldUseGitTransport(canonical) {
 		return remoteTransportGit, canonical, nil
 	}
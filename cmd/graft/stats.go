@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/remote"
+	"github.com/spf13/cobra"
+)
+
+// addStatsFlag registers --stats on cmd and returns the flag's value.
+func addStatsFlag(cmd *cobra.Command) *bool {
+	var show bool
+	cmd.Flags().BoolVar(&show, "stats", false, "print transfer statistics on stderr when done")
+	return &show
+}
+
+// printTransferStats writes a one-line transfer summary to w when show is set.
+func printTransferStats(w io.Writer, show bool, s remote.TransferStats) {
+	if show {
+		fmt.Fprintln(w, formatTransferStats(s))
+	}
+}
+
+// formatTransferStats renders s as a single summary line, e.g.
+//
+//	transfer: received 1200 objects (14.2 MiB), 4.1 MiB on the wire (3.5x), 2 rounds, 5 requests in 1.8s
+//
+// Directions and counters that saw no traffic are omitted.
+func formatTransferStats(s remote.TransferStats) string {
+	var parts []string
+	if s.ObjectsReceived > 0 || s.ObjectsSent == 0 {
+		parts = append(parts, formatStatsObjects("received", s.ObjectsReceived, s.ObjectBytesReceived))
+	}
+	if s.ObjectsSent > 0 {
+		parts = append(parts, formatStatsObjects("sent", s.ObjectsSent, s.ObjectBytesSent))
+	}
+	if wire := s.WireBytesSent + s.WireBytesReceived; wire > 0 {
+		part := formatBinaryBytes(wire) + " on the wire"
+		if ratio := s.CompressionRatio(); ratio > 0 {
+			part += fmt.Sprintf(" (%.1fx)", ratio)
+		}
+		parts = append(parts, part)
+	}
+	if s.Rounds > 0 {
+		parts = append(parts, pluralize(s.Rounds, "round"))
+	}
+	if s.Requests > 0 {
+		parts = append(parts, pluralize(s.Requests, "request"))
+	}
+	return "transfer: " + strings.Join(parts, ", ") + " in " + s.Duration.Round(time.Millisecond).String()
+}
+
+func formatStatsObjects(verb string, objects int, bytes int64) string {
+	out := verb + " " + pluralize(objects, "object")
+	if bytes > 0 {
+		out += " (" + formatBinaryBytes(bytes) + ")"
+	}
+	return out
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/remote"
+)
+
+func TestFormatTransferStats(t *testing.T) {
+	tests := []struct {
+		s    remote.TransferStats
+		want string
+	}{
+		{
+			remote.TransferStats{Requests: 5, Rounds: 2, ObjectsReceived: 1200, ObjectBytesReceived: 14 << 20, WireBytesSent: 1 << 20, WireBytesReceived: 3 << 20, Duration: 1800 * time.Millisecond},
+			"transfer: received 1200 objects (14.0 MiB), 4.0 MiB on the wire (3.5x), 2 rounds, 5 requests in 1.8s",
+		},
+		{
+			remote.TransferStats{Requests: 1, ObjectsSent: 1, ObjectBytesSent: 2048, WireBytesSent: 1024, Duration: 12345 * time.Microsecond},
+			"transfer: sent 1 object (2.0 KiB), 1.0 KiB on the wire (2.0x), 1 request in 12ms",
+		},
+		{remote.TransferStats{}, "transfer: received 0 objects in 0s"},
+	}
+	for _, tc := range tests {
+		if got := formatTransferStats(tc.s); got != tc.want {
+			t.Errorf("formatTransferStats(%+v) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/diff3"
+)
+
+// wordDiffMode selects how diff marks changed words within lines.
+type wordDiffMode int
+
+const (
+	wordDiffNone  wordDiffMode = iota // whole-line diff
+	wordDiffPlain                     // [-removed-]{+added+}
+	wordDiffColor                     // removed in red, added in green
+)
+
+// parseWordDiffMode reads the --word-diff and --color-words flags.
+func parseWordDiffMode(value string, colorWords bool) (wordDiffMode, error) {
+	if colorWords {
+		return wordDiffColor, nil
+	}
+	switch value {
+	case "", "none":
+		return wordDiffNone, nil
+	case "plain":
+		return wordDiffPlain, nil
+	case "color":
+		return wordDiffColor, nil
+	default:
+		return wordDiffNone, fmt.Errorf("invalid --word-diff mode %q: expected plain, color or none", value)
+	}
+}
+
+// markers returns the text written around removed and added words.
+func (m wordDiffMode) markers() (delOpen, delClose, insOpen, insClose string) {
+	if m == wordDiffColor {
+		return "\x1b[31m", "\x1b[m", "\x1b[32m", "\x1b[m"
+	}
+	return "[-", "-]", "{+", "+}"
+}
+
+// printWordDiff prints a diff for a single file with the same headers and
+// hunks as printLineDiff, but each run of changed lines is shown once, with
+// the words that differ marked inline. Context lines have no prefix.
+func printWordDiff(out io.Writer, path string, before, after []byte, mode wordDiffMode) error {
+	if before == nil {
+		before = []byte{}
+	}
+	if after == nil {
+		after = []byte{}
+	}
+
+	if bytes.Equal(before, after) {
+		return nil
+	}
+
+	fmt.Fprintf(out, "diff --graft a/%s b/%s\n", path, path)
+	fmt.Fprintf(out, "--- a/%s\n", path)
+	fmt.Fprintf(out, "+++ b/%s\n", path)
+
+	lines := diff3.LineDiff(before, after)
+	for _, h := range buildLineDiffHunks(lines, lineDiffContextLines) {
+		oldStart, oldCount, newStart, newCount := h.lineRange(lines)
+		fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+
+		hunk := lines[h.start:h.end]
+		for i := 0; i < len(hunk); {
+			if hunk[i].Type == diff3.Equal {
+				fmt.Fprintln(out, hunk[i].Content)
+				i++
+				continue
+			}
+			var removed, added []string
+			for ; i < len(hunk) && hunk[i].Type != diff3.Equal; i++ {
+				if hunk[i].Type == diff3.Delete {
+					removed = append(removed, hunk[i].Content)
+				} else {
+					added = append(added, hunk[i].Content)
+				}
+			}
+			fmt.Fprintln(out, renderWordDiff(strings.Join(removed, "\n"), strings.Join(added, "\n"), mode))
+		}
+	}
+
+	return nil
+}
+
+// renderWordDiff merges before and after into one text with the removed
+// and added words marked. Markers never span a line break.
+func renderWordDiff(before, after string, mode wordDiffMode) string {
+	delOpen, delClose, insOpen, insClose := mode.markers()
+
+	var b strings.Builder
+	ops := diff3.WordDiff(before, after)
+	for i := 0; i < len(ops); {
+		typ := ops[i].Type
+		var run strings.Builder
+		for ; i < len(ops) && ops[i].Type == typ; i++ {
+			run.WriteString(ops[i].Line)
+		}
+		if typ == diff3.Equal {
+			b.WriteString(run.String())
+			continue
+		}
+		openMark, closeMark := delOpen, delClose
+		if typ == diff3.Insert {
+			openMark, closeMark = insOpen, insClose
+		}
+		for j, piece := range strings.Split(run.String(), "\n") {
+			if j > 0 {
+				b.WriteByte('\n')
+			}
+			if piece != "" {
+				b.WriteString(openMark + piece + closeMark)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderWordDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		mode          wordDiffMode
+		want          string
+	}{
+		{"replaced word", "return total + tax", "return subtotal + tax", wordDiffPlain, "return [-total-]{+subtotal+} + tax"},
+		{"added words", "a b", "a new b", wordDiffPlain, "a {+new +}b"},
+		{"color", "x := 1", "x := 2", wordDiffColor, "x := \x1b[31m1\x1b[m\x1b[32m2\x1b[m"},
+		{"markers stop at line breaks", "one\ntwo", "", wordDiffPlain, "[-one-]\n[-two-]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderWordDiff(tt.before, tt.after, tt.mode); got != tt.want {
+				t.Fatalf("renderWordDiff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWordDiffMode(t *testing.T) {
+	for value, want := range map[string]wordDiffMode{"none": wordDiffNone, "plain": wordDiffPlain, "color": wordDiffColor} {
+		if got, err := parseWordDiffMode(value, false); err != nil || got != want {
+			t.Fatalf("parseWordDiffMode(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if got, _ := parseWordDiffMode("none", true); got != wordDiffColor {
+		t.Fatalf("--color-words should select color mode, got %v", got)
+	}
+	if _, err := parseWordDiffMode("porcelain", false); err == nil {
+		t.Fatal("expected an error for an unsupported mode")
+	}
+}
+
+func TestDiffWordDiffIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	dir := initRepo(t)
+	commitFile(t, dir, "notes.txt", "first line\nthe quick brown fox\nlast line\n", "initial")
+	writeFile(t, dir, "notes.txt", "first line\nthe quick red fox\nlast line\n")
+
+	out := mustRunGraft(t, dir, "diff", "--word-diff")
+	if !strings.Contains(out, "\nthe quick [-brown-]{+red+} fox\n") || !strings.Contains(out, "\nfirst line\n") {
+		t.Fatalf("diff --word-diff output:\n%s", out)
+	}
+	if colored := mustRunGraft(t, dir, "diff", "--color-words"); !strings.Contains(colored, "\x1b[31mbrown\x1b[m\x1b[32mred\x1b[m") {
+		t.Fatalf("diff --color-words output:\n%q", colored)
+	}
+	if _, err := runGraft(t, dir, "diff", "--word-diff", "--entity"); err == nil {
+		t.Fatal("expected an error combining --word-diff with --entity")
+	}
+}
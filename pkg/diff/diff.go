@@ -11,8 +11,27 @@ const (
 	Added    ChangeType = iota // Entity exists only in the after revision.
 	Removed                    // Entity exists only in the before revision.
 	Modified                   // Entity exists in both revisions but its body changed.
+	Moved                      // Entity is unchanged but was reordered (StructuralDiff only).
+	Renamed                    // Entity was renamed, possibly with edits (StructuralDiff only).
 )
 
+// String returns the lower-case name of the change, e.g. "added".
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	case Moved:
+		return "moved"
+	case Renamed:
+		return "renamed"
+	}
+	return "unknown"
+}
+
 // EntityChange records a single entity-level change between two revisions of a file.
 type EntityChange struct {
 	Type   ChangeType
@@ -46,6 +46,65 @@ func FormatEntityDiff(d *FileDiff) string {
 	return b.String()
 }
 
+// FormatStructuralDiff produces a human-readable summary of a StructuralDiff,
+// naming each declaration by its signature so that signature changes,
+// renames and moves can be read at a glance.
+//
+// Output format:
+//
+//	path:
+//	  + func Name(a int) error                  (added)
+//	  ~ func Name(a int)                        (modified)
+//	  ~ func Name(a int) -> func Name(a, b int) (modified)
+//	  R func Old() -> func New()                (renamed)
+//	  > func Name()                             (moved, line 3 -> 40)
+//	  - func Name()                             (removed)
+func FormatStructuralDiff(d *FileDiff) string {
+	if len(d.Changes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n", d.Path)
+
+	for _, c := range d.Changes {
+		var marker, label, name string
+		switch c.Type {
+		case Added:
+			marker, label, name = "+", "added", signatureContext(c.After, c.Key)
+		case Removed:
+			marker, label, name = "-", "removed", signatureContext(c.Before, c.Key)
+		case Modified:
+			marker, label = "~", "modified"
+			name = signatureContext(c.After, c.Key)
+			if before := signatureContext(c.Before, c.Key); before != name {
+				name = before + " -> " + name
+			}
+		case Renamed:
+			marker, label = "R", "renamed"
+			name = signatureContext(c.Before, c.Key) + " -> " + signatureContext(c.After, c.Key)
+		case Moved:
+			marker, label = ">", fmt.Sprintf("moved, line %d -> %d", c.Before.StartLine, c.After.StartLine)
+			name = signatureContext(c.After, c.Key)
+		}
+		fmt.Fprintf(&b, "  %s %s     (%s)\n", marker, name, label)
+	}
+
+	return b.String()
+}
+
+// signatureContext names an entity by its declaration signature, falling
+// back to its display name, or to key for non-declarations.
+func signatureContext(e *entity.Entity, key string) string {
+	if e == nil || e.Kind != entity.KindDeclaration {
+		return key
+	}
+	if e.Signature != "" {
+		return e.Signature
+	}
+	return entity.EntityDisplayName(e)
+}
+
 // FormatLineDiff produces a unified-diff-style output showing line-level
 // changes within modified entities. Only Modified changes produce output;
 // Added/Removed entities are shown in full.
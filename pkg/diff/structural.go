@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/merge"
+)
+
+// StructuralDiff computes an entity-level diff like DiffFiles, and also
+// classifies declarations that were renamed (Renamed) or reordered without
+// changing their body (Moved). Entities are paired with merge.MatchEntities,
+// so renames are detected the same way a merge detects them; a declaration
+// that keeps its name but changes its signature is reported as Modified.
+// Interstitial comments and whitespace are not reported, since they follow
+// the declarations around them.
+func StructuralDiff(path string, before, after []byte) (*FileDiff, error) {
+	beforeList, err := entity.Extract(path, before)
+	if err != nil {
+		return nil, err
+	}
+	afterList, err := entity.Extract(path, after)
+	if err != nil {
+		return nil, err
+	}
+
+	// Matching before against after, with before standing in for the
+	// unchanged side, maps each "ours" disposition onto a two-way change.
+	// changes is in match order; an entry with Type Moved is a matched,
+	// unchanged pair that is only reported if it turns out to have moved.
+	var changes []EntityChange
+	for _, m := range merge.MatchEntities(beforeList, afterList, beforeList) {
+		switch m.Disposition {
+		case merge.AddedOurs:
+			changes = append(changes, EntityChange{Type: Added, Key: m.Key, After: m.Ours})
+		case merge.DeletedOurs:
+			changes = append(changes, EntityChange{Type: Removed, Key: m.Key, Before: m.Base})
+		case merge.RenamedOurs:
+			changes = append(changes, EntityChange{Type: Renamed, Key: m.Key, Before: m.Base, After: m.Ours})
+		case merge.OursOnly:
+			changes = append(changes, EntityChange{Type: Modified, Key: m.Key, Before: m.Base, After: m.Ours})
+		case merge.Unchanged:
+			if m.Base != nil && m.Ours != nil {
+				changes = append(changes, EntityChange{Type: Moved, Key: m.Key, Before: m.Base, After: m.Ours})
+			}
+		}
+	}
+	changes = pairSignatureChanges(changes)
+	moved := movedDeclarations(beforeList, afterList, changes)
+
+	fd := &FileDiff{Path: path}
+	for _, c := range changes {
+		e := c.After
+		if e == nil {
+			e = c.Before
+		}
+		if e.Kind == entity.KindInterstitial || (c.Type == Moved && !moved[c.Key]) {
+			continue
+		}
+		fd.Changes = append(fd.Changes, c)
+	}
+	return fd, nil
+}
+
+// pairSignatureChanges turns a removed and an added declaration with the
+// same kind, receiver and name into one Modified change, and renames that
+// kept the name into modifications. Changing a signature changes the
+// identity key, so matching reports these as separate entities.
+func pairSignatureChanges(changes []EntityChange) []EntityChange {
+	sameDecl := func(e *entity.Entity) string {
+		return e.DeclKind + "\x00" + e.Receiver + "\x00" + e.Name
+	}
+	removed := make(map[string][]int)
+	for i, c := range changes {
+		if c.Type == Removed && c.Before.Kind == entity.KindDeclaration {
+			id := sameDecl(c.Before)
+			removed[id] = append(removed[id], i)
+		}
+	}
+
+	dropped := make(map[int]bool)
+	for i := range changes {
+		c := &changes[i]
+		switch {
+		case c.Type == Renamed && c.Before.Name == c.After.Name:
+			c.Type = Modified
+		case c.Type == Added && c.After.Kind == entity.KindDeclaration:
+			id := sameDecl(c.After)
+			if len(removed[id]) == 0 {
+				continue
+			}
+			j := removed[id][0]
+			removed[id] = removed[id][1:]
+			c.Type, c.Before = Modified, changes[j].Before
+			dropped[j] = true
+		}
+	}
+
+	out := changes[:0]
+	for i, c := range changes {
+		if !dropped[i] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// movedDeclarations reports which declarations were reordered: among the
+// declarations present on both sides, those outside the longest common
+// subsequence of their before and after orders.
+func movedDeclarations(beforeList, afterList *entity.EntityList, changes []EntityChange) map[string]bool {
+	// Identify each matched pair by its after key, and map before keys to it.
+	pairOf := make(map[string]string)
+	isPair := make(map[string]bool)
+	for _, c := range changes {
+		if c.Before == nil || c.After == nil || c.After.Kind != entity.KindDeclaration {
+			continue
+		}
+		pairOf[c.Before.IdentityKey()] = c.Key
+		isPair[c.Key] = true
+	}
+	var a, b []string
+	for _, key := range entity.OrderedIdentityKeys(beforeList) {
+		if pair, ok := pairOf[key]; ok {
+			a = append(a, pair)
+		}
+	}
+	for _, key := range entity.OrderedIdentityKeys(afterList) {
+		if isPair[key] {
+			b = append(b, key)
+		}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	moved := make(map[string]bool, len(b))
+	for _, key := range b {
+		moved[key] = true
+	}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			delete(moved, a[i])
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return moved
+}
@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+const goStructuralBase = `package main
+
+import "fmt"
+
+func Parse(input string) error {
+	fmt.Println("parse", input)
+	return nil
+}
+
+func Render() {
+	fmt.Println("render one")
+	fmt.Println("render two")
+	fmt.Println("render three")
+}
+
+func Close() {
+	fmt.Println("close")
+}
+
+func Drop() {
+	fmt.Println("drop")
+}
+`
+
+const goStructuralAfter = `package main
+
+import "fmt"
+
+func Close() {
+	fmt.Println("close")
+}
+
+func Parse(input string, strict bool) error {
+	fmt.Println("parse", input)
+	return nil
+}
+
+func Draw() {
+	fmt.Println("render one")
+	fmt.Println("render two")
+	fmt.Println("render three")
+}
+
+func Open() {
+	fmt.Println("open")
+}
+`
+
+func TestStructuralDiff_ClassifiesChanges(t *testing.T) {
+	fd, err := StructuralDiff("main.go", []byte(goStructuralBase), []byte(goStructuralAfter))
+	if err != nil {
+		t.Fatalf("StructuralDiff: %v", err)
+	}
+
+	got := map[string]ChangeType{}
+	for _, c := range fd.Changes {
+		e := c.After
+		if e == nil {
+			e = c.Before
+		}
+		got[e.Name] = c.Type
+	}
+	want := map[string]ChangeType{
+		"Parse": Modified,
+		"Draw":  Renamed,
+		"Close": Moved,
+		"Drop":  Removed,
+		"Open":  Added,
+	}
+	for name, typ := range want {
+		if got[name] != typ {
+			t.Errorf("%s classified as %v, want %v (all: %v)", name, got[name], typ, got)
+		}
+	}
+	if len(fd.Changes) != len(want) {
+		t.Errorf("got %d changes, want %d: %v", len(fd.Changes), len(want), got)
+	}
+
+	out := FormatStructuralDiff(fd)
+	for _, line := range []string{
+		"~ func Parse(input string) error -> func Parse(input string, strict bool) error",
+		"R func Render() -> func Draw()",
+		"> func Close()     (moved, line",
+		"+ func Open()",
+		"- func Drop()",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("formatted diff missing %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestStructuralDiff_NoChanges(t *testing.T) {
+	fd, err := StructuralDiff("main.go", []byte(goStructuralBase), []byte(goStructuralBase))
+	if err != nil {
+		t.Fatalf("StructuralDiff: %v", err)
+	}
+	if len(fd.Changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", fd.Changes)
+	}
+	if out := FormatStructuralDiff(fd); out != "" {
+		t.Fatalf("expected empty output, got %q", out)
+	}
+}
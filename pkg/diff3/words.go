@@ -0,0 +1,52 @@
+package diff3
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// WordDiff computes a word-level diff between a and b. The text is split
+// into tokens — runs of letters, digits and underscores, runs of
+// whitespace other than newlines, newlines, and single punctuation
+// characters — and the token sequences are compared with MyersDiff, so
+// each DiffOp's Line holds one token.
+func WordDiff(a, b string) []DiffOp {
+	return MyersDiff(splitWords(a), splitWords(b))
+}
+
+// splitWords splits s into the tokens WordDiff compares. Concatenating the
+// tokens yields s.
+func splitWords(s string) []string {
+	var tokens []string
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		end := size
+		switch {
+		case isWordRune(r):
+			end = runEnd(s, size, isWordRune)
+		case r != '\n' && unicode.IsSpace(r):
+			end = runEnd(s, size, func(r rune) bool { return r != '\n' && unicode.IsSpace(r) })
+		}
+		tokens = append(tokens, s[:end])
+		s = s[end:]
+	}
+	return tokens
+}
+
+// runEnd returns the end of the run of runes in s, starting at start, that
+// satisfy match.
+func runEnd(s string, start int, match func(rune) bool) int {
+	end := start
+	for end < len(s) {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		if !match(r) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,39 @@
+package diff3
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitWords_RoundTrips(t *testing.T) {
+	s := "x := foo(bar_1, \"héllo\")\t// note\n  end"
+	tokens := splitWords(s)
+	if got := strings.Join(tokens, ""); got != s {
+		t.Fatalf("tokens do not rebuild the input: %q", got)
+	}
+	want := []string{"x", " ", ":", "=", " ", "foo", "(", "bar_1", ",", " ", "\"", "héllo", "\"", ")", "\t", "/", "/", " ", "note", "\n", "  ", "end"}
+	if strings.Join(tokens, "|") != strings.Join(want, "|") {
+		t.Fatalf("splitWords = %q, want %q", tokens, want)
+	}
+}
+
+func TestWordDiff_ChangesOnlyDifferingWords(t *testing.T) {
+	ops := WordDiff("return total + tax", "return subtotal + tax")
+	var deleted, inserted, equal []string
+	for _, op := range ops {
+		switch op.Type {
+		case Delete:
+			deleted = append(deleted, op.Line)
+		case Insert:
+			inserted = append(inserted, op.Line)
+		case Equal:
+			equal = append(equal, op.Line)
+		}
+	}
+	if strings.Join(deleted, "") != "total" || strings.Join(inserted, "") != "subtotal" {
+		t.Fatalf("deleted %q, inserted %q; want total -> subtotal", deleted, inserted)
+	}
+	if strings.Join(equal, "") != "return  + tax" {
+		t.Fatalf("equal tokens = %q", equal)
+	}
+}
@@ -0,0 +1,191 @@
+// Package graft is the embeddable Go API for graft repositories. It gathers
+// the everyday operations (opening and initializing repositories, staging,
+// status, committing, merging, fetching and walking history) behind one
+// Repository type, so servers and editor tooling can drive graft in process
+// instead of running the graft command.
+//
+// Every operation that can take a while accepts a context.Context. Read-only
+// work such as Status and Log stops as soon as the context is done. Commit
+// and Merge check the context until they start changing the repository and
+// then run to completion, so a cancellation never leaves a half-written
+// commit or merge behind. Errors caused by the context wrap its error, so
+// errors.Is(err, context.Canceled) and context.DeadlineExceeded work.
+//
+// The types and errors used here are those of package repo, re-exported
+// under the same names; Repository.Repo gives access to everything else
+// repo offers.
+package graft
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+// Types shared with package repo.
+type (
+	Hash          = object.Hash
+	Commit        = object.CommitObj
+	StatusEntry   = repo.StatusEntry
+	FileStatus    = repo.FileStatus
+	AddOptions    = repo.AddOptions
+	CommitOptions = repo.CommitOptions
+	MergeOptions  = repo.MergeOptions
+	MergeReport   = repo.MergeReport
+	FetchResult   = repo.FetchResult
+	LogEntry      = repo.LogEntry
+	LogOptions    = repo.LogOptions
+	Progress      = object.Progress
+	ProgressFunc  = object.ProgressFunc
+	Event         = repo.Event
+	EventFunc     = repo.EventFunc
+)
+
+// Errors shared with package repo, for use with errors.Is.
+var (
+	ErrNotARepo       = repo.ErrNotARepo
+	ErrRefNotFound    = repo.ErrRefNotFound
+	ErrMergeConflicts = repo.ErrMergeConflicts
+	ErrNonFastForward = repo.ErrNonFastForward
+	ErrAuthRequired   = repo.ErrAuthRequired
+)
+
+// Repository is an open graft repository. Its methods may be called from
+// one goroutine at a time.
+type Repository struct {
+	repo *repo.Repo
+}
+
+// Open opens the repository containing path, which may be its root or any
+// directory below it.
+func Open(path string) (*Repository, error) {
+	r, err := repo.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{repo: r}, nil
+}
+
+// Init creates a new, empty repository at path and opens it.
+func Init(path string) (*Repository, error) {
+	r, err := repo.Init(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{repo: r}, nil
+}
+
+// Repo returns the underlying repo.Repo for operations this package does
+// not cover.
+func (r *Repository) Repo() *repo.Repo {
+	return r.repo
+}
+
+// Root returns the root directory of the working tree.
+func (r *Repository) Root() string {
+	return r.repo.RootDir
+}
+
+// SetProgress registers fn to receive progress from long-running
+// operations: files staged, checked out and merged, and objects and bytes
+// moved by fetches and GC. A nil fn disables reporting.
+func (r *Repository) SetProgress(fn ProgressFunc) {
+	r.repo.SetProgress(fn)
+}
+
+// SetEventHook registers fn to receive the start and end of add, checkout,
+// merge and gc operations. A nil fn disables the hook.
+func (r *Repository) SetEventHook(fn EventFunc) {
+	r.repo.SetEventHook(fn)
+}
+
+// Resolve resolves a revision such as "HEAD", a branch or tag name, a hash
+// or "main~2" to a commit hash.
+func (r *Repository) Resolve(rev string) (Hash, error) {
+	return r.repo.ResolveRef(rev)
+}
+
+// Add stages paths, relative to the repository root, as the graft add
+// command does.
+func (r *Repository) Add(ctx context.Context, paths []string, opts AddOptions) error {
+	return r.repo.AddContext(ctx, paths, nil, opts)
+}
+
+// Status reports how the working tree and staging area differ from HEAD,
+// one entry per path that is not clean, sorted by path.
+func (r *Repository) Status(ctx context.Context) ([]StatusEntry, error) {
+	return r.repo.StatusContext(ctx)
+}
+
+// Commit records the staging area as a new commit on the current branch
+// and returns its hash. An empty author means the configured one.
+func (r *Repository) Commit(ctx context.Context, message, author string, opts CommitOptions) (Hash, error) {
+	if author == "" {
+		author = r.repo.ResolveAuthor()
+	}
+	return r.repo.CommitContext(ctx, message, author, opts)
+}
+
+// Merge merges branch into the current branch. A merge that stops on
+// conflicts is not an error: the report's HasConflicts is set and the
+// conflicts are left in the working tree to resolve.
+func (r *Repository) Merge(ctx context.Context, branch string, opts MergeOptions) (*MergeReport, error) {
+	return r.repo.MergeContext(ctx, branch, opts)
+}
+
+// Fetch downloads objects and refs from the named remote, updating its
+// remote-tracking refs but not the working tree.
+func (r *Repository) Fetch(ctx context.Context, remote string) (*FetchResult, error) {
+	return r.repo.FetchContext(ctx, remote)
+}
+
+// Log yields the history of rev, following first parents, newest first:
+// the commits opts.Filter accepts. An empty rev means HEAD, and a
+// repository without commits yields nothing. Stop ranging to stop the walk;
+// a walk cut short by ctx ends by yielding the context's error.
+func (r *Repository) Log(ctx context.Context, rev string, opts LogOptions) iter.Seq2[LogEntry, error] {
+	return func(yield func(LogEntry, error) bool) {
+		start, err := r.resolveLogStart(rev)
+		if err != nil {
+			yield(LogEntry{}, err)
+			return
+		}
+		for entry, err := range r.repo.LogIter(ctx, start, opts) {
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
+
+// LogAll yields the commits reachable from every branch and tag that
+// opts.Filter accepts, newest first, walking all parents unless
+// opts.FirstParent is set.
+func (r *Repository) LogAll(ctx context.Context, opts LogOptions) iter.Seq2[LogEntry, error] {
+	return r.repo.LogAllIter(ctx, opts)
+}
+
+// resolveLogStart resolves Log's rev. An unborn HEAD resolves to the empty
+// hash, which LogIter walks as an empty history.
+func (r *Repository) resolveLogStart(rev string) (Hash, error) {
+	if rev == "" || rev == "HEAD" {
+		if !r.hasCommits() {
+			return "", nil
+		}
+		rev = "HEAD"
+	}
+	h, err := r.repo.ResolveRef(rev)
+	if err != nil {
+		return "", fmt.Errorf("log: %w", err)
+	}
+	return h, nil
+}
+
+// hasCommits reports whether HEAD points at a commit yet.
+func (r *Repository) hasCommits() bool {
+	h, err := r.repo.ResolveRef("HEAD")
+	return err == nil && h != ""
+}
@@ -0,0 +1,163 @@
+package graft
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeAndAdd(t *testing.T, r *Repository, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(r.Root(), name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	if err := r.Add(context.Background(), []string{name}, AddOptions{}); err != nil {
+		t.Fatalf("Add(%s): %v", name, err)
+	}
+}
+
+func TestRepository_CommitStatusLog(t *testing.T) {
+	ctx := context.Background()
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	for entry, err := range r.Log(ctx, "", LogOptions{}) {
+		t.Fatalf("Log of an empty repository yielded %v, %v", entry, err)
+	}
+
+	writeAndAdd(t, r, "a.txt", "a\n")
+	status, err := r.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(status) != 1 || status[0].Path != "a.txt" {
+		t.Fatalf("Status = %+v, want a.txt staged", status)
+	}
+
+	first, err := r.Commit(ctx, "first", "test-author", CommitOptions{})
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	writeAndAdd(t, r, "b.txt", "b\n")
+	second, err := r.Commit(ctx, "second", "test-author", CommitOptions{})
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	var hashes []Hash
+	for entry, err := range r.Log(ctx, "", LogOptions{}) {
+		if err != nil {
+			t.Fatalf("Log: %v", err)
+		}
+		hashes = append(hashes, entry.Hash)
+	}
+	if len(hashes) != 2 || hashes[0] != second || hashes[1] != first {
+		t.Fatalf("Log = %v, want [%s %s]", hashes, second, first)
+	}
+
+	// Breaking out of the loop stops the walk.
+	n := 0
+	for range r.LogAll(ctx, LogOptions{}) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Fatalf("LogAll yielded %d entries before break, want 1", n)
+	}
+
+	if head, err := r.Resolve("HEAD~1"); err != nil || head != first {
+		t.Fatalf("Resolve(HEAD~1) = %s, %v; want %s", head, err, first)
+	}
+}
+
+func TestRepository_Merge(t *testing.T) {
+	ctx := context.Background()
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	writeAndAdd(t, r, "a.txt", "a\n")
+	base, err := r.Commit(ctx, "base", "test-author", CommitOptions{})
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := r.Repo().CreateBranch("feature", base); err != nil {
+		t.Fatalf("CreateBranch: %v", err)
+	}
+	writeAndAdd(t, r, "main.txt", "main\n")
+	if _, err := r.Commit(ctx, "main work", "test-author", CommitOptions{}); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := r.Repo().Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	writeAndAdd(t, r, "feature.txt", "feature\n")
+	if _, err := r.Commit(ctx, "feature work", "test-author", CommitOptions{}); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := r.Repo().Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+
+	report, err := r.Merge(ctx, "feature", MergeOptions{})
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if report.HasConflicts || report.MergeCommit == "" {
+		t.Fatalf("Merge = %+v, want a clean merge commit", report)
+	}
+	if _, err := os.Stat(filepath.Join(r.Root(), "feature.txt")); err != nil {
+		t.Fatalf("merged file missing: %v", err)
+	}
+}
+
+func TestRepository_CanceledContext(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	writeAndAdd(t, r, "a.txt", "a\n")
+	if _, err := r.Commit(context.Background(), "first", "test-author", CommitOptions{}); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := r.Status(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Status with a canceled context = %v, want context.Canceled", err)
+	}
+	writeAndAdd(t, r, "b.txt", "b\n")
+	if _, err := r.Commit(ctx, "second", "test-author", CommitOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Commit with a canceled context = %v, want context.Canceled", err)
+	}
+	if _, err := r.Merge(ctx, "main", MergeOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Merge with a canceled context = %v, want context.Canceled", err)
+	}
+	var logErr error
+	for _, err := range r.Log(ctx, "", LogOptions{}) {
+		logErr = err
+	}
+	if !errors.Is(logErr, context.Canceled) {
+		t.Fatalf("Log with a canceled context ended with %v, want context.Canceled", logErr)
+	}
+	if err := r.Add(ctx, []string{"b.txt"}, AddOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Add with a canceled context = %v, want context.Canceled", err)
+	}
+
+	// Nothing was committed.
+	n := 0
+	for _, err := range r.Log(context.Background(), "", LogOptions{}) {
+		if err != nil {
+			t.Fatalf("Log: %v", err)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("history has %d commits after canceled commit, want 1", n)
+	}
+}
@@ -0,0 +1,189 @@
+// Package lockfile implements advisory cross-process locks built on
+// exclusively created "<target>.lock" files. Cooperating processes serialize
+// on the lock file, write the new content into it, and rename it over the
+// target to publish the update atomically.
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Suffix is appended to a target path to form its lock file path.
+const Suffix = ".lock"
+
+// StaleAfter is the age after which a lock file is assumed to have been left
+// behind by a crashed process and is removed. Holders of a Lock refresh its
+// modification time well within this period, so long-running operations
+// keep their locks.
+const StaleAfter = 5 * time.Minute
+
+const retryDelay = 5 * time.Millisecond
+
+// touchInterval is how often a held Lock refreshes its modification time.
+var touchInterval = StaleAfter / 5
+
+// ErrLocked is returned when a lock is still held by another process after
+// the wait limit expires.
+var ErrLocked = errors.New("lock held by another process")
+
+// Lock is a held lock on a target path.
+type Lock struct {
+	target string
+	path   string
+	file   *os.File
+
+	stopTouch chan struct{}
+	touchDone sync.WaitGroup
+}
+
+// Acquire takes the lock for target, waiting up to wait for a competing
+// holder to release it.
+func Acquire(target string, wait time.Duration) (*Lock, error) {
+	path := target + Suffix
+	f, err := CreateExclusive(path, wait)
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{target: target, path: path, file: f, stopTouch: make(chan struct{})}
+	l.touchDone.Add(1)
+	go l.touch()
+	return l, nil
+}
+
+// touch refreshes the lock file's modification time until the lock is
+// committed or released, so other processes never take it for stale.
+func (l *Lock) touch() {
+	defer l.touchDone.Done()
+	ticker := time.NewTicker(touchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stopTouch:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(l.path, now, now)
+		}
+	}
+}
+
+// stop ends the refresh of a held lock and returns its file, or nil when
+// the lock was already committed or released.
+func (l *Lock) stop() *os.File {
+	f := l.file
+	if f == nil {
+		return nil
+	}
+	l.file = nil
+	close(l.stopTouch)
+	l.touchDone.Wait()
+	return f
+}
+
+// CreateExclusive creates path with O_EXCL, retrying until wait expires while
+// another process holds it. Lock files older than StaleAfter are removed.
+// The caller must finish with the file well within StaleAfter; use Acquire
+// for locks held longer.
+func CreateExclusive(path string, wait time.Duration) (*os.File, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			return f, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		// A lock older than StaleAfter was likely left by a crashed
+		// process. Remove it and retry.
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > StaleAfter {
+			breakStaleLock(path, info)
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w: timeout waiting for lock %q (may be stale — remove manually if no graft process is running)", ErrLocked, path)
+		}
+		time.Sleep(retryDelay)
+	}
+}
+
+// breakStaleLock removes the lock file at path if it is still the stale file
+// described by info. The file is first renamed aside, which is atomic, so a
+// fresh lock created after info was read is put back instead of deleted. It
+// reports whether the stale lock was removed.
+func breakStaleLock(path string, info os.FileInfo) bool {
+	aside := path + "." + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36) + Suffix
+	if err := os.Rename(path, aside); err != nil {
+		return false
+	}
+	// Inode numbers are reused, so a fresh lock can look like the same
+	// file; its modification time tells them apart.
+	if moved, err := os.Stat(aside); err == nil && os.SameFile(info, moved) && moved.ModTime().Equal(info.ModTime()) {
+		os.Remove(aside)
+		return true
+	}
+	// Another process replaced the lock in the meantime. Link it back
+	// without clobbering a holder that has taken the path since; where
+	// links are unsupported, rename it back instead.
+	if err := os.Link(aside, path); err != nil && !os.IsExist(err) {
+		os.Rename(aside, path)
+	}
+	os.Remove(aside)
+	return false
+}
+
+// Path returns the lock file path.
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// Write writes data into the lock file. The data becomes the target's
+// content on Commit.
+func (l *Lock) Write(data []byte) (int, error) {
+	if l.file == nil {
+		return 0, fmt.Errorf("lock %q: already released", l.path)
+	}
+	return l.file.Write(data)
+}
+
+// Commit syncs the lock file and renames it over the target, releasing the
+// lock.
+func (l *Lock) Commit() error {
+	f := l.stop()
+	if f == nil {
+		return fmt.Errorf("lock %q: already released", l.path)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(l.path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(l.path)
+		return err
+	}
+	if err := os.Rename(l.path, l.target); err != nil {
+		os.Remove(l.path)
+		return err
+	}
+	return nil
+}
+
+// Release drops the lock without touching the target. It is safe to call
+// after Commit or more than once.
+func (l *Lock) Release() error {
+	f := l.stop()
+	if f == nil {
+		return nil
+	}
+	f.Close()
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package lockfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireCommitReplacesTarget(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "index")
+	if err := os.WriteFile(target, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Acquire(target, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := l.Write([]byte("new")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := l.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Fatalf("target = %q, want %q", data, "new")
+	}
+	if _, err := os.Stat(target + Suffix); !os.IsNotExist(err) {
+		t.Fatalf("expected lock file to be gone, stat err=%v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release after Commit: %v", err)
+	}
+}
+
+func TestAcquireTimesOutWhileHeld(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "HEAD")
+	held, err := Acquire(target, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer held.Release()
+
+	if _, err := Acquire(target, 20*time.Millisecond); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second Acquire error = %v, want ErrLocked", err)
+	}
+
+	if err := held.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	again, err := Acquire(target, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	again.Release()
+}
+
+func TestAcquireBreaksStaleLock(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "ref")
+	if err := os.WriteFile(target+Suffix, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * StaleAfter)
+	if err := os.Chtimes(target+Suffix, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Acquire(target, 0)
+	if err != nil {
+		t.Fatalf("Acquire over stale lock: %v", err)
+	}
+	l.Release()
+}
+
+func TestHeldLockIsNotTakenForStale(t *testing.T) {
+	prev := touchInterval
+	touchInterval = 10 * time.Millisecond
+	defer func() { touchInterval = prev }()
+
+	target := filepath.Join(t.TempDir(), "pack")
+	held, err := Acquire(target, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer held.Release()
+	old := time.Now().Add(-2 * StaleAfter)
+	if err := os.Chtimes(target+Suffix, old, old); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := Acquire(target, 0); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire over a held lock error = %v, want ErrLocked", err)
+	}
+}
+
+func TestBreakStaleLockKeepsReplacedLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "HEAD") + Suffix
+	if err := os.WriteFile(path, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * StaleAfter)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another process breaks the stale lock and takes a fresh one before
+	// this one gets to remove it.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("fresh"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if breakStaleLock(path, stale) {
+		t.Fatal("breakStaleLock removed a lock it did not stat")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "fresh" {
+		t.Fatalf("lock after breakStaleLock = %q, %v; want the fresh lock", data, err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("directory holds %d files, want only the lock", len(entries))
+	}
+}
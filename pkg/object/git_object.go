@@ -0,0 +1,88 @@
+package object
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// GitHashSize is the length of a raw SHA-1 git object name.
+const GitHashSize = sha1.Size
+
+// GitTreeEntry is one entry of a git tree object.
+type GitTreeEntry struct {
+	Mode string
+	Name string
+	Hash []byte // raw SHA-1 object name
+}
+
+// GitObjectHeader returns the "type len\0" envelope header git prefixes to
+// object content before hashing and compressing it.
+func GitObjectHeader(objType ObjectType, size int) string {
+	return fmt.Sprintf("%s %d\x00", objType, size)
+}
+
+// GitObjectHash returns the hex SHA-1 name git gives an object.
+func GitObjectHash(objType ObjectType, data []byte) string {
+	h := sha1.New()
+	h.Write([]byte(GitObjectHeader(objType, len(data))))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ParseGitObject splits an inflated git object into its type and content,
+// checking the size recorded in the header.
+func ParseGitObject(raw []byte) (ObjectType, []byte, error) {
+	header, data, ok := bytes.Cut(raw, []byte{0})
+	if !ok {
+		return "", nil, errors.New("invalid git object: no null separator")
+	}
+	typ, sizeStr, ok := bytes.Cut(header, []byte{' '})
+	if !ok {
+		return "", nil, fmt.Errorf("invalid git object header %q", header)
+	}
+	size, err := strconv.Atoi(string(sizeStr))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid size in git object header %q", header)
+	}
+	if size != len(data) {
+		return "", nil, fmt.Errorf("git object size mismatch: header says %d, got %d", size, len(data))
+	}
+	return ObjectType(typ), data, nil
+}
+
+// ParseGitTree parses the content of a git tree object.
+func ParseGitTree(data []byte) ([]GitTreeEntry, error) {
+	var entries []GitTreeEntry
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || nul+1+GitHashSize > len(data) {
+			return nil, errors.New("malformed git tree entry")
+		}
+		entries = append(entries, GitTreeEntry{
+			Mode: string(data[:sp]),
+			Name: string(data[sp+1 : nul]),
+			Hash: data[nul+1 : nul+1+GitHashSize],
+		})
+		data = data[nul+1+GitHashSize:]
+	}
+	return entries, nil
+}
+
+// MarshalGitTree serializes entries, already in git order, as the content
+// of a git tree object.
+func MarshalGitTree(entries []GitTreeEntry) []byte {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		buf.WriteString(e.Mode)
+		buf.WriteByte(' ')
+		buf.WriteString(e.Name)
+		buf.WriteByte(0)
+		buf.Write(e.Hash)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,47 @@
+package object
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGitObjectHashMatchesGit(t *testing.T) {
+	// git hash-object of "hello world\n".
+	if got, want := GitObjectHash(TypeBlob, []byte("hello world\n")), "3b18e512dba79e4c8300dd08aeb37f8e728b8dad"; got != want {
+		t.Fatalf("GitObjectHash = %s, want %s", got, want)
+	}
+}
+
+func TestParseGitObject(t *testing.T) {
+	typ, data, err := ParseGitObject([]byte(GitObjectHeader(TypeBlob, 3) + "abc"))
+	if err != nil || typ != TypeBlob || string(data) != "abc" {
+		t.Fatalf("ParseGitObject = %q, %q, %v", typ, data, err)
+	}
+	for _, raw := range []string{"blob 3abc", "blob\x00abc", "blob x\x00abc", "blob 4\x00abc"} {
+		if _, _, err := ParseGitObject([]byte(raw)); err == nil {
+			t.Errorf("ParseGitObject(%q) succeeded", raw)
+		}
+	}
+}
+
+func TestGitTreeRoundTrip(t *testing.T) {
+	entries := []GitTreeEntry{
+		{Mode: "100644", Name: "a.txt", Hash: bytes.Repeat([]byte{1}, GitHashSize)},
+		{Mode: TreeModeDir, Name: "dir", Hash: bytes.Repeat([]byte{2}, GitHashSize)},
+	}
+	got, err := ParseGitTree(MarshalGitTree(entries))
+	if err != nil {
+		t.Fatalf("ParseGitTree: %v", err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("got %d entries, want %d", len(got), len(entries))
+	}
+	for i := range entries {
+		if got[i].Mode != entries[i].Mode || got[i].Name != entries[i].Name || !bytes.Equal(got[i].Hash, entries[i].Hash) {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], entries[i])
+		}
+	}
+	if _, err := ParseGitTree([]byte("100644 a.txt\x00short")); err == nil {
+		t.Fatal("ParseGitTree accepted a truncated entry")
+	}
+}
@@ -95,6 +95,113 @@ func buildInsertOnlyDelta(base, target []byte) []byte {
 	return out.Bytes()
 }
 
+// deltaBlockSize is the granularity at which buildDelta indexes the base.
+// Matches shorter than this are emitted as literal inserts.
+const deltaBlockSize = 16
+
+// maxDeltaCopySize is the largest copy a single delta instruction can encode.
+const maxDeltaCopySize = 0xffffff
+
+// buildDelta returns a Git delta stream that reconstructs target from base
+// using copy instructions for regions shared with base and literal inserts
+// for everything else. Bases larger than 4GiB cannot be addressed and fall
+// back to an insert-only delta.
+func buildDelta(base, target []byte) []byte {
+	if len(base) < deltaBlockSize || len(base) > int(^uint32(0)) {
+		return buildInsertOnlyDelta(base, target)
+	}
+
+	index := make(map[string]int, len(base)/deltaBlockSize)
+	for off := 0; off+deltaBlockSize <= len(base); off += deltaBlockSize {
+		key := string(base[off : off+deltaBlockSize])
+		if _, ok := index[key]; !ok {
+			index[key] = off
+		}
+	}
+
+	var out bytes.Buffer
+	out.Write(encodeDeltaVarint(uint64(len(base))))
+	out.Write(encodeDeltaVarint(uint64(len(target))))
+
+	insertStart := 0
+	flushInsert := func(end int) {
+		for insertStart < end {
+			chunk := end - insertStart
+			if chunk > 127 {
+				chunk = 127
+			}
+			out.WriteByte(byte(chunk))
+			out.Write(target[insertStart : insertStart+chunk])
+			insertStart += chunk
+		}
+	}
+
+	pos := 0
+	for pos+deltaBlockSize <= len(target) {
+		baseOff, ok := index[string(target[pos:pos+deltaBlockSize])]
+		if !ok {
+			pos++
+			continue
+		}
+		// Extend the match backwards into pending literals, then forwards.
+		for baseOff > 0 && pos > insertStart && base[baseOff-1] == target[pos-1] {
+			baseOff--
+			pos--
+		}
+		n := 0
+		for baseOff+n < len(base) && pos+n < len(target) && base[baseOff+n] == target[pos+n] {
+			n++
+		}
+		flushInsert(pos)
+		for copied := 0; copied < n; {
+			size := n - copied
+			if size > maxDeltaCopySize {
+				size = maxDeltaCopySize
+			}
+			writeDeltaCopy(&out, uint32(baseOff+copied), uint32(size))
+			copied += size
+		}
+		pos += n
+		insertStart = pos
+	}
+	flushInsert(len(target))
+	return out.Bytes()
+}
+
+// BuildDelta returns a Git delta stream that reconstructs target from base.
+func BuildDelta(base, target []byte) []byte {
+	return buildDelta(base, target)
+}
+
+// writeDeltaCopy encodes one copy instruction, omitting zero bytes of the
+// offset and size as the format allows.
+func writeDeltaCopy(out *bytes.Buffer, offset, size uint32) {
+	cmd := byte(0x80)
+	var args [7]byte
+	n := 0
+	for i := 0; i < 4; i++ {
+		if b := byte(offset >> (8 * i)); b != 0 {
+			cmd |= 1 << i
+			args[n] = b
+			n++
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if b := byte(size >> (8 * i)); b != 0 {
+			cmd |= 0x10 << i
+			args[n] = b
+			n++
+		}
+	}
+	out.WriteByte(cmd)
+	out.Write(args[:n])
+}
+
+// ApplyDelta applies a Git delta stream to base and returns the result.
+func ApplyDelta(base, delta []byte) ([]byte, error) {
+	return applyDelta(base, delta)
+}
+
 // applyDelta applies Git delta instructions to base and returns the result.
 func applyDelta(base, delta []byte) ([]byte, error) {
 	dr := bytes.NewReader(delta)
@@ -2,6 +2,7 @@ package object
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -114,3 +115,82 @@ func TestApplyDeltaRejectsTruncatedCopyInstruction(t *testing.T) {
 		t.Fatalf("error = %q, want to contain %q", err.Error(), "delta copy")
 	}
 }
+
+func TestBuildDeltaCopiesSharedRegions(t *testing.T) {
+	var base bytes.Buffer
+	for i := 0; i < 400; i++ {
+		fmt.Fprintf(&base, "dependency-%04d@1.%d.0\n", i, i%7)
+	}
+	target := bytes.Replace(base.Bytes(), []byte("dependency-0200@1.4.0"), []byte("dependency-0200@2.0.0-rc1"), 1)
+	target = append([]byte("# lockfile v2\n"), target...)
+
+	delta := BuildDelta(base.Bytes(), target)
+	if len(delta) >= len(target)/10 {
+		t.Fatalf("delta size = %d for %d-byte target, want copy instructions to dominate", len(delta), len(target))
+	}
+	got, err := applyDelta(base.Bytes(), delta)
+	if err != nil {
+		t.Fatalf("applyDelta: %v", err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Fatal("applyDelta(BuildDelta) did not reproduce target")
+	}
+
+	// Unrelated and tiny inputs still round-trip.
+	for _, tc := range []struct{ base, target string }{
+		{"short", "other"},
+		{strings.Repeat("a", 64), strings.Repeat("b", 300)},
+		{strings.Repeat("abcdefghijklmnop", 8), ""},
+	} {
+		got, err := applyDelta([]byte(tc.base), BuildDelta([]byte(tc.base), []byte(tc.target)))
+		if err != nil || string(got) != tc.target {
+			t.Fatalf("round trip base=%q target=%q: got %q, %v", tc.base, tc.target, got, err)
+		}
+	}
+}
+
+func TestResolveThinPackEntriesUsesExternalBase(t *testing.T) {
+	base := []byte(strings.Repeat("shared content line\n", 64))
+	target := append(append([]byte(nil), base...), []byte("appended\n")...)
+	baseHash := HashObject(TypeBlob, base)
+	delta := BuildDelta(base, target)
+	compressed, err := CompressPackPayload(delta)
+	if err != nil {
+		t.Fatalf("CompressPackPayload: %v", err)
+	}
+
+	var buf bytes.Buffer
+	pw, err := NewPackWriter(&buf, 1)
+	if err != nil {
+		t.Fatalf("NewPackWriter: %v", err)
+	}
+	if err := pw.WriteCompressedRefDelta(baseHash, uint64(len(delta)), compressed); err != nil {
+		t.Fatalf("WriteCompressedRefDelta: %v", err)
+	}
+	if _, err := pw.Finish(); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	pf, err := ReadPack(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadPack: %v", err)
+	}
+	if _, err := ResolvePackEntries(pf.Entries); err == nil {
+		t.Fatal("ResolvePackEntries resolved a thin pack without its base")
+	}
+	resolved, err := ResolveThinPackEntries(pf.Entries, func(h Hash) (PackObjectType, []byte, error) {
+		if h != baseHash {
+			return 0, nil, fmt.Errorf("unexpected base %s", h)
+		}
+		return PackBlob, base, nil
+	})
+	if err != nil {
+		t.Fatalf("ResolveThinPackEntries: %v", err)
+	}
+	if resolved[0].Type != PackBlob || !bytes.Equal(resolved[0].Data, target) {
+		t.Fatalf("resolved entry = type %d, %d bytes; want blob target", resolved[0].Type, len(resolved[0].Data))
+	}
+	if resolved[0].OriginalType != PackRefDelta {
+		t.Fatalf("OriginalType = %d, want REF_DELTA", resolved[0].OriginalType)
+	}
+}
@@ -160,6 +160,24 @@ func ReadPackResolved(data []byte) (*PackFile, error) {
 // contents. Returned entries preserve OriginalType while Type is set to the
 // resolved concrete object type.
 func ResolvePackEntries(entries []PackEntry) ([]PackEntry, error) {
+	return ResolveThinPackEntries(entries, nil)
+}
+
+// ThinPackBaseLookup returns an object the receiver of a thin pack already
+// has, for use as a REF_DELTA base that is not part of the pack itself.
+type ThinPackBaseLookup func(h Hash) (PackObjectType, []byte, error)
+
+// ResolveThinPackEntries behaves like ResolvePackEntries but resolves
+// REF_DELTA bases that are missing from the pack through lookup. A nil lookup
+// requires every base to be in the pack.
+func ResolveThinPackEntries(entries []PackEntry, lookup ThinPackBaseLookup) ([]PackEntry, error) {
+	type externalBase struct {
+		typ  PackObjectType
+		data []byte
+		ok   bool
+	}
+	external := make(map[Hash]externalBase)
+
 	resolved := make([]PackEntry, len(entries))
 	done := make([]bool, len(entries))
 	remaining := len(entries)
@@ -217,16 +235,32 @@ func ResolvePackEntries(entries []PackEntry) ([]PackEntry, error) {
 					byHash[h] = i
 				}
 			case PackRefDelta:
-				baseIndex, ok := byHash[entry.BaseRef]
-				if !ok {
-					continue
+				var baseType PackObjectType
+				var baseData []byte
+				if baseIndex, ok := byHash[entry.BaseRef]; ok {
+					baseType, baseData = resolved[baseIndex].Type, resolved[baseIndex].Data
+				} else {
+					if lookup == nil {
+						continue
+					}
+					base, seen := external[entry.BaseRef]
+					if !seen {
+						if typ, data, err := lookup(entry.BaseRef); err == nil {
+							base = externalBase{typ: typ, data: data, ok: true}
+						}
+						external[entry.BaseRef] = base
+					}
+					if !base.ok {
+						continue
+					}
+					baseType, baseData = base.typ, base.data
 				}
-				out, err := applyDelta(resolved[baseIndex].Data, entry.Data)
+				out, err := applyDelta(baseData, entry.Data)
 				if err != nil {
 					return nil, fmt.Errorf("entry %d: apply ref-delta: %w", i, err)
 				}
 				r := entry
-				r.Type = resolved[baseIndex].Type
+				r.Type = baseType
 				r.Data = out
 				resolved[i] = r
 				done[i] = true
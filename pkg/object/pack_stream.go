@@ -0,0 +1,333 @@
+package object
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// packStreamBufferSize is the read-ahead buffer of a PackStreamReader.
+const packStreamBufferSize = 64 << 10
+
+// PackStreamReader decodes a pack stream entry by entry, so only one object
+// is held in memory at a time. Delta entries are returned unresolved; see
+// ResolvePackStream. The trailer checksum is verified when Next reaches the
+// end of the stream.
+type PackStreamReader struct {
+	src     *packStreamSource
+	header  PackHeader
+	read    uint32
+	done    bool
+	trailer *PackEntityTrailer
+}
+
+// NewPackStreamReader reads and validates the pack header from r.
+func NewPackStreamReader(r io.Reader) (*PackStreamReader, error) {
+	src := &packStreamSource{r: r, buf: make([]byte, packStreamBufferSize), hash: sha256.New()}
+	raw := make([]byte, packHeaderSize)
+	if _, err := io.ReadFull(src, raw); err != nil {
+		return nil, fmt.Errorf("read pack header: %w", err)
+	}
+	header, err := UnmarshalPackHeader(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &PackStreamReader{src: src, header: *header}, nil
+}
+
+// Header returns the pack header.
+func (p *PackStreamReader) Header() PackHeader {
+	return p.header
+}
+
+// Next returns the next entry. After the last entry it verifies the trailer
+// checksum, reads the optional entity trailer and returns io.EOF.
+func (p *PackStreamReader) Next() (PackEntry, error) {
+	if p.done {
+		return PackEntry{}, io.EOF
+	}
+	if p.read == p.header.NumObjects {
+		if err := p.finish(); err != nil {
+			return PackEntry{}, err
+		}
+		return PackEntry{}, io.EOF
+	}
+	i := p.read
+	entryOffset := p.src.offset
+
+	objType, size, err := readPackEntryHeader(p.src)
+	if err != nil {
+		return PackEntry{}, fmt.Errorf("entry %d: %w", i, err)
+	}
+	entry := PackEntry{Type: objType, OriginalType: objType, Size: size, Offset: entryOffset}
+	switch objType {
+	case PackOfsDelta:
+		if entry.BaseDistance, err = readOfsDeltaDistance(p.src); err != nil {
+			return PackEntry{}, fmt.Errorf("entry %d: decode ofs-delta distance: %w", i, err)
+		}
+	case PackRefDelta:
+		var raw [32]byte
+		if _, err := io.ReadFull(p.src, raw[:]); err != nil {
+			return PackEntry{}, fmt.Errorf("entry %d: truncated ref-delta base hash", i)
+		}
+		entry.BaseRef = Hash(hex.EncodeToString(raw[:]))
+	}
+
+	// The source implements io.ByteReader, so zlib consumes exactly the
+	// compressed payload and leaves the next entry unread.
+	zr, err := zlib.NewReader(p.src)
+	if err != nil {
+		return PackEntry{}, fmt.Errorf("entry %d: zlib reader: %w", i, err)
+	}
+	var out bytes.Buffer
+	out.Grow(int(min(size, packStreamBufferSize)))
+	if _, err := io.Copy(&out, io.LimitReader(zr, int64(size)+1)); err != nil {
+		_ = zr.Close()
+		return PackEntry{}, fmt.Errorf("entry %d: decompress: %w", i, err)
+	}
+	if err := zr.Close(); err != nil {
+		return PackEntry{}, fmt.Errorf("entry %d: close zlib stream: %w", i, err)
+	}
+	if uint64(out.Len()) != size {
+		return PackEntry{}, fmt.Errorf("entry %d: size mismatch header=%d decoded=%d", i, size, out.Len())
+	}
+	entry.Data = out.Bytes()
+	p.read++
+	return entry, nil
+}
+
+// EntityTrailer returns the Graft entity trailer that followed the pack, or
+// nil if there was none or Next has not yet returned io.EOF.
+func (p *PackStreamReader) EntityTrailer() *PackEntityTrailer {
+	return p.trailer
+}
+
+func (p *PackStreamReader) finish() error {
+	sum := p.src.sum()
+	var checksum [sha256.Size]byte
+	if _, err := io.ReadFull(p.src, checksum[:]); err != nil {
+		return fmt.Errorf("missing pack trailer checksum")
+	}
+	if !bytes.Equal(sum, checksum[:]) {
+		return fmt.Errorf("pack checksum mismatch")
+	}
+	remaining, err := io.ReadAll(p.src)
+	if err != nil {
+		return fmt.Errorf("read pack trailer: %w", err)
+	}
+	if len(remaining) > 0 {
+		if !bytes.Equal(remaining[:min(4, len(remaining))], packEntityTrailerMagic[:min(4, len(remaining))]) {
+			return fmt.Errorf("pack has trailing undecoded bytes: %d", len(remaining))
+		}
+		if p.trailer, err = ReadPackEntityTrailer(remaining); err != nil {
+			return fmt.Errorf("read entity trailer: %w", err)
+		}
+	}
+	p.done = true
+	return nil
+}
+
+// packStreamSource buffers the pack stream and hashes consumed bytes in bulk
+// rather than byte by byte as zlib reads them.
+type packStreamSource struct {
+	r      io.Reader
+	buf    []byte
+	start  int // next unread byte in buf
+	end    int // end of buffered data
+	mark   int // first consumed byte not yet hashed
+	offset uint64
+	hash   hash.Hash
+	err    error
+}
+
+func (s *packStreamSource) fill() error {
+	if s.start < s.end {
+		return nil
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.flush()
+	s.start, s.end, s.mark = 0, 0, 0
+	for s.end == 0 && s.err == nil {
+		s.end, s.err = s.r.Read(s.buf)
+	}
+	if s.end == 0 {
+		return s.err
+	}
+	return nil
+}
+
+// flush hashes the bytes consumed since the last flush.
+func (s *packStreamSource) flush() {
+	s.hash.Write(s.buf[s.mark:s.start])
+	s.mark = s.start
+}
+
+// sum returns the hash of every byte consumed so far.
+func (s *packStreamSource) sum() []byte {
+	s.flush()
+	return s.hash.Sum(nil)
+}
+
+func (s *packStreamSource) Read(b []byte) (int, error) {
+	if err := s.fill(); err != nil {
+		return 0, err
+	}
+	n := copy(b, s.buf[s.start:s.end])
+	s.start += n
+	s.offset += uint64(n)
+	return n, nil
+}
+
+func (s *packStreamSource) ReadByte() (byte, error) {
+	if err := s.fill(); err != nil {
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	b := s.buf[s.start]
+	s.start++
+	s.offset++
+	return b, nil
+}
+
+func readPackEntryHeader(r io.ByteReader) (PackObjectType, uint64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, fmt.Errorf("entry header truncated")
+	}
+	objType := PackObjectType((b >> 4) & 0x7)
+	size := uint64(b & 0x0f)
+	shift := uint(4)
+	for b&0x80 != 0 {
+		if shift > 63 {
+			return 0, 0, fmt.Errorf("entry header size overflows")
+		}
+		if b, err = r.ReadByte(); err != nil {
+			return 0, 0, fmt.Errorf("entry header truncated")
+		}
+		size |= uint64(b&0x7f) << shift
+		shift += 7
+	}
+	return objType, size, nil
+}
+
+func readOfsDeltaDistance(r io.ByteReader) (uint64, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("ofs-delta distance truncated")
+	}
+	offset := uint64(c & 0x7f)
+	for c&0x80 != 0 {
+		if c, err = r.ReadByte(); err != nil {
+			return 0, fmt.Errorf("ofs-delta distance truncated")
+		}
+		offset = ((offset + 1) << 7) | uint64(c&0x7f)
+	}
+	return offset, nil
+}
+
+// ResolvePackStream reads a pack stream from r and calls emit with each
+// object as soon as its delta base is available, with Type set to the
+// resolved concrete type. Bases are read back through lookup, both objects
+// emitted earlier in the stream and thin pack bases the receiver already
+// has, so emit must make each object visible to lookup before it returns
+// (writing it to a store does). A REF_DELTA whose base arrives later in the
+// stream is held until the base is emitted. It returns the entity trailer,
+// if any.
+func ResolvePackStream(r io.Reader, lookup ThinPackBaseLookup, emit func(PackEntry) error) (*PackEntityTrailer, error) {
+	if lookup == nil {
+		return nil, fmt.Errorf("resolve pack stream: base lookup is required")
+	}
+	pr, err := NewPackStreamReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	byOffset := make(map[uint64]Hash)
+	waiting := make(map[Hash][]PackEntry) // REF_DELTA entries by missing base
+	pending := 0
+
+	var resolve func(entry PackEntry, baseType PackObjectType, baseData []byte) error
+	deliver := func(entry PackEntry) error {
+		h, ok := packEntryResolvedHash(entry)
+		if !ok {
+			return fmt.Errorf("entry at offset %d: unsupported type %d", entry.Offset, entry.Type)
+		}
+		byOffset[entry.Offset] = h
+		if err := emit(entry); err != nil {
+			return err
+		}
+		deltas := waiting[h]
+		if len(deltas) == 0 {
+			return nil
+		}
+		delete(waiting, h)
+		pending -= len(deltas)
+		for _, d := range deltas {
+			if err := resolve(d, entry.Type, entry.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	resolve = func(entry PackEntry, baseType PackObjectType, baseData []byte) error {
+		out, err := applyDelta(baseData, entry.Data)
+		if err != nil {
+			return fmt.Errorf("entry at offset %d: apply delta: %w", entry.Offset, err)
+		}
+		entry.Type = baseType
+		entry.Data = out
+		return deliver(entry)
+	}
+
+	for {
+		entry, err := pr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch entry.Type {
+		case PackCommit, PackTree, PackBlob, PackTag:
+			err = deliver(entry)
+		case PackOfsDelta:
+			if entry.BaseDistance == 0 || entry.BaseDistance > entry.Offset {
+				return nil, fmt.Errorf("entry at offset %d: invalid ofs-delta base distance %d", entry.Offset, entry.BaseDistance)
+			}
+			base, ok := byOffset[entry.Offset-entry.BaseDistance]
+			if !ok {
+				return nil, fmt.Errorf("entry at offset %d: ofs-delta base not found", entry.Offset)
+			}
+			baseType, baseData, lerr := lookup(base)
+			if lerr != nil {
+				return nil, fmt.Errorf("entry at offset %d: read ofs-delta base %s: %w", entry.Offset, base, lerr)
+			}
+			err = resolve(entry, baseType, baseData)
+		case PackRefDelta:
+			baseType, baseData, lerr := lookup(entry.BaseRef)
+			if lerr != nil {
+				waiting[entry.BaseRef] = append(waiting[entry.BaseRef], entry)
+				pending++
+				continue
+			}
+			err = resolve(entry, baseType, baseData)
+		default:
+			err = fmt.Errorf("entry at offset %d: unsupported type %d", entry.Offset, entry.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if pending > 0 {
+		return nil, fmt.Errorf("unable to resolve %d delta entries", pending)
+	}
+	return pr.EntityTrailer(), nil
+}
@@ -0,0 +1,127 @@
+package object
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestPackStreamReaderMatchesReadPack(t *testing.T) {
+	base := bytes.Repeat([]byte("base line\n"), 200)
+	target := append(append([]byte{}, base...), "tail\n"...)
+	blobHash := HashObject(TypeBlob, []byte("hello"))
+
+	var buf bytes.Buffer
+	pw, err := NewPackWriter(&buf, 3)
+	if err != nil {
+		t.Fatalf("NewPackWriter: %v", err)
+	}
+	if err := pw.WriteEntry(PackBlob, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	baseOffset := pw.CurrentOffset()
+	if err := pw.WriteEntry(PackBlob, base); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.WriteOfsDelta(baseOffset, base, target); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pw.FinishWithEntityTrailer([]PackEntityTrailerEntry{{ObjectHash: blobHash, StableID: "decl:function_definition::hello"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ReadPack(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadPack: %v", err)
+	}
+	// A reader returning one byte at a time exercises every buffer refill.
+	pr, err := NewPackStreamReader(iotest.OneByteReader(bytes.NewReader(buf.Bytes())))
+	if err != nil {
+		t.Fatalf("NewPackStreamReader: %v", err)
+	}
+	for i := range want.Entries {
+		got, err := pr.Next()
+		if err != nil {
+			t.Fatalf("Next %d: %v", i, err)
+		}
+		w := want.Entries[i]
+		if got.Type != w.Type || got.Offset != w.Offset || got.BaseDistance != w.BaseDistance || !bytes.Equal(got.Data, w.Data) {
+			t.Fatalf("entry %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if _, err := pr.Next(); err != io.EOF {
+		t.Fatalf("Next after last entry = %v, want io.EOF", err)
+	}
+	if tr := pr.EntityTrailer(); tr == nil || len(tr.Entries) != 1 || tr.Entries[0].ObjectHash != blobHash {
+		t.Fatalf("EntityTrailer = %+v", tr)
+	}
+
+	corrupt := append([]byte{}, buf.Bytes()...)
+	corrupt[packHeaderSize+2] ^= 0xff
+	pr, err = NewPackStreamReader(bytes.NewReader(corrupt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = pr.Next()
+	}
+	if err == io.EOF {
+		t.Fatal("corrupted pack decoded without error")
+	}
+}
+
+func TestResolvePackStreamResolvesDeltas(t *testing.T) {
+	base := []byte("base")
+	external := []byte("external base")
+	externalHash := HashObject(TypeBlob, external)
+	baseHash := HashObject(TypeBlob, base)
+	refPrefix := func(delta []byte, h Hash) []byte {
+		raw, err := hex.DecodeString(string(h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(encodePackEntryHeader(PackRefDelta, uint64(len(delta))), raw...)
+	}
+	// The first delta's base comes later in the stream, the second is a
+	// thin pack base the receiver already has.
+	forward := buildInsertOnlyDelta(base, []byte("from base"))
+	thin := buildInsertOnlyDelta(external, []byte("from external"))
+	packData := makePackDataForReaderTests(t,
+		packRawEntry{rawPrefix: refPrefix(forward, baseHash), raw: forward},
+		packRawEntry{objType: PackBlob, raw: base},
+		packRawEntry{rawPrefix: refPrefix(thin, externalHash), raw: thin},
+	)
+
+	emitted := make(map[Hash][]byte)
+	lookup := func(h Hash) (PackObjectType, []byte, error) {
+		if h == externalHash {
+			return PackBlob, external, nil
+		}
+		if data, ok := emitted[h]; ok {
+			return PackBlob, data, nil
+		}
+		return 0, nil, fmt.Errorf("missing %s", h)
+	}
+	var order []string
+	_, err := ResolvePackStream(bytes.NewReader(packData), lookup, func(e PackEntry) error {
+		emitted[HashObject(TypeBlob, e.Data)] = e.Data
+		order = append(order, string(e.Data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ResolvePackStream: %v", err)
+	}
+	if fmt.Sprint(order) != "[base from base from external]" {
+		t.Fatalf("emitted %q", order)
+	}
+
+	_, err = ResolvePackStream(bytes.NewReader(packData), func(Hash) (PackObjectType, []byte, error) {
+		return 0, nil, fmt.Errorf("no bases")
+	}, func(PackEntry) error { return nil })
+	if err == nil {
+		t.Fatal("ResolvePackStream succeeded without the thin pack base")
+	}
+}
@@ -122,6 +122,12 @@ func (p *PackWriter) WriteEntry(objType PackObjectType, data []byte) error {
 
 // WriteOfsDelta writes an OFS_DELTA entry using an insert-only delta stream.
 func (p *PackWriter) WriteOfsDelta(baseOffset uint64, baseData, targetData []byte) error {
+	return p.writeOfsDelta(baseOffset, buildInsertOnlyDelta(baseData, targetData))
+}
+
+// writeOfsDelta writes an OFS_DELTA entry holding delta against the entry at
+// baseOffset.
+func (p *PackWriter) writeOfsDelta(baseOffset uint64, delta []byte) error {
 	if p.finished {
 		return fmt.Errorf("pack writer already finished")
 	}
@@ -133,7 +139,6 @@ func (p *PackWriter) WriteOfsDelta(baseOffset uint64, baseData, targetData []byt
 		return fmt.Errorf("base offset %d must be before current offset %d", baseOffset, current)
 	}
 
-	delta := buildInsertOnlyDelta(baseData, targetData)
 	header := encodePackEntryHeader(PackOfsDelta, uint64(len(delta)))
 	ofs := encodeOfsDeltaDistance(current - baseOffset)
 	compressed, err := compressPackPayload(delta)
@@ -155,6 +160,36 @@ func (p *PackWriter) WriteOfsDelta(baseOffset uint64, baseData, targetData []byt
 	return nil
 }
 
+// WriteCompressedRefDelta appends a REF_DELTA entry whose delta payload
+// (see BuildDelta) was already zlib-compressed by the caller. The base object
+// does not have to be part of this pack, which is how thin packs reference
+// objects the receiver already has.
+func (p *PackWriter) WriteCompressedRefDelta(baseHash Hash, deltaSize uint64, compressed []byte) error {
+	if p.finished {
+		return fmt.Errorf("pack writer already finished")
+	}
+	if p.written >= p.expected {
+		return fmt.Errorf("pack object count exceeded: expected %d", p.expected)
+	}
+	baseRaw, err := hex.DecodeString(string(baseHash))
+	if err != nil || len(baseRaw) != 32 {
+		return fmt.Errorf("invalid ref-delta base hash %q", baseHash)
+	}
+
+	if _, err := p.hashedW.Write(encodePackEntryHeader(PackRefDelta, deltaSize)); err != nil {
+		return fmt.Errorf("write ref-delta header: %w", err)
+	}
+	if _, err := p.hashedW.Write(baseRaw); err != nil {
+		return fmt.Errorf("write ref-delta base hash: %w", err)
+	}
+	if _, err := p.hashedW.Write(compressed); err != nil {
+		return fmt.Errorf("write ref-delta payload: %w", err)
+	}
+
+	p.written++
+	return nil
+}
+
 // Finish validates object count, writes the trailing pack checksum, and returns
 // that checksum as a hex digest.
 func (p *PackWriter) Finish() (Hash, error) {
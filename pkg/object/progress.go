@@ -0,0 +1,29 @@
+package object
+
+// Progress phases reported by the store and the remote transport.
+const (
+	ProgressPacking     = "packing"     // GC writing objects into a pack
+	ProgressCounting    = "counting"    // collecting objects to send
+	ProgressReceiving   = "receiving"   // writing fetched objects locally
+	ProgressDownloading = "downloading" // raw bytes read from a remote
+	ProgressUploading   = "uploading"   // raw bytes sent to a remote
+)
+
+// Progress reports the running totals of one phase of a long operation.
+type Progress struct {
+	Phase   string
+	Objects int   // objects handled so far in this phase
+	Total   int   // expected object count, or 0 when unknown
+	Bytes   int64 // bytes handled so far in this phase
+}
+
+// ProgressFunc receives progress updates. Calls for a single operation are
+// serialized; implementations should return quickly and throttle their own
+// output.
+type ProgressFunc func(Progress)
+
+// SetProgress registers fn to receive progress for long-running store
+// operations such as GC and repack. A nil fn disables reporting.
+func (s *Store) SetProgress(fn ProgressFunc) {
+	s.progress = fn
+}
@@ -40,6 +40,14 @@ func (s *Store) ReachableSet(roots []Hash) (map[Hash]struct{}, error) {
 			return nil, fmt.Errorf("reachable set parse %s (%s): %w", h, objType, err)
 		}
 		stack = append(stack, refs...)
+		if objType == TypeBlob {
+			// A chunked blob keeps its chunks alive.
+			if chunks, ok, err := s.ChunkedBlobChunks(h); err != nil {
+				return nil, fmt.Errorf("reachable set chunks %s: %w", h, err)
+			} else if ok {
+				stack = append(stack, chunks...)
+			}
+		}
 	}
 
 	return out, nil
@@ -47,7 +55,7 @@ func (s *Store) ReachableSet(roots []Hash) (map[Hash]struct{}, error) {
 
 func referencedHashes(objType ObjectType, data []byte) ([]Hash, error) {
 	switch objType {
-	case TypeBlob, TypeEntity:
+	case TypeBlob, TypeEntity, TypeChunk:
 		return nil, nil
 	case TypeTag:
 		tag, err := UnmarshalTag(data)
@@ -365,6 +365,8 @@ func parseTreeMode(mode string) (bool, string, error) {
 		return false, TreeModeFile, nil
 	case TreeModeExecutable:
 		return false, TreeModeExecutable, nil
+	case TreeModeSymlink:
+		return false, TreeModeSymlink, nil
 	case TreeModeModule:
 		return false, TreeModeModule, nil
 	default:
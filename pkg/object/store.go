@@ -34,6 +34,17 @@ type Store struct {
 	packIdxCache map[string]packIndexCacheEntry
 	// packIdxOrder tracks insertion order for cache eviction.
 	packIdxOrder []string
+
+	// chunking configures content-defined chunking of large text blobs.
+	chunking ChunkingOptions
+
+	// crypt seals new object files at rest when non-nil; cryptErr blocks
+	// writes when encryption is configured but the key failed to load.
+	crypt    *storeCipher
+	cryptErr error
+
+	// progress receives updates from long-running operations like GC.
+	progress ProgressFunc
 }
 
 // NewStore creates a Store rooted at the given directory. The objects/
@@ -72,7 +83,15 @@ func (s *Store) Write(objType ObjectType, data []byte) (Hash, error) {
 	if s.Has(h) {
 		return h, nil
 	}
+	if objType == TypeBlob && s.shouldChunk(data) {
+		return s.writeChunkedBlob(h, data)
+	}
+	return s.writeLoose(h, compressed)
+}
 
+// writeLoose atomically writes an already-compressed object envelope to its
+// loose object path, replacing any existing file.
+func (s *Store) writeLoose(h Hash, compressed []byte) (Hash, error) {
 	dir := filepath.Join(s.root, "objects", string(h[:2]))
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", fmt.Errorf("object write mkdir: %w", err)
@@ -85,7 +104,13 @@ func (s *Store) Write(objType ObjectType, data []byte) (Hash, error) {
 	}
 	tmpName := tmp.Name()
 
-	if _, err := tmp.Write(compressed); err != nil {
+	onDisk, err := s.sealObjectData(cryptKindLoose, compressed)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return "", fmt.Errorf("object write: encrypt: %w", err)
+	}
+	if _, err := tmp.Write(onDisk); err != nil {
 		tmp.Close()
 		os.Remove(tmpName)
 		return "", fmt.Errorf("object write: %w", err)
@@ -123,17 +148,14 @@ func (s *Store) Read(h Hash) (ObjectType, []byte, error) {
 }
 
 func (s *Store) readLoose(h Hash) (ObjectType, []byte, error) {
-	onDisk, err := os.ReadFile(s.objectPath(h))
+	onDisk, err := s.readObjectFile(s.objectPath(h), cryptKindLoose)
 	if err != nil {
 		return "", nil, fmt.Errorf("object read %s: %w", h, err)
 	}
 
 	// Backward compatibility: support legacy uncompressed objects.
 	if objType, content, err := parseObjectEnvelope(onDisk, h); err == nil {
-		if computed := HashObject(objType, content); computed != h {
-			return "", nil, fmt.Errorf("object %s: integrity check failed (computed %s)", h, computed)
-		}
-		return objType, content, nil
+		return s.checkLoose(h, objType, content)
 	}
 
 	raw, err := decompressObject(onDisk)
@@ -144,6 +166,19 @@ func (s *Store) readLoose(h Hash) (ObjectType, []byte, error) {
 	if err != nil {
 		return "", nil, err
 	}
+	return s.checkLoose(h, objType, content)
+}
+
+// checkLoose reassembles chunked blobs and verifies the content hash of a
+// parsed loose object.
+func (s *Store) checkLoose(h Hash, objType ObjectType, content []byte) (ObjectType, []byte, error) {
+	if objType == chunkedBlobType {
+		var err error
+		if content, err = s.assembleChunkedBlob(h, content); err != nil {
+			return "", nil, err
+		}
+		objType = TypeBlob
+	}
 	if computed := HashObject(objType, content); computed != h {
 		return "", nil, fmt.Errorf("object %s: integrity check failed (computed %s)", h, computed)
 	}
@@ -0,0 +1,226 @@
+package object
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultChunkMinSize is the blob size from which chunking applies when
+// ChunkingOptions.MinSize is left at zero with chunking enabled.
+const DefaultChunkMinSize = 1 << 20
+
+// chunkedBlobType is the loose envelope type of a blob stored as a chunk
+// list. It never escapes the store: Read reassembles the blob and reports
+// TypeBlob.
+const chunkedBlobType ObjectType = "chunked-blob"
+
+// Content-defined chunk boundaries: chunks are at least cdcMinChunk bytes,
+// average roughly cdcMinChunk+2^cdcMaskBits, and never exceed cdcMaxChunk.
+const (
+	cdcMinChunk = 2 << 10
+	cdcMaxChunk = 64 << 10
+	cdcMaskBits = 13
+)
+
+// ChunkingOptions configures how Store.Write stores large text blobs. When
+// enabled, a qualifying blob is split at content-defined boundaries; each
+// chunk is stored once as a TypeChunk object and the blob itself becomes a
+// short chunk list. Small edits to big machine-generated files (lockfiles,
+// fixtures) then only add the few chunks that changed.
+//
+// Chunking only deduplicates the local object store. Read reassembles the
+// whole blob, so push, fetch and clone still transfer it in full and the
+// receiving repository stores it according to its own settings.
+type ChunkingOptions struct {
+	Enabled bool
+	// MinSize is the smallest blob that is chunked. Zero means
+	// DefaultChunkMinSize.
+	MinSize int64
+}
+
+// SetChunking configures chunked storage for blobs written from now on.
+// Existing objects are unaffected and stay readable either way.
+func (s *Store) SetChunking(opts ChunkingOptions) {
+	if opts.MinSize <= 0 {
+		opts.MinSize = DefaultChunkMinSize
+	}
+	s.chunking = opts
+}
+
+func (s *Store) shouldChunk(data []byte) bool {
+	if !s.chunking.Enabled || int64(len(data)) < s.chunking.MinSize {
+		return false
+	}
+	// Only text is chunked; binary formats rarely keep stable boundaries.
+	probe := data
+	if len(probe) > 8000 {
+		probe = probe[:8000]
+	}
+	return bytes.IndexByte(probe, 0) < 0
+}
+
+// writeChunkedBlob stores data as chunks plus a chunk list under h.
+func (s *Store) writeChunkedBlob(h Hash, data []byte) (Hash, error) {
+	var manifest bytes.Buffer
+	for _, chunk := range splitChunks(data) {
+		ch, err := s.Write(TypeChunk, chunk)
+		if err != nil {
+			return "", fmt.Errorf("object write %s: chunk: %w", h, err)
+		}
+		fmt.Fprintf(&manifest, "%s %d\n", ch, len(chunk))
+	}
+	compressed, err := compressObject(makeObjectEnvelope(chunkedBlobType, manifest.Bytes()))
+	if err != nil {
+		return "", fmt.Errorf("object write compress: %w", err)
+	}
+	return s.writeLoose(h, compressed)
+}
+
+// ChunkedBlobChunks returns the chunk hashes a loose chunked blob is made
+// of, or ok=false when h is not stored in chunked form.
+func (s *Store) ChunkedBlobChunks(h Hash) (chunks []Hash, ok bool, err error) {
+	typ, _, err := s.statLooseHeader(h)
+	if err != nil || typ != chunkedBlobType {
+		return nil, false, nil
+	}
+	manifest, err := s.readLooseEnvelope(h)
+	if err != nil {
+		return nil, false, err
+	}
+	entries, err := parseChunkList(h, manifest)
+	if err != nil {
+		return nil, false, err
+	}
+	chunks = make([]Hash, len(entries))
+	for i, e := range entries {
+		chunks[i] = e.hash
+	}
+	return chunks, true, nil
+}
+
+// readLooseEnvelope returns the envelope content of a loose object without
+// reassembling chunks or verifying the hash.
+func (s *Store) readLooseEnvelope(h Hash) ([]byte, error) {
+	onDisk, err := s.readObjectFile(s.objectPath(h), cryptKindLoose)
+	if err != nil {
+		return nil, fmt.Errorf("object read %s: %w", h, err)
+	}
+	raw, err := decompressObject(onDisk)
+	if err != nil {
+		return nil, fmt.Errorf("object read %s: decompress: %w", h, err)
+	}
+	_, content, err := parseObjectEnvelope(raw, h)
+	return content, err
+}
+
+type chunkListEntry struct {
+	hash Hash
+	size int64
+}
+
+func parseChunkList(h Hash, manifest []byte) ([]chunkListEntry, error) {
+	var entries []chunkListEntry
+	sc := bufio.NewScanner(bytes.NewReader(manifest))
+	for sc.Scan() {
+		hashStr, sizeStr, ok := strings.Cut(sc.Text(), " ")
+		size, err := strconv.ParseInt(sizeStr, 10, 64)
+		if !ok || err != nil || !isHexHashComponent(hashStr, 64) || size < 0 {
+			return nil, fmt.Errorf("object %s: invalid chunk list line %q", h, sc.Text())
+		}
+		entries = append(entries, chunkListEntry{hash: Hash(hashStr), size: size})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("object %s: read chunk list: %w", h, err)
+	}
+	return entries, nil
+}
+
+// chunkedBlobSize returns the total content size recorded in a chunk list.
+func chunkedBlobSize(h Hash, manifest []byte) (int64, error) {
+	entries, err := parseChunkList(h, manifest)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	return total, nil
+}
+
+// assembleChunkedBlob concatenates the chunks named by a chunk list. The
+// caller verifies the blob hash of the result.
+func (s *Store) assembleChunkedBlob(h Hash, manifest []byte) ([]byte, error) {
+	entries, err := parseChunkList(h, manifest)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	if total > maxDecompressedObjectSize {
+		return nil, fmt.Errorf("object %s: chunked blob exceeds maximum size (%d bytes)", h, maxDecompressedObjectSize)
+	}
+	out := make([]byte, 0, total)
+	for _, e := range entries {
+		typ, chunk, err := s.Read(e.hash)
+		if err != nil {
+			return nil, fmt.Errorf("object %s: chunk %s: %w", h, e.hash, err)
+		}
+		if typ != TypeChunk || int64(len(chunk)) != e.size {
+			return nil, fmt.Errorf("object %s: chunk %s: unexpected %s of %d bytes", h, e.hash, typ, len(chunk))
+		}
+		out = append(out, chunk...)
+	}
+	return out, nil
+}
+
+// splitChunks cuts data at content-defined boundaries using a gear rolling
+// hash, so that an insertion or deletion only moves the boundaries next to
+// the edit.
+func splitChunks(data []byte) [][]byte {
+	// Test the high bits: they mix in the most recent 64 bytes.
+	const mask = uint64(1<<cdcMaskBits-1) << (64 - cdcMaskBits)
+	var chunks [][]byte
+	for len(data) > 0 {
+		n := len(data)
+		if n > cdcMinChunk {
+			limit := n
+			if limit > cdcMaxChunk {
+				limit = cdcMaxChunk
+			}
+			n = limit
+			var fp uint64
+			for i := cdcMinChunk; i < limit; i++ {
+				fp = fp<<1 + gearTable[data[i]]
+				if fp&mask == 0 {
+					n = i + 1
+					break
+				}
+			}
+		}
+		chunks = append(chunks, data[:n])
+		data = data[n:]
+	}
+	return chunks
+}
+
+// gearTable maps each byte to a pseudo-random 64-bit value. It is generated
+// from a fixed seed so chunk boundaries are stable across versions.
+var gearTable = func() [256]uint64 {
+	var t [256]uint64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range t {
+		// splitmix64
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		t[i] = z ^ (z >> 31)
+	}
+	return t
+}()
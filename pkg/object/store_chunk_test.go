@@ -0,0 +1,152 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func makeLockfile(entries int, bump int) []byte {
+	var b strings.Builder
+	for i := 0; i < entries; i++ {
+		version := i % 9
+		if i == bump {
+			version = 42
+		}
+		fmt.Fprintf(&b, "\"pkg-%05d\": { \"version\": \"1.%d.0\", \"integrity\": \"sha512-%064x\" }\n", i, version, i*7919)
+	}
+	return []byte(b.String())
+}
+
+func TestSplitChunksIsContentDefined(t *testing.T) {
+	data := makeLockfile(4000, -1)
+	chunks := splitChunks(data)
+	if len(chunks) < 4 {
+		t.Fatalf("got %d chunks for %d bytes, want several", len(chunks), len(data))
+	}
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("chunks do not concatenate back to the input")
+	}
+	for i, c := range chunks {
+		if len(c) > cdcMaxChunk || (i < len(chunks)-1 && len(c) < cdcMinChunk) {
+			t.Fatalf("chunk %d has %d bytes, outside [%d, %d]", i, len(c), cdcMinChunk, cdcMaxChunk)
+		}
+	}
+
+	// Inserting bytes near the start only disturbs the boundaries around it.
+	edited := append([]byte("// generated\n"), data...)
+	before := make(map[string]struct{}, len(chunks))
+	for _, c := range chunks {
+		before[string(c)] = struct{}{}
+	}
+	shared := 0
+	editedChunks := splitChunks(edited)
+	for _, c := range editedChunks {
+		if _, ok := before[string(c)]; ok {
+			shared++
+		}
+	}
+	if shared < len(editedChunks)-2 {
+		t.Fatalf("only %d of %d chunks shared after a prefix insert", shared, len(editedChunks))
+	}
+}
+
+func TestStoreChunkedBlobsShareChunksAndStayReadable(t *testing.T) {
+	s := tempStore(t)
+	s.SetChunking(ChunkingOptions{Enabled: true, MinSize: 64 << 10})
+
+	v1 := makeLockfile(4000, -1)
+	v2 := makeLockfile(4000, 2500)
+	h1, err := s.Write(TypeBlob, v1)
+	if err != nil {
+		t.Fatalf("Write(v1): %v", err)
+	}
+	h2, err := s.Write(TypeBlob, v2)
+	if err != nil {
+		t.Fatalf("Write(v2): %v", err)
+	}
+	if h1 != HashObject(TypeBlob, v1) || h2 != HashObject(TypeBlob, v2) {
+		t.Fatal("chunked blobs must keep their plain blob hash")
+	}
+
+	for _, tc := range []struct {
+		h    Hash
+		data []byte
+	}{{h1, v1}, {h2, v2}} {
+		typ, got, err := s.Read(tc.h)
+		if err != nil {
+			t.Fatalf("Read(%s): %v", tc.h, err)
+		}
+		if typ != TypeBlob || !bytes.Equal(got, tc.data) {
+			t.Fatalf("Read(%s) = %s of %d bytes, want blob of %d bytes", tc.h, typ, len(got), len(tc.data))
+		}
+		typ, size, err := s.Stat(tc.h)
+		if err != nil || typ != TypeBlob || size != int64(len(tc.data)) {
+			t.Fatalf("Stat(%s) = %s, %d, %v", tc.h, typ, size, err)
+		}
+	}
+
+	c1, ok, err := s.ChunkedBlobChunks(h1)
+	if err != nil || !ok {
+		t.Fatalf("ChunkedBlobChunks(v1) ok=%v err=%v, want chunked", ok, err)
+	}
+	c2, _, _ := s.ChunkedBlobChunks(h2)
+	first := make(map[Hash]struct{}, len(c1))
+	for _, h := range c1 {
+		first[h] = struct{}{}
+	}
+	newChunks := 0
+	for _, h := range c2 {
+		if _, ok := first[h]; !ok {
+			newChunks++
+		}
+	}
+	if newChunks > 2 {
+		t.Fatalf("a one-line edit added %d of %d chunks, want at most 2", newChunks, len(c2))
+	}
+
+	if _, err := s.Verify(); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	reachable, err := s.ReachableSet([]Hash{h1})
+	if err != nil {
+		t.Fatalf("ReachableSet: %v", err)
+	}
+	for _, h := range c1 {
+		if _, ok := reachable[h]; !ok {
+			t.Fatalf("chunk %s of a reachable blob is not reachable", h)
+		}
+	}
+
+	if _, err := s.GCReachable([]Hash{h1, h2}); err != nil {
+		t.Fatalf("GCReachable: %v", err)
+	}
+	if _, ok, _ := s.ChunkedBlobChunks(h1); !ok {
+		t.Fatal("gc packed a chunked blob instead of leaving its chunk list loose")
+	}
+	if _, got, err := s.Read(h2); err != nil || !bytes.Equal(got, v2) {
+		t.Fatalf("Read(v2) after gc: %v", err)
+	}
+}
+
+func TestStoreChunkingSkipsSmallAndBinaryBlobs(t *testing.T) {
+	s := tempStore(t)
+	s.SetChunking(ChunkingOptions{Enabled: true, MinSize: 1024})
+
+	small, err := s.Write(TypeBlob, []byte("tiny"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := bytes.Repeat([]byte{0, 1, 2, 3}, 4096)
+	bin, err := s.Write(TypeBlob, binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []Hash{small, bin} {
+		if _, ok, _ := s.ChunkedBlobChunks(h); ok {
+			t.Fatalf("blob %s was chunked", h)
+		}
+	}
+}
@@ -0,0 +1,509 @@
+package object
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/odvcencio/graft/pkg/lockfile"
+)
+
+// EncryptionKeySize is the length in bytes of an object store encryption key
+// (AES-256).
+const EncryptionKeySize = 32
+
+// Encrypted files start with a fixed header followed by a sequence of
+// AES-256-GCM sealed segments. Each segment holds up to
+// encryptedSegmentSize bytes of plaintext; the nonce combines a random
+// per-file prefix with the segment index, and the additional data binds the
+// file kind, the index, and whether the segment is the last one, so segments
+// cannot be reordered, swapped between files of different kinds, or
+// truncated away.
+const (
+	encryptedMagic       = "GRAFTENC"
+	encryptedVersion     = 1
+	encryptedPrefixSize  = 8
+	encryptedHeaderSize  = len(encryptedMagic) + 1 + encryptedPrefixSize
+	encryptedSegmentSize = 64 << 10
+)
+
+// File kinds bound into the segment additional data.
+const (
+	cryptKindLoose = "loose"
+	cryptKindPack  = "pack"
+	cryptKindIndex = "idx"
+)
+
+// ErrEncryptionKeyRequired is returned when an encrypted object file is read
+// by a store that has no encryption key.
+var ErrEncryptionKeyRequired = errors.New("object store is encrypted but no encryption key is configured")
+
+type storeCipher struct {
+	aead cipher.AEAD
+}
+
+func newStoreCipher(key []byte) (*storeCipher, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &storeCipher{aead: aead}, nil
+}
+
+// SetEncryptionKey enables at-rest encryption: loose objects, packs and pack
+// indexes written from now on are sealed with key, and encrypted files are
+// decrypted transparently on read. Existing plaintext files stay readable;
+// EncryptExisting converts them. A nil key disables encryption of new
+// writes.
+func (s *Store) SetEncryptionKey(key []byte) error {
+	if key == nil {
+		s.crypt, s.cryptErr = nil, nil
+		return nil
+	}
+	c, err := newStoreCipher(key)
+	if err != nil {
+		return fmt.Errorf("set encryption key: %w", err)
+	}
+	s.crypt, s.cryptErr = c, nil
+	return nil
+}
+
+// SetEncryptionUnavailable records that encryption is configured but its key
+// could not be loaded. Writing object files then fails with err rather than
+// silently falling back to plaintext; reads of plaintext files still work.
+func (s *Store) SetEncryptionUnavailable(err error) {
+	s.crypt = nil
+	s.cryptErr = err
+}
+
+// EncryptionEnabled reports whether new object files are written encrypted.
+func (s *Store) EncryptionEnabled() bool {
+	return s.crypt != nil
+}
+
+// EncryptExisting encrypts every plaintext loose object, pack and pack
+// index in place and returns how many files were rewritten. Files that are
+// already encrypted are left alone.
+func (s *Store) EncryptExisting() (int, error) {
+	if s.cryptErr != nil {
+		return 0, fmt.Errorf("encrypt objects: %w", s.cryptErr)
+	}
+	if s.crypt == nil {
+		return 0, fmt.Errorf("encrypt objects: %w", ErrEncryptionKeyRequired)
+	}
+	var paths []struct{ path, kind string }
+	hashes, err := s.listLooseObjectHashes()
+	if err != nil {
+		return 0, err
+	}
+	for _, h := range hashes {
+		paths = append(paths, struct{ path, kind string }{s.objectPath(h), cryptKindLoose})
+	}
+
+	// Hold the pack lock so gc cannot replace packs while they are rewritten.
+	packDir := filepath.Join(s.root, "objects", "pack")
+	if err := os.MkdirAll(packDir, 0o755); err != nil {
+		return 0, fmt.Errorf("encrypt objects: mkdir pack dir: %w", err)
+	}
+	lock, err := lockfile.Acquire(filepath.Join(packDir, "pack"), packLockWaitLimit)
+	if err != nil {
+		return 0, fmt.Errorf("encrypt objects: %w", err)
+	}
+	defer lock.Release()
+	idxPaths, err := s.listPackIndexPaths()
+	if err != nil {
+		return 0, err
+	}
+	for _, idxPath := range idxPaths {
+		paths = append(paths,
+			struct{ path, kind string }{packPathForIndex(idxPath), cryptKindPack},
+			struct{ path, kind string }{idxPath, cryptKindIndex})
+	}
+
+	rewritten := 0
+	for _, p := range paths {
+		changed, err := s.encryptFileInPlace(p.path, p.kind)
+		if err != nil {
+			return rewritten, fmt.Errorf("encrypt objects: %s: %w", filepath.Base(p.path), err)
+		}
+		if changed {
+			rewritten++
+		}
+	}
+	s.InvalidatePackIndexCache()
+	return rewritten, nil
+}
+
+func (s *Store) encryptFileInPlace(path, kind string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if isEncryptedData(data) {
+		return false, nil
+	}
+	sealed, err := s.crypt.seal(kind, data)
+	if err != nil {
+		return false, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-encrypt-*")
+	if err != nil {
+		return false, err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return false, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return false, err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return false, err
+	}
+	return true, nil
+}
+
+// missingKeyError explains why an encrypted file cannot be read, including
+// the reason the configured key failed to load when there is one.
+func (s *Store) missingKeyError() error {
+	if s.cryptErr != nil {
+		return fmt.Errorf("%w: %v", ErrEncryptionKeyRequired, s.cryptErr)
+	}
+	return ErrEncryptionKeyRequired
+}
+
+func isEncryptedData(data []byte) bool {
+	return len(data) >= len(encryptedMagic) && string(data[:len(encryptedMagic)]) == encryptedMagic
+}
+
+// readObjectFile reads a whole loose object or pack index file, decrypting
+// it when it is encrypted.
+func (s *Store) readObjectFile(path, kind string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isEncryptedData(data) {
+		return data, nil
+	}
+	if s.crypt == nil {
+		return nil, s.missingKeyError()
+	}
+	return s.crypt.open(kind, data)
+}
+
+// sealObjectData encrypts data for storage when encryption is enabled and
+// returns it unchanged otherwise.
+func (s *Store) sealObjectData(kind string, data []byte) ([]byte, error) {
+	if s.cryptErr != nil {
+		return nil, s.cryptErr
+	}
+	if s.crypt == nil {
+		return data, nil
+	}
+	return s.crypt.seal(kind, data)
+}
+
+// packReader gives random access to the plaintext of a pack file.
+type packReader interface {
+	io.ReaderAt
+	io.Closer
+	Size() int64
+}
+
+type plainPackReader struct {
+	*os.File
+	size int64
+}
+
+func (p *plainPackReader) Size() int64 { return p.size }
+
+type encryptedPackReader struct {
+	*encryptedReaderAt
+	f *os.File
+}
+
+func (p *encryptedPackReader) Close() error { return p.f.Close() }
+
+// openPackFile opens a pack for random access, decrypting it on the fly
+// when it is encrypted.
+func (s *Store) openPackFile(path string) (packReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	head := make([]byte, len(encryptedMagic))
+	if n, _ := f.ReadAt(head, 0); !isEncryptedData(head[:n]) {
+		return &plainPackReader{File: f, size: info.Size()}, nil
+	}
+	if s.crypt == nil {
+		f.Close()
+		return nil, s.missingKeyError()
+	}
+	r, err := s.crypt.newReaderAt(f, info.Size(), cryptKindPack)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &encryptedPackReader{encryptedReaderAt: r, f: f}, nil
+}
+
+// readPackFile reads a whole pack, decrypting it when it is encrypted.
+func (s *Store) readPackFile(path string) ([]byte, error) {
+	return s.readObjectFile(path, cryptKindPack)
+}
+
+// newObjectFileWriter wraps w so that everything written through it is
+// encrypted when encryption is enabled. The returned finish function must be
+// called after the last write to flush the final segment.
+func (s *Store) newObjectFileWriter(w io.Writer, kind string) (io.Writer, func() error, error) {
+	if s.cryptErr != nil {
+		return nil, nil, s.cryptErr
+	}
+	if s.crypt == nil {
+		return w, func() error { return nil }, nil
+	}
+	ew, err := s.crypt.newWriter(w, kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ew, ew.Close, nil
+}
+
+func (c *storeCipher) seal(kind string, plain []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := c.newWriter(&buf, kind)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *storeCipher) open(kind string, data []byte) ([]byte, error) {
+	r, err := c.newReaderAt(bytes.NewReader(data), int64(len(data)), kind)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, r.Size())
+	if _, err := r.ReadAt(out, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeCipher) segmentNonce(prefix []byte, index uint32) []byte {
+	nonce := make([]byte, c.aead.NonceSize())
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[len(nonce)-4:], index)
+	return nonce
+}
+
+func segmentAdditionalData(kind string, index uint32, final bool) []byte {
+	ad := make([]byte, 0, len(kind)+6)
+	ad = append(ad, kind...)
+	ad = append(ad, 0)
+	ad = binary.BigEndian.AppendUint32(ad, index)
+	if final {
+		ad = append(ad, 1)
+	} else {
+		ad = append(ad, 0)
+	}
+	return ad
+}
+
+// encryptedWriter seals a stream into segments. A full segment is only
+// flushed once more data arrives, so Close always emits a final segment
+// (possibly empty) marked as such.
+type encryptedWriter struct {
+	c      *storeCipher
+	w      io.Writer
+	kind   string
+	prefix []byte
+	index  uint32
+	buf    []byte
+	closed bool
+}
+
+func (c *storeCipher) newWriter(w io.Writer, kind string) (*encryptedWriter, error) {
+	prefix := make([]byte, encryptedPrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("encryption nonce: %w", err)
+	}
+	header := make([]byte, 0, encryptedHeaderSize)
+	header = append(header, encryptedMagic...)
+	header = append(header, encryptedVersion)
+	header = append(header, prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptedWriter{c: c, w: w, kind: kind, prefix: prefix}, nil
+}
+
+func (e *encryptedWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed encrypted writer")
+	}
+	e.buf = append(e.buf, p...)
+	for len(e.buf) > encryptedSegmentSize {
+		if err := e.flush(e.buf[:encryptedSegmentSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[encryptedSegmentSize:]
+	}
+	return len(p), nil
+}
+
+func (e *encryptedWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.flush(e.buf, true)
+}
+
+func (e *encryptedWriter) flush(plain []byte, final bool) error {
+	if e.index == ^uint32(0) {
+		return errors.New("encrypted file too large")
+	}
+	sealed := e.c.aead.Seal(nil, e.c.segmentNonce(e.prefix, e.index), plain, segmentAdditionalData(e.kind, e.index, final))
+	e.index++
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// encryptedReaderAt decrypts segments on demand, caching the most recently
+// opened one since pack reads cluster around nearby offsets.
+type encryptedReaderAt struct {
+	c         *storeCipher
+	r         io.ReaderAt
+	kind      string
+	prefix    []byte
+	segments  int64
+	lastSize  int64 // ciphertext size of the final segment
+	plainSize int64
+
+	mu       sync.Mutex
+	cacheIdx int64
+	cache    []byte
+}
+
+func (c *storeCipher) newReaderAt(r io.ReaderAt, size int64, kind string) (*encryptedReaderAt, error) {
+	header := make([]byte, encryptedHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("read encryption header: %w", err)
+	}
+	if string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return nil, errors.New("not an encrypted object file")
+	}
+	if v := header[len(encryptedMagic)]; v != encryptedVersion {
+		return nil, fmt.Errorf("unsupported encryption version %d", v)
+	}
+	overhead := int64(c.aead.Overhead())
+	full := int64(encryptedSegmentSize) + overhead
+	body := size - int64(encryptedHeaderSize)
+	segments := (body + full - 1) / full
+	if body < overhead || segments == 0 {
+		return nil, errors.New("truncated encrypted object file")
+	}
+	lastSize := body - (segments-1)*full
+	if lastSize < overhead {
+		return nil, errors.New("truncated encrypted object file")
+	}
+	return &encryptedReaderAt{
+		c:         c,
+		r:         r,
+		kind:      kind,
+		prefix:    append([]byte(nil), header[len(encryptedMagic)+1:]...),
+		segments:  segments,
+		lastSize:  lastSize,
+		plainSize: (segments-1)*encryptedSegmentSize + lastSize - overhead,
+		cacheIdx:  -1,
+	}, nil
+}
+
+// Size returns the plaintext size.
+func (e *encryptedReaderAt) Size() int64 { return e.plainSize }
+
+func (e *encryptedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= e.plainSize {
+			return n, io.EOF
+		}
+		idx := pos / encryptedSegmentSize
+		seg, err := e.segment(idx)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], seg[pos-idx*encryptedSegmentSize:])
+	}
+	return n, nil
+}
+
+func (e *encryptedReaderAt) segment(idx int64) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if idx == e.cacheIdx {
+		return e.cache, nil
+	}
+	overhead := int64(e.c.aead.Overhead())
+	full := int64(encryptedSegmentSize) + overhead
+	final := idx == e.segments-1
+	size := full
+	if final {
+		size = e.lastSize
+	}
+	sealed := make([]byte, size)
+	if _, err := e.r.ReadAt(sealed, int64(encryptedHeaderSize)+idx*full); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read encrypted segment %d: %w", idx, err)
+	}
+	plain, err := e.c.aead.Open(nil, e.c.segmentNonce(e.prefix, uint32(idx)), sealed, segmentAdditionalData(e.kind, uint32(idx), final))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s segment %d: %w", e.kind, idx, err)
+	}
+	e.cacheIdx = idx
+	e.cache = plain
+	return plain, nil
+}
@@ -0,0 +1,179 @@
+package object
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testEncryptionKey() []byte {
+	return bytes.Repeat([]byte{0x5a}, EncryptionKeySize)
+}
+
+func TestStoreEncryptedObjectsRoundTripAndHidePlaintext(t *testing.T) {
+	s := tempStore(t)
+	if err := s.SetEncryptionKey(testEncryptionKey()); err != nil {
+		t.Fatalf("SetEncryptionKey: %v", err)
+	}
+
+	secret := []byte("top secret launch codes\n")
+	// Incompressible so the pack spans several encrypted segments.
+	big := make([]byte, 3*encryptedSegmentSize)
+	if _, err := rand.Read(big); err != nil {
+		t.Fatalf("rand: %v", err)
+	}
+	hSecret, err := s.Write(TypeBlob, secret)
+	if err != nil {
+		t.Fatalf("Write(secret): %v", err)
+	}
+	onDisk, err := os.ReadFile(s.objectPath(hSecret))
+	if err != nil {
+		t.Fatalf("read loose file: %v", err)
+	}
+	if !isEncryptedData(onDisk) {
+		t.Fatal("loose object was not written encrypted")
+	}
+	if _, got, err := s.Read(hSecret); err != nil || !bytes.Equal(got, secret) {
+		t.Fatalf("Read(loose) = %q, %v", got, err)
+	}
+	if typ, size, err := s.Stat(hSecret); err != nil || typ != TypeBlob || size != int64(len(secret)) {
+		t.Fatalf("Stat(loose) = %s %d, %v", typ, size, err)
+	}
+
+	hBig, err := s.Write(TypeBlob, big)
+	if err != nil {
+		t.Fatalf("Write(big): %v", err)
+	}
+	summary, err := s.GC()
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	packDir := filepath.Join(s.root, "objects", "pack")
+	for _, name := range []string{summary.PackFile, summary.IndexFile} {
+		data, err := os.ReadFile(filepath.Join(packDir, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if !isEncryptedData(data) {
+			t.Fatalf("%s was not written encrypted", name)
+		}
+		if name == summary.PackFile && len(data) < 2*encryptedSegmentSize {
+			t.Fatalf("pack is %d bytes, want several segments", len(data))
+		}
+		if bytes.Contains(data, []byte("PACK")) || bytes.Contains(data, secret) {
+			t.Fatalf("%s leaks plaintext", name)
+		}
+	}
+
+	s.InvalidatePackIndexCache()
+	for h, want := range map[Hash][]byte{hSecret: secret, hBig: big} {
+		if _, got, err := s.Read(h); err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("Read(packed %s) = %d bytes, %v", h, len(got), err)
+		}
+	}
+	count := 0
+	if err := s.ForEachObject(TypeBlob, func(ObjectInfo) error { count++; return nil }); err != nil || count != 2 {
+		t.Fatalf("ForEachObject = %d blobs, %v", count, err)
+	}
+	report, err := s.VerifyWithOptions(VerifyOptions{})
+	if err != nil || len(report.Corrupt) != 0 {
+		t.Fatalf("Verify = %+v, %v", report, err)
+	}
+
+	locked := NewStore(s.root)
+	if _, _, err := locked.Read(hBig); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Fatalf("Read without key error = %v, want ErrEncryptionKeyRequired", err)
+	}
+}
+
+func TestStoreEncryptedFilesDetectTampering(t *testing.T) {
+	s := tempStore(t)
+	if err := s.SetEncryptionKey(testEncryptionKey()); err != nil {
+		t.Fatalf("SetEncryptionKey: %v", err)
+	}
+	h, err := s.Write(TypeBlob, []byte("integrity matters"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	path := s.objectPath(h)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read loose file: %v", err)
+	}
+	data[len(data)-1] ^= 0x01
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write tampered file: %v", err)
+	}
+	if _, _, err := s.Read(h); err == nil || !strings.Contains(err.Error(), "decrypt") {
+		t.Fatalf("Read(tampered) error = %v, want decrypt failure", err)
+	}
+
+	other := tempStore(t)
+	if err := other.SetEncryptionKey(bytes.Repeat([]byte{0x01}, EncryptionKeySize)); err != nil {
+		t.Fatalf("SetEncryptionKey: %v", err)
+	}
+	h2, err := s.Write(TypeBlob, []byte("wrong key"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	sealed, err := os.ReadFile(s.objectPath(h2))
+	if err != nil {
+		t.Fatalf("read loose file: %v", err)
+	}
+	if _, err := other.crypt.open(cryptKindLoose, sealed); err == nil {
+		t.Fatal("opening with the wrong key succeeded")
+	}
+	if _, err := s.crypt.open(cryptKindPack, sealed); err == nil {
+		t.Fatal("opening a loose object as a pack succeeded")
+	}
+}
+
+func TestStoreEncryptExistingConvertsPlaintextStore(t *testing.T) {
+	s := tempStore(t)
+	packed, err := s.Write(TypeBlob, []byte("packed before encryption"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := s.GC(); err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	loose, err := s.Write(TypeBlob, []byte("loose before encryption"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if _, err := s.EncryptExisting(); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Fatalf("EncryptExisting without key error = %v", err)
+	}
+	if err := s.SetEncryptionKey(testEncryptionKey()); err != nil {
+		t.Fatalf("SetEncryptionKey: %v", err)
+	}
+	// Plaintext files remain readable once a key is set.
+	if _, _, err := s.Read(packed); err != nil {
+		t.Fatalf("Read(plaintext pack) with key: %v", err)
+	}
+
+	n, err := s.EncryptExisting()
+	if err != nil {
+		t.Fatalf("EncryptExisting: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("EncryptExisting rewrote %d files, want 3 (loose, pack, idx)", n)
+	}
+	if n, err := s.EncryptExisting(); err != nil || n != 0 {
+		t.Fatalf("second EncryptExisting = %d, %v; want 0", n, err)
+	}
+	data, err := os.ReadFile(s.objectPath(loose))
+	if err != nil || !isEncryptedData(data) {
+		t.Fatalf("loose object not encrypted: %v", err)
+	}
+	for _, h := range []Hash{packed, loose} {
+		if _, _, err := s.Read(h); err != nil {
+			t.Fatalf("Read(%s) after EncryptExisting: %v", h, err)
+		}
+	}
+}
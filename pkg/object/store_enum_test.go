@@ -0,0 +1,161 @@
+package object
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestStoreForEachObjectFiltersByTypeAcrossLooseAndPacks(t *testing.T) {
+	s := tempStore(t)
+
+	blobHash, err := s.Write(TypeBlob, []byte("packed blob"))
+	if err != nil {
+		t.Fatalf("Write(blob): %v", err)
+	}
+	entityHash, err := s.Write(TypeEntity, []byte("packed entity"))
+	if err != nil {
+		t.Fatalf("Write(entity): %v", err)
+	}
+	treeHash, err := s.WriteTree(&TreeObj{Entries: []TreeEntry{{Name: "a.txt", BlobHash: blobHash}}})
+	if err != nil {
+		t.Fatalf("WriteTree: %v", err)
+	}
+	if _, err := s.GC(); err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	looseBlob, err := s.Write(TypeBlob, []byte("loose blob"))
+	if err != nil {
+		t.Fatalf("Write(loose blob): %v", err)
+	}
+	// A loose copy of a packed object must only be visited once.
+	if err := s.ReplaceLoose(blobHash, TypeBlob, []byte("packed blob")); err != nil {
+		t.Fatalf("ReplaceLoose: %v", err)
+	}
+
+	deltaBase := []byte("raw wire blob base\n")
+	deltaTarget := []byte("raw wire blob base, edited\n")
+	deltaHash := writeRawDeltaPack(t, s, deltaBase, deltaTarget)
+
+	collect := func(objType ObjectType) map[Hash]ObjectInfo {
+		t.Helper()
+		out := make(map[Hash]ObjectInfo)
+		err := s.ForEachObject(objType, func(info ObjectInfo) error {
+			if _, dup := out[info.Hash]; dup {
+				t.Fatalf("object %s visited twice", info.Hash)
+			}
+			out[info.Hash] = info
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ForEachObject(%q): %v", objType, err)
+		}
+		return out
+	}
+
+	blobs := collect(TypeBlob)
+	wantBlobs := []Hash{blobHash, looseBlob, HashObject(TypeBlob, deltaBase), deltaHash}
+	if got := sortedInfoHashes(blobs); !equalHashSets(got, wantBlobs) {
+		t.Fatalf("blobs = %v, want %v", got, wantBlobs)
+	}
+	if info := blobs[deltaHash]; !info.Packed || info.Size != int64(len(deltaTarget)) {
+		t.Fatalf("delta blob info = %+v", info)
+	}
+	if info := blobs[looseBlob]; info.Packed || info.Size != int64(len("loose blob")) {
+		t.Fatalf("loose blob info = %+v", info)
+	}
+
+	entities := collect(TypeEntity)
+	if info, ok := entities[entityHash]; len(entities) != 1 || !ok || !info.Packed || info.Size != int64(len("packed entity")) {
+		t.Fatalf("entities = %+v, want only the packed entity", entities)
+	}
+	trees := collect(TypeTree)
+	if _, ok := trees[treeHash]; len(trees) != 1 || !ok {
+		t.Fatalf("trees = %+v, want only %s", trees, treeHash)
+	}
+	if all := collect(""); len(all) != 6 {
+		t.Fatalf("all objects = %d, want 6", len(all))
+	}
+
+	visited := 0
+	err = s.ForEachObject("", func(ObjectInfo) error {
+		visited++
+		return ErrStopIteration
+	})
+	if err != nil || visited != 1 {
+		t.Fatalf("ErrStopIteration: visited=%d err=%v, want 1 visit and nil error", visited, err)
+	}
+}
+
+// writeRawDeltaPack installs a pack whose entries carry raw content (no
+// object envelope), with target stored as an OFS_DELTA against base, and
+// returns the target hash.
+func writeRawDeltaPack(t *testing.T, s *Store, base, target []byte) Hash {
+	t.Helper()
+	var buf bytes.Buffer
+	pw, err := NewPackWriter(&buf, 2)
+	if err != nil {
+		t.Fatalf("NewPackWriter: %v", err)
+	}
+	baseOffset := pw.CurrentOffset()
+	if err := pw.WriteEntry(PackBlob, base); err != nil {
+		t.Fatalf("WriteEntry: %v", err)
+	}
+	targetOffset := pw.CurrentOffset()
+	if err := pw.WriteOfsDelta(baseOffset, base, target); err != nil {
+		t.Fatalf("WriteOfsDelta: %v", err)
+	}
+	checksum, err := pw.Finish()
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	packDir := filepath.Join(s.root, "objects", "pack")
+	if err := os.MkdirAll(packDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	name := "pack-" + string(checksum)
+	if err := os.WriteFile(filepath.Join(packDir, name+".pack"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	targetHash := HashObject(TypeBlob, target)
+	var idx bytes.Buffer
+	if _, err := WritePackIndex(&idx, []PackIndexEntry{
+		{Hash: HashObject(TypeBlob, base), Offset: baseOffset},
+		{Hash: targetHash, Offset: targetOffset},
+	}, checksum); err != nil {
+		t.Fatalf("WritePackIndex: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(packDir, name+".idx"), idx.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.InvalidatePackIndexCache()
+	return targetHash
+}
+
+func sortedInfoHashes(m map[Hash]ObjectInfo) []Hash {
+	out := make([]Hash, 0, len(m))
+	for h := range m {
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func equalHashSets(got, want []Hash) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	set := make(map[Hash]struct{}, len(got))
+	for _, h := range got {
+		set[h] = struct{}{}
+	}
+	for _, h := range want {
+		if _, ok := set[h]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeepSuffix marks a pack that repack and gc must never rewrite or delete,
+// e.g. because it is served from read-only or shared media. The marker lives
+// next to the pack as pack-<checksum>.keep; its content is an optional reason.
+const KeepSuffix = ".keep"
+
+// IsPackKept reports whether the named pack has a .keep marker.
+func (s *Store) IsPackKept(packName string) bool {
+	_, err := os.Stat(s.keepPath(packName))
+	return err == nil
+}
+
+// KeepPack writes a .keep marker for the named pack, recording reason as the
+// marker content. The pack must exist.
+func (s *Store) KeepPack(packName, reason string) error {
+	packPath := filepath.Join(s.root, "objects", "pack", packFileName(packName))
+	if _, err := os.Stat(packPath); err != nil {
+		return fmt.Errorf("keep pack %s: %w", filepath.Base(packPath), err)
+	}
+	if reason != "" && !strings.HasSuffix(reason, "\n") {
+		reason += "\n"
+	}
+	if err := os.WriteFile(s.keepPath(packName), []byte(reason), 0o644); err != nil {
+		return fmt.Errorf("keep pack %s: %w", filepath.Base(packPath), err)
+	}
+	return nil
+}
+
+// UnkeepPack removes the .keep marker for the named pack. A missing marker is
+// not an error.
+func (s *Store) UnkeepPack(packName string) error {
+	if err := os.Remove(s.keepPath(packName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unkeep pack %s: %w", packFileName(packName), err)
+	}
+	return nil
+}
+
+// keptPackedHashSet returns the hashes indexed by kept packs along with the
+// number of kept packs.
+func (s *Store) keptPackedHashSet() (map[Hash]struct{}, int, error) {
+	idxPaths, err := s.listPackIndexPaths()
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make(map[Hash]struct{})
+	kept := 0
+	for _, idxPath := range idxPaths {
+		if !s.IsPackKept(filepath.Base(packPathForIndex(idxPath))) {
+			continue
+		}
+		kept++
+		idx, err := s.cachedPackIndex(idxPath)
+		if err != nil {
+			return nil, 0, fmt.Errorf("pack index %s: %w", filepath.Base(idxPath), err)
+		}
+		for _, entry := range idx.Entries() {
+			out[entry.Hash] = struct{}{}
+		}
+	}
+	return out, kept, nil
+}
+
+func (s *Store) keepPath(packName string) string {
+	base := strings.TrimSuffix(packFileName(packName), ".pack")
+	return filepath.Join(s.root, "objects", "pack", base+KeepSuffix)
+}
+
+// packFileName normalizes a pack reference ("pack-<sum>", "pack-<sum>.pack",
+// "pack-<sum>.idx" or a path) to its .pack file name.
+func packFileName(packName string) string {
+	base := filepath.Base(packName)
+	base = strings.TrimSuffix(base, ".idx")
+	base = strings.TrimSuffix(base, KeepSuffix)
+	if !strings.HasSuffix(base, ".pack") {
+		base += ".pack"
+	}
+	return base
+}
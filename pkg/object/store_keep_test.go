@@ -0,0 +1,131 @@
+package object
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreRepackConsolidatesPacksButSkipsKept(t *testing.T) {
+	s := tempStore(t)
+
+	gcOne := func(payload string) (Hash, *GCSummary) {
+		t.Helper()
+		h, err := s.Write(TypeBlob, []byte(payload))
+		if err != nil {
+			t.Fatalf("Write(%q): %v", payload, err)
+		}
+		summary, err := s.GC()
+		if err != nil {
+			t.Fatalf("GC: %v", err)
+		}
+		if summary.PackFile == "" {
+			t.Fatalf("GC did not write a pack for %q", payload)
+		}
+		return h, summary
+	}
+
+	keptHash, keptSummary := gcOne("kept payload")
+	firstHash, firstSummary := gcOne("first payload")
+	secondHash, secondSummary := gcOne("second payload")
+
+	if err := s.KeepPack(keptSummary.PackFile, "served from shared media"); err != nil {
+		t.Fatalf("KeepPack: %v", err)
+	}
+	if !s.IsPackKept(strings.TrimSuffix(keptSummary.PackFile, ".pack")) {
+		t.Fatal("IsPackKept = false after KeepPack")
+	}
+	keepData, err := os.ReadFile(s.keepPath(keptSummary.PackFile))
+	if err != nil || string(keepData) != "served from shared media\n" {
+		t.Fatalf("keep marker = %q, %v", keepData, err)
+	}
+
+	looseHash, err := s.Write(TypeBlob, []byte("loose payload"))
+	if err != nil {
+		t.Fatalf("Write(loose): %v", err)
+	}
+
+	summary, err := s.Repack(nil)
+	if err != nil {
+		t.Fatalf("Repack: %v", err)
+	}
+	if summary.KeptPacks != 1 {
+		t.Fatalf("KeptPacks = %d, want 1", summary.KeptPacks)
+	}
+	if summary.PackedObjects != 3 {
+		t.Fatalf("PackedObjects = %d, want 3", summary.PackedObjects)
+	}
+	if len(summary.RemovedPacks) != 2 {
+		t.Fatalf("RemovedPacks = %v, want 2 packs", summary.RemovedPacks)
+	}
+
+	packDir := filepath.Join(s.root, "objects", "pack")
+	if _, err := os.Stat(filepath.Join(packDir, keptSummary.PackFile)); err != nil {
+		t.Fatalf("kept pack was removed: %v", err)
+	}
+	for _, gone := range []*GCSummary{firstSummary, secondSummary} {
+		if _, err := os.Stat(filepath.Join(packDir, gone.PackFile)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be replaced, stat err=%v", gone.PackFile, err)
+		}
+		if _, err := os.Stat(filepath.Join(packDir, gone.IndexFile)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be replaced, stat err=%v", gone.IndexFile, err)
+		}
+	}
+
+	packs, err := s.PackFiles()
+	if err != nil {
+		t.Fatalf("PackFiles: %v", err)
+	}
+	if len(packs) != 2 {
+		t.Fatalf("PackFiles = %+v, want kept pack plus repacked pack", packs)
+	}
+	for _, p := range packs {
+		if want := p.Name == keptSummary.PackFile; p.Kept != want {
+			t.Fatalf("pack %s Kept = %v, want %v", p.Name, p.Kept, want)
+		}
+		if p.Name == summary.PackFile && p.Objects != 3 {
+			t.Fatalf("repacked pack holds %d objects, want 3", p.Objects)
+		}
+	}
+
+	for _, h := range []Hash{keptHash, firstHash, secondHash, looseHash} {
+		if _, _, err := s.Read(h); err != nil {
+			t.Fatalf("Read(%s) after repack: %v", h, err)
+		}
+	}
+
+	again, err := s.Repack(nil)
+	if err != nil {
+		t.Fatalf("second Repack: %v", err)
+	}
+	if again.PackedObjects != 0 || len(again.RemovedPacks) != 0 {
+		t.Fatalf("second Repack = %+v, want no-op", again)
+	}
+}
+
+func TestStoreSalvagePackRefusesKeptPack(t *testing.T) {
+	s := tempStore(t)
+	if _, err := s.Write(TypeBlob, []byte("payload")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	summary, err := s.GC()
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	if err := s.KeepPack(summary.PackFile, ""); err != nil {
+		t.Fatalf("KeepPack: %v", err)
+	}
+	if _, err := s.SalvagePack(summary.PackFile); err == nil || !strings.Contains(err.Error(), KeepSuffix) {
+		t.Fatalf("SalvagePack err = %v, want .keep refusal", err)
+	}
+	if err := s.UnkeepPack(summary.PackFile); err != nil {
+		t.Fatalf("UnkeepPack: %v", err)
+	}
+	if s.IsPackKept(summary.PackFile) {
+		t.Fatal("IsPackKept = true after UnkeepPack")
+	}
+	if err := s.UnkeepPack(summary.PackFile); err != nil {
+		t.Fatalf("UnkeepPack (missing marker): %v", err)
+	}
+}
@@ -15,27 +15,86 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/lockfile"
 )
 
+// packLockWaitLimit bounds how long GC waits for a concurrent pack writer in
+// another process to release objects/pack/pack.lock.
+var packLockWaitLimit = 30 * time.Second
+
 // GCSummary reports the outcome of Store.GC.
 type GCSummary struct {
 	PackedObjects int
 	PrunedObjects int
 	PackFile      string
 	IndexFile     string
+	// RemovedPacks lists packs consolidated into PackFile by a repack.
+	RemovedPacks []string
+	// KeptPacks counts packs left untouched because of a .keep marker.
+	KeptPacks int
+	// DeltaObjects counts the packed objects stored as deltas against
+	// another object of the pack.
+	DeltaObjects int
 }
 
-// VerifySummary reports the outcome of Store.Verify.
+// DefaultAggressiveDeltaWindow is the delta window of an aggressive repack
+// when none is configured.
+const DefaultAggressiveDeltaWindow = 250
+
+// RepackOptions tunes Store.RepackWithOptions.
+type RepackOptions struct {
+	// DeltaWindow is how many neighbouring objects of the same type are
+	// tried as delta bases for each packed object. Zero stores every
+	// object whole.
+	DeltaWindow int
+	// DeltaDepth bounds the length of delta chains. Zero, or a depth the
+	// pack reader cannot follow, uses the deepest chain it can.
+	DeltaDepth int
+}
+
+// VerifySummary reports the outcome of Store.Verify. The counts cover objects
+// and packs that verified cleanly; failures are listed in Corrupt.
 type VerifySummary struct {
 	LooseObjects int
 	PackFiles    int
 	PackObjects  int
+	Corrupt      []CorruptObject
+}
+
+// CorruptObject describes one integrity failure found by verification.
+// Location is "loose" or the pack file name; Hash is empty when a whole pack
+// or index could not be checked.
+type CorruptObject struct {
+	Hash     Hash
+	Location string
+	Err      error
+}
+
+// VerifyOptions configures Store.VerifyWithOptions.
+type VerifyOptions struct {
+	// Workers bounds the number of objects decoded concurrently. Zero uses
+	// GOMAXPROCS.
+	Workers int
+	// Progress, if set, is called after each object is checked. Calls are
+	// serialized within a phase.
+	Progress func(VerifyProgress)
+}
+
+// VerifyProgress reports verification progress for one phase: the loose
+// object scan or the entries of a single pack.
+type VerifyProgress struct {
+	Phase string // "loose" or "pack"
+	Pack  string // pack file name for the "pack" phase
+	Done  int
+	Total int
 }
 
 // GC packs all loose objects that are not already indexed by an existing pack
 // idx. After a successful pack+index write, packed loose objects are removed.
 func (s *Store) GC() (*GCSummary, error) {
-	return s.gcWithReachableSet(nil)
+	return s.gcWithReachableSet(nil, false, RepackOptions{})
 }
 
 // GCReachable packs loose objects reachable from roots that are not already
@@ -46,14 +105,51 @@ func (s *Store) GCReachable(roots []Hash) (*GCSummary, error) {
 	if err != nil {
 		return nil, err
 	}
-	return s.gcWithReachableSet(reachable)
+	return s.gcWithReachableSet(reachable, false, RepackOptions{})
+}
+
+// Repack consolidates loose objects reachable from roots and every object in
+// existing packs into a single new pack, then deletes the packs it replaced.
+// Packs marked with a .keep file are never rewritten or deleted, and objects
+// they already hold are not copied into the new pack. A nil roots slice packs
+// all loose objects.
+func (s *Store) Repack(roots []Hash) (*GCSummary, error) {
+	return s.RepackWithOptions(roots, RepackOptions{})
+}
+
+// RepackWithOptions is Repack with delta compression: with a DeltaWindow,
+// each object is stored as a delta against the most similar of its
+// neighbours when that saves at least half its size.
+func (s *Store) RepackWithOptions(roots []Hash, opts RepackOptions) (*GCSummary, error) {
+	var reachable map[Hash]struct{}
+	if roots != nil {
+		var err error
+		reachable, err = s.ReachableSet(roots)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.gcWithReachableSet(reachable, true, opts)
 }
 
-func (s *Store) gcWithReachableSet(reachable map[Hash]struct{}) (*GCSummary, error) {
-	if reachable != nil && len(reachable) == 0 {
+func (s *Store) gcWithReachableSet(reachable map[Hash]struct{}, repack bool, opts RepackOptions) (*GCSummary, error) {
+	if !repack && reachable != nil && len(reachable) == 0 {
 		return &GCSummary{}, nil
 	}
 
+	packDir := filepath.Join(s.root, "objects", "pack")
+	if err := os.MkdirAll(packDir, 0o755); err != nil {
+		return nil, fmt.Errorf("gc: mkdir pack dir: %w", err)
+	}
+
+	// Hold the pack lock for the whole cycle so a concurrent process cannot
+	// pack the same loose objects or prune them mid-write.
+	lock, err := lockfile.Acquire(filepath.Join(packDir, "pack"), packLockWaitLimit)
+	if err != nil {
+		return nil, fmt.Errorf("gc: %w", err)
+	}
+	defer lock.Release()
+
 	looseHashes, err := s.listLooseObjectHashes()
 	if err != nil {
 		return nil, err
@@ -64,30 +160,81 @@ func (s *Store) gcWithReachableSet(reachable map[Hash]struct{}) (*GCSummary, err
 		return nil, err
 	}
 
-	toPack := make([]Hash, 0, len(looseHashes))
+	// A repack folds every pack without a .keep marker into the new pack.
+	// Objects held by kept packs stay where they are.
+	var replaced []string
+	keptCount := 0
+	if repack {
+		kept, keptPacks, err := s.keptPackedHashSet()
+		if err != nil {
+			return nil, err
+		}
+		keptCount = keptPacks
+		idxPaths, err := s.listPackIndexPaths()
+		if err != nil {
+			return nil, err
+		}
+		for _, idxPath := range idxPaths {
+			if s.IsPackKept(filepath.Base(packPathForIndex(idxPath))) {
+				continue
+			}
+			replaced = append(replaced, idxPath)
+		}
+		packed = kept
+	}
+
+	candidates := make(map[Hash]struct{}, len(looseHashes))
+	for _, idxPath := range replaced {
+		idx, err := s.cachedPackIndex(idxPath)
+		if err != nil {
+			return nil, fmt.Errorf("gc: pack index %s: %w", filepath.Base(idxPath), err)
+		}
+		for _, entry := range idx.Entries() {
+			candidates[entry.Hash] = struct{}{}
+		}
+	}
+	newLoose := 0
 	for _, h := range looseHashes {
 		if reachable != nil {
 			if _, ok := reachable[h]; !ok {
 				continue
 			}
 		}
+		if _, ok := candidates[h]; ok {
+			continue
+		}
+		// Chunked blobs stay loose so their chunks remain shared between
+		// versions instead of being inflated into the pack.
+		if typ, _, err := s.statLooseHeader(h); err == nil && typ == chunkedBlobType {
+			continue
+		}
+		if _, ok := packed[h]; ok {
+			continue
+		}
+		candidates[h] = struct{}{}
+		newLoose++
+	}
+
+	// Nothing to consolidate: at most one unkept pack and no new loose objects.
+	if newLoose == 0 && len(replaced) <= 1 {
+		return &GCSummary{KeptPacks: keptCount}, nil
+	}
+
+	toPack := make([]Hash, 0, len(candidates))
+	for h := range candidates {
 		if _, ok := packed[h]; ok {
 			continue
 		}
 		toPack = append(toPack, h)
 	}
+	sort.Slice(toPack, func(i, j int) bool { return toPack[i] < toPack[j] })
 	if len(toPack) == 0 {
-		return &GCSummary{}, nil
+		return &GCSummary{KeptPacks: keptCount}, nil
 	}
 	if len(toPack) > int(^uint32(0)) {
 		return nil, fmt.Errorf("gc: too many objects to pack: %d", len(toPack))
 	}
 
-	packDir := filepath.Join(s.root, "objects", "pack")
-	if err := os.MkdirAll(packDir, 0o755); err != nil {
-		return nil, fmt.Errorf("gc: mkdir pack dir: %w", err)
-	}
-
 	packTmp, err := os.CreateTemp(packDir, ".tmp-pack-*.pack")
 	if err != nil {
 		return nil, fmt.Errorf("gc: create pack temp file: %w", err)
@@ -100,71 +247,38 @@ func (s *Store) gcWithReachableSet(reachable map[Hash]struct{}) (*GCSummary, err
 		}
 	}()
 
-	pw, err := NewPackWriter(packTmp, uint32(len(toPack)))
+	packOut, finishPackOut, err := s.newObjectFileWriter(packTmp, cryptKindPack)
 	if err != nil {
 		_ = packTmp.Close()
 		return nil, fmt.Errorf("gc: create pack writer: %w", err)
 	}
-
-	indexEntries := make([]PackIndexEntry, 0, len(toPack))
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	workersCount := packWorkerCount(len(toPack))
-	jobs := orderedPackJobs(ctx, toPack)
-	preparedResults := make(chan indexedPackResult, workersCount)
-	var workers sync.WaitGroup
-	for worker := 0; worker < workersCount; worker++ {
-		workers.Add(1)
-		go func() {
-			defer workers.Done()
-			for job := range jobs {
-				prepared := s.preparePackEntry(job.index, job.hash)
-				select {
-				case preparedResults <- prepared:
-				case <-ctx.Done():
-					return
-				}
-			}
-		}()
+	pw, err := NewPackWriter(packOut, uint32(len(toPack)))
+	if err != nil {
+		_ = packTmp.Close()
+		return nil, fmt.Errorf("gc: create pack writer: %w", err)
 	}
 
-	done := make(chan struct{})
-	go func() {
-		workers.Wait()
-		close(preparedResults)
-		close(done)
-	}()
-
-	pending := make(map[int]preparedPackEntry, workersCount)
-	for i := range toPack {
-		prepared, err := awaitPreparedPackEntry(ctx, preparedResults, pending, i)
-		if err != nil {
-			cancel()
-			<-done
-			_ = packTmp.Close()
-			return nil, fmt.Errorf("gc: %w", err)
-		}
-
-		offset := pw.CurrentOffset()
-		if err := pw.writeCompressedEntry(prepared.packType, prepared.rawSize, prepared.compressed); err != nil {
-			cancel()
-			<-done
-			_ = packTmp.Close()
-			return nil, fmt.Errorf("gc: write pack entry %s: %w", prepared.hash, err)
-		}
-		indexEntries = append(indexEntries, PackIndexEntry{
-			Hash:   prepared.hash,
-			Offset: offset,
-		})
+	var indexEntries []PackIndexEntry
+	deltaObjects := 0
+	if opts.DeltaWindow > 0 {
+		indexEntries, deltaObjects, err = s.writeDeltaPackEntries(pw, toPack, opts)
+	} else {
+		indexEntries, err = s.writePackEntries(pw, toPack)
+	}
+	if err != nil {
+		_ = packTmp.Close()
+		return nil, fmt.Errorf("gc: %w", err)
 	}
-	<-done
 
 	packChecksum, err := pw.Finish()
 	if err != nil {
 		_ = packTmp.Close()
 		return nil, fmt.Errorf("gc: finalize pack: %w", err)
 	}
+	if err := finishPackOut(); err != nil {
+		_ = packTmp.Close()
+		return nil, fmt.Errorf("gc: finalize pack: %w", err)
+	}
 	if err := packTmp.Close(); err != nil {
 		return nil, fmt.Errorf("gc: close pack temp file: %w", err)
 	}
@@ -190,7 +304,18 @@ func (s *Store) gcWithReachableSet(reachable map[Hash]struct{}) (*GCSummary, err
 		}
 	}()
 
-	if _, err := WritePackIndex(idxTmp, indexEntries, packChecksum); err != nil {
+	idxOut, finishIdxOut, err := s.newObjectFileWriter(idxTmp, cryptKindIndex)
+	if err != nil {
+		_ = idxTmp.Close()
+		_ = os.Remove(packPath)
+		return nil, fmt.Errorf("gc: write pack index: %w", err)
+	}
+	if _, err := WritePackIndex(idxOut, indexEntries, packChecksum); err != nil {
+		_ = idxTmp.Close()
+		_ = os.Remove(packPath)
+		return nil, fmt.Errorf("gc: write pack index: %w", err)
+	}
+	if err := finishIdxOut(); err != nil {
 		_ = idxTmp.Close()
 		_ = os.Remove(packPath)
 		return nil, fmt.Errorf("gc: write pack index: %w", err)
@@ -209,6 +334,25 @@ func (s *Store) gcWithReachableSet(reachable map[Hash]struct{}) (*GCSummary, err
 	// the newly written index.
 	s.InvalidatePackIndexCache()
 
+	var removedPacks []string
+	for _, oldIdxPath := range replaced {
+		if oldIdxPath == idxPath {
+			continue
+		}
+		oldPackPath := packPathForIndex(oldIdxPath)
+		if err := os.Remove(oldIdxPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("gc: could not remove replaced pack index", "index", filepath.Base(oldIdxPath), "error", err)
+			continue
+		}
+		if err := os.Remove(oldPackPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("gc: could not remove replaced pack", "pack", filepath.Base(oldPackPath), "error", err)
+		}
+		removedPacks = append(removedPacks, filepath.Base(oldPackPath))
+	}
+	if len(removedPacks) > 0 {
+		s.InvalidatePackIndexCache()
+	}
+
 	pruned := 0
 	removeFailed := 0
 	for _, h := range toPack {
@@ -231,24 +375,66 @@ func (s *Store) gcWithReachableSet(reachable map[Hash]struct{}) (*GCSummary, err
 		PrunedObjects: pruned,
 		PackFile:      filepath.Base(packPath),
 		IndexFile:     filepath.Base(idxPath),
+		RemovedPacks:  removedPacks,
+		KeptPacks:     keptCount,
+		DeltaObjects:  deltaObjects,
 	}, nil
 }
 
 // Verify checks object integrity across loose objects and pack/index entries.
+// Every object is checked; if any are corrupt the returned summary lists them
+// and the error describes the first one.
 func (s *Store) Verify() (*VerifySummary, error) {
+	report, err := s.VerifyWithOptions(VerifyOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return report, report.Err()
+}
+
+// Err summarizes the corruption found by verification as a single error
+// describing the first failure, or nil when everything verified cleanly.
+func (v *VerifySummary) Err() error {
+	if len(v.Corrupt) == 0 {
+		return nil
+	}
+	first := v.Corrupt[0]
+	if len(v.Corrupt) == 1 {
+		return first.Err
+	}
+	return fmt.Errorf("%w (and %d more corrupt object(s))", first.Err, len(v.Corrupt)-1)
+}
+
+// VerifyWithOptions checks object integrity across loose objects and
+// pack/index entries using a pool of workers. Corrupt objects and packs are
+// collected in the summary instead of aborting the audit; the returned error
+// is reserved for failures to enumerate the store itself.
+func (s *Store) VerifyWithOptions(opts VerifyOptions) (*VerifySummary, error) {
 	report := &VerifySummary{}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
 
 	looseHashes, err := s.listLooseObjectHashes()
 	if err != nil {
 		return nil, err
 	}
-	for _, h := range looseHashes {
+	looseErrs := runVerifyJobs(workers, len(looseHashes), func(i int) error {
+		h := looseHashes[i]
 		objType, content, err := s.readLoose(h)
 		if err != nil {
-			return nil, fmt.Errorf("verify loose %s: %w", h, err)
+			return fmt.Errorf("verify loose %s: %w", h, err)
 		}
 		if actual := HashObject(objType, content); actual != h {
-			return nil, fmt.Errorf("verify loose %s: hash mismatch (computed %s)", h, actual)
+			return fmt.Errorf("verify loose %s: hash mismatch (computed %s)", h, actual)
+		}
+		return nil
+	}, verifyProgressFunc(opts.Progress, "loose", "", len(looseHashes)))
+	for i, err := range looseErrs {
+		if err != nil {
+			report.Corrupt = append(report.Corrupt, CorruptObject{Hash: looseHashes[i], Location: "loose", Err: err})
+			continue
 		}
 		report.LooseObjects++
 	}
@@ -258,93 +444,167 @@ func (s *Store) Verify() (*VerifySummary, error) {
 		return nil, err
 	}
 	for _, idxPath := range idxPaths {
-		idxData, err := os.ReadFile(idxPath)
-		if err != nil {
-			return nil, fmt.Errorf("verify pack index %s: %w", filepath.Base(idxPath), err)
+		s.verifyPack(idxPath, workers, opts.Progress, report)
+	}
+
+	return report, nil
+}
+
+// verifyPack audits a single pack and its index, recording any corruption in
+// report. Pack-level failures are recorded without an object hash.
+func (s *Store) verifyPack(idxPath string, workers int, progress func(VerifyProgress), report *VerifySummary) {
+	packPath := packPathForIndex(idxPath)
+	packName := filepath.Base(packPath)
+	packFailure := func(err error) {
+		report.Corrupt = append(report.Corrupt, CorruptObject{Location: packName, Err: err})
+	}
+
+	idxData, err := s.readObjectFile(idxPath, cryptKindIndex)
+	if err != nil {
+		packFailure(fmt.Errorf("verify pack index %s: %w", filepath.Base(idxPath), err))
+		return
+	}
+	idx, err := ReadPackIndex(idxData)
+	if err != nil {
+		packFailure(fmt.Errorf("verify pack index %s: %w", filepath.Base(idxPath), err))
+		return
+	}
+
+	packData, err := s.readPackFile(packPath)
+	if err != nil {
+		packFailure(fmt.Errorf("verify pack %s: %w", packName, err))
+		return
+	}
+	pf, err := ReadPackResolved(packData)
+	if err != nil {
+		packFailure(fmt.Errorf("verify pack %s: %w", packName, err))
+		return
+	}
+	if pf.Checksum != idx.PackChecksum {
+		packFailure(fmt.Errorf(
+			"verify pack %s: checksum mismatch between idx (%s) and pack (%s)",
+			packName,
+			idx.PackChecksum,
+			pf.Checksum,
+		))
+		return
+	}
+
+	offsets := make(map[uint64]PackEntry, len(pf.Entries))
+	for _, entry := range pf.Entries {
+		if _, exists := offsets[entry.Offset]; exists {
+			packFailure(fmt.Errorf("verify pack %s: duplicate offset %d", packName, entry.Offset))
+			return
 		}
-		idx, err := ReadPackIndex(idxData)
-		if err != nil {
-			return nil, fmt.Errorf("verify pack index %s: %w", filepath.Base(idxPath), err)
+		offsets[entry.Offset] = entry
+	}
+
+	entries := idx.Entries()
+	if len(entries) != len(offsets) {
+		packFailure(fmt.Errorf(
+			"verify pack %s: idx entry count %d does not match pack entry count %d",
+			packName,
+			len(entries),
+			len(offsets),
+		))
+		return
+	}
+
+	seenIndexOffsets := make(map[uint64]struct{}, len(entries))
+	indexHashes := make(map[Hash]struct{}, len(entries))
+	for _, indexEntry := range entries {
+		if _, exists := seenIndexOffsets[indexEntry.Offset]; exists {
+			packFailure(fmt.Errorf("verify pack %s: duplicate idx offset %d", packName, indexEntry.Offset))
+			return
 		}
+		seenIndexOffsets[indexEntry.Offset] = struct{}{}
+		indexHashes[indexEntry.Hash] = struct{}{}
+	}
 
-		packPath := packPathForIndex(idxPath)
-		packData, err := os.ReadFile(packPath)
-		if err != nil {
-			return nil, fmt.Errorf("verify pack %s: %w", filepath.Base(packPath), err)
+	entryErrs := runVerifyJobs(workers, len(entries), func(i int) error {
+		indexEntry := entries[i]
+		packEntry, ok := offsets[indexEntry.Offset]
+		if !ok {
+			return fmt.Errorf(
+				"verify pack %s: missing pack entry for hash %s at offset %d",
+				packName,
+				indexEntry.Hash,
+				indexEntry.Offset,
+			)
+		}
+		if _, _, err := decodeIndexedPackEntry(indexEntry.Hash, packEntry); err != nil {
+			return fmt.Errorf("verify pack %s hash %s: %w", packName, indexEntry.Hash, err)
 		}
-		pf, err := ReadPackResolved(packData)
+		return nil
+	}, verifyProgressFunc(progress, "pack", packName, len(entries)))
+	for i, err := range entryErrs {
 		if err != nil {
-			return nil, fmt.Errorf("verify pack %s: %w", filepath.Base(packPath), err)
-		}
-		if pf.Checksum != idx.PackChecksum {
-			return nil, fmt.Errorf(
-				"verify pack %s: checksum mismatch between idx (%s) and pack (%s)",
-				filepath.Base(packPath),
-				idx.PackChecksum,
-				pf.Checksum,
-			)
+			report.Corrupt = append(report.Corrupt, CorruptObject{Hash: entries[i].Hash, Location: packName, Err: err})
+			continue
 		}
+		report.PackObjects++
+	}
 
-		offsets := make(map[uint64]PackEntry, len(pf.Entries))
-		for _, entry := range pf.Entries {
-			if _, exists := offsets[entry.Offset]; exists {
-				return nil, fmt.Errorf("verify pack %s: duplicate offset %d", filepath.Base(packPath), entry.Offset)
+	if pf.EntityTrailer != nil {
+		for _, trailerEntry := range pf.EntityTrailer.Entries {
+			if _, ok := indexHashes[trailerEntry.ObjectHash]; !ok {
+				packFailure(fmt.Errorf(
+					"verify pack %s: entity trailer references missing object hash %s",
+					packName,
+					trailerEntry.ObjectHash,
+				))
 			}
-			offsets[entry.Offset] = entry
-		}
-
-		entries := idx.Entries()
-		if len(entries) != len(offsets) {
-			return nil, fmt.Errorf(
-				"verify pack %s: idx entry count %d does not match pack entry count %d",
-				filepath.Base(packPath),
-				len(entries),
-				len(offsets),
-			)
 		}
+	}
+	report.PackFiles++
+}
 
-		seenIndexOffsets := make(map[uint64]struct{}, len(entries))
-		indexHashes := make(map[Hash]struct{}, len(entries))
-		for _, indexEntry := range entries {
-			if _, exists := seenIndexOffsets[indexEntry.Offset]; exists {
-				return nil, fmt.Errorf(
-					"verify pack %s: duplicate idx offset %d",
-					filepath.Base(packPath),
-					indexEntry.Offset,
-				)
-			}
-			seenIndexOffsets[indexEntry.Offset] = struct{}{}
-			indexHashes[indexEntry.Hash] = struct{}{}
+// runVerifyJobs calls check for every index in [0, total) across workers
+// goroutines and returns the per-index errors. tick is called once per
+// completed job.
+func runVerifyJobs(workers, total int, check func(i int) error, tick func()) []error {
+	errs := make([]error, total)
+	if total == 0 {
+		return errs
+	}
+	if workers > total {
+		workers = total
+	}
 
-			packEntry, ok := offsets[indexEntry.Offset]
-			if !ok {
-				return nil, fmt.Errorf(
-					"verify pack %s: missing pack entry for hash %s at offset %d",
-					filepath.Base(packPath),
-					indexEntry.Hash,
-					indexEntry.Offset,
-				)
-			}
-			if _, _, err := decodeIndexedPackEntry(indexEntry.Hash, packEntry); err != nil {
-				return nil, fmt.Errorf("verify pack %s hash %s: %w", filepath.Base(packPath), indexEntry.Hash, err)
-			}
-			report.PackObjects++
-		}
-		if pf.EntityTrailer != nil {
-			for _, trailerEntry := range pf.EntityTrailer.Entries {
-				if _, ok := indexHashes[trailerEntry.ObjectHash]; !ok {
-					return nil, fmt.Errorf(
-						"verify pack %s: entity trailer references missing object hash %s",
-						filepath.Base(packPath),
-						trailerEntry.ObjectHash,
-					)
-				}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = check(i)
+				tick()
 			}
-		}
-		report.PackFiles++
+		}()
 	}
+	for i := 0; i < total; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
 
-	return report, nil
+// verifyProgressFunc adapts a caller progress callback into a per-job tick.
+// Calls into fn are serialized so callers need no locking of their own.
+func verifyProgressFunc(fn func(VerifyProgress), phase, pack string, total int) func() {
+	if fn == nil {
+		return func() {}
+	}
+	var mu sync.Mutex
+	done := 0
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		fn(VerifyProgress{Phase: phase, Pack: pack, Done: done, Total: total})
+	}
 }
 
 // cachedPackIndex returns the parsed PackIndex for the given idx file path,
@@ -365,7 +625,7 @@ func (s *Store) cachedPackIndex(idxPath string) (*PackIndex, error) {
 	}
 	s.packIdxMu.Unlock()
 
-	idxData, err := os.ReadFile(idxPath)
+	idxData, err := s.readObjectFile(idxPath, cryptKindIndex)
 	if err != nil {
 		return nil, err
 	}
@@ -411,8 +671,8 @@ func (s *Store) InvalidatePackIndexCache() {
 //
 // The function uses an io.SectionReader over the file to avoid reading the
 // entire pack tail into memory, which could be very large for big pack files.
-func readPackEntryAt(packPath string, offset uint64) (PackEntry, error) {
-	f, err := os.Open(packPath)
+func (s *Store) readPackEntryAt(packPath string, offset uint64) (PackEntry, error) {
+	f, err := s.openPackFile(packPath)
 	if err != nil {
 		return PackEntry{}, fmt.Errorf("open pack %s: %w", filepath.Base(packPath), err)
 	}
@@ -420,21 +680,17 @@ func readPackEntryAt(packPath string, offset uint64) (PackEntry, error) {
 
 	// Validate pack header.
 	headerBuf := make([]byte, packHeaderSize)
-	if _, err := io.ReadFull(f, headerBuf); err != nil {
+	if _, err := f.ReadAt(headerBuf, 0); err != nil {
 		return PackEntry{}, fmt.Errorf("read pack header %s: %w", filepath.Base(packPath), err)
 	}
 	if _, err := UnmarshalPackHeader(headerBuf); err != nil {
 		return PackEntry{}, err
 	}
 
-	stat, err := f.Stat()
-	if err != nil {
-		return PackEntry{}, fmt.Errorf("stat pack %s: %w", filepath.Base(packPath), err)
-	}
 	// The entry data extends from offset to (at most) before the 32-byte
 	// trailing checksum. Entity trailers may exist after the checksum, but
 	// entries live before it.
-	maxEntryEnd := stat.Size() - sha256.Size
+	maxEntryEnd := f.Size() - sha256.Size
 	if int64(offset) >= maxEntryEnd {
 		return PackEntry{}, fmt.Errorf("offset %d past pack data boundary in %s", offset, filepath.Base(packPath))
 	}
@@ -520,16 +776,16 @@ const maxDeltaChainDepth = 50
 
 // readResolvedPackEntryAt reads a single pack entry at the given offset,
 // resolving delta chains by recursively reading base entries.
-func readResolvedPackEntryAt(packPath string, offset uint64) (PackEntry, error) {
-	return readResolvedPackEntryAtDepth(packPath, offset, 0)
+func (s *Store) readResolvedPackEntryAt(packPath string, offset uint64) (PackEntry, error) {
+	return s.readResolvedPackEntryAtDepth(packPath, offset, 0)
 }
 
-func readResolvedPackEntryAtDepth(packPath string, offset uint64, depth int) (PackEntry, error) {
+func (s *Store) readResolvedPackEntryAtDepth(packPath string, offset uint64, depth int) (PackEntry, error) {
 	if depth > maxDeltaChainDepth {
 		return PackEntry{}, fmt.Errorf("delta chain depth exceeds limit (%d) at offset %d", maxDeltaChainDepth, offset)
 	}
 
-	entry, err := readPackEntryAt(packPath, offset)
+	entry, err := s.readPackEntryAt(packPath, offset)
 	if err != nil {
 		return PackEntry{}, err
 	}
@@ -543,7 +799,7 @@ func readResolvedPackEntryAtDepth(packPath string, offset uint64, depth int) (Pa
 			return PackEntry{}, fmt.Errorf("invalid ofs-delta base distance %d at offset %d", entry.BaseDistance, entry.Offset)
 		}
 		baseOffset := entry.Offset - entry.BaseDistance
-		base, err := readResolvedPackEntryAtDepth(packPath, baseOffset, depth+1)
+		base, err := s.readResolvedPackEntryAtDepth(packPath, baseOffset, depth+1)
 		if err != nil {
 			return PackEntry{}, fmt.Errorf("resolve ofs-delta base at offset %d: %w", baseOffset, err)
 		}
@@ -579,7 +835,7 @@ func (s *Store) readFromPacks(h Hash) (ObjectType, []byte, error) {
 		}
 
 		packPath := packPathForIndex(idxPath)
-		packEntry, err := readResolvedPackEntryAt(packPath, indexEntry.Offset)
+		packEntry, err := s.readResolvedPackEntryAt(packPath, indexEntry.Offset)
 		if err != nil {
 			return "", nil, fmt.Errorf("object read %s: pack %s: %w", h, filepath.Base(packPath), err)
 		}
@@ -670,6 +926,148 @@ func (s *Store) packedHashSet() (map[Hash]struct{}, error) {
 	return out, nil
 }
 
+// writePackEntries writes hashes to pw whole, in order, compressing them
+// in parallel, and returns their index entries.
+func (s *Store) writePackEntries(pw *PackWriter, hashes []Hash) ([]PackIndexEntry, error) {
+	indexEntries := make([]PackIndexEntry, 0, len(hashes))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	workersCount := packWorkerCount(len(hashes))
+	jobs := orderedPackJobs(ctx, hashes)
+	preparedResults := make(chan indexedPackResult, workersCount)
+	var workers sync.WaitGroup
+	for worker := 0; worker < workersCount; worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				prepared := s.preparePackEntry(job.index, job.hash)
+				select {
+				case preparedResults <- prepared:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(preparedResults)
+		close(done)
+	}()
+
+	pending := make(map[int]preparedPackEntry, workersCount)
+	for i := range hashes {
+		prepared, err := awaitPreparedPackEntry(ctx, preparedResults, pending, i)
+		if err != nil {
+			cancel()
+			<-done
+			return nil, err
+		}
+
+		offset := pw.CurrentOffset()
+		if err := pw.writeCompressedEntry(prepared.packType, prepared.rawSize, prepared.compressed); err != nil {
+			cancel()
+			<-done
+			return nil, fmt.Errorf("write pack entry %s: %w", prepared.hash, err)
+		}
+		indexEntries = append(indexEntries, PackIndexEntry{
+			Hash:   prepared.hash,
+			Offset: offset,
+		})
+		if s.progress != nil {
+			s.progress(Progress{Phase: ProgressPacking, Objects: i + 1, Total: len(hashes), Bytes: int64(pw.CurrentOffset())})
+		}
+	}
+	<-done
+	return indexEntries, nil
+}
+
+// deltaPackObject is an object being written by writeDeltaPackEntries.
+type deltaPackObject struct {
+	hash     Hash
+	objType  ObjectType
+	envelope []byte
+	offset   uint64
+	depth    int
+}
+
+// writeDeltaPackEntries writes hashes to pw, storing each object as an
+// ofs-delta against one of the opts.DeltaWindow objects before it when the
+// delta is under half the object's size. It returns the index entries and
+// the number of deltas written.
+//
+// As in git, objects are ordered by type and then by size, largest first,
+// so versions of one file tend to sit next to each other and most deltas
+// only remove data from their base.
+func (s *Store) writeDeltaPackEntries(pw *PackWriter, hashes []Hash, opts RepackOptions) ([]PackIndexEntry, int, error) {
+	objs := make([]*deltaPackObject, 0, len(hashes))
+	for _, h := range hashes {
+		objType, content, err := s.readLoose(h)
+		if errors.Is(err, os.ErrNotExist) {
+			objType, content, err = s.readFromPacks(h)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("read object %s: %w", h, err)
+		}
+		objs = append(objs, &deltaPackObject{hash: h, objType: objType, envelope: makeObjectEnvelope(objType, content)})
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		if objs[i].objType != objs[j].objType {
+			return objs[i].objType < objs[j].objType
+		}
+		if len(objs[i].envelope) != len(objs[j].envelope) {
+			return len(objs[i].envelope) > len(objs[j].envelope)
+		}
+		return objs[i].hash < objs[j].hash
+	})
+
+	maxDepth := opts.DeltaDepth
+	if maxDepth <= 0 || maxDepth > maxDeltaChainDepth {
+		maxDepth = maxDeltaChainDepth
+	}
+	indexEntries := make([]PackIndexEntry, 0, len(objs))
+	deltas := 0
+	for i, obj := range objs {
+		var base *deltaPackObject
+		var best []byte
+		for j := i - 1; j >= 0 && i-j <= opts.DeltaWindow; j-- {
+			cand := objs[j]
+			if cand.objType != obj.objType {
+				break
+			}
+			if cand.depth >= maxDepth {
+				continue
+			}
+			delta := buildDelta(cand.envelope, obj.envelope)
+			if len(delta) < len(obj.envelope)/2 && (best == nil || len(delta) < len(best)) {
+				base, best = cand, delta
+			}
+		}
+
+		obj.offset = pw.CurrentOffset()
+		var err error
+		if base != nil {
+			err = pw.writeOfsDelta(base.offset, best)
+			obj.depth = base.depth + 1
+			deltas++
+		} else {
+			err = pw.WriteEntry(objectTypeToPackType(obj.objType), obj.envelope)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("write pack entry %s: %w", obj.hash, err)
+		}
+		indexEntries = append(indexEntries, PackIndexEntry{Hash: obj.hash, Offset: obj.offset})
+		if s.progress != nil {
+			s.progress(Progress{Phase: ProgressPacking, Objects: i + 1, Total: len(objs), Bytes: int64(pw.CurrentOffset())})
+		}
+	}
+	return indexEntries, deltas, nil
+}
+
 type orderedPackJob struct {
 	index int
 	hash  Hash
@@ -717,6 +1115,10 @@ func packWorkerCount(total int) int {
 
 func (s *Store) preparePackEntry(index int, h Hash) preparedPackEntry {
 	objType, content, err := s.readLoose(h)
+	if errors.Is(err, os.ErrNotExist) {
+		// Repack: the object only lives in a pack that is being replaced.
+		objType, content, err = s.readFromPacks(h)
+	}
 	if err != nil {
 		return preparedPackEntry{
 			index: index,
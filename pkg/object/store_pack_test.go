@@ -2,11 +2,16 @@ package object
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/lockfile"
 )
 
 func TestStoreGCIdempotentAndReadFallback(t *testing.T) {
@@ -81,6 +86,32 @@ func TestStoreGCIdempotentAndReadFallback(t *testing.T) {
 	}
 }
 
+func TestStoreGCReportsPackingProgress(t *testing.T) {
+	s := tempStore(t)
+	for _, payload := range []string{"one", "two", "three"} {
+		if _, err := s.Write(TypeBlob, []byte(payload)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	var updates []Progress
+	s.SetProgress(func(p Progress) { updates = append(updates, p) })
+	if _, err := s.GC(); err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	if len(updates) != 3 {
+		t.Fatalf("got %d progress updates, want 3: %+v", len(updates), updates)
+	}
+	for i, p := range updates {
+		if p.Phase != ProgressPacking || p.Objects != i+1 || p.Total != 3 {
+			t.Fatalf("update %d = %+v", i, p)
+		}
+		if i > 0 && p.Bytes <= updates[i-1].Bytes {
+			t.Fatalf("pack bytes did not grow: %+v", updates)
+		}
+	}
+}
+
 func TestStoreGCReachablePacksOnlyReachableObjectsAndIsIdempotent(t *testing.T) {
 	s := tempStore(t)
 
@@ -254,6 +285,44 @@ func TestStoreGCDeterministicPackAndIndexOutput(t *testing.T) {
 	}
 }
 
+func TestStoreGCWaitsForPackLock(t *testing.T) {
+	s := tempStore(t)
+	if _, err := s.Write(TypeBlob, []byte("locked payload")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	prevWait := packLockWaitLimit
+	packLockWaitLimit = 20 * time.Millisecond
+	defer func() { packLockWaitLimit = prevWait }()
+
+	packDir := filepath.Join(s.root, "objects", "pack")
+	if err := os.MkdirAll(packDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lockPath := filepath.Join(packDir, "pack"+lockfile.Suffix)
+	if err := os.WriteFile(lockPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GC(); !errors.Is(err, lockfile.ErrLocked) {
+		t.Fatalf("GC error = %v, want ErrLocked while pack lock is held", err)
+	}
+
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := s.GC()
+	if err != nil {
+		t.Fatalf("GC after lock release: %v", err)
+	}
+	if summary.PackedObjects != 1 {
+		t.Fatalf("PackedObjects = %d, want 1", summary.PackedObjects)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("expected pack lock to be released, stat err=%v", err)
+	}
+}
+
 func TestStoreHasChecksPackedObjects(t *testing.T) {
 	s := tempStore(t)
 
@@ -305,6 +374,67 @@ func TestStoreVerifyDetectsCorruptLooseObject(t *testing.T) {
 	}
 }
 
+func TestStoreVerifyWithOptionsReportsAllCorruptObjectsAndProgress(t *testing.T) {
+	s := tempStore(t)
+
+	var corrupt []Hash
+	for i := 0; i < 6; i++ {
+		h, err := s.Write(TypeBlob, []byte(fmt.Sprintf("blob %d", i)))
+		if err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if i%2 == 0 {
+			if err := os.WriteFile(s.objectPath(h), []byte("broken"), 0o644); err != nil {
+				t.Fatalf("WriteFile(corrupt loose): %v", err)
+			}
+			corrupt = append(corrupt, h)
+		}
+	}
+
+	var progressCalls int
+	var lastProgress VerifyProgress
+	report, err := s.VerifyWithOptions(VerifyOptions{
+		Workers: 3,
+		Progress: func(p VerifyProgress) {
+			progressCalls++
+			lastProgress = p
+		},
+	})
+	if err != nil {
+		t.Fatalf("VerifyWithOptions: %v", err)
+	}
+	if report.LooseObjects != 3 {
+		t.Fatalf("LooseObjects = %d, want 3", report.LooseObjects)
+	}
+	if len(report.Corrupt) != len(corrupt) {
+		t.Fatalf("len(Corrupt) = %d, want %d", len(report.Corrupt), len(corrupt))
+	}
+	for i, c := range report.Corrupt {
+		if !containsHash(corrupt, c.Hash) {
+			t.Fatalf("unexpected corrupt hash %s", c.Hash)
+		}
+		if c.Location != "loose" || c.Err == nil {
+			t.Fatalf("Corrupt[%d] = %+v, want loose entry with error", i, c)
+		}
+	}
+	if progressCalls != 6 || lastProgress.Done != 6 || lastProgress.Total != 6 || lastProgress.Phase != "loose" {
+		t.Fatalf("progress calls = %d, last = %+v; want 6 loose ticks", progressCalls, lastProgress)
+	}
+
+	if _, err := s.Verify(); err == nil || !strings.Contains(err.Error(), "and 2 more") {
+		t.Fatalf("Verify error = %v, want summary of remaining corrupt objects", err)
+	}
+}
+
+func containsHash(hashes []Hash, h Hash) bool {
+	for _, candidate := range hashes {
+		if candidate == h {
+			return true
+		}
+	}
+	return false
+}
+
 func TestStoreVerifyDetectsCorruptPackObject(t *testing.T) {
 	s := tempStore(t)
 
@@ -388,3 +518,57 @@ func TestStoreVerifyDetectsEntityTrailerMissingObjectHash(t *testing.T) {
 		)
 	}
 }
+
+func TestStoreRepackWithDeltaWindowStoresDeltas(t *testing.T) {
+	s := tempStore(t)
+
+	base := strings.Repeat("line of shared content\n", 200)
+	var hashes []Hash
+	for i := 0; i < 5; i++ {
+		h, err := s.Write(TypeBlob, []byte(base+fmt.Sprintf("version %d\n", i)))
+		if err != nil {
+			t.Fatalf("Write(blob %d): %v", i, err)
+		}
+		hashes = append(hashes, h)
+	}
+	other, err := s.Write(TypeTree, []byte("unrelated tree payload"))
+	if err != nil {
+		t.Fatalf("Write(tree): %v", err)
+	}
+
+	summary, err := s.RepackWithOptions(nil, RepackOptions{DeltaWindow: 10})
+	if err != nil {
+		t.Fatalf("RepackWithOptions: %v", err)
+	}
+	if summary.PackedObjects != 6 || summary.DeltaObjects != 4 {
+		t.Fatalf("summary = %+v, want 6 packed with 4 deltas", summary)
+	}
+
+	for i, h := range hashes {
+		objType, data, err := s.Read(h)
+		if err != nil {
+			t.Fatalf("Read(blob %d): %v", i, err)
+		}
+		if objType != TypeBlob || string(data) != base+fmt.Sprintf("version %d\n", i) {
+			t.Fatalf("blob %d read back as %s %q", i, objType, data)
+		}
+	}
+	if objType, _, err := s.Read(other); err != nil || objType != TypeTree {
+		t.Fatalf("Read(tree) = %s, %v", objType, err)
+	}
+	if report, err := s.Verify(); err != nil {
+		t.Fatalf("Verify: %v (%+v)", err, report)
+	}
+
+	// Without a window the same objects are stored whole.
+	if _, err := s.Write(TypeBlob, []byte(base+"version 5\n")); err != nil {
+		t.Fatalf("Write(blob 5): %v", err)
+	}
+	plain, err := s.Repack(nil)
+	if err != nil {
+		t.Fatalf("Repack: %v", err)
+	}
+	if plain.PackedObjects != 7 || plain.DeltaObjects != 0 {
+		t.Fatalf("plain repack summary = %+v", plain)
+	}
+}
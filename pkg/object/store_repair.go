@@ -0,0 +1,120 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/lockfile"
+)
+
+// SalvageSummary reports the outcome of Store.SalvagePack.
+type SalvageSummary struct {
+	Pack      string
+	Recovered int    // intact entries rewritten as loose objects
+	Lost      []Hash // indexed entries that could not be decoded
+	Intact    bool   // every entry decoded, so the pack was left in place
+}
+
+// ReplaceLoose writes an object as a loose file even when a (possibly
+// corrupt) copy already exists loose or in a pack. The content is hashed and
+// must match h.
+func (s *Store) ReplaceLoose(h Hash, objType ObjectType, data []byte) error {
+	if computed := HashObject(objType, data); computed != h {
+		return fmt.Errorf("replace object %s: hash mismatch (computed %s)", h, computed)
+	}
+	compressed, err := compressObject(makeObjectEnvelope(objType, data))
+	if err != nil {
+		return fmt.Errorf("replace object %s: compress: %w", h, err)
+	}
+	_, err = s.writeLoose(h, compressed)
+	return err
+}
+
+// RemoveLoose deletes a loose object file. A missing file is not an error.
+func (s *Store) RemoveLoose(h Hash) error {
+	if err := os.Remove(s.objectPath(h)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove loose object %s: %w", h, err)
+	}
+	return nil
+}
+
+// SalvagePack rescues every decodable entry of a damaged pack as a loose
+// object and then deletes the pack and its index, so the surviving objects
+// no longer depend on the corrupt file. Entries that fail to decode are
+// reported as lost. A pack whose entries all decode, damaged only at the
+// pack level, is left in place and reported Intact. If the index itself
+// cannot be read, or the pack is marked with a .keep file, the pack is
+// left in place and an error is returned. The pack lock is held
+// throughout, so gc cannot repack or prune concurrently.
+func (s *Store) SalvagePack(packName string) (*SalvageSummary, error) {
+	packDir := filepath.Join(s.root, "objects", "pack")
+	packPath := filepath.Join(packDir, filepath.Base(packName))
+	idxPath := strings.TrimSuffix(packPath, ".pack") + ".idx"
+	if s.IsPackKept(packName) {
+		return nil, fmt.Errorf("salvage pack %s: pack is marked %s", filepath.Base(packPath), KeepSuffix)
+	}
+
+	lock, err := lockfile.Acquire(filepath.Join(packDir, "pack"), packLockWaitLimit)
+	if err != nil {
+		return nil, fmt.Errorf("salvage pack %s: %w", filepath.Base(packPath), err)
+	}
+	defer lock.Release()
+
+	idxData, err := s.readObjectFile(idxPath, cryptKindIndex)
+	if err != nil {
+		return nil, fmt.Errorf("salvage pack %s: %w", filepath.Base(packPath), err)
+	}
+	idx, err := ReadPackIndex(idxData)
+	if err != nil {
+		return nil, fmt.Errorf("salvage pack %s: index: %w", filepath.Base(packPath), err)
+	}
+
+	// Find the undecodable entries first: a pack without any is kept.
+	summary := &SalvageSummary{Pack: filepath.Base(packPath)}
+	entries := idx.Entries()
+	lost := make(map[Hash]bool)
+	for _, entry := range entries {
+		if _, _, err := s.salvagePackEntry(packPath, entry); err != nil {
+			lost[entry.Hash] = true
+			summary.Lost = append(summary.Lost, entry.Hash)
+		}
+	}
+	if len(lost) == 0 {
+		summary.Intact = true
+		return summary, nil
+	}
+
+	for _, entry := range entries {
+		if lost[entry.Hash] {
+			continue
+		}
+		objType, content, err := s.salvagePackEntry(packPath, entry)
+		if err != nil {
+			return nil, fmt.Errorf("salvage pack %s: %w", summary.Pack, err)
+		}
+		if err := s.ReplaceLoose(entry.Hash, objType, content); err != nil {
+			return nil, fmt.Errorf("salvage pack %s: %w", summary.Pack, err)
+		}
+		summary.Recovered++
+	}
+
+	if err := os.Remove(idxPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("salvage pack %s: remove index: %w", summary.Pack, err)
+	}
+	if err := os.Remove(packPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("salvage pack %s: remove pack: %w", summary.Pack, err)
+	}
+	s.InvalidatePackIndexCache()
+	return summary, nil
+}
+
+func (s *Store) salvagePackEntry(packPath string, entry PackIndexEntry) (ObjectType, []byte, error) {
+	packEntry, err := s.readResolvedPackEntryAt(packPath, entry.Offset)
+	if err != nil {
+		return "", nil, err
+	}
+	return decodeIndexedPackEntry(entry.Hash, packEntry)
+}
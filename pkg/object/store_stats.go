@@ -0,0 +1,272 @@
+package object
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LooseObjectInfo describes one loose object file on disk.
+type LooseObjectInfo struct {
+	Hash     Hash
+	DiskSize int64
+}
+
+// PackFileInfo describes one pack file and its companion index.
+type PackFileInfo struct {
+	Name      string
+	PackSize  int64
+	IndexSize int64
+	Objects   int
+	Kept      bool // a .keep marker protects the pack from repack and gc
+}
+
+// ObjectSizeInfo pairs an object hash with its type and content size.
+type ObjectSizeInfo struct {
+	Hash   Hash
+	Type   ObjectType
+	Size   int64
+	Packed bool
+}
+
+// ObjectTypeStats aggregates object counts and content sizes for one type.
+type ObjectTypeStats struct {
+	Count int
+	Size  int64
+}
+
+// ObjectStats reports the outcome of Store.CountObjects.
+type ObjectStats struct {
+	LooseObjects  int
+	LooseSize     int64
+	PackFiles     int
+	PackSize      int64
+	PackedObjects int
+	ByType        map[ObjectType]ObjectTypeStats
+	Largest       []ObjectSizeInfo
+}
+
+// LooseObjects lists every loose object along with its on-disk size, sorted
+// by hash.
+func (s *Store) LooseObjects() ([]LooseObjectInfo, error) {
+	hashes, err := s.listLooseObjectHashes()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]LooseObjectInfo, 0, len(hashes))
+	for _, h := range hashes {
+		info, err := os.Stat(s.objectPath(h))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("stat loose object %s: %w", h, err)
+		}
+		out = append(out, LooseObjectInfo{Hash: h, DiskSize: info.Size()})
+	}
+	return out, nil
+}
+
+// LooseObjectCount counts the loose objects without examining them.
+func (s *Store) LooseObjectCount() (int, error) {
+	hashes, err := s.listLooseObjectHashes()
+	if err != nil {
+		return 0, err
+	}
+	return len(hashes), nil
+}
+
+// PackFiles lists every indexed pack file with its on-disk sizes and object
+// count, sorted by name.
+func (s *Store) PackFiles() ([]PackFileInfo, error) {
+	idxPaths, err := s.listPackIndexPaths()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PackFileInfo, 0, len(idxPaths))
+	for _, idxPath := range idxPaths {
+		packPath := packPathForIndex(idxPath)
+		packInfo, err := os.Stat(packPath)
+		if err != nil {
+			return nil, fmt.Errorf("stat pack %s: %w", filepath.Base(packPath), err)
+		}
+		idxInfo, err := os.Stat(idxPath)
+		if err != nil {
+			return nil, fmt.Errorf("stat pack index %s: %w", filepath.Base(idxPath), err)
+		}
+		idx, err := s.cachedPackIndex(idxPath)
+		if err != nil {
+			return nil, fmt.Errorf("pack index %s: %w", filepath.Base(idxPath), err)
+		}
+		out = append(out, PackFileInfo{
+			Name:      filepath.Base(packPath),
+			PackSize:  packInfo.Size(),
+			IndexSize: idxInfo.Size(),
+			Objects:   len(idx.Entries()),
+			Kept:      s.IsPackKept(filepath.Base(packPath)),
+		})
+	}
+	return out, nil
+}
+
+// PackedObjectHashes returns the hashes of every object indexed by a pack,
+// sorted and de-duplicated across packs.
+func (s *Store) PackedObjectHashes() ([]Hash, error) {
+	set, err := s.packedHashSet()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Hash, 0, len(set))
+	for h := range set {
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out, nil
+}
+
+// Stat returns the type and content size of an object. Loose objects only
+// have their envelope header decompressed; packed objects are resolved in
+// full because their type may live in an embedded envelope.
+func (s *Store) Stat(h Hash) (ObjectType, int64, error) {
+	objType, size, err := s.statLoose(h)
+	if err == nil {
+		return objType, size, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", 0, err
+	}
+	objType, content, err := s.readFromPacks(h)
+	if err != nil {
+		return "", 0, err
+	}
+	return objType, int64(len(content)), nil
+}
+
+func (s *Store) statLoose(h Hash) (ObjectType, int64, error) {
+	objType, size, err := s.statLooseHeader(h)
+	if err != nil || objType != chunkedBlobType {
+		return objType, size, err
+	}
+	manifest, err := s.readLooseEnvelope(h)
+	if err != nil {
+		return "", 0, err
+	}
+	size, err = chunkedBlobSize(h, manifest)
+	if err != nil {
+		return "", 0, err
+	}
+	return TypeBlob, size, nil
+}
+
+// statLooseHeader parses the envelope header of a loose object as stored,
+// without mapping chunked blobs to TypeBlob.
+func (s *Store) statLooseHeader(h Hash) (ObjectType, int64, error) {
+	f, err := os.Open(s.objectPath(h))
+	if err != nil {
+		return "", 0, fmt.Errorf("object stat %s: %w", h, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	head := make([]byte, len(encryptedMagic))
+	if n, _ := f.ReadAt(head, 0); isEncryptedData(head[:n]) {
+		onDisk, err := s.readObjectFile(s.objectPath(h), cryptKindLoose)
+		if err != nil {
+			return "", 0, fmt.Errorf("object stat %s: %w", h, err)
+		}
+		r = bytes.NewReader(onDisk)
+	}
+
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		// Legacy uncompressed object: fall back to a full read.
+		objType, content, readErr := s.readLoose(h)
+		if readErr != nil {
+			return "", 0, readErr
+		}
+		return objType, int64(len(content)), nil
+	}
+	defer zr.Close()
+
+	header, err := bufio.NewReaderSize(zr, 64).ReadString(0)
+	if err != nil {
+		return "", 0, fmt.Errorf("object stat %s: read header: %w", h, err)
+	}
+	header = strings.TrimSuffix(header, "\x00")
+	typ, lenStr, ok := strings.Cut(header, " ")
+	if !ok {
+		return "", 0, fmt.Errorf("object stat %s: invalid header %q", h, header)
+	}
+	size, err := strconv.ParseInt(lenStr, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("object stat %s: invalid length %q: %w", h, lenStr, err)
+	}
+	return ObjectType(typ), size, nil
+}
+
+// CountObjects gathers storage statistics: loose and packed object counts and
+// sizes, a per-type breakdown, and the largest objects by content size.
+// largest limits how many of the biggest objects are reported.
+func (s *Store) CountObjects(largest int) (*ObjectStats, error) {
+	stats := &ObjectStats{ByType: make(map[ObjectType]ObjectTypeStats)}
+
+	loose, err := s.LooseObjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range loose {
+		stats.LooseObjects++
+		stats.LooseSize += obj.DiskSize
+	}
+	packs, err := s.PackFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, pack := range packs {
+		stats.PackFiles++
+		stats.PackSize += pack.PackSize + pack.IndexSize
+	}
+	packed, err := s.packedHashSet()
+	if err != nil {
+		return nil, err
+	}
+	stats.PackedObjects = len(packed)
+
+	sizes := make([]ObjectSizeInfo, 0, len(loose)+len(packed))
+	err = s.ForEachObject("", func(obj ObjectInfo) error {
+		sizes = append(sizes, ObjectSizeInfo{Hash: obj.Hash, Type: obj.Type, Size: obj.Size, Packed: obj.Packed})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, obj := range sizes {
+		ts := stats.ByType[obj.Type]
+		ts.Count++
+		ts.Size += obj.Size
+		stats.ByType[obj.Type] = ts
+	}
+
+	if largest > 0 {
+		sort.Slice(sizes, func(i, j int) bool {
+			if sizes[i].Size == sizes[j].Size {
+				return sizes[i].Hash < sizes[j].Hash
+			}
+			return sizes[i].Size > sizes[j].Size
+		})
+		if len(sizes) > largest {
+			sizes = sizes[:largest]
+		}
+		stats.Largest = sizes
+	}
+	return stats, nil
+}
@@ -0,0 +1,57 @@
+package object
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStoreCountObjectsLooseAndPacked(t *testing.T) {
+	s := tempStore(t)
+
+	big := []byte(strings.Repeat("x", 4096))
+	bigHash, err := s.Write(TypeBlob, big)
+	if err != nil {
+		t.Fatalf("Write(big blob): %v", err)
+	}
+	if _, err := s.Write(TypeEntity, []byte("entity payload")); err != nil {
+		t.Fatalf("Write(entity): %v", err)
+	}
+	if _, err := s.GC(); err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	looseHash, err := s.Write(TypeBlob, []byte("loose blob"))
+	if err != nil {
+		t.Fatalf("Write(loose blob): %v", err)
+	}
+
+	stats, err := s.CountObjects(2)
+	if err != nil {
+		t.Fatalf("CountObjects: %v", err)
+	}
+	if stats.LooseObjects != 1 || stats.LooseSize <= 0 {
+		t.Fatalf("loose = %d (%d bytes), want 1 with positive size", stats.LooseObjects, stats.LooseSize)
+	}
+	if stats.PackFiles != 1 || stats.PackedObjects != 2 || stats.PackSize <= 0 {
+		t.Fatalf("packs = %d, packed = %d, size = %d; want 1 pack with 2 objects", stats.PackFiles, stats.PackedObjects, stats.PackSize)
+	}
+	if got := stats.ByType[TypeBlob]; got.Count != 2 || got.Size != int64(len(big)+len("loose blob")) {
+		t.Fatalf("blob stats = %+v", got)
+	}
+	if got := stats.ByType[TypeEntity]; got.Count != 1 {
+		t.Fatalf("entity stats = %+v, want 1 object", got)
+	}
+	if len(stats.Largest) != 2 {
+		t.Fatalf("len(Largest) = %d, want 2", len(stats.Largest))
+	}
+	if stats.Largest[0].Hash != bigHash || !stats.Largest[0].Packed {
+		t.Fatalf("Largest[0] = %+v, want packed %s", stats.Largest[0], bigHash)
+	}
+
+	objType, size, err := s.Stat(looseHash)
+	if err != nil {
+		t.Fatalf("Stat(loose): %v", err)
+	}
+	if objType != TypeBlob || size != int64(len("loose blob")) {
+		t.Fatalf("Stat(loose) = %s %d", objType, size)
+	}
+}
@@ -0,0 +1,95 @@
+package object
+
+import (
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteBlobFrom stores a blob of exactly size bytes read from r without
+// holding it in memory: the content is hashed, compressed and written to a
+// temp file in a single pass, then renamed into place. It produces the same
+// object as WriteBlob. Blobs that chunking would split are still buffered,
+// since chunk boundaries need the whole content.
+func (s *Store) WriteBlobFrom(r io.Reader, size int64) (Hash, error) {
+	if size < 0 {
+		return "", fmt.Errorf("object write: invalid blob size %d", size)
+	}
+	if s.chunking.Enabled && size >= s.chunking.MinSize {
+		data, err := io.ReadAll(io.LimitReader(r, size+1))
+		if err != nil {
+			return "", fmt.Errorf("object write: read: %w", err)
+		}
+		if int64(len(data)) != size {
+			return "", fmt.Errorf("object write: blob size changed while reading (want %d bytes, got %d)", size, len(data))
+		}
+		return s.WriteBlob(&Blob{Data: data})
+	}
+
+	objectsDir := filepath.Join(s.root, "objects")
+	if err := os.MkdirAll(objectsDir, 0o755); err != nil {
+		return "", fmt.Errorf("object write mkdir: %w", err)
+	}
+	tmp, err := os.CreateTemp(objectsDir, ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("object write tmpfile: %w", err)
+	}
+	tmpName := tmp.Name()
+	fail := func(err error) (Hash, error) {
+		tmp.Close()
+		os.Remove(tmpName)
+		return "", err
+	}
+
+	fw, finish, err := s.newObjectFileWriter(tmp, cryptKindLoose)
+	if err != nil {
+		return fail(fmt.Errorf("object write: encrypt: %w", err))
+	}
+	zw := zlib.NewWriter(fw)
+	hasher := sha256.New()
+	w := io.MultiWriter(hasher, zw)
+
+	if _, err := fmt.Fprintf(w, "%s %d\x00", TypeBlob, size); err != nil {
+		return fail(fmt.Errorf("object write: %w", err))
+	}
+	n, err := io.Copy(w, io.LimitReader(r, size+1))
+	if err != nil {
+		return fail(fmt.Errorf("object write: %w", err))
+	}
+	if n != size {
+		return fail(fmt.Errorf("object write: blob size changed while reading (want %d bytes, got %d)", size, n))
+	}
+	if err := zw.Close(); err != nil {
+		return fail(fmt.Errorf("object write compress: %w", err))
+	}
+	if err := finish(); err != nil {
+		return fail(fmt.Errorf("object write: encrypt: %w", err))
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(fmt.Errorf("object write: sync: %w", err))
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return "", fmt.Errorf("object write close: %w", err)
+	}
+
+	h := Hash(hex.EncodeToString(hasher.Sum(nil)))
+	if s.Has(h) {
+		os.Remove(tmpName)
+		return h, nil
+	}
+	dir := filepath.Join(objectsDir, string(h[:2]))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		os.Remove(tmpName)
+		return "", fmt.Errorf("object write mkdir: %w", err)
+	}
+	if err := os.Rename(tmpName, s.objectPath(h)); err != nil {
+		os.Remove(tmpName)
+		return "", fmt.Errorf("object write rename: %w", err)
+	}
+	return h, nil
+}
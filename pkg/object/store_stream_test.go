@@ -0,0 +1,73 @@
+package object
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteBlobFromMatchesWrite(t *testing.T) {
+	data := bytes.Repeat([]byte("streamed blob content\n"), 4096)
+
+	s := tempStore(t)
+	h, err := s.WriteBlobFrom(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("WriteBlobFrom: %v", err)
+	}
+	if want := HashObject(TypeBlob, data); h != want {
+		t.Fatalf("hash = %s, want %s", h, want)
+	}
+	b, err := s.ReadBlob(h)
+	if err != nil {
+		t.Fatalf("ReadBlob: %v", err)
+	}
+	if !bytes.Equal(b.Data, data) {
+		t.Fatal("streamed blob content mismatch")
+	}
+
+	// Writing the same content again reuses the object and leaves no
+	// temp files behind.
+	if h2, err := s.WriteBlobFrom(bytes.NewReader(data), int64(len(data))); err != nil || h2 != h {
+		t.Fatalf("second WriteBlobFrom = %s, %v; want %s", h2, err, h)
+	}
+	entries, err := os.ReadDir(filepath.Join(s.root, "objects"))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".tmp-") {
+			t.Fatalf("temp file %s left in objects dir", e.Name())
+		}
+	}
+}
+
+func TestWriteBlobFromEncryptedAndSizeMismatch(t *testing.T) {
+	s := tempStore(t)
+	if err := s.SetEncryptionKey(testEncryptionKey()); err != nil {
+		t.Fatalf("SetEncryptionKey: %v", err)
+	}
+	data := []byte("sealed streamed blob\n")
+	h, err := s.WriteBlobFrom(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("WriteBlobFrom: %v", err)
+	}
+	onDisk, err := os.ReadFile(s.objectPath(h))
+	if err != nil {
+		t.Fatalf("read loose file: %v", err)
+	}
+	if !isEncryptedData(onDisk) {
+		t.Fatal("streamed object was not written encrypted")
+	}
+	if _, got, err := s.Read(h); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Read = %q, %v; want %q", got, err, data)
+	}
+
+	if _, err := s.WriteBlobFrom(bytes.NewReader(data), int64(len(data))+5); err == nil {
+		t.Fatal("expected error when the reader is shorter than size")
+	}
+	if _, err := s.WriteBlobFrom(bytes.NewReader(data), 3); err == nil {
+		t.Fatal("expected error when the reader is longer than size")
+	}
+}
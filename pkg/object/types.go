@@ -41,6 +41,10 @@ const (
 	TypeEntityList ObjectType = "entitylist"
 	TypeTree       ObjectType = "tree"
 	TypeCommit     ObjectType = "commit"
+	// TypeChunk holds one content-defined chunk of a large blob stored in
+	// chunked form (see ChunkingOptions). Chunks are a storage detail and
+	// are never referenced from trees.
+	TypeChunk ObjectType = "chunk"
 )
 
 const (
@@ -48,6 +52,7 @@ const (
 	TreeModeDir        = "40000"
 	TreeModeFile       = "100644"
 	TreeModeExecutable = "100755"
+	TreeModeSymlink    = "120000" // blob holds the link target
 	TreeModeModule     = "160000"
 )
 
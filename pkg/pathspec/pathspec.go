@@ -0,0 +1,363 @@
+// Package pathspec parses and matches git-style pathspecs: plain paths that
+// select a file or everything below a directory, shell globs with recursive
+// "**", and the magic prefixes ":(exclude)" (or ":!" and ":^"), ":(icase)",
+// ":(literal)", ":(glob)" and ":(top)" (or ":/").
+//
+// Patterns match slash-separated paths relative to the repository root.
+// Resolving a command-line argument against the working directory is left to
+// the caller, which can use WithPath to substitute the resolved path.
+package pathspec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Magic holds the pathspec magic words attached to a pattern.
+type Magic struct {
+	// Exclude removes matching paths from the selection.
+	Exclude bool
+	// ICase matches without regard to letter case.
+	ICase bool
+	// Literal treats wildcard characters as ordinary characters.
+	Literal bool
+	// Glob matches the whole path with "*" and "?" never crossing a slash,
+	// instead of letting a slash-free pattern match a file name at any depth.
+	Glob bool
+	// Top resolves the pattern against the repository root rather than the
+	// working directory.
+	Top bool
+}
+
+// Pattern is a single parsed pathspec.
+type Pattern struct {
+	Magic
+	// Raw is the pathspec as given.
+	Raw string
+	// Path is the pattern with its magic removed, using forward slashes.
+	Path string
+
+	re *regexp.Regexp // wildcard matcher; nil for literal paths
+}
+
+// Parse parses a single pathspec.
+func Parse(spec string) (Pattern, error) {
+	p := Pattern{Raw: spec}
+	rest := spec
+	switch {
+	case strings.HasPrefix(rest, ":("):
+		end := strings.IndexByte(rest, ')')
+		if end < 0 {
+			return Pattern{}, fmt.Errorf("pathspec %q: missing ')' after magic", spec)
+		}
+		for _, word := range strings.Split(rest[2:end], ",") {
+			switch strings.TrimSpace(word) {
+			case "exclude":
+				p.Exclude = true
+			case "icase":
+				p.ICase = true
+			case "literal":
+				p.Literal = true
+			case "glob":
+				p.Glob = true
+			case "top":
+				p.Top = true
+			case "":
+			default:
+				return Pattern{}, fmt.Errorf("pathspec %q: unsupported magic %q", spec, word)
+			}
+		}
+		rest = rest[end+1:]
+	case strings.HasPrefix(rest, ":"):
+		rest = rest[1:]
+	short:
+		for rest != "" {
+			switch rest[0] {
+			case '!', '^':
+				p.Exclude = true
+			case '/':
+				p.Top = true
+			case ':':
+				rest = rest[1:]
+				break short
+			default:
+				break short
+			}
+			rest = rest[1:]
+		}
+	}
+	if p.Literal && p.Glob {
+		return Pattern{}, fmt.Errorf("pathspec %q: literal and glob magic cannot be combined", spec)
+	}
+	return p.WithPath(rest), nil
+}
+
+// WithPath returns a copy of p that matches path instead, keeping p's magic.
+func (p Pattern) WithPath(path string) Pattern {
+	path = strings.ReplaceAll(path, "\\", "/")
+	for strings.HasPrefix(path, "./") {
+		path = path[2:]
+	}
+	path = strings.TrimSuffix(path, "/")
+	if path == "." {
+		path = ""
+	}
+	p.Path = path
+	p.re = nil
+	if p.HasWildcards() {
+		p.re = regexp.MustCompile(globRegex(path, !p.Glob && !strings.Contains(path, "/"), p.ICase))
+	}
+	return p
+}
+
+// HasWildcards reports whether the pattern uses glob syntax.
+func (p Pattern) HasWildcards() bool {
+	return !p.Literal && HasGlobMeta(p.Path)
+}
+
+// Prefix returns the leading directories of the pattern that contain no
+// wildcards; every path the pattern matches lies below it. It is empty when
+// the pattern may match anywhere.
+func (p Pattern) Prefix() string {
+	if p.ICase {
+		return ""
+	}
+	if !p.HasWildcards() {
+		return p.Path
+	}
+	if !p.Glob && !strings.Contains(p.Path, "/") {
+		return ""
+	}
+	dir := p.Path[:strings.IndexAny(p.Path, "*?[\\")]
+	if i := strings.LastIndexByte(dir, '/'); i >= 0 {
+		return dir[:i]
+	}
+	return ""
+}
+
+// Match reports whether the pattern selects path. A pattern selects a path
+// when it matches the path itself or one of its leading directories, so a
+// directory selects everything below it.
+func (p Pattern) Match(path string) bool {
+	if p.Path == "" {
+		return true
+	}
+	if p.re == nil {
+		if p.ICase {
+			return len(path) >= len(p.Path) && strings.EqualFold(path[:len(p.Path)], p.Path) &&
+				(len(path) == len(p.Path) || path[len(p.Path)] == '/')
+		}
+		return path == p.Path || strings.HasPrefix(path, p.Path+"/")
+	}
+	for {
+		if p.re.MatchString(path) {
+			return true
+		}
+		i := strings.LastIndexByte(path, '/')
+		if i < 0 {
+			return false
+		}
+		path = path[:i]
+	}
+}
+
+// Set is a list of pathspecs. A path is selected when it matches at least
+// one including pattern, or there are none, and no excluding pattern.
+type Set struct {
+	Include []Pattern
+	Exclude []Pattern
+}
+
+// ParseSet parses specs into a Set. Blank specs are skipped.
+func ParseSet(specs []string) (*Set, error) {
+	s := &Set{}
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		p, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		s.Add(p)
+	}
+	return s, nil
+}
+
+// Add appends p to the including or excluding patterns.
+func (s *Set) Add(p Pattern) {
+	if p.Exclude {
+		s.Exclude = append(s.Exclude, p)
+	} else {
+		s.Include = append(s.Include, p)
+	}
+}
+
+// Empty reports whether the set has no patterns and so selects everything.
+func (s *Set) Empty() bool {
+	return s == nil || len(s.Include)+len(s.Exclude) == 0
+}
+
+// Match reports whether the set selects path. A nil set selects every path.
+func (s *Set) Match(path string) bool {
+	if s == nil {
+		return true
+	}
+	included := len(s.Include) == 0
+	for _, p := range s.Include {
+		if p.Match(path) {
+			included = true
+			break
+		}
+	}
+	return included && !s.Excluded(path)
+}
+
+// Excluded reports whether an excluding pattern matches path.
+func (s *Set) Excluded(path string) bool {
+	if s == nil {
+		return false
+	}
+	for _, p := range s.Exclude {
+		if p.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasGlobMeta reports whether s contains glob wildcard characters.
+func HasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// globRegex translates a glob into an anchored regular expression. "*" and
+// "?" do not match a slash, "**" as a whole segment matches any number of
+// directories, and bracket expressions accept "!" for negation. With
+// anyDepth set the glob may match the trailing part of a path, so a
+// slash-free pattern like "*.go" matches files in every directory.
+func globRegex(glob string, anyDepth, icase bool) string {
+	var b strings.Builder
+	if icase {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	if anyDepth {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case ch == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			i++
+			switch {
+			case i+1 < len(glob) && glob[i+1] == '/':
+				b.WriteString("(?:.*/)?")
+				i++
+			default:
+				b.WriteString(".*")
+			}
+		case ch == '*':
+			b.WriteString("[^/]*")
+		case ch == '?':
+			b.WriteString("[^/]")
+		case ch == '[':
+			class, end, ok := BracketRegex(glob, i)
+			if !ok {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(class)
+			i = end
+		case ch == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// BracketRegex translates the glob bracket expression starting at
+// glob[start] into a regular expression character class and returns it
+// with the index of the closing ']'. A leading '!' or '^' negates the
+// expression, a backslash escapes the next character, and a ']' first in
+// the expression is literal. The class never matches '/', not even through
+// a range such as "[+-0]". ok is false when the expression is not closed,
+// leaving the '[' literal.
+func BracketRegex(glob string, start int) (class string, end int, ok bool) {
+	i := start + 1
+	negate := false
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		negate = true
+		i++
+	}
+	var ranges [][2]rune
+	next := func() rune {
+		r, n := utf8.DecodeRuneInString(glob[i:])
+		if r == '\\' && i+n < len(glob) {
+			i += n
+			r, n = utf8.DecodeRuneInString(glob[i:])
+		}
+		i += n
+		return r
+	}
+	for first := true; i < len(glob); first = false {
+		if glob[i] == ']' && !first {
+			return classRegex(ranges, negate), i, true
+		}
+		lo := next()
+		hi := lo
+		if i+1 < len(glob) && glob[i] == '-' && glob[i+1] != ']' {
+			i++
+			hi = next()
+		}
+		ranges = append(ranges, [2]rune{lo, hi})
+	}
+	return "", -1, false
+}
+
+// classRegex writes ranges as a character class that excludes '/'.
+func classRegex(ranges [][2]rune, negate bool) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	if negate {
+		b.WriteString("^/")
+	}
+	writeRange := func(lo, hi rune) {
+		fmt.Fprintf(&b, `\x{%x}`, lo)
+		if hi != lo {
+			fmt.Fprintf(&b, `-\x{%x}`, hi)
+		}
+	}
+	empty := true
+	for _, r := range ranges {
+		lo, hi := r[0], r[1]
+		if lo > hi {
+			continue
+		}
+		if !negate && lo <= '/' && '/' <= hi {
+			if lo < '/' {
+				writeRange(lo, '/'-1)
+				empty = false
+			}
+			if hi > '/' {
+				writeRange('/'+1, hi)
+				empty = false
+			}
+			continue
+		}
+		writeRange(lo, hi)
+		empty = false
+	}
+	if empty && !negate {
+		// Nothing but '/' was listed: match nothing.
+		return `[^\x00-\x{10ffff}]`
+	}
+	b.WriteByte(']')
+	return b.String()
+}
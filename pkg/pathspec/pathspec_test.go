@@ -0,0 +1,202 @@
+package pathspec
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestParseMagic(t *testing.T) {
+	tests := []struct {
+		spec string
+		path string
+		want Magic
+	}{
+		{"src/main.go", "src/main.go", Magic{}},
+		{"./src/", "src", Magic{}},
+		{".", "", Magic{}},
+		{":(exclude)vendor", "vendor", Magic{Exclude: true}},
+		{":!vendor", "vendor", Magic{Exclude: true}},
+		{":^vendor", "vendor", Magic{Exclude: true}},
+		{":/docs", "docs", Magic{Top: true}},
+		{":!/docs", "docs", Magic{Exclude: true, Top: true}},
+		{"::weird", "weird", Magic{}},
+		{":(icase,glob)**/*.GO", "**/*.GO", Magic{ICase: true, Glob: true}},
+		{":(literal)a[1].txt", "a[1].txt", Magic{Literal: true}},
+		{":(top,exclude)build", "build", Magic{Top: true, Exclude: true}},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if p.Path != tt.path || p.Magic != tt.want || p.Raw != tt.spec {
+			t.Errorf("Parse(%q) = path %q magic %+v, want path %q magic %+v", tt.spec, p.Path, p.Magic, tt.path, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{":(exclude", ":(bogus)x", ":(literal,glob)x"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		spec  string
+		path  string
+		match bool
+	}{
+		// Plain paths select the file or everything below a directory.
+		{"src", "src/main.go", true},
+		{"src", "src/pkg/a.go", true},
+		{"src", "srcs/main.go", false},
+		{"src/main.go", "src/main.go", true},
+		{".", "anything/at/all", true},
+
+		// A slash-free glob matches file names at any depth.
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/repo/status.go", true},
+		{"*.go", "main.gox", false},
+
+		// A glob with a slash is anchored, and "*" stays in one directory.
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/sub/main.go", false},
+		{"src/?.go", "src/a.go", true},
+		{"src/[ab].go", "src/b.go", true},
+		{"src/[!ab].go", "src/b.go", false},
+		{"src/[!ab].go", "src/c.go", true},
+
+		// Bracket expressions never match a slash, even through a range.
+		{"a[+-0]b", "pkg/a-b", true},
+		{"a[+-0]b", "a/b", false},
+		{"a[!x]b", "a/b", false},
+
+		// "**" crosses directories.
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/c.go", true},
+		{"**/testdata", "pkg/x/testdata/in.txt", true},
+		{"docs/**", "docs/a/b.md", true},
+		{"docs/**", "src/docs.md", false},
+
+		// Glob magic anchors slash-free patterns at the root.
+		{":(glob)*.go", "main.go", true},
+		{":(glob)*.go", "pkg/main.go", false},
+
+		// Case-insensitive matching.
+		{":(icase)README.md", "readme.MD", true},
+		{":(icase)Docs", "docs/Guide.md", true},
+		{":(icase)*.GO", "pkg/Main.go", true},
+		{"README.md", "readme.md", false},
+
+		// Literal magic disables wildcards.
+		{":(literal)a*.txt", "a*.txt", true},
+		{":(literal)a*.txt", "ab.txt", false},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if got := p.Match(tt.path); got != tt.match {
+			t.Errorf("%q.Match(%q) = %v, want %v", tt.spec, tt.path, got, tt.match)
+		}
+	}
+}
+
+func TestPatternPrefix(t *testing.T) {
+	tests := []struct {
+		spec, prefix string
+	}{
+		{"src/main.go", "src/main.go"},
+		{"src/*.go", "src"},
+		{"src/pkg/**/*.go", "src/pkg"},
+		{"*.go", ""},
+		{":(glob)*.go", ""},
+		{"a*/b.go", ""},
+		{":(icase)src/*.go", ""},
+		{":(literal)src/*.go", "src/*.go"},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if got := p.Prefix(); got != tt.prefix {
+			t.Errorf("%q.Prefix() = %q, want %q", tt.spec, got, tt.prefix)
+		}
+	}
+}
+
+func TestSetMatch(t *testing.T) {
+	s, err := ParseSet([]string{"src", ":(exclude)src/vendor", ":!*_test.go", ""})
+	if err != nil {
+		t.Fatalf("ParseSet: %v", err)
+	}
+	if len(s.Include) != 1 || len(s.Exclude) != 2 {
+		t.Fatalf("ParseSet = %d include, %d exclude; want 1 and 2", len(s.Include), len(s.Exclude))
+	}
+	tests := map[string]bool{
+		"src/main.go":        true,
+		"src/main_test.go":   false,
+		"src/vendor/lib.go":  false,
+		"docs/readme.md":     false,
+		"src/pkg/helpers.go": true,
+	}
+	for path, want := range tests {
+		if got := s.Match(path); got != want {
+			t.Errorf("Match(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	// Exclusions alone select everything else.
+	s, err = ParseSet([]string{":!docs"})
+	if err != nil {
+		t.Fatalf("ParseSet: %v", err)
+	}
+	if !s.Match("src/main.go") || s.Match("docs/a.md") {
+		t.Fatal("exclude-only set should select everything outside docs")
+	}
+
+	var none *Set
+	if !none.Match("any") || none.Excluded("any") || !none.Empty() {
+		t.Fatal("nil set should select every path")
+	}
+}
+
+func TestBracketRegex(t *testing.T) {
+	tests := []struct {
+		glob    string
+		matches string
+		rejects string
+	}{
+		{"[abc]", "b", "!/"},
+		{"[!abc]", "!x", "abc/"},
+		{"[^a-c]", "d", "b/"},
+		{"[]a]", "]a", "b"},
+		{`[\]\-]`, "]-", `\`},
+		{"[.-0]", ".0", "/"},
+	}
+	for _, tt := range tests {
+		class, end, ok := BracketRegex(tt.glob, 0)
+		if !ok || end != len(tt.glob)-1 {
+			t.Fatalf("BracketRegex(%q) = %q, %d, %v", tt.glob, class, end, ok)
+		}
+		re := regexp.MustCompile("^" + class + "$")
+		for _, c := range tt.matches {
+			if !re.MatchString(string(c)) {
+				t.Errorf("%q (%s) does not match %q", tt.glob, class, c)
+			}
+		}
+		for _, c := range tt.rejects {
+			if re.MatchString(string(c)) {
+				t.Errorf("%q (%s) matches %q", tt.glob, class, c)
+			}
+		}
+	}
+	if _, _, ok := BracketRegex("[abc", 0); ok {
+		t.Error("BracketRegex accepted an unterminated expression")
+	}
+}
@@ -0,0 +1,165 @@
+package remote
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// Transfer checkpoint kinds.
+const (
+	CheckpointPush  = "push"
+	CheckpointFetch = "fetch"
+)
+
+const checkpointHeader = "graft-transfer-checkpoint 1"
+
+// TransferCheckpoint persists the progress of a push or fetch under
+// .graft/transfers/ so that an interrupted transfer can resume. For a push
+// it records the objects the remote has already accepted; for a fetch it
+// records the objects already written locally, which are offered back to
+// the server as haves on retry.
+//
+// The file is append-only: a header line naming the kind and a digest of the
+// key (which may embed remote credentials, so it is never stored), then one
+// hash per line. Each Record call appends and fsyncs, so progress survives a
+// crash up to the last completed chunk. A torn final line is ignored on load.
+type TransferCheckpoint struct {
+	path   string
+	header string
+	done   map[object.Hash]struct{}
+	list   []object.Hash
+}
+
+// OpenTransferCheckpoint loads the checkpoint for kind and key from
+// graftDir, or returns an empty one if none exists. key identifies the
+// transfer, e.g. the remote URL plus the remote ref being pushed.
+func OpenTransferCheckpoint(graftDir, kind, key string) (*TransferCheckpoint, error) {
+	sum := sha256.Sum256([]byte(kind + "\x00" + key))
+	cp := &TransferCheckpoint{
+		path:   filepath.Join(graftDir, "transfers", kind+"-"+hex.EncodeToString(sum[:8])),
+		header: checkpointHeader + " " + kind + " " + hex.EncodeToString(sum[:]),
+		done:   make(map[object.Hash]struct{}),
+	}
+
+	f, err := os.Open(cp.path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read transfer checkpoint: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() || sc.Text() != cp.header {
+		// Unknown format or a different transfer hashing to the same
+		// file name: start over.
+		f.Close()
+		if err := os.Remove(cp.path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("reset transfer checkpoint: %w", err)
+		}
+		return cp, nil
+	}
+	for sc.Scan() {
+		h := object.Hash(strings.TrimSpace(sc.Text()))
+		if ValidateHash(h) != nil {
+			continue
+		}
+		if _, ok := cp.done[h]; !ok {
+			cp.done[h] = struct{}{}
+			cp.list = append(cp.list, h)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read transfer checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// Len returns the number of recorded objects.
+func (c *TransferCheckpoint) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.list)
+}
+
+// Has reports whether h has already been transferred.
+func (c *TransferCheckpoint) Has(h object.Hash) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.done[h]
+	return ok
+}
+
+// Hashes returns the recorded objects in the order they were recorded.
+func (c *TransferCheckpoint) Hashes() []object.Hash {
+	if c == nil {
+		return nil
+	}
+	return append([]object.Hash(nil), c.list...)
+}
+
+// Record appends hashes to the checkpoint and syncs it to disk.
+func (c *TransferCheckpoint) Record(hashes ...object.Hash) error {
+	if c == nil {
+		return nil
+	}
+	var sb strings.Builder
+	for _, h := range hashes {
+		if _, ok := c.done[h]; ok || h == "" {
+			continue
+		}
+		c.done[h] = struct{}{}
+		c.list = append(c.list, h)
+		sb.WriteString(string(h))
+		sb.WriteByte('\n')
+	}
+	if sb.Len() == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("write transfer checkpoint: %w", err)
+	}
+	f, err := os.OpenFile(c.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("write transfer checkpoint: %w", err)
+	}
+	if info, statErr := f.Stat(); statErr == nil && info.Size() == 0 {
+		if _, err := f.WriteString(c.header + "\n"); err != nil {
+			f.Close()
+			return fmt.Errorf("write transfer checkpoint: %w", err)
+		}
+	}
+	if _, err := f.WriteString(sb.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("write transfer checkpoint: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("write transfer checkpoint: sync: %w", err)
+	}
+	return f.Close()
+}
+
+// Remove deletes the checkpoint once the transfer has completed.
+func (c *TransferCheckpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	c.done = make(map[object.Hash]struct{})
+	c.list = nil
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove transfer checkpoint: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package remote
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/odvcencio/graft/pkg/object"
@@ -27,6 +29,10 @@ type Endpoint struct {
 	Repo    string
 	user    string
 	pass    string
+
+	// LocalDir is the metadata directory of a machine-local repository.
+	// It is empty for HTTP endpoints.
+	LocalDir string
 }
 
 // ParseEndpoint parses a remote URL into a canonical endpoint.
@@ -35,11 +41,15 @@ type Endpoint struct {
 // - https://host/graft/owner/repo
 // - https://host/owner/repo (expanded to /graft/owner/repo)
 // - https://host/api/v1/graft/owner/repo
+// - file:///path/to/repo or /path/to/repo naming a local Graft repository
 func ParseEndpoint(raw string) (Endpoint, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
 		return Endpoint{}, fmt.Errorf("remote URL is required")
 	}
+	if dir, ok := LocalRepoDir(raw); ok {
+		return localEndpoint(raw, dir), nil
+	}
 	u, err := url.Parse(raw)
 	if err != nil {
 		return Endpoint{}, fmt.Errorf("parse remote URL: %w", err)
@@ -141,7 +151,36 @@ type RefUpdate struct {
 // ClientOptions configures the remote protocol client.
 type ClientOptions struct {
 	Timeout     time.Duration // HTTP client timeout (default 60s)
-	MaxAttempts int           // retry attempts (default 3)
+	MaxAttempts int           // retry attempts (default 3); overrides Retry.MaxAttempts
+
+	// Retry controls retries of failed requests. Zero fields fall back to
+	// the http section of ~/.graftconfig, then to DefaultRetryPolicy.
+	Retry RetryPolicy
+
+	// Progress, when set, receives running download and upload totals.
+	Progress object.ProgressFunc
+	// Stats, when set, receives running transfer statistics; see StatsFunc.
+	Stats StatsFunc
+
+	// Proxy is the proxy URL for every request. When empty, HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY from the environment apply.
+	Proxy string
+	// CAFile is a PEM bundle of certificates trusted in addition to the
+	// system roots.
+	CAFile string
+	// ClientCert and ClientKey are PEM files presenting a client
+	// certificate for mutual TLS. Both or neither must be set.
+	ClientCert string
+	ClientKey  string
+
+	// MaxUploadRate and MaxDownloadRate cap request and response body
+	// throughput in bytes per second across all of the client's requests.
+	// Zero means unlimited.
+	MaxUploadRate   int64
+	MaxDownloadRate int64
+	// MaxConcurrentRequests caps how many requests are in flight at once;
+	// further requests wait for a slot. Zero means unlimited.
+	MaxConcurrentRequests int
 }
 
 // Response limits per endpoint type.
@@ -154,14 +193,27 @@ const (
 
 // Client is a transport client for orchard's Graft protocol.
 type Client struct {
-	endpoint     Endpoint
-	httpClient   *http.Client
-	token        string
-	user         string
-	pass         string
-	maxAttempts  int
-	serverLimits *ServerLimits
-	serverCaps   *Capabilities
+	endpoint   Endpoint
+	httpClient *http.Client
+	token      string
+	oauth      *oauthSession // refreshes token when it came from an OAuth login
+	user       string
+	pass       string
+	retry      RetryPolicy
+
+	// metaMu guards what the server told us about itself; concurrent
+	// requests may record it.
+	metaMu         sync.Mutex
+	serverLimits   *ServerLimits
+	serverCaps     *Capabilities
+	serverProtocol string // negotiated version; "" until a response arrives
+
+	// local serves requests straight from a repository on this machine
+	// instead of over HTTP.
+	local *localTransport
+
+	progress *transferProgress
+	stats    *transferStats
 }
 
 // ErrPackUploadUnsupported indicates the remote does not accept pack uploads.
@@ -171,15 +223,21 @@ var ErrPackUploadUnsupported = errors.New("pack upload unsupported")
 //
 // Auth resolution order:
 // 1) GRAFT_TOKEN (Bearer)
-// 2) ~/.graftconfig host-matching Orchard profile token (Bearer)
+// 2) ~/.graftconfig host-matching Orchard profile token (Bearer; refreshed if expired)
 // 3) GRAFT_USERNAME + GRAFT_PASSWORD (Basic)
 // 4) URL userinfo (Basic)
+//
+// Proxy and TLS settings come from GRAFT_HTTP_PROXY, GRAFT_CA_FILE,
+// GRAFT_CLIENT_CERT and GRAFT_CLIENT_KEY, then from the http section of
+// ~/.graftconfig; HTTP_PROXY and HTTPS_PROXY apply when no proxy is set.
+// The retry policy also comes from the http section of ~/.graftconfig.
 func NewClient(remoteURL string) (*Client, error) {
 	return NewClientWithOptions(remoteURL, ClientOptions{})
 }
 
 // NewClientWithOptions creates a remote protocol client with configurable options.
-// Zero-value or negative fields in opts receive defaults (60s timeout, 3 attempts).
+// Zero-value or negative fields in opts receive defaults (60s timeout, 3 attempts);
+// empty proxy, TLS and retry fields are resolved as described for NewClient.
 func NewClientWithOptions(remoteURL string, opts ClientOptions) (*Client, error) {
 	endpoint, err := ParseEndpoint(remoteURL)
 	if err != nil {
@@ -189,32 +247,58 @@ func NewClientWithOptions(remoteURL string, opts ClientOptions) (*Client, error)
 	if opts.Timeout <= 0 {
 		opts.Timeout = 60 * time.Second
 	}
-	if opts.MaxAttempts <= 0 {
-		opts.MaxAttempts = 3
+	if opts.MaxAttempts > 0 {
+		opts.Retry.MaxAttempts = opts.MaxAttempts
+	}
+	if endpoint.LocalDir != "" {
+		return &Client{
+			endpoint:   endpoint,
+			httpClient: &http.Client{Timeout: opts.Timeout},
+			retry:      opts.Retry.withDefaults(),
+			local:      newLocalTransport(endpoint.LocalDir),
+			progress:   &transferProgress{fn: opts.Progress},
+			stats:      newTransferStats(opts.Stats),
+		}, nil
+	}
+
+	userCfg, _ := userconfig.Load()
+	opts, err = resolveHTTPOptions(opts, userCfg)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := newHTTPTransport(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	token := strings.TrimSpace(os.Getenv("GRAFT_TOKEN"))
 	user := strings.TrimSpace(os.Getenv("GRAFT_USERNAME"))
 	pass := os.Getenv("GRAFT_PASSWORD")
-	if token == "" {
-		if cfg, err := userconfig.Load(); err == nil {
-			token = strings.TrimSpace(cfg.OrchardProfile(endpoint.OrchardBaseURL()).Token)
-		}
+	var oauth *oauthSession
+	if token == "" && userCfg != nil {
+		profile := userCfg.OrchardProfile(endpoint.OrchardBaseURL())
+		token = profile.Token
+		oauth = newOAuthSession(endpoint.OrchardBaseURL(), profile)
 	}
-	if token == "" && user == "" && endpoint.user != "" {
+	if token == "" && oauth == nil && user == "" && endpoint.user != "" {
 		user = endpoint.user
 		pass = endpoint.pass
 	}
 
+	stats := newTransferStats(opts.Stats)
 	return &Client{
 		endpoint: endpoint,
 		httpClient: &http.Client{
-			Timeout: opts.Timeout,
+			Timeout:   opts.Timeout,
+			Transport: &statsTransport{base: newThrottledTransport(transport, opts), stats: stats},
 		},
-		token:       token,
-		user:        user,
-		pass:        pass,
-		maxAttempts: opts.MaxAttempts,
+		token:    token,
+		oauth:    oauth,
+		user:     user,
+		pass:     pass,
+		retry:    opts.Retry.withDefaults(),
+		progress: &transferProgress{fn: opts.Progress},
+		stats:    stats,
 	}, nil
 }
 
@@ -252,18 +336,36 @@ func (e Endpoint) OrchardBaseURL() string {
 
 // ServerLimits returns the cached server-advertised limits, or nil if not yet received.
 func (c *Client) ServerLimits() *ServerLimits {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
 	return c.serverLimits
 }
 
 // ServerCapabilities returns the cached server-advertised capabilities, or nil
 // if the server has not advertised any yet.
 func (c *Client) ServerCapabilities() *Capabilities {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
 	return c.serverCaps
 }
 
-func (c *Client) cacheServerMetadata(resp *http.Response) {
+// cacheServerMetadata records the protocol version, limits and
+// capabilities from a server response. It fails when the server answered
+// with a protocol version the client does not speak.
+func (c *Client) cacheServerMetadata(resp *http.Response) error {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
 	c.cacheServerLimits(resp)
 	c.cacheServerCapabilities(resp)
+	if c.serverProtocol != "" {
+		return nil
+	}
+	protocol, err := negotiateProtocol(resp.Header.Get(headerProtocol))
+	if err != nil {
+		return err
+	}
+	c.serverProtocol = protocol
+	return nil
 }
 
 func (c *Client) cacheServerLimits(resp *http.Response) {
@@ -295,6 +397,9 @@ func (c *Client) cacheServerCapabilities(resp *http.Response) {
 // the client loops with ?cursor=X&limit=1000 until no cursor is returned.
 // Legacy flat-map responses (no "refs" wrapper) are handled as a single page.
 func (c *Client) ListRefs(ctx context.Context) (map[string]object.Hash, error) {
+	if c.local != nil {
+		return c.local.listRefs()
+	}
 	refs := make(map[string]object.Hash)
 	cursor := ""
 	const pageLimit = 1000
@@ -360,6 +465,13 @@ func (c *Client) ListRefs(ctx context.Context) (map[string]object.Hash, error) {
 
 // BatchObjects fetches missing objects reachable from wants and not in haves.
 func (c *Client) BatchObjects(ctx context.Context, wants, haves []object.Hash, maxObjects int) ([]ObjectRecord, bool, error) {
+	if c.local != nil {
+		result, err := c.local.batchObjects(wants, haves, maxObjects, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		return result.Objects, result.Truncated, nil
+	}
 	if len(wants) == 0 {
 		return nil, false, fmt.Errorf("at least one want hash is required")
 	}
@@ -456,6 +568,66 @@ func (c *Client) BatchObjectsPack(ctx context.Context, wants, haves []object.Has
 // BatchObjectsPackShallow is like BatchObjectsPack but accepts shallow options
 // and returns shallow boundary hashes from the server response.
 func (c *Client) BatchObjectsPackShallow(ctx context.Context, wants, haves []object.Hash, maxObjects int, shallowOpts *ShallowFetchOpts) (*BatchShallowResult, error) {
+	return c.BatchObjectsPackThin(ctx, wants, haves, maxObjects, shallowOpts, nil)
+}
+
+// BatchObjectsPackThin is like BatchObjectsPackShallow but, when lookup is
+// non-nil, advertises the thin-pack capability so the server may send objects
+// as REF_DELTA entries against objects reachable from haves. lookup resolves
+// those bases from the client's own storage.
+func (c *Client) BatchObjectsPackThin(ctx context.Context, wants, haves []object.Hash, maxObjects int, shallowOpts *ShallowFetchOpts, lookup object.ThinPackBaseLookup) (*BatchShallowResult, error) {
+	if c.local != nil {
+		return c.local.batchObjects(wants, haves, maxObjects, shallowOpts)
+	}
+	var records []ObjectRecord
+	collected := make(map[object.Hash]int)
+	bases := func(h object.Hash) (object.PackObjectType, []byte, error) {
+		if i, ok := collected[h]; ok {
+			packType, _ := objectTypeToPackType(records[i].Type)
+			return packType, records[i].Data, nil
+		}
+		if lookup == nil {
+			return 0, nil, fmt.Errorf("object %s not found", h)
+		}
+		return lookup(h)
+	}
+	result, err := c.batchObjects(ctx, wants, haves, maxObjects, shallowOpts, lookup != nil, bases, func(rec ObjectRecord) error {
+		collected[rec.Hash] = len(records)
+		records = append(records, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Objects = records
+	return result, nil
+}
+
+// BatchObjectsStream is like BatchObjectsPackThin but hands each object to
+// emit as it is decoded instead of returning them, so memory stays bounded
+// however large the response. Pack responses are decoded straight from the
+// network; emit must make each object visible to lookup before returning
+// (see DecodePackTransportStream). The returned result has no Objects.
+func (c *Client) BatchObjectsStream(ctx context.Context, wants, haves []object.Hash, maxObjects int, shallowOpts *ShallowFetchOpts, lookup object.ThinPackBaseLookup, emit func(ObjectRecord) error) (*BatchShallowResult, error) {
+	if c.local != nil {
+		result, err := c.local.batchObjects(wants, haves, maxObjects, shallowOpts)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range result.Objects {
+			if err := emit(rec); err != nil {
+				return nil, err
+			}
+		}
+		result.Objects = nil
+		return result, nil
+	}
+	return c.batchObjects(ctx, wants, haves, maxObjects, shallowOpts, lookup != nil, lookup, emit)
+}
+
+// batchObjects posts a batch request and decodes the response through emit.
+// thin advertises the thin-pack capability; lookup resolves delta bases.
+func (c *Client) batchObjects(ctx context.Context, wants, haves []object.Hash, maxObjects int, shallowOpts *ShallowFetchOpts, thin bool, lookup object.ThinPackBaseLookup, emit func(ObjectRecord) error) (*BatchShallowResult, error) {
 	if len(wants) == 0 {
 		return nil, fmt.Errorf("at least one want hash is required")
 	}
@@ -506,64 +678,82 @@ func (c *Client) BatchObjectsPackShallow(ctx context.Context, wants, haves []obj
 	if err != nil {
 		return nil, err
 	}
+	// Ask for a pack unless the server said it cannot send one; a server
+	// that ignores Accept answers with JSON either way.
+	features := c.Features()
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/x-graft-pack")
-	req.Header.Set("Accept-Encoding", "zstd")
+	if features.Pack {
+		req.Header.Set("Accept", "application/x-graft-pack")
+		if features.Zstd {
+			req.Header.Set("Accept-Encoding", "zstd")
+		}
+	}
 	c.applyAuth(req)
+	if thin {
+		req.Header.Set(headerCapabilities, ClientCapabilities+","+CapThinPack)
+	}
 
-	resp, err := retryDo(c.httpClient, req, c.maxAttempts)
+	resp, err := retryDo(c.httpClient, req, c.retry)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	c.cacheServerLimits(resp)
-
-	body, readErr := io.ReadAll(io.LimitReader(resp.Body, responseLimitBatch))
-	if readErr != nil {
-		return nil, readErr
+	if err := c.cacheServerMetadata(resp); err != nil {
+		return nil, err
 	}
+	body := c.progress.reader(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		if re := tryParseRemoteError(body); re != nil {
+		raw, readErr := io.ReadAll(io.LimitReader(body, responseLimitDefault))
+		if readErr != nil {
+			return nil, readErr
+		}
+		if re := tryParseRemoteError(resp.StatusCode, raw); re != nil {
 			return nil, re
 		}
-		msg := strings.TrimSpace(string(body))
+		msg := strings.TrimSpace(string(raw))
 		if msg == "" {
 			msg = http.StatusText(resp.StatusCode)
 		}
-		return nil, fmt.Errorf("remote request failed (%s %s): %s", req.Method, req.URL.Path, msg)
+		return nil, requestFailed(req, resp.StatusCode, msg)
 	}
 
 	// Parse shallow boundaries from response header.
-	var shallowHashes []object.Hash
+	result := &BatchShallowResult{}
 	if raw := resp.Header.Get("X-Shallow"); raw != "" {
 		for _, s := range strings.Split(raw, ",") {
 			s = strings.TrimSpace(s)
 			if s != "" {
-				shallowHashes = append(shallowHashes, object.Hash(s))
+				result.Shallow = append(result.Shallow, object.Hash(s))
 			}
 		}
 	}
 
 	ct := resp.Header.Get("Content-Type")
 	if strings.HasPrefix(ct, "application/x-graft-pack") {
-		// Pack transport response: optionally zstd-compressed.
-		packData := body
+		// Pack transport response: optionally zstd-compressed, decoded as
+		// it streams in.
+		packStream := body
 		if isZstdEncoded(resp.Header.Get("Content-Encoding")) {
-			packData, err = decompressZstd(body)
+			zr, err := newZstdReader(body)
 			if err != nil {
 				return nil, fmt.Errorf("decompress pack response: %w", err)
 			}
+			defer zr.Close()
+			packStream = zr
 		}
-		records, err := DecodePackTransport(packData)
-		if err != nil {
+		if err := DecodePackTransportStream(packStream, lookup, emit); err != nil {
 			return nil, fmt.Errorf("decode pack response: %w", err)
 		}
-		truncated := strings.EqualFold(resp.Header.Get("X-Truncated"), "true")
-		return &BatchShallowResult{Objects: records, Truncated: truncated, Shallow: shallowHashes}, nil
+		result.Truncated = strings.EqualFold(resp.Header.Get("X-Truncated"), "true")
+		return result, nil
 	}
 
 	// JSON fallback: server returned application/json.
+	raw, err := io.ReadAll(io.LimitReader(body, responseLimitBatch))
+	if err != nil {
+		return nil, err
+	}
 	var jsonResp struct {
 		Objects []struct {
 			Hash string `json:"hash"`
@@ -573,7 +763,7 @@ func (c *Client) BatchObjectsPackShallow(ctx context.Context, wants, haves []obj
 		Truncated bool     `json:"truncated"`
 		Shallow   []string `json:"shallow"`
 	}
-	if err := json.Unmarshal(body, &jsonResp); err != nil {
+	if err := json.Unmarshal(raw, &jsonResp); err != nil {
 		return nil, fmt.Errorf("decode batch response: %w", err)
 	}
 
@@ -581,11 +771,10 @@ func (c *Client) BatchObjectsPackShallow(ctx context.Context, wants, haves []obj
 	for _, s := range jsonResp.Shallow {
 		s = strings.TrimSpace(s)
 		if s != "" {
-			shallowHashes = append(shallowHashes, object.Hash(s))
+			result.Shallow = append(result.Shallow, object.Hash(s))
 		}
 	}
 
-	out := make([]ObjectRecord, 0, len(jsonResp.Objects))
 	for _, obj := range jsonResp.Objects {
 		objType, err := parseObjectType(obj.Type)
 		if err != nil {
@@ -595,17 +784,19 @@ func (c *Client) BatchObjectsPackShallow(ctx context.Context, wants, haves []obj
 		if err := ValidateHash(h); err != nil {
 			return nil, fmt.Errorf("invalid hash in batch response: %w", err)
 		}
-		out = append(out, ObjectRecord{
-			Hash: h,
-			Type: objType,
-			Data: obj.Data,
-		})
+		if err := emit(ObjectRecord{Hash: h, Type: objType, Data: obj.Data}); err != nil {
+			return nil, err
+		}
 	}
-	return &BatchShallowResult{Objects: out, Truncated: jsonResp.Truncated, Shallow: shallowHashes}, nil
+	result.Truncated = jsonResp.Truncated
+	return result, nil
 }
 
 // GetObject fetches one object by hash.
 func (c *Client) GetObject(ctx context.Context, hash object.Hash) (ObjectRecord, error) {
+	if c.local != nil {
+		return c.local.getObject(object.Hash(strings.TrimSpace(string(hash))))
+	}
 	hash = object.Hash(strings.TrimSpace(string(hash)))
 	if hash == "" {
 		return ObjectRecord{}, fmt.Errorf("object hash is required")
@@ -617,13 +808,13 @@ func (c *Client) GetObject(ctx context.Context, hash object.Hash) (ObjectRecord,
 	}
 	c.applyAuth(req)
 
-	resp, err := retryDo(c.httpClient, req, c.maxAttempts)
+	resp, err := retryDo(c.httpClient, req, c.retry)
 	if err != nil {
 		return ObjectRecord{}, err
 	}
 	defer resp.Body.Close()
 
-	body, readErr := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	body, readErr := io.ReadAll(io.LimitReader(c.progress.reader(resp.Body), 32<<20))
 	if readErr != nil {
 		return ObjectRecord{}, readErr
 	}
@@ -632,7 +823,7 @@ func (c *Client) GetObject(ctx context.Context, hash object.Hash) (ObjectRecord,
 		if msg == "" {
 			msg = http.StatusText(resp.StatusCode)
 		}
-		return ObjectRecord{}, fmt.Errorf("remote request failed (%s %s): %s", req.Method, req.URL.Path, msg)
+		return ObjectRecord{}, requestFailed(req, resp.StatusCode, msg)
 	}
 
 	objType, err := parseObjectType(strings.TrimSpace(resp.Header.Get("X-Object-Type")))
@@ -648,6 +839,9 @@ func (c *Client) GetObject(ctx context.Context, hash object.Hash) (ObjectRecord,
 
 // PushObjects uploads objects using newline-delimited JSON payload.
 func (c *Client) PushObjects(ctx context.Context, objects []ObjectRecord) error {
+	if c.local != nil {
+		return c.pushLocal(objects)
+	}
 	if len(objects) == 0 {
 		return nil
 	}
@@ -685,14 +879,44 @@ func (c *Client) PushObjects(ctx context.Context, objects []ObjectRecord) error
 	if _, err := c.doWithLimit(req, http.StatusOK, 1<<20, "application/json"); err != nil {
 		return err
 	}
+	c.progress.uploaded(len(objects), int64(buf.Len()))
+	c.stats.sent(objects)
+	return nil
+}
+
+// pushLocal writes objects into a local remote, reporting their raw size as
+// uploaded bytes.
+func (c *Client) pushLocal(objects []ObjectRecord) error {
+	if err := c.local.pushObjects(objects); err != nil {
+		return err
+	}
+	var n int64
+	for _, obj := range objects {
+		n += int64(len(obj.Data))
+	}
+	c.progress.uploaded(len(objects), n)
+	c.stats.sent(objects)
 	return nil
 }
 
 // PushObjectsPack uploads objects using zstd-compressed pack transport.
 func (c *Client) PushObjectsPack(ctx context.Context, objects []ObjectRecord) error {
+	return c.PushObjectsThinPack(ctx, objects, nil)
+}
+
+// PushObjectsThinPack uploads objects as a thin pack: objects with an entry in
+// bases are sent as deltas against an object the server already has. bases
+// is ignored unless the server advertises the thin-pack capability.
+func (c *Client) PushObjectsThinPack(ctx context.Context, objects []ObjectRecord, bases map[object.Hash]ThinPackBase) error {
+	if c.local != nil {
+		return c.pushLocal(objects)
+	}
 	if len(objects) == 0 {
 		return nil
 	}
+	if !c.Features().ThinPack {
+		bases = nil
+	}
 
 	for i, obj := range objects {
 		if _, err := parseObjectType(string(obj.Type)); err != nil {
@@ -705,30 +929,42 @@ func (c *Client) PushObjectsPack(ctx context.Context, objects []ObjectRecord) er
 		objects[i].Hash = computedHash
 	}
 
-	packData, err := EncodePackTransportToBytes(objects)
+	// The compressed pack is spooled to a temporary file rather than built
+	// in memory; the file also lets retries replay the upload.
+	spool, err := os.CreateTemp("", "graft-push-*.pack")
 	if err != nil {
 		return fmt.Errorf("encode pack: %w", err)
 	}
-
-	compressed, err := compressZstd(packData)
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	if err := encodeCompressedPack(spool, objects, bases); err != nil {
+		return fmt.Errorf("encode pack: %w", err)
+	}
+	compressedSize, err := spool.Seek(0, io.SeekCurrent)
 	if err != nil {
-		return fmt.Errorf("compress pack: %w", err)
+		return fmt.Errorf("encode pack: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint.BaseURL+"/objects", bytes.NewReader(compressed))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint.BaseURL+"/objects", io.NewSectionReader(spool, 0, compressedSize))
 	if err != nil {
 		return err
 	}
+	req.ContentLength = compressedSize
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(spool, 0, compressedSize)), nil
+	}
 	req.Header.Set("Content-Type", "application/x-graft-pack")
 	req.Header.Set("Content-Encoding", "zstd")
 	c.applyAuth(req)
 
-	resp, err := retryDo(c.httpClient, req, c.maxAttempts)
+	resp, err := retryDo(c.httpClient, req, c.retry)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	c.cacheServerMetadata(resp)
+	if err := c.cacheServerMetadata(resp); err != nil {
+		return err
+	}
 
 	body, readErr := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
 	if readErr != nil {
@@ -736,7 +972,7 @@ func (c *Client) PushObjectsPack(ctx context.Context, objects []ObjectRecord) er
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		if re := tryParseRemoteError(body); re != nil {
+		if re := tryParseRemoteError(resp.StatusCode, body); re != nil {
 			if isPackUploadUnsupportedResponse(resp.StatusCode, re.Error()) {
 				return fmt.Errorf("%w: %v", ErrPackUploadUnsupported, re)
 			}
@@ -749,14 +985,35 @@ func (c *Client) PushObjectsPack(ctx context.Context, objects []ObjectRecord) er
 		if isPackUploadUnsupportedResponse(resp.StatusCode, msg) {
 			return fmt.Errorf("%w: remote request failed (%s %s): %s", ErrPackUploadUnsupported, req.Method, req.URL.Path, msg)
 		}
-		return fmt.Errorf("remote request failed (%s %s): %s", req.Method, req.URL.Path, msg)
+		return requestFailed(req, resp.StatusCode, msg)
 	}
 
+	c.progress.uploaded(len(objects), compressedSize)
+	c.stats.sent(objects)
 	return nil
 }
 
+// encodeCompressedPack writes objects to w as a zstd-compressed pack stream.
+func encodeCompressedPack(w io.Writer, objects []ObjectRecord, bases map[object.Hash]ThinPackBase) error {
+	enc, err := newZstdWriter(w)
+	if err != nil {
+		return err
+	}
+	if err := EncodeThinPackTransport(enc, objects, bases); err != nil {
+		enc.Close()
+		return err
+	}
+	return enc.Close()
+}
+
 // UpdateRefs applies atomic CAS updates on the remote refs.
 func (c *Client) UpdateRefs(ctx context.Context, updates []RefUpdate) (map[string]object.Hash, error) {
+	if c.local != nil {
+		if len(updates) == 0 {
+			return nil, fmt.Errorf("at least one ref update is required")
+		}
+		return c.local.updateRefs(updates)
+	}
 	if len(updates) == 0 {
 		return nil, fmt.Errorf("at least one ref update is required")
 	}
@@ -805,6 +1062,9 @@ func (c *Client) UpdateRefs(ctx context.Context, updates []RefUpdate) (map[strin
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	// Every retry of this request carries the same key, so an update the
+	// server applied before a timeout is not applied twice.
+	req.Header.Set(headerIdempotencyKey, rand.Text())
 
 	body, err := c.doWithLimit(req, http.StatusOK, 1<<20, "application/json")
 	if err != nil {
@@ -826,26 +1086,28 @@ func (c *Client) UpdateRefs(ctx context.Context, updates []RefUpdate) (map[strin
 
 func (c *Client) doWithLimit(req *http.Request, expectedStatus int, maxBytes int64, expectedContentType string) ([]byte, error) {
 	c.applyAuth(req)
-	resp, err := retryDo(c.httpClient, req, c.maxAttempts)
+	resp, err := retryDo(c.httpClient, req, c.retry)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	c.cacheServerMetadata(resp)
+	if err := c.cacheServerMetadata(resp); err != nil {
+		return nil, err
+	}
 
-	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+	body, readErr := io.ReadAll(io.LimitReader(c.progress.reader(resp.Body), maxBytes))
 	if readErr != nil {
 		return nil, readErr
 	}
 	if resp.StatusCode != expectedStatus {
-		if re := tryParseRemoteError(body); re != nil {
+		if re := tryParseRemoteError(resp.StatusCode, body); re != nil {
 			return nil, re
 		}
 		msg := strings.TrimSpace(string(body))
 		if msg == "" {
 			msg = http.StatusText(resp.StatusCode)
 		}
-		return nil, fmt.Errorf("remote request failed (%s %s): %s", req.Method, req.URL.Path, msg)
+		return nil, requestFailed(req, resp.StatusCode, msg)
 	}
 
 	// Validate content type on success responses before returning body.
@@ -869,6 +1131,12 @@ func (c *Client) applyAuth(req *http.Request) {
 	req.Header.Set(headerProtocol, ProtocolVersion)
 	req.Header.Set(headerCapabilities, ClientCapabilities)
 
+	if c.oauth != nil {
+		if token := c.oauth.bearer(req.Context(), c.httpClient); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return
+		}
+	}
 	if strings.TrimSpace(c.token) != "" {
 		req.Header.Set("Authorization", "Bearer "+c.token)
 		return
@@ -2,6 +2,7 @@ package remote
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -258,8 +259,8 @@ func TestNewClientWithOptionsTimeout(t *testing.T) {
 	if client.httpClient.Timeout != 120*time.Second {
 		t.Fatalf("timeout = %v, want 120s", client.httpClient.Timeout)
 	}
-	if client.maxAttempts != 5 {
-		t.Fatalf("maxAttempts = %d, want 5", client.maxAttempts)
+	if client.retry.MaxAttempts != 5 {
+		t.Fatalf("maxAttempts = %d, want 5", client.retry.MaxAttempts)
 	}
 }
 
@@ -271,8 +272,8 @@ func TestNewClientWithOptionsDefaults(t *testing.T) {
 	if client.httpClient.Timeout != 60*time.Second {
 		t.Fatalf("timeout = %v, want 60s", client.httpClient.Timeout)
 	}
-	if client.maxAttempts != 3 {
-		t.Fatalf("maxAttempts = %d, want 3", client.maxAttempts)
+	if client.retry.MaxAttempts != 3 {
+		t.Fatalf("maxAttempts = %d, want 3", client.retry.MaxAttempts)
 	}
 }
 
@@ -593,3 +594,99 @@ func TestClientCachesServerLimits(t *testing.T) {
 		t.Fatalf("MaxPayload = %d, want 10000000", limits.MaxPayload)
 	}
 }
+
+func TestUpdateRefsReusesIdempotencyKeyAcrossRetries(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var keys []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		// The first attempt of each update times out after being applied.
+		if len(keys)%2 == 1 {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"updated":{"heads/main":"` + strings.Repeat("a", 64) + `"}}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClientWithOptions(ts.URL+"/graft/alice/repo", ClientOptions{Retry: RetryPolicy{InitialBackoff: time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newHash := object.Hash(strings.Repeat("a", 64))
+	for range 2 {
+		if _, err := client.UpdateRefs(t.Context(), []RefUpdate{{Name: "heads/main", New: &newHash}}); err != nil {
+			t.Fatalf("UpdateRefs: %v", err)
+		}
+	}
+
+	if len(keys) != 4 {
+		t.Fatalf("requests = %d, want 4", len(keys))
+	}
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("retry keys = %q, %q; want the same non-empty key", keys[0], keys[1])
+	}
+	if keys[2] != keys[3] || keys[2] == keys[0] {
+		t.Fatalf("second update keys = %q, %q; want a new key shared by its retry", keys[2], keys[3])
+	}
+}
+
+func TestUpdateRefsSurfacesPerRefRejections(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"code":"ref_update_rejected","error":"ref update rejected","rejected":[` +
+			`{"name":"heads/main","code":"hook_declined","reason":"pre-receive: commits must be signed"},` +
+			`{"name":"tags/v1","reason":"tags are immutable"}]}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newHash := object.Hash(strings.Repeat("a", 64))
+	_, err = client.UpdateRefs(t.Context(), []RefUpdate{{Name: "heads/main", New: &newHash}, {Name: "tags/v1", New: &newHash}})
+	if err == nil {
+		t.Fatal("UpdateRefs succeeded, want rejection")
+	}
+	rejected := RefRejections(err)
+	if len(rejected) != 2 || rejected[0].Name != "heads/main" || rejected[0].Code != "hook_declined" || rejected[1].Reason != "tags are immutable" {
+		t.Fatalf("RefRejections = %+v", rejected)
+	}
+	want := "ref update rejected (ref_update_rejected): heads/main: pre-receive: commits must be signed; tags/v1: tags are immutable"
+	if err.Error() != want {
+		t.Fatalf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestListRefsUnauthorizedWrapsErrAuthRequired(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"plain 401", http.StatusUnauthorized, "missing token"},
+		{"json 403", http.StatusForbidden, `{"code":"denied","error":"repository is private"}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, tt.body, tt.status)
+			}))
+			defer ts.Close()
+
+			client, err := NewClient(ts.URL + "/graft/alice/repo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.ListRefs(t.Context())
+			if !errors.Is(err, ErrAuthRequired) {
+				t.Fatalf("ListRefs error = %v, want ErrAuthRequired", err)
+			}
+		})
+	}
+}
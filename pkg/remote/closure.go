@@ -0,0 +1,285 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+const (
+	// DefaultClosureConcurrency is how many requests the closure walk keeps
+	// in flight.
+	DefaultClosureConcurrency = 8
+
+	// closureBatchWants is the most wants sent in one closure batch request.
+	closureBatchWants = 256
+)
+
+// closureItem is an object the closure walk must have locally. History
+// items (roots, commit parents and tag targets) may be commits, so they are
+// fetched alone: a batch want for a commit would pull in its whole history,
+// ignoring shallow boundaries.
+type closureItem struct {
+	hash    object.Hash
+	history bool
+}
+
+// closureWalk walks the object graph from the fetch roots one level at a
+// time and fetches whatever the negotiation left missing. Missing content
+// objects of a level are requested in batches, whose responses also carry
+// the objects below them, and missing history objects with GetObject; up
+// to workers requests run at once. No haves are sent with the batches: an
+// object being present locally says nothing about its closure.
+type closureWalk struct {
+	ctx     context.Context
+	c       *Client
+	store   *object.Store
+	shallow *ShallowState // nil walks the full history
+	recv    *receiveProgress
+	bases   object.ThinPackBaseLookup
+	workers int
+
+	mu       sync.Mutex
+	written  int
+	received map[object.Hash]struct{}
+}
+
+// ensureGraphClosure fetches every object reachable from roots that is not
+// yet in store.
+func ensureGraphClosure(ctx context.Context, c *Client, store *object.Store, roots []object.Hash, cfg FetchConfig, recv *receiveProgress) (int, error) {
+	return ensureGraphClosureShallow(ctx, c, store, roots, nil, cfg, recv)
+}
+
+// ensureGraphClosureShallow walks the object graph from roots and fetches
+// any missing objects, but stops at shallow boundaries instead of trying
+// to fetch parent commits beyond the shallow depth. A nil shallow state
+// walks the full history.
+func ensureGraphClosureShallow(ctx context.Context, c *Client, store *object.Store, roots []object.Hash, shallow *ShallowState, cfg FetchConfig, recv *receiveProgress) (int, error) {
+	w := &closureWalk{
+		ctx:      ctx,
+		c:        c,
+		store:    store,
+		shallow:  shallow,
+		recv:     recv,
+		bases:    StoreThinPackBases(store),
+		workers:  max(cfg.ClosureConcurrency, 1),
+		received: make(map[object.Hash]struct{}),
+	}
+	err := w.run(roots)
+	return w.written, err
+}
+
+func (w *closureWalk) run(roots []object.Hash) error {
+	seen := make(map[object.Hash]struct{}, len(roots))
+	level := make([]closureItem, 0, len(roots))
+	for _, h := range roots {
+		level = append(level, closureItem{hash: h, history: true})
+	}
+
+	for len(level) > 0 {
+		items := make([]object.Hash, 0, len(level))
+		var content, history []object.Hash
+		for _, it := range level {
+			if it.hash == "" {
+				continue
+			}
+			if _, ok := seen[it.hash]; ok {
+				continue
+			}
+			seen[it.hash] = struct{}{}
+			items = append(items, it.hash)
+			// A missing shallow boundary is expected; skip fetching it.
+			if w.store.Has(it.hash) || w.isBoundary(it.hash) {
+				continue
+			}
+			if it.history {
+				history = append(history, it.hash)
+			} else {
+				content = append(content, it.hash)
+			}
+		}
+
+		if err := w.fetch(content, history); err != nil {
+			return err
+		}
+
+		var next []closureItem
+		for _, h := range items {
+			refs, err := w.expand(h)
+			if err != nil {
+				return err
+			}
+			next = append(next, refs...)
+		}
+		level = next
+	}
+	return nil
+}
+
+func (w *closureWalk) isBoundary(h object.Hash) bool {
+	return w.shallow != nil && w.shallow.IsShallow(h)
+}
+
+// expand reads h from the store and returns the objects it references.
+func (w *closureWalk) expand(h object.Hash) ([]closureItem, error) {
+	objType, data, err := w.store.Read(h)
+	if err != nil {
+		// If the object is a shallow boundary, we may not have it locally.
+		if w.isBoundary(h) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read object %s: %w", h, err)
+	}
+
+	switch objType {
+	case object.TypeCommit:
+		commit, err := object.UnmarshalCommit(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse object %s (%s): %w", h, objType, err)
+		}
+		refs := make([]closureItem, 0, 1+len(commit.Parents))
+		refs = append(refs, closureItem{hash: commit.TreeHash})
+		// A boundary commit that is present locally has no fetchable
+		// history, so its parents are skipped entirely.
+		if w.isBoundary(h) {
+			return refs, nil
+		}
+		for _, p := range commit.Parents {
+			if !w.isBoundary(p) {
+				refs = append(refs, closureItem{hash: p, history: true})
+			}
+		}
+		return refs, nil
+	case object.TypeTag:
+		tag, err := object.UnmarshalTag(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse object %s (%s): %w", h, objType, err)
+		}
+		return []closureItem{{hash: tag.TargetHash, history: true}}, nil
+	}
+
+	hashes, err := referencedHashes(objType, data)
+	if err != nil {
+		return nil, fmt.Errorf("parse object %s (%s): %w", h, objType, err)
+	}
+	refs := make([]closureItem, len(hashes))
+	for i, ref := range hashes {
+		refs[i] = closureItem{hash: ref}
+	}
+	return refs, nil
+}
+
+// fetch retrieves content in batches of closureBatchWants and each history
+// object alone, running up to w.workers requests concurrently.
+func (w *closureWalk) fetch(content, history []object.Hash) error {
+	jobs := make([]func(context.Context) error, 0, len(history)+len(content)/closureBatchWants+1)
+	for len(content) > 0 {
+		chunk := content[:min(len(content), closureBatchWants)]
+		content = content[len(chunk):]
+		jobs = append(jobs, func(ctx context.Context) error { return w.fetchBatch(ctx, chunk) })
+	}
+	for _, h := range history {
+		jobs = append(jobs, func(ctx context.Context) error { return w.fetchOne(ctx, h) })
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(w.ctx)
+	defer cancel()
+
+	queue := make(chan func(context.Context) error)
+	var workers sync.WaitGroup
+	var setErr sync.Once
+	var firstErr error
+
+	setFirstErr := func(err error) {
+		setErr.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for worker := 0; worker < min(w.workers, len(jobs)); worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job, ok := <-queue:
+					if !ok {
+						return
+					}
+					if err := job(ctx); err != nil {
+						setFirstErr(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+enqueueLoop:
+	for _, job := range jobs {
+		select {
+		case <-ctx.Done():
+			break enqueueLoop
+		case queue <- job:
+		}
+	}
+	close(queue)
+	workers.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return w.ctx.Err()
+}
+
+// fetchBatch requests wants in one batch and falls back to GetObject for
+// any the response did not include, or for all of them if the batch
+// request fails.
+func (w *closureWalk) fetchBatch(ctx context.Context, wants []object.Hash) error {
+	if _, err := w.c.BatchObjectsStream(ctx, wants, nil, 0, nil, w.bases, w.write); err != nil && ctx.Err() != nil {
+		return err
+	}
+	for _, h := range wants {
+		if w.store.Has(h) {
+			continue
+		}
+		if err := w.fetchOne(ctx, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *closureWalk) fetchOne(ctx context.Context, h object.Hash) error {
+	obj, err := w.c.GetObject(ctx, h)
+	if err != nil {
+		return err
+	}
+	return w.write(obj)
+}
+
+// write stores obj and counts it once, however many concurrent responses
+// carry it.
+func (w *closureWalk) write(obj ObjectRecord) error {
+	n, err := writeVerifiedObject(w.store, obj)
+	if err != nil || n == 0 {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.received[obj.Hash]; ok {
+		return nil
+	}
+	w.received[obj.Hash] = struct{}{}
+	w.written++
+	w.recv.add(obj)
+	return nil
+}
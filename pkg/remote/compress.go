@@ -65,6 +65,12 @@ func decompressZstdStream(dst io.Writer, src io.Reader) error {
 	return err
 }
 
+// newZstdWriter wraps an io.Writer with zstd compression. Close flushes the
+// final frame.
+func newZstdWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
 // newZstdReader wraps an io.Reader with zstd decompression.
 func newZstdReader(r io.Reader) (io.ReadCloser, error) {
 	dec, err := zstd.NewReader(r)
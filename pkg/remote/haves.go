@@ -0,0 +1,189 @@
+package remote
+
+import (
+	"container/heap"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+const (
+	// haveSampleDense is how many of the newest local commits are offered
+	// one by one before the sampling interval starts doubling.
+	haveSampleDense = 16
+	// haveSampleWalkLimit bounds how many local commits the sampler reads,
+	// so negotiation stays cheap in very large histories.
+	haveSampleWalkLimit = 4096
+)
+
+// haveNegotiator chooses the haves a fetch advertises in each batch request.
+// Within the per-request cap it offers, most useful first:
+//
+//  1. the haves the caller passed in, normally the local ref tips;
+//  2. commits received earlier in this fetch, newest first, which mark
+//     where the previous truncated response stopped;
+//  3. commits sampled from local history: the newest few, then ones at
+//     doubling distances, so a server can find the merge base of a long
+//     history from a handful of hashes;
+//  4. every other known object, such as trees and blobs received so far.
+type haveNegotiator struct {
+	store *object.Store
+
+	tips     []object.Hash
+	received []object.Hash // commits received during this fetch
+	other    []object.Hash // non-commit objects received during this fetch
+	known    map[object.Hash]struct{}
+
+	sampled     []object.Hash
+	sampledDone bool
+}
+
+func newHaveNegotiator(store *object.Store, haves []object.Hash) *haveNegotiator {
+	n := &haveNegotiator{store: store, known: make(map[object.Hash]struct{}, len(haves))}
+	for _, h := range uniqueHashes(haves) {
+		n.known[h] = struct{}{}
+		n.tips = append(n.tips, h)
+	}
+	return n
+}
+
+// add records an object received during the fetch.
+func (n *haveNegotiator) add(h object.Hash, typ object.ObjectType) {
+	h = object.Hash(strings.TrimSpace(string(h)))
+	if h == "" {
+		return
+	}
+	if _, ok := n.known[h]; ok {
+		return
+	}
+	n.known[h] = struct{}{}
+	if typ == object.TypeCommit {
+		n.received = append(n.received, h)
+	} else {
+		n.other = append(n.other, h)
+	}
+}
+
+// selectHaves returns up to max haves in priority order; max <= 0 means no
+// cap.
+func (n *haveNegotiator) selectHaves(max int) []object.Hash {
+	if !n.sampledDone {
+		n.sampled = sampleLocalHistory(n.store, n.tips, haveSampleWalkLimit)
+		n.sampledDone = true
+	}
+	capacity := len(n.tips) + len(n.received) + len(n.sampled) + len(n.other)
+	if max > 0 {
+		capacity = min(capacity, max)
+	}
+	out := make([]object.Hash, 0, capacity)
+	seen := make(map[object.Hash]struct{}, capacity)
+	full := func() bool { return max > 0 && len(out) >= max }
+	push := func(h object.Hash) {
+		if _, ok := seen[h]; !ok {
+			seen[h] = struct{}{}
+			out = append(out, h)
+		}
+	}
+
+	for _, h := range n.tips {
+		if full() {
+			return out
+		}
+		push(h)
+	}
+	for i := len(n.received) - 1; i >= 0; i-- {
+		if full() {
+			return out
+		}
+		push(n.received[i])
+	}
+	for _, h := range n.sampled {
+		if full() {
+			return out
+		}
+		push(h)
+	}
+	for _, h := range n.other {
+		if full() {
+			return out
+		}
+		push(h)
+	}
+	return out
+}
+
+// sampleLocalHistory walks the commits behind tips newest first and returns
+// the first haveSampleDense of them, then commits at doubling distances.
+// Tips that are not commits (annotated tags, missing objects) are skipped;
+// at most walkLimit commits are read.
+func sampleLocalHistory(store *object.Store, tips []object.Hash, walkLimit int) []object.Hash {
+	if store == nil {
+		return nil
+	}
+	queue := &commitTimeQueue{}
+	visited := make(map[object.Hash]struct{})
+	enqueue := func(h object.Hash) {
+		if _, ok := visited[h]; ok {
+			return
+		}
+		visited[h] = struct{}{}
+		c, err := store.ReadCommit(h)
+		if err != nil {
+			return
+		}
+		heap.Push(queue, commitTimeEntry{hash: h, when: commitTime(c), parents: c.Parents})
+	}
+	for _, h := range tips {
+		enqueue(h)
+	}
+
+	var sampled []object.Hash
+	next, step := 0, 1
+	for walked := 0; queue.Len() > 0 && walked < walkLimit; walked++ {
+		e := heap.Pop(queue).(commitTimeEntry)
+		if walked == next {
+			sampled = append(sampled, e.hash)
+			if walked >= haveSampleDense-1 {
+				step *= 2
+			}
+			next += step
+		}
+		for _, p := range e.parents {
+			enqueue(p)
+		}
+	}
+	return sampled
+}
+
+func commitTime(c *object.CommitObj) int64 {
+	if c.CommitterTimestamp != 0 {
+		return c.CommitterTimestamp
+	}
+	return c.Timestamp
+}
+
+type commitTimeEntry struct {
+	hash    object.Hash
+	when    int64
+	parents []object.Hash
+}
+
+// commitTimeQueue is a max-heap of commits by time, ties broken by hash so
+// the walk order is deterministic.
+type commitTimeQueue []commitTimeEntry
+
+func (q commitTimeQueue) Len() int { return len(q) }
+func (q commitTimeQueue) Less(i, j int) bool {
+	if q[i].when != q[j].when {
+		return q[i].when > q[j].when
+	}
+	return q[i].hash < q[j].hash
+}
+func (q commitTimeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitTimeQueue) Push(x any)   { *q = append(*q, x.(commitTimeEntry)) }
+func (q *commitTimeQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,90 @@
+package remote
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// writeLinearHistory writes n commits, each the parent of the next, and
+// returns them oldest first.
+func writeLinearHistory(t *testing.T, store *object.Store, n int) []object.Hash {
+	t.Helper()
+	tree, err := store.WriteTree(&object.TreeObj{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := make([]object.Hash, 0, n)
+	for i := range n {
+		c := &object.CommitObj{TreeHash: tree, Author: "tester", Timestamp: int64(1700000000 + i), Message: fmt.Sprintf("c%d\n", i)}
+		if i > 0 {
+			c.Parents = []object.Hash{commits[i-1]}
+		}
+		h, err := store.WriteCommit(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, h)
+	}
+	return commits
+}
+
+func TestSampleLocalHistoryDenseThenDoubling(t *testing.T) {
+	store := object.NewStore(t.TempDir())
+	commits := writeLinearHistory(t, store, 100)
+	tip := commits[len(commits)-1]
+
+	sampled := sampleLocalHistory(store, []object.Hash{tip}, haveSampleWalkLimit)
+	// Distances from the tip: 0..15, then gaps of 2, 4, 8, 16 and 32.
+	var want []object.Hash
+	for _, d := range []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 17, 21, 29, 45, 77} {
+		want = append(want, commits[len(commits)-1-d])
+	}
+	if !slices.Equal(sampled, want) {
+		t.Fatalf("sampled %d commits, want %d at dense-then-doubling distances", len(sampled), len(want))
+	}
+
+	if got := sampleLocalHistory(store, []object.Hash{tip}, 5); len(got) != 5 {
+		t.Fatalf("walk limit 5 sampled %d commits", len(got))
+	}
+	missing := object.Hash(strings.Repeat("f", 64))
+	if got := sampleLocalHistory(store, []object.Hash{missing}, haveSampleWalkLimit); len(got) != 0 {
+		t.Fatalf("sampling from a missing tip = %v", got)
+	}
+}
+
+func TestHaveNegotiatorKeepsTipsAheadOfReceivedObjects(t *testing.T) {
+	store := object.NewStore(t.TempDir())
+	commits := writeLinearHistory(t, store, 40)
+	tip := commits[len(commits)-1]
+	n := newHaveNegotiator(store, []object.Hash{tip, tip})
+
+	var blobs []object.Hash
+	for i := range 50 {
+		h := object.Hash(fmt.Sprintf("%064x", i+1))
+		blobs = append(blobs, h)
+		n.add(h, object.TypeBlob)
+	}
+	first := object.Hash(strings.Repeat("a", 64))
+	second := object.Hash(strings.Repeat("b", 64))
+	n.add(first, object.TypeCommit)
+	n.add(second, object.TypeCommit)
+	n.add(tip, object.TypeCommit) // already known; stays a tip
+
+	got := n.selectHaves(5)
+	want := []object.Hash{tip, second, first, commits[len(commits)-2], commits[len(commits)-3]}
+	if !slices.Equal(got, want) {
+		t.Fatalf("selectHaves(5) = %v, want %v", got, want)
+	}
+
+	all := n.selectHaves(0)
+	if len(all) != 1+2+len(sampleLocalHistory(store, []object.Hash{tip}, haveSampleWalkLimit))-1+len(blobs) {
+		t.Fatalf("selectHaves(0) returned %d haves", len(all))
+	}
+	if all[len(all)-1] != blobs[len(blobs)-1] {
+		t.Fatalf("received non-commit objects should come last, got %s", all[len(all)-1])
+	}
+}
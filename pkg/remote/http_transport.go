@@ -0,0 +1,127 @@
+package remote
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/userconfig"
+)
+
+// resolveHTTPOptions fills the proxy, TLS, retry and throttling fields opts
+// leaves empty. Proxy and TLS settings come first from GRAFT_HTTP_PROXY,
+// GRAFT_CA_FILE, GRAFT_CLIENT_CERT and GRAFT_CLIENT_KEY, then from the http
+// section of ~/.graftconfig; retry and throttling settings come from the
+// config only. A
+// client certificate and its key always come from the same source.
+func resolveHTTPOptions(opts ClientOptions, cfg *userconfig.Config) (ClientOptions, error) {
+	if opts.Proxy == "" {
+		opts.Proxy = strings.TrimSpace(os.Getenv("GRAFT_HTTP_PROXY"))
+	}
+	if opts.CAFile == "" {
+		opts.CAFile = strings.TrimSpace(os.Getenv("GRAFT_CA_FILE"))
+	}
+	if opts.ClientCert == "" && opts.ClientKey == "" {
+		opts.ClientCert = strings.TrimSpace(os.Getenv("GRAFT_CLIENT_CERT"))
+		opts.ClientKey = strings.TrimSpace(os.Getenv("GRAFT_CLIENT_KEY"))
+	}
+	if cfg == nil {
+		return opts, nil
+	}
+	if opts.Proxy == "" {
+		opts.Proxy = cfg.HTTP.Proxy
+	}
+	if opts.CAFile == "" {
+		opts.CAFile = cfg.HTTP.CAFile
+	}
+	if opts.ClientCert == "" && opts.ClientKey == "" {
+		opts.ClientCert = cfg.HTTP.ClientCert
+		opts.ClientKey = cfg.HTTP.ClientKey
+	}
+
+	if opts.Retry.MaxAttempts <= 0 {
+		opts.Retry.MaxAttempts = cfg.HTTP.RetryMaxAttempts
+	}
+	if opts.Retry.InitialBackoff <= 0 && cfg.HTTP.RetryBackoff != "" {
+		d, err := time.ParseDuration(cfg.HTTP.RetryBackoff)
+		if err != nil {
+			return opts, fmt.Errorf("parse http.retry_backoff: %w", err)
+		}
+		opts.Retry.InitialBackoff = d
+	}
+	if opts.Retry.MaxBackoff <= 0 && cfg.HTTP.RetryMaxBackoff != "" {
+		d, err := time.ParseDuration(cfg.HTTP.RetryMaxBackoff)
+		if err != nil {
+			return opts, fmt.Errorf("parse http.retry_max_backoff: %w", err)
+		}
+		opts.Retry.MaxBackoff = d
+	}
+	if len(opts.Retry.RetryableStatus) == 0 {
+		opts.Retry.RetryableStatus = cfg.HTTP.RetryStatus
+	}
+
+	if opts.MaxUploadRate <= 0 {
+		opts.MaxUploadRate = cfg.HTTP.MaxUploadRate
+	}
+	if opts.MaxDownloadRate <= 0 {
+		opts.MaxDownloadRate = cfg.HTTP.MaxDownloadRate
+	}
+	if opts.MaxConcurrentRequests <= 0 {
+		opts.MaxConcurrentRequests = cfg.HTTP.MaxConcurrentRequests
+	}
+	return opts, nil
+}
+
+// newHTTPTransport returns a transport honoring the proxy and TLS settings
+// in opts. Without an explicit proxy it uses HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY from the environment.
+func newHTTPTransport(opts ClientOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy URL: %w", err)
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("proxy URL must include scheme and host: %q", opts.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if opts.CAFile == "" && opts.ClientCert == "" && opts.ClientKey == "" {
+		return transport, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("read CA file: no PEM certificates in %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
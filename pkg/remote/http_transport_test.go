@@ -0,0 +1,243 @@
+package remote
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/userconfig"
+)
+
+// isolateHTTPConfig keeps the developer's environment and ~/.graftconfig out
+// of a test.
+func isolateHTTPConfig(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	for _, key := range []string{"GRAFT_TOKEN", "GRAFT_HTTP_PROXY", "GRAFT_CA_FILE", "GRAFT_CLIENT_CERT", "GRAFT_CLIENT_KEY"} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestClientRoutesRequestsThroughConfiguredProxy(t *testing.T) {
+	isolateHTTPConfig(t)
+
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"refs":{}}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewClientWithOptions("http://orchard.invalid/graft/alice/repo", ClientOptions{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListRefs(t.Context()); err != nil {
+		t.Fatalf("ListRefs: %v", err)
+	}
+	if proxiedHost != "orchard.invalid" {
+		t.Fatalf("proxied host = %q, want orchard.invalid", proxiedHost)
+	}
+}
+
+func TestClientUsesCAFileAndClientCertificate(t *testing.T) {
+	isolateHTTPConfig(t)
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeTestClientCertificate(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"refs":{}}`))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the CA bundle the server certificate is untrusted.
+	client, err := NewClientWithOptions(ts.URL+"/graft/alice/repo", ClientOptions{MaxAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListRefs(t.Context()); err == nil {
+		t.Fatal("expected certificate verification error, got nil")
+	}
+
+	// Without the client certificate the handshake is rejected.
+	client, err = NewClientWithOptions(ts.URL+"/graft/alice/repo", ClientOptions{CAFile: caFile, MaxAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListRefs(t.Context()); err == nil {
+		t.Fatal("expected client certificate error, got nil")
+	}
+
+	t.Setenv("GRAFT_CA_FILE", caFile)
+	t.Setenv("GRAFT_CLIENT_CERT", certFile)
+	t.Setenv("GRAFT_CLIENT_KEY", keyFile)
+	client, err = NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListRefs(t.Context()); err != nil {
+		t.Fatalf("ListRefs: %v", err)
+	}
+}
+
+func TestNewClientRejectsInvalidHTTPOptions(t *testing.T) {
+	isolateHTTPConfig(t)
+	dir := t.TempDir()
+	certFile, _, _ := writeTestClientCertificate(t, dir)
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts ClientOptions
+		want string
+	}{
+		{name: "proxy without host", opts: ClientOptions{Proxy: "proxy.example.com:3128"}, want: "proxy URL"},
+		{name: "CA file without certificates", opts: ClientOptions{CAFile: notPEM}, want: "no PEM certificates"},
+		{name: "missing CA file", opts: ClientOptions{CAFile: filepath.Join(dir, "missing.pem")}, want: "read CA file"},
+		{name: "certificate without key", opts: ClientOptions{ClientCert: certFile}, want: "must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClientWithOptions("https://orchard.dev/graft/alice/repo", tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("NewClientWithOptions error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveHTTPOptionsPrecedence(t *testing.T) {
+	isolateHTTPConfig(t)
+	cfg := &userconfig.Config{HTTP: userconfig.HTTPConfig{
+		Proxy:      "http://config-proxy:3128",
+		CAFile:     "/config/ca.pem",
+		ClientCert: "/config/cert.pem",
+		ClientKey:  "/config/key.pem",
+	}}
+
+	got, err := resolveHTTPOptions(ClientOptions{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Proxy != cfg.HTTP.Proxy || got.CAFile != cfg.HTTP.CAFile || got.ClientCert != cfg.HTTP.ClientCert || got.ClientKey != cfg.HTTP.ClientKey {
+		t.Fatalf("config fallback = %+v", got)
+	}
+
+	t.Setenv("GRAFT_HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("GRAFT_CLIENT_CERT", "/env/cert.pem")
+	t.Setenv("GRAFT_CLIENT_KEY", "/env/key.pem")
+	got, err = resolveHTTPOptions(ClientOptions{CAFile: "/opts/ca.pem"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Proxy != "http://env-proxy:3128" {
+		t.Fatalf("Proxy = %q, want env value", got.Proxy)
+	}
+	if got.CAFile != "/opts/ca.pem" {
+		t.Fatalf("CAFile = %q, want option value", got.CAFile)
+	}
+	if got.ClientCert != "/env/cert.pem" || got.ClientKey != "/env/key.pem" {
+		t.Fatalf("client certificate = %q/%q, want env values", got.ClientCert, got.ClientKey)
+	}
+
+	// A client key is never paired with a certificate from another source.
+	got, err = resolveHTTPOptions(ClientOptions{ClientCert: "/opts/cert.pem"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ClientKey != "" {
+		t.Fatalf("ClientKey = %q, want empty", got.ClientKey)
+	}
+}
+
+func TestResolveHTTPOptionsRetryPolicy(t *testing.T) {
+	isolateHTTPConfig(t)
+	cfg := &userconfig.Config{HTTP: userconfig.HTTPConfig{
+		RetryMaxAttempts: 6,
+		RetryBackoff:     "250ms",
+		RetryMaxBackoff:  "5s",
+		RetryStatus:      []int{502, 503},
+	}}
+
+	got, err := resolveHTTPOptions(ClientOptions{Retry: RetryPolicy{MaxAttempts: 2}}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RetryPolicy{MaxAttempts: 2, InitialBackoff: 250 * time.Millisecond, MaxBackoff: 5 * time.Second, RetryableStatus: []int{502, 503}}
+	if got.Retry.MaxAttempts != want.MaxAttempts || got.Retry.InitialBackoff != want.InitialBackoff || got.Retry.MaxBackoff != want.MaxBackoff || len(got.Retry.RetryableStatus) != 2 {
+		t.Fatalf("Retry = %+v, want %+v", got.Retry, want)
+	}
+
+	cfg.HTTP.RetryBackoff = "soon"
+	if _, err := resolveHTTPOptions(ClientOptions{}, cfg); err == nil || !strings.Contains(err.Error(), "retry_backoff") {
+		t.Fatalf("resolveHTTPOptions error = %v, want retry_backoff parse error", err)
+	}
+}
+
+// writeTestClientCertificate writes a self-signed client certificate and its
+// key to dir as PEM files.
+func writeTestClientCertificate(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "graft-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
@@ -0,0 +1,413 @@
+package remote
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/lockfile"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+const localRefLockWait = 2 * time.Second
+
+// LocalRepoDir resolves raw to the metadata directory of a Graft repository
+// on this machine. raw may be a file:// URL or a filesystem path naming
+// either a working tree (containing .graft/) or a bare Graft directory
+// holding HEAD, objects/ and refs/ directly. It reports false when raw does
+// not name an existing local Graft repository.
+func LocalRepoDir(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+	p := raw
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil || !strings.EqualFold(u.Scheme, "file") || (u.Host != "" && u.Host != "localhost") {
+			return "", false
+		}
+		p = u.Path
+	} else if strings.HasPrefix(raw, "git@") {
+		return "", false
+	}
+	if p == "" {
+		return "", false
+	}
+	abs, err := filepath.Abs(filepath.FromSlash(p))
+	if err != nil {
+		return "", false
+	}
+	if info, err := os.Stat(filepath.Join(abs, ".graft")); err == nil && info.IsDir() {
+		return filepath.Join(abs, ".graft"), true
+	}
+	if isBareGraftDir(abs) {
+		return abs, true
+	}
+	return "", false
+}
+
+// isBareGraftDir reports whether dir looks like a Graft metadata directory.
+// Git directories share the HEAD/objects/refs layout but always carry a
+// plain "config" file, which Graft never writes.
+func isBareGraftDir(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || info.IsDir() {
+		return false
+	}
+	for _, sub := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	_, err := os.Stat(filepath.Join(dir, "config"))
+	return os.IsNotExist(err)
+}
+
+// localEndpoint builds the endpoint for a machine-local repository whose
+// metadata lives in graftDir.
+func localEndpoint(raw, graftDir string) Endpoint {
+	root := graftDir
+	if filepath.Base(graftDir) == ".graft" {
+		root = filepath.Dir(graftDir)
+	}
+	name := filepath.Base(root)
+	for _, ext := range []string{".graft", ".got"} {
+		if trimmed := strings.TrimSuffix(name, ext); trimmed != "" {
+			name = trimmed
+		}
+	}
+	return Endpoint{
+		Raw:      raw,
+		BaseURL:  (&url.URL{Scheme: "file", Path: filepath.ToSlash(root)}).String(),
+		Repo:     name,
+		LocalDir: graftDir,
+	}
+}
+
+// localTransport serves the Graft protocol directly from a repository on
+// this machine, reading and writing its object store and ref files without
+// going through HTTP.
+type localTransport struct {
+	dir   string
+	store *object.Store
+}
+
+func newLocalTransport(graftDir string) *localTransport {
+	return &localTransport{dir: graftDir, store: currentLocalStoreOpener()(graftDir)}
+}
+
+// LocalStoreOpener opens the object store of a machine-local repository
+// given its metadata directory, with that repository's storage settings
+// such as chunking and encryption applied.
+type LocalStoreOpener func(graftDir string) *object.Store
+
+var (
+	localStoreOpenerMu sync.RWMutex
+	localStoreOpener   LocalStoreOpener
+)
+
+// SetLocalStoreOpener installs the function local transports use to open
+// the target repository's object store and returns the previous one.
+// Passing nil restores the default, which opens a plain store.
+func SetLocalStoreOpener(open LocalStoreOpener) LocalStoreOpener {
+	localStoreOpenerMu.Lock()
+	defer localStoreOpenerMu.Unlock()
+	prev := localStoreOpener
+	localStoreOpener = open
+	return prev
+}
+
+func currentLocalStoreOpener() LocalStoreOpener {
+	localStoreOpenerMu.RLock()
+	defer localStoreOpenerMu.RUnlock()
+	if localStoreOpener == nil {
+		return object.NewStore
+	}
+	return localStoreOpener
+}
+
+func (l *localTransport) listRefs() (map[string]object.Hash, error) {
+	root := filepath.Join(l.dir, "refs")
+	refs := make(map[string]object.Hash)
+	for _, ns := range []string{"heads", "tags", "notes"} {
+		err := filepath.WalkDir(filepath.Join(root, ns), func(path string, d os.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			if d.IsDir() || strings.HasSuffix(d.Name(), lockfile.Suffix) {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(rel)
+			h, err := readLocalRef(path)
+			if err != nil {
+				return fmt.Errorf("ref %q: %w", name, err)
+			}
+			if h != "" {
+				refs[name] = h
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("list local refs: %w", err)
+		}
+	}
+	return refs, nil
+}
+
+func readLocalRef(path string) (object.Hash, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	h := object.Hash(strings.TrimSpace(string(data)))
+	if err := ValidateHash(h); err != nil {
+		return "", err
+	}
+	return h, nil
+}
+
+// batchObjects walks the object graph from wants, skipping everything
+// reachable from haves, and returns at most maxObjects records. Shallow
+// options limit the commit depth and report the excluded parents as
+// boundaries; blob filters drop blobs the filter rejects.
+func (l *localTransport) batchObjects(wants, haves []object.Hash, maxObjects int, opts *ShallowFetchOpts) (*BatchShallowResult, error) {
+	roots := uniqueHashes(wants)
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("at least one non-empty want hash is required")
+	}
+	stopSet, err := ReachableSet(l.store, haves)
+	if err != nil {
+		return nil, err
+	}
+
+	var filter *ObjectFilter
+	depth, deepen := 0, 0
+	shallowSet := make(map[object.Hash]struct{})
+	if opts != nil {
+		depth, deepen = opts.Depth, opts.Deepen
+		for _, h := range opts.Shallow {
+			shallowSet[h] = struct{}{}
+		}
+		if strings.TrimSpace(opts.Filter) != "" {
+			if filter, err = ParseObjectFilter(opts.Filter); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// budget is the number of commits still allowed along the current path;
+	// zero means unlimited.
+	type item struct {
+		hash   object.Hash
+		budget int
+	}
+	stack := make([]item, 0, len(roots))
+	for _, h := range roots {
+		stack = append(stack, item{hash: h, budget: depth})
+	}
+
+	seen := make(map[object.Hash]struct{})
+	boundaries := make(map[object.Hash]struct{})
+	result := &BatchShallowResult{}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := seen[it.hash]; ok {
+			continue
+		}
+		if _, stopped := stopSet[it.hash]; stopped {
+			continue
+		}
+		if maxObjects > 0 && len(result.Objects) >= maxObjects {
+			result.Truncated = true
+			break
+		}
+		seen[it.hash] = struct{}{}
+
+		objType, data, err := l.store.Read(it.hash)
+		if err != nil {
+			return nil, fmt.Errorf("read object %s: %w", it.hash, err)
+		}
+		if objType == object.TypeBlob && filter != nil && !filter.AllowsBlob(int64(len(data))) {
+			continue
+		}
+		result.Objects = append(result.Objects, ObjectRecord{Hash: it.hash, Type: objType, Data: data})
+
+		if objType != object.TypeCommit {
+			refs, err := referencedHashes(objType, data)
+			if err != nil {
+				return nil, fmt.Errorf("parse object %s (%s): %w", it.hash, objType, err)
+			}
+			for _, ref := range refs {
+				stack = append(stack, item{hash: ref})
+			}
+			continue
+		}
+
+		commit, err := object.UnmarshalCommit(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse object %s (%s): %w", it.hash, objType, err)
+		}
+		stack = append(stack, item{hash: commit.TreeHash})
+		budget := it.budget
+		if _, ok := shallowSet[it.hash]; ok && deepen > 0 && (budget == 0 || budget > deepen) {
+			budget = deepen
+		}
+		for _, p := range commit.Parents {
+			if budget == 1 {
+				if _, have := stopSet[p]; !have {
+					boundaries[p] = struct{}{}
+				}
+				continue
+			}
+			next := 0
+			if budget > 1 {
+				next = budget - 1
+			}
+			stack = append(stack, item{hash: p, budget: next})
+		}
+	}
+
+	for h := range boundaries {
+		if _, sent := seen[h]; !sent {
+			result.Shallow = append(result.Shallow, h)
+		}
+	}
+	sort.Slice(result.Shallow, func(i, j int) bool { return result.Shallow[i] < result.Shallow[j] })
+	return result, nil
+}
+
+func (l *localTransport) getObject(hash object.Hash) (ObjectRecord, error) {
+	objType, data, err := l.store.Read(hash)
+	if err != nil {
+		return ObjectRecord{}, fmt.Errorf("read object %s: %w", hash, err)
+	}
+	return ObjectRecord{Hash: hash, Type: objType, Data: data}, nil
+}
+
+func (l *localTransport) pushObjects(objects []ObjectRecord) error {
+	for i, obj := range objects {
+		computed := object.HashObject(obj.Type, obj.Data)
+		if provided := object.Hash(strings.TrimSpace(string(obj.Hash))); provided != "" && provided != computed {
+			return fmt.Errorf("push object %d: hash mismatch (provided %s, computed %s)", i, provided, computed)
+		}
+		obj.Hash = computed
+		if _, err := writeVerifiedObject(l.store, obj); err != nil {
+			return fmt.Errorf("push object %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// updateRefs applies updates all-or-nothing: every ref is locked and its
+// expected old value checked before any of them is rewritten.
+func (l *localTransport) updateRefs(updates []RefUpdate) (map[string]object.Hash, error) {
+	checkedOut := l.checkedOutBranch()
+	locks := make([]*lockfile.Lock, 0, len(updates))
+	defer func() {
+		for _, lock := range locks {
+			_ = lock.Release()
+		}
+	}()
+
+	paths := make([]string, len(updates))
+	for i, u := range updates {
+		name := strings.Trim(strings.TrimSpace(u.Name), "/")
+		if name == "" {
+			return nil, fmt.Errorf("ref update name is required")
+		}
+		clean := filepath.ToSlash(filepath.Clean(name))
+		if clean != name || strings.HasPrefix(clean, "../") || !(strings.HasPrefix(clean, "heads/") || strings.HasPrefix(clean, "tags/") || strings.HasPrefix(clean, "notes/")) {
+			return nil, fmt.Errorf("update ref %q: unsupported ref name", u.Name)
+		}
+		if checkedOut != "" && "refs/"+name == checkedOut {
+			return nil, refRejected(name, "ref_checked_out", fmt.Sprintf("refusing to update the branch checked out in %s", filepath.Dir(l.dir)))
+		}
+		if u.New != nil && strings.TrimSpace(string(*u.New)) != "" {
+			if err := ValidateHash(*u.New); err != nil {
+				return nil, fmt.Errorf("update ref %q: %w", name, err)
+			}
+			if !l.store.Has(*u.New) {
+				return nil, fmt.Errorf("update ref %q: object %s not found", name, *u.New)
+			}
+		}
+
+		path := filepath.Join(l.dir, "refs", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("update ref %q: mkdir: %w", name, err)
+		}
+		lock, err := lockfile.Acquire(path, localRefLockWait)
+		if err != nil {
+			return nil, fmt.Errorf("update ref %q: lock: %w", name, err)
+		}
+		locks = append(locks, lock)
+		paths[i] = path
+
+		current, err := readLocalRef(path)
+		if err != nil {
+			return nil, fmt.Errorf("update ref %q: read: %w", name, err)
+		}
+		if u.Old != nil && current != object.Hash(strings.TrimSpace(string(*u.Old))) {
+			return nil, refRejected(name, "ref_conflict", fmt.Sprintf("stale old value (expected %s, found %s)", *u.Old, current))
+		}
+	}
+
+	updated := make(map[string]object.Hash, len(updates))
+	for i, u := range updates {
+		name := strings.Trim(strings.TrimSpace(u.Name), "/")
+		if u.New == nil || strings.TrimSpace(string(*u.New)) == "" {
+			if err := os.Remove(paths[i]); err != nil && !os.IsNotExist(err) {
+				return updated, fmt.Errorf("delete ref %q: %w", name, err)
+			}
+			updated[name] = ""
+			continue
+		}
+		if _, err := locks[i].Write([]byte(string(*u.New) + "\n")); err != nil {
+			return updated, fmt.Errorf("update ref %q: write: %w", name, err)
+		}
+		if err := locks[i].Commit(); err != nil {
+			return updated, fmt.Errorf("update ref %q: commit: %w", name, err)
+		}
+		updated[name] = *u.New
+	}
+	return updated, nil
+}
+
+// refRejected reports a ref update refused because of one ref, in the shape
+// an HTTP server uses for the same refusal.
+func refRejected(name, code, reason string) *RemoteError {
+	return &RemoteError{
+		Code:     code,
+		Message:  "ref update rejected",
+		Rejected: []RefRejection{{Name: name, Code: code, Reason: reason}},
+	}
+}
+
+// checkedOutBranch returns the ref HEAD points at when the repository has a
+// working tree, or "" for bare directories and detached heads.
+func (l *localTransport) checkedOutBranch() string {
+	if filepath.Base(l.dir) != ".graft" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(l.dir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(head, "ref: ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(head, "ref: "))
+}
@@ -0,0 +1,220 @@
+package remote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// newBareLocalRepo lays out a bare Graft directory holding a two-commit
+// history on heads/main and returns the directory with both commit hashes.
+func newBareLocalRepo(t *testing.T) (string, object.Hash, object.Hash) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "mirror.got")
+	if err := os.MkdirAll(filepath.Join(dir, "refs", "heads"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := object.NewStore(dir)
+	var parent object.Hash
+	var hashes []object.Hash
+	for i, content := range []string{"one\n", "two\n"} {
+		blob, err := store.WriteBlob(&object.Blob{Data: []byte(content)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := store.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{{Name: "file.txt", BlobHash: blob}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		commit := &object.CommitObj{TreeHash: tree, Author: "Alice", Timestamp: int64(1700000000 + i), Message: content}
+		if parent != "" {
+			commit.Parents = []object.Hash{parent}
+		}
+		parent, err = store.WriteCommit(commit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, parent)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "refs", "heads", "main"), []byte(string(parent)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, hashes[0], hashes[1]
+}
+
+func TestLocalRepoDirRecognizesGraftRepositories(t *testing.T) {
+	bare, _, _ := newBareLocalRepo(t)
+	worktree := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(worktree, ".graft"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gitDir := t.TempDir()
+	for _, sub := range []string{"objects", "refs"} {
+		if err := os.MkdirAll(filepath.Join(gitDir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"HEAD", "config"} {
+		if err := os.WriteFile(filepath.Join(gitDir, name), []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{raw: bare, want: bare, wantOK: true},
+		{raw: "file://" + filepath.ToSlash(bare), want: bare, wantOK: true},
+		{raw: worktree, want: filepath.Join(worktree, ".graft"), wantOK: true},
+		{raw: gitDir},
+		{raw: filepath.Join(t.TempDir(), "missing")},
+		{raw: "https://example.com/graft/alice/repo"},
+		{raw: "git@github.com:alice/repo.git"},
+	}
+	for _, tc := range tests {
+		got, ok := LocalRepoDir(tc.raw)
+		if ok != tc.wantOK || got != tc.want {
+			t.Errorf("LocalRepoDir(%q) = %q, %v; want %q, %v", tc.raw, got, ok, tc.want, tc.wantOK)
+		}
+	}
+
+	ep, err := ParseEndpoint(bare)
+	if err != nil {
+		t.Fatalf("ParseEndpoint: %v", err)
+	}
+	if ep.LocalDir != bare || ep.Repo != "mirror" || !strings.HasPrefix(ep.BaseURL, "file://") {
+		t.Fatalf("endpoint = %+v", ep)
+	}
+}
+
+func TestLocalClientFetchPushAndUpdateRefs(t *testing.T) {
+	ctx := context.Background()
+	dir, first, second := newBareLocalRepo(t)
+	client, err := NewClient(dir)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	refs, err := client.ListRefs(ctx)
+	if err != nil {
+		t.Fatalf("ListRefs: %v", err)
+	}
+	if len(refs) != 1 || refs["heads/main"] != second {
+		t.Fatalf("ListRefs = %v", refs)
+	}
+
+	local := object.NewStore(t.TempDir())
+	written, err := FetchIntoStore(ctx, client, local, []object.Hash{second}, nil)
+	if err != nil {
+		t.Fatalf("FetchIntoStore: %v", err)
+	}
+	if written != 6 {
+		t.Fatalf("fetched %d objects, want 6", written)
+	}
+
+	records, truncated, err := client.BatchObjects(ctx, []object.Hash{second}, []object.Hash{first}, 0)
+	if err != nil || truncated {
+		t.Fatalf("BatchObjects(haves) = %v, truncated=%v", err, truncated)
+	}
+	if len(records) != 3 {
+		t.Fatalf("BatchObjects with haves returned %d objects, want 3", len(records))
+	}
+	if _, truncated, err := client.BatchObjects(ctx, []object.Hash{second}, nil, 2); err != nil || !truncated {
+		t.Fatalf("BatchObjects(max=2) truncated=%v, %v", truncated, err)
+	}
+
+	blob, err := local.WriteBlob(&object.Blob{Data: []byte("three\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := local.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{{Name: "file.txt", BlobHash: blob}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := local.WriteCommit(&object.CommitObj{TreeHash: tree, Parents: []object.Hash{second}, Author: "Bob", Timestamp: 1700000002, Message: "three\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := CollectObjectsForPush(local, []object.Hash{third}, []object.Hash{second})
+	if err != nil {
+		t.Fatalf("CollectObjectsForPush: %v", err)
+	}
+	if err := client.PushObjectsPack(ctx, objects); err != nil {
+		t.Fatalf("PushObjectsPack: %v", err)
+	}
+
+	_, err = client.UpdateRefs(ctx, []RefUpdate{{Name: "heads/main", Old: &first, New: &third}})
+	if err == nil {
+		t.Fatal("UpdateRefs with stale old value succeeded")
+	}
+	if rejected := RefRejections(err); len(rejected) != 1 || rejected[0].Name != "heads/main" || rejected[0].Code != "ref_conflict" {
+		t.Fatalf("RefRejections = %+v", rejected)
+	}
+	if _, err := client.UpdateRefs(ctx, []RefUpdate{{Name: "heads/main", Old: &second}}); err != nil {
+		t.Fatalf("UpdateRefs(delete): %v", err)
+	}
+	updated, err := client.UpdateRefs(ctx, []RefUpdate{{Name: "heads/main", New: &third}, {Name: "tags/v1", New: &first}})
+	if err != nil {
+		t.Fatalf("UpdateRefs: %v", err)
+	}
+	if updated["heads/main"] != third || updated["tags/v1"] != first {
+		t.Fatalf("UpdateRefs = %v", updated)
+	}
+	refs, err = client.ListRefs(ctx)
+	if err != nil || refs["heads/main"] != third || refs["tags/v1"] != first {
+		t.Fatalf("ListRefs after push = %v, %v", refs, err)
+	}
+	if _, err := client.UpdateRefs(ctx, []RefUpdate{{Name: "../HEAD", New: &third}}); err == nil {
+		t.Fatal("UpdateRefs accepted a ref outside heads/, tags/ and notes/")
+	}
+}
+
+func TestLocalClientShallowFetchReportsBoundary(t *testing.T) {
+	dir, first, second := newBareLocalRepo(t)
+	client, err := NewClient("file://" + filepath.ToSlash(dir))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	local := object.NewStore(t.TempDir())
+	result, err := FetchIntoStoreShallow(context.Background(), client, local, []object.Hash{second}, nil, FetchConfig{Depth: 1})
+	if err != nil {
+		t.Fatalf("FetchIntoStoreShallow: %v", err)
+	}
+	if local.Has(first) {
+		t.Fatal("shallow fetch copied the parent commit")
+	}
+	if !result.ShallowState.IsShallow(first) {
+		t.Fatalf("shallow state = %v, want %s", result.ShallowState.List(), first)
+	}
+}
+
+func TestLocalClientRefusesCheckedOutBranch(t *testing.T) {
+	bare, _, second := newBareLocalRepo(t)
+	root := t.TempDir()
+	if err := os.Rename(bare, filepath.Join(root, ".graft")); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(root)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	_, err = client.UpdateRefs(context.Background(), []RefUpdate{{Name: "heads/main", New: &second}})
+	if err == nil || !strings.Contains(err.Error(), "checked out") {
+		t.Fatalf("UpdateRefs(checked-out branch) error = %v", err)
+	}
+	if _, err := client.UpdateRefs(context.Background(), []RefUpdate{{Name: "heads/feature", New: &second}}); err != nil {
+		t.Fatalf("UpdateRefs(other branch): %v", err)
+	}
+}
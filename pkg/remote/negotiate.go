@@ -0,0 +1,126 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// supportedProtocolVersions lists the protocol versions this client speaks,
+// newest first. The client requests the newest; a server that only speaks an
+// older one answers with that version and the client follows it.
+var supportedProtocolVersions = []string{ProtocolVersion}
+
+// ErrUnsupportedProtocol means the server answered with a protocol version
+// this client does not speak.
+var ErrUnsupportedProtocol = errors.New("unsupported protocol version")
+
+// Features is the transport selection for a client's session with a
+// server: the capabilities both sides support, or the client's assumptions
+// while the server has not advertised any.
+type Features struct {
+	// Protocol is the protocol version in use.
+	Protocol string
+	// Advertised reports whether the server sent its capabilities. Until it
+	// does, pack and zstd are assumed and optional features are off.
+	Advertised bool
+	// Local is set for local-path remotes, which need no negotiation.
+	Local bool
+
+	Pack       bool // binary pack transport for batch responses and pushes
+	Zstd       bool // zstd-compressed pack payloads
+	Sideband   bool // multiplexed progress and error streams
+	ThinPack   bool // REF_DELTA entries against objects the receiver has
+	Shallow    bool // shallow boundaries in batch requests
+	Filter     bool // partial clone filters in batch requests
+	IncludeTag bool // tag objects included with tagged commits
+}
+
+// clientCapabilities is every capability the client can use with a server
+// that supports it.
+var clientCapabilities = ParseCapabilities(ClientCapabilities + "," + CapThinPack + "," + CapShallow + "," + CapFilter + "," + CapIncludeTag)
+
+// selectFeatures picks the session features for the server's advertised
+// capabilities, or the defaults when server is nil.
+func selectFeatures(protocol string, server *Capabilities) Features {
+	if server == nil {
+		return Features{Protocol: protocol, Pack: true, Zstd: true}
+	}
+	common := clientCapabilities.Intersect(*server)
+	f := Features{
+		Protocol:   protocol,
+		Advertised: true,
+		Pack:       common.Has(CapPack),
+		Sideband:   common.Has(CapSideband),
+		Shallow:    common.Has(CapShallow),
+		Filter:     common.Has(CapFilter),
+		IncludeTag: common.Has(CapIncludeTag),
+	}
+	// Compression and deltas only apply to pack payloads.
+	f.Zstd = f.Pack && common.Has(CapZstd)
+	f.ThinPack = f.Pack && common.Has(CapThinPack)
+	return f
+}
+
+// negotiateProtocol returns the protocol version for a session given the
+// server's Graft-Protocol response header. A server that does not send one
+// is assumed to speak the version the client requested.
+func negotiateProtocol(server string) (string, error) {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return supportedProtocolVersions[0], nil
+	}
+	if slices.Contains(supportedProtocolVersions, server) {
+		return server, nil
+	}
+	// A newer server is expected to answer with the version the client
+	// asked for; one that does not has nothing in common with it.
+	if n, err := strconv.Atoi(server); err == nil {
+		if newest, _ := strconv.Atoi(supportedProtocolVersions[0]); n > newest {
+			return "", fmt.Errorf("%w: server requires protocol %s, client supports up to %s", ErrUnsupportedProtocol, server, supportedProtocolVersions[0])
+		}
+	}
+	return "", fmt.Errorf("%w: server speaks protocol %q, client supports %s", ErrUnsupportedProtocol, server, strings.Join(supportedProtocolVersions, ", "))
+}
+
+// Features returns the features selected for this session so far. They
+// change at most once, when the first response that advertises
+// capabilities arrives; call Negotiate to settle them up front.
+func (c *Client) Features() Features {
+	if c.local != nil {
+		// Local pushes and fetches copy whole objects between stores.
+		return Features{Protocol: ProtocolVersion, Local: true, Pack: true, Zstd: true, Shallow: true}
+	}
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	protocol := c.serverProtocol
+	if protocol == "" {
+		protocol = supportedProtocolVersions[0]
+	}
+	return selectFeatures(protocol, c.serverCaps)
+}
+
+// Negotiate makes a minimal request so the server's protocol version,
+// capabilities and limits are known, and returns the selected features.
+func (c *Client) Negotiate(ctx context.Context) (Features, error) {
+	if c.local != nil {
+		return c.Features(), nil
+	}
+	c.metaMu.Lock()
+	negotiated := c.serverProtocol != ""
+	c.metaMu.Unlock()
+	if !negotiated {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint.BaseURL+"/refs?limit=1", nil)
+		if err != nil {
+			return Features{}, err
+		}
+		if _, err := c.doWithLimit(req, http.StatusOK, responseLimitRefs, "application/json"); err != nil {
+			return Features{}, err
+		}
+	}
+	return c.Features(), nil
+}
@@ -0,0 +1,103 @@
+package remote
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestSelectFeatures(t *testing.T) {
+	if got := selectFeatures("1", nil); !got.Pack || !got.Zstd || got.ThinPack || got.Advertised {
+		t.Fatalf("features without advertisement = %+v, want pack and zstd only", got)
+	}
+
+	all := ParseCapabilities("pack,zstd,sideband,thin-pack,shallow,filter,include-tag,future-thing")
+	got := selectFeatures("1", &all)
+	want := Features{Protocol: "1", Advertised: true, Pack: true, Zstd: true, Sideband: true, ThinPack: true, Shallow: true, Filter: true, IncludeTag: true}
+	if got != want {
+		t.Fatalf("features = %+v, want %+v", got, want)
+	}
+
+	// Without pack transport, compression and deltas are off as well.
+	noPack := ParseCapabilities("zstd,thin-pack,shallow")
+	got = selectFeatures("1", &noPack)
+	if got.Pack || got.Zstd || got.ThinPack || !got.Shallow {
+		t.Fatalf("features without pack = %+v", got)
+	}
+}
+
+func TestNegotiateProtocol(t *testing.T) {
+	for _, server := range []string{"", "1", " 1 "} {
+		if got, err := negotiateProtocol(server); err != nil || got != "1" {
+			t.Fatalf("negotiateProtocol(%q) = %q, %v; want 1", server, got, err)
+		}
+	}
+	for _, server := range []string{"2", "0", "beta"} {
+		if _, err := negotiateProtocol(server); !errors.Is(err, ErrUnsupportedProtocol) {
+			t.Fatalf("negotiateProtocol(%q) error = %v, want ErrUnsupportedProtocol", server, err)
+		}
+	}
+}
+
+func TestClientNegotiateDegradesToServerFeatures(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var batchAccept, batchEncoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Graft-Protocol", "1")
+		w.Header().Set("Graft-Capabilities", "pack,sideband")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/graft/alice/repo/refs":
+			_, _ = w.Write([]byte(`{"refs":{}}`))
+		case "/graft/alice/repo/objects/batch":
+			batchAccept = r.Header.Get("Accept")
+			batchEncoding = r.Header.Get("Accept-Encoding")
+			_, _ = w.Write([]byte(`{"objects":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	features, err := client.Negotiate(t.Context())
+	if err != nil {
+		t.Fatalf("Negotiate: %v", err)
+	}
+	if !features.Advertised || !features.Pack || features.Zstd || !features.Sideband || features.ThinPack {
+		t.Fatalf("features = %+v, want pack and sideband without zstd", features)
+	}
+	if _, _, err := client.BatchObjectsPack(t.Context(), []object.Hash{object.Hash(strings.Repeat("a", 64))}, nil, 0); err != nil {
+		t.Fatalf("BatchObjectsPack: %v", err)
+	}
+	if batchAccept != "application/x-graft-pack" || batchEncoding == "zstd" {
+		t.Fatalf("batch Accept = %q, Accept-Encoding = %q; want pack without zstd", batchAccept, batchEncoding)
+	}
+}
+
+func TestClientRejectsUnsupportedServerProtocol(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Graft-Protocol", "7")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"refs":{}}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListRefs(t.Context()); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Fatalf("ListRefs error = %v, want ErrUnsupportedProtocol", err)
+	}
+}
@@ -0,0 +1,263 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/userconfig"
+)
+
+// OAuthClientID identifies graft to an Orchard OAuth server.
+const OAuthClientID = "graft"
+
+const (
+	oauthDeviceCodePath = "/api/v1/oauth/device/code"
+	oauthTokenPath      = "/api/v1/oauth/token"
+	oauthDeviceGrant    = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// oauthRefreshMargin renews a token this long before it expires so it
+	// does not lapse while a request is in flight.
+	oauthRefreshMargin = 30 * time.Second
+)
+
+// oauthPollUnit is the unit of device flow intervals and lifetimes, which
+// servers give in seconds.
+var oauthPollUnit = time.Second
+
+// ErrDeviceAuthorizationDenied indicates the user rejected a device login.
+var ErrDeviceAuthorizationDenied = errors.New("device authorization denied")
+
+// ErrDeviceCodeExpired indicates a device login was not approved in time.
+var ErrDeviceCodeExpired = errors.New("device code expired")
+
+// DeviceAuthorization is an Orchard response starting an OAuth device flow
+// (RFC 8628). The user approves the login at VerificationURI by entering
+// UserCode.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"` // seconds
+	Interval                int    `json:"interval"`   // minimum seconds between polls
+}
+
+// OAuthToken is an access token issued by an Orchard OAuth server.
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	ExpiresIn    int       `json:"expires_in,omitempty"` // seconds; 0 = does not expire
+	Expiry       time.Time `json:"-"`                    // zero if the token does not expire
+}
+
+// oauthError is the error body of an OAuth token endpoint.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *oauthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth: %s: %s", e.Code, e.Description)
+	}
+	return "oauth: " + e.Code
+}
+
+// RequestDeviceAuthorization starts an OAuth device flow against the Orchard
+// server at baseURL.
+func RequestDeviceAuthorization(ctx context.Context, httpClient *http.Client, baseURL string) (*DeviceAuthorization, error) {
+	var out DeviceAuthorization
+	if err := postOAuthForm(ctx, httpClient, baseURL+oauthDeviceCodePath, url.Values{"client_id": {OAuthClientID}}, &out); err != nil {
+		return nil, fmt.Errorf("request device code: %w", err)
+	}
+	if out.DeviceCode == "" || out.UserCode == "" || out.VerificationURI == "" {
+		return nil, fmt.Errorf("request device code: incomplete response")
+	}
+	return &out, nil
+}
+
+// PollDeviceToken polls the token endpoint until the user approves or
+// denies the device authorization, the code expires or ctx is done.
+func PollDeviceToken(ctx context.Context, httpClient *http.Client, baseURL string, auth *DeviceAuthorization) (*OAuthToken, error) {
+	// RFC 8628 has clients wait 5 seconds between polls unless told otherwise.
+	interval := 5 * oauthPollUnit
+	if auth.Interval > 0 {
+		interval = time.Duration(auth.Interval) * oauthPollUnit
+	}
+	var deadline <-chan time.Time
+	if auth.ExpiresIn > 0 {
+		timer := time.NewTimer(time.Duration(auth.ExpiresIn) * oauthPollUnit)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	form := url.Values{
+		"grant_type":  {oauthDeviceGrant},
+		"device_code": {auth.DeviceCode},
+		"client_id":   {OAuthClientID},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return nil, ErrDeviceCodeExpired
+		case <-time.After(interval):
+		}
+
+		token, err := requestOAuthToken(ctx, httpClient, baseURL, form)
+		var oe *oauthError
+		if !errors.As(err, &oe) {
+			return token, err
+		}
+		switch oe.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * oauthPollUnit
+		case "access_denied":
+			return nil, ErrDeviceAuthorizationDenied
+		case "expired_token":
+			return nil, ErrDeviceCodeExpired
+		default:
+			return nil, err
+		}
+	}
+}
+
+// RefreshOAuthToken exchanges a refresh token for a new access token. The
+// result keeps refreshToken if the server does not rotate it.
+func RefreshOAuthToken(ctx context.Context, httpClient *http.Client, baseURL, refreshToken string) (*OAuthToken, error) {
+	token, err := requestOAuthToken(ctx, httpClient, baseURL, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {OAuthClientID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("refresh token: %w", err)
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+func requestOAuthToken(ctx context.Context, httpClient *http.Client, baseURL string, form url.Values) (*OAuthToken, error) {
+	var out OAuthToken
+	if err := postOAuthForm(ctx, httpClient, baseURL+oauthTokenPath, form, &out); err != nil {
+		return nil, err
+	}
+	if out.AccessToken == "" {
+		return nil, fmt.Errorf("token response missing access_token")
+	}
+	if out.ExpiresIn > 0 {
+		out.Expiry = time.Now().Add(time.Duration(out.ExpiresIn) * time.Second)
+	}
+	return &out, nil
+}
+
+func postOAuthForm(ctx context.Context, httpClient *http.Client, endpoint string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, responseLimitDefault))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oe oauthError
+		if json.Unmarshal(raw, &oe) == nil && oe.Code != "" {
+			return &oe
+		}
+		msg := strings.TrimSpace(string(raw))
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("remote request failed (%s %s): %s", req.Method, req.URL.Path, msg)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// oauthSession holds a refreshable bearer token loaded from an Orchard
+// profile in ~/.graftconfig.
+type oauthSession struct {
+	mu           sync.Mutex
+	baseURL      string // Orchard base URL the profile belongs to
+	token        string
+	refreshToken string
+	expiry       time.Time
+}
+
+func newOAuthSession(baseURL string, profile userconfig.OrchardProfile) *oauthSession {
+	if profile.RefreshToken == "" {
+		return nil
+	}
+	s := &oauthSession{baseURL: baseURL, token: profile.Token, refreshToken: profile.RefreshToken}
+	if profile.TokenExpiresAt != "" {
+		// An unreadable expiry is treated as expired so it is refreshed.
+		s.expiry, _ = time.Parse(time.RFC3339, profile.TokenExpiresAt)
+		if s.expiry.IsZero() {
+			s.expiry = time.Unix(0, 0)
+		}
+	}
+	return s
+}
+
+// bearer returns the current access token, refreshing it first if it has
+// expired or is about to. A failed refresh keeps the old token, so the
+// server's rejection reaches the caller.
+func (s *oauthSession) bearer(ctx context.Context, httpClient *http.Client) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && (s.expiry.IsZero() || time.Until(s.expiry) > oauthRefreshMargin) {
+		return s.token
+	}
+	token, err := RefreshOAuthToken(ctx, httpClient, s.baseURL, s.refreshToken)
+	if err != nil {
+		return s.token
+	}
+	s.token, s.refreshToken, s.expiry = token.AccessToken, token.RefreshToken, token.Expiry
+	_ = SaveOAuthToken(s.baseURL, token)
+	return s.token
+}
+
+// SaveOAuthToken stores token as the credentials of the Orchard profile for
+// baseURL in ~/.graftconfig.
+func SaveOAuthToken(baseURL string, token *OAuthToken) error {
+	cfg, err := userconfig.Load()
+	if err != nil {
+		return err
+	}
+	profile := cfg.OrchardProfile(baseURL)
+	profile.Token = token.AccessToken
+	profile.RefreshToken = token.RefreshToken
+	profile.TokenExpiresAt = ""
+	if !token.Expiry.IsZero() {
+		profile.TokenExpiresAt = token.Expiry.UTC().Format(time.RFC3339)
+	}
+	key := cfg.SetOrchardProfile(baseURL, profile)
+	if key != "" && key == cfg.OrchardURL {
+		cfg.Token = profile.Token
+	}
+	return userconfig.Save(cfg)
+}
@@ -0,0 +1,159 @@
+package remote
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/userconfig"
+)
+
+func shortenOAuthPolling(t *testing.T) {
+	t.Helper()
+	prev := oauthPollUnit
+	oauthPollUnit = time.Millisecond
+	t.Cleanup(func() { oauthPollUnit = prev })
+}
+
+func TestDeviceFlowPollsUntilApproved(t *testing.T) {
+	shortenOAuthPolling(t)
+
+	var polls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		if got := r.PostForm.Get("client_id"); got != OAuthClientID {
+			t.Errorf("client_id = %q, want %q", got, OAuthClientID)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case oauthDeviceCodePath:
+			_, _ = w.Write([]byte(`{"device_code":"dev-1","user_code":"ABCD-EFGH","verification_uri":"https://orchard.example/device","expires_in":600,"interval":1}`))
+		case oauthTokenPath:
+			if got := r.PostForm.Get("grant_type"); got != oauthDeviceGrant {
+				t.Errorf("grant_type = %q, want %q", got, oauthDeviceGrant)
+			}
+			if got := r.PostForm.Get("device_code"); got != "dev-1" {
+				t.Errorf("device_code = %q, want dev-1", got)
+			}
+			switch polls.Add(1) {
+			case 1:
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+			case 2:
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"slow_down"}`))
+			default:
+				_, _ = w.Write([]byte(`{"access_token":"access-1","refresh_token":"refresh-1","token_type":"Bearer","expires_in":3600}`))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	auth, err := RequestDeviceAuthorization(t.Context(), ts.Client(), ts.URL)
+	if err != nil {
+		t.Fatalf("RequestDeviceAuthorization: %v", err)
+	}
+	if auth.UserCode != "ABCD-EFGH" {
+		t.Fatalf("UserCode = %q, want ABCD-EFGH", auth.UserCode)
+	}
+	token, err := PollDeviceToken(t.Context(), ts.Client(), ts.URL, auth)
+	if err != nil {
+		t.Fatalf("PollDeviceToken: %v", err)
+	}
+	if token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" {
+		t.Fatalf("token = %+v", token)
+	}
+	if time.Until(token.Expiry) < 59*time.Minute {
+		t.Fatalf("Expiry = %v, want about an hour from now", token.Expiry)
+	}
+	if got := polls.Load(); got != 3 {
+		t.Fatalf("polls = %d, want 3", got)
+	}
+}
+
+func TestDeviceFlowReportsDenial(t *testing.T) {
+	shortenOAuthPolling(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"access_denied"}`))
+	}))
+	defer ts.Close()
+
+	_, err := PollDeviceToken(t.Context(), ts.Client(), ts.URL, &DeviceAuthorization{DeviceCode: "dev-1", Interval: 1})
+	if !errors.Is(err, ErrDeviceAuthorizationDenied) {
+		t.Fatalf("PollDeviceToken error = %v, want %v", err, ErrDeviceAuthorizationDenied)
+	}
+}
+
+func TestClientRefreshesExpiredOAuthToken(t *testing.T) {
+	isolateHTTPConfig(t)
+
+	var refreshes atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case oauthTokenPath:
+			_ = r.ParseForm()
+			if got := r.PostForm.Get("refresh_token"); got != "refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+			refreshes.Add(1)
+			_, _ = w.Write([]byte(`{"access_token":"access-2","refresh_token":"refresh-2","expires_in":3600}`))
+		case "/graft/alice/repo/refs":
+			if got := r.Header.Get("Authorization"); got != "Bearer access-2" {
+				http.Error(w, "unauthorized: "+got, http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"refs":{}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := &userconfig.Config{}
+	cfg.SetOrchardProfile(ts.URL, userconfig.OrchardProfile{
+		Token:          "access-1",
+		RefreshToken:   "refresh-1",
+		TokenExpiresAt: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+	})
+	if err := userconfig.Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := client.ListRefs(t.Context()); err != nil {
+			t.Fatalf("ListRefs: %v", err)
+		}
+	}
+	if got := refreshes.Load(); got != 1 {
+		t.Fatalf("refreshes = %d, want 1", got)
+	}
+
+	saved, err := userconfig.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := saved.OrchardProfile(ts.URL)
+	if profile.Token != "access-2" || profile.RefreshToken != "refresh-2" {
+		t.Fatalf("saved profile = %+v, want refreshed tokens", profile)
+	}
+	if profile.TokenExpiresAt == "" {
+		t.Fatal("saved profile has no token expiry")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"runtime"
+	"strings"
 	"sync"
 
 	"github.com/odvcencio/graft/pkg/object"
@@ -45,23 +46,45 @@ func packTypeToObjectType(t object.PackObjectType) (object.ObjectType, bool) {
 
 // EncodePackTransport encodes ObjectRecords into a pack stream.
 func EncodePackTransport(w io.Writer, records []ObjectRecord) error {
-	prepared, err := preparePackTransportEntries(records)
-	if err != nil {
-		return err
-	}
+	return EncodeThinPackTransport(w, records, nil)
+}
 
+// EncodeThinPackTransport encodes ObjectRecords into a pack stream, emitting
+// REF_DELTA entries against objects the receiver already has. bases maps a
+// record hash to such an object; records without a base, or whose delta
+// would not save enough space, are written whole. The resulting pack is only
+// decodable by a receiver that can resolve the external bases (see
+// DecodeThinPackTransport).
+//
+// Records are compressed in windows of packTransportWindow objects, each
+// written to w before the next is prepared, so only one window of
+// compressed payloads is held in memory.
+func EncodeThinPackTransport(w io.Writer, records []ObjectRecord, bases map[object.Hash]ThinPackBase) error {
 	pw, err := object.NewPackWriter(w, uint32(len(records)))
 	if err != nil {
 		return fmt.Errorf("create pack writer: %w", err)
 	}
 
-	entityEntries := make([]object.PackEntityTrailerEntry, 0, len(prepared))
-	for _, entry := range prepared {
-		if entry.entityTrailer != nil {
-			entityEntries = append(entityEntries, *entry.entityTrailer)
+	var entityEntries []object.PackEntityTrailerEntry
+	for first := 0; first < len(records); first += packTransportWindow {
+		window := records[first:min(first+packTransportWindow, len(records))]
+		prepared, err := preparePackTransportEntries(window, first, bases)
+		if err != nil {
+			return err
 		}
-		if err := pw.WriteCompressedEntry(entry.packType, entry.rawSize, entry.compressed); err != nil {
-			return fmt.Errorf("write pack entry for %s: %w", entry.hash, err)
+		for _, entry := range prepared {
+			if entry.entityTrailer != nil {
+				entityEntries = append(entityEntries, *entry.entityTrailer)
+			}
+			if entry.deltaBase != "" {
+				if err := pw.WriteCompressedRefDelta(entry.deltaBase, entry.rawSize, entry.compressed); err != nil {
+					return fmt.Errorf("write pack delta for %s: %w", entry.hash, err)
+				}
+				continue
+			}
+			if err := pw.WriteCompressedEntry(entry.packType, entry.rawSize, entry.compressed); err != nil {
+				return fmt.Errorf("write pack entry for %s: %w", entry.hash, err)
+			}
 		}
 	}
 
@@ -84,9 +107,16 @@ type preparedPackTransportEntry struct {
 	rawSize       uint64
 	compressed    []byte
 	entityTrailer *object.PackEntityTrailerEntry
+	deltaBase     object.Hash // set when compressed holds a REF_DELTA payload
 }
 
-func preparePackTransportEntries(records []ObjectRecord) ([]preparedPackTransportEntry, error) {
+// packTransportWindow is how many records EncodeThinPackTransport compresses
+// in parallel before writing them out.
+const packTransportWindow = 512
+
+// preparePackTransportEntries compresses records in parallel. first is the
+// index of records[0] in the whole pack, used in error messages.
+func preparePackTransportEntries(records []ObjectRecord, first int, bases map[object.Hash]ThinPackBase) ([]preparedPackTransportEntry, error) {
 	if len(records) == 0 {
 		return nil, nil
 	}
@@ -119,9 +149,9 @@ func preparePackTransportEntries(records []ObjectRecord) ([]preparedPackTranspor
 					if !ok {
 						return
 					}
-					entry, err := preparePackTransportEntry(records[idx])
+					entry, err := preparePackTransportEntry(records[idx], bases)
 					if err != nil {
-						setFirstErr(fmt.Errorf("prepare pack object %d: %w", idx, err))
+						setFirstErr(fmt.Errorf("prepare pack object %d: %w", first+idx, err))
 						return
 					}
 					prepared[idx] = entry
@@ -147,22 +177,38 @@ enqueueLoop:
 	return prepared, nil
 }
 
-func preparePackTransportEntry(rec ObjectRecord) (preparedPackTransportEntry, error) {
+func preparePackTransportEntry(rec ObjectRecord, bases map[object.Hash]ThinPackBase) (preparedPackTransportEntry, error) {
 	packType, ok := objectTypeToPackType(rec.Type)
 	if !ok {
 		return preparedPackTransportEntry{}, fmt.Errorf("unsupported object type %q", rec.Type)
 	}
 
-	compressed, err := object.CompressPackPayload(rec.Data)
-	if err != nil {
-		return preparedPackTransportEntry{}, fmt.Errorf("compress pack entry: %w", err)
-	}
-
 	hash := rec.Hash
 	if hash == "" {
 		hash = object.HashObject(rec.Type, rec.Data)
 	}
 
+	if base, ok := bases[hash]; ok && rec.Type == object.TypeBlob && base.Hash != hash {
+		if delta := object.BuildDelta(base.Data, rec.Data); thinPackDeltaWorthwhile(len(delta), len(rec.Data)) {
+			compressed, err := object.CompressPackPayload(delta)
+			if err != nil {
+				return preparedPackTransportEntry{}, fmt.Errorf("compress pack delta: %w", err)
+			}
+			return preparedPackTransportEntry{
+				hash:       hash,
+				packType:   object.PackRefDelta,
+				rawSize:    uint64(len(delta)),
+				compressed: compressed,
+				deltaBase:  base.Hash,
+			}, nil
+		}
+	}
+
+	compressed, err := object.CompressPackPayload(rec.Data)
+	if err != nil {
+		return preparedPackTransportEntry{}, fmt.Errorf("compress pack entry: %w", err)
+	}
+
 	entry := preparedPackTransportEntry{
 		hash:       hash,
 		packType:   packType,
@@ -195,6 +241,13 @@ func packTransportWorkerCount(total int) int {
 
 // DecodePackTransport decodes a pack stream into ObjectRecords.
 func DecodePackTransport(data []byte) ([]ObjectRecord, error) {
+	return DecodeThinPackTransport(data, nil)
+}
+
+// DecodeThinPackTransport decodes a pack stream into ObjectRecords, resolving
+// REF_DELTA bases that are not part of the pack through lookup. Objects used
+// only as external bases are not returned.
+func DecodeThinPackTransport(data []byte, lookup object.ThinPackBaseLookup) ([]ObjectRecord, error) {
 	if len(data) == 0 {
 		return nil, nil
 	}
@@ -204,7 +257,7 @@ func DecodePackTransport(data []byte) ([]ObjectRecord, error) {
 		return nil, fmt.Errorf("read pack: %w", err)
 	}
 
-	resolved, err := object.ResolvePackEntries(pf.Entries)
+	resolved, err := object.ResolveThinPackEntries(pf.Entries, lookup)
 	if err != nil {
 		return nil, fmt.Errorf("resolve deltas: %w", err)
 	}
@@ -256,6 +309,79 @@ func DecodePackTransport(data []byte) ([]ObjectRecord, error) {
 	return records, nil
 }
 
+// entityPayloadPrefix starts every serialized entity and entity list. Pack
+// entries carry entities as blobs; only blobs with this prefix can turn out
+// to be entities once the trailer is read.
+var entityPayloadPrefix = []byte("version ")
+
+// DecodePackTransportStream decodes a pack stream from r incrementally and
+// calls emit with each object, without buffering the whole pack. lookup
+// resolves delta bases: objects emitted earlier in the stream as well as
+// thin pack bases the receiver has, so emit must make each record visible
+// to lookup before it returns (writing it to the store does, see
+// StoreThinPackBases).
+//
+// Blob entries that may be entities are held until the entity trailer at
+// the end of the stream assigns their real type; every other object is
+// emitted as soon as it is resolved.
+func DecodePackTransportStream(r io.Reader, lookup object.ThinPackBaseLookup, emit func(ObjectRecord) error) error {
+	if lookup == nil {
+		lookup = func(h object.Hash) (object.PackObjectType, []byte, error) {
+			return 0, nil, fmt.Errorf("object %s not found", h)
+		}
+	}
+	held := make(map[object.Hash][]byte)
+	heldOrder := make([]object.Hash, 0)
+	bases := func(h object.Hash) (object.PackObjectType, []byte, error) {
+		if data, ok := held[h]; ok {
+			return object.PackBlob, data, nil
+		}
+		return lookup(h)
+	}
+
+	trailer, err := object.ResolvePackStream(r, bases, func(entry object.PackEntry) error {
+		objType, ok := packTypeToObjectType(entry.Type)
+		if !ok {
+			return fmt.Errorf("unsupported pack type %d", entry.Type)
+		}
+		hash := object.HashObject(objType, entry.Data)
+		if objType == object.TypeBlob && bytes.HasPrefix(entry.Data, entityPayloadPrefix) {
+			if _, ok := held[hash]; !ok {
+				held[hash] = entry.Data
+				heldOrder = append(heldOrder, hash)
+			}
+			return nil
+		}
+		return emit(ObjectRecord{Hash: hash, Type: objType, Data: entry.Data})
+	})
+	if err != nil {
+		return err
+	}
+
+	typeOverrides := map[object.Hash]object.ObjectType{}
+	if trailer != nil {
+		for _, entry := range trailer.Entries {
+			if typ, ok := strings.CutPrefix(entry.StableID, "type:"); ok {
+				typeOverrides[entry.ObjectHash] = object.ObjectType(typ)
+			}
+		}
+	}
+	for _, blobHash := range heldOrder {
+		rec := ObjectRecord{Hash: blobHash, Type: object.TypeBlob, Data: held[blobHash]}
+		for _, candidate := range []object.ObjectType{object.TypeEntity, object.TypeEntityList} {
+			h := object.HashObject(candidate, rec.Data)
+			if override, ok := typeOverrides[h]; ok && override == candidate {
+				rec.Hash, rec.Type = h, candidate
+				break
+			}
+		}
+		if err := emit(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // EncodePackTransportToBytes is a convenience wrapper.
 func EncodePackTransportToBytes(records []ObjectRecord) ([]byte, error) {
 	var buf bytes.Buffer
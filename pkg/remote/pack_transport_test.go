@@ -143,3 +143,46 @@ func TestPackTransportPreservesOrderWithParallelPreparation(t *testing.T) {
 		}
 	}
 }
+
+func TestDecodePackTransportStreamRestoresEntityTypes(t *testing.T) {
+	entity := object.MarshalEntity(&object.EntityObj{Kind: "declaration", Name: "hello", DeclKind: "function_definition", Body: []byte("func hello() {}\n")})
+	entityHash := object.HashObject(object.TypeEntity, entity)
+	list := object.MarshalEntityList(&object.EntityListObj{Language: "go", Path: "main.go", EntityRefs: []object.Hash{entityHash}})
+	// A plain blob that looks like an entity must stay a blob.
+	lookalike := []byte("version 2.0 release notes\n")
+
+	var records []ObjectRecord
+	for _, rec := range []ObjectRecord{
+		{Type: object.TypeEntity, Data: entity},
+		{Type: object.TypeEntityList, Data: list},
+		{Type: object.TypeBlob, Data: lookalike},
+		{Type: object.TypeBlob, Data: []byte("hello\n")},
+	} {
+		rec.Hash = object.HashObject(rec.Type, rec.Data)
+		records = append(records, rec)
+	}
+	var buf bytes.Buffer
+	if err := EncodePackTransport(&buf, records); err != nil {
+		t.Fatalf("EncodePackTransport: %v", err)
+	}
+
+	store := object.NewStore(t.TempDir())
+	var got []ObjectRecord
+	err := DecodePackTransportStream(&buf, StoreThinPackBases(store), func(rec ObjectRecord) error {
+		got = append(got, rec)
+		_, err := writeVerifiedObject(store, rec)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("DecodePackTransportStream: %v", err)
+	}
+	if len(got) != len(records) {
+		t.Fatalf("decoded %d records, want %d", len(got), len(records))
+	}
+	for _, want := range records {
+		typ, _, err := store.Read(want.Hash)
+		if err != nil || typ != want.Type {
+			t.Fatalf("object %s (%s): read type %s, err %v", want.Hash, want.Type, typ, err)
+		}
+	}
+}
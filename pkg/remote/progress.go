@@ -0,0 +1,69 @@
+package remote
+
+import (
+	"io"
+	"sync"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// transferProgress accumulates byte and object totals for one client and
+// forwards them to the registered callback. Calls are serialized so the
+// callback never runs concurrently with itself.
+type transferProgress struct {
+	mu       sync.Mutex
+	fn       object.ProgressFunc
+	down     int64
+	up       int64
+	upObject int
+}
+
+func (p *transferProgress) enabled() bool {
+	return p != nil && p.fn != nil
+}
+
+func (p *transferProgress) downloaded(n int) {
+	if !p.enabled() || n <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down += int64(n)
+	p.fn(object.Progress{Phase: object.ProgressDownloading, Bytes: p.down})
+}
+
+func (p *transferProgress) uploaded(objects int, n int64) {
+	if !p.enabled() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.up += n
+	p.upObject += objects
+	p.fn(object.Progress{Phase: object.ProgressUploading, Objects: p.upObject, Bytes: p.up})
+}
+
+// reader wraps r so bytes read from it are reported as downloaded.
+func (p *transferProgress) reader(r io.Reader) io.Reader {
+	if !p.enabled() {
+		return r
+	}
+	return &progressReader{r: r, p: p}
+}
+
+type progressReader struct {
+	r io.Reader
+	p *transferProgress
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.downloaded(n)
+	return n, err
+}
+
+// SetProgress registers fn to receive download and upload totals for every
+// request made through c. A nil fn disables reporting.
+func (c *Client) SetProgress(fn object.ProgressFunc) {
+	c.progress = &transferProgress{fn: fn}
+}
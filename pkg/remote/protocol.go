@@ -6,7 +6,9 @@ package remote
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
@@ -24,6 +26,11 @@ const (
 	headerProtocol     = "Graft-Protocol"
 	headerCapabilities = "Graft-Capabilities"
 	headerLimits       = "Graft-Limits"
+
+	// headerIdempotencyKey names one logical ref update, so a server can
+	// answer a retried request with the first attempt's result instead of
+	// applying it again.
+	headerIdempotencyKey = "Idempotency-Key"
 )
 
 // Well-known capability names used in the Graft protocol.
@@ -34,6 +41,10 @@ const (
 	CapShallow    = "shallow"
 	CapFilter     = "filter"
 	CapIncludeTag = "include-tag"
+	// CapThinPack means the server resolves REF_DELTA bases in uploaded packs
+	// against objects it already stores. Sent by a client on a batch request,
+	// it means the client does the same for the pack in the response.
+	CapThinPack = "thin-pack"
 )
 
 // ValidateHash checks that a hash is a valid 64-character lowercase hex string (SHA-256).
@@ -101,20 +112,97 @@ func (c Capabilities) String() string {
 	return strings.Join(names, ",")
 }
 
+// Errors a remote failure can be tested for with errors.Is.
+var (
+	// ErrAuthRequired reports that the remote refused a request for lack
+	// of valid credentials: HTTP 401 or 403, or an unauthorized or
+	// forbidden RemoteError.
+	ErrAuthRequired = errors.New("authentication required")
+
+	// ErrNonFastForward reports a ref update that would drop commits from
+	// the ref, or whose expected old value no longer matches the remote.
+	ErrNonFastForward = errors.New("non-fast-forward update")
+)
+
 // RemoteError is a structured error from the remote server.
 type RemoteError struct {
 	Code    string `json:"code"`
 	Message string `json:"error"`
 	Detail  string `json:"detail,omitempty"`
+
+	// Rejected lists, for a refused ref update, each ref the server
+	// rejected and why (e.g. a server-side hook or branch policy).
+	Rejected []RefRejection `json:"rejected,omitempty"`
+
+	// Status is the HTTP status the error came with; zero for transports
+	// without one.
+	Status int `json:"-"`
+}
+
+// Is reports whether e stands for target: ErrAuthRequired for an
+// unauthorized or forbidden response, ErrNonFastForward for a ref update
+// rejected because the ref moved or would lose commits.
+func (e *RemoteError) Is(target error) bool {
+	switch target {
+	case ErrAuthRequired:
+		return e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden ||
+			e.Code == "unauthorized" || e.Code == "forbidden"
+	case ErrNonFastForward:
+		if isNonFastForwardCode(e.Code) {
+			return true
+		}
+		for _, rej := range e.Rejected {
+			if isNonFastForwardCode(rej.Code) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isNonFastForwardCode(code string) bool {
+	return code == "ref_conflict" || code == "non_fast_forward"
 }
 
 func (e *RemoteError) Error() string {
-	if e.Detail != "" {
-		return fmt.Sprintf("%s (%s): %s", e.Message, e.Code, e.Detail)
+	detail := e.Detail
+	if detail == "" && len(e.Rejected) > 0 {
+		reasons := make([]string, len(e.Rejected))
+		for i, rej := range e.Rejected {
+			reasons[i] = rej.String()
+		}
+		detail = strings.Join(reasons, "; ")
+	}
+	if detail != "" {
+		return fmt.Sprintf("%s (%s): %s", e.Message, e.Code, detail)
 	}
 	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
 }
 
+// RefRejection is the server's reason for refusing one ref of an update.
+type RefRejection struct {
+	Name   string `json:"name"`
+	Code   string `json:"code,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (r RefRejection) String() string {
+	if r.Reason == "" {
+		return r.Name + ": rejected"
+	}
+	return r.Name + ": " + r.Reason
+}
+
+// RefRejections returns the per-ref rejection reasons carried by err, or nil
+// when err is not a ref update refused ref by ref.
+func RefRejections(err error) []RefRejection {
+	var re *RemoteError
+	if errors.As(err, &re) {
+		return re.Rejected
+	}
+	return nil
+}
+
 // ServerLimits holds server-advertised protocol limits parsed from the Graft-Limits header.
 type ServerLimits struct {
 	MaxBatch   int // max objects per batch (0 = use client default)
@@ -151,14 +239,25 @@ func ParseLimits(raw string) ServerLimits {
 	return limits
 }
 
-// tryParseRemoteError attempts to parse a JSON error response body.
-func tryParseRemoteError(body []byte) *RemoteError {
+// tryParseRemoteError attempts to parse a JSON error response body that
+// came with the given HTTP status.
+func tryParseRemoteError(status int, body []byte) *RemoteError {
 	var re RemoteError
 	if err := json.Unmarshal(body, &re); err != nil {
 		return nil
 	}
-	if re.Message == "" && re.Code == "" {
+	if re.Message == "" && re.Code == "" && len(re.Rejected) == 0 {
 		return nil
 	}
+	re.Status = status
 	return &re
 }
+
+// requestFailed builds the error for a failed request whose response body
+// is not a RemoteError, wrapping ErrAuthRequired for 401 and 403.
+func requestFailed(req *http.Request, status int, msg string) error {
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return fmt.Errorf("remote request failed (%s %s): %w: %s", req.Method, req.URL.Path, ErrAuthRequired, msg)
+	}
+	return fmt.Errorf("remote request failed (%s %s): %s", req.Method, req.URL.Path, msg)
+}
@@ -1,6 +1,8 @@
 package remote
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/odvcencio/graft/pkg/object"
@@ -127,3 +129,29 @@ func TestRemoteErrorFormat(t *testing.T) {
 		t.Fatalf("Error() = %q", re.Error())
 	}
 }
+
+func TestRemoteErrorIs(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     *RemoteError
+		auth    bool
+		nonFast bool
+	}{
+		{"unauthorized status", &RemoteError{Message: "no token", Status: 401}, true, false},
+		{"forbidden code", &RemoteError{Code: "forbidden", Message: "no access"}, true, false},
+		{"ref conflict", &RemoteError{Code: "ref_conflict", Message: "stale old value"}, false, true},
+		{"rejected ref", &RemoteError{Code: "rejected", Message: "push rejected", Rejected: []RefRejection{{Name: "heads/main", Code: "ref_conflict"}}}, false, true},
+		{"other", &RemoteError{Code: "ref_not_found", Message: "ref not found", Status: 404}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("push: %w", tt.err)
+			if got := errors.Is(err, ErrAuthRequired); got != tt.auth {
+				t.Errorf("errors.Is(ErrAuthRequired) = %v, want %v", got, tt.auth)
+			}
+			if got := errors.Is(err, ErrNonFastForward); got != tt.nonFast {
+				t.Errorf("errors.Is(ErrNonFastForward) = %v, want %v", got, tt.nonFast)
+			}
+		})
+	}
+}
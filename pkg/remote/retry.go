@@ -5,26 +5,83 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// retryDo executes an HTTP request with exponential backoff retry.
-// Retries on network errors, HTTP 429, and HTTP 5xx responses.
-// Does not retry 4xx client errors.
-// For requests with a body, the body is buffered and replayed on retry.
-func retryDo(client *http.Client, req *http.Request, maxAttempts int) (*http.Response, error) {
-	if maxAttempts < 1 {
-		maxAttempts = 1
+// RetryPolicy controls how the client retries failed requests. Zero fields
+// take the defaults from DefaultRetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts     int           // attempts per request, including the first
+	InitialBackoff  time.Duration // wait before the first retry; doubles after each
+	MaxBackoff      time.Duration // longest wait between attempts
+	RetryableStatus []int         // HTTP statuses that are retried
+}
+
+// DefaultRetryPolicy returns the default policy: 3 attempts, backing off
+// from 1s up to 30s, retrying 429 and 5xx responses.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = def.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = def.MaxBackoff
+	}
+	return p
+}
+
+// retryable reports whether a response with status should be retried.
+func (p RetryPolicy) retryable(status int) bool {
+	if len(p.RetryableStatus) == 0 {
+		return isRetryableStatus(status)
+	}
+	return slices.Contains(p.RetryableStatus, status)
+}
+
+// wait returns the delay before retry number n (1 for the first retry). A
+// Retry-After header on resp, given in seconds, overrides the backoff.
+func (p RetryPolicy) wait(n int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, p.MaxBackoff)
+		}
 	}
+	d := p.InitialBackoff
+	for i := 1; i < n && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxBackoff)
+}
+
+// retryDo executes an HTTP request, retrying network errors and retryable
+// statuses (by default HTTP 429 and 5xx) with exponential backoff as policy
+// directs. Other responses are returned as is.
+// For requests with a body, the body is buffered and replayed on retry,
+// unless req.GetBody is set, in which case it supplies each attempt's body.
+func retryDo(client *http.Client, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	policy = policy.withDefaults()
 
 	// Buffer body for replay on retry. Limit size to prevent unbounded
 	// memory usage when retrying large uploads.
 	const maxRetryBodySize = 64 << 20 // 64MB
 
-	var bodyBytes []byte
-	if req.Body != nil {
-		var err error
-		bodyBytes, err = io.ReadAll(io.LimitReader(req.Body, maxRetryBodySize+1))
+	getBody := req.GetBody
+	if req.Body != nil && getBody == nil {
+		bodyBytes, err := io.ReadAll(io.LimitReader(req.Body, maxRetryBodySize+1))
 		if err != nil {
 			return nil, err
 		}
@@ -32,22 +89,33 @@ func retryDo(client *http.Client, req *http.Request, maxAttempts int) (*http.Res
 		if int64(len(bodyBytes)) > maxRetryBodySize {
 			return nil, fmt.Errorf("request body too large for retry buffering (%d bytes)", len(bodyBytes))
 		}
+		req.ContentLength = int64(len(bodyBytes))
+		getBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(bodyBytes)), nil
+		}
 	}
 
 	var lastResp *http.Response
 	var lastErr error
-	backoff := time.Second
 
-	for attempt := 0; attempt < maxAttempts; attempt++ {
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
 		if attempt > 0 {
-			time.Sleep(backoff)
-			backoff *= 2
+			timer := time.NewTimer(policy.wait(attempt, lastResp))
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
 		}
 
 		// Reset body for each attempt.
-		if bodyBytes != nil {
-			req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-			req.ContentLength = int64(len(bodyBytes))
+		if getBody != nil {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
 		}
 
 		resp, err := client.Do(req)
@@ -56,18 +124,11 @@ func retryDo(client *http.Client, req *http.Request, maxAttempts int) (*http.Res
 			lastResp = nil
 			continue
 		}
-
-		// Don't retry client errors (4xx) except 429.
-		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
-			return resp, nil
-		}
-
-		// Don't retry success.
-		if resp.StatusCode < 400 {
+		if !policy.retryable(resp.StatusCode) {
 			return resp, nil
 		}
 
-		// Retryable: 429 or 5xx. Drain and close body before retry.
+		// Drain and close body before retry.
 		_, _ = io.Copy(io.Discard, resp.Body)
 		resp.Body.Close()
 		lastResp = resp
@@ -20,7 +20,7 @@ func TestRetryDoSucceedsFirstAttempt(t *testing.T) {
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
-	resp, err := retryDo(client, req, 3)
+	resp, err := retryDo(client, req, RetryPolicy{MaxAttempts: 3})
 	if err != nil {
 		t.Fatalf("retryDo: %v", err)
 	}
@@ -48,7 +48,7 @@ func TestRetryDoRetriesOn500(t *testing.T) {
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
-	resp, err := retryDo(client, req, 3)
+	resp, err := retryDo(client, req, RetryPolicy{MaxAttempts: 3})
 	if err != nil {
 		t.Fatalf("retryDo: %v", err)
 	}
@@ -72,7 +72,7 @@ func TestRetryDoRetriesOn429(t *testing.T) {
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
-	resp, err := retryDo(client, req, 3)
+	resp, err := retryDo(client, req, RetryPolicy{MaxAttempts: 3})
 	if err != nil {
 		t.Fatalf("retryDo: %v", err)
 	}
@@ -92,7 +92,7 @@ func TestRetryDoDoesNotRetry4xx(t *testing.T) {
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
-	resp, err := retryDo(client, req, 3)
+	resp, err := retryDo(client, req, RetryPolicy{MaxAttempts: 3})
 	if err != nil {
 		t.Fatalf("retryDo: %v", err)
 	}
@@ -119,7 +119,7 @@ func TestRetryDoReplaysPostBody(t *testing.T) {
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("payload"))
-	resp, err := retryDo(client, req, 3)
+	resp, err := retryDo(client, req, RetryPolicy{MaxAttempts: 3})
 	if err != nil {
 		t.Fatalf("retryDo: %v", err)
 	}
@@ -134,6 +134,39 @@ func TestRetryDoReplaysPostBody(t *testing.T) {
 	}
 }
 
+func TestRetryDoReplaysBodyFromGetBody(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if body, _ := io.ReadAll(r.Body); string(body) != "payload" {
+			t.Errorf("call %d body = %q, want %q", calls, body, "payload")
+		}
+		if calls < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	// A body with no stdlib GetBody (as for spooled pack uploads) is not
+	// buffered; each attempt reads a fresh copy from GetBody.
+	src := strings.NewReader("payload")
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, io.NewSectionReader(src, 0, src.Size()))
+	req.ContentLength = src.Size()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(src, 0, src.Size())), nil
+	}
+	resp, err := retryDo(&http.Client{Timeout: 5 * time.Second}, req, RetryPolicy{MaxAttempts: 3})
+	if err != nil {
+		t.Fatalf("retryDo: %v", err)
+	}
+	defer resp.Body.Close()
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+}
+
 func TestRetryDoExhaustsRetries(t *testing.T) {
 	calls := 0
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -144,7 +177,7 @@ func TestRetryDoExhaustsRetries(t *testing.T) {
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
-	resp, err := retryDo(client, req, 3)
+	resp, err := retryDo(client, req, RetryPolicy{MaxAttempts: 3})
 	if err != nil {
 		t.Fatalf("retryDo: %v", err)
 	}
@@ -156,3 +189,47 @@ func TestRetryDoExhaustsRetries(t *testing.T) {
 		t.Fatalf("final status = %d, want 500", resp.StatusCode)
 	}
 }
+
+func TestRetryDoRetriesOnlyPolicyStatuses(t *testing.T) {
+	var statuses []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch len(statuses) {
+		case 0:
+			statuses = append(statuses, http.StatusRequestTimeout)
+		default:
+			statuses = append(statuses, http.StatusInternalServerError)
+		}
+		w.WriteHeader(statuses[len(statuses)-1])
+	}))
+	defer ts.Close()
+
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond, RetryableStatus: []int{http.StatusRequestTimeout}}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	resp, err := retryDo(&http.Client{Timeout: 5 * time.Second}, req, policy)
+	if err != nil {
+		t.Fatalf("retryDo: %v", err)
+	}
+	defer resp.Body.Close()
+	// 408 is retried; 500 is not in the policy, so it is returned.
+	if len(statuses) != 2 || resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("statuses = %v, final = %d; want [408 500], 500", statuses, resp.StatusCode)
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}.withDefaults()
+	for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 300 * time.Millisecond, 10: 300 * time.Millisecond} {
+		if got := p.wait(n, nil); got != want {
+			t.Fatalf("wait(%d) = %v, want %v", n, got, want)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"0"}}}
+	if got := p.wait(3, resp); got != 0 {
+		t.Fatalf("wait with Retry-After: 0 = %v, want 0", got)
+	}
+	resp.Header.Set("Retry-After", "120")
+	if got := p.wait(1, resp); got != p.MaxBackoff {
+		t.Fatalf("wait with Retry-After: 120 = %v, want capped at %v", got, p.MaxBackoff)
+	}
+}
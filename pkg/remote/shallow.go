@@ -116,6 +116,40 @@ func (s *ShallowState) Len() int {
 	return len(s.Commits)
 }
 
+// Prune drops boundaries that no longer truncate history: a boundary is
+// removed once its commit is stored locally and every parent is either
+// stored locally or itself a boundary. This keeps the shallow file accurate
+// after a deepen fetch fills in history behind old boundaries. candidates
+// limits which boundaries are re-evaluated; nil examines every boundary. The
+// removed hashes are returned in sorted order.
+func (s *ShallowState) Prune(store *object.Store, candidates []object.Hash) []object.Hash {
+	if candidates == nil {
+		candidates = s.List()
+	}
+	var removed []object.Hash
+	for _, h := range candidates {
+		if !s.IsShallow(h) || !store.Has(h) {
+			continue
+		}
+		commit, err := store.ReadCommit(h)
+		if err != nil {
+			continue
+		}
+		complete := true
+		for _, p := range commit.Parents {
+			if !store.Has(p) && !s.IsShallow(p) {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			s.Remove(h)
+			removed = append(removed, h)
+		}
+	}
+	return removed
+}
+
 // ObjectFilter represents a partial clone filter specification.
 type ObjectFilter struct {
 	Type      string // "blob:none", "blob:limit=<n>", "tree:<depth>"
@@ -305,3 +305,72 @@ func TestFetchShallowClosureStopsAtBoundary(t *testing.T) {
 		t.Errorf("expected parent %s in shallow state", parentCommitHash)
 	}
 }
+
+func TestFetchFromShallowClientAnnouncesBoundaries(t *testing.T) {
+	remoteStore := object.NewStore(t.TempDir())
+	blobHash, err := remoteStore.WriteBlob(&object.Blob{Data: []byte("file\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeHash, err := remoteStore.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{{Name: "file.txt", BlobHash: blobHash}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitHash, err := remoteStore.WriteCommit(&object.CommitObj{
+		TreeHash:  treeHash,
+		Author:    "Alice",
+		Timestamp: 1700000000,
+		Message:   "tip",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitType, commitData, _ := remoteStore.Read(commitHash)
+	treeType, treeData, _ := remoteStore.Read(treeHash)
+	blobType, blobData, _ := remoteStore.Read(blobHash)
+
+	var captured struct {
+		Depth   int      `json:"depth"`
+		Shallow []string `json:"shallow"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/graft/alice/repo/objects/batch" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"objects": []map[string]any{
+				{"hash": string(commitHash), "type": string(commitType), "data": commitData},
+				{"hash": string(treeHash), "type": string(treeType), "data": treeData},
+				{"hash": string(blobHash), "type": string(blobType), "data": blobData},
+			},
+			"truncated": false,
+		})
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := NewShallowState()
+	existing.Add(hashA)
+
+	// A plain fetch (no depth change) from a shallow client still tells the
+	// server where its history ends, and keeps the boundary.
+	result, err := FetchIntoStoreShallow(context.Background(), client, object.NewStore(t.TempDir()), []object.Hash{commitHash}, nil, FetchConfig{ShallowState: existing})
+	if err != nil {
+		t.Fatalf("FetchIntoStoreShallow: %v", err)
+	}
+	if captured.Depth != 0 || len(captured.Shallow) != 1 || captured.Shallow[0] != string(hashA) {
+		t.Fatalf("request depth=%d shallow=%v, want depth=0 shallow=[%s]", captured.Depth, captured.Shallow, hashA)
+	}
+	if !result.ShallowState.IsShallow(hashA) {
+		t.Fatalf("boundary %s dropped from result", hashA)
+	}
+}
@@ -144,6 +144,42 @@ func TestShallow_WriteEmptyState(t *testing.T) {
 	}
 }
 
+func TestShallow_PruneDropsFilledBoundaries(t *testing.T) {
+	store := object.NewStore(t.TempDir())
+	treeHash, err := store.WriteTree(&object.TreeObj{})
+	if err != nil {
+		t.Fatalf("WriteTree: %v", err)
+	}
+	root, err := store.WriteCommit(&object.CommitObj{TreeHash: treeHash, Author: "a", Message: "root"})
+	if err != nil {
+		t.Fatalf("WriteCommit(root): %v", err)
+	}
+	child, err := store.WriteCommit(&object.CommitObj{TreeHash: treeHash, Parents: []object.Hash{root}, Author: "a", Message: "child"})
+	if err != nil {
+		t.Fatalf("WriteCommit(child): %v", err)
+	}
+	truncated, err := store.WriteCommit(&object.CommitObj{TreeHash: treeHash, Parents: []object.Hash{hashA}, Author: "a", Message: "truncated"})
+	if err != nil {
+		t.Fatalf("WriteCommit(truncated): %v", err)
+	}
+
+	state := NewShallowState()
+	state.Add(child)
+	state.Add(truncated)
+	state.Add(hashB)
+
+	removed := state.Prune(store, nil)
+	if len(removed) != 1 || removed[0] != child {
+		t.Fatalf("Prune removed %v, want [%s]", removed, child)
+	}
+	if !state.IsShallow(truncated) {
+		t.Error("expected boundary with a missing parent to remain")
+	}
+	if !state.IsShallow(hashB) {
+		t.Error("expected missing boundary commit to remain")
+	}
+}
+
 func TestProtocol_NewCapabilities(t *testing.T) {
 	// Verify the capability constants have the expected values.
 	if CapPack != "pack" {
@@ -0,0 +1,188 @@
+package remote
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TransferStats summarizes the traffic of a client: the objects it moved,
+// the bytes that crossed the network, and how long it took. Object bytes are
+// the uncompressed object payloads; wire bytes are request and response
+// bodies as sent, after compression. Local-path remotes move no wire bytes.
+type TransferStats struct {
+	Requests int // HTTP requests completed, including retries
+	Rounds   int // batch negotiation rounds of fetches
+
+	ObjectsSent     int
+	ObjectsReceived int
+
+	ObjectBytesSent     int64
+	ObjectBytesReceived int64
+	WireBytesSent       int64
+	WireBytesReceived   int64
+
+	// Duration is the wall time from the first request or object to the
+	// last one.
+	Duration time.Duration
+}
+
+// CompressionRatio returns object bytes per wire byte over both directions,
+// or 0 when nothing crossed the network.
+func (s TransferStats) CompressionRatio() float64 {
+	wire := s.WireBytesSent + s.WireBytesReceived
+	if wire == 0 {
+		return 0
+	}
+	return float64(s.ObjectBytesSent+s.ObjectBytesReceived) / float64(wire)
+}
+
+// StatsFunc receives running transfer totals. It is called after each
+// completed request, negotiation round and object upload, never
+// concurrently with itself.
+type StatsFunc func(TransferStats)
+
+// transferStats accumulates TransferStats for one client.
+type transferStats struct {
+	mu    sync.Mutex
+	s     TransferStats
+	first time.Time
+	last  time.Time
+	now   func() time.Time
+	hook  StatsFunc
+}
+
+func newTransferStats(hook StatsFunc) *transferStats {
+	return &transferStats{now: time.Now, hook: hook}
+}
+
+// update applies fn to the totals under the lock, stamps the activity time,
+// and reports the result to the hook when report is set.
+func (t *transferStats) update(report bool, fn func(*TransferStats)) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if t.first.IsZero() {
+		t.first = now
+	}
+	t.last = now
+	fn(&t.s)
+	t.s.Duration = t.last.Sub(t.first)
+	if report && t.hook != nil {
+		t.hook(t.s)
+	}
+}
+
+func (t *transferStats) snapshot() TransferStats {
+	if t == nil {
+		return TransferStats{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.s
+}
+
+func (t *transferStats) received(obj ObjectRecord) {
+	t.update(false, func(s *TransferStats) {
+		s.ObjectsReceived++
+		s.ObjectBytesReceived += int64(len(obj.Data))
+	})
+}
+
+func (t *transferStats) sent(objects []ObjectRecord) {
+	var n int64
+	for _, obj := range objects {
+		n += int64(len(obj.Data))
+	}
+	t.update(true, func(s *TransferStats) {
+		s.ObjectsSent += len(objects)
+		s.ObjectBytesSent += n
+	})
+}
+
+func (t *transferStats) round() {
+	t.update(true, func(s *TransferStats) { s.Rounds++ })
+}
+
+// Stats returns the client's transfer totals so far.
+func (c *Client) Stats() TransferStats {
+	return c.stats.snapshot()
+}
+
+// SetStatsHook registers fn to receive running transfer totals for requests
+// made through c; see StatsFunc. A nil fn disables it. Totals collected
+// before the call are kept.
+func (c *Client) SetStatsHook(fn StatsFunc) {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+	c.stats.hook = fn
+}
+
+// statsTransport counts the requests and wire bytes of every round trip. A
+// request is counted once its response body is closed.
+type statsTransport struct {
+	base  http.RoundTripper
+	stats *transferStats
+}
+
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.stats.update(false, func(*TransferStats) {})
+	var sent *countingReader
+	if req.Body != nil && req.Body != http.NoBody {
+		sent = &countingReader{r: req.Body}
+		req = req.Clone(req.Context())
+		req.Body = sent
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.finish(sent, nil)
+		return nil, err
+	}
+	received := &countingReader{r: resp.Body}
+	resp.Body = &statsBody{countingReader: received, done: sync.OnceFunc(func() { t.finish(sent, received) })}
+	return resp, nil
+}
+
+func (t *statsTransport) finish(sent, received *countingReader) {
+	t.stats.update(true, func(s *TransferStats) {
+		s.Requests++
+		if sent != nil {
+			s.WireBytesSent += sent.n.Load()
+		}
+		if received != nil {
+			s.WireBytesReceived += received.n.Load()
+		}
+	})
+}
+
+// countingReader counts the bytes read through it. The transport may read a
+// request body from another goroutine, so the count is atomic.
+type countingReader struct {
+	r io.ReadCloser
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func (c *countingReader) Close() error { return c.r.Close() }
+
+// statsBody records its request in the totals when closed.
+type statsBody struct {
+	*countingReader
+	done func()
+}
+
+func (b *statsBody) Close() error {
+	err := b.countingReader.Close()
+	b.done()
+	return err
+}
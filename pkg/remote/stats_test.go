@@ -0,0 +1,127 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestFetchIntoStoreRecordsTransferStats(t *testing.T) {
+	isolateHTTPConfig(t)
+	remoteStore := object.NewStore(t.TempDir())
+	blobHash, err := remoteStore.WriteBlob(&object.Blob{Data: []byte("hello\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeHash, err := remoteStore.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{{Name: "README.md", BlobHash: blobHash}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitHash, err := remoteStore.WriteCommit(&object.CommitObj{TreeHash: treeHash, Author: "Alice", Timestamp: 1700000000, Message: "init"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var objects []map[string]any
+	var objectBytes int64
+	for _, h := range []object.Hash{commitHash, treeHash, blobHash} {
+		typ, data, err := remoteStore.Read(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objectBytes += int64(len(data))
+		objects = append(objects, map[string]any{"hash": string(h), "type": string(typ), "data": data})
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"objects": objects})
+	}))
+	defer ts.Close()
+
+	var hooked []TransferStats
+	client, err := NewClientWithOptions(ts.URL+"/graft/alice/repo", ClientOptions{Stats: func(s TransferStats) { hooked = append(hooked, s) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := object.NewStore(t.TempDir())
+	if _, err := FetchIntoStoreWithConfig(context.Background(), client, local, []object.Hash{commitHash}, nil, FetchConfig{}); err != nil {
+		t.Fatalf("FetchIntoStoreWithConfig: %v", err)
+	}
+
+	got := client.Stats()
+	if got.Requests != 1 || got.Rounds != 1 {
+		t.Fatalf("requests = %d, rounds = %d; want 1 and 1", got.Requests, got.Rounds)
+	}
+	if got.ObjectsReceived != 3 || got.ObjectBytesReceived != objectBytes {
+		t.Fatalf("received %d objects, %d bytes; want 3 objects, %d bytes", got.ObjectsReceived, got.ObjectBytesReceived, objectBytes)
+	}
+	if got.WireBytesSent == 0 || got.WireBytesReceived == 0 {
+		t.Fatalf("wire bytes = %d sent, %d received; want both counted", got.WireBytesSent, got.WireBytesReceived)
+	}
+	if got.ObjectsSent != 0 || got.ObjectBytesSent != 0 {
+		t.Fatalf("fetch counted sent objects: %+v", got)
+	}
+	if len(hooked) == 0 || hooked[len(hooked)-1] != got {
+		t.Fatalf("hook saw %+v, want final totals %+v", hooked, got)
+	}
+}
+
+func TestPushObjectsRecordsTransferStats(t *testing.T) {
+	isolateHTTPConfig(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hooked TransferStats
+	client.SetStatsHook(func(s TransferStats) { hooked = s })
+	data := []byte("pushed\n")
+	objects := []ObjectRecord{{Hash: object.HashObject(object.TypeBlob, data), Type: object.TypeBlob, Data: data}}
+	if err := client.PushObjects(context.Background(), objects); err != nil {
+		t.Fatalf("PushObjects: %v", err)
+	}
+
+	got := client.Stats()
+	if got.ObjectsSent != 1 || got.ObjectBytesSent != int64(len(data)) || got.Requests != 1 {
+		t.Fatalf("stats = %+v, want 1 object of %d bytes in 1 request", got, len(data))
+	}
+	if got.WireBytesSent <= got.ObjectBytesSent {
+		t.Fatalf("wire bytes sent = %d, want the JSON envelope counted", got.WireBytesSent)
+	}
+	if hooked != got {
+		t.Fatalf("hook saw %+v, want %+v", hooked, got)
+	}
+}
+
+func TestTransferStatsDurationAndRatio(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	stats := newTransferStats(nil)
+	stats.now = func() time.Time { return clock }
+
+	stats.received(ObjectRecord{Data: make([]byte, 300)})
+	clock = clock.Add(2 * time.Second)
+	stats.update(true, func(s *TransferStats) { s.WireBytesReceived += 100 })
+
+	got := stats.snapshot()
+	if got.Duration != 2*time.Second {
+		t.Fatalf("duration = %v, want 2s", got.Duration)
+	}
+	if ratio := got.CompressionRatio(); ratio != 3 {
+		t.Fatalf("compression ratio = %v, want 3", ratio)
+	}
+	if ratio := (TransferStats{ObjectsReceived: 1}).CompressionRatio(); ratio != 0 {
+		t.Fatalf("ratio without wire bytes = %v, want 0", ratio)
+	}
+}
@@ -47,6 +47,20 @@ type FetchConfig struct {
 	Deepen                    int           // deepen an existing shallow clone by N commits
 	Filter                    string        // partial clone filter (e.g., "blob:none")
 	ShallowState              *ShallowState // existing shallow boundaries (read from .graft/shallow)
+	Unshallow                 bool          // fetch the complete history behind existing boundaries
+
+	// ClosureConcurrency is how many requests the closure walk keeps in
+	// flight while fetching objects the negotiation left missing.
+	ClosureConcurrency int
+
+	// Checkpoint, when set, records received objects as they are written so
+	// an interrupted fetch can offer them as haves on retry. It is removed
+	// once the fetch completes.
+	Checkpoint *TransferCheckpoint
+
+	// Progress, when set, receives the running count and size of objects
+	// written to the local store.
+	Progress object.ProgressFunc
 }
 
 // DefaultFetchConfig returns the default FetchIntoStore settings.
@@ -55,13 +69,15 @@ func DefaultFetchConfig() FetchConfig {
 		MaxBatchObjects:           DefaultMaxBatchObjects,
 		MaxBatchHaveHashes:        DefaultMaxBatchHaveHashes,
 		MaxBatchNegotiationRounds: DefaultMaxBatchNegotiationRounds,
+		ClosureConcurrency:        DefaultClosureConcurrency,
 	}
 }
 
 // FetchIntoStore fetches all objects reachable from wants into the local store.
 //
 // It starts with batch negotiation, then guarantees closure by walking the
-// object graph locally and fetching any still-missing object via GetObject.
+// object graph locally and fetching any still-missing objects, batching
+// wants and running several requests concurrently.
 func FetchIntoStore(ctx context.Context, c *Client, store *object.Store, wants, haves []object.Hash) (int, error) {
 	return FetchIntoStoreWithConfig(ctx, c, store, wants, haves, FetchConfig{})
 }
@@ -83,6 +99,10 @@ func FetchIntoStoreWithConfig(ctx context.Context, c *Client, store *object.Stor
 	return result.Written, nil
 }
 
+// checkpointRecordBatch is how many streamed objects are received before
+// they are recorded in the transfer checkpoint.
+const checkpointRecordBatch = 1024
+
 // FetchIntoStoreShallow is like FetchIntoStoreWithConfig but returns
 // the full FetchResult including shallow boundary information.
 func FetchIntoStoreShallow(ctx context.Context, c *Client, store *object.Store, wants, haves []object.Hash, cfg FetchConfig) (*FetchResult, error) {
@@ -96,9 +116,11 @@ func FetchIntoStoreShallow(ctx context.Context, c *Client, store *object.Store,
 		return nil, fmt.Errorf("at least one want hash is required")
 	}
 
-	// Build shallow fetch options from config.
+	// Build shallow fetch options from config. A client with existing
+	// boundaries always announces them so the server knows which history it
+	// lacks, even when the fetch itself does not change the depth.
 	var shallowOpts *ShallowFetchOpts
-	isShallow := cfg.Depth > 0 || cfg.Deepen > 0
+	isShallow := cfg.Depth > 0 || cfg.Deepen > 0 || (cfg.ShallowState != nil && cfg.ShallowState.Len() > 0)
 	if isShallow || cfg.Filter != "" {
 		shallowOpts = &ShallowFetchOpts{
 			Depth:  cfg.Depth,
@@ -110,50 +132,72 @@ func FetchIntoStoreShallow(ctx context.Context, c *Client, store *object.Store,
 		}
 	}
 
-	// Track all shallow boundaries from server responses.
+	// Track all shallow boundaries from server responses. Prior boundaries
+	// the server does not re-report are candidates for pruning once their
+	// history has been fetched.
 	resultShallow := NewShallowState()
+	reported := make(map[object.Hash]struct{})
 	if cfg.ShallowState != nil {
 		for _, h := range cfg.ShallowState.List() {
 			resultShallow.Add(h)
 		}
 	}
 
-	knownHaves, knownHaveSet := initKnownHaves(haves)
+	// Objects an interrupted fetch already received are offered after the
+	// ref tips and history samples; their types are not recorded.
+	negotiator := newHaveNegotiator(store, haves)
+	if cfg.Checkpoint != nil {
+		for _, h := range cfg.Checkpoint.Hashes() {
+			negotiator.add(h, "")
+		}
+	}
+	recv := &receiveProgress{fn: cfg.Progress, stats: c.stats}
+	bases := StoreThinPackBases(store)
 	written := 0
 	negotiationCompleted := false
 	for round := 0; round < cfg.MaxBatchNegotiationRounds; round++ {
-		var batchObjects []ObjectRecord
-		var truncated bool
-
-		if shallowOpts != nil {
-			result, err := c.BatchObjectsPackShallow(ctx, roots, selectBatchHaves(knownHaves, cfg.MaxBatchHaveHashes), cfg.MaxBatchObjects, shallowOpts)
-			if err != nil {
-				return nil, err
-			}
-			batchObjects = result.Objects
-			truncated = result.Truncated
-			for _, h := range result.Shallow {
-				resultShallow.Add(h)
-			}
-		} else {
-			var err error
-			batchObjects, truncated, err = c.BatchObjectsPack(ctx, roots, selectBatchHaves(knownHaves, cfg.MaxBatchHaveHashes), cfg.MaxBatchObjects)
-			if err != nil {
-				return nil, err
+		// Objects are written as the response streams in. The server may
+		// delta-encode them against the haves or against objects earlier in
+		// the response; both are read back from the store.
+		newInRound := 0
+		var received []object.Hash
+		flushReceived := func() error {
+			if cfg.Checkpoint == nil || len(received) == 0 {
+				return nil
 			}
+			err := cfg.Checkpoint.Record(received...)
+			received = received[:0]
+			return err
 		}
-
-		newInRound := 0
-		for _, obj := range batchObjects {
+		result, err := c.BatchObjectsStream(ctx, roots, negotiator.selectHaves(cfg.MaxBatchHaveHashes), cfg.MaxBatchObjects, shallowOpts, bases, func(obj ObjectRecord) error {
 			n, err := writeVerifiedObject(store, obj)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			written += n
 			if n > 0 {
 				newInRound++
+				recv.add(obj)
+			}
+			negotiator.add(obj.Hash, obj.Type)
+			if received = append(received, obj.Hash); len(received) >= checkpointRecordBatch {
+				return flushReceived()
 			}
-			knownHaves, knownHaveSet = appendKnownHave(knownHaves, knownHaveSet, obj.Hash)
+			return nil
+		})
+		// Record what arrived even if the stream broke off, so a retry
+		// resumes from it.
+		if ferr := flushReceived(); err == nil {
+			err = ferr
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.stats.round()
+		truncated := result.Truncated
+		for _, h := range result.Shallow {
+			resultShallow.Add(h)
+			reported[h] = struct{}{}
 		}
 
 		if !truncated {
@@ -172,20 +216,35 @@ func FetchIntoStoreShallow(ctx context.Context, c *Client, store *object.Store,
 	}
 
 	// For shallow clones, stop at shallow boundaries instead of fetching the
-	// complete reachable graph. For full clones, run normal closure.
-	if isShallow && resultShallow.Len() > 0 {
-		n, err := ensureGraphClosureShallow(ctx, c, store, roots, resultShallow)
+	// complete reachable graph. For full clones, run normal closure. Boundaries
+	// whose history arrived in the batch are pruned first so the closure walk
+	// descends into the newly fetched commits.
+	stale := make([]object.Hash, 0, resultShallow.Len())
+	for _, h := range resultShallow.List() {
+		if _, ok := reported[h]; !ok {
+			stale = append(stale, h)
+		}
+	}
+	resultShallow.Prune(store, stale)
+	if !cfg.Unshallow && resultShallow.Len() > 0 {
+		n, err := ensureGraphClosureShallow(ctx, c, store, roots, resultShallow, cfg, recv)
 		if err != nil {
 			return nil, err
 		}
 		written += n
 	} else {
-		n, err := ensureGraphClosure(ctx, c, store, roots)
+		n, err := ensureGraphClosure(ctx, c, store, roots, cfg, recv)
 		if err != nil {
 			return nil, err
 		}
 		written += n
 	}
+	if cfg.Unshallow {
+		resultShallow.Prune(store, nil)
+	}
+	if err := cfg.Checkpoint.Remove(); err != nil {
+		return nil, err
+	}
 
 	return &FetchResult{Written: written, ShallowState: resultShallow}, nil
 }
@@ -222,52 +281,34 @@ func resolveFetchConfig(cfg FetchConfig) (FetchConfig, error) {
 		)
 	}
 
-	// Carry forward shallow/filter fields unchanged.
+	if cfg.ClosureConcurrency < 0 {
+		return out, fmt.Errorf("closure concurrency must be >= 0 (got %d)", cfg.ClosureConcurrency)
+	}
+	if cfg.ClosureConcurrency > 0 {
+		out.ClosureConcurrency = cfg.ClosureConcurrency
+	}
+
+	// Carry forward shallow/filter and checkpoint fields unchanged.
 	out.Depth = cfg.Depth
 	out.Deepen = cfg.Deepen
 	out.Filter = cfg.Filter
 	out.ShallowState = cfg.ShallowState
+	out.Unshallow = cfg.Unshallow
+	out.Checkpoint = cfg.Checkpoint
+	out.Progress = cfg.Progress
 
 	return out, nil
 }
 
-func initKnownHaves(haves []object.Hash) ([]object.Hash, map[object.Hash]struct{}) {
-	haveSet := make(map[object.Hash]struct{}, len(haves))
-	haveList := make([]object.Hash, 0, len(haves))
-	for _, h := range uniqueHashes(haves) {
-		haveList = append(haveList, h)
-		haveSet[h] = struct{}{}
-	}
-	return haveList, haveSet
-}
-
-func appendKnownHave(haveList []object.Hash, haveSet map[object.Hash]struct{}, h object.Hash) ([]object.Hash, map[object.Hash]struct{}) {
-	h = object.Hash(strings.TrimSpace(string(h)))
-	if h == "" {
-		return haveList, haveSet
-	}
-	if _, ok := haveSet[h]; ok {
-		return haveList, haveSet
-	}
-	haveSet[h] = struct{}{}
-	haveList = append(haveList, h)
-	return haveList, haveSet
-}
-
-func selectBatchHaves(haves []object.Hash, max int) []object.Hash {
-	if max <= 0 || len(haves) <= max {
-		out := make([]object.Hash, len(haves))
-		copy(out, haves)
-		return out
-	}
-	out := make([]object.Hash, max)
-	copy(out, haves[len(haves)-max:])
-	return out
-}
-
 // CollectObjectsForPush returns objects reachable from roots excluding objects
 // in stopRoots (and anything reachable from stopRoots).
 func CollectObjectsForPush(store *object.Store, roots, stopRoots []object.Hash) ([]ObjectRecord, error) {
+	return CollectObjectsForPushWithProgress(store, roots, stopRoots, nil)
+}
+
+// CollectObjectsForPushWithProgress is CollectObjectsForPush, reporting the
+// running object count and size to progress as objects are collected.
+func CollectObjectsForPushWithProgress(store *object.Store, roots, stopRoots []object.Hash, progress object.ProgressFunc) ([]ObjectRecord, error) {
 	roots = uniqueHashes(roots)
 	if len(roots) == 0 {
 		return nil, fmt.Errorf("at least one root hash is required")
@@ -283,6 +324,7 @@ func CollectObjectsForPush(store *object.Store, roots, stopRoots []object.Hash)
 	stack = append(stack, roots...)
 
 	objects := make([]ObjectRecord, 0, collectObjectsInitialCapacity)
+	var collected int64
 	for len(stack) > 0 {
 		h := stack[len(stack)-1]
 		stack = stack[:len(stack)-1]
@@ -302,6 +344,10 @@ func CollectObjectsForPush(store *object.Store, roots, stopRoots []object.Hash)
 			return nil, fmt.Errorf("read object %s: %w", h, err)
 		}
 		objects = append(objects, ObjectRecord{Hash: h, Type: objType, Data: data})
+		if progress != nil {
+			collected += int64(len(data))
+			progress(object.Progress{Phase: object.ProgressCounting, Objects: len(objects), Bytes: collected})
+		}
 
 		refs, err := referencedHashes(objType, data)
 		if err != nil {
@@ -353,126 +399,26 @@ func ReachableSet(store *object.Store, roots []object.Hash) (map[object.Hash]str
 	return out, nil
 }
 
-// ensureGraphClosureShallow walks the object graph from roots and fetches
-// any missing objects, but stops at shallow boundaries instead of trying
-// to fetch parent commits beyond the shallow depth.
-func ensureGraphClosureShallow(ctx context.Context, c *Client, store *object.Store, roots []object.Hash, shallow *ShallowState) (int, error) {
-	written := 0
-	seen := make(map[object.Hash]struct{}, len(roots))
-	stack := make([]object.Hash, 0, len(roots))
-	stack = append(stack, roots...)
-
-	for len(stack) > 0 {
-		h := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-		if h == "" {
-			continue
-		}
-		if _, ok := seen[h]; ok {
-			continue
-		}
-		seen[h] = struct{}{}
-
-		if !store.Has(h) {
-			// If this hash is a shallow boundary, skip fetching it.
-			if shallow.IsShallow(h) {
-				continue
-			}
-			obj, err := c.GetObject(ctx, h)
-			if err != nil {
-				return written, err
-			}
-			n, err := writeVerifiedObject(store, obj)
-			if err != nil {
-				return written, err
-			}
-			written += n
-		}
-
-		objType, data, err := store.Read(h)
-		if err != nil {
-			// If the object is a shallow boundary, we may not have it locally.
-			if shallow.IsShallow(h) {
-				continue
-			}
-			return written, fmt.Errorf("read object %s: %w", h, err)
-		}
-
-		refs, err := referencedHashes(objType, data)
-		if err != nil {
-			return written, fmt.Errorf("parse object %s (%s): %w", h, objType, err)
-		}
-
-		// For commit objects, filter out parent hashes that are shallow boundaries.
-		if objType == object.TypeCommit {
-			commit, parseErr := object.UnmarshalCommit(data)
-			if parseErr == nil {
-				var filtered []object.Hash
-				// Always include the tree hash.
-				filtered = append(filtered, commit.TreeHash)
-				// Only include parents that are not shallow boundaries.
-				for _, p := range commit.Parents {
-					if !shallow.IsShallow(p) {
-						filtered = append(filtered, p)
-					}
-				}
-				stack = append(stack, filtered...)
-				continue
-			}
-		}
-
-		stack = append(stack, refs...)
-	}
-
-	return written, nil
+// receiveProgress reports objects newly written during a fetch and records
+// them in the client's transfer stats.
+type receiveProgress struct {
+	fn      object.ProgressFunc
+	stats   *transferStats
+	objects int
+	bytes   int64
 }
 
-func ensureGraphClosure(ctx context.Context, c *Client, store *object.Store, roots []object.Hash) (int, error) {
-	written := 0
-	seen := make(map[object.Hash]struct{}, len(roots))
-	stack := make([]object.Hash, 0, len(roots))
-	stack = append(stack, roots...)
-
-	for len(stack) > 0 {
-		h := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-		if h == "" {
-			continue
-		}
-		if _, ok := seen[h]; ok {
-			continue
-		}
-		seen[h] = struct{}{}
-
-		if !store.Has(h) {
-			obj, err := c.GetObject(ctx, h)
-			if err != nil {
-				return written, err
-			}
-			n, err := writeVerifiedObject(store, obj)
-			if err != nil {
-				return written, err
-			}
-			written += n
-		}
-
-		// NOTE: The store.Read here re-reads data we may have just
-		// fetched above. This is intentional for correctness — the
-		// object may have already been present locally (skipping the
-		// fetch branch), and reading from the store is the uniform
-		// path that works for both cases.
-		objType, data, err := store.Read(h)
-		if err != nil {
-			return written, fmt.Errorf("read object %s: %w", h, err)
-		}
-		refs, err := referencedHashes(objType, data)
-		if err != nil {
-			return written, fmt.Errorf("parse object %s (%s): %w", h, objType, err)
-		}
-		stack = append(stack, refs...)
+func (p *receiveProgress) add(obj ObjectRecord) {
+	if p == nil {
+		return
 	}
-
-	return written, nil
+	p.stats.received(obj)
+	if p.fn == nil {
+		return
+	}
+	p.objects++
+	p.bytes += int64(len(obj.Data))
+	p.fn(object.Progress{Phase: object.ProgressReceiving, Objects: p.objects, Bytes: p.bytes})
 }
 
 func writeVerifiedObject(store *object.Store, obj ObjectRecord) (int, error) {
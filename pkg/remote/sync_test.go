@@ -10,7 +10,9 @@ import (
 	"net/http/httptest"
 	"path"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/odvcencio/graft/pkg/object"
 )
@@ -562,3 +564,273 @@ func TestFetchIntoStoreWithPackTransport(t *testing.T) {
 		}
 	}
 }
+
+func TestFetchIntoStoreResumesFromCheckpoint(t *testing.T) {
+	remoteStore := object.NewStore(t.TempDir())
+	blobHash, err := remoteStore.WriteBlob(&object.Blob{Data: []byte("hello\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeHash, err := remoteStore.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{{Name: "README.md", BlobHash: blobHash}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitHash, err := remoteStore.WriteCommit(&object.CommitObj{TreeHash: treeHash, Author: "Alice", Timestamp: 1700000000, Message: "init"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(h object.Hash) map[string]any {
+		typ, data, err := remoteStore.Read(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]any{"hash": string(h), "type": string(typ), "data": data}
+	}
+
+	var requests int
+	var resumedHaves []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Haves []string `json:"haves"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch requests {
+		case 1:
+			_ = json.NewEncoder(w).Encode(map[string]any{"objects": []map[string]any{record(commitHash)}, "truncated": true})
+		case 2:
+			http.Error(w, "connection reset", http.StatusBadRequest)
+		default:
+			resumedHaves = req.Haves
+			_ = json.NewEncoder(w).Encode(map[string]any{"objects": []map[string]any{record(treeHash), record(blobHash)}})
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graftDir := t.TempDir()
+	localStore := object.NewStore(graftDir)
+	open := func() *TransferCheckpoint {
+		cp, err := OpenTransferCheckpoint(graftDir, CheckpointFetch, ts.URL)
+		if err != nil {
+			t.Fatalf("OpenTransferCheckpoint: %v", err)
+		}
+		return cp
+	}
+
+	if _, err := FetchIntoStoreWithConfig(context.Background(), client, localStore, []object.Hash{commitHash}, nil, FetchConfig{Checkpoint: open()}); err == nil {
+		t.Fatal("expected the interrupted fetch to fail")
+	}
+	cp := open()
+	if !cp.Has(commitHash) {
+		t.Fatalf("checkpoint = %v, want commit %s", cp.Hashes(), commitHash)
+	}
+
+	written, err := FetchIntoStoreWithConfig(context.Background(), client, localStore, []object.Hash{commitHash}, nil, FetchConfig{Checkpoint: cp})
+	if err != nil {
+		t.Fatalf("resumed fetch: %v", err)
+	}
+	if written != 2 {
+		t.Fatalf("resumed fetch wrote %d objects, want 2", written)
+	}
+	if len(resumedHaves) != 1 || resumedHaves[0] != string(commitHash) {
+		t.Fatalf("resumed haves = %v, want [%s]", resumedHaves, commitHash)
+	}
+	if open().Len() != 0 {
+		t.Fatal("checkpoint not removed after a completed fetch")
+	}
+}
+
+func TestFetchIntoStoreReportsProgress(t *testing.T) {
+	remoteStore := object.NewStore(t.TempDir())
+	blobHash, err := remoteStore.WriteBlob(&object.Blob{Data: []byte("hello\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeHash, err := remoteStore.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{{Name: "README.md", BlobHash: blobHash}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitHash, err := remoteStore.WriteCommit(&object.CommitObj{TreeHash: treeHash, Author: "Alice", Timestamp: 1700000000, Message: "init"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var objects []map[string]any
+	for _, h := range []object.Hash{commitHash, treeHash, blobHash} {
+		typ, data, err := remoteStore.Read(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, map[string]any{"hash": string(h), "type": string(typ), "data": data})
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"objects": objects})
+	}))
+	defer ts.Close()
+
+	var updates []object.Progress
+	progress := func(p object.Progress) { updates = append(updates, p) }
+	client, err := NewClientWithOptions(ts.URL+"/graft/alice/repo", ClientOptions{Progress: progress})
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := object.NewStore(t.TempDir())
+	if _, err := FetchIntoStoreWithConfig(context.Background(), client, local, []object.Hash{commitHash}, nil, FetchConfig{Progress: progress}); err != nil {
+		t.Fatalf("FetchIntoStoreWithConfig: %v", err)
+	}
+
+	var downloaded int64
+	var received object.Progress
+	for _, p := range updates {
+		switch p.Phase {
+		case object.ProgressDownloading:
+			downloaded = p.Bytes
+		case object.ProgressReceiving:
+			received = p
+		}
+	}
+	if downloaded == 0 {
+		t.Fatalf("no download progress reported: %+v", updates)
+	}
+	if received.Objects != 3 || received.Bytes == 0 {
+		t.Fatalf("final receiving progress = %+v, want 3 objects", received)
+	}
+
+	var counted object.Progress
+	if _, err := CollectObjectsForPushWithProgress(local, []object.Hash{commitHash}, nil, func(p object.Progress) { counted = p }); err != nil {
+		t.Fatalf("CollectObjectsForPushWithProgress: %v", err)
+	}
+	if counted.Phase != object.ProgressCounting || counted.Objects != 3 || counted.Bytes != received.Bytes {
+		t.Fatalf("counting progress = %+v, want 3 objects and %d bytes", counted, received.Bytes)
+	}
+}
+
+func TestFetchIntoStoreClosureBatchesMissingObjectsConcurrently(t *testing.T) {
+	remoteStore := object.NewStore(t.TempDir())
+
+	const files = 2*closureBatchWants + 10
+	entries := make([]object.TreeEntry, 0, files)
+	for i := 0; i < files; i++ {
+		h, err := remoteStore.WriteBlob(&object.Blob{Data: []byte(fmt.Sprintf("file %d\n", i))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, object.TreeEntry{Name: fmt.Sprintf("f%04d.txt", i), BlobHash: h})
+	}
+	treeHash, err := remoteStore.WriteTree(&object.TreeObj{Entries: entries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitHash, err := remoteStore.WriteCommit(&object.CommitObj{
+		TreeHash:  treeHash,
+		Author:    "Alice <alice@example.com>",
+		Timestamp: 1700000000,
+		Message:   "init",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu          sync.Mutex
+		inFlight    int
+		maxInFlight int
+		batches     int
+		gets        int
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/graft/alice/repo/objects/batch":
+			var req struct {
+				Wants []string `json:"wants"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if len(req.Wants) > closureBatchWants {
+				http.Error(w, "too many wants", http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			batches++
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			}()
+			time.Sleep(50 * time.Millisecond)
+
+			// Only the wanted objects are returned, never their closure.
+			objects := make([]map[string]any, 0, len(req.Wants))
+			for _, want := range req.Wants {
+				objType, data, err := remoteStore.Read(object.Hash(want))
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				objects = append(objects, map[string]any{"hash": want, "type": string(objType), "data": data})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"objects": objects})
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/graft/alice/repo/objects/"):
+			mu.Lock()
+			gets++
+			mu.Unlock()
+			http.Error(w, "unexpected point fetch", http.StatusInternalServerError)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	localStore := object.NewStore(t.TempDir())
+
+	written, err := FetchIntoStoreWithConfig(context.Background(), client, localStore, []object.Hash{commitHash}, nil, FetchConfig{ClosureConcurrency: 4})
+	if err != nil {
+		t.Fatalf("FetchIntoStoreWithConfig: %v", err)
+	}
+	if written != files+2 {
+		t.Fatalf("written = %d, want %d", written, files+2)
+	}
+	for _, e := range entries {
+		if !localStore.Has(e.BlobHash) {
+			t.Fatalf("missing blob %s", e.BlobHash)
+		}
+	}
+	// One negotiation batch, one for the tree and three for the blobs.
+	if batches != 5 {
+		t.Fatalf("batch requests = %d, want 5", batches)
+	}
+	if gets != 0 {
+		t.Fatalf("point fetches = %d, want 0", gets)
+	}
+	if maxInFlight < 2 {
+		t.Fatalf("max concurrent batch requests = %d, want at least 2", maxInFlight)
+	}
+}
+
+func TestResolveFetchConfigClosureConcurrency(t *testing.T) {
+	cfg, err := resolveFetchConfig(FetchConfig{})
+	if err != nil {
+		t.Fatalf("resolveFetchConfig(default): %v", err)
+	}
+	if cfg.ClosureConcurrency != DefaultClosureConcurrency {
+		t.Fatalf("default closure concurrency = %d, want %d", cfg.ClosureConcurrency, DefaultClosureConcurrency)
+	}
+	if _, err := resolveFetchConfig(FetchConfig{ClosureConcurrency: -1}); err == nil {
+		t.Fatal("expected negative closure concurrency error, got nil")
+	}
+}
@@ -0,0 +1,145 @@
+package remote
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// thinPackMinObjectSize is the smallest blob worth delta-encoding against a
+// base the receiver already has; below it the delta header overhead and the
+// base lookup cost outweigh any savings.
+const thinPackMinObjectSize = 512
+
+// ThinPackBase is an object the receiver already has that can serve as the
+// REF_DELTA base for a pushed or fetched object.
+type ThinPackBase struct {
+	Hash object.Hash
+	Data []byte
+}
+
+// thinPackDeltaWorthwhile reports whether a delta is small enough, relative to
+// the full object, to send in its place.
+func thinPackDeltaWorthwhile(deltaSize, objectSize int) bool {
+	return objectSize >= thinPackMinObjectSize && deltaSize < objectSize/2
+}
+
+// FindThinPackBases pairs blobs in records with blobs the receiver already
+// has at the same path. haves are commits the receiver is known to have
+// (remote ref tips when pushing, the haves of a batch request when serving a
+// fetch); earlier haves take precedence. The returned map is keyed by the
+// hash of the blob being sent and is suitable for EncodeThinPackTransport.
+func FindThinPackBases(store *object.Store, records []ObjectRecord, haves []object.Hash) (map[object.Hash]ThinPackBase, error) {
+	pending := make(map[object.Hash]struct{})
+	var commits []object.Hash
+	for _, rec := range records {
+		switch rec.Type {
+		case object.TypeBlob:
+			if len(rec.Data) >= thinPackMinObjectSize {
+				pending[rec.Hash] = struct{}{}
+			}
+		case object.TypeCommit:
+			commits = append(commits, rec.Hash)
+		}
+	}
+	if len(pending) == 0 || len(commits) == 0 || len(haves) == 0 {
+		return nil, nil
+	}
+
+	haveBlobs := make(map[string]object.Hash)
+	visited := make(map[object.Hash]struct{})
+	for _, h := range haves {
+		commit, err := store.ReadCommit(h)
+		if err != nil {
+			continue
+		}
+		err = walkTreeBlobs(store, commit.TreeHash, "", visited, func(p string, blob object.Hash) {
+			if _, ok := haveBlobs[p]; !ok {
+				haveBlobs[p] = blob
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pairs := make(map[object.Hash]object.Hash)
+	visited = make(map[object.Hash]struct{})
+	for _, h := range commits {
+		commit, err := store.ReadCommit(h)
+		if err != nil {
+			return nil, fmt.Errorf("thin pack: read commit %s: %w", h, err)
+		}
+		err = walkTreeBlobs(store, commit.TreeHash, "", visited, func(p string, blob object.Hash) {
+			if _, ok := pending[blob]; !ok {
+				return
+			}
+			if _, ok := pairs[blob]; ok {
+				return
+			}
+			if base, ok := haveBlobs[p]; ok && base != blob {
+				pairs[blob] = base
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bases := make(map[object.Hash]ThinPackBase, len(pairs))
+	for target, base := range pairs {
+		objType, data, err := store.Read(base)
+		if err != nil || objType != object.TypeBlob {
+			continue
+		}
+		bases[target] = ThinPackBase{Hash: base, Data: data}
+	}
+	return bases, nil
+}
+
+// walkTreeBlobs calls fn for every blob reachable from tree, with its path.
+// Subtrees already in visited are skipped.
+func walkTreeBlobs(store *object.Store, tree object.Hash, prefix string, visited map[object.Hash]struct{}, fn func(path string, blob object.Hash)) error {
+	if tree == "" {
+		return nil
+	}
+	if _, ok := visited[tree]; ok {
+		return nil
+	}
+	visited[tree] = struct{}{}
+
+	t, err := store.ReadTree(tree)
+	if err != nil {
+		return fmt.Errorf("thin pack: read tree %s: %w", tree, err)
+	}
+	for _, entry := range t.Entries {
+		p := path.Join(prefix, entry.Name)
+		if entry.IsDir {
+			if err := walkTreeBlobs(store, entry.SubtreeHash, p, visited, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if entry.BlobHash != "" {
+			fn(p, entry.BlobHash)
+		}
+	}
+	return nil
+}
+
+// StoreThinPackBases returns a lookup that resolves thin pack bases from
+// store, for use with DecodeThinPackTransport.
+func StoreThinPackBases(store *object.Store) object.ThinPackBaseLookup {
+	return func(h object.Hash) (object.PackObjectType, []byte, error) {
+		objType, data, err := store.Read(h)
+		if err != nil {
+			return 0, nil, err
+		}
+		packType, ok := objectTypeToPackType(objType)
+		if !ok {
+			return 0, nil, fmt.Errorf("thin pack base %s has unsupported type %q", h, objType)
+		}
+		return packType, data, nil
+	}
+}
@@ -0,0 +1,198 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestFindThinPackBasesAndThinPackRoundTrip(t *testing.T) {
+	store := object.NewStore(t.TempDir())
+
+	writeCommit := func(lockfile, readme string, parents ...object.Hash) object.Hash {
+		t.Helper()
+		lockHash, err := store.Write(object.TypeBlob, []byte(lockfile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		readmeHash, err := store.Write(object.TypeBlob, []byte(readme))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub, err := store.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{{Name: "deps.lock", BlobHash: lockHash}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := store.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{
+			{Name: "README.md", BlobHash: readmeHash},
+			{Name: "vendor", IsDir: true, SubtreeHash: sub},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := store.WriteCommit(&object.CommitObj{
+			TreeHash:  root,
+			Parents:   parents,
+			Author:    "Alice <alice@example.com>",
+			Timestamp: 1700000000,
+			Message:   "update",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	var lock strings.Builder
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&lock, "pkg-%03d 1.0.%d sha256-%064d\n", i, i%5, i)
+	}
+	oldLock := lock.String()
+	newLock := strings.Replace(oldLock, "pkg-150 1.0.0", "pkg-150 1.1.0", 1)
+
+	remoteTip := writeCommit(oldLock, "readme v1\n")
+	localTip := writeCommit(newLock, "readme v2\n", remoteTip)
+
+	records, err := CollectObjectsForPush(store, []object.Hash{localTip}, []object.Hash{remoteTip})
+	if err != nil {
+		t.Fatalf("CollectObjectsForPush: %v", err)
+	}
+	bases, err := FindThinPackBases(store, records, []object.Hash{remoteTip})
+	if err != nil {
+		t.Fatalf("FindThinPackBases: %v", err)
+	}
+	newLockHash := object.HashObject(object.TypeBlob, []byte(newLock))
+	oldLockHash := object.HashObject(object.TypeBlob, []byte(oldLock))
+	if len(bases) != 1 || bases[newLockHash].Hash != oldLockHash {
+		t.Fatalf("bases = %v, want only deps.lock paired with its previous version", bases)
+	}
+
+	var thin, full bytes.Buffer
+	if err := EncodeThinPackTransport(&thin, records, bases); err != nil {
+		t.Fatalf("EncodeThinPackTransport: %v", err)
+	}
+	if err := EncodePackTransport(&full, records); err != nil {
+		t.Fatalf("EncodePackTransport: %v", err)
+	}
+	if thin.Len() >= full.Len() {
+		t.Fatalf("thin pack is %d bytes, full pack %d; want thin pack smaller", thin.Len(), full.Len())
+	}
+
+	if _, err := DecodePackTransport(thin.Bytes()); err == nil {
+		t.Fatal("DecodePackTransport resolved a thin pack without external bases")
+	}
+	decoded, err := DecodeThinPackTransport(thin.Bytes(), StoreThinPackBases(store))
+	if err != nil {
+		t.Fatalf("DecodeThinPackTransport: %v", err)
+	}
+	if len(decoded) != len(records) {
+		t.Fatalf("decoded %d records, want %d", len(decoded), len(records))
+	}
+	want := make(map[object.Hash]object.ObjectType, len(records))
+	for _, rec := range records {
+		want[rec.Hash] = rec.Type
+	}
+	for _, rec := range decoded {
+		if typ, ok := want[rec.Hash]; !ok || typ != rec.Type {
+			t.Fatalf("decoded unexpected record %s (%s)", rec.Hash, rec.Type)
+		}
+	}
+}
+
+func TestFetchIntoStoreResolvesThinPackResponse(t *testing.T) {
+	remoteStore := object.NewStore(t.TempDir())
+	var lock strings.Builder
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&lock, "pkg-%03d 1.0.%d sha256-%064d\n", i, i%5, i)
+	}
+	writeCommit := func(content string, parents ...object.Hash) object.Hash {
+		t.Helper()
+		blob, err := remoteStore.Write(object.TypeBlob, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := remoteStore.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{{Name: "deps.lock", BlobHash: blob}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := remoteStore.WriteCommit(&object.CommitObj{TreeHash: tree, Parents: parents, Author: "Alice", Timestamp: 1700000000, Message: "update"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	oldTip := writeCommit(lock.String())
+	newLock := strings.Replace(lock.String(), "pkg-150 1.0.0", "pkg-150 1.1.0", 1)
+	newTip := writeCommit(newLock, oldTip)
+
+	// The client already has the old commit.
+	localStore := object.NewStore(t.TempDir())
+	existing, err := CollectObjectsForPush(remoteStore, []object.Hash{oldTip}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range existing {
+		if _, err := localStore.Write(rec.Type, rec.Data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deltas := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/graft/alice/repo/objects/batch" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req struct {
+			Wants []object.Hash `json:"wants"`
+			Haves []object.Hash `json:"haves"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		records, err := CollectObjectsForPush(remoteStore, req.Wants, req.Haves)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var bases map[object.Hash]ThinPackBase
+		if ParseCapabilities(r.Header.Get(headerCapabilities)).Has(CapThinPack) {
+			if bases, err = FindThinPackBases(remoteStore, records, req.Haves); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		deltas += len(bases)
+		w.Header().Set("Content-Type", "application/x-graft-pack")
+		if err := EncodeThinPackTransport(w, records, bases); err != nil {
+			t.Errorf("EncodeThinPackTransport: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL + "/graft/alice/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := FetchIntoStore(context.Background(), client, localStore, []object.Hash{newTip}, []object.Hash{oldTip})
+	if err != nil {
+		t.Fatalf("FetchIntoStore: %v", err)
+	}
+	if deltas != 1 {
+		t.Fatalf("server sent %d deltas, want 1", deltas)
+	}
+	if written != 3 {
+		t.Fatalf("written = %d, want commit, tree and blob", written)
+	}
+	if _, data, err := localStore.Read(object.HashObject(object.TypeBlob, []byte(newLock))); err != nil || string(data) != newLock {
+		t.Fatalf("delta-encoded blob not reconstructed: %v", err)
+	}
+}
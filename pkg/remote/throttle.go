@@ -0,0 +1,155 @@
+package remote
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tokenBucket limits a byte stream to rate bytes per second, allowing bursts
+// of up to one second's worth of bytes. It is shared by every request of a
+// client, so concurrent transfers split the rate between them.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(bytesPerSecond int64) *tokenBucket {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	rate := float64(bytesPerSecond)
+	return &tokenBucket{rate: rate, burst: rate, tokens: rate, now: time.Now}
+}
+
+// wait blocks until n bytes may pass or ctx is done. A nil bucket never
+// blocks.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	// Take the tokens up front, going into debt if needed, so later callers
+	// queue behind this one instead of racing for the refill.
+	b.tokens -= float64(n)
+	deficit := -b.tokens
+	b.mu.Unlock()
+	if deficit <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttledReader passes reads through a token bucket. Reads are capped at
+// the bucket's burst so one large read cannot exceed the rate for long.
+type throttledReader struct {
+	ctx    context.Context
+	r      io.ReadCloser
+	bucket *tokenBucket
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if limit := int(t.bucket.burst); len(p) > limit {
+		p = p[:max(limit, 1)]
+	}
+	n, err := t.r.Read(p)
+	if werr := t.bucket.wait(t.ctx, n); werr != nil && err == nil {
+		err = werr
+	}
+	return n, err
+}
+
+func (t *throttledReader) Close() error { return t.r.Close() }
+
+// throttledTransport enforces upload and download rate limits and a cap on
+// in-flight requests for every request of a client. A request holds its slot
+// until its response body is closed.
+type throttledTransport struct {
+	base     http.RoundTripper
+	upload   *tokenBucket
+	download *tokenBucket
+	slots    chan struct{} // nil means unlimited concurrency
+}
+
+// newThrottledTransport wraps base with the limits in opts, or returns base
+// unchanged when opts sets none.
+func newThrottledTransport(base http.RoundTripper, opts ClientOptions) http.RoundTripper {
+	if opts.MaxUploadRate <= 0 && opts.MaxDownloadRate <= 0 && opts.MaxConcurrentRequests <= 0 {
+		return base
+	}
+	t := &throttledTransport{
+		base:     base,
+		upload:   newTokenBucket(opts.MaxUploadRate),
+		download: newTokenBucket(opts.MaxDownloadRate),
+	}
+	if opts.MaxConcurrentRequests > 0 {
+		t.slots = make(chan struct{}, opts.MaxConcurrentRequests)
+	}
+	return t
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+		case <-ctx.Done():
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+	}
+	release := sync.OnceFunc(func() {
+		if t.slots != nil {
+			<-t.slots
+		}
+	})
+
+	if t.upload != nil && req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(ctx)
+		req.Body = &throttledReader{ctx: ctx, r: req.Body, bucket: t.upload}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	body := resp.Body
+	if t.download != nil {
+		body = &throttledReader{ctx: ctx, r: body, bucket: t.download}
+	}
+	resp.Body = &releasingBody{ReadCloser: body, release: release}
+	return resp, nil
+}
+
+// releasingBody frees a request's concurrency slot when its body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,154 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/userconfig"
+)
+
+func TestTokenBucketRefillsAtRate(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	b := newTokenBucket(1000)
+	b.now = func() time.Time { return clock }
+
+	// The initial burst passes without waiting.
+	if err := b.wait(t.Context(), 1000); err != nil {
+		t.Fatal(err)
+	}
+	if b.tokens != 0 {
+		t.Fatalf("tokens after burst = %v, want 0", b.tokens)
+	}
+	clock = clock.Add(250 * time.Millisecond)
+	if err := b.wait(t.Context(), 200); err != nil {
+		t.Fatal(err)
+	}
+	if b.tokens != 50 {
+		t.Fatalf("tokens after refill = %v, want 50", b.tokens)
+	}
+	// Refill never exceeds one second's worth.
+	clock = clock.Add(time.Hour)
+	if err := b.wait(t.Context(), 0); err != nil || b.tokens != 50 {
+		t.Fatalf("wait(0) changed tokens to %v, %v", b.tokens, err)
+	}
+	if err := b.wait(t.Context(), 1); err != nil || b.tokens != 999 {
+		t.Fatalf("tokens after idle = %v, %v; want 999", b.tokens, err)
+	}
+
+	// A request that overdraws the bucket waits for the deficit and stops
+	// early when its context is cancelled.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := b.wait(ctx, 5000); err != context.Canceled {
+		t.Fatalf("wait on cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestThrottledTransportLimitsDownloadRate(t *testing.T) {
+	isolateHTTPConfig(t)
+	payload := bytes.Repeat([]byte("x"), 30<<10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(payload)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: newThrottledTransport(http.DefaultTransport, ClientOptions{MaxDownloadRate: 20 << 10})}
+	start := time.Now()
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("body = %d bytes, %v; want %d bytes", len(got), err, len(payload))
+	}
+	// 20KiB pass in the initial burst; the other 10KiB take about 500ms.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("download took %v, want at least 400ms at 20KiB/s", elapsed)
+	}
+}
+
+func TestThrottledTransportCapsConcurrentRequests(t *testing.T) {
+	isolateHTTPConfig(t)
+	var inFlight, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"refs":{}}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClientWithOptions(ts.URL+"/graft/alice/repo", ClientOptions{MaxConcurrentRequests: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Go(func() {
+			if _, err := client.ListRefs(t.Context()); err != nil {
+				t.Errorf("ListRefs: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if got := peak.Load(); got > 2 {
+		t.Fatalf("peak concurrent requests = %d, want at most 2", got)
+	}
+}
+
+func TestResolveHTTPOptionsThrottling(t *testing.T) {
+	isolateHTTPConfig(t)
+	cfg := &userconfig.Config{HTTP: userconfig.HTTPConfig{MaxUploadRate: 1 << 20, MaxDownloadRate: 4 << 20, MaxConcurrentRequests: 3}}
+
+	got, err := resolveHTTPOptions(ClientOptions{MaxDownloadRate: 512 << 10}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MaxUploadRate != 1<<20 || got.MaxDownloadRate != 512<<10 || got.MaxConcurrentRequests != 3 {
+		t.Fatalf("throttling = up %d, down %d, concurrent %d", got.MaxUploadRate, got.MaxDownloadRate, got.MaxConcurrentRequests)
+	}
+	if rt := newThrottledTransport(http.DefaultTransport, ClientOptions{}); rt != http.DefaultTransport {
+		t.Fatal("newThrottledTransport wrapped the transport without any limits")
+	}
+}
+
+func TestThrottledTransportLimitsUploadRate(t *testing.T) {
+	isolateHTTPConfig(t)
+	var received atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		received.Store(n)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: newThrottledTransport(http.DefaultTransport, ClientOptions{MaxUploadRate: 20 << 10})}
+	start := time.Now()
+	resp, err := client.Post(ts.URL, "application/octet-stream", strings.NewReader(strings.Repeat("y", 30<<10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if received.Load() != 30<<10 {
+		t.Fatalf("server received %d bytes, want %d", received.Load(), 30<<10)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("upload took %v, want at least 400ms at 20KiB/s", elapsed)
+	}
+}
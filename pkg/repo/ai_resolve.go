@@ -179,6 +179,7 @@ func (r *Repo) markConflictResolved(path string) error {
 		return err
 	}
 
+	stg.recordResolveUndo(path)
 	entry.BlobHash = blob
 	entry.Conflict = false
 	entry.BaseBlobHash = ""
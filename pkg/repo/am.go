@@ -0,0 +1,311 @@
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// am keeps its state in .graft/rebase-apply, as git does: the remaining
+// patches as numbered mailbox files, the number of the next one to apply
+// ("next") and of the last ("last"), and the HEAD to restore on abort.
+
+// AMOptions controls AM.
+type AMOptions struct {
+	// ThreeWay falls back to a three-way merge when a patch does not apply
+	// cleanly, as ApplyOptions.ThreeWay does.
+	ThreeWay bool
+}
+
+// AMResult lists the commits am created, oldest first.
+type AMResult struct {
+	Commits []object.Hash
+	Patches []*Patch
+}
+
+// ErrAMStopped is returned when am stops at a patch that does not apply
+// or that left conflicts. The state is saved so the user can fix the
+// working tree and run --continue, or run --skip or --abort.
+type ErrAMStopped struct {
+	Patch     int
+	Subject   string
+	Conflicts []string
+	Err       error
+}
+
+func (e *ErrAMStopped) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "am: patch %d (%s) failed: %v\n", e.Patch, e.Subject, e.Err)
+	if len(e.Conflicts) > 0 {
+		b.WriteString("resolve the conflicts, stage the files and run 'graft am --continue'")
+	} else {
+		b.WriteString("apply the change by hand, stage it and run 'graft am --continue', or use --skip or --abort")
+	}
+	return b.String()
+}
+
+func (e *ErrAMStopped) Unwrap() error { return e.Err }
+
+// ErrNoAMInProgress is returned when --continue/--abort/--skip is called
+// with no active am session.
+var ErrNoAMInProgress = fmt.Errorf("am: no am session in progress")
+
+func (r *Repo) amSeq() *sequencer {
+	return newSequencer(filepath.Join(r.GraftDir, "rebase-apply"))
+}
+
+// IsAMInProgress returns true if am has stopped at a patch.
+func (r *Repo) IsAMInProgress() bool {
+	return r.amSeq().IsActive()
+}
+
+// AM applies patches in order, committing each with the author, date and
+// message recorded in it. It stops with *ErrAMStopped at the first patch
+// that does not apply.
+func (r *Repo) AM(patches []*Patch, opts AMOptions) (*AMResult, error) {
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("am: no patches to apply")
+	}
+	if r.IsAMInProgress() {
+		return nil, fmt.Errorf("am: an am session is already in progress; use --continue, --abort, or --skip")
+	}
+	for i, p := range patches {
+		if p.Subject == "" {
+			return nil, fmt.Errorf("am: patch %d has no subject; am needs mailbox patches from format-patch", i+1)
+		}
+	}
+	origHead, err := r.readHeadHash()
+	if err != nil {
+		return nil, fmt.Errorf("am: resolve HEAD: %w", err)
+	}
+	if origHead == "" {
+		return nil, fmt.Errorf("am: HEAD has no commits yet; make an initial commit before applying patches")
+	}
+	// Each patch commits the whole index, so anything already staged would
+	// be swept into the first am commit.
+	if err := r.ensureIndexMatchesHead(); err != nil {
+		return nil, fmt.Errorf("am: %w", err)
+	}
+	headName, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("am: read HEAD: %w", err)
+	}
+
+	seq := r.amSeq()
+	if err := seq.Init(); err != nil {
+		return nil, fmt.Errorf("am: mkdir %q: %w", seq.Dir(), err)
+	}
+	files := map[string]string{
+		"next":      "1\n",
+		"last":      strconv.Itoa(len(patches)) + "\n",
+		"orig-head": string(origHead) + "\n",
+		"head-name": headName + "\n",
+		"threeway":  strconv.FormatBool(opts.ThreeWay) + "\n",
+	}
+	for i, p := range patches {
+		var buf bytes.Buffer
+		if err := WritePatch(&buf, p, i+1, len(patches)); err != nil {
+			return nil, err
+		}
+		files[amPatchName(i+1)] = buf.String()
+	}
+	if err := seq.WriteFiles(files); err != nil {
+		seq.Clean()
+		return nil, fmt.Errorf("am: %w", err)
+	}
+	return r.runAM(&AMResult{})
+}
+
+// AMContinue commits the staged resolution of the patch am stopped at and
+// applies the rest.
+func (r *Repo) AMContinue() (*AMResult, error) {
+	if !r.IsAMInProgress() {
+		return nil, ErrNoAMInProgress
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		return nil, fmt.Errorf("am continue: read staging: %w", err)
+	}
+	for _, entry := range stg.Entries {
+		if entry.Conflict {
+			return nil, fmt.Errorf("am continue: %w remain; resolve them and stage the files", ErrMergeConflicts)
+		}
+	}
+
+	next, _, err := r.amPosition()
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.amPatch(next)
+	if err != nil {
+		return nil, err
+	}
+	result := &AMResult{}
+	if err := r.commitAMPatch(p, result); err != nil {
+		return nil, fmt.Errorf("am continue: %w", err)
+	}
+	if err := r.amSeq().WriteFile("next", strconv.Itoa(next+1)+"\n"); err != nil {
+		return nil, fmt.Errorf("am continue: %w", err)
+	}
+	return r.runAM(result)
+}
+
+// AMSkip discards the patch am stopped at, resetting the working tree to
+// HEAD, and applies the rest.
+func (r *Repo) AMSkip() (*AMResult, error) {
+	if !r.IsAMInProgress() {
+		return nil, ErrNoAMInProgress
+	}
+	headHash, err := r.ResolveRef("HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("am skip: resolve HEAD: %w", err)
+	}
+	headCommit, err := r.Store.ReadCommit(headHash)
+	if err != nil {
+		return nil, fmt.Errorf("am skip: read HEAD commit: %w", err)
+	}
+	if err := r.checkoutTree(headCommit); err != nil {
+		return nil, fmt.Errorf("am skip: reset tree: %w", err)
+	}
+	r.invalidateStatusCache()
+
+	next, _, err := r.amPosition()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.amSeq().WriteFile("next", strconv.Itoa(next+1)+"\n"); err != nil {
+		return nil, fmt.Errorf("am skip: %w", err)
+	}
+	return r.runAM(&AMResult{})
+}
+
+// AMAbort cancels the am session, restoring HEAD and the working tree to
+// where they were before it started.
+func (r *Repo) AMAbort() error {
+	if !r.IsAMInProgress() {
+		return ErrNoAMInProgress
+	}
+	seq := r.amSeq()
+	origHead, err := seq.ReadHash("orig-head")
+	if err != nil {
+		return fmt.Errorf("am abort: read orig-head: %w", err)
+	}
+	headName, err := seq.ReadFile("head-name")
+	if err != nil {
+		return fmt.Errorf("am abort: read head-name: %w", err)
+	}
+
+	origCommit, err := r.Store.ReadCommit(origHead)
+	if err != nil {
+		return fmt.Errorf("am abort: read orig commit: %w", err)
+	}
+	if err := r.checkoutTree(origCommit); err != nil {
+		return fmt.Errorf("am abort: checkout: %w", err)
+	}
+	if strings.HasPrefix(headName, "refs/heads/") {
+		currentRef, _ := r.ResolveRef(headName)
+		if err := r.UpdateRefCAS(headName, origHead, currentRef); err != nil {
+			return fmt.Errorf("am abort: restore branch ref: %w", err)
+		}
+		if err := r.setHeadSymbolic(headName); err != nil {
+			return fmt.Errorf("am abort: reattach HEAD: %w", err)
+		}
+	} else if err := r.setHeadDetached(origHead); err != nil {
+		return fmt.Errorf("am abort: set HEAD: %w", err)
+	}
+	r.invalidateStatusCache()
+
+	if err := seq.Clean(); err != nil {
+		return fmt.Errorf("am abort: cleanup: %w", err)
+	}
+	return nil
+}
+
+// runAM applies the saved patches from "next" through "last", appending
+// the commits it makes to result, and removes the state when done.
+func (r *Repo) runAM(result *AMResult) (*AMResult, error) {
+	seq := r.amSeq()
+	next, last, err := r.amPosition()
+	if err != nil {
+		return nil, err
+	}
+	threeWay, _ := seq.ReadFile("threeway")
+
+	for ; next <= last; next++ {
+		p, err := r.amPatch(next)
+		if err != nil {
+			return nil, err
+		}
+		applied, err := r.ApplyPatch(p, ApplyOptions{Index: true, ThreeWay: threeWay == "true"})
+		if err != nil {
+			stopped := &ErrAMStopped{Patch: next, Subject: p.Subject, Err: err}
+			var conflictErr *ErrApplyConflict
+			if errors.As(err, &conflictErr) {
+				stopped.Conflicts = applied.Conflicts
+			}
+			return result, stopped
+		}
+		if err := r.commitAMPatch(p, result); err != nil {
+			return nil, fmt.Errorf("am: patch %d: %w", next, err)
+		}
+		if err := seq.WriteFile("next", strconv.Itoa(next+1)+"\n"); err != nil {
+			return nil, fmt.Errorf("am: %w", err)
+		}
+	}
+
+	if err := seq.Clean(); err != nil {
+		return nil, fmt.Errorf("am: cleanup: %w", err)
+	}
+	return result, nil
+}
+
+// commitAMPatch commits the staging area with the patch's author, date and
+// message, recording the current user as committer.
+func (r *Repo) commitAMPatch(p *Patch, result *AMResult) error {
+	h, err := r.CommitWithOptions(p.Message(), strings.TrimSpace(p.Author), CommitOptions{
+		Date:     p.Date,
+		NoVerify: true,
+	})
+	if err != nil {
+		return err
+	}
+	result.Commits = append(result.Commits, h)
+	result.Patches = append(result.Patches, p)
+	return nil
+}
+
+func (r *Repo) amPosition() (next, last int, err error) {
+	seq := r.amSeq()
+	for name, dst := range map[string]*int{"next": &next, "last": &last} {
+		val, err := seq.ReadFile(name)
+		if err != nil {
+			return 0, 0, fmt.Errorf("am: read %s: %w", name, err)
+		}
+		if *dst, err = strconv.Atoi(val); err != nil {
+			return 0, 0, fmt.Errorf("am: invalid %s %q", name, val)
+		}
+	}
+	return next, last, nil
+}
+
+func (r *Repo) amPatch(n int) (*Patch, error) {
+	seq := r.amSeq()
+	data, err := seq.ReadFile(amPatchName(n))
+	if err != nil {
+		return nil, fmt.Errorf("am: read patch %d: %w", n, err)
+	}
+	patches, err := ParsePatches([]byte(data + "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("am: patch %d: %w", n, err)
+	}
+	return patches[0], nil
+}
+
+func amPatchName(n int) string {
+	return fmt.Sprintf("%04d", n)
+}
@@ -0,0 +1,269 @@
+package repo
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// API change kinds reported by APIDiff. Removed and changed declarations
+// break callers; added ones do not.
+const (
+	APIRemoved = "removed"
+	APIChanged = "changed"
+	APIAdded   = "added"
+)
+
+// APIChange is one public declaration that differs between two revisions.
+type APIChange struct {
+	// Package is the directory holding the declaration; "." for the root.
+	Package string
+	// Path is the file declaring it: the new file, or the old one when it
+	// was removed.
+	Path         string
+	Name         string // e.g. "func Open" or "func (Repo) Commit"
+	Kind         string // APIRemoved, APIChanged or APIAdded
+	OldSignature string
+	NewSignature string
+}
+
+// Breaking reports whether the change can break existing callers.
+func (c APIChange) Breaking() bool {
+	return c.Kind == APIRemoved || c.Kind == APIChanged
+}
+
+// APIDiffReport lists the public API differences between two revisions,
+// sorted by package, then name.
+type APIDiffReport struct {
+	OldCommit object.Hash
+	NewCommit object.Hash
+	Changes   []APIChange
+}
+
+// BreakingCount returns the number of removed and changed declarations.
+func (r *APIDiffReport) BreakingCount() int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Breaking() {
+			n++
+		}
+	}
+	return n
+}
+
+// apiDecl is a public declaration found in one revision.
+type apiDecl struct {
+	pkg, path, name, signature string
+	// compare is the signature as compared across revisions.
+	compare string
+}
+
+// APIDiff compares the public declarations of two revisions by the
+// signatures entity extraction records for them. Declarations are matched
+// by package, kind, receiver and name, so moving one between files of a
+// package is not a change. Which declarations are public depends on the
+// language: capitalized names outside main and internal packages in Go,
+// names without a leading underscore in Python, exported ones in
+// JavaScript and TypeScript, pub ones in Rust, public ones in Java, C#,
+// Kotlin, Swift and PHP, and everything in C and C++ headers. Test files
+// and files in other languages are skipped.
+func (r *Repo) APIDiff(oldRev, newRev string) (*APIDiffReport, error) {
+	oldTree, oldCommit, err := r.resolveDiffTree(oldRev)
+	if err != nil {
+		return nil, err
+	}
+	newTree, newCommit, err := r.resolveDiffTree(newRev)
+	if err != nil {
+		return nil, err
+	}
+	before, err := r.publicAPI(oldTree)
+	if err != nil {
+		return nil, fmt.Errorf("api-diff: %s: %w", oldRev, err)
+	}
+	after, err := r.publicAPI(newTree)
+	if err != nil {
+		return nil, fmt.Errorf("api-diff: %s: %w", newRev, err)
+	}
+
+	report := &APIDiffReport{OldCommit: oldCommit, NewCommit: newCommit}
+	for key, old := range before {
+		cur, ok := after[key]
+		switch {
+		case !ok:
+			report.Changes = append(report.Changes, APIChange{
+				Package: old.pkg, Path: old.path, Name: old.name, Kind: APIRemoved,
+				OldSignature: old.signature,
+			})
+		case cur.compare != old.compare:
+			report.Changes = append(report.Changes, APIChange{
+				Package: cur.pkg, Path: cur.path, Name: cur.name, Kind: APIChanged,
+				OldSignature: old.signature, NewSignature: cur.signature,
+			})
+		}
+	}
+	for key, cur := range after {
+		if _, ok := before[key]; !ok {
+			report.Changes = append(report.Changes, APIChange{
+				Package: cur.pkg, Path: cur.path, Name: cur.name, Kind: APIAdded,
+				NewSignature: cur.signature,
+			})
+		}
+	}
+	sort.Slice(report.Changes, func(i, j int) bool {
+		a, b := report.Changes[i], report.Changes[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Path < b.Path
+	})
+	return report, nil
+}
+
+// publicAPI extracts the public declarations in tree, keyed by package and
+// declaration identity. When a package declares the same identity twice,
+// as Go does with build-tagged files, the first file in path order wins.
+func (r *Repo) publicAPI(tree object.Hash) (map[string]apiDecl, error) {
+	entries, err := r.FlattenTree(tree)
+	if err != nil {
+		return nil, err
+	}
+	decls := make(map[string]apiDecl)
+	for _, e := range entries {
+		ext := path.Ext(e.Path)
+		if !apiLanguages[ext] || isAPITestFile(e.Path) {
+			continue
+		}
+		if ext == ".go" && isInternalGoPath(e.Path) {
+			continue
+		}
+		blob, err := r.Store.ReadBlob(e.BlobHash)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", e.Path, err)
+		}
+		if ext == ".go" && goMainPackage.Match(blob.Data) {
+			continue
+		}
+		el, err := entity.Extract(e.Path, blob.Data)
+		if err != nil {
+			// Files the extractor cannot parse have no API to compare.
+			continue
+		}
+		pkg := path.Dir(e.Path)
+		for i := range el.Entities {
+			ent := &el.Entities[i]
+			if ent.Kind != entity.KindDeclaration || ent.Name == "" || !isPublicDecl(ext, ent) {
+				continue
+			}
+			kind := entity.ShortDeclKind(ent.DeclKind)
+			receiver := receiverType(ent.Receiver)
+			key := pkg + "\x00" + kind + "\x00" + receiver + "\x00" + ent.Name
+			if _, dup := decls[key]; dup {
+				continue
+			}
+			name := kind + " " + ent.Name
+			if receiver != "" {
+				name = fmt.Sprintf("%s (%s) %s", kind, receiver, ent.Name)
+			}
+			decls[key] = apiDecl{pkg: pkg, path: e.Path, name: name, signature: ent.Signature, compare: comparableSignature(ent)}
+		}
+	}
+	return decls, nil
+}
+
+// apiLanguages lists the file extensions APIDiff knows the visibility
+// rules of.
+var apiLanguages = map[string]bool{
+	".go": true, ".py": true,
+	".js": true, ".mjs": true, ".cjs": true, ".jsx": true, ".ts": true, ".tsx": true,
+	".rs": true, ".java": true, ".cs": true, ".kt": true, ".swift": true, ".php": true,
+	".h": true, ".hh": true, ".hpp": true,
+}
+
+var goMainPackage = regexp.MustCompile(`(?m)^package main\s*$`)
+
+// isAPITestFile reports whether p is a test file by the usual naming
+// conventions, whose declarations are not part of the API.
+func isAPITestFile(p string) bool {
+	base := path.Base(p)
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	return strings.HasSuffix(base, "_test.go") ||
+		strings.HasPrefix(base, "test_") || strings.HasSuffix(stem, "_test") ||
+		strings.HasSuffix(stem, ".test") || strings.HasSuffix(stem, ".spec")
+}
+
+// isInternalGoPath reports whether p lies in a Go internal package, which
+// other modules cannot import.
+func isInternalGoPath(p string) bool {
+	for _, dir := range strings.Split(path.Dir(p), "/") {
+		if dir == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublicDecl applies the visibility rules of the language ext names.
+func isPublicDecl(ext string, e *entity.Entity) bool {
+	sig := e.Signature
+	switch ext {
+	case ".go":
+		if !isUpperInitial(e.Name) {
+			return false
+		}
+		return e.Receiver == "" || isUpperInitial(receiverType(e.Receiver))
+	case ".py":
+		return !strings.HasPrefix(e.Name, "_")
+	case ".js", ".mjs", ".cjs", ".jsx", ".ts", ".tsx":
+		return strings.HasPrefix(sig, "export ")
+	case ".rs":
+		return strings.HasPrefix(sig, "pub ")
+	case ".h", ".hh", ".hpp":
+		return true
+	}
+	for _, word := range strings.Fields(sig) {
+		if word == "public" {
+			return true
+		}
+	}
+	return false
+}
+
+// comparableSignature returns e's signature without the name of a Go
+// method's receiver variable, which callers never see.
+func comparableSignature(e *entity.Entity) string {
+	fields := strings.Fields(e.Receiver)
+	if len(fields) < 2 {
+		return e.Signature
+	}
+	return strings.Replace(e.Signature, "("+e.Receiver+")", "("+strings.Join(fields[1:], " ")+")", 1)
+}
+
+func isUpperInitial(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// receiverType reduces a Go receiver such as "r *Repo[K, V]" to its type
+// name, "Repo", so renaming the receiver variable or type parameters does
+// not change a method's identity. Switching between pointer and value
+// receivers still shows up as a signature change.
+func receiverType(receiver string) string {
+	if i := strings.IndexByte(receiver, '['); i >= 0 {
+		receiver = receiver[:i]
+	}
+	fields := strings.Fields(receiver)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(fields[len(fields)-1], "*")
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"testing"
+)
+
+func TestAPIDiff_ReportsRemovedChangedAndAddedDeclarations(t *testing.T) {
+	r := initRepoWithFile(t, "README.md", []byte("api\n"))
+	commitFile(t, r, "lib/lib.go", []byte(`package lib
+
+type Client struct{}
+
+func Open(path string) (*Client, error) { return nil, nil }
+
+func (c *Client) Close() error { return nil }
+
+func (c *Client) Flush() {}
+
+func helper() {}
+`), "v1")
+	commitFile(t, r, "cmd/tool/main.go", []byte("package main\n\nfunc Run() {}\n"), "tool")
+	v1, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+
+	// Close moves to another file unchanged, Open gains a parameter, Flush
+	// is removed, Dial is added, and unexported and main-package changes
+	// are ignored.
+	commitFile(t, r, "lib/lib.go", []byte(`package lib
+
+type Client struct{}
+
+func Open(path string, readOnly bool) (*Client, error) { return nil, nil }
+
+func Dial(addr string) *Client { return nil }
+
+func helper(n int) {}
+`), "v2")
+	commitFile(t, r, "lib/close.go", []byte("package lib\n\nfunc (cl *Client) Close() error { return nil }\n"), "move close")
+	commitFile(t, r, "cmd/tool/main.go", []byte("package main\n\nfunc Run(args []string) {}\n"), "tool args")
+
+	report, err := r.APIDiff(string(v1), "HEAD")
+	if err != nil {
+		t.Fatalf("APIDiff: %v", err)
+	}
+
+	got := make(map[string]APIChange)
+	for _, c := range report.Changes {
+		if c.Package != "lib" {
+			t.Errorf("unexpected change outside lib: %+v", c)
+		}
+		got[c.Name] = c
+	}
+	if c, ok := got["func Open"]; !ok || c.Kind != APIChanged ||
+		c.OldSignature != "func Open(path string) (*Client, error)" ||
+		c.NewSignature != "func Open(path string, readOnly bool) (*Client, error)" {
+		t.Errorf("func Open = %+v, want changed signature", c)
+	}
+	if c, ok := got["func (Client) Flush"]; !ok || c.Kind != APIRemoved || c.Path != "lib/lib.go" {
+		t.Errorf("func (Client) Flush = %+v, want removed from lib/lib.go", c)
+	}
+	if c, ok := got["func Dial"]; !ok || c.Kind != APIAdded || c.Breaking() {
+		t.Errorf("func Dial = %+v, want a non-breaking addition", c)
+	}
+	if len(report.Changes) != 3 {
+		t.Errorf("changes = %+v, want exactly Open, Flush and Dial", report.Changes)
+	}
+	if n := report.BreakingCount(); n != 2 {
+		t.Errorf("BreakingCount = %d, want 2", n)
+	}
+}
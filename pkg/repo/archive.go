@@ -5,6 +5,7 @@ import (
 	"archive/zip"
 	"fmt"
 	"io"
+	"os"
 	"path"
 	"sort"
 	"strings"
@@ -77,10 +78,18 @@ func (r *Repo) archiveTar(w io.Writer, entries []TreeFileEntry, prefix string) e
 			Mode: parseTarMode(entry.Mode),
 			Size: int64(len(blob.Data)),
 		}
+		if isSymlinkMode(entry.Mode) {
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = string(blob.Data)
+			hdr.Size = 0
+		}
 
 		if err := tw.WriteHeader(hdr); err != nil {
 			return fmt.Errorf("archive: write tar header %s: %w", entry.Path, err)
 		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			continue
+		}
 		if _, err := tw.Write(blob.Data); err != nil {
 			return fmt.Errorf("archive: write tar data %s: %w", entry.Path, err)
 		}
@@ -108,6 +117,10 @@ func (r *Repo) archiveZip(w io.Writer, entries []TreeFileEntry, prefix string) e
 			Name:   name,
 			Method: zip.Deflate,
 		}
+		if isSymlinkMode(entry.Mode) {
+			// Zip stores a symlink as an entry whose content is the target.
+			fh.SetMode(os.ModeSymlink | 0o777)
+		}
 
 		fw, err := zw.CreateHeader(fh)
 		if err != nil {
@@ -127,6 +140,8 @@ func parseTarMode(mode string) int64 {
 	switch mode {
 	case "100755":
 		return 0o755
+	case "120000":
+		return 0o777
 	case "100644", "":
 		return 0o644
 	default:
@@ -72,7 +72,7 @@ func ParseAttributes(content string) *Attributes {
 // Returns nil if the line is empty or a comment.
 //
 // Format: <pattern> <attr1>[=<value>] [<attr2>[=<value>]] ...
-// Special: "binary" is shorthand for "-diff -merge".
+// Special: "binary" is shorthand for "-diff -merge -text".
 // Attributes prefixed with "-" set the value to "false".
 // Attributes without =value are boolean (value "true").
 func parseAttributeLine(line string) *AttributeRule {
@@ -110,9 +110,10 @@ func parseAttributeLine(line string) *AttributeRule {
 
 	for _, attr := range fields[1:] {
 		if attr == "binary" {
-			// "binary" is shorthand for -diff -merge.
+			// "binary" is shorthand for -diff -merge -text.
 			rule.Attrs["diff"] = "false"
 			rule.Attrs["merge"] = "false"
+			rule.Attrs["text"] = "false"
 			continue
 		}
 
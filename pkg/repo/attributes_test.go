@@ -106,7 +106,7 @@ func TestAttributes_EmptyFile(t *testing.T) {
 	}
 }
 
-// Test 5: binary sets diff=false and merge=false.
+// Test 5: binary sets diff=false, merge=false and text=false.
 func TestAttributes_BinaryShorthand(t *testing.T) {
 	attrs := ParseAttributes("*.jpg binary\n")
 
@@ -117,6 +117,9 @@ func TestAttributes_BinaryShorthand(t *testing.T) {
 	if m["merge"] != "false" {
 		t.Errorf("expected merge=false for binary, got %s", m["merge"])
 	}
+	if m["text"] != "false" {
+		t.Errorf("expected text=false for binary, got %s", m["text"])
+	}
 }
 
 // Test 6: docs/** matches docs/foo.md and docs/sub/bar.md.
@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// CheckoutOptions controls optional behavior of CheckoutWithOptions.
+type CheckoutOptions struct {
+	// Autostash stashes local changes, untracked files included, before
+	// switching instead of refusing to switch, and re-applies them on the
+	// target afterwards.
+	Autostash bool
+}
+
+// MergeOptions controls optional behavior of MergeWithOptions.
+type MergeOptions struct {
+	// Autostash stashes local changes before merging so the merge cannot
+	// clobber them, and re-applies them once the merge is committed. When
+	// the merge stops on conflicts the changes stay stashed.
+	Autostash bool
+}
+
+// AutostashDefault reports whether core.autostash is set, making checkout,
+// merge and rebase stash local changes without --autostash.
+func (r *Repo) AutostashDefault() bool {
+	cfg, err := r.ReadConfig()
+	return err == nil && cfg.Core != nil && cfg.Core.AutoStash
+}
+
+// CheckoutWithOptions is like Checkout, adjusted by opts.
+func (r *Repo) CheckoutWithOptions(target string, opts CheckoutOptions) error {
+	if !opts.Autostash {
+		return r.Checkout(target)
+	}
+	entry, err := r.autostashPush("checkout")
+	if err != nil {
+		return fmt.Errorf("checkout: %w", err)
+	}
+	if err := r.Checkout(target); err != nil {
+		r.autostashApply(entry)
+		return err
+	}
+	r.autostashApply(entry)
+	return nil
+}
+
+// MergeWithOptions is like Merge, adjusted by opts. With Autostash, a merge
+// that stops on conflicts leaves the local changes stashed and sets the
+// report's AutostashPending.
+func (r *Repo) MergeWithOptions(branchName string, opts MergeOptions) (*MergeReport, error) {
+	return r.MergeContext(context.Background(), branchName, opts)
+}
+
+// MergeContext is like MergeWithOptions but accepts an explicit context.
+// ctx is checked until the merge starts writing files; from then on the
+// merge runs to completion, so cancelling never leaves it half applied.
+func (r *Repo) MergeContext(ctx context.Context, branchName string, opts MergeOptions) (*MergeReport, error) {
+	if !opts.Autostash {
+		return r.merge(ctx, branchName)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("merge: %w", err)
+	}
+	entry, err := r.autostashPush("merge")
+	if err != nil {
+		return nil, fmt.Errorf("merge: %w", err)
+	}
+	report, err := r.merge(ctx, branchName)
+	if err != nil {
+		r.autostashApply(entry)
+		return nil, err
+	}
+	if report.HasConflicts {
+		report.AutostashPending = entry != nil
+		return report, nil
+	}
+	r.autostashApply(entry)
+	return report, nil
+}
+
+// autostashPush stashes local changes, untracked files included, for op.
+// It returns nil when there is nothing to stash.
+func (r *Repo) autostashPush(op string) (*StashEntry, error) {
+	statusEntries, err := r.Status()
+	if err != nil {
+		return nil, fmt.Errorf("autostash: %w", err)
+	}
+	dirty := false
+	for _, e := range statusEntries {
+		if !e.NestedRepo && (e.IndexStatus != StatusClean || e.WorkStatus != StatusClean) {
+			dirty = true
+			break
+		}
+	}
+	if !dirty {
+		return nil, nil
+	}
+	entry, err := r.StashWithOptions(op+" autostash", StashOptions{IncludeUntracked: true})
+	if err != nil {
+		return nil, fmt.Errorf("autostash: %w", err)
+	}
+	return entry, nil
+}
+
+// autostashApply re-applies a stash made by autostashPush and drops it. A
+// stash that cannot be applied cleanly is kept, with a warning on stderr;
+// the operation it wrapped is still considered successful.
+func (r *Repo) autostashApply(entry *StashEntry) {
+	if entry == nil {
+		return
+	}
+	stack, err := r.readStashStack()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: autostash: could not read stash stack: %v\n", err)
+		return
+	}
+	idx := -1
+	for i, e := range stack {
+		if e.CommitHash == entry.CommitHash {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		fmt.Fprintf(os.Stderr, "warning: autostash: stash entry not found (may have been manually popped)\n")
+		return
+	}
+
+	result, err := r.StashApplyMerge(idx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: could not restore autostash: %v\nYour changes are still in stash@{%d}.\n", err, idx)
+		return
+	}
+	if !result.Clean {
+		fmt.Fprintf(os.Stderr, "warning: applying autostash produced conflicts in: %s\nYour changes are still in stash@{%d}.\n",
+			joinPaths(result.ConflictPaths), idx)
+		return
+	}
+	if err := r.StashDrop(idx); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: autostash: %v\n", err)
+	}
+}
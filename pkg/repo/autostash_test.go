@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckoutWithOptions_AutostashCarriesChanges(t *testing.T) {
+	r, dir := setupMergeRepo(t)
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	commitFile(t, r, "feature.txt", []byte("feature\n"), "add feature.txt")
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+
+	edited := "package main\n\nfunc A() { println(\"edited\") }\n"
+	writeFile(t, filepath.Join(dir, "main.go"), []byte(edited))
+	writeFile(t, filepath.Join(dir, "scratch.txt"), []byte("scratch\n"))
+
+	if err := r.Checkout("feature"); err == nil || !strings.Contains(err.Error(), "not clean") {
+		t.Fatalf("Checkout without autostash: err = %v, want a dirty tree refusal", err)
+	}
+	if err := r.CheckoutWithOptions("feature", CheckoutOptions{Autostash: true}); err != nil {
+		t.Fatalf("CheckoutWithOptions(autostash): %v", err)
+	}
+	if branch, _ := r.CurrentBranch(); branch != "feature" {
+		t.Fatalf("current branch = %q, want feature", branch)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(data) != edited {
+		t.Fatalf("main.go = %q, want the local edit carried over", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "feature.txt")); err != nil {
+		t.Fatalf("feature.txt not checked out: %v", err)
+	}
+	status := statusByPath(t, r)
+	if status["scratch.txt"].WorkStatus != StatusUntracked {
+		t.Fatalf("scratch.txt status = %+v, want untracked", status["scratch.txt"])
+	}
+	if stack, _ := r.StashList(); len(stack) != 0 {
+		t.Fatalf("stash stack = %v, want the autostash dropped", stack)
+	}
+}
+
+func TestMergeWithOptions_AutostashProtectsLocalChanges(t *testing.T) {
+	r, dir := setupMergeRepo(t)
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	commitFile(t, r, "feature.txt", []byte("feature\n"), "add feature.txt")
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	commitFile(t, r, "main.txt", []byte("main\n"), "diverge")
+
+	writeFile(t, filepath.Join(dir, "main.txt"), []byte("main, edited\n"))
+	report, err := r.MergeWithOptions("feature", MergeOptions{Autostash: true})
+	if err != nil {
+		t.Fatalf("MergeWithOptions(autostash): %v", err)
+	}
+	if report.HasConflicts || report.MergeCommit == "" || report.AutostashPending {
+		t.Fatalf("report = %+v, want a clean merge commit", report)
+	}
+	commit, err := r.Store.ReadCommit(report.MergeCommit)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	files, err := r.FlattenTree(commit.TreeHash)
+	if err != nil {
+		t.Fatalf("FlattenTree: %v", err)
+	}
+	for _, f := range files {
+		if f.Path == "main.txt" {
+			if data, _ := r.readBlobData(f.BlobHash); string(data) != "main\n" {
+				t.Fatalf("merge commit recorded the local edit: %q", data)
+			}
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.txt")); string(data) != "main, edited\n" {
+		t.Fatalf("main.txt = %q after merge, want the local edit restored", data)
+	}
+	if got := statusByPath(t, r); got["main.txt"].WorkStatus != StatusDirty && got["main.txt"].IndexStatus != StatusModified {
+		t.Fatalf("main.txt status = %+v, want the edit left uncommitted", got["main.txt"])
+	}
+}
@@ -20,6 +20,10 @@ type BisectResult struct {
 	Remaining int         // estimated remaining commits to test
 	Steps     int         // estimated remaining steps (log2 of remaining)
 	Message   string      // first line of current commit message
+	// Suspects is set when Done is true but only skipped commits were left
+	// to test: the first bad commit is one of them or the bad commit, and
+	// FirstBad is empty.
+	Suspects []object.Hash
 }
 
 // BisectStart initializes a bisect session. It validates that both bad and good
@@ -128,7 +132,8 @@ func (r *Repo) BisectBad() (*BisectResult, error) {
 }
 
 // BisectSkip skips the current commit (can't test) and tries another nearby
-// candidate.
+// candidate. Skipped commits are remembered for the rest of the session and
+// never checked out again.
 func (r *Repo) BisectSkip() (*BisectResult, error) {
 	if !r.IsBisecting() {
 		return nil, fmt.Errorf("bisect skip: not bisecting")
@@ -152,44 +157,15 @@ func (r *Repo) BisectSkip() (*BisectResult, error) {
 		return nil, fmt.Errorf("bisect skip: %w", err)
 	}
 
-	// Find candidates excluding the current head, then pick an alternative.
-	candidates, err := r.bisectCandidates(bad, goods)
+	skips, err := r.bisectReadSkips()
 	if err != nil {
 		return nil, fmt.Errorf("bisect skip: %w", err)
 	}
-
-	// Remove the current head from the candidate list.
-	var filtered []object.Hash
-	for _, c := range candidates {
-		if c != head {
-			filtered = append(filtered, c)
-		}
-	}
-
-	if len(filtered) == 0 {
-		return nil, fmt.Errorf("bisect skip: no other candidates to test")
-	}
-
-	// Pick a candidate near the midpoint of the filtered list.
-	mid := len(filtered) / 2
-	next := filtered[mid]
-	remaining := len(filtered)
-	steps := bisectSteps(remaining)
-
-	// Checkout the candidate.
-	if err := r.Checkout(string(next)); err != nil {
-		return nil, fmt.Errorf("bisect skip: checkout %s: %w", next, err)
+	if err := r.bisectWriteSkips(append(skips, head)); err != nil {
+		return nil, fmt.Errorf("bisect skip: %w", err)
 	}
 
-	msg, _ := r.bisectCommitMessage(next)
-
-	return &BisectResult{
-		Done:      false,
-		Current:   next,
-		Remaining: remaining,
-		Steps:     steps,
-		Message:   msg,
-	}, nil
+	return r.bisectAdvance(bad, goods)
 }
 
 // BisectReset ends the bisect session, restores the original HEAD (from
@@ -346,19 +322,46 @@ func (r *Repo) bisectReadGoods() ([]object.Hash, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read bisect goods: %w", err)
 	}
+	return parseBisectHashes(data), nil
+}
+
+// bisectWriteSkips writes the list of skipped hashes to the state file.
+func (r *Repo) bisectWriteSkips(skips []object.Hash) error {
+	var lines []string
+	for _, h := range skips {
+		lines = append(lines, string(h))
+	}
+	return r.bisectWriteFileAtomic("skip", strings.Join(lines, "\n")+"\n")
+}
+
+// bisectReadSkips reads the list of skipped commit hashes from state. A
+// session without skips has no skip file.
+func (r *Repo) bisectReadSkips() ([]object.Hash, error) {
+	data, err := os.ReadFile(filepath.Join(r.bisectDir(), "skip"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read bisect skips: %w", err)
+	}
+	return parseBisectHashes(data), nil
+}
+
+// parseBisectHashes parses a state file holding one hash per line.
+func parseBisectHashes(data []byte) []object.Hash {
 	content := strings.TrimSpace(string(data))
 	if content == "" {
-		return nil, nil
+		return nil
 	}
 	lines := strings.Split(content, "\n")
-	goods := make([]object.Hash, 0, len(lines))
+	hashes := make([]object.Hash, 0, len(lines))
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if line != "" {
-			goods = append(goods, object.Hash(line))
+			hashes = append(hashes, object.Hash(line))
 		}
 	}
-	return goods, nil
+	return hashes
 }
 
 // bisectReadStartRef reads the original HEAD ref from state.
@@ -392,9 +395,14 @@ func (r *Repo) bisectWriteExpectedSteps(steps int) error {
 }
 
 // bisectAdvance finds the midpoint between bad and goods, checks it out, and
-// returns a BisectResult. If only one candidate remains, bisect is done.
+// returns a BisectResult. If no candidate remains, bisect is done; if only
+// skipped candidates remain, it is done without a single first bad commit.
 func (r *Repo) bisectAdvance(bad object.Hash, goods []object.Hash) (*BisectResult, error) {
-	midpoint, remaining, err := r.bisectFindMidpoint(bad, goods)
+	skips, err := r.bisectReadSkips()
+	if err != nil {
+		return nil, fmt.Errorf("bisect advance: %w", err)
+	}
+	midpoint, remaining, err := r.bisectFindMidpoint(bad, goods, skips)
 	if err != nil {
 		return nil, fmt.Errorf("bisect advance: %w", err)
 	}
@@ -419,6 +427,20 @@ func (r *Repo) bisectAdvance(bad object.Hash, goods []object.Hash) (*BisectResul
 		}, nil
 	}
 
+	// Every remaining candidate was skipped: report them alongside bad.
+	if midpoint == "" {
+		candidates, err := r.bisectCandidates(bad, goods)
+		if err != nil {
+			return nil, fmt.Errorf("bisect advance: %w", err)
+		}
+		return &BisectResult{
+			Done:      true,
+			Current:   bad,
+			Remaining: remaining,
+			Suspects:  append(candidates, bad),
+		}, nil
+	}
+
 	// Checkout the midpoint.
 	if err := r.Checkout(string(midpoint)); err != nil {
 		return nil, fmt.Errorf("bisect advance: checkout %s: %w", midpoint, err)
@@ -475,9 +497,11 @@ func (r *Repo) bisectCandidates(bad object.Hash, goods []object.Hash) ([]object.
 	return result, nil
 }
 
-// bisectFindMidpoint finds the commit roughly halfway between bad and goods.
-// Returns the midpoint hash and the number of remaining candidates.
-func (r *Repo) bisectFindMidpoint(bad object.Hash, goods []object.Hash) (object.Hash, int, error) {
+// bisectFindMidpoint finds the commit roughly halfway between bad and goods,
+// passing over skipped commits. Returns the midpoint hash and the number of
+// remaining candidates; the midpoint is empty when every candidate has been
+// skipped.
+func (r *Repo) bisectFindMidpoint(bad object.Hash, goods, skips []object.Hash) (object.Hash, int, error) {
 	candidates, err := r.bisectCandidates(bad, goods)
 	if err != nil {
 		return "", 0, err
@@ -487,13 +511,22 @@ func (r *Repo) bisectFindMidpoint(bad object.Hash, goods []object.Hash) (object.
 		return bad, 0, nil
 	}
 
-	if len(candidates) == 1 {
-		return candidates[0], 1, nil
+	skipped := make(map[object.Hash]bool, len(skips))
+	for _, h := range skips {
+		skipped[h] = true
 	}
 
-	// Pick the midpoint (middle of the sorted candidate list).
+	// Pick the midpoint (middle of the sorted candidate list), or failing
+	// that the untested candidate closest to it.
 	mid := len(candidates) / 2
-	return candidates[mid], len(candidates), nil
+	for offset := 0; offset <= len(candidates); offset++ {
+		for _, i := range []int{mid + offset, mid - offset} {
+			if i >= 0 && i < len(candidates) && !skipped[candidates[i]] {
+				return candidates[i], len(candidates), nil
+			}
+		}
+	}
+	return "", len(candidates), nil
 }
 
 // bisectWalkAncestors performs a BFS from the given commit, recording each
@@ -316,6 +316,44 @@ func TestBisect_Skip(t *testing.T) {
 	}
 }
 
+// TestBisect_SkipOnlySkippedLeft verifies that skipped commits are not
+// checked out again and that bisect ends with every remaining suspect once
+// only skipped commits are left.
+func TestBisect_SkipOnlySkippedLeft(t *testing.T) {
+	r, hashes := bisectTestRepo(t, 4)
+
+	res, err := r.BisectStart(hashes[3], hashes[0])
+	if err != nil {
+		t.Fatalf("BisectStart: %v", err)
+	}
+	first := res.Current
+
+	res, err = r.BisectSkip()
+	if err != nil {
+		t.Fatalf("BisectSkip: %v", err)
+	}
+	if res.Done || res.Current == first {
+		t.Fatalf("after one skip: Done=%v Current=%s, want the other candidate", res.Done, res.Current)
+	}
+
+	res, err = r.BisectSkip()
+	if err != nil {
+		t.Fatalf("second BisectSkip: %v", err)
+	}
+	if !res.Done || res.FirstBad != "" {
+		t.Fatalf("after skipping every candidate: Done=%v FirstBad=%s, want done without a first bad commit", res.Done, res.FirstBad)
+	}
+	want := map[object.Hash]bool{hashes[1]: true, hashes[2]: true, hashes[3]: true}
+	if len(res.Suspects) != len(want) {
+		t.Fatalf("Suspects = %v, want commits 1-3", res.Suspects)
+	}
+	for _, h := range res.Suspects {
+		if !want[h] {
+			t.Fatalf("Suspects = %v, want commits 1-3", res.Suspects)
+		}
+	}
+}
+
 // TestBisect_MidpointSelection verifies that the midpoint is roughly in the
 // middle of the range between good and bad.
 func TestBisect_MidpointSelection(t *testing.T) {
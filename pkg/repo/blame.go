@@ -158,16 +158,9 @@ func (r *Repo) BlameFile(path string, limit int) ([]EntityBlame, error) {
 		return nil, fmt.Errorf("blame: limit must be greater than 0")
 	}
 
-	relPath, err := r.repoRelPath(path)
+	relPath, err := r.blameRelPath(path)
 	if err != nil {
-		return nil, fmt.Errorf("blame: resolve path %q: %w", path, err)
-	}
-	relPath = filepath.ToSlash(filepath.Clean(relPath))
-	if relPath == "." || strings.TrimSpace(relPath) == "" {
-		return nil, fmt.Errorf("blame: path is required")
-	}
-	if isOutsideRepo(relPath) {
-		return nil, fmt.Errorf("blame: path %q is outside repository", path)
+		return nil, err
 	}
 
 	headHash, err := r.ResolveRef("HEAD")
@@ -0,0 +1,328 @@
+package repo
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/odvcencio/graft/pkg/diff3"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// blameCopyMinChars is how many non-whitespace characters a run of lines
+// must share with another file before DetectCopies attributes it there, so
+// that braces and blank lines are not "copied" from everywhere.
+const blameCopyMinChars = 20
+
+// LineBlame attributes one line of a file to the commit that last changed
+// it. OrigPath and OrigLine locate the line in that commit, which differ
+// from the blamed path and line when the line was moved or copied.
+type LineBlame struct {
+	Line       int
+	Content    string
+	CommitHash object.Hash
+	Author     string
+	Timestamp  int64
+	OrigPath   string
+	OrigLine   int
+}
+
+// BlameLinesOptions controls BlameLines.
+type BlameLinesOptions struct {
+	// Start and End restrict blame to lines Start through End, 1-based and
+	// inclusive. Zero means the first or the last line.
+	Start, End int
+
+	// IgnoreWhitespace treats lines that differ only in whitespace as
+	// unchanged, so reindenting does not take the blame.
+	IgnoreWhitespace bool
+
+	// DetectCopies follows lines that a commit added to the file but
+	// copied or moved from another file changed in the same commit, and
+	// keeps blaming them in that file's history.
+	DetectCopies bool
+
+	// Limit bounds the commits scanned. Lines not attributed within it
+	// are blamed on the last commit scanned. Zero means no limit.
+	Limit int
+}
+
+// blameTrack follows one blamed line back through history: its 0-based
+// line in the current commit's version of the file, and its index in the
+// result.
+type blameTrack struct {
+	line int
+	idx  int
+}
+
+// BlameLines attributes each line of the file at HEAD to the commit on the
+// first-parent history that introduced it.
+func (r *Repo) BlameLines(path string, opts BlameLinesOptions) ([]LineBlame, error) {
+	relPath, err := r.blameRelPath(path)
+	if err != nil {
+		return nil, err
+	}
+	commitHash, err := r.ResolveRef("HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("blame: cannot resolve HEAD: %w", err)
+	}
+	commit, err := r.Store.ReadCommit(commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("blame: read HEAD commit %s: %w", commitHash, err)
+	}
+	entries, err := r.treeEntriesByPath(commit.TreeHash)
+	if err != nil {
+		return nil, fmt.Errorf("blame: %w", err)
+	}
+	entry, ok := entries[relPath]
+	if !ok {
+		return nil, fmt.Errorf("blame: file %q not found in HEAD", relPath)
+	}
+
+	cache := make(map[object.Hash][]string)
+	load := func(h object.Hash) ([]string, error) {
+		if lines, ok := cache[h]; ok {
+			return lines, nil
+		}
+		data, err := r.readBlobData(h)
+		if err != nil {
+			return nil, fmt.Errorf("blame: %w", err)
+		}
+		lines := splitBlameLines(data)
+		cache[h] = lines
+		return lines, nil
+	}
+
+	lines, err := load(entry.BlobHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	start, end := opts.Start, opts.End
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > len(lines) || start > end {
+		return nil, fmt.Errorf("blame: -L %d,%d: %s has only %d lines", opts.Start, opts.End, relPath, len(lines))
+	}
+
+	results := make([]LineBlame, 0, end-start+1)
+	var tracks []blameTrack
+	for i := start - 1; i < end; i++ {
+		results = append(results, LineBlame{Line: i + 1, Content: lines[i]})
+		tracks = append(tracks, blameTrack{line: i, idx: len(results) - 1})
+	}
+	pending := map[string][]blameTrack{relPath: tracks}
+	blame := func(c *object.CommitObj, h object.Hash, path string, t blameTrack) {
+		res := &results[t.idx]
+		res.CommitHash, res.Author, res.Timestamp = h, c.Author, c.Timestamp
+		res.OrigPath, res.OrigLine = path, t.line+1
+	}
+
+	shallow, _ := r.ShallowState()
+	for scanned := 1; len(pending) > 0; scanned++ {
+		parentHash := firstParentHash(commit)
+		if parentHash == "" || (shallow != nil && shallow.IsShallow(parentHash)) || (opts.Limit > 0 && scanned >= opts.Limit) {
+			for path, tracks := range pending {
+				for _, t := range tracks {
+					blame(commit, commitHash, path, t)
+				}
+			}
+			break
+		}
+		parent, err := r.Store.ReadCommit(parentHash)
+		if err != nil {
+			return nil, fmt.Errorf("blame: read parent commit %s: %w", parentHash, err)
+		}
+		parentEntries, err := r.treeEntriesByPath(parent.TreeHash)
+		if err != nil {
+			return nil, fmt.Errorf("blame: %w", err)
+		}
+
+		next := make(map[string][]blameTrack)
+		for _, path := range sortedBlamePaths(pending) {
+			tracks := pending[path]
+			cur := entries[path]
+			prev, inParent := parentEntries[path]
+			if inParent && prev.BlobHash == cur.BlobHash {
+				next[path] = append(next[path], tracks...)
+				continue
+			}
+
+			curLines, err := load(cur.BlobHash)
+			if err != nil {
+				return nil, err
+			}
+			var lineMap map[int]int
+			if inParent {
+				prevLines, err := load(prev.BlobHash)
+				if err != nil {
+					return nil, err
+				}
+				lineMap = mapBlameLines(prevLines, curLines, opts.IgnoreWhitespace)
+			}
+
+			var unmatched []blameTrack
+			for _, t := range tracks {
+				if prevLine, ok := lineMap[t.line]; ok {
+					next[path] = append(next[path], blameTrack{line: prevLine, idx: t.idx})
+				} else {
+					unmatched = append(unmatched, t)
+				}
+			}
+			if opts.DetectCopies && len(unmatched) > 0 {
+				sources := make(map[string][]string)
+				for _, src := range changedCandidatePaths(parentEntries, entries, "") {
+					srcEntry, ok := parentEntries[src]
+					if src == path || !ok {
+						continue
+					}
+					if sources[src], err = load(srcEntry.BlobHash); err != nil {
+						return nil, err
+					}
+				}
+				unmatched = traceBlameCopies(unmatched, curLines, sources, opts.IgnoreWhitespace, next)
+			}
+			for _, t := range unmatched {
+				blame(commit, commitHash, path, t)
+			}
+		}
+
+		pending = next
+		commit, commitHash, entries = parent, parentHash, parentEntries
+	}
+	return results, nil
+}
+
+// blameRelPath resolves a blame path argument to a repository-relative
+// path.
+func (r *Repo) blameRelPath(path string) (string, error) {
+	relPath, err := r.repoRelPath(path)
+	if err != nil {
+		return "", fmt.Errorf("blame: resolve path %q: %w", path, err)
+	}
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+	if relPath == "." || strings.TrimSpace(relPath) == "" {
+		return "", fmt.Errorf("blame: path is required")
+	}
+	if isOutsideRepo(relPath) {
+		return "", fmt.Errorf("blame: path %q is outside repository", path)
+	}
+	return relPath, nil
+}
+
+func splitBlameLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.Split(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// blameKey is the text lines are compared by: the line itself, or with
+// ignoreWhitespace, the line with all whitespace removed.
+func blameKey(line string, ignoreWhitespace bool) string {
+	if !ignoreWhitespace {
+		return line
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, line)
+}
+
+func blameKeys(lines []string, ignoreWhitespace bool) []string {
+	if !ignoreWhitespace {
+		return lines
+	}
+	keys := make([]string, len(lines))
+	for i, line := range lines {
+		keys[i] = blameKey(line, true)
+	}
+	return keys
+}
+
+// mapBlameLines maps each line of cur that is unchanged from prev to its
+// line in prev.
+func mapBlameLines(prev, cur []string, ignoreWhitespace bool) map[int]int {
+	lineMap := make(map[int]int, len(cur))
+	i, j := 0, 0
+	for _, op := range diff3.MyersDiff(blameKeys(prev, ignoreWhitespace), blameKeys(cur, ignoreWhitespace)) {
+		switch op.Type {
+		case diff3.Equal:
+			lineMap[j] = i
+			i++
+			j++
+		case diff3.Delete:
+			i++
+		case diff3.Insert:
+			j++
+		}
+	}
+	return lineMap
+}
+
+// traceBlameCopies looks for each run of consecutive unmatched lines in the
+// source files, moving the tracks of a run found there into next under the
+// source path. It returns the tracks it could not place.
+func traceBlameCopies(unmatched []blameTrack, curLines []string, sources map[string][]string, ignoreWhitespace bool, next map[string][]blameTrack) []blameTrack {
+	sort.Slice(unmatched, func(a, b int) bool { return unmatched[a].line < unmatched[b].line })
+	srcPaths := make([]string, 0, len(sources))
+	srcKeys := make(map[string][]string, len(sources))
+	for path, lines := range sources {
+		srcPaths = append(srcPaths, path)
+		srcKeys[path] = blameKeys(lines, ignoreWhitespace)
+	}
+	sort.Strings(srcPaths)
+
+	var rest []blameTrack
+	for k := 0; k < len(unmatched); {
+		bestPath, bestAt, bestLen, bestChars := "", 0, 0, 0
+		for _, path := range srcPaths {
+			keys := srcKeys[path]
+			for at := range keys {
+				n, chars := 0, 0
+				for k+n < len(unmatched) && at+n < len(keys) &&
+					unmatched[k+n].line == unmatched[k].line+n &&
+					keys[at+n] == blameKey(curLines[unmatched[k+n].line], ignoreWhitespace) {
+					chars += len(strings.Join(strings.Fields(keys[at+n]), ""))
+					n++
+				}
+				if chars > bestChars {
+					bestPath, bestAt, bestLen, bestChars = path, at, n, chars
+				}
+			}
+		}
+		if bestChars < blameCopyMinChars {
+			rest = append(rest, unmatched[k])
+			k++
+			continue
+		}
+		for n := 0; n < bestLen; n++ {
+			next[bestPath] = append(next[bestPath], blameTrack{line: bestAt + n, idx: unmatched[k+n].idx})
+		}
+		k += bestLen
+	}
+	return rest
+}
+
+func sortedBlamePaths(pending map[string][]blameTrack) []string {
+	paths := make([]string, 0, len(pending))
+	for path := range pending {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func commitAs(t *testing.T, r *Repo, files map[string]string, author, msg string) object.Hash {
+	t.Helper()
+	var paths []string
+	for name, content := range files {
+		writeFile(t, filepath.Join(r.RootDir, name), []byte(content))
+		paths = append(paths, filepath.Join(r.RootDir, name))
+	}
+	if err := r.Add(paths); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	h, err := r.Commit(msg, author)
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	return h
+}
+
+func blameAuthors(lines []LineBlame) []string {
+	authors := make([]string, len(lines))
+	for i, l := range lines {
+		authors[i] = l.Author
+	}
+	return authors
+}
+
+func TestBlameLines_AttributesAndRestrictsRange(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	first := commitAs(t, r, map[string]string{"f.txt": "one\ntwo\nthree\n"}, "alice", "first")
+	second := commitAs(t, r, map[string]string{"f.txt": "one\nTWO\nthree\nfour\n"}, "bob", "second")
+
+	lines, err := r.BlameLines("f.txt", BlameLinesOptions{})
+	if err != nil {
+		t.Fatalf("BlameLines: %v", err)
+	}
+	wantHashes := []object.Hash{first, second, first, second}
+	if len(lines) != len(wantHashes) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(wantHashes))
+	}
+	for i, want := range wantHashes {
+		if lines[i].CommitHash != want || lines[i].Line != i+1 || lines[i].OrigPath != "f.txt" {
+			t.Errorf("line %d = %+v, want commit %s", i+1, lines[i], want)
+		}
+	}
+	if lines[2].OrigLine != 3 || lines[1].Content != "TWO" {
+		t.Errorf("line 3 = %+v, line 2 = %+v", lines[2], lines[1])
+	}
+
+	ranged, err := r.BlameLines("f.txt", BlameLinesOptions{Start: 2, End: 3})
+	if err != nil {
+		t.Fatalf("BlameLines -L 2,3: %v", err)
+	}
+	if got := blameAuthors(ranged); len(got) != 2 || got[0] != "bob" || got[1] != "alice" || ranged[0].Line != 2 {
+		t.Errorf("-L 2,3 = %+v", ranged)
+	}
+	if _, err := r.BlameLines("f.txt", BlameLinesOptions{Start: 9}); err == nil {
+		t.Error("-L past the end of the file succeeded")
+	}
+}
+
+func TestBlameLines_IgnoreWhitespace(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitAs(t, r, map[string]string{"f.go": "func f() {\nreturn 1\n}\n"}, "alice", "first")
+	commitAs(t, r, map[string]string{"f.go": "func f() {\n\treturn  1\n}\n"}, "bob", "reindent")
+
+	lines, err := r.BlameLines("f.go", BlameLinesOptions{})
+	if err != nil {
+		t.Fatalf("BlameLines: %v", err)
+	}
+	if got := blameAuthors(lines); got[1] != "bob" {
+		t.Errorf("without -w, line 2 blamed on %q, want bob", got[1])
+	}
+
+	lines, err = r.BlameLines("f.go", BlameLinesOptions{IgnoreWhitespace: true})
+	if err != nil {
+		t.Fatalf("BlameLines -w: %v", err)
+	}
+	for i, author := range blameAuthors(lines) {
+		if author != "alice" {
+			t.Errorf("with -w, line %d blamed on %q, want alice", i+1, author)
+		}
+	}
+}
+
+func TestBlameLines_DetectCopies(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	helper := "func helper(values []int) int {\n\ttotal := 0\n\tfor _, v := range values {\n\t\ttotal += v\n\t}\n\treturn total\n}\n"
+	first := commitAs(t, r, map[string]string{"a.go": "package a\n\n" + helper}, "alice", "add helper")
+	// Move the helper into a new file in one commit.
+	commitAs(t, r, map[string]string{"a.go": "package a\n", "b.go": "package b\n\n" + helper}, "bob", "move helper")
+
+	lines, err := r.BlameLines("b.go", BlameLinesOptions{})
+	if err != nil {
+		t.Fatalf("BlameLines: %v", err)
+	}
+	if lines[3].Author != "bob" {
+		t.Errorf("without -C, moved line blamed on %q, want bob", lines[3].Author)
+	}
+
+	lines, err = r.BlameLines("b.go", BlameLinesOptions{DetectCopies: true})
+	if err != nil {
+		t.Fatalf("BlameLines -C: %v", err)
+	}
+	for _, l := range lines[2:] {
+		if l.CommitHash != first || l.OrigPath != "a.go" || l.OrigLine != l.Line {
+			t.Errorf("with -C, line %d = %+v, want a.go:%d in %s", l.Line, l, l.Line, first)
+		}
+	}
+	if lines[0].Author != "bob" {
+		t.Errorf("with -C, package clause blamed on %q, want bob", lines[0].Author)
+	}
+}
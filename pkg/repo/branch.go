@@ -48,7 +48,7 @@ func (r *Repo) DeleteBranch(name string) error {
 	refPath := filepath.Join(r.refsBaseDir(), "refs", "heads", name)
 	if err := os.Remove(refPath); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("delete branch: branch %q does not exist", name)
+			return fmt.Errorf("delete branch: branch %q does not exist: %w", name, ErrRefNotFound)
 		}
 		return fmt.Errorf("delete branch %q: %w", name, err)
 	}
@@ -0,0 +1,247 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ignoreCase reports whether the working tree lives on a case-insensitive
+// filesystem, where paths differing only in case name the same file. The
+// core.ignorecase setting wins; otherwise the filesystem is probed once.
+func (r *Repo) ignoreCase() bool {
+	cfg, err := r.ReadConfig()
+	if err == nil && cfg.Core != nil && cfg.Core.IgnoreCase != nil {
+		return *cfg.Core.IgnoreCase
+	}
+	r.ignoreCaseOnce.Do(func() {
+		r.ignoreCaseProbe = probeCaseInsensitive(r.GraftDir)
+	})
+	return r.ignoreCaseProbe
+}
+
+// probeCaseInsensitive creates a scratch file in dir and reports whether it
+// can also be found under an upper-cased name.
+func probeCaseInsensitive(dir string) bool {
+	f, err := os.CreateTemp(dir, "case-probe-")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	f.Close()
+	defer os.Remove(name)
+
+	upper := filepath.Join(filepath.Dir(name), strings.ToUpper(filepath.Base(name)))
+	_, err = os.Lstat(upper)
+	return err == nil
+}
+
+// caseFold returns the key under which paths that differ only in case
+// collide.
+func caseFold(p string) string {
+	return strings.ToLower(p)
+}
+
+// caseCollisions finds paths that differ only in case. It maps every path
+// but the first, in sorted order, of each colliding group to that first
+// path. It returns nil when nothing collides.
+func caseCollisions(paths []string) map[string]string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	first := make(map[string]string, len(sorted))
+	var collisions map[string]string
+	for _, p := range sorted {
+		key := caseFold(p)
+		kept, ok := first[key]
+		if !ok {
+			first[key] = p
+			continue
+		}
+		if kept == p {
+			continue
+		}
+		if collisions == nil {
+			collisions = make(map[string]string)
+		}
+		collisions[p] = kept
+	}
+	return collisions
+}
+
+// diskCasePath returns rel, a slash path below root, spelled the way the
+// filesystem stores it. Each component is matched exactly first and then
+// case-insensitively; components that cannot be found are kept as given.
+func diskCasePath(root, rel string) string {
+	parts := strings.Split(rel, "/")
+	dir := root
+	for i, part := range parts {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			break
+		}
+		match := ""
+		for _, e := range entries {
+			if e.Name() == part {
+				match = part
+				break
+			}
+			if match == "" && strings.EqualFold(e.Name(), part) {
+				match = e.Name()
+			}
+		}
+		if match == "" {
+			break
+		}
+		parts[i] = match
+		dir = filepath.Join(dir, match)
+	}
+	return strings.Join(parts, "/")
+}
+
+// warnCaseCollisions tells the user which tracked paths could not be
+// written because a path differing only in case already holds their file.
+func warnCaseCollisions(twins map[string]string) {
+	if len(twins) == 0 {
+		return
+	}
+	paths := make([]string, 0, len(twins))
+	for p := range twins {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	fmt.Fprintf(os.Stderr, "warning: the following paths differ only in case and share one file on this case-insensitive filesystem:\n")
+	for _, p := range paths {
+		fmt.Fprintf(os.Stderr, "  %s (kept %s)\n", p, twins[p])
+	}
+}
+
+// caseTwinsOnDisk returns the tracked paths missing from workFiles whose
+// file is on disk under another tracked path differing only in case.
+func caseTwinsOnDisk(stg *Staging, workFiles map[string]bool) []string {
+	present := make(map[string]bool)
+	for path := range stg.Entries {
+		if workFiles[path] {
+			present[caseFold(path)] = true
+		}
+	}
+	var twins []string
+	for path := range stg.Entries {
+		if !workFiles[path] && present[caseFold(path)] {
+			twins = append(twins, path)
+		}
+	}
+	sort.Strings(twins)
+	return twins
+}
+
+// pairCaseOnlyRenames records as renames the untracked files whose path
+// differs only in case from a tracked path missing from workFiles. On a
+// case-insensitive filesystem they are the same file, whatever their
+// content, so these pairs replace any pairing made by content.
+func pairCaseOnlyRenames(stg *Staging, workFiles map[string]bool, newToOld, oldToNew map[string]string) {
+	missing := make(map[string]string)
+	for path := range stg.Entries {
+		if !workFiles[path] {
+			missing[caseFold(path)] = path
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	for path := range workFiles {
+		if _, tracked := stg.Entries[path]; tracked {
+			continue
+		}
+		oldPath, ok := missing[caseFold(path)]
+		if !ok {
+			continue
+		}
+		delete(missing, caseFold(path))
+		if prev, ok := newToOld[path]; ok {
+			delete(oldToNew, prev)
+		}
+		if prev, ok := oldToNew[oldPath]; ok {
+			delete(newToOld, prev)
+		}
+		newToOld[path] = oldPath
+		oldToNew[oldPath] = path
+	}
+}
+
+// checkMergeCaseCollisions refuses a merge result that would bring in a
+// path differing only in case from another path of the result. On a
+// case-insensitive filesystem the two would be written to one file, so
+// the second write would silently replace the first. Collisions already
+// present in ours are left alone.
+func checkMergeCaseCollisions(result *ThreeWayMergeResult, oursMap map[string]TreeFileEntry) error {
+	var paths []string
+	for _, f := range result.Files {
+		if f.Status != "deleted" {
+			paths = append(paths, f.Path)
+		}
+	}
+	twins := caseCollisions(paths)
+	collided := make([]string, 0, len(twins))
+	for p := range twins {
+		collided = append(collided, p)
+	}
+	sort.Strings(collided)
+	for _, p := range collided {
+		kept := twins[p]
+		_, oursHasP := oursMap[p]
+		_, oursHasKept := oursMap[kept]
+		if oursHasP && oursHasKept {
+			continue
+		}
+		return fmt.Errorf("paths %q and %q differ only in case and cannot both be checked out on a case-insensitive filesystem", kept, p)
+	}
+	return nil
+}
+
+// resolveAddCase spells the paths about to be added the way the
+// filesystem stores them, dropping duplicates that only differed in case.
+// A tracked path that now only exists on disk under a new case is the old
+// name of a case-only rename, and is removed from stg.
+func (r *Repo) resolveAddCase(paths []string, stg *Staging) []string {
+	seen := make(map[string]bool, len(paths))
+	resolved := paths[:0]
+	for _, p := range paths {
+		p = diskCasePath(r.RootDir, p)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		resolved = append(resolved, p)
+	}
+
+	added := make(map[string]string)
+	for _, p := range resolved {
+		if _, tracked := stg.Entries[p]; !tracked {
+			added[caseFold(p)] = p
+		}
+	}
+	if len(added) == 0 {
+		return resolved
+	}
+	for old := range stg.Entries {
+		if _, ok := added[caseFold(old)]; !ok {
+			continue
+		}
+		if !existsWithExactCase(r.RootDir, old) {
+			delete(stg.Entries, old)
+		}
+	}
+	return resolved
+}
+
+// existsWithExactCase reports whether rel exists below root spelled
+// exactly as given, even on a filesystem that would also find it under
+// another case.
+func existsWithExactCase(root, rel string) bool {
+	if _, err := os.Lstat(filepath.Join(root, filepath.FromSlash(rel))); err != nil {
+		return false
+	}
+	return diskCasePath(root, rel) == rel
+}
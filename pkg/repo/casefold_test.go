@@ -0,0 +1,200 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setCoreIgnoreCase forces core.ignorecase so the case-insensitive code
+// paths run on any filesystem.
+func setCoreIgnoreCase(t *testing.T, r *Repo, ignore bool) {
+	t.Helper()
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if cfg.Core == nil {
+		cfg.Core = &CoreConfig{}
+	}
+	cfg.Core.IgnoreCase = &ignore
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+}
+
+func TestCaseCollisions(t *testing.T) {
+	got := caseCollisions([]string{"src/Main.go", "README.md", "src/main.go", "readme.md", "Readme.md", "other.go"})
+	want := map[string]string{
+		"Readme.md":   "README.md",
+		"readme.md":   "README.md",
+		"src/main.go": "src/Main.go",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("caseCollisions = %v, want %v", got, want)
+	}
+	if got := caseCollisions([]string{"a", "b/a", "A/b"}); got != nil {
+		t.Fatalf("caseCollisions without collisions = %v, want nil", got)
+	}
+}
+
+func TestDiskCasePath(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "Docs", "Guide.md"), []byte("guide\n"))
+
+	tests := map[string]string{
+		"Docs/Guide.md": "Docs/Guide.md",
+		"docs/guide.md": "Docs/Guide.md",
+		"DOCS/missing":  "Docs/missing",
+		"other/file":    "other/file",
+	}
+	for in, want := range tests {
+		if got := diskCasePath(root, in); got != want {
+			t.Errorf("diskCasePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCheckout_CaseCollisionWritesOneFile(t *testing.T) {
+	r, dir := setupMergeRepo(t)
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "README.md"), []byte("upper\n"))
+	writeFile(t, filepath.Join(dir, "readme.md"), []byte("lower\n"))
+	if err := r.Add([]string{"README.md", "readme.md"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("add both readmes", "test-author"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+
+	setCoreIgnoreCase(t, r, true)
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature) with core.ignorecase: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	if err != nil || string(data) != "upper\n" {
+		t.Fatalf("README.md = %q, %v; want the first path of the group", data, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "readme.md")); !os.IsNotExist(err) {
+		t.Fatalf("readme.md written over its case twin: %v", err)
+	}
+
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if _, ok := stg.Entries["readme.md"]; !ok {
+		t.Fatalf("readme.md dropped from the index: %v", keys(stg.Entries))
+	}
+	got := statusByPath(t, r)
+	for _, p := range []string{"README.md", "readme.md"} {
+		if got[p].WorkStatus != StatusClean || got[p].IndexStatus != StatusClean {
+			t.Fatalf("%s status = %+v, want clean", p, got[p])
+		}
+	}
+}
+
+func TestStatusAndAdd_CaseOnlyRename(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitFile(t, r, "README.md", []byte("readme\n"), "add readme")
+	setCoreIgnoreCase(t, r, true)
+
+	if err := os.Rename(filepath.Join(r.RootDir, "README.md"), filepath.Join(r.RootDir, "Readme.md")); err != nil {
+		t.Fatal(err)
+	}
+	// The content changes too; a case-only rename is paired by name.
+	writeFile(t, filepath.Join(r.RootDir, "Readme.md"), []byte("readme, edited\n"))
+
+	got := statusByPath(t, r)
+	if e := got["Readme.md"]; e.WorkStatus != StatusRenamed || e.RenamedFrom != "README.md" {
+		t.Fatalf("Readme.md status = %+v, want renamed from README.md", e)
+	}
+	if e := got["README.md"]; e.WorkStatus == StatusDeleted {
+		t.Fatalf("README.md reported deleted: %+v", e)
+	}
+
+	// Adding the new spelling replaces the old index entry.
+	if err := r.Add([]string{"Readme.md"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if paths := keys(stg.Entries); !reflect.DeepEqual(paths, []string{"Readme.md"}) {
+		t.Fatalf("staged paths = %v, want [Readme.md]", paths)
+	}
+}
+
+func TestMerge_CaseOnlyRenameAndCollision(t *testing.T) {
+	r, dir := setupMergeRepo(t)
+	setCoreIgnoreCase(t, r, true)
+
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	if err := os.Rename(filepath.Join(dir, "main.go"), filepath.Join(dir, "Main.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add([]string{"Main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("rename main.go", "test-author"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	commitFile(t, r, "other.txt", []byte("diverge\n"), "diverge")
+
+	report, err := r.Merge("feature")
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if report.HasConflicts {
+		t.Fatalf("unexpected conflicts: %+v", report)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "Main.go")); err != nil {
+		t.Fatalf("Main.go missing after merge: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "main.go")); !os.IsNotExist(err) {
+		t.Fatalf("main.go still present after merge: %v", err)
+	}
+
+	// A branch adding a case twin of a tracked path cannot be merged.
+	headHash, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	if err := r.CreateBranch("twin", headHash); err != nil {
+		t.Fatalf("CreateBranch: %v", err)
+	}
+	if err := r.Checkout("twin"); err != nil {
+		t.Fatalf("Checkout(twin): %v", err)
+	}
+	commitFile(t, r, "OTHER.txt", []byte("twin\n"), "add twin")
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	commitFile(t, r, "more.txt", []byte("more\n"), "diverge again")
+
+	_, err = r.Merge("twin")
+	if err == nil || !strings.Contains(err.Error(), "differ only in case") {
+		t.Fatalf("Merge(twin) error = %v, want a case collision", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "other.txt"))
+	if err != nil || string(data) != "diverge\n" {
+		t.Fatalf("other.txt = %q, %v; want it untouched", data, err)
+	}
+}
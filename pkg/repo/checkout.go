@@ -20,12 +20,28 @@ import (
 //  5. Write all files from target tree to working directory.
 //  6. Update staging to match the new tree.
 //  7. Update HEAD (symbolic ref for branch, raw hash for detached).
-func (r *Repo) Checkout(target string) error {
+func (r *Repo) Checkout(target string) (err error) {
+	defer r.observe("checkout", target)(&err)
+	return r.checkout(target)
+}
+
+func (r *Repo) checkout(target string) error {
 	// 1. Check for uncommitted changes.
 	if err := r.ensureClean(); err != nil {
 		return fmt.Errorf("checkout: %w", err)
 	}
 
+	// @{-N} names the branch itself, so checking it out attaches HEAD.
+	if n, ok := parsePreviousCheckout(target); ok {
+		prev, err := r.PreviousCheckout(n)
+		if err != nil {
+			return fmt.Errorf("checkout: %w", err)
+		}
+		target = prev
+	}
+	from := r.checkoutLabel()
+	oldHead, _ := r.ResolveRef("HEAD")
+
 	// 2. Resolve target.
 	isBranch := false
 	var targetHash object.Hash
@@ -50,7 +66,7 @@ func (r *Repo) Checkout(target string) error {
 		return fmt.Errorf("checkout: cannot read commit %s: %w", targetHash, err)
 	}
 
-	targetFiles, err := r.FlattenTree(commit.TreeHash)
+	targetFiles, err := r.flattenCheckoutTree(commit.TreeHash)
 	if err != nil {
 		return fmt.Errorf("checkout: flatten target tree: %w", err)
 	}
@@ -81,20 +97,41 @@ func (r *Repo) Checkout(target string) error {
 		r.removeEmptyParents(filepath.Dir(absPath))
 	}
 
+	// On a case-insensitive filesystem, paths differing only in case share
+	// one file. Write the first of each group and leave the rest to it
+	// rather than letting them overwrite each other.
+	var caseTwins map[string]string
+	if r.ignoreCase() {
+		var paths []string
+		for _, f := range targetFiles {
+			if isSidecarPath(f.Path) || (sparseEnabled && !r.matchesSparsePatterns(f.Path)) {
+				continue
+			}
+			paths = append(paths, f.Path)
+		}
+		caseTwins = caseCollisions(paths)
+		warnCaseCollisions(caseTwins)
+	}
+
 	// 5. Write all files from target tree (skip sidecar dirs and sparse-excluded files).
-	for _, f := range targetFiles {
+	le := r.lineEndingsForTree(targetFiles)
+	for i, f := range targetFiles {
+		r.reportProgress(ProgressCheckingOut, i+1, len(targetFiles))
 		if isSidecarPath(f.Path) {
 			continue // sidecar files are restored separately after HEAD update
 		}
 		if sparseEnabled && !r.matchesSparsePatterns(f.Path) {
 			continue
 		}
+		if _, twin := caseTwins[f.Path]; twin {
+			continue
+		}
 
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
 
 		// Create parent directories.
 		dir := filepath.Dir(absPath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := mkdirWorktree(r.RootDir, dir); err != nil {
 			return fmt.Errorf("checkout: mkdir %q: %w", dir, err)
 		}
 
@@ -112,9 +149,11 @@ func (r *Repo) Checkout(target string) error {
 				blobData = lfsContent
 			}
 			// If LFS content not available, write pointer file as-is (lazy fetch later).
+		} else {
+			blobData = le.smudge(f.Path, blobData, f.Mode)
 		}
 
-		if err := os.WriteFile(absPath, blobData, filePermFromMode(f.Mode)); err != nil {
+		if err := writeWorktreeFile(absPath, blobData, f.Mode); err != nil {
 			return fmt.Errorf("checkout: write %q: %w", f.Path, err)
 		}
 	}
@@ -129,17 +168,23 @@ func (r *Repo) Checkout(target string) error {
 			continue
 		}
 
-		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
-		info, err := os.Stat(absPath)
-		if err != nil {
-			return fmt.Errorf("checkout: stat %q: %w", f.Path, err)
-		}
-
 		entry := &StagingEntry{
 			Path:           f.Path,
 			BlobHash:       f.BlobHash,
 			EntityListHash: f.EntityListHash,
 		}
+		if _, twin := caseTwins[f.Path]; twin {
+			// Not on disk under its own name; no stat data to record.
+			entry.Mode = normalizeFileMode(f.Mode)
+			stg.Entries[f.Path] = entry
+			continue
+		}
+
+		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
+		info, err := os.Lstat(absPath)
+		if err != nil {
+			return fmt.Errorf("checkout: stat %q: %w", f.Path, err)
+		}
 		setStagingEntryStat(entry, info, normalizeFileMode(f.Mode))
 		stg.Entries[f.Path] = entry
 	}
@@ -158,6 +203,10 @@ func (r *Repo) Checkout(target string) error {
 		}
 	}
 
+	// Recording the move in the HEAD reflog is best effort: HEAD has
+	// already been updated, and the log only feeds @{-N}.
+	_ = r.appendReflog("HEAD", oldHead, targetHash, fmt.Sprintf("checkout: moving from %s to %s", from, target))
+
 	// 8. Restore sidecar directories (.gts/) from the committed tree.
 	r.restoreSidecarsFromTree(commit.TreeHash)
 
@@ -187,6 +236,21 @@ func (r *Repo) ensureClean() error {
 	return nil
 }
 
+// ensureIndexMatchesHead checks that nothing is staged, for commands that
+// commit the whole index. Unstaged and untracked files are allowed.
+func (r *Repo) ensureIndexMatchesHead() error {
+	entries, err := r.Status()
+	if err != nil {
+		return fmt.Errorf("check status: %w", err)
+	}
+	for _, e := range entries {
+		if e.IndexStatus != StatusClean && e.IndexStatus != StatusUntracked {
+			return fmt.Errorf("index does not match HEAD (file %q has staged changes); commit or unstage them first", e.Path)
+		}
+	}
+	return nil
+}
+
 // trackedFiles returns a set of all currently tracked file paths. It merges
 // paths from the HEAD tree and the staging index.
 func (r *Repo) trackedFiles() map[string]bool {
@@ -227,3 +291,13 @@ func (r *Repo) removeEmptyParents(dir string) {
 		dir = filepath.Dir(dir)
 	}
 }
+
+// checkoutLabel names what HEAD points at for the HEAD reflog: the current
+// branch, or the commit hash when HEAD is detached.
+func (r *Repo) checkoutLabel() string {
+	if branch, err := r.CurrentBranch(); err == nil && branch != "" {
+		return branch
+	}
+	head, _ := r.Head()
+	return head
+}
@@ -244,7 +244,7 @@ func (r *Repo) CherryPickContinue() (*CherryPickResult, error) {
 	// Check for unresolved conflicts in staging.
 	for _, entry := range stg.Entries {
 		if entry.Conflict {
-			return nil, fmt.Errorf("cherry-pick continue: unresolved conflicts remain; resolve them and stage the files")
+			return nil, fmt.Errorf("cherry-pick continue: %w remain; resolve them and stage the files", ErrMergeConflicts)
 		}
 	}
 
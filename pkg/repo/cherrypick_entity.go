@@ -3,7 +3,6 @@ package repo
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -115,10 +114,10 @@ func (r *Repo) CherryPickEntity(selector string, targetHash object.Hash) (*Cherr
 	}
 
 	absPath := filepath.Join(r.RootDir, filepath.FromSlash(relPath))
-	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+	if err := mkdirWorktree(r.RootDir, filepath.Dir(absPath)); err != nil {
 		return nil, fmt.Errorf("cherry-pick entity: mkdir %q: %w", filepath.Dir(absPath), err)
 	}
-	if err := os.WriteFile(absPath, mergeResult.Merged, filePermFromMode(oursState.mode)); err != nil {
+	if err := writeWorktreeFile(absPath, r.lineEndings().smudge(relPath, mergeResult.Merged, oursState.mode), oursState.mode); err != nil {
 		return nil, fmt.Errorf("cherry-pick entity: write %q: %w", relPath, err)
 	}
 
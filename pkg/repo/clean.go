@@ -59,6 +59,7 @@ func (r *Repo) collectCleanPaths(opts CleanOptions) ([]string, error) {
 	for p := range stg.Entries {
 		tracked[p] = true
 	}
+	_, trackedDirs := trackedStatusPaths(stg)
 
 	ic := NewIgnoreChecker(r.RootDir)
 
@@ -86,6 +87,11 @@ func (r *Repo) collectCleanPaths(opts CleanOptions) ([]string, error) {
 			return fs.SkipDir
 		}
 
+		// Leave other repositories in the working tree alone.
+		if _, hasTracked := trackedDirs[rel]; d.IsDir() && !hasTracked && isNestedRepoDir(path) {
+			return fs.SkipDir
+		}
+
 		ignored := ic.IsIgnored(rel)
 
 		// Skip ignored directories entirely unless we care about ignored files.
@@ -174,6 +180,9 @@ func (r *Repo) collectEmptyUntrackedDirs(tracked map[string]bool, ic *IgnoreChec
 		if !d.IsDir() {
 			return nil
 		}
+		if isNestedRepoDir(path) {
+			return fs.SkipDir
+		}
 
 		// Check if the directory is empty.
 		abs := filepath.Join(r.RootDir, filepath.FromSlash(rel))
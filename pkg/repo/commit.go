@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/pathspec"
 	"github.com/odvcencio/graft/pkg/userconfig"
 )
 
@@ -27,33 +29,72 @@ func formatAuthor(name, email string) string {
 	return name
 }
 
+// Environment variables overriding the configured identity of the author
+// or the committer of new commits, field by field.
+const (
+	AuthorNameEnv     = "GRAFT_AUTHOR_NAME"
+	AuthorEmailEnv    = "GRAFT_AUTHOR_EMAIL"
+	CommitterNameEnv  = "GRAFT_COMMITTER_NAME"
+	CommitterEmailEnv = "GRAFT_COMMITTER_EMAIL"
+)
+
 // ResolveAuthor determines the commit author by checking, in priority order:
-//  1. Repo config user.name + user.email
-//  2. User config (~/.graftconfig) name + email
-//  3. $USER environment variable
-//  4. "unknown"
+//  1. $GRAFT_AUTHOR_NAME and $GRAFT_AUTHOR_EMAIL, each overriding the
+//     matching field of the identity found below
+//  2. Repo config user.name + user.email
+//  3. User config (~/.graftconfig) name + email
+//  4. $USER environment variable
+//  5. "unknown"
 func (r *Repo) ResolveAuthor() string {
-	// 1. Repo-level config.
-	if cfg, err := r.ReadConfig(); err == nil && cfg.User != nil {
-		if author := formatAuthor(cfg.User.Name, cfg.User.Email); author != "" {
-			return author
-		}
+	return r.resolveIdentity(AuthorNameEnv, AuthorEmailEnv)
+}
+
+// ResolveCommitter determines the identity recorded as committer of new
+// commits: ResolveAuthor's configured identity, overridden by
+// $GRAFT_COMMITTER_NAME and $GRAFT_COMMITTER_EMAIL rather than the author
+// variables.
+func (r *Repo) ResolveCommitter() string {
+	return r.resolveIdentity(CommitterNameEnv, CommitterEmailEnv)
+}
+
+func (r *Repo) resolveIdentity(nameEnv, emailEnv string) string {
+	name, email := r.configIdentity()
+	if v := strings.TrimSpace(os.Getenv(nameEnv)); v != "" {
+		name = v
+	}
+	if v := strings.TrimSpace(os.Getenv(emailEnv)); v != "" {
+		email = v
+	}
+	if ident := formatAuthor(name, email); ident != "" {
+		return ident
 	}
+	return "unknown"
+}
 
-	// 2. User-level config (~/.graftconfig).
-	if ucfg, err := userconfig.Load(); err == nil && ucfg != nil {
-		if author := formatAuthor(ucfg.Name, ucfg.Email); author != "" {
-			return author
-		}
+// configIdentity returns the name and email of the first of the repo
+// config, the user config and $USER that has a name.
+func (r *Repo) configIdentity() (name, email string) {
+	// Repo-level config.
+	if cfg, err := r.ReadConfig(); err == nil && cfg.User != nil && strings.TrimSpace(cfg.User.Name) != "" {
+		return cfg.User.Name, cfg.User.Email
 	}
 
-	// 3. $USER env var.
-	if u := os.Getenv("USER"); u != "" {
-		return u
+	// User-level config (~/.graftconfig).
+	if ucfg, err := userconfig.Load(); err == nil && ucfg != nil && strings.TrimSpace(ucfg.Name) != "" {
+		return ucfg.Name, ucfg.Email
 	}
 
-	// 4. Fallback.
-	return "unknown"
+	// $USER env var.
+	return os.Getenv("USER"), ""
+}
+
+// setCommitter records the resolved committer on c, dated now, for
+// commits that replay or combine existing work.
+func (r *Repo) setCommitter(c *object.CommitObj) {
+	now := time.Now()
+	c.Committer = r.ResolveCommitter()
+	c.CommitterTimestamp = now.Unix()
+	c.CommitterTimezone = now.Format("-0700")
 }
 
 // CommitSigner signs canonical commit payload bytes and returns an encoded
@@ -75,35 +116,82 @@ func (r *Repo) Commit(message, author string) (object.Hash, error) {
 
 // CommitWithSigner creates a new commit and signs it when signer is provided.
 func (r *Repo) CommitWithSigner(message, author string, signer CommitSigner) (object.Hash, error) {
-	// 0a. Run pre-commit hook. If it fails, abort.
-	if err := r.RunHook(HookPreCommit); err != nil {
-		return "", fmt.Errorf("commit: %w", err)
-	}
+	return r.CommitWithOptions(message, author, CommitOptions{Signer: signer})
+}
 
-	// 0b. Run commit-msg hook. Write message to temp file, let hook modify it,
-	// then read back the (possibly modified) message.
-	msgFile := filepath.Join(r.GraftDir, "COMMIT_EDITMSG")
-	if err := os.WriteFile(msgFile, []byte(message), 0o644); err != nil {
-		return "", fmt.Errorf("commit: write message file: %w", err)
-	}
-	if err := r.RunHook(HookCommitMsg, msgFile); err != nil {
-		os.Remove(msgFile)
+// CommitPaths creates a commit recording only the staged state of the paths
+// selected by paths: every other path keeps its content from HEAD. The
+// staging area is left as it is, so changes staged for other paths stay
+// staged for a later commit. The commit is signed when signer is non-nil.
+func (r *Repo) CommitPaths(message, author string, paths *pathspec.Set, signer CommitSigner) (object.Hash, error) {
+	return r.CommitWithOptions(message, author, CommitOptions{Signer: signer, Paths: paths})
+}
+
+// CommitOptions controls optional behavior of CommitWithOptions and
+// CommitAmendWithOptions.
+type CommitOptions struct {
+	// Signer signs the commit when non-nil.
+	Signer CommitSigner
+
+	// Paths restricts the commit to the staged state of the selected
+	// paths, as CommitPaths does. Nil commits the whole staging area.
+	// Amending ignores it.
+	Paths *pathspec.Set
+
+	// AllowEmpty permits a commit from an empty staging area, recording
+	// an empty tree.
+	AllowEmpty bool
+
+	// NoVerify skips the pre-commit and commit-msg hooks, and Lint.
+	NoVerify bool
+
+	// Lint checks the message, as the commit-msg hook left it, against
+	// the commit section of the repository config; see LintCommitMessage.
+	Lint bool
+
+	// Date overrides the author date. The zero time means now.
+	Date time.Time
+
+	// Committer is recorded as the committer, dated now. Empty means
+	// ResolveCommitter.
+	Committer string
+}
+
+// CommitWithOptions creates a new commit from the current staging area as
+// Commit does, adjusted by opts. An empty author means ResolveAuthor.
+func (r *Repo) CommitWithOptions(message, author string, opts CommitOptions) (object.Hash, error) {
+	return r.CommitContext(context.Background(), message, author, opts)
+}
+
+// CommitContext is like CommitWithOptions but accepts an explicit context.
+// ctx is checked before the hooks run and again before the commit is
+// written; once the branch ref is being updated the commit completes.
+func (r *Repo) CommitContext(ctx context.Context, message, author string, opts CommitOptions) (object.Hash, error) {
+	if err := ctx.Err(); err != nil {
 		return "", fmt.Errorf("commit: %w", err)
 	}
-	modifiedMsg, err := os.ReadFile(msgFile)
+
+	opts, author = r.commitIdentities(opts, author)
+
+	// 0. Run the pre-commit and commit-msg hooks. The commit-msg hook may
+	// rewrite the message.
+	message, err := r.runCommitHooks(message, opts)
 	if err != nil {
-		return "", fmt.Errorf("commit: read message file: %w", err)
+		return "", fmt.Errorf("commit: %w", err)
 	}
-	os.Remove(msgFile)
-	message = string(modifiedMsg)
 
 	// 1. Read staging.
 	stg, err := r.ReadStaging()
 	if err != nil {
 		return "", fmt.Errorf("commit: %w", err)
 	}
-	if len(stg.Entries) == 0 {
-		return "", fmt.Errorf("commit: nothing staged")
+	if opts.Paths != nil {
+		if stg, err = r.partialCommitStaging(stg, opts.Paths); err != nil {
+			return "", fmt.Errorf("commit: %w", err)
+		}
+	}
+	if len(stg.Entries) == 0 && !opts.AllowEmpty {
+		return "", fmt.Errorf("commit: nothing staged (use --allow-empty to record an empty commit)")
 	}
 
 	// 1b. Run pre-commit-analysis hooks before building the tree. These
@@ -121,6 +209,9 @@ func (r *Repo) CommitWithSigner(message, author string, signer CommitSigner) (ob
 	if err != nil {
 		return "", fmt.Errorf("commit: %w", err)
 	}
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("commit: %w", err)
+	}
 
 	// 3. Resolve HEAD to get parent (may not exist for first commit).
 	var parents []object.Hash
@@ -131,16 +222,10 @@ func (r *Repo) CommitWithSigner(message, author string, signer CommitSigner) (ob
 	// If HEAD resolution fails (e.g., first commit, no ref file), that's fine.
 
 	// 4. Create CommitObj.
-	commitObj := &object.CommitObj{
-		TreeHash:  treeHash,
-		Parents:   parents,
-		Author:    author,
-		Timestamp: time.Now().Unix(),
-		Message:   message,
-	}
-	if signer != nil {
+	commitObj := newCommitObj(treeHash, parents, author, message, opts)
+	if opts.Signer != nil {
 		payload := object.CommitSigningPayload(commitObj)
-		signature, err := signer(payload)
+		signature, err := opts.Signer(payload)
 		if err != nil {
 			return "", fmt.Errorf("commit: sign commit: %w", err)
 		}
@@ -198,6 +283,14 @@ func (r *Repo) CommitAmend(message, author string) (object.Hash, error) {
 // CommitAmendWithSigner is like CommitAmend but signs the new commit when
 // signer is non-nil.
 func (r *Repo) CommitAmendWithSigner(message, author string, signer CommitSigner) (object.Hash, error) {
+	return r.CommitAmendWithOptions(message, author, CommitOptions{Signer: signer})
+}
+
+// CommitAmendWithOptions is like CommitAmend, adjusted by opts. An empty
+// author means ResolveAuthor.
+func (r *Repo) CommitAmendWithOptions(message, author string, opts CommitOptions) (object.Hash, error) {
+	opts, author = r.commitIdentities(opts, author)
+
 	// 1. Read the current HEAD commit.
 	headHash, err := r.ResolveRef("HEAD")
 	if err != nil {
@@ -213,33 +306,18 @@ func (r *Repo) CommitAmendWithSigner(message, author string, signer CommitSigner
 		message = oldCommit.Message
 	}
 
-	// 3. Run pre-commit hook.
-	if err := r.RunHook(HookPreCommit); err != nil {
-		return "", fmt.Errorf("commit --amend: %w", err)
-	}
-
-	// 4. Run commit-msg hook.
-	msgFile := filepath.Join(r.GraftDir, "COMMIT_EDITMSG")
-	if err := os.WriteFile(msgFile, []byte(message), 0o644); err != nil {
-		return "", fmt.Errorf("commit --amend: write message file: %w", err)
-	}
-	if err := r.RunHook(HookCommitMsg, msgFile); err != nil {
-		os.Remove(msgFile)
-		return "", fmt.Errorf("commit --amend: %w", err)
-	}
-	modifiedMsg, err := os.ReadFile(msgFile)
+	// 3-4. Run the pre-commit and commit-msg hooks.
+	message, err = r.runCommitHooks(message, opts)
 	if err != nil {
-		return "", fmt.Errorf("commit --amend: read message file: %w", err)
+		return "", fmt.Errorf("commit --amend: %w", err)
 	}
-	os.Remove(msgFile)
-	message = string(modifiedMsg)
 
 	// 5. Read staging and build tree.
 	stg, err := r.ReadStaging()
 	if err != nil {
 		return "", fmt.Errorf("commit --amend: %w", err)
 	}
-	if len(stg.Entries) == 0 {
+	if len(stg.Entries) == 0 && !opts.AllowEmpty {
 		return "", fmt.Errorf("commit --amend: nothing staged")
 	}
 
@@ -252,16 +330,10 @@ func (r *Repo) CommitAmendWithSigner(message, author string, signer CommitSigner
 	parents := oldCommit.Parents
 
 	// 7. Create the new commit object.
-	commitObj := &object.CommitObj{
-		TreeHash:  treeHash,
-		Parents:   parents,
-		Author:    author,
-		Timestamp: time.Now().Unix(),
-		Message:   message,
-	}
-	if signer != nil {
+	commitObj := newCommitObj(treeHash, parents, author, message, opts)
+	if opts.Signer != nil {
 		payload := object.CommitSigningPayload(commitObj)
-		signature, err := signer(payload)
+		signature, err := opts.Signer(payload)
 		if err != nil {
 			return "", fmt.Errorf("commit --amend: sign commit: %w", err)
 		}
@@ -298,6 +370,71 @@ func (r *Repo) CommitAmendWithSigner(message, author string, signer CommitSigner
 	return commitHash, nil
 }
 
+// runCommitHooks runs the pre-commit hook and then the commit-msg hook,
+// which may rewrite the message, and lints the result when opts.Lint is
+// set, unless opts.NoVerify is set. It returns the message to commit.
+func (r *Repo) runCommitHooks(message string, opts CommitOptions) (string, error) {
+	if opts.NoVerify {
+		return message, nil
+	}
+	if err := r.RunHook(HookPreCommit); err != nil {
+		return "", err
+	}
+
+	// The commit-msg hook edits the message in a file.
+	msgFile := filepath.Join(r.GraftDir, "COMMIT_EDITMSG")
+	if err := os.WriteFile(msgFile, []byte(message), 0o644); err != nil {
+		return "", fmt.Errorf("write message file: %w", err)
+	}
+	defer os.Remove(msgFile)
+	if err := r.RunHook(HookCommitMsg, msgFile); err != nil {
+		return "", err
+	}
+	modifiedMsg, err := os.ReadFile(msgFile)
+	if err != nil {
+		return "", fmt.Errorf("read message file: %w", err)
+	}
+	if opts.Lint {
+		if err := r.lintCommitMessage(string(modifiedMsg)); err != nil {
+			return "", err
+		}
+	}
+	return string(modifiedMsg), nil
+}
+
+// commitIdentities fills in the resolved author and committer where the
+// caller left them empty.
+func (r *Repo) commitIdentities(opts CommitOptions, author string) (CommitOptions, string) {
+	if strings.TrimSpace(author) == "" {
+		author = r.ResolveAuthor()
+	}
+	if strings.TrimSpace(opts.Committer) == "" {
+		opts.Committer = r.ResolveCommitter()
+	}
+	return opts, author
+}
+
+// newCommitObj assembles an unsigned commit, dated now or at opts.Date,
+// with opts.Committer as committer, dated now.
+func newCommitObj(treeHash object.Hash, parents []object.Hash, author, message string, opts CommitOptions) *object.CommitObj {
+	now := time.Now()
+	commitObj := &object.CommitObj{
+		TreeHash:           treeHash,
+		Parents:            parents,
+		Author:             author,
+		Timestamp:          now.Unix(),
+		Committer:          opts.Committer,
+		CommitterTimestamp: now.Unix(),
+		CommitterTimezone:  now.Format("-0700"),
+		Message:            message,
+	}
+	if !opts.Date.IsZero() {
+		commitObj.Timestamp = opts.Date.Unix()
+		commitObj.AuthorTimezone = opts.Date.Format("-0700")
+	}
+	return commitObj
+}
+
 // Log walks the commit history starting from the given hash, following
 // first-parent links, returning up to limit commits in reverse-chronological
 // order (newest first). In a shallow repository, walking stops at shallow
@@ -370,6 +507,7 @@ func (r *Repo) commitFromStaging(p commitStagingParams) (object.Hash, error) {
 		Timestamp: time.Now().Unix(),
 		Message:   p.Message,
 	}
+	r.setCommitter(commitObj)
 
 	commitHash, err := r.Store.WriteCommit(commitObj)
 	if err != nil {
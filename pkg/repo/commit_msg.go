@@ -0,0 +1,207 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/odvcencio/graft/pkg/userconfig"
+)
+
+// DefaultConventionalTypes are the types a conventional commit subject may
+// use when commit.conventionalTypes is not configured.
+var DefaultConventionalTypes = []string{
+	"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test",
+}
+
+// Cleanup modes of CleanupCommitMessage, named after git's --cleanup.
+const (
+	// CleanupStrip drops lines starting with '#' and then cleans up
+	// whitespace as CleanupWhitespace does.
+	CleanupStrip = "strip"
+	// CleanupWhitespace trims trailing whitespace from every line,
+	// collapses runs of blank lines and drops leading and trailing blank
+	// lines, final newline included.
+	CleanupWhitespace = "whitespace"
+	// CleanupVerbatim leaves the message as it is.
+	CleanupVerbatim = "verbatim"
+)
+
+// conventionalSubject matches "type(scope)!: summary", the scope and the
+// breaking-change marker being optional.
+var conventionalSubject = regexp.MustCompile(`^([A-Za-z]+)(\([^()]+\))?!?: \S`)
+
+// lintExemptPrefixes start subjects that tools write rather than people:
+// merges, reverts and autosquash markers are not held to the conventional
+// format.
+var lintExemptPrefixes = []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "}
+
+// CleanupCommitMessage cleans up message according to mode, one of
+// CleanupStrip, CleanupWhitespace and CleanupVerbatim.
+func CleanupCommitMessage(message, mode string) (string, error) {
+	switch mode {
+	case CleanupVerbatim:
+		return message, nil
+	case CleanupStrip, CleanupWhitespace:
+	default:
+		return "", fmt.Errorf("invalid cleanup mode %q (want strip, whitespace or verbatim)", mode)
+	}
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(message, "\n") {
+		if mode == CleanupStrip && strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// LintCommitMessage checks message against the rules of cfg, reporting
+// every rule it breaks in one error. A nil cfg accepts any message.
+func LintCommitMessage(message string, cfg *CommitConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	subject, _, _ := strings.Cut(strings.TrimLeft(message, "\n"), "\n")
+	subject = strings.TrimSpace(subject)
+
+	var problems []string
+	if cfg.MaxSubjectLength > 0 {
+		if n := utf8.RuneCountInString(subject); n > cfg.MaxSubjectLength {
+			problems = append(problems, fmt.Sprintf("subject is %d characters long, more than the %d allowed by commit.maxSubjectLength", n, cfg.MaxSubjectLength))
+		}
+	}
+	if cfg.Conventional && !lintExempt(subject) {
+		types := cfg.ConventionalTypes
+		if len(types) == 0 {
+			types = DefaultConventionalTypes
+		}
+		m := conventionalSubject.FindStringSubmatch(subject)
+		switch {
+		case m == nil:
+			problems = append(problems, fmt.Sprintf("subject %q is not a conventional commit: want \"type(scope): summary\"", subject))
+		case !slices.Contains(types, m[1]):
+			problems = append(problems, fmt.Sprintf("type %q is not one of %s", m[1], strings.Join(types, ", ")))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("commit message rejected:\n  %s", strings.Join(problems, "\n  "))
+}
+
+func lintExempt(subject string) bool {
+	for _, prefix := range lintExemptPrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintCommitMessage checks message against the commit section of the
+// repository config.
+func (r *Repo) lintCommitMessage(message string) error {
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		return err
+	}
+	return LintCommitMessage(message, cfg.Commit)
+}
+
+// CommitTemplatePath returns the path of the commit message template:
+// the repository's commit.template, else the user config's. It returns ""
+// when neither is set.
+func (r *Repo) CommitTemplatePath() (string, error) {
+	var template string
+	if cfg, err := r.ReadConfig(); err != nil {
+		return "", err
+	} else if cfg.Commit != nil {
+		template = strings.TrimSpace(cfg.Commit.Template)
+	}
+	if template == "" {
+		if ucfg, err := userconfig.Load(); err == nil && ucfg != nil {
+			template = strings.TrimSpace(ucfg.CommitTemplate)
+		}
+	}
+	if template == "" {
+		return "", nil
+	}
+	if rest, ok := strings.CutPrefix(template, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("commit template: resolve home dir: %w", err)
+		}
+		return filepath.Join(home, rest), nil
+	}
+	if !filepath.IsAbs(template) {
+		template = filepath.Join(r.RootDir, template)
+	}
+	return template, nil
+}
+
+// CommitTemplate returns the content of the commit message template, ""
+// when none is configured. A configured template that cannot be read is
+// an error.
+func (r *Repo) CommitTemplate() (string, error) {
+	path, err := r.CommitTemplatePath()
+	if err != nil || path == "" {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("commit template: %w", err)
+	}
+	return string(data), nil
+}
+
+// EditCommitMessage opens $VISUAL, else $EDITOR, else vi on initial in
+// .graft/COMMIT_EDITMSG and returns the text the editor left there.
+func (r *Repo) EditCommitMessage(initial string) (string, error) {
+	msgFile := filepath.Join(r.GraftDir, "COMMIT_EDITMSG")
+	if err := os.WriteFile(msgFile, []byte(initial), 0o644); err != nil {
+		return "", fmt.Errorf("write message file: %w", err)
+	}
+	defer os.Remove(msgFile)
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	if err := RunExternalProcess(ExternalProcessSpec{
+		Dir:    r.RootDir,
+		Path:   editor,
+		Args:   []string{msgFile},
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Label:  "commit-editor",
+	}); err != nil {
+		return "", fmt.Errorf("editor exited with error: %w", err)
+	}
+
+	edited, err := os.ReadFile(msgFile)
+	if err != nil {
+		return "", fmt.Errorf("read message file: %w", err)
+	}
+	return string(edited), nil
+}
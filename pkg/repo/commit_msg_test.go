@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanupCommitMessage(t *testing.T) {
+	msg := "\n\nSubject  \n\n\n# a comment\nBody line\t\n\n#tail\n\n"
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{CleanupStrip, "Subject\n\nBody line"},
+		{CleanupWhitespace, "Subject\n\n# a comment\nBody line\n\n#tail"},
+		{CleanupVerbatim, msg},
+	}
+	for _, tt := range tests {
+		got, err := CleanupCommitMessage(msg, tt.mode)
+		if err != nil {
+			t.Fatalf("CleanupCommitMessage(%s): %v", tt.mode, err)
+		}
+		if got != tt.want {
+			t.Errorf("CleanupCommitMessage(%s) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+	if got, _ := CleanupCommitMessage("# only comments\n\n", CleanupStrip); got != "" {
+		t.Errorf("CleanupCommitMessage of comments = %q, want empty", got)
+	}
+	if _, err := CleanupCommitMessage(msg, "scissors"); err == nil {
+		t.Error("CleanupCommitMessage accepted an unknown mode")
+	}
+}
+
+func TestLintCommitMessage(t *testing.T) {
+	cfg := &CommitConfig{Conventional: true, MaxSubjectLength: 30}
+	tests := []struct {
+		message string
+		wantErr string
+	}{
+		{"feat: add parser", ""},
+		{"fix(repo)!: drop legacy refs\n\nBREAKING CHANGE: refs/old is gone", ""},
+		{"Merge branch 'topic' into main", ""},
+		{"fixup! feat: add parser", ""},
+		{"add parser", "not a conventional commit"},
+		{"feature: add parser", `type "feature" is not one of`},
+		{"feat: a subject that goes on for far too long", "maxSubjectLength"},
+	}
+	for _, tt := range tests {
+		err := LintCommitMessage(tt.message, cfg)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("LintCommitMessage(%q) = %v, want nil", tt.message, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LintCommitMessage(%q) = %v, want error containing %q", tt.message, err, tt.wantErr)
+		}
+	}
+
+	custom := &CommitConfig{Conventional: true, ConventionalTypes: []string{"feature"}}
+	if err := LintCommitMessage("feature: add parser", custom); err != nil {
+		t.Errorf("LintCommitMessage with custom types = %v", err)
+	}
+	if err := LintCommitMessage("anything goes", nil); err != nil {
+		t.Errorf("LintCommitMessage without config = %v", err)
+	}
+}
+
+func TestCommitLintAndTemplate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl, err := r.CommitTemplate(); err != nil || tmpl != "" {
+		t.Fatalf("CommitTemplate without config = %q, %v", tmpl, err)
+	}
+
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Commit = &CommitConfig{Template: "tmpl.txt", Conventional: true}
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tmpl.txt"), []byte("# why?\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if tmpl, err := r.CommitTemplate(); err != nil || tmpl != "# why?\n" {
+		t.Fatalf("CommitTemplate = %q, %v", tmpl, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add([]string{"a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CommitWithOptions("add a", "tester", CommitOptions{Lint: true}); err == nil {
+		t.Fatal("CommitWithOptions accepted a non-conventional message with Lint")
+	}
+	if _, err := r.CommitWithOptions("add a", "tester", CommitOptions{Lint: true, NoVerify: true}); err != nil {
+		t.Fatalf("CommitWithOptions with NoVerify: %v", err)
+	}
+}
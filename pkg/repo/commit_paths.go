@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/pathspec"
+)
+
+// partialCommitStaging returns the staging area a partial commit is built
+// from: the HEAD tree, with each path selected by paths replaced by its
+// staged entry, or dropped when it is no longer staged. stg itself is not
+// modified. It is an error for paths to select nothing in either HEAD or
+// the staging area.
+func (r *Repo) partialCommitStaging(stg *Staging, paths *pathspec.Set) (*Staging, error) {
+	partial := &Staging{Entries: make(map[string]*StagingEntry)}
+
+	headHash, err := r.ResolveRef("HEAD")
+	if err == nil && headHash != "" {
+		commit, err := r.Store.ReadCommit(headHash)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("read HEAD commit: %w", err)
+		}
+		if commit != nil {
+			files, modules, err := r.FlattenTreeWithModules(commit.TreeHash)
+			if err != nil {
+				return nil, fmt.Errorf("flatten HEAD tree: %w", err)
+			}
+			for _, f := range files {
+				partial.Entries[f.Path] = &StagingEntry{
+					Path:           f.Path,
+					BlobHash:       f.BlobHash,
+					EntityListHash: f.EntityListHash,
+					Mode:           normalizeFileMode(f.Mode),
+				}
+			}
+			for _, m := range modules {
+				partial.Entries[m.Path] = &StagingEntry{
+					Path:     m.Path,
+					BlobHash: m.BlobHash,
+					Mode:     object.TreeModeModule,
+				}
+			}
+		}
+	}
+
+	matched := false
+	for path := range partial.Entries {
+		if !paths.Match(path) {
+			continue
+		}
+		matched = true
+		if _, staged := stg.Entries[path]; !staged {
+			delete(partial.Entries, path)
+		}
+	}
+	for path, se := range stg.Entries {
+		if paths.Match(path) {
+			matched = true
+			partial.Entries[path] = se
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("pathspec did not match any tracked file")
+	}
+	return partial, nil
+}
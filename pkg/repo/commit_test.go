@@ -3,9 +3,13 @@ package repo
 import (
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/pathspec"
 )
 
 // helper: initRepoWithFile creates a temp repo, writes a Go file, and stages it.
@@ -374,3 +378,133 @@ func TestCommitWithSigner_PersistsSignature(t *testing.T) {
 		t.Fatalf("Signature = %q, want %q", c.Signature, sigValue)
 	}
 }
+
+func TestCommitPaths_CommitsOnlySelectedStagedPaths(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitFile(t, r, "a.txt", []byte("a1\n"), "initial a")
+	commitFile(t, r, "b.txt", []byte("b1\n"), "initial b")
+	commitFile(t, r, "old.txt", []byte("old\n"), "initial old")
+
+	writeFile(t, filepath.Join(r.RootDir, "a.txt"), []byte("a2\n"))
+	writeFile(t, filepath.Join(r.RootDir, "b.txt"), []byte("b2\n"))
+	writeFile(t, filepath.Join(r.RootDir, "docs", "new.md"), []byte("new\n"))
+	if err := r.Add([]string{"a.txt", "b.txt", "docs/new.md"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := r.Remove([]string{"old.txt"}, false); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	paths, err := pathspec.ParseSet([]string{"a.txt", "docs", "old.txt"})
+	if err != nil {
+		t.Fatalf("ParseSet: %v", err)
+	}
+	h, err := r.CommitPaths("partial", "test-author", paths, nil)
+	if err != nil {
+		t.Fatalf("CommitPaths: %v", err)
+	}
+
+	c, err := r.Store.ReadCommit(h)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	files, err := r.FlattenTree(c.TreeHash)
+	if err != nil {
+		t.Fatalf("FlattenTree: %v", err)
+	}
+	got := make(map[string]object.Hash, len(files))
+	for _, f := range files {
+		got[f.Path] = f.BlobHash
+	}
+	want := map[string]string{"a.txt": "a2\n", "b.txt": "b1\n", "docs/new.md": "new\n"}
+	if len(got) != len(want) {
+		t.Fatalf("committed paths = %v, want %v", got, want)
+	}
+	for path, content := range want {
+		if got[path] != object.HashObject(object.TypeBlob, []byte(content)) {
+			t.Fatalf("%s does not hold %q in the commit", path, content)
+		}
+	}
+
+	// b.txt is still staged against the new HEAD; the rest is committed.
+	status := statusByPath(t, r)
+	if status["b.txt"].IndexStatus != StatusModified {
+		t.Fatalf("b.txt IndexStatus = %d, want modified", status["b.txt"].IndexStatus)
+	}
+	for _, path := range []string{"a.txt", "docs/new.md"} {
+		if status[path].IndexStatus != StatusClean {
+			t.Fatalf("%s IndexStatus = %d, want clean", path, status[path].IndexStatus)
+		}
+	}
+
+	none, err := pathspec.ParseSet([]string{"missing.txt"})
+	if err != nil {
+		t.Fatalf("ParseSet: %v", err)
+	}
+	if _, err := r.CommitPaths("nothing", "test-author", none, nil); err == nil || !strings.Contains(err.Error(), "did not match") {
+		t.Fatalf("CommitPaths(missing.txt) error = %v, want a pathspec error", err)
+	}
+}
+
+func TestCommitWithOptions_AllowEmptyDateAndCommitter(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if _, err := r.Commit("empty", "test-author"); err == nil || !strings.Contains(err.Error(), "nothing staged") {
+		t.Fatalf("Commit on an empty index: err = %v, want nothing staged", err)
+	}
+
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 2*3600))
+	h, err := r.CommitWithOptions("empty", "Other <other@example.com>", CommitOptions{
+		AllowEmpty: true,
+		Date:       date,
+		Committer:  "test-author",
+	})
+	if err != nil {
+		t.Fatalf("CommitWithOptions: %v", err)
+	}
+	c, err := r.Store.ReadCommit(h)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	if files, err := r.FlattenTree(c.TreeHash); err != nil || len(files) != 0 {
+		t.Fatalf("FlattenTree = %v, %v; want an empty tree", files, err)
+	}
+	if c.Author != "Other <other@example.com>" || c.Committer != "test-author" {
+		t.Fatalf("author %q committer %q, want the overrides", c.Author, c.Committer)
+	}
+	if c.Timestamp != date.Unix() || c.AuthorTimezone != "+0200" {
+		t.Fatalf("author date = %d %s, want %d +0200", c.Timestamp, c.AuthorTimezone, date.Unix())
+	}
+	if c.CommitterTimestamp == 0 {
+		t.Fatal("committer date not recorded")
+	}
+}
+
+func TestCommitWithOptions_NoVerifySkipsHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests require unix shell scripts")
+	}
+	r := initRepoWithFile(t, "main.go", []byte("package main\n\nfunc main() {}\n"))
+	installHook(t, r, HookPreCommit, "#!/bin/sh\nexit 1\n", true)
+	installHook(t, r, HookCommitMsg, "#!/bin/sh\nexit 1\n", true)
+
+	if _, err := r.Commit("blocked", "test-author"); err == nil {
+		t.Fatal("Commit succeeded despite a failing pre-commit hook")
+	}
+	h, err := r.CommitWithOptions("unverified", "test-author", CommitOptions{NoVerify: true})
+	if err != nil {
+		t.Fatalf("CommitWithOptions(NoVerify): %v", err)
+	}
+	c, err := r.Store.ReadCommit(h)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	if c.Message != "unverified" {
+		t.Fatalf("Message = %q, want %q", c.Message, "unverified")
+	}
+}
@@ -6,18 +6,179 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/remote"
 )
 
+func init() {
+	remote.SetLocalStoreOpener(openConfiguredStore)
+}
+
 // UserConfig stores user identity for commits.
 type UserConfig struct {
 	Name  string `json:"name,omitempty"`
 	Email string `json:"email,omitempty"`
 }
 
+// StorageConfig controls how objects are laid out on disk.
+type StorageConfig struct {
+	// ChunkLargeBlobs stores large text blobs as content-defined chunk lists
+	// so successive versions share most of their chunks on disk. Transfers
+	// to and from remotes still carry whole blobs.
+	ChunkLargeBlobs bool `json:"chunkLargeBlobs,omitempty"`
+	// ChunkMinSize is the smallest blob that is chunked, in bytes. Zero uses
+	// object.DefaultChunkMinSize.
+	ChunkMinSize int64 `json:"chunkMinSize,omitempty"`
+	// Encrypt seals loose objects and packs at rest with the key read from
+	// EncryptionKeyFile, or from $GRAFT_ENCRYPTION_KEY when set.
+	Encrypt bool `json:"encrypt,omitempty"`
+	// EncryptionKeyFile is the path of a hex-encoded 32-byte key. It should
+	// live outside the repository so synced copies of .graft stay opaque.
+	EncryptionKeyFile string `json:"encryptionKeyFile,omitempty"`
+}
+
+// CoreConfig holds working tree settings.
+type CoreConfig struct {
+	// FSMonitor is a command reporting the paths changed since a token, such
+	// as a watchman hook; see queryFSMonitor. Relative paths are resolved
+	// against the repository root.
+	FSMonitor string `json:"fsmonitor,omitempty"`
+	// FileMode says whether the executable bit of working-tree files can be
+	// trusted. Set it to false on filesystems that cannot represent the bit,
+	// such as FAT or Windows drives; status and add then keep the mode
+	// recorded in the index. Unset means true, except on Windows.
+	FileMode *bool `json:"filemode,omitempty"`
+	// IgnoreCase says whether the working tree is on a case-insensitive
+	// filesystem, where paths differing only in case are one file. Unset
+	// means the filesystem is probed.
+	IgnoreCase *bool `json:"ignorecase,omitempty"`
+	// AutoCRLF converts line endings of text files without text or eol
+	// attributes: "true" stores LF and checks out CRLF, "input" only
+	// stores LF. Empty or "false" leaves line endings alone.
+	AutoCRLF string `json:"autocrlf,omitempty"`
+	// AutoStash makes checkout, merge and rebase stash local changes
+	// before running and re-apply them afterwards, as --autostash does.
+	AutoStash bool `json:"autostash,omitempty"`
+	// GitSync makes the colocated .git follow graft's history exactly
+	// rather than through shadowed git commands; see SyncToGit.
+	GitSync bool `json:"gitSync,omitempty"`
+	// Pager is the command that log, diff and blame pipe their output
+	// through when writing to a terminal. It overrides the user config and
+	// $PAGER but not $GRAFT_PAGER; "cat" turns paging off.
+	Pager string `json:"pager,omitempty"`
+}
+
+// ColorConfig controls colored output.
+type ColorConfig struct {
+	// UI is "auto", "always" or "never", the default for --color. Empty
+	// falls back to the user config, then to "auto".
+	UI string `json:"ui,omitempty"`
+}
+
+// GCConfig controls packing of the object store.
+type GCConfig struct {
+	// Auto is the number of loose objects above which commit and pull
+	// pack them. Unset means DefaultGCAuto; zero turns automatic packing
+	// off.
+	Auto *int `json:"auto,omitempty"`
+	// AutoPackLimit is the number of packs without a .keep marker above
+	// which automatic packing consolidates them. Unset means
+	// DefaultGCAutoPackLimit; zero never consolidates.
+	AutoPackLimit *int `json:"autoPackLimit,omitempty"`
+	// AggressiveWindow is the delta window of gc --aggressive. Zero means
+	// object.DefaultAggressiveDeltaWindow.
+	AggressiveWindow int `json:"aggressiveWindow,omitempty"`
+}
+
+// CommitConfig controls commit messages.
+type CommitConfig struct {
+	// Template is a file whose content the commit message editor starts
+	// from. Relative paths are resolved against the repository root and
+	// ~/ against the home directory. It overrides the user config.
+	Template string `json:"template,omitempty"`
+	// Conventional rejects messages whose subject is not a conventional
+	// commit, "type(scope)!: summary" with a type in ConventionalTypes.
+	Conventional bool `json:"conventional,omitempty"`
+	// ConventionalTypes are the types a conventional subject may use.
+	// Empty means DefaultConventionalTypes.
+	ConventionalTypes []string `json:"conventionalTypes,omitempty"`
+	// MaxSubjectLength rejects messages whose subject is longer than this
+	// many characters. Zero means no limit.
+	MaxSubjectLength int `json:"maxSubjectLength,omitempty"`
+}
+
+// RemoteConfig holds per-remote settings beyond the URL.
+type RemoteConfig struct {
+	// Fetch lists refspecs choosing which remote refs a fetch downloads and
+	// the local refs they update. Empty tracks every remote ref under
+	// refs/remotes/<remote>/.
+	Fetch []string `json:"fetch,omitempty"`
+	// Push lists refspecs mapping local branches to the remote refs a push
+	// updates. Empty pushes each branch to the remote branch of that name.
+	Push []string `json:"push,omitempty"`
+	// Mirror replicates every branch, tag and note: fetches prune refs
+	// deleted on the remote and a push without refs mirrors to it.
+	Mirror bool `json:"mirror,omitempty"`
+}
+
+// FilterConfig holds the commands of a content filter. Paths select a
+// filter by name with the filter attribute in .graftattributes. Each command
+// runs through sh with the content on stdin and writes the result to
+// stdout; %f in a command is replaced by the quoted path being filtered.
+type FilterConfig struct {
+	// Clean turns working-tree content into the content to store, on add
+	// and when status compares files.
+	Clean string `json:"clean,omitempty"`
+	// Smudge turns stored content into working-tree content on checkout.
+	Smudge string `json:"smudge,omitempty"`
+}
+
 // Config stores repository-local settings such as named remotes.
 type Config struct {
-	Remotes map[string]string `json:"remotes,omitempty"`
-	User    *UserConfig       `json:"user,omitempty"`
+	Remotes        map[string]string        `json:"remotes,omitempty"`
+	RemoteSettings map[string]*RemoteConfig `json:"remoteSettings,omitempty"`
+	User           *UserConfig              `json:"user,omitempty"`
+	Storage        *StorageConfig           `json:"storage,omitempty"`
+	Core           *CoreConfig              `json:"core,omitempty"`
+	GC             *GCConfig                `json:"gc,omitempty"`
+	Color          *ColorConfig             `json:"color,omitempty"`
+	Commit         *CommitConfig            `json:"commit,omitempty"`
+	Filters        map[string]*FilterConfig `json:"filters,omitempty"`
+}
+
+// applyStorageConfig configures the object store from the storage section of
+// the repository config. An unreadable config leaves the defaults in place;
+// commands that need the config report the error themselves. When
+// encryption is configured but the key cannot be loaded, object writes are
+// blocked so nothing is stored in plaintext by mistake.
+func (r *Repo) applyStorageConfig() {
+	cfg, err := r.ReadConfig()
+	if err != nil || cfg.Storage == nil {
+		return
+	}
+	r.Store.SetChunking(object.ChunkingOptions{
+		Enabled: cfg.Storage.ChunkLargeBlobs,
+		MinSize: cfg.Storage.ChunkMinSize,
+	})
+	if cfg.Storage.Encrypt {
+		key, err := LoadEncryptionKey(cfg.Storage.EncryptionKeyFile)
+		if err == nil {
+			err = r.Store.SetEncryptionKey(key)
+		}
+		if err != nil {
+			r.Store.SetEncryptionUnavailable(err)
+		}
+	}
+}
+
+// openConfiguredStore opens the object store in graftDir with the storage
+// config of that repository applied, so pushes into a local repository
+// honour its chunking and encryption settings.
+func openConfiguredStore(graftDir string) *object.Store {
+	r := &Repo{GraftDir: graftDir, Store: object.NewStore(graftDir)}
+	r.applyStorageConfig()
+	return r.Store
 }
 
 func (r *Repo) configPath() string {
@@ -0,0 +1,165 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// DefaultDirtyMark is appended to the description of a dirty working tree
+// when Format is given no mark of its own.
+const DefaultDirtyMark = "-dirty"
+
+// ErrNoDescription is returned by Describe when no tag reaches the commit
+// and DescribeOptions.Always is not set.
+var ErrNoDescription = errors.New("no tag can describe the commit")
+
+// DescribeOptions controls Describe.
+type DescribeOptions struct {
+	// Tags considers lightweight tags too, not only annotated ones.
+	Tags bool
+	// Always falls back to the abbreviated commit hash when no tag
+	// reaches the commit.
+	Always bool
+	// Dirty checks the working tree and staging area against HEAD; only
+	// valid when describing HEAD.
+	Dirty bool
+}
+
+// Description names a commit relative to the nearest tag it descends
+// from.
+type Description struct {
+	Commit object.Hash
+	// Tag is the nearest tag, or empty when none reaches Commit.
+	Tag string
+	// Distance counts the commits reachable from Commit but not from Tag.
+	Distance int
+	// Dirty reports uncommitted changes to tracked files; set only when
+	// DescribeOptions.Dirty was.
+	Dirty bool
+}
+
+// Format renders d the way git describe does: the tag alone for a tagged
+// commit, otherwise <tag>-<distance>-g<hash>, or just the abbreviated hash
+// when there is no tag. long forces the <tag>-<distance>-g<hash> form for
+// tagged commits too. dirtyMark, or DefaultDirtyMark when empty, is
+// appended for a dirty working tree.
+func (d *Description) Format(long bool, dirtyMark string) string {
+	var s string
+	switch {
+	case d.Tag == "":
+		s = shortHash(d.Commit)
+	case d.Distance == 0 && !long:
+		s = d.Tag
+	default:
+		s = fmt.Sprintf("%s-%d-g%s", d.Tag, d.Distance, shortHash(d.Commit))
+	}
+	if d.Dirty {
+		if dirtyMark == "" {
+			dirtyMark = DefaultDirtyMark
+		}
+		s += dirtyMark
+	}
+	return s
+}
+
+// Describe finds the tag nearest to rev (HEAD when empty): of the tags
+// whose commit rev descends from, the one with the fewest commits between
+// them, preferring the newer tag on a tie.
+func (r *Repo) Describe(rev string, opts DescribeOptions) (*Description, error) {
+	if rev != "" && opts.Dirty {
+		return nil, fmt.Errorf("describe: --dirty describes the working tree and cannot be combined with a revision")
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	h, err := r.ResolveTreeish(rev)
+	if err != nil {
+		return nil, fmt.Errorf("describe: %w", err)
+	}
+	commit, err := r.peelToCommit(h)
+	if err != nil {
+		return nil, fmt.Errorf("describe: %s: %w", rev, err)
+	}
+
+	d := &Description{Commit: commit}
+	if err := r.nearestTag(d, opts.Tags); err != nil {
+		if !errors.Is(err, ErrNoDescription) || !opts.Always {
+			return nil, err
+		}
+	}
+	if opts.Dirty {
+		if d.Dirty, err = r.worktreeDirty(); err != nil {
+			return nil, fmt.Errorf("describe: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// nearestTag fills in d.Tag and d.Distance.
+func (r *Repo) nearestTag(d *Description, lightweight bool) error {
+	tags, err := r.ListTagInfo(TagListOptions{})
+	if err != nil {
+		return fmt.Errorf("describe: %w", err)
+	}
+	ancestors, err := r.RevList(&RevRange{Include: []object.Hash{d.Commit}}, RevListOptions{})
+	if err != nil {
+		return fmt.Errorf("describe: %w", err)
+	}
+	inHistory := make(map[object.Hash]bool, len(ancestors))
+	for _, h := range ancestors {
+		inHistory[h] = true
+	}
+
+	var best *TagInfo
+	skippedLightweight := false
+	for i := range tags {
+		t := &tags[i]
+		if !inHistory[t.Commit] {
+			continue
+		}
+		if !t.Annotated && !lightweight {
+			skippedLightweight = true
+			continue
+		}
+		distance := 0
+		if t.Commit != d.Commit {
+			between, err := r.RevList(&RevRange{Include: []object.Hash{d.Commit}, Exclude: []object.Hash{t.Commit}}, RevListOptions{})
+			if err != nil {
+				return fmt.Errorf("describe: %w", err)
+			}
+			distance = len(between)
+		}
+		if best == nil || distance < d.Distance || (distance == d.Distance && t.Timestamp > best.Timestamp) {
+			best, d.Distance = t, distance
+		}
+	}
+	if best == nil {
+		d.Distance = 0
+		if skippedLightweight {
+			return fmt.Errorf("describe: %w %s; only lightweight tags reach it, try --tags", ErrNoDescription, shortHash(d.Commit))
+		}
+		return fmt.Errorf("describe: %w %s", ErrNoDescription, shortHash(d.Commit))
+	}
+	d.Tag = best.Name
+	return nil
+}
+
+// worktreeDirty reports whether a tracked file differs between HEAD, the
+// staging area and the working tree. Untracked files do not count.
+func (r *Repo) worktreeDirty() (bool, error) {
+	entries, err := r.Status()
+	if err != nil {
+		return false, err
+	}
+	for _, e := range entries {
+		if e.IndexStatus != StatusClean && e.IndexStatus != StatusUntracked {
+			return true, nil
+		}
+		if e.WorkStatus != StatusClean && e.WorkStatus != StatusUntracked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescribeNearestTagDistanceAndDirty(t *testing.T) {
+	r := initRepoWithFile(t, "main.go", []byte("package main\n"))
+	first, err := r.Commit("first", "test-author")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := r.CreateTag("v0.1", first, false); err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+
+	d, err := r.Describe("", DescribeOptions{})
+	if !errors.Is(err, ErrNoDescription) {
+		t.Fatalf("Describe with only a lightweight tag = %+v, %v; want ErrNoDescription", d, err)
+	}
+	if d, err = r.Describe("", DescribeOptions{Tags: true}); err != nil {
+		t.Fatalf("Describe --tags: %v", err)
+	}
+	if got := d.Format(false, ""); got != "v0.1" {
+		t.Fatalf("Describe --tags = %q, want v0.1", got)
+	}
+
+	if _, err := r.CreateAnnotatedTag("v1.0", first, "test-author", "release", false); err != nil {
+		t.Fatalf("CreateAnnotatedTag: %v", err)
+	}
+	commitFile(t, r, "main.go", []byte("package main\n\nfunc a() {}\n"), "second")
+	head := commitFile(t, r, "main.go", []byte("package main\n\nfunc a() {}\n\nfunc b() {}\n"), "third")
+
+	if d, err = r.Describe("", DescribeOptions{Dirty: true}); err != nil {
+		t.Fatalf("Describe --dirty: %v", err)
+	}
+	if d.Tag != "v1.0" || d.Distance != 2 || d.Commit != head || d.Dirty {
+		t.Fatalf("Describe = %+v, want v1.0 two commits back and clean", d)
+	}
+	if got, want := d.Format(false, ""), "v1.0-2-g"+shortHash(head); got != want {
+		t.Fatalf("Format = %q, want %q", got, want)
+	}
+	if d, err = r.Describe("v1.0", DescribeOptions{}); err != nil {
+		t.Fatalf("Describe v1.0: %v", err)
+	}
+	if got, want := d.Format(true, ""), "v1.0-0-g"+shortHash(first); got != want {
+		t.Fatalf("Format long = %q, want %q", got, want)
+	}
+
+	// Untracked files do not make the tree dirty; changed tracked files do.
+	if err := os.WriteFile(filepath.Join(r.RootDir, "notes.txt"), []byte("scratch\n"), 0o644); err != nil {
+		t.Fatalf("write notes.txt: %v", err)
+	}
+	if d, err = r.Describe("", DescribeOptions{Dirty: true}); err != nil || d.Dirty {
+		t.Fatalf("Describe --dirty with an untracked file = %+v, %v; want clean", d, err)
+	}
+	if err := os.WriteFile(filepath.Join(r.RootDir, "main.go"), []byte("package changed\n"), 0o644); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+	if d, err = r.Describe("", DescribeOptions{Dirty: true}); err != nil || !d.Dirty {
+		t.Fatalf("Describe --dirty with a modified file = %+v, %v; want dirty", d, err)
+	}
+	if got, want := d.Format(false, "+wip"), "v1.0-2-g"+shortHash(head)+"+wip"; got != want {
+		t.Fatalf("Format with mark = %q, want %q", got, want)
+	}
+
+	if _, err := r.Describe("HEAD", DescribeOptions{Dirty: true}); err == nil {
+		t.Fatal("Describe with a revision and Dirty succeeded")
+	}
+}
+
+func TestDescribeAlwaysFallsBackToHash(t *testing.T) {
+	r := initRepoWithFile(t, "main.go", []byte("package main\n"))
+	head, err := r.Commit("first", "test-author")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	d, err := r.Describe("", DescribeOptions{Always: true})
+	if err != nil {
+		t.Fatalf("Describe --always: %v", err)
+	}
+	if got := d.Format(false, ""); got != shortHash(head) {
+		t.Fatalf("Describe --always = %q, want %q", got, shortHash(head))
+	}
+}
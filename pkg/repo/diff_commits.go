@@ -3,6 +3,7 @@ package repo
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/odvcencio/graft/pkg/object"
 )
@@ -26,24 +27,33 @@ type CommitDiffReport struct {
 // DiffCommits compares two commits and returns the set of file-level and
 // entity-level changes between them.
 func (r *Repo) DiffCommits(oldCommit, newCommit object.Hash) (*CommitDiffReport, error) {
-	// Read old commit tree.
 	oldCommitObj, err := r.Store.ReadCommit(oldCommit)
 	if err != nil {
 		return nil, fmt.Errorf("DiffCommits: read old commit %s: %w", oldCommit, err)
 	}
-	oldEntries, err := r.FlattenTree(oldCommitObj.TreeHash)
+	newCommitObj, err := r.Store.ReadCommit(newCommit)
 	if err != nil {
-		return nil, fmt.Errorf("DiffCommits: flatten old tree: %w", err)
+		return nil, fmt.Errorf("DiffCommits: read new commit %s: %w", newCommit, err)
 	}
+	report, err := r.DiffTrees(oldCommitObj.TreeHash, newCommitObj.TreeHash)
+	if err != nil {
+		return nil, fmt.Errorf("DiffCommits: %w", err)
+	}
+	report.OldCommit = oldCommit
+	report.NewCommit = newCommit
+	return report, nil
+}
 
-	// Read new commit tree.
-	newCommitObj, err := r.Store.ReadCommit(newCommit)
+// DiffTrees compares two trees and returns the set of file-level and
+// entity-level changes between them. The report's commit fields are empty.
+func (r *Repo) DiffTrees(oldTree, newTree object.Hash) (*CommitDiffReport, error) {
+	oldEntries, err := r.FlattenTree(oldTree)
 	if err != nil {
-		return nil, fmt.Errorf("DiffCommits: read new commit %s: %w", newCommit, err)
+		return nil, fmt.Errorf("flatten old tree: %w", err)
 	}
-	newEntries, err := r.FlattenTree(newCommitObj.TreeHash)
+	newEntries, err := r.FlattenTree(newTree)
 	if err != nil {
-		return nil, fmt.Errorf("DiffCommits: flatten new tree: %w", err)
+		return nil, fmt.Errorf("flatten new tree: %w", err)
 	}
 
 	// Build path maps.
@@ -103,7 +113,7 @@ func (r *Repo) DiffCommits(oldCommit, newCommit object.Hash) (*CommitDiffReport,
 	}
 
 	// Compute entity-level changes.
-	entityChanges, err := DiffTreeEntities(r, oldCommit, newCommit)
+	entityChanges, err := diffEntryEntities(r, oldByPath, newByPath)
 	if err != nil {
 		// Entity diffing is best-effort; include file diffs even if entity
 		// diffing fails (e.g., no entity support for file types).
@@ -111,22 +121,97 @@ func (r *Repo) DiffCommits(oldCommit, newCommit object.Hash) (*CommitDiffReport,
 	}
 
 	return &CommitDiffReport{
-		OldCommit:     oldCommit,
-		NewCommit:     newCommit,
 		Files:         files,
 		EntityChanges: entityChanges,
 	}, nil
 }
 
-// DiffRefs resolves two ref names and delegates to DiffCommits.
+// DiffRefs resolves two ref names and delegates to DiffRevisions.
 func (r *Repo) DiffRefs(ref1, ref2 string) (*CommitDiffReport, error) {
-	h1, err := r.ResolveRef(ref1)
+	return r.DiffRevisions(ref1, ref2)
+}
+
+// DiffRevisions compares the trees two revisions name. A revision may be a
+// commit, an annotated tag, or a tree such as HEAD:dir; the report's commit
+// fields are set for the sides that are commits.
+func (r *Repo) DiffRevisions(oldRev, newRev string) (*CommitDiffReport, error) {
+	oldTree, oldCommit, err := r.resolveDiffTree(oldRev)
+	if err != nil {
+		return nil, err
+	}
+	newTree, newCommit, err := r.resolveDiffTree(newRev)
+	if err != nil {
+		return nil, err
+	}
+	report, err := r.DiffTrees(oldTree, newTree)
+	if err != nil {
+		return nil, fmt.Errorf("diff %s %s: %w", oldRev, newRev, err)
+	}
+	report.OldCommit = oldCommit
+	report.NewCommit = newCommit
+	return report, nil
+}
+
+// DiffRange compares the two sides of "A..B", or for "A...B" the merge base
+// of A and B with B, which shows only the changes made on B's side. An
+// empty side means HEAD.
+func (r *Repo) DiffRange(spec string) (*CommitDiffReport, error) {
+	if left, right, ok := strings.Cut(spec, "..."); ok {
+		left, right = revOrHead(left), revOrHead(right)
+		_, a, err := r.resolveDiffTree(left)
+		if err != nil {
+			return nil, err
+		}
+		_, b, err := r.resolveDiffTree(right)
+		if err != nil {
+			return nil, err
+		}
+		if a == "" || b == "" {
+			return nil, fmt.Errorf("diff %s: both sides of ... must be commits", spec)
+		}
+		base, err := r.FindMergeBase(a, b)
+		if err != nil {
+			return nil, fmt.Errorf("diff %s: %w", spec, err)
+		}
+		if base == "" {
+			return nil, fmt.Errorf("diff %s: no merge base", spec)
+		}
+		return r.DiffCommits(base, b)
+	}
+	if left, right, ok := strings.Cut(spec, ".."); ok {
+		return r.DiffRevisions(revOrHead(left), revOrHead(right))
+	}
+	return nil, fmt.Errorf("invalid revision range %q: expected A..B or A...B", spec)
+}
+
+func revOrHead(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
+
+// resolveDiffTree resolves rev to the tree it names, peeling commits and
+// annotated tags. commit is set when rev names a commit.
+func (r *Repo) resolveDiffTree(rev string) (tree, commit object.Hash, err error) {
+	h, err := r.ResolveTreeish(rev)
+	if err != nil {
+		return "", "", fmt.Errorf("diff: resolve %q: %w", rev, err)
+	}
+	objType, _, err := r.Store.Stat(h)
+	if err != nil {
+		return "", "", fmt.Errorf("diff: %s: %w", rev, err)
+	}
+	if objType == object.TypeTree {
+		return h, "", nil
+	}
+	commit, err = r.peelToCommit(h)
 	if err != nil {
-		return nil, fmt.Errorf("DiffRefs: resolve %q: %w", ref1, err)
+		return "", "", fmt.Errorf("diff: %s: %w", rev, err)
 	}
-	h2, err := r.ResolveRef(ref2)
+	c, err := r.Store.ReadCommit(commit)
 	if err != nil {
-		return nil, fmt.Errorf("DiffRefs: resolve %q: %w", ref2, err)
+		return "", "", fmt.Errorf("diff: %s: %w", rev, err)
 	}
-	return r.DiffCommits(h1, h2)
+	return c.TreeHash, commit, nil
 }
@@ -288,3 +288,47 @@ func TestDiffRefs_BadRef(t *testing.T) {
 		t.Fatal("expected error for bad ref, got nil")
 	}
 }
+
+func TestDiffRange_ThreeDotsUsesMergeBase(t *testing.T) {
+	r, _ := setupMergeRepo(t)
+	commitFile(t, r, "main.txt", []byte("main\n"), "main work")
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	commitFile(t, r, "dir/feature.txt", []byte("feature\n"), "feature work")
+
+	paths := func(report *CommitDiffReport) []string {
+		var out []string
+		for _, f := range report.Files {
+			out = append(out, f.Status+" "+f.Path)
+		}
+		return out
+	}
+
+	twoDots, err := r.DiffRange("main..feature")
+	if err != nil {
+		t.Fatalf("DiffRange(main..feature): %v", err)
+	}
+	if got := paths(twoDots); len(got) != 2 || got[0] != "added dir/feature.txt" || got[1] != "deleted main.txt" {
+		t.Fatalf("main..feature = %v", got)
+	}
+
+	threeDots, err := r.DiffRange("main...")
+	if err != nil {
+		t.Fatalf("DiffRange(main...): %v", err)
+	}
+	if got := paths(threeDots); len(got) != 1 || got[0] != "added dir/feature.txt" {
+		t.Fatalf("main... = %v, want only the feature change", got)
+	}
+
+	trees, err := r.DiffRevisions("main:", "feature:dir")
+	if err != nil {
+		t.Fatalf("DiffRevisions(trees): %v", err)
+	}
+	if trees.OldCommit != "" || trees.NewCommit != "" {
+		t.Fatalf("tree diff reported commits %s, %s", trees.OldCommit, trees.NewCommit)
+	}
+	if _, err := r.DiffRange("main"); err == nil {
+		t.Fatal("expected an error for a spec without ..")
+	}
+}
@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// StagedEntityChange describes one entity that differs between the HEAD
+// tree and the staging area.
+type StagedEntityChange struct {
+	Path        string
+	EntityKey   string
+	DisplayName string // e.g. "func (*Repo) Commit"
+	ChangeType  string // "added", "modified", "removed"
+}
+
+// DiffStagedEntities reports the entities the next commit would add, modify
+// or remove, sorted by path and entity key. It compares the entity lists
+// recorded in the HEAD tree with the EntityListHash stored in each staging
+// entry rather than reparsing file content, so files whose entity list is
+// unchanged are never read. Files without entity lists (binary files,
+// unsupported languages, unresolved conflicts) are not reported, nor are
+// the comments and whitespace between declarations.
+func (r *Repo) DiffStagedEntities() ([]StagedEntityChange, error) {
+	stg, err := r.ReadStaging()
+	if err != nil {
+		return nil, fmt.Errorf("diff staged entities: %w", err)
+	}
+
+	headLists := make(map[string]object.Hash)
+	if headHash, err := r.ResolveRef("HEAD"); err == nil {
+		commit, err := r.Store.ReadCommit(headHash)
+		if err != nil {
+			return nil, fmt.Errorf("diff staged entities: read HEAD commit: %w", err)
+		}
+		files, err := r.FlattenTree(commit.TreeHash)
+		if err != nil {
+			return nil, fmt.Errorf("diff staged entities: flatten HEAD tree: %w", err)
+		}
+		for _, f := range files {
+			if f.EntityListHash != "" {
+				headLists[f.Path] = f.EntityListHash
+			}
+		}
+	}
+
+	stagedLists := make(map[string]object.Hash)
+	for path, se := range stg.Entries {
+		if se.EntityListHash != "" && !se.Conflict {
+			stagedLists[path] = se.EntityListHash
+		}
+	}
+
+	paths := make(map[string]struct{}, len(headLists)+len(stagedLists))
+	for p := range headLists {
+		paths[p] = struct{}{}
+	}
+	for p := range stagedLists {
+		paths[p] = struct{}{}
+	}
+
+	var changes []StagedEntityChange
+	for path := range paths {
+		oldList, newList := headLists[path], stagedLists[path]
+		if oldList == newList {
+			continue
+		}
+		oldEntities, err := r.readStagedDiffEntities(oldList)
+		if err != nil {
+			return nil, fmt.Errorf("diff staged entities: read HEAD entities for %s: %w", path, err)
+		}
+		newEntities, err := r.readStagedDiffEntities(newList)
+		if err != nil {
+			return nil, fmt.Errorf("diff staged entities: read staged entities for %s: %w", path, err)
+		}
+
+		for key, after := range newEntities {
+			before, ok := oldEntities[key]
+			switch {
+			case !ok:
+				changes = append(changes, StagedEntityChange{Path: path, EntityKey: key, DisplayName: after.name, ChangeType: "added"})
+			case before.hash != after.hash:
+				changes = append(changes, StagedEntityChange{Path: path, EntityKey: key, DisplayName: after.name, ChangeType: "modified"})
+			}
+		}
+		for key, before := range oldEntities {
+			if _, ok := newEntities[key]; !ok {
+				changes = append(changes, StagedEntityChange{Path: path, EntityKey: key, DisplayName: before.name, ChangeType: "removed"})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].EntityKey < changes[j].EntityKey
+	})
+	return changes, nil
+}
+
+// readStagedDiffEntities reads an entity list into a map keyed by an
+// identity that survives edits to an entity's body: the declaration kind,
+// receiver and name for declarations, and the kind plus position among
+// entities of that kind otherwise. Interstitial entities are skipped. An
+// empty hash yields an empty map.
+func (r *Repo) readStagedDiffEntities(listHash object.Hash) (map[string]entityDetail, error) {
+	result := make(map[string]entityDetail)
+	if listHash == "" {
+		return result, nil
+	}
+	el, err := r.Store.ReadEntityList(listHash)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]int)
+	for _, ref := range el.EntityRefs {
+		ent, err := r.Store.ReadEntity(ref)
+		if err != nil {
+			return nil, fmt.Errorf("read entity %s: %w", ref, err)
+		}
+		var key, name string
+		switch ent.Kind {
+		case entity.KindInterstitial.String():
+			continue
+		case entity.KindDeclaration.String():
+			key = "decl:" + ent.DeclKind + ":" + ent.Receiver + ":" + ent.Name
+			name = entity.EntityDisplayName(&entity.Entity{
+				Kind:     entity.KindDeclaration,
+				Name:     ent.Name,
+				DeclKind: ent.DeclKind,
+				Receiver: ent.Receiver,
+			})
+		default:
+			key = ent.Kind
+			name = ent.Kind
+		}
+		// Repeated identities (overloads, several import blocks) are told
+		// apart by their order of appearance.
+		n := seen[key]
+		seen[key]++
+		if n > 0 {
+			key += ":" + strconv.Itoa(n)
+			name += " #" + strconv.Itoa(n+1)
+		}
+		result[key] = entityDetail{hash: ref, name: name}
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffStagedEntities_AddedModifiedRemoved(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitFile(t, r, "main.go", []byte("package main\n\nfunc Keep() {}\n\nfunc Edit() int { return 1 }\n\nfunc Drop() {}\n"), "initial")
+	commitFile(t, r, "old.go", []byte("package main\n\nfunc Old() {}\n"), "add old")
+
+	writeFile(t, filepath.Join(r.RootDir, "main.go"), []byte("package main\n\nfunc Keep() {}\n\nfunc Edit() int { return 2 }\n\nfunc New() {}\n"))
+	// Unstaged edits are not part of the staged diff.
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	writeFile(t, filepath.Join(r.RootDir, "main.go"), []byte("package main\n\nfunc Unstaged() {}\n"))
+	if err := r.Remove([]string{"old.go"}, false); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	changes, err := r.DiffStagedEntities()
+	if err != nil {
+		t.Fatalf("DiffStagedEntities: %v", err)
+	}
+	got := make(map[string]string)
+	for _, c := range changes {
+		got[c.Path+" "+c.DisplayName] = c.ChangeType
+	}
+	want := map[string]string{
+		"main.go func Edit": "modified",
+		"main.go func New":  "added",
+		"main.go func Drop": "removed",
+		"old.go func Old":   "removed",
+		"old.go preamble":   "removed",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q (all changes: %v)", k, got[k], v, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// EncryptionKeyEnv names the environment variable that supplies the object
+// store encryption key as hex, overriding the configured key file.
+const EncryptionKeyEnv = "GRAFT_ENCRYPTION_KEY"
+
+// LoadEncryptionKey returns the object store encryption key from
+// $GRAFT_ENCRYPTION_KEY when set, and otherwise from keyFile.
+func LoadEncryptionKey(keyFile string) ([]byte, error) {
+	if v := strings.TrimSpace(os.Getenv(EncryptionKeyEnv)); v != "" {
+		key, err := decodeEncryptionKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EncryptionKeyEnv, err)
+		}
+		return key, nil
+	}
+	if keyFile == "" {
+		return nil, fmt.Errorf("encryption key: no key file configured and %s is unset", EncryptionKeyEnv)
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key: %w", err)
+	}
+	key, err := decodeEncryptionKey(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %s: %w", keyFile, err)
+	}
+	return key, nil
+}
+
+func decodeEncryptionKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex key: %w", err)
+	}
+	if len(key) != object.EncryptionKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", object.EncryptionKeySize, len(key))
+	}
+	return key, nil
+}
+
+// EncryptionSetup reports the outcome of EnableEncryption.
+type EncryptionSetup struct {
+	KeyFile      string
+	GeneratedKey bool // a new key file was created
+	Rewritten    int  // existing object files converted to ciphertext
+}
+
+// EnableEncryption turns on at-rest encryption of the object store. The key
+// is read from keyFile, which is created with a fresh random key when it does
+// not exist yet, unless $GRAFT_ENCRYPTION_KEY supplies one. The key file path
+// is recorded in the repository config and every existing loose object and
+// pack is encrypted in place.
+func (r *Repo) EnableEncryption(keyFile string) (*EncryptionSetup, error) {
+	if keyFile == "" {
+		return nil, errors.New("enable encryption: key file path is required")
+	}
+	abs, err := filepath.Abs(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("enable encryption: %w", err)
+	}
+	if rel, err := filepath.Rel(r.RootDir, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("enable encryption: key file %s must live outside the repository", keyFile)
+	}
+
+	setup := &EncryptionSetup{KeyFile: abs}
+	if os.Getenv(EncryptionKeyEnv) == "" {
+		if _, err := os.Stat(abs); errors.Is(err, os.ErrNotExist) {
+			if err := writeNewEncryptionKey(abs); err != nil {
+				return nil, fmt.Errorf("enable encryption: %w", err)
+			}
+			setup.GeneratedKey = true
+		}
+	}
+	key, err := LoadEncryptionKey(abs)
+	if err != nil {
+		return nil, fmt.Errorf("enable encryption: %w", err)
+	}
+	if err := r.Store.SetEncryptionKey(key); err != nil {
+		return nil, fmt.Errorf("enable encryption: %w", err)
+	}
+
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("enable encryption: %w", err)
+	}
+	if cfg.Storage == nil {
+		cfg.Storage = &StorageConfig{}
+	}
+	cfg.Storage.Encrypt = true
+	cfg.Storage.EncryptionKeyFile = abs
+	if err := r.WriteConfig(cfg); err != nil {
+		return nil, fmt.Errorf("enable encryption: %w", err)
+	}
+
+	setup.Rewritten, err = r.Store.EncryptExisting()
+	if err != nil {
+		return setup, fmt.Errorf("enable encryption: %w", err)
+	}
+	return setup, nil
+}
+
+func writeNewEncryptionKey(path string) error {
+	key := make([]byte, object.EncryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create key dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("create key file: %w", err)
+	}
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("write key file: %w", err)
+	}
+	return f.Close()
+}
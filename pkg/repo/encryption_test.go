@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/remote"
+)
+
+func TestEnableEncryptionGeneratesKeyAndReopens(t *testing.T) {
+	t.Setenv(EncryptionKeyEnv, "")
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := r.Store.Write(object.TypeBlob, []byte("written before encryption"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if _, err := r.EnableEncryption(filepath.Join(dir, "repo.key")); err == nil {
+		t.Fatal("EnableEncryption accepted a key file inside the repository")
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "keys", "repo.key")
+	setup, err := r.EnableEncryption(keyFile)
+	if err != nil {
+		t.Fatalf("EnableEncryption: %v", err)
+	}
+	if !setup.GeneratedKey || setup.Rewritten != 1 {
+		t.Fatalf("setup = %+v, want generated key and 1 rewritten file", setup)
+	}
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatalf("stat key file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("key file mode = %o, want 600", perm)
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !reopened.Store.EncryptionEnabled() {
+		t.Fatal("reopened store does not encrypt")
+	}
+	after, err := reopened.Store.Write(object.TypeBlob, []byte("written after encryption"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	for _, h := range []object.Hash{before, after} {
+		if _, _, err := reopened.Store.Read(h); err != nil {
+			t.Fatalf("Read(%s): %v", h, err)
+		}
+	}
+
+	// Without the key, reads of ciphertext and all writes fail.
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	locked, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open without key: %v", err)
+	}
+	if _, _, err := locked.Store.Read(after); !errors.Is(err, object.ErrEncryptionKeyRequired) {
+		t.Fatalf("Read without key error = %v", err)
+	}
+	if _, err := locked.Store.Write(object.TypeBlob, []byte("must not be plaintext")); err == nil || !strings.Contains(err.Error(), "encryption key") {
+		t.Fatalf("Write without key error = %v", err)
+	}
+}
+
+func TestLoadEncryptionKeyPrefersEnvironment(t *testing.T) {
+	t.Setenv(EncryptionKeyEnv, strings.Repeat("ab", object.EncryptionKeySize))
+	key, err := LoadEncryptionKey("")
+	if err != nil {
+		t.Fatalf("LoadEncryptionKey: %v", err)
+	}
+	if len(key) != object.EncryptionKeySize || key[0] != 0xab {
+		t.Fatalf("key = %x", key)
+	}
+
+	t.Setenv(EncryptionKeyEnv, "abcd")
+	if _, err := LoadEncryptionKey(""); err == nil {
+		t.Fatal("LoadEncryptionKey accepted a short key")
+	}
+}
+
+func TestLocalPushHonoursTargetEncryption(t *testing.T) {
+	t.Setenv(EncryptionKeyEnv, "")
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "repo.key")
+	if _, err := r.EnableEncryption(keyFile); err != nil {
+		t.Fatalf("EnableEncryption: %v", err)
+	}
+
+	push := func(content string) (object.Hash, error) {
+		client, err := remote.NewClient(dir)
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		data := []byte(content)
+		h := object.HashObject(object.TypeBlob, data)
+		return h, client.PushObjects(context.Background(), []remote.ObjectRecord{{Hash: h, Type: object.TypeBlob, Data: data}})
+	}
+
+	h, err := push("pushed secret contents")
+	if err != nil {
+		t.Fatalf("PushObjects: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, ".graft", "objects", string(h[:2]), string(h[2:])))
+	if err != nil {
+		t.Fatalf("read object file: %v", err)
+	}
+	if strings.Contains(string(raw), "pushed secret contents") {
+		t.Fatal("pushed object was stored in plaintext")
+	}
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, data, err := reopened.Store.Read(h); err != nil || string(data) != "pushed secret contents" {
+		t.Fatalf("Read = %q, %v", data, err)
+	}
+
+	// Without the key the push is refused rather than stored in plaintext.
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := push("must not be plaintext"); err == nil || !strings.Contains(err.Error(), "encryption key") {
+		t.Fatalf("PushObjects without key error = %v", err)
+	}
+}
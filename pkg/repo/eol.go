@@ -0,0 +1,169 @@
+package repo
+
+import (
+	"bytes"
+	"strings"
+)
+
+// lineEndings converts line endings between the working tree and the
+// object store. Text files are stored with LF endings and written out with
+// CRLF where core.autocrlf or the eol attribute asks for it. The text and
+// eol attributes from .graftattributes decide per path:
+//
+//	text        always normalize
+//	-text       never convert (also implied by binary)
+//	text=auto   normalize unless the content looks binary
+//	eol=lf      normalize, check out with LF
+//	eol=crlf    normalize, check out with CRLF
+//
+// Paths without either attribute follow core.autocrlf: "true" normalizes
+// and checks out with CRLF, "input" only normalizes, and anything else
+// leaves files alone.
+//
+// The filter attribute names a content filter from the config whose clean
+// command runs before line endings are normalized and whose smudge command
+// runs after CRLF endings are written; see FilterConfig. A nil
+// *lineEndings converts nothing.
+type lineEndings struct {
+	attrs    *Attributes
+	autoCRLF string
+	filters  map[string]*FilterConfig
+	root     string // working directory for filter commands
+}
+
+// eolAction is the conversion chosen for one path.
+type eolAction struct {
+	normalize bool // CRLF -> LF when staging
+	crlf      bool // LF -> CRLF when writing the working tree
+	auto      bool // skip content that looks binary
+}
+
+// lineEndings loads the line-ending settings for an operation on the
+// working tree as it is, reading .graftattributes from disk.
+func (r *Repo) lineEndings() *lineEndings {
+	attrs, err := r.ReadAttributes()
+	if err != nil {
+		attrs = nil
+	}
+	return r.newLineEndings(attrs)
+}
+
+// lineEndingsForTree loads the line-ending settings for writing out files,
+// a flattened tree, taking attributes from the tree's own .graftattributes
+// rather than from a working tree that is about to be replaced.
+func (r *Repo) lineEndingsForTree(files []TreeFileEntry) *lineEndings {
+	var attrs *Attributes
+	for _, f := range files {
+		if f.Path != ".graftattributes" {
+			continue
+		}
+		if data, err := r.readBlobData(f.BlobHash); err == nil {
+			attrs = ParseAttributes(string(data))
+		}
+		break
+	}
+	return r.newLineEndings(attrs)
+}
+
+// newLineEndings combines core.autocrlf and the configured filters with
+// attrs. It returns nil when none of them asks for any conversion.
+func (r *Repo) newLineEndings(attrs *Attributes) *lineEndings {
+	le := &lineEndings{root: r.RootDir}
+	if cfg, err := r.ReadConfig(); err == nil {
+		if cfg.Core != nil {
+			le.autoCRLF = strings.ToLower(cfg.Core.AutoCRLF)
+		}
+		le.filters = cfg.Filters
+	}
+	if attrs != nil {
+		for _, rule := range attrs.Rules {
+			_, text := rule.Attrs["text"]
+			_, eol := rule.Attrs["eol"]
+			_, filter := rule.Attrs["filter"]
+			if text || eol || (filter && len(le.filters) > 0) {
+				le.attrs = attrs
+				break
+			}
+		}
+	}
+	if le.attrs == nil && le.autoCRLF != "true" && le.autoCRLF != "input" {
+		return nil
+	}
+	return le
+}
+
+// rewrites reports whether stored content for path may differ from the
+// working-tree file in more than line endings, or in line endings, so a
+// size change alone does not prove the file was edited.
+func (le *lineEndings) rewrites(path string) bool {
+	if le.action(path).normalize {
+		return true
+	}
+	_, f := le.filterFor(path)
+	return f != nil
+}
+
+func (le *lineEndings) action(path string) eolAction {
+	if le == nil {
+		return eolAction{}
+	}
+	var text, eol string
+	if le.attrs != nil {
+		m := le.attrs.Match(path)
+		text, eol = m["text"], m["eol"]
+	}
+	switch {
+	case text == "false":
+		return eolAction{}
+	case text == "" && eol == "":
+		switch le.autoCRLF {
+		case "true":
+			return eolAction{normalize: true, crlf: true, auto: true}
+		case "input":
+			return eolAction{normalize: true, auto: true}
+		}
+		return eolAction{}
+	}
+	crlf := eol == "crlf" || (eol == "" && le.autoCRLF == "true")
+	return eolAction{normalize: true, crlf: crlf, auto: text == "auto"}
+}
+
+// clean returns data as it should be stored for path: passed through its
+// clean filter, and with CRLF endings turned into LF for text files.
+// Symlink targets are never converted.
+func (le *lineEndings) clean(path string, data []byte, mode string) []byte {
+	if le == nil || isSymlinkMode(mode) {
+		return data
+	}
+	data = le.applyFilter(path, data, false)
+	a := le.action(path)
+	if !a.normalize || !bytes.Contains(data, []byte("\r\n")) {
+		return data
+	}
+	if a.auto && isBinaryContent(data) {
+		return data
+	}
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+// smudge returns stored data as it should be written to the working tree
+// for path: with LF endings turned into CRLF where CRLF is configured, and
+// then passed through its smudge filter. Content that already has CRLF
+// endings is left as stored.
+func (le *lineEndings) smudge(path string, data []byte, mode string) []byte {
+	if le == nil || isSymlinkMode(mode) {
+		return data
+	}
+	return le.applyFilter(path, le.toCRLF(path, data), true)
+}
+
+func (le *lineEndings) toCRLF(path string, data []byte) []byte {
+	a := le.action(path)
+	if !a.crlf || !bytes.Contains(data, []byte("\n")) || bytes.Contains(data, []byte("\r\n")) {
+		return data
+	}
+	if a.auto && isBinaryContent(data) {
+		return data
+	}
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
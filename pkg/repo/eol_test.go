@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func setCoreAutoCRLF(t *testing.T, r *Repo, value string) {
+	t.Helper()
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if cfg.Core == nil {
+		cfg.Core = &CoreConfig{}
+	}
+	cfg.Core.AutoCRLF = value
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+}
+
+func TestLineEndings_CleanAndSmudge(t *testing.T) {
+	attrs := ParseAttributes("*.sh eol=lf\n*.bat eol=crlf\n*.dat -text\n*.png binary\n*.txt text=auto\n")
+	tests := []struct {
+		autoCRLF string
+		path     string
+		in       string
+		clean    string
+		smudge   string
+	}{
+		{"", "run.sh", "a\r\nb\r\n", "a\nb\n", "a\r\nb\r\n"},
+		{"true", "run.sh", "a\nb\n", "a\nb\n", "a\nb\n"},
+		{"", "run.bat", "a\nb\n", "a\nb\n", "a\r\nb\r\n"},
+		{"true", "x.dat", "a\r\n", "a\r\n", "a\r\n"},
+		{"true", "x.png", "a\r\n", "a\r\n", "a\r\n"},
+		{"", "notes.txt", "a\r\n", "a\n", "a\r\n"},
+		{"true", "notes.txt", "a\n", "a\n", "a\r\n"},
+		{"", "notes.txt", "a\r\n\x00", "a\r\n\x00", "a\r\n\x00"},
+		{"", "main.go", "a\r\n", "a\r\n", "a\r\n"},
+		{"input", "main.go", "a\r\n", "a\n", "a\r\n"},
+		{"true", "main.go", "a\n", "a\n", "a\r\n"},
+		{"true", "main.go", "a\r\nb\n", "a\nb\n", "a\r\nb\n"},
+	}
+	for _, tt := range tests {
+		le := &lineEndings{attrs: attrs, autoCRLF: tt.autoCRLF}
+		if got := string(le.clean(tt.path, []byte(tt.in), object.TreeModeFile)); got != tt.clean {
+			t.Errorf("autocrlf=%q clean(%s, %q) = %q, want %q", tt.autoCRLF, tt.path, tt.in, got, tt.clean)
+		}
+		if got := string(le.smudge(tt.path, []byte(tt.in), object.TreeModeFile)); got != tt.smudge {
+			t.Errorf("autocrlf=%q smudge(%s, %q) = %q, want %q", tt.autoCRLF, tt.path, tt.in, got, tt.smudge)
+		}
+	}
+
+	le := &lineEndings{autoCRLF: "true"}
+	if got := string(le.smudge("link", []byte("target\n"), object.TreeModeSymlink)); got != "target\n" {
+		t.Fatalf("symlink target converted to %q", got)
+	}
+	var none *lineEndings
+	if got := string(none.clean("a.txt", []byte("a\r\n"), object.TreeModeFile)); got != "a\r\n" {
+		t.Fatalf("nil lineEndings converted to %q", got)
+	}
+}
+
+func TestAutoCRLF_AddStoresLFAndCheckoutWritesCRLF(t *testing.T) {
+	r, dir := setupMergeRepo(t)
+	setCoreAutoCRLF(t, r, "true")
+
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "notes.txt"), []byte("one\r\ntwo\r\n"))
+	if err := r.Add([]string{"notes.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if got := stg.Entries["notes.txt"].BlobHash; got != object.HashObject(object.TypeBlob, []byte("one\ntwo\n")) {
+		t.Fatal("notes.txt was not stored with LF endings")
+	}
+	if _, err := r.Commit("add notes", "test-author"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	if err != nil || string(data) != "one\r\ntwo\r\n" {
+		t.Fatalf("notes.txt = %q, %v; want CRLF endings", data, err)
+	}
+	if got := statusByPath(t, r); got["notes.txt"].WorkStatus != StatusClean {
+		t.Fatalf("notes.txt WorkStatus = %d after checkout, want clean", got["notes.txt"].WorkStatus)
+	}
+
+	// Switching a file to LF endings is not a change either.
+	writeFile(t, filepath.Join(dir, "notes.txt"), []byte("one\ntwo\n"))
+	if got := statusByPath(t, r); got["notes.txt"].WorkStatus != StatusClean {
+		t.Fatalf("notes.txt WorkStatus = %d with LF endings, want clean", got["notes.txt"].WorkStatus)
+	}
+	writeFile(t, filepath.Join(dir, "notes.txt"), []byte("one\r\nthree\r\n"))
+	if got := statusByPath(t, r); got["notes.txt"].WorkStatus != StatusDirty {
+		t.Fatalf("notes.txt WorkStatus = %d after an edit, want dirty", got["notes.txt"].WorkStatus)
+	}
+}
+
+func TestEOLAttribute_CheckoutUsesTargetAttributes(t *testing.T) {
+	r, dir := setupMergeRepo(t)
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	writeFile(t, filepath.Join(dir, ".graftattributes"), []byte("*.bat eol=crlf\n"))
+	writeFile(t, filepath.Join(dir, "build.bat"), []byte("echo one\r\necho two\r\n"))
+	if err := r.Add([]string{".graftattributes", "build.bat"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("add script", "test-author"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got, err := r.ReadWorktreeFile("build.bat"); err != nil || string(got) != "echo one\necho two\n" {
+		t.Fatalf("ReadWorktreeFile = %q, %v; want normalized content", got, err)
+	}
+
+	// main has no .graftattributes, so checking out feature must take the
+	// eol attribute from the tree being checked out.
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "build.bat"))
+	if err != nil || string(data) != "echo one\r\necho two\r\n" {
+		t.Fatalf("build.bat = %q, %v; want CRLF endings", data, err)
+	}
+}
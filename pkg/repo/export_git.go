@@ -0,0 +1,385 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// ExportGitOptions configures ExportGit.
+type ExportGitOptions struct {
+	// Force allows ref updates that are not fast-forwards, and moving
+	// existing tags.
+	Force bool
+}
+
+// ExportGitResult summarizes an ExportGit call. The hashes of its ref
+// updates are git hashes.
+type ExportGitResult struct {
+	Commits      int
+	Blobs        int
+	Tags         int // annotated tag objects
+	UpdatedRefs  []RefUpdate
+	RejectedRefs []RefUpdate // updates refused without ExportGitOptions.Force
+	SkippedRefs  []string    // tags of trees or blobs
+	// Created reports that path held no repository and a bare one was
+	// created.
+	Created bool
+}
+
+// ExportGit writes the branches and tags of the repository into the git
+// repository at path, the reverse of ImportGit. Objects are written
+// directly as loose git objects, so git need not be installed. When path
+// does not exist or is an empty directory a bare repository is created
+// there, ready to push to any git host, with HEAD naming the current
+// branch.
+//
+// Merges, authors, committers, dates, messages, executable bits and
+// symlinks carry over, and annotated tags stay annotated. Entity lists have
+// no git counterpart and module entries are left out. Commits already in the
+// target, found through the map ImportGit and PushToGit share, are not
+// written again.
+//
+// Refs are updated as by ImportGit: non-fast-forward updates are rejected
+// unless opts.Force is set. A work tree checked out in the target is not
+// touched.
+func (r *Repo) ExportGit(ctx context.Context, path string, opts ExportGitOptions) (*ExportGitResult, error) {
+	res := &ExportGitResult{}
+	if entries, err := os.ReadDir(path); errors.Is(err, os.ErrNotExist) || (err == nil && len(entries) == 0) {
+		if err := initBareGitDir(path); err != nil {
+			return nil, fmt.Errorf("export git: %w", err)
+		}
+		res.Created = true
+	}
+	db, err := openGitObjectDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("export git: %w", err)
+	}
+	defer db.Close()
+	if err := os.MkdirAll(r.gitInteropDir(), 0o755); err != nil {
+		return nil, fmt.Errorf("export git: %w", err)
+	}
+	hm, err := openGitInteropMap(filepath.Join(r.gitInteropDir(), gitInteropMapName))
+	if err != nil {
+		return nil, fmt.Errorf("export git: %w", err)
+	}
+	defer hm.Close()
+
+	e := &gitExporter{
+		r:       r,
+		db:      db,
+		hashMap: hm,
+		gitOf:   make(map[object.Hash]string),
+		graftOf: make(map[string]object.Hash),
+		trees:   make(map[object.Hash]string),
+		res:     res,
+	}
+	if err := e.run(ctx, opts.Force); err != nil {
+		return nil, fmt.Errorf("export git: %w", err)
+	}
+	return res, nil
+}
+
+type gitExporter struct {
+	r       *Repo
+	db      *gitObjectDB
+	hashMap *gitInteropMap
+	gitOf   map[object.Hash]string // graft commit, blob or tag -> git object in the target
+	graftOf map[string]object.Hash // git commit -> graft commit, for fast-forward checks
+	trees   map[object.Hash]string // graft tree -> git tree, "" when empty
+	res     *ExportGitResult
+}
+
+func (e *gitExporter) run(ctx context.Context, force bool) error {
+	var names []string
+	values := make(map[string]object.Hash)
+	for _, prefix := range []string{"heads", "tags"} {
+		refs, err := e.r.ListRefs(prefix)
+		if err != nil {
+			return err
+		}
+		for name, h := range refs {
+			names = append(names, "refs/"+name)
+			values["refs/"+name] = h
+		}
+	}
+	sort.Strings(names)
+
+	type exportRef struct {
+		name   string
+		commit object.Hash
+	}
+	var refs []exportRef
+	var pending []object.Hash
+	queued := make(map[object.Hash]bool)
+	for _, name := range names {
+		commit, err := e.r.peelToCommit(values[name])
+		if err != nil {
+			e.res.SkippedRefs = append(e.res.SkippedRefs, name)
+			continue
+		}
+		commits, err := e.r.commitsParentsFirst(commit, func(h object.Hash) bool {
+			return queued[h] || e.known(h)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, h := range commits {
+			queued[h] = true
+		}
+		pending = append(pending, commits...)
+		refs = append(refs, exportRef{name: name, commit: commit})
+	}
+	for i, h := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := e.exportCommit(h); err != nil {
+			return fmt.Errorf("commit %s: %w", h, err)
+		}
+		e.r.reportProgress(ProgressExporting, i+1, len(pending))
+	}
+
+	tips := make(map[string]string, len(refs))
+	commits := make(map[string]object.Hash, len(refs))
+	for _, ref := range refs {
+		tips[ref.name] = e.gitOf[ref.commit]
+		commits[ref.name] = ref.commit
+		if h := values[ref.name]; h != ref.commit && strings.HasPrefix(ref.name, "refs/tags/") {
+			tag, err := e.exportTag(strings.TrimPrefix(ref.name, "refs/tags/"), h, tips[ref.name])
+			if err != nil {
+				return fmt.Errorf("%s: %w", ref.name, err)
+			}
+			tips[ref.name] = tag
+		}
+	}
+
+	if err := e.updateRefs(tips, commits, force); err != nil {
+		return err
+	}
+	if e.res.Created {
+		if head, err := e.r.Head(); err == nil && tips[head] != "" {
+			return e.db.setHead(head)
+		}
+	}
+	return nil
+}
+
+// known reports whether a graft commit already has a counterpart in the
+// target, recording it when the map names one the target holds.
+func (e *gitExporter) known(h object.Hash) bool {
+	if _, ok := e.gitOf[h]; ok {
+		return true
+	}
+	gitHash, ok := e.hashMap.GraftToGit(h)
+	if !ok || !e.db.has(gitHash) {
+		return false
+	}
+	e.gitOf[h] = gitHash
+	e.graftOf[gitHash] = h
+	return true
+}
+
+// record notes that graft object h was written as gitHash, adding the pair
+// to the shared map unless h is already mapped.
+func (e *gitExporter) record(h object.Hash, gitHash string) error {
+	e.gitOf[h] = gitHash
+	if _, ok := e.hashMap.GraftToGit(h); ok {
+		return nil
+	}
+	return e.hashMap.Put(h, gitHash)
+}
+
+func (e *gitExporter) exportCommit(h object.Hash) error {
+	commit, err := e.r.Store.ReadCommit(h)
+	if err != nil {
+		return err
+	}
+	tree, err := e.exportTree(commit.TreeHash, true)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "tree %s\n", tree)
+	for _, parent := range commit.Parents {
+		gitParent, ok := e.gitOf[parent]
+		if !ok {
+			return fmt.Errorf("parent %s has not been exported", parent)
+		}
+		fmt.Fprintf(&buf, "parent %s\n", gitParent)
+	}
+	committer, committerTS, committerTZ := commit.Committer, commit.CommitterTimestamp, commit.CommitterTimezone
+	if committer == "" {
+		committer, committerTS, committerTZ = commit.Author, commit.Timestamp, commit.AuthorTimezone
+	}
+	fmt.Fprintf(&buf, "author %s\n", gitIdent(commit.Author, commit.Timestamp, commit.AuthorTimezone))
+	fmt.Fprintf(&buf, "committer %s\n\n%s", gitIdent(committer, committerTS, committerTZ), commit.Message)
+
+	gitHash, err := e.db.write(object.TypeCommit, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	e.res.Commits++
+	e.graftOf[gitHash] = h
+	return e.record(h, gitHash)
+}
+
+// exportTree writes a graft tree as a git tree and returns its hash, or ""
+// for a subtree left empty by dropped module entries. The root tree is
+// written even when empty.
+func (e *gitExporter) exportTree(h object.Hash, root bool) (string, error) {
+	if gitHash, ok := e.trees[h]; ok && (gitHash != "" || !root) {
+		return gitHash, nil
+	}
+	tree, err := e.r.Store.ReadTree(h)
+	if err != nil {
+		return "", err
+	}
+	type gitEntry struct {
+		mode, name, hash string
+		isDir            bool
+	}
+	entries := make([]gitEntry, 0, len(tree.Entries))
+	for _, te := range tree.Entries {
+		switch {
+		case te.IsDir:
+			sub, err := e.exportTree(te.SubtreeHash, false)
+			if err != nil {
+				return "", err
+			}
+			if sub != "" {
+				entries = append(entries, gitEntry{mode: object.TreeModeDir, name: te.Name, hash: sub, isDir: true})
+			}
+		case te.Mode == object.TreeModeModule:
+			// Module entries point at graft commits, which have no git
+			// counterpart to reference as a gitlink.
+		default:
+			blob, err := e.exportBlob(te.BlobHash)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", te.Name, err)
+			}
+			entries = append(entries, gitEntry{mode: normalizeFileMode(te.Mode), name: te.Name, hash: blob})
+		}
+	}
+	if len(entries) == 0 && !root {
+		e.trees[h] = ""
+		return "", nil
+	}
+
+	// Git orders a directory as if its name ended in "/".
+	sortName := func(en gitEntry) string {
+		if en.isDir {
+			return en.name + "/"
+		}
+		return en.name
+	}
+	sort.Slice(entries, func(i, j int) bool { return sortName(entries[i]) < sortName(entries[j]) })
+	gitEntries := make([]object.GitTreeEntry, len(entries))
+	for i, en := range entries {
+		raw, err := hex.DecodeString(en.hash)
+		if err != nil {
+			return "", err
+		}
+		gitEntries[i] = object.GitTreeEntry{Mode: en.mode, Name: en.name, Hash: raw}
+	}
+	gitHash, err := e.db.write(object.TypeTree, object.MarshalGitTree(gitEntries))
+	if err != nil {
+		return "", err
+	}
+	e.trees[h] = gitHash
+	return gitHash, nil
+}
+
+func (e *gitExporter) exportBlob(h object.Hash) (string, error) {
+	if gitHash, ok := e.gitOf[h]; ok {
+		return gitHash, nil
+	}
+	if gitHash, ok := e.hashMap.GraftToGit(h); ok && e.db.has(gitHash) {
+		e.gitOf[h] = gitHash
+		return gitHash, nil
+	}
+	blob, err := e.r.Store.ReadBlob(h)
+	if err != nil {
+		return "", err
+	}
+	existed := e.db.has(gitBlobHash(blob.Data))
+	gitHash, err := e.db.write(object.TypeBlob, blob.Data)
+	if err != nil {
+		return "", err
+	}
+	if !existed {
+		e.res.Blobs++
+	}
+	return gitHash, e.record(h, gitHash)
+}
+
+// exportTag writes an annotated graft tag as a git tag of gitCommit, the
+// commit it peels to.
+func (e *gitExporter) exportTag(name string, h object.Hash, gitCommit string) (string, error) {
+	if gitHash, ok := e.hashMap.GraftToGit(h); ok && e.db.has(gitHash) {
+		return gitHash, nil
+	}
+	tag, err := e.r.Store.ReadTag(h)
+	if err != nil {
+		return "", err
+	}
+	tagger, ts, message := parseTagData(tag.Data)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "object %s\ntype commit\ntag %s\ntagger %s\n\n%s\n", gitCommit, name, gitIdent(tagger, ts, ""), message)
+	gitHash, err := e.db.write(object.TypeTag, buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	e.res.Tags++
+	return gitHash, e.record(h, gitHash)
+}
+
+// updateRefs points the target's refs at the exported tips. A branch moves
+// only when its current git commit is an ancestor of the new one, which is
+// checked on the graft side, so branches holding commits unknown to this
+// repository need Force.
+func (e *gitExporter) updateRefs(tips map[string]string, commits map[string]object.Hash, force bool) error {
+	existing, err := e.db.refs()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(tips))
+	for name := range tips {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		old, tip := existing[name], tips[name]
+		if old == tip {
+			continue
+		}
+		update := RefUpdate{Name: name, OldHash: object.Hash(old), NewHash: object.Hash(tip)}
+		if old != "" && !force && (strings.HasPrefix(name, "refs/tags/") || !e.fastForward(old, commits[name])) {
+			e.res.RejectedRefs = append(e.res.RejectedRefs, update)
+			continue
+		}
+		if err := e.db.updateRef(name, tip, old); err != nil {
+			return err
+		}
+		e.res.UpdatedRefs = append(e.res.UpdatedRefs, update)
+	}
+	return nil
+}
+
+func (e *gitExporter) fastForward(oldGit string, newCommit object.Hash) bool {
+	old, ok := e.graftOf[oldGit]
+	if !ok {
+		if old, ok = e.hashMap.GitToGraft(oldGit); !ok {
+			return false
+		}
+	}
+	return old == newCommit || e.r.isFastForward(old, newCommit)
+}
@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestExportGitMergesTagsAndModes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+
+	r := initRepoWithFile(t, "main.go", []byte("package main\n\nfunc main() {}\n"))
+	first, err := r.Commit("first", "Alice <alice@example.com>")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	shadowWriteFile(t, r.RootDir, "bin/run.sh", "#!/bin/sh\necho hi\n")
+	if err := os.Chmod(filepath.Join(r.RootDir, "bin/run.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add([]string{"bin/run.sh"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	second, err := r.Commit("add script", "Alice <alice@example.com>")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	// A side commit and a merge of it, written directly.
+	side, err := r.Store.WriteCommit(&object.CommitObj{
+		TreeHash: mustReadCommit(t, r, first).TreeHash, Parents: []object.Hash{first},
+		Author: "Bob <bob@example.com>", Timestamp: 1700000000, AuthorTimezone: "+0100", Message: "side\n",
+	})
+	if err != nil {
+		t.Fatalf("WriteCommit: %v", err)
+	}
+	merge, err := r.Store.WriteCommit(&object.CommitObj{
+		TreeHash: mustReadCommit(t, r, second).TreeHash, Parents: []object.Hash{second, side},
+		Author: "Alice <alice@example.com>", Timestamp: 1700000100, AuthorTimezone: "+0000", Message: "merge side\n",
+	})
+	if err != nil {
+		t.Fatalf("WriteCommit: %v", err)
+	}
+	if err := r.UpdateRef("refs/heads/main", merge); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+	if _, err := r.CreateAnnotatedTag("v1.0", first, "Alice <alice@example.com>", "first release", false); err != nil {
+		t.Fatalf("CreateAnnotatedTag: %v", err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "published.git")
+	res, err := r.ExportGit(ctx, dst, ExportGitOptions{})
+	if err != nil {
+		t.Fatalf("ExportGit: %v", err)
+	}
+	if !res.Created || res.Commits != 4 || res.Blobs != 2 || res.Tags != 1 || len(res.UpdatedRefs) != 2 {
+		t.Fatalf("ExportGit = %+v, want a new repository with 4 commits, 2 blobs, 1 tag and 2 refs", res)
+	}
+	gitOutput(t, dst, "fsck", "--strict", "--no-progress")
+	if got := gitOutput(t, dst, "symbolic-ref", "HEAD"); got != "refs/heads/main" {
+		t.Fatalf("HEAD = %q", got)
+	}
+	if parents := strings.Fields(gitOutput(t, dst, "rev-list", "--parents", "-1", "main")); len(parents) != 3 {
+		t.Fatalf("main parents = %v, want a merge", parents)
+	}
+	if got := gitOutput(t, dst, "log", "-1", "--format=%an <%ae> %ad|%s", "--date=raw", "main^2"); got != "Bob <bob@example.com> 1700000000 +0100|side" {
+		t.Fatalf("side commit = %q", got)
+	}
+	if got := gitOutput(t, dst, "ls-tree", "main", "bin/run.sh"); !strings.HasPrefix(got, "100755 blob ") {
+		t.Fatalf("run.sh entry = %q, want executable", got)
+	}
+	if got := gitOutput(t, dst, "cat-file", "-t", "v1.0"); got != "tag" {
+		t.Fatalf("v1.0 is a %s, want an annotated tag", got)
+	}
+	if got := gitOutput(t, dst, "log", "-1", "--format=%s", "v1.0^{commit}"); got != "first" {
+		t.Fatalf("v1.0 points at %q", got)
+	}
+
+	// Exporting again writes nothing.
+	again, err := r.ExportGit(ctx, dst, ExportGitOptions{})
+	if err != nil || again.Commits != 0 || again.Blobs != 0 || len(again.UpdatedRefs) != 0 {
+		t.Fatalf("second export = %+v, %v", again, err)
+	}
+
+	// A rewritten branch is only exported with Force.
+	if err := r.UpdateRef("refs/heads/main", side); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+	rewritten, err := r.ExportGit(ctx, dst, ExportGitOptions{})
+	if err != nil || len(rewritten.RejectedRefs) != 1 {
+		t.Fatalf("rewritten export = %+v, %v; want main rejected", rewritten, err)
+	}
+	if _, err := r.ExportGit(ctx, dst, ExportGitOptions{Force: true}); err != nil {
+		t.Fatalf("forced export: %v", err)
+	}
+	if got := gitOutput(t, dst, "log", "-1", "--format=%s", "main"); got != "side" {
+		t.Fatalf("main after forced export = %q", got)
+	}
+}
@@ -0,0 +1,351 @@
+package repo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// Fast-export and fast-import speak git's fast-import stream format, so
+// graft history can be moved through the same migration tooling git uses
+// (git fast-import, reposurgeon, hg-fast-export, ...). Blobs and commits are
+// named by marks within a stream; a marks file records which object each
+// mark stood for so a later stream can build on an earlier one. Entity lists
+// have no place in the format and are regenerated on import.
+
+// FastExportOptions configures FastExport.
+type FastExportOptions struct {
+	// Refs lists the refs to export, as full names (refs/heads/main) or as
+	// branch or tag names. Empty exports every branch and tag.
+	Refs []string
+	// Marks holds the marks of objects a previous export already wrote.
+	// Those objects are referenced by mark instead of being written again,
+	// and the marks this export assigns are added to the map.
+	Marks map[int]object.Hash
+}
+
+// FastExport writes the history reachable from the selected refs to w as a
+// fast-import stream. Module entries are left out, as graft commits have no
+// git hash to record as a gitlink, and commit signatures are dropped.
+func (r *Repo) FastExport(w io.Writer, opts FastExportOptions) error {
+	refs, err := r.fastExportRefs(opts.Refs)
+	if err != nil {
+		return fmt.Errorf("fast-export: %w", err)
+	}
+	marks := opts.Marks
+	if marks == nil {
+		marks = make(map[int]object.Hash)
+	}
+	e := &fastExporter{r: r, bw: bufio.NewWriter(w), marks: marks, markOf: make(map[object.Hash]int)}
+	for mark, h := range marks {
+		e.markOf[h] = mark
+		e.nextMark = max(e.nextMark, mark)
+	}
+
+	for _, ref := range refs {
+		commit, err := r.peelToCommit(ref.hash)
+		if err != nil {
+			return fmt.Errorf("fast-export: %s: %w", ref.name, err)
+		}
+		ref.commit = commit
+		pending, err := r.commitsParentsFirst(commit, func(h object.Hash) bool {
+			_, ok := e.markOf[h]
+			return ok
+		})
+		if err != nil {
+			return fmt.Errorf("fast-export: %w", err)
+		}
+		for _, h := range pending {
+			if err := e.writeCommit(ref.name, h); err != nil {
+				return fmt.Errorf("fast-export: commit %s: %w", h, err)
+			}
+		}
+	}
+	for _, ref := range refs {
+		if err := e.writeRef(ref); err != nil {
+			return fmt.Errorf("fast-export: %s: %w", ref.name, err)
+		}
+	}
+	return e.bw.Flush()
+}
+
+type fastExportRef struct {
+	name   string
+	hash   object.Hash // ref value, possibly a tag object
+	commit object.Hash // commit the ref peels to
+}
+
+// fastExportRefs resolves the refs to export, sorted by name.
+func (r *Repo) fastExportRefs(names []string) ([]*fastExportRef, error) {
+	var refs []*fastExportRef
+	if len(names) == 0 {
+		for _, prefix := range []string{"heads", "tags"} {
+			all, err := r.ListRefs(prefix)
+			if err != nil {
+				return nil, err
+			}
+			for name, h := range all {
+				refs = append(refs, &fastExportRef{name: "refs/" + name, hash: h})
+			}
+		}
+	}
+	for _, name := range names {
+		candidates := []string{name}
+		if !strings.HasPrefix(name, "refs/") {
+			candidates = []string{"refs/heads/" + name, "refs/tags/" + name}
+		}
+		found := false
+		for _, full := range candidates {
+			h, err := r.ResolveRef(full)
+			if err != nil {
+				continue
+			}
+			refs = append(refs, &fastExportRef{name: full, hash: h})
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("ref %q: %w", name, ErrRefNotFound)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].name < refs[j].name })
+	return refs, nil
+}
+
+type fastExporter struct {
+	r        *Repo
+	bw       *bufio.Writer
+	marks    map[int]object.Hash
+	markOf   map[object.Hash]int
+	nextMark int
+	// gitHash, when set, names objects the importing git repository
+	// already holds; they are referenced by hash instead of being written.
+	gitHash func(object.Hash) (string, bool)
+}
+
+func (e *fastExporter) mark(h object.Hash) int {
+	e.nextMark++
+	e.marks[e.nextMark] = h
+	e.markOf[h] = e.nextMark
+	return e.nextMark
+}
+
+// dataRef returns how the stream refers to an object already written or
+// already held by the importer, and false for an object it has to write.
+func (e *fastExporter) dataRef(h object.Hash) (string, bool) {
+	if mark, ok := e.markOf[h]; ok {
+		return ":" + strconv.Itoa(mark), true
+	}
+	if e.gitHash != nil {
+		return e.gitHash(h)
+	}
+	return "", false
+}
+
+// writeCommit writes the blobs a commit introduces and then the commit,
+// with its tree given as changes against its first parent.
+func (e *fastExporter) writeCommit(ref string, h object.Hash) error {
+	commit, err := e.r.Store.ReadCommit(h)
+	if err != nil {
+		return err
+	}
+	files, err := e.r.FlattenTree(commit.TreeHash)
+	if err != nil {
+		return err
+	}
+	var parentFiles map[string]TreeFileEntry
+	if len(commit.Parents) > 0 {
+		parent, err := e.r.Store.ReadCommit(commit.Parents[0])
+		if err != nil {
+			return err
+		}
+		parentFiles, err = e.r.flattenTreeMap(parent.TreeHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	var changed []TreeFileEntry
+	current := make(map[string]bool, len(files))
+	for _, f := range files {
+		current[f.Path] = true
+		if old, ok := parentFiles[f.Path]; ok && old.BlobHash == f.BlobHash && old.Mode == f.Mode {
+			continue
+		}
+		changed = append(changed, f)
+		if _, ok := e.dataRef(f.BlobHash); ok {
+			continue
+		}
+		blob, err := e.r.Store.ReadBlob(f.BlobHash)
+		if err != nil {
+			return fmt.Errorf("read blob %s: %w", f.BlobHash, err)
+		}
+		fmt.Fprintf(e.bw, "blob\nmark :%d\n", e.mark(f.BlobHash))
+		writeFastData(e.bw, blob.Data)
+	}
+	var deleted []string
+	for p := range parentFiles {
+		if !current[p] {
+			deleted = append(deleted, p)
+		}
+	}
+	sort.Strings(deleted)
+
+	if len(commit.Parents) == 0 {
+		fmt.Fprintf(e.bw, "reset %s\n", ref)
+	}
+	fmt.Fprintf(e.bw, "commit %s\nmark :%d\n", ref, e.mark(h))
+	committer, committerTS, committerTZ := commit.Committer, commit.CommitterTimestamp, commit.CommitterTimezone
+	if committer == "" {
+		committer, committerTS, committerTZ = commit.Author, commit.Timestamp, commit.AuthorTimezone
+	}
+	fmt.Fprintf(e.bw, "author %s\n", gitIdent(commit.Author, commit.Timestamp, commit.AuthorTimezone))
+	fmt.Fprintf(e.bw, "committer %s\n", gitIdent(committer, committerTS, committerTZ))
+	writeFastData(e.bw, []byte(commit.Message))
+	for i, parent := range commit.Parents {
+		verb := "merge"
+		if i == 0 {
+			verb = "from"
+		}
+		ref, _ := e.dataRef(parent)
+		fmt.Fprintf(e.bw, "%s %s\n", verb, ref)
+	}
+	for _, p := range deleted {
+		fmt.Fprintf(e.bw, "D %s\n", fastImportPath(p))
+	}
+	for _, f := range changed {
+		ref, _ := e.dataRef(f.BlobHash)
+		fmt.Fprintf(e.bw, "M %s %s %s\n", normalizeFileMode(f.Mode), ref, fastImportPath(f.Path))
+	}
+	e.bw.WriteByte('\n')
+	return nil
+}
+
+// writeRef points a ref at its exported commit. Annotated tags are written
+// as tag commands; everything else as a reset.
+func (e *fastExporter) writeRef(ref *fastExportRef) error {
+	mark := e.markOf[ref.commit]
+	if name, ok := strings.CutPrefix(ref.name, "refs/tags/"); ok && ref.hash != ref.commit {
+		tag, err := e.r.Store.ReadTag(ref.hash)
+		if err != nil {
+			return err
+		}
+		tagger, ts, message := parseTagData(tag.Data)
+		fmt.Fprintf(e.bw, "tag %s\nfrom :%d\n", name, mark)
+		fmt.Fprintf(e.bw, "tagger %s\n", gitIdent(tagger, ts, ""))
+		writeFastData(e.bw, []byte(message+"\n"))
+		return nil
+	}
+	fmt.Fprintf(e.bw, "reset %s\nfrom :%d\n\n", ref.name, mark)
+	return nil
+}
+
+// writeFastData writes a data command with its payload and trailing LF.
+func writeFastData(bw *bufio.Writer, data []byte) {
+	fmt.Fprintf(bw, "data %d\n", len(data))
+	bw.Write(data)
+	bw.WriteByte('\n')
+}
+
+// flattenTreeMap flattens a tree into a map keyed by path.
+func (r *Repo) flattenTreeMap(h object.Hash) (map[string]TreeFileEntry, error) {
+	files, err := r.FlattenTree(h)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]TreeFileEntry, len(files))
+	for _, f := range files {
+		m[f.Path] = f
+	}
+	return m, nil
+}
+
+// commitsParentsFirst lists the commits reachable from tip, stopping at
+// commits known reports true for, with parents before their children.
+func (r *Repo) commitsParentsFirst(tip object.Hash, known func(object.Hash) bool) ([]object.Hash, error) {
+	type frame struct {
+		hash     object.Hash
+		expanded bool
+	}
+	var order []object.Hash
+	visited := make(map[object.Hash]bool)
+	stack := []frame{{hash: tip}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if top.expanded {
+			order = append(order, top.hash)
+			continue
+		}
+		if visited[top.hash] {
+			continue
+		}
+		visited[top.hash] = true
+		if known(top.hash) {
+			continue
+		}
+		commit, err := r.Store.ReadCommit(top.hash)
+		if err != nil {
+			return nil, fmt.Errorf("read commit %s (shallow history cannot be exported): %w", top.hash, err)
+		}
+		stack = append(stack, frame{hash: top.hash, expanded: true})
+		for i := len(commit.Parents) - 1; i >= 0; i-- {
+			if !visited[commit.Parents[i]] {
+				stack = append(stack, frame{hash: commit.Parents[i]})
+			}
+		}
+	}
+	return order, nil
+}
+
+// ReadFastMarks reads a marks file of ":<mark> <hash>" lines. A missing
+// file yields an empty map.
+func ReadFastMarks(path string) (map[int]object.Hash, error) {
+	marks := make(map[int]object.Hash)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return marks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read marks: %w", err)
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		markStr, hashStr, ok := strings.Cut(line, " ")
+		mark, err := strconv.Atoi(strings.TrimPrefix(markStr, ":"))
+		if !ok || !strings.HasPrefix(markStr, ":") || err != nil || mark <= 0 {
+			return nil, fmt.Errorf("read marks: %s:%d: malformed line %q", path, i+1, line)
+		}
+		if err := object.ValidateHash(hashStr); err != nil {
+			return nil, fmt.Errorf("read marks: %s:%d: %w", path, i+1, err)
+		}
+		marks[mark] = object.Hash(hashStr)
+	}
+	return marks, nil
+}
+
+// WriteFastMarks writes marks to path in mark order, replacing the file.
+func WriteFastMarks(path string, marks map[int]object.Hash) error {
+	nums := make([]int, 0, len(marks))
+	for mark := range marks {
+		nums = append(nums, mark)
+	}
+	sort.Ints(nums)
+	var b strings.Builder
+	for _, mark := range nums {
+		fmt.Fprintf(&b, ":%d %s\n", mark, marks[mark])
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("write marks: %w", err)
+	}
+	return nil
+}
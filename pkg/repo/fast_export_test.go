@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestFastExportImportRoundTrip(t *testing.T) {
+	src := initRepoWithFile(t, "main.go", []byte("package main\n\nfunc A() {}\n"))
+	first, err := src.Commit("first", "Test User <test@example.com>")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	commitFile(t, src, "pkg/util.go", []byte("package pkg\n\nfunc B() {}\n"), "second")
+	if _, err := src.CreateAnnotatedTag("v1.0", first, "Test User <test@example.com>", "release", false); err != nil {
+		t.Fatalf("CreateAnnotatedTag: %v", err)
+	}
+
+	var stream bytes.Buffer
+	marks := make(map[int]object.Hash)
+	if err := src.FastExport(&stream, FastExportOptions{Marks: marks}); err != nil {
+		t.Fatalf("FastExport: %v", err)
+	}
+	for _, want := range []string{"commit refs/heads/main\n", "M 100644 :", " pkg/util.go\n", "tag v1.0\n"} {
+		if !strings.Contains(stream.String(), want) {
+			t.Fatalf("stream lacks %q:\n%s", want, stream.String())
+		}
+	}
+
+	dst, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	res, err := dst.FastImport(bytes.NewReader(stream.Bytes()), FastImportOptions{})
+	if err != nil {
+		t.Fatalf("FastImport: %v", err)
+	}
+	if res.Commits != 2 || res.Tags != 1 || len(res.UpdatedRefs) != 2 {
+		t.Fatalf("FastImport = %+v, want 2 commits, 1 tag and 2 refs", res)
+	}
+	tip, err := dst.ResolveRef("refs/heads/main")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	files, err := dst.FlattenTree(mustReadCommit(t, dst, tip).TreeHash)
+	if err != nil {
+		t.Fatalf("FlattenTree: %v", err)
+	}
+	if len(files) != 2 || files[1].Path != "pkg/util.go" || files[1].EntityListHash == "" {
+		t.Fatalf("imported files = %+v, want main.go and pkg/util.go with entity lists", files)
+	}
+
+	// The imported history exports to the same stream.
+	var again bytes.Buffer
+	if err := dst.FastExport(&again, FastExportOptions{}); err != nil {
+		t.Fatalf("FastExport imported: %v", err)
+	}
+	if again.String() != stream.String() {
+		t.Fatalf("round trip changed the stream:\n%s\nwant:\n%s", again.String(), stream.String())
+	}
+
+	// With the marks of the first export, only new history is written.
+	commitFile(t, src, "main.go", []byte("package main\n\nfunc A() { println() }\n"), "third")
+	var incremental bytes.Buffer
+	if err := src.FastExport(&incremental, FastExportOptions{Refs: []string{"main"}, Marks: marks}); err != nil {
+		t.Fatalf("FastExport incremental: %v", err)
+	}
+	if !strings.HasPrefix(incremental.String(), "blob\n") || strings.Count(incremental.String(), "mark :") != 2 {
+		t.Fatalf("incremental stream should hold one blob and one commit:\n%s", incremental.String())
+	}
+}
+
+func TestFastImportFileCommandsAndRefSafety(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	stream := `feature done
+blob
+mark :1
+data 4
+one
+
+commit refs/heads/main
+mark :2
+author A U Thor <author@example.com> 1700000000 +0100
+committer C O Mitter <committer@example.com> 1700000100 -0500
+data <<EOT
+add files
+EOT
+M 644 :1 dir/a.txt
+M 100755 inline "dir/run me.sh"
+data 10
+#!/bin/sh
+
+commit refs/heads/main
+committer C O Mitter <committer@example.com> 1700000200 -0500
+data 5
+moves
+R dir moved
+D "moved/run me.sh"
+
+progress imported
+done
+`
+	var progress bytes.Buffer
+	res, err := r.FastImport(strings.NewReader(stream), FastImportOptions{Progress: &progress})
+	if err != nil {
+		t.Fatalf("FastImport: %v", err)
+	}
+	if res.Commits != 2 || res.Blobs != 2 || progress.String() != "progress imported\n" {
+		t.Fatalf("FastImport = %+v, progress %q", res, progress.String())
+	}
+	tip, err := r.ResolveRef("refs/heads/main")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	commit := mustReadCommit(t, r, tip)
+	if len(commit.Parents) != 1 || commit.Author != "C O Mitter <committer@example.com>" || commit.Message != "moves" {
+		t.Fatalf("tip commit = %+v", commit)
+	}
+	parent := mustReadCommit(t, r, commit.Parents[0])
+	if parent.Author != "A U Thor <author@example.com>" || parent.AuthorTimezone != "+0100" || parent.Message != "add files\n" {
+		t.Fatalf("first commit = %+v", parent)
+	}
+	files, err := r.FlattenTree(commit.TreeHash)
+	if err != nil {
+		t.Fatalf("FlattenTree: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "moved/a.txt" {
+		t.Fatalf("tip files = %+v, want moved/a.txt", files)
+	}
+
+	// A stream rewriting main from scratch is not a fast-forward.
+	rewrite := "commit refs/heads/main\ncommitter X <x@example.com> 1700000300 +0000\ndata 7\nrewrite\ndeleteall\n\nreset refs/heads/main\nfrom :2\n\n"
+	if _, err := r.FastImport(strings.NewReader(rewrite), FastImportOptions{}); err == nil {
+		t.Fatal("expected mark :2 to be undefined in a new stream")
+	}
+	rewrite = strings.Replace(rewrite, "reset refs/heads/main\nfrom :2\n\n", "", 1)
+	res, err = r.FastImport(strings.NewReader("reset refs/heads/main\n\n"+rewrite), FastImportOptions{})
+	if err != nil {
+		t.Fatalf("FastImport rewrite: %v", err)
+	}
+	if len(res.RejectedRefs) != 1 || len(res.UpdatedRefs) != 0 {
+		t.Fatalf("FastImport rewrite = %+v, want main rejected", res)
+	}
+	if h, _ := r.ResolveRef("refs/heads/main"); h != tip {
+		t.Fatalf("main moved to %s after a rejected update", h)
+	}
+	res, err = r.FastImport(strings.NewReader("reset refs/heads/main\n\n"+rewrite), FastImportOptions{Force: true})
+	if err != nil || len(res.UpdatedRefs) != 1 {
+		t.Fatalf("FastImport --force = %+v, %v", res, err)
+	}
+}
+
+func mustReadCommit(t *testing.T, r *Repo, h object.Hash) *object.CommitObj {
+	t.Helper()
+	c, err := r.Store.ReadCommit(h)
+	if err != nil {
+		t.Fatalf("ReadCommit %s: %v", h, err)
+	}
+	return c
+}
@@ -0,0 +1,704 @@
+package repo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// FastImportOptions configures FastImport.
+type FastImportOptions struct {
+	// Marks holds the marks of an earlier stream the input may refer to.
+	// The marks the input defines are added to the map.
+	Marks map[int]object.Hash
+	// Force allows ref updates that are not fast-forwards, and moving
+	// existing tags.
+	Force bool
+	// Progress receives the messages of the stream's progress commands.
+	Progress io.Writer
+}
+
+// FastImportResult summarizes a FastImport call.
+type FastImportResult struct {
+	Blobs        int
+	Commits      int
+	Tags         int
+	UpdatedRefs  []RefUpdate
+	RejectedRefs []RefUpdate // updates refused without FastImportOptions.Force
+}
+
+// FastImport reads a fast-import stream, as written by git fast-export or
+// FastExport, stores the blobs, commits and annotated tags it describes and
+// updates the refs it names. Entity lists are extracted for every file the
+// stream adds or changes. Gitlink entries are skipped, and notes, cat-blob
+// and ls commands are not supported.
+//
+// Refs are updated once the whole stream has been read. An update that is
+// not a fast-forward is reported in RejectedRefs and left undone unless
+// Force is set. The working tree is not touched, even when the checked-out
+// branch moves.
+func (r *Repo) FastImport(rd io.Reader, opts FastImportOptions) (*FastImportResult, error) {
+	marks := opts.Marks
+	if marks == nil {
+		marks = make(map[int]object.Hash)
+	}
+	im := &fastImporter{
+		r:           r,
+		br:          bufio.NewReader(rd),
+		marks:       marks,
+		tips:        make(map[string]object.Hash),
+		entityCache: make(map[string]object.Hash),
+		progress:    opts.Progress,
+		force:       opts.Force,
+		res:         &FastImportResult{},
+	}
+	if err := im.run(); err != nil {
+		if im.lineNum > 0 {
+			return nil, fmt.Errorf("fast-import: line %d: %w", im.lineNum, err)
+		}
+		return nil, fmt.Errorf("fast-import: %w", err)
+	}
+	if err := im.updateRefs(); err != nil {
+		return nil, fmt.Errorf("fast-import: %w", err)
+	}
+	return im.res, nil
+}
+
+type fastImporter struct {
+	r           *Repo
+	br          *bufio.Reader
+	lineNum     int
+	unread      *string
+	marks       map[int]object.Hash
+	tips        map[string]object.Hash // in-stream branch tips; "" after a reset
+	entityCache map[string]object.Hash // path + "\x00" + blob -> entity list
+	progress    io.Writer
+	force       bool
+	requireDone bool
+	res         *FastImportResult
+
+	// Files of the most recent commit, so a run of commits on one branch
+	// does not re-read each parent's tree.
+	lastCommit object.Hash
+	lastFiles  map[string]*StagingEntry
+}
+
+// next returns the next line without its LF, or io.EOF.
+func (im *fastImporter) next() (string, error) {
+	if im.unread != nil {
+		line := *im.unread
+		im.unread = nil
+		return line, nil
+	}
+	line, err := im.br.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+	im.lineNum++
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+func (im *fastImporter) push(line string) {
+	im.unread = &line
+}
+
+func (im *fastImporter) run() error {
+	for {
+		line, err := im.next()
+		if errors.Is(err, io.EOF) {
+			if im.requireDone {
+				return errors.New("stream ended without done")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"), line == "checkpoint":
+		case line == "done":
+			return nil
+		case line == "blob":
+			err = im.blob()
+		case strings.HasPrefix(line, "commit "):
+			err = im.commit(strings.TrimPrefix(line, "commit "))
+		case strings.HasPrefix(line, "tag "):
+			err = im.tag(strings.TrimPrefix(line, "tag "))
+		case strings.HasPrefix(line, "reset "):
+			err = im.reset(strings.TrimPrefix(line, "reset "))
+		case strings.HasPrefix(line, "progress "):
+			if im.progress != nil {
+				fmt.Fprintln(im.progress, line)
+			}
+		case strings.HasPrefix(line, "feature "):
+			err = im.feature(strings.TrimPrefix(line, "feature "))
+		case strings.HasPrefix(line, "option "):
+			// Options tune the importing program and carry no history.
+		default:
+			return fmt.Errorf("unsupported command %q", line)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (im *fastImporter) feature(name string) error {
+	switch name {
+	case "date-format=raw":
+	case "done":
+		im.requireDone = true
+	case "force":
+		im.force = true
+	default:
+		return fmt.Errorf("unsupported feature %q", name)
+	}
+	return nil
+}
+
+// optional consumes the next line if it starts with prefix.
+func (im *fastImporter) optional(prefix string) (string, bool, error) {
+	line, err := im.next()
+	if errors.Is(err, io.EOF) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if rest, ok := strings.CutPrefix(line, prefix); ok {
+		return rest, true, nil
+	}
+	im.push(line)
+	return "", false, nil
+}
+
+func (im *fastImporter) optionalMark() (int, error) {
+	value, ok, err := im.optional("mark ")
+	if err != nil || !ok {
+		return 0, err
+	}
+	mark, err := strconv.Atoi(strings.TrimPrefix(value, ":"))
+	if err != nil || !strings.HasPrefix(value, ":") || mark <= 0 {
+		return 0, fmt.Errorf("invalid mark %q", value)
+	}
+	return mark, nil
+}
+
+// data reads a data command in either its counted or delimited form.
+func (im *fastImporter) data() ([]byte, error) {
+	line, err := im.next()
+	if err != nil {
+		return nil, fmt.Errorf("expected data: %w", err)
+	}
+	arg, ok := strings.CutPrefix(line, "data ")
+	if !ok {
+		return nil, fmt.Errorf("expected data, found %q", line)
+	}
+	if delim, ok := strings.CutPrefix(arg, "<<"); ok {
+		var b strings.Builder
+		for {
+			line, err := im.next()
+			if err != nil {
+				return nil, fmt.Errorf("data not terminated by %q: %w", delim, err)
+			}
+			if line == delim {
+				return []byte(b.String()), nil
+			}
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid data length %q", arg)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(im.br, buf); err != nil {
+		return nil, fmt.Errorf("read %d bytes of data: %w", n, err)
+	}
+	im.lineNum += strings.Count(string(buf), "\n")
+	// The LF after the payload is optional.
+	if b, err := im.br.Peek(1); err == nil && b[0] == '\n' {
+		im.br.ReadByte()
+		im.lineNum++
+	}
+	return buf, nil
+}
+
+func (im *fastImporter) blob() error {
+	mark, err := im.optionalMark()
+	if err != nil {
+		return err
+	}
+	if _, _, err := im.optional("original-oid "); err != nil {
+		return err
+	}
+	data, err := im.data()
+	if err != nil {
+		return err
+	}
+	h, err := im.r.Store.WriteBlob(&object.Blob{Data: data})
+	if err != nil {
+		return err
+	}
+	if mark > 0 {
+		im.marks[mark] = h
+	}
+	im.res.Blobs++
+	return nil
+}
+
+func (im *fastImporter) commit(ref string) error {
+	if !strings.HasPrefix(ref, "refs/") {
+		return fmt.Errorf("commit ref %q is not a full ref name", ref)
+	}
+	mark, err := im.optionalMark()
+	if err != nil {
+		return err
+	}
+	if _, _, err := im.optional("original-oid "); err != nil {
+		return err
+	}
+	commit := &object.CommitObj{}
+	if author, ok, err := im.optional("author "); err != nil {
+		return err
+	} else if ok {
+		commit.Author, commit.Timestamp, commit.AuthorTimezone = parseGitIdent(author)
+	}
+	committer, ok, err := im.optional("committer ")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("commit %s: missing committer", ref)
+	}
+	commit.Committer, commit.CommitterTimestamp, commit.CommitterTimezone = parseGitIdent(committer)
+	if commit.Author == "" {
+		commit.Author, commit.Timestamp, commit.AuthorTimezone = commit.Committer, commit.CommitterTimestamp, commit.CommitterTimezone
+	}
+	if _, ok, err := im.optional("gpgsig "); err != nil {
+		return err
+	} else if ok {
+		if _, err := im.data(); err != nil {
+			return err
+		}
+	}
+	if _, _, err := im.optional("encoding "); err != nil {
+		return err
+	}
+	message, err := im.data()
+	if err != nil {
+		return err
+	}
+	commit.Message = string(message)
+
+	if from, ok, err := im.optional("from "); err != nil {
+		return err
+	} else if ok {
+		parent, err := im.commitish(from)
+		if err != nil {
+			return err
+		}
+		commit.Parents = append(commit.Parents, parent)
+	} else if tip, err := im.tip(ref); err != nil {
+		return err
+	} else if tip != "" {
+		commit.Parents = append(commit.Parents, tip)
+	}
+	for {
+		merge, ok, err := im.optional("merge ")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		parent, err := im.commitish(merge)
+		if err != nil {
+			return err
+		}
+		commit.Parents = append(commit.Parents, parent)
+	}
+
+	files, err := im.parentFiles(commit.Parents)
+	if err != nil {
+		return err
+	}
+	if err := im.fileChanges(files); err != nil {
+		return fmt.Errorf("commit %s: %w", ref, err)
+	}
+	for p, entry := range files {
+		if entry.EntityListHash != "" || isSymlinkMode(entry.Mode) {
+			continue
+		}
+		key := p + "\x00" + string(entry.BlobHash)
+		entityListHash, cached := im.entityCache[key]
+		if !cached {
+			entityListHash, err = im.r.entityListForBlob(p, entry.BlobHash)
+			if err != nil {
+				return err
+			}
+			im.entityCache[key] = entityListHash
+		}
+		entry.EntityListHash = entityListHash
+	}
+	treeHash, err := im.r.buildTreeDir(&Staging{Entries: files}, "")
+	if err != nil {
+		return err
+	}
+	commit.TreeHash = treeHash
+	h, err := im.r.Store.WriteCommit(commit)
+	if err != nil {
+		return err
+	}
+	if mark > 0 {
+		im.marks[mark] = h
+	}
+	im.tips[ref] = h
+	im.lastCommit, im.lastFiles = h, files
+	im.res.Commits++
+	return nil
+}
+
+// parentFiles returns a fresh copy of the files of the first parent.
+func (im *fastImporter) parentFiles(parents []object.Hash) (map[string]*StagingEntry, error) {
+	files := make(map[string]*StagingEntry)
+	if len(parents) == 0 {
+		return files, nil
+	}
+	if parents[0] == im.lastCommit && im.lastFiles != nil {
+		for p, entry := range im.lastFiles {
+			copied := *entry
+			files[p] = &copied
+		}
+		return files, nil
+	}
+	parent, err := im.r.Store.ReadCommit(parents[0])
+	if err != nil {
+		return nil, err
+	}
+	flat, err := im.r.FlattenTree(parent.TreeHash)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flat {
+		files[f.Path] = &StagingEntry{Path: f.Path, BlobHash: f.BlobHash, EntityListHash: f.EntityListHash, Mode: f.Mode}
+	}
+	return files, nil
+}
+
+// fileChanges applies a commit's M, D, C, R and deleteall commands to files.
+// Entries a command adds or moves lose their entity list, to be extracted
+// afresh for their new content or path.
+func (im *fastImporter) fileChanges(files map[string]*StagingEntry) error {
+	for {
+		line, err := im.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "":
+			return nil
+		case line == "deleteall":
+			clear(files)
+		case strings.HasPrefix(line, "M "):
+			if err := im.modify(files, strings.TrimPrefix(line, "M ")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "D "):
+			p, _, err := fastImportParsePath(strings.TrimPrefix(line, "D "), true)
+			if err != nil {
+				return err
+			}
+			for _, match := range pathsUnder(files, p) {
+				delete(files, match)
+			}
+		case strings.HasPrefix(line, "C "), strings.HasPrefix(line, "R "):
+			src, rest, err := fastImportParsePath(line[2:], false)
+			if err != nil {
+				return err
+			}
+			dst, _, err := fastImportParsePath(rest, true)
+			if err != nil {
+				return err
+			}
+			matches := pathsUnder(files, src)
+			if len(matches) == 0 {
+				return fmt.Errorf("%s: path %q not found", line[:1], src)
+			}
+			for _, match := range matches {
+				entry := *files[match]
+				entry.Path = dst + strings.TrimPrefix(match, src)
+				entry.EntityListHash = ""
+				if line[0] == 'R' {
+					delete(files, match)
+				}
+				files[entry.Path] = &entry
+			}
+		case strings.HasPrefix(line, "N "):
+			return errors.New("notes are not supported")
+		default:
+			im.push(line)
+			return nil
+		}
+	}
+}
+
+// modify applies "M <mode> <dataref> <path>".
+func (im *fastImporter) modify(files map[string]*StagingEntry, args string) error {
+	mode, rest, ok := strings.Cut(args, " ")
+	dataRef, pathArg, ok2 := strings.Cut(rest, " ")
+	if !ok || !ok2 {
+		return fmt.Errorf("malformed M command %q", args)
+	}
+	p, _, err := fastImportParsePath(pathArg, true)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case "644", object.TreeModeFile:
+		mode = object.TreeModeFile
+	case "755", object.TreeModeExecutable:
+		mode = object.TreeModeExecutable
+	case object.TreeModeSymlink:
+	case object.TreeModeModule:
+		// Gitlinks name git commits graft cannot resolve.
+		return nil
+	default:
+		return fmt.Errorf("unsupported mode %s for %s", mode, p)
+	}
+
+	var blobHash object.Hash
+	if dataRef == "inline" {
+		data, err := im.data()
+		if err != nil {
+			return err
+		}
+		blobHash, err = im.r.Store.WriteBlob(&object.Blob{Data: data})
+		if err != nil {
+			return err
+		}
+		im.res.Blobs++
+	} else {
+		blobHash, err = im.objectRef(dataRef)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	files[p] = &StagingEntry{Path: p, BlobHash: blobHash, Mode: mode}
+	return nil
+}
+
+// objectRef resolves a mark or a full graft hash.
+func (im *fastImporter) objectRef(ref string) (object.Hash, error) {
+	if markStr, ok := strings.CutPrefix(ref, ":"); ok {
+		mark, err := strconv.Atoi(markStr)
+		if err != nil {
+			return "", fmt.Errorf("invalid mark %q", ref)
+		}
+		h, ok := im.marks[mark]
+		if !ok {
+			return "", fmt.Errorf("mark %s is not defined", ref)
+		}
+		return h, nil
+	}
+	if object.ValidateHash(ref) == nil && im.r.Store.Has(object.Hash(ref)) {
+		return object.Hash(ref), nil
+	}
+	return "", fmt.Errorf("unknown object %q", ref)
+}
+
+// commitish resolves the argument of from, merge and reset: a mark, a
+// hash, or a branch written earlier in the stream or already in the repo.
+func (im *fastImporter) commitish(ref string) (object.Hash, error) {
+	if strings.HasPrefix(ref, ":") || object.ValidateHash(ref) == nil {
+		return im.objectRef(ref)
+	}
+	name := strings.TrimSuffix(ref, "^0")
+	tip, err := im.tip(name)
+	if err != nil {
+		return "", err
+	}
+	if tip == "" {
+		return "", fmt.Errorf("unknown commit %q", ref)
+	}
+	return tip, nil
+}
+
+// tip returns the current tip of a branch: its latest value in the stream,
+// or else its value in the repository, or "" when it has neither.
+func (im *fastImporter) tip(ref string) (object.Hash, error) {
+	if h, ok := im.tips[ref]; ok {
+		return h, nil
+	}
+	h, err := im.r.ResolveRef(ref)
+	if errors.Is(err, ErrRefNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return im.r.peelToCommit(h)
+}
+
+func (im *fastImporter) reset(ref string) error {
+	if !strings.HasPrefix(ref, "refs/") {
+		return fmt.Errorf("reset ref %q is not a full ref name", ref)
+	}
+	from, ok, err := im.optional("from ")
+	if err != nil {
+		return err
+	}
+	im.tips[ref] = ""
+	if ok {
+		h, err := im.commitish(from)
+		if err != nil {
+			return err
+		}
+		im.tips[ref] = h
+	}
+	return nil
+}
+
+func (im *fastImporter) tag(name string) error {
+	if err := validateTagName(name); err != nil {
+		return err
+	}
+	mark, err := im.optionalMark()
+	if err != nil {
+		return err
+	}
+	from, ok, err := im.optional("from ")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("tag %s: missing from", name)
+	}
+	target, err := im.commitish(from)
+	if err != nil {
+		return err
+	}
+	if _, _, err := im.optional("original-oid "); err != nil {
+		return err
+	}
+	tagger, ts, tz := "unknown", int64(0), "+0000"
+	if ident, ok, err := im.optional("tagger "); err != nil {
+		return err
+	} else if ok {
+		tagger, ts, tz = parseGitIdent(ident)
+	}
+	message, err := im.data()
+	if err != nil {
+		return err
+	}
+	targetType, _, err := im.r.Store.Stat(target)
+	if err != nil {
+		return err
+	}
+	h, err := im.r.Store.WriteTag(&object.TagObj{
+		TargetHash: target,
+		Data:       formatTagData(target, targetType, name, tagger, ts, tz, strings.TrimRight(string(message), "\n")),
+	})
+	if err != nil {
+		return err
+	}
+	if mark > 0 {
+		im.marks[mark] = h
+	}
+	im.tips["refs/tags/"+name] = h
+	im.res.Tags++
+	return nil
+}
+
+// updateRefs points every ref the stream wrote at its final tip.
+func (im *fastImporter) updateRefs() error {
+	updated, rejected, err := im.r.updateImportedRefs(im.tips, im.force)
+	im.res.UpdatedRefs, im.res.RejectedRefs = updated, rejected
+	return err
+}
+
+// updateImportedRefs points refs at the tips an import produced, in name
+// order, skipping empty tips. Updates that are not fast-forwards, and moves
+// of existing tags, are returned as rejected unless force is set.
+func (r *Repo) updateImportedRefs(tips map[string]object.Hash, force bool) (updated, rejected []RefUpdate, err error) {
+	names := make([]string, 0, len(tips))
+	for name, h := range tips {
+		if h != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		update := RefUpdate{Name: name, NewHash: tips[name]}
+		old, err := r.ResolveRef(name)
+		if err != nil && !errors.Is(err, ErrRefNotFound) {
+			return updated, rejected, err
+		}
+		update.OldHash = old
+		if old == update.NewHash {
+			continue
+		}
+		if old != "" && !force && (strings.HasPrefix(name, "refs/tags/") || !r.isFastForward(old, update.NewHash)) {
+			rejected = append(rejected, update)
+			continue
+		}
+		if err := r.UpdateRefCAS(name, update.NewHash, old); err != nil {
+			return updated, rejected, err
+		}
+		updated = append(updated, update)
+	}
+	return updated, rejected, nil
+}
+
+// fastImportParsePath reads a path that is either C-quoted or, when last is
+// set, the rest of the line, or else runs to the next space.
+func fastImportParsePath(s string, last bool) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return "", "", fmt.Errorf("unterminated quoted path %q", s)
+		}
+		p, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return "", "", fmt.Errorf("invalid quoted path %s: %w", s[:end+1], err)
+		}
+		return p, strings.TrimPrefix(s[end+1:], " "), nil
+	}
+	if last {
+		return s, "", nil
+	}
+	p, rest, _ := strings.Cut(s, " ")
+	return p, rest, nil
+}
+
+// pathsUnder returns p itself if it is a file, or else the files below the
+// directory p.
+func pathsUnder(files map[string]*StagingEntry, p string) []string {
+	if _, ok := files[p]; ok {
+		return []string{p}
+	}
+	var matches []string
+	for f := range files {
+		if strings.HasPrefix(f, p+"/") {
+			matches = append(matches, f)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
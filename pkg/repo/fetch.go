@@ -4,12 +4,21 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/odvcencio/graft/pkg/object"
 	"github.com/odvcencio/graft/pkg/remote"
 )
 
+// Remote errors, re-exported so callers of fetch and push need not import
+// package remote to test for them with errors.Is.
+var (
+	ErrAuthRequired   = remote.ErrAuthRequired
+	ErrNonFastForward = remote.ErrNonFastForward
+)
+
 // RefUpdate describes how a single reference changed during a fetch.
 type RefUpdate struct {
 	Name    string      // tracking ref name, e.g. "refs/remotes/origin/heads/main"
@@ -19,10 +28,40 @@ type RefUpdate struct {
 
 // FetchResult summarizes the outcome of a Fetch operation.
 type FetchResult struct {
-	RemoteName  string
-	RemoteURL   string
-	UpdatedRefs []RefUpdate
-	ObjectCount int // number of new objects written to the store
+	RemoteName   string
+	RemoteURL    string
+	UpdatedRefs  []RefUpdate
+	PrunedRefs   []RefUpdate // tracking refs deleted by FetchOptions.Prune (NewHash is "")
+	RejectedRefs []RefUpdate // non-fast-forward updates refused by a refspec without "+"
+	ObjectCount  int         // number of new objects written to the store
+	Resumed      int         // objects already received by an interrupted earlier fetch
+	Shallow      int         // shallow boundaries remaining after the fetch
+
+	// Stats holds the transfer totals of the fetch. Fetches from local-path
+	// remotes copy objects directly and only count objects and time.
+	Stats remote.TransferStats
+}
+
+// FetchOptions controls optional Fetch behavior.
+type FetchOptions struct {
+	// Prune deletes tracking refs under refs/remotes/<remote>/ whose
+	// upstream ref no longer exists on the remote. Mirror remotes always
+	// prune.
+	Prune bool
+
+	// Depth limits the fetched history to this many commits from each tip.
+	Depth int
+	// Deepen extends the history of a shallow repository by this many
+	// commits behind its current boundaries.
+	Deepen int
+	// Unshallow fetches the complete history of a shallow repository.
+	Unshallow bool
+}
+
+// shallow reports whether a fetch with opts must negotiate shallow
+// boundaries with the remote.
+func (o FetchOptions) shallow(r *Repo) bool {
+	return o.Depth > 0 || o.Deepen > 0 || o.Unshallow || r.IsShallowRepository()
 }
 
 // Fetch downloads objects and refs from the named remote without modifying
@@ -31,18 +70,34 @@ type FetchResult struct {
 //
 // For local-path remotes the source repository is opened directly and objects
 // are copied by walking the object graph. For HTTP remotes the existing
-// remote.Client + FetchIntoStore protocol is used.
+// remote.Client + FetchIntoStore protocol is used. Shallow repositories
+// always fetch through the protocol client so their boundaries are kept.
 func (r *Repo) Fetch(remoteName string) (*FetchResult, error) {
 	return r.FetchContext(context.Background(), remoteName)
 }
 
 // FetchContext is like Fetch but accepts an explicit context.
 func (r *Repo) FetchContext(ctx context.Context, remoteName string) (*FetchResult, error) {
+	return r.FetchWithOptions(ctx, remoteName, FetchOptions{})
+}
+
+// FetchWithOptions is like FetchContext with additional options.
+func (r *Repo) FetchWithOptions(ctx context.Context, remoteName string, opts FetchOptions) (*FetchResult, error) {
 	remoteName = strings.TrimSpace(remoteName)
 	if remoteName == "" {
 		remoteName = "origin"
 	}
 
+	if opts.Depth < 0 || opts.Deepen < 0 {
+		return nil, fmt.Errorf("fetch: depth must be positive")
+	}
+	if opts.Unshallow && (opts.Depth > 0 || opts.Deepen > 0) {
+		return nil, fmt.Errorf("fetch: unshallow cannot be combined with depth or deepen")
+	}
+	if opts.Unshallow && !r.IsShallowRepository() {
+		return nil, fmt.Errorf("fetch: unshallow on a complete repository does not make sense")
+	}
+
 	remoteURL, err := r.RemoteURL(remoteName)
 	if err != nil {
 		return nil, fmt.Errorf("fetch: %w", err)
@@ -54,23 +109,74 @@ func (r *Repo) FetchContext(ctx context.Context, remoteName string) (*FetchResul
 	}
 
 	// Determine whether the remote is a local path or an HTTP endpoint.
-	if isLocalPath(remoteURL) {
-		if err := r.fetchFromLocal(ctx, remoteName, remoteURL, result); err != nil {
+	var remoteRefs map[string]object.Hash
+	if isLocalPath(remoteURL) && !opts.shallow(r) {
+		remoteRefs, err = r.fetchFromLocal(ctx, remoteName, remoteURL, result)
+	} else {
+		remoteRefs, err = r.fetchFromRemote(ctx, remoteName, remoteURL, opts, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Prune {
+		// Mirrors always drop the refs the remote no longer has.
+		if opts.Prune, err = r.IsMirrorRemote(remoteName); err != nil {
+			return nil, fmt.Errorf("fetch: %w", err)
+		}
+	}
+	if opts.Prune {
+		pruned, err := r.PruneTrackingRefs(remoteName, remoteRefs)
+		if err != nil {
 			return nil, err
 		}
-		return result, nil
+		result.PrunedRefs = pruned
 	}
+	return result, nil
+}
 
-	if err := r.fetchFromRemote(ctx, remoteName, remoteURL, result); err != nil {
-		return nil, err
+// PruneTrackingRefs deletes tracking refs of remoteName that no longer have
+// a counterpart in remoteRefs, the complete ref listing of the remote keyed
+// by remote ref name (e.g. "heads/main"). Candidates are the refs under
+// refs/remotes/<remoteName>/, or those covered by the configured fetch
+// refspecs. It returns the deleted refs with their last value in OldHash.
+func (r *Repo) PruneTrackingRefs(remoteName string, remoteRefs map[string]object.Hash) ([]RefUpdate, error) {
+	mappings, err := r.FetchRefMappings(remoteName, remoteRefs)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: prune: %w", err)
 	}
-	return result, nil
+	live := make(map[string]struct{}, len(mappings))
+	for _, m := range mappings {
+		live[m.Local] = struct{}{}
+	}
+	tracking, err := r.pruneCandidates(remoteName)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: prune: %w", err)
+	}
+	names := make([]string, 0, len(tracking))
+	for name := range tracking {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pruned []RefUpdate
+	for _, name := range names {
+		if _, ok := live[name]; ok {
+			continue
+		}
+		if err := r.DeleteRefCAS(name, tracking[name]); err != nil {
+			return pruned, fmt.Errorf("fetch: prune: %w", err)
+		}
+		pruned = append(pruned, RefUpdate{Name: name, OldHash: tracking[name]})
+	}
+	return pruned, nil
 }
 
 // isLocalPath returns true when the URL looks like a filesystem path rather
-// than an HTTP(S) endpoint.
+// than an HTTP(S) endpoint. file:// URLs go through the protocol client,
+// which serves them from the local repository as well.
 func isLocalPath(url string) bool {
-	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+	if strings.Contains(url, "://") {
 		return false
 	}
 	// Absolute or relative filesystem path.
@@ -79,118 +185,120 @@ func isLocalPath(url string) bool {
 
 // fetchFromLocal fetches from a local graft repository by opening it,
 // listing its refs, and copying the full object graph.
-func (r *Repo) fetchFromLocal(_ context.Context, remoteName, path string, result *FetchResult) error {
+func (r *Repo) fetchFromLocal(_ context.Context, remoteName, path string, result *FetchResult) (map[string]object.Hash, error) {
 	srcRepo, err := Open(path)
 	if err != nil {
-		return fmt.Errorf("fetch: open local remote %q: %w", path, err)
+		return nil, fmt.Errorf("fetch: open local remote %q: %w", path, err)
 	}
 
 	// List the source repo's refs.
 	srcRefs, err := srcRepo.ListRefs("")
 	if err != nil {
-		return fmt.Errorf("fetch: list remote refs: %w", err)
+		return nil, fmt.Errorf("fetch: list remote refs: %w", err)
 	}
 
 	if len(srcRefs) == 0 {
-		return nil
+		return srcRefs, nil
 	}
-
-	// Collect all ref tip hashes we need to fetch.
-	wants := make([]object.Hash, 0, len(srcRefs))
-	for _, h := range srcRefs {
-		if strings.TrimSpace(string(h)) != "" {
-			wants = append(wants, h)
-		}
+	mappings, err := r.FetchRefMappings(remoteName, srcRefs)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
 	}
 
 	// Copy objects by walking the graph from each want root.
+	start := time.Now()
 	written := 0
-	for _, wantHash := range wants {
+	for _, wantHash := range MappingHashes(mappings) {
 		n, err := copyObjectGraph(srcRepo.Store, r.Store, wantHash)
 		if err != nil {
-			return fmt.Errorf("fetch: copy objects: %w", err)
+			return nil, fmt.Errorf("fetch: copy objects: %w", err)
 		}
 		written += n
 	}
 	result.ObjectCount = written
+	result.Stats = remote.TransferStats{ObjectsReceived: written, Duration: time.Since(start)}
 
-	// Update tracking refs.
-	for refName, h := range srcRefs {
-		trackingRef := trackingRefName(remoteName, refName)
-		oldHash, _ := r.ResolveRef(trackingRef)
-		if oldHash == h {
-			continue // already up to date
-		}
-		if err := r.UpdateRef(trackingRef, h); err != nil {
-			return fmt.Errorf("fetch: update tracking ref %q: %w", trackingRef, err)
-		}
-		result.UpdatedRefs = append(result.UpdatedRefs, RefUpdate{
-			Name:    trackingRef,
-			OldHash: oldHash,
-			NewHash: h,
-		})
+	result.UpdatedRefs, result.RejectedRefs, err = r.UpdateFetchedRefs(mappings)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
+	return srcRefs, nil
 }
 
 // fetchFromRemote fetches from an HTTP remote using the graft protocol client.
-func (r *Repo) fetchFromRemote(ctx context.Context, remoteName, remoteURL string, result *FetchResult) error {
+// Shallow fetches send the depth options and current boundaries with each
+// batch request and record the boundaries the remote reports back.
+func (r *Repo) fetchFromRemote(ctx context.Context, remoteName, remoteURL string, opts FetchOptions, result *FetchResult) (map[string]object.Hash, error) {
 	client, err := remote.NewClient(remoteURL)
 	if err != nil {
-		return fmt.Errorf("fetch: create client: %w", err)
+		return nil, fmt.Errorf("fetch: create client: %w", err)
+	}
+	if r.progress != nil {
+		client.SetProgress(r.progress)
 	}
+	client.SetStatsHook(r.transferStats)
+	defer func() { result.Stats = client.Stats() }()
 
 	remoteRefs, err := client.ListRefs(ctx)
 	if err != nil {
-		return fmt.Errorf("fetch: list remote refs: %w", err)
+		return nil, fmt.Errorf("fetch: list remote refs: %w", err)
 	}
 
 	if len(remoteRefs) == 0 {
-		return nil
+		return remoteRefs, nil
 	}
 
-	// Collect wants from all remote refs.
-	wants := make([]object.Hash, 0, len(remoteRefs))
-	for _, h := range remoteRefs {
-		if strings.TrimSpace(string(h)) != "" {
-			wants = append(wants, h)
-		}
+	// Collect wants from the remote refs selected by the fetch refspecs.
+	mappings, err := r.FetchRefMappings(remoteName, remoteRefs)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
 	}
+	wants := MappingHashes(mappings)
 
 	// Collect local haves from all existing refs.
 	haves, err := r.localRefTips()
 	if err != nil {
-		return fmt.Errorf("fetch: collect local refs: %w", err)
+		return nil, fmt.Errorf("fetch: collect local refs: %w", err)
 	}
 
-	// Fetch objects into store.
+	// Fetch objects into store. Objects received by an earlier, interrupted
+	// fetch from this remote are offered back as haves so it resumes.
 	if len(wants) > 0 {
-		written, err := remote.FetchIntoStore(ctx, client, r.Store, wants, haves)
+		checkpoint, err := remote.OpenTransferCheckpoint(r.GraftDir, remote.CheckpointFetch, remoteURL)
 		if err != nil {
-			return fmt.Errorf("fetch: download objects: %w", err)
-		}
-		result.ObjectCount = written
-	}
-
-	// Update tracking refs.
-	for refName, h := range remoteRefs {
-		trackingRef := trackingRefName(remoteName, refName)
-		oldHash, _ := r.ResolveRef(trackingRef)
-		if oldHash == h {
-			continue
+			return nil, fmt.Errorf("fetch: %w", err)
 		}
-		if err := r.UpdateRef(trackingRef, h); err != nil {
-			return fmt.Errorf("fetch: update tracking ref %q: %w", trackingRef, err)
+		result.Resumed = checkpoint.Len()
+		cfg := remote.FetchConfig{Checkpoint: checkpoint, Progress: r.progress}
+		if !opts.shallow(r) {
+			written, err := remote.FetchIntoStoreWithConfig(ctx, client, r.Store, wants, haves, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("fetch: download objects: %w", err)
+			}
+			result.ObjectCount = written
+		} else {
+			cfg.Depth, cfg.Deepen, cfg.Unshallow = opts.Depth, opts.Deepen, opts.Unshallow
+			if cfg.ShallowState, err = r.ShallowState(); err != nil {
+				return nil, fmt.Errorf("fetch: read shallow state: %w", err)
+			}
+			fetched, err := remote.FetchIntoStoreShallow(ctx, client, r.Store, wants, haves, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("fetch: download objects: %w", err)
+			}
+			// The shallow file is removed once no boundaries remain.
+			if err := r.WriteShallowState(fetched.ShallowState); err != nil {
+				return nil, fmt.Errorf("fetch: write shallow state: %w", err)
+			}
+			result.ObjectCount = fetched.Written
+			result.Shallow = fetched.ShallowState.Len()
 		}
-		result.UpdatedRefs = append(result.UpdatedRefs, RefUpdate{
-			Name:    trackingRef,
-			OldHash: oldHash,
-			NewHash: h,
-		})
 	}
 
-	return nil
+	result.UpdatedRefs, result.RejectedRefs, err = r.UpdateFetchedRefs(mappings)
+	if err != nil {
+		return nil, err
+	}
+	return remoteRefs, nil
 }
 
 // localRefTips returns hash tips from all local refs for have negotiation.
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -341,6 +342,43 @@ func TestFetch_RemoteTags(t *testing.T) {
 	}
 }
 
+// TestFetch_PruneDeletesStaleTrackingRefs verifies that only a pruning fetch
+// removes tracking refs for branches deleted on the remote.
+func TestFetch_PruneDeletesStaleTrackingRefs(t *testing.T) {
+	local, remoteRepo, commitHash := setupRemotePair(t)
+	if err := remoteRepo.UpdateRef("refs/heads/feature", commitHash); err != nil {
+		t.Fatalf("create remote branch: %v", err)
+	}
+	if _, err := local.Fetch("origin"); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if err := remoteRepo.DeleteRefCAS("refs/heads/feature", commitHash); err != nil {
+		t.Fatalf("delete remote branch: %v", err)
+	}
+
+	staleRef := "refs/remotes/origin/heads/feature"
+	if _, err := local.Fetch("origin"); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if _, err := local.ResolveRef(staleRef); err != nil {
+		t.Fatalf("plain fetch removed %q: %v", staleRef, err)
+	}
+
+	result, err := local.FetchWithOptions(context.Background(), "origin", FetchOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("FetchWithOptions(prune): %v", err)
+	}
+	if len(result.PrunedRefs) != 1 || result.PrunedRefs[0].Name != staleRef || result.PrunedRefs[0].OldHash != commitHash {
+		t.Fatalf("PrunedRefs = %+v, want %s", result.PrunedRefs, staleRef)
+	}
+	if _, err := local.ResolveRef(staleRef); err == nil {
+		t.Fatalf("%q still exists after prune", staleRef)
+	}
+	if got, err := local.ResolveRef("refs/remotes/origin/heads/main"); err != nil || got != commitHash {
+		t.Fatalf("prune touched the live tracking ref: %q, %v", got, err)
+	}
+}
+
 func contains(s, sub string) bool {
 	return len(s) >= len(sub) && containsImpl(s, sub)
 }
@@ -353,3 +391,61 @@ func containsImpl(s, sub string) bool {
 	}
 	return false
 }
+
+// TestFetch_ShallowKeepsBoundaries verifies that a depth-limited fetch
+// records its boundary, that a later plain fetch downloads only the new
+// commits, and that unshallow completes the history.
+func TestFetch_ShallowKeepsBoundaries(t *testing.T) {
+	local, remoteRepo, first := setupRemotePair(t)
+	commitFile := func(name, msg string) object.Hash {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(remoteRepo.RootDir, name), []byte("package main\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := remoteRepo.Add([]string{name}); err != nil {
+			t.Fatal(err)
+		}
+		h, err := remoteRepo.Commit(msg, "test-author")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	second := commitFile("second.go", "second")
+
+	ctx := context.Background()
+	result, err := local.FetchWithOptions(ctx, "origin", FetchOptions{Depth: 1})
+	if err != nil {
+		t.Fatalf("FetchWithOptions(depth): %v", err)
+	}
+	if result.Shallow != 1 {
+		t.Fatalf("Shallow = %d, want 1", result.Shallow)
+	}
+	if !local.Store.Has(second) || local.Store.Has(first) {
+		t.Fatal("depth 1 fetch should copy only the tip commit")
+	}
+	if state, _ := local.ShallowState(); !state.IsShallow(first) {
+		t.Fatalf("boundary %s not recorded", first)
+	}
+
+	third := commitFile("third.go", "third")
+	if _, err := local.Fetch("origin"); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got, _ := local.ResolveRef("refs/remotes/origin/heads/main"); got != third {
+		t.Fatalf("tracking ref = %s, want %s", got, third)
+	}
+	if local.Store.Has(first) || !local.IsShallowRepository() {
+		t.Fatal("plain fetch of a shallow repository pulled in history behind the boundary")
+	}
+
+	if _, err := local.FetchWithOptions(ctx, "origin", FetchOptions{Unshallow: true, Depth: 1}); err == nil {
+		t.Fatal("unshallow combined with depth should fail")
+	}
+	if _, err := local.FetchWithOptions(ctx, "origin", FetchOptions{Unshallow: true}); err != nil {
+		t.Fatalf("FetchWithOptions(unshallow): %v", err)
+	}
+	if !local.Store.Has(first) || local.IsShallowRepository() {
+		t.Fatal("unshallow should fetch the complete history and drop the boundary")
+	}
+}
@@ -1,22 +1,60 @@
 package repo
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/odvcencio/graft/pkg/object"
 )
 
+// modeFromFileInfo returns the tree mode for a working-tree path. info must
+// come from os.Lstat so that symlinks are seen as links.
 func modeFromFileInfo(info os.FileInfo) string {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return object.TreeModeSymlink
+	}
 	if info.Mode()&0o111 != 0 {
 		return object.TreeModeExecutable
 	}
 	return object.TreeModeFile
 }
 
+// worktreeMode returns the tree mode to record for a working-tree path
+// described by an Lstat result. When trustMode is false the executable bit
+// on disk means nothing, so a regular file keeps the executable bit of
+// indexMode, its mode in the index, and new files are not executable.
+func worktreeMode(info os.FileInfo, indexMode string, trustMode bool) string {
+	mode := modeFromFileInfo(info)
+	if trustMode || isSymlinkMode(mode) {
+		return mode
+	}
+	if normalizeFileMode(indexMode) == object.TreeModeExecutable {
+		return object.TreeModeExecutable
+	}
+	return object.TreeModeFile
+}
+
+// trustFileMode reports whether the executable bit of working-tree files is
+// meaningful, as set by core.filemode. It defaults to true except on
+// Windows, where the filesystem does not record the bit.
+func (r *Repo) trustFileMode() bool {
+	cfg, err := r.ReadConfig()
+	if err == nil && cfg.Core != nil && cfg.Core.FileMode != nil {
+		return *cfg.Core.FileMode
+	}
+	return runtime.GOOS != "windows"
+}
+
 func normalizeFileMode(mode string) string {
 	switch mode {
 	case object.TreeModeExecutable:
 		return object.TreeModeExecutable
+	case object.TreeModeSymlink:
+		return object.TreeModeSymlink
 	case object.TreeModeModule:
 		return object.TreeModeModule
 	default:
@@ -24,9 +62,104 @@ func normalizeFileMode(mode string) string {
 	}
 }
 
+func isSymlinkMode(mode string) bool {
+	return mode == object.TreeModeSymlink
+}
+
 func filePermFromMode(mode string) os.FileMode {
 	if normalizeFileMode(mode) == object.TreeModeExecutable {
 		return 0o755
 	}
 	return 0o644
 }
+
+// readWorktreeFile returns the blob content for a working-tree path: the
+// file's bytes, or the link target for a symlink. info must come from
+// os.Lstat.
+func readWorktreeFile(absPath string, info os.FileInfo) ([]byte, error) {
+	if info.Mode()&os.ModeSymlink == 0 {
+		return os.ReadFile(absPath)
+	}
+	target, err := os.Readlink(absPath)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(filepath.ToSlash(target)), nil
+}
+
+// errSymlinkedParent is returned by mkdirWorktree for a parent directory
+// that is a symlink in the working tree.
+var errSymlinkedParent = errors.New("refusing to write through a symlinked directory")
+
+// mkdirWorktree creates dir and its missing parents below root, the root
+// of a working tree, checking every component with Lstat. It refuses
+// components that are symlinks and dirs outside root, so that a tree
+// placing a link where a directory is expected cannot make checkout write
+// outside the working tree.
+func mkdirWorktree(root, dir string) error {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside the working tree", dir)
+	}
+	cur := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		cur = filepath.Join(cur, part)
+		info, err := os.Lstat(cur)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			if err := os.Mkdir(cur, 0o755); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case info.Mode()&os.ModeSymlink != 0:
+			return fmt.Errorf("%s: %w", cur, errSymlinkedParent)
+		case !info.IsDir():
+			return fmt.Errorf("%s: not a directory", cur)
+		}
+	}
+	return nil
+}
+
+// writeWorktreeFile materializes blob data at absPath: as a symlink to data
+// when mode is a symlink mode, otherwise as a regular file. A symlink
+// already at absPath is replaced rather than written through, and a regular
+// file is replaced when a symlink takes its place. Callers create the
+// parent directories with mkdirWorktree.
+func writeWorktreeFile(absPath string, data []byte, mode string) error {
+	symlink := isSymlinkMode(mode)
+	if info, err := os.Lstat(absPath); err == nil && (symlink || info.Mode()&os.ModeSymlink != 0) {
+		if err := os.Remove(absPath); err != nil {
+			return err
+		}
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if symlink {
+		return os.Symlink(filepath.FromSlash(string(data)), absPath)
+	}
+	return os.WriteFile(absPath, data, filePermFromMode(mode))
+}
+
+// ReadWorktreeFile returns the content the working-tree file at relPath
+// would be staged with. For a symlink that is the link target, not the
+// contents of the file it points to; text files have their line endings
+// normalized.
+func (r *Repo) ReadWorktreeFile(relPath string) ([]byte, error) {
+	absPath := filepath.Join(r.RootDir, filepath.FromSlash(relPath))
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readWorktreeFile(absPath, info)
+	if err != nil {
+		return nil, err
+	}
+	return r.lineEndings().clean(relPath, data, modeFromFileInfo(info)), nil
+}
@@ -0,0 +1,344 @@
+package repo
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func skipWithoutSymlinks(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink tests require unix symlinks")
+	}
+}
+
+func symlinkForTest(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+		t.Fatalf("mkdir for %s: %v", link, err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("symlink %s -> %s: %v", link, target, err)
+	}
+}
+
+func assertSymlink(t *testing.T, link, wantTarget string) {
+	t.Helper()
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatalf("lstat %s: %v", link, err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%s is %v, want a symlink", link, info.Mode())
+	}
+	target, err := os.Readlink(link)
+	if err != nil {
+		t.Fatalf("readlink %s: %v", link, err)
+	}
+	if target != wantTarget {
+		t.Fatalf("%s -> %q, want %q", link, target, wantTarget)
+	}
+}
+
+func TestAdd_SymlinkStagesLinkTarget(t *testing.T) {
+	skipWithoutSymlinks(t)
+	r := initRepoWithFile(t, "main.go", []byte("package main\n"))
+	symlinkForTest(t, "main.go", filepath.Join(r.RootDir, "link.go"))
+	writeFile(t, filepath.Join(r.RootDir, "lib", "a.txt"), []byte("a\n"))
+	symlinkForTest(t, "lib", filepath.Join(r.RootDir, "libdir"))
+
+	if err := r.Add([]string{"link.go", "libdir"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	for path, target := range map[string]string{"link.go": "main.go", "libdir": "lib"} {
+		se := stg.Entries[path]
+		if se == nil {
+			t.Fatalf("%s not staged: %v", path, keys(stg.Entries))
+		}
+		if se.Mode != object.TreeModeSymlink {
+			t.Fatalf("%s mode = %q, want %q", path, se.Mode, object.TreeModeSymlink)
+		}
+		if se.BlobHash != object.HashObject(object.TypeBlob, []byte(target)) {
+			t.Fatalf("%s blob does not hold the link target %q", path, target)
+		}
+		if se.EntityListHash != "" {
+			t.Fatalf("%s has an entity list; symlinks are not parsed", path)
+		}
+	}
+	if _, ok := stg.Entries["libdir/a.txt"]; ok {
+		t.Fatal("add followed the directory symlink")
+	}
+
+	if got := statusByPath(t, r); got["link.go"].WorkStatus != StatusClean {
+		t.Fatalf("link.go WorkStatus = %d, want clean", got["link.go"].WorkStatus)
+	}
+
+	// Retargeting the link is a change even when both targets hold the
+	// same content.
+	writeFile(t, filepath.Join(r.RootDir, "copy.go"), []byte("package main\n"))
+	if err := os.Remove(filepath.Join(r.RootDir, "link.go")); err != nil {
+		t.Fatal(err)
+	}
+	symlinkForTest(t, "copy.go", filepath.Join(r.RootDir, "link.go"))
+	if got := statusByPath(t, r); got["link.go"].WorkStatus != StatusDirty {
+		t.Fatalf("link.go WorkStatus = %d after retarget, want dirty", got["link.go"].WorkStatus)
+	}
+}
+
+func TestCheckout_RestoresSymlinks(t *testing.T) {
+	skipWithoutSymlinks(t)
+	r, dir := setupMergeRepo(t)
+
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	symlinkForTest(t, "../main.go", filepath.Join(dir, "docs", "main-link"))
+	if err := r.Add([]string{"docs/main-link"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("add link", "test-author"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "docs", "main-link")); !os.IsNotExist(err) {
+		t.Fatalf("link still present on main: %v", err)
+	}
+
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	assertSymlink(t, filepath.Join(dir, "docs", "main-link"), "../main.go")
+	if got := statusByPath(t, r); got["docs/main-link"].WorkStatus != StatusClean {
+		t.Fatalf("restored link WorkStatus = %d, want clean", got["docs/main-link"].WorkStatus)
+	}
+
+	// Merging the branch into main brings the link along as a link.
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "other.txt"), []byte("diverge\n"))
+	if err := r.Add([]string{"other.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("diverge", "test-author"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	report, err := r.Merge("feature")
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if report.HasConflicts {
+		t.Fatalf("unexpected conflicts: %+v", report)
+	}
+	assertSymlink(t, filepath.Join(dir, "docs", "main-link"), "../main.go")
+}
+
+func TestResetHard_RefusesFilesBelowSymlink(t *testing.T) {
+	skipWithoutSymlinks(t)
+	r, dir := setupMergeRepo(t)
+	outside := t.TempDir()
+
+	head, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef(HEAD): %v", err)
+	}
+	linkBlob := mustWriteBlob(t, r.Store, outside)
+	pwnBlob := mustWriteBlob(t, r.Store, "pwned\n")
+	subtree, err := r.Store.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{
+		{Name: "pwn.txt", Mode: object.TreeModeFile, BlobHash: pwnBlob},
+	}})
+	if err != nil {
+		t.Fatalf("WriteTree(sub): %v", err)
+	}
+	root, err := r.Store.WriteTree(&object.TreeObj{Entries: []object.TreeEntry{
+		{Name: "a", Mode: object.TreeModeSymlink, BlobHash: linkBlob},
+		{Name: "a", IsDir: true, Mode: object.TreeModeDir, SubtreeHash: subtree},
+	}})
+	if err != nil {
+		t.Fatalf("WriteTree(root): %v", err)
+	}
+	evil, err := r.Store.WriteCommit(&object.CommitObj{
+		TreeHash: root,
+		Parents:  []object.Hash{head},
+		Author:   "test-author",
+		Message:  "escape",
+	})
+	if err != nil {
+		t.Fatalf("WriteCommit: %v", err)
+	}
+
+	if err := r.ResetToCommit(evil, ResetHard); err == nil {
+		t.Fatal("ResetToCommit accepted a tree with a file below a symlink")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "pwn.txt")); !os.IsNotExist(err) {
+		t.Fatalf("checkout wrote outside the working tree: %v", err)
+	}
+
+	// A symlinked directory already in the working tree is not written
+	// through either.
+	symlinkForTest(t, outside, filepath.Join(dir, "b"))
+	err = mkdirWorktree(dir, filepath.Join(dir, "b", "nested"))
+	if !errors.Is(err, errSymlinkedParent) {
+		t.Fatalf("mkdirWorktree through a symlink = %v, want errSymlinkedParent", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "nested")); !os.IsNotExist(err) {
+		t.Fatalf("mkdirWorktree created a directory outside the working tree: %v", err)
+	}
+}
+
+func TestThreeWayTreeMerge_SymlinkConflictKeepsOurs(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	blob := func(s string) object.Hash {
+		h, err := r.Store.WriteBlob(&object.Blob{Data: []byte(s)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	link := func(target string) TreeFileEntry {
+		return TreeFileEntry{Path: "current", BlobHash: blob(target), Mode: object.TreeModeSymlink}
+	}
+	base := map[string]TreeFileEntry{"current": link("v1")}
+	ours := map[string]TreeFileEntry{"current": link("v2")}
+	theirs := map[string]TreeFileEntry{"current": link("v3")}
+
+	result, err := r.threeWayTreeMerge(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("threeWayTreeMerge: %v", err)
+	}
+	if !result.HasConflicts || len(result.Files) != 1 {
+		t.Fatalf("result = %+v, want one conflict", result)
+	}
+	f := result.Files[0]
+	if f.Status != "conflict" || f.Mode != object.TreeModeSymlink || string(f.Content) != "v2" {
+		t.Fatalf("file = status %q mode %q content %q, want our link v2", f.Status, f.Mode, f.Content)
+	}
+
+	// A link deleted on one side and retargeted on the other keeps the
+	// surviving link instead of writing conflict markers into its target.
+	result, err = r.threeWayTreeMerge(base, map[string]TreeFileEntry{}, theirs)
+	if err != nil {
+		t.Fatalf("threeWayTreeMerge: %v", err)
+	}
+	if f := result.Files[0]; f.Status != "conflict" || string(f.Content) != "v3" {
+		t.Fatalf("delete/modify file = status %q content %q, want conflict keeping v3", f.Status, f.Content)
+	}
+}
+
+func TestArchive_TarStoresSymlinks(t *testing.T) {
+	skipWithoutSymlinks(t)
+	r := initRepoWithFile(t, "a.txt", []byte("a\n"))
+	symlinkForTest(t, "a.txt", filepath.Join(r.RootDir, "b.txt"))
+	if err := r.Add([]string{"b.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("add link", "test-author"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := r.Archive(&buf, "HEAD", ArchiveOptions{Format: "tar"}); err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			t.Fatal("b.txt missing from archive")
+		}
+		if err != nil {
+			t.Fatalf("read tar: %v", err)
+		}
+		if hdr.Name != "b.txt" {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != "a.txt" {
+			t.Fatalf("b.txt header = type %c link %q, want symlink to a.txt", hdr.Typeflag, hdr.Linkname)
+		}
+		return
+	}
+}
+
+func setCoreFileMode(t *testing.T, r *Repo, trust bool) {
+	t.Helper()
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	cfg.Core = &CoreConfig{FileMode: &trust}
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+}
+
+func TestCoreFileModeFalseIgnoresExecutableBit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test toggles the executable bit")
+	}
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	scriptPath := filepath.Join(r.RootDir, "run.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(r.RootDir, "plain.txt"), []byte("plain\n"))
+	if err := r.Add([]string{"run.sh", "plain.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	setCoreFileMode(t, r, false)
+
+	// Losing the bit, as a checkout onto FAT does, is not a change.
+	if err := os.Chmod(scriptPath, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(r.RootDir, "plain.txt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	got := statusByPath(t, r)
+	for _, p := range []string{"run.sh", "plain.txt"} {
+		if got[p].WorkStatus != StatusClean {
+			t.Fatalf("%s WorkStatus = %d with core.filemode=false, want clean", p, got[p].WorkStatus)
+		}
+	}
+
+	// Restaging content keeps the index mode.
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\necho hi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add([]string{"run.sh"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if mode := stg.Entries["run.sh"].Mode; mode != object.TreeModeExecutable {
+		t.Fatalf("run.sh mode = %q after add, want %q", mode, object.TreeModeExecutable)
+	}
+
+	// With the setting back on, the missing bit shows up again.
+	setCoreFileMode(t, r, true)
+	if got := statusByPath(t, r); got["run.sh"].WorkStatus != StatusDirty {
+		t.Fatalf("run.sh WorkStatus = %d with core.filemode=true, want dirty", got["run.sh"].WorkStatus)
+	}
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runContentFilter pipes data through the filter command for path and
+// returns its output. The command runs in the repository root.
+func (le *lineEndings) runContentFilter(command, path string, data []byte) ([]byte, error) {
+	command = strings.ReplaceAll(command, "%f", shellQuote(path))
+	var out, stderr bytes.Buffer
+	err := RunExternalProcess(ExternalProcessSpec{
+		Dir:    le.root,
+		Path:   "sh",
+		Args:   []string{"-c", command},
+		Stdin:  bytes.NewReader(data),
+		Stdout: &out,
+		Stderr: &stderr,
+		Env: append(os.Environ(),
+			"GRAFT_WORK_TREE="+le.root,
+		),
+		Label: "filter",
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// applyFilter runs the clean or smudge command of the filter selected for
+// path. A filter that is not configured, or whose command fails, leaves
+// data unchanged; a failure is reported on stderr.
+func (le *lineEndings) applyFilter(path string, data []byte, smudge bool) []byte {
+	name, f := le.filterFor(path)
+	if f == nil {
+		return data
+	}
+	command, kind := f.Clean, "clean"
+	if smudge {
+		command, kind = f.Smudge, "smudge"
+	}
+	if strings.TrimSpace(command) == "" {
+		return data
+	}
+	out, err := le.runContentFilter(command, path, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s filter %q failed for %s: %v\n", kind, name, path, err)
+		return data
+	}
+	return out
+}
+
+// filterFor returns the filter the filter attribute selects for path, or
+// nil when there is none or it is not configured.
+func (le *lineEndings) filterFor(path string) (string, *FilterConfig) {
+	if le == nil || le.attrs == nil || len(le.filters) == 0 {
+		return "", nil
+	}
+	name := le.attrs.Match(path)["filter"]
+	if name == "" || name == "false" || name == "true" {
+		return "", nil
+	}
+	return name, le.filters[name]
+}
+
+// shellQuote quotes s as a single sh word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestContentFilter_CleanOnAddSmudgeOnCheckout(t *testing.T) {
+	r, dir := setupMergeRepo(t)
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	cfg.Filters = map[string]*FilterConfig{
+		"rot13": {Clean: "tr a-z n-za-m", Smudge: "tr n-za-m a-z"},
+	}
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	writeFile(t, filepath.Join(dir, ".graftattributes"), []byte("*.secret filter=rot13\n"))
+	writeFile(t, filepath.Join(dir, "db.secret"), []byte("hunter\n"))
+	if err := r.Add([]string{".graftattributes", "db.secret"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if got := stg.Entries["db.secret"].BlobHash; got != object.HashObject(object.TypeBlob, []byte("uhagre\n")) {
+		t.Fatal("db.secret was not stored through the clean filter")
+	}
+	if _, err := r.Commit("add secret", "test-author"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := statusByPath(t, r); got["db.secret"].WorkStatus != StatusClean {
+		t.Fatalf("db.secret WorkStatus = %d after commit, want clean", got["db.secret"].WorkStatus)
+	}
+
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "db.secret"))
+	if err != nil || string(data) != "hunter\n" {
+		t.Fatalf("db.secret = %q, %v; want the smudged content", data, err)
+	}
+	if got := statusByPath(t, r); got["db.secret"].WorkStatus != StatusClean {
+		t.Fatalf("db.secret WorkStatus = %d after checkout, want clean", got["db.secret"].WorkStatus)
+	}
+
+	writeFile(t, filepath.Join(dir, "db.secret"), []byte("hunter2\n"))
+	if got := statusByPath(t, r); got["db.secret"].WorkStatus != StatusDirty {
+		t.Fatalf("db.secret WorkStatus = %d after an edit, want dirty", got["db.secret"].WorkStatus)
+	}
+}
@@ -0,0 +1,255 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// fsmonitorProtocolVersion is passed as the first argument of the fsmonitor
+// command. It is version 2 of git's fsmonitor hook protocol, so hooks
+// written for git, such as the watchman sample, work unchanged.
+const fsmonitorProtocolVersion = "2"
+
+// fsmonitorStateFile is the file under .graft recording the last token and
+// the paths Status must examine again.
+const fsmonitorStateFile = "fsmonitor"
+
+// fsmonitorState is what Status remembers between runs when a filesystem
+// monitor is configured.
+type fsmonitorState struct {
+	// Token is the monitor's token from the start of the last status; the
+	// next query asks for changes since it.
+	Token string `json:"token"`
+	// Index identifies the staging index the last status compared against.
+	// Any other index makes the state stale.
+	Index fsmonitorIndexStamp `json:"index"`
+	// Recheck lists the paths that were not clean in the last status:
+	// modified, deleted, renamed, conflicted or untracked. They are
+	// examined again whether or not the monitor reports them.
+	Recheck []string `json:"recheck,omitempty"`
+}
+
+type fsmonitorIndexStamp struct {
+	Size        int64 `json:"size"`
+	ModTimeNano int64 `json:"modTimeNano"`
+}
+
+// fsmonitorQuery is the answer of the filesystem monitor for one status.
+type fsmonitorQuery struct {
+	token   string   // token to record for the next query; "" when the monitor failed
+	full    bool     // the monitor cannot narrow the changes; walk the whole tree
+	changed []string // repo-relative paths changed since the recorded token
+	recheck []string // paths carried over from the last status
+}
+
+// queryFSMonitor asks the command configured as core.fsmonitor which paths
+// changed since the last status. It returns nil when no monitor is
+// configured.
+//
+// The command runs in the repository root with the protocol version and
+// the last token as arguments, and prints a new token followed by the
+// changed paths, each terminated by a NUL byte. Paths are relative to the
+// root; a path of "/" means anything may have changed. The result asks for
+// a full walk when the command fails, there is no earlier token, the index
+// changed since the last status, or an ignore file changed.
+func (r *Repo) queryFSMonitor() *fsmonitorQuery {
+	cfg, err := r.ReadConfig()
+	if err != nil || cfg.Core == nil || strings.TrimSpace(cfg.Core.FSMonitor) == "" {
+		return nil
+	}
+	command := strings.TrimSpace(cfg.Core.FSMonitor)
+	if !filepath.IsAbs(command) {
+		command = filepath.Join(r.RootDir, command)
+	}
+	state := r.readFSMonitorState()
+
+	var out bytes.Buffer
+	if err := RunExternalProcess(ExternalProcessSpec{
+		Context: context.Background(),
+		Dir:     r.RootDir,
+		Path:    command,
+		Args:    []string{fsmonitorProtocolVersion, state.Token},
+		Stdout:  &out,
+		Stderr:  os.Stderr,
+		Env: append(os.Environ(),
+			"GRAFT_DIR="+r.GraftDir,
+			"GRAFT_WORK_TREE="+r.RootDir,
+		),
+		Label: "fsmonitor",
+	}); err != nil {
+		return &fsmonitorQuery{full: true}
+	}
+
+	fields := strings.Split(out.String(), "\x00")
+	q := &fsmonitorQuery{token: strings.TrimSpace(fields[0])}
+	if q.token == "" || state.Token == "" || state.Index != r.fsmonitorIndexStamp() {
+		q.full = true
+		return q
+	}
+	for _, p := range fields[1:] {
+		if p == "" {
+			continue
+		}
+		if p == "/" || isIgnoreControlFile(path.Base(strings.TrimSuffix(p, "/"))) {
+			q.full = true
+			return q
+		}
+		q.changed = append(q.changed, p)
+	}
+	q.recheck = state.Recheck
+	return q
+}
+
+// isIgnoreControlFile reports whether a file of this name changes which
+// paths are ignored, so a change to it invalidates everything.
+func isIgnoreControlFile(name string) bool {
+	for _, n := range ignoreFileNames {
+		if name == n {
+			return true
+		}
+	}
+	return name == ".graftmodules"
+}
+
+// monitoredWorkFiles builds the working-tree file set for Status from a
+// monitor answer instead of a full walk. Changed and recheck paths are
+// examined on disk, directories among them are walked, and every other
+// tracked file is assumed present and returned in unchanged. Untracked
+// changes inside nested repositories are recorded as their root in nested.
+func (r *Repo) monitoredWorkFiles(ctx context.Context, stg *Staging, ic *IgnoreChecker, q *fsmonitorQuery, trackedPaths, trackedDirs, nested map[string]struct{}) (map[string]bool, map[string]struct{}, error) {
+	workFiles := make(map[string]bool)
+	candidates := make(map[string]struct{})
+	// dirs holds changed paths that are not files now: directories, and
+	// paths that were removed and may have been directories. Tracked files
+	// below them are examined too.
+	dirs := make(map[string]struct{})
+
+	for _, list := range [][]string{q.changed, q.recheck} {
+		for _, p := range list {
+			p = strings.Trim(filepath.ToSlash(p), "/")
+			if p == "" || p == "." {
+				continue
+			}
+			if _, seen := candidates[p]; seen {
+				continue
+			}
+			candidates[p] = struct{}{}
+
+			if _, tracked := trackedPaths[p]; !tracked {
+				if root := r.nestedRepoRoot(p); root != "" {
+					if _, hasTracked := trackedDirs[root]; !hasTracked {
+						if !ic.IsIgnored(root) {
+							nested[root] = struct{}{}
+						}
+						continue
+					}
+				}
+			}
+
+			absPath := filepath.Join(r.RootDir, filepath.FromSlash(p))
+			info, err := os.Lstat(absPath)
+			switch {
+			case os.IsNotExist(err):
+				dirs[p] = struct{}{}
+			case err != nil:
+				return nil, nil, err
+			case info.IsDir():
+				dirs[p] = struct{}{}
+				if err := r.walkStatusFiles(ctx, absPath, ic, trackedPaths, trackedDirs, false, workFiles, nested); err != nil {
+					return nil, nil, err
+				}
+			default:
+				if _, tracked := trackedPaths[p]; tracked || !ic.IsIgnored(p) {
+					workFiles[p] = true
+				}
+			}
+		}
+	}
+
+	unchanged := make(map[string]struct{}, len(stg.Entries))
+	for p := range stg.Entries {
+		if _, ok := candidates[p]; ok || underAnyDir(p, dirs) {
+			continue
+		}
+		workFiles[p] = true
+		unchanged[p] = struct{}{}
+	}
+	return workFiles, unchanged, nil
+}
+
+// underAnyDir reports whether p lies below one of dirs.
+func underAnyDir(p string, dirs map[string]struct{}) bool {
+	if len(dirs) == 0 {
+		return false
+	}
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' {
+			if _, ok := dirs[p[:i]]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// saveFSMonitorState records token and the paths of entries that need a
+// second look for the next status. An empty token removes the state, so
+// the next status walks the whole tree. Failures only cost that walk and
+// are not reported.
+func (r *Repo) saveFSMonitorState(token string, entries []StatusEntry) {
+	statePath := filepath.Join(r.GraftDir, fsmonitorStateFile)
+	if token == "" {
+		_ = os.Remove(statePath)
+		return
+	}
+	state := fsmonitorState{Token: token, Index: r.fsmonitorIndexStamp()}
+	for _, e := range entries {
+		if e.WorkStatus == StatusClean {
+			continue
+		}
+		state.Recheck = append(state.Recheck, e.Path)
+		if e.RenamedFrom != "" {
+			state.Recheck = append(state.Recheck, e.RenamedFrom)
+		}
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(r.GraftDir, ".fsmonitor-tmp-*")
+	if err != nil {
+		return
+	}
+	tmpName := tmp.Name()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(tmpName, statePath) != nil {
+		os.Remove(tmpName)
+	}
+}
+
+func (r *Repo) readFSMonitorState() fsmonitorState {
+	var state fsmonitorState
+	data, err := os.ReadFile(filepath.Join(r.GraftDir, fsmonitorStateFile))
+	if err != nil || json.Unmarshal(data, &state) != nil {
+		return fsmonitorState{}
+	}
+	return state
+}
+
+// fsmonitorIndexStamp identifies the current staging index file. Index
+// writes replace the file, so any write changes the stamp.
+func (r *Repo) fsmonitorIndexStamp() fsmonitorIndexStamp {
+	info, err := os.Stat(r.indexPath())
+	if err != nil {
+		return fsmonitorIndexStamp{}
+	}
+	return fsmonitorIndexStamp{Size: info.Size(), ModTimeNano: info.ModTime().UnixNano()}
+}
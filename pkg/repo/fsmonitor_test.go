@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// installFSMonitor configures a monitor hook that answers with a fixed token
+// followed by the paths listed, one per line, in .graft/test-fsmonitor.
+func installFSMonitor(t *testing.T, r *Repo, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fsmonitor tests require unix shell scripts")
+	}
+	hookPath := filepath.Join(r.GraftDir, "fsmonitor-hook")
+	if err := os.WriteFile(hookPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fsmonitor hook: %v", err)
+	}
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	cfg.Core = &CoreConfig{FSMonitor: hookPath}
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+}
+
+const testFSMonitorHook = `#!/bin/sh
+printf 'token-1\0'
+if [ -f "$GRAFT_DIR/test-fsmonitor" ]; then
+	tr '\n' '\0' < "$GRAFT_DIR/test-fsmonitor"
+fi
+`
+
+func reportFSMonitorChanges(t *testing.T, r *Repo, paths ...string) {
+	t.Helper()
+	data := strings.Join(paths, "\n")
+	if len(paths) > 0 {
+		data += "\n"
+	}
+	if err := os.WriteFile(filepath.Join(r.GraftDir, "test-fsmonitor"), []byte(data), 0o644); err != nil {
+		t.Fatalf("write monitor changes: %v", err)
+	}
+}
+
+func statusByPath(t *testing.T, r *Repo) map[string]StatusEntry {
+	t.Helper()
+	entries, err := r.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	out := make(map[string]StatusEntry, len(entries))
+	for _, e := range entries {
+		out[e.Path] = e
+	}
+	return out
+}
+
+func TestStatusFSMonitorOnlyExaminesReportedPaths(t *testing.T) {
+	r := initRepoWithFile(t, "a.txt", []byte("a\n"))
+	writeFile(t, filepath.Join(r.RootDir, "b.txt"), []byte("b\n"))
+	if err := r.Add([]string{"b.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	installFSMonitor(t, r, testFSMonitorHook)
+
+	// The first status has no token to ask about and walks the tree.
+	statusByPath(t, r)
+	state := r.readFSMonitorState()
+	if state.Token != "token-1" {
+		t.Fatalf("saved token = %q, want token-1", state.Token)
+	}
+
+	// Changes the monitor does not report are not seen.
+	writeFile(t, filepath.Join(r.RootDir, "a.txt"), []byte("changed a\n"))
+	writeFile(t, filepath.Join(r.RootDir, "b.txt"), []byte("changed b\n"))
+	writeFile(t, filepath.Join(r.RootDir, "new.txt"), []byte("new\n"))
+	reportFSMonitorChanges(t, r, "b.txt", "new.txt")
+
+	got := statusByPath(t, r)
+	if got["a.txt"].WorkStatus != StatusClean {
+		t.Fatalf("a.txt WorkStatus = %d, want clean (not reported)", got["a.txt"].WorkStatus)
+	}
+	if got["b.txt"].WorkStatus != StatusDirty {
+		t.Fatalf("b.txt WorkStatus = %d, want dirty", got["b.txt"].WorkStatus)
+	}
+	if got["new.txt"].WorkStatus != StatusUntracked {
+		t.Fatalf("new.txt WorkStatus = %d, want untracked", got["new.txt"].WorkStatus)
+	}
+
+	// Paths that were not clean stay under watch without being reported again.
+	reportFSMonitorChanges(t, r)
+	got = statusByPath(t, r)
+	if got["b.txt"].WorkStatus != StatusDirty || got["new.txt"].WorkStatus != StatusUntracked {
+		t.Fatalf("recheck lost changes: b.txt=%d new.txt=%d", got["b.txt"].WorkStatus, got["new.txt"].WorkStatus)
+	}
+}
+
+func TestStatusFSMonitorReportedDirectory(t *testing.T) {
+	r := initRepoWithFile(t, "src/main.go", []byte("package main\n"))
+	installFSMonitor(t, r, testFSMonitorHook)
+	statusByPath(t, r)
+
+	if err := os.RemoveAll(filepath.Join(r.RootDir, "src")); err != nil {
+		t.Fatal(err)
+	}
+	reportFSMonitorChanges(t, r, "src")
+
+	got := statusByPath(t, r)
+	if got["src/main.go"].WorkStatus != StatusDeleted {
+		t.Fatalf("src/main.go WorkStatus = %d, want deleted", got["src/main.go"].WorkStatus)
+	}
+}
+
+func TestStatusFSMonitorFallsBackToFullWalk(t *testing.T) {
+	r := initRepoWithFile(t, "a.txt", []byte("a\n"))
+	installFSMonitor(t, r, testFSMonitorHook)
+	statusByPath(t, r)
+
+	// Restaging invalidates the saved state even though nothing is reported.
+	writeFile(t, filepath.Join(r.RootDir, "b.txt"), []byte("b\n"))
+	if err := r.Add([]string{"b.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	writeFile(t, filepath.Join(r.RootDir, "a.txt"), []byte("changed a\n"))
+	reportFSMonitorChanges(t, r)
+	if got := statusByPath(t, r); got["a.txt"].WorkStatus != StatusDirty {
+		t.Fatalf("a.txt WorkStatus = %d after index change, want dirty", got["a.txt"].WorkStatus)
+	}
+
+	// A failing monitor walks the tree and forgets its token.
+	installFSMonitor(t, r, "#!/bin/sh\nexit 1\n")
+	writeFile(t, filepath.Join(r.RootDir, "b.txt"), []byte("changed b\n"))
+	if got := statusByPath(t, r); got["b.txt"].WorkStatus != StatusDirty {
+		t.Fatalf("b.txt WorkStatus = %d with failing monitor, want dirty", got["b.txt"].WorkStatus)
+	}
+	if state := r.readFSMonitorState(); state.Token != "" {
+		t.Fatalf("saved token = %q after monitor failure, want none", state.Token)
+	}
+}
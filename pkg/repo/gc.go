@@ -9,7 +9,27 @@ import (
 )
 
 // GC packs loose objects reachable from refs.
-func (r *Repo) GC() (*object.GCSummary, error) {
+func (r *Repo) GC() (summary *object.GCSummary, err error) {
+	defer r.observe("gc", "")(&err)
+	roots, err := r.gcRoots()
+	if err != nil {
+		return nil, err
+	}
+	return r.finishGC(r.Store.GCReachable(roots))
+}
+
+// Repack consolidates loose objects reachable from refs and all existing
+// packs into a single pack. Packs marked with a .keep file are left alone.
+func (r *Repo) Repack() (summary *object.GCSummary, err error) {
+	defer r.observe("gc", "")(&err)
+	roots, err := r.gcRoots()
+	if err != nil {
+		return nil, err
+	}
+	return r.finishGC(r.Store.Repack(roots))
+}
+
+func (r *Repo) gcRoots() ([]object.Hash, error) {
 	refs, err := r.ListRefs("")
 	if err != nil {
 		return nil, err
@@ -29,8 +49,10 @@ func (r *Repo) GC() (*object.GCSummary, error) {
 		roots = append(roots, h)
 	}
 	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+	return roots, nil
+}
 
-	summary, err := r.Store.GCReachable(roots)
+func (r *Repo) finishGC(summary *object.GCSummary, err error) (*object.GCSummary, error) {
 	if err != nil {
 		return nil, err
 	}
@@ -43,3 +65,70 @@ func (r *Repo) GC() (*object.GCSummary, error) {
 
 	return summary, nil
 }
+
+// Default thresholds of AutoGC, used when the gc section of the config
+// leaves them unset.
+const (
+	DefaultGCAuto          = 6700
+	DefaultGCAutoPackLimit = 50
+)
+
+// AggressiveGC repacks every object reachable from refs, and everything
+// already packed, into one pack with a wide delta window, trading time for
+// a smaller pack. The window is gc.aggressiveWindow from the config.
+func (r *Repo) AggressiveGC() (summary *object.GCSummary, err error) {
+	defer r.observe("gc", "")(&err)
+	roots, err := r.gcRoots()
+	if err != nil {
+		return nil, err
+	}
+	window := object.DefaultAggressiveDeltaWindow
+	if cfg, err := r.ReadConfig(); err == nil && cfg.GC != nil && cfg.GC.AggressiveWindow > 0 {
+		window = cfg.GC.AggressiveWindow
+	}
+	return r.finishGC(r.Store.RepackWithOptions(roots, object.RepackOptions{DeltaWindow: window}))
+}
+
+// AutoGC packs the repository when it has more loose objects than gc.auto
+// allows, repacking instead when it also has more packs than
+// gc.autoPackLimit. It returns nil when no packing was needed.
+func (r *Repo) AutoGC() (*object.GCSummary, error) {
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	limit, packLimit := DefaultGCAuto, DefaultGCAutoPackLimit
+	if cfg.GC != nil && cfg.GC.Auto != nil {
+		limit = *cfg.GC.Auto
+	}
+	if cfg.GC != nil && cfg.GC.AutoPackLimit != nil {
+		packLimit = *cfg.GC.AutoPackLimit
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	loose, err := r.Store.LooseObjectCount()
+	if err != nil {
+		return nil, fmt.Errorf("auto gc: %w", err)
+	}
+	packs := 0
+	if packLimit > 0 {
+		infos, err := r.Store.PackFiles()
+		if err != nil {
+			return nil, fmt.Errorf("auto gc: %w", err)
+		}
+		for _, p := range infos {
+			if !p.Kept {
+				packs++
+			}
+		}
+	}
+	switch {
+	case packLimit > 0 && packs > packLimit:
+		return r.Repack()
+	case loose > limit:
+		return r.GC()
+	}
+	return nil, nil
+}
@@ -672,3 +672,86 @@ func TestGC_ReachabilityWalksEntireGraph(t *testing.T) {
 		t.Errorf("FlattenTree returned %d entries, want %d", len(entries), len(files))
 	}
 }
+
+func TestAutoGC_PacksOnlyAboveThreshold(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitFile(t, r, "main.go", []byte("package main\n"), "initial commit")
+
+	summary, err := r.AutoGC()
+	if err != nil {
+		t.Fatalf("AutoGC: %v", err)
+	}
+	if summary != nil {
+		t.Fatalf("AutoGC below the default threshold packed %d objects", summary.PackedObjects)
+	}
+
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	limit := 1
+	cfg.GC = &GCConfig{Auto: &limit}
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+	summary, err = r.AutoGC()
+	if err != nil {
+		t.Fatalf("AutoGC: %v", err)
+	}
+	if summary == nil || summary.PackedObjects == 0 {
+		t.Fatalf("AutoGC above gc.auto did not pack, summary = %+v", summary)
+	}
+	loose, err := r.Store.LooseObjectCount()
+	if err != nil {
+		t.Fatalf("LooseObjectCount: %v", err)
+	}
+	if loose != 0 {
+		t.Fatalf("loose objects after AutoGC = %d, want 0", loose)
+	}
+
+	disabled := 0
+	cfg.GC.Auto = &disabled
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+	commitFile(t, r, "util.go", []byte("package main\n\nfunc util() {}\n"), "add util")
+	if summary, err := r.AutoGC(); err != nil || summary != nil {
+		t.Fatalf("AutoGC with gc.auto=0 = %+v, %v; want nil, nil", summary, err)
+	}
+}
+
+func TestAggressiveGC_StoresDeltas(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	var body []byte
+	for i := 0; i < 200; i++ {
+		body = append(body, []byte("// a line of the file that stays the same between versions\n")...)
+	}
+	var heads []object.Hash
+	for i := 0; i < 3; i++ {
+		content := append(append([]byte{}, body...), []byte("var version = \""+string(rune('a'+i))+"\"\n")...)
+		heads = append(heads, commitFile(t, r, "main.go", content, "version"))
+	}
+
+	summary, err := r.AggressiveGC()
+	if err != nil {
+		t.Fatalf("AggressiveGC: %v", err)
+	}
+	if summary.DeltaObjects == 0 {
+		t.Fatalf("AggressiveGC stored no deltas, summary = %+v", summary)
+	}
+	for _, h := range heads {
+		for _, oh := range collectReachableHashes(t, r, h) {
+			if _, _, err := r.Store.Read(oh); err != nil {
+				t.Errorf("object %s not readable after AggressiveGC: %v", oh, err)
+			}
+		}
+	}
+}
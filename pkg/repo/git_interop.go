@@ -0,0 +1,669 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/odvcencio/gotreesitter/grammars"
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// Git interop translates graft history to and from git's object model so a
+// repository without a co-located .git directory can push to and fetch from
+// ordinary git remotes. Translated history lives in a private bare git
+// repository under .graft/git-interop, and a map file records which graft
+// commit and blob each git object corresponds to so every object is
+// translated once. Entity lists are not representable in git; they are
+// regenerated locally when git history is imported.
+const (
+	gitInteropDirName = "git-interop"
+	gitInteropMapName = "graft-map"
+	gitInteropRef     = "refs/graft-interop/export"
+)
+
+// GitFetchResult summarizes a FetchFromGit call.
+type GitFetchResult struct {
+	Tip             object.Hash // graft commit for the fetched branch tip
+	TrackingRef     string
+	ImportedCommits int
+	ImportedBlobs   int
+}
+
+// ObjectCount returns the number of commits and blobs imported.
+func (res *GitFetchResult) ObjectCount() int {
+	return res.ImportedCommits + res.ImportedBlobs
+}
+
+type gitInterop struct {
+	r       *Repo
+	dir     string
+	hashMap *gitInteropMap
+}
+
+// gitInteropMap records graft<->git hash pairs, one "graft git" line per
+// translated object, appended as objects are translated.
+type gitInteropMap struct {
+	file    *os.File
+	toGit   map[object.Hash]string
+	toGraft map[string]object.Hash
+}
+
+func openGitInteropMap(path string) (*gitInteropMap, error) {
+	m := &gitInteropMap{
+		toGit:   make(map[object.Hash]string),
+		toGraft: make(map[string]object.Hash),
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			graftHash, gitHash, ok := strings.Cut(strings.TrimSpace(line), " ")
+			if !ok {
+				continue
+			}
+			m.toGit[object.Hash(graftHash)] = gitHash
+			m.toGraft[gitHash] = object.Hash(graftHash)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read git interop map: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open git interop map: %w", err)
+	}
+	m.file = f
+	return m, nil
+}
+
+func (m *gitInteropMap) GraftToGit(h object.Hash) (string, bool) {
+	gitHash, ok := m.toGit[h]
+	return gitHash, ok
+}
+
+func (m *gitInteropMap) GitToGraft(gitHash string) (object.Hash, bool) {
+	h, ok := m.toGraft[gitHash]
+	return h, ok
+}
+
+func (m *gitInteropMap) Put(h object.Hash, gitHash string) error {
+	m.toGit[h] = gitHash
+	m.toGraft[gitHash] = h
+	if _, err := fmt.Fprintf(m.file, "%s %s\n", h, gitHash); err != nil {
+		return fmt.Errorf("write git interop map: %w", err)
+	}
+	return nil
+}
+
+func (m *gitInteropMap) Close() error {
+	return m.file.Close()
+}
+
+func gitBlobHash(data []byte) string {
+	return object.GitObjectHash(object.TypeBlob, data)
+}
+
+func (r *Repo) gitInteropDir() string {
+	return filepath.Join(r.refsBaseDir(), gitInteropDirName)
+}
+
+// openGitInterop opens the interop repository, creating it on first use.
+func (r *Repo) openGitInterop(ctx context.Context) (*gitInterop, error) {
+	dir := r.gitInteropDir()
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("git interop: %w", err)
+		}
+		spec := ExternalProcessSpec{
+			Context: ctx,
+			Dir:     dir,
+			Path:    gitPath(),
+			Args:    []string{"init", "--quiet", "--bare", dir},
+			Stdout:  io.Discard,
+			Stderr:  io.Discard,
+			Label:   "git-interop:init",
+		}
+		if err := RunExternalProcess(spec); err != nil {
+			return nil, fmt.Errorf("git interop: init: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("git interop: %w", err)
+	}
+	hm, err := openGitInteropMap(filepath.Join(dir, gitInteropMapName))
+	if err != nil {
+		return nil, fmt.Errorf("git interop: %w", err)
+	}
+	return &gitInterop{r: r, dir: dir, hashMap: hm}, nil
+}
+
+func (g *gitInterop) Close() error {
+	return g.hashMap.Close()
+}
+
+// git runs a git command against the interop repository.
+func (g *gitInterop) git(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	var stderr bytes.Buffer
+	spec := ExternalProcessSpec{
+		Context: ctx,
+		Dir:     g.dir,
+		Path:    gitPath(),
+		Args:    append([]string{"--git-dir", g.dir}, args...),
+		Stdin:   stdin,
+		Stdout:  stdout,
+		Stderr:  &stderr,
+		Label:   "git-interop:" + args[0],
+	}
+	if err := RunExternalProcess(spec); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return nil
+}
+
+func (g *gitInterop) gitOutput(ctx context.Context, args ...string) ([]byte, error) {
+	var out bytes.Buffer
+	err := g.git(ctx, nil, &out, args...)
+	return out.Bytes(), err
+}
+
+// PushToGit translates the history reachable from local into git objects and
+// pushes it to remoteRef (e.g. "heads/main") on a git remote. Git enforces
+// fast-forward updates unless force is set. Progress from git is written to
+// out and errOut.
+func (r *Repo) PushToGit(ctx context.Context, remoteURL string, local object.Hash, remoteRef string, force bool, out, errOut io.Writer) (string, error) {
+	g, err := r.openGitInterop(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer g.Close()
+
+	commit, err := r.peelToCommit(local)
+	if err != nil {
+		return "", fmt.Errorf("push to git: %w", err)
+	}
+	gitHash, err := g.export(ctx, commit)
+	if err != nil {
+		return "", fmt.Errorf("push to git: %w", err)
+	}
+
+	args := []string{"push"}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, remoteURL, gitHash+":refs/"+strings.TrimPrefix(remoteRef, "refs/"))
+	spec := ExternalProcessSpec{
+		Context: ctx,
+		Dir:     g.dir,
+		Path:    gitPath(),
+		Args:    append([]string{"--git-dir", g.dir}, args...),
+		Stdout:  out,
+		Stderr:  errOut,
+		Label:   "git-interop:push",
+	}
+	if err := RunExternalProcess(spec); err != nil {
+		return "", fmt.Errorf("push to git: %w", err)
+	}
+	return gitHash, nil
+}
+
+// FetchFromGit fetches branch from a git remote, imports any new history as
+// graft commits, and points refs/remotes/<remoteName>/heads/<branch> at the
+// imported tip.
+func (r *Repo) FetchFromGit(ctx context.Context, remoteName, remoteURL, branch string) (*GitFetchResult, error) {
+	g, err := r.openGitInterop(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer g.Close()
+
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	gitTracking := "refs/remotes/" + remoteName + "/" + branch
+	if err := g.git(ctx, nil, nil, "fetch", "--quiet", "--no-tags", remoteURL, "+refs/heads/"+branch+":"+gitTracking); err != nil {
+		return nil, fmt.Errorf("fetch from git: %w", err)
+	}
+	tipOut, err := g.gitOutput(ctx, "rev-parse", "--verify", gitTracking+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("fetch from git: %w", err)
+	}
+
+	res := &GitFetchResult{TrackingRef: "refs/remotes/" + remoteName + "/heads/" + branch}
+	tip, err := g.importHistory(ctx, strings.TrimSpace(string(tipOut)), res)
+	if err != nil {
+		return nil, fmt.Errorf("fetch from git: %w", err)
+	}
+	res.Tip = tip
+	if err := r.UpdateRef(res.TrackingRef, tip); err != nil {
+		return nil, fmt.Errorf("fetch from git: %w", err)
+	}
+	return res, nil
+}
+
+func (r *Repo) peelToCommit(h object.Hash) (object.Hash, error) {
+	for i := 0; i < 16; i++ {
+		objType, _, err := r.Store.Stat(h)
+		if err != nil {
+			return "", err
+		}
+		switch objType {
+		case object.TypeCommit:
+			return h, nil
+		case object.TypeTag:
+			tag, err := r.Store.ReadTag(h)
+			if err != nil {
+				return "", err
+			}
+			h = tag.TargetHash
+		default:
+			return "", fmt.Errorf("%s is a %s, not a commit", h, objType)
+		}
+	}
+	return "", fmt.Errorf("tag chain starting at %s is too deep", h)
+}
+
+// --- Export: graft -> git ---
+
+// export writes every commit reachable from tip that has no git counterpart
+// yet through git fast-import and returns the git hash of tip.
+func (g *gitInterop) export(ctx context.Context, tip object.Hash) (string, error) {
+	pending, err := g.pendingExports(tip)
+	if err != nil {
+		return "", err
+	}
+	if len(pending) > 0 {
+		if err := g.fastImport(ctx, pending); err != nil {
+			return "", err
+		}
+	}
+	gitHash, ok := g.hashMap.GraftToGit(tip)
+	if !ok {
+		return "", fmt.Errorf("commit %s was not exported", tip)
+	}
+	return gitHash, nil
+}
+
+// pendingExports lists the commits reachable from tip that are not mapped
+// yet, parents before children.
+func (g *gitInterop) pendingExports(tip object.Hash) ([]object.Hash, error) {
+	return g.r.commitsParentsFirst(tip, func(h object.Hash) bool {
+		_, ok := g.hashMap.GraftToGit(h)
+		return ok
+	})
+}
+
+func (g *gitInterop) fastImport(ctx context.Context, commits []object.Hash) error {
+	marksPath := filepath.Join(g.dir, "export-marks")
+	defer os.Remove(marksPath)
+
+	marks := make(map[int]object.Hash)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(g.writeFastImportStream(pw, commits, marks))
+	}()
+	err := g.git(ctx, pr, nil, "fast-import", "--quiet", "--force", "--export-marks="+marksPath)
+	pr.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(marksPath)
+	if err != nil {
+		return fmt.Errorf("read fast-import marks: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		markStr, hashStr, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		mark, err := strconv.Atoi(strings.TrimPrefix(markStr, ":"))
+		if err != nil {
+			continue
+		}
+		graftHash, ok := marks[mark]
+		if !ok {
+			continue
+		}
+		if err := g.hashMap.Put(graftHash, hashStr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFastImportStream writes commits, parents first, as a fast-import
+// stream on gitInteropRef. Objects the interop repository already holds are
+// referenced by their git hash.
+func (g *gitInterop) writeFastImportStream(w io.Writer, commits []object.Hash, marks map[int]object.Hash) error {
+	e := &fastExporter{
+		r:       g.r,
+		bw:      bufio.NewWriter(w),
+		marks:   marks,
+		markOf:  make(map[object.Hash]int),
+		gitHash: g.hashMap.GraftToGit,
+	}
+	for _, h := range commits {
+		if err := e.writeCommit(gitInteropRef, h); err != nil {
+			return fmt.Errorf("commit %s: %w", h, err)
+		}
+	}
+	return e.bw.Flush()
+}
+
+var gitIdentPattern = regexp.MustCompile(`^\s*(.*?)\s*<([^<>]*)>\s*$`)
+
+// gitIdent formats a graft author string ("Name <email>" or a bare name) as
+// a git identity line.
+func gitIdent(author string, ts int64, tz string) string {
+	name, email := strings.TrimSpace(author), ""
+	if m := gitIdentPattern.FindStringSubmatch(author); m != nil {
+		name, email = m[1], m[2]
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	if !isGitTimezone(tz) {
+		tz = "+0000"
+	}
+	return fmt.Sprintf("%s <%s> %d %s", name, email, ts, tz)
+}
+
+func isGitTimezone(tz string) bool {
+	if len(tz) != 5 || (tz[0] != '+' && tz[0] != '-') {
+		return false
+	}
+	_, err := strconv.Atoi(tz[1:])
+	return err == nil
+}
+
+// fastImportPath quotes a path for fast-import when it would otherwise be
+// ambiguous.
+func fastImportPath(p string) string {
+	if strings.HasPrefix(p, `"`) || strings.ContainsAny(p, "\n\\") {
+		return strconv.Quote(p)
+	}
+	return p
+}
+
+// --- Import: git -> graft ---
+
+type gitTreeFile struct {
+	mode string
+	hash string
+	path string
+}
+
+// importHistory imports every commit reachable from gitTip that has no
+// graft counterpart yet and returns the graft hash of gitTip.
+func (g *gitInterop) importHistory(ctx context.Context, gitTip string, res *GitFetchResult) (object.Hash, error) {
+	out, err := g.gitOutput(ctx, "rev-list", "--topo-order", "--reverse", gitTip)
+	if err != nil {
+		return "", err
+	}
+	entityCache := make(map[string]object.Hash)
+	for _, gitCommit := range strings.Fields(string(out)) {
+		if _, ok := g.hashMap.GitToGraft(gitCommit); ok {
+			continue
+		}
+		graftHash, err := g.importCommit(ctx, gitCommit, entityCache, res)
+		if err != nil {
+			return "", fmt.Errorf("import git commit %s: %w", gitCommit, err)
+		}
+		if err := g.hashMap.Put(graftHash, gitCommit); err != nil {
+			return "", err
+		}
+		res.ImportedCommits++
+	}
+
+	tip, ok := g.hashMap.GitToGraft(gitTip)
+	if !ok {
+		return "", fmt.Errorf("git commit %s was not imported", gitTip)
+	}
+	return tip, nil
+}
+
+func (g *gitInterop) importCommit(ctx context.Context, gitCommit string, entityCache map[string]object.Hash, res *GitFetchResult) (object.Hash, error) {
+	raw, err := g.gitOutput(ctx, "cat-file", "commit", gitCommit)
+	if err != nil {
+		return "", err
+	}
+	commit, err := parseGitCommit(raw)
+	if err != nil {
+		return "", err
+	}
+	for i, parent := range commit.Parents {
+		graftParent, ok := g.hashMap.GitToGraft(string(parent))
+		if !ok {
+			return "", fmt.Errorf("parent %s has not been imported (shallow git history is not supported)", parent)
+		}
+		commit.Parents[i] = graftParent
+	}
+
+	files, err := g.lsTree(ctx, gitCommit)
+	if err != nil {
+		return "", err
+	}
+	if err := g.importBlobs(ctx, files, res); err != nil {
+		return "", err
+	}
+
+	stg := &Staging{Entries: make(map[string]*StagingEntry, len(files))}
+	for _, f := range files {
+		blobHash, ok := g.hashMap.GitToGraft(f.hash)
+		if !ok {
+			return "", fmt.Errorf("blob %s for %s was not imported", f.hash, f.path)
+		}
+		mode := object.TreeModeFile
+		switch f.mode {
+		case object.TreeModeExecutable, object.TreeModeSymlink:
+			mode = f.mode
+		}
+		cacheKey := f.path + "\x00" + string(blobHash)
+		entityListHash, cached := entityCache[cacheKey]
+		if isSymlinkMode(mode) {
+			entityListHash = ""
+		} else if !cached {
+			entityListHash, err = g.r.entityListForBlob(f.path, blobHash)
+			if err != nil {
+				return "", err
+			}
+			entityCache[cacheKey] = entityListHash
+		}
+		stg.Entries[f.path] = &StagingEntry{
+			Path:           f.path,
+			BlobHash:       blobHash,
+			EntityListHash: entityListHash,
+			Mode:           mode,
+		}
+	}
+	treeHash, err := g.r.buildTreeDir(stg, "")
+	if err != nil {
+		return "", err
+	}
+	commit.TreeHash = treeHash
+	return g.r.Store.WriteCommit(commit)
+}
+
+// lsTree lists the files of a git commit. Gitlinks are skipped; symlinks are
+// listed with their git mode and a blob holding the link target.
+func (g *gitInterop) lsTree(ctx context.Context, gitCommit string) ([]gitTreeFile, error) {
+	out, err := g.gitOutput(ctx, "ls-tree", "-r", "-z", "--full-tree", gitCommit)
+	if err != nil {
+		return nil, err
+	}
+	var files []gitTreeFile
+	for _, rec := range strings.Split(string(out), "\x00") {
+		if rec == "" {
+			continue
+		}
+		meta, path, ok := strings.Cut(rec, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected ls-tree output %q", rec)
+		}
+		if fields[1] != "blob" {
+			continue
+		}
+		files = append(files, gitTreeFile{mode: fields[0], hash: fields[2], path: path})
+	}
+	return files, nil
+}
+
+// importBlobs stores every blob of files that has no graft counterpart yet,
+// reading them in one cat-file --batch call.
+func (g *gitInterop) importBlobs(ctx context.Context, files []gitTreeFile, res *GitFetchResult) error {
+	var want bytes.Buffer
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if seen[f.hash] {
+			continue
+		}
+		seen[f.hash] = true
+		if _, ok := g.hashMap.GitToGraft(f.hash); ok {
+			continue
+		}
+		want.WriteString(f.hash + "\n")
+	}
+	if want.Len() == 0 {
+		return nil
+	}
+
+	// Stream the batch output rather than buffering it: a large history can
+	// hold far more blob data than fits in memory.
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := g.git(ctx, &want, pw, "cat-file", "--batch")
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	readErr := g.storeBlobBatch(pr, res)
+	// Stop git's writes if the batch was abandoned part way through.
+	pr.CloseWithError(io.ErrClosedPipe)
+	gitErr := <-done
+	if readErr != nil {
+		return readErr
+	}
+	return gitErr
+}
+
+// storeBlobBatch stores the blobs of a cat-file --batch stream.
+func (g *gitInterop) storeBlobBatch(out io.Reader, res *GitFetchResult) error {
+	br := bufio.NewReader(out)
+	for {
+		header, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) && header == "" {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read cat-file output: %w", err)
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 || fields[1] != "blob" {
+			return fmt.Errorf("unexpected cat-file header %q", strings.TrimSpace(header))
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("unexpected cat-file header %q", strings.TrimSpace(header))
+		}
+		data := make([]byte, size+1) // content plus trailing LF
+		if _, err := io.ReadFull(br, data); err != nil {
+			return fmt.Errorf("read blob %s: %w", fields[0], err)
+		}
+		data = data[:size]
+		if got := gitBlobHash(data); got != fields[0] {
+			return fmt.Errorf("blob %s: content hashes to %s", fields[0], got)
+		}
+		blobHash, err := g.r.Store.WriteBlob(&object.Blob{Data: data})
+		if err != nil {
+			return err
+		}
+		if err := g.hashMap.Put(blobHash, fields[0]); err != nil {
+			return err
+		}
+		res.ImportedBlobs++
+	}
+}
+
+// parseGitCommit decodes a raw git commit into a CommitObj whose TreeHash
+// and Parents still hold git hashes. Signatures and other extra headers are
+// dropped.
+func parseGitCommit(raw []byte) (*object.CommitObj, error) {
+	headers, message, ok := bytes.Cut(raw, []byte("\n\n"))
+	if !ok {
+		headers, message = bytes.TrimSuffix(raw, []byte("\n")), nil
+	}
+	commit := &object.CommitObj{Message: string(message)}
+	for _, line := range strings.Split(string(headers), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			commit.TreeHash = object.Hash(value)
+		case "parent":
+			commit.Parents = append(commit.Parents, object.Hash(value))
+		case "author":
+			commit.Author, commit.Timestamp, commit.AuthorTimezone = parseGitIdent(value)
+		case "committer":
+			commit.Committer, commit.CommitterTimestamp, commit.CommitterTimezone = parseGitIdent(value)
+		}
+	}
+	if commit.Author == "" {
+		return nil, errors.New("commit has no author")
+	}
+	return commit, nil
+}
+
+// parseGitIdent splits "Name <email> 1700000000 +0100" into the graft author
+// form "Name <email>", the timestamp, and the timezone.
+func parseGitIdent(value string) (string, int64, string) {
+	end := strings.LastIndexByte(value, '>')
+	if end < 0 {
+		return strings.TrimSpace(value), 0, ""
+	}
+	ident := value[:end+1]
+	fields := strings.Fields(value[end+1:])
+	var ts int64
+	tz := ""
+	if len(fields) > 0 {
+		ts, _ = strconv.ParseInt(fields[0], 10, 64)
+	}
+	if len(fields) > 1 {
+		tz = fields[1]
+	}
+	return ident, ts, tz
+}
+
+// entityListForBlob extracts and stores the entity list of a blob the same
+// way Add does, returning "" when the file has no extractable entities.
+func (r *Repo) entityListForBlob(relPath string, blobHash object.Hash) (object.Hash, error) {
+	langEntry := grammars.DetectLanguage(relPath)
+	if langEntry == nil {
+		return "", nil
+	}
+	blob, err := r.Store.ReadBlob(blobHash)
+	if err != nil {
+		return "", err
+	}
+	content := blob.Data
+	if len(content) == 0 || isBinaryContent(content) || int64(len(content)) > maxEntityExtractionSize {
+		return "", nil
+	}
+	if entity.ShouldSkipExtraction(langEntry.Name, int64(len(content)), false) {
+		return "", nil
+	}
+	el, err := entity.ExtractWithOptions(relPath, content, entity.ExtractOptions{})
+	if err != nil || len(el.Entities) == 0 {
+		return "", nil
+	}
+	return r.writeEntityList(relPath, el)
+}
@@ -0,0 +1,174 @@
+package repo
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitInteropPushAndFetchRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadowWriteFile(t, r.RootDir, "main.go", "package main\n\nfunc main() {}\n")
+	shadowWriteFile(t, r.RootDir, "docs/readme.txt", "hello\n")
+	if err := r.Add([]string{"main.go", "docs/readme.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("first commit", "Alice <alice@example.com>"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	shadowWriteFile(t, r.RootDir, "docs/readme.txt", "hello again\n")
+	if err := r.Add([]string{"docs/readme.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	head, err := r.Commit("second commit", "Alice <alice@example.com>")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	gitOutput(t, t.TempDir(), "init", "--bare", "-b", "main", remoteDir)
+
+	gitHead, err := r.PushToGit(ctx, remoteDir, head, "heads/main", false, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("PushToGit: %v", err)
+	}
+	if got := gitOutput(t, remoteDir, "rev-parse", "main"); got != gitHead {
+		t.Fatalf("remote main = %s, want %s", got, gitHead)
+	}
+	if got := gitOutput(t, remoteDir, "log", "--format=%s|%an <%ae>", "main"); got != "second commit|Alice <alice@example.com>\nfirst commit|Alice <alice@example.com>" {
+		t.Fatalf("remote log = %q", got)
+	}
+	if got := gitOutput(t, remoteDir, "show", "main:docs/readme.txt"); got != "hello again" {
+		t.Fatalf("remote readme = %q", got)
+	}
+
+	// A plain git user adds a commit on top.
+	clone := filepath.Join(t.TempDir(), "clone")
+	gitOutput(t, t.TempDir(), "clone", "-q", remoteDir, clone)
+	shadowWriteFile(t, clone, "util.go", "package main\n\nfunc helper() int { return 1 }\n")
+	gitOutput(t, clone, "add", "util.go")
+	gitOutput(t, clone, "-c", "user.name=Bob", "-c", "user.email=bob@example.com", "commit", "-q", "-m", "add helper")
+	gitOutput(t, clone, "push", "-q", "origin", "main")
+
+	res, err := r.FetchFromGit(ctx, "origin", remoteDir, "main")
+	if err != nil {
+		t.Fatalf("FetchFromGit: %v", err)
+	}
+	if res.ImportedCommits != 1 || res.ImportedBlobs != 1 {
+		t.Fatalf("imported %d commits, %d blobs; want 1 and 1", res.ImportedCommits, res.ImportedBlobs)
+	}
+	if tracking, err := r.ResolveRef("refs/remotes/origin/heads/main"); err != nil || tracking != res.Tip {
+		t.Fatalf("tracking ref = %s, %v; want %s", tracking, err, res.Tip)
+	}
+	commit, err := r.Store.ReadCommit(res.Tip)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	if len(commit.Parents) != 1 || commit.Parents[0] != head {
+		t.Fatalf("imported parents = %v, want [%s]", commit.Parents, head)
+	}
+	if commit.Author != "Bob <bob@example.com>" || strings.TrimSpace(commit.Message) != "add helper" {
+		t.Fatalf("imported commit = %+v", commit)
+	}
+	files, err := r.FlattenTree(commit.TreeHash)
+	if err != nil {
+		t.Fatalf("FlattenTree: %v", err)
+	}
+	var util *TreeFileEntry
+	for i := range files {
+		if files[i].Path == "util.go" {
+			util = &files[i]
+		}
+	}
+	if util == nil || util.EntityListHash == "" {
+		t.Fatalf("util.go missing or has no regenerated entity list: %+v", files)
+	}
+
+	// Re-fetching imports nothing and pushing the imported tip maps back to
+	// the same git commit.
+	again, err := r.FetchFromGit(ctx, "origin", remoteDir, "main")
+	if err != nil || again.ImportedCommits != 0 || again.Tip != res.Tip {
+		t.Fatalf("second fetch = %+v, %v", again, err)
+	}
+	gitTip, err := r.PushToGit(ctx, remoteDir, res.Tip, "heads/main", false, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("PushToGit(imported tip): %v", err)
+	}
+	if want := gitOutput(t, remoteDir, "rev-parse", "main"); gitTip != want {
+		t.Fatalf("re-exported tip = %s, want %s", gitTip, want)
+	}
+}
+
+func TestGitInteropPushBuildsOnExportedHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadowWriteFile(t, r.RootDir, "keep.txt", "unchanged\n")
+	shadowWriteFile(t, r.RootDir, "edit.txt", "v1\n")
+	if err := r.Add([]string{"keep.txt", "edit.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	first, err := r.Commit("first commit", "Alice <alice@example.com>")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	gitOutput(t, t.TempDir(), "init", "--bare", "-b", "main", remoteDir)
+	gitFirst, err := r.PushToGit(ctx, remoteDir, first, "heads/main", false, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("PushToGit: %v", err)
+	}
+
+	// The second push refers to the first commit and keep.txt by git hash.
+	shadowWriteFile(t, r.RootDir, "edit.txt", "v2\n")
+	if err := r.Add([]string{"edit.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	second, err := r.Commit("second commit", "Alice <alice@example.com>")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	gitSecond, err := r.PushToGit(ctx, remoteDir, second, "heads/main", false, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("PushToGit: %v", err)
+	}
+	if got := gitOutput(t, remoteDir, "rev-parse", gitSecond+"^"); got != gitFirst {
+		t.Fatalf("parent of pushed commit = %s, want %s", got, gitFirst)
+	}
+	if got := gitOutput(t, remoteDir, "ls-tree", "--name-only", gitSecond); got != "edit.txt\nkeep.txt" {
+		t.Fatalf("pushed tree = %q", got)
+	}
+	if got := gitOutput(t, remoteDir, "show", gitSecond+":edit.txt"); got != "v2" {
+		t.Fatalf("pushed edit.txt = %q", got)
+	}
+}
+
+func TestParseGitIdentAndFormat(t *testing.T) {
+	author, ts, tz := parseGitIdent("Carol Example <carol@example.com> 1700000000 -0500")
+	if author != "Carol Example <carol@example.com>" || ts != 1700000000 || tz != "-0500" {
+		t.Fatalf("parseGitIdent = %q %d %q", author, ts, tz)
+	}
+	if got := gitIdent(author, ts, tz); got != "Carol Example <carol@example.com> 1700000000 -0500" {
+		t.Fatalf("gitIdent = %q", got)
+	}
+	if got := gitIdent("tester", 5, ""); got != "tester <> 5 +0000" {
+		t.Fatalf("gitIdent(bare name) = %q", got)
+	}
+}
@@ -0,0 +1,564 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/lockfile"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// gitObjectDB reads objects and refs straight from a git repository's
+// directory, without running git: loose objects, packfiles through their
+// version 2 indexes, loose refs and packed-refs. New objects and refs are
+// written loose. Only SHA-1 repositories are supported.
+type gitObjectDB struct {
+	gitDir     string // per-worktree directory holding HEAD
+	commonDir  string // directory holding objects and refs
+	objectDirs []string
+	packs      []*gitPack
+
+	// Recently resolved packed objects by pack and offset, so delta chains
+	// sharing a base do not inflate it again.
+	cache      map[gitPackPos]gitObject
+	cacheBytes int
+}
+
+type gitObject struct {
+	typ  object.ObjectType
+	data []byte
+}
+
+type gitPackPos struct {
+	pack   *gitPack
+	offset int64
+}
+
+// gitPack is an open packfile and its parsed index.
+type gitPack struct {
+	file    *os.File
+	size    int64
+	fanout  [256]uint32
+	names   []byte // sorted 20-byte object names
+	offsets []int64
+}
+
+const (
+	gitObjectCacheLimit  = 64 << 20
+	gitPackIndexV2Header = "\xfftOc\x00\x00\x00\x02"
+)
+
+// openGitObjectDB opens the git repository at path: a work tree with a .git
+// directory or file, or a git directory itself.
+func openGitObjectDB(path string) (*gitObjectDB, error) {
+	gitDir, err := findGitDir(path)
+	if err != nil {
+		return nil, err
+	}
+	db := &gitObjectDB{gitDir: gitDir, commonDir: gitDir, cache: make(map[gitPackPos]gitObject)}
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		db.commonDir = filepath.Clean(common)
+	}
+	if cfg, err := os.ReadFile(filepath.Join(db.commonDir, "config")); err == nil && bytes.Contains(bytes.ToLower(cfg), []byte("objectformat = sha256")) {
+		return nil, fmt.Errorf("%s: SHA-256 git repositories are not supported", path)
+	}
+
+	objectsDir := filepath.Join(db.commonDir, "objects")
+	db.objectDirs = []string{objectsDir}
+	if data, err := os.ReadFile(filepath.Join(objectsDir, "info", "alternates")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(objectsDir, line)
+			}
+			db.objectDirs = append(db.objectDirs, filepath.Clean(line))
+		}
+	}
+	for _, dir := range db.objectDirs {
+		idxPaths, _ := filepath.Glob(filepath.Join(dir, "pack", "*.idx"))
+		sort.Strings(idxPaths)
+		for _, idxPath := range idxPaths {
+			pack, err := openGitPack(idxPath)
+			if err != nil {
+				db.Close()
+				return nil, err
+			}
+			db.packs = append(db.packs, pack)
+		}
+	}
+	return db, nil
+}
+
+// findGitDir locates the git directory for path.
+func findGitDir(path string) (string, error) {
+	dotGit := filepath.Join(path, ".git")
+	info, err := os.Stat(dotGit)
+	switch {
+	case err == nil && info.IsDir():
+		return dotGit, nil
+	case err == nil:
+		data, err := os.ReadFile(dotGit)
+		if err != nil {
+			return "", err
+		}
+		target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+		if !ok {
+			return "", fmt.Errorf("%s: not a gitdir file", dotGit)
+		}
+		target = strings.TrimSpace(target)
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(path, target)
+		}
+		return filepath.Clean(target), nil
+	}
+	if _, err := os.Stat(filepath.Join(path, "HEAD")); err == nil {
+		if _, err := os.Stat(filepath.Join(path, "objects")); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s: not a git repository", path)
+}
+
+// Close closes the packfiles.
+func (db *gitObjectDB) Close() error {
+	for _, p := range db.packs {
+		p.file.Close()
+	}
+	return nil
+}
+
+// head returns the branch HEAD names, or "" when HEAD is detached.
+func (db *gitObjectDB) head() string {
+	data, err := os.ReadFile(filepath.Join(db.gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref, _ := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: ")
+	if !strings.HasPrefix(ref, "refs/") {
+		return ""
+	}
+	return ref
+}
+
+// refs returns every ref under refs/ by full name, loose refs taking
+// precedence over packed ones. Symbolic refs are left out.
+func (db *gitObjectDB) refs() (map[string]string, error) {
+	refs := make(map[string]string)
+	if data, err := os.ReadFile(filepath.Join(db.commonDir, "packed-refs")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line == "" || line[0] == '#' || line[0] == '^' {
+				continue
+			}
+			hash, name, ok := strings.Cut(strings.TrimSpace(line), " ")
+			if ok && isGitHash(hash) {
+				refs[name] = hash
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read packed-refs: %w", err)
+	}
+
+	refsDir := filepath.Join(db.commonDir, "refs")
+	err := filepath.WalkDir(refsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		value := strings.TrimSpace(string(data))
+		if !isGitHash(value) {
+			return nil
+		}
+		rel, err := filepath.Rel(db.commonDir, p)
+		if err != nil {
+			return err
+		}
+		refs[filepath.ToSlash(rel)] = value
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read refs: %w", err)
+	}
+	return refs, nil
+}
+
+func isGitHash(s string) bool {
+	if len(s) != 2*object.GitHashSize {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// read returns the type and content of the object named by a hex hash.
+func (db *gitObjectDB) read(hash string) (object.ObjectType, []byte, error) {
+	name, err := hex.DecodeString(hash)
+	if err != nil || len(name) != object.GitHashSize {
+		return "", nil, fmt.Errorf("invalid git hash %q", hash)
+	}
+	for _, p := range db.packs {
+		if offset, ok := p.find(name); ok {
+			obj, err := db.readPacked(p, offset)
+			if err != nil {
+				return "", nil, fmt.Errorf("git object %s: %w", hash, err)
+			}
+			return obj.typ, obj.data, nil
+		}
+	}
+	for _, dir := range db.objectDirs {
+		typ, data, err := readLooseGitObject(filepath.Join(dir, hash[:2], hash[2:]))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("git object %s: %w", hash, err)
+		}
+		return typ, data, nil
+	}
+	return "", nil, fmt.Errorf("git object %s: %w", hash, os.ErrNotExist)
+}
+
+// readType reads an object and checks its type.
+func (db *gitObjectDB) readType(hash string, want object.ObjectType) ([]byte, error) {
+	typ, data, err := db.read(hash)
+	if err != nil {
+		return nil, err
+	}
+	if typ != want {
+		return nil, fmt.Errorf("git object %s is a %s, not a %s", hash, typ, want)
+	}
+	return data, nil
+}
+
+// has reports whether the object named by a hex hash exists.
+func (db *gitObjectDB) has(hash string) bool {
+	name, err := hex.DecodeString(hash)
+	if err != nil || len(name) != object.GitHashSize {
+		return false
+	}
+	for _, p := range db.packs {
+		if _, ok := p.find(name); ok {
+			return true
+		}
+	}
+	for _, dir := range db.objectDirs {
+		if _, err := os.Stat(filepath.Join(dir, hash[:2], hash[2:])); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// write stores an object loose unless it already exists and returns its
+// hash.
+func (db *gitObjectDB) write(typ object.ObjectType, data []byte) (string, error) {
+	hash := object.GitObjectHash(typ, data)
+	if db.has(hash) {
+		return hash, nil
+	}
+	dir := filepath.Join(db.objectDirs[0], hash[:2])
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, "tmp_obj_")
+	if err != nil {
+		return "", err
+	}
+	zw := zlib.NewWriter(tmp)
+	io.WriteString(zw, object.GitObjectHeader(typ, len(data)))
+	zw.Write(data)
+	err = zw.Close()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, hash[2:]))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("write git object %s: %w", hash, err)
+	}
+	return hash, nil
+}
+
+// updateRef points a ref at hash with a loose ref file, which takes
+// precedence over any packed value. The ref is locked the way git locks it,
+// and the update fails with ErrRefCASMismatch unless the ref still holds
+// old ("" for a ref that must not exist).
+func (db *gitObjectDB) updateRef(name, hash, old string) error {
+	path := filepath.Join(db.commonDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	lock, err := lockfile.Acquire(path, refLockWaitLimit)
+	if err != nil {
+		return fmt.Errorf("update %s: %w", name, err)
+	}
+	defer lock.Release()
+	current, err := db.refValue(name)
+	if err != nil {
+		return fmt.Errorf("update %s: %w", name, err)
+	}
+	if current != old {
+		return fmt.Errorf("update %s: %w", name, ErrRefCASMismatch)
+	}
+	if _, err := lock.Write([]byte(hash + "\n")); err != nil {
+		return fmt.Errorf("update %s: %w", name, err)
+	}
+	return lock.Commit()
+}
+
+// refValue returns the hash a ref holds, or "" when it does not exist.
+func (db *gitObjectDB) refValue(name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(db.commonDir, filepath.FromSlash(name)))
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	packed, err := os.ReadFile(filepath.Join(db.commonDir, "packed-refs"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(packed), "\n") {
+		if hash, ref, ok := strings.Cut(strings.TrimSpace(line), " "); ok && ref == name && isGitHash(hash) {
+			return hash, nil
+		}
+	}
+	return "", nil
+}
+
+// setHead points HEAD at a branch.
+func (db *gitObjectDB) setHead(branch string) error {
+	return os.WriteFile(filepath.Join(db.gitDir, "HEAD"), []byte("ref: "+branch+"\n"), 0o644)
+}
+
+// initBareGitDir lays out an empty bare git repository at dir, whose HEAD
+// names refs/heads/main until something else is checked out.
+func initBareGitDir(dir string) error {
+	for _, sub := range []string{"objects/info", "objects/pack", "refs/heads", "refs/tags"} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(sub)), 0o755); err != nil {
+			return err
+		}
+	}
+	config := "[core]\n\trepositoryformatversion = 0\n\tfilemode = true\n\tbare = true\n"
+	if err := os.WriteFile(filepath.Join(dir, "config"), []byte(config), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644)
+}
+
+func readLooseGitObject(path string) (object.ObjectType, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	zr, err := zlib.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return "", nil, err
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+	return object.ParseGitObject(raw)
+}
+
+// openGitPack reads a version 2 pack index and opens its pack.
+func openGitPack(idxPath string) (*gitPack, error) {
+	idx, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, fmt.Errorf("read pack index: %w", err)
+	}
+	if len(idx) < 8+256*4 || string(idx[:8]) != gitPackIndexV2Header {
+		return nil, fmt.Errorf("%s: unsupported pack index version", idxPath)
+	}
+	p := &gitPack{}
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(idx[8+4*i:])
+	}
+	n := int(p.fanout[255])
+	namesStart := 8 + 256*4
+	offsetsStart := namesStart + n*object.GitHashSize + n*4 // names, then CRCs
+	largeStart := offsetsStart + n*4
+	if len(idx) < largeStart {
+		return nil, fmt.Errorf("%s: truncated pack index", idxPath)
+	}
+	p.names = idx[namesStart : namesStart+n*object.GitHashSize]
+	p.offsets = make([]int64, n)
+	for i := range n {
+		off := binary.BigEndian.Uint32(idx[offsetsStart+4*i:])
+		if off&0x80000000 == 0 {
+			p.offsets[i] = int64(off)
+			continue
+		}
+		pos := largeStart + 8*int(off&0x7fffffff)
+		if pos+8 > len(idx) {
+			return nil, fmt.Errorf("%s: truncated large offset table", idxPath)
+		}
+		p.offsets[i] = int64(binary.BigEndian.Uint64(idx[pos:]))
+	}
+
+	packPath := strings.TrimSuffix(idxPath, ".idx") + ".pack"
+	f, err := os.Open(packPath)
+	if err != nil {
+		return nil, fmt.Errorf("open pack: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("open pack: %w", err)
+	}
+	p.file, p.size = f, info.Size()
+	return p, nil
+}
+
+// find returns the pack offset of the object with the given 20-byte name.
+func (p *gitPack) find(name []byte) (int64, bool) {
+	lo := 0
+	if name[0] > 0 {
+		lo = int(p.fanout[name[0]-1])
+	}
+	hi := int(p.fanout[name[0]])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.names[(lo+i)*object.GitHashSize:(lo+i+1)*object.GitHashSize], name) >= 0
+	})
+	if i < hi && bytes.Equal(p.names[i*object.GitHashSize:(i+1)*object.GitHashSize], name) {
+		return p.offsets[i], true
+	}
+	return 0, false
+}
+
+// readPacked reads the object at offset in p, resolving deltas.
+func (db *gitObjectDB) readPacked(p *gitPack, offset int64) (gitObject, error) {
+	pos := gitPackPos{pack: p, offset: offset}
+	if obj, ok := db.cache[pos]; ok {
+		return obj, nil
+	}
+
+	var header [32]byte
+	n, err := p.file.ReadAt(header[:], offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return gitObject{}, err
+	}
+	buf := header[:n]
+	if len(buf) == 0 {
+		return gitObject{}, fmt.Errorf("pack entry at %d: truncated", offset)
+	}
+	c := buf[0]
+	typ := object.PackObjectType((c >> 4) & 0x7)
+	size := uint64(c & 0x0f)
+	shift, i := uint(4), 1
+	for c&0x80 != 0 {
+		if i >= len(buf) {
+			return gitObject{}, fmt.Errorf("pack entry at %d: truncated header", offset)
+		}
+		c = buf[i]
+		i++
+		size |= uint64(c&0x7f) << shift
+		shift += 7
+	}
+
+	var base gitObject
+	switch typ {
+	case object.PackOfsDelta:
+		if i >= len(buf) {
+			return gitObject{}, fmt.Errorf("pack entry at %d: truncated delta offset", offset)
+		}
+		c = buf[i]
+		i++
+		dist := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if i >= len(buf) {
+				return gitObject{}, fmt.Errorf("pack entry at %d: truncated delta offset", offset)
+			}
+			c = buf[i]
+			i++
+			dist = ((dist + 1) << 7) | int64(c&0x7f)
+		}
+		if dist <= 0 || dist > offset {
+			return gitObject{}, fmt.Errorf("pack entry at %d: invalid delta base offset", offset)
+		}
+		if base, err = db.readPacked(p, offset-dist); err != nil {
+			return gitObject{}, err
+		}
+	case object.PackRefDelta:
+		if i+object.GitHashSize > len(buf) {
+			return gitObject{}, fmt.Errorf("pack entry at %d: truncated delta base", offset)
+		}
+		baseType, baseData, err := db.read(hex.EncodeToString(buf[i : i+object.GitHashSize]))
+		if err != nil {
+			return gitObject{}, err
+		}
+		base = gitObject{typ: baseType, data: baseData}
+		i += object.GitHashSize
+	}
+
+	start := offset + int64(i)
+	zr, err := zlib.NewReader(io.NewSectionReader(p.file, start, p.size-start))
+	if err != nil {
+		return gitObject{}, fmt.Errorf("pack entry at %d: %w", offset, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(zr, int64(size)+1))
+	zr.Close()
+	if err != nil {
+		return gitObject{}, fmt.Errorf("pack entry at %d: %w", offset, err)
+	}
+	if uint64(len(data)) != size {
+		return gitObject{}, fmt.Errorf("pack entry at %d: size mismatch", offset)
+	}
+
+	var obj gitObject
+	switch typ {
+	case object.PackCommit:
+		obj = gitObject{typ: object.TypeCommit, data: data}
+	case object.PackTree:
+		obj = gitObject{typ: object.TypeTree, data: data}
+	case object.PackBlob:
+		obj = gitObject{typ: object.TypeBlob, data: data}
+	case object.PackTag:
+		obj = gitObject{typ: object.TypeTag, data: data}
+	case object.PackOfsDelta, object.PackRefDelta:
+		out, err := object.ApplyDelta(base.data, data)
+		if err != nil {
+			return gitObject{}, fmt.Errorf("pack entry at %d: %w", offset, err)
+		}
+		obj = gitObject{typ: base.typ, data: out}
+	default:
+		return gitObject{}, fmt.Errorf("pack entry at %d: unknown type %d", offset, typ)
+	}
+
+	if db.cacheBytes+len(obj.data) > gitObjectCacheLimit {
+		clear(db.cache)
+		db.cacheBytes = 0
+	}
+	db.cache[pos] = obj
+	db.cacheBytes += len(obj.data)
+	return obj, nil
+}
@@ -81,13 +81,20 @@ func (r *Repo) ClearShadowFailures() {
 	os.Remove(filepath.Join(r.GraftDir, shadowFailuresLog))
 }
 
+// shadowing reports whether graft operations should be mirrored into the
+// colocated .git.
+func (r *Repo) shadowing() bool {
+	return !r.gitSyncing && r.HasGitDir()
+}
+
 // --- Public shadow API ---
-// All methods are no-ops if HasGitDir() returns false.
+// All methods are no-ops if HasGitDir() returns false. With git sync on,
+// those that move refs export graft's history instead; see gitSyncShadow.
 
 // GitShadowStage stages the given paths via git add, batched in groups of
 // 500 to avoid argument length limits.
 func (r *Repo) GitShadowStage(paths []string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
 		return
 	}
 	for i := 0; i < len(paths); i += shadowBatchSize {
@@ -104,7 +111,11 @@ func (r *Repo) GitShadowStage(paths []string) {
 // If amend is true, --amend is added. The committer is always set to
 // graft/graft@noreply via environment variables.
 func (r *Repo) GitShadowCommit(message, author string, amend bool) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
+		return
+	}
+	if r.GitSyncEnabled() {
+		r.gitSyncShadow("git-sync:commit", false)
 		return
 	}
 	args := []string{"commit", "--allow-empty", "-m", message, "--author", author}
@@ -120,7 +131,11 @@ func (r *Repo) GitShadowCommit(message, author string, amend bool) {
 
 // GitShadowCreateBranch creates a new git branch.
 func (r *Repo) GitShadowCreateBranch(name string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
+		return
+	}
+	if r.GitSyncEnabled() {
+		r.gitSyncShadow("git-sync:create-branch", true)
 		return
 	}
 	r.gitShadow("git-shadow:create-branch", "branch", name)
@@ -128,7 +143,7 @@ func (r *Repo) GitShadowCreateBranch(name string) {
 
 // GitShadowDeleteBranch force-deletes a git branch.
 func (r *Repo) GitShadowDeleteBranch(name string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
 		return
 	}
 	r.gitShadow("git-shadow:delete-branch", "branch", "-D", name)
@@ -136,7 +151,11 @@ func (r *Repo) GitShadowDeleteBranch(name string) {
 
 // GitShadowCheckout checks out the given ref.
 func (r *Repo) GitShadowCheckout(ref string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
+		return
+	}
+	if r.GitSyncEnabled() {
+		r.gitSyncShadow("git-sync:checkout", false)
 		return
 	}
 	r.gitShadow("git-shadow:checkout", "checkout", ref)
@@ -144,7 +163,11 @@ func (r *Repo) GitShadowCheckout(ref string) {
 
 // GitShadowCreateTag creates a lightweight tag.
 func (r *Repo) GitShadowCreateTag(name string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
+		return
+	}
+	if r.GitSyncEnabled() {
+		r.gitSyncShadow("git-sync:create-tag", true)
 		return
 	}
 	r.gitShadow("git-shadow:create-tag", "tag", name)
@@ -152,7 +175,7 @@ func (r *Repo) GitShadowCreateTag(name string) {
 
 // GitShadowDeleteTag deletes a tag.
 func (r *Repo) GitShadowDeleteTag(name string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
 		return
 	}
 	r.gitShadow("git-shadow:delete-tag", "tag", "-d", name)
@@ -161,7 +184,11 @@ func (r *Repo) GitShadowDeleteTag(name string) {
 // GitShadowReset runs git reset with the given mode and target. If mode is
 // empty, no --<mode> flag is passed.
 func (r *Repo) GitShadowReset(mode, target string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
+		return
+	}
+	if r.GitSyncEnabled() {
+		r.gitSyncShadow("git-sync:reset", mode == "soft")
 		return
 	}
 	var args []string
@@ -175,7 +202,7 @@ func (r *Repo) GitShadowReset(mode, target string) {
 
 // GitShadowResetPaths runs git reset -- <paths>.
 func (r *Repo) GitShadowResetPaths(paths []string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
 		return
 	}
 	args := append([]string{"reset", "--"}, paths...)
@@ -184,7 +211,11 @@ func (r *Repo) GitShadowResetPaths(paths []string) {
 
 // GitShadowStash runs git stash <sub> where sub is "push", "pop", or "drop".
 func (r *Repo) GitShadowStash(sub string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
+		return
+	}
+	if r.GitSyncEnabled() {
+		r.gitSyncShadow("git-sync:stash", false)
 		return
 	}
 	r.gitShadow("git-shadow:stash", "stash", sub)
@@ -192,7 +223,7 @@ func (r *Repo) GitShadowStash(sub string) {
 
 // GitShadowRm runs git rm --cached -- <paths>.
 func (r *Repo) GitShadowRm(paths []string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
 		return
 	}
 	args := append([]string{"rm", "--cached", "--"}, paths...)
@@ -201,7 +232,11 @@ func (r *Repo) GitShadowRm(paths []string) {
 
 // GitShadowSyncSnapshot stages all files and creates a snapshot commit.
 func (r *Repo) GitShadowSyncSnapshot(message, author string) {
-	if !r.HasGitDir() {
+	if !r.shadowing() {
+		return
+	}
+	if r.GitSyncEnabled() {
+		r.gitSyncShadow("git-sync:snapshot", false)
 		return
 	}
 	r.gitShadow("git-shadow:sync-stage", "add", "-A", "--", ".")
@@ -0,0 +1,260 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// Git sync is an opt-in, stricter form of the git shadow. The shadow
+// replays graft operations as git commands, so the colocated .git gets
+// commits of its own that merely look like graft's. With core.gitSync set,
+// operations that move refs instead export graft's exact history into .git
+// with ExportGit, and git hooks import commits made with git back through
+// ImportGit. Both repositories then hold the same history under the same
+// branch names, and git can stay the system of record while graft's
+// structural merge is tried out.
+
+// gitSyncHooks are the git hooks that run after git moves HEAD or a branch.
+var gitSyncHooks = []string{"post-commit", "post-merge", "post-checkout", "post-rewrite"}
+
+// gitSyncHookMarker identifies hooks written by InstallGitSyncHooks.
+const gitSyncHookMarker = "# graft git-sync hook"
+
+// GitSyncEnabled reports whether core.gitSync is set and a colocated .git
+// exists.
+func (r *Repo) GitSyncEnabled() bool {
+	if !r.HasGitDir() {
+		return false
+	}
+	cfg, err := r.ReadConfig()
+	return err == nil && cfg.Core != nil && cfg.Core.GitSync
+}
+
+// SetGitSync sets or clears core.gitSync.
+func (r *Repo) SetGitSync(enabled bool) error {
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Core == nil {
+		cfg.Core = &CoreConfig{}
+	}
+	cfg.Core.GitSync = enabled
+	return r.WriteConfig(cfg)
+}
+
+// SyncToGit exports graft's branches and tags into the colocated .git and
+// points git's HEAD where graft's HEAD points. Git's index is left alone;
+// the caller decides whether it should follow.
+func (r *Repo) SyncToGit(ctx context.Context, force bool) (*ExportGitResult, error) {
+	if !r.HasGitDir() {
+		return nil, errors.New("git sync: no .git in the repository root")
+	}
+	res, err := r.ExportGit(ctx, r.RootDir, ExportGitOptions{Force: force})
+	if err != nil {
+		return nil, fmt.Errorf("git sync: %w", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("git sync: %w", err)
+	}
+	db, err := openGitObjectDB(r.RootDir)
+	if err != nil {
+		return nil, fmt.Errorf("git sync: %w", err)
+	}
+	defer db.Close()
+	if strings.HasPrefix(head, "refs/") {
+		err = db.setHead(head)
+	} else {
+		err = r.withGitInteropMap(func(hm *gitInteropMap) error {
+			gitHash, ok := hm.GraftToGit(object.Hash(head))
+			if !ok {
+				return fmt.Errorf("HEAD %s has not been exported", head)
+			}
+			return os.WriteFile(filepath.Join(db.gitDir, "HEAD"), []byte(gitHash+"\n"), 0o644)
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("git sync: %w", err)
+	}
+	return res, nil
+}
+
+// SyncFromGit imports git's branches and tags, points graft's HEAD where
+// git's HEAD points, and resets graft's index to it. The working tree, which
+// git has already updated, is not touched. Shadowing is off meanwhile, so
+// the import is not replayed into git.
+func (r *Repo) SyncFromGit(ctx context.Context, force bool) (*ImportGitResult, error) {
+	if !r.HasGitDir() {
+		return nil, errors.New("git sync: no .git in the repository root")
+	}
+	r.gitSyncing = true
+	defer func() { r.gitSyncing = false }()
+
+	oldHeadName, _ := r.Head()
+	oldHead, _ := r.ResolveRef("HEAD")
+	res, err := r.ImportGit(ctx, r.RootDir, ImportGitOptions{Force: force})
+	if err != nil {
+		return nil, fmt.Errorf("git sync: %w", err)
+	}
+	target, err := r.gitSyncHead(res.Head)
+	if err != nil || target == "" {
+		return res, err
+	}
+	// Leave the index alone when git's HEAD did not move, so staged changes
+	// survive a sync triggered by another branch.
+	if newHeadName, _ := r.Head(); target == oldHead && newHeadName == oldHeadName {
+		return res, nil
+	}
+	if err := r.ResetToCommit(target, ResetMixed); err != nil {
+		return nil, fmt.Errorf("git sync: %w", err)
+	}
+	return res, nil
+}
+
+// gitSyncHead points graft's HEAD at git's: the branch gitHead, or the
+// commit git's detached HEAD names. It returns the commit HEAD resolves to,
+// or "" when git's HEAD is unborn.
+func (r *Repo) gitSyncHead(gitHead string) (object.Hash, error) {
+	if gitHead != "" {
+		target, err := r.ResolveRef(gitHead)
+		if errors.Is(err, ErrRefNotFound) {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("git sync: %w", err)
+		}
+		if err := r.SetHeadSymbolic(gitHead); err != nil {
+			return "", fmt.Errorf("git sync: %w", err)
+		}
+		return target, nil
+	}
+	gitDir, err := findGitDir(r.RootDir)
+	if err != nil {
+		return "", fmt.Errorf("git sync: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("git sync: %w", err)
+	}
+	var target object.Hash
+	err = r.withGitInteropMap(func(hm *gitInteropMap) error {
+		var ok bool
+		gitHash := strings.TrimSpace(string(data))
+		if target, ok = hm.GitToGraft(gitHash); !ok {
+			return fmt.Errorf("git HEAD %s has not been imported", gitHash)
+		}
+		return nil
+	})
+	if err == nil {
+		err = r.setHeadDetached(target)
+	}
+	if err != nil {
+		return "", fmt.Errorf("git sync: %w", err)
+	}
+	return target, nil
+}
+
+// withGitInteropMap runs fn with the map of graft and git hashes open.
+func (r *Repo) withGitInteropMap(fn func(*gitInteropMap) error) error {
+	if err := os.MkdirAll(r.gitInteropDir(), 0o755); err != nil {
+		return err
+	}
+	hm, err := openGitInteropMap(filepath.Join(r.gitInteropDir(), gitInteropMapName))
+	if err != nil {
+		return err
+	}
+	defer hm.Close()
+	return fn(hm)
+}
+
+// gitSyncShadow replaces a shadowed git command that would move refs when
+// git sync is on: graft's history is exported instead and, unless keepIndex
+// is set, git's index is reset to the new HEAD. Failures are logged like
+// other shadow failures.
+func (r *Repo) gitSyncShadow(label string, keepIndex bool) {
+	if _, err := r.SyncToGit(context.Background(), true); err != nil {
+		r.logShadowFailure(label, nil, err)
+		return
+	}
+	if !keepIndex {
+		r.gitShadow(label, "reset", "-q")
+	}
+}
+
+// InstallGitSyncHooks writes the git hooks that run "graft git-sync
+// from-git" after git commits, merges, checkouts and rewrites. Hooks graft
+// did not write are left in place and reported in the error.
+func (r *Repo) InstallGitSyncHooks() error {
+	dir, err := r.gitHooksDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("git sync: %w", err)
+	}
+	var foreign []string
+	for _, name := range gitSyncHooks {
+		p := filepath.Join(dir, name)
+		if data, err := os.ReadFile(p); err == nil && !bytes.Contains(data, []byte(gitSyncHookMarker)) {
+			foreign = append(foreign, name)
+			continue
+		}
+		script := "#!/bin/sh\n" + gitSyncHookMarker + "; keeps .graft in step with git.\n" +
+			"exec graft git-sync from-git --hook\n"
+		if err := os.WriteFile(p, []byte(script), 0o755); err != nil {
+			return fmt.Errorf("git sync: %w", err)
+		}
+	}
+	if len(foreign) > 0 {
+		return fmt.Errorf("git sync: existing git hooks were kept: %s; add \"graft git-sync from-git --hook\" to them", strings.Join(foreign, ", "))
+	}
+	return nil
+}
+
+// RemoveGitSyncHooks deletes the hooks InstallGitSyncHooks wrote.
+func (r *Repo) RemoveGitSyncHooks() error {
+	dir, err := r.gitHooksDir()
+	if err != nil {
+		return err
+	}
+	for _, name := range gitSyncHooks {
+		p := filepath.Join(dir, name)
+		if data, err := os.ReadFile(p); err == nil && bytes.Contains(data, []byte(gitSyncHookMarker)) {
+			if err := os.Remove(p); err != nil {
+				return fmt.Errorf("git sync: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// gitHooksDir asks git where the colocated repository's hooks live, which
+// honors core.hooksPath.
+func (r *Repo) gitHooksDir() (string, error) {
+	var out bytes.Buffer
+	spec := ExternalProcessSpec{
+		Dir:    r.RootDir,
+		Path:   gitPath(),
+		Args:   []string{"rev-parse", "--git-path", "hooks"},
+		Stdout: &out,
+		Stderr: io.Discard,
+		Label:  "git-sync:hooks-path",
+	}
+	if err := RunExternalProcess(spec); err != nil {
+		return "", fmt.Errorf("git sync: locate hooks: %w", err)
+	}
+	dir := strings.TrimSpace(out.String())
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.RootDir, dir)
+	}
+	return dir, nil
+}
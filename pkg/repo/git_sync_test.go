@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitSyncRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	gitOutput(t, dir, "init", "-q", "-b", "main")
+	shadowWriteFile(t, dir, ".git/info/exclude", ".graft/\n")
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetGitSync(true); err != nil {
+		t.Fatalf("SetGitSync: %v", err)
+	}
+	if !r.GitSyncEnabled() {
+		t.Fatal("GitSyncEnabled = false after SetGitSync(true)")
+	}
+
+	// A graft commit lands in git as the same history, with a clean index.
+	shadowWriteFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	first, err := r.Commit("first", "Alice <alice@example.com>")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if r.HasShadowFailures() {
+		data, _ := os.ReadFile(filepath.Join(r.GraftDir, shadowFailuresLog))
+		t.Fatalf("shadow failures:\n%s", data)
+	}
+	if got := gitOutput(t, dir, "log", "--format=%s|%an <%ae>", "main"); got != "first|Alice <alice@example.com>" {
+		t.Fatalf("git log = %q", got)
+	}
+	if got := gitOutput(t, dir, "status", "--porcelain"); got != "" {
+		t.Fatalf("git status = %q, want clean", got)
+	}
+
+	// A git commit is imported and becomes graft's HEAD.
+	shadowWriteFile(t, dir, "util.go", "package main\n\nfunc helper() int { return 1 }\n")
+	gitOutput(t, dir, "add", "util.go")
+	gitOutput(t, dir, "-c", "user.name=Bob", "-c", "user.email=bob@example.com", "commit", "-q", "-m", "add helper")
+	gitTip := gitOutput(t, dir, "rev-parse", "main")
+	res, err := r.SyncFromGit(ctx, true)
+	if err != nil {
+		t.Fatalf("SyncFromGit: %v", err)
+	}
+	if res.Commits != 1 || len(res.UpdatedRefs) != 1 {
+		t.Fatalf("SyncFromGit = %+v, want 1 commit on main", res)
+	}
+	head, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	if c := mustReadCommit(t, r, head); len(c.Parents) != 1 || c.Parents[0] != first || c.Author != "Bob <bob@example.com>" {
+		t.Fatalf("graft HEAD = %+v, want Bob's commit on top of %s", c, first)
+	}
+	entries, err := r.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, e := range entries {
+		if e.Path == "util.go" && (e.IndexStatus != StatusClean || e.WorkStatus != StatusClean) {
+			t.Fatalf("util.go status = %+v, want clean after sync", e)
+		}
+	}
+
+	// Graft builds on the imported commit without rewriting it in git.
+	shadowWriteFile(t, dir, "main.go", "package main\n\nfunc main() { println(helper()) }\n")
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("use helper", "Alice <alice@example.com>"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := gitOutput(t, dir, "rev-parse", "main^"); got != gitTip {
+		t.Fatalf("git main^ = %s, want the git commit %s", got, gitTip)
+	}
+
+	if err := r.InstallGitSyncHooks(); err != nil {
+		t.Fatalf("InstallGitSyncHooks: %v", err)
+	}
+	hook, err := os.ReadFile(filepath.Join(dir, ".git", "hooks", "post-commit"))
+	if err != nil || !strings.Contains(string(hook), "graft git-sync from-git --hook") {
+		t.Fatalf("post-commit hook = %q, %v", hook, err)
+	}
+	if err := r.RemoveGitSyncHooks(); err != nil {
+		t.Fatalf("RemoveGitSyncHooks: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git", "hooks", "post-commit")); !os.IsNotExist(err) {
+		t.Fatalf("post-commit hook still present: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// GraphExportOptions controls ExportGraph.
+type GraphExportOptions struct {
+	// Revs selects the commits the way rev-list reads them. Empty means
+	// every commit reachable from HEAD, a branch or a tag.
+	Revs []string
+
+	// MaxCount stops after that many commits. Zero means no limit.
+	MaxCount int
+
+	// Entities records the declarations each commit created, modified or
+	// deleted relative to its first parent.
+	Entities bool
+}
+
+// GraphCommit is one node of an exported commit graph. Parents may name
+// commits outside the export when the revisions exclude them.
+type GraphCommit struct {
+	Hash      object.Hash
+	Parents   []object.Hash
+	Author    string
+	Timestamp int64
+	Subject   string
+	Entities  []ReflogEntityChange
+}
+
+// ExportGraph lists the commits opts selects in topological order, newest
+// first, for rendering the history as a graph.
+func (r *Repo) ExportGraph(opts GraphExportOptions) ([]GraphCommit, error) {
+	var rr *RevRange
+	if len(opts.Revs) > 0 {
+		var err error
+		if rr, err = r.ParseRevRange(opts.Revs); err != nil {
+			return nil, err
+		}
+	} else {
+		tips, err := r.refTips()
+		if err != nil {
+			return nil, fmt.Errorf("graph export: %w", err)
+		}
+		rr = &RevRange{Include: tips}
+	}
+	hashes, err := r.RevList(rr, RevListOptions{MaxCount: opts.MaxCount, TopoOrder: true})
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]GraphCommit, 0, len(hashes))
+	for _, h := range hashes {
+		c, err := r.Store.ReadCommit(h)
+		if err != nil {
+			return nil, fmt.Errorf("graph export: read commit %s: %w", h, err)
+		}
+		gc := GraphCommit{
+			Hash:      h,
+			Parents:   c.Parents,
+			Author:    c.Author,
+			Timestamp: c.Timestamp,
+			Subject:   commitTitle(c.Message),
+		}
+		if opts.Entities {
+			if gc.Entities, err = r.graphEntityChanges(c); err != nil {
+				return nil, fmt.Errorf("graph export: commit %s: %w", h, err)
+			}
+		}
+		commits = append(commits, gc)
+	}
+	return commits, nil
+}
+
+// refTips returns the commits HEAD, the branches and the tags point at.
+func (r *Repo) refTips() ([]object.Hash, error) {
+	seen := make(map[object.Hash]bool)
+	var tips []object.Hash
+	add := func(h object.Hash) error {
+		c, err := r.peelToCommit(h)
+		if err != nil {
+			return err
+		}
+		if !seen[c] {
+			seen[c] = true
+			tips = append(tips, c)
+		}
+		return nil
+	}
+
+	if head, err := r.ResolveRef("HEAD"); err == nil {
+		if err := add(head); err != nil {
+			return nil, fmt.Errorf("HEAD: %w", err)
+		}
+	}
+	for _, prefix := range []string{"heads", "tags"} {
+		refs, err := r.ListRefs(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", prefix, err)
+		}
+		names := make([]string, 0, len(refs))
+		for name := range refs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := add(refs[name]); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return tips, nil
+}
+
+// graphEntityChanges lists the declarations c changed relative to its
+// first parent, ordered by path and key.
+func (r *Repo) graphEntityChanges(c *object.CommitObj) ([]ReflogEntityChange, error) {
+	after, err := r.treeEntriesByPath(c.TreeHash)
+	if err != nil {
+		return nil, err
+	}
+	before, err := r.firstParentEntries(c)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := diffEntryEntities(r, before, after)
+	if err != nil {
+		return nil, err
+	}
+	decls := changes[:0]
+	for _, ch := range changes {
+		if strings.HasPrefix(ch.EntityKey, "declaration:") {
+			decls = append(decls, ch)
+		}
+	}
+	sort.Slice(decls, func(i, j int) bool {
+		if decls[i].Path != decls[j].Path {
+			return decls[i].Path < decls[j].Path
+		}
+		return decls[i].EntityKey < decls[j].EntityKey
+	})
+	return decls, nil
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestExportGraph_AllRefsAndEntityChanges(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	c1 := commitFile(t, r, "main.go", []byte("package main\n\nfunc Load() {}\n"), "add load")
+	c2 := commitFile(t, r, "main.go", []byte("package main\n\nfunc Load() { run() }\n\nfunc Save() {}\n"), "add save")
+	c3 := commitFile(t, r, "notes.txt", []byte("notes\n"), "notes")
+
+	// Only the feature branch reaches c3 once HEAD's branch moves back.
+	if err := r.CreateBranch("feature", c3); err != nil {
+		t.Fatalf("CreateBranch: %v", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if err := r.UpdateRef(head, c2); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+
+	commits, err := r.ExportGraph(GraphExportOptions{Entities: true})
+	if err != nil {
+		t.Fatalf("ExportGraph: %v", err)
+	}
+	want := []object.Hash{c3, c2, c1}
+	if len(commits) != len(want) {
+		t.Fatalf("ExportGraph returned %d commits, want %d", len(commits), len(want))
+	}
+	for i, h := range want {
+		if commits[i].Hash != h {
+			t.Fatalf("commit %d = %s, want %s", i, commits[i].Hash, h)
+		}
+	}
+	if len(commits[0].Parents) != 1 || commits[0].Parents[0] != c2 || commits[0].Subject != "notes" {
+		t.Fatalf("head commit = %+v", commits[0])
+	}
+	if len(commits[0].Entities) != 0 {
+		t.Fatalf("notes commit entities = %+v, want none", commits[0].Entities)
+	}
+
+	changes := commits[1].Entities
+	if len(changes) != 2 ||
+		changes[0].EntityKey != "declaration:Load" || changes[0].ChangeType != "modify" ||
+		changes[1].EntityKey != "declaration:Save" || changes[1].ChangeType != "create" {
+		t.Fatalf("add save entities = %+v", changes)
+	}
+	if root := commits[2].Entities; len(root) != 1 || root[0].ChangeType != "create" {
+		t.Fatalf("root commit entities = %+v", root)
+	}
+
+	ranged, err := r.ExportGraph(GraphExportOptions{Revs: []string{string(c1) + ".." + string(c3)}, MaxCount: 1})
+	if err != nil {
+		t.Fatalf("ExportGraph(range): %v", err)
+	}
+	if len(ranged) != 1 || ranged[0].Hash != c3 || ranged[0].Entities != nil {
+		t.Fatalf("ExportGraph(range) = %+v", ranged)
+	}
+}
@@ -70,8 +70,7 @@ func (r *Repo) Grep(opts GrepOptions) ([]GrepResult, error) {
 			}
 		}
 
-		absPath := filepath.Join(r.RootDir, filepath.FromSlash(p))
-		data, err := os.ReadFile(absPath)
+		data, err := r.ReadWorktreeFile(p)
 		if err != nil {
 			if os.IsNotExist(err) {
 				continue // file deleted from disk but still tracked
@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"github.com/odvcencio/gotreesitter/grammars"
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// HashedEntity is one entity of a file hashed by HashObject.
+type HashedEntity struct {
+	Hash     object.Hash
+	Kind     string
+	Name     string
+	DeclKind string
+	Receiver string
+}
+
+// HashedObject holds the object hashes Add would record for a file.
+type HashedObject struct {
+	Blob object.Hash
+	// EntityList is empty, and Entities nil, for content Add stores
+	// without entities: binary or oversized data, an unsupported
+	// language, or source without extractable entities.
+	EntityList object.Hash
+	Entities   []HashedEntity
+}
+
+// HashObject computes the blob hash of content and, when entities is set,
+// the entity and entity list hashes Add would store for it at relPath; the
+// path selects the language and is part of the entity list. Content is
+// hashed as given, without line-ending or LFS conversion. A non-nil store
+// receives the objects; a nil one leaves them unwritten.
+func HashObject(store *object.Store, relPath string, content []byte, entities bool) (*HashedObject, error) {
+	blobHash, err := hashOrWrite(store, object.TypeBlob, content)
+	if err != nil {
+		return nil, err
+	}
+	out := &HashedObject{Blob: blobHash}
+	if !entities {
+		return out, nil
+	}
+
+	langEntry := grammars.DetectLanguage(relPath)
+	if langEntry == nil {
+		return out, nil
+	}
+	if len(content) == 0 || isBinaryContent(content) || int64(len(content)) > maxEntityExtractionSize {
+		return out, nil
+	}
+	if entity.ShouldSkipExtraction(langEntry.Name, int64(len(content)), false) {
+		return out, nil
+	}
+	el, err := entity.ExtractWithOptions(relPath, content, entity.ExtractOptions{})
+	if err != nil || len(el.Entities) == 0 {
+		return out, nil
+	}
+
+	refs := make([]object.Hash, 0, len(el.Entities))
+	for i := range el.Entities {
+		entObj := entityObject(&el.Entities[i])
+		h, err := hashOrWrite(store, object.TypeEntity, object.MarshalEntity(entObj))
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, h)
+		out.Entities = append(out.Entities, HashedEntity{
+			Hash:     h,
+			Kind:     entObj.Kind,
+			Name:     entObj.Name,
+			DeclKind: entObj.DeclKind,
+			Receiver: entObj.Receiver,
+		})
+	}
+	out.EntityList, err = hashOrWrite(store, object.TypeEntityList, object.MarshalEntityList(&object.EntityListObj{
+		Language:   el.Language,
+		Path:       relPath,
+		EntityRefs: refs,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// hashOrWrite writes an object to store, or only hashes it if store is nil.
+func hashOrWrite(store *object.Store, objType object.ObjectType, data []byte) (object.Hash, error) {
+	if store == nil {
+		return object.HashObject(objType, data), nil
+	}
+	return store.Write(objType, data)
+}
@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestHashObject_MatchesAdd(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	src := []byte("package main\n\nfunc hello() {\n\tprintln(\"hello\")\n}\n")
+
+	// Hashing without a store writes nothing.
+	hashed, err := HashObject(nil, "main.go", src, true)
+	if err != nil {
+		t.Fatalf("HashObject: %v", err)
+	}
+	if r.Store.Has(hashed.Blob) || r.Store.Has(hashed.EntityList) {
+		t.Fatal("HashObject with a nil store wrote objects")
+	}
+	var names []string
+	for _, e := range hashed.Entities {
+		if e.Name != "" {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) != 1 || names[0] != "hello" {
+		t.Fatalf("named entities = %v, want [hello]", names)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), src, 0o644); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	entry := stg.Entries["main.go"]
+	if entry == nil || entry.BlobHash != hashed.Blob || entry.EntityListHash != hashed.EntityList {
+		t.Fatalf("staged %+v, want blob %s and entity list %s", entry, hashed.Blob, hashed.EntityList)
+	}
+
+	// Another path changes the entity list but not the blob; plain text
+	// has no entities.
+	moved, err := HashObject(nil, "cmd/main.go", src, true)
+	if err != nil {
+		t.Fatalf("HashObject: %v", err)
+	}
+	if moved.Blob != hashed.Blob || moved.EntityList == hashed.EntityList {
+		t.Fatalf("HashObject at another path = %+v, want the same blob and a different entity list", moved)
+	}
+	text, err := HashObject(nil, "notes.txt", []byte("notes\n"), true)
+	if err != nil {
+		t.Fatalf("HashObject: %v", err)
+	}
+	if text.EntityList != "" || len(text.Entities) != 0 {
+		t.Fatalf("HashObject of plain text = %+v, want no entities", text)
+	}
+}
+
+func TestHashObject_WritesToStore(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	hashed, err := HashObject(r.Store, "main.go", []byte("package main\n\nfunc A() {}\n"), true)
+	if err != nil {
+		t.Fatalf("HashObject: %v", err)
+	}
+	if hashed.EntityList == "" {
+		t.Fatal("HashObject extracted no entities")
+	}
+	for _, h := range []object.Hash{hashed.Blob, hashed.EntityList, hashed.Entities[0].Hash} {
+		if !r.Store.Has(h) {
+			t.Fatalf("object %s not written", h)
+		}
+	}
+	el, err := r.Store.ReadEntityList(hashed.EntityList)
+	if err != nil {
+		t.Fatalf("ReadEntityList: %v", err)
+	}
+	if el.Path != "main.go" || len(el.EntityRefs) != len(hashed.Entities) {
+		t.Fatalf("entity list = %+v, want path main.go with %d entities", el, len(hashed.Entities))
+	}
+}
@@ -3,17 +3,49 @@ package repo
 import (
 	"bufio"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+
+	"github.com/odvcencio/graft/pkg/pathspec"
+	"github.com/odvcencio/graft/pkg/userconfig"
 )
 
+// ignoreFileNames lists the per-directory ignore files in order of
+// preference; only the first one present in a directory is read.
+var ignoreFileNames = []string{".graftignore", ".gotignore", ".gitignore"}
+
 // IgnoreChecker determines if a path should be ignored.
+//
+// Rules follow gitignore semantics. They come from, in increasing order of
+// precedence: the builtin patterns, the user-wide ignore file (see
+// userconfig.Config.GlobalIgnorePath), the repository root ignore file,
+// module paths from .graftmodules, and the ignore files of subdirectories,
+// whose patterns are relative to their directory and override those of
+// their parents. Within that order the last matching pattern wins, and a
+// path inside an ignored directory stays ignored whatever later negations
+// say.
 type IgnoreChecker struct {
+	ignoreRules // rules that apply from the repository root
+
+	root string
+
+	// nested caches the rules of subdirectory ignore files by
+	// repo-relative directory; a nil value means the directory has none.
+	nested sync.Map // string -> *ignoreRules
+	// dirs caches the ignore decision of directories, so each ancestor of
+	// a walked path is evaluated once.
+	dirs sync.Map // string -> bool
+}
+
+// ignoreRules is an ordered set of patterns from one directory, indexed so
+// that IsIgnored avoids matching every pattern.
+type ignoreRules struct {
 	patterns []ignorePattern
 
 	// Precompiled/indexed pattern groups used by IsIgnored fast paths.
-	dirPrefixPatterns    map[string][]int
 	exactBasePatterns    map[string][]int
 	exactPathPatterns    map[string][]int
 	wildcardBaseNoPrefix []int
@@ -35,12 +67,14 @@ type ignorePattern struct {
 }
 
 // NewIgnoreChecker creates an IgnoreChecker for the given repository root.
-// It always ignores .graft/, .got/, .git/, and .gts/. Patterns are loaded from the
-// first ignore file found: .graftignore, then .gotignore (legacy), then
-// .gitignore (fallback so that projects without a graft-specific ignore file
-// still respect their git ignore rules).
+// It always ignores .graft/, .got/, .git/, and .gts/. Each directory's
+// patterns are loaded from the first ignore file found in it: .graftignore,
+// then .gotignore (legacy), then .gitignore (fallback so that projects
+// without a graft-specific ignore file still respect their git ignore
+// rules). Subdirectory ignore files are read the first time a path below
+// them is checked.
 func NewIgnoreChecker(repoRoot string) *IgnoreChecker {
-	ic := &IgnoreChecker{}
+	ic := &IgnoreChecker{root: repoRoot}
 
 	// Hardcoded patterns: always ignore .graft/, .got/, .git/, and .gts/.
 	ic.patterns = append(ic.patterns,
@@ -50,30 +84,15 @@ func NewIgnoreChecker(repoRoot string) *IgnoreChecker {
 		ignorePattern{pattern: ".gts", original: ".gts", source: "builtin", dirOnly: false, hasSlash: false},
 	)
 
-	// Read .graftignore if it exists, falling back to .gotignore, then .gitignore.
-	ignorePath := filepath.Join(repoRoot, ".graftignore")
-	if _, err := os.Stat(ignorePath); os.IsNotExist(err) {
-		ignorePath = filepath.Join(repoRoot, ".gotignore")
-		if _, err := os.Stat(ignorePath); os.IsNotExist(err) {
-			ignorePath = filepath.Join(repoRoot, ".gitignore")
-		}
-	}
-	f, err := os.Open(ignorePath)
-	if err == nil {
-		defer f.Close()
-
-		scanner := bufio.NewScanner(f)
-		lineNo := 0
-		for scanner.Scan() {
-			line := scanner.Text()
-			lineNo++
-			p := parseLineFromSource(line, filepath.Base(ignorePath), lineNo)
-			if p != nil {
-				ic.patterns = append(ic.patterns, *p)
-			}
+	// The user-wide ignore file applies before the repository's own rules.
+	if ucfg, err := userconfig.Load(); err == nil {
+		if global := ucfg.GlobalIgnorePath(); global != "" {
+			ic.patterns = append(ic.patterns, readIgnoreFile(global, global)...)
 		}
 	}
 
+	ic.patterns = append(ic.patterns, readDirIgnoreFile(repoRoot, "")...)
+
 	// Auto-ignore module working tree paths from .graftmodules.
 	if mf, err := os.Open(filepath.Join(repoRoot, ".graftmodules")); err == nil {
 		defer mf.Close()
@@ -96,6 +115,41 @@ func NewIgnoreChecker(repoRoot string) *IgnoreChecker {
 	return ic
 }
 
+// readDirIgnoreFile reads the first ignore file present in dir, which is
+// relDir relative to the repository root. Pattern sources are repo-relative
+// file paths.
+func readDirIgnoreFile(dir, relDir string) []ignorePattern {
+	for _, name := range ignoreFileNames {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		return readIgnoreFile(file, path.Join(relDir, name))
+	}
+	return nil
+}
+
+// readIgnoreFile parses the ignore file at file, recording source as the
+// origin of its patterns. A missing or unreadable file has no patterns.
+func readIgnoreFile(file, source string) []ignorePattern {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if p := parseLineFromSource(scanner.Text(), source, lineNo); p != nil {
+			patterns = append(patterns, *p)
+		}
+	}
+	return patterns
+}
+
 // parseLine parses a single line from a .gotignore file. Returns nil if the
 // line is empty or a comment.
 func parseLine(line string) *ignorePattern {
@@ -105,8 +159,8 @@ func parseLine(line string) *ignorePattern {
 func parseLineFromSource(line, source string, lineNo int) *ignorePattern {
 	raw := line
 
-	// Trim trailing whitespace.
-	line = strings.TrimRight(line, " \t")
+	// Trim trailing whitespace unless it is escaped with a backslash.
+	line = trimIgnoreTrailingSpace(line)
 
 	// Empty lines are skipped.
 	if line == "" {
@@ -120,10 +174,13 @@ func parseLineFromSource(line, source string, lineNo int) *ignorePattern {
 
 	p := &ignorePattern{}
 
-	// Negation: lines starting with ! un-ignore a pattern.
+	// Negation: lines starting with ! un-ignore a pattern. A leading \!
+	// or \# stands for the literal character.
 	if strings.HasPrefix(line, "!") {
 		p.negated = true
 		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
 	}
 
 	// Directory-only: lines ending with / match directories only.
@@ -138,6 +195,9 @@ func parseLineFromSource(line, source string, lineNo int) *ignorePattern {
 		p.rooted = true
 		line = strings.TrimLeft(line, "/")
 	}
+	if line == "" {
+		return nil
+	}
 
 	// If the pattern contains a slash (after stripping leading /), match
 	// against the full relative path.
@@ -147,7 +207,9 @@ func parseLineFromSource(line, source string, lineNo int) *ignorePattern {
 	p.original = strings.TrimRight(raw, " \t")
 	p.source = source
 	p.line = lineNo
-	if strings.Contains(line, "**") {
+	// path.Match handles the rest; "**" and bracket expressions, which
+	// gitignore negates with "!" and which never match "/", need a regexp.
+	if strings.Contains(line, "**") || strings.Contains(line, "[") {
 		if re, err := regexp.Compile(globToRegex(line)); err == nil {
 			p.regex = re
 		}
@@ -155,21 +217,98 @@ func parseLineFromSource(line, source string, lineNo int) *ignorePattern {
 	return p
 }
 
+// trimIgnoreTrailingSpace removes trailing spaces and tabs, keeping a space
+// escaped as "\ " without its backslash.
+func trimIgnoreTrailingSpace(line string) string {
+	trimmed := strings.TrimRight(line, " \t")
+	if strings.HasSuffix(trimmed, `\`) && len(trimmed) < len(line) && line[len(trimmed)] == ' ' {
+		return trimmed[:len(trimmed)-1] + " "
+	}
+	return trimmed
+}
+
 // IsIgnored checks whether a relative path should be ignored. The path should
 // use forward slashes and be relative to the repository root.
 //
-// Last matching pattern wins (to support negation).
+// Last matching pattern wins (to support negation), but nothing below an
+// ignored directory can be re-included.
 func (ic *IgnoreChecker) IsIgnored(path string) bool {
 	// Normalise to forward slashes.
-	path = filepath.ToSlash(path)
+	path = strings.TrimSuffix(filepath.ToSlash(path), "/")
+
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && ic.isDirIgnored(path[:i]) {
+			return true
+		}
+	}
+	ignored, _ := ic.decide(path)
+	return ignored
+}
+
+// isPathIgnored reports whether path itself matches an ignore rule, without
+// considering its ancestor directories.
+func (ic *IgnoreChecker) isPathIgnored(path string) bool {
+	ignored, _ := ic.decide(strings.TrimSuffix(filepath.ToSlash(path), "/"))
+	return ignored
+}
+
+// isDirIgnored reports whether the directory dir matches an ignore rule,
+// without considering its ancestors.
+func (ic *IgnoreChecker) isDirIgnored(dir string) bool {
+	if v, ok := ic.dirs.Load(dir); ok {
+		return v.(bool)
+	}
+	ignored, _ := ic.decide(dir)
+	ic.dirs.Store(dir, ignored)
+	return ignored
+}
+
+// decide applies the root rules and then the rules of each ancestor
+// directory of path, shallowest first, and reports the outcome of the last
+// matching pattern.
+func (ic *IgnoreChecker) decide(path string) (ignored, matched bool) {
+	ignored, matched = ic.ignoreRules.decide(path)
+	for i := 0; i < len(path); i++ {
+		if path[i] != '/' {
+			continue
+		}
+		if rules := ic.dirRules(path[:i]); rules != nil {
+			if ig, ok := rules.decide(path[i+1:]); ok {
+				ignored, matched = ig, true
+			}
+		}
+	}
+	return ignored, matched
+}
+
+// dirRules returns the rules of the ignore file in the repo-relative
+// directory dir, or nil when it has none.
+func (ic *IgnoreChecker) dirRules(dir string) *ignoreRules {
+	if ic.root == "" {
+		return nil
+	}
+	if v, ok := ic.nested.Load(dir); ok {
+		return v.(*ignoreRules)
+	}
+	var rules *ignoreRules
+	if patterns := readDirIgnoreFile(filepath.Join(ic.root, filepath.FromSlash(dir)), dir); len(patterns) > 0 {
+		rules = &ignoreRules{patterns: patterns}
+		rules.compile()
+	}
+	v, _ := ic.nested.LoadOrStore(dir, rules)
+	return v.(*ignoreRules)
+}
+
+// decide reports whether the last pattern of rs matching path ignores it,
+// and whether any pattern matched at all.
+func (rs *ignoreRules) decide(path string) (ignored, matched bool) {
 	base := filepath.Base(path)
 
 	lastMatch := -1
-	ignored := false
 	apply := func(idx int) {
 		if idx > lastMatch {
 			lastMatch = idx
-			ignored = !ic.patterns[idx].negated
+			ignored = !rs.patterns[idx].negated
 		}
 	}
 	applyAll := func(patterns []int) {
@@ -178,116 +317,70 @@ func (ic *IgnoreChecker) IsIgnored(path string) bool {
 		}
 	}
 
-	// Directory-prefix patterns match the full path or any ancestor prefix.
-	if idxs, ok := ic.dirPrefixPatterns[path]; ok {
-		applyAll(idxs)
-	}
-	// Also check the basename of the full path for non-rooted dir patterns.
-	// This handles "frontend/.next" matching pattern ".next/".
-	if pathBase := filepath.Base(path); pathBase != path {
-		if idxs, ok := ic.dirPrefixPatterns[pathBase]; ok {
-			for _, idx := range idxs {
-				if !ic.patterns[idx].rooted {
-					apply(idx)
-				}
-			}
-		}
-	}
-	for i := 0; i < len(path); i++ {
-		if path[i] == '/' {
-			prefix := path[:i]
-			if idxs, ok := ic.dirPrefixPatterns[prefix]; ok {
-				applyAll(idxs)
-			}
-			// For non-rooted patterns (no leading /), also check the
-			// basename of each path segment. This allows patterns like
-			// ".next/" to match "frontend/.next/..." at any depth.
-			segment := filepath.Base(prefix)
-			if segment != prefix {
-				if idxs, ok := ic.dirPrefixPatterns[segment]; ok {
-					for _, idx := range idxs {
-						if !ic.patterns[idx].rooted {
-							apply(idx)
-						}
-					}
-				}
-			}
-		}
-	}
-
 	// Exact literals are resolved via maps.
-	if idxs, ok := ic.exactPathPatterns[path]; ok {
+	if idxs, ok := rs.exactPathPatterns[path]; ok {
 		applyAll(idxs)
 	}
-	if idxs, ok := ic.exactBasePatterns[base]; ok {
+	if idxs, ok := rs.exactBasePatterns[base]; ok {
 		applyAll(idxs)
 	}
 
 	// Wildcards still require matching checks, but most are narrowed by
 	// literal prefix buckets before glob matching.
-	ic.applyWildcardPatterns(path, ic.wildcardPathNoPrefix, ic.wildcardPathByPrefix, apply)
-	ic.applyWildcardPatterns(base, ic.wildcardBaseNoPrefix, ic.wildcardBaseByPrefix, apply)
+	rs.applyWildcardPatterns(path, rs.wildcardPathNoPrefix, rs.wildcardPathByPrefix, apply)
+	rs.applyWildcardPatterns(base, rs.wildcardBaseNoPrefix, rs.wildcardBaseByPrefix, apply)
 
-	return ignored
+	return ignored, lastMatch >= 0
 }
 
-func (ic *IgnoreChecker) compile() {
-	ic.dirPrefixPatterns = make(map[string][]int)
-	ic.exactBasePatterns = make(map[string][]int)
-	ic.exactPathPatterns = make(map[string][]int)
-	ic.wildcardBaseNoPrefix = nil
-	ic.wildcardPathNoPrefix = nil
-	ic.wildcardBaseByPrefix = make(map[string][]int)
-	ic.wildcardPathByPrefix = make(map[string][]int)
-
-	for idx := range ic.patterns {
-		p := ic.patterns[idx]
-
-		// Keep hardcoded .graft/.got/.git/.gts special prefix behavior.
-		if p.dirOnly || p.pattern == ".graft" || p.pattern == ".got" || p.pattern == ".git" || p.pattern == ".gts" {
-			ic.dirPrefixPatterns[p.pattern] = append(ic.dirPrefixPatterns[p.pattern], idx)
-			if p.dirOnly {
-				continue
-			}
-		}
+func (rs *ignoreRules) compile() {
+	rs.exactBasePatterns = make(map[string][]int)
+	rs.exactPathPatterns = make(map[string][]int)
+	rs.wildcardBaseNoPrefix = nil
+	rs.wildcardPathNoPrefix = nil
+	rs.wildcardBaseByPrefix = make(map[string][]int)
+	rs.wildcardPathByPrefix = make(map[string][]int)
+
+	for idx := range rs.patterns {
+		p := rs.patterns[idx]
 
 		switch {
 		case p.regex != nil:
-			ic.addWildcardPattern(idx)
+			rs.addWildcardPattern(idx)
 		case isLiteralPattern(p.pattern):
 			if p.hasSlash {
-				ic.exactPathPatterns[p.pattern] = append(ic.exactPathPatterns[p.pattern], idx)
+				rs.exactPathPatterns[p.pattern] = append(rs.exactPathPatterns[p.pattern], idx)
 			} else {
-				ic.exactBasePatterns[p.pattern] = append(ic.exactBasePatterns[p.pattern], idx)
+				rs.exactBasePatterns[p.pattern] = append(rs.exactBasePatterns[p.pattern], idx)
 			}
 		default:
-			ic.addWildcardPattern(idx)
+			rs.addWildcardPattern(idx)
 		}
 	}
 }
 
-func (ic *IgnoreChecker) addWildcardPattern(idx int) {
-	p := ic.patterns[idx]
+func (rs *ignoreRules) addWildcardPattern(idx int) {
+	p := rs.patterns[idx]
 	prefix := wildcardLiteralPrefix(p.pattern)
 	if prefix == "" {
 		if p.hasSlash {
-			ic.wildcardPathNoPrefix = append(ic.wildcardPathNoPrefix, idx)
+			rs.wildcardPathNoPrefix = append(rs.wildcardPathNoPrefix, idx)
 		} else {
-			ic.wildcardBaseNoPrefix = append(ic.wildcardBaseNoPrefix, idx)
+			rs.wildcardBaseNoPrefix = append(rs.wildcardBaseNoPrefix, idx)
 		}
 		return
 	}
 
 	if p.hasSlash {
-		ic.wildcardPathByPrefix[prefix] = append(ic.wildcardPathByPrefix[prefix], idx)
+		rs.wildcardPathByPrefix[prefix] = append(rs.wildcardPathByPrefix[prefix], idx)
 		return
 	}
-	ic.wildcardBaseByPrefix[prefix] = append(ic.wildcardBaseByPrefix[prefix], idx)
+	rs.wildcardBaseByPrefix[prefix] = append(rs.wildcardBaseByPrefix[prefix], idx)
 }
 
-func (ic *IgnoreChecker) applyWildcardPatterns(target string, noPrefix []int, byPrefix map[string][]int, apply func(int)) {
+func (rs *ignoreRules) applyWildcardPatterns(target string, noPrefix []int, byPrefix map[string][]int, apply func(int)) {
 	for _, idx := range noPrefix {
-		if ic.patterns[idx].match(target) {
+		if rs.patterns[idx].match(target) {
 			apply(idx)
 		}
 	}
@@ -295,7 +388,7 @@ func (ic *IgnoreChecker) applyWildcardPatterns(target string, noPrefix []int, by
 	for i := 1; i <= len(target); i++ {
 		if idxs, ok := byPrefix[target[:i]]; ok {
 			for _, idx := range idxs {
-				if ic.patterns[idx].match(target) {
+				if rs.patterns[idx].match(target) {
 					apply(idx)
 				}
 			}
@@ -304,7 +397,7 @@ func (ic *IgnoreChecker) applyWildcardPatterns(target string, noPrefix []int, by
 }
 
 func isLiteralPattern(pattern string) bool {
-	return !strings.ContainsAny(pattern, "*?[")
+	return !strings.ContainsAny(pattern, `*?[\`)
 }
 
 // wildcardLiteralPrefix returns the maximal literal prefix before any glob
@@ -322,22 +415,10 @@ func wildcardLiteralPrefix(pattern string) string {
 	return pattern
 }
 
-// matches checks if the given relative path matches this ignore pattern.
+// matches checks if the given relative path matches this ignore pattern,
+// treating the path as a directory for directory-only patterns. Paths
+// inside a matching directory are not matched; IsIgnored handles those.
 func (p *ignorePattern) matches(path string) bool {
-	// For hardcoded patterns (.graft, .got, .git, .gts) and dir-only patterns,
-	// check if the path is the directory itself or starts with it.
-	if p.dirOnly || p.pattern == ".graft" || p.pattern == ".got" || p.pattern == ".git" || p.pattern == ".gts" {
-		// Check exact match or prefix match (path is under this directory).
-		if path == p.pattern || strings.HasPrefix(path, p.pattern+"/") {
-			return true
-		}
-	}
-
-	// If dirOnly and the above didn't match, nothing else to check.
-	if p.dirOnly {
-		return false
-	}
-
 	if p.hasSlash {
 		// Pattern contains a slash: match against the full relative path.
 		return p.match(path)
@@ -352,38 +433,60 @@ func (p *ignorePattern) match(target string) bool {
 	if p.regex != nil {
 		return p.regex.MatchString(target)
 	}
-	matched, _ := filepath.Match(p.pattern, target)
+	matched, _ := path.Match(p.pattern, target)
 	return matched
 }
 
+// globToRegex translates a pattern containing "**" into a regular
+// expression; bracket expressions follow pathspec.BracketRegex. "**" is a globstar only as a whole path segment: a leading
+// "**/" matches in any directory, a trailing "/**" matches everything
+// inside, and "/**/" matches zero or more directories. Elsewhere it acts
+// like a single "*".
 func globToRegex(pattern string) string {
 	var b strings.Builder
 	b.WriteString("^")
 	for i := 0; i < len(pattern); i++ {
 		ch := pattern[i]
-		if ch == '*' {
+		switch ch {
+		case '*':
 			if i+1 < len(pattern) && pattern[i+1] == '*' {
-				if i+2 < len(pattern) && pattern[i+2] == '/' {
+				segmentStart := i == 0 || pattern[i-1] == '/'
+				for i+1 < len(pattern) && pattern[i+1] == '*' {
+					i++
+				}
+				switch {
+				case segmentStart && i+1 < len(pattern) && pattern[i+1] == '/':
 					// Globstar directory segment: match zero or more path segments.
 					b.WriteString("(?:.*/)?")
-					i += 2
-				} else {
-					b.WriteString(".*")
 					i++
+				case segmentStart && i+1 == len(pattern):
+					b.WriteString(".*")
+				default:
+					b.WriteString("[^/]*")
 				}
 				continue
 			}
 			b.WriteString("[^/]*")
-			continue
-		}
-		if ch == '?' {
+		case '?':
 			b.WriteString("[^/]")
-			continue
-		}
-		if strings.ContainsRune(`.+()|[]{}^$\\`, rune(ch)) {
-			b.WriteByte('\\')
+		case '[':
+			class, end, ok := pathspec.BracketRegex(pattern, i)
+			if !ok {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(class)
+			i = end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+				continue
+			}
+			b.WriteString(`\\`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
 		}
-		b.WriteByte(ch)
 	}
 	b.WriteString("$")
 	return b.String()
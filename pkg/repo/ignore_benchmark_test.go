@@ -125,9 +125,6 @@ type wildcardScanIndex struct {
 func buildWildcardScanIndex(patterns []ignorePattern) wildcardScanIndex {
 	var index wildcardScanIndex
 	for idx, p := range patterns {
-		if p.dirOnly || p.pattern == ".graft" || p.pattern == ".git" {
-			continue
-		}
 		if p.regex != nil || !isLiteralPattern(p.pattern) {
 			if p.hasSlash {
 				index.path = append(index.path, idx)
@@ -157,17 +154,6 @@ func compiledLinearWildcardIsIgnored(ic *IgnoreChecker, wildcardScan wildcardSca
 		}
 	}
 
-	if idxs, ok := ic.dirPrefixPatterns[path]; ok {
-		applyAll(idxs)
-	}
-	for i := 0; i < len(path); i++ {
-		if path[i] == '/' {
-			if idxs, ok := ic.dirPrefixPatterns[path[:i]]; ok {
-				applyAll(idxs)
-			}
-		}
-	}
-
 	if idxs, ok := ic.exactPathPatterns[path]; ok {
 		applyAll(idxs)
 	}
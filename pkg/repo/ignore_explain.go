@@ -1,6 +1,9 @@
 package repo
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"strings"
+)
 
 // IgnoreMatch describes one ignore rule that matched a path.
 type IgnoreMatch struct {
@@ -23,32 +26,64 @@ type IgnoreExplanation struct {
 
 // Explain reports which ignore rules matched the given repo-relative path.
 // Rules are returned in evaluation order, and the final match determines
-// whether the path is ignored.
+// whether the path is ignored. When an ancestor directory is ignored, the
+// rules matching that directory are reported instead and MatchedPath names
+// it.
 func (ic *IgnoreChecker) Explain(path string) IgnoreExplanation {
-	path = filepath.ToSlash(path)
+	path = strings.TrimSuffix(filepath.ToSlash(path), "/")
 
-	result := IgnoreExplanation{Path: path, MatchedPath: path}
-	for _, pattern := range ic.patterns {
-		if !pattern.matches(path) {
+	for i := 0; i < len(path); i++ {
+		if path[i] != '/' {
 			continue
 		}
-
-		match := IgnoreMatch{
-			Pattern:       pattern.original,
-			Source:        pattern.source,
-			Line:          pattern.line,
-			Negated:       pattern.negated,
-			DirectoryOnly: pattern.dirOnly,
-			Rooted:        pattern.rooted,
-		}
-		if match.Pattern == "" {
-			match.Pattern = pattern.pattern
+		if dir := ic.explainPath(path[:i]); dir.Ignored {
+			dir.Path = path
+			return dir
 		}
+	}
+	return ic.explainPath(path)
+}
+
+// explainPath lists the rules matching path itself, root rules first and
+// then those of each ancestor directory's ignore file.
+func (ic *IgnoreChecker) explainPath(path string) IgnoreExplanation {
+	result := IgnoreExplanation{Path: path, MatchedPath: path}
+	final := -1
+	collect := func(patterns []ignorePattern, target string) {
+		for _, pattern := range patterns {
+			if !pattern.matches(target) {
+				continue
+			}
 
-		result.Matches = append(result.Matches, match)
-		result.Ignored = !pattern.negated
-		result.Final = &result.Matches[len(result.Matches)-1]
+			match := IgnoreMatch{
+				Pattern:       pattern.original,
+				Source:        pattern.source,
+				Line:          pattern.line,
+				Negated:       pattern.negated,
+				DirectoryOnly: pattern.dirOnly,
+				Rooted:        pattern.rooted,
+			}
+			if match.Pattern == "" {
+				match.Pattern = pattern.pattern
+			}
+
+			result.Matches = append(result.Matches, match)
+			result.Ignored = !pattern.negated
+			final = len(result.Matches) - 1
+		}
 	}
 
+	collect(ic.patterns, path)
+	for i := 0; i < len(path); i++ {
+		if path[i] != '/' {
+			continue
+		}
+		if rules := ic.dirRules(path[:i]); rules != nil {
+			collect(rules.patterns, path[i+1:])
+		}
+	}
+	if final >= 0 {
+		result.Final = &result.Matches[final]
+	}
 	return result
 }
@@ -3,6 +3,7 @@ package repo
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -126,6 +127,43 @@ func TestIgnore_NoGotignoreFile(t *testing.T) {
 	}
 }
 
+func TestIgnore_GitignoreFallback(t *testing.T) {
+	dir := t.TempDir()
+
+	// Without a .graftignore or .gotignore, the root .gitignore is read.
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.log\nbuild/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A directory with its own .gotignore ignores its .gitignore.
+	if err := os.WriteFile(filepath.Join(dir, "lib", ".gotignore"), []byte("*.tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib", ".gitignore"), []byte("*.go\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ic := NewIgnoreChecker(dir)
+
+	if !ic.IsIgnored("debug.log") || !ic.IsIgnored("build/out.bin") {
+		t.Error("expected the root .gitignore patterns to apply")
+	}
+	if !ic.IsIgnored("lib/scratch.tmp") {
+		t.Error("expected lib/.gotignore to apply")
+	}
+	if ic.IsIgnored("lib/util.go") {
+		t.Error("expected lib/.gitignore to be shadowed by lib/.gotignore")
+	}
+
+	// Adding a root .graftignore replaces the root .gitignore.
+	writeGotignore(t, dir, "*.bak\n")
+	ic = NewIgnoreChecker(dir)
+	if ic.IsIgnored("debug.log") || !ic.IsIgnored("old.bak") {
+		t.Error("expected .graftignore to take precedence over .gitignore")
+	}
+}
+
 // Test 8: Subdirectory file — *.o matches src/foo.o.
 func TestIgnore_SubdirectoryFileMatch(t *testing.T) {
 	dir := t.TempDir()
@@ -196,10 +234,10 @@ func TestIgnore_LastMatchWinsAcrossWildcardAndLiteral(t *testing.T) {
 	}
 }
 
-func TestIgnore_DirPatternOverriddenByExactPathNegation(t *testing.T) {
+func TestIgnore_DirContentsOverriddenByExactPathNegation(t *testing.T) {
 	dir := t.TempDir()
 
-	writeGotignore(t, dir, "build/\n!build/keep.txt\n")
+	writeGotignore(t, dir, "build/*\n!build/keep.txt\n")
 	ic := NewIgnoreChecker(dir)
 
 	if !ic.IsIgnored("build/out.bin") {
@@ -208,6 +246,24 @@ func TestIgnore_DirPatternOverriddenByExactPathNegation(t *testing.T) {
 	if ic.IsIgnored("build/keep.txt") {
 		t.Error("expected build/keep.txt to be unignored by exact path negation")
 	}
+	if ic.IsIgnored("build") {
+		t.Error("expected the build directory itself to NOT be ignored")
+	}
+}
+
+func TestIgnore_NegationCannotReincludeInsideIgnoredDir(t *testing.T) {
+	dir := t.TempDir()
+
+	writeGotignore(t, dir, "build/\n!build/keep.txt\n")
+	ic := NewIgnoreChecker(dir)
+
+	if !ic.IsIgnored("build/keep.txt") {
+		t.Error("expected build/keep.txt to stay ignored because build/ is ignored")
+	}
+	explanation := ic.Explain("build/keep.txt")
+	if !explanation.Ignored || explanation.MatchedPath != "build" || explanation.Final == nil || explanation.Final.Pattern != "build/" {
+		t.Fatalf("Explain(build/keep.txt) = %+v, want a match of build/ on build", explanation)
+	}
 }
 
 func TestIgnore_GlobstarOverriddenByExactPathNegation(t *testing.T) {
@@ -375,6 +431,106 @@ func TestIgnore_GtsDirAlwaysIgnored(t *testing.T) {
 	}
 }
 
+func TestIgnore_NestedIgnoreFilesAreRelativeAndOverrideParents(t *testing.T) {
+	dir := t.TempDir()
+
+	writeGotignore(t, dir, "*.log\n")
+	if err := os.MkdirAll(filepath.Join(dir, "web", "dist"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Patterns in web/.gitignore are relative to web/; its .gitignore is
+	// read because web/ has no .graftignore.
+	if err := os.WriteFile(filepath.Join(dir, "web", ".gitignore"), []byte("/dist/\n!keep.log\ncache\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ic := NewIgnoreChecker(dir)
+
+	if !ic.IsIgnored("web/dist/app.js") {
+		t.Error("expected web/dist/app.js to be ignored by web/.gitignore")
+	}
+	if ic.IsIgnored("dist/app.js") {
+		t.Error("expected dist/app.js to NOT be ignored; /dist/ is anchored to web/")
+	}
+	if ic.IsIgnored("web/keep.log") || ic.IsIgnored("web/sub/keep.log") {
+		t.Error("expected keep.log under web/ to be unignored by the nested negation")
+	}
+	if !ic.IsIgnored("keep.log") || !ic.IsIgnored("web/debug.log") {
+		t.Error("expected the root *.log rule to apply elsewhere")
+	}
+	if !ic.IsIgnored("web/src/cache/data") {
+		t.Error("expected web/src/cache/data to be ignored by a nested basename pattern")
+	}
+
+	explanation := ic.Explain("web/keep.log")
+	if explanation.Final == nil || explanation.Final.Source != "web/.gitignore" || explanation.Final.Line != 2 {
+		t.Fatalf("Explain(web/keep.log).Final = %+v, want web/.gitignore line 2", explanation.Final)
+	}
+}
+
+func TestIgnore_GlobalIgnoreFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if err := os.MkdirAll(filepath.Join(home, ".config", "graft"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".config", "graft", "ignore"), []byte(".DS_Store\n*.swp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeGotignore(t, dir, "!keep.swp\n")
+	ic := NewIgnoreChecker(dir)
+
+	if !ic.IsIgnored(".DS_Store") || !ic.IsIgnored("src/main.go.swp") {
+		t.Error("expected patterns from the global ignore file to apply")
+	}
+	if ic.IsIgnored("keep.swp") {
+		t.Error("expected the repository ignore file to override the global one")
+	}
+
+	// excludes_file in ~/.graftconfig replaces the default location.
+	custom := filepath.Join(home, "ignore-all")
+	if err := os.WriteFile(custom, []byte("*.tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".graftconfig"), []byte(`{"version":1,"excludes_file":"~/ignore-all"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ic = NewIgnoreChecker(dir)
+	if !ic.IsIgnored("a.tmp") || ic.IsIgnored(".DS_Store") {
+		t.Error("expected excludes_file to replace the default global ignore file")
+	}
+}
+
+func TestIgnore_GitignorePatternSyntax(t *testing.T) {
+	dir := t.TempDir()
+
+	writeGotignore(t, dir, strings.Join([]string{
+		"a/**/z",
+		"logs/**",
+		"foo**bar",
+		"tmp[!0-9]",
+		"cache[!abc]",
+		"x[+-0]y",
+		`\#notes`,
+		`\!bang`,
+		"trailing\\ ",
+		"vendor",
+	}, "\n")+"\n")
+	ic := NewIgnoreChecker(dir)
+
+	for _, path := range []string{"a/z", "a/b/z", "a/b/c/z", "logs/x", "logs/x/y", "foobar", "fooXbar", "tmpx", "cached", "cache!", "x-y", "#notes", "!bang", "trailing ", "vendor/lib/x.go", "src/vendor/x.go"} {
+		if !ic.IsIgnored(path) {
+			t.Errorf("expected %q to be ignored", path)
+		}
+	}
+	for _, path := range []string{"b/a/z", "logs", "foo/bar", "tmp1", "cachea", "x/y", "notes", "bang", "trailing"} {
+		if ic.IsIgnored(path) {
+			t.Errorf("expected %q to NOT be ignored", path)
+		}
+	}
+}
+
 func writeGotignore(t *testing.T, dir, content string) {
 	t.Helper()
 	if err := os.WriteFile(filepath.Join(dir, ".graftignore"), []byte(content), 0o644); err != nil {
@@ -0,0 +1,373 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// ImportGitOptions configures ImportGit.
+type ImportGitOptions struct {
+	// Force allows ref updates that are not fast-forwards, and moving
+	// existing tags.
+	Force bool
+}
+
+// ImportGitResult summarizes an ImportGit call.
+type ImportGitResult struct {
+	Commits      int
+	Blobs        int
+	Tags         int // annotated tag objects
+	UpdatedRefs  []RefUpdate
+	RejectedRefs []RefUpdate // updates refused without ImportGitOptions.Force
+	SkippedRefs  []string    // tags of trees or blobs, which graft cannot hold
+	// Head is the branch the git repository's HEAD names, such as
+	// "refs/heads/main", or "" when it is detached.
+	Head string
+}
+
+// ImportGit converts the branches and tags of the git repository at path,
+// a work tree or a git directory, into graft history. Objects are read
+// straight from the repository's loose objects and packfiles, so git need
+// not be installed.
+//
+// Commits keep their authors, committers, dates and messages, and entity
+// lists are extracted for every file. Annotated tags become graft tag
+// objects with the same tagger and message. Gitlinks are skipped, and
+// signatures are dropped as they would no longer verify.
+//
+// The git hash of every converted commit and blob is recorded in the same
+// map FetchFromGit and PushToGit use, so importing again only converts new
+// history. Refs are updated as by FastImport: non-fast-forward updates are
+// rejected unless opts.Force is set, and the working tree is not touched.
+func (r *Repo) ImportGit(ctx context.Context, path string, opts ImportGitOptions) (*ImportGitResult, error) {
+	db, err := openGitObjectDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("import git: %w", err)
+	}
+	defer db.Close()
+	if err := os.MkdirAll(r.gitInteropDir(), 0o755); err != nil {
+		return nil, fmt.Errorf("import git: %w", err)
+	}
+	hm, err := openGitInteropMap(filepath.Join(r.gitInteropDir(), gitInteropMapName))
+	if err != nil {
+		return nil, fmt.Errorf("import git: %w", err)
+	}
+	defer hm.Close()
+
+	im := &gitImporter{
+		r:           r,
+		db:          db,
+		hashMap:     hm,
+		trees:       make(map[string]object.Hash),
+		entityCache: make(map[string]object.Hash),
+		res:         &ImportGitResult{Head: db.head()},
+	}
+	if err := im.run(ctx, opts.Force); err != nil {
+		return nil, fmt.Errorf("import git: %w", err)
+	}
+	return im.res, nil
+}
+
+type gitImporter struct {
+	r           *Repo
+	db          *gitObjectDB
+	hashMap     *gitInteropMap
+	trees       map[string]object.Hash // path + "\x00" + git tree -> graft tree
+	entityCache map[string]object.Hash // path + "\x00" + blob -> entity list
+	res         *ImportGitResult
+}
+
+// gitImportRef is a branch or tag to convert.
+type gitImportRef struct {
+	name   string
+	commit string // git commit the ref peels to
+	tag    string // outermost git tag object, for annotated tags
+}
+
+func (im *gitImporter) run(ctx context.Context, force bool) error {
+	refs, err := im.db.refs()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		if strings.HasPrefix(name, "refs/heads/") || strings.HasPrefix(name, "refs/tags/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var targets []gitImportRef
+	for _, name := range names {
+		ref, ok, err := im.peel(name, refs[name])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if !ok {
+			im.res.SkippedRefs = append(im.res.SkippedRefs, name)
+			continue
+		}
+		targets = append(targets, ref)
+	}
+
+	var pending []string
+	visited := make(map[string]bool)
+	for _, ref := range targets {
+		commits, err := im.pendingCommits(ref.commit, visited)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, commits...)
+	}
+	for i, gitCommit := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := im.importCommit(gitCommit); err != nil {
+			return fmt.Errorf("commit %s: %w", gitCommit, err)
+		}
+		im.r.reportProgress(ProgressImporting, i+1, len(pending))
+	}
+
+	tips := make(map[string]object.Hash, len(targets))
+	for _, ref := range targets {
+		commit, _ := im.hashMap.GitToGraft(ref.commit)
+		tips[ref.name] = commit
+		if ref.tag == "" {
+			continue
+		}
+		tag, err := im.importTag(strings.TrimPrefix(ref.name, "refs/tags/"), ref.tag, commit)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ref.name, err)
+		}
+		tips[ref.name] = tag
+	}
+	im.res.UpdatedRefs, im.res.RejectedRefs, err = im.r.updateImportedRefs(tips, force)
+	return err
+}
+
+// peel follows tag objects from hash to the commit they name. It reports
+// false for refs that end at a tree or blob.
+func (im *gitImporter) peel(name, hash string) (gitImportRef, bool, error) {
+	ref := gitImportRef{name: name}
+	for range 16 {
+		typ, data, err := im.db.read(hash)
+		if err != nil {
+			return ref, false, err
+		}
+		switch typ {
+		case object.TypeCommit:
+			ref.commit = hash
+			return ref, true, nil
+		case object.TypeTag:
+			if ref.tag == "" && strings.HasPrefix(name, "refs/tags/") {
+				ref.tag = hash
+			}
+			target, _, _, _, _ := parseGitTag(data)
+			hash = target
+		default:
+			return ref, false, nil
+		}
+	}
+	return ref, false, fmt.Errorf("tag chain starting at %s is too deep", hash)
+}
+
+// pendingCommits lists the commits reachable from tip that are not
+// converted yet, parents before children.
+func (im *gitImporter) pendingCommits(tip string, visited map[string]bool) ([]string, error) {
+	type frame struct {
+		hash     string
+		expanded bool
+	}
+	var order []string
+	stack := []frame{{hash: tip}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if top.expanded {
+			order = append(order, top.hash)
+			continue
+		}
+		if visited[top.hash] {
+			continue
+		}
+		visited[top.hash] = true
+		if _, ok := im.hashMap.GitToGraft(top.hash); ok {
+			continue
+		}
+		raw, err := im.db.readType(top.hash, object.TypeCommit)
+		if err != nil {
+			return nil, err
+		}
+		commit, err := parseGitCommit(raw)
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: %w", top.hash, err)
+		}
+		stack = append(stack, frame{hash: top.hash, expanded: true})
+		for i := len(commit.Parents) - 1; i >= 0; i-- {
+			if parent := string(commit.Parents[i]); !visited[parent] {
+				stack = append(stack, frame{hash: parent})
+			}
+		}
+	}
+	return order, nil
+}
+
+func (im *gitImporter) importCommit(gitCommit string) error {
+	raw, err := im.db.readType(gitCommit, object.TypeCommit)
+	if err != nil {
+		return err
+	}
+	commit, err := parseGitCommit(raw)
+	if err != nil {
+		return err
+	}
+	for i, parent := range commit.Parents {
+		graftParent, ok := im.hashMap.GitToGraft(string(parent))
+		if !ok {
+			return fmt.Errorf("parent %s has not been converted", parent)
+		}
+		commit.Parents[i] = graftParent
+	}
+	treeHash, _, err := im.importTree("", string(commit.TreeHash))
+	if err != nil {
+		return err
+	}
+	commit.TreeHash = treeHash
+	h, err := im.r.Store.WriteCommit(commit)
+	if err != nil {
+		return err
+	}
+	im.res.Commits++
+	return im.hashMap.Put(h, gitCommit)
+}
+
+// importTree converts the git tree at prefix, returning the graft tree and
+// whether it holds any entries. Subtrees left empty by skipped gitlinks are
+// dropped, as graft trees only hold directories with files.
+func (im *gitImporter) importTree(prefix, gitTree string) (object.Hash, bool, error) {
+	key := prefix + "\x00" + gitTree
+	if h, ok := im.trees[key]; ok {
+		return h, h != "", nil
+	}
+	data, err := im.db.readType(gitTree, object.TypeTree)
+	if err != nil {
+		return "", false, err
+	}
+	gitEntries, err := object.ParseGitTree(data)
+	if err != nil {
+		return "", false, fmt.Errorf("tree %s: %w", gitTree, err)
+	}
+
+	entries := make([]object.TreeEntry, 0, len(gitEntries))
+	for _, e := range gitEntries {
+		p, hash := e.Name, hex.EncodeToString(e.Hash)
+		if prefix != "" {
+			p = prefix + "/" + e.Name
+		}
+		switch e.Mode {
+		case object.TreeModeDir, "040000":
+			sub, ok, err := im.importTree(p, hash)
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				entries = append(entries, object.TreeEntry{Name: e.Name, IsDir: true, Mode: object.TreeModeDir, SubtreeHash: sub})
+			}
+			continue
+		case object.TreeModeModule:
+			continue
+		}
+		mode := normalizeFileMode(e.Mode)
+		blobHash, err := im.importBlob(hash)
+		if err != nil {
+			return "", false, fmt.Errorf("%s: %w", p, err)
+		}
+		var entityListHash object.Hash
+		if !isSymlinkMode(mode) {
+			cacheKey := p + "\x00" + string(blobHash)
+			var cached bool
+			if entityListHash, cached = im.entityCache[cacheKey]; !cached {
+				if entityListHash, err = im.r.entityListForBlob(p, blobHash); err != nil {
+					return "", false, err
+				}
+				im.entityCache[cacheKey] = entityListHash
+			}
+		}
+		entries = append(entries, object.TreeEntry{Name: e.Name, Mode: mode, BlobHash: blobHash, EntityListHash: entityListHash})
+	}
+
+	// Git orders directories as if their names ended in "/"; graft orders
+	// entries by name alone.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	var h object.Hash
+	if len(entries) > 0 || prefix == "" {
+		if h, err = im.r.Store.WriteTree(&object.TreeObj{Entries: entries}); err != nil {
+			return "", false, err
+		}
+	}
+	im.trees[key] = h
+	return h, len(entries) > 0, nil
+}
+
+func (im *gitImporter) importBlob(gitBlob string) (object.Hash, error) {
+	if h, ok := im.hashMap.GitToGraft(gitBlob); ok {
+		return h, nil
+	}
+	data, err := im.db.readType(gitBlob, object.TypeBlob)
+	if err != nil {
+		return "", err
+	}
+	h, err := im.r.Store.WriteBlob(&object.Blob{Data: data})
+	if err != nil {
+		return "", err
+	}
+	im.res.Blobs++
+	return h, im.hashMap.Put(h, gitBlob)
+}
+
+// importTag converts an annotated git tag into a graft tag object that
+// names target, the converted commit it peels to.
+func (im *gitImporter) importTag(name, gitTag string, target object.Hash) (object.Hash, error) {
+	if h, ok := im.hashMap.GitToGraft(gitTag); ok {
+		return h, nil
+	}
+	data, err := im.db.readType(gitTag, object.TypeTag)
+	if err != nil {
+		return "", err
+	}
+	_, tagger, ts, tz, message := parseGitTag(data)
+	h, err := im.r.Store.WriteTag(&object.TagObj{
+		TargetHash: target,
+		Data:       formatTagData(target, object.TypeCommit, name, tagger, ts, tz, message),
+	})
+	if err != nil {
+		return "", err
+	}
+	im.res.Tags++
+	return h, im.hashMap.Put(h, gitTag)
+}
+
+// parseGitTag decodes the target, tagger and message of a raw git tag.
+func parseGitTag(raw []byte) (target, tagger string, ts int64, tz, message string) {
+	headers, body, _ := bytes.Cut(raw, []byte("\n\n"))
+	tagger, tz = "unknown", "+0000"
+	for _, line := range strings.Split(string(headers), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "object":
+			target = value
+		case "tagger":
+			tagger, ts, tz = parseGitIdent(value)
+		}
+	}
+	return target, tagger, ts, tz, strings.TrimRight(string(body), "\n")
+}
@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportGitLooseAndPackedHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+
+	src := filepath.Join(t.TempDir(), "src")
+	gitOutput(t, t.TempDir(), "init", "-q", "-b", "main", src)
+	commit := func(msg string) {
+		gitOutput(t, src, "add", "-A")
+		gitOutput(t, src, "-c", "user.name=Alice", "-c", "user.email=alice@example.com", "commit", "-q", "-m", msg)
+	}
+	shadowWriteFile(t, src, "main.go", "package main\n\nfunc main() {}\n")
+	shadowWriteFile(t, src, "docs/readme.txt", "hello\n")
+	commit("first")
+	gitOutput(t, src, "-c", "user.name=Alice", "-c", "user.email=alice@example.com", "tag", "-a", "-m", "first release", "v1.0")
+	gitOutput(t, src, "branch", "topic")
+	// Pack what exists so far; the commit below stays loose.
+	gitOutput(t, src, "gc", "-q")
+	shadowWriteFile(t, src, "run.sh", "#!/bin/sh\necho hi\n")
+	if err := os.Chmod(filepath.Join(src, "run.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	commit("add script")
+
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := r.ImportGit(ctx, src, ImportGitOptions{})
+	if err != nil {
+		t.Fatalf("ImportGit: %v", err)
+	}
+	if res.Commits != 2 || res.Blobs != 3 || res.Tags != 1 || len(res.UpdatedRefs) != 3 || res.Head != "refs/heads/main" {
+		t.Fatalf("ImportGit = %+v, want 2 commits, 3 blobs, 1 tag, 3 refs and HEAD main", res)
+	}
+
+	tip, err := r.ResolveRef("refs/heads/main")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	c := mustReadCommit(t, r, tip)
+	if c.Author != "Alice <alice@example.com>" || strings.TrimSpace(c.Message) != "add script" || len(c.Parents) != 1 {
+		t.Fatalf("imported tip = %+v", c)
+	}
+	files, err := r.FlattenTree(c.TreeHash)
+	if err != nil {
+		t.Fatalf("FlattenTree: %v", err)
+	}
+	if len(files) != 3 || files[0].Path != "docs/readme.txt" || files[1].Path != "main.go" || files[2].Path != "run.sh" {
+		t.Fatalf("imported files = %+v", files)
+	}
+	if files[1].EntityListHash == "" || files[2].Mode != "100755" {
+		t.Fatalf("main.go lacks entities or run.sh is not executable: %+v", files)
+	}
+	if topic, err := r.ResolveRef("refs/heads/topic"); err != nil || topic != c.Parents[0] {
+		t.Fatalf("topic = %s, %v; want %s", topic, err, c.Parents[0])
+	}
+	tagHash, err := r.ResolveRef("refs/tags/v1.0")
+	if err != nil {
+		t.Fatalf("ResolveRef tag: %v", err)
+	}
+	tag, err := r.Store.ReadTag(tagHash)
+	if err != nil {
+		t.Fatalf("ReadTag: %v", err)
+	}
+	if tagger, _, message := parseTagData(tag.Data); tag.TargetHash != c.Parents[0] || tagger != "Alice <alice@example.com>" || message != "first release" {
+		t.Fatalf("imported tag = %+v", tag)
+	}
+
+	// Importing again converts nothing and leaves the refs alone.
+	again, err := r.ImportGit(ctx, src, ImportGitOptions{})
+	if err != nil || again.Commits != 0 || again.Blobs != 0 || len(again.UpdatedRefs) != 0 {
+		t.Fatalf("second import = %+v, %v", again, err)
+	}
+
+	// History rewritten in git is only taken with Force.
+	gitOutput(t, src, "reset", "-q", "--hard", "topic")
+	shadowWriteFile(t, src, "main.go", "package main\n\nfunc main() { println() }\n")
+	commit("rewrite")
+	rewritten, err := r.ImportGit(ctx, src, ImportGitOptions{})
+	if err != nil || len(rewritten.RejectedRefs) != 1 || rewritten.RejectedRefs[0].Name != "refs/heads/main" {
+		t.Fatalf("rewritten import = %+v, %v; want main rejected", rewritten, err)
+	}
+	if _, err := r.ImportGit(ctx, src, ImportGitOptions{Force: true}); err != nil {
+		t.Fatalf("forced import: %v", err)
+	}
+	if now, _ := r.ResolveRef("refs/heads/main"); now == tip {
+		t.Fatal("forced import left main unchanged")
+	}
+}
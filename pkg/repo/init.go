@@ -8,12 +8,22 @@ import (
 	"strings"
 	"time"
 
+	"github.com/odvcencio/graft/pkg/lockfile"
 	"github.com/odvcencio/graft/pkg/object"
 )
 
 var ErrRefCASMismatch = errors.New("ref compare-and-swap mismatch")
 var ErrRefUpdatedButReflogAppendFailed = errors.New("ref updated but reflog append failed")
 
+// ErrNotARepo is returned by Open when neither the path nor any of its
+// parents contains a .graft directory.
+var ErrNotARepo = errors.New("not a graft repository")
+
+// ErrRefNotFound is wrapped by errors for a ref or revision that names
+// nothing: a missing branch, tag or ref file, or an unknown hash. Errors
+// from ResolveRef for a missing ref file also still match os.ErrNotExist.
+var ErrRefNotFound = errors.New("ref not found")
+
 // RefUpdateReflogError indicates the ref file update succeeded, but appending
 // the corresponding reflog entry failed.
 type RefUpdateReflogError struct {
@@ -49,8 +59,7 @@ func (e *RefUpdateReflogError) Is(target error) bool {
 }
 
 const (
-	refLockRetryDelay = 5 * time.Millisecond
-	refLockWaitLimit  = 2 * time.Second
+	refLockWaitLimit = 2 * time.Second
 )
 
 // Init creates a new Graft repository at path. It creates the .graft/ directory
@@ -93,6 +102,15 @@ func Init(path string) (*Repo, error) {
 // linked worktrees, or .graft symlink for module working trees) and opens the
 // repository. Returns an error if no .graft entry is found.
 func Open(path string) (*Repo, error) {
+	r, err := openRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	r.applyStorageConfig()
+	return r, nil
+}
+
+func openRepo(path string) (*Repo, error) {
 	// Resolve to absolute path for consistent traversal.
 	abs, err := filepath.Abs(path)
 	if err != nil {
@@ -149,7 +167,7 @@ func Open(path string) (*Repo, error) {
 		parent := filepath.Dir(cur)
 		if parent == cur {
 			// Reached filesystem root without finding .graft/.
-			return nil, fmt.Errorf("open: not a graft repository (or any parent up to /)")
+			return nil, fmt.Errorf("open: %w (or any parent up to /)", ErrNotARepo)
 		}
 		cur = parent
 	}
@@ -213,31 +231,20 @@ func openLinkedWorktree(rootDir, graftFile string) (*Repo, error) {
 	}, nil
 }
 
-// writeHeadAtomic atomically writes the HEAD file using temp+fsync+rename.
+// writeHeadAtomic atomically writes the HEAD file under HEAD.lock, so
+// concurrent processes serialize instead of clobbering each other's temp file.
 func (r *Repo) writeHeadAtomic(content string) error {
-	headPath := filepath.Join(r.GraftDir, "HEAD")
-	tmpPath := headPath + ".lock"
-	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	lock, err := lockfile.Acquire(filepath.Join(r.GraftDir, "HEAD"), refLockWaitLimit)
 	if err != nil {
-		return fmt.Errorf("write HEAD: create temp: %w", err)
+		return fmt.Errorf("write HEAD: lock: %w", err)
 	}
-	if _, err := f.WriteString(content); err != nil {
-		f.Close()
-		os.Remove(tmpPath)
+	defer lock.Release()
+
+	if _, err := lock.Write([]byte(content)); err != nil {
 		return fmt.Errorf("write HEAD: write: %w", err)
 	}
-	if err := f.Sync(); err != nil {
-		f.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("write HEAD: sync: %w", err)
-	}
-	if err := f.Close(); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("write HEAD: close: %w", err)
-	}
-	if err := os.Rename(tmpPath, headPath); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("write HEAD: rename: %w", err)
+	if err := lock.Commit(); err != nil {
+		return fmt.Errorf("write HEAD: commit: %w", err)
 	}
 	return nil
 }
@@ -279,7 +286,13 @@ func (r *Repo) Head() (string, error) {
 //  1. If name is "HEAD", read HEAD. If HEAD is symbolic, resolve the target ref.
 //  2. If name starts with "refs/", read .graft/<name>.
 //  3. Otherwise, try "refs/heads/<name>".
+//
+// Names using extended revision syntax (HEAD~3, @{-1}, main@{upstream},
+// HEAD:path, ...) are resolved by ResolveTreeish instead.
 func (r *Repo) ResolveRef(name string) (object.Hash, error) {
+	if isExtendedRevision(name) {
+		return r.ResolveTreeish(name)
+	}
 	if name == "HEAD" {
 		head, err := r.Head()
 		if err != nil {
@@ -307,6 +320,9 @@ func (r *Repo) ResolveRef(name string) (object.Hash, error) {
 
 	data, err := os.ReadFile(refPath)
 	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("resolve ref %q: %w: %w", name, ErrRefNotFound, err)
+		}
 		return "", fmt.Errorf("resolve ref %q: %w", name, err)
 	}
 	hashStr := strings.TrimRight(string(data), "\n")
@@ -366,7 +382,12 @@ func (r *Repo) UpdateRefCAS(name string, h object.Hash, expectedOld ...object.Ha
 		}
 	}()
 
-	oldHash, err := readRefHash(refPath)
+	var oldHash object.Hash
+	if name == "HEAD" {
+		oldHash, err = r.readHeadHash()
+	} else {
+		oldHash, err = readRefHash(refPath)
+	}
 	if err != nil {
 		return fmt.Errorf("update ref %q: read old hash: %w", name, err)
 	}
@@ -435,7 +456,7 @@ func (r *Repo) DeleteRefCAS(name string, expectedOld object.Hash) error {
 		return fmt.Errorf("delete ref %q: read: %w", name, err)
 	}
 	if oldHash == "" {
-		return fmt.Errorf("delete ref %q: not found", name)
+		return fmt.Errorf("delete ref %q: %w", name, ErrRefNotFound)
 	}
 	if oldHash != expectedOld {
 		return fmt.Errorf(
@@ -467,29 +488,21 @@ func (r *Repo) DeleteRefCAS(name string, expectedOld object.Hash) error {
 }
 
 func acquireRefLock(lockPath string) (*os.File, error) {
-	deadline := time.Now().Add(refLockWaitLimit)
-	for {
-		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
-		if err == nil {
-			return f, nil
-		}
-		if os.IsExist(err) {
-			// Check for stale lock: if the lock file is older than 5 minutes,
-			// it was likely left by a crashed process. Remove it and retry.
-			if info, statErr := os.Stat(lockPath); statErr == nil {
-				if time.Since(info.ModTime()) > 5*time.Minute {
-					os.Remove(lockPath)
-					continue
-				}
-			}
-			if time.Now().After(deadline) {
-				return nil, fmt.Errorf("timeout waiting for lock %q (may be stale — remove manually if no graft process is running)", lockPath)
-			}
-			time.Sleep(refLockRetryDelay)
-			continue
-		}
-		return nil, err
+	return lockfile.CreateExclusive(lockPath, refLockWaitLimit)
+}
+
+// readHeadHash returns the commit HEAD points at, directly or through the
+// branch it names; "" for an unborn branch. Writing a hash to HEAD detaches
+// it, so the value it replaces is the resolved one.
+func (r *Repo) readHeadHash() (object.Hash, error) {
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(head, "refs/") {
+		return readRefHash(filepath.Join(r.refsBaseDir(), head))
 	}
+	return readRefHash(filepath.Join(r.GraftDir, "HEAD"))
 }
 
 func readRefHash(refPath string) (object.Hash, error) {
@@ -98,6 +98,31 @@ func TestOpen_NoRepo_Error(t *testing.T) {
 	if err == nil {
 		t.Fatal("Open should fail in non-repo directory, got nil error")
 	}
+	if !errors.Is(err, ErrNotARepo) {
+		t.Fatalf("Open error = %v, want ErrNotARepo", err)
+	}
+}
+
+func TestResolveRef_MissingWrapsErrRefNotFound(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	_, err = r.ResolveRef("no-such-branch")
+	if !errors.Is(err, ErrRefNotFound) {
+		t.Fatalf("ResolveRef error = %v, want ErrRefNotFound", err)
+	}
+	// Callers that test for a missing ref file keep working.
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("ResolveRef error = %v, want os.ErrNotExist", err)
+	}
+	if _, err := r.ResolveRef("no-such-branch~1"); !errors.Is(err, ErrRefNotFound) {
+		t.Fatalf("ResolveRef(no-such-branch~1) error = %v, want ErrRefNotFound", err)
+	}
+	if err := r.DeleteBranch("no-such-branch"); !errors.Is(err, ErrRefNotFound) {
+		t.Fatalf("DeleteBranch error = %v, want ErrRefNotFound", err)
+	}
 }
 
 // Test 5: HEAD defaults to "ref: refs/heads/main".
@@ -1,8 +1,11 @@
 package repo
 
 import (
+	"container/heap"
+	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"os"
 	"path/filepath"
 	"sort"
@@ -23,111 +26,102 @@ type LogEntry struct {
 // refs are deduplicated. In a shallow repository, walking stops at shallow
 // boundaries.
 func (r *Repo) LogAll(limit int) ([]LogEntry, error) {
+	return r.LogAllWithOptions(limit, LogOptions{})
+}
+
+// LogAllWithOptions is like LogAll, keeping only commits accepted by
+// opts.Filter and, with opts.FirstParent, reachable from a tip through
+// first parents only. The walk is LogAllIter's, so it stops once limit
+// commits are accepted instead of reading the whole history.
+func (r *Repo) LogAllWithOptions(limit int, opts LogOptions) ([]LogEntry, error) {
 	if limit <= 0 {
 		return nil, nil
 	}
-
-	// Collect all ref tips: branches + tags.
-	branchRefs, err := r.ListRefs("heads")
-	if err != nil {
-		return nil, fmt.Errorf("log all: list branches: %w", err)
-	}
-	tagRefs, err := r.ListRefs("tags")
-	if err != nil {
-		return nil, fmt.Errorf("log all: list tags: %w", err)
+	var results []LogEntry
+	for entry, err := range r.LogAllIter(context.Background(), opts) {
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, entry)
+		if len(results) == limit {
+			break
+		}
 	}
+	return results, nil
+}
 
-	seen := make(map[object.Hash]struct{})
-	var all []LogEntry
-
-	shallow, _ := r.ShallowState()
+// LogAllIter yields the commits reachable from all branches and tags that
+// opts.Filter accepts, newest first. Commits are visited from a queue seeded
+// with the ref tips and only commit objects are read, so stopping early
+// skips the rest of the history. Once ctx is done it yields ctx's error and
+// stops.
+func (r *Repo) LogAllIter(ctx context.Context, opts LogOptions) iter.Seq2[LogEntry, error] {
+	return func(yield func(LogEntry, error) bool) {
+		// Collect all ref tips: branches + tags.
+		branchRefs, err := r.ListRefs("heads")
+		if err != nil {
+			yield(LogEntry{}, fmt.Errorf("log all: list branches: %w", err))
+			return
+		}
+		tagRefs, err := r.ListRefs("tags")
+		if err != nil {
+			yield(LogEntry{}, fmt.Errorf("log all: list tags: %w", err))
+			return
+		}
 
-	// Walk from each ref tip collecting all reachable commits.
-	for _, refs := range []map[string]object.Hash{branchRefs, tagRefs} {
-		for _, tip := range refs {
-			current := tip
-			for current != "" {
-				if _, dup := seen[current]; dup {
-					break
-				}
+		shallow, _ := r.ShallowState()
 
-				c, err := r.Store.ReadCommit(current)
-				if err != nil {
-					if errors.Is(err, os.ErrNotExist) {
-						break
-					}
-					return nil, fmt.Errorf("log all: read commit %s: %w", current, err)
+		seen := make(map[object.Hash]*object.CommitObj)
+		queue := &revListHeap{commits: seen}
+		enqueue := func(h object.Hash) error {
+			if _, dup := seen[h]; dup {
+				return nil
+			}
+			c, err := r.Store.ReadCommit(h)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
 				}
-
-				seen[current] = struct{}{}
-				all = append(all, LogEntry{Hash: current, Commit: c})
-
-				// Follow first parent.
-				if len(c.Parents) == 0 {
-					break
+				return fmt.Errorf("log all: read commit %s: %w", h, err)
+			}
+			seen[h] = c
+			heap.Push(queue, h)
+			return nil
+		}
+		for _, refs := range []map[string]object.Hash{branchRefs, tagRefs} {
+			for _, tip := range refs {
+				if err := enqueue(tip); err != nil {
+					yield(LogEntry{}, err)
+					return
 				}
+			}
+		}
 
-				// Also enqueue non-first parents for walking.
-				for _, p := range c.Parents[1:] {
-					if _, dup := seen[p]; !dup {
-						if shallow != nil && shallow.IsShallow(p) {
-							continue
-						}
-						// Walk secondary parents iteratively via a stack.
-						stack := []object.Hash{p}
-						for len(stack) > 0 {
-							top := stack[len(stack)-1]
-							stack = stack[:len(stack)-1]
-
-							if _, dup := seen[top]; dup {
-								continue
-							}
-
-							pc, err := r.Store.ReadCommit(top)
-							if err != nil {
-								if errors.Is(err, os.ErrNotExist) {
-									continue
-								}
-								return nil, fmt.Errorf("log all: read commit %s: %w", top, err)
-							}
-
-							seen[top] = struct{}{}
-							all = append(all, LogEntry{Hash: top, Commit: pc})
-
-							for _, pp := range pc.Parents {
-								if _, dup := seen[pp]; !dup {
-									if shallow == nil || !shallow.IsShallow(pp) {
-										stack = append(stack, pp)
-									}
-								}
-							}
-						}
-					}
+		for queue.Len() > 0 {
+			if err := ctx.Err(); err != nil {
+				yield(LogEntry{}, fmt.Errorf("log all: %w", err))
+				return
+			}
+			h := heap.Pop(queue).(object.Hash)
+			c := seen[h]
+			if opts.Filter.accepts(c) && !yield(LogEntry{Hash: h, Commit: c}, nil) {
+				return
+			}
+			parents := c.Parents
+			if opts.FirstParent && len(parents) > 1 {
+				parents = parents[:1]
+			}
+			for _, p := range parents {
+				if shallow != nil && shallow.IsShallow(p) {
+					continue
 				}
-
-				next := c.Parents[0]
-				if shallow != nil && shallow.IsShallow(next) {
-					break
+				if err := enqueue(p); err != nil {
+					yield(LogEntry{}, err)
+					return
 				}
-				current = next
 			}
 		}
 	}
-
-	// Sort by timestamp descending (newest first), break ties by hash.
-	sort.Slice(all, func(i, j int) bool {
-		if all[i].Commit.Timestamp != all[j].Commit.Timestamp {
-			return all[i].Commit.Timestamp > all[j].Commit.Timestamp
-		}
-		return all[i].Hash < all[j].Hash
-	})
-
-	// Apply limit.
-	if len(all) > limit {
-		all = all[:limit]
-	}
-
-	return all, nil
 }
 
 // LogByEntity walks first-parent history from start and returns up to limit
@@ -135,13 +129,19 @@ func (r *Repo) LogAll(limit int) ([]LogEntry, error) {
 // restricted to that path. In a shallow repository, walking stops at shallow
 // boundaries.
 func (r *Repo) LogByEntity(start object.Hash, limit int, pathFilter, entityKey string) ([]LogEntry, error) {
+	return r.LogByEntityWithOptions(start, limit, pathFilter, entityKey, LogOptions{})
+}
+
+// LogByEntityWithOptions is like LogByEntity, keeping only commits accepted
+// by opts.Filter.
+func (r *Repo) LogByEntityWithOptions(start object.Hash, limit int, pathFilter, entityKey string, opts LogOptions) ([]LogEntry, error) {
 	if limit <= 0 || start == "" || entityKey == "" {
 		return nil, nil
 	}
 
 	normalizedPath := normalizeLogEntityPath(pathFilter)
 	if normalizedPath != "" {
-		return r.logByEntityTrackedPath(start, limit, normalizedPath, entityKey)
+		return r.logByEntityTrackedPath(start, limit, normalizedPath, entityKey, opts.Filter)
 	}
 
 	shallow, _ := r.ShallowState()
@@ -158,9 +158,11 @@ func (r *Repo) LogByEntity(start object.Hash, limit int, pathFilter, entityKey s
 			return nil, fmt.Errorf("log by entity: read commit %s: %w", current, err)
 		}
 
-		matches, err := r.commitTouchesEntity(c, normalizedPath, entityKey)
-		if err != nil {
-			return nil, err
+		matches := opts.Filter.accepts(c)
+		if matches {
+			if matches, err = r.commitTouchesEntity(c, normalizedPath, entityKey); err != nil {
+				return nil, err
+			}
 		}
 		if matches {
 			results = append(results, LogEntry{Hash: current, Commit: c})
@@ -179,7 +181,7 @@ func (r *Repo) LogByEntity(start object.Hash, limit int, pathFilter, entityKey s
 	return results, nil
 }
 
-func (r *Repo) logByEntityTrackedPath(start object.Hash, limit int, relPath, entityKey string) ([]LogEntry, error) {
+func (r *Repo) logByEntityTrackedPath(start object.Hash, limit int, relPath, entityKey string, filter CommitPredicate) ([]LogEntry, error) {
 	shallowState, _ := r.ShallowState()
 
 	results := make([]LogEntry, 0, limit)
@@ -277,7 +279,7 @@ func (r *Repo) logByEntityTrackedPath(start object.Hash, limit int, relPath, ent
 			}
 		}
 
-		if touched {
+		if touched && filter.accepts(commit) {
 			results = append(results, LogEntry{Hash: currentHash, Commit: commit})
 		}
 		locator = nextLocator
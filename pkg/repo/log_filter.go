@@ -0,0 +1,153 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// CommitPredicate reports whether a commit belongs in a log. A nil
+// predicate accepts every commit.
+type CommitPredicate func(c *object.CommitObj) bool
+
+// LogOptions controls optional behavior of the *WithOptions log walks.
+type LogOptions struct {
+	// Filter limits the log to commits it accepts. The walk continues past
+	// rejected commits, so the limit counts accepted commits only.
+	Filter CommitPredicate
+
+	// FirstParent follows only the first parent of each merge. Log,
+	// LogByPaths and LogByEntity always do; LogAll walks every parent
+	// unless it is set.
+	FirstParent bool
+}
+
+func (p CommitPredicate) accepts(c *object.CommitObj) bool {
+	return p == nil || p(c)
+}
+
+// AuthorMatches accepts commits whose author ("Name <email>") matches the
+// regular expression pattern.
+func AuthorMatches(pattern string) (CommitPredicate, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid author pattern %q: %w", pattern, err)
+	}
+	return func(c *object.CommitObj) bool { return re.MatchString(c.Author) }, nil
+}
+
+// MessageMatches accepts commits whose message matches the regular
+// expression pattern.
+func MessageMatches(pattern string) (CommitPredicate, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message pattern %q: %w", pattern, err)
+	}
+	return func(c *object.CommitObj) bool { return re.MatchString(c.Message) }, nil
+}
+
+// CommittedSince accepts commits dated at or after t.
+func CommittedSince(t time.Time) CommitPredicate {
+	since := t.Unix()
+	return func(c *object.CommitObj) bool { return c.Timestamp >= since }
+}
+
+// CommittedUntil accepts commits dated at or before t.
+func CommittedUntil(t time.Time) CommitPredicate {
+	until := t.Unix()
+	return func(c *object.CommitObj) bool { return c.Timestamp <= until }
+}
+
+// MergesOnly accepts merge commits, those with more than one parent.
+func MergesOnly() CommitPredicate {
+	return func(c *object.CommitObj) bool { return len(c.Parents) > 1 }
+}
+
+// NoMerges accepts commits with at most one parent.
+func NoMerges() CommitPredicate {
+	return func(c *object.CommitObj) bool { return len(c.Parents) <= 1 }
+}
+
+// AllOf accepts commits accepted by every non-nil predicate in preds. It
+// returns nil, accepting everything, when there are none.
+func AllOf(preds ...CommitPredicate) CommitPredicate {
+	var active []CommitPredicate
+	for _, p := range preds {
+		if p != nil {
+			active = append(active, p)
+		}
+	}
+	switch len(active) {
+	case 0:
+		return nil
+	case 1:
+		return active[0]
+	}
+	return func(c *object.CommitObj) bool {
+		for _, p := range active {
+			if !p(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// LogWithOptions walks first-parent history from start like Log, returning
+// up to limit commits accepted by opts.Filter together with their hashes.
+func (r *Repo) LogWithOptions(start object.Hash, limit int, opts LogOptions) ([]LogEntry, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	var results []LogEntry
+	for entry, err := range r.LogIter(context.Background(), start, opts) {
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, entry)
+		if len(results) == limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// LogIter walks first-parent history from start, yielding the commits
+// accepted by opts.Filter one at a time so callers can stop whenever they
+// have seen enough. Once ctx is done it yields ctx's error and stops.
+func (r *Repo) LogIter(ctx context.Context, start object.Hash, opts LogOptions) iter.Seq2[LogEntry, error] {
+	return func(yield func(LogEntry, error) bool) {
+		shallow, _ := r.ShallowState()
+		for current := start; current != ""; {
+			if err := ctx.Err(); err != nil {
+				yield(LogEntry{}, fmt.Errorf("log: %w", err))
+				return
+			}
+			c, err := r.Store.ReadCommit(current)
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					yield(LogEntry{}, fmt.Errorf("log: read commit %s: %w", current, err))
+				}
+				return
+			}
+			if opts.Filter.accepts(c) && !yield(LogEntry{Hash: current, Commit: c}, nil) {
+				return
+			}
+
+			if len(c.Parents) == 0 {
+				return
+			}
+			next := c.Parents[0]
+			if shallow != nil && shallow.IsShallow(next) {
+				return
+			}
+			current = next
+		}
+	}
+}
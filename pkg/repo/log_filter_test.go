@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestLogWithOptions_ComposesPredicates(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	commits := []struct {
+		author, msg string
+		date        time.Time
+	}{
+		{"Alice <alice@example.com>", "add parser", day(1)},
+		{"Bob <bob@example.com>", "fix parser crash", day(5)},
+		{"Alice <alice@example.com>", "fix lexer", day(10)},
+		{"Alice <alice@example.com>", "docs", day(15)},
+	}
+	var hashes []object.Hash
+	for i, c := range commits {
+		if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte{byte('a' + i)}, 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := r.Add([]string{"f.txt"}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		h, err := r.CommitWithOptions(c.msg, c.author, CommitOptions{Date: c.date})
+		if err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		hashes = append(hashes, h)
+	}
+	head := hashes[len(hashes)-1]
+
+	byAuthor, err := AuthorMatches("^Alice")
+	if err != nil {
+		t.Fatalf("AuthorMatches: %v", err)
+	}
+	fixes, err := MessageMatches(`\bfix\b`)
+	if err != nil {
+		t.Fatalf("MessageMatches: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter CommitPredicate
+		limit  int
+		want   []object.Hash
+	}{
+		{"no filter", nil, 10, []object.Hash{hashes[3], hashes[2], hashes[1], hashes[0]}},
+		{"author", byAuthor, 10, []object.Hash{hashes[3], hashes[2], hashes[0]}},
+		{"author and grep", AllOf(byAuthor, fixes), 10, []object.Hash{hashes[2]}},
+		{"date range", AllOf(CommittedSince(day(5)), CommittedUntil(day(10))), 10, []object.Hash{hashes[2], hashes[1]}},
+		{"limit counts matches", byAuthor, 2, []object.Hash{hashes[3], hashes[2]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := r.LogWithOptions(head, tt.limit, LogOptions{Filter: tt.filter})
+			if err != nil {
+				t.Fatalf("LogWithOptions: %v", err)
+			}
+			var got []object.Hash
+			for _, e := range entries {
+				got = append(got, e.Hash)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	all, err := r.LogAllWithOptions(10, LogOptions{Filter: fixes})
+	if err != nil {
+		t.Fatalf("LogAllWithOptions: %v", err)
+	}
+	if len(all) != 2 || all[0].Hash != hashes[2] || all[1].Hash != hashes[1] {
+		t.Fatalf("LogAllWithOptions = %v, want fix commits newest first", all)
+	}
+
+	if _, err := AuthorMatches("("); err == nil {
+		t.Fatal("expected error for invalid author pattern")
+	}
+}
+
+func TestLogOptions_MergeFilters(t *testing.T) {
+	r, _ := setupMergeRepo(t)
+	commitFile(t, r, "a.txt", []byte("a\n"), "main work")
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	featureWork := commitFile(t, r, "b.txt", []byte("b\n"), "feature work")
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	report, err := r.Merge("feature")
+	if err != nil || report.MergeCommit == "" {
+		t.Fatalf("Merge(feature) = %+v, %v; want a merge commit", report, err)
+	}
+	merge := report.MergeCommit
+
+	entries, err := r.LogWithOptions(merge, 10, LogOptions{Filter: MergesOnly()})
+	if err != nil {
+		t.Fatalf("LogWithOptions(merges): %v", err)
+	}
+	if len(entries) != 1 || entries[0].Hash != merge {
+		t.Fatalf("merges-only log = %v, want just the merge %s", logHashes(entries), merge)
+	}
+
+	entries, err = r.LogAllWithOptions(10, LogOptions{Filter: NoMerges()})
+	if err != nil {
+		t.Fatalf("LogAllWithOptions(no merges): %v", err)
+	}
+	found := false
+	for _, e := range entries {
+		if e.Hash == merge {
+			t.Fatalf("no-merges log all includes the merge: %v", logHashes(entries))
+		}
+		found = found || e.Hash == featureWork
+	}
+	if len(entries) != 3 || !found {
+		t.Fatalf("no-merges log all = %v, want the 3 non-merge commits", logHashes(entries))
+	}
+
+	entries, err = r.LogAllWithOptions(1, LogOptions{Filter: NoMerges()})
+	if err != nil {
+		t.Fatalf("LogAllWithOptions(limit 1): %v", err)
+	}
+	if len(entries) != 1 || entries[0].Hash == merge {
+		t.Fatalf("limited no-merges log all = %v, want one non-merge commit", logHashes(entries))
+	}
+}
+
+func logHashes(entries []LogEntry) []object.Hash {
+	hashes := make([]object.Hash, len(entries))
+	for i, e := range entries {
+		hashes[i] = e.Hash
+	}
+	return hashes
+}
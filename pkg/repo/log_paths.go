@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/pathspec"
+)
+
+// LogByPaths walks first-parent history from start and returns up to limit
+// commits that added, removed or modified a file selected by paths. A root
+// commit touches every file it contains. In a shallow repository, walking
+// stops at shallow boundaries.
+func (r *Repo) LogByPaths(start object.Hash, limit int, paths *pathspec.Set) ([]LogEntry, error) {
+	return r.LogByPathsWithOptions(start, limit, paths, LogOptions{})
+}
+
+// LogByPathsWithOptions is like LogByPaths, keeping only commits accepted by
+// opts.Filter.
+func (r *Repo) LogByPathsWithOptions(start object.Hash, limit int, paths *pathspec.Set, opts LogOptions) ([]LogEntry, error) {
+	if limit <= 0 || start == "" {
+		return nil, nil
+	}
+
+	shallow, _ := r.ShallowState()
+
+	results := make([]LogEntry, 0, limit)
+	current := start
+	var afterEntries map[string]TreeFileEntry
+
+	for current != "" && len(results) < limit {
+		c, err := r.Store.ReadCommit(current)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			return nil, fmt.Errorf("log by path: read commit %s: %w", current, err)
+		}
+
+		next := object.Hash("")
+		if len(c.Parents) > 0 && (shallow == nil || !shallow.IsShallow(c.Parents[0])) {
+			next = c.Parents[0]
+		}
+		// Filters on commit metadata are cheap: only the commits they
+		// accept need their trees compared.
+		if !opts.Filter.accepts(c) {
+			current, afterEntries = next, nil
+			continue
+		}
+		if afterEntries == nil {
+			if afterEntries, err = r.treeEntriesByPath(c.TreeHash); err != nil {
+				return nil, err
+			}
+		}
+		beforeEntries := map[string]TreeFileEntry{}
+		if next != "" {
+			parent, err := r.Store.ReadCommit(next)
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				next = ""
+			case err != nil:
+				return nil, fmt.Errorf("log by path: read commit %s: %w", next, err)
+			default:
+				if beforeEntries, err = r.treeEntriesByPath(parent.TreeHash); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if treeChangeMatches(beforeEntries, afterEntries, paths) {
+			results = append(results, LogEntry{Hash: current, Commit: c})
+		}
+		current = next
+		afterEntries = beforeEntries
+	}
+
+	return results, nil
+}
+
+// treeChangeMatches reports whether a file selected by paths differs
+// between two flattened trees.
+func treeChangeMatches(before, after map[string]TreeFileEntry, paths *pathspec.Set) bool {
+	for p, a := range after {
+		if b, ok := before[p]; ok && b.BlobHash == a.BlobHash && b.Mode == a.Mode {
+			continue
+		}
+		if paths.Match(p) {
+			return true
+		}
+	}
+	for p := range before {
+		if _, ok := after[p]; !ok && paths.Match(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/pathspec"
+)
+
+func TestLogByPaths_SelectsCommitsTouchingMatchingFiles(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	first := commitFile(t, r, "README.md", []byte("readme\n"), "initial")
+	addGo := commitFile(t, r, "pkg/a.go", []byte("package pkg\n"), "add a.go")
+	addTest := commitFile(t, r, "pkg/a_test.go", []byte("package pkg\n"), "add test")
+	editDocs := commitFile(t, r, "README.md", []byte("readme v2\n"), "edit readme")
+
+	if err := os.Remove(filepath.Join(r.RootDir, "pkg", "a.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Remove([]string{"pkg/a.go"}, true); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	removeGo, err := r.Commit("remove a.go", "test-author")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	tests := []struct {
+		specs []string
+		want  []object.Hash
+	}{
+		{[]string{"pkg"}, []object.Hash{removeGo, addTest, addGo}},
+		{[]string{"**/*.go", ":!*_test.go"}, []object.Hash{removeGo, addGo}},
+		{[]string{":(icase)readme.MD"}, []object.Hash{editDocs, first}},
+		{[]string{"missing"}, nil},
+	}
+	for _, tt := range tests {
+		set, err := pathspec.ParseSet(tt.specs)
+		if err != nil {
+			t.Fatalf("ParseSet(%v): %v", tt.specs, err)
+		}
+		entries, err := r.LogByPaths(removeGo, 10, set)
+		if err != nil {
+			t.Fatalf("LogByPaths(%v): %v", tt.specs, err)
+		}
+		var got []object.Hash
+		for _, e := range entries {
+			got = append(got, e.Hash)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("LogByPaths(%v) = %v, want %v", tt.specs, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("LogByPaths(%v)[%d] = %s, want %s", tt.specs, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	set, _ := pathspec.ParseSet([]string{"pkg"})
+	entries, err := r.LogByPaths(removeGo, 1, set)
+	if err != nil {
+		t.Fatalf("LogByPaths limit: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Hash != removeGo {
+		t.Fatalf("LogByPaths with limit 1 = %v, want only %s", entries, removeGo)
+	}
+}
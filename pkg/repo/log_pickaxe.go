@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/odvcencio/graft/pkg/diff3"
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// DiffLinesMatch accepts commits whose diff against their first parent
+// adds or removes a line matching the regular expression pattern, as
+// git log -G does. Binary files are skipped, and commits whose objects
+// cannot be read do not match.
+func (r *Repo) DiffLinesMatch(pattern string) (CommitPredicate, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid diff pattern %q: %w", pattern, err)
+	}
+	return func(c *object.CommitObj) bool {
+		matched := false
+		_ = r.walkCommitChanges(c, "", func(path string, before, after []byte) bool {
+			if isBinaryContent(before) || isBinaryContent(after) {
+				return true
+			}
+			for _, line := range diff3.LineDiff(before, after) {
+				if line.Type != diff3.Equal && re.MatchString(line.Content) {
+					matched = true
+					return false
+				}
+			}
+			return true
+		})
+		return matched
+	}, nil
+}
+
+// EntityBodyContains accepts commits that changed a declaration so that
+// its body matches the regular expression pattern where it did not match
+// before, including declarations the commit added. A non-empty key
+// restricts the check to declarations whose identity key or name is key,
+// and a non-empty path to that file.
+func (r *Repo) EntityBodyContains(path, key, pattern string) (CommitPredicate, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entity pattern %q: %w", pattern, err)
+	}
+	path = normalizeLogEntityPath(path)
+	selected := func(e *entity.Entity) bool {
+		return e.Kind == entity.KindDeclaration && (key == "" || e.IdentityKey() == key || e.Name == key)
+	}
+	return func(c *object.CommitObj) bool {
+		matched := false
+		_ = r.walkCommitChanges(c, path, func(p string, before, after []byte) bool {
+			afterList, err := entity.Extract(p, after)
+			if err != nil {
+				return true
+			}
+			beforeBodies := make(map[string][]byte)
+			if beforeList, err := entity.Extract(p, before); err == nil {
+				for i := range beforeList.Entities {
+					e := &beforeList.Entities[i]
+					beforeBodies[e.IdentityKey()] = e.Body
+				}
+			}
+			for i := range afterList.Entities {
+				e := &afterList.Entities[i]
+				if !selected(e) || !re.Match(e.Body) {
+					continue
+				}
+				if prev, ok := beforeBodies[e.IdentityKey()]; !ok || !re.Match(prev) {
+					matched = true
+					return false
+				}
+			}
+			return true
+		})
+		return matched
+	}, nil
+}
+
+// walkCommitChanges calls fn with the before and after contents of each
+// file c changed relative to its first parent, in path order, until fn
+// returns false. A non-empty pathFilter restricts the walk to that path.
+func (r *Repo) walkCommitChanges(c *object.CommitObj, pathFilter string, fn func(path string, before, after []byte) bool) error {
+	afterEntries, err := r.treeEntriesByPath(c.TreeHash)
+	if err != nil {
+		return err
+	}
+	beforeEntries, err := r.firstParentEntries(c)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range changedCandidatePaths(beforeEntries, afterEntries, pathFilter) {
+		before, hasBefore := beforeEntries[p]
+		after, hasAfter := afterEntries[p]
+		beforeData, err := r.readBlobForEntry(before, hasBefore, p)
+		if err != nil {
+			return err
+		}
+		afterData, err := r.readBlobForEntry(after, hasAfter, p)
+		if err != nil {
+			return err
+		}
+		if !fn(p, beforeData, afterData) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// firstParentEntries returns the files of c's first parent by path. A root
+// commit, or one whose parent lies beyond a shallow boundary, has none.
+func (r *Repo) firstParentEntries(c *object.CommitObj) (map[string]TreeFileEntry, error) {
+	parentHash := firstParentHash(c)
+	if parentHash == "" {
+		return map[string]TreeFileEntry{}, nil
+	}
+	if shallow, _ := r.ShallowState(); shallow != nil && shallow.IsShallow(parentHash) {
+		return map[string]TreeFileEntry{}, nil
+	}
+	parent, err := r.Store.ReadCommit(parentHash)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]TreeFileEntry{}, nil
+		}
+		return nil, fmt.Errorf("read parent commit %s: %w", parentHash, err)
+	}
+	return r.treeEntriesByPath(parent.TreeHash)
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestLogPickaxe_DiffLinesAndEntityBodies(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	commitFile(t, r, "main.go", []byte("package main\n\nfunc Load() int {\n\treturn 1\n}\n\nfunc Save() {\n}\n"), "add load and save")
+	c2 := commitFile(t, r, "main.go", []byte("package main\n\nfunc Load() int {\n\treturn 1\n}\n\nfunc Save() {\n\tmu.Lock()\n}\n"), "lock in save")
+	c3 := commitFile(t, r, "main.go", []byte("package main\n\nfunc Load() int {\n\tmu.Lock()\n\treturn 1\n}\n\nfunc Save() {\n\tmu.Lock()\n}\n"), "lock in load")
+	c4 := commitFile(t, r, "main.go", []byte("package main\n\nfunc Load() int {\n\tmu.Lock()\n\treturn 2\n}\n\nfunc Save() {\n}\n"), "drop lock in save")
+	c5 := commitFile(t, r, "notes.txt", []byte("remember mu.Lock\n"), "notes")
+
+	run := func(name string, pred CommitPredicate) []object.Hash {
+		t.Helper()
+		entries, err := r.LogWithOptions(c5, 10, LogOptions{Filter: pred})
+		if err != nil {
+			t.Fatalf("%s: LogWithOptions: %v", name, err)
+		}
+		var got []object.Hash
+		for _, e := range entries {
+			got = append(got, e.Hash)
+		}
+		return got
+	}
+
+	diffLock, err := r.DiffLinesMatch(`mu\.Lock\(\)`)
+	if err != nil {
+		t.Fatalf("DiffLinesMatch: %v", err)
+	}
+	anyLock, err := r.EntityBodyContains("", "", `mu\.Lock`)
+	if err != nil {
+		t.Fatalf("EntityBodyContains: %v", err)
+	}
+	loadLock, err := r.EntityBodyContains("main.go", "Load", `mu\.Lock`)
+	if err != nil {
+		t.Fatalf("EntityBodyContains: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		pred CommitPredicate
+		want []object.Hash
+	}{
+		// c4 removes the line from Save, so -G still sees it.
+		{"diff lines", diffLock, []object.Hash{c4, c3, c2}},
+		{"any entity", anyLock, []object.Hash{c3, c2}},
+		{"named entity", loadLock, []object.Hash{c3}},
+	}
+	for _, tt := range tests {
+		got := run(tt.name, tt.pred)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+
+	if _, err := r.DiffLinesMatch("("); err == nil {
+		t.Fatal("expected an error for an invalid -G pattern")
+	}
+}
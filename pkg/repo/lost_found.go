@@ -0,0 +1,258 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// LostFoundOptions controls FindLostObjects.
+type LostFoundOptions struct {
+	// NoReflogs stops reflog entries from counting as reachable, so
+	// commits only the reflog remembers are reported too.
+	NoReflogs bool
+}
+
+// LostObject is an unreachable commit or blob.
+type LostObject struct {
+	Hash object.Hash
+	Type object.ObjectType
+
+	// Dangling reports that no other unreachable object refers to this
+	// one: a commit that is not the parent of a lost commit, or a blob
+	// outside every lost commit and tree.
+	Dangling bool
+}
+
+// DanglingCommit is the tip of a line of lost commits.
+type DanglingCommit struct {
+	Hash      object.Hash
+	Author    string
+	Timestamp int64
+	Subject   string
+}
+
+// LostFoundReport lists the objects no ref, HEAD, stash, staged file or,
+// unless LostFoundOptions.NoReflogs is set, reflog entry reaches.
+type LostFoundReport struct {
+	// Unreachable lists the lost commits and blobs, commits first, each
+	// ordered by hash.
+	Unreachable []LostObject
+
+	// DanglingCommits lists the dangling commits, newest first.
+	DanglingCommits []DanglingCommit
+}
+
+// lostFoundDir is the directory, relative to the graft directory, that
+// WriteLostFound fills.
+const lostFoundDir = "lost-found"
+
+// FindLostObjects enumerates the commits and blobs in the object store
+// that nothing reaches, so work lost to a bad reset, a dropped stash or a
+// deleted branch can be recovered.
+func (r *Repo) FindLostObjects(opts LostFoundOptions) (*LostFoundReport, error) {
+	roots, err := r.lostFoundRoots(opts)
+	if err != nil {
+		return nil, fmt.Errorf("lost-found: %w", err)
+	}
+	reachable, err := r.Store.ReachableSet(roots)
+	if err != nil {
+		return nil, fmt.Errorf("lost-found: %w", err)
+	}
+
+	lost := make(map[object.ObjectType][]object.Hash)
+	for _, objType := range []object.ObjectType{object.TypeCommit, object.TypeTree, object.TypeBlob} {
+		err := r.Store.ForEachObject(objType, func(info object.ObjectInfo) error {
+			if _, ok := reachable[info.Hash]; !ok {
+				lost[objType] = append(lost[objType], info.Hash)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("lost-found: list %s objects: %w", objType, err)
+		}
+		sort.Slice(lost[objType], func(i, j int) bool { return lost[objType][i] < lost[objType][j] })
+	}
+
+	// A lost commit's parents and tree, and a lost tree's entries, are
+	// referred to and so not dangling.
+	referenced := make(map[object.Hash]bool)
+	commits := make(map[object.Hash]*object.CommitObj, len(lost[object.TypeCommit]))
+	var trees []object.Hash
+	for _, h := range lost[object.TypeCommit] {
+		c, err := r.Store.ReadCommit(h)
+		if err != nil {
+			return nil, fmt.Errorf("lost-found: read commit %s: %w", h, err)
+		}
+		commits[h] = c
+		for _, p := range c.Parents {
+			referenced[p] = true
+		}
+		trees = append(trees, c.TreeHash)
+	}
+	trees = append(trees, lost[object.TypeTree]...)
+	inTrees, err := r.Store.ReachableSet(trees)
+	if err != nil {
+		return nil, fmt.Errorf("lost-found: %w", err)
+	}
+	for h := range inTrees {
+		referenced[h] = true
+	}
+
+	report := &LostFoundReport{}
+	for _, h := range lost[object.TypeCommit] {
+		dangling := !referenced[h]
+		report.Unreachable = append(report.Unreachable, LostObject{Hash: h, Type: object.TypeCommit, Dangling: dangling})
+		if dangling {
+			c := commits[h]
+			report.DanglingCommits = append(report.DanglingCommits, DanglingCommit{
+				Hash:      h,
+				Author:    c.Author,
+				Timestamp: c.Timestamp,
+				Subject:   commitTitle(c.Message),
+			})
+		}
+	}
+	for _, h := range lost[object.TypeBlob] {
+		report.Unreachable = append(report.Unreachable, LostObject{Hash: h, Type: object.TypeBlob, Dangling: !referenced[h]})
+	}
+	sort.SliceStable(report.DanglingCommits, func(i, j int) bool {
+		return report.DanglingCommits[i].Timestamp > report.DanglingCommits[j].Timestamp
+	})
+	return report, nil
+}
+
+// lostFoundRoots returns every object that counts as reachable on its own:
+// ref and HEAD targets, stash entries, staged blobs and, unless
+// opts.NoReflogs is set, both sides of every reflog entry.
+func (r *Repo) lostFoundRoots(opts LostFoundOptions) ([]object.Hash, error) {
+	roots, err := r.gcRoots()
+	if err != nil {
+		return nil, err
+	}
+	if head, err := r.ResolveRef("HEAD"); err == nil {
+		roots = append(roots, head)
+	}
+
+	stash, err := r.readStashStack()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range stash {
+		roots = append(roots, e.CommitHash, e.UntrackedTree)
+	}
+
+	stg, err := r.ReadStaging()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range stg.Entries {
+		roots = append(roots, e.BlobHash, e.EntityListHash, e.BaseBlobHash, e.OursBlobHash, e.TheirsBlobHash)
+	}
+
+	if !opts.NoReflogs {
+		refs, err := r.reflogRefNames()
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			entries, err := r.readReflogFile(ref, 0)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				roots = append(roots, e.OldHash, e.NewHash)
+			}
+		}
+	}
+
+	out := roots[:0]
+	for _, h := range roots {
+		if h != "" && h != zeroHash {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+// reflogRefNames lists the refs that have a reflog, HEAD included.
+func (r *Repo) reflogRefNames() ([]string, error) {
+	var names []string
+	if _, err := os.Stat(filepath.Join(r.GraftDir, "logs", "HEAD")); err == nil {
+		names = append(names, "HEAD")
+	}
+	root := filepath.Join(r.refsBaseDir(), "logs")
+	err := filepath.WalkDir(filepath.Join(root, "refs"), func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("list reflogs: %w", err)
+	}
+	return names, nil
+}
+
+// WriteLostFound writes the dangling objects of report under
+// .graft/lost-found/, replacing what an earlier run left there: each
+// commit as a file named after it under commit/ holding its hash, and each
+// blob under other/ holding its content. It returns the directory.
+func (r *Repo) WriteLostFound(report *LostFoundReport) (string, error) {
+	dir := filepath.Join(r.GraftDir, lostFoundDir)
+	for _, sub := range []string{"commit", "other"} {
+		if err := os.RemoveAll(filepath.Join(dir, sub)); err != nil {
+			return "", fmt.Errorf("lost-found: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return "", fmt.Errorf("lost-found: %w", err)
+		}
+	}
+	for _, obj := range report.Unreachable {
+		if !obj.Dangling {
+			continue
+		}
+		var path string
+		var data []byte
+		switch obj.Type {
+		case object.TypeCommit:
+			path = filepath.Join(dir, "commit", string(obj.Hash))
+			data = []byte(string(obj.Hash) + "\n")
+		case object.TypeBlob:
+			blob, err := r.Store.ReadBlob(obj.Hash)
+			if err != nil {
+				return "", fmt.Errorf("lost-found: read blob %s: %w", obj.Hash, err)
+			}
+			path = filepath.Join(dir, "other", string(obj.Hash))
+			data = blob.Data
+		default:
+			continue
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return "", fmt.Errorf("lost-found: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// DanglingCount counts the dangling objects of type objType.
+func (report *LostFoundReport) DanglingCount(objType object.ObjectType) int {
+	n := 0
+	for _, obj := range report.Unreachable {
+		if obj.Dangling && obj.Type == objType {
+			n++
+		}
+	}
+	return n
+}
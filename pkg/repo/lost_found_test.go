@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestFindLostObjects_DanglingCommitsAndBlobs(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+
+	first := commitAs(t, r, map[string]string{"a.txt": "one\n"}, "alice", "first")
+	second := commitAs(t, r, map[string]string{"a.txt": "two\n"}, "alice", "second")
+	sc, err := r.Store.ReadCommit(second)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+
+	// A line of two commits nothing points at, as a deleted branch leaves.
+	lostBase, err := r.Store.WriteCommit(&object.CommitObj{TreeHash: sc.TreeHash, Parents: []object.Hash{first}, Author: "bob", Timestamp: sc.Timestamp + 1, Message: "lost base\n"})
+	if err != nil {
+		t.Fatalf("WriteCommit: %v", err)
+	}
+	lostTip, err := r.Store.WriteCommit(&object.CommitObj{TreeHash: sc.TreeHash, Parents: []object.Hash{lostBase}, Author: "bob", Timestamp: sc.Timestamp + 2, Message: "lost tip\n\nbody\n"})
+	if err != nil {
+		t.Fatalf("WriteCommit: %v", err)
+	}
+	blob, err := r.Store.WriteBlob(&object.Blob{Data: []byte("orphan\n")})
+	if err != nil {
+		t.Fatalf("WriteBlob: %v", err)
+	}
+
+	// Resetting away from second leaves it to the reflog.
+	if err := r.UpdateRef(head, first); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+
+	report, err := r.FindLostObjects(LostFoundOptions{})
+	if err != nil {
+		t.Fatalf("FindLostObjects: %v", err)
+	}
+	lost := make(map[object.Hash]LostObject)
+	for _, obj := range report.Unreachable {
+		lost[obj.Hash] = obj
+	}
+	if _, ok := lost[second]; ok {
+		t.Fatalf("commit kept by the reflog reported as lost")
+	}
+	if obj, ok := lost[lostBase]; !ok || obj.Dangling {
+		t.Fatalf("lost base = %+v, want unreachable and not dangling", obj)
+	}
+	if obj, ok := lost[lostTip]; !ok || !obj.Dangling || obj.Type != object.TypeCommit {
+		t.Fatalf("lost tip = %+v, want a dangling commit", obj)
+	}
+	if obj, ok := lost[blob]; !ok || !obj.Dangling || obj.Type != object.TypeBlob {
+		t.Fatalf("orphan blob = %+v, want a dangling blob", obj)
+	}
+	if len(report.DanglingCommits) != 1 || report.DanglingCommits[0].Hash != lostTip || report.DanglingCommits[0].Subject != "lost tip" {
+		t.Fatalf("dangling commits = %+v", report.DanglingCommits)
+	}
+
+	noReflogs, err := r.FindLostObjects(LostFoundOptions{NoReflogs: true})
+	if err != nil {
+		t.Fatalf("FindLostObjects(NoReflogs): %v", err)
+	}
+	if len(noReflogs.DanglingCommits) != 2 || noReflogs.DanglingCommits[0].Hash != lostTip || noReflogs.DanglingCommits[1].Hash != second {
+		t.Fatalf("dangling commits without reflogs = %+v", noReflogs.DanglingCommits)
+	}
+
+	dir, err := r.WriteLostFound(report)
+	if err != nil {
+		t.Fatalf("WriteLostFound: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "commit", string(lostTip))); err != nil || string(data) != string(lostTip)+"\n" {
+		t.Fatalf("lost-found commit = %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "other", string(blob))); err != nil || string(data) != "orphan\n" {
+		t.Fatalf("lost-found blob = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "commit", string(lostBase))); !os.IsNotExist(err) {
+		t.Fatalf("non-dangling commit written to lost-found: %v", err)
+	}
+}
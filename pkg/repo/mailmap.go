@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MailmapFile is the file at the root of the working tree that maps the
+// identities recorded in commits to canonical ones.
+const MailmapFile = ".mailmap"
+
+// Mailmap maps commit identities to canonical ones, as git's .mailmap
+// does. Each line of the file takes one of the forms
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+//
+// Emails and names are matched case-insensitively; '#' starts a comment.
+// The zero Mailmap, and a nil one, map every identity to itself.
+type Mailmap struct {
+	entries []mailmapEntry
+}
+
+type mailmapEntry struct {
+	properName, properEmail string
+	commitName, commitEmail string
+}
+
+// ParseMailmap parses the content of a .mailmap file. Lines it cannot read
+// are reported with their line number.
+func ParseMailmap(data []byte) (*Mailmap, error) {
+	m := &Mailmap{}
+	for i, line := range strings.Split(string(data), "\n") {
+		if hash := strings.IndexByte(line, '#'); hash >= 0 && hash >= strings.LastIndexByte(line, '>') {
+			line = line[:hash]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name1, email1, rest, ok := cutMailmapIdentity(line)
+		if !ok {
+			return nil, fmt.Errorf("mailmap: line %d: want \"Name <email>\"", i+1)
+		}
+		e := mailmapEntry{properName: name1, commitEmail: email1}
+		if strings.TrimSpace(rest) != "" {
+			name2, email2, tail, ok := cutMailmapIdentity(rest)
+			if !ok || strings.TrimSpace(tail) != "" {
+				return nil, fmt.Errorf("mailmap: line %d: want \"Proper Name <proper@email> [Commit Name] <commit@email>\"", i+1)
+			}
+			e = mailmapEntry{properName: name1, properEmail: email1, commitName: name2, commitEmail: email2}
+		}
+		if e.properName == "" && e.properEmail == "" {
+			continue
+		}
+		m.entries = append(m.entries, e)
+	}
+	return m, nil
+}
+
+// cutMailmapIdentity splits "Name <email> rest" at the first email.
+func cutMailmapIdentity(s string) (name, email, rest string, ok bool) {
+	open := strings.IndexByte(s, '<')
+	if open < 0 {
+		return "", "", "", false
+	}
+	closing := strings.IndexByte(s[open:], '>')
+	if closing < 0 {
+		return "", "", "", false
+	}
+	closing += open
+	return strings.TrimSpace(s[:open]), strings.TrimSpace(s[open+1 : closing]), s[closing+1:], true
+}
+
+// Lookup returns the canonical name and email of the identity name and
+// email. An entry naming the commit name wins over one matching the email
+// alone; identities without an entry are returned as they are.
+func (m *Mailmap) Lookup(name, email string) (string, string) {
+	if m == nil || email == "" {
+		return name, email
+	}
+	var match *mailmapEntry
+	for i := range m.entries {
+		e := &m.entries[i]
+		if !strings.EqualFold(e.commitEmail, email) {
+			continue
+		}
+		if e.commitName != "" {
+			if strings.EqualFold(e.commitName, name) {
+				match = e
+				break
+			}
+			continue
+		}
+		if match == nil {
+			match = e
+		}
+	}
+	if match == nil {
+		return name, email
+	}
+	if match.properName != "" {
+		name = match.properName
+	}
+	if match.properEmail != "" {
+		email = match.properEmail
+	}
+	return name, email
+}
+
+// Map returns the canonical form of an author string as commits record
+// it, "Name <email>" or just a name.
+func (m *Mailmap) Map(author string) string {
+	if m == nil || len(m.entries) == 0 {
+		return author
+	}
+	open := strings.LastIndexByte(author, '<')
+	if open < 0 || !strings.HasSuffix(author, ">") {
+		return author
+	}
+	name, email := m.Lookup(strings.TrimSpace(author[:open]), author[open+1:len(author)-1])
+	if name == "" {
+		return "<" + email + ">"
+	}
+	return formatAuthor(name, email)
+}
+
+// Mailmap reads the .mailmap file at the root of the working tree. A
+// repository without one gets an empty Mailmap.
+func (r *Repo) Mailmap() (*Mailmap, error) {
+	data, err := os.ReadFile(filepath.Join(r.RootDir, MailmapFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Mailmap{}, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", MailmapFile, err)
+	}
+	return ParseMailmap(data)
+}
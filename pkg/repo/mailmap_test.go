@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testMailmap = `# Canonical identities.
+Alice Smith <alice@example.com>
+<bob@example.com> <bob@old.example.com>
+Carol Jones <carol@example.com> <cj@laptop.local>
+Dave <dave@example.com> Build Bot <bot@example.com>
+`
+
+func TestMailmapMap(t *testing.T) {
+	m, err := ParseMailmap([]byte(testMailmap))
+	if err != nil {
+		t.Fatalf("ParseMailmap: %v", err)
+	}
+	tests := []struct {
+		author string
+		want   string
+	}{
+		{"alice <alice@example.com>", "Alice Smith <alice@example.com>"},
+		{"Alice <ALICE@example.com>", "Alice Smith <ALICE@example.com>"},
+		{"Bob <bob@old.example.com>", "Bob <bob@example.com>"},
+		{"carol <cj@laptop.local>", "Carol Jones <carol@example.com>"},
+		{"Build Bot <bot@example.com>", "Dave <dave@example.com>"},
+		// The last entry only maps the bot's name at that email.
+		{"Someone <bot@example.com>", "Someone <bot@example.com>"},
+		{"Eve <eve@example.com>", "Eve <eve@example.com>"},
+		{"tester", "tester"},
+	}
+	for _, tt := range tests {
+		if got := m.Map(tt.author); got != tt.want {
+			t.Errorf("Map(%q) = %q, want %q", tt.author, got, tt.want)
+		}
+	}
+
+	var none *Mailmap
+	if got := none.Map("Eve <eve@example.com>"); got != "Eve <eve@example.com>" {
+		t.Errorf("nil Mailmap Map = %q", got)
+	}
+	if _, err := ParseMailmap([]byte("No Email Here\n")); err == nil {
+		t.Error("ParseMailmap accepted a line without an email")
+	}
+}
+
+func TestShortlogAndStatsHonorMailmap(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for i, author := range []string{"alice <alice@example.com>", "Alice Smith <alice@example.com>", "Bob <bob@old.example.com>"} {
+		writeFile(t, filepath.Join(dir, "f.txt"), []byte{byte('a' + i), '\n'})
+		if err := r.Add([]string{"f.txt"}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		if _, err := r.Commit("change", author); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, MailmapFile), []byte(testMailmap), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := r.Shortlog(ShortlogOptions{Numbered: true})
+	if err != nil {
+		t.Fatalf("Shortlog: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Author != "Alice Smith <alice@example.com>" || entries[0].Count != 2 || entries[1].Author != "Bob <bob@example.com>" {
+		t.Fatalf("Shortlog = %+v, want Alice Smith (2) and Bob at bob@example.com", entries)
+	}
+
+	report, err := r.Stats(nil)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if len(report.Authors) != 2 || report.Authors[0].Author != "Alice Smith <alice@example.com>" || report.Authors[0].Commits != 2 {
+		t.Fatalf("Stats authors = %+v, want Alice Smith with 2 commits first", report.Authors)
+	}
+}
@@ -3,6 +3,7 @@ package repo
 import (
 	"bytes"
 	"container/heap"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -32,6 +33,25 @@ type MergeReport struct {
 	TotalConflicts int
 	MergeCommit    object.Hash // set if auto-committed (clean merge)
 	IsFastForward  bool        // true if fast-forward (no merge commit created)
+
+	// AutostashPending is set by MergeWithOptions when the merge stopped on
+	// conflicts with local changes autostashed; they are left in stash@{0}.
+	AutostashPending bool
+}
+
+// ErrMergeConflicts is wrapped by errors from operations that stop, or
+// refuse to continue, because of unresolved merge conflicts. Merge itself
+// reports conflicts in its MergeReport; MergeReport.Err turns them into an
+// error.
+var ErrMergeConflicts = errors.New("unresolved conflicts")
+
+// Err returns an error wrapping ErrMergeConflicts if the merge stopped on
+// conflicts, and nil otherwise.
+func (m *MergeReport) Err() error {
+	if !m.HasConflicts {
+		return nil
+	}
+	return fmt.Errorf("merge: %w: %d conflict(s)", ErrMergeConflicts, m.TotalConflicts)
 }
 
 type mergeConflictState struct {
@@ -677,10 +697,27 @@ func (r *Repo) MergePreview(branchName string) (*MergeReport, error) {
 //  6. If clean: write files, stage, auto-commit with two parents
 //  7. If conflicts: write conflict-marker files, save merge state, do NOT commit
 func (r *Repo) Merge(branchName string) (*MergeReport, error) {
+	return r.merge(context.Background(), branchName)
+}
+
+// merge implements Merge, giving up with ctx's error if ctx is done before
+// the merge starts changing the repository.
+func (r *Repo) merge(ctx context.Context, branchName string) (report *MergeReport, err error) {
+	defer r.observe("merge", branchName)(&err)
+	return r.runMerge(ctx, branchName)
+}
+
+func (r *Repo) runMerge(ctx context.Context, branchName string) (*MergeReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("merge: %w", err)
+	}
 	input, err := r.buildMergeReport(branchName)
 	if err != nil {
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("merge: %w", err)
+	}
 
 	report := input.report
 
@@ -710,27 +747,30 @@ func (r *Repo) Merge(branchName string) (*MergeReport, error) {
 		})
 	}
 
+	// Remove deleted files first, so that a file renamed only in case is
+	// not removed again after being written under its new name.
+	for _, path := range input.deletedPaths {
+		absPath := filepath.Join(r.RootDir, filepath.FromSlash(path))
+		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("merge: remove %q: %w", path, err)
+		}
+		r.removeEmptyParents(filepath.Dir(absPath))
+	}
+
 	// 6/7. Write files to working directory.
-	for _, mf := range mergedFiles {
+	le := r.lineEndings()
+	for i, mf := range mergedFiles {
+		r.reportProgress(ProgressMerging, i+1, len(mergedFiles))
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(mf.path))
 		dir := filepath.Dir(absPath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := mkdirWorktree(r.RootDir, dir); err != nil {
 			return nil, fmt.Errorf("merge: mkdir %q: %w", dir, err)
 		}
-		if err := os.WriteFile(absPath, mf.content, filePermFromMode(mf.mode)); err != nil {
+		if err := writeWorktreeFile(absPath, le.smudge(mf.path, mf.content, mf.mode), mf.mode); err != nil {
 			return nil, fmt.Errorf("merge: write %q: %w", mf.path, err)
 		}
 	}
 
-	// Remove deleted files.
-	for _, path := range input.deletedPaths {
-		absPath := filepath.Join(r.RootDir, filepath.FromSlash(path))
-		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
-			return nil, fmt.Errorf("merge: remove %q: %w", path, err)
-		}
-		r.removeEmptyParents(filepath.Dir(absPath))
-	}
-
 	if !report.HasConflicts {
 		// Stage all merged files and commit.
 		var pathsToAdd []string
@@ -844,11 +884,11 @@ func (r *Repo) stageConflictState(conflicted []mergeConflictState, deletedPaths
 
 	for _, cf := range conflicted {
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(cf.path))
-		info, err := os.Stat(absPath)
+		info, err := os.Lstat(absPath)
 		if err != nil {
 			return fmt.Errorf("stat conflicted file %q: %w", cf.path, err)
 		}
-		data, err := os.ReadFile(absPath)
+		data, err := readWorktreeFile(absPath, info)
 		if err != nil {
 			return fmt.Errorf("read conflicted file %q: %w", cf.path, err)
 		}
@@ -869,6 +909,7 @@ func (r *Repo) stageConflictState(conflicted []mergeConflictState, deletedPaths
 		}
 		setStagingEntryStat(entry, info, normalizeFileMode(cf.mode))
 		stg.Entries[cf.path] = entry
+		delete(stg.ResolveUndo, cf.path)
 	}
 
 	if err := r.WriteStaging(stg); err != nil {
@@ -917,6 +958,7 @@ func (r *Repo) commitMerge(message, author string, parent1, parent2 object.Hash)
 		Timestamp: time.Now().Unix(),
 		Message:   message,
 	}
+	r.setCommitter(commitObj)
 
 	commitHash, err := r.Store.WriteCommit(commitObj)
 	if err != nil {
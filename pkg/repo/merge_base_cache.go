@@ -3,6 +3,7 @@ package repo
 import (
 	"errors"
 	"fmt"
+	"os"
 	"sync"
 
 	"github.com/odvcencio/graft/pkg/object"
@@ -92,8 +93,10 @@ func (s *mergeBaseTraversalState) readCommit(r *Repo, h object.Hash) (*object.Co
 
 	commit, err := r.Store.ReadCommit(h)
 	if err != nil {
-		// In shallow repos, missing commits at boundaries are expected.
-		if shallow != nil && shallow.IsShallow(h) {
+		// In shallow repos, missing commits at boundaries are expected. A
+		// boundary commit's parents are also absent, so any missing commit in
+		// a shallow repository is treated as lying beyond the boundary.
+		if shallow != nil && (shallow.IsShallow(h) || (shallow.Len() > 0 && errors.Is(err, os.ErrNotExist))) {
 			return nil, fmt.Errorf("%w: commit %s", ErrShallowBoundary, h)
 		}
 		return nil, fmt.Errorf("find merge base: read commit %s: %w", h, err)
@@ -0,0 +1,383 @@
+package repo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/diff3"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// ParentDiff is a commit's diff against one of its parents.
+type ParentDiff struct {
+	Parent object.Hash
+	Files  []FilePatch
+}
+
+// DiffParents diffs commit h against each of its parents in order, as
+// show -m does, or with firstParent against the first parent only. A root
+// commit is diffed against the empty tree under an empty Parent.
+func (r *Repo) DiffParents(h object.Hash, firstParent bool) ([]ParentDiff, error) {
+	commit, err := r.Store.ReadCommit(h)
+	if err != nil {
+		return nil, fmt.Errorf("read commit %s: %w", h, err)
+	}
+	after, err := r.treeEntriesByPath(commit.TreeHash)
+	if err != nil {
+		return nil, err
+	}
+	parents := commit.Parents
+	if len(parents) == 0 {
+		parents = []object.Hash{""}
+	}
+	if firstParent {
+		parents = parents[:1]
+	}
+
+	diffs := make([]ParentDiff, 0, len(parents))
+	for _, p := range parents {
+		before := map[string]TreeFileEntry{}
+		if p != "" {
+			pc, err := r.Store.ReadCommit(p)
+			if err != nil {
+				return nil, fmt.Errorf("read parent %s: %w", p, err)
+			}
+			if before, err = r.treeEntriesByPath(pc.TreeHash); err != nil {
+				return nil, err
+			}
+		}
+		files, err := r.treeFilePatches(before, after)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, ParentDiff{Parent: p, Files: files})
+	}
+	return diffs, nil
+}
+
+// CombinedFileDiff is one file of a combined diff: the merge result against
+// all of its parents at once. ParentBlobs and ResultBlob are empty where
+// the file is absent.
+type CombinedFileDiff struct {
+	Path        string
+	ParentBlobs []object.Hash
+	ResultBlob  object.Hash
+	Binary      bool
+	Hunks       []CombinedHunk
+}
+
+// CombinedHunk is a hunk of a combined diff. Each line starts with one
+// column per parent: "+" where the line was added relative to that parent,
+// "-" where it was removed from it, and " " otherwise.
+type CombinedHunk struct {
+	OldStart []int
+	OldCount []int
+	NewStart int
+	NewCount int
+	Lines    []string
+}
+
+// combinedLine is a line of the result, or one lost from some parents, and
+// the parents that contain it.
+type combinedLine struct {
+	text     string
+	inResult bool
+	parents  []bool
+}
+
+func (l *combinedLine) changed() bool {
+	if !l.inResult {
+		return true
+	}
+	for _, in := range l.parents {
+		if !in {
+			return true
+		}
+	}
+	return false
+}
+
+// CombinedDiff diffs commit h against all of its parents at once, as
+// show -c does. Only files that differ from every parent are listed.
+func (r *Repo) CombinedDiff(h object.Hash) ([]CombinedFileDiff, error) {
+	commit, err := r.Store.ReadCommit(h)
+	if err != nil {
+		return nil, fmt.Errorf("read commit %s: %w", h, err)
+	}
+	after, err := r.treeEntriesByPath(commit.TreeHash)
+	if err != nil {
+		return nil, err
+	}
+	befores := make([]map[string]TreeFileEntry, len(commit.Parents))
+	for i, p := range commit.Parents {
+		pc, err := r.Store.ReadCommit(p)
+		if err != nil {
+			return nil, fmt.Errorf("read parent %s: %w", p, err)
+		}
+		if befores[i], err = r.treeEntriesByPath(pc.TreeHash); err != nil {
+			return nil, err
+		}
+	}
+	if len(befores) == 0 {
+		befores = []map[string]TreeFileEntry{{}}
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, m := range append([]map[string]TreeFileEntry{after}, befores...) {
+		for p := range m {
+			if !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	var files []CombinedFileDiff
+	for _, path := range paths {
+		res, inResult := after[path]
+		differs := true
+		for _, before := range befores {
+			prev, inParent := before[path]
+			if inParent == inResult && prev.BlobHash == res.BlobHash {
+				differs = false
+				break
+			}
+		}
+		if !differs {
+			continue
+		}
+
+		fd := CombinedFileDiff{Path: path, ResultBlob: res.BlobHash}
+		resultData, err := r.readBlobForEntry(res, inResult, path)
+		if err != nil {
+			return nil, err
+		}
+		binary := isBinaryContent(resultData)
+		parentData := make([][]byte, len(befores))
+		for i, before := range befores {
+			prev, inParent := before[path]
+			fd.ParentBlobs = append(fd.ParentBlobs, prev.BlobHash)
+			if parentData[i], err = r.readBlobForEntry(prev, inParent, path); err != nil {
+				return nil, err
+			}
+			binary = binary || isBinaryContent(parentData[i])
+		}
+		if binary {
+			fd.Binary = true
+		} else {
+			fd.Hunks = buildCombinedHunks(parentData, resultData)
+		}
+		files = append(files, fd)
+	}
+	return files, nil
+}
+
+// buildCombinedHunks lines the result up against each parent and groups
+// the changed lines into hunks with patchContextLines lines of context.
+func buildCombinedHunks(parentData [][]byte, resultData []byte) []CombinedHunk {
+	result := patchLines(resultData)
+	n := len(parentData)
+
+	// inParent[p][j] reports whether result line j is unchanged from
+	// parent p; lost[p][j] holds the lines of p removed just before it.
+	inParent := make([][]bool, n)
+	lost := make([][][]string, n)
+	for p, data := range parentData {
+		inParent[p] = make([]bool, len(result))
+		lost[p] = make([][]string, len(result)+1)
+		prev := patchLines(data)
+		i, j := 0, 0
+		for _, op := range diff3.MyersDiff(prev, result) {
+			switch op.Type {
+			case diff3.Equal:
+				inParent[p][j] = true
+				i++
+				j++
+			case diff3.Delete:
+				lost[p][j] = append(lost[p][j], prev[i])
+				i++
+			case diff3.Insert:
+				j++
+			}
+		}
+	}
+
+	var seq []combinedLine
+	for j := 0; j <= len(result); j++ {
+		// Share a line lost from several parents, as long as that keeps
+		// each parent's lines in order.
+		var gap []combinedLine
+		for p := 0; p < n; p++ {
+			pos := 0
+			for _, text := range lost[p][j] {
+				found := -1
+				for k := pos; k < len(gap); k++ {
+					if gap[k].text == text && !gap[k].parents[p] {
+						found = k
+						break
+					}
+				}
+				if found < 0 {
+					gap = append(gap, combinedLine{text: text, parents: make([]bool, n)})
+					found = len(gap) - 1
+				}
+				gap[found].parents[p] = true
+				pos = found + 1
+			}
+		}
+		seq = append(seq, gap...)
+		if j < len(result) {
+			line := combinedLine{text: result[j], inResult: true, parents: make([]bool, n)}
+			for p := 0; p < n; p++ {
+				line.parents[p] = inParent[p][j]
+			}
+			seq = append(seq, line)
+		}
+	}
+
+	// oldPos[p] and newPos count the lines of parent p and of the result
+	// before the current position in seq.
+	oldPos := make([]int, n)
+	newPos := 0
+	advance := func(l *combinedLine) {
+		for p, in := range l.parents {
+			if in {
+				oldPos[p]++
+			}
+		}
+		if l.inResult {
+			newPos++
+		}
+	}
+
+	var hunks []CombinedHunk
+	prevEnd := 0
+	for k := 0; k < len(seq); {
+		if !seq[k].changed() {
+			advance(&seq[k])
+			k++
+			continue
+		}
+		// Step back over leading context. Unchanged lines are in every
+		// parent and the result, so the counters rewind by one each.
+		start := k
+		for start > prevEnd && k-start < patchContextLines && !seq[start-1].changed() {
+			start--
+		}
+		back := k - start
+		h := CombinedHunk{OldStart: make([]int, n), OldCount: make([]int, n), NewStart: newPos - back + 1}
+		for p := range oldPos {
+			h.OldStart[p] = oldPos[p] - back + 1
+		}
+
+		end := k
+		for end < len(seq) {
+			if seq[end].changed() {
+				end++
+				continue
+			}
+			run := end
+			for run < len(seq) && !seq[run].changed() {
+				run++
+			}
+			if run == len(seq) || run-end > 2*patchContextLines {
+				end = min(end+patchContextLines, len(seq))
+				break
+			}
+			end = run
+		}
+
+		for i := start; i < end; i++ {
+			l := &seq[i]
+			var cols strings.Builder
+			for p, in := range l.parents {
+				switch {
+				case l.inResult && !in:
+					cols.WriteByte('+')
+				case !l.inResult && in:
+					cols.WriteByte('-')
+				default:
+					cols.WriteByte(' ')
+				}
+				if in {
+					h.OldCount[p]++
+				}
+			}
+			if l.inResult {
+				h.NewCount++
+			}
+			h.Lines = append(h.Lines, cols.String()+l.text)
+		}
+		for i := k; i < end; i++ {
+			advance(&seq[i])
+		}
+		// An empty side is numbered from the line before it.
+		for p := range h.OldStart {
+			if h.OldCount[p] == 0 {
+				h.OldStart[p]--
+			}
+		}
+		if h.NewCount == 0 {
+			h.NewStart--
+		}
+		hunks = append(hunks, h)
+		k, prevEnd = end, end
+	}
+	return hunks
+}
+
+// WriteCombinedDiff writes files in the combined diff format, with one
+// "-" range per parent in each "@@@ ... @@@" hunk header.
+func WriteCombinedDiff(w io.Writer, files []CombinedFileDiff) error {
+	bw := bufio.NewWriter(w)
+	for _, fd := range files {
+		fmt.Fprintf(bw, "diff --combined %s\n", fd.Path)
+		hashLen := len(fd.ResultBlob)
+		for _, h := range fd.ParentBlobs {
+			hashLen = max(hashLen, len(h))
+		}
+		zero := func(h object.Hash) object.Hash {
+			if h == "" {
+				return object.Hash(strings.Repeat("0", hashLen))
+			}
+			return h
+		}
+		blobs := make([]string, len(fd.ParentBlobs))
+		for i, h := range fd.ParentBlobs {
+			blobs[i] = string(zero(h))
+		}
+		fmt.Fprintf(bw, "index %s..%s\n", strings.Join(blobs, ","), zero(fd.ResultBlob))
+		if fd.Binary {
+			fmt.Fprintf(bw, "Binary files differ\n")
+			continue
+		}
+		if len(fd.Hunks) == 0 {
+			continue
+		}
+		newName := "b/" + fd.Path
+		if fd.ResultBlob == "" {
+			newName = "/dev/null"
+		}
+		fmt.Fprintf(bw, "--- a/%s\n+++ %s\n", fd.Path, newName)
+		marker := strings.Repeat("@", len(fd.ParentBlobs)+1)
+		for _, h := range fd.Hunks {
+			bw.WriteString(marker)
+			for p := range h.OldStart {
+				fmt.Fprintf(bw, " -%s", hunkRange(h.OldStart[p], h.OldCount[p]))
+			}
+			fmt.Fprintf(bw, " +%s %s\n", hunkRange(h.NewStart, h.NewCount), marker)
+			for _, line := range h.Lines {
+				bw.WriteString(line)
+				if !strings.HasSuffix(line, "\n") {
+					bw.WriteString("\n\\ No newline at end of file\n")
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// writeMergeCommit records a merge of ours and theirs whose tree is that of
+// result.
+func writeMergeCommit(t *testing.T, r *Repo, result, ours, theirs object.Hash) object.Hash {
+	t.Helper()
+	rc, err := r.Store.ReadCommit(result)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	h, err := r.Store.WriteCommit(&object.CommitObj{
+		TreeHash:  rc.TreeHash,
+		Parents:   []object.Hash{ours, theirs},
+		Author:    "test-author",
+		Timestamp: rc.Timestamp,
+		Message:   "merge theirs",
+	})
+	if err != nil {
+		t.Fatalf("WriteCommit: %v", err)
+	}
+	return h
+}
+
+func TestMergeDiff_SeparateAndCombined(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+
+	base := commitFile(t, r, "f.txt", []byte("a\nb\nc\n"), "base")
+	ours := commitFile(t, r, "f.txt", []byte("a\nB\nc\n"), "ours")
+	if err := r.UpdateRef(head, base); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+	commitFile(t, r, "theirs.txt", []byte("theirs\n"), "theirs only")
+	theirs := commitFile(t, r, "f.txt", []byte("a\nb\nC\n"), "theirs")
+	result := commitFile(t, r, "f.txt", []byte("a\nB\nC\nd\n"), "resolved")
+	merge := writeMergeCommit(t, r, result, ours, theirs)
+
+	diffs, err := r.DiffParents(merge, false)
+	if err != nil {
+		t.Fatalf("DiffParents: %v", err)
+	}
+	if len(diffs) != 2 || diffs[0].Parent != ours || diffs[1].Parent != theirs {
+		t.Fatalf("DiffParents parents = %+v", diffs)
+	}
+	// Against ours, theirs.txt is new; against theirs only f.txt changed.
+	if len(diffs[0].Files) != 2 || diffs[0].Files[1].Path() != "theirs.txt" {
+		t.Fatalf("diff against ours = %+v", diffs[0].Files)
+	}
+	if len(diffs[1].Files) != 1 || diffs[1].Files[0].Path() != "f.txt" {
+		t.Fatalf("diff against theirs = %+v", diffs[1].Files)
+	}
+
+	first, err := r.DiffParents(merge, true)
+	if err != nil {
+		t.Fatalf("DiffParents(firstParent): %v", err)
+	}
+	if len(first) != 1 || first[0].Parent != ours {
+		t.Fatalf("DiffParents(firstParent) = %+v", first)
+	}
+
+	combined, err := r.CombinedDiff(merge)
+	if err != nil {
+		t.Fatalf("CombinedDiff: %v", err)
+	}
+	// theirs.txt matches theirs, so only f.txt differs from both parents.
+	if len(combined) != 1 || combined[0].Path != "f.txt" {
+		t.Fatalf("CombinedDiff files = %+v", combined)
+	}
+	var buf bytes.Buffer
+	if err := WriteCombinedDiff(&buf, combined); err != nil {
+		t.Fatalf("WriteCombinedDiff: %v", err)
+	}
+	// Each column marks a line against one parent: B is new only against
+	// theirs, C only against ours, and d against both.
+	want := strings.Join([]string{
+		"@@@ -1,3 -1,3 +1,4 @@@",
+		"  a",
+		" -b",
+		" +B",
+		"- c",
+		"+ C",
+		"++d",
+		"",
+	}, "\n")
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Fatalf("combined diff =\n%s\nwant suffix\n%s", got, want)
+	}
+	if !strings.HasPrefix(buf.String(), "diff --combined f.txt\n") {
+		t.Fatalf("combined diff header = %q", buf.String())
+	}
+}
@@ -93,6 +93,10 @@ func (r *Repo) threeWayTreeMerge(
 			if err != nil {
 				return nil, err
 			}
+			if isSymlinkMode(oursMap[path].Mode) || isSymlinkMode(theirsMap[path].Mode) {
+				result.addSymlinkConflict(path, oursData, oursMap[path].Mode)
+				continue
+			}
 			mergeResult, err := merge.MergeFiles(path, baseData, oursData, theirsData)
 			if err != nil {
 				return nil, fmt.Errorf("merge %q: %w", path, err)
@@ -144,6 +148,10 @@ func (r *Repo) threeWayTreeMerge(
 			if err != nil {
 				return nil, err
 			}
+			if isSymlinkMode(oursMap[path].Mode) || isSymlinkMode(theirsMap[path].Mode) {
+				result.addSymlinkConflict(path, oursData, oursMap[path].Mode)
+				continue
+			}
 			mergeResult, err := merge.MergeFiles(path, nil, oursData, theirsData)
 			if err != nil {
 				return nil, fmt.Errorf("merge %q: %w", path, err)
@@ -183,7 +191,10 @@ func (r *Repo) threeWayTreeMerge(
 				if err != nil {
 					return nil, err
 				}
-				content := renderFileConflict(oursData, nil)
+				content := oursData
+				if !isSymlinkMode(oursMap[path].Mode) {
+					content = renderFileConflict(oursData, nil)
+				}
 				result.Files = append(result.Files, ThreeWayFileResult{
 					Path:      path,
 					Content:   content,
@@ -211,7 +222,10 @@ func (r *Repo) threeWayTreeMerge(
 				if err != nil {
 					return nil, err
 				}
-				content := renderFileConflict(nil, theirsData)
+				content := theirsData
+				if !isSymlinkMode(theirsMap[path].Mode) {
+					content = renderFileConflict(nil, theirsData)
+				}
 				result.Files = append(result.Files, ThreeWayFileResult{
 					Path:      path,
 					Content:   content,
@@ -238,9 +252,31 @@ func (r *Repo) threeWayTreeMerge(
 		}
 	}
 
+	if r.ignoreCase() {
+		if err := checkMergeCaseCollisions(result, oursMap); err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
 
+// addSymlinkConflict records a conflict on a path both sides changed where
+// either side is a symlink. Link targets are not merged line by line, so
+// the path keeps our version, which may itself be a symlink.
+func (r *ThreeWayMergeResult) addSymlinkConflict(path string, oursData []byte, oursMode string) {
+	r.HasConflicts = true
+	r.TotalConflicts++
+	r.ConflictDetails = append(r.ConflictDetails, path)
+	r.Files = append(r.Files, ThreeWayFileResult{
+		Path:      path,
+		Content:   oursData,
+		Mode:      normalizeFileMode(oursMode),
+		Status:    "conflict",
+		Conflicts: 1,
+	})
+}
+
 // conflictDetailsString returns a comma-separated string of conflicted paths
 // suitable for error messages.
 func (r *ThreeWayMergeResult) conflictDetailsString() string {
@@ -248,30 +284,33 @@ func (r *ThreeWayMergeResult) conflictDetailsString() string {
 }
 
 // applyThreeWayResult writes the merge results to the working directory:
-// writing changed/conflicted/added files and removing deleted files.
+// removing deleted files and writing changed/conflicted/added files.
 func (r *Repo) applyThreeWayResult(result *ThreeWayMergeResult) error {
+	// Deletions go first: on a case-insensitive filesystem a path renamed
+	// only in case names the same file as the path it replaces.
+	for _, path := range result.DeletedPaths {
+		absPath := filepath.Join(r.RootDir, filepath.FromSlash(path))
+		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %q: %w", path, err)
+		}
+		r.removeEmptyParents(filepath.Dir(absPath))
+	}
+
+	le := r.lineEndings()
 	for _, f := range result.Files {
 		if f.Status == "unchanged" || f.Status == "deleted" {
 			continue
 		}
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
 		dir := filepath.Dir(absPath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := mkdirWorktree(r.RootDir, dir); err != nil {
 			return fmt.Errorf("mkdir %q: %w", dir, err)
 		}
-		if err := os.WriteFile(absPath, f.Content, filePermFromMode(f.Mode)); err != nil {
+		if err := writeWorktreeFile(absPath, le.smudge(f.Path, f.Content, f.Mode), f.Mode); err != nil {
 			return fmt.Errorf("write %q: %w", f.Path, err)
 		}
 	}
 
-	for _, path := range result.DeletedPaths {
-		absPath := filepath.Join(r.RootDir, filepath.FromSlash(path))
-		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("remove %q: %w", path, err)
-		}
-		r.removeEmptyParents(filepath.Dir(absPath))
-	}
-
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -192,6 +193,9 @@ func A() { println("theirs") }
 	if report.MergeCommit != "" {
 		t.Error("MergeCommit should be empty for conflicted merge")
 	}
+	if err := report.Err(); !errors.Is(err, ErrMergeConflicts) {
+		t.Errorf("report.Err() = %v, want ErrMergeConflicts", err)
+	}
 
 	// Verify conflict markers in the file on disk.
 	merged, err := os.ReadFile(filepath.Join(dir, "main.go"))
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// mirrorNamespaces are the ref namespaces a mirror replicates.
+var mirrorNamespaces = []string{"heads", "tags", "notes"}
+
+// MirrorRefspecs returns the fetch refspecs of a mirror remote, which
+// force-update every branch, tag and note under its own local name.
+func MirrorRefspecs() []string {
+	specs := make([]string, len(mirrorNamespaces))
+	for i, ns := range mirrorNamespaces {
+		specs[i] = "+refs/" + ns + "/*:refs/" + ns + "/*"
+	}
+	return specs
+}
+
+// IsMirrorRef reports whether the ref name, as used on the wire (e.g.
+// "heads/main"), falls in a namespace that mirrors replicate.
+func IsMirrorRef(name string) bool {
+	for _, ns := range mirrorNamespaces {
+		if strings.HasPrefix(name, ns+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// MirrorRefs returns the local branches, tags and notes keyed by their wire
+// name (e.g. "heads/main").
+func (r *Repo) MirrorRefs() (map[string]object.Hash, error) {
+	out := make(map[string]object.Hash)
+	for _, ns := range mirrorNamespaces {
+		refs, err := r.ListRefs(ns)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("list mirror refs: %w", err)
+		}
+		for name, h := range refs {
+			out[name] = h
+		}
+	}
+	return out, nil
+}
+
+// SetRemoteMirror marks remoteName as a mirror: fetches replace the local
+// branches, tags and notes with the remote's and prune the ones it no longer
+// has, and a push without refs mirrors the repository to it.
+func (r *Repo) SetRemoteMirror(remoteName string) error {
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Remotes[remoteName]; !ok {
+		return fmt.Errorf("set remote mirror: remote %q is not configured", remoteName)
+	}
+	if cfg.RemoteSettings == nil {
+		cfg.RemoteSettings = make(map[string]*RemoteConfig)
+	}
+	rc := cfg.RemoteSettings[remoteName]
+	if rc == nil {
+		rc = &RemoteConfig{}
+		cfg.RemoteSettings[remoteName] = rc
+	}
+	rc.Mirror = true
+	rc.Fetch = MirrorRefspecs()
+	return r.WriteConfig(cfg)
+}
+
+// IsMirrorRemote reports whether remoteName was configured as a mirror.
+func (r *Repo) IsMirrorRemote(remoteName string) (bool, error) {
+	rc, err := r.remoteConfig(remoteName)
+	if err != nil || rc == nil {
+		return false, err
+	}
+	return rc.Mirror, nil
+}
@@ -59,7 +59,7 @@ func (r *Repo) syncModule(entry ModuleEntry, lockEntry ModuleLockEntry) error {
 		return fmt.Errorf("read commit %s: %w", lockEntry.Commit, err)
 	}
 
-	files, err := r.FlattenTree(commit.TreeHash)
+	files, err := r.flattenCheckoutTree(commit.TreeHash)
 	if err != nil {
 		return fmt.Errorf("flatten tree: %w", err)
 	}
@@ -72,7 +72,7 @@ func (r *Repo) syncModule(entry ModuleEntry, lockEntry ModuleLockEntry) error {
 	}
 
 	// Ensure the module directory exists.
-	if err := os.MkdirAll(moduleDir, 0o755); err != nil {
+	if err := mkdirWorktree(r.RootDir, moduleDir); err != nil {
 		return fmt.Errorf("mkdir module dir: %w", err)
 	}
 
@@ -81,7 +81,7 @@ func (r *Repo) syncModule(entry ModuleEntry, lockEntry ModuleLockEntry) error {
 		absPath := filepath.Join(moduleDir, filepath.FromSlash(f.Path))
 
 		dir := filepath.Dir(absPath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := mkdirWorktree(r.RootDir, dir); err != nil {
 			return fmt.Errorf("mkdir %q: %w", dir, err)
 		}
 
@@ -90,7 +90,7 @@ func (r *Repo) syncModule(entry ModuleEntry, lockEntry ModuleLockEntry) error {
 			return fmt.Errorf("read blob for %q: %w", f.Path, err)
 		}
 
-		if err := os.WriteFile(absPath, blob.Data, filePermFromMode(f.Mode)); err != nil {
+		if err := writeWorktreeFile(absPath, blob.Data, f.Mode); err != nil {
 			return fmt.Errorf("write %q: %w", f.Path, err)
 		}
 	}
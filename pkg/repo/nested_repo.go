@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// nestedRepoMarkers name the entries that make a directory the root of
+// another repository: a graft (or legacy got) repository, or a git
+// repository or worktree, where .git may be a file.
+var nestedRepoMarkers = []string{".graft", ".got", ".git"}
+
+// isNestedRepoDir reports whether the directory absDir is the root of a
+// repository of its own. Status and add treat such a directory as a
+// single untracked entry instead of descending into another repository's
+// files.
+func isNestedRepoDir(absDir string) bool {
+	for _, marker := range nestedRepoMarkers {
+		if _, err := os.Lstat(filepath.Join(absDir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedRepoRoot returns the repo-relative root of the outermost nested
+// repository that contains rel, or "" when rel is not inside one. Only the
+// directories above rel are checked.
+func (r *Repo) nestedRepoRoot(rel string) string {
+	root := ""
+	for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if isNestedRepoDir(filepath.Join(r.RootDir, filepath.FromSlash(dir))) {
+			root = dir
+		}
+	}
+	return root
+}
+
+// warnNestedRepo tells the user that add left the nested repository at rel
+// out.
+func warnNestedRepo(rel string) {
+	fmt.Fprintf(os.Stderr, "warning: skipping nested repository %s/ (use 'graft module add' to track it)\n", rel)
+}
@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNestedRepo_StatusAddAndCleanSkipContents(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitFile(t, r, "main.go", []byte("package main\n"), "initial")
+
+	if _, err := Init(filepath.Join(r.RootDir, "vendor", "other")); err != nil {
+		t.Fatalf("Init nested graft repo: %v", err)
+	}
+	writeFile(t, filepath.Join(r.RootDir, "vendor", "other", "lib.go"), []byte("package other\n"))
+	writeFile(t, filepath.Join(r.RootDir, "tools", "gitrepo", ".git"), []byte("gitdir: /elsewhere\n"))
+	writeFile(t, filepath.Join(r.RootDir, "tools", "gitrepo", "tool.go"), []byte("package tool\n"))
+	writeFile(t, filepath.Join(r.RootDir, "notes.txt"), []byte("notes\n"))
+
+	entries, err := r.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	var untracked []string
+	for _, e := range entries {
+		if e.IndexStatus == StatusUntracked {
+			untracked = append(untracked, e.Path)
+			if e.NestedRepo != strings.HasSuffix(e.Path, "/") {
+				t.Fatalf("%s NestedRepo = %v", e.Path, e.NestedRepo)
+			}
+		}
+	}
+	if want := []string{"notes.txt", "tools/gitrepo/", "vendor/other/"}; !reflect.DeepEqual(untracked, want) {
+		t.Fatalf("untracked = %v, want %v", untracked, want)
+	}
+
+	if err := r.Add([]string{"."}); err != nil {
+		t.Fatalf("Add(.): %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	got := keys(stg.Entries)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"main.go", "notes.txt"}) {
+		t.Fatalf("staged paths = %v, want the nested repositories left out", got)
+	}
+	err = r.Add([]string{"vendor/other/lib.go"})
+	if err == nil || !strings.Contains(err.Error(), "nested repository") {
+		t.Fatalf("Add(vendor/other/lib.go) error = %v, want nested repository error", err)
+	}
+
+	paths, err := r.CleanDryRun(CleanOptions{})
+	if err != nil {
+		t.Fatalf("CleanDryRun: %v", err)
+	}
+	if len(paths) != 0 {
+		t.Fatalf("clean would remove %v, want nested repositories kept", paths)
+	}
+	if _, err := os.Stat(filepath.Join(r.RootDir, "vendor", "other", "lib.go")); err != nil {
+		t.Fatalf("nested file: %v", err)
+	}
+}
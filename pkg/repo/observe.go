@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// Progress phases reported through SetProgress by Repo operations, besides
+// the store and transport phases of package object. Their counts are files,
+// not objects, except for ProgressImporting and ProgressExporting, which
+// count commits.
+const (
+	ProgressStaging     = "staging"      // Add storing file contents
+	ProgressExtracting  = "extracting"   // Add extracting entities
+	ProgressCheckingOut = "checking out" // Checkout writing the target tree
+	ProgressMerging     = "merging"      // Merge writing merged files
+	ProgressImporting   = "importing"    // ImportGit converting commits
+	ProgressExporting   = "exporting"    // ExportGit writing commits
+)
+
+// Event kinds.
+const (
+	EventStart = "start"
+	EventDone  = "done"
+)
+
+// Event marks the start or the end of a long-running operation on a Repo:
+// "add", "checkout", "merge" or "gc". Operations may nest; a merge that
+// fast-forwards, for instance, runs a checkout.
+type Event struct {
+	Op   string
+	Kind string // EventStart or EventDone
+	// Target is what the operation acts on: the branch or commit checked
+	// out or merged, or the paths added. It is empty for gc.
+	Target string
+	// Err and Elapsed are set on EventDone: the error the operation failed
+	// with, if any, and how long it ran.
+	Err     error
+	Elapsed time.Duration
+}
+
+// EventFunc receives operation events. It is called synchronously on the
+// goroutine running the operation, so it should return quickly.
+type EventFunc func(Event)
+
+// SetEventHook registers fn to receive start and done events of add,
+// checkout, merge and gc operations on r. A nil fn disables the hook.
+// Progress within those operations goes to the function set by SetProgress.
+func (r *Repo) SetEventHook(fn EventFunc) {
+	r.events = fn
+}
+
+// observe reports the start of op on target and returns a function that
+// reports its end with the error *errp holds, for use with defer:
+//
+//	defer r.observe("gc", "")(&err)
+func (r *Repo) observe(op, target string) func(errp *error) {
+	fn := r.events
+	if fn == nil {
+		return func(*error) {}
+	}
+	start := time.Now()
+	fn(Event{Op: op, Kind: EventStart, Target: target})
+	return func(errp *error) {
+		fn(Event{Op: op, Kind: EventDone, Target: target, Err: *errp, Elapsed: time.Since(start)})
+	}
+}
+
+// reportProgress sends the file count of a Repo progress phase to the
+// function set by SetProgress, if any.
+func (r *Repo) reportProgress(phase string, done, total int) {
+	if r.progress != nil {
+		r.progress(object.Progress{Phase: phase, Objects: done, Total: total})
+	}
+}
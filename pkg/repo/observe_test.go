@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestObserve_CheckoutAndMergeReportProgressAndEvents(t *testing.T) {
+	r, _ := setupMergeRepo(t)
+	commitFile(t, r, "a.txt", []byte("a\n"), "main work")
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	commitFile(t, r, "b.txt", []byte("b\n"), "feature work")
+
+	var events []Event
+	var progress []object.Progress
+	r.SetEventHook(func(e Event) { events = append(events, e) })
+	r.SetProgress(func(p object.Progress) { progress = append(progress, p) })
+
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	if _, err := r.Merge("feature"); err != nil {
+		t.Fatalf("Merge(feature): %v", err)
+	}
+	if err := r.Checkout("no-such-branch"); err == nil {
+		t.Fatal("Checkout(no-such-branch) succeeded")
+	}
+
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Op+":"+e.Kind+":"+e.Target)
+	}
+	// The merge stages its files, so an add nests inside it.
+	want := []string{
+		"checkout:start:main", "checkout:done:main",
+		"merge:start:feature", "add:start:b.txt", "add:done:b.txt", "merge:done:feature",
+		"checkout:start:no-such-branch", "checkout:done:no-such-branch",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if last := events[len(events)-1]; last.Err == nil {
+		t.Fatal("failed checkout reported no error")
+	}
+	if events[1].Err != nil || events[1].Elapsed <= 0 {
+		t.Fatalf("checkout done event = %+v, want no error and a duration", events[1])
+	}
+
+	last := map[string]object.Progress{}
+	for _, p := range progress {
+		last[p.Phase] = p
+	}
+	for _, phase := range []string{ProgressCheckingOut, ProgressMerging, ProgressStaging} {
+		p, ok := last[phase]
+		if !ok || p.Objects != p.Total || p.Total == 0 {
+			t.Fatalf("last %q progress = %+v (reported %v), want a completed count", phase, p, ok)
+		}
+	}
+}
@@ -19,9 +19,23 @@ type OwnerRule struct {
 	regex    *regexp.Regexp // compiled regex for ** or * patterns
 }
 
-// OwnersFile holds the parsed rules from a .graftowners file.
+// OwnersFile holds the parsed rules from a .graftowners or CODEOWNERS file.
 type OwnersFile struct {
 	Rules []OwnerRule
+
+	// LastMatchWins gives a path the owners of the last path rule matching
+	// it, as CODEOWNERS does, instead of the union of every match. Entity
+	// rules still add their owners on top.
+	LastMatchWins bool
+}
+
+// ownersFileNames lists where ReadOwnersFile looks for ownership rules, in
+// order. The first file found is used.
+var ownersFileNames = []string{
+	".graftowners",
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
 }
 
 // entityPrefixes lists the known entity kind prefixes that identify entity
@@ -63,7 +77,19 @@ func isEntityPattern(pattern string) bool {
 // Entity patterns have the form "func:*Handler" or "type:Config*".
 // Path patterns use glob syntax: "pkg/auth/**", "*.go", etc.
 func ParseOwnersFile(data []byte) (*OwnersFile, error) {
-	of := &OwnersFile{}
+	return parseOwners(data, false)
+}
+
+// ParseCodeOwners parses CODEOWNERS content. It differs from
+// ParseOwnersFile in that the last matching path rule decides a file's
+// owners, and a pattern listed without owners leaves its files unowned.
+// Section headers such as "[Docs]" are skipped.
+func ParseCodeOwners(data []byte) (*OwnersFile, error) {
+	return parseOwners(data, true)
+}
+
+func parseOwners(data []byte, codeOwners bool) (*OwnersFile, error) {
+	of := &OwnersFile{LastMatchWins: codeOwners}
 	scanner := bufio.NewScanner(strings.NewReader(string(data)))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -72,9 +98,15 @@ func ParseOwnersFile(data []byte) (*OwnersFile, error) {
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		if codeOwners && strings.HasPrefix(line, "[") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
 
 		fields := strings.Fields(line)
-		if len(fields) < 2 {
+		if len(fields) < 2 && !(codeOwners && len(fields) == 1) {
 			// A line with only a pattern and no owners is invalid; skip.
 			continue
 		}
@@ -92,7 +124,7 @@ func ParseOwnersFile(data []byte) (*OwnersFile, error) {
 			rule.hasSlash = strings.Contains(pattern, "/")
 			// Compile regex for patterns with ** or * for flexible matching.
 			if strings.Contains(pattern, "**") {
-				if re, err := regexp.Compile(globToRegex(pattern)); err == nil {
+				if re, err := regexp.Compile(globToRegex(strings.TrimPrefix(pattern, "/"))); err == nil {
 					rule.regex = re
 				}
 			}
@@ -110,7 +142,8 @@ func ParseOwnersFile(data []byte) (*OwnersFile, error) {
 // OwnersFor returns all unique owners that match the given path and/or entity key.
 // Path patterns are matched against the file path. Entity patterns are matched
 // against the entity key (e.g., "func:LoginHandler"). Both types of matches
-// accumulate; duplicates are removed while preserving order.
+// accumulate, except that with LastMatchWins only the last matching path rule
+// counts; duplicates are removed while preserving order.
 func (o *OwnersFile) OwnersFor(path, entityKey string) []string {
 	if o == nil || len(o.Rules) == 0 {
 		return nil
@@ -121,8 +154,28 @@ func (o *OwnersFile) OwnersFor(path, entityKey string) []string {
 	var owners []string
 	seen := make(map[string]bool)
 
+	if o.LastMatchWins && path != "" {
+		var last *OwnerRule
+		for i := range o.Rules {
+			if !o.Rules[i].IsEntity && matchPathPattern(&o.Rules[i], path) {
+				last = &o.Rules[i]
+			}
+		}
+		if last != nil {
+			for _, owner := range last.Owners {
+				if !seen[owner] {
+					seen[owner] = true
+					owners = append(owners, owner)
+				}
+			}
+		}
+	}
+
 	for _, rule := range o.Rules {
 		matched := false
+		if o.LastMatchWins && !rule.IsEntity {
+			continue
+		}
 
 		if rule.IsEntity {
 			// Match entity key against the entity pattern.
@@ -181,8 +234,12 @@ func matchEntityPattern(pattern, entityKey string) bool {
 }
 
 // matchPathPattern checks if a file path matches a path-based owner rule.
+// A leading slash anchors the pattern at the repository root, where it
+// matches a directory's contents as well as the directory itself.
 func matchPathPattern(rule *OwnerRule, path string) bool {
 	pattern := rule.Pattern
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
 
 	// Handle trailing slash as directory prefix match.
 	if strings.HasSuffix(pattern, "/") {
@@ -196,8 +253,10 @@ func matchPathPattern(rule *OwnerRule, path string) bool {
 
 	// Pattern contains a slash: match against the full relative path.
 	if rule.hasSlash {
-		matched, _ := filepath.Match(pattern, path)
-		return matched
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
+		}
+		return anchored && strings.HasPrefix(path, pattern+"/")
 	}
 
 	// Pattern without a slash: match against the filename component only.
@@ -206,16 +265,28 @@ func matchPathPattern(rule *OwnerRule, path string) bool {
 	return matched
 }
 
-// ReadOwnersFile loads and parses .graftowners from the repository root.
-// Returns an empty OwnersFile if the file does not exist.
+// ReadOwnersFile loads and parses the ownership rules in the working tree:
+// .graftowners, or failing that the first of .github/CODEOWNERS, CODEOWNERS
+// and docs/CODEOWNERS. Returns an empty OwnersFile if none exists.
 func (r *Repo) ReadOwnersFile() (*OwnersFile, error) {
-	ownerPath := filepath.Join(r.RootDir, ".graftowners")
-	data, err := os.ReadFile(ownerPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return &OwnersFile{}, nil
+	for _, name := range ownersFileNames {
+		data, err := os.ReadFile(filepath.Join(r.RootDir, filepath.FromSlash(name)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
 		}
-		return nil, err
+		return parseOwnersNamed(name, data)
+	}
+	return &OwnersFile{}, nil
+}
+
+// parseOwnersNamed parses data with the syntax the owners file name
+// implies.
+func parseOwnersNamed(name string, data []byte) (*OwnersFile, error) {
+	if name == ".graftowners" {
+		return ParseOwnersFile(data)
 	}
-	return ParseOwnersFile(data)
+	return ParseCodeOwners(data)
 }
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -328,3 +329,34 @@ func TestOwnersFor_GlobstarPattern(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCodeOwners_LastMatchWins(t *testing.T) {
+	data := []byte(`# CODEOWNERS
+*                 @everyone
+/pkg/api          @api-team   # the HTTP layer
+[Docs]
+docs/             @docs-team
+/pkg/api/vendor
+`)
+	of, err := ParseCodeOwners(data)
+	if err != nil {
+		t.Fatalf("ParseCodeOwners: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"main.go", []string{"@everyone"}},
+		{"pkg/api/handler.go", []string{"@api-team"}},
+		{"cmd/pkg/api/x.go", []string{"@everyone"}},
+		{"docs/guide.md", []string{"@docs-team"}},
+		{"pkg/api/vendor/lib.go", nil},
+	}
+	for _, tt := range tests {
+		got := of.OwnersFor(tt.path, "")
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("OwnersFor(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package repo
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// OwnedChange is one change in an ownership report: an entity, or a whole
+// file when the file has no entity-level changes (a binary or unsupported
+// file, for example).
+type OwnedChange struct {
+	Path string
+	// EntityKey is empty for a file-level change.
+	EntityKey  string
+	ChangeType string // "create", "modify", or "delete"
+	Owners     []string
+	// Authors lists, newest first, the authors of the commits in the range
+	// that changed the entity or file.
+	Authors []string
+}
+
+// OwnerChanges groups the changes one owner is responsible for.
+type OwnerChanges struct {
+	Owner   string
+	Changes []OwnedChange
+}
+
+// OwnershipReport attributes the entity-level changes of a revision range
+// to their owners.
+type OwnershipReport struct {
+	OldCommit object.Hash // empty when the range starts at a root commit
+	NewCommit object.Hash
+	// OwnersFile names the rules file used, or is empty when none exists.
+	OwnersFile string
+	Changes    []OwnedChange
+	// Owners is sorted by number of changes, most first.
+	Owners  []OwnerChanges
+	Unowned []OwnedChange
+}
+
+// EntityOwnership reports which owners own the entities changed by spec:
+// "A..B" or "A...B" as for DiffRange, or a single revision for the changes
+// that commit made to its first parent. An empty spec means HEAD. Owners
+// come from the .graftowners or CODEOWNERS file in the range's newest
+// commit, falling back to the working tree.
+func (r *Repo) EntityOwnership(spec string) (*OwnershipReport, error) {
+	oldCommit, newCommit, err := r.ownershipRange(spec)
+	if err != nil {
+		return nil, err
+	}
+	newObj, err := r.Store.ReadCommit(newCommit)
+	if err != nil {
+		return nil, fmt.Errorf("owners: read commit %s: %w", newCommit, err)
+	}
+	after, err := r.treeEntriesByPath(newObj.TreeHash)
+	if err != nil {
+		return nil, fmt.Errorf("owners: %w", err)
+	}
+	before := map[string]TreeFileEntry{}
+	if oldCommit != "" {
+		oldObj, err := r.Store.ReadCommit(oldCommit)
+		if err != nil {
+			return nil, fmt.Errorf("owners: read commit %s: %w", oldCommit, err)
+		}
+		if before, err = r.treeEntriesByPath(oldObj.TreeHash); err != nil {
+			return nil, fmt.Errorf("owners: %w", err)
+		}
+	}
+
+	rules, source, err := r.ownersFileInTree(newObj.TreeHash)
+	if err != nil {
+		return nil, fmt.Errorf("owners: %w", err)
+	}
+	if source == "" {
+		if rules, err = r.ReadOwnersFile(); err != nil {
+			return nil, fmt.Errorf("owners: %w", err)
+		}
+		if len(rules.Rules) > 0 {
+			source = "working tree"
+		}
+	}
+
+	authors, err := r.ownershipAuthors(oldCommit, newCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OwnershipReport{OldCommit: oldCommit, NewCommit: newCommit, OwnersFile: source}
+	for _, c := range ownedChanges(r, before, after) {
+		c.Owners = rules.OwnersFor(c.Path, c.EntityKey)
+		c.Authors = authors[c.Path+"\x00"+c.EntityKey]
+		report.Changes = append(report.Changes, c)
+	}
+
+	byOwner := make(map[string]*OwnerChanges)
+	for _, c := range report.Changes {
+		if len(c.Owners) == 0 {
+			report.Unowned = append(report.Unowned, c)
+			continue
+		}
+		for _, owner := range c.Owners {
+			oc := byOwner[owner]
+			if oc == nil {
+				oc = &OwnerChanges{Owner: owner}
+				byOwner[owner] = oc
+			}
+			oc.Changes = append(oc.Changes, c)
+		}
+	}
+	for _, oc := range byOwner {
+		report.Owners = append(report.Owners, *oc)
+	}
+	sort.Slice(report.Owners, func(i, j int) bool {
+		a, b := report.Owners[i], report.Owners[j]
+		if len(a.Changes) != len(b.Changes) {
+			return len(a.Changes) > len(b.Changes)
+		}
+		return a.Owner < b.Owner
+	})
+	return report, nil
+}
+
+// ownershipRange resolves an EntityOwnership spec to the commits it
+// compares. oldCommit is empty for a root commit.
+func (r *Repo) ownershipRange(spec string) (oldCommit, newCommit object.Hash, err error) {
+	commitOf := func(rev string) (object.Hash, error) {
+		_, c, err := r.resolveDiffTree(revOrHead(rev))
+		if err != nil {
+			return "", err
+		}
+		if c == "" {
+			return "", fmt.Errorf("owners: %s is not a commit", revOrHead(rev))
+		}
+		return c, nil
+	}
+
+	if left, right, ok := strings.Cut(spec, "..."); ok {
+		a, err := commitOf(left)
+		if err != nil {
+			return "", "", err
+		}
+		b, err := commitOf(right)
+		if err != nil {
+			return "", "", err
+		}
+		base, err := r.FindMergeBase(a, b)
+		if err != nil {
+			return "", "", fmt.Errorf("owners %s: %w", spec, err)
+		}
+		if base == "" {
+			return "", "", fmt.Errorf("owners %s: no merge base", spec)
+		}
+		return base, b, nil
+	}
+	if left, right, ok := strings.Cut(spec, ".."); ok {
+		if oldCommit, err = commitOf(left); err != nil {
+			return "", "", err
+		}
+		newCommit, err = commitOf(right)
+		return oldCommit, newCommit, err
+	}
+
+	if newCommit, err = commitOf(spec); err != nil {
+		return "", "", err
+	}
+	c, err := r.Store.ReadCommit(newCommit)
+	if err != nil {
+		return "", "", fmt.Errorf("owners: read commit %s: %w", newCommit, err)
+	}
+	return firstParentHash(c), newCommit, nil
+}
+
+// ownedChanges lists the entity changes between two flattened trees, plus
+// a file-level change for each changed file without entity changes.
+func ownedChanges(r *Repo, before, after map[string]TreeFileEntry) []OwnedChange {
+	// Entity diffing is best-effort, as in DiffTrees; files whose entities
+	// cannot be extracted are still reported at file level.
+	entityChanges, _ := diffEntryEntities(r, before, after)
+
+	var changes []OwnedChange
+	withEntities := make(map[string]bool)
+	for _, ec := range entityChanges {
+		withEntities[ec.Path] = true
+		changes = append(changes, OwnedChange{Path: ec.Path, EntityKey: ec.EntityKey, ChangeType: ec.ChangeType})
+	}
+	for _, f := range changedFiles(before, after) {
+		if !withEntities[f.Path] {
+			changes = append(changes, f)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].EntityKey < changes[j].EntityKey
+	})
+	return changes
+}
+
+// changedFiles returns a file-level change for every path whose blob
+// differs between before and after.
+func changedFiles(before, after map[string]TreeFileEntry) []OwnedChange {
+	var changes []OwnedChange
+	for p, a := range after {
+		b, ok := before[p]
+		switch {
+		case !ok:
+			changes = append(changes, OwnedChange{Path: p, ChangeType: "create"})
+		case b.BlobHash != a.BlobHash:
+			changes = append(changes, OwnedChange{Path: p, ChangeType: "modify"})
+		}
+	}
+	for p := range before {
+		if _, ok := after[p]; !ok {
+			changes = append(changes, OwnedChange{Path: p, ChangeType: "delete"})
+		}
+	}
+	return changes
+}
+
+// ownershipAuthors maps each entity and file changed by the commits between
+// oldCommit and newCommit, keyed by path and entity key, to the authors of
+// those commits, newest first. Merge commits are skipped: the commits they
+// bring in are walked on their own.
+func (r *Repo) ownershipAuthors(oldCommit, newCommit object.Hash) (map[string][]string, error) {
+	rr := &RevRange{Include: []object.Hash{newCommit}}
+	if oldCommit != "" {
+		rr.Exclude = []object.Hash{oldCommit}
+	}
+	commits, err := r.RevList(rr, RevListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("owners: %w", err)
+	}
+
+	authors := make(map[string][]string)
+	for _, h := range commits {
+		c, err := r.Store.ReadCommit(h)
+		if err != nil {
+			return nil, fmt.Errorf("owners: read commit %s: %w", h, err)
+		}
+		if len(c.Parents) > 1 {
+			continue
+		}
+		after, err := r.treeEntriesByPath(c.TreeHash)
+		if err != nil {
+			return nil, fmt.Errorf("owners: %w", err)
+		}
+		before := map[string]TreeFileEntry{}
+		if len(c.Parents) == 1 {
+			parent, err := r.Store.ReadCommit(c.Parents[0])
+			if err != nil {
+				return nil, fmt.Errorf("owners: read parent %s: %w", c.Parents[0], err)
+			}
+			if before, err = r.treeEntriesByPath(parent.TreeHash); err != nil {
+				return nil, fmt.Errorf("owners: %w", err)
+			}
+		}
+		for _, change := range ownedChanges(r, before, after) {
+			// Record the file too, so that a file reported at file level
+			// for the whole range still finds the commits that changed
+			// its entities.
+			for _, key := range []string{change.Path + "\x00" + change.EntityKey, change.Path + "\x00"} {
+				if !slices.Contains(authors[key], c.Author) {
+					authors[key] = append(authors[key], c.Author)
+				}
+			}
+		}
+	}
+	return authors, nil
+}
+
+// ownersFileInTree reads the ownership rules committed in tree, looking in
+// the same places as ReadOwnersFile. source is empty when there are none.
+func (r *Repo) ownersFileInTree(tree object.Hash) (of *OwnersFile, source string, err error) {
+	for _, name := range ownersFileNames {
+		entry, ok, err := r.treeEntryAtPath(tree, name)
+		if err != nil {
+			return nil, "", err
+		}
+		if !ok {
+			continue
+		}
+		blob, err := r.Store.ReadBlob(entry.BlobHash)
+		if err != nil {
+			return nil, "", fmt.Errorf("read %s: %w", name, err)
+		}
+		if of, err = parseOwnersNamed(name, blob.Data); err != nil {
+			return nil, "", fmt.Errorf("parse %s: %w", name, err)
+		}
+		return of, name, nil
+	}
+	return &OwnersFile{}, "", nil
+}
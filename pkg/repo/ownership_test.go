@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEntityOwnership_GroupsRangeChangesByOwner(t *testing.T) {
+	r := initRepoWithFile(t, ".github/CODEOWNERS", []byte("*  @core\n/api/  @api-team\n"))
+	base := commitFile(t, r, "api/handler.go", []byte("package api\n\nfunc Serve() {}\n\nfunc Stop() {}\n"), "add api")
+
+	commitFile(t, r, "api/handler.go", []byte("package api\n\nfunc Serve() { println(1) }\n\nfunc Stop() {}\n"), "change serve")
+	head := commitFile(t, r, "README.md", []byte("hello\n"), "add readme")
+
+	report, err := r.EntityOwnership(string(base) + ".." + string(head))
+	if err != nil {
+		t.Fatalf("EntityOwnership: %v", err)
+	}
+	if report.OldCommit != base || report.NewCommit != head {
+		t.Fatalf("range = %s..%s, want %s..%s", report.OldCommit, report.NewCommit, base, head)
+	}
+	if report.OwnersFile != ".github/CODEOWNERS" {
+		t.Fatalf("OwnersFile = %q, want .github/CODEOWNERS", report.OwnersFile)
+	}
+	if len(report.Owners) != 2 {
+		t.Fatalf("owners = %+v, want @api-team and @core", report.Owners)
+	}
+
+	byOwner := map[string]OwnerChanges{}
+	for _, oc := range report.Owners {
+		byOwner[oc.Owner] = oc
+	}
+	api := byOwner["@api-team"].Changes
+	if len(api) != 1 || api[0].Path != "api/handler.go" || !strings.HasSuffix(api[0].EntityKey, ":Serve") {
+		t.Fatalf("@api-team changes = %+v, want only api/handler.go Serve", api)
+	}
+	if api[0].ChangeType != "modify" || len(api[0].Authors) != 1 || api[0].Authors[0] != "test-author" {
+		t.Fatalf("@api-team change = %+v, want a modify by test-author", api[0])
+	}
+	core := byOwner["@core"].Changes
+	if len(core) != 1 || core[0].Path != "README.md" || core[0].EntityKey != "" || core[0].ChangeType != "create" {
+		t.Fatalf("@core changes = %+v, want README.md created", core)
+	}
+	if len(report.Unowned) != 0 {
+		t.Fatalf("unowned = %+v, want none", report.Unowned)
+	}
+}
+
+func TestEntityOwnership_SingleCommitWithoutOwnersFile(t *testing.T) {
+	r := initRepoWithFile(t, "main.go", []byte("package main\n\nfunc main() {}\n"))
+	root, err := r.Commit("initial", "test-author")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	report, err := r.EntityOwnership("")
+	if err != nil {
+		t.Fatalf("EntityOwnership: %v", err)
+	}
+	if report.OldCommit != "" || report.NewCommit != root {
+		t.Fatalf("range = %q..%s, want root commit %s", report.OldCommit, report.NewCommit, root)
+	}
+	if report.OwnersFile != "" || len(report.Owners) != 0 {
+		t.Fatalf("report = %+v, want no owners", report)
+	}
+	if len(report.Unowned) == 0 || len(report.Unowned) != len(report.Changes) {
+		t.Fatalf("unowned = %+v, want every change of %+v", report.Unowned, report.Changes)
+	}
+	for _, c := range report.Unowned {
+		if c.Path != "main.go" || c.ChangeType != "create" {
+			t.Fatalf("unowned change = %+v, want main.go created", c)
+		}
+	}
+}
@@ -0,0 +1,919 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/diff3"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// patchContextLines is the number of unchanged lines around each hunk.
+const patchContextLines = 3
+
+// patchSignature ends every patch written by WritePatch, as git ends its
+// patches with the git version.
+const patchSignature = "-- \ngraft\n"
+
+// Patch is one commit's worth of changes in the mailbox format written by
+// format-patch and read by apply and am.
+type Patch struct {
+	// Commit is the commit the patch was made from. It is empty for a
+	// patch parsed from a plain diff.
+	Commit  object.Hash
+	Author  string
+	Date    time.Time
+	Subject string
+	Body    string
+	Files   []FilePatch
+}
+
+// FilePatch is the change to one file. OldPath is empty for a created file
+// and NewPath is empty for a deleted one. OldBlob and NewBlob carry the
+// full blob hashes so that apply can fall back to a three-way merge when
+// the recorded preimage is in the object store.
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	OldBlob object.Hash
+	NewBlob object.Hash
+	OldMode string
+	NewMode string
+	Binary  bool
+	Hunks   []PatchHunk
+}
+
+// PatchHunk is one @@ section of a file patch. Each line keeps its
+// ' ', '-' or '+' prefix and its line terminator; a line without a
+// terminator is the last line of a file that does not end in a newline.
+type PatchHunk struct {
+	OldStart, OldCount int
+	NewStart, NewCount int
+	Lines              []string
+}
+
+// Path returns the path the file patch applies to.
+func (fp *FilePatch) Path() string {
+	if fp.NewPath != "" {
+		return fp.NewPath
+	}
+	return fp.OldPath
+}
+
+// Message returns the commit message recorded in the patch.
+func (p *Patch) Message() string {
+	if p.Body == "" {
+		return p.Subject
+	}
+	return p.Subject + "\n\n" + p.Body
+}
+
+// CommitPatch builds the patch for the changes commit h makes to its first
+// parent. A root commit is diffed against the empty tree.
+func (r *Repo) CommitPatch(h object.Hash) (*Patch, error) {
+	commit, err := r.Store.ReadCommit(h)
+	if err != nil {
+		return nil, fmt.Errorf("read commit %s: %w", h, err)
+	}
+	var oldEntries []TreeFileEntry
+	if len(commit.Parents) > 0 {
+		parent, err := r.Store.ReadCommit(commit.Parents[0])
+		if err != nil {
+			return nil, fmt.Errorf("read parent %s: %w", commit.Parents[0], err)
+		}
+		if oldEntries, err = r.FlattenTree(parent.TreeHash); err != nil {
+			return nil, err
+		}
+	}
+	newEntries, err := r.FlattenTree(commit.TreeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, body, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+	p := &Patch{
+		Commit:  h,
+		Author:  commit.Author,
+		Date:    commitAuthorTime(commit),
+		Subject: strings.TrimSpace(subject),
+		Body:    strings.Trim(body, "\n"),
+	}
+
+	if p.Files, err = r.treeFilePatches(indexByPath(oldEntries), indexByPath(newEntries)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// treeFilePatches diffs two flattened trees, returning one FilePatch per
+// changed path in path order.
+func (r *Repo) treeFilePatches(oldByPath, newByPath map[string]TreeFileEntry) ([]FilePatch, error) {
+	paths := make([]string, 0, len(oldByPath)+len(newByPath))
+	for path := range oldByPath {
+		paths = append(paths, path)
+	}
+	for path := range newByPath {
+		if _, ok := oldByPath[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var files []FilePatch
+	for _, path := range paths {
+		oldEntry, hasOld := oldByPath[path]
+		newEntry, hasNew := newByPath[path]
+		if hasOld && hasNew && oldEntry.BlobHash == newEntry.BlobHash && normalizeFileMode(oldEntry.Mode) == normalizeFileMode(newEntry.Mode) {
+			continue
+		}
+		fp := FilePatch{}
+		var oldData, newData []byte
+		var err error
+		if hasOld {
+			fp.OldPath, fp.OldBlob, fp.OldMode = path, oldEntry.BlobHash, normalizeFileMode(oldEntry.Mode)
+			if oldData, err = r.readBlobData(oldEntry.BlobHash); err != nil {
+				return nil, err
+			}
+		}
+		if hasNew {
+			fp.NewPath, fp.NewBlob, fp.NewMode = path, newEntry.BlobHash, normalizeFileMode(newEntry.Mode)
+			if newData, err = r.readBlobData(newEntry.BlobHash); err != nil {
+				return nil, err
+			}
+		}
+		if isBinaryContent(oldData) || isBinaryContent(newData) {
+			fp.Binary = fp.OldBlob != fp.NewBlob
+		} else {
+			fp.Hunks = buildPatchHunks(oldData, newData)
+		}
+		files = append(files, fp)
+	}
+	return files, nil
+}
+
+// FormatPatches builds one patch per non-merge commit in the revision
+// range, oldest first. A single revision without ".." means every commit
+// since it, as in "<rev>..HEAD".
+func (r *Repo) FormatPatches(revs []string) ([]*Patch, error) {
+	if len(revs) == 1 && !strings.Contains(revs[0], "..") {
+		revs = []string{revs[0] + ".."}
+	}
+	rr, err := r.ParseRevRange(revs)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := r.RevList(rr, RevListOptions{TopoOrder: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var patches []*Patch
+	for i := len(hashes) - 1; i >= 0; i-- {
+		commit, err := r.Store.ReadCommit(hashes[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(commit.Parents) > 1 {
+			continue
+		}
+		p, err := r.CommitPatch(hashes[i])
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, p)
+	}
+	return patches, nil
+}
+
+// commitAuthorTime returns the author date of c in its recorded zone.
+func commitAuthorTime(c *object.CommitObj) time.Time {
+	t := time.Unix(c.Timestamp, 0)
+	if zone, err := time.Parse("-0700", c.AuthorTimezone); err == nil {
+		t = t.In(zone.Location())
+	}
+	return t
+}
+
+// patchLines splits data into lines that keep their terminators.
+func patchLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// buildPatchHunks diffs oldData against newData and groups the changes into
+// hunks with patchContextLines lines of context.
+func buildPatchHunks(oldData, newData []byte) []PatchHunk {
+	ops := diff3.MyersDiff(patchLines(oldData), patchLines(newData))
+
+	var hunks []PatchHunk
+	oldLine, newLine := 1, 1
+	prevEnd := 0
+	for i := 0; i < len(ops); {
+		if ops[i].Type == diff3.Equal {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// Back up over leading context, then extend until the gap to the
+		// next change is longer than two contexts' worth.
+		start := max(i-patchContextLines, prevEnd)
+		end := i
+		for end < len(ops) {
+			if ops[end].Type != diff3.Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Type == diff3.Equal {
+				run++
+			}
+			if run == len(ops) || run-end > 2*patchContextLines {
+				end = min(end+patchContextLines, len(ops))
+				break
+			}
+			end = run
+		}
+
+		h := PatchHunk{OldStart: oldLine - (i - start), NewStart: newLine - (i - start)}
+		for _, op := range ops[start:end] {
+			switch op.Type {
+			case diff3.Equal:
+				h.Lines = append(h.Lines, " "+op.Line)
+				h.OldCount++
+				h.NewCount++
+			case diff3.Delete:
+				h.Lines = append(h.Lines, "-"+op.Line)
+				h.OldCount++
+			case diff3.Insert:
+				h.Lines = append(h.Lines, "+"+op.Line)
+				h.NewCount++
+			}
+		}
+		for _, op := range ops[i:end] {
+			if op.Type != diff3.Insert {
+				oldLine++
+			}
+			if op.Type != diff3.Delete {
+				newLine++
+			}
+		}
+		// An empty side is numbered from the line before it, as in
+		// "@@ -0,0 +1,3 @@".
+		if h.OldCount == 0 {
+			h.OldStart--
+		}
+		if h.NewCount == 0 {
+			h.NewStart--
+		}
+		hunks = append(hunks, h)
+		i, prevEnd = end, end
+	}
+	return hunks
+}
+
+// WritePatch writes p as one mailbox message. n and total number the
+// subject as "[PATCH n/total]"; a lone patch is just "[PATCH]". The full
+// blob hashes on each index line let apply --3way find the preimage.
+func WritePatch(w io.Writer, p *Patch, n, total int) error {
+	bw := bufio.NewWriter(w)
+	commit := p.Commit
+	if commit == "" {
+		commit = object.Hash(strings.Repeat("0", 40))
+	}
+	fmt.Fprintf(bw, "From %s Mon Sep 17 00:00:00 2001\n", commit)
+	if p.Author != "" {
+		fmt.Fprintf(bw, "From: %s\n", p.Author)
+	}
+	if !p.Date.IsZero() {
+		fmt.Fprintf(bw, "Date: %s\n", p.Date.Format(time.RFC1123Z))
+	}
+	if total > 1 {
+		fmt.Fprintf(bw, "Subject: [PATCH %d/%d] %s\n", n, total, p.Subject)
+	} else {
+		fmt.Fprintf(bw, "Subject: [PATCH] %s\n", p.Subject)
+	}
+	bw.WriteString("\n")
+	if p.Body != "" {
+		for _, line := range strings.Split(p.Body, "\n") {
+			if mboxFromLine.MatchString(line) {
+				bw.WriteString(">")
+			}
+			bw.WriteString(line + "\n")
+		}
+		bw.WriteString("\n")
+	}
+	bw.WriteString("---\n")
+	writePatchStat(bw, p.Files)
+	bw.WriteString("\n")
+	for i := range p.Files {
+		writeFilePatch(bw, &p.Files[i])
+	}
+	bw.WriteString(patchSignature)
+	bw.WriteString("\n")
+	return bw.Flush()
+}
+
+// WriteDiff writes files as a plain diff, without the mail headers and
+// diffstat WritePatch adds.
+func WriteDiff(w io.Writer, files []FilePatch) error {
+	bw := bufio.NewWriter(w)
+	for i := range files {
+		writeFilePatch(bw, &files[i])
+	}
+	return bw.Flush()
+}
+
+// writePatchStat writes the diffstat between the message and the diff.
+func writePatchStat(w *bufio.Writer, files []FilePatch) {
+	width := 0
+	for i := range files {
+		width = max(width, len(files[i].Path()))
+	}
+	insertions, deletions := 0, 0
+	for i := range files {
+		fp := &files[i]
+		if fp.Binary {
+			fmt.Fprintf(w, " %-*s | Bin\n", width, fp.Path())
+			continue
+		}
+		added, removed := 0, 0
+		for _, h := range fp.Hunks {
+			for _, line := range h.Lines {
+				switch line[0] {
+				case '+':
+					added++
+				case '-':
+					removed++
+				}
+			}
+		}
+		insertions += added
+		deletions += removed
+		fmt.Fprintf(w, " %-*s | %d %s%s\n", width, fp.Path(), added+removed,
+			strings.Repeat("+", min(added, 40)), strings.Repeat("-", min(removed, 40)))
+	}
+	noun := "files"
+	if len(files) == 1 {
+		noun = "file"
+	}
+	fmt.Fprintf(w, " %d %s changed, %d insertions(+), %d deletions(-)\n", len(files), noun, insertions, deletions)
+}
+
+func writeFilePatch(w *bufio.Writer, fp *FilePatch) {
+	oldName, newName := "a/"+fp.Path(), "b/"+fp.Path()
+	fmt.Fprintf(w, "diff --graft %s %s\n", oldName, newName)
+
+	oldBlob, newBlob := fp.OldBlob, fp.NewBlob
+	switch {
+	case fp.OldPath == "":
+		fmt.Fprintf(w, "new file mode %s\n", fp.NewMode)
+		oldBlob, oldName = object.Hash(strings.Repeat("0", len(newBlob))), "/dev/null"
+		fmt.Fprintf(w, "index %s..%s\n", oldBlob, newBlob)
+	case fp.NewPath == "":
+		fmt.Fprintf(w, "deleted file mode %s\n", fp.OldMode)
+		newBlob, newName = object.Hash(strings.Repeat("0", len(oldBlob))), "/dev/null"
+		fmt.Fprintf(w, "index %s..%s\n", oldBlob, newBlob)
+	case fp.OldMode != fp.NewMode:
+		fmt.Fprintf(w, "old mode %s\nnew mode %s\n", fp.OldMode, fp.NewMode)
+		if oldBlob != newBlob {
+			fmt.Fprintf(w, "index %s..%s\n", oldBlob, newBlob)
+		}
+	default:
+		fmt.Fprintf(w, "index %s..%s %s\n", oldBlob, newBlob, fp.NewMode)
+	}
+
+	if fp.Binary {
+		fmt.Fprintf(w, "Binary files %s and %s differ\n", oldName, newName)
+		return
+	}
+	if len(fp.Hunks) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range fp.Hunks {
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(h.OldStart, h.OldCount), hunkRange(h.NewStart, h.NewCount))
+		for _, line := range h.Lines {
+			w.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				w.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// mboxSeparator matches the "From <hash> <date>" line that starts each
+// message in a mailbox.
+var mboxSeparator = regexp.MustCompile(`^From [0-9a-fA-F]+ `)
+
+// mboxFromLine matches the body lines WritePatch escapes with a ">" so they
+// cannot be taken for a separator: "From " behind any number of ">".
+// Parsing strips one ">" again, so quoted lines survive a round trip.
+var mboxFromLine = regexp.MustCompile(`^>*From `)
+
+// patchSubjectPrefix matches the "[PATCH n/m]" tag format-patch adds.
+var patchSubjectPrefix = regexp.MustCompile(`^(\[[^\]]*PATCH[^\]]*\]\s*)+`)
+
+// hunkHeader matches "@@ -l,s +l,s @@".
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatches reads the patches in a mailbox written by WritePatch, or a
+// single plain diff without mail headers.
+func ParsePatches(data []byte) ([]*Patch, error) {
+	lines := patchLines(data)
+	var messages [][]string
+	for _, line := range lines {
+		if mboxSeparator.MatchString(line) || len(messages) == 0 {
+			messages = append(messages, nil)
+		}
+		messages[len(messages)-1] = append(messages[len(messages)-1], line)
+	}
+
+	var patches []*Patch
+	for _, msg := range messages {
+		p, err := parsePatchMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.Files) == 0 && p.Subject == "" {
+			continue
+		}
+		patches = append(patches, p)
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no patches found")
+	}
+	return patches, nil
+}
+
+// parsePatchMessage parses one mailbox message, or a plain diff when the
+// lines do not start with a mailbox separator.
+func parsePatchMessage(lines []string) (*Patch, error) {
+	p := &Patch{}
+	if len(lines) == 0 || !mboxSeparator.MatchString(lines[0]) {
+		files, err := parseFilePatches(lines)
+		if err != nil {
+			return nil, err
+		}
+		p.Files = files
+		return p, nil
+	}
+	p.Commit = object.Hash(strings.Fields(lines[0])[1])
+
+	msg, err := mail.ReadMessage(strings.NewReader(strings.Join(lines[1:], "")))
+	if err != nil {
+		return nil, fmt.Errorf("patch %s: %w", shortHash(p.Commit), err)
+	}
+	p.Author = msg.Header.Get("From")
+	if addr, err := mail.ParseAddress(p.Author); err == nil && addr.Name != "" {
+		p.Author = fmt.Sprintf("%s <%s>", addr.Name, addr.Address)
+	}
+	if date, err := mail.ParseDate(msg.Header.Get("Date")); err == nil {
+		p.Date = date
+	}
+	p.Subject = patchSubjectPrefix.ReplaceAllString(strings.TrimSpace(msg.Header.Get("Subject")), "")
+
+	rest, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	bodyLines := patchLines(rest)
+	var body []string
+	i := 0
+	for ; i < len(bodyLines); i++ {
+		line := bodyLines[i]
+		if line == "---\n" || strings.HasPrefix(line, "diff --") {
+			break
+		}
+		if strings.HasPrefix(line, ">") && mboxFromLine.MatchString(line[1:]) {
+			line = line[1:]
+		}
+		body = append(body, line)
+	}
+	p.Body = strings.TrimSpace(strings.Join(body, ""))
+
+	if p.Files, err = parseFilePatches(bodyLines[i:]); err != nil {
+		return nil, fmt.Errorf("patch %q: %w", p.Subject, err)
+	}
+	return p, nil
+}
+
+// parseFilePatches parses the per-file diffs in lines, ignoring any text
+// before the first diff header, such as a diffstat, and stopping at the
+// signature.
+func parseFilePatches(lines []string) ([]FilePatch, error) {
+	var files []FilePatch
+	var cur *FilePatch
+	start := func() {
+		files = append(files, FilePatch{})
+		cur = &files[len(files)-1]
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\n")
+		switch {
+		case line == "-- ":
+			return files, nil
+		case strings.HasPrefix(line, "diff --"):
+			start()
+			if idx := strings.LastIndex(line, " b/"); idx >= 0 {
+				cur.NewPath = line[idx+3:]
+				cur.OldPath = cur.NewPath
+			}
+		case cur == nil && !strings.HasPrefix(line, "--- "):
+			continue
+		case strings.HasPrefix(line, "new file mode "):
+			cur.OldPath, cur.NewMode = "", strings.TrimPrefix(line, "new file mode ")
+		case strings.HasPrefix(line, "deleted file mode "):
+			cur.NewPath, cur.OldMode = "", strings.TrimPrefix(line, "deleted file mode ")
+		case strings.HasPrefix(line, "old mode "):
+			cur.OldMode = strings.TrimPrefix(line, "old mode ")
+		case strings.HasPrefix(line, "new mode "):
+			cur.NewMode = strings.TrimPrefix(line, "new mode ")
+		case strings.HasPrefix(line, "index "):
+			fields := strings.Fields(strings.TrimPrefix(line, "index "))
+			if oldBlob, newBlob, ok := strings.Cut(fields[0], ".."); ok {
+				cur.OldBlob, cur.NewBlob = patchBlobHash(oldBlob), patchBlobHash(newBlob)
+			}
+			if len(fields) > 1 {
+				cur.OldMode, cur.NewMode = fields[1], fields[1]
+			}
+		case strings.HasPrefix(line, "Binary files "):
+			cur.Binary = true
+		case strings.HasPrefix(line, "--- "):
+			if cur == nil || len(cur.Hunks) > 0 {
+				start()
+			}
+			cur.OldPath = patchPath(strings.TrimPrefix(line, "--- "), "a/")
+		case strings.HasPrefix(line, "+++ "):
+			cur.NewPath = patchPath(strings.TrimPrefix(line, "+++ "), "b/")
+		case strings.HasPrefix(line, "@@ "):
+			h, next, err := parsePatchHunk(lines, i)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", cur.Path(), err)
+			}
+			cur.Hunks = append(cur.Hunks, h)
+			i = next - 1
+		}
+	}
+	return files, nil
+}
+
+// parsePatchHunk parses the hunk whose header is lines[i] and returns it
+// with the index of the line after it.
+func parsePatchHunk(lines []string, i int) (PatchHunk, int, error) {
+	m := hunkHeader.FindStringSubmatch(lines[i])
+	if m == nil {
+		return PatchHunk{}, 0, fmt.Errorf("malformed hunk header %q", strings.TrimSpace(lines[i]))
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	h := PatchHunk{OldCount: count(m[2]), NewCount: count(m[4])}
+	h.OldStart, _ = strconv.Atoi(m[1])
+	h.NewStart, _ = strconv.Atoi(m[3])
+
+	oldLeft, newLeft := h.OldCount, h.NewCount
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "\\") {
+			if n := len(h.Lines); n > 0 {
+				h.Lines[n-1] = strings.TrimSuffix(h.Lines[n-1], "\n")
+			}
+			continue
+		}
+		if oldLeft == 0 && newLeft == 0 {
+			break
+		}
+		if line == "\n" {
+			// Some mailers strip the space from empty context lines.
+			line = " \n"
+		}
+		switch line[0] {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		default:
+			return PatchHunk{}, 0, fmt.Errorf("hunk at line %d is truncated", h.OldStart)
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return PatchHunk{}, 0, fmt.Errorf("hunk at line %d has more lines than its header", h.OldStart)
+		}
+		h.Lines = append(h.Lines, line)
+	}
+	if oldLeft != 0 || newLeft != 0 {
+		return PatchHunk{}, 0, fmt.Errorf("hunk at line %d is truncated", h.OldStart)
+	}
+	return h, i, nil
+}
+
+// patchPath strips the a/ or b/ prefix from a ---/+++ path. /dev/null is
+// the empty path.
+func patchPath(name, prefix string) string {
+	name, _, _ = strings.Cut(name, "\t")
+	if name == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(name, prefix)
+}
+
+// patchBlobHash reads a blob hash from an index line; all zeros means none.
+func patchBlobHash(s string) object.Hash {
+	if strings.Trim(s, "0") == "" {
+		return ""
+	}
+	return object.Hash(s)
+}
+
+// applyHunks applies hunks to data. Each hunk is placed where its preimage
+// matches, searching outward from its recorded position, offset by how far
+// earlier hunks moved.
+func applyHunks(data []byte, hunks []PatchHunk) ([]byte, error) {
+	lines := patchLines(data)
+	var out []string
+	pos, offset := 0, 0
+	for n, h := range hunks {
+		var before, after []string
+		for _, line := range h.Lines {
+			if line[0] != '+' {
+				before = append(before, line[1:])
+			}
+			if line[0] != '-' {
+				after = append(after, line[1:])
+			}
+		}
+
+		want := h.OldStart - 1
+		if h.OldCount == 0 {
+			want = h.OldStart
+		}
+		want += offset
+		at := -1
+		for d := 0; at < 0 && (want-d >= pos || want+d <= len(lines)-len(before)); d++ {
+			for _, k := range []int{want - d, want + d} {
+				if k >= pos && k+len(before) <= len(lines) && slices.Equal(lines[k:k+len(before)], before) {
+					at = k
+					break
+				}
+			}
+		}
+		if at < 0 {
+			return nil, fmt.Errorf("hunk #%d (line %d) does not apply", n+1, h.OldStart)
+		}
+		out = append(out, lines[pos:at]...)
+		out = append(out, after...)
+		pos = at + len(before)
+		offset = at - (want - offset)
+	}
+	out = append(out, lines[pos:]...)
+	return []byte(strings.Join(out, "")), nil
+}
+
+// ApplyOptions controls ApplyPatch.
+type ApplyOptions struct {
+	// Index stages the patched files as well as writing them to the
+	// working tree.
+	Index bool
+
+	// ThreeWay falls back to a three-way merge when a file's hunks do not
+	// apply: the preimage blob named on the patch's index line is patched
+	// and merged with the working-tree file. Conflicts are written with
+	// markers and recorded in the staging area. It implies Index.
+	ThreeWay bool
+}
+
+// ApplyResult lists the paths an applied patch touched.
+type ApplyResult struct {
+	Paths     []string
+	Merged    []string // applied by three-way merge
+	Conflicts []string // merged with conflicts
+}
+
+// ErrApplyConflict is returned when a three-way apply leaves conflicts.
+// The other files of the patch have been applied.
+type ErrApplyConflict struct {
+	Paths []string
+}
+
+func (e *ErrApplyConflict) Error() string {
+	return fmt.Sprintf("apply: conflicts in %s", strings.Join(e.Paths, ", "))
+}
+
+// plannedPatchFile is the outcome of applying one file patch, computed
+// before anything is written so that a patch applies all or nothing.
+type plannedPatchFile struct {
+	path     string
+	data     []byte
+	mode     string
+	remove   bool
+	conflict *mergeConflictState
+	merged   bool
+}
+
+// ApplyPatch applies the file changes in p to the working tree, and to the
+// staging area when opts.Index or opts.ThreeWay is set. Nothing is written
+// unless every file applies, cleanly or, with opts.ThreeWay, by merge.
+func (r *Repo) ApplyPatch(p *Patch, opts ApplyOptions) (*ApplyResult, error) {
+	if opts.ThreeWay {
+		opts.Index = true
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		return nil, fmt.Errorf("apply: %w", err)
+	}
+
+	plan := make([]plannedPatchFile, 0, len(p.Files))
+	for i := range p.Files {
+		pf, err := r.planPatchFile(&p.Files[i], stg, opts)
+		if err != nil {
+			return nil, fmt.Errorf("apply: %s: %w", p.Files[i].Path(), err)
+		}
+		plan = append(plan, pf)
+	}
+
+	result := &ApplyResult{}
+	var toStage, toUnstage []string
+	var conflicts []mergeConflictState
+	for _, pf := range plan {
+		absPath := filepath.Join(r.RootDir, filepath.FromSlash(pf.path))
+		result.Paths = append(result.Paths, pf.path)
+		if pf.remove {
+			if err := os.Remove(absPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("apply: remove %s: %w", pf.path, err)
+			}
+			r.removeEmptyParents(filepath.Dir(absPath))
+			if _, tracked := stg.Entries[pf.path]; tracked {
+				toUnstage = append(toUnstage, absPath)
+			}
+			continue
+		}
+		if err := mkdirWorktree(r.RootDir, filepath.Dir(absPath)); err != nil {
+			return nil, fmt.Errorf("apply: %w", err)
+		}
+		if err := writeWorktreeFile(absPath, pf.data, pf.mode); err != nil {
+			return nil, fmt.Errorf("apply: write %s: %w", pf.path, err)
+		}
+		if pf.merged {
+			result.Merged = append(result.Merged, pf.path)
+		}
+		if pf.conflict != nil {
+			conflicts = append(conflicts, *pf.conflict)
+			result.Conflicts = append(result.Conflicts, pf.path)
+			continue
+		}
+		toStage = append(toStage, absPath)
+	}
+	r.invalidateStatusCache()
+
+	if opts.Index {
+		if len(toStage) > 0 {
+			if err := r.Add(toStage); err != nil {
+				return nil, fmt.Errorf("apply: %w", err)
+			}
+		}
+		if len(toUnstage) > 0 {
+			if err := r.Remove(toUnstage, true); err != nil {
+				return nil, fmt.Errorf("apply: %w", err)
+			}
+		}
+		if len(conflicts) > 0 {
+			if err := r.stageConflictState(conflicts, nil); err != nil {
+				return nil, fmt.Errorf("apply: %w", err)
+			}
+		}
+	}
+	if len(result.Conflicts) > 0 {
+		return result, &ErrApplyConflict{Paths: result.Conflicts}
+	}
+	return result, nil
+}
+
+// planPatchFile computes the new content of the file fp patches.
+func (r *Repo) planPatchFile(fp *FilePatch, stg *Staging, opts ApplyOptions) (plannedPatchFile, error) {
+	path := fp.Path()
+	if path == "" || isOutsideRepo(path) || filepath.IsAbs(path) || path == ".graft" || strings.HasPrefix(path, ".graft/") {
+		return plannedPatchFile{}, fmt.Errorf("invalid path")
+	}
+	pf := plannedPatchFile{path: path, mode: fp.NewMode}
+	if pf.mode == "" {
+		pf.mode = object.TreeModeFile
+		if entry, ok := stg.Entries[path]; ok && entry.Mode != "" {
+			pf.mode = entry.Mode
+		}
+	}
+
+	current, err := r.ReadWorktreeFile(path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return plannedPatchFile{}, err
+	}
+	switch {
+	case fp.OldPath == "" && exists:
+		return plannedPatchFile{}, fmt.Errorf("already exists in working tree")
+	case fp.OldPath != "" && !exists:
+		return plannedPatchFile{}, fmt.Errorf("does not exist in working tree")
+	}
+
+	if fp.Binary {
+		if fp.OldBlob != "" {
+			old, err := r.readBlobData(fp.OldBlob)
+			if err != nil {
+				return plannedPatchFile{}, fmt.Errorf("binary patch needs preimage: %w", err)
+			}
+			if !bytes.Equal(old, current) {
+				return plannedPatchFile{}, fmt.Errorf("binary patch does not apply")
+			}
+		}
+		if fp.NewPath == "" {
+			pf.remove = true
+			return pf, nil
+		}
+		if pf.data, err = r.readBlobData(fp.NewBlob); err != nil {
+			return plannedPatchFile{}, fmt.Errorf("binary patch needs postimage: %w", err)
+		}
+		return pf, nil
+	}
+
+	data, applyErr := applyHunks(current, fp.Hunks)
+	if applyErr == nil {
+		if fp.NewPath == "" {
+			if len(data) != 0 {
+				return plannedPatchFile{}, fmt.Errorf("file to delete has changed")
+			}
+			pf.remove = true
+		}
+		pf.data = data
+		return pf, nil
+	}
+	if !opts.ThreeWay || fp.OldPath == "" || fp.NewPath == "" {
+		return plannedPatchFile{}, applyErr
+	}
+
+	// Three-way fallback: patch the recorded preimage, then merge that
+	// change into the working-tree file.
+	if fp.OldBlob == "" || !r.Store.Has(fp.OldBlob) {
+		return plannedPatchFile{}, fmt.Errorf("%w, and the preimage blob is not available for a three-way merge", applyErr)
+	}
+	base, err := r.readBlobData(fp.OldBlob)
+	if err != nil {
+		return plannedPatchFile{}, err
+	}
+	theirs, err := applyHunks(base, fp.Hunks)
+	if err != nil {
+		return plannedPatchFile{}, fmt.Errorf("patch does not apply to its own preimage: %w", err)
+	}
+	merged := diff3.Merge(base, current, theirs)
+	pf.data, pf.merged = merged.Merged, true
+	if merged.HasConflicts {
+		oursHash, err := r.Store.WriteBlob(&object.Blob{Data: current})
+		if err != nil {
+			return plannedPatchFile{}, err
+		}
+		theirsHash, err := r.Store.WriteBlob(&object.Blob{Data: theirs})
+		if err != nil {
+			return plannedPatchFile{}, err
+		}
+		pf.conflict = &mergeConflictState{
+			path:       path,
+			baseHash:   fp.OldBlob,
+			oursHash:   oursHash,
+			theirsHash: theirsHash,
+			mode:       pf.mode,
+		}
+	}
+	return pf, nil
+}
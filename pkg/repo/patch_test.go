@@ -0,0 +1,363 @@
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+const patchBaseText = "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+
+// setupPatchRepos returns a source repo with a base commit and a commit on
+// top of it that edits, adds and deletes files, and a target repo holding
+// only the base commit.
+func setupPatchRepos(t *testing.T) (src, dst *Repo) {
+	t.Helper()
+	var err error
+	for _, r := range []**Repo{&src, &dst} {
+		if *r, err = Init(t.TempDir()); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		writeFile(t, filepath.Join((*r).RootDir, "gone.txt"), []byte("bye\n"))
+		if err := (*r).Add([]string{filepath.Join((*r).RootDir, "gone.txt")}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		commitFile(t, *r, "a.txt", []byte(patchBaseText), "base")
+	}
+
+	writeFile(t, filepath.Join(src.RootDir, "a.txt"), []byte(strings.Replace(patchBaseText, "two\n", "TWO\n", 1)))
+	writeFile(t, filepath.Join(src.RootDir, "new.txt"), []byte("no newline"))
+	if err := src.Add([]string{filepath.Join(src.RootDir, "a.txt"), filepath.Join(src.RootDir, "new.txt")}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := src.Remove([]string{filepath.Join(src.RootDir, "gone.txt")}, false); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := src.Commit("Edit files\n\nFrom the body.", "Ada <ada@example.com>"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	return src, dst
+}
+
+func TestPatch_FormatParseRoundTrip(t *testing.T) {
+	src, _ := setupPatchRepos(t)
+	patches, err := src.FormatPatches([]string{"HEAD~1"})
+	if err != nil {
+		t.Fatalf("FormatPatches: %v", err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("got %d patches, want 1", len(patches))
+	}
+
+	var buf bytes.Buffer
+	if err := WritePatch(&buf, patches[0], 1, 1); err != nil {
+		t.Fatalf("WritePatch: %v", err)
+	}
+	text := buf.String()
+	for _, want := range []string{
+		"Subject: [PATCH] Edit files\n",
+		"From: Ada <ada@example.com>\n",
+		">From the body.\n",
+		"deleted file mode 100644\n",
+		"@@ -1,5 +1,5 @@\n",
+		"+no newline\n\\ No newline at end of file\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("patch missing %q:\n%s", want, text)
+		}
+	}
+
+	parsed, err := ParsePatches(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParsePatches: %v", err)
+	}
+	if len(parsed) != 1 {
+		t.Fatalf("parsed %d patches, want 1", len(parsed))
+	}
+	got, want := parsed[0], patches[0]
+	if got.Subject != want.Subject || got.Body != want.Body || got.Author != want.Author || !got.Date.Equal(want.Date) {
+		t.Errorf("metadata = %q %q %q %v, want %q %q %q %v", got.Subject, got.Body, got.Author, got.Date, want.Subject, want.Body, want.Author, want.Date)
+	}
+	if len(got.Files) != len(want.Files) {
+		t.Fatalf("parsed %d files, want %d", len(got.Files), len(want.Files))
+	}
+	for i := range want.Files {
+		g, w := got.Files[i], want.Files[i]
+		if g.OldPath != w.OldPath || g.NewPath != w.NewPath || g.OldBlob != w.OldBlob || g.NewBlob != w.NewBlob {
+			t.Errorf("file %d = %+v, want %+v", i, g, w)
+		}
+		if len(g.Hunks) != len(w.Hunks) {
+			t.Fatalf("file %s: %d hunks, want %d", w.Path(), len(g.Hunks), len(w.Hunks))
+		}
+		for j := range w.Hunks {
+			if strings.Join(g.Hunks[j].Lines, "") != strings.Join(w.Hunks[j].Lines, "") {
+				t.Errorf("file %s hunk %d = %q, want %q", w.Path(), j, g.Hunks[j].Lines, w.Hunks[j].Lines)
+			}
+		}
+	}
+}
+
+func TestPatch_RoundTripKeepsQuotedLines(t *testing.T) {
+	body := "> quoted reply\n>> nested quote\nFrom the body.\n>From escaped already\nplain"
+	p := &Patch{Commit: object.Hash(strings.Repeat("a", 64)), Author: "Ada <ada@example.com>", Subject: "Quote", Body: body}
+	for round := 1; round <= 2; round++ {
+		var buf bytes.Buffer
+		if err := WritePatch(&buf, p, 1, 1); err != nil {
+			t.Fatalf("WritePatch: %v", err)
+		}
+		parsed, err := ParsePatches(buf.Bytes())
+		if err != nil {
+			t.Fatalf("ParsePatches: %v", err)
+		}
+		if len(parsed) != 1 || parsed[0].Body != body {
+			t.Fatalf("round %d body = %q, want %q", round, parsed[0].Body, body)
+		}
+		p = parsed[0]
+	}
+}
+
+func TestApplyPatch_AppliesWithOffset(t *testing.T) {
+	src, dst := setupPatchRepos(t)
+	p, err := src.CommitPatch(mustResolve(t, src, "HEAD"))
+	if err != nil {
+		t.Fatalf("CommitPatch: %v", err)
+	}
+
+	// Lines added above the hunk shift it without changing its context.
+	writeFile(t, filepath.Join(dst.RootDir, "a.txt"), []byte("zero\nzero\n"+patchBaseText))
+	if _, err := dst.ApplyPatch(p, ApplyOptions{Index: true}); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+
+	assertFileContent(t, dst, "a.txt", "zero\nzero\n"+strings.Replace(patchBaseText, "two\n", "TWO\n", 1))
+	assertFileContent(t, dst, "new.txt", "no newline")
+	if _, err := os.Stat(filepath.Join(dst.RootDir, "gone.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("gone.txt still exists: %v", err)
+	}
+	stg, err := dst.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if _, ok := stg.Entries["new.txt"]; !ok {
+		t.Error("new.txt is not staged")
+	}
+	if _, ok := stg.Entries["gone.txt"]; ok {
+		t.Error("gone.txt is still staged")
+	}
+}
+
+func TestApplyPatch_FailsWithoutWriting(t *testing.T) {
+	src, dst := setupPatchRepos(t)
+	p, err := src.CommitPatch(mustResolve(t, src, "HEAD"))
+	if err != nil {
+		t.Fatalf("CommitPatch: %v", err)
+	}
+
+	edited := strings.Replace(patchBaseText, "three\n", "THREE\n", 1)
+	writeFile(t, filepath.Join(dst.RootDir, "a.txt"), []byte(edited))
+	if _, err := dst.ApplyPatch(p, ApplyOptions{}); err == nil || !strings.Contains(err.Error(), "does not apply") {
+		t.Fatalf("ApplyPatch error = %v, want hunk failure", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst.RootDir, "new.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("new.txt was written by a failed apply: %v", err)
+	}
+	assertFileContent(t, dst, "a.txt", edited)
+}
+
+func TestApplyPatch_ThreeWayFallback(t *testing.T) {
+	src, dst := setupPatchRepos(t)
+	p, err := src.CommitPatch(mustResolve(t, src, "HEAD"))
+	if err != nil {
+		t.Fatalf("CommitPatch: %v", err)
+	}
+
+	// Editing a context line defeats a plain apply, but merges cleanly.
+	edited := strings.Replace(patchBaseText, "three\n", "THREE\n", 1)
+	writeFile(t, filepath.Join(dst.RootDir, "a.txt"), []byte(edited))
+	result, err := dst.ApplyPatch(p, ApplyOptions{ThreeWay: true})
+	if err != nil {
+		t.Fatalf("ApplyPatch --3way: %v", err)
+	}
+	if len(result.Merged) != 1 || result.Merged[0] != "a.txt" {
+		t.Errorf("Merged = %v, want [a.txt]", result.Merged)
+	}
+	assertFileContent(t, dst, "a.txt", strings.Replace(edited, "two\n", "TWO\n", 1))
+
+	// A conflicting edit leaves markers and a conflict entry.
+	_, dst = setupPatchRepos(t)
+	writeFile(t, filepath.Join(dst.RootDir, "a.txt"), []byte(strings.Replace(patchBaseText, "two\n", "deux\n", 1)))
+	_, err = dst.ApplyPatch(p, ApplyOptions{ThreeWay: true})
+	var conflictErr *ErrApplyConflict
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("ApplyPatch error = %v, want *ErrApplyConflict", err)
+	}
+	stg, err := dst.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if entry := stg.Entries["a.txt"]; entry == nil || !entry.Conflict {
+		t.Errorf("a.txt staging entry = %+v, want a conflict", entry)
+	}
+}
+
+func TestAM_CommitsPatchesWithMetadata(t *testing.T) {
+	src, dst := setupPatchRepos(t)
+	commitFile(t, src, "a.txt", []byte(strings.Replace(patchBaseText, "ten\n", "TEN\n", 1)), "Second change")
+	patches, err := src.FormatPatches([]string{"HEAD~2..HEAD"})
+	if err != nil {
+		t.Fatalf("FormatPatches: %v", err)
+	}
+	var buf bytes.Buffer
+	for i, p := range patches {
+		if err := WritePatch(&buf, p, i+1, len(patches)); err != nil {
+			t.Fatalf("WritePatch: %v", err)
+		}
+	}
+	parsed, err := ParsePatches(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParsePatches: %v", err)
+	}
+
+	result, err := dst.AM(parsed, AMOptions{})
+	if err != nil {
+		t.Fatalf("AM: %v", err)
+	}
+	if len(result.Commits) != 2 {
+		t.Fatalf("AM made %d commits, want 2", len(result.Commits))
+	}
+	first, err := dst.Store.ReadCommit(result.Commits[0])
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	srcFirst, err := src.Store.ReadCommit(mustResolve(t, src, "HEAD~1"))
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	if first.Author != "Ada <ada@example.com>" || first.Message != srcFirst.Message || first.Timestamp != srcFirst.Timestamp {
+		t.Errorf("commit = %q %q %d, want author Ada, message %q, timestamp %d", first.Author, first.Message, first.Timestamp, srcFirst.Message, srcFirst.Timestamp)
+	}
+	srcHead, err := src.Store.ReadCommit(mustResolve(t, src, "HEAD"))
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	dstHead, err := dst.Store.ReadCommit(mustResolve(t, dst, "HEAD"))
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	if dstHead.TreeHash != srcHead.TreeHash {
+		t.Errorf("tree after am = %s, want %s", dstHead.TreeHash, srcHead.TreeHash)
+	}
+	if dst.IsAMInProgress() {
+		t.Error("am state left behind after a clean run")
+	}
+}
+
+func TestAM_StopsAndAborts(t *testing.T) {
+	src, dst := setupPatchRepos(t)
+	p, err := src.CommitPatch(mustResolve(t, src, "HEAD"))
+	if err != nil {
+		t.Fatalf("CommitPatch: %v", err)
+	}
+	origHead := commitFile(t, dst, "a.txt", []byte(strings.Replace(patchBaseText, "two\n", "deux\n", 1)), "Diverge")
+
+	_, err = dst.AM([]*Patch{p}, AMOptions{ThreeWay: true})
+	var stopped *ErrAMStopped
+	if !errors.As(err, &stopped) || len(stopped.Conflicts) != 1 {
+		t.Fatalf("AM error = %v, want *ErrAMStopped with one conflict", err)
+	}
+	if !dst.IsAMInProgress() {
+		t.Fatal("am state not saved")
+	}
+	if _, err := dst.AM([]*Patch{p}, AMOptions{}); err == nil {
+		t.Error("second AM while stopped succeeded")
+	}
+
+	if err := dst.AMAbort(); err != nil {
+		t.Fatalf("AMAbort: %v", err)
+	}
+	if dst.IsAMInProgress() {
+		t.Error("am state left behind after abort")
+	}
+	if head := mustResolve(t, dst, "HEAD"); head != origHead {
+		t.Errorf("HEAD after abort = %s, want %s", head, origHead)
+	}
+	assertFileContent(t, dst, "a.txt", strings.Replace(patchBaseText, "two\n", "deux\n", 1))
+	assertFileContent(t, dst, "gone.txt", "bye\n")
+}
+
+func TestAM_RefusesStagedChanges(t *testing.T) {
+	src, dst := setupPatchRepos(t)
+	p, err := src.CommitPatch(mustResolve(t, src, "HEAD"))
+	if err != nil {
+		t.Fatalf("CommitPatch: %v", err)
+	}
+	origHead := mustResolve(t, dst, "HEAD")
+	writeFile(t, filepath.Join(dst.RootDir, "unrelated.txt"), []byte("staged by the user\n"))
+	if err := dst.Add([]string{filepath.Join(dst.RootDir, "unrelated.txt")}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if _, err := dst.AM([]*Patch{p}, AMOptions{}); err == nil || !strings.Contains(err.Error(), "index does not match HEAD") {
+		t.Fatalf("AM with staged changes error = %v", err)
+	}
+	if dst.IsAMInProgress() {
+		t.Error("am state saved after refusing to start")
+	}
+	head := mustResolve(t, dst, "HEAD")
+	if head != origHead {
+		t.Fatalf("HEAD moved to %s", head)
+	}
+	commit, err := dst.Store.ReadCommit(head)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	files, err := dst.FlattenTree(commit.TreeHash)
+	if err != nil {
+		t.Fatalf("FlattenTree: %v", err)
+	}
+	for _, f := range files {
+		if f.Path == "unrelated.txt" {
+			t.Fatal("staged file was committed")
+		}
+	}
+}
+
+func TestAM_RefusesUnbornBranch(t *testing.T) {
+	src, _ := setupPatchRepos(t)
+	p, err := src.CommitPatch(mustResolve(t, src, "HEAD"))
+	if err != nil {
+		t.Fatalf("CommitPatch: %v", err)
+	}
+	empty, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if _, err := empty.AM([]*Patch{p}, AMOptions{}); err == nil || !strings.Contains(err.Error(), "no commits yet") {
+		t.Fatalf("AM on unborn branch error = %v", err)
+	}
+}
+
+func mustResolve(t *testing.T, r *Repo, rev string) object.Hash {
+	t.Helper()
+	h, err := r.ResolveRef(rev)
+	if err != nil {
+		t.Fatalf("ResolveRef(%s): %v", rev, err)
+	}
+	return h
+}
+
+func assertFileContent(t *testing.T, r *Repo, name, want string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(r.RootDir, name))
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", name, data, want)
+	}
+}
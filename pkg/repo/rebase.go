@@ -234,7 +234,7 @@ func (r *Repo) RebaseContinue() error {
 	}
 	for _, entry := range stg.Entries {
 		if entry.Conflict {
-			return fmt.Errorf("rebase continue: unresolved conflicts remain; resolve them and stage the files")
+			return fmt.Errorf("rebase continue: %w remain; resolve them and stage the files", ErrMergeConflicts)
 		}
 	}
 
@@ -390,7 +390,7 @@ func (r *Repo) resolveToHash(target string) (object.Hash, error) {
 			return object.Hash(target), nil
 		}
 	}
-	return "", fmt.Errorf("cannot resolve %q to a commit", target)
+	return "", fmt.Errorf("cannot resolve %q to a commit: %w", target, ErrRefNotFound)
 }
 
 // collectCommits walks first-parent links from tip backward to stop (exclusive),
@@ -618,6 +618,7 @@ func (r *Repo) replaySingleCommit(commitHash object.Hash) error {
 		Timestamp: time.Now().Unix(),
 		Message:   origCommit.Message,
 	}
+	r.setCommitter(newCommit)
 
 	newHash, err := r.Store.WriteCommit(newCommit)
 	if err != nil {
@@ -695,7 +696,7 @@ func (r *Repo) detachHead(hash object.Hash) error {
 
 // checkoutTree writes the tree of a commit to the working directory and updates staging.
 func (r *Repo) checkoutTree(commit *object.CommitObj) error {
-	targetFiles, err := r.FlattenTree(commit.TreeHash)
+	targetFiles, err := r.flattenCheckoutTree(commit.TreeHash)
 	if err != nil {
 		return fmt.Errorf("flatten tree: %w", err)
 	}
@@ -711,17 +712,18 @@ func (r *Repo) checkoutTree(commit *object.CommitObj) error {
 	}
 
 	// Write all files from target tree.
+	le := r.lineEndingsForTree(targetFiles)
 	for _, f := range targetFiles {
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
 		dir := filepath.Dir(absPath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := mkdirWorktree(r.RootDir, dir); err != nil {
 			return fmt.Errorf("mkdir %q: %w", dir, err)
 		}
 		blob, err := r.Store.ReadBlob(f.BlobHash)
 		if err != nil {
 			return fmt.Errorf("read blob for %q: %w", f.Path, err)
 		}
-		if err := os.WriteFile(absPath, blob.Data, filePermFromMode(f.Mode)); err != nil {
+		if err := writeWorktreeFile(absPath, le.smudge(f.Path, blob.Data, f.Mode), f.Mode); err != nil {
 			return fmt.Errorf("write %q: %w", f.Path, err)
 		}
 	}
@@ -730,7 +732,7 @@ func (r *Repo) checkoutTree(commit *object.CommitObj) error {
 	stg := &Staging{Entries: make(map[string]*StagingEntry, len(targetFiles))}
 	for _, f := range targetFiles {
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
-		info, err := os.Stat(absPath)
+		info, err := os.Lstat(absPath)
 		if err != nil {
 			return fmt.Errorf("stat %q: %w", f.Path, err)
 		}
@@ -625,6 +625,7 @@ func (r *Repo) amendHeadWithStaged() error {
 		Timestamp: time.Now().Unix(),
 		Message:   headCommit.Message,
 	}
+	r.setCommitter(newCommit)
 
 	newHash, err := r.Store.WriteCommit(newCommit)
 	if err != nil {
@@ -821,6 +822,7 @@ func (r *Repo) squashCommit(commitHash object.Hash, combineMessages bool) error
 		Timestamp: time.Now().Unix(),
 		Message:   newMessage,
 	}
+	r.setCommitter(newCommit)
 
 	newHash, err := r.Store.WriteCommit(newCommit)
 	if err != nil {
@@ -901,6 +903,7 @@ func (r *Repo) rewordLastCommit() error {
 		Timestamp: headCommit.Timestamp,
 		Message:   newMessage,
 	}
+	r.setCommitter(newCommit)
 
 	newHash, err := r.Store.WriteCommit(newCommit)
 	if err != nil {
@@ -318,6 +318,11 @@ func DiffTreeEntities(r *Repo, oldCommit, newCommit object.Hash) ([]ReflogEntity
 		oldByPath = make(map[string]TreeFileEntry)
 	}
 
+	return diffEntryEntities(r, oldByPath, newByPath)
+}
+
+// diffEntryEntities compares the entity lists of two path-indexed trees.
+func diffEntryEntities(r *Repo, oldByPath, newByPath map[string]TreeFileEntry) ([]ReflogEntityChange, error) {
 	// Collect all unique paths.
 	allPaths := make(map[string]struct{})
 	for p := range oldByPath {
@@ -436,6 +441,12 @@ func (r *Repo) ReadReflog(ref string, limit int) ([]ReflogEntry, error) {
 		return nil, err
 	}
 
+	return r.readReflogFile(refName, limit)
+}
+
+// readReflogFile reads the reflog of the fully qualified refName, newest
+// first. Unlike ReadReflog it does not map HEAD to the checked-out branch.
+func (r *Repo) readReflogFile(refName string, limit int) ([]ReflogEntry, error) {
 	baseDir := r.refsBaseDir()
 	if refName == "HEAD" {
 		baseDir = r.GraftDir
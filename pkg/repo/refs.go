@@ -131,15 +131,29 @@ func (r *Repo) refsBaseDir() string {
 // ancestor notation (e.g., HEAD~3, main^2, HEAD~2^2, @~1) matching Git
 // syntax. It tries, in order: refs/tags/<base>, refs/heads/<base>, HEAD
 // (if base is "HEAD"), and finally treats the value as a raw hash — then
-// applies any ancestor suffix operations to walk the commit graph.
+// applies any ancestor suffix operations to walk the commit graph. The
+// reflog (@{-N}, @{upstream}, ref@{N}) and <rev>:<path> forms are described
+// in revparse.go.
 func (r *Repo) ResolveTreeish(treeish string) (object.Hash, error) {
+	// <rev>:<path> names an object inside a tree rather than a commit.
+	if rev, relPath, ok := strings.Cut(treeish, ":"); ok {
+		h, err := r.resolveRevisionPath(rev, relPath)
+		if err != nil {
+			return "", fmt.Errorf("cannot resolve treeish %q: %w", treeish, err)
+		}
+		return h, nil
+	}
+
 	// Parse ancestor suffix (e.g., "HEAD~3^2" → base="HEAD", ops=[~3,^2]).
 	base, ops := parseRevisionSuffix(treeish)
 
 	// Resolve the base ref.
 	h, err := r.resolveBaseTreeish(base)
 	if err != nil {
-		return "", fmt.Errorf("cannot resolve treeish %q", treeish)
+		if _, _, ok := cutReflogSelector(base); ok {
+			return "", fmt.Errorf("cannot resolve treeish %q: %w", treeish, err)
+		}
+		return "", fmt.Errorf("cannot resolve treeish %q: %w", treeish, ErrRefNotFound)
 	}
 
 	// If no suffix operations, return the resolved hash directly.
@@ -159,6 +173,9 @@ func (r *Repo) ResolveTreeish(treeish string) (object.Hash, error) {
 // to a commit hash using the standard resolution order: tag, branch, raw
 // ref, raw hash.
 func (r *Repo) resolveBaseTreeish(base string) (object.Hash, error) {
+	if name, selector, ok := cutReflogSelector(base); ok {
+		return r.resolveReflogSelector(name, selector)
+	}
 	// Try tag ref first.
 	if h, err := r.ResolveRef("refs/tags/" + base); err == nil {
 		return h, nil
@@ -178,7 +195,7 @@ func (r *Repo) resolveBaseTreeish(base string) (object.Hash, error) {
 			return h, nil
 		}
 	}
-	return "", fmt.Errorf("cannot resolve base ref %q", base)
+	return "", fmt.Errorf("cannot resolve base ref %q: %w", base, ErrRefNotFound)
 }
 
 // ListRefs lists references under .graft/refs.
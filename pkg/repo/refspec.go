@@ -0,0 +1,275 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// Refspec maps refs between a remote and the local repository. It is written
+// "[+]<src>:<dst>" as in git: src names refs on the sending side, dst the refs
+// they update on the receiving side, and both may contain a single "*" that
+// matches any suffix. A leading "+" allows non-fast-forward updates.
+//
+// Short names are expanded: "main" means refs/heads/main, and "heads/x" or
+// "tags/x" (the names used on the wire) gain a "refs/" prefix. A refspec
+// without ":" maps a ref to the same name.
+type Refspec struct {
+	Force bool
+	Src   string
+	Dst   string
+}
+
+// ParseRefspec parses a refspec such as "+refs/heads/*:refs/remotes/origin/heads/*".
+func ParseRefspec(raw string) (Refspec, error) {
+	text := strings.TrimSpace(raw)
+	var spec Refspec
+	if strings.HasPrefix(text, "+") {
+		spec.Force = true
+		text = text[1:]
+	}
+	src, dst, hasDst := strings.Cut(text, ":")
+	if !hasDst {
+		dst = src
+	}
+	var err error
+	if spec.Src, err = expandRefspecName(src); err != nil {
+		return Refspec{}, fmt.Errorf("invalid refspec %q: %w", raw, err)
+	}
+	if spec.Dst, err = expandRefspecName(dst); err != nil {
+		return Refspec{}, fmt.Errorf("invalid refspec %q: %w", raw, err)
+	}
+	if strings.Contains(spec.Src, "*") != strings.Contains(spec.Dst, "*") {
+		return Refspec{}, fmt.Errorf("invalid refspec %q: both sides must have a wildcard or neither", raw)
+	}
+	return spec, nil
+}
+
+// ParseRefspecs parses each of raw, stopping at the first invalid refspec.
+func ParseRefspecs(raw []string) ([]Refspec, error) {
+	specs := make([]Refspec, 0, len(raw))
+	for _, s := range raw {
+		spec, err := ParseRefspec(s)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func expandRefspecName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", fmt.Errorf("empty ref name")
+	case strings.Count(name, "*") > 1:
+		return "", fmt.Errorf("%q has more than one wildcard", name)
+	case strings.Contains(name, "..") || strings.ContainsAny(name, " \t\\:?[~^") ||
+		strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.Contains(name, "//"):
+		return "", fmt.Errorf("%q is not a valid ref name", name)
+	case strings.HasPrefix(name, "refs/"):
+		return name, nil
+	case strings.HasPrefix(name, "heads/"), strings.HasPrefix(name, "tags/"):
+		return "refs/" + name, nil
+	default:
+		return "refs/heads/" + name, nil
+	}
+}
+
+// String returns the canonical form of s.
+func (s Refspec) String() string {
+	prefix := ""
+	if s.Force {
+		prefix = "+"
+	}
+	return prefix + s.Src + ":" + s.Dst
+}
+
+// Map returns the destination ref for the full ref name src, or false when s
+// does not match it.
+func (s Refspec) Map(src string) (string, bool) {
+	suffix, ok := matchRefPattern(s.Src, src)
+	if !ok {
+		return "", false
+	}
+	return strings.Replace(s.Dst, "*", suffix, 1), true
+}
+
+// matchesDst reports whether name falls under the destination side of s.
+func (s Refspec) matchesDst(name string) bool {
+	_, ok := matchRefPattern(s.Dst, name)
+	return ok
+}
+
+// matchRefPattern matches name against pattern, returning the part matched
+// by the wildcard.
+func matchRefPattern(pattern, name string) (string, bool) {
+	prefix, suffix, wild := strings.Cut(pattern, "*")
+	if !wild {
+		return "", name == pattern
+	}
+	if len(name) < len(prefix)+len(suffix) || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return "", false
+	}
+	return name[len(prefix) : len(name)-len(suffix)], true
+}
+
+// RefMapping pairs a ref advertised by a remote with the local ref a fetch
+// updates from it.
+type RefMapping struct {
+	Remote string      // remote ref name as advertised, e.g. "heads/main"
+	Local  string      // local ref updated, e.g. "refs/remotes/origin/heads/main"
+	Hash   object.Hash // advertised value
+	Force  bool        // allow non-fast-forward updates
+}
+
+// FetchRefspecs returns the fetch refspecs configured for remoteName, or nil
+// when the remote uses the default of tracking every advertised ref under
+// refs/remotes/<remoteName>/.
+func (r *Repo) FetchRefspecs(remoteName string) ([]Refspec, error) {
+	rc, err := r.remoteConfig(remoteName)
+	if err != nil || rc == nil || len(rc.Fetch) == 0 {
+		return nil, err
+	}
+	return ParseRefspecs(rc.Fetch)
+}
+
+// PushRefspecs returns the push refspecs configured for remoteName, or nil
+// when branches are pushed to the remote branch of the same name.
+func (r *Repo) PushRefspecs(remoteName string) ([]Refspec, error) {
+	rc, err := r.remoteConfig(remoteName)
+	if err != nil || rc == nil || len(rc.Push) == 0 {
+		return nil, err
+	}
+	return ParseRefspecs(rc.Push)
+}
+
+func (r *Repo) remoteConfig(remoteName string) (*RemoteConfig, error) {
+	cfg, err := r.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.RemoteSettings[remoteName], nil
+}
+
+// FetchRefMappings decides which local ref each advertised remote ref
+// updates, applying the fetch refspecs configured for remoteName. Refs that
+// no refspec matches are left out. The result is sorted by local ref name.
+func (r *Repo) FetchRefMappings(remoteName string, remoteRefs map[string]object.Hash) ([]RefMapping, error) {
+	specs, err := r.FetchRefspecs(remoteName)
+	if err != nil {
+		return nil, err
+	}
+	mappings := make([]RefMapping, 0, len(remoteRefs))
+	for name, h := range remoteRefs {
+		if strings.TrimSpace(string(h)) == "" {
+			continue
+		}
+		if local, force, ok := mapFetchRef(specs, remoteName, name); ok {
+			mappings = append(mappings, RefMapping{Remote: name, Local: local, Hash: h, Force: force})
+		}
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Local < mappings[j].Local })
+	return mappings, nil
+}
+
+// mapFetchRef applies specs to the advertised ref name. With no specs every
+// ref is force-updated under refs/remotes/<remoteName>/.
+func mapFetchRef(specs []Refspec, remoteName, name string) (string, bool, bool) {
+	if specs == nil {
+		return trackingRefName(remoteName, name), true, true
+	}
+	full := "refs/" + strings.TrimPrefix(name, "/")
+	for _, spec := range specs {
+		if local, ok := spec.Map(full); ok {
+			return local, spec.Force, true
+		}
+	}
+	return "", false, false
+}
+
+// TrackingRefFor returns the local ref that a fetch from remoteName updates
+// for remoteRef (e.g. "heads/main"), or false when the fetch refspecs leave
+// remoteRef out.
+func (r *Repo) TrackingRefFor(remoteName, remoteRef string) (string, bool, error) {
+	specs, err := r.FetchRefspecs(remoteName)
+	if err != nil {
+		return "", false, err
+	}
+	local, _, ok := mapFetchRef(specs, remoteName, remoteRef)
+	return local, ok, nil
+}
+
+// MappingHashes returns the distinct hashes of mappings, for use as fetch
+// wants.
+func MappingHashes(mappings []RefMapping) []object.Hash {
+	seen := make(map[object.Hash]struct{}, len(mappings))
+	out := make([]object.Hash, 0, len(mappings))
+	for _, m := range mappings {
+		if _, ok := seen[m.Hash]; ok {
+			continue
+		}
+		seen[m.Hash] = struct{}{}
+		out = append(out, m.Hash)
+	}
+	return out
+}
+
+// UpdateFetchedRefs writes the local refs in mappings once their objects
+// have been fetched. A mapping without Force only fast-forwards; a
+// non-fast-forward update is skipped and returned in rejected.
+func (r *Repo) UpdateFetchedRefs(mappings []RefMapping) (updated, rejected []RefUpdate, err error) {
+	for _, m := range mappings {
+		oldHash, _ := r.ResolveRef(m.Local)
+		if oldHash == m.Hash {
+			continue
+		}
+		update := RefUpdate{Name: m.Local, OldHash: oldHash, NewHash: m.Hash}
+		if !m.Force && oldHash != "" {
+			base, baseErr := r.FindMergeBase(oldHash, m.Hash)
+			if baseErr != nil || base != oldHash {
+				rejected = append(rejected, update)
+				continue
+			}
+		}
+		if err := r.UpdateRef(m.Local, m.Hash); err != nil {
+			return updated, rejected, fmt.Errorf("fetch: update tracking ref %q: %w", m.Local, err)
+		}
+		updated = append(updated, update)
+	}
+	return updated, rejected, nil
+}
+
+// pruneCandidates lists the local refs a pruning fetch from remoteName may
+// delete: everything under refs/remotes/<remoteName>/ by default, or the refs
+// covered by the destination side of the configured fetch refspecs.
+func (r *Repo) pruneCandidates(remoteName string) (map[string]object.Hash, error) {
+	specs, err := r.FetchRefspecs(remoteName)
+	if err != nil {
+		return nil, err
+	}
+	if specs == nil {
+		specs = []Refspec{{Src: "refs/*", Dst: "refs/remotes/" + remoteName + "/*"}}
+	}
+	out := make(map[string]object.Hash)
+	for _, spec := range specs {
+		dir, _, _ := strings.Cut(spec.Dst, "*")
+		if i := strings.LastIndex(dir, "/"); i >= 0 {
+			dir = dir[:i]
+		}
+		refs, err := r.ListRefs(strings.TrimPrefix(dir, "refs/"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for name, h := range refs {
+			if full := "refs/" + name; spec.matchesDst(full) {
+				out[full] = h
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRefspec(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Refspec
+	}{
+		{"+refs/heads/*:refs/remotes/origin/heads/*", Refspec{Force: true, Src: "refs/heads/*", Dst: "refs/remotes/origin/heads/*"}},
+		{"main:release", Refspec{Src: "refs/heads/main", Dst: "refs/heads/release"}},
+		{"tags/v1", Refspec{Src: "refs/tags/v1", Dst: "refs/tags/v1"}},
+		{"heads/*:refs/mirror/*", Refspec{Src: "refs/heads/*", Dst: "refs/mirror/*"}},
+	}
+	for _, tc := range tests {
+		got, err := ParseRefspec(tc.raw)
+		if err != nil {
+			t.Errorf("ParseRefspec(%q): %v", tc.raw, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseRefspec(%q) = %+v, want %+v", tc.raw, got, tc.want)
+		}
+	}
+
+	for _, raw := range []string{"", "+", "refs/heads/*:refs/remotes/x", "a:", "refs/*/*:refs/x/*/*", "a..b:c", "refs/heads/a b"} {
+		if _, err := ParseRefspec(raw); err == nil {
+			t.Errorf("ParseRefspec(%q) succeeded, want error", raw)
+		}
+	}
+
+	spec, _ := ParseRefspec("refs/heads/feature/*:refs/remotes/origin/f/*")
+	if got, ok := spec.Map("refs/heads/feature/login"); !ok || got != "refs/remotes/origin/f/login" {
+		t.Fatalf("Map = %q, %v", got, ok)
+	}
+	if _, ok := spec.Map("refs/heads/main"); ok {
+		t.Fatal("Map matched a ref outside the pattern")
+	}
+}
+
+func TestFetchHonorsConfiguredRefspecs(t *testing.T) {
+	local, remoteRepo, first := setupRemotePair(t)
+	if err := remoteRepo.UpdateRef("refs/heads/feature", first); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+	cfg, err := local.ReadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.RemoteSettings = map[string]*RemoteConfig{"origin": {Fetch: []string{"refs/heads/main:refs/remotes/upstream/main"}}}
+	if err := local.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := local.Fetch("origin")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(result.UpdatedRefs) != 1 || result.UpdatedRefs[0].Name != "refs/remotes/upstream/main" {
+		t.Fatalf("UpdatedRefs = %+v", result.UpdatedRefs)
+	}
+	if _, err := local.ResolveRef("refs/remotes/origin/heads/feature"); err == nil {
+		t.Fatal("fetch created a tracking ref outside the refspec")
+	}
+	if ref, ok, err := local.TrackingRefFor("origin", "heads/main"); err != nil || !ok || ref != "refs/remotes/upstream/main" {
+		t.Fatalf("TrackingRefFor = %q, %v, %v", ref, ok, err)
+	}
+
+	// Rewriting the remote branch is refused without "+".
+	if err := os.WriteFile(filepath.Join(remoteRepo.RootDir, "other.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := remoteRepo.Add([]string{"other.go"}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := remoteRepo.Commit("second", "test-author")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.Fetch("origin"); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if err := remoteRepo.UpdateRef("refs/heads/main", first); err != nil {
+		t.Fatal(err)
+	}
+	result, err = local.Fetch("origin")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(result.RejectedRefs) != 1 || len(result.UpdatedRefs) != 0 {
+		t.Fatalf("non-fast-forward fetch: updated=%+v rejected=%+v", result.UpdatedRefs, result.RejectedRefs)
+	}
+	if got, _ := local.ResolveRef("refs/remotes/upstream/main"); got != second {
+		t.Fatalf("rejected update moved the ref to %s", got)
+	}
+
+	cfg.RemoteSettings["origin"].Fetch = []string{"+refs/heads/main:refs/remotes/upstream/main"}
+	if err := local.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.Fetch("origin"); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got, _ := local.ResolveRef("refs/remotes/upstream/main"); got != first {
+		t.Fatalf("forced refspec left the ref at %s, want %s", got, first)
+	}
+
+	// Pruning only considers refs covered by the refspec destinations.
+	if err := local.UpdateRef("refs/remotes/upstream/stale", first); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.UpdateRef("refs/remotes/origin/heads/old", first); err != nil {
+		t.Fatal(err)
+	}
+	cfg.RemoteSettings["origin"].Fetch = []string{"+refs/heads/*:refs/remotes/upstream/*"}
+	if err := local.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	result, err = local.FetchWithOptions(context.Background(), "origin", FetchOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("FetchWithOptions(prune): %v", err)
+	}
+	if len(result.PrunedRefs) != 1 || result.PrunedRefs[0].Name != "refs/remotes/upstream/stale" {
+		t.Fatalf("PrunedRefs = %+v", result.PrunedRefs)
+	}
+	if _, err := local.ResolveRef("refs/remotes/origin/heads/old"); err != nil {
+		t.Fatal("prune removed a ref outside the refspec destinations")
+	}
+}
@@ -0,0 +1,178 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/remote"
+)
+
+// RepairedObject records an object restored from a remote.
+type RepairedObject struct {
+	Hash   object.Hash
+	Remote string
+}
+
+// RepairReport summarizes the outcome of RepairObjects.
+type RepairReport struct {
+	Corrupt         int              // integrity failures found by verification
+	SalvagedPacks   []string         // damaged packs whose intact entries were rewritten loose
+	Salvaged        int              // intact objects rescued from damaged packs
+	IntactPacks     []string         // packs damaged only at the pack level, kept as every entry decodes
+	Refetched       []RepairedObject // objects replaced with a verified remote copy
+	Unrecovered     []object.Hash    // objects no remote could supply
+	UnreadablePacks []string         // packs whose index could not be read
+}
+
+// objectSource fetches a single object by hash from a remote.
+type objectSource struct {
+	name  string
+	fetch func(ctx context.Context, h object.Hash) (object.ObjectType, []byte, error)
+}
+
+// RepairObjects verifies the object store and repairs what it can. Intact
+// entries of packs with undecodable entries are rewritten as loose objects
+// and the pack is dropped; packs whose entries all decode are kept; every corrupt or lost object is then refetched by hash from the
+// named remotes, in order, and replaced with the verified copy. When
+// remoteNames is empty all configured remotes are tried, "origin" first.
+func (r *Repo) RepairObjects(ctx context.Context, remoteNames []string) (*RepairReport, error) {
+	verify, err := r.Store.VerifyWithOptions(object.VerifyOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("repair: verify: %w", err)
+	}
+
+	report := &RepairReport{Corrupt: len(verify.Corrupt)}
+	if len(verify.Corrupt) == 0 {
+		return report, nil
+	}
+
+	missing := make(map[object.Hash]struct{})
+	damagedPacks := make(map[string]struct{})
+	for _, c := range verify.Corrupt {
+		if c.Location == "loose" {
+			missing[c.Hash] = struct{}{}
+			continue
+		}
+		damagedPacks[c.Location] = struct{}{}
+	}
+
+	packNames := make([]string, 0, len(damagedPacks))
+	for name := range damagedPacks {
+		packNames = append(packNames, name)
+	}
+	sort.Strings(packNames)
+	for _, name := range packNames {
+		salvage, err := r.Store.SalvagePack(name)
+		if err != nil {
+			report.UnreadablePacks = append(report.UnreadablePacks, name)
+			continue
+		}
+		if salvage.Intact {
+			report.IntactPacks = append(report.IntactPacks, name)
+			continue
+		}
+		report.SalvagedPacks = append(report.SalvagedPacks, name)
+		report.Salvaged += salvage.Recovered
+		for _, h := range salvage.Lost {
+			missing[h] = struct{}{}
+		}
+	}
+
+	sources, err := r.repairSources(remoteNames)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]object.Hash, 0, len(missing))
+	for h := range missing {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	for _, h := range hashes {
+		// A good copy may survive elsewhere (e.g. loose beside a damaged pack).
+		if _, _, err := r.Store.Read(h); err == nil {
+			continue
+		}
+		repaired := false
+		for _, src := range sources {
+			objType, data, err := src.fetch(ctx, h)
+			if err != nil {
+				continue
+			}
+			if err := r.Store.ReplaceLoose(h, objType, data); err != nil {
+				continue
+			}
+			report.Refetched = append(report.Refetched, RepairedObject{Hash: h, Remote: src.name})
+			repaired = true
+			break
+		}
+		if !repaired {
+			report.Unrecovered = append(report.Unrecovered, h)
+		}
+	}
+
+	return report, nil
+}
+
+// repairSources resolves remote names into object sources. Local-path
+// remotes are read directly; everything else goes through the HTTP client.
+func (r *Repo) repairSources(remoteNames []string) ([]objectSource, error) {
+	if len(remoteNames) == 0 {
+		cfg, err := r.ReadConfig()
+		if err != nil {
+			return nil, err
+		}
+		for name := range cfg.Remotes {
+			remoteNames = append(remoteNames, name)
+		}
+		sort.Slice(remoteNames, func(i, j int) bool {
+			if (remoteNames[i] == "origin") != (remoteNames[j] == "origin") {
+				return remoteNames[i] == "origin"
+			}
+			return remoteNames[i] < remoteNames[j]
+		})
+	}
+
+	sources := make([]objectSource, 0, len(remoteNames))
+	for _, name := range remoteNames {
+		remoteURL, err := r.RemoteURL(name)
+		if err != nil {
+			return nil, fmt.Errorf("repair: %w", err)
+		}
+		if isLocalPath(remoteURL) {
+			src, err := Open(remoteURL)
+			if err != nil {
+				return nil, fmt.Errorf("repair: open local remote %q: %w", remoteURL, err)
+			}
+			sources = append(sources, objectSource{
+				name: name,
+				fetch: func(_ context.Context, h object.Hash) (object.ObjectType, []byte, error) {
+					return src.Store.Read(h)
+				},
+			})
+			continue
+		}
+		client, err := remote.NewClient(remoteURL)
+		if err != nil {
+			return nil, fmt.Errorf("repair: remote %q: %w", name, err)
+		}
+		sources = append(sources, objectSource{
+			name: name,
+			fetch: func(ctx context.Context, h object.Hash) (object.ObjectType, []byte, error) {
+				obj, err := client.GetObject(ctx, h)
+				if err != nil {
+					return "", nil, err
+				}
+				if strings.TrimSpace(string(obj.Hash)) != "" && obj.Hash != h {
+					return "", nil, fmt.Errorf("remote returned %s for %s", obj.Hash, h)
+				}
+				return obj.Type, obj.Data, nil
+			},
+		})
+	}
+	return sources, nil
+}
@@ -0,0 +1,161 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestRepairObjectsRefetchesCorruptObjectsFromLocalRemote(t *testing.T) {
+	srcDir := t.TempDir()
+	src, err := Init(srcDir)
+	if err != nil {
+		t.Fatalf("Init(src): %v", err)
+	}
+	looseHash, err := src.Store.Write(object.TypeBlob, []byte("loose payload"))
+	if err != nil {
+		t.Fatalf("Write(loose): %v", err)
+	}
+	packedHash, err := src.Store.Write(object.TypeBlob, []byte("packed payload"))
+	if err != nil {
+		t.Fatalf("Write(packed): %v", err)
+	}
+	survivorHash, err := src.Store.Write(object.TypeBlob, []byte("survivor payload"))
+	if err != nil {
+		t.Fatalf("Write(survivor): %v", err)
+	}
+
+	dstDir := t.TempDir()
+	dst, err := Init(dstDir)
+	if err != nil {
+		t.Fatalf("Init(dst): %v", err)
+	}
+	for _, h := range []object.Hash{packedHash, survivorHash} {
+		objType, data, err := src.Store.Read(h)
+		if err != nil {
+			t.Fatalf("Read(%s): %v", h, err)
+		}
+		if _, err := dst.Store.Write(objType, data); err != nil {
+			t.Fatalf("Write(%s): %v", h, err)
+		}
+	}
+	summary, err := dst.Store.GC()
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	if _, err := dst.Store.Write(object.TypeBlob, []byte("loose payload")); err != nil {
+		t.Fatalf("Write(dst loose): %v", err)
+	}
+	if err := dst.SetRemote("origin", srcDir); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+
+	// Corrupt the loose object and the packed copy of packedHash.
+	loosePath := filepath.Join(dst.GraftDir, "objects", string(looseHash[:2]), string(looseHash[2:]))
+	if err := os.WriteFile(loosePath, []byte("broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	corruptPackEntry(t, dst, summary.PackFile, packedHash)
+
+	report, err := dst.RepairObjects(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("RepairObjects: %v", err)
+	}
+	if report.Corrupt != 2 {
+		t.Fatalf("Corrupt = %d, want 2 (loose object + pack)", report.Corrupt)
+	}
+	if len(report.SalvagedPacks) != 1 || report.Salvaged != 1 {
+		t.Fatalf("salvage = %v/%d, want one pack with 1 intact object", report.SalvagedPacks, report.Salvaged)
+	}
+	if len(report.Refetched) != 2 {
+		t.Fatalf("Refetched = %+v, want the loose and the packed object", report.Refetched)
+	}
+	for _, obj := range report.Refetched {
+		if (obj.Hash != looseHash && obj.Hash != packedHash) || obj.Remote != "origin" {
+			t.Fatalf("Refetched = %+v, want the loose and the packed object from origin", report.Refetched)
+		}
+	}
+	if len(report.Unrecovered) != 0 {
+		t.Fatalf("Unrecovered = %v, want none", report.Unrecovered)
+	}
+
+	verify, err := dst.Store.Verify()
+	if err != nil {
+		t.Fatalf("Verify after repair: %v", err)
+	}
+	if verify.LooseObjects != 3 {
+		t.Fatalf("LooseObjects after repair = %d, want 3", verify.LooseObjects)
+	}
+}
+
+func TestRepairObjectsKeepsPackWithIntactEntries(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for _, payload := range []string{"one", "two"} {
+		if _, err := r.Store.Write(object.TypeBlob, []byte(payload)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	summary, err := r.Store.GC()
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+
+	// Only the pack's trailing checksum is damaged.
+	packPath := filepath.Join(r.GraftDir, "objects", "pack", summary.PackFile)
+	packData, err := os.ReadFile(packPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packData[len(packData)-1] ^= 0xff
+	if err := os.WriteFile(packPath, packData, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := r.RepairObjects(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("RepairObjects: %v", err)
+	}
+	if len(report.SalvagedPacks) != 0 || len(report.IntactPacks) != 1 {
+		t.Fatalf("salvaged %v, intact %v, want the pack kept", report.SalvagedPacks, report.IntactPacks)
+	}
+	if _, err := os.Stat(packPath); err != nil {
+		t.Fatalf("pack removed: %v", err)
+	}
+}
+
+// corruptPackEntry flips a byte in the compressed data of h's entry in the
+// named pack.
+func corruptPackEntry(t *testing.T, r *Repo, packFile string, h object.Hash) {
+	t.Helper()
+	packPath := filepath.Join(r.GraftDir, "objects", "pack", packFile)
+	idxData, err := os.ReadFile(strings.TrimSuffix(packPath, ".pack") + ".idx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := object.ReadPackIndex(idxData)
+	if err != nil {
+		t.Fatalf("ReadPackIndex: %v", err)
+	}
+	packData, err := os.ReadFile(packPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range idx.Entries() {
+		if entry.Hash == h {
+			packData[entry.Offset+4] ^= 0xff
+			if err := os.WriteFile(packPath, packData, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+	t.Fatalf("%s not in %s", h, packFile)
+}
@@ -4,6 +4,10 @@
 package repo
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/odvcencio/graft/pkg/object"
@@ -28,10 +32,41 @@ type Repo struct {
 	shallowState *remote.ShallowState
 	shallowErr   error
 
+	ignoreCaseOnce  sync.Once
+	ignoreCaseProbe bool
+
 	// AddHook, if set, is called during Add after entity extraction for each
 	// file. It receives the relative path and the identity keys of entities
 	// found in the file. Errors are logged as warnings but do not block staging.
 	AddHook AddEntityHook
+
+	// progress receives updates from fetches, store maintenance, add,
+	// checkout and merge; see SetProgress.
+	progress object.ProgressFunc
+	// events receives operation start and done events; see SetEventHook.
+	events EventFunc
+	// transferStats receives transfer totals from fetches; see
+	// SetTransferStatsHook.
+	transferStats remote.StatsFunc
+	// gitSyncing turns the git shadow off while SyncFromGit brings graft in
+	// step with git.
+	gitSyncing bool
+}
+
+// SetProgress registers fn to receive progress from long-running operations
+// on r: network fetches, object store maintenance such as GC, and the files
+// handled by Add, Checkout and Merge (see ProgressStaging and the phases
+// after it). A nil fn disables reporting.
+func (r *Repo) SetProgress(fn object.ProgressFunc) {
+	r.progress = fn
+	r.Store.SetProgress(fn)
+}
+
+// SetTransferStatsHook registers fn to receive running transfer totals from
+// network fetches on r. The final totals of a fetch are also returned in
+// FetchResult.Stats. A nil fn disables the hook.
+func (r *Repo) SetTransferStatsHook(fn remote.StatsFunc) {
+	r.transferStats = fn
 }
 
 func (r *Repo) getMergeTraversalState() *mergeBaseTraversalState {
@@ -62,6 +97,25 @@ func (r *Repo) ShallowState() (*remote.ShallowState, error) {
 	return r.shallowState, r.shallowErr
 }
 
+// WriteShallowState persists shallow boundaries to .graft/shallow and
+// refreshes the cached state. When no boundaries remain the file is removed,
+// turning the repository back into a complete one.
+func (r *Repo) WriteShallowState(state *remote.ShallowState) error {
+	if state == nil || state.Len() == 0 {
+		if err := os.Remove(filepath.Join(r.GraftDir, "shallow")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove shallow file: %w", err)
+		}
+		state = remote.NewShallowState()
+	} else if err := remote.WriteShallowFile(r.GraftDir, state); err != nil {
+		return err
+	}
+
+	r.shallowOnce.Do(func() {})
+	r.shallowState, r.shallowErr = state, nil
+	r.InvalidateMergeBaseCache()
+	return nil
+}
+
 // IsShallowRepository returns true if this repository has shallow boundaries.
 func (r *Repo) IsShallowRepository() bool {
 	state, err := r.ShallowState()
@@ -81,7 +81,7 @@ func (r *Repo) ResetToCommit(target object.Hash, mode ResetMode) error {
 	}
 
 	// 5. For mixed and hard: reset staging to match target tree.
-	targetEntries, err := r.FlattenTree(commit.TreeHash)
+	targetEntries, err := r.flattenCheckoutTree(commit.TreeHash)
 	if err != nil {
 		return fmt.Errorf("reset: flatten target tree: %w", err)
 	}
@@ -127,11 +127,12 @@ func (r *Repo) ResetToCommit(target object.Hash, mode ResetMode) error {
 	}
 
 	// 6b. Write all files from target tree.
+	le := r.lineEndingsForTree(targetEntries)
 	for _, e := range targetEntries {
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(e.Path))
 
 		dir := filepath.Dir(absPath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := mkdirWorktree(r.RootDir, dir); err != nil {
 			return fmt.Errorf("reset --hard: mkdir %q: %w", dir, err)
 		}
 
@@ -146,9 +147,11 @@ func (r *Repo) ResetToCommit(target object.Hash, mode ResetMode) error {
 			if lfsErr == nil {
 				blobData = lfsContent
 			}
+		} else {
+			blobData = le.smudge(e.Path, blobData, e.Mode)
 		}
 
-		if err := os.WriteFile(absPath, blobData, filePermFromMode(e.Mode)); err != nil {
+		if err := writeWorktreeFile(absPath, blobData, e.Mode); err != nil {
 			return fmt.Errorf("reset --hard: write %q: %w", e.Path, err)
 		}
 	}
@@ -156,7 +159,7 @@ func (r *Repo) ResetToCommit(target object.Hash, mode ResetMode) error {
 	// 6c. Update staging with accurate stat info from the freshly written files.
 	for path, se := range stg.Entries {
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(path))
-		info, err := os.Stat(absPath)
+		info, err := os.Lstat(absPath)
 		if err != nil {
 			continue
 		}
@@ -175,6 +175,90 @@ func TestResolveAuthor_RepoOverridesUserConfig(t *testing.T) {
 	}
 }
 
+func TestResolveIdentity_EnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", dir)
+
+	cfg := &Config{
+		Remotes: make(map[string]string),
+		User:    &UserConfig{Name: "Alice", Email: "alice@example.com"},
+	}
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(AuthorNameEnv, "")
+	t.Setenv(AuthorEmailEnv, "alice@work.example.com")
+	t.Setenv(CommitterNameEnv, "CI Bot")
+	t.Setenv(CommitterEmailEnv, "ci@example.com")
+
+	if got, want := r.ResolveAuthor(), "Alice <alice@work.example.com>"; got != want {
+		t.Fatalf("ResolveAuthor = %q, want %q", got, want)
+	}
+	if got, want := r.ResolveCommitter(), "CI Bot <ci@example.com>"; got != want {
+		t.Fatalf("ResolveCommitter = %q, want %q", got, want)
+	}
+}
+
+func TestCommitRecordsCommitter(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", dir)
+	t.Setenv(AuthorNameEnv, "")
+	t.Setenv(AuthorEmailEnv, "")
+	t.Setenv(CommitterNameEnv, "")
+	t.Setenv(CommitterEmailEnv, "")
+
+	cfg := &Config{
+		Remotes: make(map[string]string),
+		User:    &UserConfig{Name: "Alice", Email: "alice@example.com"},
+	}
+	if err := r.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add([]string{"a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := r.CommitWithOptions("add a", "", CommitOptions{})
+	if err != nil {
+		t.Fatalf("CommitWithOptions: %v", err)
+	}
+	c, err := r.Store.ReadCommit(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Author != "Alice <alice@example.com>" || c.Committer != "Alice <alice@example.com>" {
+		t.Fatalf("author %q committer %q, want both resolved from config", c.Author, c.Committer)
+	}
+	if c.CommitterTimestamp == 0 || c.CommitterTimezone == "" {
+		t.Fatalf("committer date not recorded: %d %q", c.CommitterTimestamp, c.CommitterTimezone)
+	}
+
+	// Someone else's work keeps its author; the committer is still us.
+	t.Setenv(CommitterNameEnv, "CI Bot")
+	h, err = r.CommitWithOptions("empty", "Bob <bob@example.com>", CommitOptions{AllowEmpty: true})
+	if err != nil {
+		t.Fatalf("CommitWithOptions: %v", err)
+	}
+	if c, err = r.Store.ReadCommit(h); err != nil {
+		t.Fatal(err)
+	}
+	if c.Author != "Bob <bob@example.com>" || c.Committer != "CI Bot <alice@example.com>" {
+		t.Fatalf("author %q committer %q, want Bob and CI Bot", c.Author, c.Committer)
+	}
+}
+
 func TestFormatAuthor(t *testing.T) {
 	tests := []struct {
 		name, email, want string
@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/odvcencio/graft/pkg/merge"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// ResolveUndoEntry remembers the merge stages of a conflicted path after the
+// conflict was resolved, so the conflict can be recreated later.
+type ResolveUndoEntry struct {
+	Path           string      `json:"path"`
+	Mode           string      `json:"mode,omitempty"`
+	BaseBlobHash   object.Hash `json:"base_blob_hash,omitempty"`
+	OursBlobHash   object.Hash `json:"ours_blob_hash,omitempty"`
+	TheirsBlobHash object.Hash `json:"theirs_blob_hash,omitempty"`
+}
+
+// recordResolveUndo saves the merge stages of path before its conflicted
+// entry is replaced or removed. It does nothing when path is not conflicted.
+func (s *Staging) recordResolveUndo(path string) {
+	e := s.Entries[path]
+	if e == nil || !e.Conflict {
+		return
+	}
+	if s.ResolveUndo == nil {
+		s.ResolveUndo = make(map[string]*ResolveUndoEntry)
+	}
+	s.ResolveUndo[path] = &ResolveUndoEntry{
+		Path:           path,
+		Mode:           e.Mode,
+		BaseBlobHash:   e.BaseBlobHash,
+		OursBlobHash:   e.OursBlobHash,
+		TheirsBlobHash: e.TheirsBlobHash,
+	}
+}
+
+// RecreateConflicts rewrites each path with the conflict markers of its
+// merge and marks it conflicted in the index again. A path qualifies while
+// it is still conflicted, or after its conflict was resolved and restaged,
+// using the merge stages kept as resolve-undo data. Local edits to the
+// paths are overwritten. It returns the repo-relative paths recreated.
+func (r *Repo) RecreateConflicts(paths []string) ([]string, error) {
+	stg, err := r.ReadStaging()
+	if err != nil {
+		return nil, fmt.Errorf("recreate conflicts: %w", err)
+	}
+
+	type stages struct {
+		path, mode         string
+		base, ours, theirs object.Hash
+	}
+	var todo []stages
+	for _, p := range paths {
+		relPath, err := r.repoRelPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("recreate conflicts: resolve path %q: %w", p, err)
+		}
+		relPath = filepath.ToSlash(filepath.Clean(relPath))
+		if relPath == "." || isOutsideRepo(relPath) {
+			return nil, fmt.Errorf("recreate conflicts: path %q is outside repository", p)
+		}
+		if e := stg.Entries[relPath]; e != nil && e.Conflict {
+			todo = append(todo, stages{relPath, e.Mode, e.BaseBlobHash, e.OursBlobHash, e.TheirsBlobHash})
+			continue
+		}
+		ru := stg.ResolveUndo[relPath]
+		if ru == nil {
+			return nil, fmt.Errorf("recreate conflicts: %s: no conflict to recreate", relPath)
+		}
+		todo = append(todo, stages{relPath, ru.Mode, ru.BaseBlobHash, ru.OursBlobHash, ru.TheirsBlobHash})
+	}
+
+	le := r.lineEndings()
+	recreated := make([]string, 0, len(todo))
+	for _, st := range todo {
+		content, err := r.renderRecordedConflict(st.path, st.base, st.ours, st.theirs)
+		if err != nil {
+			return nil, fmt.Errorf("recreate conflicts: %s: %w", st.path, err)
+		}
+		mode := normalizeFileMode(st.mode)
+		absPath := filepath.Join(r.RootDir, filepath.FromSlash(st.path))
+		if err := mkdirWorktree(r.RootDir, filepath.Dir(absPath)); err != nil {
+			return nil, fmt.Errorf("recreate conflicts: %w", err)
+		}
+		if err := writeWorktreeFile(absPath, le.smudge(st.path, content, mode), mode); err != nil {
+			return nil, fmt.Errorf("recreate conflicts: write %q: %w", st.path, err)
+		}
+		info, err := os.Lstat(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("recreate conflicts: stat %q: %w", st.path, err)
+		}
+		blobHash, err := r.Store.WriteBlob(&object.Blob{Data: content})
+		if err != nil {
+			return nil, fmt.Errorf("recreate conflicts: write blob %q: %w", st.path, err)
+		}
+
+		entry := &StagingEntry{
+			Path:           st.path,
+			BlobHash:       blobHash,
+			Conflict:       true,
+			BaseBlobHash:   st.base,
+			OursBlobHash:   st.ours,
+			TheirsBlobHash: st.theirs,
+		}
+		setStagingEntryStat(entry, info, mode)
+		stg.Entries[st.path] = entry
+		delete(stg.ResolveUndo, st.path)
+		recreated = append(recreated, st.path)
+	}
+
+	if err := r.WriteStaging(stg); err != nil {
+		return nil, fmt.Errorf("recreate conflicts: %w", err)
+	}
+	return recreated, nil
+}
+
+// renderRecordedConflict reproduces the conflicted content a merge wrote for
+// path from its base, ours and theirs blobs. A missing side means the path
+// was deleted on that side.
+func (r *Repo) renderRecordedConflict(path string, base, ours, theirs object.Hash) ([]byte, error) {
+	read := func(h object.Hash) ([]byte, error) {
+		if h == "" {
+			return nil, nil
+		}
+		return r.readBlobData(h)
+	}
+	baseData, err := read(base)
+	if err != nil {
+		return nil, err
+	}
+	oursData, err := read(ours)
+	if err != nil {
+		return nil, err
+	}
+	theirsData, err := read(theirs)
+	if err != nil {
+		return nil, err
+	}
+
+	if ours == "" || theirs == "" {
+		return renderFileConflict(oursData, theirsData), nil
+	}
+	result, err := merge.MergeFiles(path, baseData, oursData, theirsData)
+	if err != nil {
+		return nil, fmt.Errorf("structural merge: %w", err)
+	}
+	return result.Merged, nil
+}
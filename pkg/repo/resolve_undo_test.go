@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecreateConflicts_AfterResolutionUsesResolveUndo(t *testing.T) {
+	r, dir := setupMergeRepo(t)
+
+	commitFile(t, r, "main.go", []byte("package main\n\nfunc A() { println(\"ours\") }\n"), "modify A on main")
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	commitFile(t, r, "main.go", []byte("package main\n\nfunc A() { println(\"theirs\") }\n"), "modify A on feature")
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+
+	report, err := r.Merge("feature")
+	if err != nil {
+		t.Fatalf("Merge(feature): %v", err)
+	}
+	if !report.HasConflicts {
+		t.Fatal("expected conflicts")
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	conflicted := *stg.Entries["main.go"]
+	markers, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatalf("read main.go: %v", err)
+	}
+
+	// Resolve by accident: take ours and restage.
+	writeFile(t, filepath.Join(dir, "main.go"), []byte("package main\n\nfunc A() { println(\"ours\") }\n"))
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// The resolve-undo data survives a round trip through the index.
+	stg, err = r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if stg.Entries["main.go"].Conflict {
+		t.Fatal("main.go should be resolved after add")
+	}
+	ru := stg.ResolveUndo["main.go"]
+	if ru == nil {
+		t.Fatal("missing resolve-undo entry for main.go")
+	}
+	if ru.BaseBlobHash != conflicted.BaseBlobHash || ru.OursBlobHash != conflicted.OursBlobHash || ru.TheirsBlobHash != conflicted.TheirsBlobHash {
+		t.Fatalf("resolve-undo = %+v, want stages of %+v", ru, conflicted)
+	}
+
+	recreated, err := r.RecreateConflicts([]string{filepath.Join(dir, "main.go")})
+	if err != nil {
+		t.Fatalf("RecreateConflicts: %v", err)
+	}
+	if len(recreated) != 1 || recreated[0] != "main.go" {
+		t.Fatalf("recreated = %v, want [main.go]", recreated)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatalf("read main.go: %v", err)
+	}
+	if string(got) != string(markers) {
+		t.Fatalf("recreated content:\n%s\nwant:\n%s", got, markers)
+	}
+	if !strings.Contains(string(got), "<<<<<<<") {
+		t.Fatalf("expected conflict markers, got:\n%s", got)
+	}
+
+	stg, err = r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	e := stg.Entries["main.go"]
+	if !e.Conflict || e.OursBlobHash != conflicted.OursBlobHash || e.TheirsBlobHash != conflicted.TheirsBlobHash {
+		t.Fatalf("entry after recreate = %+v, want conflicted with original stages", e)
+	}
+	if _, ok := stg.ResolveUndo["main.go"]; ok {
+		t.Fatal("resolve-undo entry should be consumed once the conflict is back")
+	}
+}
+
+func TestRecreateConflicts_RejectsPathWithoutConflict(t *testing.T) {
+	r, dir := setupMergeRepo(t)
+	if _, err := r.RecreateConflicts([]string{filepath.Join(dir, "main.go")}); err == nil || !strings.Contains(err.Error(), "no conflict to recreate") {
+		t.Fatalf("RecreateConflicts error = %v, want no conflict to recreate", err)
+	}
+}
@@ -0,0 +1,218 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// RestoreEntityResult describes an entity restored by RestoreEntity.
+type RestoreEntityResult struct {
+	Path        string
+	EntityKey   string // identity key of the entity in the source commit
+	DisplayName string // e.g. "func (*Repo) Commit"
+	Source      object.Hash
+	Added       bool // the entity was missing from the working file and was appended
+}
+
+// RestoreEntity replaces one entity of a working-tree file with its version
+// from the source commit, leaving the rest of the file untouched. The
+// selector is either <path::entity_key> or <path:Name>, where Name is a
+// declaration name, optionally qualified by its receiver (Repo.Commit).
+//
+// The entity is matched in the working file by identity key first and by
+// declaration name second, so restoring a function whose signature changed
+// since the source commit still replaces it. An entity missing from the
+// working file is appended to it. Nothing is staged.
+func (r *Repo) RestoreEntity(selector string, source object.Hash) (*RestoreEntityResult, error) {
+	pathSpec, entityKey, name, err := parseRestoreEntitySelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := r.repoRelPath(pathSpec)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: resolve path %q: %w", pathSpec, err)
+	}
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+	if relPath == "." || isOutsideRepo(relPath) {
+		return nil, fmt.Errorf("restore entity: path %q is outside repository", pathSpec)
+	}
+
+	commit, err := r.Store.ReadCommit(source)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: read commit %s: %w", source, err)
+	}
+	treeEntry, found, err := r.treeEntryAtPath(commit.TreeHash, relPath)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: read %q at %s: %w", relPath, shortHash(source), err)
+	}
+	if !found {
+		return nil, fmt.Errorf("restore entity: path %q does not exist in %s", relPath, shortHash(source))
+	}
+	sourceData, err := r.readBlobData(treeEntry.BlobHash)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: read blob %s: %w", treeEntry.BlobHash, err)
+	}
+	sourceList, err := entity.Extract(relPath, sourceData)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: extract entities from %q at %s: %w", relPath, shortHash(source), err)
+	}
+	sourceEnt, err := findRestoreEntity(sourceList, entityKey, name)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: %s at %s: %w", selector, shortHash(source), err)
+	}
+
+	absPath := filepath.Join(r.RootDir, filepath.FromSlash(relPath))
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: %w", err)
+	}
+	mode := worktreeMode(info, "", true)
+	current, err := r.ReadWorktreeFile(relPath)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: read %q: %w", relPath, err)
+	}
+	currentList, err := entity.Extract(relPath, current)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: extract entities from %q: %w", relPath, err)
+	}
+
+	result := &RestoreEntityResult{
+		Path:        relPath,
+		EntityKey:   sourceEnt.IdentityKey(),
+		DisplayName: entity.EntityDisplayName(sourceEnt),
+		Source:      source,
+	}
+
+	target, err := matchRestoreTarget(currentList, sourceEnt)
+	if err != nil {
+		return nil, fmt.Errorf("restore entity: %s in %q: %w", result.DisplayName, relPath, err)
+	}
+	var restored []byte
+	if target >= 0 {
+		if bytes.Equal(currentList.Entities[target].Body, sourceEnt.Body) {
+			return nil, fmt.Errorf("restore entity: %s is unchanged since %s", result.DisplayName, shortHash(source))
+		}
+		currentList.Entities[target].Body = append([]byte(nil), sourceEnt.Body...)
+		restored = entity.Reconstruct(currentList)
+	} else {
+		restored = append([]byte(nil), current...)
+		if len(restored) > 0 && !bytes.HasSuffix(restored, []byte("\n\n")) {
+			if !bytes.HasSuffix(restored, []byte("\n")) {
+				restored = append(restored, '\n')
+			}
+			restored = append(restored, '\n')
+		}
+		restored = append(restored, sourceEnt.Body...)
+		if !bytes.HasSuffix(restored, []byte("\n")) {
+			restored = append(restored, '\n')
+		}
+		result.Added = true
+	}
+
+	if err := mkdirWorktree(r.RootDir, filepath.Dir(absPath)); err != nil {
+		return nil, fmt.Errorf("restore entity: %w", err)
+	}
+	if err := writeWorktreeFile(absPath, r.lineEndings().smudge(relPath, restored, mode), mode); err != nil {
+		return nil, fmt.Errorf("restore entity: write %q: %w", relPath, err)
+	}
+	return result, nil
+}
+
+// parseRestoreEntitySelector splits a restore selector into its path and
+// either an identity key (path::key) or a declaration name (path:Name).
+func parseRestoreEntitySelector(selector string) (pathSpec, entityKey, name string, err error) {
+	if strings.Contains(selector, "::") {
+		pathSpec, entityKey, err = parseEntitySelector(selector)
+		return pathSpec, entityKey, "", err
+	}
+	selector = strings.TrimSpace(selector)
+	i := strings.LastIndex(selector, ":")
+	if i <= 0 || i == len(selector)-1 {
+		return "", "", "", fmt.Errorf("%w: expected <path:Name> or <path::entity_key>, got %q", ErrInvalidEntitySelector, selector)
+	}
+	return strings.TrimSpace(selector[:i]), "", strings.TrimSpace(selector[i+1:]), nil
+}
+
+// findRestoreEntity finds the entity selected by key or by name in el. A
+// name must identify exactly one declaration.
+func findRestoreEntity(el *entity.EntityList, entityKey, name string) (*entity.Entity, error) {
+	if entityKey != "" {
+		if ent, ok := entity.BuildEntityMap(el)[entityKey]; ok {
+			return ent, nil
+		}
+		return nil, ErrEntityNotFound
+	}
+
+	var matches []*entity.Entity
+	for i := range el.Entities {
+		if declNameMatches(&el.Entities[i], name) {
+			matches = append(matches, &el.Entities[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, ErrEntityNotFound
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, 0, len(matches))
+	for _, m := range matches {
+		names = append(names, m.IdentityKey())
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("%q is ambiguous; use one of the entity keys:\n  %s", name, strings.Join(names, "\n  "))
+}
+
+// declNameMatches reports whether e is a declaration called name. A name
+// of the form Recv.Name also has to match the receiver type, ignoring the
+// receiver variable, pointers and type parameters.
+func declNameMatches(e *entity.Entity, name string) bool {
+	if e.Kind != entity.KindDeclaration {
+		return false
+	}
+	if e.Name == name {
+		return true
+	}
+	recv, method, ok := strings.Cut(name, ".")
+	if !ok || e.Name != method {
+		return false
+	}
+	fields := strings.Fields(strings.Trim(e.Receiver, "()"))
+	if len(fields) == 0 {
+		return false
+	}
+	recvType, _, _ := strings.Cut(strings.TrimLeft(fields[len(fields)-1], "*"), "[")
+	return recvType == strings.TrimLeft(recv, "*")
+}
+
+// matchRestoreTarget returns the index in el of the entity that src should
+// replace, or -1 when el has none.
+func matchRestoreTarget(el *entity.EntityList, src *entity.Entity) (int, error) {
+	key := src.IdentityKey()
+	for i := range el.Entities {
+		if el.Entities[i].IdentityKey() == key {
+			return i, nil
+		}
+	}
+	if src.Kind != entity.KindDeclaration {
+		return -1, nil
+	}
+	match := -1
+	for i := range el.Entities {
+		e := &el.Entities[i]
+		if e.Kind == entity.KindDeclaration && e.DeclKind == src.DeclKind && e.Receiver == src.Receiver && e.Name == src.Name {
+			if match >= 0 {
+				return -1, fmt.Errorf("several declarations match; select one by entity key")
+			}
+			match = i
+		}
+	}
+	return match, nil
+}
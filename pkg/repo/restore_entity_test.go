@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRestoreEntity_SplicesHistoricalBody(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitFile(t, r, "main.go", []byte("package main\n\ntype Repo struct{}\n\nfunc helper() int { return 1 }\n\nfunc (r *Repo) Target() int { return 1 }\n"), "base")
+	source, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	commitFile(t, r, "main.go", []byte("package main\n\ntype Repo struct{}\n\nfunc helper() int { return 2 }\n\nfunc (r *Repo) Target(x int) int { return x }\n"), "change both")
+
+	// An uncommitted edit elsewhere in the file must survive the restore.
+	abs := filepath.Join(r.RootDir, "main.go")
+	writeFile(t, abs, []byte("package main\n\ntype Repo struct{}\n\nfunc helper() int { return 3 }\n\nfunc (r *Repo) Target(x int) int { return x }\n"))
+
+	result, err := r.RestoreEntity("main.go:Repo.Target", source)
+	if err != nil {
+		t.Fatalf("RestoreEntity: %v", err)
+	}
+	if result.Added || result.DisplayName != "func (r *Repo) Target" {
+		t.Fatalf("result = %+v", result)
+	}
+	got, err := os.ReadFile(abs)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := "package main\n\ntype Repo struct{}\n\nfunc helper() int { return 3 }\n\nfunc (r *Repo) Target() int { return 1 }\n"
+	if string(got) != want {
+		t.Fatalf("main.go = %q, want %q", got, want)
+	}
+	if st := statusByPath(t, r)["main.go"]; st.IndexStatus != StatusClean {
+		t.Fatalf("main.go index status = %d, want the restore left unstaged", st.IndexStatus)
+	}
+
+	if _, err := r.RestoreEntity("main.go:Target", source); err == nil || !strings.Contains(err.Error(), "unchanged") {
+		t.Fatalf("second RestoreEntity error = %v, want unchanged", err)
+	}
+	if _, err := r.RestoreEntity("main.go:missing", source); !errors.Is(err, ErrEntityNotFound) {
+		t.Fatalf("RestoreEntity(missing) error = %v, want ErrEntityNotFound", err)
+	}
+	if _, err := r.RestoreEntity("main.go", source); !errors.Is(err, ErrInvalidEntitySelector) {
+		t.Fatalf("RestoreEntity(no name) error = %v, want ErrInvalidEntitySelector", err)
+	}
+}
+
+func TestRestoreEntity_AppendsDeletedEntity(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitFile(t, r, "main.go", []byte("package main\n\nfunc keep() {}\n\nfunc gone() {}\n"), "base")
+	source, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	commitFile(t, r, "main.go", []byte("package main\n\nfunc keep() {}\n"), "drop gone")
+
+	result, err := r.RestoreEntity("main.go:gone", source)
+	if err != nil {
+		t.Fatalf("RestoreEntity: %v", err)
+	}
+	if !result.Added {
+		t.Fatalf("result = %+v, want Added", result)
+	}
+	got, err := os.ReadFile(filepath.Join(r.RootDir, "main.go"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if want := "package main\n\nfunc keep() {}\n\nfunc gone() {}\n"; string(got) != want {
+		t.Fatalf("main.go = %q, want %q", got, want)
+	}
+}
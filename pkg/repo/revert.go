@@ -194,7 +194,7 @@ func (r *Repo) RevertContinue() (*RevertResult, error) {
 	}
 	for _, entry := range stg.Entries {
 		if entry.Conflict {
-			return nil, fmt.Errorf("revert continue: unresolved conflicts remain; resolve them and stage the files")
+			return nil, fmt.Errorf("revert continue: %w remain; resolve them and stage the files", ErrMergeConflicts)
 		}
 	}
 
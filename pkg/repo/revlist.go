@@ -0,0 +1,322 @@
+package repo
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// RevRange is a set of commits described by revision arguments: every
+// commit reachable from an Include tip and from no Exclude tip.
+type RevRange struct {
+	Include []object.Hash
+	Exclude []object.Hash
+}
+
+// RevListOptions controls RevList.
+type RevListOptions struct {
+	// MaxCount stops the listing after that many commits. Zero means no
+	// limit.
+	MaxCount int
+
+	// TopoOrder lists no parent before all of its children, keeping the
+	// commits of a line of history together. The default order is newest
+	// commit date first.
+	TopoOrder bool
+}
+
+// ParseRevRange resolves revision arguments the way rev-list reads them:
+//
+//	A..B    commits reachable from B but not from A
+//	A...B   commits reachable from exactly one of A and B
+//	^A      exclude commits reachable from A
+//	--not   flip the sense of every following revision and ^ prefix
+//
+// An empty side of a range means HEAD, so "A.." is "A..HEAD".
+func (r *Repo) ParseRevRange(args []string) (*RevRange, error) {
+	rr := &RevRange{}
+	negate := false
+	for _, arg := range args {
+		if arg == "--not" {
+			negate = !negate
+			continue
+		}
+
+		if left, right, ok := strings.Cut(arg, "..."); ok {
+			a, err := r.resolveRevListCommit(left)
+			if err != nil {
+				return nil, err
+			}
+			b, err := r.resolveRevListCommit(right)
+			if err != nil {
+				return nil, err
+			}
+			// Criss-cross merges leave several best common ancestors;
+			// every one of them bounds the symmetric difference.
+			bases, err := r.mergeBases(a, b)
+			if err != nil {
+				return nil, fmt.Errorf("rev-list: %s: %w", arg, err)
+			}
+			rr.add(a, negate)
+			rr.add(b, negate)
+			for _, base := range bases {
+				rr.add(base, !negate)
+			}
+			continue
+		}
+		if left, right, ok := strings.Cut(arg, ".."); ok {
+			a, err := r.resolveRevListCommit(left)
+			if err != nil {
+				return nil, err
+			}
+			b, err := r.resolveRevListCommit(right)
+			if err != nil {
+				return nil, err
+			}
+			rr.add(a, !negate)
+			rr.add(b, negate)
+			continue
+		}
+
+		exclude := negate
+		if rest, ok := strings.CutPrefix(arg, "^"); ok {
+			arg, exclude = rest, !exclude
+		}
+		h, err := r.resolveRevListCommit(arg)
+		if err != nil {
+			return nil, err
+		}
+		rr.add(h, exclude)
+	}
+	return rr, nil
+}
+
+func (rr *RevRange) add(h object.Hash, exclude bool) {
+	if exclude {
+		rr.Exclude = append(rr.Exclude, h)
+	} else {
+		rr.Include = append(rr.Include, h)
+	}
+}
+
+// resolveRevListCommit resolves one side of a revision argument to a
+// commit, peeling annotated tags. An empty revision means HEAD.
+func (r *Repo) resolveRevListCommit(rev string) (object.Hash, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	h, err := r.ResolveTreeish(rev)
+	if err != nil {
+		return "", fmt.Errorf("rev-list: %w", err)
+	}
+	c, err := r.peelToCommit(h)
+	if err != nil {
+		return "", fmt.Errorf("rev-list: %s: %w", rev, err)
+	}
+	return c, nil
+}
+
+// RevList lists the commits in rr, ordered per opts. Without TopoOrder the
+// included history is walked newest first from a queue, so MaxCount stops
+// the walk early.
+func (r *Repo) RevList(rr *RevRange, opts RevListOptions) ([]object.Hash, error) {
+	excluded := make(map[object.Hash]*object.CommitObj)
+	for _, h := range rr.Exclude {
+		if err := r.collectRevListCommits(h, nil, excluded); err != nil {
+			return nil, fmt.Errorf("rev-list: %w", err)
+		}
+	}
+	exclude := make(map[object.Hash]bool, len(excluded))
+	for h := range excluded {
+		exclude[h] = true
+	}
+
+	if opts.TopoOrder {
+		commits := make(map[object.Hash]*object.CommitObj)
+		for _, h := range rr.Include {
+			if err := r.collectRevListCommits(h, exclude, commits); err != nil {
+				return nil, fmt.Errorf("rev-list: %w", err)
+			}
+		}
+		order := topoOrderCommits(commits)
+		if opts.MaxCount > 0 && len(order) > opts.MaxCount {
+			order = order[:opts.MaxCount]
+		}
+		return order, nil
+	}
+
+	shallow, _ := r.ShallowState()
+	seen := make(map[object.Hash]*object.CommitObj)
+	queue := &revListHeap{commits: seen}
+	enqueue := func(h object.Hash) error {
+		if h == "" || exclude[h] || seen[h] != nil {
+			return nil
+		}
+		c, err := r.Store.ReadCommit(h)
+		if err != nil {
+			return fmt.Errorf("rev-list: read commit %s: %w", h, err)
+		}
+		seen[h] = c
+		heap.Push(queue, h)
+		return nil
+	}
+	for _, h := range rr.Include {
+		if err := enqueue(h); err != nil {
+			return nil, err
+		}
+	}
+
+	var order []object.Hash
+	for queue.Len() > 0 {
+		h := heap.Pop(queue).(object.Hash)
+		order = append(order, h)
+		if len(order) == opts.MaxCount {
+			break
+		}
+		if shallow != nil && shallow.IsShallow(h) {
+			continue
+		}
+		for _, p := range seen[h].Parents {
+			if err := enqueue(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return order, nil
+}
+
+// mergeBases returns every best common ancestor of a and b: the common
+// ancestors that are not themselves ancestors of another common ancestor.
+func (r *Repo) mergeBases(a, b object.Hash) ([]object.Hash, error) {
+	ancestorsA := make(map[object.Hash]*object.CommitObj)
+	if err := r.collectRevListCommits(a, nil, ancestorsA); err != nil {
+		return nil, err
+	}
+	// Walk b's history, stopping at the first common commit on each path;
+	// those commits and their ancestors are the common ancestors.
+	common := make(map[object.Hash]*object.CommitObj)
+	visited := make(map[object.Hash]bool)
+	stack := []object.Hash{b}
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if h == "" || visited[h] {
+			continue
+		}
+		visited[h] = true
+		if ancestorsA[h] != nil {
+			if err := r.collectRevListCommits(h, nil, common); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		c, err := r.Store.ReadCommit(h)
+		if err != nil {
+			return nil, fmt.Errorf("read commit %s: %w", h, err)
+		}
+		stack = append(stack, c.Parents...)
+	}
+
+	// A common ancestor that is the parent of another one is not best.
+	notBest := make(map[object.Hash]bool)
+	for _, c := range common {
+		for _, p := range c.Parents {
+			notBest[p] = true
+		}
+	}
+	var bases []object.Hash
+	for h := range common {
+		if !notBest[h] {
+			bases = append(bases, h)
+		}
+	}
+	sort.Slice(bases, func(i, j int) bool { return newerCommit(common, bases[i], bases[j]) })
+	return bases, nil
+}
+
+// collectRevListCommits adds to commits every commit reachable from start
+// that is not in exclude, stopping at shallow boundaries.
+func (r *Repo) collectRevListCommits(start object.Hash, exclude map[object.Hash]bool, commits map[object.Hash]*object.CommitObj) error {
+	shallow, _ := r.ShallowState()
+	stack := []object.Hash{start}
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if h == "" || exclude[h] || commits[h] != nil {
+			continue
+		}
+		c, err := r.Store.ReadCommit(h)
+		if err != nil {
+			return fmt.Errorf("read commit %s: %w", h, err)
+		}
+		commits[h] = c
+		if shallow != nil && shallow.IsShallow(h) {
+			continue
+		}
+		stack = append(stack, c.Parents...)
+	}
+	return nil
+}
+
+// topoOrderCommits orders commits so that every commit comes before its
+// parents. Among the commits ready to be listed, the newest goes first.
+func topoOrderCommits(commits map[object.Hash]*object.CommitObj) []object.Hash {
+	children := make(map[object.Hash]int, len(commits))
+	for _, c := range commits {
+		for _, p := range c.Parents {
+			if commits[p] != nil {
+				children[p]++
+			}
+		}
+	}
+
+	ready := &revListHeap{commits: commits}
+	for h := range commits {
+		if children[h] == 0 {
+			ready.hashes = append(ready.hashes, h)
+		}
+	}
+	heap.Init(ready)
+
+	order := make([]object.Hash, 0, len(commits))
+	for ready.Len() > 0 {
+		h := heap.Pop(ready).(object.Hash)
+		order = append(order, h)
+		for _, p := range commits[h].Parents {
+			if commits[p] == nil {
+				continue
+			}
+			if children[p]--; children[p] == 0 {
+				heap.Push(ready, p)
+			}
+		}
+	}
+	return order
+}
+
+// revListHeap pops the newest commit first.
+type revListHeap struct {
+	hashes  []object.Hash
+	commits map[object.Hash]*object.CommitObj
+}
+
+func (h revListHeap) Len() int           { return len(h.hashes) }
+func (h revListHeap) Less(i, j int) bool { return newerCommit(h.commits, h.hashes[i], h.hashes[j]) }
+func (h revListHeap) Swap(i, j int)      { h.hashes[i], h.hashes[j] = h.hashes[j], h.hashes[i] }
+func (h *revListHeap) Push(x any)        { h.hashes = append(h.hashes, x.(object.Hash)) }
+func (h *revListHeap) Pop() any {
+	last := h.hashes[len(h.hashes)-1]
+	h.hashes = h.hashes[:len(h.hashes)-1]
+	return last
+}
+
+// newerCommit orders commits by timestamp, newest first, then by hash.
+func newerCommit(commits map[object.Hash]*object.CommitObj, a, b object.Hash) bool {
+	if ta, tb := commits[a].Timestamp, commits[b].Timestamp; ta != tb {
+		return ta > tb
+	}
+	return a < b
+}
@@ -0,0 +1,168 @@
+package repo
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestRevList_RangesAndTopoOrder(t *testing.T) {
+	r, _ := setupMergeRepo(t)
+	base, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef(HEAD): %v", err)
+	}
+	m1 := commitFile(t, r, "a.txt", []byte("a\n"), "main work")
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	f1 := commitFile(t, r, "b.txt", []byte("b\n"), "feature work")
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+
+	list := func(opts RevListOptions, args ...string) []object.Hash {
+		t.Helper()
+		rr, err := r.ParseRevRange(args)
+		if err != nil {
+			t.Fatalf("ParseRevRange(%v): %v", args, err)
+		}
+		hashes, err := r.RevList(rr, opts)
+		if err != nil {
+			t.Fatalf("RevList(%v): %v", args, err)
+		}
+		return hashes
+	}
+	sameSet := func(args []string, got []object.Hash, want ...object.Hash) {
+		t.Helper()
+		g := append([]object.Hash(nil), got...)
+		w := append([]object.Hash(nil), want...)
+		sort.Slice(g, func(i, j int) bool { return g[i] < g[j] })
+		sort.Slice(w, func(i, j int) bool { return w[i] < w[j] })
+		if len(g) != len(w) {
+			t.Fatalf("rev-list %v = %v, want %v", args, got, want)
+		}
+		for i := range g {
+			if g[i] != w[i] {
+				t.Fatalf("rev-list %v = %v, want %v", args, got, want)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want []object.Hash
+	}{
+		{[]string{"main..feature"}, []object.Hash{f1}},
+		{[]string{"feature..main"}, []object.Hash{m1}},
+		{[]string{"feature.."}, []object.Hash{m1}},
+		{[]string{"main...feature"}, []object.Hash{m1, f1}},
+		{[]string{"feature", "--not", "main"}, []object.Hash{f1}},
+		{[]string{"^main", "feature"}, []object.Hash{f1}},
+		{[]string{"main", "feature"}, []object.Hash{m1, f1, base}},
+	} {
+		sameSet(tc.args, list(RevListOptions{}, tc.args...), tc.want...)
+	}
+
+	report, err := r.Merge("feature")
+	if err != nil {
+		t.Fatalf("Merge(feature): %v", err)
+	}
+	if report.HasConflicts || report.MergeCommit == "" {
+		t.Fatalf("expected a clean merge commit, got %+v", report)
+	}
+
+	topo := list(RevListOptions{TopoOrder: true}, "HEAD")
+	if len(topo) != 4 || topo[0] != report.MergeCommit || topo[3] != base {
+		t.Fatalf("topo order = %v, want merge first and root last", topo)
+	}
+	if got := list(RevListOptions{MaxCount: 2}, "HEAD"); len(got) != 2 {
+		t.Fatalf("MaxCount 2 returned %d commits", len(got))
+	}
+
+	if _, err := r.ParseRevRange([]string{"no-such-branch..main"}); err == nil {
+		t.Fatal("expected an error for an unknown revision")
+	}
+}
+
+func TestRevList_SymmetricDifferenceExcludesEveryMergeBase(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	tree, err := r.Store.WriteTree(&object.TreeObj{})
+	if err != nil {
+		t.Fatalf("WriteTree: %v", err)
+	}
+	ts := int64(1700000000)
+	commit := func(msg string, parents ...object.Hash) object.Hash {
+		t.Helper()
+		ts++
+		h, err := r.Store.WriteCommit(&object.CommitObj{TreeHash: tree, Parents: parents, Author: "T", Timestamp: ts, Message: msg})
+		if err != nil {
+			t.Fatalf("WriteCommit: %v", err)
+		}
+		return h
+	}
+	// Criss-cross: x and y are both best common ancestors of a2 and b2.
+	root := commit("root")
+	x := commit("x", root)
+	y := commit("y", root)
+	a := commit("a", x, y)
+	b := commit("b", y, x)
+	a2 := commit("a2", a)
+	b2 := commit("b2", b)
+
+	bases, err := r.mergeBases(a2, b2)
+	if err != nil {
+		t.Fatalf("mergeBases: %v", err)
+	}
+	if len(bases) != 2 {
+		t.Fatalf("merge bases = %v, want x and y", bases)
+	}
+
+	rr, err := r.ParseRevRange([]string{string(a2) + "..." + string(b2)})
+	if err != nil {
+		t.Fatalf("ParseRevRange: %v", err)
+	}
+	got, err := r.RevList(rr, RevListOptions{})
+	if err != nil {
+		t.Fatalf("RevList: %v", err)
+	}
+	want := []object.Hash{b2, a2, b, a}
+	if len(got) != len(want) {
+		t.Fatalf("rev-list = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("rev-list = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRevList_MaxCountStopsTheWalk(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	tree, err := r.Store.WriteTree(&object.TreeObj{})
+	if err != nil {
+		t.Fatalf("WriteTree: %v", err)
+	}
+	// The parent is missing from the store, so reading past the tip fails.
+	missing := object.HashBytes([]byte("missing parent"))
+	tip, err := r.Store.WriteCommit(&object.CommitObj{TreeHash: tree, Parents: []object.Hash{missing}, Author: "T", Timestamp: 1700000000, Message: "tip"})
+	if err != nil {
+		t.Fatalf("WriteCommit: %v", err)
+	}
+
+	rr := &RevRange{Include: []object.Hash{tip}}
+	got, err := r.RevList(rr, RevListOptions{MaxCount: 1})
+	if err != nil || len(got) != 1 || got[0] != tip {
+		t.Fatalf("RevList -n1 = %v, %v; want only the tip", got, err)
+	}
+	if _, err := r.RevList(rr, RevListOptions{}); err == nil {
+		t.Fatal("RevList without a limit read past the missing parent")
+	}
+}
@@ -0,0 +1,244 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// Besides ancestor suffixes, ResolveTreeish understands the reflog and
+// path forms of Git's revision syntax:
+//
+//	@{-N}                the Nth branch checked out before the current one
+//	<branch>@{upstream}  the tracking ref of branch; @{u} for short, and the
+//	                     current branch when branch is omitted
+//	<ref>@{N}            the Nth prior value of ref from its reflog; the
+//	                     current branch when ref is omitted
+//	<rev>:<path>         the blob or tree at path in the tree of rev
+//	:<path>              the blob staged for path
+//
+// Ancestor suffixes apply after a reflog selector, as in @{-1}~2.
+
+// isExtendedRevision reports whether name uses revision syntax beyond a
+// plain ref name, so that ResolveRef hands it to ResolveTreeish.
+func isExtendedRevision(name string) bool {
+	return name == "@" || strings.ContainsAny(name, "~^:") || strings.Contains(name, "@{")
+}
+
+// cutReflogSelector splits "main@{2}" into "main" and "2".
+func cutReflogSelector(base string) (name, selector string, ok bool) {
+	i := strings.Index(base, "@{")
+	if i < 0 || !strings.HasSuffix(base, "}") {
+		return "", "", false
+	}
+	return base[:i], base[i+2 : len(base)-1], true
+}
+
+// parsePreviousCheckout recognizes the @{-N} form and returns N.
+func parsePreviousCheckout(spec string) (int, bool) {
+	name, selector, ok := cutReflogSelector(spec)
+	if !ok || name != "" || !strings.HasPrefix(selector, "-") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(selector[1:])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// resolveReflogSelector resolves name@{selector} to a commit hash.
+func (r *Repo) resolveReflogSelector(name, selector string) (object.Hash, error) {
+	if n, ok := parsePreviousCheckout("@{" + selector + "}"); ok && name == "" {
+		prev, err := r.PreviousCheckout(n)
+		if err != nil {
+			return "", err
+		}
+		return r.resolveBaseTreeish(prev)
+	}
+
+	switch strings.ToLower(selector) {
+	case "u", "upstream":
+		upstream, err := r.UpstreamRef(name)
+		if err != nil {
+			return "", err
+		}
+		return r.ResolveRef(upstream)
+	}
+
+	n, err := strconv.Atoi(selector)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("unsupported reflog selector @{%s}", selector)
+	}
+	if name == "@" {
+		name = "HEAD"
+	}
+	entries, err := r.ReadReflog(name, n+1)
+	if err != nil {
+		return "", err
+	}
+	if n >= len(entries) {
+		return "", fmt.Errorf("reflog of %q has only %d entries", name, len(entries))
+	}
+	return entries[n].NewHash, nil
+}
+
+// PreviousCheckout returns the Nth branch (or detached commit) that was
+// checked out before the current one, as recorded in the HEAD reflog.
+func (r *Repo) PreviousCheckout(n int) (string, error) {
+	entries, err := r.readReflogFile("HEAD", 0)
+	if err != nil {
+		return "", err
+	}
+	seen := 0
+	for _, e := range entries {
+		rest, ok := strings.CutPrefix(e.Reason, "checkout: moving from ")
+		if !ok {
+			continue
+		}
+		from, _, ok := strings.Cut(rest, " to ")
+		if !ok {
+			continue
+		}
+		if seen++; seen == n {
+			return from, nil
+		}
+	}
+	return "", fmt.Errorf("@{-%d}: only %d checkout(s) recorded", n, seen)
+}
+
+// UpstreamRef returns the tracking ref that a fetch of branch from origin
+// updates. An empty branch means the current one.
+func (r *Repo) UpstreamRef(branch string) (string, error) {
+	if branch == "" || branch == "HEAD" || branch == "@" {
+		current, err := r.CurrentBranch()
+		if err != nil {
+			return "", err
+		}
+		if current == "" {
+			return "", fmt.Errorf("HEAD is detached and has no upstream")
+		}
+		branch = current
+	}
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	if _, err := r.RemoteURL(defaultUpstreamRemote); err != nil {
+		return "", fmt.Errorf("branch %q has no upstream: remote %q is not configured", branch, defaultUpstreamRemote)
+	}
+	upstream, ok, err := r.TrackingRefFor(defaultUpstreamRemote, "heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("branch %q has no upstream", branch)
+	}
+	return upstream, nil
+}
+
+// resolveRevisionPath resolves <rev>:<path> to the hash of the blob or tree
+// at path in the tree of rev, and :<path> to the blob staged for path.
+func (r *Repo) resolveRevisionPath(rev, relPath string) (object.Hash, error) {
+	relPath = strings.Trim(path.Clean("/"+relPath), "/")
+	if rev == "" {
+		stg, err := r.ReadStaging()
+		if err != nil {
+			return "", err
+		}
+		entry, ok := stg.Entries[relPath]
+		if !ok {
+			return "", fmt.Errorf("path %q is not staged", relPath)
+		}
+		if entry.Conflict {
+			return "", fmt.Errorf("path %q is in conflict", relPath)
+		}
+		return entry.BlobHash, nil
+	}
+
+	h, err := r.ResolveTreeish(rev)
+	if err != nil {
+		return "", err
+	}
+	commitHash, err := r.peelToCommit(h)
+	if err != nil {
+		return "", err
+	}
+	commit, err := r.Store.ReadCommit(commitHash)
+	if err != nil {
+		return "", err
+	}
+	current := commit.TreeHash
+	if relPath == "" {
+		return current, nil
+	}
+	parts := strings.Split(relPath, "/")
+	for i, part := range parts {
+		tree, err := r.Store.ReadTree(current)
+		if err != nil {
+			return "", err
+		}
+		var found *object.TreeEntry
+		for j := range tree.Entries {
+			if tree.Entries[j].Name == part {
+				found = &tree.Entries[j]
+				break
+			}
+		}
+		if found == nil {
+			return "", fmt.Errorf("path %q does not exist in %s: %w", relPath, rev, os.ErrNotExist)
+		}
+		if !found.IsDir {
+			if i != len(parts)-1 {
+				return "", fmt.Errorf("path %q does not exist in %s: %s is a file", relPath, rev, strings.Join(parts[:i+1], "/"))
+			}
+			return found.BlobHash, nil
+		}
+		current = found.SubtreeHash
+	}
+	return current, nil
+}
+
+// SymbolicName returns the short ref name that rev refers to: the current
+// branch for HEAD, the branch behind @{-N}, and the remote-tracking name
+// behind @{upstream}. It fails for revisions that do not name a ref.
+func (r *Repo) SymbolicName(rev string) (string, error) {
+	if rev == "HEAD" || rev == "@" {
+		current, err := r.CurrentBranch()
+		if err != nil {
+			return "", err
+		}
+		if current == "" {
+			return "HEAD", nil
+		}
+		return current, nil
+	}
+	if n, ok := parsePreviousCheckout(rev); ok {
+		return r.PreviousCheckout(n)
+	}
+	if name, selector, ok := cutReflogSelector(rev); ok {
+		switch strings.ToLower(selector) {
+		case "u", "upstream":
+			upstream, err := r.UpstreamRef(name)
+			if err != nil {
+				return "", err
+			}
+			return upstreamDisplayName(upstream), nil
+		}
+	}
+	for _, prefix := range []string{"refs/heads/", "refs/tags/", "refs/remotes/"} {
+		if short, ok := strings.CutPrefix(rev, prefix); ok {
+			if _, err := r.ResolveRef(rev); err != nil {
+				return "", err
+			}
+			return short, nil
+		}
+	}
+	for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+		if _, err := r.ResolveRef(prefix + rev); err == nil {
+			return rev, nil
+		}
+	}
+	return "", fmt.Errorf("%q does not name a ref", rev)
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestResolveTreeish_ExtendedSyntax(t *testing.T) {
+	r, _ := setupMergeRepo(t)
+	base, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef(HEAD): %v", err)
+	}
+	m1 := commitFile(t, r, "dir/a.txt", []byte("a\n"), "main one")
+	m2 := commitFile(t, r, "dir/a.txt", []byte("a2\n"), "main two")
+
+	if err := r.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout(feature): %v", err)
+	}
+	if err := r.Checkout("main"); err != nil {
+		t.Fatalf("Checkout(main): %v", err)
+	}
+	if err := r.SetRemote("origin", "https://example.com/got/alice/repo"); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+	if err := r.UpdateRef("refs/remotes/origin/heads/main", m1); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+
+	headCommit, err := r.Store.ReadCommit(m2)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	blob := object.Hash("")
+	tree, err := r.Store.ReadTree(headCommit.TreeHash)
+	if err != nil {
+		t.Fatalf("ReadTree: %v", err)
+	}
+	for _, e := range tree.Entries {
+		if e.Name == "dir" {
+			sub, err := r.Store.ReadTree(e.SubtreeHash)
+			if err != nil {
+				t.Fatalf("ReadTree(dir): %v", err)
+			}
+			blob = sub.Entries[0].BlobHash
+		}
+	}
+
+	for _, tc := range []struct {
+		rev  string
+		want object.Hash
+	}{
+		{"HEAD~2", base},
+		{"@{-1}", base},
+		{"@{-2}", m2},
+		{"@{upstream}", m1},
+		{"main@{u}~1", base},
+		{"main@{0}", m2},
+		{"main@{1}", m1},
+		{"HEAD:", headCommit.TreeHash},
+		{"HEAD:dir/a.txt", blob},
+		{":dir/a.txt", blob},
+	} {
+		got, err := r.ResolveRef(tc.rev)
+		if err != nil {
+			t.Fatalf("ResolveRef(%q): %v", tc.rev, err)
+		}
+		if got != tc.want {
+			t.Fatalf("ResolveRef(%q) = %s, want %s", tc.rev, got, tc.want)
+		}
+	}
+
+	for _, rev := range []string{"@{-5}", "feature@{upstream}", "main@{9}", "HEAD:missing.txt", "HEAD:dir/a.txt/x"} {
+		if _, err := r.ResolveTreeish(rev); err == nil {
+			t.Fatalf("ResolveTreeish(%q): expected an error", rev)
+		}
+	}
+
+	for rev, want := range map[string]string{"HEAD": "main", "@{-1}": "feature", "@{u}": "origin/main", "refs/heads/feature": "feature"} {
+		got, err := r.SymbolicName(rev)
+		if err != nil || got != want {
+			t.Fatalf("SymbolicName(%q) = %q, %v; want %q", rev, got, err, want)
+		}
+	}
+}
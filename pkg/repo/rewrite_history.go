@@ -0,0 +1,468 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// RewriteHistoryOptions controls RewriteHistory.
+type RewriteHistoryOptions struct {
+	// RemovePaths drops these files, or directories with everything below
+	// them, from every commit.
+	RemovePaths []string
+
+	// MaxBlobSize drops every file whose content is larger than this many
+	// bytes. Zero keeps files of any size.
+	MaxBlobSize int64
+
+	// AuthorMap replaces author and committer identities. A key is either
+	// a full "Name <email>" identity or a bare "<email>" matching any name;
+	// a full identity wins.
+	AuthorMap map[string]string
+
+	// DryRun writes the rewritten objects but leaves every ref alone.
+	DryRun bool
+}
+
+// RewrittenRef is a ref moved by RewriteHistory.
+type RewrittenRef struct {
+	Name string
+	Old  object.Hash
+	New  object.Hash
+}
+
+// RewriteHistoryResult reports what RewriteHistory did.
+type RewriteHistoryResult struct {
+	// Commits lists every rewritten commit's old hash, parents first.
+	Commits []object.Hash
+
+	// CommitMap maps each commit in Commits to its replacement. Commits
+	// left untouched map to themselves, and a commit that no longer
+	// changes anything maps to the replacement of its parent.
+	CommitMap map[object.Hash]object.Hash
+
+	// Pruned counts the commits dropped because they became empty.
+	Pruned int
+
+	// Refs lists the refs whose target changed, ordered by name.
+	Refs []RewrittenRef
+}
+
+// Changed counts the commits whose hash changed.
+func (res *RewriteHistoryResult) Changed() int {
+	n := 0
+	for _, old := range res.Commits {
+		if res.CommitMap[old] != old {
+			n++
+		}
+	}
+	return n
+}
+
+// rewriteHistoryDir holds the maps of the last RewriteHistory run, relative
+// to the graft directory.
+const rewriteHistoryDir = "rewrite-history"
+
+// historyRewriter carries the options and memo tables of one rewrite.
+type historyRewriter struct {
+	r       *Repo
+	opts    RewriteHistoryOptions
+	remove  map[string]bool
+	trees   map[string]object.Hash
+	sizes   map[object.Hash]int64
+	commits map[object.Hash]object.Hash
+}
+
+// RewriteHistory rewrites every commit reachable from HEAD, the branches
+// and the tags, in the manner of filter-repo: it removes paths, drops
+// oversized files and maps author identities, then moves the refs to the
+// rewritten commits. Annotated tags are rewritten to point at the new
+// commits. A non-merge commit whose changes were all removed is pruned.
+//
+// Trees are rewritten once per directory and hash, and blob sizes are read
+// once per blob, so history shared between branches costs nothing extra.
+// Rewritten commits lose their signatures.
+//
+// The ref that HEAD names is moved like a mixed reset, so the staging area
+// matches the new tree and removed files stay in the working tree as
+// untracked files. Because that reset would discard staged changes, and
+// moving refs would pull history out from under a merge, rebase,
+// cherry-pick, revert or am session, a rewrite that moves refs refuses to
+// run unless the index matches HEAD and no such operation is in progress.
+// Unless opts.DryRun is set, the commit and ref maps are also written under
+// .graft/rewrite-history/.
+func (r *Repo) RewriteHistory(opts RewriteHistoryOptions) (*RewriteHistoryResult, error) {
+	if !opts.DryRun {
+		if err := r.ensureRewritable(); err != nil {
+			return nil, fmt.Errorf("rewrite history: %w", err)
+		}
+	}
+	rw := &historyRewriter{
+		r:       r,
+		opts:    opts,
+		remove:  make(map[string]bool),
+		trees:   make(map[string]object.Hash),
+		sizes:   make(map[object.Hash]int64),
+		commits: make(map[object.Hash]object.Hash),
+	}
+	for _, p := range opts.RemovePaths {
+		clean := path.Clean(filepath.ToSlash(strings.TrimSpace(p)))
+		clean = strings.TrimPrefix(clean, "/")
+		if clean == "." || clean == "" || isOutsideRepo(clean) {
+			return nil, fmt.Errorf("rewrite history: invalid path %q", p)
+		}
+		rw.remove[clean] = true
+	}
+
+	tips, err := r.refTips()
+	if err != nil {
+		return nil, fmt.Errorf("rewrite history: %w", err)
+	}
+	order, err := r.RevList(&RevRange{Include: tips}, RevListOptions{TopoOrder: true})
+	if err != nil {
+		return nil, fmt.Errorf("rewrite history: %w", err)
+	}
+
+	res := &RewriteHistoryResult{CommitMap: rw.commits}
+	for i := len(order) - 1; i >= 0; i-- {
+		old := order[i]
+		pruned, err := rw.rewriteCommit(old)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite history: commit %s: %w", old, err)
+		}
+		if pruned {
+			res.Pruned++
+		}
+		res.Commits = append(res.Commits, old)
+	}
+
+	if res.Refs, err = rw.rewriteRefs(); err != nil {
+		return nil, fmt.Errorf("rewrite history: %w", err)
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+	if err := rw.applyRefs(res.Refs); err != nil {
+		return nil, fmt.Errorf("rewrite history: %w", err)
+	}
+	if err := r.writeRewriteHistoryMaps(res); err != nil {
+		return nil, fmt.Errorf("rewrite history: %w", err)
+	}
+	return res, nil
+}
+
+// ensureRewritable checks that moving the refs cannot lose staged changes
+// or the state of an operation in progress.
+func (r *Repo) ensureRewritable() error {
+	for _, op := range []struct {
+		name     string
+		progress func() bool
+	}{
+		{"merge", r.IsMergeInProgress},
+		{"rebase", r.isRebaseInProgress},
+		{"cherry-pick", r.IsCherryPickInProgress},
+		{"revert", r.IsRevertInProgress},
+		{"am session", r.IsAMInProgress},
+	} {
+		if op.progress() {
+			return fmt.Errorf("a %s is in progress; finish or abort it first", op.name)
+		}
+	}
+	return r.ensureIndexMatchesHead()
+}
+
+// rewriteCommit rewrites one commit whose parents were already rewritten
+// and records the result. It reports whether the commit was pruned.
+func (rw *historyRewriter) rewriteCommit(old object.Hash) (bool, error) {
+	c, err := rw.r.Store.ReadCommit(old)
+	if err != nil {
+		return false, err
+	}
+	tree, err := rw.rewriteTree(c.TreeHash, "")
+	if err != nil {
+		return false, err
+	}
+
+	parents := make([]object.Hash, 0, len(c.Parents))
+	seen := make(map[object.Hash]bool, len(c.Parents))
+	for _, p := range c.Parents {
+		// Parents beyond a shallow boundary were never visited and keep
+		// their hash.
+		if np, ok := rw.commits[p]; ok {
+			p = np
+		}
+		if !seen[p] {
+			seen[p] = true
+			parents = append(parents, p)
+		}
+	}
+
+	if len(c.Parents) == 1 && tree != c.TreeHash {
+		emptied, err := rw.becameEmpty(c, parents[0], tree)
+		if err != nil {
+			return false, err
+		}
+		if emptied {
+			rw.commits[old] = parents[0]
+			return true, nil
+		}
+	}
+
+	author, committer := rw.mapIdentity(c.Author), rw.mapIdentity(c.Committer)
+	if tree == c.TreeHash && author == c.Author && committer == c.Committer && slices.Equal(parents, c.Parents) {
+		rw.commits[old] = old
+		return false, nil
+	}
+	nc := *c
+	nc.TreeHash, nc.Parents, nc.Author, nc.Committer = tree, parents, author, committer
+	nc.Signature = ""
+	h, err := rw.r.Store.WriteCommit(&nc)
+	if err != nil {
+		return false, err
+	}
+	rw.commits[old] = h
+	return false, nil
+}
+
+// becameEmpty reports whether c, which changed something relative to its
+// parent, no longer does once rewritten to tree on top of newParent.
+func (rw *historyRewriter) becameEmpty(c *object.CommitObj, newParent, tree object.Hash) (bool, error) {
+	oldParent, err := rw.r.Store.ReadCommit(c.Parents[0])
+	if err != nil {
+		// A parent beyond a shallow boundary cannot be compared.
+		return false, nil
+	}
+	if oldParent.TreeHash == c.TreeHash {
+		return false, nil
+	}
+	np, err := rw.r.Store.ReadCommit(newParent)
+	if err != nil {
+		return false, err
+	}
+	return np.TreeHash == tree, nil
+}
+
+// rewriteTree returns tree h, found at dir, with the removed paths and
+// oversized files left out. Directories left empty are dropped.
+func (rw *historyRewriter) rewriteTree(h object.Hash, dir string) (object.Hash, error) {
+	// Which paths are removed depends on where the tree sits, so a tree
+	// is only shared between directories when no paths are removed.
+	key := string(h)
+	if len(rw.remove) > 0 {
+		key = dir + "\x00" + key
+	}
+	if nh, ok := rw.trees[key]; ok {
+		return nh, nil
+	}
+
+	tree, err := rw.r.Store.ReadTree(h)
+	if err != nil {
+		return "", fmt.Errorf("read tree %s: %w", h, err)
+	}
+	entries := make([]object.TreeEntry, 0, len(tree.Entries))
+	changed := false
+	for _, e := range tree.Entries {
+		p := e.Name
+		if dir != "" {
+			p = dir + "/" + e.Name
+		}
+		keep, err := rw.keepEntry(e, p)
+		if err != nil {
+			return "", err
+		}
+		if !keep {
+			changed = true
+			continue
+		}
+		if e.IsDir {
+			sub, err := rw.rewriteTree(e.SubtreeHash, p)
+			if err != nil {
+				return "", err
+			}
+			if sub == "" {
+				changed = true
+				continue
+			}
+			if sub != e.SubtreeHash {
+				e.SubtreeHash = sub
+				changed = true
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	nh := h
+	switch {
+	case len(entries) == 0 && dir != "":
+		nh = ""
+	case changed:
+		if nh, err = rw.r.Store.WriteTree(&object.TreeObj{Entries: entries}); err != nil {
+			return "", fmt.Errorf("write tree: %w", err)
+		}
+	}
+	rw.trees[key] = nh
+	return nh, nil
+}
+
+// keepEntry reports whether the tree entry at p survives the rewrite.
+func (rw *historyRewriter) keepEntry(e object.TreeEntry, p string) (bool, error) {
+	if rw.remove[p] {
+		return false, nil
+	}
+	if e.IsDir || rw.opts.MaxBlobSize <= 0 || e.Mode == object.TreeModeModule {
+		return true, nil
+	}
+	size, ok := rw.sizes[e.BlobHash]
+	if !ok {
+		blob, err := rw.r.Store.ReadBlob(e.BlobHash)
+		if err != nil {
+			return false, fmt.Errorf("read blob %s for %q: %w", e.BlobHash, p, err)
+		}
+		size = int64(len(blob.Data))
+		rw.sizes[e.BlobHash] = size
+	}
+	return size <= rw.opts.MaxBlobSize, nil
+}
+
+// mapIdentity applies the author map to an identity.
+func (rw *historyRewriter) mapIdentity(ident string) string {
+	if len(rw.opts.AuthorMap) == 0 || ident == "" {
+		return ident
+	}
+	if mapped, ok := rw.opts.AuthorMap[ident]; ok {
+		return mapped
+	}
+	if i := strings.LastIndex(ident, "<"); i >= 0 {
+		if mapped, ok := rw.opts.AuthorMap[strings.TrimSpace(ident[i:])]; ok {
+			return mapped
+		}
+	}
+	return ident
+}
+
+// rewriteRefs works out the new target of HEAD, when detached, and of
+// every branch and tag, writing rewritten annotated tag objects.
+func (rw *historyRewriter) rewriteRefs() ([]RewrittenRef, error) {
+	var refs []RewrittenRef
+	for _, prefix := range []string{"heads", "tags"} {
+		listed, err := rw.r.ListRefs(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", prefix, err)
+		}
+		for name, old := range listed {
+			nh, err := rw.rewriteRefTarget(old)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if nh != old {
+				refs = append(refs, RewrittenRef{Name: "refs/" + name, Old: old, New: nh})
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+
+	head, err := rw.r.Head()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(head, "refs/") && head != "" {
+		old := object.Hash(head)
+		if nh := rw.commits[old]; nh != "" && nh != old {
+			refs = append([]RewrittenRef{{Name: "HEAD", Old: old, New: nh}}, refs...)
+		}
+	}
+	return refs, nil
+}
+
+// rewriteRefTarget returns what a ref pointing at h should point at after
+// the rewrite. An annotated tag of a rewritten commit is copied with its
+// object header pointing at the new commit.
+func (rw *historyRewriter) rewriteRefTarget(h object.Hash) (object.Hash, error) {
+	if nh, ok := rw.commits[h]; ok {
+		return nh, nil
+	}
+	objType, _, err := rw.r.Store.Read(h)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", h, err)
+	}
+	if objType != object.TypeTag {
+		return h, nil
+	}
+	tag, err := rw.r.Store.ReadTag(h)
+	if err != nil {
+		return "", fmt.Errorf("read tag %s: %w", h, err)
+	}
+	target, err := rw.rewriteRefTarget(tag.TargetHash)
+	if err != nil {
+		return "", err
+	}
+	if target == tag.TargetHash {
+		return h, nil
+	}
+	data := tag.Data
+	if header := []byte("object " + string(tag.TargetHash) + "\n"); bytes.HasPrefix(data, header) {
+		data = append([]byte("object "+string(target)+"\n"), data[len(header):]...)
+	}
+	nh, err := rw.r.Store.WriteTag(&object.TagObj{TargetHash: target, Data: data})
+	if err != nil {
+		return "", fmt.Errorf("write tag: %w", err)
+	}
+	return nh, nil
+}
+
+// applyRefs moves the rewritten refs. The ref HEAD names, or a detached
+// HEAD, moves last through a mixed reset.
+func (rw *historyRewriter) applyRefs(refs []RewrittenRef) error {
+	head, err := rw.r.Head()
+	if err != nil {
+		return err
+	}
+	var headRef *RewrittenRef
+	for i := range refs {
+		ref := &refs[i]
+		if ref.Name == head || ref.Name == "HEAD" {
+			headRef = ref
+			continue
+		}
+		if err := rw.r.UpdateRefCAS(ref.Name, ref.New, ref.Old); err != nil {
+			return err
+		}
+	}
+	if headRef != nil {
+		if err := rw.r.ResetToCommit(headRef.New, ResetMixed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRewriteHistoryMaps records the commit map and the ref updates of a
+// rewrite as "old new" lines, replacing those of an earlier run.
+func (r *Repo) writeRewriteHistoryMaps(res *RewriteHistoryResult) error {
+	dir := filepath.Join(r.GraftDir, rewriteHistoryDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	var commits bytes.Buffer
+	commits.WriteString("old new\n")
+	for _, old := range res.Commits {
+		fmt.Fprintf(&commits, "%s %s\n", old, res.CommitMap[old])
+	}
+	if err := os.WriteFile(filepath.Join(dir, "commit-map"), commits.Bytes(), 0o644); err != nil {
+		return err
+	}
+	var refs bytes.Buffer
+	refs.WriteString("old new ref\n")
+	for _, ref := range res.Refs {
+		fmt.Fprintf(&refs, "%s %s %s\n", ref.Old, ref.New, ref.Name)
+	}
+	return os.WriteFile(filepath.Join(dir, "ref-map"), refs.Bytes(), 0o644)
+}
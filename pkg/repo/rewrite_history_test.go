@@ -0,0 +1,175 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+func TestRewriteHistory_RemovesPathsAndMapsAuthors(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	first := commitAs(t, r, map[string]string{
+		"main.go":          "package main\n",
+		"secrets/key.txt":  "hunter2\n",
+		"assets/big.bin":   strings.Repeat("x", 4096),
+		"assets/small.txt": "small\n",
+	}, "Old Name <old@example.com>", "initial")
+	commitAs(t, r, map[string]string{"secrets/key.txt": "hunter3\n"}, "alice <alice@example.com>", "rotate key")
+	third := commitAs(t, r, map[string]string{"main.go": "package main\n\nfunc main() {}\n"}, "alice <alice@example.com>", "add main")
+	if _, err := r.CreateAnnotatedTag("v1", third, "alice", "release", false); err != nil {
+		t.Fatalf("CreateAnnotatedTag: %v", err)
+	}
+
+	dry, err := r.RewriteHistory(RewriteHistoryOptions{RemovePaths: []string{"secrets"}, DryRun: true})
+	if err != nil {
+		t.Fatalf("RewriteHistory(dry run): %v", err)
+	}
+	if head, _ := r.ResolveRef("HEAD"); head != third || len(dry.Refs) == 0 {
+		t.Fatalf("dry run moved HEAD to %s, refs %+v", head, dry.Refs)
+	}
+
+	res, err := r.RewriteHistory(RewriteHistoryOptions{
+		RemovePaths: []string{"secrets"},
+		MaxBlobSize: 1024,
+		AuthorMap:   map[string]string{"<old@example.com>": "New Name <new@example.com>"},
+	})
+	if err != nil {
+		t.Fatalf("RewriteHistory: %v", err)
+	}
+	if len(res.Commits) != 3 || res.Pruned != 1 || res.Changed() != 3 {
+		t.Fatalf("result = %d commits, %d pruned, %d changed", len(res.Commits), res.Pruned, res.Changed())
+	}
+
+	head, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef(HEAD): %v", err)
+	}
+	if head != res.CommitMap[third] || head == third {
+		t.Fatalf("HEAD = %s, want rewritten %s", head, res.CommitMap[third])
+	}
+	entries, err := r.FlattenTree(mustCommitTree(t, r, head))
+	if err != nil {
+		t.Fatalf("FlattenTree: %v", err)
+	}
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+	if got := strings.Join(paths, ","); got != "assets/small.txt,main.go" {
+		t.Fatalf("rewritten tree = %s", got)
+	}
+
+	// The key rotation only touched removed paths, so it was pruned and
+	// the next commit sits directly on the rewritten root.
+	c, err := r.Store.ReadCommit(head)
+	if err != nil {
+		t.Fatalf("ReadCommit: %v", err)
+	}
+	root := res.CommitMap[first]
+	if len(c.Parents) != 1 || c.Parents[0] != root {
+		t.Fatalf("rewritten parents = %v, want [%s]", c.Parents, root)
+	}
+	rc, err := r.Store.ReadCommit(root)
+	if err != nil {
+		t.Fatalf("ReadCommit(root): %v", err)
+	}
+	if rc.Author != "New Name <new@example.com>" {
+		t.Fatalf("root author = %q", rc.Author)
+	}
+
+	tagRef, err := r.ResolveRef("refs/tags/v1")
+	if err != nil {
+		t.Fatalf("ResolveRef(v1): %v", err)
+	}
+	tag, err := r.Store.ReadTag(tagRef)
+	if err != nil {
+		t.Fatalf("ReadTag: %v", err)
+	}
+	if tag.TargetHash != head || !strings.HasPrefix(string(tag.Data), "object "+string(head)+"\n") {
+		t.Fatalf("tag = %s / %q, want target %s", tag.TargetHash, tag.Data, head)
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.GraftDir, "rewrite-history", "commit-map"))
+	if err != nil {
+		t.Fatalf("read commit-map: %v", err)
+	}
+	if !strings.Contains(string(data), string(third)+" "+string(head)+"\n") {
+		t.Fatalf("commit-map = %q", data)
+	}
+}
+
+func TestRewriteHistory_UnchangedHistoryKeepsHashes(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	head := commitAs(t, r, map[string]string{"a.txt": "a\n"}, "alice <alice@example.com>", "first")
+
+	res, err := r.RewriteHistory(RewriteHistoryOptions{RemovePaths: []string{"missing.txt"}})
+	if err != nil {
+		t.Fatalf("RewriteHistory: %v", err)
+	}
+	if res.Changed() != 0 || len(res.Refs) != 0 || res.CommitMap[head] != head {
+		t.Fatalf("result = %+v, want no changes", res)
+	}
+	if _, err := r.RewriteHistory(RewriteHistoryOptions{RemovePaths: []string{"../x"}}); err == nil {
+		t.Fatal("expected an error for a path outside the repository")
+	}
+}
+
+func TestRewriteHistory_RefusesStagedChangesAndOperationsInProgress(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	head := commitAs(t, r, map[string]string{"a.txt": "a\n", "secret.txt": "s\n"}, "alice <alice@example.com>", "first")
+	opts := RewriteHistoryOptions{RemovePaths: []string{"secret.txt"}}
+
+	writeFile(t, filepath.Join(r.RootDir, "a.txt"), []byte("staged\n"))
+	if err := r.Add([]string{filepath.Join(r.RootDir, "a.txt")}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.RewriteHistory(opts); err == nil || !strings.Contains(err.Error(), "staged changes") {
+		t.Fatalf("RewriteHistory with staged changes: err = %v", err)
+	}
+	if _, err := r.RewriteHistory(RewriteHistoryOptions{RemovePaths: opts.RemovePaths, DryRun: true}); err != nil {
+		t.Fatalf("RewriteHistory(dry run) with staged changes: %v", err)
+	}
+	writeFile(t, filepath.Join(r.RootDir, "a.txt"), []byte("a\n"))
+	if err := r.Add([]string{filepath.Join(r.RootDir, "a.txt")}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	mergeHead := filepath.Join(r.GraftDir, "MERGE_HEAD")
+	if err := os.WriteFile(mergeHead, []byte(string(head)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RewriteHistory(opts); err == nil || !strings.Contains(err.Error(), "merge is in progress") {
+		t.Fatalf("RewriteHistory during a merge: err = %v", err)
+	}
+	if got, _ := r.ResolveRef("HEAD"); got != head {
+		t.Fatalf("refused rewrite moved HEAD to %s", got)
+	}
+	if err := os.Remove(mergeHead); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RewriteHistory(opts); err != nil {
+		t.Fatalf("RewriteHistory: %v", err)
+	}
+}
+
+func mustCommitTree(t *testing.T, r *Repo, h object.Hash) object.Hash {
+	t.Helper()
+	c, err := r.Store.ReadCommit(h)
+	if err != nil {
+		t.Fatalf("ReadCommit(%s): %v", h, err)
+	}
+	return c.TreeHash
+}
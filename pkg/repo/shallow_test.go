@@ -325,3 +325,30 @@ func TestFindMergeBaseWithShallowBoundary(t *testing.T) {
 		t.Fatalf("FindMergeBase with shallow boundary: %v", err)
 	}
 }
+
+func TestWriteShallowState_RemovesFileWhenEmpty(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := remote.NewShallowState()
+	state.Add(object.Hash("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	if err := r.WriteShallowState(state); err != nil {
+		t.Fatalf("WriteShallowState: %v", err)
+	}
+	if !r.IsShallowRepository() {
+		t.Fatal("expected repository to be shallow after writing boundaries")
+	}
+
+	if err := r.WriteShallowState(remote.NewShallowState()); err != nil {
+		t.Fatalf("WriteShallowState(empty): %v", err)
+	}
+	if r.IsShallowRepository() {
+		t.Fatal("expected repository to be complete after clearing boundaries")
+	}
+	if _, err := os.Stat(filepath.Join(r.GraftDir, "shallow")); !os.IsNotExist(err) {
+		t.Fatalf("expected shallow file to be removed, stat err=%v", err)
+	}
+}
@@ -21,10 +21,10 @@ type ShortlogOptions struct {
 	Limit    int  // max commits to walk (0 = all)
 }
 
-// Shortlog walks HEAD history (first-parent) and groups commits by author.
-// By default entries are sorted by author name; with Numbered they are sorted
-// by count descending. In a shallow repository, walking stops at shallow
-// boundaries.
+// Shortlog walks HEAD history (first-parent) and groups commits by author,
+// as the .mailmap file maps them. By default entries are sorted by author
+// name; with Numbered they are sorted by count descending. In a shallow
+// repository, walking stops at shallow boundaries.
 func (r *Repo) Shortlog(opts ShortlogOptions) ([]ShortlogEntry, error) {
 	headHash, err := r.ResolveRef("HEAD")
 	if err != nil {
@@ -32,6 +32,10 @@ func (r *Repo) Shortlog(opts ShortlogOptions) ([]ShortlogEntry, error) {
 	}
 
 	shallow, _ := r.ShallowState()
+	mailmap, err := r.Mailmap()
+	if err != nil {
+		return nil, fmt.Errorf("shortlog: %w", err)
+	}
 
 	type authorData struct {
 		titles []string
@@ -53,7 +57,7 @@ func (r *Repo) Shortlog(opts ShortlogOptions) ([]ShortlogEntry, error) {
 			return nil, fmt.Errorf("shortlog: read commit %s: %w", current, err)
 		}
 
-		author := c.Author
+		author := mailmap.Map(c.Author)
 		title := commitTitle(c.Message)
 
 		ad, ok := byAuthor[author]
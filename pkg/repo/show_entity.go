@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// RevisionEntity is a declaration as it existed in a commit.
+type RevisionEntity struct {
+	Commit   object.Hash
+	Path     string
+	Name     string
+	DeclKind string
+	Receiver string
+	Body     []byte
+}
+
+// EntityAtRevision returns one declaration of the file at path as of the
+// commit rev names (HEAD when empty), without touching the working tree.
+// The path is relative to the repository root. The selector is an identity
+// key or a declaration name, optionally qualified by its receiver
+// (Repo.Commit), which must identify exactly one declaration.
+//
+// The entity is read from the objects recorded for the file's entity list.
+// Only when those cannot tell declarations apart, or the file was
+// committed without one, is the file parsed again.
+func (r *Repo) EntityAtRevision(rev, path, selector string) (*RevisionEntity, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	h, err := r.ResolveTreeish(rev)
+	if err != nil {
+		return nil, err
+	}
+	commitHash, err := r.peelToCommit(h)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rev, err)
+	}
+	commit, err := r.Store.ReadCommit(commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("read commit %s: %w", commitHash, err)
+	}
+	relPath := filepath.ToSlash(filepath.Clean(strings.TrimPrefix(path, "/")))
+	if relPath == "." || isOutsideRepo(relPath) {
+		return nil, fmt.Errorf("%w: path %q is outside repository", ErrInvalidEntitySelector, path)
+	}
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return nil, fmt.Errorf("%w: entity name or key is required", ErrInvalidEntitySelector)
+	}
+	entry, found, err := r.treeEntryAtPath(commit.TreeHash, relPath)
+	if err != nil {
+		return nil, fmt.Errorf("read %q at %s: %w", relPath, shortHash(commitHash), err)
+	}
+	if !found {
+		return nil, fmt.Errorf("path %q does not exist in %s", relPath, shortHash(commitHash))
+	}
+
+	result := &RevisionEntity{Commit: commitHash, Path: relPath}
+	if entry.EntityListHash != "" {
+		ent, ok, err := r.storedRevisionEntity(entry.EntityListHash, selector)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result.Name, result.DeclKind, result.Receiver, result.Body = ent.Name, ent.DeclKind, ent.Receiver, ent.Body
+			return result, nil
+		}
+	}
+
+	data, err := r.readBlobData(entry.BlobHash)
+	if err != nil {
+		return nil, fmt.Errorf("read blob %s: %w", entry.BlobHash, err)
+	}
+	el, err := entity.Extract(relPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("extract entities from %q at %s: %w", relPath, shortHash(commitHash), err)
+	}
+	key, name := selector, ""
+	if !strings.HasPrefix(selector, "decl:") {
+		key, name = "", selector
+	}
+	ent, err := findRestoreEntity(el, key, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s#%s at %s: %w", relPath, selector, shortHash(commitHash), err)
+	}
+	result.Name, result.DeclKind, result.Receiver, result.Body = ent.Name, ent.DeclKind, ent.Receiver, ent.Body
+	return result, nil
+}
+
+// storedRevisionEntity looks the selector up among the entity objects of
+// an entity list. It reports false when the stored objects, which lack the
+// signature and ordinal of an identity key, match no declaration or more
+// than one.
+func (r *Repo) storedRevisionEntity(listHash object.Hash, selector string) (*object.EntityObj, bool, error) {
+	el, err := r.Store.ReadEntityList(listHash)
+	if err != nil {
+		return nil, false, fmt.Errorf("read entity list %s: %w", listHash, err)
+	}
+
+	matches := func(e *entity.Entity) bool { return declNameMatches(e, selector) }
+	if rest, ok := strings.CutPrefix(selector, "decl:"); ok {
+		parts := strings.SplitN(rest, ":", 4)
+		if len(parts) < 4 {
+			return nil, false, nil
+		}
+		matches = func(e *entity.Entity) bool {
+			return e.DeclKind == parts[0] && e.Receiver == parts[1] && e.Name == parts[2]
+		}
+	}
+
+	var match *object.EntityObj
+	for _, ref := range el.EntityRefs {
+		obj, err := r.Store.ReadEntity(ref)
+		if err != nil {
+			return nil, false, fmt.Errorf("read entity %s: %w", ref, err)
+		}
+		if obj.Kind != entity.KindDeclaration.String() {
+			continue
+		}
+		candidate := &entity.Entity{Kind: entity.KindDeclaration, Name: obj.Name, DeclKind: obj.DeclKind, Receiver: obj.Receiver}
+		if !matches(candidate) {
+			continue
+		}
+		if match != nil {
+			return nil, false, nil
+		}
+		match = obj
+	}
+	return match, match != nil, nil
+}
@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEntityAtRevision(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	first := commitAs(t, r, map[string]string{
+		"main.go": "package main\n\nfunc Load() int {\n\treturn 1\n}\n\nfunc Save() {\n}\n",
+	}, "alice", "add load")
+	commitAs(t, r, map[string]string{
+		"main.go": "package main\n\nfunc Load() int {\n\treturn 2\n}\n\nfunc Save() {\n}\n",
+	}, "alice", "change load")
+
+	old, err := r.EntityAtRevision("HEAD~1", "main.go", "Load")
+	if err != nil {
+		t.Fatalf("EntityAtRevision(HEAD~1): %v", err)
+	}
+	if old.Commit != first || old.Name != "Load" || !strings.Contains(string(old.Body), "return 1") {
+		t.Fatalf("HEAD~1 entity = %+v body %q", old, old.Body)
+	}
+
+	cur, err := r.EntityAtRevision("", "main.go", "Load")
+	if err != nil {
+		t.Fatalf("EntityAtRevision(HEAD): %v", err)
+	}
+	if !strings.Contains(string(cur.Body), "return 2") {
+		t.Fatalf("HEAD body = %q", cur.Body)
+	}
+
+	key := "decl:" + cur.DeclKind + ":" + cur.Receiver + ":Load:"
+	byKey, err := r.EntityAtRevision(string(first), "main.go", key)
+	if err != nil {
+		t.Fatalf("EntityAtRevision(key %q): %v", key, err)
+	}
+	if !strings.Contains(string(byKey.Body), "return 1") {
+		t.Fatalf("body by key = %q", byKey.Body)
+	}
+
+	if _, err := r.EntityAtRevision("HEAD", "main.go", "Missing"); err == nil {
+		t.Fatal("EntityAtRevision(Missing) succeeded, want error")
+	}
+	if _, err := r.EntityAtRevision("HEAD", "other.go", "Load"); err == nil {
+		t.Fatal("EntityAtRevision(other.go) succeeded, want error")
+	}
+	if _, err := r.EntityAtRevision("HEAD", "../main.go", "Load"); !errors.Is(err, ErrInvalidEntitySelector) {
+		t.Fatalf("EntityAtRevision(../main.go) error = %v, want ErrInvalidEntitySelector", err)
+	}
+}
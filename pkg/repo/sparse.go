@@ -132,13 +132,14 @@ func (r *Repo) applySparseCheckout() error {
 		return fmt.Errorf("sparse-checkout apply: read commit: %w", err)
 	}
 
-	targetFiles, err := r.FlattenTree(commit.TreeHash)
+	targetFiles, err := r.flattenCheckoutTree(commit.TreeHash)
 	if err != nil {
 		return fmt.Errorf("sparse-checkout apply: flatten tree: %w", err)
 	}
 
 	sparseEnabled := r.IsSparseEnabled()
 
+	le := r.lineEndingsForTree(targetFiles)
 	for _, f := range targetFiles {
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
 
@@ -153,7 +154,7 @@ func (r *Repo) applySparseCheckout() error {
 
 		// File should be materialized — write it if missing or stale.
 		dir := filepath.Dir(absPath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := mkdirWorktree(r.RootDir, dir); err != nil {
 			return fmt.Errorf("sparse-checkout apply: mkdir %q: %w", dir, err)
 		}
 
@@ -162,7 +163,7 @@ func (r *Repo) applySparseCheckout() error {
 			return fmt.Errorf("sparse-checkout apply: read blob for %q: %w", f.Path, err)
 		}
 
-		if err := os.WriteFile(absPath, blob.Data, filePermFromMode(f.Mode)); err != nil {
+		if err := writeWorktreeFile(absPath, le.smudge(f.Path, blob.Data, f.Mode), f.Mode); err != nil {
 			return fmt.Errorf("sparse-checkout apply: write %q: %w", f.Path, err)
 		}
 	}
@@ -171,7 +172,7 @@ func (r *Repo) applySparseCheckout() error {
 	stg := &Staging{Entries: make(map[string]*StagingEntry, len(targetFiles))}
 	for _, f := range targetFiles {
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
-		info, err := os.Stat(absPath)
+		info, err := os.Lstat(absPath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				// File is excluded by sparse — skip staging entry.
@@ -8,15 +8,17 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/lockfile"
 	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/pathspec"
 
 	"github.com/odvcencio/gotreesitter/grammars"
 )
@@ -43,6 +45,53 @@ type StagingEntry struct {
 // Staging holds the full staging area (index) for a Graft repository.
 type Staging struct {
 	Entries map[string]*StagingEntry `json:"entries"`
+
+	// ResolveUndo keeps the merge stages of conflicts that were resolved,
+	// keyed by path, so RecreateConflicts can bring them back.
+	ResolveUndo map[string]*ResolveUndoEntry `json:"resolve_undo,omitempty"`
+
+	// base identifies the index file this staging area was read from, so
+	// that writing it back can detect a writer that got there first. It is
+	// nil for a staging area built from scratch.
+	base *indexStamp
+}
+
+// ErrIndexChanged is returned when the index was rewritten by another
+// process between reading and writing it. Writing anyway would drop that
+// process's changes.
+var ErrIndexChanged = errors.New("index was modified by another process; retry the command")
+
+// indexStamp identifies one version of the index file. Every write renames
+// a new file into place, so a new version has a new identity even when its
+// size and timestamp match.
+type indexStamp struct {
+	exists    bool
+	size      int64
+	modTime   int64
+	hasFileID bool
+	device    uint64
+	inode     uint64
+}
+
+func statIndex(path string) (*indexStamp, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &indexStamp{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	stamp := &indexStamp{
+		exists:  true,
+		size:    info.Size(),
+		modTime: info.ModTime().UnixNano(),
+	}
+	if dev, ino, ok := statusDeviceAndInode(info); ok {
+		stamp.hasFileID = true
+		stamp.device = dev
+		stamp.inode = ino
+	}
+	return stamp, nil
 }
 
 const (
@@ -65,26 +114,52 @@ type AddProgress struct {
 type AddProgressFunc func(AddProgress)
 
 type preparedAddEntry struct {
-	entry   *StagingEntry
-	content []byte // retained for Phase 2 entity extraction
-	err     error
+	entry    *StagingEntry
+	content  []byte // retained for Phase 2 entity extraction
+	streamed bool   // blob was streamed from disk; Phase 2 skips it
+	err      error
 }
 
+// indexLockWaitLimit bounds how long an index write waits for a concurrent
+// writer in another process to release index.lock.
+const indexLockWaitLimit = 2 * time.Second
+
 // indexPath returns the filesystem path to the staging index file.
 func (r *Repo) indexPath() string {
 	return filepath.Join(r.GraftDir, "index")
 }
 
 // ReadStaging loads the staging area from .graft/index. If the file does not
-// exist, an empty Staging is returned (no error).
+// exist, an empty Staging is returned (no error). Both the binary index
+// format and the JSON format written by earlier versions are accepted.
+//
+// The returned Staging remembers which version of the index it came from;
+// WriteStaging fails with ErrIndexChanged if another process has written
+// the index since.
 func (r *Repo) ReadStaging() (*Staging, error) {
-	data, err := os.ReadFile(r.indexPath())
+	// Stat before reading: if the file is replaced in between, the stamp
+	// is the older one and a later write fails rather than losing data.
+	base, err := statIndex(r.indexPath())
+	if err != nil {
+		return nil, fmt.Errorf("read staging: %w", err)
+	}
+	data, release, err := mapIndexFile(r.indexPath())
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return &Staging{Entries: make(map[string]*StagingEntry)}, nil
+			return &Staging{Entries: make(map[string]*StagingEntry), base: base}, nil
 		}
 		return nil, fmt.Errorf("read staging: %w", err)
 	}
+	defer release()
+
+	if isBinaryStaging(data) {
+		stg, err := decodeBinaryStaging(data)
+		if err != nil {
+			return nil, fmt.Errorf("read staging: %w", err)
+		}
+		stg.base = base
+		return stg, nil
+	}
 
 	var stg Staging
 	if err := json.Unmarshal(data, &stg); err != nil {
@@ -93,45 +168,50 @@ func (r *Repo) ReadStaging() (*Staging, error) {
 	if stg.Entries == nil {
 		stg.Entries = make(map[string]*StagingEntry)
 	}
+	stg.base = base
 	return &stg, nil
 }
 
-// WriteStaging atomically writes the staging area to .graft/index.
+// WriteStaging atomically writes the staging area to .graft/index. A
+// staging area read with ReadStaging is only written if the index has not
+// changed since; otherwise ErrIndexChanged is returned.
 func (r *Repo) WriteStaging(s *Staging) error {
-	return r.writeStaging(s, true)
+	return r.writeStaging(s, true, indexLockWaitLimit)
 }
 
-func (r *Repo) writeStaging(s *Staging, invalidateStatusCache bool) error {
-	data, err := json.MarshalIndent(s, "", "  ")
+func (r *Repo) writeStaging(s *Staging, invalidateStatusCache bool, lockWait time.Duration) error {
+	data, err := encodeBinaryStaging(s)
 	if err != nil {
-		return fmt.Errorf("write staging: marshal: %w", err)
+		return fmt.Errorf("write staging: %w", err)
 	}
 
-	// Atomic write via temp file + rename.
-	tmp, err := os.CreateTemp(r.GraftDir, ".index-tmp-*")
+	// Serialize writers across processes via index.lock, then publish the
+	// new index atomically by renaming the lock file into place.
+	lock, err := lockfile.Acquire(r.indexPath(), lockWait)
 	if err != nil {
-		return fmt.Errorf("write staging: tmpfile: %w", err)
+		return fmt.Errorf("write staging: lock: %w", err)
 	}
-	tmpName := tmp.Name()
+	defer lock.Release()
 
-	if _, err := tmp.Write(data); err != nil {
-		tmp.Close()
-		os.Remove(tmpName)
-		return fmt.Errorf("write staging: write: %w", err)
+	if s.base != nil {
+		current, err := statIndex(r.indexPath())
+		if err != nil {
+			return fmt.Errorf("write staging: %w", err)
+		}
+		if *current != *s.base {
+			return fmt.Errorf("write staging: %w", ErrIndexChanged)
+		}
 	}
-	if err := tmp.Sync(); err != nil {
-		tmp.Close()
-		os.Remove(tmpName)
-		return fmt.Errorf("write staging: sync: %w", err)
+
+	if _, err := lock.Write(data); err != nil {
+		return fmt.Errorf("write staging: write: %w", err)
 	}
-	if err := tmp.Close(); err != nil {
-		os.Remove(tmpName)
-		return fmt.Errorf("write staging: close: %w", err)
+	if err := lock.Commit(); err != nil {
+		return fmt.Errorf("write staging: commit: %w", err)
 	}
-
-	if err := os.Rename(tmpName, r.indexPath()); err != nil {
-		os.Remove(tmpName)
-		return fmt.Errorf("write staging: rename: %w", err)
+	// The index now holds s; further writes of s build on this version.
+	if s.base, err = statIndex(r.indexPath()); err != nil {
+		s.base = nil
 	}
 
 	if invalidateStatusCache {
@@ -150,6 +230,11 @@ type AddOptions struct {
 	// ForceEntities bypasses the data format size denylist and always
 	// extracts entities, even for large JSON/YAML/TOML files.
 	ForceEntities bool
+
+	// Update stages only files that are already tracked: modified files are
+	// restaged and deleted files are removed from the index. Untracked
+	// files are never added. With no paths, every tracked file is considered.
+	Update bool
 }
 
 // Add stages the given file paths. Each path is resolved relative to the
@@ -160,24 +245,31 @@ type AddOptions struct {
 //  3. A StagingEntry is created/updated with the resulting hashes and file
 //     metadata, and the staging area is flushed to disk.
 func (r *Repo) Add(paths []string) error {
-	return r.add(paths, nil, AddOptions{})
+	return r.add(context.Background(), paths, nil, AddOptions{})
 }
 
 // AddWithProgress stages files while emitting coarse-grained progress events.
 func (r *Repo) AddWithProgress(paths []string, progress AddProgressFunc) error {
-	return r.add(paths, progress, AddOptions{})
+	return r.add(context.Background(), paths, progress, AddOptions{})
 }
 
 // AddWithOptions stages files with the given options and progress callback.
 func (r *Repo) AddWithOptions(paths []string, progress AddProgressFunc, opts AddOptions) error {
-	return r.add(paths, progress, opts)
+	return r.add(context.Background(), paths, progress, opts)
+}
+
+// AddContext is like AddWithOptions but stops hashing and extraction when
+// ctx is done, returning its error without updating the staging area.
+func (r *Repo) AddContext(ctx context.Context, paths []string, progress AddProgressFunc, opts AddOptions) error {
+	return r.add(ctx, paths, progress, opts)
 }
 
 // blobResult holds the output of Phase 1 (blob staging) for a single file.
 type blobResult struct {
-	relPath string
-	entry   *StagingEntry
-	content []byte // retained for Phase 2 entity extraction
+	relPath      string
+	entry        *StagingEntry
+	content      []byte // retained for Phase 2 entity extraction
+	skipEntities bool   // too large for extraction; never re-read
 }
 
 // sourceBytesSemaphore limits aggregate in-flight source bytes during entity
@@ -264,6 +356,11 @@ func maxAddFileSize() int64 {
 // by tree-sitter to avoid runaway AST allocation.
 const maxEntityExtractionSize int64 = 10 * 1024 * 1024 // 10 MB
 
+// streamBlobThreshold is the file size above which Add streams a file into
+// the object store instead of reading it into memory. Such files are past
+// maxEntityExtractionSize, so nothing needs their content in memory.
+const streamBlobThreshold = maxEntityExtractionSize
+
 // isBinaryContent reports whether data appears to be binary by checking
 // for null bytes in the first 8 KB.
 func isBinaryContent(data []byte) bool {
@@ -279,19 +376,31 @@ func isBinaryContent(data []byte) bool {
 	return false
 }
 
-func (r *Repo) add(paths []string, progress AddProgressFunc, opts AddOptions) error {
+func (r *Repo) add(parent context.Context, paths []string, progress AddProgressFunc, opts AddOptions) (err error) {
+	defer r.observe("add", strings.Join(paths, " "))(&err)
 	stg, err := r.ReadStaging()
 	if err != nil {
 		return fmt.Errorf("add: %w", err)
 	}
 
 	emitAddProgress(progress, AddProgress{Phase: AddProgressPhaseScanStart})
-	toAdd, err := r.expandAddPaths(paths)
-	if err != nil {
-		return fmt.Errorf("add: %w", err)
-	}
-	if len(toAdd) == 0 {
-		return fmt.Errorf("add: no files matched")
+	var toAdd, toDelete []string
+	if opts.Update {
+		toAdd, toDelete, err = r.expandUpdatePaths(paths, stg)
+		if err != nil {
+			return fmt.Errorf("add: %w", err)
+		}
+	} else {
+		toAdd, err = r.expandAddPaths(paths)
+		if err != nil {
+			return fmt.Errorf("add: %w", err)
+		}
+		if len(toAdd) == 0 {
+			return fmt.Errorf("add: no files matched")
+		}
+		if r.ignoreCase() {
+			toAdd = r.resolveAddCase(toAdd, stg)
+		}
 	}
 	emitAddProgress(progress, AddProgress{
 		Phase: AddProgressPhaseScanComplete,
@@ -299,9 +408,23 @@ func (r *Repo) add(paths []string, progress AddProgressFunc, opts AddOptions) er
 	})
 
 	// ── Phase 1: Blob staging (parallel, GOMAXPROCS workers) ──────────
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
+	// Without a trustworthy executable bit, files keep their index mode.
+	// Collect it up front: workers must not read stg while it is updated.
+	var indexModes map[string]string
+	if !r.trustFileMode() {
+		indexModes = make(map[string]string, len(toAdd))
+		for _, p := range toAdd {
+			if se := stg.Entries[p]; se != nil {
+				indexModes[p] = se.Mode
+			}
+		}
+	}
+
+	le := r.lineEndings()
+
 	workersCount := addWorkerCount(len(toAdd))
 	jobs := orderedIndexJobs(ctx, toAdd)
 	preparedResults := make(chan indexedResult[preparedAddEntry], workersCount)
@@ -311,11 +434,11 @@ func (r *Repo) add(paths []string, progress AddProgressFunc, opts AddOptions) er
 		go func() {
 			defer workers.Done()
 			for job := range jobs {
-				entry, content, err := r.prepareBlobEntry(job.value, opts)
+				entry, content, streamed, err := r.prepareBlobEntry(job.value, indexModes, le, opts)
 				select {
 				case preparedResults <- indexedResult[preparedAddEntry]{
 					index: job.index,
-					value: preparedAddEntry{entry: entry, content: content, err: err},
+					value: preparedAddEntry{entry: entry, content: content, streamed: streamed, err: err},
 				}:
 				case <-ctx.Done():
 					return
@@ -346,12 +469,15 @@ func (r *Repo) add(paths []string, progress AddProgressFunc, opts AddOptions) er
 			<-blobDone
 			return fmt.Errorf("add: %w", err)
 		}
+		r.reportProgress(ProgressStaging, i+1, len(toAdd))
 		blobs[i] = blobResult{
-			relPath: relPath,
-			entry:   prepared.entry,
+			relPath:      relPath,
+			entry:        prepared.entry,
+			skipEntities: prepared.streamed,
 			// Content not retained; Phase 2 re-reads from blob store
 			// to avoid accumulating all file contents in memory.
 		}
+		stg.recordResolveUndo(relPath)
 		stg.Entries[relPath] = prepared.entry
 	}
 	<-blobDone
@@ -369,6 +495,9 @@ func (r *Repo) add(paths []string, progress AddProgressFunc, opts AddOptions) er
 		var entityWg sync.WaitGroup
 		var entityErr error
 		var entityErrOnce sync.Once
+		// Progress calls are serialized, as SetProgress promises.
+		var extractedMu sync.Mutex
+		extracted := 0
 
 		for w := 0; w < ewCount; w++ {
 			entityWg.Add(1)
@@ -380,6 +509,10 @@ func (r *Repo) add(paths []string, progress AddProgressFunc, opts AddOptions) er
 						entityErrOnce.Do(func() { entityErr = err })
 						return
 					}
+					extractedMu.Lock()
+					extracted++
+					r.reportProgress(ProgressExtracting, extracted, len(blobs))
+					extractedMu.Unlock()
 				}
 			}()
 		}
@@ -397,6 +530,10 @@ func (r *Repo) add(paths []string, progress AddProgressFunc, opts AddOptions) er
 		if entityErr != nil {
 			return fmt.Errorf("add: %w", entityErr)
 		}
+		// A cancelled extraction leaves some entries without entities.
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("add: %w", err)
+		}
 
 		// Emit per-file entity progress events and update staging entries.
 		for i, br := range blobs {
@@ -415,17 +552,27 @@ func (r *Repo) add(paths []string, progress AddProgressFunc, opts AddOptions) er
 		})
 	}
 
+	for _, relPath := range toDelete {
+		stg.recordResolveUndo(relPath)
+		delete(stg.Entries, relPath)
+	}
+
 	emitAddProgress(progress, AddProgress{
 		Phase:   AddProgressPhaseWriteIndex,
-		Current: len(toAdd),
-		Total:   len(toAdd),
+		Current: len(toAdd) + len(toDelete),
+		Total:   len(toAdd) + len(toDelete),
 	})
 	if err := r.WriteStaging(stg); err != nil {
 		return fmt.Errorf("add: %w", err)
 	}
 
 	// Unified staging: also stage in git if a .git/ directory exists.
-	r.GitShadowStage(toAdd)
+	if len(toAdd) > 0 {
+		r.GitShadowStage(toAdd)
+	}
+	if len(toDelete) > 0 {
+		r.GitShadowRm(toDelete)
+	}
 
 	return nil
 }
@@ -434,6 +581,9 @@ func (r *Repo) add(paths []string, progress AddProgressFunc, opts AddOptions) er
 // guarded by the source-bytes semaphore. It updates br.entry.EntityListHash
 // in place and calls the AddHook if set.
 func (r *Repo) extractAndStoreEntities(ctx context.Context, sem *sourceBytesSemaphore, br *blobResult, opts AddOptions) error {
+	if br.skipEntities {
+		return nil
+	}
 	// Read content from the blob store instead of retaining it in memory
 	// across phases. This prevents holding all file contents simultaneously.
 	var content []byte
@@ -581,51 +731,88 @@ func addWorkerCount(total int) int {
 // check, and blob write. It returns the staging entry (with BlobHash set,
 // EntityListHash empty) and the raw content for Phase 2 entity extraction.
 // Binary files are staged but return nil content to skip entity extraction.
-func (r *Repo) prepareBlobEntry(relPath string, opts AddOptions) (*StagingEntry, []byte, error) {
+// A non-nil indexModes means the executable bit on disk is not trusted, and
+// holds the index modes the files keep. Line endings are normalized per le.
+//
+// Regular files above streamBlobThreshold that need no content rewriting
+// (LFS, line endings, filters) are streamed into the store instead of being
+// read into memory, and reported as streamed so Phase 2 skips them.
+func (r *Repo) prepareBlobEntry(relPath string, indexModes map[string]string, le *lineEndings, opts AddOptions) (*StagingEntry, []byte, bool, error) {
 	absPath := filepath.Join(r.RootDir, filepath.FromSlash(relPath))
 
-	// Stat first to check size before reading into memory.
-	info, err := os.Stat(absPath)
+	// Stat first to check size before reading into memory. Symlinks are
+	// not followed: a link is staged with its target path as content.
+	info, err := os.Lstat(absPath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("stat %q: %w", relPath, err)
+		return nil, nil, false, fmt.Errorf("stat %q: %w", relPath, err)
 	}
 	if limit := maxAddFileSize(); info.Size() > limit {
-		return nil, nil, fmt.Errorf("file %q too large (%d bytes, limit %d); set GRAFT_MAX_FILE_SIZE_MB to override or add to .graftignore",
+		return nil, nil, false, fmt.Errorf("file %q too large (%d bytes, limit %d); set GRAFT_MAX_FILE_SIZE_MB to override or add to .graftignore",
 			relPath, info.Size(), limit)
 	}
+	mode := worktreeMode(info, indexModes[relPath], indexModes == nil)
+
+	if info.Mode().IsRegular() && info.Size() > streamBlobThreshold &&
+		!le.rewrites(relPath) && !r.IsLFSTracked(relPath) {
+		entry, err := r.streamBlobEntry(relPath, absPath, info, mode)
+		return entry, nil, err == nil, err
+	}
 
-	content, err := os.ReadFile(absPath)
+	content, err := readWorktreeFile(absPath, info)
 	if err != nil {
-		return nil, nil, fmt.Errorf("read %q: %w", relPath, err)
+		return nil, nil, false, fmt.Errorf("read %q: %w", relPath, err)
 	}
 
 	// LFS: if file is tracked via .graftattributes filter=lfs,
 	// store actual content in LFS and replace with pointer.
-	if r.IsLFSTracked(relPath) {
+	if !isSymlinkMode(mode) && r.IsLFSTracked(relPath) {
 		oid, err := r.StoreLFSObject(content)
 		if err != nil {
-			return nil, nil, fmt.Errorf("lfs store %q: %w", relPath, err)
+			return nil, nil, false, fmt.Errorf("lfs store %q: %w", relPath, err)
 		}
 		content = WriteLFSPointer(oid, int64(len(content)))
+	} else {
+		content = le.clean(relPath, content, mode)
 	}
 
 	blobHash, err := r.Store.WriteBlob(&object.Blob{Data: content})
 	if err != nil {
-		return nil, nil, fmt.Errorf("write blob %q: %w", relPath, err)
+		return nil, nil, false, fmt.Errorf("write blob %q: %w", relPath, err)
 	}
 
 	entry := &StagingEntry{
 		Path:     relPath,
 		BlobHash: blobHash,
 	}
-	setStagingEntryStat(entry, info, modeFromFileInfo(info))
+	setStagingEntryStat(entry, info, mode)
 
-	// Binary files: write the blob but skip entity extraction.
-	if isBinaryContent(content) {
-		return entry, nil, nil
+	// Binary files and symlinks: write the blob but skip entity extraction.
+	if isSymlinkMode(mode) || isBinaryContent(content) {
+		return entry, nil, false, nil
+	}
+
+	return entry, content, false, nil
+}
+
+// streamBlobEntry stages a large regular file by streaming it into the
+// object store, keeping memory bounded regardless of file size.
+func (r *Repo) streamBlobEntry(relPath, absPath string, info os.FileInfo, mode string) (*StagingEntry, error) {
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("read %q: %w", relPath, err)
 	}
+	defer f.Close()
 
-	return entry, content, nil
+	blobHash, err := r.Store.WriteBlobFrom(f, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("write blob %q: %w", relPath, err)
+	}
+	entry := &StagingEntry{
+		Path:     relPath,
+		BlobHash: blobHash,
+	}
+	setStagingEntryStat(entry, info, mode)
+	return entry, nil
 }
 
 // Remove stages file deletions and optionally removes files from disk.
@@ -644,6 +831,7 @@ func (r *Repo) Remove(paths []string, cached bool) error {
 	}
 
 	for _, relPath := range toRemove {
+		stg.recordResolveUndo(relPath)
 		delete(stg.Entries, relPath)
 		if cached {
 			continue
@@ -667,16 +855,9 @@ func (r *Repo) Remove(paths []string, cached bool) error {
 // their hashes, then writes and returns the hash of the EntityListObj.
 func (r *Repo) writeEntityList(relPath string, el *entity.EntityList) (object.Hash, error) {
 	var refs []object.Hash
-	for _, ent := range el.Entities {
-		entObj := &object.EntityObj{
-			Kind:     ent.Kind.String(),
-			Name:     ent.Name,
-			DeclKind: ent.DeclKind,
-			Receiver: ent.Receiver,
-			Body:     ent.Body,
-			BodyHash: object.Hash(ent.BodyHash),
-		}
-		h, err := r.Store.WriteEntity(entObj)
+	for i := range el.Entities {
+		ent := &el.Entities[i]
+		h, err := r.Store.WriteEntity(entityObject(ent))
 		if err != nil {
 			return "", fmt.Errorf("write entity %q in %q: %w", ent.Name, relPath, err)
 		}
@@ -691,6 +872,18 @@ func (r *Repo) writeEntityList(relPath string, el *entity.EntityList) (object.Ha
 	return r.Store.WriteEntityList(elObj)
 }
 
+// entityObject converts an extracted entity to the object Add stores for it.
+func entityObject(ent *entity.Entity) *object.EntityObj {
+	return &object.EntityObj{
+		Kind:     ent.Kind.String(),
+		Name:     ent.Name,
+		DeclKind: ent.DeclKind,
+		Receiver: ent.Receiver,
+		Body:     ent.Body,
+		BodyHash: object.Hash(ent.BodyHash),
+	}
+}
+
 // repoRelPath converts a path (absolute, or relative to CWD) into a path
 // relative to the repository root. If the path is already relative and does
 // not start with the repo root, it is assumed to already be repo-relative.
@@ -726,65 +919,90 @@ func (r *Repo) repoRelPath(p string) (string, error) {
 	return filepath.ToSlash(rel), nil
 }
 
-func (r *Repo) expandAddPaths(inputs []string) ([]string, error) {
-	ic := NewIgnoreChecker(r.RootDir)
-	seen := make(map[string]struct{})
-
+// ParsePathspecs parses pathspec arguments and resolves them against the
+// working directory, except :(top) patterns, which are already relative to
+// the repository root.
+func (r *Repo) ParsePathspecs(inputs []string) (*pathspec.Set, error) {
+	set := &pathspec.Set{}
 	for _, input := range inputs {
 		input = strings.TrimSpace(input)
 		if input == "" {
 			continue
 		}
+		spec, err := pathspec.Parse(input)
+		if err != nil {
+			return nil, err
+		}
+		if !spec.Top {
+			// An empty path, as in "." or a bare ":!", is the working directory.
+			path := spec.Path
+			if path == "" {
+				path = "."
+			}
+			rel, err := r.repoRelPath(path)
+			if err != nil {
+				return nil, fmt.Errorf("resolve path %q: %w", input, err)
+			}
+			if isOutsideRepo(rel) {
+				return nil, fmt.Errorf("path %q is outside repository", input)
+			}
+			spec = spec.WithPath(rel)
+		}
+		set.Add(spec)
+	}
+	return set, nil
+}
+
+func (r *Repo) expandAddPaths(inputs []string) ([]string, error) {
+	ic := NewIgnoreChecker(r.RootDir)
+	specs, err := r.ParsePathspecs(inputs)
+	if err != nil {
+		return nil, err
+	}
+	includes := specs.Include
+	if len(includes) == 0 && len(specs.Exclude) > 0 {
+		// Exclusions alone select everything else, as in git.
+		includes = []pathspec.Pattern{{}}
+	}
 
-		if hasGlobMeta(input) {
+	seen := make(map[string]struct{})
+	for _, spec := range includes {
+		absPath := filepath.Join(r.RootDir, filepath.FromSlash(spec.Path))
+		if spec.HasWildcards() || spec.ICase {
 			// If the path exists literally (e.g. Next.js [owner]/page.tsx with bracket
 			// chars that would otherwise be interpreted as glob syntax), treat it as a
 			// plain path rather than a glob pattern.
-			if _, statErr := os.Stat(filepath.Join(r.RootDir, filepath.FromSlash(input))); statErr == nil {
-				if err := r.collectAddPath(input, ic, seen); err != nil {
-					return nil, fmt.Errorf("add %q: %w", input, err)
+			if !spec.ICase {
+				if _, statErr := os.Stat(absPath); statErr == nil {
+					if err := r.collectAddPath(absPath, ic, seen); err != nil {
+						return nil, fmt.Errorf("add %q: %w", spec.Raw, err)
+					}
+					continue
 				}
-				continue
 			}
 
-			spec, err := r.repoRelPath(input)
+			matches, err := r.matchWorktreeFiles(spec, ic)
 			if err != nil {
-				return nil, fmt.Errorf("resolve path %q: %w", input, err)
-			}
-			if isOutsideRepo(spec) {
-				return nil, fmt.Errorf("path %q is outside repository", input)
-			}
-			var matches []string
-			if strings.Contains(spec, "**") {
-				matches, err = r.globWithGlobstar(spec, ic)
-				if err != nil {
-					return nil, fmt.Errorf("glob %q: %w", input, err)
-				}
-			} else {
-				globPattern := filepath.Join(r.RootDir, filepath.FromSlash(spec))
-				matches, err = filepath.Glob(globPattern)
-				if err != nil {
-					return nil, fmt.Errorf("glob %q: %w", input, err)
-				}
+				return nil, fmt.Errorf("glob %q: %w", spec.Raw, err)
 			}
 			if len(matches) == 0 {
-				return nil, fmt.Errorf("pathspec %q did not match any files", input)
+				return nil, fmt.Errorf("pathspec %q did not match any files", spec.Raw)
 			}
 			for _, m := range matches {
-				if err := r.collectAddPath(m, ic, seen); err != nil {
-					return nil, err
-				}
+				seen[m] = struct{}{}
 			}
 			continue
 		}
-		if err := r.collectAddPath(input, ic, seen); err != nil {
+		if err := r.collectAddPath(absPath, ic, seen); err != nil {
 			return nil, err
 		}
 	}
 
 	out := make([]string, 0, len(seen))
 	for p := range seen {
-		out = append(out, p)
+		if !specs.Excluded(p) {
+			out = append(out, p)
+		}
 	}
 	sort.Strings(out)
 	return out, nil
@@ -803,15 +1021,27 @@ func (r *Repo) collectAddPath(input string, ic *IgnoreChecker, seen map[string]s
 	}
 
 	absPath := filepath.Join(r.RootDir, filepath.FromSlash(relPath))
-	info, err := os.Stat(absPath)
+	// A symlink named explicitly is staged as a link, even when it points
+	// at a directory.
+	stat := os.Lstat
+	if relPath == "" {
+		stat = os.Stat
+	}
+	info, err := stat(absPath)
 	if err != nil {
 		return fmt.Errorf("stat %q: %w", relPath, err)
 	}
 	if !info.IsDir() {
+		// A file named explicitly is only skipped when a rule matches the
+		// file itself, so tracked files inside ignored directories can
+		// still be staged.
 		rel := filepath.ToSlash(relPath)
-		if ic.IsIgnored(rel) {
+		if ic.isPathIgnored(rel) {
 			return nil
 		}
+		if root := r.nestedRepoRoot(rel); root != "" {
+			return fmt.Errorf("path %q is inside nested repository %q", input, root+"/")
+		}
 		seen[rel] = struct{}{}
 		return nil
 	}
@@ -832,6 +1062,10 @@ func (r *Repo) collectAddPath(input string, ic *IgnoreChecker, seen map[string]s
 			if ic.IsIgnored(rel) {
 				return filepath.SkipDir
 			}
+			if isNestedRepoDir(path) {
+				warnNestedRepo(rel)
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		if ic.IsIgnored(rel) {
@@ -842,66 +1076,94 @@ func (r *Repo) collectAddPath(input string, ic *IgnoreChecker, seen map[string]s
 	})
 }
 
-func (r *Repo) expandRemovePaths(inputs []string, stg *Staging) ([]string, error) {
-	tracked := make([]string, 0, len(stg.Entries))
-	for p := range stg.Entries {
-		tracked = append(tracked, filepath.ToSlash(p))
+// expandUpdatePaths selects the tracked files under paths that add --update
+// must stage: toAdd holds files whose stat data no longer matches the index,
+// and conflicted files, and toDelete holds files missing from the working
+// tree. Files whose stat data still matches are skipped without reading them.
+func (r *Repo) expandUpdatePaths(paths []string, stg *Staging) (toAdd, toDelete []string, err error) {
+	var candidates []string
+	if len(paths) == 0 {
+		for p := range stg.Entries {
+			candidates = append(candidates, p)
+		}
+		sort.Strings(candidates)
+	} else {
+		candidates, err = r.expandRemovePaths(paths, stg)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	sort.Strings(tracked)
 
-	seen := make(map[string]struct{})
-	for _, input := range inputs {
-		input = strings.TrimSpace(input)
-		if input == "" {
+	trustMode := r.trustFileMode()
+	for _, relPath := range candidates {
+		se := stg.Entries[relPath]
+		if normalizeFileMode(se.Mode) == object.TreeModeModule {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(r.RootDir, filepath.FromSlash(relPath)))
+		if errors.Is(err, os.ErrNotExist) || (err == nil && info.IsDir()) {
+			toDelete = append(toDelete, relPath)
 			continue
 		}
-
-		spec, err := r.repoRelPath(input)
 		if err != nil {
-			return nil, fmt.Errorf("resolve path %q: %w", input, err)
+			return nil, nil, fmt.Errorf("stat %q: %w", relPath, err)
 		}
-		spec = filepath.ToSlash(spec)
-		if isOutsideRepo(spec) {
-			return nil, fmt.Errorf("path %q is outside repository", input)
+		if se.Conflict || !stagingStatMatchesWorktree(se, info, worktreeMode(info, se.Mode, trustMode)) {
+			toAdd = append(toAdd, relPath)
 		}
+	}
+	return toAdd, toDelete, nil
+}
 
+func (r *Repo) expandRemovePaths(inputs []string, stg *Staging) ([]string, error) {
+	tracked := make([]string, 0, len(stg.Entries))
+	for p := range stg.Entries {
+		tracked = append(tracked, filepath.ToSlash(p))
+	}
+	sort.Strings(tracked)
+
+	specs, err := r.ParsePathspecs(inputs)
+	if err != nil {
+		return nil, err
+	}
+	includes := specs.Include
+	if len(includes) == 0 && len(specs.Exclude) > 0 {
+		includes = []pathspec.Pattern{{Raw: "."}}
+	}
+
+	seen := make(map[string]struct{})
+	for _, spec := range includes {
 		matched := false
-		if spec == "." || spec == "" {
-			for _, p := range tracked {
+		for _, p := range tracked {
+			if spec.Match(p) {
 				seen[p] = struct{}{}
-			}
-			matched = len(tracked) > 0
-		} else if hasGlobMeta(spec) {
-			for _, p := range tracked {
-				if matchPathspec(spec, p) {
-					seen[p] = struct{}{}
-					matched = true
-				}
-			}
-		} else {
-			for _, p := range tracked {
-				if p == spec || strings.HasPrefix(p, spec+"/") {
-					seen[p] = struct{}{}
-					matched = true
-				}
+				matched = true
 			}
 		}
 		if !matched {
-			return nil, fmt.Errorf("pathspec %q did not match tracked files", input)
+			return nil, fmt.Errorf("pathspec %q did not match tracked files", spec.Raw)
 		}
 	}
 
 	out := make([]string, 0, len(seen))
 	for p := range seen {
-		out = append(out, p)
+		if !specs.Excluded(p) {
+			out = append(out, p)
+		}
 	}
 	sort.Strings(out)
 	return out, nil
 }
 
-func (r *Repo) globWithGlobstar(spec string, ic *IgnoreChecker) ([]string, error) {
+// matchWorktreeFiles walks the working tree below spec's literal prefix and
+// returns the repo-relative files spec selects, skipping ignored paths.
+func (r *Repo) matchWorktreeFiles(spec pathspec.Pattern, ic *IgnoreChecker) ([]string, error) {
+	start := filepath.Join(r.RootDir, filepath.FromSlash(spec.Prefix()))
+	if _, err := os.Stat(start); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
 	var matches []string
-	err := filepath.WalkDir(r.RootDir, func(path string, d fs.DirEntry, walkErr error) error {
+	err := filepath.WalkDir(start, func(path string, d fs.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
@@ -917,13 +1179,17 @@ func (r *Repo) globWithGlobstar(spec string, ic *IgnoreChecker) ([]string, error
 			if ic.IsIgnored(rel) {
 				return filepath.SkipDir
 			}
+			if isNestedRepoDir(path) {
+				warnNestedRepo(rel)
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		if ic.IsIgnored(rel) {
 			return nil
 		}
-		if matchPathspec(spec, rel) {
-			matches = append(matches, path)
+		if spec.Match(rel) {
+			matches = append(matches, rel)
 		}
 		return nil
 	})
@@ -933,61 +1199,7 @@ func (r *Repo) globWithGlobstar(spec string, ic *IgnoreChecker) ([]string, error
 	return matches, nil
 }
 
-func hasGlobMeta(path string) bool {
-	return strings.ContainsAny(path, "*?[")
-}
-
-func matchPathspec(spec, path string) bool {
-	spec = filepath.ToSlash(spec)
-	path = filepath.ToSlash(path)
-	if strings.Contains(spec, "**") {
-		re, err := regexp.Compile(globPatternToRegex(spec))
-		if err != nil {
-			return false
-		}
-		return re.MatchString(path)
-	}
-	if strings.Contains(spec, "/") {
-		ok, _ := filepath.Match(spec, path)
-		return ok
-	}
-	ok, _ := filepath.Match(spec, filepath.Base(path))
-	return ok
-}
-
 func isOutsideRepo(rel string) bool {
 	rel = filepath.ToSlash(filepath.Clean(rel))
 	return rel == ".." || strings.HasPrefix(rel, "../")
 }
-
-func globPatternToRegex(pattern string) string {
-	var b strings.Builder
-	b.WriteString("^")
-	for i := 0; i < len(pattern); i++ {
-		ch := pattern[i]
-		if ch == '*' {
-			if i+1 < len(pattern) && pattern[i+1] == '*' {
-				if i+2 < len(pattern) && pattern[i+2] == '/' {
-					b.WriteString("(?:.*/)?")
-					i += 2
-				} else {
-					b.WriteString(".*")
-					i++
-				}
-				continue
-			}
-			b.WriteString("[^/]*")
-			continue
-		}
-		if ch == '?' {
-			b.WriteString("[^/]")
-			continue
-		}
-		if strings.ContainsRune(`.+()|[]{}^$\\`, rune(ch)) {
-			b.WriteByte('\\')
-		}
-		b.WriteByte(ch)
-	}
-	b.WriteString("$")
-	return b.String()
-}
@@ -0,0 +1,386 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// Binary staging index format (GIX1):
+//
+//   Header (16 bytes):
+//     Magic      [4]byte   "GIX1"
+//     Version    uint32    1 (big-endian)
+//     Count      uint32    number of entries (big-endian)
+//     Reserved   uint32    0
+//
+//   Entry section (Count entries, sorted by path):
+//     Fixed part (80 bytes):
+//       ModTime        int64     modification time, unix nanoseconds
+//       Size           int64     file size in bytes
+//       ChangeTimeNano int64     status change time (valid with indexHasChangeTime)
+//       Device         uint64    device number (valid with indexHasFileID)
+//       Inode          uint64    inode number (valid with indexHasFileID)
+//       Mode           uint32    octal tree mode, 0 when unset
+//       Flags          uint16    indexConflict | indexHas* bits below
+//       PathLen        uint16    length of Path in bytes
+//       BlobHash       [32]byte  blob hash (raw SHA-256)
+//     Optional hashes, 32 bytes each, in this order when their flag is set:
+//       EntityListHash, BaseBlobHash, OursBlobHash, TheirsBlobHash
+//     Path       [PathLen]byte  repo-relative slash path
+//
+//     Hashes that are not 64 hex digits cannot be stored raw. Such an entry
+//     sets indexTextHashes, leaves BlobHash zero, and stores every hash as
+//     a length byte and its text after Path instead.
+//
+//   Extensions (optional, after the entries):
+//     Signature  [4]byte   extension type
+//     Size       uint32    length of Data (big-endian)
+//     Data       [Size]byte
+//
+//     Readers skip extensions with unknown signatures. Defined extensions:
+//       "REUC" resolve-undo, one record per path, sorted by path:
+//         PathLen uint16, Path, Mode uint32, then BaseBlobHash,
+//         OursBlobHash and TheirsBlobHash, each as a length byte and its
+//         text (length 0 when the stage is absent).
+//
+//   Trailer (32 bytes):
+//     Checksum   [32]byte  SHA-256 of all preceding bytes
+
+const (
+	indexMagic       = "GIX1"
+	indexVersion     = 1
+	indexHeaderSize  = 16
+	indexEntryFixed  = 5*8 + 4 + 2 + 2 + 32 // 80 bytes
+	indexChecksumLen = 32
+
+	indexExtResolveUndo = "REUC"
+)
+
+// Entry flags.
+const (
+	indexConflict uint16 = 1 << iota
+	indexHasChangeTime
+	indexHasFileID
+	indexHasEntityList
+	indexHasBase
+	indexHasOurs
+	indexHasTheirs
+	indexTextHashes
+)
+
+// encodeBinaryStaging serializes s in the GIX1 format.
+func encodeBinaryStaging(s *Staging) ([]byte, error) {
+	paths := make([]string, 0, len(s.Entries))
+	size := indexHeaderSize + indexChecksumLen
+	for p := range s.Entries {
+		paths = append(paths, p)
+		size += indexEntryFixed + len(p)
+	}
+	sort.Strings(paths)
+
+	buf := make([]byte, 0, size)
+	buf = append(buf, indexMagic...)
+	buf = appendUint32(buf, indexVersion)
+	buf = appendUint32(buf, uint32(len(paths)))
+	buf = appendUint32(buf, 0) // reserved
+
+	for _, p := range paths {
+		e := s.Entries[p]
+		if len(p) > 0xffff {
+			return nil, fmt.Errorf("binary index: path too long (%d bytes): %.64s...", len(p), p)
+		}
+		var mode uint64
+		if e.Mode != "" {
+			m, err := strconv.ParseUint(e.Mode, 8, 32)
+			if err != nil {
+				return nil, fmt.Errorf("binary index: %s: invalid mode %q", p, e.Mode)
+			}
+			mode = m
+		}
+
+		optional := []struct {
+			flag uint16
+			hash object.Hash
+		}{
+			{indexHasEntityList, e.EntityListHash},
+			{indexHasBase, e.BaseBlobHash},
+			{indexHasOurs, e.OursBlobHash},
+			{indexHasTheirs, e.TheirsBlobHash},
+		}
+		var flags uint16
+		if e.Conflict {
+			flags |= indexConflict
+		}
+		if e.HasChangeTime {
+			flags |= indexHasChangeTime
+		}
+		if e.HasFileID {
+			flags |= indexHasFileID
+		}
+		rawHashes := isRawIndexHash(e.BlobHash)
+		for _, o := range optional {
+			if o.hash != "" {
+				flags |= o.flag
+				rawHashes = rawHashes && isRawIndexHash(o.hash)
+			}
+		}
+		if !rawHashes {
+			flags |= indexTextHashes
+		}
+
+		buf = appendInt64(buf, e.ModTime)
+		buf = appendInt64(buf, e.Size)
+		buf = appendInt64(buf, e.ChangeTimeNano)
+		buf = binary.BigEndian.AppendUint64(buf, e.Device)
+		buf = binary.BigEndian.AppendUint64(buf, e.Inode)
+		buf = appendUint32(buf, uint32(mode))
+		buf = binary.BigEndian.AppendUint16(buf, flags)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(p)))
+
+		if rawHashes {
+			buf = appendRawIndexHash(buf, e.BlobHash)
+			for _, o := range optional {
+				if o.hash != "" {
+					buf = appendRawIndexHash(buf, o.hash)
+				}
+			}
+			buf = append(buf, p...)
+			continue
+		}
+
+		buf = append(buf, make([]byte, 32)...)
+		buf = append(buf, p...)
+		for _, h := range []object.Hash{e.BlobHash, e.EntityListHash, e.BaseBlobHash, e.OursBlobHash, e.TheirsBlobHash} {
+			if len(h) > 0xff {
+				return nil, fmt.Errorf("binary index: %s: hash too long (%d bytes)", p, len(h))
+			}
+			buf = append(buf, byte(len(h)))
+			buf = append(buf, h...)
+		}
+	}
+
+	if len(s.ResolveUndo) > 0 {
+		ext, err := encodeResolveUndo(s.ResolveUndo)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, indexExtResolveUndo...)
+		buf = appendUint32(buf, uint32(len(ext)))
+		buf = append(buf, ext...)
+	}
+
+	checksum := sha256.Sum256(buf)
+	return append(buf, checksum[:]...), nil
+}
+
+// encodeResolveUndo serializes the data of a REUC extension.
+func encodeResolveUndo(entries map[string]*ResolveUndoEntry) ([]byte, error) {
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var buf []byte
+	for _, p := range paths {
+		e := entries[p]
+		if len(p) > 0xffff {
+			return nil, fmt.Errorf("binary index: resolve-undo path too long (%d bytes): %.64s...", len(p), p)
+		}
+		var mode uint64
+		if e.Mode != "" {
+			m, err := strconv.ParseUint(e.Mode, 8, 32)
+			if err != nil {
+				return nil, fmt.Errorf("binary index: resolve-undo %s: invalid mode %q", p, e.Mode)
+			}
+			mode = m
+		}
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(p)))
+		buf = append(buf, p...)
+		buf = appendUint32(buf, uint32(mode))
+		for _, h := range []object.Hash{e.BaseBlobHash, e.OursBlobHash, e.TheirsBlobHash} {
+			if len(h) > 0xff {
+				return nil, fmt.Errorf("binary index: resolve-undo %s: hash too long (%d bytes)", p, len(h))
+			}
+			buf = append(buf, byte(len(h)))
+			buf = append(buf, h...)
+		}
+	}
+	return buf, nil
+}
+
+// decodeResolveUndo parses the data of a REUC extension.
+func decodeResolveUndo(data []byte) (map[string]*ResolveUndoEntry, error) {
+	entries := make(map[string]*ResolveUndoEntry)
+	off := 0
+	for off < len(data) {
+		if off+2 > len(data) {
+			return nil, fmt.Errorf("binary index: resolve-undo record truncated")
+		}
+		pathLen := int(binary.BigEndian.Uint16(data[off : off+2]))
+		off += 2
+		if off+pathLen+4 > len(data) {
+			return nil, fmt.Errorf("binary index: resolve-undo record truncated")
+		}
+		e := &ResolveUndoEntry{Path: string(data[off : off+pathLen])}
+		off += pathLen
+		if mode := binary.BigEndian.Uint32(data[off : off+4]); mode != 0 {
+			e.Mode = strconv.FormatUint(uint64(mode), 8)
+		}
+		off += 4
+		for _, h := range []*object.Hash{&e.BaseBlobHash, &e.OursBlobHash, &e.TheirsBlobHash} {
+			if off >= len(data) || off+1+int(data[off]) > len(data) {
+				return nil, fmt.Errorf("binary index: resolve-undo %s truncated", e.Path)
+			}
+			n := int(data[off])
+			*h = object.Hash(data[off+1 : off+1+n])
+			off += 1 + n
+		}
+		entries[e.Path] = e
+	}
+	return entries, nil
+}
+
+// decodeBinaryStaging parses a GIX1 index. Strings are copied out of data,
+// so data may be released once it returns.
+func decodeBinaryStaging(data []byte) (*Staging, error) {
+	if len(data) < indexHeaderSize+indexChecksumLen {
+		return nil, fmt.Errorf("binary index: file too small (%d bytes)", len(data))
+	}
+	if string(data[:4]) != indexMagic {
+		return nil, fmt.Errorf("binary index: bad magic %q", string(data[:4]))
+	}
+	checksumOffset := len(data) - indexChecksumLen
+	if sha256.Sum256(data[:checksumOffset]) != [32]byte(data[checksumOffset:]) {
+		return nil, fmt.Errorf("binary index: checksum mismatch")
+	}
+	if version := binary.BigEndian.Uint32(data[4:8]); version != indexVersion {
+		return nil, fmt.Errorf("binary index: unsupported version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+	if int64(count)*indexEntryFixed > int64(checksumOffset-indexHeaderSize) {
+		return nil, fmt.Errorf("binary index: entry count %d overflows file", count)
+	}
+
+	body := data[:checksumOffset]
+	off := indexHeaderSize
+	stg := &Staging{Entries: make(map[string]*StagingEntry, count)}
+	for i := uint32(0); i < count; i++ {
+		if off+indexEntryFixed > len(body) {
+			return nil, fmt.Errorf("binary index: entry %d truncated", i)
+		}
+		fixed := body[off : off+indexEntryFixed]
+		off += indexEntryFixed
+
+		e := &StagingEntry{
+			ModTime:        int64(binary.BigEndian.Uint64(fixed[0:8])),
+			Size:           int64(binary.BigEndian.Uint64(fixed[8:16])),
+			ChangeTimeNano: int64(binary.BigEndian.Uint64(fixed[16:24])),
+			Device:         binary.BigEndian.Uint64(fixed[24:32]),
+			Inode:          binary.BigEndian.Uint64(fixed[32:40]),
+		}
+		if mode := binary.BigEndian.Uint32(fixed[40:44]); mode != 0 {
+			e.Mode = strconv.FormatUint(uint64(mode), 8)
+		}
+		flags := binary.BigEndian.Uint16(fixed[44:46])
+		pathLen := int(binary.BigEndian.Uint16(fixed[46:48]))
+		e.Conflict = flags&indexConflict != 0
+		e.HasChangeTime = flags&indexHasChangeTime != 0
+		e.HasFileID = flags&indexHasFileID != 0
+
+		optional := []struct {
+			flag uint16
+			hash *object.Hash
+		}{
+			{indexHasEntityList, &e.EntityListHash},
+			{indexHasBase, &e.BaseBlobHash},
+			{indexHasOurs, &e.OursBlobHash},
+			{indexHasTheirs, &e.TheirsBlobHash},
+		}
+		if flags&indexTextHashes == 0 {
+			e.BlobHash = rawToHash([32]byte(fixed[48:80]))
+			for _, o := range optional {
+				if flags&o.flag == 0 {
+					continue
+				}
+				if off+32 > len(body) {
+					return nil, fmt.Errorf("binary index: entry %d truncated", i)
+				}
+				*o.hash = rawToHash([32]byte(body[off : off+32]))
+				off += 32
+			}
+		}
+
+		if off+pathLen > len(body) {
+			return nil, fmt.Errorf("binary index: entry %d truncated", i)
+		}
+		e.Path = string(body[off : off+pathLen])
+		off += pathLen
+
+		if flags&indexTextHashes != 0 {
+			for _, h := range []*object.Hash{&e.BlobHash, &e.EntityListHash, &e.BaseBlobHash, &e.OursBlobHash, &e.TheirsBlobHash} {
+				if off >= len(body) || off+1+int(body[off]) > len(body) {
+					return nil, fmt.Errorf("binary index: entry %d truncated", i)
+				}
+				n := int(body[off])
+				*h = object.Hash(body[off+1 : off+1+n])
+				off += 1 + n
+			}
+		}
+		stg.Entries[e.Path] = e
+	}
+	for off < len(body) {
+		if off+8 > len(body) {
+			return nil, fmt.Errorf("binary index: %d trailing bytes after entries", len(body)-off)
+		}
+		sig := string(body[off : off+4])
+		size := int(binary.BigEndian.Uint32(body[off+4 : off+8]))
+		off += 8
+		if size > len(body)-off {
+			return nil, fmt.Errorf("binary index: extension %q truncated", sig)
+		}
+		ext := body[off : off+size]
+		off += size
+		if sig == indexExtResolveUndo {
+			ru, err := decodeResolveUndo(ext)
+			if err != nil {
+				return nil, err
+			}
+			stg.ResolveUndo = ru
+		}
+	}
+	return stg, nil
+}
+
+// isBinaryStaging reports whether data starts with the binary index magic.
+func isBinaryStaging(data []byte) bool {
+	return len(data) >= 4 && string(data[:4]) == indexMagic
+}
+
+// isRawIndexHash reports whether h is 64 lowercase hex digits, which is
+// what rawToHash reproduces.
+func isRawIndexHash(h object.Hash) bool {
+	if len(h) != 64 {
+		return false
+	}
+	for i := 0; i < len(h); i++ {
+		c := h[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func appendRawIndexHash(buf []byte, h object.Hash) []byte {
+	n := len(buf)
+	buf = append(buf, make([]byte, 32)...)
+	hex.Decode(buf[n:], []byte(h)) // validated by isRawIndexHash
+	return buf
+}
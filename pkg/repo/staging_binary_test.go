@@ -0,0 +1,214 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// TestBinaryStaging_RoundTrip covers every entry field, including optional
+// hashes and stat data.
+func TestBinaryStaging_RoundTrip(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	want := &Staging{Entries: map[string]*StagingEntry{
+		"src/main.go": {
+			Path:           "src/main.go",
+			BlobHash:       makeHash(1),
+			EntityListHash: makeHash(2),
+			Mode:           object.TreeModeExecutable,
+			ModTime:        1700000000123456789,
+			Size:           4096,
+			HasChangeTime:  true,
+			ChangeTimeNano: 1700000000987654321,
+			HasFileID:      true,
+			Device:         66306,
+			Inode:          1234567,
+		},
+		"conflicted.txt": {
+			Path:           "conflicted.txt",
+			BlobHash:       makeHash(3),
+			Conflict:       true,
+			BaseBlobHash:   makeHash(4),
+			OursBlobHash:   makeHash(5),
+			TheirsBlobHash: makeHash(6),
+		},
+		"deleted-side.txt": {
+			Path:         "deleted-side.txt",
+			Mode:         object.TreeModeFile,
+			Conflict:     true,
+			OursBlobHash: makeHash(7),
+		},
+	}}
+
+	if err := r.WriteStaging(want); err != nil {
+		t.Fatalf("WriteStaging: %v", err)
+	}
+	data, err := os.ReadFile(r.indexPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isBinaryStaging(data) {
+		t.Fatalf("index starts with %q, want binary magic", data[:4])
+	}
+
+	got, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", got.Entries, want.Entries)
+	}
+}
+
+// TestBinaryStaging_TextHashes keeps hashes that are not raw SHA-256 hex,
+// such as abbreviated test fixtures, intact.
+func TestBinaryStaging_TextHashes(t *testing.T) {
+	want := &Staging{Entries: map[string]*StagingEntry{
+		"a.txt": {Path: "a.txt", BlobHash: "deadbeef", OursBlobHash: makeHash(1), Mode: object.TreeModeFile, Size: 9},
+		"b.txt": {Path: "b.txt", BlobHash: object.Hash(strings.ToUpper(string(makeHash(2))))},
+	}}
+	data, err := encodeBinaryStaging(want)
+	if err != nil {
+		t.Fatalf("encodeBinaryStaging: %v", err)
+	}
+	got, err := decodeBinaryStaging(data)
+	if err != nil {
+		t.Fatalf("decodeBinaryStaging: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", got.Entries, want.Entries)
+	}
+}
+
+// TestBinaryStaging_SortedAndDeterministic writes entries in path order so
+// equal staging areas produce identical files.
+func TestBinaryStaging_SortedAndDeterministic(t *testing.T) {
+	stg := makeBenchStaging(50)
+	first, err := encodeBinaryStaging(stg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		again, err := encodeBinaryStaging(stg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(first) {
+			t.Fatal("encoding is not deterministic")
+		}
+	}
+	decoded, err := decodeBinaryStaging(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Entries) != 50 {
+		t.Fatalf("decoded %d entries, want 50", len(decoded.Entries))
+	}
+}
+
+func TestBinaryStaging_Checksum(t *testing.T) {
+	data, err := encodeBinaryStaging(makeBenchStaging(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[indexHeaderSize+10] ^= 0xff
+	if _, err := decodeBinaryStaging(data); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("decode of corrupted index: err = %v, want checksum mismatch", err)
+	}
+}
+
+// TestBinaryStaging_ReadsLegacyJSON keeps indexes written by earlier
+// versions readable, and rewrites them in the binary format.
+func TestBinaryStaging_ReadsLegacyJSON(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	legacy := &Staging{Entries: map[string]*StagingEntry{
+		"main.go": {Path: "main.go", BlobHash: makeHash(1), Mode: object.TreeModeFile, ModTime: 42, Size: 7},
+	}}
+	data, err := json.MarshalIndent(legacy, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(r.indexPath(), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging(json): %v", err)
+	}
+	if !reflect.DeepEqual(got.Entries, legacy.Entries) {
+		t.Fatalf("legacy read mismatch: got %+v", got.Entries)
+	}
+	if err := r.WriteStaging(got); err != nil {
+		t.Fatalf("WriteStaging: %v", err)
+	}
+	data, err = os.ReadFile(r.indexPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isBinaryStaging(data) {
+		t.Fatal("rewritten index is not binary")
+	}
+}
+
+func BenchmarkBinaryStaging_Read100k(b *testing.B) {
+	r, err := Init(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := r.WriteStaging(makeBenchStaging(100000)); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.ReadStaging(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBinaryStaging_Write100k(b *testing.B) {
+	r, err := Init(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	stg := makeBenchStaging(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.WriteStaging(stg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func makeBenchStaging(n int) *Staging {
+	stg := &Staging{Entries: make(map[string]*StagingEntry, n)}
+	for i := 0; i < n; i++ {
+		p := fmt.Sprintf("pkg/mod%03d/file%05d.go", i%100, i)
+		stg.Entries[p] = &StagingEntry{
+			Path:           p,
+			BlobHash:       makeHash(i),
+			EntityListHash: makeHash(n + i),
+			Mode:           object.TreeModeFile,
+			ModTime:        1700000000000000000 + int64(i),
+			Size:           int64(100 + i),
+			HasChangeTime:  true,
+			ChangeTimeNano: 1700000000000000000 + int64(i),
+			HasFileID:      true,
+			Device:         2049,
+			Inode:          uint64(1000 + i),
+		}
+	}
+	return stg
+}
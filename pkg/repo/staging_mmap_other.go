@@ -0,0 +1,15 @@
+//go:build !unix
+
+package repo
+
+import "os"
+
+// mapIndexFile reads the index at path into memory; memory-mapped reads are
+// only used on unix platforms.
+func mapIndexFile(path string) ([]byte, func(), error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() {}, nil
+}
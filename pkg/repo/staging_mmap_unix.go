@@ -0,0 +1,36 @@
+//go:build unix
+
+package repo
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapIndexFile maps the index at path read-only. The returned release
+// function unmaps it; the data must not be used afterwards. Empty files and
+// filesystems that refuse the mapping are read into memory instead.
+func mapIndexFile(path string) ([]byte, func(), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := info.Size()
+	if size > 0 && size == int64(int(size)) {
+		data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+		if err == nil {
+			return data, func() { _ = syscall.Munmap(data) }, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() {}, nil
+}
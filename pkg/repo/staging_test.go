@@ -1,13 +1,19 @@
 package repo
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/odvcencio/graft/pkg/lockfile"
 	"github.com/odvcencio/graft/pkg/object"
 )
 
@@ -589,6 +595,77 @@ func TestAdd_GlobstarPathspecStagesRecursively(t *testing.T) {
 	}
 }
 
+func TestAdd_ExcludeAndICasePathspecs(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for _, p := range []string{"src/main.go", "src/main_test.go", "src/vendor/lib.go", "docs/README.md", "notes.txt"} {
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(p)), []byte(p+"\n"))
+	}
+
+	if err := r.Add([]string{"src", ":(exclude)src/vendor", ":!*_test.go"}); err != nil {
+		t.Fatalf("Add with exclusions: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if got := keys(stg.Entries); len(got) != 1 || stg.Entries["src/main.go"] == nil {
+		t.Fatalf("staged %v, want only src/main.go", got)
+	}
+
+	if err := r.Add([]string{":(icase)docs/readme.MD"}); err != nil {
+		t.Fatalf("Add icase: %v", err)
+	}
+	// Exclusions alone select everything else in the working tree.
+	if err := r.Add([]string{":!src"}); err != nil {
+		t.Fatalf("Add exclude-only: %v", err)
+	}
+	stg, err = r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	for _, p := range []string{"src/main.go", "docs/README.md", "notes.txt"} {
+		if _, ok := stg.Entries[p]; !ok {
+			t.Fatalf("missing staged entry for %s in %v", p, keys(stg.Entries))
+		}
+	}
+	for _, p := range []string{"src/main_test.go", "src/vendor/lib.go"} {
+		if _, ok := stg.Entries[p]; ok {
+			t.Fatalf("did not expect %s to be staged", p)
+		}
+	}
+}
+
+func TestRemove_GlobPathspecWithExclusion(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for _, p := range []string{"pkg/a.go", "pkg/sub/b.go", "pkg/sub/keep.go", "main.go"} {
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(p)), []byte("package x\n"))
+	}
+	if err := r.Add([]string{"."}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if err := r.Remove([]string{"pkg/**/*.go", ":!**/keep.go"}, true); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	got := keys(stg.Entries)
+	sort.Strings(got)
+	if want := []string{"main.go", "pkg/sub/keep.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("staged after rm = %v, want %v", got, want)
+	}
+}
+
 func TestAdd_DuplicateContentStagesDeterministically(t *testing.T) {
 	prevProcs := runtime.GOMAXPROCS(4)
 	defer runtime.GOMAXPROCS(prevProcs)
@@ -751,6 +828,73 @@ func TestRemove_DirectoryPathRemovesTrackedPrefix(t *testing.T) {
 	}
 }
 
+func TestAdd_UpdateRestagesTrackedFilesOnly(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	for name, content := range map[string]string{
+		"a.go":     "package a\n",
+		"b.go":     "package b\n",
+		"pkg/c.go": "package pkg\n",
+	} {
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(name)), []byte(content))
+	}
+	if err := r.Add([]string{"a.go", "b.go", "pkg/c.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	before, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {}\n"))
+	writeFile(t, filepath.Join(dir, "pkg", "c.go"), []byte("package pkg\n\nfunc C() {}\n"))
+	writeFile(t, filepath.Join(dir, "new.go"), []byte("package main\n"))
+	if err := os.Remove(filepath.Join(dir, "b.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A pathspec limits the update to tracked files below it.
+	if err := r.AddWithOptions([]string{"pkg"}, nil, AddOptions{Update: true}); err != nil {
+		t.Fatalf("add -u pkg: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if stg.Entries["pkg/c.go"].BlobHash == before.Entries["pkg/c.go"].BlobHash {
+		t.Fatal("pkg/c.go was not restaged")
+	}
+	if stg.Entries["a.go"].BlobHash != before.Entries["a.go"].BlobHash || stg.Entries["b.go"] == nil {
+		t.Fatal("add -u pkg touched files outside pkg")
+	}
+
+	if err := r.AddWithOptions(nil, nil, AddOptions{Update: true}); err != nil {
+		t.Fatalf("add -u: %v", err)
+	}
+	stg, err = r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if stg.Entries["a.go"].BlobHash == before.Entries["a.go"].BlobHash {
+		t.Fatal("a.go was not restaged")
+	}
+	if _, ok := stg.Entries["b.go"]; ok {
+		t.Fatal("deleted b.go is still staged")
+	}
+	if _, ok := stg.Entries["new.go"]; ok {
+		t.Fatal("add -u staged untracked new.go")
+	}
+
+	// Nothing left to update is not an error.
+	if err := r.AddWithOptions(nil, nil, AddOptions{Update: true}); err != nil {
+		t.Fatalf("add -u with nothing to update: %v", err)
+	}
+}
+
 func TestAddWithProgress_ReportsPhasesAndCounts(t *testing.T) {
 	dir := t.TempDir()
 	r, err := Init(dir)
@@ -912,6 +1056,46 @@ func TestAdd_LargeJSONSkipsEntities(t *testing.T) {
 	}
 }
 
+func TestAdd_LargeFileStreamsBlobAndSkipsEntities(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	line := []byte("func generated() { return }\n")
+	data := bytes.Repeat(line, int(streamBlobThreshold)/len(line)+1)
+	if err := os.WriteFile(filepath.Join(dir, "huge.go"), data, 0o644); err != nil {
+		t.Fatalf("write huge.go: %v", err)
+	}
+	if err := r.Add([]string{"huge.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	stg, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	entry := stg.Entries["huge.go"]
+	if entry == nil {
+		t.Fatal("missing entry for huge.go")
+	}
+	if want := object.HashObject(object.TypeBlob, data); entry.BlobHash != want {
+		t.Errorf("BlobHash = %s, want %s", entry.BlobHash, want)
+	}
+	if entry.EntityListHash != "" {
+		t.Error("EntityListHash should be empty for a streamed file")
+	}
+	if entry.Size != int64(len(data)) {
+		t.Errorf("Size = %d, want %d", entry.Size, len(data))
+	}
+	blob, err := r.Store.ReadBlob(entry.BlobHash)
+	if err != nil {
+		t.Fatalf("ReadBlob: %v", err)
+	}
+	if !bytes.Equal(blob.Data, data) {
+		t.Error("streamed blob content mismatch")
+	}
+}
+
 func TestAdd_ForceEntitiesOnLargeJSON(t *testing.T) {
 	dir := t.TempDir()
 	r, err := Init(dir)
@@ -1046,3 +1230,110 @@ func keys(m map[string]*StagingEntry) []string {
 	}
 	return ks
 }
+
+func TestWriteStaging_RejectsIndexChangedSinceRead(t *testing.T) {
+	r := initRepoWithFile(t, "a.txt", []byte("a\n"))
+	if err := r.Add([]string{"a.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// Two writers read the same index.
+	first, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	second, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	first.Entries["b.txt"] = &StagingEntry{Path: "b.txt", BlobHash: first.Entries["a.txt"].BlobHash}
+	second.Entries["c.txt"] = &StagingEntry{Path: "c.txt", BlobHash: second.Entries["a.txt"].BlobHash}
+
+	if err := r.WriteStaging(first); err != nil {
+		t.Fatalf("WriteStaging(first): %v", err)
+	}
+	err = r.WriteStaging(second)
+	if !errors.Is(err, ErrIndexChanged) {
+		t.Fatalf("WriteStaging(second) error = %v, want ErrIndexChanged", err)
+	}
+
+	got, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if _, ok := got.Entries["b.txt"]; !ok {
+		t.Fatalf("first writer's entry lost: %v", keys(got.Entries))
+	}
+	if _, ok := got.Entries["c.txt"]; ok {
+		t.Fatal("stale writer overwrote the index")
+	}
+	if _, err := os.Stat(r.indexPath() + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("index.lock left behind: %v", err)
+	}
+
+	// A writer may keep writing the staging area it last wrote.
+	delete(first.Entries, "b.txt")
+	if err := r.WriteStaging(first); err != nil {
+		t.Fatalf("second WriteStaging(first): %v", err)
+	}
+}
+
+func TestStatusRefresh_SkipsLockedIndex(t *testing.T) {
+	r := initRepoWithFile(t, "a.txt", []byte("a\n"))
+	if err := r.Add([]string{"a.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	// New stat data makes Status refresh the index.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(r.RootDir, "a.txt"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := lockfile.Acquire(r.indexPath(), time.Second)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer lock.Release()
+
+	start := time.Now()
+	if _, err := r.Status(); err != nil {
+		t.Fatalf("Status with the index locked: %v", err)
+	}
+	if waited := time.Since(start); waited >= indexLockWaitLimit {
+		t.Fatalf("Status waited %v for the index lock", waited)
+	}
+}
+
+func TestStatusRefresh_KeepsIndexChangedSinceRead(t *testing.T) {
+	r := initRepoWithFile(t, "a.txt", []byte("a\n"))
+	if err := r.Add([]string{"a.txt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	stale, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	other, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	other.Entries["b.txt"] = &StagingEntry{Path: "b.txt", BlobHash: other.Entries["a.txt"].BlobHash}
+	if err := r.WriteStaging(other); err != nil {
+		t.Fatalf("WriteStaging: %v", err)
+	}
+
+	// Status's stat refresh is best-effort and drops a stale index.
+	if err := r.refreshStaging(stale); err != nil {
+		t.Fatalf("refreshStaging: %v", err)
+	}
+	got, err := r.ReadStaging()
+	if err != nil {
+		t.Fatalf("ReadStaging: %v", err)
+	}
+	if _, ok := got.Entries["b.txt"]; !ok {
+		t.Fatalf("refresh overwrote the other writer's index: %v", keys(got.Entries))
+	}
+	if _, err := r.Status(); err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+}
@@ -18,6 +18,22 @@ type StashEntry struct {
 	CommitHash object.Hash `json:"commit_hash"`
 	Message    string      `json:"message"`
 	Timestamp  int64       `json:"timestamp"`
+
+	// UntrackedTree holds the untracked (and with StashOptions.IncludeIgnored,
+	// ignored) files saved by a stash pushed with StashOptions, kept apart
+	// from the stash commit so they are restored as untracked files.
+	UntrackedTree object.Hash `json:"untracked_tree,omitempty"`
+}
+
+// StashOptions controls optional behavior of StashWithOptions.
+type StashOptions struct {
+	// IncludeUntracked saves untracked files in a separate tree and
+	// removes them from the working tree (stash -u).
+	IncludeUntracked bool
+
+	// IncludeIgnored saves ignored files along with untracked ones
+	// (stash -a). It implies IncludeUntracked.
+	IncludeIgnored bool
 }
 
 // stashPath returns the filesystem path to the stash file.
@@ -81,14 +97,33 @@ func (r *Repo) writeStashStack(entries []StashEntry) error {
 
 // Stash saves the current staging and working tree state as a commit with
 // HEAD as parent, then reverts the working tree and staging to match HEAD.
+// New untracked files are saved in the stash commit alongside tracked ones.
 // Returns an error if there are no changes to stash.
 func (r *Repo) Stash(author string) (*StashEntry, error) {
+	return r.StashWithOptions(author, StashOptions{})
+}
+
+// StashWithOptions is like Stash, adjusted by opts. With IncludeUntracked
+// or IncludeIgnored, untracked files are not staged into the stash commit:
+// they are saved in the entry's UntrackedTree and deleted from the working
+// tree, leaving it clean, and applying the stash writes them back as
+// untracked files.
+func (r *Repo) StashWithOptions(author string, opts StashOptions) (*StashEntry, error) {
+	separateUntracked := opts.IncludeUntracked || opts.IncludeIgnored
+
 	// 1. Check that there are changes to stash.
 	statusEntries, err := r.Status()
 	if err != nil {
 		return nil, fmt.Errorf("stash: %w", err)
 	}
-	hasChanges := false
+	var untracked []string
+	if separateUntracked {
+		untracked, err = r.collectCleanPaths(CleanOptions{IgnoredToo: opts.IncludeIgnored})
+		if err != nil {
+			return nil, fmt.Errorf("stash: %w", err)
+		}
+	}
+	hasChanges := len(untracked) > 0
 	for _, e := range statusEntries {
 		if e.IndexStatus != StatusClean || e.WorkStatus != StatusClean {
 			hasChanges = true
@@ -99,11 +134,20 @@ func (r *Repo) Stash(author string) (*StashEntry, error) {
 		return nil, fmt.Errorf("stash: no changes to stash")
 	}
 
+	// 1b. Save untracked files in their own tree before anything is staged.
+	var untrackedTree object.Hash
+	if len(untracked) > 0 {
+		if untrackedTree, err = r.writeUntrackedTree(untracked); err != nil {
+			return nil, fmt.Errorf("stash: save untracked files: %w", err)
+		}
+	}
+
 	// 2. Stage all dirty working tree files so the stash commit captures
-	//    everything (including unstaged modifications and new untracked files).
+	//    everything (including unstaged modifications and, unless they are
+	//    saved separately, new untracked files).
 	var toStage []string
 	for _, e := range statusEntries {
-		if e.WorkStatus == StatusDirty || e.WorkStatus == StatusUntracked {
+		if e.WorkStatus == StatusDirty || (e.WorkStatus == StatusUntracked && !separateUntracked && !e.NestedRepo) {
 			toStage = append(toStage, e.Path)
 		}
 	}
@@ -161,25 +205,96 @@ func (r *Repo) Stash(author string) (*StashEntry, error) {
 		return nil, err
 	}
 	entry := StashEntry{
-		CommitHash: commitHash,
-		Message:    commitObj.Message,
-		Timestamp:  now.Unix(),
+		CommitHash:    commitHash,
+		Message:       commitObj.Message,
+		Timestamp:     now.Unix(),
+		UntrackedTree: untrackedTree,
 	}
 	stack = append([]StashEntry{entry}, stack...)
 	if err := r.writeStashStack(stack); err != nil {
 		return nil, err
 	}
 
-	// 7. Revert working tree and staging to HEAD.
+	// 7. Revert working tree and staging to HEAD, and remove the saved
+	//    untracked files.
 	if err := r.revertToHEAD(); err != nil {
 		return nil, fmt.Errorf("stash: revert: %w", err)
 	}
+	for _, rel := range untracked {
+		absPath := filepath.Join(r.RootDir, filepath.FromSlash(rel))
+		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("stash: remove untracked %q: %w", rel, err)
+		}
+		r.removeEmptyParents(filepath.Dir(absPath))
+	}
 
 	r.GitShadowStash("push")
 
 	return &entry, nil
 }
 
+// writeUntrackedTree stores the files at paths, as they are on disk, in a
+// tree and returns its hash. Content is stored unconverted, so it can be
+// written back byte for byte.
+func (r *Repo) writeUntrackedTree(paths []string) (object.Hash, error) {
+	stg := &Staging{Entries: make(map[string]*StagingEntry, len(paths))}
+	for _, rel := range paths {
+		absPath := filepath.Join(r.RootDir, filepath.FromSlash(rel))
+		info, err := os.Lstat(absPath)
+		if err != nil {
+			return "", fmt.Errorf("stat %q: %w", rel, err)
+		}
+		data, err := readWorktreeFile(absPath, info)
+		if err != nil {
+			return "", fmt.Errorf("read %q: %w", rel, err)
+		}
+		blobHash, err := r.Store.WriteBlob(&object.Blob{Data: data})
+		if err != nil {
+			return "", fmt.Errorf("write blob %q: %w", rel, err)
+		}
+		entry := &StagingEntry{Path: rel, BlobHash: blobHash}
+		setStagingEntryStat(entry, info, worktreeMode(info, "", true))
+		stg.Entries[rel] = entry
+	}
+	// buildTreeDir rather than BuildTree: sidecar directories belong to
+	// commits, not to this tree.
+	return r.buildTreeDir(stg, "")
+}
+
+// checkUntrackedTree flattens an untracked tree saved by StashWithOptions
+// and fails if any of its files already exists in the working tree.
+func (r *Repo) checkUntrackedTree(treeHash object.Hash) ([]TreeFileEntry, error) {
+	files, err := r.flattenCheckoutTree(treeHash)
+	if err != nil {
+		return nil, fmt.Errorf("flatten untracked tree: %w", err)
+	}
+	for _, f := range files {
+		if _, err := os.Lstat(filepath.Join(r.RootDir, filepath.FromSlash(f.Path))); err == nil {
+			return nil, fmt.Errorf("untracked file %q already exists; move it away to apply this stash", f.Path)
+		}
+	}
+	return files, nil
+}
+
+// restoreUntrackedFiles writes files from an untracked tree back into the
+// working tree without staging them.
+func (r *Repo) restoreUntrackedFiles(files []TreeFileEntry) error {
+	for _, f := range files {
+		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
+		if err := mkdirWorktree(r.RootDir, filepath.Dir(absPath)); err != nil {
+			return fmt.Errorf("mkdir %q: %w", filepath.Dir(absPath), err)
+		}
+		blob, err := r.Store.ReadBlob(f.BlobHash)
+		if err != nil {
+			return fmt.Errorf("read blob for %q: %w", f.Path, err)
+		}
+		if err := writeWorktreeFile(absPath, blob.Data, f.Mode); err != nil {
+			return fmt.Errorf("write %q: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
 // revertToHEAD resets the working tree and staging to match the HEAD commit's
 // tree. If HEAD has no commits yet, it clears all tracked files and staging.
 func (r *Repo) revertToHEAD() error {
@@ -205,7 +320,7 @@ func (r *Repo) revertToHEAD() error {
 		return fmt.Errorf("read HEAD commit: %w", err)
 	}
 
-	targetFiles, err := r.FlattenTree(commit.TreeHash)
+	targetFiles, err := r.flattenCheckoutTree(commit.TreeHash)
 	if err != nil {
 		return fmt.Errorf("flatten HEAD tree: %w", err)
 	}
@@ -225,11 +340,12 @@ func (r *Repo) revertToHEAD() error {
 	}
 
 	// Write all files from HEAD tree.
+	le := r.lineEndingsForTree(targetFiles)
 	for _, f := range targetFiles {
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
 
 		dir := filepath.Dir(absPath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := mkdirWorktree(r.RootDir, dir); err != nil {
 			return fmt.Errorf("mkdir %q: %w", dir, err)
 		}
 
@@ -238,7 +354,7 @@ func (r *Repo) revertToHEAD() error {
 			return fmt.Errorf("read blob for %q: %w", f.Path, err)
 		}
 
-		if err := os.WriteFile(absPath, blob.Data, filePermFromMode(f.Mode)); err != nil {
+		if err := writeWorktreeFile(absPath, le.smudge(f.Path, blob.Data, f.Mode), f.Mode); err != nil {
 			return fmt.Errorf("write %q: %w", f.Path, err)
 		}
 	}
@@ -247,7 +363,7 @@ func (r *Repo) revertToHEAD() error {
 	stg := &Staging{Entries: make(map[string]*StagingEntry, len(targetFiles))}
 	for _, f := range targetFiles {
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(f.Path))
-		info, err := os.Stat(absPath)
+		info, err := os.Lstat(absPath)
 		if err != nil {
 			return fmt.Errorf("stat %q: %w", f.Path, err)
 		}
@@ -294,8 +410,8 @@ func (r *Repo) StashApply(index int) error {
 		return err
 	}
 	if !result.Clean {
-		return fmt.Errorf("stash apply: %d conflict(s) in: %s",
-			len(result.ConflictPaths),
+		return fmt.Errorf("stash apply: %w: %d conflict(s) in: %s",
+			ErrMergeConflicts, len(result.ConflictPaths),
 			joinPaths(result.ConflictPaths))
 	}
 	return nil
@@ -321,6 +437,15 @@ func (r *Repo) StashApplyMerge(index int) (*StashApplyResult, error) {
 
 	entry := stack[index]
 
+	// Files saved by a stash with untracked files come back untracked;
+	// refuse before touching anything if one would overwrite a file.
+	var untrackedFiles []TreeFileEntry
+	if entry.UntrackedTree != "" {
+		if untrackedFiles, err = r.checkUntrackedTree(entry.UntrackedTree); err != nil {
+			return nil, fmt.Errorf("stash: %w", err)
+		}
+	}
+
 	// Read the stash commit.
 	stashCommit, err := r.Store.ReadCommit(entry.CommitHash)
 	if err != nil {
@@ -405,6 +530,10 @@ func (r *Repo) StashApplyMerge(index int) (*StashApplyResult, error) {
 		}
 	}
 
+	if err := r.restoreUntrackedFiles(untrackedFiles); err != nil {
+		return nil, fmt.Errorf("stash: restore untracked files: %w", err)
+	}
+
 	return &StashApplyResult{
 		Clean:         !mergeResult.HasConflicts,
 		ConflictPaths: mergeResult.ConflictDetails,
@@ -442,12 +571,13 @@ func (r *Repo) StashDrop(index int) error {
 // StashShowEntry describes a single file changed in a stash entry.
 type StashShowEntry struct {
 	Path       string // file path
-	ChangeType string // "added", "modified", "deleted"
+	ChangeType string // "added", "modified", "deleted", "untracked"
 }
 
 // StashShow returns the list of files changed in the stash at the given index
 // by comparing the stash commit's tree against its parent's tree. If the stash
 // has no parent (created on an empty repo), all files are reported as "added".
+// Files saved in the entry's untracked tree follow, as "untracked".
 func (r *Repo) StashShow(index int) ([]StashShowEntry, error) {
 	stack, err := r.readStashStack()
 	if err != nil {
@@ -518,6 +648,16 @@ func (r *Repo) StashShow(index int) ([]StashShowEntry, error) {
 		}
 	}
 
+	if entry.UntrackedTree != "" {
+		untrackedFiles, err := r.FlattenTree(entry.UntrackedTree)
+		if err != nil {
+			return nil, fmt.Errorf("stash: flatten untracked tree: %w", err)
+		}
+		for _, f := range untrackedFiles {
+			result = append(result, StashShowEntry{Path: f.Path, ChangeType: "untracked"})
+		}
+	}
+
 	return result, nil
 }
 
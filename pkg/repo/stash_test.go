@@ -674,3 +674,72 @@ func TestStashShowDiff(t *testing.T) {
 		t.Errorf("diff should contain +modified, got:\n%s", content)
 	}
 }
+
+func TestStashWithOptions_UntrackedAndIgnoredFiles(t *testing.T) {
+	r := stashTestRepo(t, "hello.txt", []byte("original"))
+	commitFile(t, r, ".graftignore", []byte("*.log\n"), "ignore logs")
+
+	writeFile(t, filepath.Join(r.RootDir, "hello.txt"), []byte("modified"))
+	writeFile(t, filepath.Join(r.RootDir, "notes", "todo.txt"), []byte("todo"))
+	writeFile(t, filepath.Join(r.RootDir, "build.log"), []byte("log"))
+
+	entry, err := r.StashWithOptions("test-author", StashOptions{IncludeUntracked: true})
+	if err != nil {
+		t.Fatalf("StashWithOptions(-u): %v", err)
+	}
+	if entry.UntrackedTree == "" {
+		t.Fatal("stash -u recorded no untracked tree")
+	}
+	if _, err := os.Stat(filepath.Join(r.RootDir, "notes")); !os.IsNotExist(err) {
+		t.Fatalf("untracked directory left behind: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(r.RootDir, "build.log")); err != nil {
+		t.Fatalf("ignored file removed by -u: %v", err)
+	}
+	shown, err := r.StashShow(0)
+	if err != nil {
+		t.Fatalf("StashShow: %v", err)
+	}
+	if len(shown) != 2 || shown[1] != (StashShowEntry{Path: "notes/todo.txt", ChangeType: "untracked"}) {
+		t.Fatalf("StashShow = %+v, want hello.txt modified and notes/todo.txt untracked", shown)
+	}
+
+	// A file in the way of an untracked one blocks the apply untouched.
+	writeFile(t, filepath.Join(r.RootDir, "notes", "todo.txt"), []byte("in the way"))
+	if err := r.StashPop(0); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("StashPop over an existing file: err = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(r.RootDir, "hello.txt")); string(data) != "original" {
+		t.Fatalf("hello.txt = %q after a refused pop, want it untouched", data)
+	}
+	if err := os.RemoveAll(filepath.Join(r.RootDir, "notes")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.StashPop(0); err != nil {
+		t.Fatalf("StashPop: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(r.RootDir, "notes", "todo.txt")); err != nil || string(data) != "todo" {
+		t.Fatalf("notes/todo.txt = %q, %v after pop", data, err)
+	}
+	status := statusByPath(t, r)
+	if status["notes/todo.txt"].WorkStatus != StatusUntracked {
+		t.Fatalf("notes/todo.txt status = %+v, want untracked", status["notes/todo.txt"])
+	}
+
+	// -a takes ignored files along.
+	if _, err := r.StashWithOptions("test-author", StashOptions{IncludeIgnored: true}); err != nil {
+		t.Fatalf("StashWithOptions(-a): %v", err)
+	}
+	for _, p := range []string{"build.log", "notes/todo.txt"} {
+		if _, err := os.Stat(filepath.Join(r.RootDir, p)); !os.IsNotExist(err) {
+			t.Fatalf("%s left behind by -a: %v", p, err)
+		}
+	}
+	if err := r.StashPop(0); err != nil {
+		t.Fatalf("StashPop(-a): %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(r.RootDir, "build.log")); err != nil || string(data) != "log" {
+		t.Fatalf("build.log = %q, %v after pop", data, err)
+	}
+}
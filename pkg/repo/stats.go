@@ -0,0 +1,208 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/odvcencio/graft/pkg/diff3"
+	"github.com/odvcencio/graft/pkg/entity"
+)
+
+// StatsReport summarises the churn of a revision range: how many commits
+// it holds and how many lines they added and removed, broken down by
+// author and by entity. Merge commits are not counted.
+type StatsReport struct {
+	Commits  int
+	Added    int
+	Removed  int
+	Authors  []AuthorStats // most commits first
+	Entities []EntityStats // most lines changed first
+}
+
+// AuthorStats is one author's share of a StatsReport. Entities counts the
+// distinct declarations the author's commits changed.
+type AuthorStats struct {
+	Author   string
+	Commits  int
+	Entities int
+	Added    int
+	Removed  int
+}
+
+// EntityStats is the churn of one declaration. Added lines are attributed
+// to the declaration containing them after the commit, removed lines to
+// the one containing them before.
+type EntityStats struct {
+	Path     string
+	Key      string
+	Name     string
+	DeclKind string
+	Commits  int
+	Authors  int
+	Added    int
+	Removed  int
+}
+
+// Stats computes a StatsReport over the commits revs selects, read the way
+// rev-list reads them. No revs means the history of HEAD. Each commit is
+// compared against its first parent; binary files count toward no lines.
+// Authors are grouped as the .mailmap file maps them.
+func (r *Repo) Stats(revs []string) (*StatsReport, error) {
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+	rr, err := r.ParseRevRange(revs)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := r.RevList(rr, RevListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	mailmap, err := r.Mailmap()
+	if err != nil {
+		return nil, fmt.Errorf("stats: %w", err)
+	}
+
+	report := &StatsReport{}
+	authors := make(map[string]*AuthorStats)
+	authorEntities := make(map[string]map[string]bool)
+	entities := make(map[string]*EntityStats)
+	entityAuthors := make(map[string]map[string]bool)
+
+	for _, h := range hashes {
+		c, err := r.Store.ReadCommit(h)
+		if err != nil {
+			return nil, fmt.Errorf("stats: read commit %s: %w", h, err)
+		}
+		if len(c.Parents) > 1 {
+			continue
+		}
+		author := mailmap.Map(c.Author)
+
+		as := authors[author]
+		if as == nil {
+			as = &AuthorStats{Author: author}
+			authors[author] = as
+			authorEntities[author] = make(map[string]bool)
+		}
+		as.Commits++
+		report.Commits++
+
+		touched := make(map[string]bool)
+		err = r.walkCommitChanges(c, "", func(path string, before, after []byte) bool {
+			if isBinaryContent(before) || isBinaryContent(after) {
+				return true
+			}
+			beforeDecls := statsDeclarations(path, before)
+			afterDecls := statsDeclarations(path, after)
+			count := func(decls []entity.Entity, line int, added bool) {
+				if added {
+					as.Added++
+					report.Added++
+				} else {
+					as.Removed++
+					report.Removed++
+				}
+				e := statsEntityAt(decls, line)
+				if e == nil {
+					return
+				}
+				key := path + "\x00" + e.IdentityKey()
+				es := entities[key]
+				if es == nil {
+					es = &EntityStats{Path: path, Key: e.IdentityKey(), Name: e.Name, DeclKind: e.DeclKind}
+					entities[key] = es
+					entityAuthors[key] = make(map[string]bool)
+				}
+				if added {
+					es.Added++
+				} else {
+					es.Removed++
+				}
+				if !touched[key] {
+					touched[key] = true
+					es.Commits++
+				}
+				entityAuthors[key][author] = true
+				authorEntities[author][key] = true
+			}
+
+			oldLine, newLine := 0, 0
+			for _, line := range diff3.LineDiff(before, after) {
+				switch line.Type {
+				case diff3.Equal:
+					oldLine++
+					newLine++
+				case diff3.Delete:
+					oldLine++
+					count(beforeDecls, oldLine, false)
+				case diff3.Insert:
+					newLine++
+					count(afterDecls, newLine, true)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("stats: commit %s: %w", h, err)
+		}
+	}
+
+	for author, as := range authors {
+		as.Entities = len(authorEntities[author])
+		report.Authors = append(report.Authors, *as)
+	}
+	sort.Slice(report.Authors, func(i, j int) bool {
+		a, b := report.Authors[i], report.Authors[j]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		return a.Author < b.Author
+	})
+
+	for key, es := range entities {
+		es.Authors = len(entityAuthors[key])
+		report.Entities = append(report.Entities, *es)
+	}
+	sort.Slice(report.Entities, func(i, j int) bool {
+		a, b := report.Entities[i], report.Entities[j]
+		if a.Added+a.Removed != b.Added+b.Removed {
+			return a.Added+a.Removed > b.Added+b.Removed
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Key < b.Key
+	})
+	return report, nil
+}
+
+// statsDeclarations returns the declarations of a file version, or none
+// when its language is not supported.
+func statsDeclarations(path string, data []byte) []entity.Entity {
+	if len(data) == 0 {
+		return nil
+	}
+	el, err := entity.Extract(path, data)
+	if err != nil {
+		return nil
+	}
+	var decls []entity.Entity
+	for _, e := range el.Entities {
+		if e.Kind == entity.KindDeclaration {
+			decls = append(decls, e)
+		}
+	}
+	return decls
+}
+
+// statsEntityAt returns the declaration spanning the 1-based line, if any.
+func statsEntityAt(decls []entity.Entity, line int) *entity.Entity {
+	for i := range decls {
+		if decls[i].StartLine <= line && line <= decls[i].EndLine {
+			return &decls[i]
+		}
+	}
+	return nil
+}
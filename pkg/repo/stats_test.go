@@ -0,0 +1,59 @@
+package repo
+
+import "testing"
+
+func TestStats_AuthorAndEntityChurn(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	base := commitAs(t, r, map[string]string{
+		"main.go": "package main\n\nfunc Load() int {\n\treturn 1\n}\n\nfunc Save() {\n}\n",
+	}, "alice", "add load and save")
+	commitAs(t, r, map[string]string{
+		"main.go": "package main\n\nfunc Load() int {\n\treturn 2\n}\n\nfunc Save() {\n\tflush()\n}\n",
+	}, "bob", "change load and save")
+	commitAs(t, r, map[string]string{
+		"main.go":   "package main\n\nfunc Load() int {\n\treturn 3\n}\n\nfunc Save() {\n\tflush()\n}\n",
+		"README.md": "notes\n",
+	}, "alice", "change load again")
+
+	report, err := r.Stats(nil)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if report.Commits != 3 || report.Added != 12 || report.Removed != 2 {
+		t.Fatalf("totals = %d commits +%d -%d, want 3 commits +12 -2", report.Commits, report.Added, report.Removed)
+	}
+
+	if len(report.Authors) != 2 {
+		t.Fatalf("authors = %+v, want 2", report.Authors)
+	}
+	alice, bob := report.Authors[0], report.Authors[1]
+	if alice.Author != "alice" || alice.Commits != 2 || alice.Entities != 2 || alice.Added != 10 || alice.Removed != 1 {
+		t.Fatalf("alice = %+v", alice)
+	}
+	if bob.Author != "bob" || bob.Commits != 1 || bob.Entities != 2 || bob.Added != 2 || bob.Removed != 1 {
+		t.Fatalf("bob = %+v", bob)
+	}
+
+	if len(report.Entities) != 2 {
+		t.Fatalf("entities = %+v, want 2", report.Entities)
+	}
+	load := report.Entities[0]
+	if load.Name != "Load" || load.Path != "main.go" || load.Commits != 3 || load.Authors != 2 || load.Added != 5 || load.Removed != 2 {
+		t.Fatalf("Load = %+v", load)
+	}
+	if save := report.Entities[1]; save.Name != "Save" || save.Commits != 2 || save.Added != 3 {
+		t.Fatalf("Save = %+v", save)
+	}
+
+	ranged, err := r.Stats([]string{string(base) + ".."})
+	if err != nil {
+		t.Fatalf("Stats(range): %v", err)
+	}
+	if ranged.Commits != 2 || ranged.Added != 4 || ranged.Removed != 2 {
+		t.Fatalf("range totals = %d commits +%d -%d, want 2 commits +4 -2", ranged.Commits, ranged.Added, ranged.Removed)
+	}
+}
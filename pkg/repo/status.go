@@ -1,14 +1,18 @@
 package repo
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/odvcencio/graft/pkg/lockfile"
 	"github.com/odvcencio/graft/pkg/object"
 )
 
@@ -32,6 +36,7 @@ type StatusEntry struct {
 	RenamedFrom string     // non-empty when IndexStatus or WorkStatus is StatusRenamed
 	IndexStatus FileStatus // staging vs HEAD comparison
 	WorkStatus  FileStatus // working tree vs staging comparison
+	NestedRepo  bool       // Path ("dir/") is the root of another repository
 }
 
 type headTreeState struct {
@@ -44,10 +49,19 @@ type headTreeState struct {
 // Algorithm:
 //  1. Read staging index.
 //  2. Walk the working directory (skipping .graft/ and ignored paths).
+//     Untracked directories holding a repository of their own are not
+//     entered; each is reported as one untracked "dir/" entry.
 //  3. Compare working tree files against staging entries.
 //  4. Compare staging entries against HEAD tree (if available).
 //  5. Return a sorted list of status entries.
 func (r *Repo) Status() ([]StatusEntry, error) {
+	return r.StatusContext(context.Background())
+}
+
+// StatusContext is like Status but accepts an explicit context. The
+// working-tree walk and content hashing stop once ctx is done, and the
+// context's error is returned.
+func (r *Repo) StatusContext(ctx context.Context) ([]StatusEntry, error) {
 	stg, err := r.ReadStaging()
 	if err != nil {
 		return nil, fmt.Errorf("status: %w", err)
@@ -56,72 +70,52 @@ func (r *Repo) Status() ([]StatusEntry, error) {
 	ic := NewIgnoreChecker(r.RootDir)
 	sparseEnabled := r.IsSparseEnabled()
 	trackedPaths, trackedDirs := trackedStatusPaths(stg)
-
-	// Collect all working-tree files (repo-relative paths).
-	workFiles := make(map[string]bool)
-	err = filepath.WalkDir(r.RootDir, func(path string, d fs.DirEntry, walkErr error) error {
-		if walkErr != nil {
-			return walkErr
-		}
-
-		rel, err := filepath.Rel(r.RootDir, path)
+	trustMode := r.trustFileMode()
+	le := r.lineEndings()
+
+	// Collect working-tree files (repo-relative paths). With a filesystem
+	// monitor only the paths it reports changed are examined, and the other
+	// tracked files are taken to be unchanged.
+	var workFiles map[string]bool
+	var unchanged map[string]struct{}
+	nested := make(map[string]struct{})
+	monitor := r.queryFSMonitor()
+	if monitor != nil && !monitor.full && !sparseEnabled {
+		workFiles, unchanged, err = r.monitoredWorkFiles(ctx, stg, ic, monitor, trackedPaths, trackedDirs, nested)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("status: %w", err)
 		}
-		rel = filepath.ToSlash(rel)
-
-		// Skip the root directory itself.
-		if rel == "." {
-			return nil
-		}
-
-		// Ignore rules should not hide already tracked paths. Otherwise a root
-		// ignore like "orchard" would make tracked files under cmd/orchard/ look
-		// deleted in status output.
-		if ic.IsIgnored(rel) {
-			if _, tracked := trackedPaths[rel]; tracked {
-				// Keep walking/recording tracked paths even if they currently match
-				// an ignore rule.
-			} else if d.IsDir() {
-				if _, keepWalking := trackedDirs[rel]; keepWalking {
-					// An ignored directory still contains tracked content, so it must
-					// remain visible to the status walk.
-				} else {
-					return fs.SkipDir
-				}
-			} else {
-				return nil
-			}
+	} else {
+		workFiles = make(map[string]bool)
+		if err := r.walkStatusFiles(ctx, r.RootDir, ic, trackedPaths, trackedDirs, sparseEnabled, workFiles, nested); err != nil {
+			return nil, fmt.Errorf("status: walk: %w", err)
 		}
+	}
 
-		// Skip paths excluded by sparse checkout.
-		if sparseEnabled && !r.matchesSparsePatterns(rel) {
-			if d.IsDir() {
-				if !r.dirCouldContainSparseMatch(rel) {
-					return fs.SkipDir
-				}
-				return nil
+	ignoreCase := r.ignoreCase()
+	if ignoreCase {
+		// Tracked paths that share their file with a case twin are as
+		// clean as the file the twin found on disk.
+		for _, path := range caseTwinsOnDisk(stg, workFiles) {
+			workFiles[path] = true
+			if unchanged == nil {
+				unchanged = make(map[string]struct{})
 			}
-			return nil
+			unchanged[path] = struct{}{}
 		}
-
-		// Only track regular files.
-		if !d.IsDir() {
-			workFiles[rel] = true
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("status: walk: %w", err)
 	}
 
 	// Build the result map keyed by path.
 	result := make(map[string]*StatusEntry)
-	workRenamedNewToOld, workRenamedOldToNew, err := r.detectWorktreeRenames(stg, workFiles)
+	workRenamedNewToOld, workRenamedOldToNew, err := r.detectWorktreeRenames(stg, workFiles, trustMode, le)
 	if err != nil {
 		return nil, fmt.Errorf("status: detect worktree renames: %w", err)
 	}
+	if ignoreCase {
+		pairCaseOnlyRenames(stg, workFiles, workRenamedNewToOld, workRenamedOldToNew)
+	}
 	refreshStaging := false
+	var hashJobs []statusHashJob
 
 	// --- Working tree vs staging comparison ---
 
@@ -156,36 +150,63 @@ func (r *Repo) Status() ([]StatusEntry, error) {
 			continue
 		}
 
+		// The monitor reported no change since the last status, when the
+		// file matched the index.
+		if _, ok := unchanged[path]; ok {
+			result[path] = &StatusEntry{Path: path, WorkStatus: StatusClean}
+			continue
+		}
+
 		// File is in staging — compare metadata first, then content hash if needed.
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(path))
-		info, err := os.Stat(absPath)
+		info, err := os.Lstat(absPath)
 		if err != nil {
 			return nil, fmt.Errorf("status: stat %q: %w", path, err)
 		}
-		workMode := modeFromFileInfo(info)
-		workStatus := StatusClean
+		workMode := worktreeMode(info, se.Mode, trustMode)
+		entry := &StatusEntry{
+			Path:       path,
+			WorkStatus: StatusClean,
+		}
+		result[path] = entry
 		if !stagingStatMatchesWorktree(se, info, workMode) {
-			if stagingStatDefinitelyDirty(se, info, workMode) {
-				workStatus = StatusDirty
+			// A new size proves nothing when line endings are converted
+			// or a filter runs: the file may have only switched between LF
+			// and CRLF, or be the smudged form of the stored content.
+			if stagingStatDefinitelyDirty(se, info, workMode) && !le.rewrites(path) {
+				entry.WorkStatus = StatusDirty
 			} else {
-				workHash, err := r.worktreeBlobHash(path, absPath, info, workMode)
-				if err != nil {
-					return nil, fmt.Errorf("status: read %q: %w", path, err)
-				}
-				if workHash != se.BlobHash || workMode != normalizeFileMode(se.Mode) {
-					workStatus = StatusDirty
-				} else if refreshStagingEntryStat(se, info, workMode) {
-					refreshStaging = true
-				}
+				// Only the content can tell; hash these together below.
+				hashJobs = append(hashJobs, statusHashJob{
+					entry:   entry,
+					staged:  se,
+					absPath: absPath,
+					info:    info,
+					mode:    workMode,
+				})
 			}
 		}
+	}
 
-		entry := &StatusEntry{
-			Path:       path,
-			WorkStatus: workStatus,
+	for dir := range nested {
+		result[dir+"/"] = &StatusEntry{
+			Path:        dir + "/",
+			IndexStatus: StatusUntracked,
+			WorkStatus:  StatusUntracked,
+			NestedRepo:  true,
 		}
+	}
 
-		result[path] = entry
+	hashes, err := r.hashStatusCandidates(ctx, hashJobs, le)
+	if err != nil {
+		return nil, err
+	}
+	for i, job := range hashJobs {
+		if hashes[i] != job.staged.BlobHash || job.mode != normalizeFileMode(job.staged.Mode) {
+			job.entry.WorkStatus = StatusDirty
+		} else if refreshStagingEntryStat(job.staged, job.info, job.mode) {
+			refreshStaging = true
+		}
 	}
 
 	// For each staged entry not on disk → deleted from working tree.
@@ -275,14 +296,159 @@ func (r *Repo) Status() ([]StatusEntry, error) {
 	})
 
 	if refreshStaging {
-		if err := r.writeStaging(stg, false); err != nil {
+		if err := r.refreshStaging(stg); err != nil {
 			return nil, fmt.Errorf("status: refresh staging: %w", err)
 		}
 	}
+	if monitor != nil {
+		r.saveFSMonitorState(monitor.token, entries)
+	}
 
 	return entries, nil
 }
 
+// refreshStaging writes back the stat data Status refreshed in stg. The
+// refresh is only a cache: when another process holds the index lock or
+// has rewritten the index since stg was read, the refresh is dropped
+// rather than waited for.
+func (r *Repo) refreshStaging(stg *Staging) error {
+	err := r.writeStaging(stg, false, 0)
+	if err != nil && !errors.Is(err, ErrIndexChanged) && !errors.Is(err, lockfile.ErrLocked) {
+		return err
+	}
+	return nil
+}
+
+// statusHashJob is a tracked file whose stat data no longer matches the
+// index, so Status must hash it to learn whether its content changed.
+type statusHashJob struct {
+	entry   *StatusEntry
+	staged  *StagingEntry
+	absPath string
+	info    os.FileInfo
+	mode    string
+}
+
+// hashStatusCandidates hashes the working-tree content of jobs on a bounded
+// worker pool and returns the hashes in job order. After a branch switch
+// most tracked files fail the stat check at once, so hashing them one at a
+// time would leave all but one core idle. When several files fail to read,
+// the error for the first in job order is returned.
+func (r *Repo) hashStatusCandidates(ctx context.Context, jobs []statusHashJob, le *lineEndings) ([]object.Hash, error) {
+	hashes := make([]object.Hash, len(jobs))
+	errs := make([]error, len(jobs))
+	hashOne := func(i int) {
+		job := jobs[i]
+		if errs[i] = ctx.Err(); errs[i] != nil {
+			return
+		}
+		hashes[i], errs[i] = r.worktreeBlobHash(job.entry.Path, job.absPath, job.info, job.mode, le)
+	}
+
+	workers := addWorkerCount(len(jobs))
+	if workers <= 1 {
+		for i := range jobs {
+			hashOne(i)
+		}
+	} else {
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					hashOne(i)
+				}
+			}()
+		}
+		for i := range jobs {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("status: %w", err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("status: read %q: %w", jobs[i].entry.Path, err)
+		}
+	}
+	return hashes, nil
+}
+
+// walkStatusFiles records in workFiles the working-tree files under the
+// absolute directory start that Status reports: tracked files, and untracked
+// files that are neither ignored nor outside the sparse checkout. Untracked
+// directories that are repositories of their own are recorded in nested and
+// not entered. The walk stops with ctx's error once ctx is done.
+func (r *Repo) walkStatusFiles(ctx context.Context, start string, ic *IgnoreChecker, trackedPaths, trackedDirs map[string]struct{}, sparseEnabled bool, workFiles map[string]bool, nested map[string]struct{}) error {
+	return filepath.WalkDir(start, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(r.RootDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		// Skip the root directory itself.
+		if rel == "." {
+			return nil
+		}
+
+		// Ignore rules should not hide already tracked paths. Otherwise a root
+		// ignore like "orchard" would make tracked files under cmd/orchard/ look
+		// deleted in status output.
+		if ic.IsIgnored(rel) {
+			if _, tracked := trackedPaths[rel]; tracked {
+				// Keep walking/recording tracked paths even if they currently match
+				// an ignore rule.
+			} else if d.IsDir() {
+				if _, keepWalking := trackedDirs[rel]; keepWalking {
+					// An ignored directory still contains tracked content, so it must
+					// remain visible to the status walk.
+				} else {
+					return fs.SkipDir
+				}
+			} else {
+				return nil
+			}
+		}
+
+		// Skip paths excluded by sparse checkout.
+		if sparseEnabled && !r.matchesSparsePatterns(rel) {
+			if d.IsDir() {
+				if !r.dirCouldContainSparseMatch(rel) {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			if _, tracked := trackedDirs[rel]; !tracked && isNestedRepoDir(path) {
+				nested[rel] = struct{}{}
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		// Only track regular files.
+		workFiles[rel] = true
+		return nil
+	})
+}
+
 func trackedStatusPaths(stg *Staging) (map[string]struct{}, map[string]struct{}) {
 	trackedPaths := make(map[string]struct{}, len(stg.Entries))
 	trackedDirs := make(map[string]struct{})
@@ -506,7 +672,7 @@ func detectIndexRenames(stg *Staging, headEntries map[string]headTreeState) (map
 	return pairRenameCandidates(newByKey, oldByKey)
 }
 
-func (r *Repo) detectWorktreeRenames(stg *Staging, workFiles map[string]bool) (map[string]string, map[string]string, error) {
+func (r *Repo) detectWorktreeRenames(stg *Staging, workFiles map[string]bool, trustMode bool, le *lineEndings) (map[string]string, map[string]string, error) {
 	oldByKey := make(map[string][]string)
 	newByKey := make(map[string][]string)
 
@@ -514,7 +680,12 @@ func (r *Repo) detectWorktreeRenames(stg *Staging, workFiles map[string]bool) (m
 		if workFiles[path] {
 			continue
 		}
-		key := renameMatchKey(se.BlobHash, se.Mode)
+		mode := se.Mode
+		if !trustMode && normalizeFileMode(mode) == object.TreeModeExecutable {
+			// New files cannot show the bit either; match them as plain files.
+			mode = object.TreeModeFile
+		}
+		key := renameMatchKey(se.BlobHash, mode)
 		oldByKey[key] = append(oldByKey[key], path)
 	}
 
@@ -523,12 +694,12 @@ func (r *Repo) detectWorktreeRenames(stg *Staging, workFiles map[string]bool) (m
 			continue
 		}
 		absPath := filepath.Join(r.RootDir, filepath.FromSlash(path))
-		info, err := os.Stat(absPath)
+		info, err := os.Lstat(absPath)
 		if err != nil {
 			return nil, nil, err
 		}
-		workMode := modeFromFileInfo(info)
-		blobHash, err := r.worktreeBlobHash(path, absPath, info, workMode)
+		workMode := worktreeMode(info, "", trustMode)
+		blobHash, err := r.worktreeBlobHash(path, absPath, info, workMode, le)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -30,18 +30,18 @@ func (r *Repo) invalidateStatusCache() {
 	r.statusHashCacheMu.Unlock()
 }
 
-func (r *Repo) worktreeBlobHash(path, absPath string, info os.FileInfo, mode string) (object.Hash, error) {
+func (r *Repo) worktreeBlobHash(path, absPath string, info os.FileInfo, mode string, le *lineEndings) (object.Hash, error) {
 	fingerprint := statusFingerprintFromFileInfo(info, mode)
 	if blobHash, ok := r.statusHashCacheLookup(path, fingerprint); ok {
 		return blobHash, nil
 	}
 
-	data, err := os.ReadFile(absPath)
+	data, err := readWorktreeFile(absPath, info)
 	if err != nil {
 		return "", err
 	}
 
-	blobHash := r.statusBlobHash(data)
+	blobHash := r.statusBlobHash(le.clean(path, data, mode))
 	r.statusHashCacheStore(path, fingerprint, blobHash)
 	return blobHash, nil
 }
@@ -1,8 +1,10 @@
 package repo
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -926,6 +928,82 @@ func TestStatus_DetectsSameSizeRewriteWhenMtimeRestored(t *testing.T) {
 	}
 }
 
+func TestStatus_HashesManyTouchedFilesInParallel(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Init(dir)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	const fileCount = 64
+	paths := make([]string, 0, fileCount)
+	for i := 0; i < fileCount; i++ {
+		relPath := fmt.Sprintf("src/file-%02d.txt", i)
+		absPath := filepath.Join(dir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(absPath, []byte(fmt.Sprintf("content %d\n", i)), 0o644); err != nil {
+			t.Fatalf("write %s: %v", relPath, err)
+		}
+		paths = append(paths, relPath)
+	}
+	if err := r.Add(paths); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("initial", "test-author"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// Touch every file, as a branch switch would, and change a few of them
+	// without changing their size.
+	touchedTime := time.Now().Add(2 * time.Minute)
+	modified := map[string]bool{paths[3]: true, paths[40]: true, paths[63]: true}
+	for _, relPath := range paths {
+		absPath := filepath.Join(dir, filepath.FromSlash(relPath))
+		if modified[relPath] {
+			data, err := os.ReadFile(absPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[0] = 'C'
+			if err := os.WriteFile(absPath, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Chtimes(absPath, touchedTime, touchedTime); err != nil {
+			t.Fatalf("Chtimes(%s): %v", relPath, err)
+		}
+	}
+
+	var hashCalls atomic.Int64
+	r.statusBlobHasher = func(data []byte) object.Hash {
+		hashCalls.Add(1)
+		return object.HashObject(object.TypeBlob, data)
+	}
+
+	entries, err := r.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if got := hashCalls.Load(); got != fileCount {
+		t.Fatalf("hash calls = %d, want %d", got, fileCount)
+	}
+	for _, relPath := range paths {
+		entry := statusEntryForPath(entries, relPath)
+		if entry == nil {
+			t.Fatalf("missing %s in status", relPath)
+		}
+		want := StatusClean
+		if modified[relPath] {
+			want = StatusDirty
+		}
+		if entry.WorkStatus != want {
+			t.Errorf("%s WorkStatus = %d, want %d", relPath, entry.WorkStatus, want)
+		}
+	}
+}
+
 func statusEntryForPath(entries []StatusEntry, path string) *StatusEntry {
 	for i := range entries {
 		if entries[i].Path == path {
@@ -1,10 +1,12 @@
 package repo
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -69,23 +71,9 @@ func (r *Repo) CreateAnnotatedTag(name string, target object.Hash, tagger, messa
 	}
 
 	now := time.Now()
-	payload := fmt.Sprintf(
-		"object %s\n"+
-			"type %s\n"+
-			"tag %s\n"+
-			"tagger %s %d %s\n\n"+
-			"%s\n",
-		target,
-		targetType,
-		name,
-		tagger,
-		now.Unix(),
-		formatTimezoneOffset(now),
-		message,
-	)
 	tagHash, err := r.Store.WriteTag(&object.TagObj{
 		TargetHash: target,
-		Data:       []byte(payload),
+		Data:       formatTagData(target, targetType, name, tagger, now.Unix(), formatTimezoneOffset(now), message),
 	})
 	if err != nil {
 		return "", fmt.Errorf("create annotated tag: write tag object: %w", err)
@@ -107,7 +95,7 @@ func (r *Repo) DeleteTag(name string) error {
 	refPath := filepath.Join(r.refsBaseDir(), "refs", "tags", filepath.FromSlash(name))
 	if err := os.Remove(refPath); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("delete tag: tag %q does not exist", name)
+			return fmt.Errorf("delete tag: tag %q does not exist: %w", name, ErrRefNotFound)
 		}
 		return fmt.Errorf("delete tag: %w", err)
 	}
@@ -155,6 +143,220 @@ func (r *Repo) ListTagsWithHashes() (map[string]object.Hash, error) {
 	return out, nil
 }
 
+// TagInfo describes a tag for listing.
+type TagInfo struct {
+	Name string
+	// Target is the hash the tag ref points at: a tag object for an
+	// annotated tag, otherwise the tagged object itself.
+	Target object.Hash
+	// Commit is the commit the tag peels to, or empty when it tags
+	// something else.
+	Commit    object.Hash
+	Annotated bool
+	Tagger    string
+	// Timestamp is the tagger date of an annotated tag, or the commit date
+	// of a lightweight one.
+	Timestamp int64
+	// Message is the annotation of an annotated tag, or the message of
+	// the tagged commit for a lightweight one.
+	Message string
+}
+
+// TagListOptions controls ListTagInfo.
+type TagListOptions struct {
+	// Sort orders the tags: "refname" (the default), "version:refname"
+	// (or "v:refname"), which compares runs of digits numerically, or
+	// "creatordate". A leading "-" reverses the order.
+	Sort string
+	// Contains keeps only the tags whose commit has this revision as an
+	// ancestor, or is it.
+	Contains string
+}
+
+// ListTagInfo lists tags with the details read from their tag objects,
+// filtered and sorted per opts.
+func (r *Repo) ListTagInfo(opts TagListOptions) ([]TagInfo, error) {
+	less, err := tagSortFunc(opts.Sort)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	var contains object.Hash
+	if rev := strings.TrimSpace(opts.Contains); rev != "" {
+		h, err := r.ResolveTreeish(rev)
+		if err != nil {
+			return nil, fmt.Errorf("list tags: --contains: %w", err)
+		}
+		if contains, err = r.peelToCommit(h); err != nil {
+			return nil, fmt.Errorf("list tags: --contains %s: %w", rev, err)
+		}
+	}
+
+	tags, err := r.ListTagsWithHashes()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]TagInfo, 0, len(tags))
+	for name, target := range tags {
+		info, err := r.readTagInfo(name, target)
+		if err != nil {
+			return nil, fmt.Errorf("list tags: %s: %w", name, err)
+		}
+		if contains != "" {
+			if info.Commit == "" {
+				continue
+			}
+			base, err := r.FindMergeBase(contains, info.Commit)
+			if err != nil {
+				return nil, fmt.Errorf("list tags: %s: %w", name, err)
+			}
+			if base != contains {
+				continue
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return less(infos[i], infos[j]) })
+	return infos, nil
+}
+
+// readTagInfo reads the tag object, if any, behind target and the commit
+// it peels to.
+func (r *Repo) readTagInfo(name string, target object.Hash) (TagInfo, error) {
+	info := TagInfo{Name: name, Target: target}
+	objType, _, err := r.Store.Stat(target)
+	if err != nil {
+		return info, err
+	}
+	if objType == object.TypeTag {
+		tag, err := r.Store.ReadTag(target)
+		if err != nil {
+			return info, err
+		}
+		info.Annotated = true
+		info.Tagger, info.Timestamp, info.Message = parseTagData(tag.Data)
+	}
+	commit, err := r.peelToCommit(target)
+	if err != nil {
+		// A tag of a tree or blob has no commit to report.
+		return info, nil
+	}
+	info.Commit = commit
+	if !info.Annotated {
+		c, err := r.Store.ReadCommit(commit)
+		if err != nil {
+			return info, err
+		}
+		info.Timestamp = c.Timestamp
+		info.Message = strings.TrimSpace(c.Message)
+	}
+	return info, nil
+}
+
+// formatTagData builds the payload of an annotated tag object.
+func formatTagData(target object.Hash, targetType object.ObjectType, name, tagger string, ts int64, tz, message string) []byte {
+	return []byte(fmt.Sprintf(
+		"object %s\n"+
+			"type %s\n"+
+			"tag %s\n"+
+			"tagger %s %d %s\n\n"+
+			"%s\n",
+		target,
+		targetType,
+		name,
+		tagger,
+		ts,
+		tz,
+		message,
+	))
+}
+
+// parseTagData reads the tagger, tagger date and message of the payload
+// CreateAnnotatedTag writes.
+func parseTagData(data []byte) (tagger string, timestamp int64, message string) {
+	header, body, _ := strings.Cut(string(data), "\n\n")
+	for _, line := range strings.Split(header, "\n") {
+		rest, ok := strings.CutPrefix(line, "tagger ")
+		if !ok {
+			continue
+		}
+		// The identity may contain spaces; the date and zone come last.
+		fields := strings.Fields(rest)
+		if len(fields) >= 3 {
+			if ts, err := strconv.ParseInt(fields[len(fields)-2], 10, 64); err == nil {
+				timestamp = ts
+				rest = strings.Join(fields[:len(fields)-2], " ")
+			}
+		}
+		tagger = rest
+	}
+	return tagger, timestamp, strings.TrimSpace(body)
+}
+
+// tagSortFunc returns the ordering named by a TagListOptions.Sort key.
+func tagSortFunc(key string) (func(a, b TagInfo) bool, error) {
+	field, reverse := strings.CutPrefix(strings.TrimSpace(key), "-")
+	var less func(a, b TagInfo) bool
+	switch field {
+	case "", "refname":
+		less = func(a, b TagInfo) bool { return a.Name < b.Name }
+	case "version:refname", "v:refname":
+		less = func(a, b TagInfo) bool {
+			if c := compareVersions(a.Name, b.Name); c != 0 {
+				return c < 0
+			}
+			return a.Name < b.Name
+		}
+	case "creatordate":
+		less = func(a, b TagInfo) bool {
+			if a.Timestamp != b.Timestamp {
+				return a.Timestamp < b.Timestamp
+			}
+			return a.Name < b.Name
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sort key %q (want refname, version:refname or creatordate)", key)
+	}
+	if reverse {
+		return func(a, b TagInfo) bool { return less(b, a) }, nil
+	}
+	return less, nil
+}
+
+// compareVersions compares two tag names as versions: runs of digits
+// compare numerically and everything else byte by byte, so v1.10 sorts
+// after v1.9.
+func compareVersions(a, b string) int {
+	for a != "" && b != "" {
+		ra, restA := versionRun(a)
+		rb, restB := versionRun(b)
+		if isDigit(ra[0]) && isDigit(rb[0]) {
+			na, nb := strings.TrimLeft(ra, "0"), strings.TrimLeft(rb, "0")
+			if len(na) != len(nb) {
+				return cmp.Compare(len(na), len(nb))
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+		} else if c := strings.Compare(ra, rb); c != 0 {
+			return c
+		}
+		a, b = restA, restB
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// versionRun splits off the leading run of digits or non-digits of s.
+func versionRun(s string) (run, rest string) {
+	digits := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
 func validateTagName(name string) error {
 	if name == "" {
 		return fmt.Errorf("tag name is required")
@@ -159,3 +159,101 @@ func TestCreateAnnotatedTagRequiresMessage(t *testing.T) {
 		t.Fatalf("expected CreateAnnotatedTag to fail without message")
 	}
 }
+
+func TestListTagInfoReadsMessagesSortsAndFilters(t *testing.T) {
+	r := initRepoWithFile(t, "main.go", []byte("package main\n"))
+	first, err := r.Commit("first release", "test-author")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	second := commitFile(t, r, "main.go", []byte("package main\n\nfunc main() {}\n"), "second release")
+
+	if err := r.CreateTag("v1.9", first, false); err != nil {
+		t.Fatalf("CreateTag v1.9: %v", err)
+	}
+	if _, err := r.CreateAnnotatedTag("v1.10", second, "Tagger <t@example.com>", "Big release\n\nwith notes", false); err != nil {
+		t.Fatalf("CreateAnnotatedTag v1.10: %v", err)
+	}
+	if err := r.CreateTag("v1.2", first, false); err != nil {
+		t.Fatalf("CreateTag v1.2: %v", err)
+	}
+
+	names := func(infos []TagInfo) string {
+		var out []string
+		for _, info := range infos {
+			out = append(out, info.Name)
+		}
+		return strings.Join(out, " ")
+	}
+
+	infos, err := r.ListTagInfo(TagListOptions{})
+	if err != nil {
+		t.Fatalf("ListTagInfo: %v", err)
+	}
+	if got := names(infos); got != "v1.10 v1.2 v1.9" {
+		t.Fatalf("refname order = %q", got)
+	}
+	annotated := infos[0]
+	if !annotated.Annotated || annotated.Commit != second || annotated.Tagger != "Tagger <t@example.com>" || annotated.Timestamp == 0 {
+		t.Fatalf("annotated tag info = %+v", annotated)
+	}
+	if annotated.Message != "Big release\n\nwith notes" {
+		t.Fatalf("annotated tag message = %q", annotated.Message)
+	}
+	if infos[1].Annotated || infos[1].Message != "first release" || infos[1].Commit != first {
+		t.Fatalf("lightweight tag info = %+v", infos[1])
+	}
+
+	infos, err = r.ListTagInfo(TagListOptions{Sort: "version:refname"})
+	if err != nil {
+		t.Fatalf("ListTagInfo version sort: %v", err)
+	}
+	if got := names(infos); got != "v1.2 v1.9 v1.10" {
+		t.Fatalf("version order = %q", got)
+	}
+	infos, err = r.ListTagInfo(TagListOptions{Sort: "-v:refname"})
+	if err != nil {
+		t.Fatalf("ListTagInfo reversed version sort: %v", err)
+	}
+	if got := names(infos); got != "v1.10 v1.9 v1.2" {
+		t.Fatalf("reversed version order = %q", got)
+	}
+
+	infos, err = r.ListTagInfo(TagListOptions{Contains: string(second)})
+	if err != nil {
+		t.Fatalf("ListTagInfo contains: %v", err)
+	}
+	if got := names(infos); got != "v1.10" {
+		t.Fatalf("tags containing the second commit = %q, want v1.10", got)
+	}
+	infos, err = r.ListTagInfo(TagListOptions{Contains: "v1.9"})
+	if err != nil {
+		t.Fatalf("ListTagInfo contains tag: %v", err)
+	}
+	if got := names(infos); got != "v1.10 v1.2 v1.9" {
+		t.Fatalf("tags containing the first commit = %q", got)
+	}
+
+	if _, err := r.ListTagInfo(TagListOptions{Sort: "size"}); err == nil {
+		t.Fatal("ListTagInfo with an unknown sort key succeeded")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.9", "v1.10", -1},
+		{"v1.10", "v1.9", 1},
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2", "v1.2.1", -1},
+		{"v01.2", "v1.10", -1},
+		{"release-2", "release-10", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
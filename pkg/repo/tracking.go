@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/odvcencio/graft/pkg/object"
+)
+
+// defaultUpstreamRemote is the remote whose tracking refs a branch is
+// compared against, matching the default of fetch, pull and push.
+const defaultUpstreamRemote = "origin"
+
+// TrackingInfo relates a local branch to its upstream tracking ref.
+type TrackingInfo struct {
+	Upstream string // tracking ref, e.g. "refs/remotes/origin/heads/main"
+	Name     string // short upstream name, e.g. "origin/main"
+	Ahead    int    // commits on the branch but not on the upstream
+	Behind   int    // commits on the upstream but not on the branch
+}
+
+// BranchTracking compares branch with the tracking ref that a fetch of the
+// same-named branch from origin updates. It returns nil when origin is not
+// configured, its fetch refspecs leave the branch out, or the branch has
+// not been fetched yet.
+func (r *Repo) BranchTracking(branch string) (*TrackingInfo, error) {
+	if branch == "" {
+		return nil, nil
+	}
+	if _, err := r.RemoteURL(defaultUpstreamRemote); err != nil {
+		return nil, nil
+	}
+	upstream, ok, err := r.TrackingRefFor(defaultUpstreamRemote, "heads/"+branch)
+	if err != nil {
+		return nil, fmt.Errorf("branch tracking: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	upstreamHash, err := r.ResolveRef(upstream)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("branch tracking: resolve %s: %w", upstream, err)
+	}
+	localHash, err := r.ResolveRef("refs/heads/" + branch)
+	if err != nil {
+		return nil, fmt.Errorf("branch tracking: resolve %s: %w", branch, err)
+	}
+
+	ahead, behind, err := r.AheadBehind(localHash, upstreamHash)
+	if err != nil {
+		return nil, fmt.Errorf("branch tracking: %w", err)
+	}
+	return &TrackingInfo{
+		Upstream: upstream,
+		Name:     upstreamDisplayName(upstream),
+		Ahead:    ahead,
+		Behind:   behind,
+	}, nil
+}
+
+// AheadBehind counts the commits reachable from local but not from
+// upstream (ahead) and the reverse (behind). When one side contains the
+// other, found through the merge base, only the newer side is walked.
+func (r *Repo) AheadBehind(local, upstream object.Hash) (ahead, behind int, err error) {
+	if local == upstream {
+		return 0, 0, nil
+	}
+	base, err := r.FindMergeBase(local, upstream)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if base != upstream {
+		exclude, err := r.reachableCommits(local, nil)
+		if err != nil {
+			return 0, 0, err
+		}
+		only, err := r.reachableCommits(upstream, exclude)
+		if err != nil {
+			return 0, 0, err
+		}
+		behind = len(only)
+	}
+	if base != local {
+		exclude, err := r.reachableCommits(upstream, nil)
+		if err != nil {
+			return 0, 0, err
+		}
+		only, err := r.reachableCommits(local, exclude)
+		if err != nil {
+			return 0, 0, err
+		}
+		ahead = len(only)
+	}
+	return ahead, behind, nil
+}
+
+// reachableCommits returns the commits reachable from start, not walking
+// past any commit in exclude.
+func (r *Repo) reachableCommits(start object.Hash, exclude map[object.Hash]bool) (map[object.Hash]bool, error) {
+	seen := make(map[object.Hash]bool)
+	queue := []object.Hash{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == "" || seen[cur] || exclude[cur] {
+			continue
+		}
+		seen[cur] = true
+
+		commit, err := r.Store.ReadCommit(cur)
+		if err != nil {
+			return nil, fmt.Errorf("read commit %s: %w", cur, err)
+		}
+		queue = append(queue, commit.Parents...)
+	}
+	return seen, nil
+}
+
+// upstreamDisplayName shortens a tracking ref for display:
+// "refs/remotes/origin/heads/main" becomes "origin/main".
+func upstreamDisplayName(ref string) string {
+	name := strings.TrimPrefix(ref, "refs/remotes/")
+	if remote, rest, ok := strings.Cut(name, "/"); ok {
+		return remote + "/" + strings.TrimPrefix(rest, "heads/")
+	}
+	return name
+}
@@ -0,0 +1,68 @@
+package repo
+
+import "testing"
+
+func TestBranchTracking_AheadBehind(t *testing.T) {
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitFile(t, r, "a.txt", []byte("a\n"), "base")
+	branch, err := r.CurrentBranch()
+	if err != nil {
+		t.Fatalf("CurrentBranch: %v", err)
+	}
+	base, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+
+	if info, err := r.BranchTracking(branch); err != nil || info != nil {
+		t.Fatalf("BranchTracking without origin = %+v, %v; want nil", info, err)
+	}
+	if err := r.SetRemote("origin", "https://example.com/got/alice/repo"); err != nil {
+		t.Fatalf("SetRemote: %v", err)
+	}
+	if info, err := r.BranchTracking(branch); err != nil || info != nil {
+		t.Fatalf("BranchTracking before fetch = %+v, %v; want nil", info, err)
+	}
+
+	upstreamRef := "refs/remotes/origin/heads/" + branch
+	if err := r.UpdateRef(upstreamRef, base); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+	commitFile(t, r, "b.txt", []byte("b\n"), "local one")
+	commitFile(t, r, "c.txt", []byte("c\n"), "local two")
+
+	info, err := r.BranchTracking(branch)
+	if err != nil {
+		t.Fatalf("BranchTracking: %v", err)
+	}
+	if info == nil || info.Name != "origin/"+branch || info.Ahead != 2 || info.Behind != 0 {
+		t.Fatalf("BranchTracking = %+v, want 2 ahead of origin/%s", info, branch)
+	}
+
+	// Give the upstream a commit of its own so the histories diverge.
+	local, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	if err := r.ResetToCommit(base, ResetHard); err != nil {
+		t.Fatalf("ResetToCommit: %v", err)
+	}
+	remoteTip := commitFile(t, r, "d.txt", []byte("d\n"), "remote")
+	if err := r.UpdateRef(upstreamRef, remoteTip); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+	if err := r.UpdateRef("refs/heads/"+branch, local); err != nil {
+		t.Fatalf("UpdateRef: %v", err)
+	}
+
+	ahead, behind, err := r.AheadBehind(local, remoteTip)
+	if err != nil || ahead != 2 || behind != 1 {
+		t.Fatalf("AheadBehind = %d, %d, %v; want 2, 1", ahead, behind, err)
+	}
+	if ahead, behind, err := r.AheadBehind(base, base); err != nil || ahead != 0 || behind != 0 {
+		t.Fatalf("AheadBehind(same) = %d, %d, %v; want 0, 0", ahead, behind, err)
+	}
+}
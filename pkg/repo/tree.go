@@ -265,6 +265,43 @@ func (r *Repo) FlattenTree(h object.Hash) ([]TreeFileEntry, error) {
 	return result, nil
 }
 
+// flattenCheckoutTree is FlattenTree for a tree about to be written to the
+// working tree. It rejects trees checkout cannot write safely; see
+// checkCheckoutPaths.
+func (r *Repo) flattenCheckoutTree(h object.Hash) ([]TreeFileEntry, error) {
+	files, err := r.FlattenTree(h)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCheckoutPaths(files); err != nil {
+		return nil, fmt.Errorf("tree %s: %w", h, err)
+	}
+	return files, nil
+}
+
+// checkCheckoutPaths rejects flattened entries with the same path, and
+// entries below the path of a file or symlink, such as a tree holding both
+// a symlink "a" and a directory "a" would produce.
+func checkCheckoutPaths(files []TreeFileEntry) error {
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		p := path.Clean(f.Path)
+		if seen[p] {
+			return fmt.Errorf("duplicate entry %q", p)
+		}
+		seen[p] = true
+	}
+	for _, f := range files {
+		p := path.Clean(f.Path)
+		for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if seen[dir] {
+				return fmt.Errorf("entry %q is below the file or symlink %q", p, dir)
+			}
+		}
+	}
+	return nil
+}
+
 func (r *Repo) flattenTreeInto(h object.Hash, prefix string, out *[]TreeFileEntry) error {
 	treeObj, err := r.Store.ReadTree(h)
 	if err != nil {
@@ -129,23 +129,24 @@ func (r *Repo) WorktreeAdd(path, branch string) (*Repo, error) {
 		return nil, fmt.Errorf("worktree add: read commit %s: %w", branchHash, err)
 	}
 
-	files, err := wtRepo.FlattenTree(commit.TreeHash)
+	files, err := wtRepo.flattenCheckoutTree(commit.TreeHash)
 	if err != nil {
 		return nil, fmt.Errorf("worktree add: flatten tree: %w", err)
 	}
 
 	// Write files to the worktree working directory.
+	le := r.lineEndingsForTree(files)
 	for _, f := range files {
 		absFilePath := filepath.Join(absPath, filepath.FromSlash(f.Path))
 		dir := filepath.Dir(absFilePath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := mkdirWorktree(absPath, dir); err != nil {
 			return nil, fmt.Errorf("worktree add: mkdir %q: %w", dir, err)
 		}
 		blob, err := wtRepo.Store.ReadBlob(f.BlobHash)
 		if err != nil {
 			return nil, fmt.Errorf("worktree add: read blob for %q: %w", f.Path, err)
 		}
-		if err := os.WriteFile(absFilePath, blob.Data, filePermFromMode(f.Mode)); err != nil {
+		if err := writeWorktreeFile(absFilePath, le.smudge(f.Path, blob.Data, f.Mode), f.Mode); err != nil {
 			return nil, fmt.Errorf("worktree add: write %q: %w", f.Path, err)
 		}
 	}
@@ -154,7 +155,7 @@ func (r *Repo) WorktreeAdd(path, branch string) (*Repo, error) {
 	stg := &Staging{Entries: make(map[string]*StagingEntry, len(files))}
 	for _, f := range files {
 		absFilePath := filepath.Join(absPath, filepath.FromSlash(f.Path))
-		info, err := os.Stat(absFilePath)
+		info, err := os.Lstat(absFilePath)
 		if err != nil {
 			return nil, fmt.Errorf("worktree add: stat %q: %w", f.Path, err)
 		}
@@ -24,6 +24,11 @@ type OrchardProfile struct {
 	Token    string `json:"token,omitempty"`
 	Username string `json:"username,omitempty"`
 	Owner    string `json:"owner,omitempty"`
+
+	// RefreshToken and TokenExpiresAt (RFC 3339) are set when Token came
+	// from an OAuth login, so an expired Token can be renewed.
+	RefreshToken   string `json:"refresh_token,omitempty"`
+	TokenExpiresAt string `json:"token_expires_at,omitempty"`
 }
 
 type CoordConfig struct {
@@ -34,6 +39,23 @@ type CoordConfig struct {
 	DefaultConflictMode string `json:"default_conflict_mode,omitempty"`
 }
 
+// HTTPConfig configures how graft reaches remotes over HTTP(S).
+type HTTPConfig struct {
+	Proxy      string `json:"proxy,omitempty"`       // proxy URL; empty honors HTTP(S)_PROXY
+	CAFile     string `json:"ca_file,omitempty"`     // PEM bundle of extra trusted roots
+	ClientCert string `json:"client_cert,omitempty"` // PEM client certificate for mutual TLS
+	ClientKey  string `json:"client_key,omitempty"`  // PEM private key for ClientCert
+
+	RetryMaxAttempts int    `json:"retry_max_attempts,omitempty"` // attempts per request, including the first
+	RetryBackoff     string `json:"retry_backoff,omitempty"`      // initial retry delay, e.g. "500ms"
+	RetryMaxBackoff  string `json:"retry_max_backoff,omitempty"`  // longest retry delay, e.g. "30s"
+	RetryStatus      []int  `json:"retry_status,omitempty"`       // HTTP statuses to retry
+
+	MaxUploadRate         int64 `json:"max_upload_rate,omitempty"`         // upload bytes per second; 0 is unlimited
+	MaxDownloadRate       int64 `json:"max_download_rate,omitempty"`       // download bytes per second; 0 is unlimited
+	MaxConcurrentRequests int   `json:"max_concurrent_requests,omitempty"` // requests in flight at once; 0 is unlimited
+}
+
 type Config struct {
 	Version         int                       `json:"version"`
 	Name            string                    `json:"name,omitempty"`
@@ -47,6 +69,20 @@ type Config struct {
 	AutoSign        bool                      `json:"auto_sign,omitempty"`
 	Workspaces      map[string]string         `json:"workspaces,omitempty"`
 	Coord           CoordConfig               `json:"coord,omitempty"`
+	HTTP            HTTPConfig                `json:"http,omitempty"`
+
+	// ExcludesFile is a user-wide ignore file applied to every repository
+	// before its own ignore files. Empty means ~/.config/graft/ignore.
+	ExcludesFile string `json:"excludes_file,omitempty"`
+
+	// Pager is the command long output is piped through; a repository's
+	// core.pager overrides it. ColorUI is "auto", "always" or "never".
+	Pager   string `json:"pager,omitempty"`
+	ColorUI string `json:"color_ui,omitempty"`
+
+	// CommitTemplate is a file the commit message editor starts from; a
+	// repository's commit.template overrides it.
+	CommitTemplate string `json:"commit_template,omitempty"`
 }
 
 // Load reads ~/.graftconfig. Missing file returns an empty config.
@@ -120,6 +156,31 @@ func Save(cfg *Config) error {
 	return nil
 }
 
+// GlobalIgnorePath returns the user-wide ignore file: ExcludesFile with a
+// leading ~ expanded, or $XDG_CONFIG_HOME/graft/ignore (default
+// ~/.config/graft/ignore) when it is unset. It returns "" when no home
+// directory is known.
+func (c *Config) GlobalIgnorePath() string {
+	home, _ := os.UserHomeDir()
+	if c != nil && c.ExcludesFile != "" {
+		file := c.ExcludesFile
+		if file == "~" || strings.HasPrefix(file, "~/") {
+			if home == "" {
+				return ""
+			}
+			file = filepath.Join(home, file[1:])
+		}
+		return file
+	}
+	if xdg := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME")); xdg != "" {
+		return filepath.Join(xdg, "graft", "ignore")
+	}
+	if strings.TrimSpace(home) == "" {
+		return ""
+	}
+	return filepath.Join(home, ".config", "graft", "ignore")
+}
+
 // Path returns the absolute path for ~/.graftconfig.
 func Path() (string, error) {
 	return path()
@@ -231,11 +292,18 @@ func (c *Config) normalize() {
 	}
 	c.Name = strings.TrimSpace(c.Name)
 	c.Email = strings.TrimSpace(c.Email)
+	c.ExcludesFile = strings.TrimSpace(c.ExcludesFile)
 	c.OrchardURL = normalizeOrchardHostKey(c.OrchardURL)
 	c.Token = strings.TrimSpace(c.Token)
 	c.Username = strings.TrimSpace(c.Username)
 	c.Owner = strings.TrimSpace(c.Owner)
 	c.SigningKeyPath = strings.TrimSpace(c.SigningKeyPath)
+	c.HTTP.Proxy = strings.TrimSpace(c.HTTP.Proxy)
+	c.HTTP.CAFile = strings.TrimSpace(c.HTTP.CAFile)
+	c.HTTP.ClientCert = strings.TrimSpace(c.HTTP.ClientCert)
+	c.HTTP.ClientKey = strings.TrimSpace(c.HTTP.ClientKey)
+	c.HTTP.RetryBackoff = strings.TrimSpace(c.HTTP.RetryBackoff)
+	c.HTTP.RetryMaxBackoff = strings.TrimSpace(c.HTTP.RetryMaxBackoff)
 
 	if len(c.OrchardProfiles) > 0 {
 		normalized := make(map[string]OrchardProfile, len(c.OrchardProfiles))
@@ -277,17 +345,21 @@ func (p *OrchardProfile) normalize() {
 	p.Token = strings.TrimSpace(p.Token)
 	p.Username = strings.TrimSpace(p.Username)
 	p.Owner = strings.TrimSpace(p.Owner)
+	p.RefreshToken = strings.TrimSpace(p.RefreshToken)
+	p.TokenExpiresAt = strings.TrimSpace(p.TokenExpiresAt)
 }
 
 func (p OrchardProfile) isZero() bool {
-	return p.Token == "" && p.Username == "" && p.Owner == ""
+	return p.Token == "" && p.Username == "" && p.Owner == "" && p.RefreshToken == ""
 }
 
 func mergeOrchardProfiles(dst, src OrchardProfile) OrchardProfile {
 	dst.normalize()
 	src.normalize()
-	if dst.Token == "" {
+	if dst.Token == "" && dst.RefreshToken == "" {
 		dst.Token = src.Token
+		dst.RefreshToken = src.RefreshToken
+		dst.TokenExpiresAt = src.TokenExpiresAt
 	}
 	if dst.Username == "" {
 		dst.Username = src.Username
@@ -182,3 +182,23 @@ func TestOrchardProfileDoesNotLeakAcrossHosts(t *testing.T) {
 		t.Fatalf("unexpected profile leak: %+v", leaked)
 	}
 }
+
+func TestGlobalIgnorePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	if got, want := (&Config{}).GlobalIgnorePath(), filepath.Join(home, ".config", "graft", "ignore"); got != want {
+		t.Fatalf("default GlobalIgnorePath = %q, want %q", got, want)
+	}
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+	if got, want := (&Config{}).GlobalIgnorePath(), filepath.Join(home, "xdg", "graft", "ignore"); got != want {
+		t.Fatalf("XDG GlobalIgnorePath = %q, want %q", got, want)
+	}
+	if got, want := (&Config{ExcludesFile: "~/.ignore"}).GlobalIgnorePath(), filepath.Join(home, ".ignore"); got != want {
+		t.Fatalf("ExcludesFile GlobalIgnorePath = %q, want %q", got, want)
+	}
+	if got := (&Config{ExcludesFile: "/etc/graft-ignore"}).GlobalIgnorePath(); got != "/etc/graft-ignore" {
+		t.Fatalf("absolute ExcludesFile GlobalIgnorePath = %q", got)
+	}
+}
@@ -0,0 +1,644 @@
+// Package watchd implements graft's watch daemon. It polls a repository's
+// refs and working tree, keeps the status and the entities of changed files
+// warm in memory, and publishes change events to clients of a unix socket,
+// such as IDE plugins and shell prompts.
+//
+// Clients speak JSON lines. Each request is one Request object; "status"
+// and "entities" are answered with one Response, while "subscribe" is
+// answered with a stream of Event objects until the client disconnects.
+package watchd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/entity"
+	"github.com/odvcencio/graft/pkg/object"
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+// Event types published to subscribers.
+const (
+	EventHeadChanged     = "head_changed"     // HEAD moved or switched branch: previous, current, branch
+	EventRefChanged      = "ref_changed"      // a branch, tag or remote-tracking ref moved: ref, previous, current
+	EventStatusChanged   = "status_changed"   // paths whose status changed: entries
+	EventFileChanged     = "file_changed"     // a file that is not clean was edited: path
+	EventEntitiesChanged = "entities_changed" // declarations of a changed file: path, added, removed, modified
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// it is dropped.
+const subscriberBuffer = 256
+
+// Event is a change observed by the daemon.
+type Event struct {
+	Type string         `json:"type"`
+	Time time.Time      `json:"time"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Request is a client request: "status", "entities" with a path, or
+// "subscribe".
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path,omitempty"`
+}
+
+// Response answers a "status" or "entities" request.
+type Response struct {
+	Status   *Status  `json:"status,omitempty"`
+	Entities []Entity `json:"entities,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Status is the cached status of the repository: HEAD and the paths that
+// are not clean.
+type Status struct {
+	Head    string        `json:"head,omitempty"`
+	Branch  string        `json:"branch,omitempty"`
+	Entries []StatusEntry `json:"entries"`
+}
+
+// StatusEntry is the status of one path.
+type StatusEntry struct {
+	Path        string `json:"path"`
+	RenamedFrom string `json:"renamed_from,omitempty"`
+	IndexStatus string `json:"index_status"`
+	WorkStatus  string `json:"work_status"`
+}
+
+// Entity describes an entity of a working tree file.
+type Entity struct {
+	Key       string `json:"key"`
+	Name      string `json:"name,omitempty"`
+	Kind      string `json:"kind"`
+	Signature string `json:"signature,omitempty"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// Options configures a Daemon.
+type Options struct {
+	// Interval is how often the daemon polls; 500ms when zero.
+	Interval time.Duration
+	// Log receives the errors of polls after the first, which the daemon
+	// survives and retries; nil discards them.
+	Log io.Writer
+}
+
+// Daemon watches one repository.
+type Daemon struct {
+	repo *repo.Repo
+	opts Options
+
+	mu     sync.Mutex
+	polled bool
+	head   object.Hash
+	branch string
+	refs   map[string]object.Hash
+	status map[string]repo.StatusEntry
+	files  map[string]*fileState
+	subs   map[chan Event]struct{}
+}
+
+// fileState caches the entities of a working tree file for as long as its
+// size and modification time stay the same. entities is nil for files
+// whose language has no entity support.
+type fileState struct {
+	size     int64
+	modTime  time.Time
+	entities *entity.EntityList
+}
+
+// New returns a daemon for r. Poll or Run start it.
+func New(r *repo.Repo, opts Options) *Daemon {
+	if opts.Interval <= 0 {
+		opts.Interval = 500 * time.Millisecond
+	}
+	return &Daemon{
+		repo:  r,
+		opts:  opts,
+		files: make(map[string]*fileState),
+		subs:  make(map[chan Event]struct{}),
+	}
+}
+
+// SocketPath returns where the daemon of the repository with the given
+// .graft directory listens.
+func SocketPath(graftDir string) string {
+	return filepath.Join(graftDir, "daemon.sock")
+}
+
+// Listen listens on the daemon socket of graftDir, replacing a socket left
+// behind by a daemon that is gone. It fails if a daemon is running.
+func Listen(graftDir string) (net.Listener, error) {
+	path := SocketPath(graftDir)
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("remove stale socket: %w", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Dial connects to the daemon of the repository with the given .graft
+// directory.
+func Dial(graftDir string) (net.Conn, error) {
+	conn, err := net.Dial("unix", SocketPath(graftDir))
+	if err != nil {
+		return nil, fmt.Errorf("no daemon is running (start one with graft daemon): %w", err)
+	}
+	return conn, nil
+}
+
+// Run polls every interval and serves clients on l until ctx is done. It
+// closes l and disconnects subscribers on return. Only a failure of the
+// first poll stops it; later failures, such as an index locked by a running
+// command, are logged to Options.Log and the next poll tries again.
+func (d *Daemon) Run(ctx context.Context, l net.Listener) error {
+	if _, err := d.Poll(); err != nil {
+		return err
+	}
+	go d.serve(l)
+	defer func() {
+		l.Close()
+		d.mu.Lock()
+		for ch := range d.subs {
+			delete(d.subs, ch)
+			close(ch)
+		}
+		d.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+	lastErr := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			_, err := d.Poll()
+			switch {
+			case err == nil:
+				lastErr = ""
+			case err.Error() != lastErr:
+				// Log a failure once rather than on every poll.
+				lastErr = err.Error()
+				if d.opts.Log != nil {
+					fmt.Fprintf(d.opts.Log, "graft daemon: poll: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+// Poll refreshes the cached state and publishes, and returns, the events
+// for what changed since the last poll. The first poll only fills the
+// caches.
+func (d *Daemon) Poll() ([]Event, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now().UTC()
+	var events []Event
+	emit := func(eventType string, data map[string]any) {
+		events = append(events, Event{Type: eventType, Time: now, Data: data})
+	}
+
+	// HEAD is unborn, with no hash, in a repository without commits.
+	head, _ := d.repo.ResolveRef("HEAD")
+	branch := ""
+	if ref, err := d.repo.Head(); err == nil {
+		branch = strings.TrimPrefix(ref, "refs/heads/")
+	}
+	if d.polled && (head != d.head || branch != d.branch) {
+		emit(EventHeadChanged, map[string]any{"previous": string(d.head), "current": string(head), "branch": branch})
+	}
+	d.head, d.branch = head, branch
+
+	refs, err := d.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	if d.polled {
+		for _, name := range changedRefs(d.refs, refs) {
+			emit(EventRefChanged, map[string]any{"ref": name, "previous": string(d.refs[name]), "current": string(refs[name])})
+		}
+	}
+	d.refs = refs
+
+	entries, err := d.repo.Status()
+	if err != nil {
+		return nil, fmt.Errorf("status: %w", err)
+	}
+	status := make(map[string]repo.StatusEntry)
+	for _, e := range entries {
+		if e.IndexStatus != repo.StatusClean || e.WorkStatus != repo.StatusClean {
+			status[e.Path] = e
+		}
+	}
+	if d.polled {
+		if changed := changedStatus(d.status, status); len(changed) > 0 {
+			emit(EventStatusChanged, map[string]any{"entries": changed})
+		}
+	}
+	// A file that became clean matches the index again; forget its
+	// parse so that its next edit is compared with the index.
+	for p := range d.status {
+		if _, ok := status[p]; !ok {
+			delete(d.files, p)
+		}
+	}
+	d.status = status
+
+	// Parse changed files again as soon as their content changes, so
+	// that entities queries are answered from the cache and subscribers
+	// learn which declarations were edited.
+	paths := make([]string, 0, len(status))
+	for p := range status {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var stg *repo.Staging
+	for _, p := range paths {
+		prev, cached := d.files[p]
+		state, err := d.refreshFile(p)
+		if err != nil || state == nil || state == prev || !d.polled {
+			continue
+		}
+		emit(EventFileChanged, map[string]any{"path": p})
+		var before *entity.EntityList
+		if cached {
+			before = prev.entities
+		} else {
+			if stg == nil {
+				if stg, err = d.repo.ReadStaging(); err != nil {
+					return nil, fmt.Errorf("read index: %w", err)
+				}
+			}
+			before = d.stagedEntities(stg, p)
+		}
+		if added, removed, modified := diffEntities(before, state.entities); len(added)+len(removed)+len(modified) > 0 {
+			emit(EventEntitiesChanged, map[string]any{"path": p, "added": added, "removed": removed, "modified": modified})
+		}
+	}
+
+	d.polled = true
+	d.publish(events)
+	return events, nil
+}
+
+// Status returns the cached status.
+func (d *Daemon) Status() *Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := &Status{Head: string(d.head), Branch: d.branch, Entries: []StatusEntry{}}
+	for _, e := range d.status {
+		s.Entries = append(s.Entries, statusEntry(e))
+	}
+	sort.Slice(s.Entries, func(i, j int) bool { return s.Entries[i].Path < s.Entries[j].Path })
+	return s
+}
+
+// Entities returns the entities of the working tree file at path, relative
+// to the repository root, parsing it only if it changed since it was last
+// parsed.
+func (d *Daemon) Entities(path string) ([]Entity, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, err := d.refreshFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, fmt.Errorf("%s does not exist", path)
+	}
+	if state.entities == nil {
+		return nil, fmt.Errorf("%s: no entity support for this language", path)
+	}
+	out := []Entity{}
+	for _, e := range state.entities.Entities {
+		if e.Kind == entity.KindInterstitial {
+			continue
+		}
+		out = append(out, Entity{
+			Key:       e.IdentityKey(),
+			Name:      e.Name,
+			Kind:      e.Kind.String(),
+			Signature: e.Signature,
+			StartLine: e.StartLine,
+			EndLine:   e.EndLine,
+		})
+	}
+	return out, nil
+}
+
+// Subscribe returns a channel receiving every event published from now
+// on, and a function that ends the subscription. The channel is closed
+// when the subscription ends, when the daemon stops, or when the
+// subscriber falls too far behind.
+func (d *Daemon) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	d.mu.Lock()
+	d.subs[ch] = struct{}{}
+	d.mu.Unlock()
+	return ch, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if _, ok := d.subs[ch]; ok {
+			delete(d.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish sends events to the subscribers. d.mu must be held.
+func (d *Daemon) publish(events []Event) {
+	for ch := range d.subs {
+		for _, ev := range events {
+			select {
+			case ch <- ev:
+				continue
+			default:
+			}
+			// The subscriber fell too far behind.
+			delete(d.subs, ch)
+			close(ch)
+			break
+		}
+	}
+}
+
+// refreshFile returns the cached state of the file at p, parsing it again
+// if its size or modification time changed. It returns nil for a file
+// that does not exist. d.mu must be held.
+func (d *Daemon) refreshFile(p string) (*fileState, error) {
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || filepath.IsAbs(p) {
+		return nil, fmt.Errorf("path %q is outside the repository", p)
+	}
+	abs := filepath.Join(d.repo.RootDir, filepath.FromSlash(clean))
+	info, err := os.Stat(abs)
+	if os.IsNotExist(err) {
+		delete(d.files, clean)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", p)
+	}
+	if state, ok := d.files[clean]; ok && state.size == info.Size() && state.modTime.Equal(info.ModTime()) {
+		return state, nil
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	state := &fileState{size: info.Size(), modTime: info.ModTime()}
+	if el, err := entity.Extract(clean, data); err == nil {
+		state.entities = el
+	}
+	d.files[clean] = state
+	return state, nil
+}
+
+// stagedEntities returns the entities of the staged version of p, or nil
+// when p is not staged or has no entity support.
+func (d *Daemon) stagedEntities(stg *repo.Staging, p string) *entity.EntityList {
+	e, ok := stg.Entries[p]
+	if !ok || e.Conflict {
+		return nil
+	}
+	blob, err := d.repo.Store.ReadBlob(e.BlobHash)
+	if err != nil {
+		return nil
+	}
+	el, err := entity.Extract(p, blob.Data)
+	if err != nil {
+		return nil
+	}
+	return el
+}
+
+// listRefs returns the branches, tags and remote-tracking refs by full
+// name.
+func (d *Daemon) listRefs() (map[string]object.Hash, error) {
+	refs := make(map[string]object.Hash)
+	for _, prefix := range []string{"heads", "tags", "remotes"} {
+		found, err := d.repo.ListRefs(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for name, h := range found {
+			refs["refs/"+name] = h
+		}
+	}
+	return refs, nil
+}
+
+// serve accepts clients on l until it is closed.
+func (d *Daemon) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go d.handle(conn)
+	}
+}
+
+// handle answers the requests of one client.
+func (d *Daemon) handle(conn net.Conn) {
+	defer conn.Close()
+	enc := json.NewEncoder(conn)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			if enc.Encode(Response{Error: "invalid request: " + err.Error()}) != nil {
+				return
+			}
+			continue
+		}
+		var resp Response
+		switch req.Method {
+		case "status":
+			resp.Status = d.Status()
+		case "entities":
+			entities, err := d.Entities(req.Path)
+			if err != nil {
+				resp.Error = err.Error()
+			}
+			resp.Entities = entities
+		case "subscribe":
+			d.stream(conn, enc)
+			return
+		default:
+			resp.Error = fmt.Sprintf("unknown method %q", req.Method)
+		}
+		if enc.Encode(resp) != nil {
+			return
+		}
+	}
+}
+
+// stream writes events to a subscribed client until it disconnects or the
+// subscription ends.
+func (d *Daemon) stream(conn net.Conn, enc *json.Encoder) {
+	events, cancel := d.Subscribe()
+	defer cancel()
+
+	// A subscribed client sends nothing more; its reads end when it
+	// disconnects.
+	gone := make(chan struct{})
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				close(gone)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// changedRefs returns the names of refs that were created, moved or
+// deleted between before and after, sorted.
+func changedRefs(before, after map[string]object.Hash) []string {
+	var names []string
+	for name, h := range after {
+		if before[name] != h {
+			names = append(names, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// changedStatus returns the entries whose status differs between before
+// and after, with paths that became clean reported as clean.
+func changedStatus(before, after map[string]repo.StatusEntry) []StatusEntry {
+	var changed []StatusEntry
+	for p, e := range after {
+		if prev, ok := before[p]; !ok || prev != e {
+			changed = append(changed, statusEntry(e))
+		}
+	}
+	for p := range before {
+		if _, ok := after[p]; !ok {
+			changed = append(changed, StatusEntry{Path: p, IndexStatus: "clean", WorkStatus: "clean"})
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Path < changed[j].Path })
+	return changed
+}
+
+// diffEntities returns the identity keys of declarations added, removed
+// and modified from before to after. A missing list has no declarations.
+func diffEntities(before, after *entity.EntityList) (added, removed, modified []string) {
+	beforeMap, afterMap := declarations(before), declarations(after)
+	for key, e := range afterMap {
+		prev, ok := beforeMap[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case prev.BodyHash != e.BodyHash:
+			modified = append(modified, key)
+		}
+	}
+	for key := range beforeMap {
+		if _, ok := afterMap[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+	return added, removed, modified
+}
+
+// declarations indexes the declarations of el by identity key.
+func declarations(el *entity.EntityList) map[string]*entity.Entity {
+	if el == nil {
+		return nil
+	}
+	m := entity.BuildEntityMap(el)
+	for key, e := range m {
+		if e.Kind != entity.KindDeclaration {
+			delete(m, key)
+		}
+	}
+	return m
+}
+
+func statusEntry(e repo.StatusEntry) StatusEntry {
+	return StatusEntry{
+		Path:        e.Path,
+		RenamedFrom: e.RenamedFrom,
+		IndexStatus: fileStatusName(e.IndexStatus),
+		WorkStatus:  fileStatusName(e.WorkStatus),
+	}
+}
+
+func fileStatusName(status repo.FileStatus) string {
+	switch status {
+	case repo.StatusClean:
+		return "clean"
+	case repo.StatusNew:
+		return "new"
+	case repo.StatusModified:
+		return "modified"
+	case repo.StatusRenamed:
+		return "renamed"
+	case repo.StatusConflict:
+		return "conflict"
+	case repo.StatusDeleted:
+		return "deleted"
+	case repo.StatusUntracked:
+		return "untracked"
+	case repo.StatusDirty:
+		return "dirty"
+	default:
+		return "unknown"
+	}
+}
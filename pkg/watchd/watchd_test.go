@@ -0,0 +1,249 @@
+package watchd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/odvcencio/graft/pkg/repo"
+)
+
+func initWatchRepo(t *testing.T) (string, *repo.Repo) {
+	t.Helper()
+	dir := t.TempDir()
+	r, err := repo.Init(dir)
+	if err != nil {
+		t.Fatalf("repo.Init: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc a() int {\n\treturn 1\n}\n")
+	if err := r.Add([]string{"main.go"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := r.Commit("initial", "tester"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	return dir, r
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventsByType(events []Event) map[string]Event {
+	m := make(map[string]Event)
+	for _, ev := range events {
+		m[ev.Type] = ev
+	}
+	return m
+}
+
+func TestPollPublishesChanges(t *testing.T) {
+	dir, r := initWatchRepo(t)
+	d := New(r, Options{})
+	if events, err := d.Poll(); err != nil || len(events) != 0 {
+		t.Fatalf("first Poll = %v, %v; want no events", events, err)
+	}
+	sub, cancel := d.Subscribe()
+	defer cancel()
+
+	// Editing a function and adding one is reported against the index.
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc a() int {\n\treturn 2\n}\n\nfunc b() {}\n")
+	events, err := d.Poll()
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	byType := eventsByType(events)
+	if _, ok := byType[EventStatusChanged]; !ok {
+		t.Fatalf("events = %+v, want %s", events, EventStatusChanged)
+	}
+	if _, ok := byType[EventFileChanged]; !ok {
+		t.Fatalf("events = %+v, want %s", events, EventFileChanged)
+	}
+	ent, ok := byType[EventEntitiesChanged]
+	if !ok {
+		t.Fatalf("events = %+v, want %s", events, EventEntitiesChanged)
+	}
+	if added, modified := ent.Data["added"].([]string), ent.Data["modified"].([]string); len(added) != 1 || len(modified) != 1 {
+		t.Fatalf("entities_changed = %+v, want one added and one modified", ent.Data)
+	}
+	if got := len(sub); got != len(events) {
+		t.Fatalf("subscriber received %d events, want %d", got, len(events))
+	}
+
+	// Nothing changed, nothing to report.
+	if events, err := d.Poll(); err != nil || len(events) != 0 {
+		t.Fatalf("idle Poll = %+v, %v; want no events", events, err)
+	}
+
+	head, err := r.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateBranch("feature", head); err != nil {
+		t.Fatalf("CreateBranch: %v", err)
+	}
+	events, err = d.Poll()
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != EventRefChanged || events[0].Data["ref"] != "refs/heads/feature" {
+		t.Fatalf("events after branch = %+v, want one ref_changed for refs/heads/feature", events)
+	}
+
+	status := d.Status()
+	if len(status.Entries) != 1 || status.Entries[0].Path != "main.go" || status.Entries[0].WorkStatus != "dirty" || status.Branch != "main" {
+		t.Fatalf("Status = %+v", status)
+	}
+	entities, err := d.Entities("main.go")
+	if err != nil {
+		t.Fatalf("Entities: %v", err)
+	}
+	var names []string
+	for _, e := range entities {
+		if e.Kind == "declaration" {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Fatalf("Entities declarations = %v, want [a b]", names)
+	}
+	if _, err := d.Entities("../outside.go"); err == nil {
+		t.Fatal("Entities outside the repository succeeded")
+	}
+}
+
+func TestRunServesSocket(t *testing.T) {
+	dir, r := initWatchRepo(t)
+	l, err := Listen(r.GraftDir)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- New(r, Options{Interval: 10 * time.Millisecond}).Run(ctx, l) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+
+	if _, err := Listen(r.GraftDir); err == nil {
+		t.Fatal("second Listen succeeded while a daemon is running")
+	}
+
+	conn, err := Dial(r.GraftDir)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	enc := json.NewEncoder(conn)
+	reader := bufio.NewReader(conn)
+
+	if err := enc.Encode(Request{Method: "status"}); err != nil {
+		t.Fatal(err)
+	}
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read status: %v", err)
+	}
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil || resp.Status == nil || len(resp.Status.Entries) != 0 {
+		t.Fatalf("status response = %s, %v", line, err)
+	}
+
+	if err := enc.Encode(Request{Method: "subscribe"}); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the subscription to register before changing anything.
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "notes.txt"), "hello\n")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err = reader.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read event: %v", err)
+	}
+	var ev Event
+	if err := json.Unmarshal(line, &ev); err != nil || ev.Type != EventStatusChanged {
+		t.Fatalf("event = %s, %v; want %s", line, err, EventStatusChanged)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the daemon goroutine to write while
+// the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunSurvivesFailedPoll(t *testing.T) {
+	_, r := initWatchRepo(t)
+	l, err := Listen(r.GraftDir)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	var log syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- New(r, Options{Interval: 10 * time.Millisecond, Log: &log}).Run(ctx, l) }()
+	// Let the first poll succeed before breaking the index.
+	time.Sleep(50 * time.Millisecond)
+
+	indexPath := filepath.Join(r.GraftDir, "index")
+	index, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, indexPath, "not an index")
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(log.String(), "poll:") {
+		select {
+		case err := <-done:
+			t.Fatalf("Run returned after a failed poll: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("failed poll was not logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := os.WriteFile(indexPath, index, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned after a failed poll: %v", err)
+	default:
+	}
+	if n := strings.Count(log.String(), "poll:"); n != 1 {
+		t.Errorf("logged %d poll failures, want the repeated failure logged once:\n%s", n, log.String())
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+}